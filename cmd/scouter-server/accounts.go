@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zbum/scouter-server-go/internal/login"
+)
+
+// runImportAccounts merges the accounts and group policies of a Java
+// server's conf directory into the conf directory of this server.
+func runImportAccounts(args []string) {
+	fs := flag.NewFlagSet("import-accounts", flag.ExitOnError)
+	from := fs.String("from", "", "conf directory of the Java server (required)")
+	overwrite := fs.Bool("overwrite", false, "replace accounts and groups that already exist")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without writing")
+	fs.Parse(args)
+	if *from == "" {
+		fmt.Fprintln(os.Stderr, "--from is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	cfg, _ := loadToolConfig()
+	confDir := cfg.ConfDir()
+	if confDir == "" {
+		confDir = "./conf"
+	}
+	fmt.Printf("Import accounts: from=%s, conf=%s, overwrite=%v, dry-run=%v\n\n", *from, confDir, *overwrite, *dryRun)
+
+	result, err := login.ImportJavaAccounts(*from, confDir, *overwrite, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		os.Exit(1)
+	}
+	for _, l := range []struct {
+		label string
+		names []string
+	}{
+		{"groups added", result.GroupsAdded},
+		{"groups replaced", result.GroupsReplaced},
+		{"groups skipped", result.GroupsSkipped},
+		{"accounts added", result.AccountsAdded},
+		{"accounts replaced", result.AccountsReplaced},
+		{"accounts skipped", result.AccountsSkipped},
+	} {
+		if len(l.names) > 0 {
+			fmt.Printf("  %-18s %s\n", l.label+":", strings.Join(l.names, ", "))
+		}
+	}
+	for _, w := range result.Warnings {
+		fmt.Printf("  warning: %s\n", w)
+	}
+	fmt.Printf("\n=== Import Complete: %d accounts, %d groups ===\n",
+		len(result.AccountsAdded)+len(result.AccountsReplaced), len(result.GroupsAdded)+len(result.GroupsReplaced))
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/admin"
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db"
+	"github.com/zbum/scouter-server-go/internal/db/compress"
+	dbio "github.com/zbum/scouter-server-go/internal/db/io"
+	"github.com/zbum/scouter-server-go/internal/standby"
+)
+
+// startAdminSocket serves status, flush, day container, storage fault,
+// read-only, reload, config history and shutdown commands on the local admin
+// socket. standbyPub and standbyReplica may be nil.
+func startAdminSocket(ctx context.Context, shutdown context.CancelFunc, dataDir, confFile string,
+	objectCache *cache.ObjectCache, deadTimeout time.Duration, counterCheck *core.CounterCheck, clockSkew *core.ClockSkew,
+	ingestQuota *core.IngestQuota, xlogSampler *core.XLogSampler, ingestLatency *core.IngestLatency, ioStats *core.IOStats,
+	readOnly *core.ReadOnly, days *db.DayContainerAdmin,
+	standbyPub *standby.Publisher, standbyReplica *standby.Replica) error {
+	started := time.Now()
+	srv := admin.NewServer(admin.SocketPath(dataDir))
+
+	srv.Handle("status", func(args []string) (string, error) {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		var b strings.Builder
+		fmt.Fprintf(&b, "version: %s (built %s)\n", Version, BuildTime)
+		fmt.Fprintf(&b, "pid: %d\n", os.Getpid())
+		fmt.Fprintf(&b, "uptime: %s\n", time.Since(started).Round(time.Second))
+		fmt.Fprintf(&b, "data_dir: %s\n", dataDir)
+		fmt.Fprintf(&b, "config: %s\n", confFile)
+		fmt.Fprintf(&b, "objects: %d live / %d total\n", len(objectCache.GetLive(deadTimeout)), objectCache.Size())
+		fmt.Fprintf(&b, "goroutines: %d\n", runtime.NumGoroutine())
+		fmt.Fprintf(&b, "heap: %s\n", formatBytes(int64(mem.HeapAlloc)))
+		if cfg := config.Get(); cfg != nil && cfg.CounterCheckEnabled() {
+			fmt.Fprintf(&b, "counter check: %s\n", counterCheck.Summary())
+		}
+		if cfg := config.Get(); cfg != nil && cfg.ClockSkewCheckEnabled() {
+			fmt.Fprintf(&b, "clock skew: %s\n", clockSkew.Summary())
+		}
+		if cfg := config.Get(); cfg != nil && (cfg.IngestQuotaXLogPerSec() != "" || cfg.IngestQuotaProfilePerSec() != "") {
+			fmt.Fprintf(&b, "ingest quota: %s\n", ingestQuota.Summary())
+		}
+		if cfg := config.Get(); cfg != nil && cfg.XLogSamplingRate() < 100 {
+			fmt.Fprintf(&b, "xlog sampling: %s\n", xlogSampler.Summary())
+		}
+		fmt.Fprintf(&b, "ingest latency: %s\n", ingestLatency.Summary())
+		fmt.Fprintf(&b, "storage io: %s\n", ioStats.Summary())
+		if readOnly.Active() {
+			fmt.Fprintf(&b, "read-only: %s\n", readOnly.Summary())
+		}
+		if standbyPub != nil {
+			fmt.Fprintf(&b, "standby publisher: %s\n", standbyPub.Summary())
+		}
+		if standbyReplica != nil {
+			fmt.Fprintf(&b, "standby replica: %s\n", standbyReplica.Summary())
+		}
+		fmt.Fprintf(&b, "index flush: %s\n", flushSummary(dbio.GetFlushController().Stats()))
+		if comp := compress.GetStats().Snapshot(); len(comp) > 0 {
+			fmt.Fprintf(&b, "compression: %s\n", compressionSummary(comp))
+		}
+		if faults := dbio.GetFaultInjector().List(); len(faults) > 0 {
+			fmt.Fprintf(&b, "storage faults: %d injected (see admin fault)\n", len(faults))
+		}
+		return b.String(), nil
+	})
+	srv.Handle("flush", func(args []string) (string, error) {
+		return formatFlushStats(dataDir, dbio.GetFlushController().Stats()), nil
+	})
+	srv.Handle("days", func(args []string) (string, error) {
+		return formatDayContainers(days.List()), nil
+	})
+	srv.Handle("close-day", func(args []string) (string, error) {
+		if len(args) != 1 {
+			return "", fmt.Errorf("usage: close-day YYYYMMDD")
+		}
+		closed, err := days.Close(args[0])
+		if err != nil {
+			return "", err
+		}
+		return "closed: " + strings.Join(closed, ", "), nil
+	})
+	srv.Handle("open-day", func(args []string) (string, error) {
+		if len(args) != 1 {
+			return "", fmt.Errorf("usage: open-day YYYYMMDD")
+		}
+		opened, err := days.Open(args[0])
+		if err != nil {
+			return "", err
+		}
+		return "open: " + strings.Join(opened, ", "), nil
+	})
+	srv.Handle("fault", func(args []string) (string, error) {
+		faults := dbio.GetFaultInjector()
+		switch {
+		case len(args) == 0 || args[0] == "list":
+			return formatFaults(faults.List()), nil
+		case args[0] == "add":
+			f, err := dbio.ParseFault(args[1:])
+			if err != nil {
+				return "", fmt.Errorf("%v\nusage: fault add read|write [match=S] [latency=D] [error=PCT] [for=D]", err)
+			}
+			if err := faults.Add(f); err != nil {
+				return "", err
+			}
+			return "added: " + f.String(), nil
+		case args[0] == "clear":
+			return fmt.Sprintf("cleared %d faults", faults.Clear()), nil
+		}
+		return "", fmt.Errorf("usage: fault [list|add ...|clear]")
+	})
+	srv.Handle("read-only", func(args []string) (string, error) {
+		switch {
+		case len(args) == 0 || args[0] == "status":
+			return "read-only: " + readOnly.Summary(), nil
+		case args[0] == "on":
+			if !readOnly.Enable("admin") {
+				return "already read-only", nil
+			}
+			return "read-only: ingestion paused", nil
+		case args[0] == "off":
+			if !readOnly.Disable() {
+				return "not read-only", nil
+			}
+			return "writable: ingestion resumed", nil
+		}
+		return "", fmt.Errorf("usage: read-only [status|on|off]")
+	})
+	srv.Handle("reload", func(args []string) (string, error) {
+		if err := config.ReloadBy(confFile, "admin"); err != nil {
+			return "", err
+		}
+		if unknown := config.Get().UnknownKeys(); len(unknown) > 0 {
+			return "unknown keys ignored: " + strings.Join(unknown, ", "), nil
+		}
+		return "", nil
+	})
+	srv.Handle("config-history", func(args []string) (string, error) {
+		return formatConfigHistory(config.History(time.Time{})), nil
+	})
+	srv.Handle("shutdown", func(args []string) (string, error) {
+		// Reply before the listener is torn down by the cancelled context.
+		time.AfterFunc(100*time.Millisecond, shutdown)
+		return fmt.Sprintf("shutting down pid %d", os.Getpid()), nil
+	})
+
+	return srv.Start(ctx)
+}
+
+const adminUsage = `Usage: scouter-server admin <command> [--socket path] [--wait 30s] [date]
+
+  status           print version, uptime and object counts of the running server
+  flush            print per-file index flush intervals, counts and latencies
+  days             list open day containers with their index files and memory
+  close-day DATE   flush and close the containers of DATE (YYYYMMDD)
+  open-day DATE    open the read containers of DATE ahead of queries
+  fault [list]     list injected storage faults
+  fault add OP ... inject latency or errors into storage reads or writes
+                   (needs testing_fault_injection_enabled), e.g.
+                   fault add write match=xlog latency=200ms error=10 for=5m
+  fault clear      remove all injected storage faults
+  read-only on|off pause or resume ingestion for storage maintenance; queries
+                   and purges keep working
+  reload           re-read the configuration file now
+  config-history   list configuration changes since the server started
+  shutdown         gracefully stop the running server
+`
+
+func runAdmin(args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, adminUsage)
+		os.Exit(1)
+	}
+	command := args[0]
+
+	_, dataDir := loadToolConfig()
+	fs := flag.NewFlagSet("admin "+command, flag.ExitOnError)
+	socket := fs.String("socket", admin.SocketPath(dataDir), "admin socket path")
+	wait := fs.Duration("wait", 30*time.Second, "shutdown: how long to wait for the server to exit")
+	fs.Parse(args[1:])
+
+	out, err := admin.Send(*socket, strings.Join(append([]string{command}, fs.Args()...), " "), 10*time.Second)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "admin %s failed: %v\n", command, err)
+		os.Exit(1)
+	}
+	fmt.Print(out)
+
+	if command == "shutdown" {
+		// The data directory lock is released only when the process exits.
+		deadline := time.Now().Add(*wait)
+		for admin.IsLocked(filepath.Dir(*socket)) {
+			if time.Now().After(deadline) {
+				fmt.Fprintf(os.Stderr, "server still running after %s\n", *wait)
+				os.Exit(1)
+			}
+			time.Sleep(200 * time.Millisecond)
+		}
+		fmt.Println("stopped")
+	}
+}
+
+// flushSummary condenses the index flush statistics to one status line.
+func flushSummary(stats []dbio.FlushStat) string {
+	var flushes, bytes int64
+	var maxLatency time.Duration
+	dirty := 0
+	for _, st := range stats {
+		flushes += st.Flushes
+		bytes += st.Bytes
+		if st.Dirty > 0 {
+			dirty++
+		}
+		if st.MaxLatency > maxLatency {
+			maxLatency = st.MaxLatency
+		}
+	}
+	return fmt.Sprintf("%d files (%d dirty), %d flushes, %s flushed, max latency %s",
+		len(stats), dirty, flushes, formatBytes(bytes), maxLatency.Round(time.Microsecond))
+}
+
+// compressionSummary describes the data written since start per kind.
+func compressionSummary(stats []compress.KindStat) string {
+	parts := make([]string, len(stats))
+	for i, st := range stats {
+		parts[i] = fmt.Sprintf("%s %.2fx (%s -> %s, %d of %d records compressed)",
+			st.Kind, st.Ratio(), formatBytes(st.RawBytes), formatBytes(st.Stored), st.Compressed, st.Records)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatFlushStats renders one line per index file, paths relative to dataDir.
+func formatFlushStats(dataDir string, stats []dbio.FlushStat) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-10s %8s %10s %10s %10s %10s  %s\n", "interval", "flushes", "flushed", "dirty", "avg", "max", "file")
+	for _, st := range stats {
+		file := st.File
+		if rel, err := filepath.Rel(dataDir, file); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+		fmt.Fprintf(&b, "%-10s %8d %10s %10s %10s %10s  %s\n",
+			st.Interval.Round(time.Millisecond), st.Flushes, formatBytes(st.Bytes), formatBytes(int64(st.Dirty)),
+			st.AvgLatency.Round(time.Microsecond), st.MaxLatency.Round(time.Microsecond), file)
+	}
+	return b.String()
+}
+
+// formatFaults renders one line per injected storage fault.
+func formatFaults(faults []dbio.Fault) string {
+	if len(faults) == 0 {
+		return "no storage faults"
+	}
+	var b strings.Builder
+	for _, f := range faults {
+		fmt.Fprintf(&b, "%s (%d hits)\n", f, f.Hits)
+	}
+	return b.String()
+}
+
+// formatConfigHistory renders each change as a header line followed by one
+// line per key.
+func formatConfigHistory(changes []config.Change) string {
+	if len(changes) == 0 {
+		return "no configuration changes"
+	}
+	var b strings.Builder
+	for _, c := range changes {
+		fmt.Fprintf(&b, "%s by %s\n", c.Time.Format("2006-01-02 15:04:05"), c.Source)
+		for _, k := range c.Keys {
+			switch {
+			case k.Added:
+				fmt.Fprintf(&b, "  + %s=%s\n", k.Key, k.New)
+			case k.Removed:
+				fmt.Fprintf(&b, "  - %s=%s\n", k.Key, k.Old)
+			default:
+				fmt.Fprintf(&b, "  ~ %s: %s -> %s\n", k.Key, k.Old, k.New)
+			}
+		}
+	}
+	return b.String()
+}
+
+// formatDayContainers renders one line per open date.
+func formatDayContainers(infos []db.DayContainerInfo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-8s %6s %10s  %s\n", "date", "files", "memory", "components")
+	var files int
+	var mem int64
+	for _, info := range infos {
+		fmt.Fprintf(&b, "%-8s %6d %10s  %s\n", info.Date, info.Files, formatBytes(info.MemBytes), strings.Join(info.Components, ","))
+		files += info.Files
+		mem += info.MemBytes
+	}
+	fmt.Fprintf(&b, "%d dates, %d files, %s\n", len(infos), files, formatBytes(mem))
+	return b.String()
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/bench"
+	"github.com/zbum/scouter-server-go/internal/core"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	scoutercounter "github.com/zbum/scouter-server-go/internal/counter"
+	"github.com/zbum/scouter-server-go/internal/db/alert"
+	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/db/profile"
+	dbtext "github.com/zbum/scouter-server-go/internal/db/text"
+	"github.com/zbum/scouter-server-go/internal/db/xlog"
+	"github.com/zbum/scouter-server-go/internal/netio/udp"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// benchPipeline is an in-process collector used when no --target is given.
+type benchPipeline struct {
+	processor     *udp.NetDataProcessor
+	xlogCore      *core.XLogCore
+	profileCore   *core.ProfileCore
+	perfCountCore *core.PerfCountCore
+	xlogWR        *xlog.XLogWR
+	profileWR     *profile.ProfileWR
+	counterWR     *counter.CounterWR
+	closers       []func()
+}
+
+func newBenchPipeline(ctx context.Context, dataDir string) *benchPipeline {
+	textCache := cache.NewTextCache()
+	xlogCache := cache.NewXLogCache(10000)
+	counterCache := cache.NewCounterCache()
+	objectCache := cache.NewObjectCache()
+
+	textWR := dbtext.NewTextWR(dataDir)
+	textWR.Start(ctx)
+	xlogWR := xlog.NewXLogWR(dataDir)
+	xlogWR.Start(ctx)
+	counterWR := counter.NewCounterWR(dataDir)
+	counterWR.Start(ctx)
+	profileWR := profile.NewProfileWR(dataDir, 1000)
+	profileWR.Start(ctx)
+	alertWR := alert.NewAlertWR(dataDir)
+	alertWR.Start(ctx)
+	textRD := dbtext.NewTextRD(dataDir)
+
+	textCore := core.NewTextCore(textCache, textWR)
+	xlogGroupPerf := core.NewXLogGroupPerf(textCache, textRD)
+	xlogCore := core.NewXLogCore(xlogCache, xlogWR, profileWR, xlogGroupPerf, core.WithObjectCache(objectCache))
+	perfCountCore := core.NewPerfCountCore(counterCache, counterWR)
+	profileCore := core.NewProfileCore(profileWR)
+	alertCore := core.NewAlertCore(alertWR, cache.NewAlertCache(1024))
+	agentManager := core.NewAgentManager(objectCache, 8*time.Second, scoutercounter.NewObjectTypeManager(), textCache, textCore, alertCore)
+
+	dispatcher := core.NewDispatcher()
+	dispatcher.Register(pack.PackTypeText, textCore.Handler())
+	dispatcher.Register(pack.PackTypeXLog, xlogCore.Handler())
+	dispatcher.Register(pack.PackTypePerfCounter, perfCountCore.Handler())
+	dispatcher.Register(pack.PackTypeXLogProfile, profileCore.Handler())
+	dispatcher.Register(pack.PackTypeObject, agentManager.Handler())
+
+	return &benchPipeline{
+		processor:     udp.NewNetDataProcessor(dispatcher, 4),
+		xlogCore:      xlogCore,
+		profileCore:   profileCore,
+		perfCountCore: perfCountCore,
+		xlogWR:        xlogWR,
+		profileWR:     profileWR,
+		counterWR:     counterWR,
+		closers: []func(){
+			textWR.Close, xlogWR.Close, counterWR.Close,
+			profileWR.Close, alertWR.Close, textRD.Close,
+		},
+	}
+}
+
+// pending returns the number of packs queued anywhere in the pipeline.
+func (p *benchPipeline) pending() int {
+	return p.processor.Pending() + p.xlogCore.Pending() + p.profileCore.Pending() +
+		p.perfCountCore.Pending() + p.xlogWR.Pending() + p.profileWR.Pending() + p.counterWR.Pending()
+}
+
+// losslessSink feeds the pipeline but pauses while its queues are backed up,
+// so unthrottled sources such as replay don't overflow them.
+type losslessSink struct {
+	pipeline *benchPipeline
+	next     *bench.FuncSink
+	sent     int
+}
+
+const (
+	losslessCheckEvery = 64
+	losslessMaxPending = 256
+)
+
+func newLosslessSink(p *benchPipeline) *losslessSink {
+	return &losslessSink{pipeline: p, next: bench.NewFuncSink(p.processor.Add)}
+}
+
+func (s *losslessSink) Send(p pack.Pack) error {
+	s.sent++
+	if s.sent%losslessCheckEvery == 0 {
+		for s.pipeline.pending() > losslessMaxPending {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	return s.next.Send(p)
+}
+
+func (s *losslessSink) Close() error {
+	return s.next.Close()
+}
+
+func (p *benchPipeline) close() {
+	for _, c := range p.closers {
+		c()
+	}
+}
+
+func runBench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	tps := fs.Int("tps", 1000, "target XLogs per second")
+	objects := fs.Int("objects", 50, "number of synthetic agents")
+	services := fs.Int("services", 200, "number of distinct service names")
+	duration := fs.Duration("duration", time.Minute, "run length (e.g. 30s, 10m)")
+	profileRatio := fs.Float64("profile-ratio", 0.1, "fraction of XLogs sent with a profile")
+	errorRatio := fs.Float64("error-ratio", 0.01, "fraction of XLogs marked as errors")
+	target := fs.String("target", "", "collector address (udp://host:port); empty runs an in-process collector")
+	dataDirFlag := fs.String("data-dir", "", "directory to measure storage growth (in-process default: a temp dir)")
+	keep := fs.Bool("keep", false, "keep the in-process temp data directory after the run")
+	fs.Parse(args)
+
+	cfg, _ := loadToolConfig()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	var sink bench.Sink
+	var pipeline *benchPipeline
+	dataDir := *dataDirFlag
+
+	if *target == "" {
+		if dataDir == "" {
+			if err := os.MkdirAll(cfg.TempDir(), 0755); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to create temp dir: %v\n", err)
+				os.Exit(1)
+			}
+			tmp, err := os.MkdirTemp(cfg.TempDir(), "bench-")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to create bench data dir: %v\n", err)
+				os.Exit(1)
+			}
+			dataDir = tmp
+			if !*keep {
+				defer os.RemoveAll(tmp)
+			}
+		}
+		pipeline = newBenchPipeline(ctx, dataDir)
+		sink = bench.NewFuncSink(pipeline.processor.Add)
+		fmt.Printf("Bench: in-process collector, dataDir=%s\n", dataDir)
+	} else {
+		u, err := url.Parse(*target)
+		if err != nil || u.Scheme != "udp" || u.Host == "" {
+			fmt.Fprintf(os.Stderr, "Invalid --target %q (expected udp://host:port)\n", *target)
+			os.Exit(1)
+		}
+		s, err := bench.NewUDPSink(u.Host)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to %s: %v\n", u.Host, err)
+			os.Exit(1)
+		}
+		sink = s
+		fmt.Printf("Bench: target=%s\n", u.Host)
+	}
+	defer sink.Close()
+
+	var sizeBefore int64
+	if dataDir != "" {
+		sizeBefore = util.DirSize(dataDir)
+	}
+
+	fmt.Printf("Bench: tps=%d objects=%d services=%d duration=%s profileRatio=%.2f errorRatio=%.2f\n\n",
+		*tps, *objects, *services, *duration, *profileRatio, *errorRatio)
+
+	rep := bench.Run(ctx, bench.Config{
+		TPS:          *tps,
+		Objects:      *objects,
+		Services:     *services,
+		Duration:     *duration,
+		ProfileRatio: *profileRatio,
+		ErrorRatio:   *errorRatio,
+	}, sink, func(r bench.Report) {
+		fmt.Printf("  %6s  xlogs=%-10d tps=%-9.0f profiles=%-9d sendErrors=%d\n",
+			r.Elapsed.Round(time.Second), r.XLogs, r.AchievedTPS(), r.Profiles, r.SendErrors)
+	})
+
+	if pipeline != nil {
+		// Give the async writers a moment to drain before measuring storage.
+		time.Sleep(2 * time.Second)
+		pipeline.close()
+	}
+
+	fmt.Printf("\n=== Bench Complete ===\n")
+	fmt.Printf("  elapsed        %s\n", rep.Elapsed.Round(time.Millisecond))
+	fmt.Printf("  xlogs          %d (%.1f/s, target %d/s)\n", rep.XLogs, rep.AchievedTPS(), *tps)
+	fmt.Printf("  profiles       %d\n", rep.Profiles)
+	fmt.Printf("  counters       %d\n", rep.Counters)
+	fmt.Printf("  objects        %d\n", rep.Objects)
+	fmt.Printf("  texts          %d\n", rep.Texts)
+	fmt.Printf("  send errors    %d\n", rep.SendErrors)
+
+	if pipeline != nil {
+		fmt.Printf("\n  Drops:\n")
+		fmt.Printf("    udp queue      %d\n", pipeline.processor.Dropped())
+		fmt.Printf("    xlog core      %d\n", pipeline.xlogCore.Dropped())
+		fmt.Printf("    profile core   %d\n", pipeline.profileCore.Dropped())
+		fmt.Printf("    counter core   %d\n", pipeline.perfCountCore.Dropped())
+		fmt.Printf("    xlog writer    %d\n", pipeline.xlogWR.Dropped())
+		fmt.Printf("    profile writer %d\n", pipeline.profileWR.Dropped())
+		fmt.Printf("    counter writer %d\n", pipeline.counterWR.Dropped())
+	}
+
+	if dataDir != "" {
+		growth := util.DirSize(dataDir) - sizeBefore
+		fmt.Printf("\n  Storage growth %s", formatBytes(growth))
+		if secs := rep.Elapsed.Seconds(); secs > 0 {
+			fmt.Printf(" (%s/day projected)", formatBytes(int64(float64(growth)/secs*86400)))
+		}
+		fmt.Println()
+	}
+}
+
+// formatBytes renders a byte count with a binary unit suffix.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
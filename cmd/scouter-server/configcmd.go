@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+)
+
+const configUsage = `Usage: scouter-server config defaults [--format table|conf]
+       scouter-server config check [--file path] [--effective] [--show-secrets]
+
+  defaults   print every known configuration key with its type, default
+             and whether it is applied on hot reload
+  check      report unknown keys, type errors and conflicting settings of
+             the configuration file with the SCOUTER_* environment
+             overrides; exits 1 on errors
+`
+
+func runConfig(args []string) {
+	if len(args) > 0 && args[0] == "check" {
+		runConfigCheck(args[1:])
+		return
+	}
+	if len(args) == 0 || args[0] != "defaults" {
+		fmt.Fprint(os.Stderr, configUsage)
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("config defaults", flag.ExitOnError)
+	format := fs.String("format", "table", "output format: table or conf (commented scouter.conf template)")
+	fs.Parse(args[1:])
+
+	metas := config.ConfigMetaMap()
+	switch *format {
+	case "table":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEY\tTYPE\tDEFAULT\tHOT-RELOAD\tDESCRIPTION")
+		for _, key := range config.ConfigKeys() {
+			m := metas[key]
+			reload := "no"
+			if m.HotReload {
+				reload = "yes"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", key, config.ValueTypeName(m.ValueType), m.Default, reload, m.Desc)
+		}
+		tw.Flush()
+	case "conf":
+		for _, key := range config.ConfigKeys() {
+			m := metas[key]
+			note := "restart required"
+			if m.HotReload {
+				note = "hot-reloadable"
+			}
+			fmt.Printf("# %s (%s, %s)\n#%s=%s\n\n", m.Desc, config.ValueTypeName(m.ValueType), note, key, m.Default)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown --format %q (expected table or conf)\n", *format)
+		os.Exit(1)
+	}
+}
+
+func runConfigCheck(args []string) {
+	confFile := "./conf/scouter.conf"
+	if f := os.Getenv("SCOUTER_CONF"); f != "" {
+		confFile = f
+	}
+	fs := flag.NewFlagSet("config check", flag.ExitOnError)
+	file := fs.String("file", confFile, "configuration file (default $SCOUTER_CONF or ./conf/scouter.conf)")
+	effective := fs.Bool("effective", false, "also print the effective value and source of every key")
+	showSecrets := fs.Bool("show-secrets", false, "print passwords and tokens in --effective instead of masking them")
+	fs.Parse(args)
+
+	cfg, issues, err := config.CheckFile(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot read %s: %v\n", *file, err)
+		os.Exit(1)
+	}
+	if _, err := os.Stat(*file); err != nil {
+		fmt.Printf("%s not found, checking the defaults and environment overrides\n", *file)
+	}
+
+	if *effective {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+		for _, s := range cfg.Effective() {
+			value := s.Value
+			if value != "" && !*showSecrets && config.IsSecretKey(s.Key) {
+				value = "****"
+			}
+			source := s.Source
+			if source == "env" {
+				source = "env " + config.EnvName(s.Key)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Key, value, source)
+		}
+		tw.Flush()
+		fmt.Println()
+	}
+
+	errors := 0
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, issue := range issues {
+		if issue.Level == config.IssueError {
+			errors++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", issue.Level, issue.Key, issue.Message)
+	}
+	tw.Flush()
+	fmt.Printf("%s: %d errors, %d warnings\n", *file, errors, len(issues)-errors)
+	if errors > 0 {
+		os.Exit(1)
+	}
+}
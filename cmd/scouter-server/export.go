@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/admin"
+	"github.com/zbum/scouter-server-go/internal/db"
+)
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	date := fs.String("date", "", "day to export (YYYYMMDD)")
+	out := fs.String("out", "", "archive to write (.tar.zst), - for stdout")
+	typeSpec := fs.String("types", strings.Join(db.DefaultExportTypes, ","), "data types to export ("+strings.Join(db.ExportTypeNames(), ",")+",all)")
+	permText := fs.Bool("perm-text", true, "include the permanent text store the day's names are resolved from")
+	force := fs.Bool("force", false, "export today's data even if a running server is detected")
+	fs.Parse(args)
+
+	if *date == "" || *out == "" {
+		fmt.Fprintf(os.Stderr, "Usage: scouter-server export --date 20260207 --out 20260207.tar.zst [--types xlog,profile,...] [--perm-text=false]\n")
+		os.Exit(1)
+	}
+	types, err := db.ParseExportTypes(*typeSpec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, dataDir := loadToolConfig()
+
+	// Past days are no longer written; today's files change while exporting.
+	if *date == time.Now().Format("20060102") && admin.IsLocked(dataDir) && !*force {
+		fmt.Fprintf(os.Stderr, "A running server is writing %s/%s (pid %d).\n", dataDir, *date, admin.LockHolder(dataDir))
+		fmt.Fprintf(os.Stderr, "Export a past day, or pass --force to export a possibly inconsistent copy.\n")
+		os.Exit(1)
+	}
+
+	opts := db.ExportOptions{
+		Types:         types,
+		PermText:      *permText,
+		ServerID:      cfg.ServerID(),
+		ServerVersion: Version,
+	}
+	var m *db.ExportManifest
+	if *out == "-" {
+		m, err = db.ExportDay(dataDir, *date, opts, os.Stdout)
+	} else {
+		m, err = exportToFile(dataDir, *date, opts, *out)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %s: %d files, %d bytes, types=%s\n", *date, len(m.Files), m.Bytes(), strings.Join(m.Types, ","))
+	if *out != "-" {
+		if info, err := os.Stat(*out); err == nil {
+			fmt.Fprintf(os.Stderr, "Wrote %s (%d bytes)\n", *out, info.Size())
+		}
+	}
+}
+
+// exportToFile writes the archive next to path and renames it into place,
+// so an interrupted export never leaves a truncated archive behind.
+func exportToFile(dataDir, date string, opts db.ExportOptions, path string) (*db.ExportManifest, error) {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return nil, err
+	}
+	m, err := db.ExportDay(dataDir, date, opts, f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return m, nil
+}
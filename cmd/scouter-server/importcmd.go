@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/admin"
+	"github.com/zbum/scouter-server-go/internal/db"
+	"github.com/zbum/scouter-server-go/internal/db/format"
+)
+
+// runImport unpacks an archive written by the export command into the data
+// directory.
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	file := fs.String("file", "", "archive to import (.tar.zst), - for stdin")
+	overwrite := fs.Bool("overwrite", false, "replace files of the day that exist already")
+	fs.Parse(args)
+
+	if *file == "" {
+		fmt.Fprintf(os.Stderr, "Usage: scouter-server import --file 20260207.tar.zst [--overwrite]\n")
+		os.Exit(1)
+	}
+
+	_, dataDir := loadToolConfig()
+
+	// The permanent texts are merged into a store the server keeps open.
+	if admin.IsLocked(dataDir) {
+		fmt.Fprintf(os.Stderr, "A running server holds %s (pid %d).\n", dataDir, admin.LockHolder(dataDir))
+		fmt.Fprintf(os.Stderr, "Stop the server first.\n")
+		os.Exit(1)
+	}
+
+	var r io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		r = f
+	}
+	fmt.Printf("Import: dataDir=%s, file=%s\n\n", dataDir, *file)
+
+	start := time.Now()
+	res, err := db.ImportArchive(dataDir, r, db.ImportOptions{
+		Overwrite: *overwrite,
+		Validate:  checkImportFormat,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	m := res.Manifest
+	fmt.Printf("  archive   date=%s, types=%v, server=%s %s\n", m.Date, m.Types, m.ServerID, m.ServerVersion)
+	fmt.Printf("  files     %d (%d bytes)\n", res.Files, res.Bytes)
+	switch {
+	case res.PermTextCopied:
+		fmt.Printf("  texts     permanent texts copied\n")
+	case res.PermTextAdded > 0:
+		fmt.Printf("  texts     %d permanent texts added\n", res.PermTextAdded)
+	}
+	for _, index := range res.Rebuilt {
+		fmt.Printf("  rebuilt   %s\n", index)
+	}
+	fmt.Printf("\n=== Import Complete: %s in %s ===\n", m.Date, time.Since(start).Round(time.Millisecond))
+}
+
+// checkImportFormat refuses days written in a format newer than this
+// server reads. Older days are imported; scouter-server upgrade migrates them.
+func checkImportFormat(dayDir string) error {
+	for _, c := range format.Components {
+		v, _, err := c.Version(filepath.Join(dayDir, c.Dir))
+		if err != nil {
+			return err
+		}
+		if v > c.Current {
+			return fmt.Errorf("%s version %d, this server supports %d: %w", c.Name, v, c.Current, format.ErrUnsupported)
+		}
+	}
+	return nil
+}
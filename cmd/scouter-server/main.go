@@ -6,23 +6,33 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"syscall"
 	"time"
 
+	"github.com/zbum/scouter-server-go/internal/admin"
+	"github.com/zbum/scouter-server-go/internal/alertrule"
+	"github.com/zbum/scouter-server-go/internal/apitoken"
+	"github.com/zbum/scouter-server-go/internal/backup"
 	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/core"
 	"github.com/zbum/scouter-server-go/internal/core/cache"
 	scoutercounter "github.com/zbum/scouter-server-go/internal/counter"
 	"github.com/zbum/scouter-server-go/internal/db"
+	"github.com/zbum/scouter-server-go/internal/db/agentinv"
 	"github.com/zbum/scouter-server-go/internal/db/alert"
 	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/db/heatmap"
+	dbio "github.com/zbum/scouter-server-go/internal/db/io"
 	"github.com/zbum/scouter-server-go/internal/db/kv"
+	"github.com/zbum/scouter-server-go/internal/db/objevent"
 	"github.com/zbum/scouter-server-go/internal/db/profile"
 	"github.com/zbum/scouter-server-go/internal/db/summary"
 	dbtext "github.com/zbum/scouter-server-go/internal/db/text"
 	"github.com/zbum/scouter-server-go/internal/db/visitor"
 	"github.com/zbum/scouter-server-go/internal/db/xlog"
+	"github.com/zbum/scouter-server-go/internal/deploywin"
 	"github.com/zbum/scouter-server-go/internal/geoip"
 	scouterhttp "github.com/zbum/scouter-server-go/internal/http"
 	"github.com/zbum/scouter-server-go/internal/logging"
@@ -30,7 +40,17 @@ import (
 	"github.com/zbum/scouter-server-go/internal/netio/service"
 	"github.com/zbum/scouter-server-go/internal/netio/tcp"
 	"github.com/zbum/scouter-server-go/internal/netio/udp"
+	"github.com/zbum/scouter-server-go/internal/notify"
+	"github.com/zbum/scouter-server-go/internal/objalias"
+	"github.com/zbum/scouter-server-go/internal/objgroup"
+	"github.com/zbum/scouter-server-go/internal/otlp"
+	"github.com/zbum/scouter-server-go/internal/probe"
+	"github.com/zbum/scouter-server-go/internal/profstat"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/report"
+	"github.com/zbum/scouter-server-go/internal/slo"
+	"github.com/zbum/scouter-server-go/internal/sqltop"
+	"github.com/zbum/scouter-server-go/internal/standby"
 	"github.com/zbum/scouter-server-go/internal/tagcnt"
 )
 
@@ -50,6 +70,84 @@ func main() {
 		return
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		runAdmin(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		runConfig(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "purge" {
+		runPurge(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		runExport(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		runImport(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "backup" {
+		runBackup(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "tail" {
+		runTail(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		runBench(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		runReplay(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "retention" {
+		runRetention(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "anonymize" {
+		runAnonymize(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "upgrade" {
+		runUpgrade(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "import-accounts" {
+		runImportAccounts(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "service" {
+		runService(os.Args[2:])
+		return
+	}
+
+	if err := runServer(context.Background()); err != nil {
+		os.Exit(1)
+	}
+}
+
+// runServer starts all server components and blocks until parent is cancelled,
+// a shutdown signal is received, or an admin shutdown command arrives.
+func runServer(parent context.Context) error {
 	// --- Startup banner ---
 	printBanner()
 
@@ -92,8 +190,16 @@ func main() {
 	}
 	slog.Info("Data directory", "path", dataDir)
 
+	// --- Single-instance lock (held until exit; released by the OS on crash) ---
+	lock, err := admin.AcquireLock(dataDir)
+	if err != nil {
+		slog.Error("Cannot lock data directory; is another server running?", "path", dataDir, "error", err)
+		return err
+	}
+	defer lock.Release()
+
 	// --- Graceful shutdown context ---
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(parent)
 	defer cancel()
 
 	// --- Start log rotation & cleanup goroutines ---
@@ -155,6 +261,20 @@ func main() {
 	customKV.Start(ctx)
 	defer customKV.Close()
 
+	kvNamespaces := kv.NewNamespaces(dataDir, map[string]*kv.KVStore{"global": globalKV, "custom": customKV})
+	kvNamespaces.Start(ctx)
+	defer kvNamespaces.Close()
+
+	accountKV := kv.NewAccountStores(dataDir)
+	accountKV.Start(ctx)
+	defer accountKV.Close()
+
+	// API tokens get a store of their own, out of reach of the KV commands.
+	tokenKV := kv.NewKVStore(dataDir, "api_tokens.json")
+	tokenKV.Start(ctx)
+	defer tokenKV.Close()
+	apiTokens := apitoken.NewStore(tokenKV)
+
 	// --- Alert cache ---
 	alertCache := cache.NewAlertCache(1024)
 
@@ -170,9 +290,30 @@ func main() {
 	// GeoIP
 	var geoIPUtil *geoip.GeoIPUtil
 	if cfg.GeoIPEnabled() {
-		geoIPUtil = geoip.New(cfg.GeoIPDataCityFile())
+		geoIPUtil = geoip.New(geoip.Files{
+			City:       cfg.GeoIPDataCityMMDBFile(),
+			ASN:        cfg.GeoIPDataASNMMDBFile(),
+			LegacyCity: cfg.GeoIPDataCityFile(),
+		})
 		xlogOpts = append(xlogOpts, core.WithGeoIP(geoIPUtil))
-		slog.Info("GeoIP lookup enabled", "db", cfg.GeoIPDataCityFile())
+		slog.Info("GeoIP lookup enabled", "db", cfg.GeoIPDataCityMMDBFile())
+
+		if cfg.GeoIPUpdateEnabled() {
+			if cfg.GeoIPLicenseKey() == "" {
+				slog.Warn("GeoIP update enabled but geoip_license_key is not set")
+			} else {
+				geoip.NewUpdater(geoIPUtil, geoip.UpdaterConfig{
+					URL:         cfg.GeoIPUpdateURL(),
+					AccountID:   cfg.GeoIPAccountID(),
+					LicenseKey:  cfg.GeoIPLicenseKey(),
+					Interval:    time.Duration(cfg.GeoIPUpdateIntervalHours()) * time.Hour,
+					CityEdition: cfg.GeoIPUpdateCityEdition(),
+					CityFile:    cfg.GeoIPDataCityMMDBFile(),
+					ASNEdition:  cfg.GeoIPUpdateASNEdition(),
+					ASNFile:     cfg.GeoIPDataASNMMDBFile(),
+				}).Start(ctx)
+			}
+		}
 	}
 
 	// SQL table parser
@@ -199,13 +340,100 @@ func main() {
 		slog.Info("Tag counting enabled")
 	}
 
+	// Elapsed-time heatmap index
+	var heatmapDB *heatmap.DB
+	if cfg.XLogHeatmapEnabled() {
+		heatmapDB = heatmap.NewDB(dataDir)
+		heatmapDB.StartFlusher(ctx.Done())
+		xlogOpts = append(xlogOpts, core.WithHeatmap(heatmapDB))
+	}
+
+	// Service-level objectives (alerts go through the dispatcher, created below)
+	var dispatcher *core.Dispatcher
+	var sloTracker *slo.Tracker
+	if cfg.SLOEnabled() {
+		sloTracker = slo.NewTracker(slo.NewStore(globalKV), dataDir, func(hash int32) string {
+			s, _ := textCache.Get("service", hash)
+			return s
+		}, func(p pack.Pack) { dispatcher.Dispatch(p, nil) })
+		sloTracker.Start(ctx)
+		xlogOpts = append(xlogOpts, core.WithSLO(sloTracker))
+	}
+
+	// Sampling of the stored XLogs (idle until xlog_sampling_rate is lowered)
+	xlogSampler := core.NewXLogSampler(func(hash int32) string {
+		s, _ := textCache.Get("service", hash)
+		return s
+	})
+	xlogOpts = append(xlogOpts, core.WithSampler(xlogSampler))
+
 	xlogCore := core.NewXLogCore(xlogCache, xlogWR, profileWR, xlogGroupPerf, xlogOpts...)
 	perfCountCore := core.NewPerfCountCore(counterCache, counterWR)
+	// Idle until counter_check_enabled is set (hot reload).
+	counterCheck := core.NewCounterCheck(counterRD, objectCache)
+	counterCheck.Start(ctx)
+	perfCountCore.SetCheck(counterCheck)
 	profileCore := core.NewProfileCore(profileWR)
 	typeManager := scoutercounter.NewObjectTypeManager()
 	alertCore := core.NewAlertCore(alertWR, alertCache)
+	deployWindows := deploywin.NewManager(objectCache)
+	alertCore.SetDeployWindows(deployWindows)
+	// Counter alert rules, evaluated as realtime counters are processed.
+	var alertRules *alertrule.Engine
+	if cfg.AlertRulesEnabled() {
+		alertRules = alertrule.NewEngine(alertrule.NewStore(globalKV), func(objHash int32) (string, string) {
+			if info, ok := objectCache.Get(objHash); ok {
+				return info.Pack.ObjName, info.Pack.ObjType
+			}
+			return "", ""
+		}, alertCore.Add)
+		perfCountCore.SetAlertRules(alertRules)
+	}
 	agentManager := core.NewAgentManager(objectCache, deadTimeout, typeManager, textCache, textCore, alertCore)
+	objEvents := objevent.NewStore(dataDir)
+	defer objEvents.Close()
+	agentManager.SetEvents(objEvents)
+	agentInventory, err := agentinv.Open(dataDir)
+	if err != nil {
+		slog.Error("Failed to load agent inventory", "error", err)
+		return err
+	}
+	defer agentInventory.Close()
+	agentManager.SetInventory(agentInventory)
+	notifyDeliveries, err := notify.OpenDeliveries(dataDir)
+	if err != nil {
+		slog.Error("Failed to load notification deliveries", "error", err)
+		return err
+	}
+	defer notifyDeliveries.Close()
+	alertObjName := func(objHash int32) string {
+		if info, ok := objectCache.Get(objHash); ok {
+			return info.Pack.ObjName
+		}
+		return ""
+	}
+	// Idle until alert_webhook_urls is set (hot reload).
+	alertWebhooks := notify.NewAlertWebhooks(dataDir, notifyDeliveries, alertObjName)
+	defer alertWebhooks.Close()
+	alertWebhooks.Start(ctx)
+	alertCore.SetWebhooks(alertWebhooks)
+	// Idle until alert_mail_to and notify_smtp_addr are set (hot reload).
+	alertMails := notify.NewAlertMails(notifyDeliveries, alertObjName)
+	alertMails.Start(ctx)
+	alertCore.SetMails(alertMails)
 	summaryCore := core.NewSummaryCore(summaryWR)
+	var sqlTop *sqltop.Core
+	if cfg.SQLTopEnabled() {
+		sqlTop = sqltop.NewCore(dataDir)
+		defer sqlTop.Flush()
+		summaryCore.SetSQLTop(sqlTop)
+	}
+	var profStat *profstat.Core
+	if cfg.ProfileStatEnabled() {
+		profStat = profstat.NewCore(dataDir)
+		defer profStat.Flush()
+		profileCore.SetProfileStat(profStat)
+	}
 
 	// --- Cleanup for optional subsystems ---
 	if geoIPUtil != nil {
@@ -213,29 +441,95 @@ func main() {
 	}
 
 	// --- Dispatcher ---
-	dispatcher := core.NewDispatcher()
+	dispatcher = core.NewDispatcher()
 	dispatcher.Register(pack.PackTypeText, textCore.Handler())
-	dispatcher.Register(pack.PackTypeXLog, xlogCore.Handler())
-	dispatcher.Register(pack.PackTypePerfCounter, perfCountCore.Handler())
-	dispatcher.Register(pack.PackTypeXLogProfile, profileCore.Handler())
-	dispatcher.Register(pack.PackTypeXLogProfile2, profileCore.Handler())
+	dispatcher.RegisterStamped(pack.PackTypeXLog, xlogCore.StampedHandler())
+	dispatcher.RegisterStamped(pack.PackTypePerfCounter, perfCountCore.StampedHandler())
+	dispatcher.RegisterStamped(pack.PackTypeXLogProfile, profileCore.StampedHandler())
+	dispatcher.RegisterStamped(pack.PackTypeXLogProfile2, profileCore.StampedHandler())
 	dispatcher.Register(pack.PackTypeObject, agentManager.Handler())
 	dispatcher.Register(pack.PackTypeAlert, alertCore.Handler())
 	dispatcher.Register(pack.PackTypeSummary, summaryCore.Handler())
 
-	// --- Zipkin span ingestion (optional) ---
-	if cfg.ZipkinEnabled() {
+	// Pack mirroring is idle until mirror_pack_enabled is set (hot reload).
+	packMirror := core.NewPackMirror()
+	defer packMirror.Close()
+	dispatcher.SetMirror(packMirror)
+	// The last pack_inspect_count packs per object and type, for PACK_INSPECT.
+	packInspector := core.NewPackInspector()
+	dispatcher.SetInspector(packInspector)
+
+	// Agent clock skew detection; correction is off until clock_skew_correct_enabled.
+	clockSkew := core.NewClockSkew(alertCore, objectCache)
+	dispatcher.SetClockSkew(clockSkew)
+
+	// Receive-to-indexed latency of xlog, profile and counter packs.
+	ingestLatency := core.NewIngestLatency(alertCore, func(p pack.Pack) { dispatcher.Dispatch(p, nil) })
+	ingestLatency.Start(ctx)
+
+	// Reads, writes and index chain depth of the storage files.
+	ioStats := core.NewIOStats(func(p pack.Pack) { dispatcher.Dispatch(p, nil) })
+	ioStats.Start(ctx)
+
+	// Per-objType XLog/profile limits; unlimited until ingest_quota_* is set.
+	ingestQuota := core.NewIngestQuota(objectCache)
+	dispatcher.SetQuota(ingestQuota)
+
+	// Read-only maintenance mode, switched by the admin read-only command.
+	readOnly := core.NewReadOnly()
+	dispatcher.SetReadOnly(readOnly)
+
+	// Object aliases; packs keep their agent identity until object_alias is set.
+	objAlias := objalias.NewManager(globalKV)
+	dispatcher.SetAlias(objAlias)
+	counterRD.SetSources(objAlias.Sources)
+
+	// --- Zipkin and OTLP span ingestion (optional) ---
+	if cfg.ZipkinEnabled() || cfg.OtlpEnabled() {
 		spanCore := core.NewSpanCore(xlogCache, xlogWR, objectCache, profileWR, textCache)
+		spanCore.SetSummary(summaryCore, textCore)
+		spanCore.SetObjectIngest(func(p pack.Pack) { dispatcher.Dispatch(p, nil) })
 		dispatcher.Register(pack.PackTypeSpan, spanCore.Handler())
 		dispatcher.Register(pack.PackTypeSpanContainer, spanCore.ContainerHandler())
 		slog.Info("Zipkin span ingestion enabled")
 	}
+	if cfg.OtlpEnabled() {
+		// The receiver's SpanPacks pass the dispatcher like UDP ones, so
+		// read-only mode, quotas and aliases apply to them too.
+		otlpReceiver := otlp.NewReceiver(func(p pack.Pack) { dispatcher.Dispatch(p, nil) })
+		var addrs []string
+		for _, port := range []int{cfg.OtlpGrpcPort(), cfg.OtlpHTTPPort()} {
+			if port > 0 {
+				addrs = append(addrs, fmt.Sprintf(":%d", port))
+			}
+		}
+		if err := otlpReceiver.Start(ctx, addrs...); err != nil {
+			slog.Error("OTLP receiver failed to start", "error", err)
+		}
+	}
+	if cfg.ProbeEnabled() {
+		// Probe results pass the dispatcher like agent packs.
+		probe.NewProber(func(p pack.Pack) { dispatcher.Dispatch(p, nil) }).Start(ctx)
+		slog.Info("Synthetic probes enabled", "targets", cfg.ProbeTargets(), "intervalSec", cfg.ProbeIntervalSec())
+	}
 
 	// --- Account Manager ---
 	confDir := cfg.ConfDir()
 	if confDir == "" {
 		confDir = "./conf"
 	}
+	if dir := cfg.AccountImportDir(); dir != "" {
+		if _, err := os.Stat(filepath.Join(confDir, "account.xml")); os.IsNotExist(err) {
+			if result, err := login.ImportJavaAccounts(dir, confDir, false, false); err != nil {
+				slog.Error("Account import failed", "dir", dir, "error", err)
+			} else {
+				slog.Info("Accounts imported", "dir", dir, "accounts", len(result.AccountsAdded), "groups", len(result.GroupsAdded))
+				for _, w := range result.Warnings {
+					slog.Warn("Account import", "warning", w)
+				}
+			}
+		}
+	}
 	accountManager := login.NewAccountManager(confDir)
 	accountManager.StartWatcher(ctx)
 
@@ -249,22 +543,55 @@ func main() {
 	service.RegisterObjectHandlers(registry, objectCache, deadTimeout, counterCache, typeManager)
 	service.RegisterCounterHandlers(registry, counterCache, objectCache, deadTimeout, counterRD)
 	service.RegisterXLogHandlers(registry, xlogCache, xlogRD)
+	service.RegisterXLogFilterHandlers(registry, xlogCache, objectCache, sessions)
 	service.RegisterTextHandlers(registry, textCache, textRD, textWR)
 	service.RegisterXLogReadHandlers(registry, xlogRD, profileRD, profileWR, xlogWR)
+	service.RegisterXLogSearchHandlers(registry, xlogRD, xlogWR, textCache, textRD, textWR)
+	service.RegisterXLogCallTreeHandlers(registry, xlogRD, xlogWR)
 	service.RegisterCounterReadHandlers(registry, counterRD, objectCache, deadTimeout)
 	service.RegisterAlertHandlers(registry, alertRD, alertCache)
+	service.RegisterAlertXLogHandlers(registry, alertRD, xlogRD, xlogWR)
+	service.RegisterObjectEventHandlers(registry, objEvents)
+	service.RegisterAgentInventoryHandlers(registry, agentInventory)
+	service.RegisterNotifyHandlers(registry, notifyDeliveries)
 	service.RegisterSummaryHandlers(registry, summaryRD)
+	if sqlTop != nil {
+		service.RegisterSQLTopHandlers(registry, sqlTop)
+	}
+	if profStat != nil {
+		service.RegisterProfileStatHandlers(registry, profStat)
+	}
 	service.RegisterCounterExtHandlers(registry, counterCache, objectCache, deadTimeout, counterRD)
 	service.RegisterObjectExtHandlers(registry, objectCache, deadTimeout)
-	service.RegisterConfigureHandlers(registry, Version, typeManager)
-	service.RegisterServerMgmtHandlers(registry, Version, dataDir)
+	service.RegisterObjectCleanupHandlers(registry, objectCache, agentInventory)
+	service.RegisterObjectDashboardHandlers(registry, objectCache, counterCache, counterRD, alertRD)
+	service.RegisterConfigureHandlers(registry, Version, typeManager, sessions)
+	trash := db.NewTrash(dataDir)
+	trash.Start(ctx)
+	service.RegisterServerMgmtHandlers(registry, Version, dataDir, trash)
+	service.RegisterWriteStatsHandlers(registry, dataDir)
 	service.RegisterKVHandlers(registry, globalKV, customKV)
+	service.RegisterKVNamespaceHandlers(registry, kvNamespaces)
+	service.RegisterAccountKVHandlers(registry, accountKV, sessions)
+	service.RegisterTokenHandlers(registry, apiTokens, sessions, accountManager)
+	service.RegisterPackInspectHandlers(registry, packInspector, sessions)
 	service.RegisterActiveSpeedHandlers(registry, counterCache, objectCache, deadTimeout)
 	service.RegisterLoginExtHandlers(registry, sessions, accountManager)
 	service.RegisterAccountHandlers(registry, accountManager)
 	service.RegisterVisitorHandlers(registry, visitorDB, hourlyDB, objectCache, deadTimeout)
 	service.RegisterAlertExtHandlers(registry, summaryRD)
 	service.RegisterGroupHandlers(registry, xlogGroupPerf, textCache)
+	if heatmapDB != nil {
+		service.RegisterHeatmapHandlers(registry, heatmapDB)
+	}
+	service.RegisterObjectAliasHandlers(registry, objAlias)
+	service.RegisterObjectGroupHandlers(registry, objgroup.NewManager(globalKV), objectCache, counterCache, xlogCache)
+	if sloTracker != nil {
+		service.RegisterSLOHandlers(registry, sloTracker)
+	}
+	if alertRules != nil {
+		service.RegisterAlertRuleHandlers(registry, alertRules)
+	}
 
 	// --- UDP pipeline ---
 	processor := udp.NewNetDataProcessor(dispatcher, 4)
@@ -276,6 +603,17 @@ func main() {
 	}
 	udpServer := udp.NewServer(udpConfig, processor)
 
+	// The server's own counters, as a scouter-server object.
+	selfMonitor := core.NewSelfMonitor(Version, func(p pack.Pack) { dispatcher.Dispatch(p, nil) })
+	selfMonitor.SetUDP(processor)
+	selfMonitor.AddDispatchQueue(xlogCore)
+	selfMonitor.AddDispatchQueue(profileCore)
+	selfMonitor.AddDispatchQueue(perfCountCore)
+	selfMonitor.AddWriteQueue(xlogWR)
+	selfMonitor.AddWriteQueue(profileWR)
+	selfMonitor.AddWriteQueue(counterWR)
+	selfMonitor.Start(ctx)
+
 	// --- TCP server ---
 	tcpConfig := tcp.ServerConfig{
 		ListenIP:        cfg.NetTCPListenIP(),
@@ -288,7 +626,19 @@ func main() {
 			GetConnWait:       time.Duration(cfg.NetTcpGetAgentConnectionWaitMs()) * time.Millisecond,
 		},
 	}
+	if cfg.NetTcpTLSEnabled() {
+		tlsConfig, err := tcp.NewTLSConfig(cfg.NetTcpTLSCertFile(), cfg.NetTcpTLSKeyFile(), cfg.NetTcpTLSClientCAFile())
+		if err != nil {
+			return fmt.Errorf("TCP TLS: %w", err)
+		}
+		if cfg.NetTcpTLSAgentCertRequired() && tlsConfig.ClientCAs == nil {
+			return fmt.Errorf("TCP TLS: net_tcp_tls_agent_cert_required needs net_tcp_tls_client_ca_file")
+		}
+		tcpConfig.TLSConfig = tlsConfig
+		tcpConfig.AgentCertRequired = cfg.NetTcpTLSAgentCertRequired()
+	}
 	tcpServer := tcp.NewServer(tcpConfig, registry, sessions)
+	tcpServer.AgentMgr().SetAlias(objAlias)
 
 	// --- Agent proxy handlers (requires tcpServer for agent RPC) ---
 	service.RegisterAgentProxyHandlers(registry, tcpServer, objectCache, deadTimeout)
@@ -309,6 +659,52 @@ func main() {
 	purger.Start(ctx)
 	slog.Info("Day container purger started", "keepHours", cfg.DayContainerKeepHours())
 
+	// --- Manual purge (SERVER_DB_PURGE / REST) ---
+	manualPurger := db.NewManualPurger(dataDir,
+		xlogWR, xlogRD,
+		counterWR, counterRD,
+		profileWR, profileRD,
+		alertWR, alertRD,
+		summaryWR, summaryRD,
+		textWR, textRD,
+	)
+	manualPurger.SetTrash(trash)
+	service.RegisterPurgeHandlers(registry, manualPurger)
+
+	// --- Day container admin (admin socket days/close-day/open-day) ---
+	dayAdmin := db.NewDayContainerAdmin(dataDir)
+	dayAdmin.Add("xlog-wr", xlogWR)
+	dayAdmin.Add("xlog-rd", xlogRD)
+	dayAdmin.Add("counter-wr", counterWR)
+	dayAdmin.Add("counter-rd", counterRD)
+	dayAdmin.Add("profile-wr", profileWR)
+	dayAdmin.Add("profile-rd", profileRD)
+	dayAdmin.Add("alert-wr", alertWR)
+	dayAdmin.Add("alert-rd", alertRD)
+	dayAdmin.Add("summary-wr", summaryWR)
+	dayAdmin.Add("summary-rd", summaryRD)
+	dayAdmin.Add("text-wr", textWR)
+	dayAdmin.Add("text-rd", textRD)
+
+	// --- Backup of previous days to S3-compatible storage ---
+	backups := backup.NewScheduler(dataDir, cfg.ServerID(), Version)
+	backups.SetDayCloser(func(date string) error {
+		_, err := dayAdmin.Close(date)
+		return err
+	})
+	if cfg.BackupEnabled() {
+		backups.Start(ctx)
+		slog.Info("Backup scheduler started", "bucket", cfg.BackupS3Bucket(), "prefix", cfg.BackupS3Prefix())
+	}
+	if cfg.BackupS3Endpoint() != "" && cfg.BackupS3Bucket() != "" {
+		dbio.SetDayRestorer(backups.FetchMissingDay)
+	}
+
+	// --- Compaction of long-lived hash indexes ---
+	indexCompactor := db.NewIndexCompactor()
+	indexCompactor.Add("text", textWR.CompactPermanent)
+	indexCompactor.Start(ctx)
+
 	// --- Auto-delete scheduler ---
 	if keepDays := cfg.DBKeepDays(); keepDays > 0 {
 		cleaner := db.NewAutoDeleteScheduler(dataDir, keepDays)
@@ -327,24 +723,65 @@ func main() {
 			cfg.MgrPurgeDailyTextDays(),
 			cfg.MgrPurgeDiskUsagePct(),
 		)
+		dataPurger.SetRealtimeDownsample(cfg.MgrPurgeRealtimeCounterDownsampleDays(), counter.DownsampleRealtime)
+		if divDays, err := db.ParseDivKeepDays(cfg.MgrPurgeDailyTextDaysByDiv()); err != nil {
+			slog.Warn("Ignoring mgr_purge_daily_text_days_by_div", "error", err)
+		} else if len(divDays) > 0 {
+			dataPurger.SetDailyTextCompact(divDays, func(dir string, drop []string) (int64, bool, error) {
+				r, err := dbtext.CompactDaily(dir, drop)
+				return r.Reclaimed(), r.Compacted, err
+			}, textWR, textRD)
+		}
+		if errorDays, slowDays := cfg.MgrPurgeProfileErrorKeepDays(), cfg.MgrPurgeProfileSlowKeepDays(); errorDays > 0 || slowDays > 0 {
+			slowMs := int32(cfg.MgrPurgeProfileSlowMs())
+			dataPurger.SetProfileOutcomeRetention(errorDays, slowDays, func(date string, errors, slow bool) (int64, bool, error) {
+				o := profile.Outcomes{Errors: errors}
+				if slow {
+					o.SlowMs = slowMs
+				}
+				r, err := profile.CompactOutcomes(dataDir, xlogRD, date, o)
+				return r.Reclaimed(), r.Compacted, err
+			}, profileWR, profileRD)
+		}
 		dataPurger.Start(ctx)
 		slog.Info("Data purge scheduler started",
 			"profileKeepDays", cfg.MgrPurgeProfileKeepDays(),
+			"profileErrorKeepDays", cfg.MgrPurgeProfileErrorKeepDays(),
+			"profileSlowKeepDays", cfg.MgrPurgeProfileSlowKeepDays(),
 			"xlogKeepDays", cfg.MgrPurgeXLogKeepDays(),
 			"sumKeepDays", cfg.MgrPurgeSumDataDays(),
 			"counterKeepDays", cfg.MgrPurgeCounterKeepDays(),
 			"realtimeCounterKeepDays", cfg.MgrPurgeRealtimeCounterKeepDays(),
+			"realtimeCounterDownsampleDays", cfg.MgrPurgeRealtimeCounterDownsampleDays(),
 			"dailyTextKeepDays", cfg.MgrPurgeDailyTextDays(),
+			"dailyTextKeepDaysByDiv", cfg.MgrPurgeDailyTextDaysByDiv(),
 			"diskUsagePct", cfg.MgrPurgeDiskUsagePct(),
 		)
 	}
 
+	// --- Scheduled reports (report_enabled is checked on every run) ---
+	reportText := func(date, div string, hash int32) string {
+		if s, ok := textCache.Get(div, hash); ok {
+			return s
+		}
+		if s, err := textRD.GetString(div, hash); err == nil && s != "" {
+			return s
+		}
+		s, _ := textRD.GetDailyString(date, div, hash)
+		return s
+	}
+	reportScheduler := report.NewScheduler(summaryRD, alertRD, reportText)
+	reportScheduler.SetDeliveries(notifyDeliveries)
+	reportScheduler.Start(ctx)
+
 	// --- HTTP API server (optional) ---
 	if cfg.HTTPEnabled() {
 		httpSrv := scouterhttp.NewServer(scouterhttp.ServerConfig{
 			Port:                 cfg.HTTPPort(),
 			CorsAllowOrigin:      cfg.NetHTTPApiCorsAllowOrigin(),
 			CorsAllowCredentials: cfg.NetHTTPApiCorsAllowCredentials(),
+			CorsRouteMethods:     cfg.NetHTTPApiCorsRouteMethods(),
+			CorsMaxAge:           time.Duration(cfg.NetHTTPApiCorsMaxAgeSec()) * time.Second,
 			GzipEnabled:          cfg.NetHTTPApiGzipEnabled(),
 			ClientDir:            cfg.ClientDir(),
 			AccountManager:       accountManager,
@@ -353,9 +790,22 @@ func main() {
 			CounterCache:         counterCache,
 			XLogCache:            xlogCache,
 			TextCache:            textCache,
+			AlertCache:           alertCache,
 			XLogRD:               xlogRD,
+			XLogWR:               xlogWR,
+			TextRD:               textRD,
 			CounterRD:            counterRD,
 			AlertRD:              alertRD,
+			Purger:               manualPurger,
+			Ingest:               func(p pack.Pack) { dispatcher.Dispatch(p, nil) },
+			ReadOnly:             readOnly.Active,
+			SLO:                  sloTracker,
+			AlertRules:           alertRules,
+			KVNamespaces:         kvNamespaces,
+			AgentInventory:       agentInventory,
+			Reports:              report.NewBuilder(summaryRD, alertRD, reportText, cfg.ReportTopN()),
+			DeployWindows:        deployWindows,
+			APITokens:            apiTokens,
 		})
 		go func() {
 			if err := httpSrv.Start(ctx); err != nil {
@@ -364,32 +814,34 @@ func main() {
 		}()
 	}
 
-	// --- PID file ---
-	pidFile := fmt.Sprintf("%d.scouter", os.Getpid())
-	if err := os.WriteFile(pidFile, []byte(fmt.Sprintf("%d", os.Getpid())), 0644); err != nil {
-		slog.Warn("Failed to create PID file", "file", pidFile, "error", err)
+	// --- Standby cache replication ---
+	standbyCaches := standby.Caches{Objects: objectCache, Counters: counterCache, XLogs: xlogCache}
+	var standbyPub *standby.Publisher
+	if addr := cfg.StandbyReplListen(); addr != "" {
+		standbyPub = standby.NewPublisher(standbyCaches, cfg.StandbyReplToken())
+		if err := standbyPub.Start(ctx, addr); err != nil {
+			slog.Error("Standby publisher failed to start", "addr", addr, "error", err)
+			standbyPub = nil
+		} else {
+			slog.Info("Standby publisher listening", "addr", addr)
+		}
+	}
+	var standbyReplica *standby.Replica
+	if primary := cfg.StandbyReplPrimary(); primary != "" {
+		standbyReplica = standby.NewReplica(standbyCaches, primary, cfg.StandbyReplToken())
+		standbyReplica.Start(ctx)
+		slog.Info("Standby replica started", "primary", primary)
+	}
+
+	// --- Admin socket (status / reload / shutdown) ---
+	if err := startAdminSocket(ctx, cancel, dataDir, confFile, objectCache, deadTimeout, counterCheck, clockSkew, ingestQuota, xlogSampler, ingestLatency, ioStats, readOnly, dayAdmin, standbyPub, standbyReplica); err != nil {
+		slog.Warn("Admin socket disabled", "path", admin.SocketPath(dataDir), "error", err)
 	} else {
-		slog.Info("PID file created", "file", pidFile)
-		defer os.Remove(pidFile)
-		go func() {
-			ticker := time.NewTicker(1 * time.Second)
-			defer ticker.Stop()
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case <-ticker.C:
-					if _, err := os.Stat(pidFile); os.IsNotExist(err) {
-						slog.Info("PID file deleted, shutting down", "file", pidFile)
-						cancel()
-						return
-					}
-				}
-			}
-		}()
+		slog.Info("Admin socket listening", "path", admin.SocketPath(dataDir))
 	}
 
 	// --- Graceful shutdown ---
+	// On Windows, CTRL_C/CTRL_BREAK arrive as SIGINT and CTRL_CLOSE/LOGOFF/SHUTDOWN as SIGTERM.
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
@@ -411,14 +863,16 @@ func main() {
 	slog.Info("TCP server starting", "port", tcpConfig.ListenPort)
 	if err := tcpServer.Start(ctx); err != nil {
 		slog.Error("TCP server error", "error", err)
-		os.Exit(1)
+		return err
 	}
 
 	slog.Info("Scouter Server stopped")
+	return nil
 }
 
-func runRehash() {
-	// --- Configuration ---
+// loadToolConfig loads the server configuration for offline subcommands
+// and resolves the data directory, honoring SCOUTER_CONF and SCOUTER_DATA_DIR.
+func loadToolConfig() (*config.Config, string) {
 	confFile := "./conf/scouter.conf"
 	if f := os.Getenv("SCOUTER_CONF"); f != "" {
 		confFile = f
@@ -433,6 +887,11 @@ func runRehash() {
 	if d := os.Getenv("SCOUTER_DATA_DIR"); d != "" {
 		dataDir = d
 	}
+	return cfg, dataDir
+}
+
+func runRehash() {
+	_, dataDir := loadToolConfig()
 
 	// Default: 128MB, override with --size flag
 	hashSizeMB := 128
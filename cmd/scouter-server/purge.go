@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/admin"
+	"github.com/zbum/scouter-server-go/internal/db"
+)
+
+func runPurge(args []string) {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	dateSpec := fs.String("date", "", "dates to purge: YYYYMMDD, YYYYMMDD..YYYYMMDD or a comma-separated list")
+	typeSpec := fs.String("types", "", "data types to purge ("+strings.Join(db.PurgeTypeNames(), ",")+")")
+	force := fs.Bool("force", false, "purge even if a running server is detected")
+	restore := fs.String("restore", "", "undo an earlier purge by moving its trash entry back")
+	fs.Parse(args)
+
+	if (*dateSpec == "" || *typeSpec == "") && *restore == "" {
+		fmt.Fprintf(os.Stderr, "Usage: scouter-server purge --date 20260101..20260131 --types xlog,profile [--force]\n")
+		fmt.Fprintf(os.Stderr, "       scouter-server purge --restore <trash id> [--force]\n")
+		os.Exit(1)
+	}
+
+	_, dataDir := loadToolConfig()
+
+	// The offline purger cannot close a running server's open containers.
+	// A live server should be purged through SERVER_DB_PURGE or /api/v1/admin/purge.
+	if admin.IsLocked(dataDir) && !*force {
+		fmt.Fprintf(os.Stderr, "A running server holds %s (pid %d).\n", dataDir, admin.LockHolder(dataDir))
+		fmt.Fprintf(os.Stderr, "Use the SERVER_DB_PURGE command or POST /api/v1/admin/purge instead, or pass --force.\n")
+		os.Exit(1)
+	}
+	purger := db.NewManualPurger(dataDir)
+	trash := db.NewTrash(dataDir)
+	trash.Expire()
+	purger.SetTrash(trash)
+
+	if *restore != "" {
+		e, err := purger.Restore(*restore)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Restore failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Restored %s: dates=%s, types=%s\n", e.ID, strings.Join(e.Dates, ","), strings.Join(e.Types, ","))
+		return
+	}
+	fmt.Printf("Purge: dataDir=%s, date=%s, types=%s\n\n", dataDir, *dateSpec, *typeSpec)
+
+	results, err := purger.PurgeSpec(*dateSpec, *typeSpec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Purge failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	deleted := 0
+	for _, r := range results {
+		switch {
+		case r.Skipped != "":
+			fmt.Printf("  %s  %-16s  (skipped - %s)\n", r.Date, "-", r.Skipped)
+		case r.Deleted:
+			deleted++
+			fmt.Printf("  %s  %-16s  deleted\n", r.Date, r.Type)
+		default:
+			fmt.Printf("  %s  %-16s  (nothing to delete)\n", r.Date, r.Type)
+		}
+	}
+	fmt.Printf("\n=== Purge Complete: %d deleted ===\n", deleted)
+	for _, r := range results {
+		if r.TrashID != "" {
+			fmt.Printf("Moved to trash %s until %s; undo with: scouter-server purge --restore %s\n",
+				r.TrashID, time.Now().Add(trash.Window()).Format("2006-01-02 15:04"), r.TrashID)
+			break
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/bench"
+	"github.com/zbum/scouter-server-go/internal/replay"
+)
+
+// replaySink is the bench sink used as replay target.
+type replaySink interface {
+	replay.Sink
+	Close() error
+}
+
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	date := fs.String("date", "", "day to replay (YYYYMMDD)")
+	file := fs.String("file", "", "replay a pack mirror capture (temp_dir/mirror/*.scm) instead of a stored day")
+	speedSpec := fs.String("speed", "1x", "playback speed (e.g. 1x, 10x, 0.5x); max sends as fast as possible")
+	target := fs.String("target", "", "collector address (udp://host:port); empty replays into an in-process pipeline")
+	typeSpec := fs.String("types", "xlog,profile,counter", "data types to replay")
+	rebase := fs.Bool("rebase", true, "rewrite timestamps to the replay time")
+	objType := fs.String("objtype", "java", "object type announced for replayed agents")
+	outDir := fs.String("out", "", "data directory of the in-process pipeline (default: a temp dir)")
+	keep := fs.Bool("keep", false, "keep the in-process temp data directory after the run")
+	fs.Parse(args)
+
+	if *date == "" && *file == "" {
+		fmt.Fprintf(os.Stderr, "Usage: scouter-server replay --date 20260207 | --file capture.scm [--speed 10x] [--target udp://host:6100]\n")
+		os.Exit(1)
+	}
+	speed, err := parseSpeed(*speedSpec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --speed %q: %v\n", *speedSpec, err)
+		os.Exit(1)
+	}
+	opts := replay.Options{
+		Date:     *date,
+		Speed:    speed,
+		Rebase:   *rebase,
+		ObjType:  *objType,
+		Progress: 5 * time.Second,
+	}
+	for _, t := range strings.Split(*typeSpec, ",") {
+		switch strings.TrimSpace(t) {
+		case "xlog":
+			opts.XLog = true
+		case "profile":
+			opts.Profile = true
+		case "counter":
+			opts.Counter = true
+		case "":
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown type %q (expected xlog, profile, counter)\n", t)
+			os.Exit(1)
+		}
+	}
+
+	source := *date
+	if *file != "" {
+		source = *file
+	}
+
+	cfg, dataDir := loadToolConfig()
+	if *file == "" {
+		if _, err := os.Stat(filepath.Join(dataDir, *date)); err != nil {
+			fmt.Fprintf(os.Stderr, "No data for %s in %s\n", *date, dataDir)
+			os.Exit(1)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	var sink replaySink
+	var pipeline *benchPipeline
+	if *target == "" {
+		dir := *outDir
+		if dir == "" {
+			if err := os.MkdirAll(cfg.TempDir(), 0755); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to create temp dir: %v\n", err)
+				os.Exit(1)
+			}
+			tmp, err := os.MkdirTemp(cfg.TempDir(), "replay-")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to create replay data dir: %v\n", err)
+				os.Exit(1)
+			}
+			dir = tmp
+			if !*keep {
+				defer os.RemoveAll(tmp)
+			}
+		} else if absPath(dir) == absPath(dataDir) {
+			fmt.Fprintf(os.Stderr, "--out must differ from the source data directory %s\n", dataDir)
+			os.Exit(1)
+		}
+		pipeline = newBenchPipeline(ctx, dir)
+		sink = newLosslessSink(pipeline)
+		fmt.Printf("Replay: %s into in-process pipeline, dataDir=%s\n", source, dir)
+	} else {
+		u, err := url.Parse(*target)
+		if err != nil || u.Scheme != "udp" || u.Host == "" {
+			fmt.Fprintf(os.Stderr, "Invalid --target %q (expected udp://host:port)\n", *target)
+			os.Exit(1)
+		}
+		s, err := bench.NewUDPSink(u.Host)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to %s: %v\n", u.Host, err)
+			os.Exit(1)
+		}
+		sink = s
+		fmt.Printf("Replay: %s to %s\n", source, u.Host)
+	}
+	defer sink.Close()
+	fmt.Printf("Replay: speed=%s types=%s rebase=%v\n\n", *speedSpec, *typeSpec, *rebase)
+
+	progress := func(s replay.Stats) {
+		fmt.Printf("  %6s  at %s  xlogs=%-10d profiles=%-9d counters=%-9d sendErrors=%d\n",
+			s.Elapsed.Round(time.Second), time.UnixMilli(s.Position).Format("15:04:05"),
+			s.XLogs, s.Profiles, s.Counters, s.SendErrors)
+	}
+	var st replay.Stats
+	if *file != "" {
+		st, err = replay.RunCapture(ctx, *file, sink, opts, progress)
+	} else {
+		r := replay.New(dataDir, sink, opts)
+		defer r.Close()
+		st, err = r.Run(ctx, progress)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Replay failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	if pipeline != nil {
+		// Give the async writers a moment to drain.
+		time.Sleep(2 * time.Second)
+		pipeline.close()
+	}
+
+	fmt.Printf("\n=== Replay Complete ===\n")
+	fmt.Printf("  elapsed        %s\n", st.Elapsed.Round(time.Millisecond))
+	if st.Position > 0 {
+		fmt.Printf("  replayed until %s\n", time.UnixMilli(st.Position).Format("15:04:05"))
+	}
+	fmt.Printf("  xlogs          %d\n", st.XLogs)
+	fmt.Printf("  profiles       %d\n", st.Profiles)
+	fmt.Printf("  counters       %d\n", st.Counters)
+	fmt.Printf("  objects        %d\n", st.Objects)
+	fmt.Printf("  texts          %d\n", st.Texts)
+	if st.Other > 0 {
+		fmt.Printf("  other packs    %d\n", st.Other)
+	}
+	fmt.Printf("  send errors    %d\n", st.SendErrors)
+	if pipeline != nil {
+		fmt.Printf("  dropped        %d\n", pipeline.processor.Dropped()+pipeline.xlogCore.Dropped()+
+			pipeline.profileCore.Dropped()+pipeline.perfCountCore.Dropped())
+	}
+}
+
+// parseSpeed parses "10x", "10", "0.5x" or "max" (0 = unthrottled).
+func parseSpeed(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "max" {
+		return 0, nil
+	}
+	s = strings.TrimSuffix(s, "x")
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if v <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return v, nil
+}
+
+func absPath(p string) string {
+	if a, err := filepath.Abs(p); err == nil {
+		return a
+	}
+	return p
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// runRetention simulates future disk usage from the measured per-day sizes
+// and the mgr_purge_* settings, so retention values can be tried out before
+// they are changed on a live server.
+func runRetention(args []string) {
+	cfg, dataDir := loadToolConfig()
+
+	fs := flag.NewFlagSet("retention", flag.ExitOnError)
+	profileDays := fs.Int("profile-days", cfg.MgrPurgeProfileKeepDays(), "mgr_purge_profile_keep_days to simulate")
+	xlogDays := fs.Int("xlog-days", cfg.MgrPurgeXLogKeepDays(), "mgr_purge_xlog_keep_days to simulate")
+	sumDays := fs.Int("sum-days", cfg.MgrPurgeSumDataDays(), "mgr_purge_sum_data_days to simulate")
+	counterDays := fs.Int("counter-days", cfg.MgrPurgeCounterKeepDays(), "mgr_purge_counter_keep_days to simulate")
+	realtimeDays := fs.Int("realtime-counter-days", cfg.MgrPurgeRealtimeCounterKeepDays(), "mgr_purge_realtime_counter_keep_days to simulate")
+	textDays := fs.Int("daily-text-days", cfg.MgrPurgeDailyTextDays(), "mgr_purge_daily_text_days to simulate")
+	diskPct := fs.Int("disk-pct", cfg.MgrPurgeDiskUsagePct(), "mgr_purge_disk_usage_pct to simulate")
+	diskSize := fs.String("disk-size", "", "simulate a dedicated volume of this size (e.g. 500G) instead of the current disk")
+	window := fs.Int("window", 7, "number of recent complete days averaged to project daily growth")
+	horizon := fs.Int("days", 365, "number of days to simulate")
+	fs.Parse(args)
+
+	days := db.MeasureDaySizes(dataDir)
+	if len(days) == 0 {
+		fmt.Fprintf(os.Stderr, "No date directories in %s\n", dataDir)
+		os.Exit(1)
+	}
+
+	var disk db.Disk
+	if *diskSize != "" {
+		n, err := parseByteSize(*diskSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --disk-size: %v\n", err)
+			os.Exit(1)
+		}
+		disk.Total = n
+	} else {
+		total, used, err := util.DiskSpace(dataDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read disk space of %s: %v\n", dataDir, err)
+			os.Exit(1)
+		}
+		disk.Total = int64(total)
+		disk.Other = max(int64(used)-util.DirSize(dataDir), 0)
+	}
+
+	policy := db.RetentionPolicy{
+		ProfileKeepDays:         *profileDays,
+		XLogKeepDays:            *xlogDays,
+		SumKeepDays:             *sumDays,
+		CounterKeepDays:         *counterDays,
+		RealtimeCounterKeepDays: *realtimeDays,
+		DailyTextKeepDays:       *textDays,
+		DiskUsagePct:            *diskPct,
+	}
+	fc := db.SimulateRetention(days, policy, disk, *window, *horizon)
+
+	var current int64
+	for _, d := range days {
+		current += d.Total()
+	}
+	fmt.Printf("Retention: dataDir=%s, %d days (%s..%s), %s\n",
+		dataDir, len(days), days[0].Date, days[len(days)-1].Date, formatBytes(current))
+	fmt.Printf("  disk %s, other files %s, threshold %d%%\n\n", formatBytes(disk.Total), formatBytes(disk.Other), policy.DiskUsagePct)
+
+	fmt.Printf("  %-18s %10s %12s %14s\n", "category", "keep days", "per day", "steady state")
+	var perDay, steady int64
+	unbounded := false
+	for _, c := range db.Categories {
+		keep := "forever"
+		if k := policy.KeepDays(c); k > 0 {
+			keep = strconv.Itoa(k)
+		}
+		state := "unbounded"
+		if s := fc.SteadyState[c]; s >= 0 {
+			state = formatBytes(s)
+			steady += s
+		} else if fc.DailyGrowth[c] > 0 {
+			unbounded = true
+		}
+		perDay += fc.DailyGrowth[c]
+		fmt.Printf("  %-18s %10s %12s %14s\n", c, keep, formatBytes(fc.DailyGrowth[c]), state)
+	}
+	total := formatBytes(steady)
+	if unbounded {
+		total = "unbounded"
+	}
+	fmt.Printf("  %-18s %10s %12s %14s\n\n", "total", "", formatBytes(perDay), total)
+
+	fmt.Printf("=== Forecast (%d days) ===\n", *horizon)
+	fmt.Printf("  peak data size   %s on %s\n", formatBytes(fc.Peak), fc.PeakDate)
+	if fc.ThresholdDate == "" {
+		fmt.Printf("  disk threshold   not reached\n")
+		return
+	}
+	when, _ := time.ParseInLocation("20060102", fc.ThresholdDate, time.Local)
+	fmt.Printf("  disk threshold   reached on %s (%d%%, in %d days)\n",
+		fc.ThresholdDate, fc.ThresholdPct, int(time.Until(when).Hours()/24)+1)
+	fmt.Printf("  disk usage purge keeps as few as %d days of data; lower the keep days to purge by age instead\n", fc.EffectiveDays)
+}
+
+// parseByteSize parses a size such as "500G", "1.5T" or "1048576".
+func parseByteSize(s string) (int64, error) {
+	s = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B")
+	s = strings.TrimSuffix(s, "I")
+	mult := int64(1)
+	if n := len(s); n > 0 {
+		if i := strings.IndexByte("KMGTP", s[n-1]); i >= 0 {
+			s = strings.TrimSpace(s[:n-1])
+			for ; i >= 0; i-- {
+				mult *= 1024
+			}
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f <= 0 {
+		return 0, fmt.Errorf("bad size %q", s)
+	}
+	return int64(f * float64(mult)), nil
+}
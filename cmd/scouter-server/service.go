@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// serviceOptions configures the OS service registration.
+type serviceOptions struct {
+	name   string
+	dir    string // working directory; conf/ and data/ are resolved from here
+	manual bool   // manual start type instead of automatic
+}
+
+const serviceUsage = `Usage: scouter-server service <install|uninstall|start|stop> [options]
+
+  install    register the server as a Windows service
+  uninstall  remove the service registration
+  start      start the installed service
+  stop       stop the running service (graceful shutdown)
+`
+
+func runService(args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, serviceUsage)
+		os.Exit(1)
+	}
+	action := args[0]
+
+	cwd, _ := os.Getwd()
+	fs := flag.NewFlagSet("service "+action, flag.ExitOnError)
+	name := fs.String("name", "scouter-server", "service name")
+	dir := fs.String("dir", cwd, "working directory of the service (install/run)")
+	manual := fs.Bool("manual", false, "install with manual start instead of automatic (install)")
+	fs.Parse(args[1:])
+
+	opts := serviceOptions{name: *name, dir: *dir, manual: *manual}
+
+	var err error
+	switch action {
+	case "install":
+		err = installService(opts)
+	case "uninstall":
+		err = uninstallService(opts)
+	case "start":
+		err = startService(opts)
+	case "stop":
+		err = stopService(opts)
+	case "run":
+		// Invoked by the service control manager, not by users.
+		err = runAsService(opts)
+	default:
+		fmt.Fprint(os.Stderr, serviceUsage)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "service %s failed: %v\n", action, err)
+		os.Exit(1)
+	}
+	if action != "run" {
+		fmt.Printf("service %s: %s ok\n", opts.name, action)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+var errServiceUnsupported = errors.New("service management is only supported on Windows (use systemd or launchd on this platform)")
+
+func installService(opts serviceOptions) error   { return errServiceUnsupported }
+func uninstallService(opts serviceOptions) error { return errServiceUnsupported }
+func startService(opts serviceOptions) error     { return errServiceUnsupported }
+func stopService(opts serviceOptions) error      { return errServiceUnsupported }
+func runAsService(opts serviceOptions) error     { return errServiceUnsupported }
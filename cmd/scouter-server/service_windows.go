@@ -0,0 +1,297 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Service Control Manager constants (winsvc.h).
+const (
+	scManagerAllAccess = 0xF003F
+	serviceAllAccess   = 0xF01FF
+
+	serviceWin32OwnProcess = 0x10
+	serviceAutoStart       = 2
+	serviceDemandStart     = 3
+	serviceErrorNormal     = 1
+	serviceConfigDesc      = 1
+
+	serviceControlStop     = 1
+	serviceControlShutdown = 5
+
+	serviceStopped      = 1
+	serviceStartPending = 2
+	serviceStopPending  = 3
+	serviceRunning      = 4
+
+	serviceAcceptStop     = 1
+	serviceAcceptShutdown = 4
+
+	errorServiceNotActive = 1062
+
+	serviceDescription = "Scouter APM collector server"
+	serviceStopTimeout = 30 * time.Second
+)
+
+var (
+	advapi32 = syscall.NewLazyDLL("advapi32.dll")
+
+	procOpenSCManagerW                = advapi32.NewProc("OpenSCManagerW")
+	procCreateServiceW                = advapi32.NewProc("CreateServiceW")
+	procOpenServiceW                  = advapi32.NewProc("OpenServiceW")
+	procDeleteService                 = advapi32.NewProc("DeleteService")
+	procStartServiceW                 = advapi32.NewProc("StartServiceW")
+	procControlService                = advapi32.NewProc("ControlService")
+	procQueryServiceStatus            = advapi32.NewProc("QueryServiceStatus")
+	procCloseServiceHandle            = advapi32.NewProc("CloseServiceHandle")
+	procChangeServiceConfig2W         = advapi32.NewProc("ChangeServiceConfig2W")
+	procStartServiceCtrlDispatcherW   = advapi32.NewProc("StartServiceCtrlDispatcherW")
+	procRegisterServiceCtrlHandlerExW = advapi32.NewProc("RegisterServiceCtrlHandlerExW")
+	procSetServiceStatus              = advapi32.NewProc("SetServiceStatus")
+)
+
+// serviceStatus mirrors SERVICE_STATUS.
+type serviceStatus struct {
+	ServiceType             uint32
+	CurrentState            uint32
+	ControlsAccepted        uint32
+	Win32ExitCode           uint32
+	ServiceSpecificExitCode uint32
+	CheckPoint              uint32
+	WaitHint                uint32
+}
+
+// serviceTableEntry mirrors SERVICE_TABLE_ENTRYW.
+type serviceTableEntry struct {
+	ServiceName *uint16
+	ServiceProc uintptr
+}
+
+func openSCManager() (uintptr, error) {
+	h, _, err := procOpenSCManagerW.Call(0, 0, scManagerAllAccess)
+	if h == 0 {
+		return 0, fmt.Errorf("open service manager: %w", err)
+	}
+	return h, nil
+}
+
+func openService(scm uintptr, name string) (uintptr, error) {
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return 0, err
+	}
+	h, _, err := procOpenServiceW.Call(scm, uintptr(unsafe.Pointer(namePtr)), serviceAllAccess)
+	if h == 0 {
+		return 0, fmt.Errorf("open service %q: %w", name, err)
+	}
+	return h, nil
+}
+
+func closeServiceHandle(h uintptr) {
+	procCloseServiceHandle.Call(h)
+}
+
+func installService(opts serviceOptions) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	dir, err := filepath.Abs(opts.dir)
+	if err != nil {
+		return err
+	}
+	binPath := fmt.Sprintf(`"%s" service run --name "%s" --dir "%s"`, exe, opts.name, dir)
+
+	startType := uintptr(serviceAutoStart)
+	if opts.manual {
+		startType = serviceDemandStart
+	}
+
+	scm, err := openSCManager()
+	if err != nil {
+		return err
+	}
+	defer closeServiceHandle(scm)
+
+	namePtr, _ := syscall.UTF16PtrFromString(opts.name)
+	displayPtr, _ := syscall.UTF16PtrFromString("Scouter Server (" + opts.name + ")")
+	binPtr, err := syscall.UTF16PtrFromString(binPath)
+	if err != nil {
+		return err
+	}
+	h, _, err := procCreateServiceW.Call(scm,
+		uintptr(unsafe.Pointer(namePtr)),
+		uintptr(unsafe.Pointer(displayPtr)),
+		serviceAllAccess,
+		serviceWin32OwnProcess,
+		startType,
+		serviceErrorNormal,
+		uintptr(unsafe.Pointer(binPtr)),
+		0, 0, 0, 0, 0, // load order group, tag, dependencies, LocalSystem account, password
+	)
+	if h == 0 {
+		return fmt.Errorf("create service: %w", err)
+	}
+	defer closeServiceHandle(h)
+
+	descPtr, _ := syscall.UTF16PtrFromString(serviceDescription)
+	procChangeServiceConfig2W.Call(h, serviceConfigDesc, uintptr(unsafe.Pointer(&descPtr)))
+	return nil
+}
+
+func uninstallService(opts serviceOptions) error {
+	scm, err := openSCManager()
+	if err != nil {
+		return err
+	}
+	defer closeServiceHandle(scm)
+	h, err := openService(scm, opts.name)
+	if err != nil {
+		return err
+	}
+	defer closeServiceHandle(h)
+
+	if r, _, err := procDeleteService.Call(h); r == 0 {
+		return fmt.Errorf("delete service: %w", err)
+	}
+	return nil
+}
+
+func startService(opts serviceOptions) error {
+	scm, err := openSCManager()
+	if err != nil {
+		return err
+	}
+	defer closeServiceHandle(scm)
+	h, err := openService(scm, opts.name)
+	if err != nil {
+		return err
+	}
+	defer closeServiceHandle(h)
+
+	if r, _, err := procStartServiceW.Call(h, 0, 0); r == 0 {
+		return fmt.Errorf("start service: %w", err)
+	}
+	return nil
+}
+
+// stopService sends SERVICE_CONTROL_STOP and waits for the server to finish
+// its graceful shutdown.
+func stopService(opts serviceOptions) error {
+	scm, err := openSCManager()
+	if err != nil {
+		return err
+	}
+	defer closeServiceHandle(scm)
+	h, err := openService(scm, opts.name)
+	if err != nil {
+		return err
+	}
+	defer closeServiceHandle(h)
+
+	var st serviceStatus
+	if r, _, err := procControlService.Call(h, serviceControlStop, uintptr(unsafe.Pointer(&st))); r == 0 {
+		if errno, ok := err.(syscall.Errno); ok && errno == errorServiceNotActive {
+			return nil
+		}
+		return fmt.Errorf("stop service: %w", err)
+	}
+
+	deadline := time.Now().Add(serviceStopTimeout)
+	for st.CurrentState != serviceStopped {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service did not stop within %s", serviceStopTimeout)
+		}
+		time.Sleep(300 * time.Millisecond)
+		if r, _, err := procQueryServiceStatus.Call(h, uintptr(unsafe.Pointer(&st))); r == 0 {
+			return fmt.Errorf("query service status: %w", err)
+		}
+	}
+	return nil
+}
+
+// Service runtime state. The SCM calls serviceMain and the control handler on
+// its own threads, so these are set before the dispatcher starts.
+var (
+	svcName         *uint16
+	svcStatusHandle uintptr
+	svcCancel       context.CancelFunc
+	svcCtx          context.Context
+
+	serviceMainCallback = syscall.NewCallback(serviceMain)
+	ctlHandlerCallback  = syscall.NewCallback(serviceCtlHandler)
+)
+
+// runAsService connects to the service control manager and runs the server
+// until a stop or shutdown control is received.
+func runAsService(opts serviceOptions) error {
+	// Services start in %SystemRoot%\System32; relative conf/ and data/ paths
+	// must resolve against the install directory.
+	if err := os.Chdir(opts.dir); err != nil {
+		return err
+	}
+
+	name, err := syscall.UTF16PtrFromString(opts.name)
+	if err != nil {
+		return err
+	}
+	svcName = name
+	svcCtx, svcCancel = context.WithCancel(context.Background())
+
+	table := []serviceTableEntry{
+		{ServiceName: svcName, ServiceProc: serviceMainCallback},
+		{},
+	}
+	// Blocks until serviceMain returns.
+	if r, _, err := procStartServiceCtrlDispatcherW.Call(uintptr(unsafe.Pointer(&table[0]))); r == 0 {
+		return fmt.Errorf("not started by the service control manager: %w", err)
+	}
+	return nil
+}
+
+func setServiceStatus(state, accepts, exitCode uint32) {
+	st := serviceStatus{
+		ServiceType:      serviceWin32OwnProcess,
+		CurrentState:     state,
+		ControlsAccepted: accepts,
+		Win32ExitCode:    exitCode,
+	}
+	if state == serviceStartPending || state == serviceStopPending {
+		st.WaitHint = uint32(serviceStopTimeout / time.Millisecond)
+	}
+	procSetServiceStatus.Call(svcStatusHandle, uintptr(unsafe.Pointer(&st)))
+}
+
+func serviceMain(argc, argv uintptr) uintptr {
+	h, _, _ := procRegisterServiceCtrlHandlerExW.Call(uintptr(unsafe.Pointer(svcName)), ctlHandlerCallback, 0)
+	if h == 0 {
+		return 0
+	}
+	svcStatusHandle = h
+
+	setServiceStatus(serviceStartPending, 0, 0)
+	setServiceStatus(serviceRunning, serviceAcceptStop|serviceAcceptShutdown, 0)
+
+	var exitCode uint32
+	if err := runServer(svcCtx); err != nil {
+		exitCode = 1
+	}
+	setServiceStatus(serviceStopped, 0, exitCode)
+	return 0
+}
+
+func serviceCtlHandler(ctrl, eventType, eventData, context uintptr) uintptr {
+	switch ctrl {
+	case serviceControlStop, serviceControlShutdown:
+		setServiceStatus(serviceStopPending, 0, 0)
+		svcCancel()
+	}
+	return 0
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/netio/client"
+	"github.com/zbum/scouter-server-go/internal/tail"
+)
+
+func runTail(args []string) {
+	cfg, _ := loadToolConfig()
+
+	fs := flag.NewFlagSet("tail", flag.ExitOnError)
+	server := fs.String("server", net.JoinHostPort("127.0.0.1", strconv.Itoa(cfg.TCPPort())), "server TCP address (host:port)")
+	user := fs.String("user", "admin", "login id")
+	password := fs.String("password", os.Getenv("SCOUTER_PASSWORD"), "login password (default $SCOUTER_PASSWORD)")
+	objType := fs.String("objtype", "", "only show XLogs from objects of this type (e.g. java)")
+	errorOnly := fs.Bool("error-only", false, "only show XLogs with an error")
+	minElapsed := fs.Int("min-elapsed", 0, "only show XLogs at least this slow (ms)")
+	interval := fs.Duration("interval", time.Second, "polling interval")
+	fs.Parse(args)
+
+	c, err := client.Dial(*server, 10*time.Second)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to %s: %v\n", *server, err)
+		os.Exit(1)
+	}
+	defer c.Close()
+	if err := c.Login(*user, *password); err != nil {
+		fmt.Fprintf(os.Stderr, "Login failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Tailing XLogs from %s (Ctrl-C to stop)\n", *server)
+	t := tail.New(c, tail.Options{
+		ObjType:    *objType,
+		ErrorOnly:  *errorOnly,
+		MinElapsed: int32(*minElapsed),
+		Interval:   *interval,
+	})
+	if err := t.Run(ctx, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Tail stopped: %v\n", err)
+		os.Exit(1)
+	}
+}
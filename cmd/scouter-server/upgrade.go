@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/admin"
+	"github.com/zbum/scouter-server-go/internal/db/format"
+)
+
+// runUpgrade migrates the xlog, profile and counter files of every day
+// directory to the format version of this build.
+func runUpgrade(args []string) {
+	fs := flag.NewFlagSet("upgrade", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "report what would be upgraded without writing")
+	force := fs.Bool("force", false, "upgrade past days even if a running server is detected")
+	fs.Parse(args)
+
+	_, dataDir := loadToolConfig()
+
+	// A running server holds today's files open, so only past days are
+	// touched while it runs, and only when asked to.
+	skip := map[string]bool{}
+	if admin.IsLocked(dataDir) && !*dryRun {
+		if !*force {
+			fmt.Fprintf(os.Stderr, "A running server holds %s (pid %d).\n", dataDir, admin.LockHolder(dataDir))
+			fmt.Fprintf(os.Stderr, "Stop the server first, or pass --force to upgrade all days but today.\n")
+			os.Exit(1)
+		}
+		skip[time.Now().Format("20060102")] = true
+	}
+	fmt.Printf("Upgrade: dataDir=%s, dry-run=%v\n\n", dataDir, *dryRun)
+
+	results, err := format.Upgrade(dataDir, skip, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Upgrade failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	changed, failed := 0, 0
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			fmt.Printf("  %s  %-8s  v%d  error: %v\n", r.Date, r.Component, r.From, r.Err)
+		case r.Marked:
+			changed++
+			fmt.Printf("  %s  %-8s  v%d  marked\n", r.Date, r.Component, r.To)
+		default:
+			changed++
+			fmt.Printf("  %s  %-8s  v%d -> v%d  %s\n", r.Date, r.Component, r.From, r.To, strings.Join(r.Steps, "; "))
+		}
+	}
+	fmt.Printf("\n=== Upgrade Complete: %d upgraded, %d failed ===\n", changed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
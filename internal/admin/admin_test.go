@@ -0,0 +1,95 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcquireLock_SingleInstance(t *testing.T) {
+	dir := t.TempDir()
+
+	lock, err := AcquireLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsLocked(dir) {
+		t.Error("expected directory to be locked")
+	}
+	if pid := LockHolder(dir); pid != os.Getpid() {
+		t.Errorf("LockHolder = %d, want %d", pid, os.Getpid())
+	}
+
+	if _, err := AcquireLock(dir); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked on second acquire, got %v", err)
+	}
+
+	lock.Release()
+	if IsLocked(dir) {
+		t.Error("expected directory to be unlocked after Release")
+	}
+	lock2, err := AcquireLock(dir)
+	if err != nil {
+		t.Fatalf("expected re-acquire after release, got %v", err)
+	}
+	lock2.Release()
+}
+
+func TestServer_Commands(t *testing.T) {
+	// Unix socket paths are length-limited; keep the directory short.
+	dir, err := os.MkdirTemp("", "adm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := NewServer(SocketPath(dir))
+	srv.Handle("echo", func(args []string) (string, error) {
+		return strings.Join(args, " "), nil
+	})
+	srv.Handle("fail", func(args []string) (string, error) {
+		return "", fmt.Errorf("boom")
+	})
+	if err := srv.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := Send(SocketPath(dir), "echo a b", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "a b\n" {
+		t.Errorf("echo output = %q", out)
+	}
+
+	if _, err := Send(SocketPath(dir), "fail", time.Second); err == nil || err.Error() != "boom" {
+		t.Errorf("expected boom error, got %v", err)
+	}
+	if _, err := Send(SocketPath(dir), "nope", time.Second); err == nil {
+		t.Error("expected unknown command error")
+	}
+
+	out, err = Send(SocketPath(dir), "help", time.Second)
+	if err != nil || !strings.Contains(out, "echo") || !strings.Contains(out, "fail") {
+		t.Errorf("help output = %q, err %v", out, err)
+	}
+
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := os.Stat(SocketPath(dir)); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("socket file not removed after shutdown")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
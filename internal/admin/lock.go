@@ -0,0 +1,84 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LockFileName is the single-instance lock file created in the data directory.
+const LockFileName = "scouter.lock"
+
+// ErrLocked is returned when another process holds the data directory lock.
+var ErrLocked = errors.New("data directory is locked by another process")
+
+// Lock is an exclusive, process-scoped lock on a data directory. The OS
+// releases it automatically if the process dies, so no stale-lock cleanup
+// is needed.
+type Lock struct {
+	f *os.File
+}
+
+// AcquireLock takes the single-instance lock for dir, creating dir if needed.
+// If another process holds it, the returned error wraps ErrLocked and names
+// the holder's PID.
+func AcquireLock(dir string) (*Lock, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, LockFileName)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		if pid := LockHolder(dir); pid > 0 {
+			return nil, fmt.Errorf("%w (pid %d)", ErrLocked, pid)
+		}
+		return nil, ErrLocked
+	}
+
+	f.Truncate(0)
+	f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	return &Lock{f: f}, nil
+}
+
+// Release unlocks the data directory. The lock file itself is left in place:
+// removing it could race with another process that has just opened it.
+func (l *Lock) Release() {
+	if l == nil || l.f == nil {
+		return
+	}
+	unlockFile(l.f)
+	l.f.Close()
+	l.f = nil
+}
+
+// LockHolder returns the PID recorded in dir's lock file, or 0 if unknown.
+// The PID is informational only; use IsLocked to test for a live holder.
+func LockHolder(dir string) int {
+	b, err := os.ReadFile(filepath.Join(dir, LockFileName))
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(b)))
+	return pid
+}
+
+// IsLocked reports whether a running process currently holds dir's lock.
+func IsLocked(dir string) bool {
+	f, err := os.OpenFile(filepath.Join(dir, LockFileName), os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return true
+	}
+	unlockFile(f)
+	return false
+}
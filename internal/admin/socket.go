@@ -0,0 +1,161 @@
+package admin
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SocketFileName is the admin socket created in the data directory.
+const SocketFileName = "admin.sock"
+
+// SocketPath returns the admin socket path for a data directory.
+func SocketPath(dataDir string) string {
+	return filepath.Join(dataDir, SocketFileName)
+}
+
+// Handler executes an admin command and returns its text output.
+type Handler func(args []string) (string, error)
+
+// Server accepts one-line commands on a local Unix domain socket.
+//
+// Wire format: the client sends "<command> [args...]\n"; the server replies
+// with "ok\n" or "error: <message>\n", followed by the command output, and
+// closes the connection.
+type Server struct {
+	path     string
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// NewServer creates an admin server listening on path once started.
+func NewServer(path string) *Server {
+	s := &Server{
+		path:     path,
+		handlers: make(map[string]Handler),
+	}
+	s.Handle("help", func(args []string) (string, error) {
+		return strings.Join(s.commands(), "\n"), nil
+	})
+	return s
+}
+
+// Handle registers a command handler.
+func (s *Server) Handle(cmd string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[cmd] = h
+}
+
+func (s *Server) commands() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cmds := make([]string, 0, len(s.handlers))
+	for c := range s.handlers {
+		cmds = append(cmds, c)
+	}
+	sort.Strings(cmds)
+	return cmds
+}
+
+// Start listens on the socket and serves commands until ctx is cancelled.
+// The caller must hold the data directory lock, which makes removing a
+// stale socket file left by a crashed process safe.
+func (s *Server) Start(ctx context.Context) error {
+	os.Remove(s.path)
+	ln, err := net.Listen("unix", s.path)
+	if err != nil {
+		return err
+	}
+	os.Chmod(s.path, 0600)
+
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+		os.Remove(s.path)
+	}()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Warn("Admin socket accept error", "error", err)
+				}
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return nil
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil && line == "" {
+		return
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		fmt.Fprint(conn, "error: empty command\n")
+		return
+	}
+
+	s.mu.RLock()
+	h := s.handlers[fields[0]]
+	s.mu.RUnlock()
+	if h == nil {
+		fmt.Fprintf(conn, "error: unknown command %q (try help)\n", fields[0])
+		return
+	}
+
+	slog.Info("Admin command", "cmd", fields[0])
+	out, err := h(fields[1:])
+	if err != nil {
+		fmt.Fprintf(conn, "error: %v\n", err)
+		return
+	}
+	fmt.Fprint(conn, "ok\n")
+	if out != "" {
+		fmt.Fprint(conn, strings.TrimRight(out, "\n")+"\n")
+	}
+}
+
+// Send issues a command to the admin socket at path and returns its output.
+func Send(path, command string, timeout time.Duration) (string, error) {
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := fmt.Fprintf(conn, "%s\n", command); err != nil {
+		return "", err
+	}
+	r := bufio.NewReader(conn)
+	status, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	var body strings.Builder
+	if _, err := r.WriteTo(&body); err != nil {
+		return "", err
+	}
+	status = strings.TrimSpace(status)
+	if status != "ok" {
+		return "", fmt.Errorf("%s", strings.TrimPrefix(status, "error: "))
+	}
+	return body.String(), nil
+}
@@ -0,0 +1,126 @@
+// Package alertrule evaluates threshold rules on counter series. It backs the
+// alert preview API, which replays a proposed rule over stored counter data so
+// thresholds can be tuned before the rule goes live, and the Engine, which
+// evaluates the live rules of a Store on realtime counters as they arrive.
+package alertrule
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MaxForSec bounds how long a condition can be required to hold.
+const MaxForSec = 24 * 60 * 60
+
+// Rule fires when a counter crosses a threshold and stays there.
+type Rule struct {
+	Counter string `json:"counter"`
+	// Op compares the counter value to Threshold: >, >=, < or <=.
+	Op        string  `json:"op"`
+	Threshold float64 `json:"threshold"`
+	// ForSec is how long the condition must hold before the rule fires;
+	// 0 fires on the first matching sample.
+	ForSec int `json:"forSec"`
+}
+
+// Validate checks the rule.
+func (r *Rule) Validate() error {
+	if strings.TrimSpace(r.Counter) == "" {
+		return errors.New("counter is empty")
+	}
+	switch r.Op {
+	case ">", ">=", "<", "<=":
+	default:
+		return fmt.Errorf("bad op %q: use >, >=, < or <=", r.Op)
+	}
+	if math.IsNaN(r.Threshold) || math.IsInf(r.Threshold, 0) {
+		return errors.New("threshold must be a finite number")
+	}
+	if r.ForSec < 0 || r.ForSec > MaxForSec {
+		return fmt.Errorf("forSec must be between 0 and %d", MaxForSec)
+	}
+	return nil
+}
+
+// Match reports whether v meets the condition. NaN never matches.
+func (r *Rule) Match(v float64) bool {
+	switch r.Op {
+	case ">":
+		return v > r.Threshold
+	case ">=":
+		return v >= r.Threshold
+	case "<":
+		return v < r.Threshold
+	case "<=":
+		return v <= r.Threshold
+	}
+	return false
+}
+
+// worse reports whether a is further past the threshold than b.
+func (r *Rule) worse(a, b float64) bool {
+	if strings.HasPrefix(r.Op, ">") {
+		return a > b
+	}
+	return a < b
+}
+
+// Sample is one counter value. NaN marks a missing value.
+type Sample struct {
+	TimeMs int64
+	Value  float64
+}
+
+// Firing is one period in which the rule would have fired.
+type Firing struct {
+	// SinceMs is when the condition started to hold.
+	SinceMs int64 `json:"since"`
+	// StartMs is when the rule would have fired, ForSec after SinceMs.
+	StartMs int64 `json:"start"`
+	// EndMs is the first sample no longer matching, or the last matching
+	// sample if the data stops first.
+	EndMs int64 `json:"end"`
+	// Peak is the value furthest past the threshold.
+	Peak float64 `json:"peak"`
+}
+
+// Evaluate returns the firings of the rule over samples, which must be in
+// time order. A missing value or a gap longer than maxGapMs between samples
+// breaks the condition.
+func (r *Rule) Evaluate(samples []Sample, maxGapMs int64) []Firing {
+	var firings []Firing
+	var cur *Firing // condition holding since cur.SinceMs
+	var lastMs int64
+	fired := false
+
+	end := func(endMs int64) {
+		if cur != nil && fired {
+			cur.EndMs = endMs
+			firings = append(firings, *cur)
+		}
+		cur, fired = nil, false
+	}
+	for _, s := range samples {
+		if cur != nil && maxGapMs > 0 && s.TimeMs-lastMs > maxGapMs {
+			end(lastMs)
+		}
+		if !r.Match(s.Value) {
+			end(s.TimeMs)
+			continue
+		}
+		if cur == nil {
+			cur = &Firing{SinceMs: s.TimeMs, Peak: s.Value}
+		} else if r.worse(s.Value, cur.Peak) {
+			cur.Peak = s.Value
+		}
+		lastMs = s.TimeMs
+		if !fired && s.TimeMs-cur.SinceMs >= int64(r.ForSec)*1000 {
+			cur.StartMs = s.TimeMs
+			fired = true
+		}
+	}
+	end(lastMs)
+	return firings
+}
@@ -0,0 +1,57 @@
+package alertrule
+
+import (
+	"math"
+	"testing"
+)
+
+func series(step int64, values ...float64) []Sample {
+	samples := make([]Sample, len(values))
+	for i, v := range values {
+		samples[i] = Sample{TimeMs: int64(i) * step, Value: v}
+	}
+	return samples
+}
+
+func TestRuleEvaluate(t *testing.T) {
+	nan := math.NaN()
+	r := Rule{Counter: "TPS", Op: ">", Threshold: 10, ForSec: 2}
+	if err := r.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Held for 2s from t=1s, cleared at t=5s; the t=6s spike is too short.
+	got := r.Evaluate(series(1000, 5, 11, 20, 12, 15, 3, 50, 4), 0)
+	if len(got) != 1 || got[0] != (Firing{SinceMs: 1000, StartMs: 3000, EndMs: 5000, Peak: 20}) {
+		t.Errorf("firings = %+v", got)
+	}
+
+	// A missing value breaks the condition.
+	if got := r.Evaluate(series(1000, 11, 11, nan, 11, 11), 0); len(got) != 0 {
+		t.Errorf("firings across a missing value = %+v", got)
+	}
+
+	// So does a gap longer than maxGapMs; data ending keeps the last sample.
+	samples := []Sample{{0, 11}, {2000, 11}, {3000, 11}, {9000, 11}}
+	got = r.Evaluate(samples, 5000)
+	if len(got) != 1 || got[0].StartMs != 2000 || got[0].EndMs != 3000 {
+		t.Errorf("firings with gap = %+v", got)
+	}
+
+	low := Rule{Counter: "Heap", Op: "<=", Threshold: 1}
+	got = low.Evaluate(series(1000, 2, 1, 0, 1, 2, 1), 0)
+	if len(got) != 2 || got[0].Peak != 0 || got[0].StartMs != 1000 || got[1].EndMs != 5000 {
+		t.Errorf("<= firings = %+v", got)
+	}
+
+	for _, bad := range []Rule{
+		{Op: ">"},
+		{Counter: "TPS", Op: "=="},
+		{Counter: "TPS", Op: ">", Threshold: math.Inf(1)},
+		{Counter: "TPS", Op: ">", ForSec: -1},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Validate(%+v) accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package alertrule
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// RealtimeGap is the longest gap between the realtime values of an object
+// that does not break a condition; agents send their counters every few
+// seconds.
+const RealtimeGap = 30 * time.Second
+
+type stateKey struct {
+	rule    string
+	objHash int32
+}
+
+// state is a condition holding for one object.
+type state struct {
+	def    Definition
+	since  int64 // when the condition started to hold
+	last   int64 // latest matching value
+	start  int64 // when the alert was raised, 0 while not yet
+	peak   float64
+	latest float64
+}
+
+// Active is a condition currently holding for an object.
+type Active struct {
+	Rule    string  `json:"rule"`
+	ObjHash int32   `json:"objHash"`
+	ObjName string  `json:"objName,omitempty"`
+	SinceMs int64   `json:"since"`
+	StartMs int64   `json:"start,omitempty"` // 0 while still waiting for forSec
+	Peak    float64 `json:"peak"`
+	Value   float64 `json:"value"`
+}
+
+// Engine evaluates the definitions of a Store on realtime counters as they
+// are ingested, raising an alert when a rule has held for its forSec and a
+// resolving INFO alert when it stops holding.
+type Engine struct {
+	store  *Store
+	object func(objHash int32) (objName, objType string)
+	emit   func(ap *pack.AlertPack)
+
+	mu     sync.Mutex
+	defs   map[string]Definition // definitions the states were built under
+	states map[stateKey]*state
+}
+
+// NewEngine creates an Engine for the definitions of store. object resolves
+// an objHash to its name and type; emit receives the alerts.
+func NewEngine(store *Store, object func(objHash int32) (objName, objType string), emit func(ap *pack.AlertPack)) *Engine {
+	return &Engine{
+		store:  store,
+		object: object,
+		emit:   emit,
+		states: make(map[stateKey]*state),
+	}
+}
+
+// Store returns the definitions store.
+func (e *Engine) Store() *Store {
+	return e.store
+}
+
+// Observe evaluates the realtime counters of objHash taken at timeMs.
+func (e *Engine) Observe(objHash int32, timeMs int64, counters map[string]value.Value) {
+	defs := e.store.snapshot()
+	objName, objType := e.object(objHash)
+
+	var alerts []*pack.AlertPack
+	e.mu.Lock()
+	e.sync(defs)
+	for name, d := range defs {
+		v, ok := counters[d.Counter]
+		if !ok || !d.Selects(objHash, objType) {
+			continue
+		}
+		f := numeric(v)
+		key := stateKey{name, objHash}
+		st := e.states[key]
+		if st != nil && timeMs < st.last {
+			continue // out of order
+		}
+		if st != nil && timeMs-st.last > RealtimeGap.Milliseconds() {
+			alerts = appendResolved(alerts, st, objHash, objName, objType, st.last)
+			delete(e.states, key)
+			st = nil
+		}
+		if !d.Match(f) {
+			if st != nil {
+				alerts = appendResolved(alerts, st, objHash, objName, objType, timeMs)
+				delete(e.states, key)
+			}
+			continue
+		}
+		if st == nil {
+			st = &state{def: d, since: timeMs, peak: f}
+			e.states[key] = st
+		} else if d.worse(f, st.peak) {
+			st.peak = f
+		}
+		st.last, st.latest = timeMs, f
+		if st.start == 0 && timeMs-st.since >= int64(d.ForSec)*1000 {
+			st.start = timeMs
+			alerts = append(alerts, newAlert(d, objHash, objName, objType, timeMs, byte(d.LevelByte()), d.Name,
+				fmt.Sprintf("%s %s %s for %ds on %s: %s", d.Counter, d.Op, formatValue(d.Threshold), d.ForSec, objLabel(objName, objHash), formatValue(f))))
+		}
+	}
+	e.mu.Unlock()
+
+	for _, ap := range alerts {
+		e.emit(ap)
+	}
+}
+
+// sync drops the states of rules that were removed or changed, so a new
+// definition starts from scratch. Caller must hold e.mu.
+func (e *Engine) sync(defs map[string]Definition) {
+	if sameDefs(e.defs, defs) {
+		return
+	}
+	for key, st := range e.states {
+		if d, ok := defs[key.rule]; !ok || d != st.def {
+			delete(e.states, key)
+		}
+	}
+	e.defs = defs
+}
+
+func sameDefs(a, b map[string]Definition) bool {
+	if len(a) != len(b) || a == nil {
+		return a == nil && b == nil
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Active returns the conditions currently holding, sorted by rule and object.
+func (e *Engine) Active() []Active {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	result := make([]Active, 0, len(e.states))
+	for key, st := range e.states {
+		objName, _ := e.object(key.objHash)
+		result = append(result, Active{
+			Rule:    key.rule,
+			ObjHash: key.objHash,
+			ObjName: objName,
+			SinceMs: st.since,
+			StartMs: st.start,
+			Peak:    st.peak,
+			Value:   st.latest,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Rule != result[j].Rule {
+			return result[i].Rule < result[j].Rule
+		}
+		return result[i].ObjHash < result[j].ObjHash
+	})
+	return result
+}
+
+// appendResolved adds the resolving alert of st if it had been raised.
+func appendResolved(alerts []*pack.AlertPack, st *state, objHash int32, objName, objType string, timeMs int64) []*pack.AlertPack {
+	if st.start == 0 {
+		return alerts
+	}
+	d := st.def
+	return append(alerts, newAlert(d, objHash, objName, objType, timeMs, 0, d.Name+"_RESOLVED",
+		fmt.Sprintf("%s %s %s no longer holds on %s (peak %s).", d.Counter, d.Op, formatValue(d.Threshold), objLabel(objName, objHash), formatValue(st.peak))))
+}
+
+func newAlert(d Definition, objHash int32, objName, objType string, timeMs int64, level byte, title, message string) *pack.AlertPack {
+	tags := value.NewMapValue()
+	tags.Put("rule", value.NewTextValue(d.Name))
+	tags.Put("counter", value.NewTextValue(d.Counter))
+	if objName != "" {
+		tags.Put("objName", value.NewTextValue(objName))
+	}
+	return &pack.AlertPack{
+		Time:    timeMs,
+		Level:   level,
+		ObjType: objType,
+		ObjHash: objHash,
+		Title:   title,
+		Message: message,
+		Tags:    tags,
+	}
+}
+
+func objLabel(objName string, objHash int32) string {
+	if objName != "" {
+		return objName
+	}
+	return strconv.Itoa(int(objHash))
+}
+
+func formatValue(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// numeric returns the value of a counter, NaN if it is not a number.
+func numeric(v value.Value) float64 {
+	switch tv := v.(type) {
+	case *value.DecimalValue:
+		return float64(tv.Value)
+	case *value.FloatValue:
+		return float64(tv.Value)
+	case *value.DoubleValue:
+		return tv.Value
+	}
+	return math.NaN()
+}
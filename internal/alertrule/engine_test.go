@@ -0,0 +1,113 @@
+package alertrule
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/db/kv"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+func tps(v int64) map[string]value.Value {
+	return map[string]value.Value{"TPS": value.NewDecimalValue(v)}
+}
+
+func TestStore(t *testing.T) {
+	kvs := kv.NewKVStore(t.TempDir(), "global.json")
+	store := NewStore(kvs)
+	if err := store.Put(Definition{Name: "tps", Rule: Rule{Counter: "TPS", Op: ">", Threshold: 100}, Level: "error"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []Definition{
+		{Rule: Rule{Counter: "TPS", Op: ">"}},
+		{Name: "x", Rule: Rule{Counter: "TPS", Op: "!="}},
+		{Name: "x", Rule: Rule{Counter: "TPS", Op: ">"}, Level: "LOUD"},
+	} {
+		if err := store.Put(d); err == nil {
+			t.Errorf("%+v: expected an error", d)
+		}
+	}
+
+	// A second store on the same KV sees the definition.
+	list := NewStore(kvs).List()
+	if len(list) != 1 || list[0].Level != "ERROR" || list[0].Threshold != 100 {
+		t.Errorf("list = %+v", list)
+	}
+	if found, err := store.Delete("tps"); !found || err != nil {
+		t.Errorf("delete: %v %v", found, err)
+	}
+	if found, _ := store.Delete("tps"); found {
+		t.Error("deleted twice")
+	}
+}
+
+func TestEngine(t *testing.T) {
+	store := NewStore(kv.NewKVStore(t.TempDir(), "global.json"))
+	store.Put(Definition{Name: "HIGH_TPS", Rule: Rule{Counter: "TPS", Op: ">", Threshold: 100, ForSec: 10}, ObjType: "tomcat"})
+	var alerts []*pack.AlertPack
+	e := NewEngine(store, func(objHash int32) (string, string) {
+		if objHash == 1 {
+			return "/host/app1", "tomcat"
+		}
+		return "/host/db", "mysql"
+	}, func(ap *pack.AlertPack) { alerts = append(alerts, ap) })
+
+	// Held for 10s from t=2s; other types are not selected.
+	for i, v := range []int64{50, 150, 200, 120, 130, 110} {
+		e.Observe(1, int64(i)*2000, tps(v))
+		e.Observe(2, int64(i)*2000, tps(v))
+	}
+	if len(alerts) != 0 {
+		t.Fatalf("fired before forSec: %+v", alerts[0])
+	}
+	e.Observe(1, 12000, tps(140))
+	if len(alerts) != 1 {
+		t.Fatalf("%d alerts, want 1", len(alerts))
+	}
+	ap := alerts[0]
+	if ap.Title != "HIGH_TPS" || ap.Level != 1 || ap.ObjHash != 1 || ap.ObjType != "tomcat" ||
+		ap.Message != "TPS > 100 for 10s on /host/app1: 140" {
+		t.Errorf("alert %+v", ap)
+	}
+
+	// Fires once; an out-of-order value is ignored.
+	e.Observe(1, 14000, tps(300))
+	e.Observe(1, 13000, tps(10))
+	if len(alerts) != 1 {
+		t.Fatalf("%d alerts, want 1", len(alerts))
+	}
+	active := e.Active()
+	if len(active) != 1 || active[0].StartMs != 12000 || active[0].Peak != 300 {
+		t.Errorf("active = %+v", active)
+	}
+
+	// Resolved when the value drops.
+	e.Observe(1, 16000, tps(90))
+	if len(alerts) != 2 || alerts[1].Title != "HIGH_TPS_RESOLVED" || alerts[1].Level != 0 ||
+		!strings.Contains(alerts[1].Message, "peak 300") {
+		t.Fatalf("alerts %+v", alerts)
+	}
+	if len(e.Active()) != 0 {
+		t.Error("still active after resolving")
+	}
+
+	// A gap breaks the condition, which starts over.
+	alerts = nil
+	e.Observe(1, 20000, tps(150))
+	e.Observe(1, 60000, tps(150))
+	e.Observe(1, 65000, tps(150))
+	if len(alerts) != 0 {
+		t.Errorf("fired across a gap: %+v", alerts)
+	}
+
+	// Changing the rule restarts its conditions.
+	store.Put(Definition{Name: "HIGH_TPS", Rule: Rule{Counter: "TPS", Op: ">", Threshold: 100}, Level: "FATAL"})
+	e.Observe(1, 66000, tps(150))
+	if len(alerts) != 1 || alerts[0].Level != 3 {
+		t.Errorf("alerts after change %+v", alerts)
+	}
+	if active := e.Active(); len(active) != 1 || active[0].SinceMs != 66000 {
+		t.Errorf("active after change %+v", active)
+	}
+}
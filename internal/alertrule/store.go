@@ -0,0 +1,100 @@
+package alertrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zbum/scouter-server-go/internal/db/kv"
+)
+
+// kvKey is the KV store key holding all rule definitions as one JSON object.
+const kvKey = "alert_rules"
+
+// Levels are the alert level names, indexed by the AlertPack level.
+var Levels = []string{"INFO", "WARN", "ERROR", "FATAL"}
+
+// Definition is a live rule: a Rule evaluated on the realtime counters of
+// the objects it selects as they arrive.
+type Definition struct {
+	Name string `json:"name"`
+	Rule
+	// ObjType and ObjHash, if set, restrict the rule to the objects of one
+	// type or to one object.
+	ObjType string `json:"objType,omitempty"`
+	ObjHash int32  `json:"objHash,omitempty"`
+	// Level is the alert level name (default WARN).
+	Level string `json:"level"`
+}
+
+// Validate checks the definition and fills in the default level.
+func (d *Definition) Validate() error {
+	if strings.TrimSpace(d.Name) == "" {
+		return errors.New("rule name is empty")
+	}
+	if err := d.Rule.Validate(); err != nil {
+		return err
+	}
+	if d.Level == "" {
+		d.Level = "WARN"
+	}
+	d.Level = strings.ToUpper(d.Level)
+	if d.LevelByte() < 0 {
+		return fmt.Errorf("bad level %q: use INFO, WARN, ERROR or FATAL", d.Level)
+	}
+	return nil
+}
+
+// LevelByte returns the AlertPack level of d, -1 if unknown.
+func (d *Definition) LevelByte() int {
+	for i, l := range Levels {
+		if l == d.Level {
+			return i
+		}
+	}
+	return -1
+}
+
+// Selects reports whether the rule applies to an object.
+func (d *Definition) Selects(objHash int32, objType string) bool {
+	return (d.ObjHash == 0 || d.ObjHash == objHash) && (d.ObjType == "" || d.ObjType == objType)
+}
+
+// Store keeps rule definitions in a KV store.
+type Store struct {
+	defs *kv.JSONMap[Definition]
+}
+
+// NewStore creates a Store backed by store.
+func NewStore(store *kv.KVStore) *Store {
+	return &Store{defs: kv.NewJSONMap[Definition](store, kvKey, nil)}
+}
+
+// List returns all definitions sorted by name.
+func (s *Store) List() []Definition {
+	result := s.defs.Values()
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// snapshot returns the current definitions keyed by name; the map must not
+// be modified.
+func (s *Store) snapshot() map[string]Definition {
+	defs, _ := s.defs.Snapshot()
+	return defs
+}
+
+// Put creates or replaces a definition.
+func (s *Store) Put(d Definition) error {
+	d.Name = strings.TrimSpace(d.Name)
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	return s.defs.Put(d.Name, d)
+}
+
+// Delete removes a definition and reports whether it existed.
+func (s *Store) Delete(name string) (bool, error) {
+	return s.defs.Delete(name)
+}
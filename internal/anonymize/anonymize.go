@@ -0,0 +1,390 @@
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/step"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// Anonymizer scrubs or hashes sensitive fields of packs while keeping their
+// structure, hashes and timing intact.
+//
+// Hashed values (IPs, user ids, host names) are keyed with a salt, so the
+// same input maps to the same output within an export but cannot be
+// reversed by hashing guessed values without the salt.
+type Anonymizer struct {
+	salt []byte
+
+	mu      sync.Mutex
+	objHash map[int32]int32 // original objHash → anonymized objHash
+	stats   Stats
+}
+
+// Stats counts anonymized records.
+type Stats struct {
+	Texts         int64
+	XLogs         int64
+	Profiles      int64
+	DroppedBlocks int64 // profile blocks that could not be decoded
+	Objects       int64
+	Counters      int64
+}
+
+// New creates an Anonymizer keyed with salt.
+func New(salt []byte) *Anonymizer {
+	return &Anonymizer{
+		salt:    salt,
+		objHash: make(map[int32]int32),
+	}
+}
+
+// Stats returns the counters collected so far.
+func (a *Anonymizer) Stats() Stats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.stats
+}
+
+// Pack anonymizes p in place. It returns nil if p must be dropped.
+func (a *Anonymizer) Pack(p pack.Pack) pack.Pack {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch x := p.(type) {
+	case *pack.TextPack:
+		x.Text = a.text(x.XType, x.Text)
+		a.stats.Texts++
+	case *pack.ObjectPack:
+		a.object(x)
+		a.stats.Objects++
+	case *pack.XLogPack:
+		a.xlog(x)
+		a.stats.XLogs++
+	case *pack.XLogProfilePack:
+		x.ObjHash = a.mapObjHash(x.ObjHash)
+		b, ok := a.profile(x.Profile)
+		if !ok {
+			a.stats.DroppedBlocks++
+			return nil
+		}
+		x.Profile = b
+		a.stats.Profiles++
+	case *pack.PerfCounterPack:
+		x.ObjName = a.objName(x.ObjName)
+		a.stats.Counters++
+	}
+	return p
+}
+
+// ---------------------------------------------------------------------------
+// Keyed hashing
+// ---------------------------------------------------------------------------
+
+func (a *Anonymizer) mac(kind string, b []byte) []byte {
+	h := hmac.New(sha256.New, a.salt)
+	h.Write([]byte(kind))
+	h.Write([]byte{0})
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+// Token returns a short stable token for s, e.g. "host-3fa94c1e".
+func (a *Anonymizer) Token(prefix, s string) string {
+	return prefix + "-" + hex.EncodeToString(a.mac(prefix, []byte(s))[:4])
+}
+
+// IP maps an address to a stable pseudo address of the same family:
+// IPv4 into 10.0.0.0/8 and IPv6 into fd00::/8.
+func (a *Anonymizer) IP(ip []byte) []byte {
+	if len(ip) == 0 {
+		return ip
+	}
+	m := a.mac("ip", ip)
+	if v4 := net.IP(ip).To4(); v4 != nil && len(ip) == 4 {
+		return []byte{10, m[0], m[1], m[2]}
+	}
+	out := make([]byte, len(ip))
+	out[0] = 0xfd
+	copy(out[1:], m)
+	return out
+}
+
+func (a *Anonymizer) ipString(s string) string {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return a.Token("host", s)
+	}
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	return net.IP(a.IP(ip)).String()
+}
+
+// Int64 maps a non-zero id to a stable pseudo id.
+func (a *Anonymizer) Int64(kind string, v int64) int64 {
+	if v == 0 {
+		return 0
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v))
+	r := int64(binary.BigEndian.Uint64(a.mac(kind, b[:])))
+	if r == 0 {
+		r = 1
+	}
+	return r
+}
+
+// ---------------------------------------------------------------------------
+// Packs
+// ---------------------------------------------------------------------------
+
+func (a *Anonymizer) xlog(x *pack.XLogPack) {
+	x.ObjHash = a.mapObjHash(x.ObjHash)
+	x.IPAddr = a.IP(x.IPAddr)
+	x.Userid = a.Int64("userid", x.Userid)
+	x.Text1 = ScrubText(x.Text1)
+	x.Text2 = ScrubText(x.Text2)
+	x.Text3 = ScrubText(x.Text3)
+	x.Text4 = ScrubText(x.Text4)
+	x.Text5 = ScrubText(x.Text5)
+}
+
+func (a *Anonymizer) object(x *pack.ObjectPack) {
+	name := a.objName(x.ObjName)
+	newHash := util.HashString(name)
+	a.objHash[x.ObjHash] = newHash
+	x.ObjName = name
+	x.ObjHash = newHash
+	if x.Address != "" {
+		x.Address = a.ipString(x.Address)
+	}
+	// Tags are free-form and often carry host or environment names.
+	x.Tags = value.NewMapValue()
+}
+
+// objName replaces the host segment of an object name ("/host/app") with a
+// token and keeps the remaining segments, which name the application.
+func (a *Anonymizer) objName(name string) string {
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = a.Token("host", p)
+			break
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func (a *Anonymizer) mapObjHash(h int32) int32 {
+	if m, ok := a.objHash[h]; ok {
+		return m
+	}
+	// Agent never announced: derive a name-independent pseudo hash.
+	m := util.HashString(a.Token("obj", util.Hexa32ToString32(h)))
+	a.objHash[h] = m
+	return m
+}
+
+// text scrubs a text table entry according to its type.
+func (a *Anonymizer) text(div, s string) string {
+	switch div {
+	case "sql":
+		return ScrubSQL(s)
+	case "service", "apicall", "referer":
+		return ScrubURL(s)
+	case "login":
+		return a.Token("user", s)
+	case "object":
+		return a.objName(s)
+	case "error", "hmsg", "desc":
+		return ScrubText(s)
+	}
+	// method, group, ua, etc. describe code and clients, not people.
+	return s
+}
+
+// profile decodes a block of steps, scrubs literal values and re-encodes it.
+// Blocks that cannot be fully decoded are reported as not ok so they are
+// dropped rather than exported unscrubbed.
+func (a *Anonymizer) profile(block []byte) ([]byte, bool) {
+	in := protocol.NewDataInputX(block)
+	out := protocol.NewDataOutputX()
+	for in.Available() > 0 {
+		s, err := step.ReadStep(in)
+		if err != nil {
+			return nil, false
+		}
+		a.step(s)
+		step.WriteStep(out, s)
+	}
+	return out.ToByteArray(), true
+}
+
+func (a *Anonymizer) step(s step.Step) {
+	switch x := s.(type) {
+	case *step.SqlStep:
+		x.Param = ScrubParams(x.Param)
+	case *step.SqlStep2:
+		x.Param = ScrubParams(x.Param)
+	case *step.SqlStep3:
+		x.Param = ScrubParams(x.Param)
+	case *step.MessageStep:
+		x.Message = ScrubText(x.Message)
+	case *step.ParameterizedMessageStep:
+		x.ParamString = ScrubText(x.ParamString)
+	case *step.SocketStep:
+		x.IPAddr = a.IP(x.IPAddr)
+	case *step.ApiCallStep:
+		x.Address = a.address(x.Address)
+	case *step.ApiCallStep2:
+		x.Address = a.address(x.Address)
+	case *step.DispatchStep:
+		x.Address = a.address(x.Address)
+	case *step.DumpStep:
+		x.ThreadName = ScrubText(x.ThreadName)
+	case *step.SpanStep:
+		x.LocalEndpointIp = a.IP(x.LocalEndpointIp)
+		x.RemoteEndpointIp = a.IP(x.RemoteEndpointIp)
+	}
+}
+
+// address anonymizes the host of a "host:port" or URL address.
+func (a *Anonymizer) address(s string) string {
+	if s == "" {
+		return s
+	}
+	rest := ""
+	if i := strings.Index(s, "://"); i >= 0 {
+		scheme := s[:i+3]
+		hostPath := s[i+3:]
+		if j := strings.IndexByte(hostPath, '/'); j >= 0 {
+			rest = ScrubURL(hostPath[j:])
+			hostPath = hostPath[:j]
+		}
+		return scheme + a.hostPort(hostPath) + rest
+	}
+	return a.hostPort(s)
+}
+
+func (a *Anonymizer) hostPort(s string) string {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return a.ipString(s)
+	}
+	return net.JoinHostPort(a.ipString(host), port)
+}
+
+// ---------------------------------------------------------------------------
+// Literal scrubbing
+// ---------------------------------------------------------------------------
+
+var (
+	sqlString  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlNumber  = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	sqlInList  = regexp.MustCompile(`(?i)\bIN\s*\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	emailRe    = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	ipv4Re     = regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}\b`)
+	quotedRe   = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+	longNumRe  = regexp.MustCompile(`\d{3,}`)
+	uuidRe     = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	hexIDRe    = regexp.MustCompile(`(?i)^[0-9a-f]{16,}$`)
+	numSegment = regexp.MustCompile(`^\d+$`)
+)
+
+// ScrubSQL replaces string and numeric literals with '?'. Identifiers such
+// as table names containing digits are kept.
+func ScrubSQL(sql string) string {
+	sql = sqlString.ReplaceAllString(sql, "?")
+	sql = sqlNumber.ReplaceAllString(sql, "?")
+	return sqlInList.ReplaceAllString(sql, "IN (?)")
+}
+
+// ScrubParams replaces every bind value in a comma-separated SQL parameter
+// string with '?', keeping the parameter count.
+func ScrubParams(params string) string {
+	if params == "" {
+		return params
+	}
+	n := strings.Count(params, ",") + 1
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// ScrubText masks values that identify people or systems in free text:
+// quoted strings, e-mail and IP addresses, UUIDs and numbers of 3+ digits.
+func ScrubText(s string) string {
+	if s == "" {
+		return s
+	}
+	s = emailRe.ReplaceAllString(s, "<email>")
+	s = uuidRe.ReplaceAllString(s, "<uuid>")
+	s = ipv4Re.ReplaceAllString(s, "<ip>")
+	s = quotedRe.ReplaceAllString(s, "'?'")
+	return longNumRe.ReplaceAllString(s, "<n>")
+}
+
+// ScrubURL keeps the path structure of a URL, replacing numeric and id-like
+// path segments with placeholders and dropping query parameter values.
+func ScrubURL(u string) string {
+	path, query, hasQuery := strings.Cut(u, "?")
+	segs := strings.Split(path, "/")
+	for i, s := range segs {
+		switch {
+		case numSegment.MatchString(s):
+			segs[i] = "{n}"
+		case uuidRe.MatchString(s) || hexIDRe.MatchString(s):
+			segs[i] = "{id}"
+		case emailRe.MatchString(s):
+			segs[i] = "{email}"
+		}
+	}
+	path = strings.Join(segs, "/")
+	if !hasQuery {
+		return path
+	}
+	params := strings.Split(query, "&")
+	for i, p := range params {
+		if k, _, ok := strings.Cut(p, "="); ok {
+			params[i] = k + "=?"
+		}
+	}
+	return path + "?" + strings.Join(params, "&")
+}
+
+// ---------------------------------------------------------------------------
+// Sink
+// ---------------------------------------------------------------------------
+
+// Sender receives packs. It is implemented by the bench and replay sinks.
+type Sender interface {
+	Send(p pack.Pack) error
+}
+
+// Sink anonymizes packs before passing them to the next sender.
+type Sink struct {
+	a    *Anonymizer
+	next Sender
+}
+
+// NewSink wraps next with a.
+func NewSink(a *Anonymizer, next Sender) *Sink {
+	return &Sink{a: a, next: next}
+}
+
+// Send anonymizes p and forwards it unless it was dropped.
+func (s *Sink) Send(p pack.Pack) error {
+	if p = s.a.Pack(p); p == nil {
+		return nil
+	}
+	return s.next.Send(p)
+}
@@ -0,0 +1,155 @@
+package anonymize
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/step"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+func TestScrubSQL(t *testing.T) {
+	got := ScrubSQL("SELECT * FROM user2 WHERE name = 'O''Brien' AND id IN (1, 2, 3) AND age > 30.5")
+	want := "SELECT * FROM user2 WHERE name = ? AND id IN (?) AND age > ?"
+	if got != want {
+		t.Errorf("ScrubSQL = %q, want %q", got, want)
+	}
+}
+
+func TestScrubParams(t *testing.T) {
+	if got := ScrubParams("'kim',42,'a@b.com'"); got != "?,?,?" {
+		t.Errorf("ScrubParams = %q", got)
+	}
+	if got := ScrubParams(""); got != "" {
+		t.Errorf("ScrubParams(empty) = %q", got)
+	}
+}
+
+func TestScrubText(t *testing.T) {
+	got := ScrubText(`login failed for kim@example.com from 192.168.0.7, order 123456 "secret"`)
+	want := `login failed for <email> from <ip>, order <n> '?'`
+	if got != want {
+		t.Errorf("ScrubText = %q, want %q", got, want)
+	}
+}
+
+func TestScrubURL(t *testing.T) {
+	got := ScrubURL("/users/12345/orders/550e8400-e29b-41d4-a716-446655440000?token=abc&page=2")
+	want := "/users/{n}/orders/{id}?token=?&page=?"
+	if got != want {
+		t.Errorf("ScrubURL = %q, want %q", got, want)
+	}
+	if got := ScrubURL("/api/v1/orders"); got != "/api/v1/orders" {
+		t.Errorf("ScrubURL kept path = %q", got)
+	}
+}
+
+func TestKeyedHashing(t *testing.T) {
+	a := New([]byte("salt-a"))
+	b := New([]byte("salt-b"))
+
+	ip := []byte{192, 168, 0, 7}
+	got := a.IP(ip)
+	if len(got) != 4 || got[0] != 10 {
+		t.Fatalf("IP = %v, want 10.x.x.x", got)
+	}
+	if !bytes.Equal(got, a.IP(ip)) {
+		t.Error("IP is not stable for the same salt")
+	}
+	if bytes.Equal(got, b.IP(ip)) {
+		t.Error("IP does not depend on the salt")
+	}
+	if v6 := a.IP(make([]byte, 16)); len(v6) != 16 || v6[0] != 0xfd {
+		t.Errorf("IPv6 = %v, want fd00::/8", v6)
+	}
+
+	if a.Int64("userid", 0) != 0 {
+		t.Error("zero userid must stay zero")
+	}
+	if u := a.Int64("userid", 42); u == 42 || u != a.Int64("userid", 42) {
+		t.Errorf("Int64 = %d", u)
+	}
+}
+
+func TestObjectAndXLog(t *testing.T) {
+	a := New([]byte("salt"))
+	origHash := util.HashString("/prod-web01/tomcat1")
+
+	obj := a.Pack(&pack.ObjectPack{ObjName: "/prod-web01/tomcat1", ObjHash: origHash, Address: "192.168.0.7"}).(*pack.ObjectPack)
+	if strings.Contains(obj.ObjName, "prod-web01") || !strings.HasSuffix(obj.ObjName, "/tomcat1") {
+		t.Errorf("ObjName = %q", obj.ObjName)
+	}
+	if obj.ObjHash != util.HashString(obj.ObjName) {
+		t.Error("ObjHash does not match the anonymized name")
+	}
+	if !strings.HasPrefix(obj.Address, "10.") {
+		t.Errorf("Address = %q", obj.Address)
+	}
+
+	xp := a.Pack(&pack.XLogPack{
+		ObjHash: origHash,
+		IPAddr:  []byte{192, 168, 0, 7},
+		Userid:  99,
+		Text1:   "card 4111111111111111",
+		Elapsed: 123,
+		EndTime: 1000,
+	}).(*pack.XLogPack)
+	if xp.ObjHash != obj.ObjHash {
+		t.Errorf("xlog objHash = %d, want %d", xp.ObjHash, obj.ObjHash)
+	}
+	if xp.IPAddr[0] != 10 || xp.Userid == 99 || xp.Text1 != "card <n>" {
+		t.Errorf("xlog = %+v", xp)
+	}
+	if xp.Elapsed != 123 || xp.EndTime != 1000 {
+		t.Error("timing must be preserved")
+	}
+
+	// The text table entry for the object name is rewritten the same way.
+	tp := a.Pack(&pack.TextPack{XType: "object", Hash: origHash, Text: "/prod-web01/tomcat1"}).(*pack.TextPack)
+	if tp.Text != obj.ObjName {
+		t.Errorf("object text = %q, want %q", tp.Text, obj.ObjName)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	a := New([]byte("salt"))
+
+	o := protocol.NewDataOutputX()
+	step.WriteStep(o, &step.MethodStep{StepSingle: step.StepSingle{Parent: -1}, Hash: 1, Elapsed: 5})
+	step.WriteStep(o, &step.SqlStep{StepSingle: step.StepSingle{Parent: -1, Index: 1}, Hash: 2, Param: "'kim',42"})
+	step.WriteStep(o, &step.MessageStep{StepSingle: step.StepSingle{Parent: -1, Index: 2}, Message: "user kim@example.com"})
+
+	p := a.Pack(&pack.XLogProfilePack{Txid: 1, Profile: o.ToByteArray()}).(*pack.XLogProfilePack)
+
+	in := protocol.NewDataInputX(p.Profile)
+	var steps []step.Step
+	for in.Available() > 0 {
+		s, err := step.ReadStep(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		steps = append(steps, s)
+	}
+	if len(steps) != 3 {
+		t.Fatalf("steps = %d, want 3", len(steps))
+	}
+	if m := steps[0].(*step.MethodStep); m.Hash != 1 || m.Elapsed != 5 {
+		t.Errorf("method step = %+v", m)
+	}
+	if s := steps[1].(*step.SqlStep); s.Param != "?,?" || s.Hash != 2 {
+		t.Errorf("sql step = %+v", s)
+	}
+	if m := steps[2].(*step.MessageStep); m.Message != "user <email>" {
+		t.Errorf("message = %q", m.Message)
+	}
+
+	if a.Pack(&pack.XLogProfilePack{Txid: 2, Profile: []byte{0xff, 1, 2}}) != nil {
+		t.Error("undecodable block must be dropped")
+	}
+	if st := a.Stats(); st.Profiles != 1 || st.DroppedBlocks != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+}
@@ -0,0 +1,173 @@
+// Package apitoken issues and verifies the bearer tokens of the HTTP API.
+//
+// A token belongs to an account and acts with its rights. Only the SHA-256
+// of a token is stored, in a KV store of its own that the KV commands cannot
+// reach, so the secret is shown once when it is issued and cannot be
+// recovered afterwards; a lost token is revoked and a new one issued.
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/db/kv"
+)
+
+const (
+	// kvKey is the KV store key holding all tokens as one JSON object.
+	kvKey = "api_tokens"
+	// Prefix starts every token, so leaked ones are easy to search for.
+	Prefix = "sct_"
+	// idLen is the length of the public token ID, the first characters of
+	// the secret after Prefix.
+	idLen = 8
+)
+
+// Token describes an issued token.
+type Token struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Account string `json:"account"`
+	// ReadOnly tokens may only send GET and HEAD requests.
+	ReadOnly bool   `json:"readOnly,omitempty"`
+	Hash     string `json:"hash"`              // hex SHA-256 of the secret
+	Created  int64  `json:"created"`           // ms
+	Expires  int64  `json:"expires,omitempty"` // ms, 0 for never
+}
+
+// Expired reports whether t has expired at now.
+func (t *Token) Expired(now time.Time) bool {
+	return t.Expires != 0 && now.UnixMilli() >= t.Expires
+}
+
+// TTL returns the lifetime of a token asked to last days days, the longest
+// allowed if days is nil, checking it against
+// net_http_api_token_max_ttl_days. 0 means never expiring.
+func TTL(days *int) (time.Duration, error) {
+	maxDays := 365
+	if cfg := config.Get(); cfg != nil {
+		maxDays = cfg.NetHTTPApiTokenMaxTTLDays()
+	}
+	n := maxDays
+	if days != nil {
+		n = *days
+	}
+	switch {
+	case n < 0:
+		return 0, errors.New("ttlDays must not be negative")
+	case maxDays > 0 && (n == 0 || n > maxDays):
+		return 0, fmt.Errorf("ttlDays must be between 1 and %d", maxDays)
+	}
+	return time.Duration(n) * 24 * time.Hour, nil
+}
+
+// Store keeps the tokens in a KV store.
+type Store struct {
+	tokens *kv.JSONMap[Token] // keyed by ID
+}
+
+// NewStore creates a Store backed by store.
+func NewStore(store *kv.KVStore) *Store {
+	return &Store{tokens: kv.NewJSONMap[Token](store, kvKey, nil)}
+}
+
+// Issue creates a token named name for account, expiring after ttl (never
+// if ttl is 0), and returns its secret. Expired tokens are dropped on the
+// way.
+func (s *Store) Issue(name, account string, ttl time.Duration, readOnly bool, now time.Time) (string, Token, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return "", Token{}, errors.New("token name is empty")
+	case account == "":
+		return "", Token{}, errors.New("account is empty")
+	case ttl < 0:
+		return "", Token{}, errors.New("negative ttl")
+	}
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", Token{}, err
+	}
+	secret := Prefix + hex.EncodeToString(b)
+	t := Token{
+		ID:       secret[len(Prefix) : len(Prefix)+idLen],
+		Name:     name,
+		Account:  account,
+		ReadOnly: readOnly,
+		Hash:     hashOf(secret),
+		Created:  now.UnixMilli(),
+	}
+	if ttl > 0 {
+		t.Expires = now.Add(ttl).UnixMilli()
+	}
+
+	err := s.tokens.Update(func(tokens map[string]Token) error {
+		for id, old := range tokens {
+			if old.Expired(now) {
+				delete(tokens, id)
+			}
+		}
+		if _, ok := tokens[t.ID]; ok {
+			return errors.New("token ID collision, try again")
+		}
+		tokens[t.ID] = t
+		return nil
+	})
+	if err != nil {
+		return "", Token{}, err
+	}
+	return secret, t, nil
+}
+
+// Get returns the token with id.
+func (s *Store) Get(id string) (Token, bool) {
+	return s.tokens.Get(id)
+}
+
+// Revoke removes the token with id and reports whether it existed.
+func (s *Store) Revoke(id string) (bool, error) {
+	return s.tokens.Delete(id)
+}
+
+// List returns the tokens of account, or all of them if account is empty,
+// oldest first.
+func (s *Store) List(account string) []Token {
+	var result []Token
+	for _, t := range s.tokens.Values() {
+		if account == "" || t.Account == account {
+			result = append(result, t)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Created != result[j].Created {
+			return result[i].Created < result[j].Created
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// Verify returns the token whose secret is secret, if it has not expired.
+func (s *Store) Verify(secret string, now time.Time) (Token, bool) {
+	if !strings.HasPrefix(secret, Prefix) || len(secret) < len(Prefix)+idLen {
+		return Token{}, false
+	}
+	t, ok := s.tokens.Get(secret[len(Prefix) : len(Prefix)+idLen])
+	if !ok || subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hashOf(secret))) != 1 || t.Expired(now) {
+		return Token{}, false
+	}
+	return t, true
+}
+
+func hashOf(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
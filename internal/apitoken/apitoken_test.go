@@ -0,0 +1,65 @@
+package apitoken
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/kv"
+)
+
+func TestStore(t *testing.T) {
+	kvs := kv.NewKVStore(t.TempDir(), "global.json")
+	s := NewStore(kvs)
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	secret, tok, err := s.Issue("ci", "deployer", time.Hour, true, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, Prefix) || !strings.HasPrefix(secret[len(Prefix):], tok.ID) || strings.Contains(tok.Hash, secret) {
+		t.Errorf("unexpected secret %q for %+v", secret, tok)
+	}
+	if got, ok := s.Verify(secret, now); !ok || got.Account != "deployer" || !got.ReadOnly {
+		t.Errorf("Verify = %+v, %v", got, ok)
+	}
+	if _, ok := s.Verify(secret[:len(secret)-1]+"x", now); ok {
+		t.Error("a wrong secret with the same ID verified")
+	}
+	if _, ok := s.Verify(secret, now.Add(time.Hour)); ok {
+		t.Error("an expired token verified")
+	}
+
+	// Tokens are kept in the KV store.
+	other, _, _ := s.Issue("grafana", "viewer", 0, false, now.Add(2*time.Hour))
+	s2 := NewStore(kvs)
+	if len(s2.List("")) != 1 {
+		t.Errorf("expired token not dropped on issue: %+v", s2.List(""))
+	}
+	if got := s2.List("viewer"); len(got) != 1 || got[0].Name != "grafana" || got[0].Expires != 0 {
+		t.Errorf("List(viewer) = %+v", got)
+	}
+	if _, ok := s2.Verify(other, now.AddDate(10, 0, 0)); !ok {
+		t.Error("a token without expiry did not verify")
+	}
+
+	for _, c := range []struct {
+		name, account string
+		ttl           time.Duration
+	}{{"", "a", 0}, {"x", "", 0}, {"x", "a", -time.Second}} {
+		if _, _, err := s.Issue(c.name, c.account, c.ttl, false, now); err == nil {
+			t.Errorf("Issue(%q, %q, %v) succeeded", c.name, c.account, c.ttl)
+		}
+	}
+
+	id := s.List("viewer")[0].ID
+	if found, err := s.Revoke(id); !found || err != nil {
+		t.Fatalf("Revoke = %v, %v", found, err)
+	}
+	if _, ok := s.Verify(other, now); ok {
+		t.Error("a revoked token verified")
+	}
+	if found, _ := s.Revoke(id); found {
+		t.Error("revoked twice")
+	}
+}
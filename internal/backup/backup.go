@@ -0,0 +1,395 @@
+// Package backup uploads the previous days of the data directory to an S3 or
+// S3-compatible bucket as export archives, and restores them from there.
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/db"
+)
+
+const (
+	// stateDir holds the record of the uploaded days and the archives
+	// being uploaded, below the data directory.
+	stateDir = ".backup"
+	// missRetry is how long a day found missing in the bucket is not asked
+	// for again.
+	missRetry = 10 * time.Minute
+	// restoreTimeout bounds the download of a day a read is waiting for.
+	restoreTimeout = 10 * time.Minute
+)
+
+// uploaded records what a day looked like when it was uploaded.
+type uploaded struct {
+	Fingerprint string `json:"fingerprint"`
+	Bytes       int64  `json:"bytes"`
+	Time        int64  `json:"time"` // ms
+}
+
+// Scheduler periodically uploads every previous day of the data directory
+// that was not uploaded yet, or changed since, e.g. by late writes or a
+// purge, and deletes archives older than backup_keep_days from the bucket.
+// The backup_* settings are read on every run.
+type Scheduler struct {
+	dataDir  string
+	serverID string
+	version  string
+	closeDay func(date string) error
+
+	runMu sync.Mutex // serializes runs
+	mu    sync.Mutex // guards state
+	state map[string]uploaded
+
+	fetchMu  sync.Mutex
+	fetching map[string]chan struct{}
+	missed   map[string]time.Time
+
+	now func() time.Time
+}
+
+// NewScheduler creates a scheduler for dataDir. serverID and version are
+// recorded in the archives' manifests.
+func NewScheduler(dataDir, serverID, version string) *Scheduler {
+	s := &Scheduler{
+		dataDir:  dataDir,
+		serverID: serverID,
+		version:  version,
+		state:    make(map[string]uploaded),
+		fetching: make(map[string]chan struct{}),
+		missed:   make(map[string]time.Time),
+		now:      time.Now,
+	}
+	s.loadState()
+	return s
+}
+
+// SetDayCloser sets the function that flushes and closes the open
+// containers of a day before it is uploaded.
+func (s *Scheduler) SetDayCloser(fn func(date string) error) {
+	s.closeDay = fn
+}
+
+// Start runs a backup immediately and then every backup_interval_minutes.
+func (s *Scheduler) Start(ctx context.Context) {
+	go func() {
+		for {
+			if _, err := s.Run(ctx); err != nil && ctx.Err() == nil {
+				slog.Warn("Backup failed", "error", err)
+			}
+			interval := time.Hour
+			if cfg := config.Get(); cfg != nil && cfg.BackupIntervalMinutes() > 0 {
+				interval = time.Duration(cfg.BackupIntervalMinutes()) * time.Minute
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// Run uploads the previous days not uploaded in their current state and
+// applies backup_keep_days, returning the uploaded dates. A day that fails
+// is logged and retried on the next run.
+func (s *Scheduler) Run(ctx context.Context) ([]string, error) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	cfg := config.Get()
+	if cfg == nil {
+		return nil, nil
+	}
+	client, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	types, err := db.ParseExportTypes(cfg.BackupTypes())
+	if err != nil {
+		return nil, err
+	}
+	dates, err := db.GetDateDirs(s.dataDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	today := s.now().Format("20060102")
+	var done []string
+	for _, date := range dates {
+		if date >= today {
+			continue
+		}
+		if ctx.Err() != nil {
+			return done, ctx.Err()
+		}
+		fp := fingerprint(filepath.Join(s.dataDir, date))
+		if s.uploadedAs(date) == fp {
+			continue
+		}
+		if s.closeDay != nil {
+			if err := s.closeDay(date); err != nil {
+				slog.Warn("Backup: closing day failed", "date", date, "error", err)
+			}
+			fp = fingerprint(filepath.Join(s.dataDir, date))
+		}
+		n, err := s.upload(ctx, client, cfg, date, types)
+		if err != nil {
+			slog.Warn("Backup: upload failed", "date", date, "error", err)
+			continue
+		}
+		s.record(date, fp, n)
+		done = append(done, date)
+		slog.Info("Backup: day uploaded", "date", date, "bytes", n, "key", objectKey(cfg, date))
+	}
+
+	if keep := cfg.BackupKeepDays(); keep > 0 {
+		if err := s.expire(ctx, client, cfg, s.now().AddDate(0, 0, -keep).Format("20060102")); err != nil {
+			return done, err
+		}
+	}
+	return done, nil
+}
+
+// upload exports date to a temporary archive and uploads it, returning its
+// size.
+func (s *Scheduler) upload(ctx context.Context, client *Client, cfg *config.Config, date string, types []string) (int64, error) {
+	dir := filepath.Join(s.dataDir, stateDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+	f, err := os.CreateTemp(dir, date+"-*.tar.zst")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// The permanent texts are not a day's; they stay with the server.
+	opts := db.ExportOptions{Types: types, ServerID: s.serverID, ServerVersion: s.version}
+	if _, err := db.ExportDay(s.dataDir, date, opts, f); err != nil {
+		return 0, err
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return size, client.Put(ctx, objectKey(cfg, date), f, size, cfg.BackupStorageClass())
+}
+
+// expire deletes the archives of days before cutoff from the bucket.
+func (s *Scheduler) expire(ctx context.Context, client *Client, cfg *config.Config, cutoff string) error {
+	prefix := cfg.BackupS3Prefix()
+	objects, err := client.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	for _, o := range objects {
+		date, ok := strings.CutSuffix(strings.TrimPrefix(o.Key, prefix), ".tar.zst")
+		if !ok || len(date) != 8 || date >= cutoff {
+			continue
+		}
+		if _, err := time.Parse("20060102", date); err != nil {
+			continue
+		}
+		if err := client.Delete(ctx, o.Key); err != nil {
+			return err
+		}
+		slog.Info("Backup: expired archive deleted", "key", o.Key)
+	}
+	return nil
+}
+
+// Restore downloads the archive of date from the bucket and imports it.
+// Existing files of the day are kept unless overwrite is set.
+func (s *Scheduler) Restore(ctx context.Context, date string, overwrite bool) (*db.ImportResult, error) {
+	cfg := config.Get()
+	if cfg == nil {
+		return nil, errors.New("no configuration")
+	}
+	client, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	body, err := client.Get(ctx, objectKey(cfg, date))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	res, err := db.ImportArchive(s.dataDir, body, db.ImportOptions{Overwrite: overwrite})
+	if err != nil {
+		return nil, err
+	}
+	// The day is in the bucket as it is now; do not upload it again.
+	s.record(date, fingerprint(filepath.Join(s.dataDir, date)), res.Bytes)
+	return res, nil
+}
+
+// FetchMissingDay restores a previous day a read found missing, if
+// backup_restore_on_miss is set. Reads of the same day wait for one
+// download; a day missing in the bucket is not asked for again for a while.
+// It reports whether the day is present afterwards.
+func (s *Scheduler) FetchMissingDay(date string) bool {
+	cfg := config.Get()
+	if cfg == nil || !cfg.BackupRestoreOnMiss() || date >= s.now().Format("20060102") {
+		return false
+	}
+	if _, err := time.Parse("20060102", date); err != nil {
+		return false
+	}
+	dayDir := filepath.Join(s.dataDir, date)
+
+	s.fetchMu.Lock()
+	if until, ok := s.missed[date]; ok && s.now().Before(until) {
+		s.fetchMu.Unlock()
+		return false
+	}
+	if wait, ok := s.fetching[date]; ok {
+		s.fetchMu.Unlock()
+		<-wait
+		_, err := os.Stat(dayDir)
+		return err == nil
+	}
+	if _, err := os.Stat(dayDir); err == nil {
+		s.fetchMu.Unlock()
+		return true
+	}
+	done := make(chan struct{})
+	s.fetching[date] = done
+	s.fetchMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+	res, err := s.Restore(ctx, date, false)
+	cancel()
+
+	s.fetchMu.Lock()
+	delete(s.fetching, date)
+	if err != nil {
+		s.missed[date] = s.now().Add(missRetry)
+	}
+	s.fetchMu.Unlock()
+	close(done)
+
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			slog.Debug("Backup: day not in the bucket", "date", date)
+		} else {
+			slog.Warn("Backup: restore failed", "date", date, "error", err)
+		}
+		return false
+	}
+	slog.Info("Backup: day restored on read", "date", date, "files", res.Files, "bytes", res.Bytes)
+	return true
+}
+
+// Uploaded returns the dates uploaded so far, oldest first.
+func (s *Scheduler) Uploaded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dates := make([]string, 0, len(s.state))
+	for date := range s.state {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// uploadedAs returns the fingerprint date had when it was last uploaded.
+func (s *Scheduler) uploadedAs(date string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state[date].Fingerprint
+}
+
+func (s *Scheduler) record(date, fp string, bytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state[date] = uploaded{Fingerprint: fp, Bytes: bytes, Time: s.now().UnixMilli()}
+	s.saveState()
+}
+
+func (s *Scheduler) statePath() string {
+	return filepath.Join(s.dataDir, stateDir, "state.json")
+}
+
+func (s *Scheduler) loadState() {
+	data, err := os.ReadFile(s.statePath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		slog.Warn("Backup: ignoring unreadable state, every day is uploaded again", "file", s.statePath(), "error", err)
+		s.state = make(map[string]uploaded)
+	}
+}
+
+func (s *Scheduler) saveState() {
+	if err := os.MkdirAll(filepath.Dir(s.statePath()), 0755); err != nil {
+		slog.Warn("Backup: saving state failed", "error", err)
+		return
+	}
+	data, _ := json.MarshalIndent(s.state, "", "  ")
+	tmp := s.statePath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		slog.Warn("Backup: saving state failed", "error", err)
+		return
+	}
+	if err := os.Rename(tmp, s.statePath()); err != nil {
+		slog.Warn("Backup: saving state failed", "error", err)
+	}
+}
+
+// fingerprint summarizes the files of dir, changing whenever one is added,
+// removed or written.
+func fingerprint(dir string) string {
+	var files, bytes, latest int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files++
+		bytes += info.Size()
+		latest = max(latest, info.ModTime().UnixNano())
+		return nil
+	})
+	return fmt.Sprintf("%d/%d/%d", files, bytes, latest)
+}
+
+func objectKey(cfg *config.Config, date string) string {
+	return cfg.BackupS3Prefix() + date + ".tar.zst"
+}
+
+func newClient(cfg *config.Config) (*Client, error) {
+	if cfg.BackupS3Endpoint() == "" || cfg.BackupS3Bucket() == "" {
+		return nil, errors.New("backup_s3_endpoint and backup_s3_bucket are not set")
+	}
+	return &Client{
+		Endpoint:  cfg.BackupS3Endpoint(),
+		Region:    cfg.BackupS3Region(),
+		Bucket:    cfg.BackupS3Bucket(),
+		AccessKey: cfg.BackupS3AccessKey(),
+		SecretKey: cfg.BackupS3SecretKey(),
+		PathStyle: cfg.BackupS3PathStyle(),
+		HTTP:      &http.Client{Timeout: 30 * time.Minute},
+	}, nil
+}
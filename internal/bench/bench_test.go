@@ -0,0 +1,107 @@
+package bench
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+type countingSink struct {
+	byType map[byte]int
+}
+
+func (s *countingSink) Send(p pack.Pack) error {
+	// Round-trip through the wire format to make sure generated packs decode.
+	d := protocol.NewDataInputX(encodeCafe(p))
+	if _, err := d.ReadInt32(); err != nil {
+		return err
+	}
+	if _, err := pack.ReadPack(d); err != nil {
+		return err
+	}
+	s.byType[p.PackType()]++
+	return nil
+}
+
+func (s *countingSink) Close() error { return nil }
+
+func TestGenerator_XLogProfileRatio(t *testing.T) {
+	g := NewGenerator(1, 5, 10, 1.0, 0)
+	xp, prof := g.XLog(time.Now().UnixMilli())
+	if prof == nil {
+		t.Fatal("expected profile with profileRatio=1")
+	}
+	if prof.Txid != xp.Txid || prof.ObjHash != xp.ObjHash {
+		t.Errorf("profile does not match xlog: txid %d/%d objHash %d/%d", prof.Txid, xp.Txid, prof.ObjHash, xp.ObjHash)
+	}
+	if xp.ProfileSize != int32(len(prof.Profile)) {
+		t.Errorf("ProfileSize=%d, want %d", xp.ProfileSize, len(prof.Profile))
+	}
+
+	g = NewGenerator(1, 5, 10, 0, 0)
+	for i := 0; i < 100; i++ {
+		if _, prof := g.XLog(time.Now().UnixMilli()); prof != nil {
+			t.Fatal("expected no profile with profileRatio=0")
+		}
+	}
+}
+
+func TestGenerator_TextsCoverObjects(t *testing.T) {
+	g := NewGenerator(1, 7, 3, 0, 0)
+	objects := 0
+	for _, p := range g.Texts() {
+		if p.(*pack.TextPack).XType == "object" {
+			objects++
+		}
+	}
+	if objects != 7 {
+		t.Errorf("expected 7 object texts, got %d", objects)
+	}
+	if n := len(g.Objects(0)); n != 7 {
+		t.Errorf("expected 7 object packs, got %d", n)
+	}
+	if n := len(g.Counters(0)); n != 7 {
+		t.Errorf("expected 7 counter packs, got %d", n)
+	}
+}
+
+func TestRun_ReachesTargetRate(t *testing.T) {
+	sink := &countingSink{byType: make(map[byte]int)}
+	rep := Run(context.Background(), Config{
+		TPS:          500,
+		Objects:      3,
+		Services:     5,
+		Duration:     300 * time.Millisecond,
+		ProfileRatio: 0.5,
+		Seed:         42,
+	}, sink, nil)
+
+	if rep.SendErrors != 0 {
+		t.Fatalf("unexpected send errors: %d", rep.SendErrors)
+	}
+	if rep.XLogs < 100 {
+		t.Errorf("expected at least 100 xlogs at 500 tps over 300ms, got %d", rep.XLogs)
+	}
+	if int64(sink.byType[pack.PackTypeXLog]) != rep.XLogs {
+		t.Errorf("sink saw %d xlogs, report says %d", sink.byType[pack.PackTypeXLog], rep.XLogs)
+	}
+	if int64(sink.byType[pack.PackTypeXLogProfile]) != rep.Profiles {
+		t.Errorf("sink saw %d profiles, report says %d", sink.byType[pack.PackTypeXLogProfile], rep.Profiles)
+	}
+	if rep.Objects != 3 || rep.Counters != 3 {
+		t.Errorf("expected one heartbeat round (3 objects/3 counters), got %d/%d", rep.Objects, rep.Counters)
+	}
+}
+
+func TestRun_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sink := &countingSink{byType: make(map[byte]int)}
+	rep := Run(ctx, Config{TPS: 1000, Objects: 1, Duration: time.Hour}, sink, nil)
+	if rep.Elapsed > time.Second {
+		t.Errorf("expected immediate stop, ran for %s", rep.Elapsed)
+	}
+}
@@ -0,0 +1,211 @@
+// Package bench synthesizes realistic agent traffic (objects, texts, XLogs,
+// profiles and counters) so collector sizing can be validated before production.
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/step"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// benchObject is a synthetic agent.
+type benchObject struct {
+	objType string
+	objName string
+	objHash int32
+	address string
+}
+
+// Generator builds synthetic packs that look like traffic from Java agents.
+// It is not safe for concurrent use.
+type Generator struct {
+	rnd          *rand.Rand
+	objects      []benchObject
+	services     []string
+	sqls         []string
+	methods      []string
+	profileRatio float64
+	errorRatio   float64
+	txid         int64
+}
+
+// NewGenerator creates a generator for the given number of objects and services.
+// profileRatio and errorRatio are fractions (0..1) of XLogs that carry a
+// profile or an error, respectively.
+func NewGenerator(seed int64, objects, services int, profileRatio, errorRatio float64) *Generator {
+	if objects <= 0 {
+		objects = 1
+	}
+	if services <= 0 {
+		services = 1
+	}
+	g := &Generator{
+		rnd:          rand.New(rand.NewSource(seed)),
+		profileRatio: profileRatio,
+		errorRatio:   errorRatio,
+		txid:         seed << 32,
+	}
+	for i := 0; i < objects; i++ {
+		name := fmt.Sprintf("/bench-host%03d/tomcat%d", i/4, i%4)
+		g.objects = append(g.objects, benchObject{
+			objType: "tomcat",
+			objName: name,
+			objHash: util.HashString(name),
+			address: fmt.Sprintf("10.0.%d.%d", i/250, i%250+1),
+		})
+	}
+	for i := 0; i < services; i++ {
+		g.services = append(g.services, fmt.Sprintf("/api/bench/v1/resource%d/{id}", i))
+	}
+	for i := 0; i < 20; i++ {
+		g.sqls = append(g.sqls, fmt.Sprintf("SELECT id, name, status FROM bench_table%d WHERE id = ?", i))
+	}
+	for i := 0; i < 20; i++ {
+		g.methods = append(g.methods, fmt.Sprintf("com.example.bench.Service%d.handle()", i))
+	}
+	return g
+}
+
+// Texts returns TextPacks for every service, SQL and method name the generator
+// references, so the collector can resolve hashes in stored XLogs and profiles.
+func (g *Generator) Texts() []pack.Pack {
+	var out []pack.Pack
+	for _, s := range g.services {
+		out = append(out, &pack.TextPack{XType: "service", Hash: util.HashString(s), Text: s})
+	}
+	for _, s := range g.sqls {
+		out = append(out, &pack.TextPack{XType: "sql", Hash: util.HashString(s), Text: s})
+	}
+	for _, s := range g.methods {
+		out = append(out, &pack.TextPack{XType: "method", Hash: util.HashString(s), Text: s})
+	}
+	for _, o := range g.objects {
+		out = append(out, &pack.TextPack{XType: "object", Hash: o.objHash, Text: o.objName})
+	}
+	return out
+}
+
+// Objects returns an ObjectPack heartbeat for every synthetic agent.
+func (g *Generator) Objects(now int64) []pack.Pack {
+	out := make([]pack.Pack, 0, len(g.objects))
+	for _, o := range g.objects {
+		out = append(out, &pack.ObjectPack{
+			ObjType: o.objType,
+			ObjHash: o.objHash,
+			ObjName: o.objName,
+			Address: o.address,
+			Version: "bench",
+			Alive:   true,
+			Wakeup:  now,
+			Tags:    value.NewMapValue(),
+		})
+	}
+	return out
+}
+
+// Counters returns a realtime PerfCounterPack for every synthetic agent.
+func (g *Generator) Counters(now int64) []pack.Pack {
+	out := make([]pack.Pack, 0, len(g.objects))
+	for _, o := range g.objects {
+		m := value.NewMapValue()
+		m.Put("TPS", &value.FloatValue{Value: float32(g.rnd.Intn(200))})
+		m.Put("ElapsedTime", value.NewDecimalValue(int64(50+g.rnd.Intn(500))))
+		m.Put("ErrorRate", &value.FloatValue{Value: g.rnd.Float32() * 2})
+		m.Put("ActiveService", value.NewDecimalValue(int64(g.rnd.Intn(30))))
+		m.Put("HeapUsed", &value.FloatValue{Value: float32(256 + g.rnd.Intn(768))})
+		m.Put("HeapTotal", &value.FloatValue{Value: 1024})
+		m.Put("ProcCpu", &value.FloatValue{Value: g.rnd.Float32() * 100})
+		m.Put("GcCount", value.NewDecimalValue(int64(g.rnd.Intn(5))))
+		m.Put("GcTime", value.NewDecimalValue(int64(g.rnd.Intn(100))))
+		out = append(out, &pack.PerfCounterPack{
+			Time:     now,
+			ObjName:  o.objName,
+			TimeType: cache.TimeTypeRealtime,
+			Data:     m,
+		})
+	}
+	return out
+}
+
+// XLog returns one synthetic transaction and, for a profileRatio share of
+// transactions, its profile. The profile is nil when not sampled.
+func (g *Generator) XLog(now int64) (*pack.XLogPack, *pack.XLogProfilePack) {
+	o := g.objects[g.rnd.Intn(len(g.objects))]
+	service := g.services[g.rnd.Intn(len(g.services))]
+	g.txid++
+
+	// Long-tailed elapsed distribution: most fast, a few slow.
+	elapsed := int32(5 + g.rnd.ExpFloat64()*120)
+	xp := &pack.XLogPack{
+		EndTime:      now,
+		ObjHash:      o.objHash,
+		Service:      util.HashString(service),
+		Txid:         g.txid,
+		Elapsed:      elapsed,
+		Cpu:          elapsed / 3,
+		SqlCount:     int32(g.rnd.Intn(8)),
+		SqlTime:      elapsed / 4,
+		IPAddr:       []byte{10, byte(g.rnd.Intn(256)), byte(g.rnd.Intn(256)), byte(1 + g.rnd.Intn(254))},
+		Kbytes:       int32(g.rnd.Intn(2048)),
+		Status:       200,
+		Userid:       g.rnd.Int63n(100000),
+		ApicallCount: int32(g.rnd.Intn(3)),
+		XType:        pack.XLogTypeWebService,
+	}
+	if g.rnd.Float64() < g.errorRatio {
+		xp.Error = util.HashString("java.lang.RuntimeException")
+		xp.Status = 500
+	}
+	if g.rnd.Float64() >= g.profileRatio {
+		return xp, nil
+	}
+
+	steps := g.profileSteps(elapsed)
+	xp.ProfileCount = int32(len(steps))
+	o2 := protocol.NewDataOutputX()
+	for _, s := range steps {
+		step.WriteStep(o2, s)
+	}
+	prof := o2.ToByteArray()
+	xp.ProfileSize = int32(len(prof))
+	return xp, &pack.XLogProfilePack{
+		Time:    now,
+		ObjHash: o.objHash,
+		Service: xp.Service,
+		Txid:    xp.Txid,
+		Profile: prof,
+	}
+}
+
+// profileSteps builds a flat profile of alternating method and SQL steps.
+func (g *Generator) profileSteps(elapsed int32) []step.Step {
+	n := 10 + g.rnd.Intn(30)
+	steps := make([]step.Step, 0, n)
+	per := elapsed / int32(n)
+	for i := 0; i < n; i++ {
+		single := step.StepSingle{Parent: -1, Index: int32(i), StartTime: per * int32(i)}
+		if i%3 == 2 {
+			sql := g.sqls[g.rnd.Intn(len(g.sqls))]
+			steps = append(steps, &step.SqlStep{
+				StepSingle: single,
+				Hash:       util.HashString(sql),
+				Elapsed:    per,
+				Param:      fmt.Sprintf("%d", g.rnd.Intn(100000)),
+			})
+			continue
+		}
+		method := g.methods[g.rnd.Intn(len(g.methods))]
+		steps = append(steps, &step.MethodStep{
+			StepSingle: single,
+			Hash:       util.HashString(method),
+			Elapsed:    per,
+		})
+	}
+	return steps
+}
@@ -0,0 +1,123 @@
+package bench
+
+import (
+	"context"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// Config controls a load generation run.
+type Config struct {
+	TPS          int           // target XLogs per second
+	Objects      int           // number of synthetic agents
+	Services     int           // number of distinct service names
+	Duration     time.Duration // run length
+	ProfileRatio float64       // fraction of XLogs with a profile
+	ErrorRatio   float64       // fraction of XLogs marked as errors
+	Seed         int64
+}
+
+// heartbeatInterval matches the interval Java agents send objects and realtime counters.
+const heartbeatInterval = 2 * time.Second
+
+// tickInterval is the pacing granularity for XLog emission.
+const tickInterval = 10 * time.Millisecond
+
+// Report summarizes a finished run.
+type Report struct {
+	Elapsed    time.Duration
+	XLogs      int64
+	Profiles   int64
+	Counters   int64
+	Objects    int64
+	Texts      int64
+	SendErrors int64
+}
+
+// AchievedTPS returns the XLog rate actually sent.
+func (r Report) AchievedTPS() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.XLogs) / r.Elapsed.Seconds()
+}
+
+// Run generates traffic into sink until cfg.Duration elapses or ctx is cancelled.
+// If progress is non-nil it is called about once per second with the running totals.
+func Run(ctx context.Context, cfg Config, sink Sink, progress func(Report)) Report {
+	if cfg.TPS <= 0 {
+		cfg.TPS = 1
+	}
+	if cfg.Services <= 0 {
+		cfg.Services = 100
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	gen := NewGenerator(cfg.Seed, cfg.Objects, cfg.Services, cfg.ProfileRatio, cfg.ErrorRatio)
+
+	var rep Report
+	send := func(p pack.Pack, n *int64) {
+		if err := sink.Send(p); err != nil {
+			rep.SendErrors++
+		}
+		*n++
+	}
+	emit := func(packs []pack.Pack, n *int64) {
+		for _, p := range packs {
+			send(p, n)
+		}
+	}
+
+	start := time.Now()
+	now := start.UnixMilli()
+	emit(gen.Texts(), &rep.Texts)
+	emit(gen.Objects(now), &rep.Objects)
+	emit(gen.Counters(now), &rep.Counters)
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	lastHeartbeat := start
+	lastProgress := start
+	deadline := start.Add(cfg.Duration)
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case t := <-ticker.C:
+			if cfg.Duration > 0 && t.After(deadline) {
+				break loop
+			}
+			now := t.UnixMilli()
+
+			// Catch up to the target rate based on wall-clock time, so a slow
+			// tick doesn't permanently lower the achieved throughput.
+			want := int64(t.Sub(start).Seconds() * float64(cfg.TPS))
+			for rep.XLogs < want {
+				xp, prof := gen.XLog(now)
+				send(xp, &rep.XLogs)
+				if prof != nil {
+					send(prof, &rep.Profiles)
+				}
+			}
+
+			if t.Sub(lastHeartbeat) >= heartbeatInterval {
+				lastHeartbeat = t
+				emit(gen.Objects(now), &rep.Objects)
+				emit(gen.Counters(now), &rep.Counters)
+			}
+
+			if progress != nil && t.Sub(lastProgress) >= time.Second {
+				lastProgress = t
+				rep.Elapsed = t.Sub(start)
+				progress(rep)
+			}
+		}
+	}
+
+	rep.Elapsed = time.Since(start)
+	return rep
+}
@@ -0,0 +1,70 @@
+package bench
+
+import (
+	"net"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// Sink receives synthesized packs.
+type Sink interface {
+	Send(p pack.Pack) error
+	Close() error
+}
+
+// encodeCafe wraps a single pack in a CAFE UDP frame.
+func encodeCafe(p pack.Pack) []byte {
+	o := protocol.NewDataOutputX()
+	o.WriteInt32(protocol.UDP_CAFE)
+	pack.WritePack(o, p)
+	return o.ToByteArray()
+}
+
+// UDPSink sends each pack as a CAFE datagram to a remote collector.
+type UDPSink struct {
+	conn net.Conn
+}
+
+// NewUDPSink dials the collector's UDP address (host:port).
+func NewUDPSink(addr string) (*UDPSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &UDPSink{conn: conn}, nil
+}
+
+func (s *UDPSink) Send(p pack.Pack) error {
+	_, err := s.conn.Write(encodeCafe(p))
+	return err
+}
+
+func (s *UDPSink) Close() error {
+	return s.conn.Close()
+}
+
+// FuncSink hands CAFE frames to an in-process receiver, such as
+// udp.NetDataProcessor.Add, so the full parse/dispatch path is exercised
+// without a socket.
+type FuncSink struct {
+	fn   func(data []byte, addr *net.UDPAddr)
+	addr *net.UDPAddr
+}
+
+// NewFuncSink creates a sink that calls fn with each encoded frame.
+func NewFuncSink(fn func(data []byte, addr *net.UDPAddr)) *FuncSink {
+	return &FuncSink{
+		fn:   fn,
+		addr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0},
+	}
+}
+
+func (s *FuncSink) Send(p pack.Pack) error {
+	s.fn(encodeCafe(p), s.addr)
+	return nil
+}
+
+func (s *FuncSink) Close() error {
+	return nil
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Issue levels reported by Check.
+const (
+	IssueError   = "error"   // the value is ignored or the server will not start
+	IssueWarning = "warning" // the value is applied but probably not what was meant
+)
+
+// Issue is a problem found in a configuration.
+type Issue struct {
+	Key     string
+	Level   string
+	Message string
+}
+
+// Setting is the effective value of a key and where it comes from: "env",
+// "file" or "default".
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// Check parses content as a scouter.conf file, applies the environment
+// overrides as Load does, and reports unknown and repeated keys, values that
+// do not fit the key's type and settings that conflict. The returned Config
+// is not made the global one.
+func Check(content string) (*Config, []Issue) {
+	cfg := &Config{props: make(map[string]string), env: envOverrides()}
+	counts := make(map[string]int)
+	parseProps(strings.NewReader(content), func(key, val string) {
+		cfg.props[key] = val
+		counts[key]++
+	})
+
+	var issues []Issue
+	for _, key := range cfg.UnknownKeys() {
+		issues = append(issues, Issue{key, IssueWarning, "unknown key, ignored"})
+	}
+	for key, n := range counts {
+		if n > 1 {
+			issues = append(issues, Issue{key, IssueWarning, fmt.Sprintf("set %d times, the last value is used", n)})
+		}
+	}
+	issues = append(issues, cfg.Validate()...)
+	sortIssues(issues)
+	return cfg, issues
+}
+
+// CheckFile is Check for the file at path. A missing file is no error: the
+// server starts with the defaults and the environment overrides.
+func CheckFile(path string) (*Config, []Issue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+	cfg, issues := Check(string(data))
+	cfg.filePath = path
+	return cfg, issues, nil
+}
+
+// Validate reports the values of c that do not fit their key's type and the
+// settings that conflict with each other.
+func (c *Config) Validate() []Issue {
+	c.mu.RLock()
+	var issues []Issue
+	for _, key := range ConfigKeys() {
+		v, ok := c.lookup(key)
+		if !ok {
+			continue
+		}
+		meta := configMetas[key]
+		switch meta.ValueType {
+		case ValueTypeNum:
+			if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+				issues = append(issues, Issue{key, IssueError, fmt.Sprintf("%q is not a number, the default %s is used", v, meta.Default)})
+			}
+		case ValueTypeBool:
+			switch strings.ToLower(v) {
+			case "true", "1", "yes", "on", "false", "0", "no", "off":
+			default:
+				issues = append(issues, Issue{key, IssueError, fmt.Sprintf("%q is not a boolean, the default %s is used", v, meta.Default)})
+			}
+		}
+	}
+	c.mu.RUnlock()
+
+	issues = append(issues, c.conflicts()...)
+	sortIssues(issues)
+	return issues
+}
+
+// conflicts reports settings that contradict each other.
+func (c *Config) conflicts() []Issue {
+	var issues []Issue
+	add := func(key, level, format string, args ...interface{}) {
+		issues = append(issues, Issue{key, level, fmt.Sprintf(format, args...)})
+	}
+
+	// The auto-delete scheduler removes whole days after db_keep_days,
+	// whatever the per-type purge keeps. Some defaults already exceed it,
+	// so only days someone set are reported.
+	if keep := c.DBKeepDays(); keep > 0 && c.MgrPurgeEnabled() {
+		for _, key := range []string{
+			"mgr_purge_profile_keep_days",
+			"mgr_purge_profile_error_keep_days",
+			"mgr_purge_profile_slow_keep_days",
+			"mgr_purge_xlog_keep_days",
+			"mgr_purge_counter_keep_days",
+			"mgr_purge_realtime_counter_keep_days",
+			"mgr_purge_daily_text_days",
+			"mgr_purge_sum_data_days",
+		} {
+			if days := c.registeredInt(key); days > keep && (c.isSet(key) || c.isSet("db_keep_days")) {
+				add(key, IssueWarning, "%d days exceeds db_keep_days=%d; the data is deleted after %d days", days, keep, keep)
+			}
+		}
+		if days := c.MgrPurgeRealtimeCounterKeepDays() + c.MgrPurgeRealtimeCounterDownsampleDays(); c.MgrPurgeRealtimeCounterDownsampleDays() > 0 && days > keep {
+			add("mgr_purge_realtime_counter_downsample_days", IssueWarning, "downsampled data would be kept until day %d, but db_keep_days=%d deletes it earlier", days, keep)
+		}
+	}
+	if profile, xlog := c.MgrPurgeProfileKeepDays(), c.MgrPurgeXLogKeepDays(); profile > xlog {
+		add("mgr_purge_profile_keep_days", IssueWarning, "%d days exceeds mgr_purge_xlog_keep_days=%d; profiles are only found through their XLogs", profile, xlog)
+	}
+	for _, key := range []string{"mgr_purge_profile_error_keep_days", "mgr_purge_profile_slow_keep_days"} {
+		if days := c.registeredInt(key); days > 0 && days <= c.MgrPurgeProfileKeepDays() {
+			add(key, IssueWarning, "%d days is not above mgr_purge_profile_keep_days=%d and has no effect", days, c.MgrPurgeProfileKeepDays())
+		}
+	}
+	if hour := c.MgrCompactionHour(); hour < -1 || hour > 23 {
+		add("mgr_compaction_hour", IssueError, "%d is not an hour between 0 and 23 or -1; indexes are never compacted", hour)
+	}
+
+	if rate := c.XLogSamplingRate(); rate < 0 || rate > 100 {
+		add("xlog_sampling_rate", IssueError, "%d is not a percentage between 0 and 100", rate)
+	}
+	for _, p := range strings.Split(c.XLogSamplingExcludePatterns(), ",") {
+		if _, err := path.Match(strings.TrimSpace(p), ""); err != nil {
+			add("xlog_sampling_exclude_patterns", IssueError, "bad pattern %q; it matches no service", strings.TrimSpace(p))
+		}
+	}
+
+	if c.NetTcpTLSEnabled() {
+		if c.NetTcpTLSCertFile() == "" || c.NetTcpTLSKeyFile() == "" {
+			add("net_tcp_tls_enabled", IssueError, "needs net_tcp_tls_cert_file and net_tcp_tls_key_file; the server will not start")
+		}
+		if c.NetTcpTLSAgentCertRequired() && c.NetTcpTLSClientCAFile() == "" {
+			add("net_tcp_tls_agent_cert_required", IssueError, "needs net_tcp_tls_client_ca_file to verify agent certificates; every agent is rejected")
+		}
+	}
+	if (c.BackupEnabled() || c.BackupRestoreOnMiss()) && (c.BackupS3Endpoint() == "" || c.BackupS3Bucket() == "") {
+		add("backup_s3_bucket", IssueError, "backups need backup_s3_endpoint and backup_s3_bucket; nothing is uploaded or restored")
+	}
+	if c.StandbyReplListen() != "" && c.StandbyReplToken() == "" {
+		add("standby_repl_token", IssueError, "is empty; the standby publisher on standby_repl_listen will not start")
+	}
+	if token := strings.TrimSpace(c.NetTcpInternalApiToken()); token != "" {
+		if _, err := strconv.ParseInt(token, 10, 64); err != nil {
+			add("net_tcp_internal_api_token", IssueError, "%q is not a number; every internal API command is denied", token)
+		}
+	}
+	return issues
+}
+
+// isSet reports whether key is set in the file or the environment.
+func (c *Config) isSet(key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.lookup(key)
+	return ok
+}
+
+// Effective returns the value every known key takes in c, sorted by key.
+func (c *Config) Effective() []Setting {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	settings := make([]Setting, 0, len(configMetas))
+	for _, key := range ConfigKeys() {
+		s := Setting{Key: key, Value: configMetas[key].Default, Source: "default"}
+		if v, ok := c.env[key]; ok {
+			s.Value, s.Source = v, "env"
+		} else if v, ok := c.props[key]; ok {
+			s.Value, s.Source = v, "file"
+		}
+		settings = append(settings, s)
+	}
+	return settings
+}
+
+// IsSecretKey reports whether the value of key is a secret, such as a
+// password or a webhook URL carrying a token, to be masked when the
+// configuration is shown to users.
+func IsSecretKey(key string) bool {
+	for _, suffix := range []string{"_password", "_token", "_secret", "_license_key", "_secret_key"} {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return key == "alert_webhook_urls"
+}
+
+// sortIssues orders issues by key, errors first within a key.
+func sortIssues(issues []Issue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Key != issues[j].Key {
+			return issues[i].Key < issues[j].Key
+		}
+		return issues[i].Level == IssueError && issues[j].Level != IssueError
+	})
+}
@@ -2,9 +2,11 @@ package config
 
 import (
 	"bufio"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -16,10 +18,33 @@ import (
 type Config struct {
 	mu       sync.RWMutex
 	props    map[string]string
+	env      map[string]string // overrides from the environment, by key
 	filePath string
 	modTime  time.Time
 }
 
+// EnvPrefix starts the names of the environment variables overriding config
+// keys, e.g. SCOUTER_NET_TCP_LISTEN_PORT for net_tcp_listen_port.
+const EnvPrefix = "SCOUTER_"
+
+// EnvName returns the name of the environment variable overriding key.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// envOverrides returns the values of the environment variables overriding
+// registered keys. They are read when the config is loaded, so a reload
+// picks up nothing new but keeps them applied over the file.
+func envOverrides() map[string]string {
+	env := make(map[string]string)
+	for _, key := range ConfigKeys() {
+		if v, ok := os.LookupEnv(EnvName(key)); ok {
+			env[key] = strings.TrimSpace(v)
+		}
+	}
+	return env
+}
+
 var globalConfig atomic.Pointer[Config]
 
 // Get returns the global config instance.
@@ -38,6 +63,7 @@ func Load(filePath string) (*Config, error) {
 
 	cfg := &Config{
 		props:    make(map[string]string),
+		env:      envOverrides(),
 		filePath: absPath,
 	}
 
@@ -45,6 +71,9 @@ func Load(filePath string) (*Config, error) {
 	if err != nil {
 		// File does not exist -- return default config, no error.
 		globalConfig.Store(cfg)
+		if len(cfg.env) > 0 {
+			slog.Info("config loaded from environment", "keys", cfg.EnvOverrides())
+		}
 		return cfg, nil
 	}
 	cfg.modTime = info.ModTime()
@@ -57,7 +86,22 @@ func Load(filePath string) (*Config, error) {
 	}
 	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
+	if err := parseProps(f, func(key, val string) { cfg.props[key] = val }); err != nil {
+		return nil, err
+	}
+
+	globalConfig.Store(cfg)
+	slog.Info("config loaded", "path", absPath, "properties", len(cfg.props), "envOverrides", cfg.EnvOverrides())
+	for _, key := range cfg.UnknownKeys() {
+		slog.Warn("unknown config key ignored", "key", key)
+	}
+	return cfg, nil
+}
+
+// parseProps calls set for every key=value line of r in order, skipping
+// blank lines and # comments.
+func parseProps(r io.Reader, set func(key, val string)) error {
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" || strings.HasPrefix(line, "#") {
@@ -70,27 +114,45 @@ func Load(filePath string) (*Config, error) {
 		key := strings.TrimSpace(line[:idx])
 		val := strings.TrimSpace(line[idx+1:])
 		if key != "" {
-			cfg.props[key] = val
+			set(key, val)
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	return scanner.Err()
+}
+
+// lookup returns the value of key: its environment override if there is one,
+// else the one set in the file. Caller must hold c.mu.
+func (c *Config) lookup(key string) (string, bool) {
+	if v, ok := c.env[key]; ok {
+		return v, true
 	}
+	v, ok := c.props[key]
+	return v, ok
+}
 
-	globalConfig.Store(cfg)
-	slog.Info("config loaded", "path", absPath, "properties", len(cfg.props))
-	return cfg, nil
+// EnvOverrides returns the keys overridden by environment variables, sorted.
+func (c *Config) EnvOverrides() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := make([]string, 0, len(c.env))
+	for k := range c.env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // ---------------------------------------------------------------------------
 // Generic typed getters
 // ---------------------------------------------------------------------------
 
-// GetString returns a config value, or the default if not set.
+// GetString returns a config value, or the default if not set. Like the
+// other getters it prefers the value of the key's environment variable (see
+// EnvName) over the file.
 func (c *Config) GetString(key, defaultVal string) string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	if v, ok := c.props[key]; ok {
+	if v, ok := c.lookup(key); ok {
 		return v
 	}
 	return defaultVal
@@ -100,7 +162,7 @@ func (c *Config) GetString(key, defaultVal string) string {
 func (c *Config) GetInt(key string, defaultVal int) int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	if v, ok := c.props[key]; ok {
+	if v, ok := c.lookup(key); ok {
 		if i, err := strconv.Atoi(v); err == nil {
 			return i
 		}
@@ -112,7 +174,7 @@ func (c *Config) GetInt(key string, defaultVal int) int {
 func (c *Config) GetInt64(key string, defaultVal int64) int64 {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	if v, ok := c.props[key]; ok {
+	if v, ok := c.lookup(key); ok {
 		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
 			return i
 		}
@@ -125,7 +187,7 @@ func (c *Config) GetInt64(key string, defaultVal int64) int64 {
 func (c *Config) GetBool(key string, defaultVal bool) bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	if v, ok := c.props[key]; ok {
+	if v, ok := c.lookup(key); ok {
 		switch strings.ToLower(v) {
 		case "true", "1", "yes", "on":
 			return true
@@ -136,89 +198,143 @@ func (c *Config) GetBool(key string, defaultVal bool) bool {
 	return defaultVal
 }
 
+// registeredMeta returns the registry entry for key. It panics on unknown keys
+// so that a mistyped key in an accessor fails loudly instead of silently
+// falling back to a default.
+func registeredMeta(key string) ConfigMeta {
+	meta, ok := configMetas[key]
+	if !ok {
+		panic("config: unregistered key " + key)
+	}
+	return meta
+}
+
+func (c *Config) registeredString(key string) string {
+	return c.GetString(key, registeredMeta(key).Default)
+}
+
+func (c *Config) registeredInt(key string) int {
+	def, _ := strconv.Atoi(registeredMeta(key).Default)
+	return c.GetInt(key, def)
+}
+
+func (c *Config) registeredBool(key string) bool {
+	return c.GetBool(key, registeredMeta(key).Default == "true")
+}
+
+// UnknownKeys returns keys set in the config file that are not registered,
+// typically typos. They are otherwise ignored.
+func (c *Config) UnknownKeys() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var unknown []string
+	for k := range c.props {
+		if !IsKnownKey(k) {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
 // ---------------------------------------------------------------------------
 // Convenience accessors for well-known configuration keys
 // ---------------------------------------------------------------------------
 
 // ServerID returns the server_id (default "0").
 func (c *Config) ServerID() string {
-	return c.GetString("server_id", "0")
+	return c.registeredString("server_id")
 }
 
 // UDPPort returns net_udp_listen_port (default 6100).
 func (c *Config) UDPPort() int {
-	return c.GetInt("net_udp_listen_port", 6100)
+	return c.registeredInt("net_udp_listen_port")
 }
 
 // TCPPort returns net_tcp_listen_port (default 6100).
 func (c *Config) TCPPort() int {
-	return c.GetInt("net_tcp_listen_port", 6100)
+	return c.registeredInt("net_tcp_listen_port")
 }
 
 // HTTPPort returns net_http_port (default 6180).
 func (c *Config) HTTPPort() int {
-	return c.GetInt("net_http_port", 6180)
+	return c.registeredInt("net_http_port")
 }
 
 // HTTPEnabled returns net_http_enabled (default false).
 func (c *Config) HTTPEnabled() bool {
-	return c.GetBool("net_http_enabled", false)
+	return c.registeredBool("net_http_enabled")
 }
 
 // DBDir returns db_dir (default "./database").
 func (c *Config) DBDir() string {
-	return c.GetString("db_dir", "./database")
+	return c.registeredString("db_dir")
 }
 
 // LogDir returns log_dir (default "./logs").
 func (c *Config) LogDir() string {
-	return c.GetString("log_dir", "./logs")
+	return c.registeredString("log_dir")
 }
 
 // LogRotationEnabled returns log_rotation_enabled (default true).
 func (c *Config) LogRotationEnabled() bool {
-	return c.GetBool("log_rotation_enabled", true)
+	return c.registeredBool("log_rotation_enabled")
 }
 
 // LogKeepDays returns log_keep_days (default 30).
 func (c *Config) LogKeepDays() int {
-	return c.GetInt("log_keep_days", 30)
+	return c.registeredInt("log_keep_days")
 }
 
 // DBKeepDays returns db_keep_days (default 30).
 func (c *Config) DBKeepDays() int {
-	return c.GetInt("db_keep_days", 30)
+	return c.registeredInt("db_keep_days")
 }
 
 // DBMaxDiskUsagePct returns db_max_disk_usage_pct (default 80).
 func (c *Config) DBMaxDiskUsagePct() int {
-	return c.GetInt("db_max_disk_usage_pct", 80)
+	return c.registeredInt("db_max_disk_usage_pct")
+}
+
+// FlushAdaptiveEnabled returns flush_adaptive_enabled (default true).
+func (c *Config) FlushAdaptiveEnabled() bool {
+	return c.registeredBool("flush_adaptive_enabled")
+}
+
+// FlushMaxIntervalMs returns flush_max_interval_ms (default 10000).
+func (c *Config) FlushMaxIntervalMs() int {
+	return c.registeredInt("flush_max_interval_ms")
+}
+
+// FlushDirtyBytesThreshold returns flush_dirty_bytes_threshold (default 8192).
+func (c *Config) FlushDirtyBytesThreshold() int {
+	return c.registeredInt("flush_dirty_bytes_threshold")
 }
 
 // ObjectDeadTimeMs returns object_deadtime_ms (default 8000).
 func (c *Config) ObjectDeadTimeMs() int {
-	return c.GetInt("object_deadtime_ms", 8000)
+	return c.registeredInt("object_deadtime_ms")
 }
 
 // XLogQueueSize returns xlog_queue_size (default 10000).
 func (c *Config) XLogQueueSize() int {
-	return c.GetInt("xlog_queue_size", 10000)
+	return c.registeredInt("xlog_queue_size")
 }
 
 // TextCacheMaxSize returns text_cache_max_size (default 100000).
 func (c *Config) TextCacheMaxSize() int {
-	return c.GetInt("text_cache_max_size", 100000)
+	return c.registeredInt("text_cache_max_size")
 }
 
 // DayContainerKeepHours returns day_container_keep_hours (default 48).
 // Containers older than this are automatically closed to free memory and file handles.
 func (c *Config) DayContainerKeepHours() int {
-	return c.GetInt("day_container_keep_hours", 48)
+	return c.registeredInt("day_container_keep_hours")
 }
 
 // IsDebug returns debug (default false).
 func (c *Config) IsDebug() bool {
-	return c.GetBool("debug", false)
+	return c.registeredBool("debug")
 }
 
 // FilePath returns the absolute path to the config file.
@@ -228,6 +344,11 @@ func (c *Config) FilePath() string {
 	return c.filePath
 }
 
+// AccountImportDir returns account_import_dir (default "").
+func (c *Config) AccountImportDir() string {
+	return c.registeredString("account_import_dir")
+}
+
 // ConfDir returns the directory containing the config file.
 func (c *Config) ConfDir() string {
 	c.mu.RLock()
@@ -240,7 +361,62 @@ func (c *Config) ConfDir() string {
 
 // NetTcpClientSoTimeoutMs returns net_tcp_client_so_timeout_ms (default 8000).
 func (c *Config) NetTcpClientSoTimeoutMs() int {
-	return c.GetInt("net_tcp_client_so_timeout_ms", 8000)
+	return c.registeredInt("net_tcp_client_so_timeout_ms")
+}
+
+// NetTcpResponseCacheTTLSec returns net_tcp_response_cache_ttl_sec (default 10).
+func (c *Config) NetTcpResponseCacheTTLSec() int {
+	return c.registeredInt("net_tcp_response_cache_ttl_sec")
+}
+
+// NetTcpCompressEnabled returns net_tcp_compress_enabled (default true).
+func (c *Config) NetTcpCompressEnabled() bool {
+	return c.registeredBool("net_tcp_compress_enabled")
+}
+
+// NetTcpCompressMinBytes returns net_tcp_compress_min_bytes (default 32768).
+func (c *Config) NetTcpCompressMinBytes() int {
+	return c.registeredInt("net_tcp_compress_min_bytes")
+}
+
+// NetTcpInternalApiEnabled returns net_tcp_internal_api_enabled (default false).
+func (c *Config) NetTcpInternalApiEnabled() bool {
+	return c.registeredBool("net_tcp_internal_api_enabled")
+}
+
+// NetTcpInternalApiCommands returns net_tcp_internal_api_commands (default "").
+func (c *Config) NetTcpInternalApiCommands() string {
+	return c.registeredString("net_tcp_internal_api_commands")
+}
+
+// NetTcpInternalApiToken returns net_tcp_internal_api_token (default "").
+func (c *Config) NetTcpInternalApiToken() string {
+	return c.registeredString("net_tcp_internal_api_token")
+}
+
+// NetTcpTLSEnabled returns net_tcp_tls_enabled (default false).
+func (c *Config) NetTcpTLSEnabled() bool {
+	return c.registeredBool("net_tcp_tls_enabled")
+}
+
+// NetTcpTLSCertFile returns net_tcp_tls_cert_file (default "").
+func (c *Config) NetTcpTLSCertFile() string {
+	return c.registeredString("net_tcp_tls_cert_file")
+}
+
+// NetTcpTLSKeyFile returns net_tcp_tls_key_file (default "").
+func (c *Config) NetTcpTLSKeyFile() string {
+	return c.registeredString("net_tcp_tls_key_file")
+}
+
+// NetTcpTLSClientCAFile returns net_tcp_tls_client_ca_file (default "").
+func (c *Config) NetTcpTLSClientCAFile() string {
+	return c.registeredString("net_tcp_tls_client_ca_file")
+}
+
+// NetTcpTLSAgentCertRequired returns net_tcp_tls_agent_cert_required (default false).
+func (c *Config) NetTcpTLSAgentCertRequired() bool {
+	return c.registeredBool("net_tcp_tls_agent_cert_required")
 }
 
 // ---------------------------------------------------------------------------
@@ -249,12 +425,12 @@ func (c *Config) NetTcpClientSoTimeoutMs() int {
 
 // NetUDPListenIP returns net_udp_listen_ip (default "0.0.0.0").
 func (c *Config) NetUDPListenIP() string {
-	return c.GetString("net_udp_listen_ip", "0.0.0.0")
+	return c.registeredString("net_udp_listen_ip")
 }
 
 // NetTCPListenIP returns net_tcp_listen_ip (default "0.0.0.0").
 func (c *Config) NetTCPListenIP() string {
-	return c.GetString("net_tcp_listen_ip", "0.0.0.0")
+	return c.registeredString("net_tcp_listen_ip")
 }
 
 // ---------------------------------------------------------------------------
@@ -263,22 +439,32 @@ func (c *Config) NetTCPListenIP() string {
 
 // NetTcpAgentSoTimeoutMs returns net_tcp_agent_so_timeout_ms (default 60000).
 func (c *Config) NetTcpAgentSoTimeoutMs() int {
-	return c.GetInt("net_tcp_agent_so_timeout_ms", 60000)
+	return c.registeredInt("net_tcp_agent_so_timeout_ms")
 }
 
 // NetTcpAgentKeepaliveIntervalMs returns net_tcp_agent_keepalive_interval_ms (default 5000).
 func (c *Config) NetTcpAgentKeepaliveIntervalMs() int {
-	return c.GetInt("net_tcp_agent_keepalive_interval_ms", 5000)
+	return c.registeredInt("net_tcp_agent_keepalive_interval_ms")
 }
 
 // NetTcpGetAgentConnectionWaitMs returns net_tcp_get_agent_connection_wait_ms (default 1000).
 func (c *Config) NetTcpGetAgentConnectionWaitMs() int {
-	return c.GetInt("net_tcp_get_agent_connection_wait_ms", 1000)
+	return c.registeredInt("net_tcp_get_agent_connection_wait_ms")
 }
 
 // NetTcpServicePoolSize returns net_tcp_service_pool_size (default 100).
 func (c *Config) NetTcpServicePoolSize() int {
-	return c.GetInt("net_tcp_service_pool_size", 100)
+	return c.registeredInt("net_tcp_service_pool_size")
+}
+
+// DBIndexCheckOnOpen returns db_index_check_on_open (default true).
+func (c *Config) DBIndexCheckOnOpen() bool {
+	return c.registeredBool("db_index_check_on_open")
+}
+
+// NetTcpCommandConcurrency returns net_tcp_command_concurrency (default "").
+func (c *Config) NetTcpCommandConcurrency() string {
+	return c.registeredString("net_tcp_command_concurrency")
 }
 
 // ---------------------------------------------------------------------------
@@ -287,12 +473,12 @@ func (c *Config) NetTcpServicePoolSize() int {
 
 // NetUDPPacketBufferSize returns net_udp_packet_buffer_size (default 65535).
 func (c *Config) NetUDPPacketBufferSize() int {
-	return c.GetInt("net_udp_packet_buffer_size", 65535)
+	return c.registeredInt("net_udp_packet_buffer_size")
 }
 
 // NetUDPSoRcvbufSize returns net_udp_so_rcvbuf_size (default 4MB).
 func (c *Config) NetUDPSoRcvbufSize() int {
-	return c.GetInt("net_udp_so_rcvbuf_size", 4*1024*1024)
+	return c.registeredInt("net_udp_so_rcvbuf_size")
 }
 
 // ---------------------------------------------------------------------------
@@ -301,47 +487,92 @@ func (c *Config) NetUDPSoRcvbufSize() int {
 
 // NetHTTPApiEnabled returns net_http_api_enabled (default false).
 func (c *Config) NetHTTPApiEnabled() bool {
-	return c.GetBool("net_http_api_enabled", false)
+	return c.registeredBool("net_http_api_enabled")
 }
 
 // NetHTTPApiCorsAllowOrigin returns net_http_api_cors_allow_origin (default "*").
 func (c *Config) NetHTTPApiCorsAllowOrigin() string {
-	return c.GetString("net_http_api_cors_allow_origin", "*")
+	return c.registeredString("net_http_api_cors_allow_origin")
 }
 
 // NetHTTPApiCorsAllowCredentials returns net_http_api_cors_allow_credentials (default "true").
 func (c *Config) NetHTTPApiCorsAllowCredentials() string {
-	return c.GetString("net_http_api_cors_allow_credentials", "true")
+	return c.registeredString("net_http_api_cors_allow_credentials")
+}
+
+// NetHTTPApiCorsRouteMethods returns net_http_api_cors_route_methods (default "").
+func (c *Config) NetHTTPApiCorsRouteMethods() string {
+	return c.registeredString("net_http_api_cors_route_methods")
+}
+
+// NetHTTPApiCorsMaxAgeSec returns net_http_api_cors_max_age_sec (default 600).
+func (c *Config) NetHTTPApiCorsMaxAgeSec() int {
+	return c.registeredInt("net_http_api_cors_max_age_sec")
 }
 
 // NetHTTPApiAuthIpEnabled returns net_http_api_auth_ip_enabled (default false).
 func (c *Config) NetHTTPApiAuthIpEnabled() bool {
-	return c.GetBool("net_http_api_auth_ip_enabled", false)
+	return c.registeredBool("net_http_api_auth_ip_enabled")
 }
 
 // NetHTTPApiAuthSessionEnabled returns net_http_api_auth_session_enabled (default false).
 func (c *Config) NetHTTPApiAuthSessionEnabled() bool {
-	return c.GetBool("net_http_api_auth_session_enabled", false)
+	return c.registeredBool("net_http_api_auth_session_enabled")
 }
 
 // NetHTTPApiSessionTimeout returns net_http_api_session_timeout in seconds (default 86400).
 func (c *Config) NetHTTPApiSessionTimeout() int {
-	return c.GetInt("net_http_api_session_timeout", 86400)
+	return c.registeredInt("net_http_api_session_timeout")
 }
 
 // NetHTTPApiAuthBearerTokenEnabled returns net_http_api_auth_bearer_token_enabled (default false).
 func (c *Config) NetHTTPApiAuthBearerTokenEnabled() bool {
-	return c.GetBool("net_http_api_auth_bearer_token_enabled", false)
+	return c.registeredBool("net_http_api_auth_bearer_token_enabled")
 }
 
 // NetHTTPApiGzipEnabled returns net_http_api_gzip_enabled (default true).
 func (c *Config) NetHTTPApiGzipEnabled() bool {
-	return c.GetBool("net_http_api_gzip_enabled", true)
+	return c.registeredBool("net_http_api_gzip_enabled")
 }
 
 // NetHTTPApiAllowIps returns net_http_api_allow_ips (default "localhost,127.0.0.1,0:0:0:0:0:0:0:1,::1").
 func (c *Config) NetHTTPApiAllowIps() string {
-	return c.GetString("net_http_api_allow_ips", "localhost,127.0.0.1,0:0:0:0:0:0:0:1,::1")
+	return c.registeredString("net_http_api_allow_ips")
+}
+
+// NetHTTPApiCacheTTLSec returns net_http_api_cache_ttl_sec (default 30).
+func (c *Config) NetHTTPApiCacheTTLSec() int {
+	return c.registeredInt("net_http_api_cache_ttl_sec")
+}
+
+// NetHTTPApiTokenMaxTTLDays returns net_http_api_token_max_ttl_days (default 365).
+func (c *Config) NetHTTPApiTokenMaxTTLDays() int {
+	return c.registeredInt("net_http_api_token_max_ttl_days")
+}
+
+// NetHTTPWsIntervalMs returns net_http_ws_interval_ms (default 500).
+func (c *Config) NetHTTPWsIntervalMs() int {
+	return c.registeredInt("net_http_ws_interval_ms")
+}
+
+// NetHTTPWsMaxConns returns net_http_ws_max_conns (default 100).
+func (c *Config) NetHTTPWsMaxConns() int {
+	return c.registeredInt("net_http_ws_max_conns")
+}
+
+// NetHTTPApiMetricsObjName returns net_http_api_metrics_obj_name (default "").
+func (c *Config) NetHTTPApiMetricsObjName() string {
+	return c.registeredString("net_http_api_metrics_obj_name")
+}
+
+// LogHTTPAccessEnabled returns log_http_access_enabled (default true).
+func (c *Config) LogHTTPAccessEnabled() bool {
+	return c.registeredBool("log_http_access_enabled")
+}
+
+// NetHTTPApiCacheMaxEntries returns net_http_api_cache_max_entries (default 1000).
+func (c *Config) NetHTTPApiCacheMaxEntries() int {
+	return c.registeredInt("net_http_api_cache_max_entries")
 }
 
 // ---------------------------------------------------------------------------
@@ -350,17 +581,37 @@ func (c *Config) NetHTTPApiAllowIps() string {
 
 // NetWebappTcpClientPoolSize returns net_webapp_tcp_client_pool_size (default 30).
 func (c *Config) NetWebappTcpClientPoolSize() int {
-	return c.GetInt("net_webapp_tcp_client_pool_size", 30)
+	return c.registeredInt("net_webapp_tcp_client_pool_size")
 }
 
 // NetWebappTcpClientPoolTimeout returns net_webapp_tcp_client_pool_timeout in ms (default 60000).
 func (c *Config) NetWebappTcpClientPoolTimeout() int {
-	return c.GetInt("net_webapp_tcp_client_pool_timeout", 60000)
+	return c.registeredInt("net_webapp_tcp_client_pool_timeout")
 }
 
 // NetWebappTcpClientSoTimeout returns net_webapp_tcp_client_so_timeout in ms (default 30000).
 func (c *Config) NetWebappTcpClientSoTimeout() int {
-	return c.GetInt("net_webapp_tcp_client_so_timeout", 30000)
+	return c.registeredInt("net_webapp_tcp_client_so_timeout")
+}
+
+// StandbyReplListen returns standby_repl_listen (default "").
+func (c *Config) StandbyReplListen() string {
+	return c.registeredString("standby_repl_listen")
+}
+
+// StandbyReplPrimary returns standby_repl_primary (default "").
+func (c *Config) StandbyReplPrimary() string {
+	return c.registeredString("standby_repl_primary")
+}
+
+// StandbyReplToken returns standby_repl_token (default "").
+func (c *Config) StandbyReplToken() string {
+	return c.registeredString("standby_repl_token")
+}
+
+// StandbyReplIntervalMs returns standby_repl_interval_ms (default 2000).
+func (c *Config) StandbyReplIntervalMs() int {
+	return c.registeredInt("standby_repl_interval_ms")
 }
 
 // ---------------------------------------------------------------------------
@@ -369,92 +620,127 @@ func (c *Config) NetWebappTcpClientSoTimeout() int {
 
 // LogTcpActionEnabled returns log_tcp_action_enabled (default false).
 func (c *Config) LogTcpActionEnabled() bool {
-	return c.GetBool("log_tcp_action_enabled", false)
+	return c.registeredBool("log_tcp_action_enabled")
 }
 
 // LogUDPMultipacket returns log_udp_multipacket (default false).
 func (c *Config) LogUDPMultipacket() bool {
-	return c.GetBool("log_udp_multipacket", false)
+	return c.registeredBool("log_udp_multipacket")
 }
 
 // LogExpiredMultipacket returns log_expired_multipacket (default true).
 func (c *Config) LogExpiredMultipacket() bool {
-	return c.GetBool("log_expired_multipacket", true)
+	return c.registeredBool("log_expired_multipacket")
 }
 
 // LogUDPPacket returns log_udp_packet (default false).
 func (c *Config) LogUDPPacket() bool {
-	return c.GetBool("log_udp_packet", false)
+	return c.registeredBool("log_udp_packet")
 }
 
 // LogUDPCounter returns log_udp_counter (default false).
 func (c *Config) LogUDPCounter() bool {
-	return c.GetBool("log_udp_counter", false)
+	return c.registeredBool("log_udp_counter")
 }
 
 // LogUDPInteractionCounter returns log_udp_interaction_counter (default false).
 func (c *Config) LogUDPInteractionCounter() bool {
-	return c.GetBool("log_udp_interaction_counter", false)
+	return c.registeredBool("log_udp_interaction_counter")
 }
 
 // LogUDPXLog returns log_udp_xlog (default false).
 func (c *Config) LogUDPXLog() bool {
-	return c.GetBool("log_udp_xlog", false)
+	return c.registeredBool("log_udp_xlog")
 }
 
 // LogUDPProfile returns log_udp_profile (default false).
 func (c *Config) LogUDPProfile() bool {
-	return c.GetBool("log_udp_profile", false)
+	return c.registeredBool("log_udp_profile")
 }
 
 // LogUDPText returns log_udp_text (default false).
 func (c *Config) LogUDPText() bool {
-	return c.GetBool("log_udp_text", false)
+	return c.registeredBool("log_udp_text")
 }
 
 // LogUDPAlert returns log_udp_alert (default false).
 func (c *Config) LogUDPAlert() bool {
-	return c.GetBool("log_udp_alert", false)
+	return c.registeredBool("log_udp_alert")
 }
 
 // LogUDPObject returns log_udp_object (default false).
 func (c *Config) LogUDPObject() bool {
-	return c.GetBool("log_udp_object", false)
+	return c.registeredBool("log_udp_object")
 }
 
 // LogUDPStatus returns log_udp_status (default false).
 func (c *Config) LogUDPStatus() bool {
-	return c.GetBool("log_udp_status", false)
+	return c.registeredBool("log_udp_status")
 }
 
 // LogUDPStack returns log_udp_stack (default false).
 func (c *Config) LogUDPStack() bool {
-	return c.GetBool("log_udp_stack", false)
+	return c.registeredBool("log_udp_stack")
 }
 
 // LogUDPSummary returns log_udp_summary (default false).
 func (c *Config) LogUDPSummary() bool {
-	return c.GetBool("log_udp_summary", false)
+	return c.registeredBool("log_udp_summary")
 }
 
 // LogUDPBatch returns log_udp_batch (default false).
 func (c *Config) LogUDPBatch() bool {
-	return c.GetBool("log_udp_batch", false)
+	return c.registeredBool("log_udp_batch")
 }
 
 // LogUDPSpan returns log_udp_span (default false).
 func (c *Config) LogUDPSpan() bool {
-	return c.GetBool("log_udp_span", false)
+	return c.registeredBool("log_udp_span")
 }
 
 // LogIndexTraversalWarningCount returns log_index_traversal_warning_count (default 100).
 func (c *Config) LogIndexTraversalWarningCount() int {
-	return c.GetInt("log_index_traversal_warning_count", 100)
+	return c.registeredInt("log_index_traversal_warning_count")
 }
 
 // LogSqlParsingFailEnabled returns log_sql_parsing_fail_enabled (default false).
 func (c *Config) LogSqlParsingFailEnabled() bool {
-	return c.GetBool("log_sql_parsing_fail_enabled", false)
+	return c.registeredBool("log_sql_parsing_fail_enabled")
+}
+
+// MirrorPackEnabled returns mirror_pack_enabled (default false).
+func (c *Config) MirrorPackEnabled() bool {
+	return c.registeredBool("mirror_pack_enabled")
+}
+
+// MirrorPackSamplePct returns mirror_pack_sample_pct (default 100).
+func (c *Config) MirrorPackSamplePct() int {
+	return c.registeredInt("mirror_pack_sample_pct")
+}
+
+// MirrorPackTypes returns mirror_pack_types (default "").
+func (c *Config) MirrorPackTypes() string {
+	return c.registeredString("mirror_pack_types")
+}
+
+// MirrorPackObjHashes returns mirror_pack_obj_hashes (default "").
+func (c *Config) MirrorPackObjHashes() string {
+	return c.registeredString("mirror_pack_obj_hashes")
+}
+
+// MirrorPackMaxMB returns mirror_pack_max_mb (default 100).
+func (c *Config) MirrorPackMaxMB() int {
+	return c.registeredInt("mirror_pack_max_mb")
+}
+
+// PackInspectCount returns pack_inspect_count (default 10).
+func (c *Config) PackInspectCount() int {
+	return c.registeredInt("pack_inspect_count")
+}
+
+// TestingFaultInjectionEnabled returns testing_fault_injection_enabled (default false).
+func (c *Config) TestingFaultInjectionEnabled() bool {
+	return c.registeredBool("testing_fault_injection_enabled")
 }
 
 // ---------------------------------------------------------------------------
@@ -463,22 +749,22 @@ func (c *Config) LogSqlParsingFailEnabled() bool {
 
 // PluginDir returns plugin_dir (default "./plugin").
 func (c *Config) PluginDir() string {
-	return c.GetString("plugin_dir", "./plugin")
+	return c.registeredString("plugin_dir")
 }
 
 // PluginEnabled returns plugin_enabled (default true).
 func (c *Config) PluginEnabled() bool {
-	return c.GetBool("plugin_enabled", true)
+	return c.registeredBool("plugin_enabled")
 }
 
 // ClientDir returns client_dir (default "./client").
 func (c *Config) ClientDir() string {
-	return c.GetString("client_dir", "./client")
+	return c.registeredString("client_dir")
 }
 
 // TempDir returns temp_dir (default "./tempdata").
 func (c *Config) TempDir() string {
-	return c.GetString("temp_dir", "./tempdata")
+	return c.registeredString("temp_dir")
 }
 
 // ---------------------------------------------------------------------------
@@ -487,7 +773,7 @@ func (c *Config) TempDir() string {
 
 // ObjectInactiveAlertLevel returns object_inactive_alert_level (default 0).
 func (c *Config) ObjectInactiveAlertLevel() int {
-	return c.GetInt("object_inactive_alert_level", 0)
+	return c.registeredInt("object_inactive_alert_level")
 }
 
 // ---------------------------------------------------------------------------
@@ -496,56 +782,101 @@ func (c *Config) ObjectInactiveAlertLevel() int {
 
 // CompressXLogEnabled returns compress_xlog_enabled (default false).
 func (c *Config) CompressXLogEnabled() bool {
-	return c.GetBool("compress_xlog_enabled", false)
+	return c.registeredBool("compress_xlog_enabled")
 }
 
 // CompressProfileEnabled returns compress_profile_enabled (default true).
 func (c *Config) CompressProfileEnabled() bool {
-	return c.GetBool("compress_profile_enabled", true)
+	return c.registeredBool("compress_profile_enabled")
+}
+
+// XLogFieldDictEnabled returns xlog_field_dict_enabled (default false).
+func (c *Config) XLogFieldDictEnabled() bool {
+	return c.registeredBool("xlog_field_dict_enabled")
 }
 
 // ---------------------------------------------------------------------------
 // Purge / Retention manager
 // ---------------------------------------------------------------------------
 
+// MgrCompactionHour returns mgr_compaction_hour (default 4).
+func (c *Config) MgrCompactionHour() int {
+	return c.registeredInt("mgr_compaction_hour")
+}
+
+// MgrCompactionMinDroppedPct returns mgr_compaction_min_dropped_pct (default 20).
+func (c *Config) MgrCompactionMinDroppedPct() int {
+	return c.registeredInt("mgr_compaction_min_dropped_pct")
+}
+
 // MgrPurgeEnabled returns mgr_purge_enabled (default true).
 func (c *Config) MgrPurgeEnabled() bool {
-	return c.GetBool("mgr_purge_enabled", true)
+	return c.registeredBool("mgr_purge_enabled")
 }
 
 // MgrPurgeDiskUsagePct returns mgr_purge_disk_usage_pct (default 80).
 func (c *Config) MgrPurgeDiskUsagePct() int {
-	return c.GetInt("mgr_purge_disk_usage_pct", 80)
+	return c.registeredInt("mgr_purge_disk_usage_pct")
 }
 
 // MgrPurgeProfileKeepDays returns mgr_purge_profile_keep_days (default 10).
 func (c *Config) MgrPurgeProfileKeepDays() int {
-	return c.GetInt("mgr_purge_profile_keep_days", 10)
+	return c.registeredInt("mgr_purge_profile_keep_days")
+}
+
+// MgrPurgeProfileErrorKeepDays returns mgr_purge_profile_error_keep_days (default 0).
+func (c *Config) MgrPurgeProfileErrorKeepDays() int {
+	return c.registeredInt("mgr_purge_profile_error_keep_days")
+}
+
+// MgrPurgeProfileSlowKeepDays returns mgr_purge_profile_slow_keep_days (default 0).
+func (c *Config) MgrPurgeProfileSlowKeepDays() int {
+	return c.registeredInt("mgr_purge_profile_slow_keep_days")
+}
+
+// MgrPurgeProfileSlowMs returns mgr_purge_profile_slow_ms (default 8000).
+func (c *Config) MgrPurgeProfileSlowMs() int {
+	return c.registeredInt("mgr_purge_profile_slow_ms")
 }
 
 // MgrPurgeXLogKeepDays returns mgr_purge_xlog_keep_days (default 30).
 func (c *Config) MgrPurgeXLogKeepDays() int {
-	return c.GetInt("mgr_purge_xlog_keep_days", 30)
+	return c.registeredInt("mgr_purge_xlog_keep_days")
 }
 
 // MgrPurgeCounterKeepDays returns mgr_purge_counter_keep_days (default 70).
 func (c *Config) MgrPurgeCounterKeepDays() int {
-	return c.GetInt("mgr_purge_counter_keep_days", 70)
+	return c.registeredInt("mgr_purge_counter_keep_days")
 }
 
 // MgrPurgeRealtimeCounterKeepDays returns mgr_purge_realtime_counter_keep_days (default 70).
 func (c *Config) MgrPurgeRealtimeCounterKeepDays() int {
-	return c.GetInt("mgr_purge_realtime_counter_keep_days", 70)
+	return c.registeredInt("mgr_purge_realtime_counter_keep_days")
+}
+
+// MgrPurgeRealtimeCounterDownsampleDays returns mgr_purge_realtime_counter_downsample_days (default 0).
+func (c *Config) MgrPurgeRealtimeCounterDownsampleDays() int {
+	return c.registeredInt("mgr_purge_realtime_counter_downsample_days")
 }
 
 // MgrPurgeDailyTextDays returns mgr_purge_daily_text_days (default 140).
 func (c *Config) MgrPurgeDailyTextDays() int {
-	return c.GetInt("mgr_purge_daily_text_days", 140)
+	return c.registeredInt("mgr_purge_daily_text_days")
+}
+
+// MgrPurgeDailyTextDaysByDiv returns mgr_purge_daily_text_days_by_div (default "").
+func (c *Config) MgrPurgeDailyTextDaysByDiv() string {
+	return c.registeredString("mgr_purge_daily_text_days_by_div")
+}
+
+// MgrPurgeTrashHours returns mgr_purge_trash_hours (default 24).
+func (c *Config) MgrPurgeTrashHours() int {
+	return c.registeredInt("mgr_purge_trash_hours")
 }
 
 // MgrPurgeSumDataDays returns mgr_purge_sum_data_days (default 60).
 func (c *Config) MgrPurgeSumDataDays() int {
-	return c.GetInt("mgr_purge_sum_data_days", 60)
+	return c.registeredInt("mgr_purge_sum_data_days")
 }
 
 // ---------------------------------------------------------------------------
@@ -554,17 +885,17 @@ func (c *Config) MgrPurgeSumDataDays() int {
 
 // MgrTextDbDailyServiceEnabled returns mgr_text_db_daily_service_enabled (default false).
 func (c *Config) MgrTextDbDailyServiceEnabled() bool {
-	return c.GetBool("mgr_text_db_daily_service_enabled", false)
+	return c.registeredBool("mgr_text_db_daily_service_enabled")
 }
 
 // MgrTextDbDailyApiEnabled returns mgr_text_db_daily_api_enabled (default false).
 func (c *Config) MgrTextDbDailyApiEnabled() bool {
-	return c.GetBool("mgr_text_db_daily_api_enabled", false)
+	return c.registeredBool("mgr_text_db_daily_api_enabled")
 }
 
 // MgrTextDbDailyUaEnabled returns mgr_text_db_daily_ua_enabled (default false).
 func (c *Config) MgrTextDbDailyUaEnabled() bool {
-	return c.GetBool("mgr_text_db_daily_ua_enabled", false)
+	return c.registeredBool("mgr_text_db_daily_ua_enabled")
 }
 
 // MgrTextDbIndexMB returns the hash index size in MB for a given text div.
@@ -572,25 +903,45 @@ func (c *Config) MgrTextDbDailyUaEnabled() bool {
 func (c *Config) MgrTextDbIndexMB(div string) int {
 	switch div {
 	case "service":
-		return c.GetInt("_mgr_text_db_index_service_mb", 1)
+		return c.registeredInt("_mgr_text_db_index_service_mb")
 	case "apicall":
-		return c.GetInt("_mgr_text_db_index_api_mb", 1)
+		return c.registeredInt("_mgr_text_db_index_api_mb")
 	case "ua":
-		return c.GetInt("_mgr_text_db_index_ua_mb", 1)
+		return c.registeredInt("_mgr_text_db_index_ua_mb")
 	case "login":
-		return c.GetInt("_mgr_text_db_index_login_mb", 1)
+		return c.registeredInt("_mgr_text_db_index_login_mb")
 	case "desc":
-		return c.GetInt("_mgr_text_db_index_desc_mb", 1)
+		return c.registeredInt("_mgr_text_db_index_desc_mb")
 	case "hmsg":
-		return c.GetInt("_mgr_text_db_index_hmsg_mb", 1)
+		return c.registeredInt("_mgr_text_db_index_hmsg_mb")
 	default:
-		return c.GetInt("_mgr_text_db_index_default_mb", 1)
+		return c.registeredInt("_mgr_text_db_index_default_mb")
 	}
 }
 
 // MgrTextDbDailyIndexMB returns _mgr_text_db_daily_index_mb (default 1).
 func (c *Config) MgrTextDbDailyIndexMB() int {
-	return c.GetInt("_mgr_text_db_daily_index_mb", 1)
+	return c.registeredInt("_mgr_text_db_daily_index_mb")
+}
+
+// MgrTextMaxLength returns mgr_text_max_length (default 0).
+func (c *Config) MgrTextMaxLength() int {
+	return c.registeredInt("mgr_text_max_length")
+}
+
+// MgrTextMaxLengthByType returns mgr_text_max_length_by_type (default "").
+func (c *Config) MgrTextMaxLengthByType() string {
+	return c.registeredString("mgr_text_max_length_by_type")
+}
+
+// MgrTextSqlCollapseLiterals returns mgr_text_sql_collapse_literals (default false).
+func (c *Config) MgrTextSqlCollapseLiterals() bool {
+	return c.registeredBool("mgr_text_sql_collapse_literals")
+}
+
+// MgrTextUrlStripQuery returns mgr_text_url_strip_query (default false).
+func (c *Config) MgrTextUrlStripQuery() bool {
+	return c.registeredBool("mgr_text_url_strip_query")
 }
 
 // ---------------------------------------------------------------------------
@@ -599,17 +950,77 @@ func (c *Config) MgrTextDbDailyIndexMB() int {
 
 // XLogRealtimeLowerBoundMs returns xlog_realtime_lower_bound_ms (default 0).
 func (c *Config) XLogRealtimeLowerBoundMs() int {
-	return c.GetInt("xlog_realtime_lower_bound_ms", 0)
+	return c.registeredInt("xlog_realtime_lower_bound_ms")
 }
 
 // XLogPasttimeLowerBoundMs returns xlog_pasttime_lower_bound_ms (default 0).
 func (c *Config) XLogPasttimeLowerBoundMs() int {
-	return c.GetInt("xlog_pasttime_lower_bound_ms", 0)
+	return c.registeredInt("xlog_pasttime_lower_bound_ms")
+}
+
+// XLogGxidAdjacentDays returns xlog_gxid_adjacent_days (default 1).
+func (c *Config) XLogGxidAdjacentDays() int {
+	return c.registeredInt("xlog_gxid_adjacent_days")
+}
+
+// XLogUseridIndexEnabled returns xlog_userid_index_enabled (default false).
+func (c *Config) XLogUseridIndexEnabled() bool {
+	return c.registeredBool("xlog_userid_index_enabled")
+}
+
+// XLogSecondaryIndexEnabled returns xlog_secondary_index_enabled (default false).
+func (c *Config) XLogSecondaryIndexEnabled() bool {
+	return c.registeredBool("xlog_secondary_index_enabled")
+}
+
+// XLogHeatmapEnabled returns xlog_heatmap_enabled (default true).
+func (c *Config) XLogHeatmapEnabled() bool {
+	return c.registeredBool("xlog_heatmap_enabled")
+}
+
+// XLogSamplingRate returns xlog_sampling_rate (default 100).
+func (c *Config) XLogSamplingRate() int {
+	return c.registeredInt("xlog_sampling_rate")
+}
+
+// XLogSamplingElapsedMs returns xlog_sampling_elapsed_ms (default 1000).
+func (c *Config) XLogSamplingElapsedMs() int {
+	return c.registeredInt("xlog_sampling_elapsed_ms")
+}
+
+// XLogSamplingExcludePatterns returns xlog_sampling_exclude_patterns (default "").
+func (c *Config) XLogSamplingExcludePatterns() string {
+	return c.registeredString("xlog_sampling_exclude_patterns")
+}
+
+// SLOEnabled returns slo_enabled (default true).
+func (c *Config) SLOEnabled() bool {
+	return c.registeredBool("slo_enabled")
+}
+
+// AlertRulesEnabled returns alert_rules_enabled (default true).
+func (c *Config) AlertRulesEnabled() bool {
+	return c.registeredBool("alert_rules_enabled")
 }
 
 // ProfileQueueSize returns profile_queue_size (default 1000).
 func (c *Config) ProfileQueueSize() int {
-	return c.GetInt("profile_queue_size", 1000)
+	return c.registeredInt("profile_queue_size")
+}
+
+// ProfileSinglePackMaxBytes returns profile_single_pack_max_bytes (default 33554432).
+func (c *Config) ProfileSinglePackMaxBytes() int {
+	return c.registeredInt("profile_single_pack_max_bytes")
+}
+
+// ProfileStreamChunkBytes returns profile_stream_chunk_bytes (default 262144).
+func (c *Config) ProfileStreamChunkBytes() int {
+	return c.registeredInt("profile_stream_chunk_bytes")
+}
+
+// ProfileStreamMaxBytes returns profile_stream_max_bytes (default 536870912).
+func (c *Config) ProfileStreamMaxBytes() int {
+	return c.registeredInt("profile_stream_max_bytes")
 }
 
 // ---------------------------------------------------------------------------
@@ -618,12 +1029,57 @@ func (c *Config) ProfileQueueSize() int {
 
 // GeoIPEnabled returns geoip_enabled (default true).
 func (c *Config) GeoIPEnabled() bool {
-	return c.GetBool("geoip_enabled", true)
+	return c.registeredBool("geoip_enabled")
 }
 
 // GeoIPDataCityFile returns geoip_data_city_file (default "./conf/GeoLiteCity.dat").
 func (c *Config) GeoIPDataCityFile() string {
-	return c.GetString("geoip_data_city_file", "./conf/GeoLiteCity.dat")
+	return c.registeredString("geoip_data_city_file")
+}
+
+// GeoIPDataCityMMDBFile returns geoip_data_city_mmdb_file (default "./conf/GeoLite2-City.mmdb").
+func (c *Config) GeoIPDataCityMMDBFile() string {
+	return c.registeredString("geoip_data_city_mmdb_file")
+}
+
+// GeoIPDataASNMMDBFile returns geoip_data_asn_mmdb_file (default "./conf/GeoLite2-ASN.mmdb").
+func (c *Config) GeoIPDataASNMMDBFile() string {
+	return c.registeredString("geoip_data_asn_mmdb_file")
+}
+
+// GeoIPUpdateEnabled returns geoip_update_enabled (default false).
+func (c *Config) GeoIPUpdateEnabled() bool {
+	return c.registeredBool("geoip_update_enabled")
+}
+
+// GeoIPUpdateIntervalHours returns geoip_update_interval_hours (default 24).
+func (c *Config) GeoIPUpdateIntervalHours() int {
+	return c.registeredInt("geoip_update_interval_hours")
+}
+
+// GeoIPUpdateURL returns geoip_update_url.
+func (c *Config) GeoIPUpdateURL() string {
+	return c.registeredString("geoip_update_url")
+}
+
+// GeoIPAccountID returns geoip_account_id (default "").
+func (c *Config) GeoIPAccountID() string {
+	return c.registeredString("geoip_account_id")
+}
+
+// GeoIPLicenseKey returns geoip_license_key (default "").
+func (c *Config) GeoIPLicenseKey() string {
+	return c.registeredString("geoip_license_key")
+}
+
+// GeoIPUpdateCityEdition returns geoip_update_city_edition (default "GeoLite2-City").
+func (c *Config) GeoIPUpdateCityEdition() string {
+	return c.registeredString("geoip_update_city_edition")
+}
+
+// GeoIPUpdateASNEdition returns geoip_update_asn_edition (default "GeoLite2-ASN").
+func (c *Config) GeoIPUpdateASNEdition() string {
+	return c.registeredString("geoip_update_asn_edition")
 }
 
 // ---------------------------------------------------------------------------
@@ -632,22 +1088,275 @@ func (c *Config) GeoIPDataCityFile() string {
 
 // SqlTableParsingEnabled returns sql_table_parsing_enabled (default true).
 func (c *Config) SqlTableParsingEnabled() bool {
-	return c.GetBool("sql_table_parsing_enabled", true)
+	return c.registeredBool("sql_table_parsing_enabled")
 }
 
 // TagcntEnabled returns tagcnt_enabled (default true).
 func (c *Config) TagcntEnabled() bool {
-	return c.GetBool("tagcnt_enabled", true)
+	return c.registeredBool("tagcnt_enabled")
+}
+
+// SQLTopEnabled returns sql_top_enabled (default true).
+func (c *Config) SQLTopEnabled() bool {
+	return c.registeredBool("sql_top_enabled")
+}
+
+// ProfileStatEnabled returns profile_stat_enabled (default true).
+func (c *Config) ProfileStatEnabled() bool {
+	return c.registeredBool("profile_stat_enabled")
+}
+
+// SQLTopN returns sql_top_n (default 50).
+func (c *Config) SQLTopN() int {
+	return c.registeredInt("sql_top_n")
 }
 
 // ReqSearchXLogMaxCount returns req_search_xlog_max_count (default 500).
 func (c *Config) ReqSearchXLogMaxCount() int {
-	return c.GetInt("req_search_xlog_max_count", 500)
+	return c.registeredInt("req_search_xlog_max_count")
+}
+
+// ReqResultMaxRows returns req_result_max_rows (default 1000000).
+func (c *Config) ReqResultMaxRows() int {
+	return c.registeredInt("req_result_max_rows")
+}
+
+// ReqResultMaxBytes returns req_result_max_bytes (default 268435456).
+func (c *Config) ReqResultMaxBytes() int {
+	return c.registeredInt("req_result_max_bytes")
 }
 
 // VisitorHourlyCountEnabled returns visitor_hourly_count_enabled (default true).
 func (c *Config) VisitorHourlyCountEnabled() bool {
-	return c.GetBool("visitor_hourly_count_enabled", true)
+	return c.registeredBool("visitor_hourly_count_enabled")
+}
+
+// CounterCheckEnabled returns counter_check_enabled (default false).
+func (c *Config) CounterCheckEnabled() bool {
+	return c.registeredBool("counter_check_enabled")
+}
+
+// CounterCheckSkewMs returns counter_check_skew_ms (default 5000).
+func (c *Config) CounterCheckSkewMs() int {
+	return c.registeredInt("counter_check_skew_ms")
+}
+
+// KVNamespaceMax returns kv_namespace_max (default 100).
+func (c *Config) KVNamespaceMax() int {
+	return c.registeredInt("kv_namespace_max")
+}
+
+// KVAccountQuotaBytes returns kv_account_quota_bytes (default 1048576).
+func (c *Config) KVAccountQuotaBytes() int {
+	return c.registeredInt("kv_account_quota_bytes")
+}
+
+// ClockSkewCheckEnabled returns clock_skew_check_enabled (default true).
+func (c *Config) ClockSkewCheckEnabled() bool {
+	return c.registeredBool("clock_skew_check_enabled")
+}
+
+// ClockSkewThresholdMs returns clock_skew_threshold_ms (default 60000).
+func (c *Config) ClockSkewThresholdMs() int {
+	return c.registeredInt("clock_skew_threshold_ms")
+}
+
+// ClockSkewAlertLevel returns clock_skew_alert_level (default 1).
+func (c *Config) ClockSkewAlertLevel() int {
+	return c.registeredInt("clock_skew_alert_level")
+}
+
+// ClockSkewCorrectEnabled returns clock_skew_correct_enabled (default false).
+func (c *Config) ClockSkewCorrectEnabled() bool {
+	return c.registeredBool("clock_skew_correct_enabled")
+}
+
+// IngestQuotaXLogPerSec returns ingest_quota_xlog_per_sec (default "").
+func (c *Config) IngestQuotaXLogPerSec() string {
+	return c.registeredString("ingest_quota_xlog_per_sec")
+}
+
+// IngestQuotaProfilePerSec returns ingest_quota_profile_per_sec (default "").
+func (c *Config) IngestQuotaProfilePerSec() string {
+	return c.registeredString("ingest_quota_profile_per_sec")
+}
+
+// ObjectAlias returns object_alias (default "").
+func (c *Config) ObjectAlias() string {
+	return c.registeredString("object_alias")
+}
+
+// ObjectAutoGroup returns object_auto_group (default "").
+func (c *Config) ObjectAutoGroup() string {
+	return c.registeredString("object_auto_group")
+}
+
+// DeployWindowMaxMinutes returns deploy_window_max_minutes (default 120).
+func (c *Config) DeployWindowMaxMinutes() int {
+	return c.registeredInt("deploy_window_max_minutes")
+}
+
+// IngestLatencyObjName returns ingest_latency_obj_name (default "").
+func (c *Config) IngestLatencyObjName() string {
+	return c.registeredString("ingest_latency_obj_name")
+}
+
+// IngestLatencyAlertP99Ms returns ingest_latency_alert_p99_ms (default 10000).
+func (c *Config) IngestLatencyAlertP99Ms() int {
+	return c.registeredInt("ingest_latency_alert_p99_ms")
+}
+
+// IngestLatencyAlertLevel returns ingest_latency_alert_level (default 1).
+func (c *Config) IngestLatencyAlertLevel() int {
+	return c.registeredInt("ingest_latency_alert_level")
+}
+
+// IOStatsObjName returns io_stats_obj_name (default "").
+func (c *Config) IOStatsObjName() string {
+	return c.registeredString("io_stats_obj_name")
+}
+
+// SelfMonitorEnabled returns self_monitor_enabled (default true).
+func (c *Config) SelfMonitorEnabled() bool {
+	return c.registeredBool("self_monitor_enabled")
+}
+
+// SelfMonitorObjName returns self_monitor_obj_name (default "").
+func (c *Config) SelfMonitorObjName() string {
+	return c.registeredString("self_monitor_obj_name")
+}
+
+// ReadOnlyRetryAfterSec returns read_only_retry_after_sec (default 60).
+func (c *Config) ReadOnlyRetryAfterSec() int {
+	return c.registeredInt("read_only_retry_after_sec")
+}
+
+// ---------------------------------------------------------------------------
+// Reports
+// ---------------------------------------------------------------------------
+
+// ReportEnabled returns report_enabled (default false).
+func (c *Config) ReportEnabled() bool {
+	return c.registeredBool("report_enabled")
+}
+
+// ReportSchedule returns report_schedule (default "weekly").
+func (c *Config) ReportSchedule() string {
+	return c.registeredString("report_schedule")
+}
+
+// ReportHour returns report_hour (default 8).
+func (c *Config) ReportHour() int {
+	return c.registeredInt("report_hour")
+}
+
+// ReportDir returns report_dir (default "./report").
+func (c *Config) ReportDir() string {
+	return c.registeredString("report_dir")
+}
+
+// ReportTopN returns report_top_n (default 10).
+func (c *Config) ReportTopN() int {
+	return c.registeredInt("report_top_n")
+}
+
+// ReportMailTo returns report_mail_to (default "").
+func (c *Config) ReportMailTo() string {
+	return c.registeredString("report_mail_to")
+}
+
+// ---------------------------------------------------------------------------
+// Notification channels
+// ---------------------------------------------------------------------------
+
+// NotifySMTPAddr returns notify_smtp_addr (default "").
+func (c *Config) NotifySMTPAddr() string {
+	return c.registeredString("notify_smtp_addr")
+}
+
+// NotifySMTPUser returns notify_smtp_user (default "").
+func (c *Config) NotifySMTPUser() string {
+	return c.registeredString("notify_smtp_user")
+}
+
+// NotifySMTPPassword returns notify_smtp_password (default "").
+func (c *Config) NotifySMTPPassword() string {
+	return c.registeredString("notify_smtp_password")
+}
+
+// NotifySMTPTLS returns notify_smtp_tls (default "starttls").
+func (c *Config) NotifySMTPTLS() string {
+	return c.registeredString("notify_smtp_tls")
+}
+
+// NotifyMailFrom returns notify_mail_from (default "scouter@localhost").
+func (c *Config) NotifyMailFrom() string {
+	return c.registeredString("notify_mail_from")
+}
+
+// NotifyRetryCount returns notify_retry_count (default 2).
+func (c *Config) NotifyRetryCount() int {
+	return c.registeredInt("notify_retry_count")
+}
+
+// NotifyRetryBackoffMs returns notify_retry_backoff_ms (default 5000).
+func (c *Config) NotifyRetryBackoffMs() int {
+	return c.registeredInt("notify_retry_backoff_ms")
+}
+
+// AlertWebhookURLs returns alert_webhook_urls (default "").
+func (c *Config) AlertWebhookURLs() string {
+	return c.registeredString("alert_webhook_urls")
+}
+
+// AlertWebhookMinLevel returns alert_webhook_min_level (default 1).
+func (c *Config) AlertWebhookMinLevel() int {
+	return c.registeredInt("alert_webhook_min_level")
+}
+
+// AlertWebhookObjTypes returns alert_webhook_obj_types (default "").
+func (c *Config) AlertWebhookObjTypes() string {
+	return c.registeredString("alert_webhook_obj_types")
+}
+
+// AlertWebhookTemplate returns alert_webhook_template (default "").
+func (c *Config) AlertWebhookTemplate() string {
+	return c.registeredString("alert_webhook_template")
+}
+
+// AlertWebhookRetryCount returns alert_webhook_retry_count (default 3).
+func (c *Config) AlertWebhookRetryCount() int {
+	return c.registeredInt("alert_webhook_retry_count")
+}
+
+// AlertWebhookRetryBackoffMs returns alert_webhook_retry_backoff_ms (default 1000).
+func (c *Config) AlertWebhookRetryBackoffMs() int {
+	return c.registeredInt("alert_webhook_retry_backoff_ms")
+}
+
+// AlertWebhookTimeoutMs returns alert_webhook_timeout_ms (default 5000).
+func (c *Config) AlertWebhookTimeoutMs() int {
+	return c.registeredInt("alert_webhook_timeout_ms")
+}
+
+// AlertMailTo returns alert_mail_to (default "").
+func (c *Config) AlertMailTo() string {
+	return c.registeredString("alert_mail_to")
+}
+
+// AlertMailMinLevel returns alert_mail_min_level (default 1).
+func (c *Config) AlertMailMinLevel() int {
+	return c.registeredInt("alert_mail_min_level")
+}
+
+// AlertMailImmediateLevel returns alert_mail_immediate_level (default 2).
+func (c *Config) AlertMailImmediateLevel() int {
+	return c.registeredInt("alert_mail_immediate_level")
+}
+
+// AlertMailDigestIntervalMin returns alert_mail_digest_interval_min (default 60).
+func (c *Config) AlertMailDigestIntervalMin() int {
+	return c.registeredInt("alert_mail_digest_interval_min")
 }
 
 // ---------------------------------------------------------------------------
@@ -656,12 +1365,12 @@ func (c *Config) VisitorHourlyCountEnabled() bool {
 
 // ExtLinkName returns ext_link_name (default "scouter-paper").
 func (c *Config) ExtLinkName() string {
-	return c.GetString("ext_link_name", "scouter-paper")
+	return c.registeredString("ext_link_name")
 }
 
 // ExtLinkUrlPattern returns ext_link_url_pattern (default "").
 func (c *Config) ExtLinkUrlPattern() string {
-	return c.GetString("ext_link_url_pattern", "")
+	return c.registeredString("ext_link_url_pattern")
 }
 
 // ---------------------------------------------------------------------------
@@ -672,5 +1381,131 @@ func (c *Config) ExtLinkUrlPattern() string {
 // When enabled, the server accepts Zipkin span data (via zipkin-scouter UDP storage)
 // and converts them to XLog entries for display in the Scouter client.
 func (c *Config) ZipkinEnabled() bool {
-	return c.GetBool("zipkin_enabled", false)
+	return c.registeredBool("zipkin_enabled")
+}
+
+// ZipkinServiceMapping returns zipkin_service_mapping (default "").
+func (c *Config) ZipkinServiceMapping() string {
+	return c.registeredString("zipkin_service_mapping")
+}
+
+// ZipkinSummaryEnabled returns zipkin_summary_enabled (default true).
+func (c *Config) ZipkinSummaryEnabled() bool {
+	return c.registeredBool("zipkin_summary_enabled")
+}
+
+// ---------------------------------------------------------------------------
+// OTLP trace ingestion
+// ---------------------------------------------------------------------------
+
+// OtlpEnabled returns otlp_enabled (default false).
+// When enabled, the server receives OpenTelemetry traces over OTLP/gRPC and
+// OTLP/HTTP and converts them to XLog entries like Zipkin spans.
+func (c *Config) OtlpEnabled() bool {
+	return c.registeredBool("otlp_enabled")
+}
+
+// OtlpGrpcPort returns otlp_grpc_port (default 4317).
+func (c *Config) OtlpGrpcPort() int {
+	return c.registeredInt("otlp_grpc_port")
+}
+
+// OtlpHTTPPort returns otlp_http_port (default 4318).
+func (c *Config) OtlpHTTPPort() int {
+	return c.registeredInt("otlp_http_port")
+}
+
+// OtlpObjType returns otlp_obj_type (default "otel").
+func (c *Config) OtlpObjType() string {
+	return c.registeredString("otlp_obj_type")
+}
+
+// ProbeEnabled returns probe_enabled (default false).
+func (c *Config) ProbeEnabled() bool {
+	return c.registeredBool("probe_enabled")
+}
+
+// ProbeTargets returns probe_targets (default "").
+func (c *Config) ProbeTargets() string {
+	return c.registeredString("probe_targets")
+}
+
+// ProbeIntervalSec returns probe_interval_sec (default 30).
+func (c *Config) ProbeIntervalSec() int {
+	return c.registeredInt("probe_interval_sec")
+}
+
+// ProbeTimeoutMs returns probe_timeout_ms (default 5000).
+func (c *Config) ProbeTimeoutMs() int {
+	return c.registeredInt("probe_timeout_ms")
+}
+
+// ProbeObjType returns probe_obj_type (default "synthetic").
+func (c *Config) ProbeObjType() string {
+	return c.registeredString("probe_obj_type")
+}
+
+// BackupEnabled returns backup_enabled (default false).
+func (c *Config) BackupEnabled() bool {
+	return c.registeredBool("backup_enabled")
+}
+
+// BackupIntervalMinutes returns backup_interval_minutes (default 60).
+func (c *Config) BackupIntervalMinutes() int {
+	return c.registeredInt("backup_interval_minutes")
+}
+
+// BackupTypes returns backup_types (default "all").
+func (c *Config) BackupTypes() string {
+	return c.registeredString("backup_types")
+}
+
+// BackupKeepDays returns backup_keep_days (default 0).
+func (c *Config) BackupKeepDays() int {
+	return c.registeredInt("backup_keep_days")
+}
+
+// BackupStorageClass returns backup_storage_class (default "").
+func (c *Config) BackupStorageClass() string {
+	return c.registeredString("backup_storage_class")
+}
+
+// BackupRestoreOnMiss returns backup_restore_on_miss (default false).
+func (c *Config) BackupRestoreOnMiss() bool {
+	return c.registeredBool("backup_restore_on_miss")
+}
+
+// BackupS3Endpoint returns backup_s3_endpoint (default "").
+func (c *Config) BackupS3Endpoint() string {
+	return c.registeredString("backup_s3_endpoint")
+}
+
+// BackupS3Region returns backup_s3_region (default "us-east-1").
+func (c *Config) BackupS3Region() string {
+	return c.registeredString("backup_s3_region")
+}
+
+// BackupS3Bucket returns backup_s3_bucket (default "").
+func (c *Config) BackupS3Bucket() string {
+	return c.registeredString("backup_s3_bucket")
+}
+
+// BackupS3Prefix returns backup_s3_prefix (default "scouter/").
+func (c *Config) BackupS3Prefix() string {
+	return c.registeredString("backup_s3_prefix")
+}
+
+// BackupS3AccessKey returns backup_s3_access_key (default "").
+func (c *Config) BackupS3AccessKey() string {
+	return c.registeredString("backup_s3_access_key")
+}
+
+// BackupS3SecretKey returns backup_s3_secret_key (default "").
+func (c *Config) BackupS3SecretKey() string {
+	return c.registeredString("backup_s3_secret_key")
+}
+
+// BackupS3PathStyle returns backup_s3_path_style (default true).
+func (c *Config) BackupS3PathStyle() bool {
+	return c.registeredBool("backup_s3_path_style")
 }
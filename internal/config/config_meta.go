@@ -1,5 +1,10 @@
 package config
 
+import (
+	"maps"
+	"sort"
+)
+
 // ValueType constants matching Java's scouter.lang.conf.ValueType enum.
 const (
 	ValueTypeString = 1 // Plain string
@@ -7,142 +12,342 @@ const (
 	ValueTypeBool   = 3 // Boolean
 )
 
-// ConfigMeta holds description and value type for a config key.
+// ConfigMeta holds description, value type, and default for a config key.
 type ConfigMeta struct {
 	Desc      string
 	ValueType int
+	Default   string
+	HotReload bool // takes effect on config reload without a restart
+}
+
+// ValueTypeName returns a display name for a ValueType constant.
+func ValueTypeName(t int) string {
+	switch t {
+	case ValueTypeNum:
+		return "number"
+	case ValueTypeBool:
+		return "bool"
+	default:
+		return "string"
+	}
 }
 
 // ConfigMetaMap returns metadata for all known server config keys.
 func ConfigMetaMap() map[string]ConfigMeta {
-	return map[string]ConfigMeta{
-		// Server identity
-		"server_id": {"Server ID", ValueTypeString},
-
-		// Network – UDP
-		"net_udp_listen_ip":         {"UDP listen IP address", ValueTypeString},
-		"net_udp_listen_port":       {"UDP listen port for agent data", ValueTypeNum},
-		"net_udp_packet_buffer_size": {"UDP packet buffer size in bytes", ValueTypeNum},
-		"net_udp_so_rcvbuf_size":    {"UDP socket receive buffer size in bytes", ValueTypeNum},
-
-		// Network – TCP
-		"net_tcp_listen_ip":                      {"TCP listen IP address", ValueTypeString},
-		"net_tcp_listen_port":                    {"TCP listen port for client connections", ValueTypeNum},
-		"net_tcp_client_so_timeout_ms":           {"TCP client socket timeout in ms", ValueTypeNum},
-		"net_tcp_agent_so_timeout_ms":            {"TCP agent socket timeout in ms", ValueTypeNum},
-		"net_tcp_agent_keepalive_interval_ms":    {"TCP agent keepalive interval in ms", ValueTypeNum},
-		"net_tcp_get_agent_connection_wait_ms":   {"Wait time for agent connection in ms", ValueTypeNum},
-		"net_tcp_service_pool_size":              {"TCP service thread pool size", ValueTypeNum},
-
-		// Network – HTTP API
-		"net_http_port":                           {"HTTP API port", ValueTypeNum},
-		"net_http_enabled":                        {"Enable HTTP API server", ValueTypeBool},
-		"net_http_api_enabled":                    {"Enable HTTP API", ValueTypeBool},
-		"net_http_api_cors_allow_origin":          {"CORS allow origin header", ValueTypeString},
-		"net_http_api_cors_allow_credentials":     {"CORS allow credentials header", ValueTypeString},
-		"net_http_api_auth_ip_enabled":            {"Enable HTTP API IP-based auth", ValueTypeBool},
-		"net_http_api_auth_session_enabled":       {"Enable HTTP API session auth", ValueTypeBool},
-		"net_http_api_session_timeout":            {"HTTP API session timeout in seconds", ValueTypeNum},
-		"net_http_api_auth_bearer_token_enabled":  {"Enable HTTP API bearer token auth", ValueTypeBool},
-		"net_http_api_gzip_enabled":               {"Enable HTTP API gzip compression", ValueTypeBool},
-		"net_http_api_allow_ips":                  {"Allowed IPs for HTTP API access", ValueTypeString},
-
-		// Network – webapp TCP pool
-		"net_webapp_tcp_client_pool_size":    {"Webapp TCP client pool size", ValueTypeNum},
-		"net_webapp_tcp_client_pool_timeout": {"Webapp TCP client pool timeout in ms", ValueTypeNum},
-		"net_webapp_tcp_client_so_timeout":   {"Webapp TCP client socket timeout in ms", ValueTypeNum},
-
-		// Database
-		"db_dir":               {"Database directory path", ValueTypeString},
-		"db_keep_days":         {"Number of days to keep database files", ValueTypeNum},
-		"db_max_disk_usage_pct": {"Maximum disk usage percentage for database", ValueTypeNum},
-
-		// Logging
-		"debug":                  {"Enable debug logging", ValueTypeBool},
-		"log_dir":                {"Log directory path", ValueTypeString},
-		"log_rotation_enabled":   {"Enable log file rotation", ValueTypeBool},
-		"log_keep_days":          {"Number of days to keep log files", ValueTypeNum},
-		"log_tcp_action_enabled": {"Log TCP actions for debugging", ValueTypeBool},
-
-		// Logging – UDP debug
-		"log_udp_multipacket":       {"Log UDP multipacket debug info", ValueTypeBool},
-		"log_expired_multipacket":   {"Log expired multipacket warnings", ValueTypeBool},
-		"log_udp_packet":            {"Log UDP packet debug info", ValueTypeBool},
-		"log_udp_counter":           {"Log UDP counter data", ValueTypeBool},
-		"log_udp_interaction_counter": {"Log UDP interaction counter data", ValueTypeBool},
-		"log_udp_xlog":              {"Log UDP XLog data", ValueTypeBool},
-		"log_udp_profile":           {"Log UDP profile data", ValueTypeBool},
-		"log_udp_text":              {"Log UDP text data", ValueTypeBool},
-		"log_udp_alert":             {"Log UDP alert data", ValueTypeBool},
-		"log_udp_object":            {"Log UDP object data", ValueTypeBool},
-		"log_udp_status":            {"Log UDP status data", ValueTypeBool},
-		"log_udp_stack":             {"Log UDP stack data", ValueTypeBool},
-		"log_udp_summary":           {"Log UDP summary data", ValueTypeBool},
-		"log_udp_batch":             {"Log UDP batch data", ValueTypeBool},
-		"log_udp_span":              {"Log UDP span data", ValueTypeBool},
-		"log_index_traversal_warning_count": {"Index traversal warning threshold count", ValueTypeNum},
-		"log_sql_parsing_fail_enabled":      {"Log SQL parsing failures", ValueTypeBool},
-
-		// Object management
-		"object_deadtime_ms":          {"Object dead time threshold in ms", ValueTypeNum},
-		"object_inactive_alert_level": {"Alert level for inactive objects (0=disabled)", ValueTypeNum},
-
-		// XLog / Profile
-		"xlog_queue_size":             {"XLog queue size for real-time streaming", ValueTypeNum},
-		"xlog_realtime_lower_bound_ms": {"Minimum elapsed ms for real-time XLog", ValueTypeNum},
-		"xlog_pasttime_lower_bound_ms": {"Minimum elapsed ms for past-time XLog", ValueTypeNum},
-		"profile_queue_size":           {"Profile write queue size", ValueTypeNum},
-		"text_cache_max_size":          {"Maximum text cache entries", ValueTypeNum},
-
-		// Compression
-		"compress_xlog_enabled":    {"Enable XLog compression", ValueTypeBool},
-		"compress_profile_enabled": {"Enable profile compression", ValueTypeBool},
-
-		// Purge / Retention
-		"day_container_keep_hours":           {"Hours to keep day containers open", ValueTypeNum},
-		"mgr_purge_enabled":                 {"Enable automatic data purge", ValueTypeBool},
-		"mgr_purge_disk_usage_pct":          {"Disk usage threshold for purging", ValueTypeNum},
-		"mgr_purge_profile_keep_days":       {"Days to keep profile data", ValueTypeNum},
-		"mgr_purge_xlog_keep_days":          {"Days to keep XLog data", ValueTypeNum},
-		"mgr_purge_counter_keep_days":       {"Days to keep counter data", ValueTypeNum},
-		"mgr_purge_realtime_counter_keep_days": {"Days to keep realtime counter data", ValueTypeNum},
-		"mgr_purge_daily_text_days":         {"Days to keep daily text data", ValueTypeNum},
-		"mgr_purge_sum_data_days":           {"Days to keep summary data", ValueTypeNum},
-
-		// Text DB
-		"mgr_text_db_daily_service_enabled": {"Enable daily text DB for services", ValueTypeBool},
-		"mgr_text_db_daily_api_enabled":     {"Enable daily text DB for APIs", ValueTypeBool},
-		"mgr_text_db_daily_ua_enabled":      {"Enable daily text DB for user agents", ValueTypeBool},
-		"_mgr_text_db_index_default_mb":     {"Hash index size in MB for default text types", ValueTypeNum},
-		"_mgr_text_db_index_service_mb":     {"Hash index size in MB for service text", ValueTypeNum},
-		"_mgr_text_db_index_api_mb":         {"Hash index size in MB for API call text", ValueTypeNum},
-		"_mgr_text_db_index_ua_mb":          {"Hash index size in MB for user agent text", ValueTypeNum},
-		"_mgr_text_db_index_login_mb":       {"Hash index size in MB for login text", ValueTypeNum},
-		"_mgr_text_db_index_desc_mb":        {"Hash index size in MB for desc text", ValueTypeNum},
-		"_mgr_text_db_index_hmsg_mb":        {"Hash index size in MB for hash message text", ValueTypeNum},
-		"_mgr_text_db_daily_index_mb":       {"Hash index size in MB for daily text", ValueTypeNum},
-
-		// Directories
-		"plugin_dir":     {"Plugin directory path", ValueTypeString},
-		"plugin_enabled": {"Enable plugin system", ValueTypeBool},
-		"client_dir":     {"Client file directory path", ValueTypeString},
-		"temp_dir":       {"Temporary data directory path", ValueTypeString},
-
-		// GeoIP
-		"geoip_enabled":         {"Enable GeoIP lookups", ValueTypeBool},
-		"geoip_data_city_file":  {"GeoIP city database file path", ValueTypeString},
-
-		// SQL & features
-		"sql_table_parsing_enabled": {"Enable SQL table name parsing", ValueTypeBool},
-		"tagcnt_enabled":            {"Enable tag counting", ValueTypeBool},
-		"req_search_xlog_max_count": {"Maximum XLog count for search requests", ValueTypeNum},
-		"visitor_hourly_count_enabled": {"Enable hourly visitor counting", ValueTypeBool},
-
-		// External link
-		"ext_link_name":        {"External link display name", ValueTypeString},
-		"ext_link_url_pattern": {"External link URL pattern", ValueTypeString},
-
-		// Zipkin span ingestion
-		"zipkin_enabled": {"Enable Zipkin span ingestion (converts spans to XLog)", ValueTypeBool},
+	return maps.Clone(configMetas)
+}
+
+// ConfigKeys returns all known server config keys in sorted order.
+func ConfigKeys() []string {
+	keys := make([]string, 0, len(configMetas))
+	for k := range configMetas {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
+	return keys
+}
+
+// IsKnownKey reports whether key is a registered server config key.
+func IsKnownKey(key string) bool {
+	_, ok := configMetas[key]
+	return ok
+}
+
+// configMetas is the central registry of server config keys. Every typed
+// accessor on Config reads its default from here.
+var configMetas = map[string]ConfigMeta{
+	// Server identity
+	"server_id": {"Server ID", ValueTypeString, "0", true},
+
+	// Network – UDP
+	"net_udp_listen_ip":          {"UDP listen IP address", ValueTypeString, "0.0.0.0", false},
+	"net_udp_listen_port":        {"UDP listen port for agent data", ValueTypeNum, "6100", false},
+	"net_udp_packet_buffer_size": {"UDP packet buffer size in bytes", ValueTypeNum, "65535", false},
+	"net_udp_so_rcvbuf_size":     {"UDP socket receive buffer size in bytes", ValueTypeNum, "4194304", false},
+
+	// Network – TCP
+	"net_tcp_listen_ip":                    {"TCP listen IP address", ValueTypeString, "0.0.0.0", false},
+	"net_tcp_listen_port":                  {"TCP listen port for client connections", ValueTypeNum, "6100", false},
+	"net_tcp_client_so_timeout_ms":         {"TCP client socket timeout in ms", ValueTypeNum, "8000", false},
+	"net_tcp_agent_so_timeout_ms":          {"TCP agent socket timeout in ms", ValueTypeNum, "60000", false},
+	"net_tcp_agent_keepalive_interval_ms":  {"TCP agent keepalive interval in ms", ValueTypeNum, "5000", false},
+	"net_tcp_get_agent_connection_wait_ms": {"Wait time for agent connection in ms", ValueTypeNum, "1000", false},
+	"net_tcp_service_pool_size":            {"TCP service thread pool size", ValueTypeNum, "100", false},
+	"net_tcp_command_concurrency":          {"Per-command limits of concurrently served TCP requests, e.g. TRANX_LOAD_TIME_GROUP:2,XLOG_LOAD_BY_USERID:2 (empty = unlimited)", ValueTypeString, "", true},
+	"net_tcp_response_cache_ttl_sec":       {"Seconds responses of rarely-changing TCP commands (counter model, counter days) are reused (0 = no caching)", ValueTypeNum, "10", true},
+	"net_tcp_compress_enabled":             {"Compress large TCP responses for clients that request it at login", ValueTypeBool, "true", true},
+	"net_tcp_compress_min_bytes":           {"Response size in bytes above which TCP responses are compressed", ValueTypeNum, "32768", true},
+	"net_tcp_internal_api_enabled":         {"Let loopback clients run the commands of net_tcp_internal_api_commands without logging in", ValueTypeBool, "false", true},
+	"net_tcp_internal_api_commands":        {"Commands loopback clients may run without a session, e.g. OBJECT_LIST_REAL_TIME,COUNTER_REAL_TIME_ALL", ValueTypeString, "", true},
+	"net_tcp_internal_api_token":           {"Number session-less loopback clients must send as their session ID (empty = any)", ValueTypeString, "", true},
+	"net_tcp_tls_enabled":                  {"Serve the TCP port over TLS only", ValueTypeBool, "false", false},
+	"net_tcp_tls_cert_file":                {"PEM certificate chain of the TLS TCP port", ValueTypeString, "", false},
+	"net_tcp_tls_key_file":                 {"PEM private key of net_tcp_tls_cert_file", ValueTypeString, "", false},
+	"net_tcp_tls_client_ca_file":           {"PEM CA certificates that verify the client certificates presented on the TLS TCP port (empty = not requested)", ValueTypeString, "", false},
+	"net_tcp_tls_agent_cert_required":      {"Reject agent connections on the TLS TCP port without a client certificate verified by net_tcp_tls_client_ca_file", ValueTypeBool, "false", false},
+
+	// Network – HTTP API
+	"net_http_port":                          {"HTTP API port", ValueTypeNum, "6180", false},
+	"net_http_enabled":                       {"Enable HTTP API server", ValueTypeBool, "false", false},
+	"net_http_api_enabled":                   {"Enable HTTP API", ValueTypeBool, "false", true},
+	"net_http_api_cors_allow_origin":         {"CORS allowed origins, comma-separated; https://*.example.com matches subdomains, * any origin", ValueTypeString, "*", false},
+	"net_http_api_cors_allow_credentials":    {"CORS allow credentials header", ValueTypeString, "true", false},
+	"net_http_api_cors_route_methods":        {"CORS methods per path prefix, e.g. /api/v1/admin=GET;/api/v1/kv=GET,PUT (empty = all methods)", ValueTypeString, "", false},
+	"net_http_api_cors_max_age_sec":          {"Seconds browsers may cache CORS preflight responses (0 = no Access-Control-Max-Age)", ValueTypeNum, "600", false},
+	"net_http_api_auth_ip_enabled":           {"Enable HTTP API IP-based auth", ValueTypeBool, "false", true},
+	"net_http_api_auth_session_enabled":      {"Enable HTTP API session auth", ValueTypeBool, "false", true},
+	"net_http_api_session_timeout":           {"HTTP API session timeout in seconds", ValueTypeNum, "86400", false},
+	"net_http_api_auth_bearer_token_enabled": {"Enable HTTP API bearer token auth", ValueTypeBool, "false", true},
+	"net_http_api_token_max_ttl_days":        {"Longest lifetime of API tokens in days, also the default (0 = tokens may never expire)", ValueTypeNum, "365", true},
+	"net_http_api_gzip_enabled":              {"Enable HTTP API gzip compression", ValueTypeBool, "true", false},
+	"net_http_api_allow_ips":                 {"Allowed IPs for HTTP API access", ValueTypeString, "localhost,127.0.0.1,0:0:0:0:0:0:0:1,::1", true},
+	"net_http_api_cache_ttl_sec":             {"Seconds responses of daily counter and summary endpoints are cached (0 = no caching, ETag only)", ValueTypeNum, "30", true},
+	"net_http_api_cache_max_entries":         {"Maximum cached HTTP API responses", ValueTypeNum, "1000", true},
+	"net_http_ws_interval_ms":                {"Milliseconds between the messages of the /ws/v1 and /api/v1/alert/stream streams", ValueTypeNum, "500", true},
+	"net_http_ws_max_conns":                  {"Maximum open /ws/v1 and /api/v1/alert/stream streams (0 = unlimited)", ValueTypeNum, "100", true},
+	"net_http_api_metrics_obj_name":          {"objName under which HTTP API request counters are stored as a scouter object (empty = not stored)", ValueTypeString, "", true},
+
+	// Network – webapp TCP pool
+	"net_webapp_tcp_client_pool_size":    {"Webapp TCP client pool size", ValueTypeNum, "30", false},
+	"net_webapp_tcp_client_pool_timeout": {"Webapp TCP client pool timeout in ms", ValueTypeNum, "60000", false},
+	"net_webapp_tcp_client_so_timeout":   {"Webapp TCP client socket timeout in ms", ValueTypeNum, "30000", false},
+
+	// Network – standby cache replication
+	"standby_repl_listen":      {"Address (e.g. :6180) on which standbys are served the realtime caches of this server (empty = disabled)", ValueTypeString, "", false},
+	"standby_repl_primary":     {"host:port of the primary's standby_repl_listen to replicate the realtime caches from (empty = not a standby)", ValueTypeString, "", false},
+	"standby_repl_token":       {"Shared secret a standby presents to the primary (required by standby_repl_listen)", ValueTypeString, "", false},
+	"standby_repl_interval_ms": {"Interval between cache replication batches sent to standbys", ValueTypeNum, "2000", true},
+
+	// Database
+	"db_dir":                      {"Database directory path", ValueTypeString, "./database", false},
+	"db_keep_days":                {"Number of days to keep database files", ValueTypeNum, "30", false},
+	"db_max_disk_usage_pct":       {"Maximum disk usage percentage for database", ValueTypeNum, "80", false},
+	"db_index_check_on_open":      {"Check hash index files when a day container opens and repair corrupt tails, links and hash files", ValueTypeBool, "true", true},
+	"flush_adaptive_enabled":      {"Adapt index file flush intervals to the bytes waiting to be written", ValueTypeBool, "true", true},
+	"flush_max_interval_ms":       {"Longest a lightly written index file waits before it is flushed", ValueTypeNum, "10000", true},
+	"flush_dirty_bytes_threshold": {"Dirty bytes at which an index file is flushed on the next tick", ValueTypeNum, "8192", true},
+
+	// Logging
+	"debug":                   {"Enable debug logging", ValueTypeBool, "false", false},
+	"log_dir":                 {"Log directory path", ValueTypeString, "./logs", false},
+	"log_rotation_enabled":    {"Enable log file rotation", ValueTypeBool, "true", false},
+	"log_keep_days":           {"Number of days to keep log files", ValueTypeNum, "30", false},
+	"log_tcp_action_enabled":  {"Log TCP actions for debugging", ValueTypeBool, "false", true},
+	"log_http_access_enabled": {"Log every HTTP API request with method, path, status, latency and account", ValueTypeBool, "true", true},
+
+	// Logging – UDP debug
+	"log_udp_multipacket":               {"Log UDP multipacket debug info", ValueTypeBool, "false", true},
+	"log_expired_multipacket":           {"Log expired multipacket warnings", ValueTypeBool, "true", true},
+	"log_udp_packet":                    {"Log UDP packet debug info", ValueTypeBool, "false", true},
+	"log_udp_counter":                   {"Log UDP counter data", ValueTypeBool, "false", true},
+	"log_udp_interaction_counter":       {"Log UDP interaction counter data", ValueTypeBool, "false", true},
+	"log_udp_xlog":                      {"Log UDP XLog data", ValueTypeBool, "false", true},
+	"log_udp_profile":                   {"Log UDP profile data", ValueTypeBool, "false", true},
+	"log_udp_text":                      {"Log UDP text data", ValueTypeBool, "false", true},
+	"log_udp_alert":                     {"Log UDP alert data", ValueTypeBool, "false", true},
+	"log_udp_object":                    {"Log UDP object data", ValueTypeBool, "false", true},
+	"log_udp_status":                    {"Log UDP status data", ValueTypeBool, "false", true},
+	"log_udp_stack":                     {"Log UDP stack data", ValueTypeBool, "false", true},
+	"log_udp_summary":                   {"Log UDP summary data", ValueTypeBool, "false", true},
+	"log_udp_batch":                     {"Log UDP batch data", ValueTypeBool, "false", true},
+	"log_udp_span":                      {"Log UDP span data", ValueTypeBool, "false", true},
+	"log_index_traversal_warning_count": {"Index traversal warning threshold count", ValueTypeNum, "100", true},
+	"log_sql_parsing_fail_enabled":      {"Log SQL parsing failures", ValueTypeBool, "false", true},
+
+	// Debug – pack mirroring
+	"mirror_pack_enabled":    {"Capture incoming packs to a file under temp_dir/mirror", ValueTypeBool, "false", true},
+	"mirror_pack_sample_pct": {"Percentage of matching packs to capture", ValueTypeNum, "100", true},
+	"mirror_pack_types":      {"Pack types to capture, comma-separated (xlog,profile,text,counter,...); empty for all", ValueTypeString, "", true},
+	"mirror_pack_obj_hashes": {"Object hashes or names to capture, comma-separated; empty for all", ValueTypeString, "", true},
+	"mirror_pack_max_mb":     {"Stop capturing when the mirror file reaches this size", ValueTypeNum, "100", true},
+
+	// Debug – raw pack inspection
+	"pack_inspect_count": {"Raw packs kept per object and pack type for the admin PACK_INSPECT command; 0 disables", ValueTypeNum, "10", true},
+
+	// Testing – storage fault injection
+	"testing_fault_injection_enabled": {"Allow the admin fault command to inject latency or errors into storage reads and writes; never enable in production", ValueTypeBool, "false", true},
+
+	// Object management
+	"object_deadtime_ms":          {"Object dead time threshold in ms", ValueTypeNum, "8000", false},
+	"object_inactive_alert_level": {"Alert level for inactive objects (0=disabled)", ValueTypeNum, "0", true},
+
+	// XLog / Profile
+	"xlog_queue_size":                {"XLog queue size for real-time streaming", ValueTypeNum, "10000", false},
+	"xlog_realtime_lower_bound_ms":   {"Minimum elapsed ms for real-time XLog", ValueTypeNum, "0", true},
+	"xlog_pasttime_lower_bound_ms":   {"Minimum elapsed ms for past-time XLog", ValueTypeNum, "0", true},
+	"xlog_gxid_adjacent_days":        {"Days before and after the requested date also searched by gxid reads (max 7)", ValueTypeNum, "1", true},
+	"xlog_userid_index_enabled":      {"Index XLogs by userid for XLOG_LOAD_BY_USERID (adds a key index of about 1MB plus 30 bytes per XLog to each day)", ValueTypeBool, "false", true},
+	"xlog_secondary_index_enabled":   {"Index XLogs by objHash and service per minute so range reads filtered by them skip other XLogs (adds a key index of about 2MB plus 70 bytes per XLog to each day)", ValueTypeBool, "false", true},
+	"xlog_heatmap_enabled":           {"Maintain per-5-minute elapsed-time heatmaps per objType", ValueTypeBool, "true", false},
+	"xlog_sampling_rate":             {"Percent of normal XLogs below xlog_sampling_elapsed_ms that are stored (100 = all); errors are always stored", ValueTypeNum, "100", true},
+	"xlog_sampling_elapsed_ms":       {"Elapsed ms from which XLogs are always stored regardless of xlog_sampling_rate", ValueTypeNum, "1000", true},
+	"xlog_sampling_exclude_patterns": {"Comma-separated service name patterns whose XLogs are never sampled out, e.g. /order/*,/pay/* (path.Match syntax)", ValueTypeString, "", true},
+	"slo_enabled":                    {"Evaluate service-level objectives from the XLog stream", ValueTypeBool, "true", false},
+	"alert_rules_enabled":            {"Evaluate the counter alert rules on realtime counters as they arrive", ValueTypeBool, "true", false},
+	"profile_queue_size":             {"Profile write queue size", ValueTypeNum, "1000", false},
+	"profile_single_pack_max_bytes":  {"Maximum profile bytes returned as one pack by TRANX_PROFILE (0 = unlimited); larger profiles need TRANX_PROFILE_CHUNKED", ValueTypeNum, "33554432", true},
+	"profile_stream_chunk_bytes":     {"Target chunk size of TRANX_PROFILE_STREAM and TRANX_PROFILE_CHUNKED responses", ValueTypeNum, "262144", true},
+	"profile_stream_max_bytes":       {"Maximum profile bytes sent by TRANX_PROFILE_STREAM and TRANX_PROFILE_CHUNKED (0 = unlimited)", ValueTypeNum, "536870912", true},
+	"text_cache_max_size":            {"Maximum text cache entries", ValueTypeNum, "100000", false},
+
+	// Compression
+	"compress_xlog_enabled":    {"Store new XLog records zstd-compressed where that makes them smaller", ValueTypeBool, "false", true},
+	"compress_profile_enabled": {"Store new profile blocks zstd-compressed where that makes them smaller", ValueTypeBool, "true", true},
+	"xlog_field_dict_enabled":  {"Store XLog objHash/service as per-day dictionary indexes and EndTime as a delta", ValueTypeBool, "false", true},
+
+	// Purge / Retention
+	"day_container_keep_hours":                   {"Hours to keep day containers open", ValueTypeNum, "48", false},
+	"mgr_compaction_hour":                        {"Hour of day (0-23) at which hash indexes are compacted, dropping deleted and expired records (-1 = never)", ValueTypeNum, "4", true},
+	"mgr_compaction_min_dropped_pct":             {"Percentage of deleted and expired records from which an index is rewritten by the compaction", ValueTypeNum, "20", true},
+	"mgr_purge_enabled":                          {"Enable automatic data purge", ValueTypeBool, "true", false},
+	"mgr_purge_disk_usage_pct":                   {"Disk usage threshold for purging", ValueTypeNum, "80", false},
+	"mgr_purge_profile_keep_days":                {"Days to keep profile data", ValueTypeNum, "10", false},
+	"mgr_purge_profile_error_keep_days":          {"Days to keep the profiles of errored transactions when above mgr_purge_profile_keep_days (0 = same as others)", ValueTypeNum, "0", false},
+	"mgr_purge_profile_slow_keep_days":           {"Days to keep the profiles of slow transactions when above mgr_purge_profile_keep_days (0 = same as others)", ValueTypeNum, "0", false},
+	"mgr_purge_profile_slow_ms":                  {"Elapsed time in ms from which a transaction counts as slow for mgr_purge_profile_slow_keep_days", ValueTypeNum, "8000", false},
+	"mgr_purge_xlog_keep_days":                   {"Days to keep XLog data", ValueTypeNum, "30", false},
+	"mgr_purge_counter_keep_days":                {"Days to keep counter data", ValueTypeNum, "70", false},
+	"mgr_purge_realtime_counter_keep_days":       {"Days to keep realtime counter data", ValueTypeNum, "70", false},
+	"mgr_purge_realtime_counter_downsample_days": {"Days to keep realtime counter data at 1-minute resolution after mgr_purge_realtime_counter_keep_days (0: delete)", ValueTypeNum, "0", false},
+	"mgr_purge_daily_text_days":                  {"Days to keep daily text data", ValueTypeNum, "140", false},
+	"mgr_purge_daily_text_days_by_div":           {"Per-div days to keep daily text as div:days pairs, e.g. ua:7,apicall:30; expired divs are removed by compacting the day (empty = mgr_purge_daily_text_days for every div)", ValueTypeString, "", false},
+	"mgr_purge_trash_hours":                      {"Hours data removed by manual purges (SERVER_DB_PURGE, SERVER_DB_DELETE, /api/v1/admin/purge, purge CLI) stays restorable in the .trash directory before permanent removal (0 = delete immediately)", ValueTypeNum, "24", true},
+	"mgr_purge_sum_data_days":                    {"Days to keep summary data", ValueTypeNum, "60", false},
+
+	// Text DB
+	"mgr_text_db_daily_service_enabled": {"Enable daily text DB for services", ValueTypeBool, "false", true},
+	"mgr_text_db_daily_api_enabled":     {"Enable daily text DB for APIs", ValueTypeBool, "false", true},
+	"mgr_text_db_daily_ua_enabled":      {"Enable daily text DB for user agents", ValueTypeBool, "false", true},
+	"_mgr_text_db_index_default_mb":     {"Hash index size in MB for default text types", ValueTypeNum, "1", false},
+	"_mgr_text_db_index_service_mb":     {"Hash index size in MB for service text", ValueTypeNum, "1", false},
+	"_mgr_text_db_index_api_mb":         {"Hash index size in MB for API call text", ValueTypeNum, "1", false},
+	"_mgr_text_db_index_ua_mb":          {"Hash index size in MB for user agent text", ValueTypeNum, "1", false},
+	"_mgr_text_db_index_login_mb":       {"Hash index size in MB for login text", ValueTypeNum, "1", false},
+	"_mgr_text_db_index_desc_mb":        {"Hash index size in MB for desc text", ValueTypeNum, "1", false},
+	"_mgr_text_db_index_hmsg_mb":        {"Hash index size in MB for hash message text", ValueTypeNum, "1", false},
+	"_mgr_text_db_daily_index_mb":       {"Hash index size in MB for daily text", ValueTypeNum, "1", false},
+	"mgr_text_max_length":               {"Longest text in bytes stored for any text type; longer texts are cut (0 = unlimited)", ValueTypeNum, "0", true},
+	"mgr_text_max_length_by_type":       {"Per-type text length limits overriding mgr_text_max_length, e.g. sql:8192,error:2048", ValueTypeString, "", true},
+	"mgr_text_sql_collapse_literals":    {"Replace string and numeric literals in SQL texts with ? before storing them", ValueTypeBool, "false", true},
+	"mgr_text_url_strip_query":          {"Drop the query string and fragment of service, apicall and referer texts before storing them", ValueTypeBool, "false", true},
+
+	// Directories
+	"plugin_dir":         {"Plugin directory path", ValueTypeString, "./plugin", true},
+	"plugin_enabled":     {"Enable plugin system", ValueTypeBool, "true", true},
+	"client_dir":         {"Client file directory path", ValueTypeString, "./client", false},
+	"temp_dir":           {"Temporary data directory path", ValueTypeString, "./tempdata", false},
+	"account_import_dir": {"Java server conf directory whose account.xml and account_group.xml seed the accounts when this server has none yet", ValueTypeString, "", false},
+
+	// GeoIP
+	"geoip_enabled":               {"Enable GeoIP lookups", ValueTypeBool, "true", false},
+	"geoip_data_city_file":        {"Legacy GeoIP city database (.dat) path, used when no mmdb is available", ValueTypeString, "./conf/GeoLiteCity.dat", false},
+	"geoip_data_city_mmdb_file":   {"GeoIP2/GeoLite2 City or Country database (.mmdb) path", ValueTypeString, "./conf/GeoLite2-City.mmdb", false},
+	"geoip_data_asn_mmdb_file":    {"GeoLite2 ASN database (.mmdb) path", ValueTypeString, "./conf/GeoLite2-ASN.mmdb", false},
+	"geoip_update_enabled":        {"Periodically download fresh GeoIP mmdb databases", ValueTypeBool, "false", false},
+	"geoip_update_interval_hours": {"GeoIP database download interval in hours", ValueTypeNum, "24", false},
+	"geoip_update_url":            {"GeoIP download URL; {edition} is replaced by the edition ID", ValueTypeString, "https://download.maxmind.com/geoip/databases/{edition}/download?suffix=tar.gz", false},
+	"geoip_account_id":            {"MaxMind account ID for GeoIP downloads", ValueTypeString, "", false},
+	"geoip_license_key":           {"MaxMind license key for GeoIP downloads", ValueTypeString, "", false},
+	"geoip_update_city_edition":   {"GeoIP city edition to download (empty = skip)", ValueTypeString, "GeoLite2-City", false},
+	"geoip_update_asn_edition":    {"GeoIP ASN edition to download (empty = skip)", ValueTypeString, "GeoLite2-ASN", false},
+
+	// SQL & features
+	"sql_table_parsing_enabled":    {"Enable SQL table name parsing", ValueTypeBool, "true", false},
+	"tagcnt_enabled":               {"Enable tag counting", ValueTypeBool, "true", false},
+	"sql_top_enabled":              {"Rank the slowest SQL statements of every hour from SQL summaries", ValueTypeBool, "true", false},
+	"sql_top_n":                    {"SQL statements kept per hour and returned by default by SQL_TOP_HOURLY", ValueTypeNum, "50", true},
+	"profile_stat_enabled":         {"Break the time of every service down by step type in 5-minute buckets from profiles", ValueTypeBool, "true", false},
+	"req_search_xlog_max_count":    {"Maximum XLog count for search requests", ValueTypeNum, "500", true},
+	"req_result_max_rows":          {"Maximum rows (XLogs, summary records, counter points) one XLog, counter or summary request returns before it is cut with a truncated pack (0 = unlimited)", ValueTypeNum, "1000000", true},
+	"req_result_max_bytes":         {"Maximum response bytes of one XLog, counter or summary request before it is cut with a truncated pack (0 = unlimited)", ValueTypeNum, "268435456", true},
+	"visitor_hourly_count_enabled": {"Enable hourly visitor counting", ValueTypeBool, "true", false},
+	"counter_check_enabled":        {"Compare cached realtime counters with persisted ones every minute and log divergence", ValueTypeBool, "false", true},
+	"counter_check_skew_ms":        {"Pack time vs receive time difference reported as clock skew by the counter check", ValueTypeNum, "5000", true},
+	"kv_namespace_max":             {"Maximum number of client-created KV namespaces (0 = unlimited)", ValueTypeNum, "100", true},
+	"kv_account_quota_bytes":       {"Size limit in bytes of each account's private KV store (0 = unlimited)", ValueTypeNum, "1048576", true},
+	"clock_skew_check_enabled":     {"Detect agents whose pack time drifts from the server clock", ValueTypeBool, "true", true},
+	"clock_skew_threshold_ms":      {"Pack time vs server time difference treated as agent clock skew", ValueTypeNum, "60000", true},
+	"clock_skew_alert_level":       {"Alert level of CLOCK_SKEW (0=INFO, 1=WARN, 2=ERROR, 3=FATAL)", ValueTypeNum, "1", true},
+	"clock_skew_correct_enabled":   {"Replace the time of skewed packs with the server receive time", ValueTypeBool, "false", true},
+	"ingest_quota_xlog_per_sec":    {"Per-objType XLog packs accepted per second, e.g. tomcat:2000,*:500 (empty = unlimited)", ValueTypeString, "", true},
+	"ingest_quota_profile_per_sec": {"Per-objType profile packs accepted per second, e.g. tomcat:500,*:100 (empty = unlimited)", ValueTypeString, "", true},
+	"object_alias":                 {"Object alias rules as pattern=name pairs, e.g. /order-api-*/tomcat=/order-api/tomcat (path.Match syntax, empty = none)", ValueTypeString, "", true},
+	"object_auto_group":            {"Object group rules as regex => group separated by ';', matched against objName, e.g. ^/[^/]+/(checkout|cart)-\\d+$ => $1 (empty = explicit groups only)", ValueTypeString, "", true},
+	"deploy_window_max_minutes":    {"Longest deployment window that can be opened through /api/v1/deploy-window, in minutes (0 = unlimited)", ValueTypeNum, "120", true},
+	"ingest_latency_obj_name":      {"Object name under which ingest latency per pack type is stored as counters (empty = not stored)", ValueTypeString, "", true},
+	"ingest_latency_alert_p99_ms":  {"p99 receive-to-indexed latency of a pack type that raises INGEST_LATENCY (0 = no alert)", ValueTypeNum, "10000", true},
+	"ingest_latency_alert_level":   {"Alert level of INGEST_LATENCY (0=INFO, 1=WARN, 2=ERROR, 3=FATAL)", ValueTypeNum, "1", true},
+	"io_stats_obj_name":            {"Object name under which storage read/write counts, latency and index chain depth are stored as counters (empty = not stored)", ValueTypeString, "", true},
+	"self_monitor_enabled":         {"Register the server as a scouter-server object and store its UDP, queue, flush, goroutine and heap counters", ValueTypeBool, "true", true},
+	"self_monitor_obj_name":        {"Object name of the server's own object (empty = /{hostname}/scouter-server)", ValueTypeString, "", true},
+	"read_only_retry_after_sec":    {"Retry-After seconds returned by the write APIs while the server is read-only for maintenance", ValueTypeNum, "60", true},
+
+	// Reports
+	"report_enabled":  {"Generate scheduled daily/weekly reports", ValueTypeBool, "false", true},
+	"report_schedule": {"Reports to generate, comma-separated: daily, weekly", ValueTypeString, "weekly", true},
+	"report_hour":     {"Hour of day (0-23) from which the report of the previous period is generated", ValueTypeNum, "8", true},
+	"report_dir":      {"Directory reports are written to", ValueTypeString, "./report", true},
+	"report_top_n":    {"Number of services and alerts listed in a report", ValueTypeNum, "10", true},
+	"report_mail_to":  {"Report mail recipients, comma-separated; empty to only write files", ValueTypeString, "", true},
+
+	// Notification channels
+	"notify_smtp_addr":        {"SMTP server (host:port) used to send notification mails", ValueTypeString, "", true},
+	"notify_smtp_user":        {"SMTP PLAIN auth user; empty for no auth", ValueTypeString, "", true},
+	"notify_smtp_password":    {"SMTP PLAIN auth password", ValueTypeString, "", true},
+	"notify_smtp_tls":         {"SMTP connection security: starttls (upgrade when offered), tls (TLS from the start, usually port 465) or none", ValueTypeString, "starttls", true},
+	"notify_mail_from":        {"Sender address of notification mails", ValueTypeString, "scouter@localhost", true},
+	"notify_retry_count":      {"Retries of a failed notification", ValueTypeNum, "2", true},
+	"notify_retry_backoff_ms": {"Wait before the first retry of a failed notification, doubled for each further retry", ValueTypeNum, "5000", true},
+
+	// Alert webhooks
+	"alert_webhook_urls":             {"HTTP endpoints alerts are posted to as JSON, comma-separated (empty = off)", ValueTypeString, "", true},
+	"alert_webhook_min_level":        {"Lowest alert level posted to the webhooks (0=INFO, 1=WARN, 2=ERROR, 3=FATAL)", ValueTypeNum, "1", true},
+	"alert_webhook_obj_types":        {"Object types whose alerts are posted, comma-separated (empty = all)", ValueTypeString, "", true},
+	"alert_webhook_template":         {"JSON payload with ${level}, ${levelNum}, ${title}, ${message}, ${objName}, ${objType}, ${objHash}, ${time} and ${timeIso} placeholders (empty = built-in)", ValueTypeString, "", true},
+	"alert_webhook_retry_count":      {"Retries of a failed webhook post before it goes to the dead letter log", ValueTypeNum, "3", true},
+	"alert_webhook_retry_backoff_ms": {"Wait before the first retry of a webhook post, doubled for each further retry", ValueTypeNum, "1000", true},
+	"alert_webhook_timeout_ms":       {"Timeout of one webhook post in ms", ValueTypeNum, "5000", true},
+
+	// Alert mails
+	"alert_mail_to":                  {"Alert mail recipients as objType=addr,addr entries separated by ';', * for unlisted types, e.g. *=ops@example.com;tomcat=was@example.com (empty = off)", ValueTypeString, "", true},
+	"alert_mail_min_level":           {"Lowest alert level mailed (0=INFO, 1=WARN, 2=ERROR, 3=FATAL)", ValueTypeNum, "1", true},
+	"alert_mail_immediate_level":     {"Lowest alert level mailed at once; lower levels go to the digest", ValueTypeNum, "2", true},
+	"alert_mail_digest_interval_min": {"Minutes between digest mails of lower-level alerts (0 = mail every alert at once)", ValueTypeNum, "60", true},
+
+	// External link
+	"ext_link_name":        {"External link display name", ValueTypeString, "scouter-paper", true},
+	"ext_link_url_pattern": {"External link URL pattern", ValueTypeString, "", true},
+
+	// Zipkin span ingestion
+	"zipkin_enabled":         {"Enable Zipkin span ingestion (converts spans to XLog)", ValueTypeBool, "false", false},
+	"zipkin_service_mapping": {"Span grouping rules as [tag~]regex => objType:objName separated by ';', e.g. ^(order|payment)-.* => mesh:/mesh/$1 (empty = the object sent with the span)", ValueTypeString, "", true},
+	"zipkin_summary_enabled": {"Synthesize service/SQL/API call summaries from Zipkin spans", ValueTypeBool, "true", true},
+
+	// OTLP trace ingestion
+	"otlp_enabled":   {"Enable the OTLP trace receiver (spans go through the Zipkin span pipeline)", ValueTypeBool, "false", false},
+	"otlp_grpc_port": {"OTLP/gRPC port (0 = not listening)", ValueTypeNum, "4317", false},
+	"otlp_http_port": {"OTLP/HTTP port (0 = not listening)", ValueTypeNum, "4318", false},
+	"otlp_obj_type":  {"objType of the objects registered for OTLP resources", ValueTypeString, "otel", true},
+
+	// Synthetic health checks
+	"probe_enabled":      {"Run the synthetic HTTP checks of probe_targets, stored as XLogs and counters", ValueTypeBool, "false", false},
+	"probe_targets":      {"Endpoints to check as name=url separated by ';', e.g. orders=https://orders.example.com/health", ValueTypeString, "", true},
+	"probe_interval_sec": {"Seconds between two checks of every probe target", ValueTypeNum, "30", true},
+	"probe_timeout_ms":   {"Time after which a probe check fails", ValueTypeNum, "5000", true},
+	"probe_obj_type":     {"objType of the objects registered for probe targets", ValueTypeString, "synthetic", true},
+
+	// Backup to S3-compatible storage
+	"backup_enabled":          {"Upload every previous day, once and again after it changes, as an export archive to backup_s3_bucket", ValueTypeBool, "false", false},
+	"backup_interval_minutes": {"Minutes between two backup runs", ValueTypeNum, "60", true},
+	"backup_types":            {"Data types of the uploaded days, comma-separated, or all", ValueTypeString, "all", true},
+	"backup_keep_days":        {"Days after which uploaded archives are deleted from the bucket (0 = kept)", ValueTypeNum, "0", true},
+	"backup_storage_class":    {"Storage class of the uploaded archives, e.g. STANDARD_IA or GLACIER_IR (empty = the bucket's default)", ValueTypeString, "", true},
+	"backup_restore_on_miss":  {"Download and import a previous day from the bucket when a read finds it missing locally", ValueTypeBool, "false", true},
+	"backup_s3_endpoint":      {"S3 endpoint URL, e.g. https://s3.ap-northeast-2.amazonaws.com or http://minio:9000", ValueTypeString, "", true},
+	"backup_s3_region":        {"S3 region the requests are signed for", ValueTypeString, "us-east-1", true},
+	"backup_s3_bucket":        {"Bucket the archives are uploaded to", ValueTypeString, "", true},
+	"backup_s3_prefix":        {"Key prefix of the archives, named {prefix}{YYYYMMDD}.tar.zst", ValueTypeString, "scouter/", true},
+	"backup_s3_access_key":    {"S3 access key ID", ValueTypeString, "", true},
+	"backup_s3_secret_key":    {"S3 secret access key", ValueTypeString, "", true},
+	"backup_s3_path_style":    {"Address the bucket in the URL path instead of the host name, as MinIO needs", ValueTypeBool, "true", true},
 }
@@ -3,7 +3,10 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func writeTempConf(t *testing.T, content string) string {
@@ -245,3 +248,209 @@ func TestGetInt64(t *testing.T) {
 		t.Errorf("expected default -1, got %d", cfg.GetInt64("missing", -1))
 	}
 }
+
+func TestRegistry_DefaultsMatchValueType(t *testing.T) {
+	for key, meta := range ConfigMetaMap() {
+		switch meta.ValueType {
+		case ValueTypeNum:
+			if _, err := strconv.Atoi(meta.Default); err != nil {
+				t.Errorf("%s: numeric default %q does not parse", key, meta.Default)
+			}
+		case ValueTypeBool:
+			if meta.Default != "true" && meta.Default != "false" {
+				t.Errorf("%s: bool default %q must be true or false", key, meta.Default)
+			}
+		}
+	}
+}
+
+func TestRegistry_AccessorsUseRegisteredKeys(t *testing.T) {
+	cfg, _ := Load(filepath.Join(t.TempDir(), "missing.conf"))
+	// Accessors panic on unregistered keys; call every no-arg accessor.
+	v := reflect.ValueOf(cfg)
+	for i := 0; i < v.NumMethod(); i++ {
+		m := v.Method(i)
+		if m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+			continue
+		}
+		name := v.Type().Method(i).Name
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%s: %v", name, r)
+				}
+			}()
+			m.Call(nil)
+		}()
+	}
+	for _, div := range []string{"service", "apicall", "ua", "login", "desc", "hmsg", "other"} {
+		cfg.MgrTextDbIndexMB(div)
+	}
+
+	if cfg.UDPPort() != 6100 || !cfg.CompressProfileEnabled() || cfg.DBDir() != "./database" {
+		t.Error("registry defaults not applied")
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	path := writeTempConf(t, "net_udp_listen_port=7100\nnet_udp_lisen_port=7200\ncustom_thing=1\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown := cfg.UnknownKeys()
+	if len(unknown) != 2 || unknown[0] != "custom_thing" || unknown[1] != "net_udp_lisen_port" {
+		t.Errorf("unexpected unknown keys: %v", unknown)
+	}
+}
+
+func TestReloadBy_History(t *testing.T) {
+	path := writeTempConf(t, "mgr_purge_xlog_keep_days=30\nnet_tcp_listen_port=6100\n")
+	if _, err := Load(path); err != nil {
+		t.Fatal(err)
+	}
+	since := time.Now()
+
+	if err := Reload(path); err != nil {
+		t.Fatal(err)
+	}
+	if h := History(since); len(h) != 0 {
+		t.Fatalf("unchanged reload recorded: %+v", h)
+	}
+
+	os.WriteFile(path, []byte("mgr_purge_xlog_keep_days=3\nmgr_purge_disk_usage_pct=70\n"), 0644)
+	if err := ReloadBy(path, "admin@10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	h := History(since)
+	if len(h) != 1 || h[0].Source != "admin@10.0.0.1" {
+		t.Fatalf("history = %+v", h)
+	}
+	want := []KeyChange{
+		{Key: "mgr_purge_disk_usage_pct", New: "70", Added: true},
+		{Key: "mgr_purge_xlog_keep_days", Old: "30", New: "3"},
+		{Key: "net_tcp_listen_port", Old: "6100", Removed: true},
+	}
+	if !reflect.DeepEqual(h[0].Keys, want) {
+		t.Errorf("keys = %+v, want %+v", h[0].Keys, want)
+	}
+	if h := History(time.Now().Add(time.Second)); len(h) != 0 {
+		t.Errorf("History(future) = %+v", h)
+	}
+}
+
+func TestSave_KeepsSymlink(t *testing.T) {
+	target := writeTempConf(t, "mgr_purge_xlog_keep_days=30\n")
+	link := filepath.Join(t.TempDir(), "scouter.conf")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	if _, err := Load(link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Save(link, "mgr_purge_xlog_keep_days=5\n", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("link replaced by a regular file: %v, %v", info, err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "mgr_purge_xlog_keep_days=5\n" {
+		t.Errorf("target content = %q", data)
+	}
+	if got := Get().MgrPurgeXLogKeepDays(); got != 5 {
+		t.Errorf("keep days after save = %d, want 5", got)
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	if got := EnvName("net_tcp_listen_port"); got != "SCOUTER_NET_TCP_LISTEN_PORT" {
+		t.Errorf("EnvName = %q", got)
+	}
+	t.Setenv("SCOUTER_NET_TCP_LISTEN_PORT", "7200")
+	t.Setenv("SCOUTER_DEBUG", " true ")
+	t.Setenv("SCOUTER_NOT_A_KEY", "x")
+
+	path := writeTempConf(t, "net_tcp_listen_port=6100\nserver_id=3\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.TCPPort(); got != 7200 {
+		t.Errorf("TCPPort = %d, want the environment's 7200", got)
+	}
+	if !cfg.IsDebug() {
+		t.Error("debug should be overridden by the environment")
+	}
+	if got := cfg.ServerID(); got != "3" {
+		t.Errorf("ServerID = %q, want the file's 3", got)
+	}
+	if got := cfg.EnvOverrides(); !reflect.DeepEqual(got, []string{"debug", "net_tcp_listen_port"}) {
+		t.Errorf("EnvOverrides = %v", got)
+	}
+
+	// Without a file the environment still applies.
+	cfg, _ = Load(filepath.Join(t.TempDir(), "missing.conf"))
+	if got := cfg.TCPPort(); got != 7200 {
+		t.Errorf("TCPPort without file = %d, want 7200", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	t.Setenv("SCOUTER_NET_TCP_TLS_ENABLED", "true")
+	cfg, issues := Check(`
+net_tcp_listen_port=61OO
+debug=maybe
+net_udp_lisen_port=6100
+db_keep_days=20
+db_keep_days=40
+mgr_purge_xlog_keep_days=50
+mgr_purge_profile_keep_days=60
+standby_repl_listen=:6180
+`)
+	got := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		got = append(got, issue.Level+" "+issue.Key)
+	}
+	want := []string{
+		"warning db_keep_days",
+		"error debug",
+		"warning mgr_purge_counter_keep_days",
+		"warning mgr_purge_daily_text_days",
+		"warning mgr_purge_profile_keep_days", // exceeds db_keep_days
+		"warning mgr_purge_profile_keep_days", // exceeds mgr_purge_xlog_keep_days
+		"warning mgr_purge_realtime_counter_keep_days",
+		"warning mgr_purge_sum_data_days",
+		"warning mgr_purge_xlog_keep_days",
+		"error net_tcp_listen_port",
+		"error net_tcp_tls_enabled",
+		"warning net_udp_lisen_port",
+		"error standby_repl_token",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %v\nwant %v", issues, want)
+	}
+
+	sources := make(map[string]Setting)
+	for _, s := range cfg.Effective() {
+		sources[s.Key] = s
+	}
+	for key, want := range map[string]Setting{
+		"db_keep_days":        {"db_keep_days", "40", "file"},
+		"net_tcp_tls_enabled": {"net_tcp_tls_enabled", "true", "env"},
+		"server_id":           {"server_id", "0", "default"},
+	} {
+		if sources[key] != want {
+			t.Errorf("effective %s = %+v, want %+v", key, sources[key], want)
+		}
+	}
+
+	// Defaults alone are consistent.
+	t.Setenv("SCOUTER_NET_TCP_TLS_ENABLED", "false")
+	if _, issues := Check(""); len(issues) != 0 {
+		t.Errorf("defaults have issues: %v", issues)
+	}
+	if !IsSecretKey("notify_smtp_password") || IsSecretKey("net_http_api_token_max_ttl_days") {
+		t.Error("IsSecretKey")
+	}
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxHistory is the number of configuration changes kept in memory.
+const maxHistory = 100
+
+// KeyChange is one key that differs between two loads of the config file.
+// Old is "" for an added key and New is "" for a removed one.
+type KeyChange struct {
+	Key     string
+	Old     string
+	New     string
+	Added   bool
+	Removed bool
+}
+
+// Change is a reload of the config file that changed at least one key.
+type Change struct {
+	Time   time.Time
+	Source string // "file" for changes found by the watcher, else who applied them
+	Keys   []KeyChange
+}
+
+var history struct {
+	mu      sync.Mutex
+	changes []Change
+}
+
+// History returns the recorded changes made at or after since, oldest first.
+func History(since time.Time) []Change {
+	history.mu.Lock()
+	defer history.mu.Unlock()
+	i := sort.Search(len(history.changes), func(i int) bool {
+		return !history.changes[i].Time.Before(since)
+	})
+	return append([]Change(nil), history.changes[i:]...)
+}
+
+// recordChange diffs old against cur and keeps the result, if any.
+func recordChange(source string, old, cur *Config) {
+	if old == nil || cur == nil {
+		return
+	}
+	old.mu.RLock()
+	cur.mu.RLock()
+	keys := diffProps(old.props, cur.props)
+	cur.mu.RUnlock()
+	old.mu.RUnlock()
+	if len(keys) == 0 {
+		return
+	}
+	for _, k := range keys {
+		slog.Info("config changed", "source", source, "key", k.Key, "old", k.Old, "new", k.New)
+	}
+
+	history.mu.Lock()
+	defer history.mu.Unlock()
+	history.changes = append(history.changes, Change{Time: time.Now(), Source: source, Keys: keys})
+	if n := len(history.changes) - maxHistory; n > 0 {
+		history.changes = append(history.changes[:0], history.changes[n:]...)
+	}
+}
+
+// diffProps returns the keys that differ between old and cur, sorted by key.
+func diffProps(old, cur map[string]string) []KeyChange {
+	var keys []KeyChange
+	for k, v := range cur {
+		if ov, ok := old[k]; !ok {
+			keys = append(keys, KeyChange{Key: k, New: v, Added: true})
+		} else if ov != v {
+			keys = append(keys, KeyChange{Key: k, Old: ov, New: v})
+		}
+	}
+	for k, v := range old {
+		if _, ok := cur[k]; !ok {
+			keys = append(keys, KeyChange{Key: k, Old: v, Removed: true})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+	return keys
+}
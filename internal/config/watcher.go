@@ -4,6 +4,8 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"sync"
 	"time"
 )
 
@@ -27,15 +29,80 @@ func StartWatcher(ctx context.Context, filePath string, interval time.Duration)
 					continue
 				}
 				if info.ModTime().After(current.modTime) {
-					newCfg, err := Load(filePath)
-					if err != nil {
+					if err := Reload(filePath); err != nil {
 						slog.Error("config reload failed", "error", err)
-						continue
 					}
-					globalConfig.Store(newCfg)
-					slog.Info("config reloaded", "file", filePath)
 				}
 			}
 		}
 	}()
 }
+
+// Reload re-reads filePath immediately and makes it the global config.
+func Reload(filePath string) error {
+	return ReloadBy(filePath, "file")
+}
+
+// ReloadBy is Reload that records source, such as the user who saved the
+// file, as the origin of the keys it changes in History.
+func ReloadBy(filePath, source string) error {
+	old := Get()
+	newCfg, err := Load(filePath)
+	if err != nil {
+		return err
+	}
+	globalConfig.Store(newCfg)
+	recordChange(source, old, newCfg)
+	slog.Info("config reloaded", "file", filePath)
+	return nil
+}
+
+// saveMu serializes Save so concurrent edits cannot interleave.
+var saveMu sync.Mutex
+
+// Save replaces the file at filePath with content and reloads it, recording
+// source as in ReloadBy. The content is written to a temporary file next to
+// the file, synced and renamed over it, so the server and its watcher never
+// read a half-written configuration and a failed write leaves the old file
+// in place. The file keeps its permissions, and a symlinked file is replaced
+// at its target so the link stays.
+func Save(filePath, content, source string) error {
+	saveMu.Lock()
+	defer saveMu.Unlock()
+
+	target := filePath
+	if resolved, err := filepath.EvalSymlinks(filePath); err == nil {
+		target = resolved
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(target); err == nil {
+		mode = info.Mode().Perm()
+	}
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.WriteString(content)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, target)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return ReloadBy(filePath, source)
+}
@@ -4,12 +4,16 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/counter"
 	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/agentinv"
+	"github.com/zbum/scouter-server-go/internal/db/objevent"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
 	"github.com/zbum/scouter-server-go/internal/util"
 )
 
@@ -21,6 +25,8 @@ type AgentManager struct {
 	alertCore   *AlertCore
 	deadTimeout time.Duration
 	typeManager *counter.ObjectTypeManager
+	events      atomic.Pointer[objevent.Store]
+	inventory   atomic.Pointer[agentinv.Store]
 }
 
 func NewAgentManager(objectCache *cache.ObjectCache, deadTimeout time.Duration, typeManager *counter.ObjectTypeManager, textCache *cache.TextCache, textCore *TextCore, alertCore *AlertCore) *AgentManager {
@@ -36,6 +42,30 @@ func NewAgentManager(objectCache *cache.ObjectCache, deadTimeout time.Duration,
 	return am
 }
 
+// SetEvents installs s to record object lifecycle events.
+func (am *AgentManager) SetEvents(s *objevent.Store) {
+	am.events.Store(s)
+}
+
+// SetInventory installs s to track agent versions and capabilities.
+func (am *AgentManager) SetInventory(s *agentinv.Store) {
+	am.inventory.Store(s)
+}
+
+// emit records a lifecycle event of op if an event store is installed.
+func (am *AgentManager) emit(typ string, op *pack.ObjectPack, oldName string) {
+	if s := am.events.Load(); s != nil {
+		s.Add(objevent.Event{
+			Type:    typ,
+			ObjHash: op.ObjHash,
+			ObjName: op.ObjName,
+			ObjType: op.ObjType,
+			Address: op.Address,
+			OldName: oldName,
+		})
+	}
+}
+
 func (am *AgentManager) Handler() PackHandler {
 	return func(p pack.Pack, addr *net.UDPAddr) {
 		op, ok := p.(*pack.ObjectPack)
@@ -51,8 +81,11 @@ func (am *AgentManager) Handler() PackHandler {
 
 		// Check if this agent was previously dead (for ACTIVATED_OBJECT alert)
 		wasDead := false
-		if existing, ok := am.objectCache.Get(op.ObjHash); ok {
+		existing, known := am.objectCache.Get(op.ObjHash)
+		oldName := ""
+		if known {
 			wasDead = !existing.Pack.Alive
+			oldName = existing.Pack.ObjName
 		}
 
 		op.Alive = true
@@ -63,6 +96,19 @@ func (am *AgentManager) Handler() PackHandler {
 		}
 
 		am.objectCache.Put(op.ObjHash, op)
+		if inv := am.inventory.Load(); inv != nil {
+			inv.Update(inventoryAgent(op), op.Wakeup)
+		}
+
+		switch {
+		case !known:
+			am.emit(objevent.Registered, op, "")
+		case wasDead:
+			am.emit(objevent.Recovered, op, "")
+		}
+		if known && op.ObjName != "" && oldName != "" && op.ObjName != oldName {
+			am.emit(objevent.Renamed, op, oldName)
+		}
 
 		// Generate ACTIVATED_OBJECT alert if agent was previously dead
 		if wasDead && am.alertCore != nil {
@@ -94,32 +140,66 @@ func (am *AgentManager) Handler() PackHandler {
 	}
 }
 
+// inventoryAgent describes op for the agent inventory. The OS comes from the
+// "os" tag; every tag set to boolean true is a capability flag.
+func inventoryAgent(op *pack.ObjectPack) agentinv.Agent {
+	a := agentinv.Agent{
+		ObjHash: op.ObjHash,
+		ObjName: op.ObjName,
+		ObjType: op.ObjType,
+		Address: op.Address,
+		Version: op.Version,
+	}
+	if op.Tags == nil {
+		return a
+	}
+	for _, e := range op.Tags.Entries {
+		switch tv := e.Value.(type) {
+		case *value.TextValue:
+			if e.Key == pack.TagOS {
+				a.OS = tv.Value
+			}
+		case *value.BooleanValue:
+			if tv.Value {
+				a.Capabilities = append(a.Capabilities, e.Key)
+			}
+		}
+	}
+	return a
+}
+
 func (am *AgentManager) monitorLoop() {
 	slog.Info("AgentManager monitorLoop started", "deadTimeout", am.deadTimeout)
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 	for range ticker.C {
-		dead := am.objectCache.MarkDead(am.deadTimeout)
-		for _, d := range dead {
-			slog.Info("Agent inactive",
-				"objName", d.Pack.ObjName,
-				"objHash", d.Pack.ObjHash)
-
-			// Generate INACTIVE_OBJECT alert
-			if am.alertCore != nil {
-				alertLevel := byte(0)
-				if cfg := config.Get(); cfg != nil {
-					alertLevel = byte(cfg.ObjectInactiveAlertLevel())
-				}
-				am.alertCore.Add(&pack.AlertPack{
-					Time:    time.Now().UnixMilli(),
-					Level:   alertLevel,
-					ObjType: "scouter",
-					ObjHash: d.Pack.ObjHash,
-					Title:   "INACTIVE_OBJECT",
-					Message: fmt.Sprintf("%s is not running.", d.Pack.ObjName),
-				})
+		am.markDead()
+	}
+}
+
+// markDead marks objects past their dead timeout as dead and reports them.
+func (am *AgentManager) markDead() {
+	dead := am.objectCache.MarkDead(am.deadTimeout)
+	for _, d := range dead {
+		slog.Info("Agent inactive",
+			"objName", d.Pack.ObjName,
+			"objHash", d.Pack.ObjHash)
+		am.emit(objevent.Dead, d.Pack, "")
+
+		// Generate INACTIVE_OBJECT alert
+		if am.alertCore != nil {
+			alertLevel := byte(0)
+			if cfg := config.Get(); cfg != nil {
+				alertLevel = byte(cfg.ObjectInactiveAlertLevel())
 			}
+			am.alertCore.Add(&pack.AlertPack{
+				Time:    time.Now().UnixMilli(),
+				Level:   alertLevel,
+				ObjType: "scouter",
+				ObjHash: d.Pack.ObjHash,
+				Title:   "INACTIVE_OBJECT",
+				Message: fmt.Sprintf("%s is not running.", d.Pack.ObjName),
+			})
 		}
 	}
 }
@@ -7,6 +7,8 @@ import (
 
 	"github.com/zbum/scouter-server-go/internal/core/cache"
 	"github.com/zbum/scouter-server-go/internal/db/alert"
+	"github.com/zbum/scouter-server-go/internal/deploywin"
+	"github.com/zbum/scouter-server-go/internal/notify"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 )
@@ -16,6 +18,10 @@ type AlertCore struct {
 	queue      chan *pack.AlertPack
 	alertWR    *alert.AlertWR
 	alertCache *cache.AlertCache
+
+	deployWindows *deploywin.Manager
+	webhooks      *notify.AlertWebhooks
+	mails         *notify.AlertMails
 }
 
 func NewAlertCore(alertWR *alert.AlertWR, alertCache *cache.AlertCache) *AlertCore {
@@ -28,6 +34,24 @@ func NewAlertCore(alertWR *alert.AlertWR, alertCache *cache.AlertCache) *AlertCo
 	return ac
 }
 
+// SetDeployWindows makes open deployment windows drop or tag the alerts of
+// the objects they cover. It must be called before alerts arrive.
+func (ac *AlertCore) SetDeployWindows(dw *deploywin.Manager) {
+	ac.deployWindows = dw
+}
+
+// SetWebhooks makes stored alerts also go to the alert webhooks. It must be
+// called before alerts arrive.
+func (ac *AlertCore) SetWebhooks(w *notify.AlertWebhooks) {
+	ac.webhooks = w
+}
+
+// SetMails makes stored alerts also go to the alert mails. It must be
+// called before alerts arrive.
+func (ac *AlertCore) SetMails(m *notify.AlertMails) {
+	ac.mails = m
+}
+
 func (ac *AlertCore) Handler() PackHandler {
 	return func(p pack.Pack, addr *net.UDPAddr) {
 		ap, ok := p.(*pack.AlertPack)
@@ -56,6 +80,10 @@ func (ac *AlertCore) run() {
 			"objHash", ap.ObjHash,
 			"title", ap.Title)
 
+		if ac.deployWindows != nil && !ac.deployWindows.Apply(ap) {
+			continue
+		}
+
 		o := protocol.NewDataOutputX()
 		pack.WritePack(o, ap)
 		data := o.ToByteArray()
@@ -72,5 +100,12 @@ func (ac *AlertCore) run() {
 				Data:   data,
 			})
 		}
+
+		if ac.webhooks != nil {
+			ac.webhooks.Add(ap)
+		}
+		if ac.mails != nil {
+			ac.mails.Add(ap)
+		}
 	}
 }
@@ -81,6 +81,33 @@ func (c *AlertCache) GetSince(clientLoop int64, clientIndex int) ([][]byte, int6
 	return result, curLoop, curIndex
 }
 
+// Seq returns the number of alerts added so far.
+func (c *AlertCache) Seq() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.seq()
+}
+
+func (c *AlertCache) seq() int64 {
+	return c.loop*int64(c.size) + int64(c.index)
+}
+
+// Since returns the alerts added after the first seq ones, oldest first,
+// with the sequence number of the first one returned: alert i of the result
+// is the (first+i+1)th added. Alerts already overwritten are skipped, and a
+// seq beyond the current one, such as from before a restart, returns none.
+func (c *AlertCache) Since(seq int64) ([][]byte, int64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cur := c.seq()
+	seq = min(max(seq, cur-int64(c.size), 0), cur)
+	result := make([][]byte, 0, cur-seq)
+	for n := seq; n < cur; n++ {
+		result = append(result, c.buf[n%int64(c.size)])
+	}
+	return result, seq
+}
+
 // Position returns the current loop and index.
 func (c *AlertCache) Position() (int64, int) {
 	c.mu.RLock()
@@ -108,6 +108,28 @@ func TestCounterCache_GetByObjHash_Empty(t *testing.T) {
 	}
 }
 
+func TestCounterCache_Since(t *testing.T) {
+	c := NewCounterCache()
+	c.Put(CounterKey{ObjHash: 1, Counter: "TPS"}, value.NewDecimalValue(10))
+	seq := c.Seq()
+	if got, next := c.Since(seq); len(got) != 0 || next != seq {
+		t.Fatalf("expected nothing new, got %v at %d", got, next)
+	}
+
+	c.Put(CounterKey{ObjHash: 2, Counter: "TPS"}, value.NewDecimalValue(20))
+	c.Put(CounterKey{ObjHash: 1, Counter: "TPS"}, value.NewDecimalValue(11))
+	got, next := c.Since(seq)
+	if len(got) != 2 || next != seq+2 {
+		t.Fatalf("expected 2 changes at %d, got %v at %d", seq+2, got, next)
+	}
+	if v := got[CounterKey{ObjHash: 1, Counter: "TPS"}].(*value.DecimalValue); v.Value != 11 {
+		t.Fatalf("expected latest value 11, got %d", v.Value)
+	}
+	if got, _ := c.Since(next); len(got) != 0 {
+		t.Fatalf("expected nothing after %d, got %v", next, got)
+	}
+}
+
 // --- XLogCache tests ---
 
 func TestXLogCache_PutAndGetRecent(t *testing.T) {
@@ -187,6 +209,26 @@ func TestXLogCache_Count(t *testing.T) {
 	}
 }
 
+func TestXLogCache_Position(t *testing.T) {
+	c := NewXLogCache(3)
+	c.Put(1, 100, false, []byte("a"))
+	c.Put(1, 100, false, []byte("b"))
+	loop, index := c.Position()
+	if res := c.Get(loop, index, 0, nil); len(res.Data) != 0 {
+		t.Fatalf("expected nothing after the position, got %d entries", len(res.Data))
+	}
+
+	c.Put(2, 100, false, []byte("c"))
+	c.Put(2, 100, false, []byte("d"))
+	res := c.Get(loop, index, 0, nil)
+	if len(res.Data) != 2 || string(res.Data[0].Data) != "c" || string(res.Data[1].Data) != "d" {
+		t.Fatalf("expected c, d across the wrap, got %+v", res.Data)
+	}
+	if l, i := c.Position(); res.Loop != l || res.Index != i {
+		t.Fatalf("Get ended at (%d, %d), Position is (%d, %d)", res.Loop, res.Index, l, i)
+	}
+}
+
 func TestXLogCache_Empty(t *testing.T) {
 	c := NewXLogCache(10)
 	entries := c.GetRecent(10)
@@ -258,6 +300,31 @@ func TestObjectCache_MarkDead(t *testing.T) {
 	}
 }
 
+func TestObjectCache_MarkDeadHonorsDeadTime(t *testing.T) {
+	c := NewObjectCache()
+	tags := value.NewMapValue()
+	tags.Put(pack.TagDeadTime, value.NewDecimalValue(int64(5*time.Minute/time.Millisecond)))
+	c.Put(1, &pack.ObjectPack{ObjHash: 1, ObjName: "rest", Alive: true, Tags: tags})
+
+	c.mu.Lock()
+	c.store[1].LastSeen = time.Now().Add(-1 * time.Minute)
+	c.mu.Unlock()
+
+	if dead := c.MarkDead(30 * time.Second); len(dead) != 0 {
+		t.Fatalf("expected object with 5m deadtime to stay alive, got %d dead", len(dead))
+	}
+	if live := c.GetLive(30 * time.Second); len(live) != 1 {
+		t.Fatalf("expected 1 live, got %d", len(live))
+	}
+
+	c.mu.Lock()
+	c.store[1].LastSeen = time.Now().Add(-6 * time.Minute)
+	c.mu.Unlock()
+	if dead := c.MarkDead(30 * time.Second); len(dead) != 1 {
+		t.Fatalf("expected 1 dead after deadtime, got %d", len(dead))
+	}
+}
+
 func TestObjectCache_Size(t *testing.T) {
 	c := NewObjectCache()
 	if c.Size() != 0 {
@@ -268,3 +335,32 @@ func TestObjectCache_Size(t *testing.T) {
 		t.Fatalf("expected 1, got %d", c.Size())
 	}
 }
+
+// --- AlertCache tests ---
+
+func TestAlertCache_Since(t *testing.T) {
+	c := NewAlertCache(3)
+	for _, s := range []string{"a", "b"} {
+		c.Add([]byte(s))
+	}
+	if got, first := c.Since(0); len(got) != 2 || first != 0 || string(got[1]) != "b" {
+		t.Fatalf("expected a, b from 0, got %q from %d", got, first)
+	}
+	for _, s := range []string{"c", "d", "e"} {
+		c.Add([]byte(s))
+	}
+	if c.Seq() != 5 {
+		t.Fatalf("expected seq 5, got %d", c.Seq())
+	}
+	// a and b were overwritten
+	got, first := c.Since(1)
+	if len(got) != 3 || first != 2 || string(got[0]) != "c" || string(got[2]) != "e" {
+		t.Fatalf("expected c, d, e from 2, got %q from %d", got, first)
+	}
+	if got, first := c.Since(4); len(got) != 1 || first != 4 || string(got[0]) != "e" {
+		t.Fatalf("expected e from 4, got %q from %d", got, first)
+	}
+	if got, first := c.Since(9); len(got) != 0 || first != 5 {
+		t.Fatalf("expected nothing from a future seq, got %q from %d", got, first)
+	}
+}
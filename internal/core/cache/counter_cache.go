@@ -20,15 +20,20 @@ type CounterKey struct {
 	TimeType byte
 }
 
-// CounterCache stores the latest counter values per object.
+// CounterCache stores the latest counter values per object. Every Put is
+// numbered, so streaming readers can ask for what changed since they last
+// looked.
 type CounterCache struct {
-	mu    sync.RWMutex
-	store map[CounterKey]value.Value
+	mu      sync.RWMutex
+	store   map[CounterKey]value.Value
+	seq     uint64
+	updated map[CounterKey]uint64 // seq of the latest Put of each key
 }
 
 func NewCounterCache() *CounterCache {
 	return &CounterCache{
-		store: make(map[CounterKey]value.Value),
+		store:   make(map[CounterKey]value.Value),
+		updated: make(map[CounterKey]uint64),
 	}
 }
 
@@ -36,6 +41,32 @@ func (c *CounterCache) Put(key CounterKey, v value.Value) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.store[key] = v
+	c.seq++
+	c.updated[key] = c.seq
+}
+
+// Seq returns the number of the latest Put.
+func (c *CounterCache) Seq() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.seq
+}
+
+// Since returns the values put after Put number seq, and the number of the
+// latest Put to ask from next time.
+func (c *CounterCache) Since(seq uint64) (map[CounterKey]value.Value, uint64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make(map[CounterKey]value.Value)
+	if seq >= c.seq {
+		return result, c.seq
+	}
+	for k, n := range c.updated {
+		if n > seq {
+			result[k] = c.store[k]
+		}
+	}
+	return result, c.seq
 }
 
 func (c *CounterCache) Get(key CounterKey) (value.Value, bool) {
@@ -57,3 +88,14 @@ func (c *CounterCache) GetByObjHash(objHash int32) map[string]value.Value {
 	}
 	return result
 }
+
+// Snapshot returns a copy of every cached counter value.
+func (c *CounterCache) Snapshot() map[CounterKey]value.Value {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make(map[CounterKey]value.Value, len(c.store))
+	for k, v := range c.store {
+		result[k] = v
+	}
+	return result
+}
@@ -13,6 +13,15 @@ type ObjectInfo struct {
 	LastSeen time.Time
 }
 
+// expired reports whether the object has not been seen within timeout, or
+// within its own dead time if it set one.
+func (v *ObjectInfo) expired(now time.Time, timeout time.Duration) bool {
+	if d := v.Pack.DeadTime(); d > 0 {
+		timeout = time.Duration(d) * time.Millisecond
+	}
+	return now.Sub(v.LastSeen) >= timeout
+}
+
 // ObjectCache stores registered agents/objects keyed by object hash.
 type ObjectCache struct {
 	mu    sync.RWMutex
@@ -34,6 +43,17 @@ func (c *ObjectCache) Put(objHash int32, p *pack.ObjectPack) {
 	}
 }
 
+// PutSeen is Put with the time the object was last seen, for objects
+// learned from another server.
+func (c *ObjectCache) PutSeen(objHash int32, p *pack.ObjectPack, lastSeen time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store[objHash] = &ObjectInfo{
+		Pack:     p,
+		LastSeen: lastSeen,
+	}
+}
+
 func (c *ObjectCache) Get(objHash int32) (*ObjectInfo, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -58,7 +78,7 @@ func (c *ObjectCache) GetLive(timeout time.Duration) []*ObjectInfo {
 	now := time.Now()
 	var result []*ObjectInfo
 	for _, v := range c.store {
-		if now.Sub(v.LastSeen) < timeout {
+		if !v.expired(now, timeout) {
 			result = append(result, v)
 		}
 	}
@@ -73,7 +93,7 @@ func (c *ObjectCache) MarkDead(timeout time.Duration) []*ObjectInfo {
 	now := time.Now()
 	var dead []*ObjectInfo
 	for _, v := range c.store {
-		if v.Pack.Alive && now.Sub(v.LastSeen) >= timeout {
+		if v.Pack.Alive && v.expired(now, timeout) {
 			v.Pack.Alive = false
 			dead = append(dead, v)
 		}
@@ -115,6 +135,25 @@ func (c *ObjectCache) ClearInactive() int {
 	return count
 }
 
+// RemoveDead removes the non-alive objects last seen before cutoff, of
+// objType if it is not empty, and returns them. With dryRun nothing is
+// removed.
+func (c *ObjectCache) RemoveDead(cutoff time.Time, objType string, dryRun bool) []*ObjectInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var removed []*ObjectInfo
+	for hash, v := range c.store {
+		if v.Pack.Alive || !v.LastSeen.Before(cutoff) || (objType != "" && v.Pack.ObjType != objType) {
+			continue
+		}
+		removed = append(removed, v)
+		if !dryRun {
+			delete(c.store, hash)
+		}
+	}
+	return removed
+}
+
 func (c *ObjectCache) Size() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
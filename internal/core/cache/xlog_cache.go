@@ -75,6 +75,14 @@ func (c *XLogCache) Put(objHash int32, elapsed int32, isError bool, data []byte)
 	}
 }
 
+// Position returns the (loop, index) of the next entry, from which Get
+// returns only entries added afterwards.
+func (c *XLogCache) Position() (int64, int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.loop, c.pos
+}
+
 // Get returns entries added since (lastLoop, lastIndex), filtered by minElapsed.
 // Entries with elapsed >= minElapsed OR isError are returned.
 // If objHashSet is non-nil, entries are filtered to those object hashes.
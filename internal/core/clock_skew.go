@@ -0,0 +1,208 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// clockSkewAlertInterval bounds how often CLOCK_SKEW is raised for one object
+// whose skew keeps coming and going.
+const clockSkewAlertInterval = 10 * time.Minute
+
+// ClockSkewInfo describes an object whose clock is off from the server's.
+type ClockSkewInfo struct {
+	ObjHash int32
+	ObjName string
+	// SkewMs is pack time minus receive time of the last pack; positive when
+	// the agent clock is ahead.
+	SkewMs int64
+	// Corrected is the number of packs whose time was rewritten.
+	Corrected int64
+	Since     time.Time
+}
+
+type clockSkewState struct {
+	skewMs    int64
+	skewed    bool
+	since     time.Time
+	alerted   time.Time
+	corrected int64
+}
+
+// ClockSkew compares the time of packs received from agents with the server
+// clock. Objects drifting beyond clock_skew_threshold_ms are logged and raise
+// a CLOCK_SKEW alert; with clock_skew_correct_enabled the pack time is
+// replaced by the receive time so data is not filed under another second or
+// another day's container. All settings are hot-reloadable.
+//
+// Only packs carrying the agent's notion of "now" are inspected: XLogs (end
+// time), realtime counters and alerts. Summaries cover past intervals and are
+// left alone.
+type ClockSkew struct {
+	alertCore   *AlertCore
+	objectCache *cache.ObjectCache
+	now         func() time.Time
+
+	mu      sync.Mutex
+	objects map[int32]*clockSkewState
+}
+
+// NewClockSkew creates a ClockSkew raising alerts through alertCore (may be nil).
+// objectCache, if not nil, is used to name objects in logs and alerts.
+func NewClockSkew(alertCore *AlertCore, objectCache *cache.ObjectCache) *ClockSkew {
+	return &ClockSkew{
+		alertCore:   alertCore,
+		objectCache: objectCache,
+		now:         time.Now,
+		objects:     make(map[int32]*clockSkewState),
+	}
+}
+
+// Check inspects p and, if enabled and needed, rewrites its time.
+func (c *ClockSkew) Check(cfg *config.Config, p pack.Pack) {
+	if !cfg.ClockSkewCheckEnabled() {
+		return
+	}
+	var objHash int32
+	var t *int64
+	switch v := p.(type) {
+	case *pack.XLogPack:
+		objHash, t = v.ObjHash, &v.EndTime
+	case *pack.PerfCounterPack:
+		if v.ObjName == "" {
+			return
+		}
+		objHash, t = util.HashString(v.ObjName), &v.Time
+	case *pack.AlertPack:
+		objHash, t = v.ObjHash, &v.Time
+	default:
+		return
+	}
+	if *t <= 0 {
+		return
+	}
+
+	now := c.now()
+	skew := *t - now.UnixMilli()
+	threshold := int64(cfg.ClockSkewThresholdMs())
+	beyond := skew > threshold || skew < -threshold
+	correct := beyond && cfg.ClockSkewCorrectEnabled()
+	if correct {
+		*t = now.UnixMilli()
+	}
+
+	c.mu.Lock()
+	st := c.objects[objHash]
+	if st == nil {
+		if !beyond {
+			c.mu.Unlock()
+			return
+		}
+		st = &clockSkewState{}
+		c.objects[objHash] = st
+	}
+	st.skewMs = skew
+	if correct {
+		st.corrected++
+	}
+	var detected, recovered, alert bool
+	switch {
+	case beyond && !st.skewed:
+		st.skewed, st.since = true, now
+		detected = true
+		if now.Sub(st.alerted) >= clockSkewAlertInterval {
+			st.alerted = now
+			alert = true
+		}
+	case !beyond && st.skewed:
+		st.skewed = false
+		recovered = true
+	}
+	if !st.skewed && now.Sub(st.alerted) >= clockSkewAlertInterval {
+		delete(c.objects, objHash)
+	}
+	c.mu.Unlock()
+
+	switch {
+	case detected:
+		objName := c.objName(objHash)
+		slog.Warn("Agent clock skew detected", "objName", objName, "objHash", objHash,
+			"skewMs", skew, "thresholdMs", threshold, "correct", cfg.ClockSkewCorrectEnabled())
+		if alert && c.alertCore != nil {
+			c.alertCore.Add(&pack.AlertPack{
+				Time:    now.UnixMilli(),
+				Level:   byte(cfg.ClockSkewAlertLevel()),
+				ObjType: "scouter",
+				ObjHash: objHash,
+				Title:   "CLOCK_SKEW",
+				Message: fmt.Sprintf("%s clock is off by %s from the server.", objName, formatSkew(skew)),
+			})
+		}
+	case recovered:
+		slog.Info("Agent clock skew recovered", "objName", c.objName(objHash), "objHash", objHash, "skewMs", skew)
+	}
+}
+
+// Skewed returns the objects currently beyond the threshold, largest skew first.
+func (c *ClockSkew) Skewed() []ClockSkewInfo {
+	c.mu.Lock()
+	var result []ClockSkewInfo
+	for objHash, st := range c.objects {
+		if st.skewed {
+			result = append(result, ClockSkewInfo{ObjHash: objHash, SkewMs: st.skewMs, Corrected: st.corrected, Since: st.since})
+		}
+	}
+	c.mu.Unlock()
+	for i := range result {
+		result[i].ObjName = c.objName(result[i].ObjHash)
+	}
+	sort.Slice(result, func(i, j int) bool { return abs64(result[i].SkewMs) > abs64(result[j].SkewMs) })
+	return result
+}
+
+// Summary returns a one-line description of the skewed objects.
+func (c *ClockSkew) Summary() string {
+	skewed := c.Skewed()
+	if len(skewed) == 0 {
+		return "none"
+	}
+	worst := skewed[0]
+	name := worst.ObjName
+	if name == "" {
+		name = fmt.Sprint(worst.ObjHash)
+	}
+	return fmt.Sprintf("%d objects, worst %s %s", len(skewed), name, formatSkew(worst.SkewMs))
+}
+
+func (c *ClockSkew) objName(objHash int32) string {
+	if c.objectCache != nil {
+		if info, ok := c.objectCache.Get(objHash); ok {
+			return info.Pack.ObjName
+		}
+	}
+	return ""
+}
+
+// formatSkew renders a skew as e.g. "+1m30s" (agent ahead) or "-2s" (behind).
+func formatSkew(ms int64) string {
+	d := (time.Duration(ms) * time.Millisecond).Round(time.Second)
+	if d >= 0 {
+		return "+" + d.String()
+	}
+	return d.String()
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
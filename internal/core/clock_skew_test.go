@@ -0,0 +1,84 @@
+package core
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+func TestClockSkew_DetectAlertCorrect(t *testing.T) {
+	dir := t.TempDir()
+	cfg := mirrorConfig(t, dir, "clock_skew_threshold_ms=60000\nclock_skew_alert_level=2\n")
+
+	// Not started: alerts stay in the queue.
+	ac := &AlertCore{queue: make(chan *pack.AlertPack, 10)}
+	c := NewClockSkew(ac, nil)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	ahead := now.Add(2 * time.Hour).UnixMilli()
+	xp := &pack.XLogPack{ObjHash: 1, EndTime: ahead}
+	c.Check(cfg, xp)
+	c.Check(cfg, &pack.XLogPack{ObjHash: 1, EndTime: ahead})
+	c.Check(cfg, &pack.XLogPack{ObjHash: 2, EndTime: now.Add(-time.Second).UnixMilli()})
+
+	if xp.EndTime != ahead {
+		t.Error("time rewritten with correction disabled")
+	}
+	if len(ac.queue) != 1 {
+		t.Fatalf("alerts = %d, want 1", len(ac.queue))
+	}
+	ap := <-ac.queue
+	if ap.Title != "CLOCK_SKEW" || ap.ObjHash != 1 || ap.Level != 2 {
+		t.Errorf("alert = %+v", ap)
+	}
+	skewed := c.Skewed()
+	if len(skewed) != 1 || skewed[0].ObjHash != 1 || skewed[0].SkewMs != 2*3600*1000 {
+		t.Fatalf("skewed = %+v", skewed)
+	}
+
+	// Recovery, then skew again within the alert interval: logged, not alerted.
+	c.Check(cfg, &pack.XLogPack{ObjHash: 1, EndTime: now.UnixMilli()})
+	if len(c.Skewed()) != 0 {
+		t.Errorf("skewed after recovery = %+v", c.Skewed())
+	}
+	c.Check(cfg, &pack.XLogPack{ObjHash: 1, EndTime: ahead})
+	if len(ac.queue) != 0 {
+		t.Errorf("alert repeated within %s", clockSkewAlertInterval)
+	}
+
+	// Correction rewrites counters, xlogs and alerts to the receive time.
+	cfg = mirrorConfig(t, dir, "clock_skew_correct_enabled=true\n")
+	behind := now.AddDate(0, 0, -1).UnixMilli()
+	cp := &pack.PerfCounterPack{ObjName: "/host/tomcat", Time: behind}
+	xp = &pack.XLogPack{ObjHash: 1, EndTime: ahead}
+	c.Check(cfg, cp)
+	c.Check(cfg, xp)
+	if cp.Time != now.UnixMilli() || xp.EndTime != now.UnixMilli() {
+		t.Errorf("times not corrected: counter %d, xlog %d", cp.Time, xp.EndTime)
+	}
+	if len(c.Skewed()) != 2 || c.Skewed()[0].ObjHash != util.HashString("/host/tomcat") {
+		t.Errorf("skewed = %+v", c.Skewed())
+	}
+}
+
+func TestDispatcher_ClockSkewSkipsServerPacks(t *testing.T) {
+	mirrorConfig(t, t.TempDir(), "clock_skew_correct_enabled=true\n")
+	d := NewDispatcher()
+	d.SetClockSkew(NewClockSkew(nil, nil))
+	var got int64
+	d.Register(pack.PackTypeAlert, func(p pack.Pack, addr *net.UDPAddr) { got = p.(*pack.AlertPack).Time })
+
+	old := time.Now().Add(-time.Hour).UnixMilli()
+	d.Dispatch(&pack.AlertPack{Time: old}, nil)
+	if got != old {
+		t.Errorf("server pack time changed to %d", got)
+	}
+	d.Dispatch(&pack.AlertPack{Time: old}, &net.UDPAddr{})
+	if got == old {
+		t.Error("agent pack time not corrected")
+	}
+}
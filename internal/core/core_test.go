@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/agentinv"
+	"github.com/zbum/scouter-server-go/internal/db/objevent"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
 	"github.com/zbum/scouter-server-go/internal/util"
@@ -247,6 +249,66 @@ func TestAgentManager_Handler_NilAddr(t *testing.T) {
 	}
 }
 
+func TestAgentManager_Events(t *testing.T) {
+	// Built directly so the monitor loop does not race markDead.
+	am := &AgentManager{objectCache: cache.NewObjectCache(), deadTimeout: 30 * time.Second}
+	events := objevent.NewStore(t.TempDir())
+	defer events.Close()
+	am.SetEvents(events)
+	handler := am.Handler()
+
+	handler(&pack.ObjectPack{ObjHash: 7, ObjName: "/a/agent", ObjType: "java"}, nil)
+	handler(&pack.ObjectPack{ObjHash: 7, ObjName: "/a/agent", ObjType: "java"}, nil)
+	handler(&pack.ObjectPack{ObjHash: 7, ObjName: "/b/agent", ObjType: "java"}, nil)
+
+	am.deadTimeout = time.Millisecond
+	time.Sleep(5 * time.Millisecond)
+	am.markDead()
+	am.deadTimeout = 30 * time.Second
+	handler(&pack.ObjectPack{ObjHash: 7, ObjName: "/b/agent", ObjType: "java"}, nil)
+
+	got := events.Since(0)
+	want := []string{objevent.Registered, objevent.Renamed, objevent.Dead, objevent.Recovered}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), got)
+	}
+	for i, e := range got {
+		if e.Type != want[i] || e.ObjHash != 7 {
+			t.Fatalf("event %d: expected %s for 7, got %+v", i, want[i], e)
+		}
+	}
+	if got[1].OldName != "/a/agent" || got[1].ObjName != "/b/agent" {
+		t.Fatalf("unexpected rename event: %+v", got[1])
+	}
+}
+
+func TestAgentManager_Inventory(t *testing.T) {
+	am := &AgentManager{objectCache: cache.NewObjectCache(), deadTimeout: 30 * time.Second}
+	inv, err := agentinv.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inv.Close()
+	am.SetInventory(inv)
+	handler := am.Handler()
+
+	tags := value.NewMapValue()
+	tags.Put(pack.TagOS, value.NewTextValue("linux"))
+	tags.Put("kube", &value.BooleanValue{Value: true})
+	tags.Put("profiling", &value.BooleanValue{Value: false})
+	tags.Put(pack.TagDeadTime, value.NewDecimalValue(1000))
+	handler(&pack.ObjectPack{ObjHash: 7, ObjName: "/a/agent", ObjType: "java", Version: "2.20.0", Tags: tags}, nil)
+
+	agents := inv.List("")
+	if len(agents) != 1 {
+		t.Fatalf("agents = %+v", agents)
+	}
+	a := agents[0]
+	if a.Version != "2.20.0" || a.OS != "linux" || len(a.Capabilities) != 1 || a.Capabilities[0] != "kube" {
+		t.Errorf("agent = %+v", a)
+	}
+}
+
 // --- AlertCore tests ---
 
 func TestAlertCore_Handler(t *testing.T) {
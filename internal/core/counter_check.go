@@ -0,0 +1,308 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// counterCheckDelay is how long after a minute ends it is checked, so packs
+// still queued for the counter writer are not reported as lost.
+const counterCheckDelay = 30 * time.Second
+
+// counterCheckMaxLogged bounds the per-object warnings logged for one minute.
+const counterCheckMaxLogged = 10
+
+// CounterProblem describes the divergence found for one object in one minute.
+type CounterProblem struct {
+	ObjHash int32
+	ObjName string
+	// Missing is the number of seconds with a received pack but no stored entry.
+	Missing int
+	// Mismatched lists the counters whose cached value differs from the
+	// stored entry of the last received second.
+	Mismatched []string
+	// SkewMs is the largest difference between pack time and receive time.
+	SkewMs int64
+}
+
+// CounterCheckResult is the outcome of checking one minute.
+type CounterCheckResult struct {
+	Minute   time.Time
+	Objects  int // objects that sent realtime counters in the minute
+	Seconds  int // distinct (object, second) packs received
+	Problems []CounterProblem
+}
+
+// Missing returns the total number of received seconds not stored.
+func (r *CounterCheckResult) Missing() int {
+	n := 0
+	for _, p := range r.Problems {
+		n += p.Missing
+	}
+	return n
+}
+
+// counterMinute is what one object sent in one minute.
+type counterMinute struct {
+	seconds  map[int32]bool // seconds of day (pack time) received
+	lastSec  int32
+	last     map[string]value.Value // counters cached for lastSec
+	maxSkew  int64
+	received int
+}
+
+// CounterCheck compares the realtime counters put into the CounterCache
+// with what the counter writer persisted, one minute at a time. It reports
+// seconds that never reached the store (dropped or failed writes), values
+// that differ from the cache, and packs whose time is far from the receive
+// time (agent clock skew, which also files the entry under another second).
+// It is driven by the hot-reloadable counter_check_* settings.
+type CounterCheck struct {
+	counterRD   *counter.CounterRD
+	objectCache *cache.ObjectCache
+
+	mu      sync.Mutex
+	minutes map[int64]map[int32]*counterMinute // by epoch minute, then objHash
+	checked int64                              // last epoch minute checked
+	last    *CounterCheckResult
+	total   CounterCheckTotals
+}
+
+// CounterCheckTotals accumulates results since the server started.
+type CounterCheckTotals struct {
+	Minutes    int
+	Missing    int
+	Mismatched int
+	Skewed     int
+}
+
+// NewCounterCheck creates a CounterCheck reading stored counters from counterRD.
+// objectCache, if not nil, is used to name objects in reports.
+func NewCounterCheck(counterRD *counter.CounterRD, objectCache *cache.ObjectCache) *CounterCheck {
+	return &CounterCheck{
+		counterRD:   counterRD,
+		objectCache: objectCache,
+		minutes:     make(map[int64]map[int32]*counterMinute),
+	}
+}
+
+// Observe records a realtime counter pack as cached at receiveTime.
+func (c *CounterCheck) Observe(objHash int32, packTime int64, receiveTime time.Time, counters map[string]value.Value) {
+	cfg := config.Get()
+	if cfg == nil || !cfg.CounterCheckEnabled() {
+		return
+	}
+	minute := packTime / 60000
+	recv := receiveTime.UnixMilli()
+	skew := recv - packTime
+	if skew < 0 {
+		skew = -skew
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if minute <= c.checked || minute > recv/60000+5 {
+		// Already checked, or too far ahead to keep around; only the skew counts.
+		if skew > int64(cfg.CounterCheckSkewMs()) {
+			c.total.Skewed++
+		}
+		return
+	}
+	objs := c.minutes[minute]
+	if objs == nil {
+		objs = make(map[int32]*counterMinute)
+		c.minutes[minute] = objs
+	}
+	m := objs[objHash]
+	if m == nil {
+		m = &counterMinute{seconds: make(map[int32]bool)}
+		objs[objHash] = m
+	}
+	sec := secOfDay(packTime)
+	m.seconds[sec] = true
+	m.received++
+	if sec >= m.lastSec {
+		m.lastSec = sec
+		m.last = counters
+	}
+	m.maxSkew = max(m.maxSkew, skew)
+}
+
+// Start checks each minute once it is counterCheckDelay old, until ctx is done.
+func (c *CounterCheck) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				cfg := config.Get()
+				if cfg == nil || !cfg.CounterCheckEnabled() {
+					c.reset()
+					continue
+				}
+				c.CheckUntil(now.Add(-counterCheckDelay), int64(cfg.CounterCheckSkewMs()))
+			}
+		}
+	}()
+}
+
+// reset drops collected data while checking is disabled.
+func (c *CounterCheck) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.minutes) > 0 {
+		c.minutes = make(map[int64]map[int32]*counterMinute)
+	}
+}
+
+// CheckUntil checks every observed minute that ended before t.
+func (c *CounterCheck) CheckUntil(t time.Time, skewMs int64) {
+	until := t.UnixMilli()/60000 - 1 // last complete minute
+
+	c.mu.Lock()
+	var due []int64
+	for minute := range c.minutes {
+		if minute <= until {
+			due = append(due, minute)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i] < due[j] })
+	batches := make([]map[int32]*counterMinute, len(due))
+	for i, minute := range due {
+		batches[i] = c.minutes[minute]
+		delete(c.minutes, minute)
+	}
+	c.checked = max(c.checked, until)
+	c.mu.Unlock()
+
+	for i, minute := range due {
+		r := c.check(minute, batches[i], skewMs)
+		c.report(r)
+	}
+}
+
+func (c *CounterCheck) check(minute int64, objs map[int32]*counterMinute, skewMs int64) *CounterCheckResult {
+	start := time.UnixMilli(minute * 60000)
+	r := &CounterCheckResult{Minute: start, Objects: len(objs)}
+	date := util.FormatDate(start.UnixMilli())
+	startSec := secOfDay(start.UnixMilli())
+
+	for objHash, m := range objs {
+		r.Seconds += len(m.seconds)
+		stored := make(map[int32]map[string]value.Value)
+		err := c.counterRD.ReadRealtimeRange(date, objHash, startSec, startSec+59, func(sec int32, counters map[string]value.Value) {
+			stored[sec] = counters
+		})
+		if err != nil {
+			slog.Warn("Counter check: read failed", "date", date, "objHash", objHash, "error", err)
+			continue
+		}
+
+		p := CounterProblem{ObjHash: objHash}
+		for sec := range m.seconds {
+			if stored[sec] == nil {
+				p.Missing++
+			}
+		}
+		if persisted := stored[m.lastSec]; persisted != nil {
+			for name, v := range m.last {
+				if !reflect.DeepEqual(v, persisted[name]) {
+					p.Mismatched = append(p.Mismatched, name)
+				}
+			}
+			sort.Strings(p.Mismatched)
+		}
+		if m.maxSkew > skewMs {
+			p.SkewMs = m.maxSkew
+		}
+		if p.Missing > 0 || len(p.Mismatched) > 0 || p.SkewMs > 0 {
+			p.ObjName = c.objName(objHash)
+			r.Problems = append(r.Problems, p)
+		}
+	}
+	sort.Slice(r.Problems, func(i, j int) bool { return r.Problems[i].ObjHash < r.Problems[j].ObjHash })
+	return r
+}
+
+func (c *CounterCheck) report(r *CounterCheckResult) {
+	c.mu.Lock()
+	c.last = r
+	c.total.Minutes++
+	for _, p := range r.Problems {
+		c.total.Missing += p.Missing
+		c.total.Mismatched += len(p.Mismatched)
+		if p.SkewMs > 0 {
+			c.total.Skewed++
+		}
+	}
+	c.mu.Unlock()
+
+	if len(r.Problems) == 0 {
+		slog.Debug("Counter check: consistent", "minute", r.Minute.Format("15:04"), "objects", r.Objects, "seconds", r.Seconds)
+		return
+	}
+	slog.Warn("Counter check: divergence", "minute", r.Minute.Format("15:04"),
+		"objects", r.Objects, "seconds", r.Seconds, "missing", r.Missing(), "problemObjects", len(r.Problems))
+	for i, p := range r.Problems {
+		if i == counterCheckMaxLogged {
+			slog.Warn("Counter check: more objects diverged", "count", len(r.Problems)-i)
+			break
+		}
+		slog.Warn("Counter check: object diverged", "objName", p.ObjName, "objHash", p.ObjHash,
+			"missing", p.Missing, "mismatched", p.Mismatched, "skewMs", p.SkewMs)
+	}
+}
+
+// Last returns the most recent result, or nil if no minute was checked yet.
+func (c *CounterCheck) Last() *CounterCheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// Totals returns the accumulated results.
+func (c *CounterCheck) Totals() CounterCheckTotals {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
+
+// Summary returns a one-line description of the last result and totals.
+func (c *CounterCheck) Summary() string {
+	last, total := c.Last(), c.Totals()
+	if last == nil {
+		return "no minute checked"
+	}
+	return fmt.Sprintf("last %s: %d objects, %d seconds, %d missing, %d objects diverged; total %d minutes, %d missing, %d mismatched, %d skewed",
+		last.Minute.Format("15:04"), last.Objects, last.Seconds, last.Missing(), len(last.Problems),
+		total.Minutes, total.Missing, total.Mismatched, total.Skewed)
+}
+
+func (c *CounterCheck) objName(objHash int32) string {
+	if c.objectCache != nil {
+		if info, ok := c.objectCache.Get(objHash); ok {
+			return info.Pack.ObjName
+		}
+	}
+	return ""
+}
+
+// secOfDay returns the second of the local day, as used by the realtime counter store.
+func secOfDay(timeMs int64) int32 {
+	t := time.UnixMilli(timeMs)
+	return int32(t.Hour()*3600 + t.Minute()*60 + t.Second())
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+func TestCounterCheck_ReportsDivergence(t *testing.T) {
+	dir := t.TempDir()
+	mirrorConfig(t, dir, "counter_check_enabled=true\ncounter_check_skew_ms=5000\n")
+
+	wr := counter.NewCounterWR(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wr.Start(ctx)
+
+	base := time.Date(2026, 1, 5, 10, 0, 0, 0, time.Local)
+	at := func(sec int) int64 { return base.Add(time.Duration(sec) * time.Second).UnixMilli() }
+	tps := func(v int64) map[string]value.Value { return map[string]value.Value{"TPS": value.NewDecimalValue(v)} }
+
+	// Object 1: seconds 0-9 stored, 10-11 lost.
+	for sec := 0; sec < 10; sec++ {
+		wr.AddRealtimeFromPerfCounter(at(sec), 1, tps(int64(sec)))
+	}
+	// Object 2: stored value differs from the cached one.
+	wr.AddRealtimeFromPerfCounter(at(30), 2, tps(1))
+	// Object 3: consistent, but its clock is 10s behind.
+	wr.AddRealtimeFromPerfCounter(at(40), 3, tps(1))
+	// Object 4: consistent.
+	wr.AddRealtimeFromPerfCounter(at(50), 4, tps(1))
+	for wr.Pending() > 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	wr.Close()
+
+	c := NewCounterCheck(counter.NewCounterRD(dir), nil)
+	for sec := 0; sec < 12; sec++ {
+		c.Observe(1, at(sec), time.UnixMilli(at(sec)), tps(int64(sec)))
+	}
+	c.Observe(2, at(30), time.UnixMilli(at(30)), tps(2))
+	c.Observe(3, at(40), time.UnixMilli(at(50)), tps(1))
+	c.Observe(4, at(50), time.UnixMilli(at(50)), tps(1))
+
+	c.CheckUntil(base.Add(30*time.Second), 5000) // minute not complete yet
+	if c.Last() != nil {
+		t.Fatal("an incomplete minute must not be checked")
+	}
+	c.CheckUntil(base.Add(2*time.Minute), 5000)
+
+	r := c.Last()
+	if r == nil || r.Objects != 4 || r.Seconds != 15 {
+		t.Fatalf("result = %+v", r)
+	}
+	if len(r.Problems) != 3 {
+		t.Fatalf("problems = %+v", r.Problems)
+	}
+	if p := r.Problems[0]; p.ObjHash != 1 || p.Missing != 2 || len(p.Mismatched) != 0 {
+		t.Errorf("object 1: %+v", p)
+	}
+	if p := r.Problems[1]; p.ObjHash != 2 || p.Missing != 0 || len(p.Mismatched) != 1 || p.Mismatched[0] != "TPS" {
+		t.Errorf("object 2: %+v", p)
+	}
+	if p := r.Problems[2]; p.ObjHash != 3 || p.SkewMs != 10000 {
+		t.Errorf("object 3: %+v", p)
+	}
+	if tot := c.Totals(); tot.Minutes != 1 || tot.Missing != 2 || tot.Mismatched != 1 || tot.Skewed != 1 {
+		t.Errorf("totals = %+v", tot)
+	}
+
+	// Packs for a minute already checked are not collected again.
+	c.Observe(1, at(5), time.UnixMilli(at(5)), tps(5))
+	c.CheckUntil(base.Add(3*time.Minute), 5000)
+	if c.Totals().Minutes != 1 {
+		t.Errorf("a checked minute was checked again")
+	}
+}
@@ -3,22 +3,42 @@ package core
 import (
 	"log/slog"
 	"net"
+	"time"
 
 	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/objalias"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 )
 
 // PackHandler processes a single pack received from the network.
 type PackHandler func(p pack.Pack, addr *net.UDPAddr)
 
+// StampedPackHandler is a PackHandler that also receives the time the pack
+// arrived, zero for packs the server ingests itself, to measure ingest latency.
+type StampedPackHandler func(p pack.Pack, addr *net.UDPAddr, received time.Time)
+
+// queued is a pack waiting in a core's queue with its receive time.
+type queued[T pack.Pack] struct {
+	p        T
+	received time.Time
+}
+
 // Dispatcher routes incoming packs to registered handlers by pack type.
 type Dispatcher struct {
 	handlers map[byte]PackHandler
+	stamped  map[byte]StampedPackHandler
+	mirror   *PackMirror
+	inspect  *PackInspector
+	skew     *ClockSkew
+	quota    *IngestQuota
+	alias    *objalias.Manager
+	readOnly *ReadOnly
 }
 
 func NewDispatcher() *Dispatcher {
 	return &Dispatcher{
 		handlers: make(map[byte]PackHandler),
+		stamped:  make(map[byte]StampedPackHandler),
 	}
 }
 
@@ -27,72 +47,161 @@ func (d *Dispatcher) Register(packType byte, handler PackHandler) {
 	d.handlers[packType] = handler
 }
 
+// RegisterStamped associates a receive-time-aware handler with a pack type.
+// It takes precedence over a handler registered with Register.
+func (d *Dispatcher) RegisterStamped(packType byte, handler StampedPackHandler) {
+	d.stamped[packType] = handler
+}
+
+// SetMirror installs m to capture incoming packs when mirror_pack_enabled is set.
+func (d *Dispatcher) SetMirror(m *PackMirror) {
+	d.mirror = m
+}
+
+// SetInspector installs pi to keep the packs passed to DispatchRaw.
+func (d *Dispatcher) SetInspector(pi *PackInspector) {
+	d.inspect = pi
+}
+
+// SetClockSkew installs c to check the time of packs received from agents.
+func (d *Dispatcher) SetClockSkew(c *ClockSkew) {
+	d.skew = c
+}
+
+// SetQuota installs q to drop XLog and profile packs beyond the per-objType limits.
+func (d *Dispatcher) SetQuota(q *IngestQuota) {
+	d.quota = q
+}
+
+// SetAlias installs a to move packs to the alias identity of their object.
+func (d *Dispatcher) SetAlias(a *objalias.Manager) {
+	d.alias = a
+}
+
+// SetReadOnly installs r to drop packs while the server is read-only.
+func (d *Dispatcher) SetReadOnly(r *ReadOnly) {
+	d.readOnly = r
+}
+
 // Dispatch routes a pack to its registered handler.
 func (d *Dispatcher) Dispatch(p pack.Pack, addr *net.UDPAddr) {
+	d.dispatch(p, addr, time.Time{})
+}
+
+func (d *Dispatcher) dispatch(p pack.Pack, addr *net.UDPAddr, received time.Time) {
 	if p == nil {
 		return
 	}
 
 	packType := p.PackType()
+	if d.readOnly != nil && !d.readOnly.Allow(p) {
+		return
+	}
 
 	// Per-type debug logging controlled by config flags
 	if cfg := config.Get(); cfg != nil {
 		logUDPPack(cfg, packType, addr)
+		if d.mirror != nil {
+			d.mirror.Mirror(cfg, p, addr)
+		}
+		if d.alias != nil {
+			d.alias.Rewrite(p)
+		}
+		// Packs ingested by the server itself (addr == nil) carry server time.
+		if d.skew != nil && addr != nil {
+			d.skew.Check(cfg, p)
+		}
+		if d.quota != nil && !d.quota.Allow(cfg, p) {
+			return
+		}
 	}
 
-	h, ok := d.handlers[packType]
-	if ok {
+	if h, ok := d.stamped[packType]; ok {
+		h(p, addr, received)
+	} else if h, ok := d.handlers[packType]; ok {
 		h(p, addr)
 	} else {
 		slog.Debug("no handler for pack type", "type", packType)
 	}
 }
 
+// DispatchRaw is Dispatch for a pack decoded from raw, the bytes received
+// from the agent at received. The inspector keeps raw before aliasing or
+// quotas apply.
+func (d *Dispatcher) DispatchRaw(p pack.Pack, raw []byte, addr *net.UDPAddr, received time.Time) {
+	if d.inspect != nil && p != nil {
+		if cfg := config.Get(); cfg != nil {
+			d.inspect.Record(cfg, p, raw, addr)
+		}
+	}
+	d.dispatch(p, addr, received)
+}
+
 // logUDPPack logs pack reception when the corresponding config flag is enabled.
 func logUDPPack(cfg *config.Config, packType byte, addr *net.UDPAddr) {
 	var enabled bool
-	var typeName string
 
-	switch packType {
-	case pack.PackTypeXLog, pack.PackTypeDroppedXLog:
+	typeName := packTypeName(packType)
+	switch typeName {
+	case "xlog":
 		enabled = cfg.LogUDPXLog()
-		typeName = "xlog"
-	case pack.PackTypeXLogProfile, pack.PackTypeXLogProfile2:
+	case "profile":
 		enabled = cfg.LogUDPProfile()
-		typeName = "profile"
-	case pack.PackTypeText:
+	case "text":
 		enabled = cfg.LogUDPText()
-		typeName = "text"
-	case pack.PackTypePerfCounter:
+	case "counter":
 		enabled = cfg.LogUDPCounter()
-		typeName = "counter"
-	case pack.PackTypeObject:
+	case "object":
 		enabled = cfg.LogUDPObject()
-		typeName = "object"
-	case pack.PackTypeAlert:
+	case "alert":
 		enabled = cfg.LogUDPAlert()
-		typeName = "alert"
-	case pack.PackTypeSummary:
+	case "summary":
 		enabled = cfg.LogUDPSummary()
-		typeName = "summary"
-	case pack.PackTypeBatch:
+	case "batch":
 		enabled = cfg.LogUDPBatch()
-		typeName = "batch"
-	case pack.PackTypeSpan, pack.PackTypeSpanContainer:
+	case "span":
 		enabled = cfg.LogUDPSpan()
-		typeName = "span"
-	case pack.PackTypeStack:
+	case "stack":
 		enabled = cfg.LogUDPStack()
-		typeName = "stack"
-	case pack.PackTypePerfStatus:
+	case "status":
 		enabled = cfg.LogUDPStatus()
-		typeName = "status"
-	case pack.PackTypePerfInteractionCounter:
+	case "interaction_counter":
 		enabled = cfg.LogUDPInteractionCounter()
-		typeName = "interaction_counter"
 	}
 
 	if enabled {
 		slog.Info("UDP pack received", "type", typeName, "packType", packType, "addr", addr)
 	}
 }
+
+// packTypeName returns the short name used by the log_udp_* and mirror_pack_*
+// settings for a pack type, or "" for types without one.
+func packTypeName(packType byte) string {
+	switch packType {
+	case pack.PackTypeXLog, pack.PackTypeDroppedXLog:
+		return "xlog"
+	case pack.PackTypeXLogProfile, pack.PackTypeXLogProfile2:
+		return "profile"
+	case pack.PackTypeText:
+		return "text"
+	case pack.PackTypePerfCounter:
+		return "counter"
+	case pack.PackTypeObject:
+		return "object"
+	case pack.PackTypeAlert:
+		return "alert"
+	case pack.PackTypeSummary:
+		return "summary"
+	case pack.PackTypeBatch:
+		return "batch"
+	case pack.PackTypeSpan, pack.PackTypeSpanContainer:
+		return "span"
+	case pack.PackTypeStack:
+		return "stack"
+	case pack.PackTypePerfStatus:
+		return "status"
+	case pack.PackTypePerfInteractionCounter:
+		return "interaction_counter"
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/ingest"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+const (
+	ingestLatencyInterval = 10 * time.Second
+	// ingestLatencyAlertInterval bounds how often INGEST_LATENCY is raised
+	// for one pack type while its latency stays high.
+	ingestLatencyAlertInterval = 10 * time.Minute
+)
+
+// ingestCounterPrefix names the self-metric counters of each pack type.
+var ingestCounterPrefix = map[string]string{
+	"xlog":    "IngestXLog",
+	"profile": "IngestProfile",
+	"counter": "IngestCounter",
+}
+
+// IngestLatency reports how long packs take from UDP receive until the
+// writers have indexed them. Every interval it takes the histograms kept by
+// ingest.GetLatency, stores p50/p99/max per pack type as counters of the
+// object named by ingest_latency_obj_name, and raises INGEST_LATENCY when a
+// p99 exceeds ingest_latency_alert_p99_ms, so queues building up behind a
+// slow disk are noticed before they overflow.
+type IngestLatency struct {
+	alertCore *AlertCore
+	ingest    func(pack.Pack)
+
+	mu      sync.Mutex
+	last    map[string]ingest.Histogram
+	alerted map[string]time.Time
+}
+
+// NewIngestLatency creates an IngestLatency raising alerts through alertCore
+// (may be nil) and storing its counters through ingestFn.
+func NewIngestLatency(alertCore *AlertCore, ingestFn func(pack.Pack)) *IngestLatency {
+	return &IngestLatency{
+		alertCore: alertCore,
+		ingest:    ingestFn,
+		last:      make(map[string]ingest.Histogram),
+		alerted:   make(map[string]time.Time),
+	}
+}
+
+// Start reports the latency every interval until ctx is cancelled.
+func (l *IngestLatency) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(ingestLatencyInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if cfg := config.Get(); cfg != nil {
+					l.report(cfg, now, ingest.GetLatency().Drain())
+				}
+			}
+		}
+	}()
+}
+
+// report handles the histograms of one interval.
+func (l *IngestLatency) report(cfg *config.Config, now time.Time, hists map[string]ingest.Histogram) {
+	l.mu.Lock()
+	l.last = hists
+	l.mu.Unlock()
+
+	objName := cfg.IngestLatencyObjName()
+	if objName != "" && l.ingest != nil && len(hists) > 0 {
+		for _, p := range ingestLatencyPacks(objName, now, hists) {
+			l.ingest(p)
+		}
+	}
+
+	threshold := time.Duration(cfg.IngestLatencyAlertP99Ms()) * time.Millisecond
+	if threshold <= 0 {
+		return
+	}
+	for _, kind := range sortedKinds(hists) {
+		p99 := hists[kind].Quantile(0.99)
+		if p99 <= threshold {
+			continue
+		}
+		l.mu.Lock()
+		alert := now.Sub(l.alerted[kind]) >= ingestLatencyAlertInterval
+		if alert {
+			l.alerted[kind] = now
+		}
+		l.mu.Unlock()
+
+		slog.Warn("Ingest latency high", "type", kind, "p99", p99, "max", hists[kind].Max, "threshold", threshold)
+		if alert && l.alertCore != nil {
+			var objHash int32
+			if objName != "" {
+				objHash = util.HashString(objName)
+			}
+			l.alertCore.Add(&pack.AlertPack{
+				Time:    now.UnixMilli(),
+				Level:   byte(cfg.IngestLatencyAlertLevel()),
+				ObjType: "scouter",
+				ObjHash: objHash,
+				Title:   "INGEST_LATENCY",
+				Message: fmt.Sprintf("%s packs take %s (p99) from receive until indexed, over %s.",
+					kind, p99.Round(time.Millisecond), threshold),
+			})
+		}
+	}
+}
+
+// Summary returns a one-line description of the last interval.
+func (l *IngestLatency) Summary() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.last) == 0 {
+		return "no packs"
+	}
+	var parts []string
+	for _, kind := range sortedKinds(l.last) {
+		h := l.last[kind]
+		parts = append(parts, fmt.Sprintf("%s %d p50 %s p99 %s max %s", kind, h.Count,
+			h.Quantile(0.5).Round(time.Millisecond), h.Quantile(0.99).Round(time.Millisecond), h.Max.Round(time.Millisecond)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ingestLatencyPacks builds the object and counter packs for one interval.
+func ingestLatencyPacks(objName string, now time.Time, hists map[string]ingest.Histogram) []pack.Pack {
+	tags := value.NewMapValue()
+	tags.Put(pack.TagDeadTime, value.NewDecimalValue(3*ingestLatencyInterval.Milliseconds()))
+	data := value.NewMapValue()
+	for kind, h := range hists {
+		prefix, ok := ingestCounterPrefix[kind]
+		if !ok {
+			continue
+		}
+		data.Put(prefix+"Count", value.NewDecimalValue(h.Count))
+		data.Put(prefix+"P50", &value.DoubleValue{Value: durationMs(h.Quantile(0.5))})
+		data.Put(prefix+"P99", &value.DoubleValue{Value: durationMs(h.Quantile(0.99))})
+		data.Put(prefix+"Max", &value.DoubleValue{Value: durationMs(h.Max)})
+	}
+	return []pack.Pack{
+		&pack.ObjectPack{
+			ObjType: "scouter",
+			ObjHash: util.HashString(objName),
+			ObjName: objName,
+			Version: "ingest",
+			Alive:   true,
+			Tags:    tags,
+		},
+		&pack.PerfCounterPack{
+			Time:     now.UnixMilli(),
+			ObjName:  objName,
+			TimeType: cache.TimeTypeRealtime,
+			Data:     data,
+		},
+	}
+}
+
+func sortedKinds(hists map[string]ingest.Histogram) []string {
+	kinds := make([]string, 0, len(hists))
+	for kind := range hists {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
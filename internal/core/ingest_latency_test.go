@@ -0,0 +1,60 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/ingest"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+func TestIngestLatency_ReportAlert(t *testing.T) {
+	dir := t.TempDir()
+	cfg := mirrorConfig(t, dir, "ingest_latency_obj_name=/scouter/ingest\ningest_latency_alert_p99_ms=1000\ningest_latency_alert_level=2\n")
+
+	ac := &AlertCore{queue: make(chan *pack.AlertPack, 10)}
+	var packs []pack.Pack
+	l := NewIngestLatency(ac, func(p pack.Pack) { packs = append(packs, p) })
+
+	latency := ingest.GetLatency()
+	latency.Drain()
+	for range 10 {
+		latency.Observe("xlog", time.Now().Add(-3*time.Second))
+		latency.Observe("counter", time.Now().Add(-2*time.Millisecond))
+	}
+	now := time.Now()
+	l.report(cfg, now, latency.Drain())
+
+	if len(packs) != 2 {
+		t.Fatalf("packs = %d, want object and counter", len(packs))
+	}
+	pc, ok := packs[1].(*pack.PerfCounterPack)
+	if !ok || pc.ObjName != "/scouter/ingest" {
+		t.Fatalf("counter pack = %+v", packs[1])
+	}
+	if v, _ := pc.Data.Get("IngestXLogCount"); v == nil || v.(*value.DecimalValue).Value != 10 {
+		t.Errorf("IngestXLogCount = %v", v)
+	}
+	if v, _ := pc.Data.Get("IngestXLogP99"); v == nil || v.(*value.DoubleValue).Value < 3000 {
+		t.Errorf("IngestXLogP99 = %v", v)
+	}
+
+	if len(ac.queue) != 1 {
+		t.Fatalf("alerts = %d, want 1 (xlog only)", len(ac.queue))
+	}
+	if ap := <-ac.queue; ap.Title != "INGEST_LATENCY" || ap.Level != 2 || !strings.Contains(ap.Message, "xlog") {
+		t.Errorf("alert = %+v", ap)
+	}
+	if s := l.Summary(); !strings.HasPrefix(s, "counter 10") || !strings.Contains(s, "xlog 10") {
+		t.Errorf("summary = %q", s)
+	}
+
+	// Still slow within the alert interval: no repeated alert.
+	latency.Observe("xlog", time.Now().Add(-3*time.Second))
+	l.report(cfg, now.Add(ingestLatencyInterval), latency.Drain())
+	if len(ac.queue) != 0 {
+		t.Errorf("alert repeated within %s", ingestLatencyAlertInterval)
+	}
+}
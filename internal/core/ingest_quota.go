@@ -0,0 +1,203 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// ingestQuotaLogInterval bounds how often throttling is logged per objType and kind.
+const ingestQuotaLogInterval = time.Minute
+
+// ingestQuotaDefault is the objType key applying to types not listed.
+const ingestQuotaDefault = "*"
+
+type quotaKey struct {
+	kind    string // "xlog" or "profile", as in packTypeName
+	objType string
+}
+
+type quotaWindow struct {
+	sec       int64 // epoch second the count belongs to
+	count     int
+	throttled int64 // since start
+	logged    time.Time
+	pending   int64 // throttled since last logged
+}
+
+// IngestThrottled reports the packs dropped for one objType and kind.
+type IngestThrottled struct {
+	Kind      string `json:"kind"`
+	ObjType   string `json:"objType"`
+	Throttled int64  `json:"throttled"`
+}
+
+// IngestQuota limits the XLog and profile packs accepted per second for each
+// object type, so a single misconfigured cluster cannot exhaust the server.
+// Limits come from the hot-reloadable ingest_quota_xlog_per_sec and
+// ingest_quota_profile_per_sec settings, written as "objType:limit" pairs
+// separated by commas; "*" applies to unlisted types. Packs beyond the limit
+// are dropped and counted.
+type IngestQuota struct {
+	objectCache *cache.ObjectCache
+	now         func() time.Time
+
+	mu      sync.Mutex
+	spec    string
+	limits  map[quotaKey]int
+	windows map[quotaKey]*quotaWindow
+}
+
+// NewIngestQuota creates an IngestQuota resolving object types through objectCache.
+func NewIngestQuota(objectCache *cache.ObjectCache) *IngestQuota {
+	return &IngestQuota{
+		objectCache: objectCache,
+		now:         time.Now,
+		windows:     make(map[quotaKey]*quotaWindow),
+	}
+}
+
+// Allow reports whether p is within its quota. Packs other than XLogs and
+// profiles are always allowed.
+func (q *IngestQuota) Allow(cfg *config.Config, p pack.Pack) bool {
+	kind := packTypeName(p.PackType())
+	if kind != "xlog" && kind != "profile" {
+		return true
+	}
+	if p.PackType() == pack.PackTypeDroppedXLog {
+		return true
+	}
+	objHash, ok := packObjHash(p)
+	if !ok {
+		return true
+	}
+	objType := q.objType(objHash)
+	now := q.now()
+
+	q.mu.Lock()
+	q.updateLimits(cfg)
+	limit, key, ok := q.limitFor(kind, objType)
+	if !ok {
+		q.mu.Unlock()
+		return true
+	}
+	w := q.windows[key]
+	if w == nil {
+		w = &quotaWindow{}
+		q.windows[key] = w
+	}
+	if sec := now.Unix(); sec != w.sec {
+		w.sec, w.count = sec, 0
+	}
+	if w.count < limit {
+		w.count++
+		q.mu.Unlock()
+		return true
+	}
+	w.throttled++
+	w.pending++
+	var dropped int64
+	if now.Sub(w.logged) >= ingestQuotaLogInterval {
+		dropped, w.pending, w.logged = w.pending, 0, now
+	}
+	q.mu.Unlock()
+
+	if dropped > 0 {
+		slog.Warn("Ingest quota exceeded, dropping packs", "kind", kind, "objType", key.objType,
+			"limitPerSec", limit, "dropped", dropped)
+	}
+	return false
+}
+
+// limitFor returns the limit applying to objType and the key it is counted
+// under: unlisted types share the "*" window. Caller must hold q.mu.
+func (q *IngestQuota) limitFor(kind, objType string) (int, quotaKey, bool) {
+	key := quotaKey{kind: kind, objType: objType}
+	if limit, ok := q.limits[key]; ok {
+		return limit, key, limit > 0
+	}
+	key.objType = ingestQuotaDefault
+	limit, ok := q.limits[key]
+	return limit, key, ok && limit > 0
+}
+
+// updateLimits re-parses the quota settings when they change. Caller must hold q.mu.
+func (q *IngestQuota) updateLimits(cfg *config.Config) {
+	xlogSpec, profileSpec := cfg.IngestQuotaXLogPerSec(), cfg.IngestQuotaProfilePerSec()
+	spec := xlogSpec + "|" + profileSpec
+	if spec == q.spec && q.limits != nil {
+		return
+	}
+	q.spec = spec
+	q.limits = make(map[quotaKey]int)
+	parseQuotaSpec(q.limits, "xlog", xlogSpec)
+	parseQuotaSpec(q.limits, "profile", profileSpec)
+}
+
+// parseQuotaSpec adds the "objType:limit" entries of spec to limits. A limit
+// of 0 exempts a type from the "*" default.
+func parseQuotaSpec(limits map[quotaKey]int, kind, spec string) {
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		objType, limitStr, ok := strings.Cut(entry, ":")
+		limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+		objType = strings.TrimSpace(objType)
+		if !ok || err != nil || limit < 0 || objType == "" {
+			slog.Warn("Ingest quota: bad entry ignored", "kind", kind, "entry", entry)
+			continue
+		}
+		limits[quotaKey{kind: kind, objType: objType}] = limit
+	}
+}
+
+// Throttled returns the packs dropped since start, largest first.
+func (q *IngestQuota) Throttled() []IngestThrottled {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var result []IngestThrottled
+	for key, w := range q.windows {
+		if w.throttled > 0 {
+			result = append(result, IngestThrottled{Kind: key.kind, ObjType: key.objType, Throttled: w.throttled})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Throttled != result[j].Throttled {
+			return result[i].Throttled > result[j].Throttled
+		}
+		return result[i].Kind+result[i].ObjType < result[j].Kind+result[j].ObjType
+	})
+	return result
+}
+
+// Summary returns a one-line description of the throttled packs.
+func (q *IngestQuota) Summary() string {
+	throttled := q.Throttled()
+	if len(throttled) == 0 {
+		return "nothing throttled"
+	}
+	parts := make([]string, len(throttled))
+	for i, t := range throttled {
+		parts[i] = fmt.Sprintf("%s/%s %d", t.Kind, t.ObjType, t.Throttled)
+	}
+	return "throttled " + strings.Join(parts, ", ")
+}
+
+func (q *IngestQuota) objType(objHash int32) string {
+	if q.objectCache != nil {
+		if info, ok := q.objectCache.Get(objHash); ok {
+			return info.Pack.ObjType
+		}
+	}
+	return ""
+}
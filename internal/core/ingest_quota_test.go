@@ -0,0 +1,87 @@
+package core
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+func TestIngestQuota_PerObjType(t *testing.T) {
+	cfg := mirrorConfig(t, t.TempDir(),
+		"ingest_quota_xlog_per_sec=test:3,prod:0,*:5\ningest_quota_profile_per_sec=test:1\n")
+
+	objects := cache.NewObjectCache()
+	objects.Put(1, &pack.ObjectPack{ObjHash: 1, ObjType: "test"})
+	objects.Put(2, &pack.ObjectPack{ObjHash: 2, ObjType: "prod"})
+	objects.Put(3, &pack.ObjectPack{ObjHash: 3, ObjType: "batch"})
+
+	q := NewIngestQuota(objects)
+	now := time.Unix(1700000000, 0)
+	q.now = func() time.Time { return now }
+
+	accepted := func(p pack.Pack, n int) int {
+		ok := 0
+		for i := 0; i < n; i++ {
+			if q.Allow(cfg, p) {
+				ok++
+			}
+		}
+		return ok
+	}
+	if n := accepted(&pack.XLogPack{ObjHash: 1}, 10); n != 3 {
+		t.Errorf("test xlogs accepted = %d, want 3", n)
+	}
+	if n := accepted(&pack.XLogPack{ObjHash: 2}, 10); n != 10 {
+		t.Errorf("prod xlogs accepted = %d, want 10 (exempt)", n)
+	}
+	// Unlisted and unknown objects share the default window.
+	if n := accepted(&pack.XLogPack{ObjHash: 3}, 3) + accepted(&pack.XLogPack{ObjHash: 99}, 3); n != 5 {
+		t.Errorf("default xlogs accepted = %d, want 5", n)
+	}
+	if n := accepted(&pack.XLogProfilePack{ObjHash: 1}, 4); n != 1 {
+		t.Errorf("test profiles accepted = %d, want 1", n)
+	}
+	if n := accepted(&pack.XLogProfilePack{ObjHash: 3}, 4); n != 4 {
+		t.Errorf("batch profiles accepted = %d, want 4 (no default)", n)
+	}
+	if n := accepted(&pack.PerfCounterPack{ObjName: "x"}, 10); n != 10 {
+		t.Errorf("counters accepted = %d, want 10", n)
+	}
+
+	// A new second opens a new window.
+	now = now.Add(time.Second)
+	if n := accepted(&pack.XLogPack{ObjHash: 1}, 10); n != 3 {
+		t.Errorf("test xlogs accepted next second = %d, want 3", n)
+	}
+
+	got := q.Throttled()
+	want := []IngestThrottled{{"xlog", "test", 14}, {"profile", "test", 3}, {"xlog", "*", 1}}
+	if len(got) != len(want) {
+		t.Fatalf("throttled = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("throttled[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDispatcher_Quota(t *testing.T) {
+	mirrorConfig(t, t.TempDir(), "ingest_quota_xlog_per_sec=*:2\n")
+	d := NewDispatcher()
+	q := NewIngestQuota(nil)
+	now := time.Now()
+	q.now = func() time.Time { return now }
+	d.SetQuota(q)
+	handled := 0
+	d.Register(pack.PackTypeXLog, func(p pack.Pack, addr *net.UDPAddr) { handled++ })
+	for i := 0; i < 5; i++ {
+		d.Dispatch(&pack.XLogPack{ObjHash: 1}, &net.UDPAddr{})
+	}
+	if handled != 2 {
+		t.Errorf("handled = %d, want 2", handled)
+	}
+}
@@ -0,0 +1,175 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/compress"
+	"github.com/zbum/scouter-server-go/internal/db/io"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+const ioStatsInterval = 10 * time.Second
+
+// ioStoreNames names the storages in the self-metric counters; others are
+// capitalized as they are.
+var ioStoreNames = map[string]string{
+	"xlog":    "XLog",
+	"profile": "Profile",
+	"counter": "Counter",
+	"text":    "Text",
+	"alert":   "Alert",
+	"summary": "Summary",
+}
+
+// IOStats reports the reads and writes of the storage files. Every interval
+// it takes the statistics kept by io.GetIOStats and stores, per storage and
+// operation, the count, bytes, p99/max latency and p99/max hash chain depth
+// as counters of the object named by io_stats_obj_name, so a slow disk or a
+// degenerating index can be followed over time for every storage alike.
+// The compression ratio of the XLog and profile data written in the
+// interval, from compress.GetStats, is stored alongside.
+type IOStats struct {
+	ingest func(pack.Pack)
+
+	mu       sync.Mutex
+	last     []io.OpStat
+	lastComp []compress.KindStat // totals at the last report
+}
+
+// NewIOStats creates an IOStats storing its counters through ingestFn.
+func NewIOStats(ingestFn func(pack.Pack)) *IOStats {
+	return &IOStats{ingest: ingestFn}
+}
+
+// Start reports the statistics every interval until ctx is cancelled.
+func (s *IOStats) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(ioStatsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if cfg := config.Get(); cfg != nil {
+					s.report(cfg, now, io.GetIOStats().Drain())
+				}
+			}
+		}
+	}()
+}
+
+// report handles the statistics of one interval.
+func (s *IOStats) report(cfg *config.Config, now time.Time, stats []io.OpStat) {
+	comp := compress.GetStats().Snapshot()
+	s.mu.Lock()
+	s.last = stats
+	delta := compressDelta(comp, s.lastComp)
+	s.lastComp = comp
+	s.mu.Unlock()
+
+	objName := cfg.IOStatsObjName()
+	if objName != "" && s.ingest != nil && len(stats) > 0 {
+		for _, p := range ioStatsPacks(objName, now, stats, delta) {
+			s.ingest(p)
+		}
+	}
+}
+
+// Summary returns a one-line description of the last interval.
+func (s *IOStats) Summary() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.last) == 0 {
+		return "no operations"
+	}
+	var parts []string
+	for _, st := range s.last {
+		part := fmt.Sprintf("%s %s %d p99 %s", st.Store, st.Op, st.Latency.Count, microsDuration(st.Latency.Quantile(0.99)))
+		if st.Depth.Count > 0 {
+			part += fmt.Sprintf(" depth p99 %d", st.Depth.Quantile(0.99))
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// compressDelta returns what was written between the totals prev and cur.
+func compressDelta(cur, prev []compress.KindStat) []compress.KindStat {
+	var delta []compress.KindStat
+	for _, c := range cur {
+		for _, p := range prev {
+			if p.Kind == c.Kind {
+				c.Records -= p.Records
+				c.Compressed -= p.Compressed
+				c.RawBytes -= p.RawBytes
+				c.Stored -= p.Stored
+			}
+		}
+		if c.Records > 0 {
+			delta = append(delta, c)
+		}
+	}
+	return delta
+}
+
+// ioStatsPacks builds the object and counter packs for one interval.
+func ioStatsPacks(objName string, now time.Time, stats []io.OpStat, comp []compress.KindStat) []pack.Pack {
+	tags := value.NewMapValue()
+	tags.Put(pack.TagDeadTime, value.NewDecimalValue(3*ioStatsInterval.Milliseconds()))
+	data := value.NewMapValue()
+	for _, st := range stats {
+		prefix := "Io" + ioCounterName(st.Store) + ioCounterName(st.Op)
+		data.Put(prefix+"Count", value.NewDecimalValue(st.Latency.Count))
+		data.Put(prefix+"P99", &value.DoubleValue{Value: durationMs(microsDuration(st.Latency.Quantile(0.99)))})
+		data.Put(prefix+"Max", &value.DoubleValue{Value: durationMs(microsDuration(st.Latency.Max))})
+		if st.Bytes > 0 {
+			data.Put(prefix+"Bytes", value.NewDecimalValue(st.Bytes))
+		}
+		if st.Depth.Count > 0 {
+			data.Put(prefix+"DepthP99", value.NewDecimalValue(st.Depth.Quantile(0.99)))
+			data.Put(prefix+"DepthMax", value.NewDecimalValue(st.Depth.Max))
+		}
+	}
+	for _, st := range comp {
+		data.Put("Compress"+ioCounterName(st.Kind)+"Ratio", &value.DoubleValue{Value: st.Ratio()})
+	}
+	return []pack.Pack{
+		&pack.ObjectPack{
+			ObjType: "scouter",
+			ObjHash: util.HashString(objName),
+			ObjName: objName,
+			Version: "io",
+			Alive:   true,
+			Tags:    tags,
+		},
+		&pack.PerfCounterPack{
+			Time:     now.UnixMilli(),
+			ObjName:  objName,
+			TimeType: cache.TimeTypeRealtime,
+			Data:     data,
+		},
+	}
+}
+
+func ioCounterName(s string) string {
+	if name, ok := ioStoreNames[s]; ok {
+		return name
+	}
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func microsDuration(us int64) time.Duration {
+	return time.Duration(us) * time.Microsecond
+}
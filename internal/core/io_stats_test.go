@@ -0,0 +1,59 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/io"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+func TestIOStats_Report(t *testing.T) {
+	dir := t.TempDir()
+	cfg := mirrorConfig(t, dir, "io_stats_obj_name=/scouter/io\n")
+
+	var packs []pack.Pack
+	s := NewIOStats(func(p pack.Pack) { packs = append(packs, p) })
+	if got := s.Summary(); got != "no operations" {
+		t.Errorf("summary = %q", got)
+	}
+
+	xlogDir := filepath.Join(dir, "20261016", "xlog")
+	os.MkdirAll(xlogDir, 0755)
+	idx, err := io.NewIndexKeyFile(filepath.Join(xlogDir, "xlog_tid"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	stats := io.GetIOStats()
+	stats.Drain()
+	for i := range 3 {
+		idx.Put([]byte("tx"), []byte{byte(i)})
+	}
+	idx.GetAll([]byte("tx"))
+	s.report(cfg, time.Now(), stats.Drain())
+
+	if len(packs) != 2 {
+		t.Fatalf("packs = %d, want object and counter", len(packs))
+	}
+	if op, ok := packs[0].(*pack.ObjectPack); !ok || op.ObjName != "/scouter/io" || op.ObjType != "scouter" {
+		t.Fatalf("object pack = %+v", packs[0])
+	}
+	pc := packs[1].(*pack.PerfCounterPack)
+	if v, _ := pc.Data.Get("IoXLogWriteCount"); v == nil || v.(*value.DecimalValue).Value != 3 {
+		t.Errorf("IoXLogWriteCount = %v", v)
+	}
+	if v, _ := pc.Data.Get("IoXLogReadDepthMax"); v == nil || v.(*value.DecimalValue).Value != 3 {
+		t.Errorf("IoXLogReadDepthMax = %v", v)
+	}
+	if v, _ := pc.Data.Get("IoXLogWriteDepthMax"); v != nil {
+		t.Errorf("IoXLogWriteDepthMax = %v, appends walk no chain", v)
+	}
+	if got := s.Summary(); !strings.HasPrefix(got, "xlog read 1 ") || !strings.Contains(got, "depth p99 3") {
+		t.Errorf("summary = %q", got)
+	}
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// inspectMaxBytes bounds the raw bytes kept per pack; profiles can be large.
+const inspectMaxBytes = 4096
+
+// InspectedPack is a pack as received from an agent.
+type InspectedPack struct {
+	Time int64 // receive time, ms
+	Addr string
+	Type string // as in mirror_pack_types
+	Pack pack.Pack
+	Raw  []byte // wire bytes of the pack, cut at inspectMaxBytes
+	Size int    // length of the pack on the wire
+}
+
+type inspectKey struct {
+	objHash  int32
+	packType byte
+}
+
+// PackInspector keeps the last pack_inspect_count packs of every object and
+// pack type so agent protocol problems can be examined on a running server,
+// without restarting it with the log_udp_* settings.
+type PackInspector struct {
+	mu    sync.Mutex
+	count int
+	rings map[inspectKey][]InspectedPack // oldest first
+}
+
+// NewPackInspector creates an empty PackInspector.
+func NewPackInspector() *PackInspector {
+	return &PackInspector{rings: make(map[inspectKey][]InspectedPack)}
+}
+
+// Record keeps p, decoded from raw, if pack_inspect_count is set.
+func (pi *PackInspector) Record(cfg *config.Config, p pack.Pack, raw []byte, addr *net.UDPAddr) {
+	n := cfg.PackInspectCount()
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+	if n != pi.count {
+		// A changed count drops what was kept rather than resizing every ring.
+		pi.count = n
+		clear(pi.rings)
+	}
+	if n <= 0 {
+		return
+	}
+	objHash, ok := packObjHash(p)
+	if !ok {
+		return
+	}
+
+	ip := InspectedPack{Time: time.Now().UnixMilli(), Type: packTypeName(p.PackType()), Pack: p, Size: len(raw)}
+	ip.Raw = append([]byte(nil), raw[:min(len(raw), inspectMaxBytes)]...)
+	if addr != nil {
+		ip.Addr = addr.String()
+	}
+	key := inspectKey{objHash: objHash, packType: p.PackType()}
+	ring := pi.rings[key]
+	if len(ring) >= n {
+		ring = append(ring[:0], ring[len(ring)-n+1:]...)
+	}
+	pi.rings[key] = append(ring, ip)
+}
+
+// Recent returns up to max packs of objHash, newest first. typeName, one of
+// the names used by mirror_pack_types, limits them to one pack type.
+func (pi *PackInspector) Recent(objHash int32, typeName string, max int) []InspectedPack {
+	pi.mu.Lock()
+	var result []InspectedPack
+	for key, ring := range pi.rings {
+		if key.objHash == objHash && (typeName == "" || packTypeName(key.packType) == typeName) {
+			for i := len(ring) - 1; i >= 0; i-- {
+				result = append(result, ring[i])
+			}
+		}
+	}
+	pi.mu.Unlock()
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Time > result[j].Time })
+	if max > 0 && len(result) > max {
+		result = result[:max]
+	}
+	return result
+}
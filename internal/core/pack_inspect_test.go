@@ -0,0 +1,59 @@
+package core
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+func TestPackInspector(t *testing.T) {
+	dir := t.TempDir()
+	cfg := mirrorConfig(t, dir, "pack_inspect_count=3\n")
+	pi := NewPackInspector()
+	addr := &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 6100}
+
+	raw := func(p pack.Pack) []byte {
+		out := protocol.NewDataOutputX()
+		pack.WritePack(out, p)
+		return out.ToByteArray()
+	}
+	for txid := int64(1); txid <= 5; txid++ {
+		xp := &pack.XLogPack{ObjHash: 11, Txid: txid}
+		pi.Record(cfg, xp, raw(xp), addr)
+	}
+	op := &pack.ObjectPack{ObjHash: 11, ObjName: "/host/app", ObjType: "java", Tags: value.NewMapValue()}
+	pi.Record(cfg, op, raw(op), addr)
+	pi.Record(cfg, &pack.XLogPack{ObjHash: 22, Txid: 9}, nil, addr)
+	pi.Record(cfg, &pack.TextPack{XType: "service", Hash: 1, Text: "/a"}, nil, addr)
+
+	got := pi.Recent(11, "", 0)
+	if len(got) != 4 {
+		t.Fatalf("Recent = %d packs, want 3 xlogs and 1 object", len(got))
+	}
+	xlogs := pi.Recent(11, "xlog", 0)
+	if len(xlogs) != 3 || xlogs[0].Pack.(*pack.XLogPack).Txid != 5 || xlogs[2].Pack.(*pack.XLogPack).Txid != 3 {
+		t.Fatalf("xlogs not the last 3, newest first: %+v", xlogs)
+	}
+	if x := xlogs[0]; x.Addr != "10.0.0.1:6100" || x.Type != "xlog" || !bytes.Equal(x.Raw, raw(x.Pack)) || x.Size != len(x.Raw) {
+		t.Errorf("inspected = %+v", x)
+	}
+	if len(pi.Recent(11, "object", 0)) != 1 || len(pi.Recent(11, "", 2)) != 2 {
+		t.Error("type filter or max not applied")
+	}
+
+	big := &pack.XLogProfilePack{ObjHash: 11, Profile: make([]byte, 2*inspectMaxBytes)}
+	pi.Record(cfg, big, raw(big), addr)
+	if p := pi.Recent(11, "profile", 0)[0]; len(p.Raw) != inspectMaxBytes || p.Size <= inspectMaxBytes {
+		t.Errorf("profile raw %d bytes of %d", len(p.Raw), p.Size)
+	}
+
+	cfg = mirrorConfig(t, dir, "pack_inspect_count=0\n")
+	pi.Record(cfg, op, raw(op), addr)
+	if got := pi.Recent(11, "", 0); len(got) != 0 {
+		t.Errorf("disabled inspector kept %d packs", len(got))
+	}
+}
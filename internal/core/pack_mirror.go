@@ -0,0 +1,224 @@
+package core
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/mirror"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// mirrorFlushInterval bounds how much of a capture is lost if the server dies.
+const mirrorFlushInterval = time.Second
+
+// PackMirror captures a sample of incoming packs to a file under
+// temp_dir/mirror so protocol issues with specific agents can be analysed or
+// replayed later. It is driven by the hot-reloadable mirror_pack_* settings:
+// enabling starts a new file, disabling closes it.
+type PackMirror struct {
+	active atomic.Bool // a capture file is open
+	full   atomic.Bool // stopped by size or error; waits for a disable/enable cycle
+
+	mu        sync.Mutex
+	file      *os.File
+	w         *mirror.Writer
+	path      string
+	written   int64
+	lastFlush time.Time
+
+	filterSpec string // "types|objHashes" the filters were built from
+	types      map[string]bool
+	objHashes  map[int32]bool
+}
+
+// NewPackMirror creates an idle PackMirror.
+func NewPackMirror() *PackMirror {
+	return &PackMirror{}
+}
+
+// Mirror captures p if mirroring is enabled and p passes the filters.
+func (m *PackMirror) Mirror(cfg *config.Config, p pack.Pack, addr *net.UDPAddr) {
+	if !cfg.MirrorPackEnabled() {
+		if m.active.Load() || m.full.Load() {
+			m.mu.Lock()
+			m.closeLocked("disabled")
+			m.full.Store(false)
+			m.mu.Unlock()
+		}
+		return
+	}
+
+	if m.full.Load() {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.updateFilters(cfg)
+	if len(m.types) > 0 && !m.types[packTypeName(p.PackType())] {
+		return
+	}
+	if len(m.objHashes) > 0 {
+		if h, ok := packObjHash(p); ok && !m.objHashes[h] {
+			return
+		}
+	}
+	if pct := cfg.MirrorPackSamplePct(); pct < 100 && rand.IntN(100) >= pct {
+		return
+	}
+
+	if m.file == nil && !m.open(cfg.TempDir()) {
+		m.full.Store(true) // don't retry on every pack; wait for a toggle
+		return
+	}
+	var a string
+	if addr != nil {
+		a = addr.String()
+	}
+	n, err := m.w.Write(time.Now().UnixMilli(), a, p)
+	if err != nil {
+		slog.Error("PackMirror: write failed", "path", m.path, "error", err)
+		m.closeLocked("write error")
+		m.full.Store(true)
+		return
+	}
+	m.written += int64(n)
+
+	if m.written >= int64(cfg.MirrorPackMaxMB())*1024*1024 {
+		m.closeLocked("mirror_pack_max_mb reached; toggle mirror_pack_enabled to start a new file")
+		m.full.Store(true)
+		return
+	}
+	if time.Since(m.lastFlush) >= mirrorFlushInterval {
+		m.w.Flush()
+		m.lastFlush = time.Now()
+	}
+}
+
+// Close flushes and closes the current capture file, if any.
+func (m *PackMirror) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closeLocked("server stopping")
+}
+
+// Path returns the current capture file, or "" if none is open.
+func (m *PackMirror) Path() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.file == nil {
+		return ""
+	}
+	return m.path
+}
+
+func (m *PackMirror) open(tempDir string) bool {
+	dir := filepath.Join(tempDir, "mirror")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		slog.Error("PackMirror: create dir failed", "dir", dir, "error", err)
+		return false
+	}
+	path := filepath.Join(dir, "mirror-"+time.Now().Format("20060102-150405")+".scm")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		slog.Error("PackMirror: open failed", "path", path, "error", err)
+		return false
+	}
+	w, err := mirror.NewWriter(f)
+	if err != nil {
+		f.Close()
+		slog.Error("PackMirror: open failed", "path", path, "error", err)
+		return false
+	}
+	m.file, m.w, m.path = f, w, path
+	m.written = 0
+	m.lastFlush = time.Now()
+	m.active.Store(true)
+	slog.Info("PackMirror: capturing", "path", path, "filter", m.filterSpec)
+	return true
+}
+
+func (m *PackMirror) closeLocked(reason string) {
+	if m.file == nil {
+		return
+	}
+	m.w.Flush()
+	m.file.Close()
+	slog.Info("PackMirror: capture closed", "path", m.path, "bytes", m.written, "reason", reason)
+	m.file, m.w = nil, nil
+	m.active.Store(false)
+}
+
+// updateFilters rebuilds the type and object filters when their settings change.
+func (m *PackMirror) updateFilters(cfg *config.Config) {
+	typeSpec, objSpec := cfg.MirrorPackTypes(), cfg.MirrorPackObjHashes()
+	spec := typeSpec + "|" + objSpec
+	if spec == m.filterSpec && m.types != nil {
+		return
+	}
+	m.filterSpec = spec
+	m.types = make(map[string]bool)
+	m.objHashes = make(map[int32]bool)
+	for _, t := range strings.Split(typeSpec, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			m.types[t] = true
+		}
+	}
+	// Entries are objHash values or object names such as /host/tomcat1.
+	for _, o := range strings.Split(objSpec, ",") {
+		o = strings.TrimSpace(o)
+		if o == "" {
+			continue
+		}
+		if h, err := strconv.ParseInt(o, 10, 32); err == nil {
+			m.objHashes[int32(h)] = true
+		} else {
+			m.objHashes[util.HashString(o)] = true
+		}
+	}
+}
+
+// packObjHash returns the object a pack belongs to. Packs without one, such
+// as texts, pass the object filter so captures stay resolvable.
+func packObjHash(p pack.Pack) (int32, bool) {
+	switch x := p.(type) {
+	case *pack.XLogPack:
+		return x.ObjHash, true
+	case *pack.XLogProfilePack:
+		return x.ObjHash, true
+	case *pack.XLogProfilePack2:
+		return x.ObjHash, true
+	case *pack.ObjectPack:
+		if x.ObjHash == 0 {
+			return util.HashString(x.ObjName), true
+		}
+		return x.ObjHash, true
+	case *pack.PerfCounterPack:
+		return util.HashString(x.ObjName), true
+	case *pack.InteractionPerfCounterPack:
+		return util.HashString(x.ObjName), true
+	case *pack.AlertPack:
+		return x.ObjHash, true
+	case *pack.SummaryPack:
+		return x.ObjHash, true
+	case *pack.BatchPack:
+		return x.ObjHash, true
+	case *pack.SpanPack:
+		return x.ObjHash, true
+	case *pack.StackPack:
+		return x.ObjHash, true
+	case *pack.StatusPack:
+		return x.ObjHash, true
+	}
+	return 0, false
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/mirror"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+func mirrorConfig(t *testing.T, dir, props string) *config.Config {
+	t.Helper()
+	path := filepath.Join(dir, "scouter.conf")
+	if err := os.WriteFile(path, []byte("temp_dir="+dir+"\n"+props), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestPackMirror_FilterAndToggle(t *testing.T) {
+	dir := t.TempDir()
+	m := NewPackMirror()
+
+	off := mirrorConfig(t, dir, "")
+	m.Mirror(off, &pack.XLogPack{ObjHash: 1}, nil)
+	if m.Path() != "" {
+		t.Fatal("mirror must stay idle while disabled")
+	}
+
+	target := util.HashString("/host/tomcat1")
+	on := mirrorConfig(t, dir, "mirror_pack_enabled=true\nmirror_pack_types=xlog,text\nmirror_pack_obj_hashes=/host/tomcat1\n")
+	m.Mirror(on, &pack.XLogPack{ObjHash: target, Txid: 1}, nil)
+	m.Mirror(on, &pack.XLogPack{ObjHash: 999, Txid: 2}, nil)                     // other object
+	m.Mirror(on, &pack.XLogProfilePack{ObjHash: target, Txid: 1}, nil)           // other type
+	m.Mirror(on, &pack.TextPack{XType: "service", Hash: 1, Text: "/order"}, nil) // no object: kept
+	path := m.Path()
+	if path == "" || filepath.Dir(path) != filepath.Join(dir, "mirror") {
+		t.Fatalf("capture path = %q", path)
+	}
+
+	// Disabling closes the file.
+	m.Mirror(off, &pack.XLogPack{ObjHash: target}, nil)
+	if m.Path() != "" {
+		t.Fatal("disabling must close the capture")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := mirror.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []pack.Pack
+	for {
+		rec, err := r.Next()
+		if err != nil {
+			break
+		}
+		got = append(got, rec.Pack)
+	}
+	if len(got) != 2 {
+		t.Fatalf("captured %d packs, want 2", len(got))
+	}
+	if xp, ok := got[0].(*pack.XLogPack); !ok || xp.Txid != 1 {
+		t.Errorf("pack 0 = %+v", got[0])
+	}
+	if _, ok := got[1].(*pack.TextPack); !ok {
+		t.Errorf("pack 1 = %T, want TextPack", got[1])
+	}
+}
+
+func TestPackMirror_MaxSize(t *testing.T) {
+	dir := t.TempDir()
+	m := NewPackMirror()
+	defer m.Close()
+
+	on := mirrorConfig(t, dir, "mirror_pack_enabled=true\nmirror_pack_max_mb=0\n")
+	m.Mirror(on, &pack.XLogPack{Txid: 1}, nil)
+	if m.Path() != "" {
+		t.Fatal("capture must stop once mirror_pack_max_mb is reached")
+	}
+	m.Mirror(on, &pack.XLogPack{Txid: 2}, nil)
+	files, _ := filepath.Glob(filepath.Join(dir, "mirror", "*.scm"))
+	if len(files) != 1 {
+		t.Errorf("files = %v, want one capture until re-enabled", files)
+	}
+}
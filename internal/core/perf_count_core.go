@@ -3,8 +3,10 @@ package core
 import (
 	"log/slog"
 	"net"
+	"sync/atomic"
 	"time"
 
+	"github.com/zbum/scouter-server-go/internal/alertrule"
 	"github.com/zbum/scouter-server-go/internal/core/cache"
 	"github.com/zbum/scouter-server-go/internal/db/counter"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
@@ -18,63 +20,150 @@ import (
 type PerfCountCore struct {
 	counterCache *cache.CounterCache
 	counterWR    *counter.CounterWR
-	queue        chan *pack.PerfCounterPack
+	queue        chan queued[*pack.PerfCounterPack]
+	dropped      atomic.Int64
+	check        *CounterCheck
+	alertRules   *alertrule.Engine
+	now          func() time.Time
+	noWorker     bool
 }
 
-func NewPerfCountCore(counterCache *cache.CounterCache, counterWR *counter.CounterWR) *PerfCountCore {
+// PerfCountCoreOption configures a PerfCountCore.
+type PerfCountCoreOption func(*PerfCountCore)
+
+// WithPerfCountClock sets the clock stamping counter packs that arrive
+// without a time.
+func WithPerfCountClock(now func() time.Time) PerfCountCoreOption {
+	return func(pc *PerfCountCore) { pc.now = now }
+}
+
+// WithoutPerfCountWorker starts no worker goroutine; queued packs are
+// processed only by Drain. Used by deterministic tests.
+func WithoutPerfCountWorker() PerfCountCoreOption {
+	return func(pc *PerfCountCore) { pc.noWorker = true }
+}
+
+func NewPerfCountCore(counterCache *cache.CounterCache, counterWR *counter.CounterWR, opts ...PerfCountCoreOption) *PerfCountCore {
 	pc := &PerfCountCore{
 		counterCache: counterCache,
 		counterWR:    counterWR,
-		queue:        make(chan *pack.PerfCounterPack, 4096),
+		queue:        make(chan queued[*pack.PerfCounterPack], 4096),
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(pc)
+	}
+	if !pc.noWorker {
+		go pc.run()
 	}
-	go pc.run()
 	return pc
 }
 
 func (pc *PerfCountCore) Handler() PackHandler {
-	return func(p pack.Pack, addr *net.UDPAddr) {
+	h := pc.StampedHandler()
+	return func(p pack.Pack, addr *net.UDPAddr) { h(p, addr, time.Time{}) }
+}
+
+// StampedHandler is Handler passing the receive time on to CounterWR.
+func (pc *PerfCountCore) StampedHandler() StampedPackHandler {
+	return func(p pack.Pack, addr *net.UDPAddr, received time.Time) {
 		cp, ok := p.(*pack.PerfCounterPack)
 		if !ok {
 			return
 		}
 		if cp.Time == 0 {
-			cp.Time = time.Now().UnixMilli()
+			cp.Time = pc.now().UnixMilli()
 		}
 		select {
-		case pc.queue <- cp:
+		case pc.queue <- queued[*pack.PerfCounterPack]{cp, received}:
 		default:
+			pc.dropped.Add(1)
 			slog.Warn("PerfCountCore queue overflow")
 		}
 	}
 }
 
+// SetCheck installs c to verify that cached realtime counters are persisted.
+// It must be called before packs are dispatched.
+func (pc *PerfCountCore) SetCheck(c *CounterCheck) {
+	pc.check = c
+}
+
+// SetAlertRules installs e to evaluate the alert rules on realtime counters.
+// It must be called before packs are dispatched.
+func (pc *PerfCountCore) SetAlertRules(e *alertrule.Engine) {
+	pc.alertRules = e
+}
+
+// Dropped returns the number of counter packs dropped due to queue overflow.
+func (pc *PerfCountCore) Dropped() int64 {
+	return pc.dropped.Load()
+}
+
+// Pending returns the number of counter packs waiting to be processed.
+func (pc *PerfCountCore) Pending() int {
+	return len(pc.queue)
+}
+
+// Drain processes every queued pack on the calling goroutine and returns
+// how many were processed. See WithoutPerfCountWorker.
+func (pc *PerfCountCore) Drain() int {
+	n := 0
+	for {
+		select {
+		case q := <-pc.queue:
+			pc.process(q)
+			n++
+		default:
+			return n
+		}
+	}
+}
+
 func (pc *PerfCountCore) run() {
-	for cp := range pc.queue {
-		objHash := util.HashString(cp.ObjName)
-
-		// Cache each counter value
-		for _, entry := range cp.Data.Entries {
-			key := cache.CounterKey{
-				ObjHash:  objHash,
-				Counter:  entry.Key,
-				TimeType: cp.TimeType,
-			}
-			pc.counterCache.Put(key, entry.Value)
+	for q := range pc.queue {
+		pc.process(q)
+	}
+}
+
+func (pc *PerfCountCore) process(q queued[*pack.PerfCounterPack]) {
+	cp := q.p
+	objHash := util.HashString(cp.ObjName)
+
+	// Cache each counter value
+	for _, entry := range cp.Data.Entries {
+		key := cache.CounterKey{
+			ObjHash:  objHash,
+			Counter:  entry.Key,
+			TimeType: cp.TimeType,
 		}
+		pc.counterCache.Put(key, entry.Value)
+	}
 
-		slog.Debug("PerfCountCore processing",
-			"objName", cp.ObjName,
-			"objHash", objHash,
-			"counters", cp.Data.Size())
-		if pc.counterWR != nil {
-			if cp.TimeType == cache.TimeTypeRealtime {
-				// Convert MapValue entries to map[string]value.Value
-				counters := make(map[string]value.Value)
-				for _, entry := range cp.Data.Entries {
-					counters[entry.Key] = entry.Value
-				}
-				pc.counterWR.AddRealtimeFromPerfCounter(cp.Time, objHash, counters)
-			}
+	slog.Debug("PerfCountCore processing",
+		"objName", cp.ObjName,
+		"objHash", objHash,
+		"counters", cp.Data.Size())
+	if cp.TimeType != cache.TimeTypeRealtime {
+		return
+	}
+	// Convert MapValue entries to map[string]value.Value
+	counters := make(map[string]value.Value)
+	for _, entry := range cp.Data.Entries {
+		counters[entry.Key] = entry.Value
+	}
+	if pc.counterWR != nil {
+		pc.counterWR.AddRealtime(&counter.RealtimeEntry{
+			TimeMs:   cp.Time,
+			ObjHash:  objHash,
+			Counters: counters,
+			Received: q.received,
+		})
+		if pc.check != nil {
+			pc.check.Observe(objHash, cp.Time, pc.now(), counters)
 		}
 	}
+	if pc.alertRules != nil {
+		pc.alertRules.Observe(objHash, cp.Time, counters)
+	}
 }
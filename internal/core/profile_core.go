@@ -3,37 +3,53 @@ package core
 import (
 	"log/slog"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/zbum/scouter-server-go/internal/db/profile"
+	"github.com/zbum/scouter-server-go/internal/profstat"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 )
 
 // ProfileCore processes incoming XLogProfilePack data.
 type ProfileCore struct {
 	profileWR *profile.ProfileWR
-	queue     chan *pack.XLogProfilePack
+	profStat  *profstat.Core
+	queue     chan queued[*pack.XLogProfilePack]
+	dropped   atomic.Int64
 }
 
 func NewProfileCore(profileWR *profile.ProfileWR) *ProfileCore {
 	pc := &ProfileCore{
 		profileWR: profileWR,
-		queue:     make(chan *pack.XLogProfilePack, 4096),
+		queue:     make(chan queued[*pack.XLogProfilePack], 4096),
 	}
 	go pc.run()
 	return pc
 }
 
+// SetProfileStat feeds the profiles to the per-service step time breakdown.
+func (pc *ProfileCore) SetProfileStat(c *profstat.Core) {
+	pc.profStat = c
+}
+
 func (pc *ProfileCore) Handler() PackHandler {
-	return func(p pack.Pack, addr *net.UDPAddr) {
+	h := pc.StampedHandler()
+	return func(p pack.Pack, addr *net.UDPAddr) { h(p, addr, time.Time{}) }
+}
+
+// StampedHandler is Handler passing the receive time on to ProfileWR.
+func (pc *ProfileCore) StampedHandler() StampedPackHandler {
+	return func(p pack.Pack, addr *net.UDPAddr, received time.Time) {
 		switch pp := p.(type) {
 		case *pack.XLogProfilePack:
 			if pp.Time == 0 {
 				pp.Time = time.Now().UnixMilli()
 			}
 			select {
-			case pc.queue <- pp:
+			case pc.queue <- queued[*pack.XLogProfilePack]{pp, received}:
 			default:
+				pc.dropped.Add(1)
 				slog.Warn("ProfileCore queue overflow")
 			}
 		case *pack.XLogProfilePack2:
@@ -50,23 +66,40 @@ func (pc *ProfileCore) Handler() PackHandler {
 				Profile: pp.Profile,
 			}
 			select {
-			case pc.queue <- converted:
+			case pc.queue <- queued[*pack.XLogProfilePack]{converted, received}:
 			default:
+				pc.dropped.Add(1)
 				slog.Warn("ProfileCore queue overflow")
 			}
 		}
 	}
 }
 
+// Dropped returns the number of profiles dropped due to queue overflow.
+func (pc *ProfileCore) Dropped() int64 {
+	return pc.dropped.Load()
+}
+
+// Pending returns the number of profiles waiting to be processed.
+func (pc *ProfileCore) Pending() int {
+	return len(pc.queue)
+}
+
 func (pc *ProfileCore) run() {
-	for pp := range pc.queue {
+	for q := range pc.queue {
+		pp := q.p
 		if pc.profileWR != nil {
 			pc.profileWR.Add(&profile.ProfileEntry{
-				TimeMs: pp.Time,
-				Txid:   pp.Txid,
-				Data:   pp.Profile,
+				TimeMs:   pp.Time,
+				Txid:     pp.Txid,
+				ObjHash:  pp.ObjHash,
+				Data:     pp.Profile,
+				Received: q.received,
 			})
 		}
+		if pc.profStat != nil {
+			pc.profStat.ProcessProfile(pp)
+		}
 		slog.Debug("ProfileCore processing", "txid", pp.Txid, "profileLen", len(pp.Profile))
 	}
 }
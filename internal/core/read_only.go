@@ -0,0 +1,93 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// ReadOnlyStatus describes the read-only maintenance mode.
+type ReadOnlyStatus struct {
+	Active  bool
+	Since   time.Time
+	By      string
+	Dropped int64 // packs dropped since Since
+}
+
+// ReadOnly pauses ingestion for storage maintenance windows. While active the
+// dispatcher drops every pack except object heartbeats, which only refresh
+// the in-memory object cache and keep agents from being reported dead, so the
+// writers stay idle and purges or offline compaction can run against the
+// data directory. Queries are served as usual.
+type ReadOnly struct {
+	mu     sync.Mutex
+	status ReadOnlyStatus
+}
+
+// NewReadOnly creates a ReadOnly in the writable state.
+func NewReadOnly() *ReadOnly {
+	return &ReadOnly{}
+}
+
+// Enable switches to read-only; by names who asked, for logs and status.
+// It reports false if the server was already read-only.
+func (r *ReadOnly) Enable(by string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status.Active {
+		return false
+	}
+	r.status = ReadOnlyStatus{Active: true, Since: time.Now(), By: by}
+	slog.Warn("Server is read-only, ingestion paused", "by", by)
+	return true
+}
+
+// Disable resumes ingestion. It reports false if the server was not read-only.
+func (r *ReadOnly) Disable() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.status.Active {
+		return false
+	}
+	slog.Warn("Server is writable, ingestion resumed",
+		"readOnlyFor", time.Since(r.status.Since).Round(time.Second), "dropped", r.status.Dropped)
+	r.status.Active = false
+	return true
+}
+
+// Active reports whether the server is read-only.
+func (r *ReadOnly) Active() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status.Active
+}
+
+// Status returns the current state.
+func (r *ReadOnly) Status() ReadOnlyStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// Allow reports whether p may be ingested, counting the packs dropped.
+func (r *ReadOnly) Allow(p pack.Pack) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.status.Active || p.PackType() == pack.PackTypeObject {
+		return true
+	}
+	r.status.Dropped++
+	return false
+}
+
+// Summary returns a one-line description of the state.
+func (r *ReadOnly) Summary() string {
+	st := r.Status()
+	if !st.Active {
+		return "off"
+	}
+	return fmt.Sprintf("since %s by %s, %d packs dropped", st.Since.Format("2006-01-02 15:04:05"), st.By, st.Dropped)
+}
@@ -0,0 +1,43 @@
+package core
+
+import (
+	"net"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+func TestReadOnly_Dispatch(t *testing.T) {
+	var got []byte
+	d := NewDispatcher()
+	for _, pt := range []byte{pack.PackTypeXLog, pack.PackTypeObject, pack.PackTypePerfCounter} {
+		d.Register(pt, func(p pack.Pack, addr *net.UDPAddr) { got = append(got, p.PackType()) })
+	}
+	r := NewReadOnly()
+	d.SetReadOnly(r)
+
+	if !r.Enable("test") || r.Enable("test") {
+		t.Fatal("Enable should succeed once")
+	}
+	d.Dispatch(&pack.XLogPack{ObjHash: 1}, nil)
+	d.Dispatch(&pack.ObjectPack{ObjHash: 1}, nil)
+	d.Dispatch(&pack.PerfCounterPack{ObjName: "/a"}, nil)
+	if len(got) != 1 || got[0] != pack.PackTypeObject {
+		t.Errorf("dispatched while read-only = %v, want the object pack only", got)
+	}
+	if st := r.Status(); !st.Active || st.By != "test" || st.Dropped != 2 {
+		t.Errorf("status = %+v", st)
+	}
+
+	if !r.Disable() || r.Disable() {
+		t.Fatal("Disable should succeed once")
+	}
+	got = nil
+	d.Dispatch(&pack.XLogPack{ObjHash: 1}, nil)
+	if len(got) != 1 {
+		t.Errorf("dispatched after Disable = %v", got)
+	}
+	if r.Summary() != "off" {
+		t.Errorf("summary = %q", r.Summary())
+	}
+}
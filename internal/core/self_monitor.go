@@ -0,0 +1,197 @@
+package core
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/io"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+const (
+	selfMonitorInterval = 10 * time.Second
+
+	// SelfObjType is the object type the server registers itself under.
+	SelfObjType = "scouter-server"
+)
+
+// Queue is a queue of the ingestion pipeline.
+type Queue interface {
+	Pending() int   // packs waiting now
+	Dropped() int64 // packs dropped on overflow in total
+}
+
+// UDPQueue is the receive queue of the UDP server.
+type UDPQueue interface {
+	Queue
+	Received() int64 // datagrams received in total
+}
+
+// SelfMonitor registers the server itself as an object of type
+// scouter-server and stores its health as counters every interval: UDP
+// datagrams received per second and dropped, the packs waiting in and
+// dropped from the dispatch and write queues, the index flushes and their
+// average latency, the goroutine count and the heap in use. The packs go
+// through the dispatcher like agent packs, into the counter cache and the
+// counter files.
+type SelfMonitor struct {
+	version string
+	ingest  func(pack.Pack)
+
+	mu       sync.Mutex
+	udp      UDPQueue
+	dispatch []Queue // core queues between the dispatcher and the writers
+	write    []Queue // writer queues
+	last     selfTotals
+	lastTime time.Time
+}
+
+// selfTotals are the running totals the per-interval counters derive from.
+type selfTotals struct {
+	received     int64
+	udpDropped   int64
+	queueDropped int64
+	flushes      int64
+	flushLatency time.Duration
+}
+
+// NewSelfMonitor creates a SelfMonitor for the server of the given version,
+// storing its counters through ingestFn.
+func NewSelfMonitor(version string, ingestFn func(pack.Pack)) *SelfMonitor {
+	return &SelfMonitor{version: version, ingest: ingestFn}
+}
+
+// SetUDP sets the UDP receive queue.
+func (m *SelfMonitor) SetUDP(q UDPQueue) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.udp = q
+}
+
+// AddDispatchQueue adds a queue between the dispatcher and the writers.
+func (m *SelfMonitor) AddDispatchQueue(q Queue) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dispatch = append(m.dispatch, q)
+}
+
+// AddWriteQueue adds the queue of a writer.
+func (m *SelfMonitor) AddWriteQueue(q Queue) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.write = append(m.write, q)
+}
+
+// Start reports every interval until ctx is cancelled.
+func (m *SelfMonitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(selfMonitorInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if cfg := config.Get(); cfg != nil {
+					m.report(cfg, now, io.GetFlushController().Stats())
+				}
+			}
+		}
+	}()
+}
+
+// report stores the counters of the interval ending at now.
+func (m *SelfMonitor) report(cfg *config.Config, now time.Time, flushes []io.FlushStat) {
+	m.mu.Lock()
+	cur := selfTotals{}
+	data := value.NewMapValue()
+	if m.udp != nil {
+		cur.received = m.udp.Received()
+		cur.udpDropped = m.udp.Dropped()
+		data.Put("UdpQueueDepth", value.NewDecimalValue(int64(m.udp.Pending())))
+	}
+	data.Put("DispatchQueueDepth", value.NewDecimalValue(queueTotals(m.dispatch, &cur.queueDropped)))
+	data.Put("WriteQueueDepth", value.NewDecimalValue(queueTotals(m.write, &cur.queueDropped)))
+	for _, st := range flushes {
+		cur.flushes += st.Flushes
+		cur.flushLatency += st.TotalLatency
+	}
+	prev, prevTime := m.last, m.lastTime
+	m.last, m.lastTime = cur, now
+	m.mu.Unlock()
+
+	if !cfg.SelfMonitorEnabled() || m.ingest == nil || prevTime.IsZero() {
+		return // the first interval only sets the totals
+	}
+	if secs := now.Sub(prevTime).Seconds(); secs > 0 {
+		data.Put("UdpPacketRate", &value.DoubleValue{Value: float64(max(cur.received-prev.received, 0)) / secs})
+	}
+	data.Put("UdpDropCount", value.NewDecimalValue(max(cur.udpDropped-prev.udpDropped, 0)))
+	data.Put("QueueDropCount", value.NewDecimalValue(max(cur.queueDropped-prev.queueDropped, 0)))
+	// Files closed meanwhile take their flushes with them; skip the latency then.
+	if n := cur.flushes - prev.flushes; n > 0 && cur.flushLatency >= prev.flushLatency {
+		data.Put("FlushCount", value.NewDecimalValue(n))
+		data.Put("FlushLatency", &value.DoubleValue{Value: durationMs((cur.flushLatency - prev.flushLatency) / time.Duration(n))})
+	} else {
+		data.Put("FlushCount", value.NewDecimalValue(0))
+	}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	data.Put("Goroutine", value.NewDecimalValue(int64(runtime.NumGoroutine())))
+	data.Put("HeapUsed", &value.DoubleValue{Value: float64(ms.HeapAlloc) / (1024 * 1024)})
+
+	for _, p := range selfMonitorPacks(selfObjName(cfg), m.version, now, data) {
+		m.ingest(p)
+	}
+}
+
+// queueTotals returns the packs waiting in qs and adds their drops to dropped.
+func queueTotals(qs []Queue, dropped *int64) int64 {
+	var pending int64
+	for _, q := range qs {
+		pending += int64(q.Pending())
+		*dropped += q.Dropped()
+	}
+	return pending
+}
+
+// selfObjName returns self_monitor_obj_name, or /{hostname}/scouter-server.
+func selfObjName(cfg *config.Config) string {
+	if name := cfg.SelfMonitorObjName(); name != "" {
+		return name
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+	return "/" + host + "/" + SelfObjType
+}
+
+// selfMonitorPacks builds the object and counter packs for one interval.
+func selfMonitorPacks(objName, version string, now time.Time, data *value.MapValue) []pack.Pack {
+	tags := value.NewMapValue()
+	tags.Put(pack.TagDeadTime, value.NewDecimalValue(3*selfMonitorInterval.Milliseconds()))
+	return []pack.Pack{
+		&pack.ObjectPack{
+			ObjType: SelfObjType,
+			ObjHash: util.HashString(objName),
+			ObjName: objName,
+			Version: version,
+			Alive:   true,
+			Tags:    tags,
+		},
+		&pack.PerfCounterPack{
+			Time:     now.UnixMilli(),
+			ObjName:  objName,
+			TimeType: cache.TimeTypeRealtime,
+			Data:     data,
+		},
+	}
+}
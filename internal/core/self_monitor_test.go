@@ -0,0 +1,83 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/io"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+type fakeQueue struct {
+	received, dropped int64
+	pending           int
+}
+
+func (q *fakeQueue) Received() int64 { return q.received }
+func (q *fakeQueue) Dropped() int64  { return q.dropped }
+func (q *fakeQueue) Pending() int    { return q.pending }
+
+func TestSelfMonitor_Report(t *testing.T) {
+	dir := t.TempDir()
+	cfg := mirrorConfig(t, dir, "self_monitor_obj_name=/scouter/server\n")
+
+	var packs []pack.Pack
+	m := NewSelfMonitor("1.2.3", func(p pack.Pack) { packs = append(packs, p) })
+	udp := &fakeQueue{received: 100, dropped: 1}
+	core, writer := &fakeQueue{pending: 3}, &fakeQueue{pending: 5, dropped: 2}
+	m.SetUDP(udp)
+	m.AddDispatchQueue(core)
+	m.AddWriteQueue(writer)
+
+	start := time.Now()
+	m.report(cfg, start, []io.FlushStat{{Flushes: 10, TotalLatency: 10 * time.Millisecond}})
+	if len(packs) != 0 {
+		t.Fatalf("first interval stored %d packs, want none", len(packs))
+	}
+
+	udp.received, udp.dropped, udp.pending = 300, 4, 7
+	writer.dropped = 3
+	m.report(cfg, start.Add(10*time.Second), []io.FlushStat{{Flushes: 14, TotalLatency: 30 * time.Millisecond}})
+	if len(packs) != 2 {
+		t.Fatalf("packs = %d, want object and counter", len(packs))
+	}
+	op, ok := packs[0].(*pack.ObjectPack)
+	if !ok || op.ObjName != "/scouter/server" || op.ObjType != SelfObjType || op.Version != "1.2.3" {
+		t.Fatalf("object pack = %+v", packs[0])
+	}
+	pc := packs[1].(*pack.PerfCounterPack)
+	if pc.ObjName != "/scouter/server" {
+		t.Errorf("counter objName = %q", pc.ObjName)
+	}
+	for name, want := range map[string]int64{
+		"UdpDropCount":       3,
+		"QueueDropCount":     1,
+		"UdpQueueDepth":      7,
+		"DispatchQueueDepth": 3,
+		"WriteQueueDepth":    5,
+		"FlushCount":         4,
+	} {
+		if v, _ := pc.Data.Get(name); v == nil || v.(*value.DecimalValue).Value != want {
+			t.Errorf("%s = %v, want %d", name, v, want)
+		}
+	}
+	for name, want := range map[string]float64{"UdpPacketRate": 20, "FlushLatency": 5} {
+		if v, _ := pc.Data.Get(name); v == nil || v.(*value.DoubleValue).Value != want {
+			t.Errorf("%s = %v, want %v", name, v, want)
+		}
+	}
+	if v, _ := pc.Data.Get("Goroutine"); v == nil || v.(*value.DecimalValue).Value <= 0 {
+		t.Errorf("Goroutine = %v", v)
+	}
+	if v, _ := pc.Data.Get("HeapUsed"); v == nil || v.(*value.DoubleValue).Value <= 0 {
+		t.Errorf("HeapUsed = %v", v)
+	}
+
+	packs = nil
+	cfg = mirrorConfig(t, dir, "self_monitor_enabled=false\n")
+	m.report(cfg, start.Add(20*time.Second), nil)
+	if len(packs) != 0 {
+		t.Errorf("stored %d packs with self_monitor_enabled=false", len(packs))
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/step"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
 )
 
 // SpanCore processes incoming SpanPack and SpanContainerPack data
@@ -25,7 +26,10 @@ type SpanCore struct {
 	xlogWR      *xlog.XLogWR
 	profileWR   *profile.ProfileWR
 	textCache   *cache.TextCache
+	summary     *spanSummary
 	queue       chan *pack.SpanPack
+	mapper      spanMapper
+	ingest      func(p pack.Pack) // registers mapped objects; nil puts them in objectCache
 }
 
 func NewSpanCore(xlogCache *cache.XLogCache, xlogWR *xlog.XLogWR, objectCache *cache.ObjectCache, profileWR *profile.ProfileWR, textCache *cache.TextCache) *SpanCore {
@@ -41,6 +45,21 @@ func NewSpanCore(xlogCache *cache.XLogCache, xlogWR *xlog.XLogWR, objectCache *c
 	return sc
 }
 
+// SetSummary makes the SpanCore synthesize service, SQL and API call
+// SummaryPacks from spans and hand them to summaryCore every 5 minutes. It
+// must be called before spans arrive.
+func (sc *SpanCore) SetSummary(summaryCore *SummaryCore, textCore *TextCore) {
+	sc.summary = newSpanSummary(sc.objectCache, sc.textCache, textCore, summaryCore.Handler())
+	go sc.summary.run()
+}
+
+// SetObjectIngest makes the objects created by zipkin_service_mapping rules
+// be registered through ingest, like agent heartbeats. It must be called
+// before spans arrive.
+func (sc *SpanCore) SetObjectIngest(ingest func(p pack.Pack)) {
+	sc.ingest = ingest
+}
+
 // Handler returns a PackHandler for PackTypeSpan.
 func (sc *SpanCore) Handler() PackHandler {
 	return func(p pack.Pack, addr *net.UDPAddr) {
@@ -101,6 +120,7 @@ func (sc *SpanCore) ContainerHandler() PackHandler {
 
 func (sc *SpanCore) run() {
 	for sp := range sc.queue {
+		sc.mapObject(sp)
 		xp := spanToXLog(sp)
 
 		// Serialize XLogPack for caching and storage
@@ -117,6 +137,10 @@ func (sc *SpanCore) run() {
 			sc.objectCache.Touch(xp.ObjHash)
 		}
 
+		if sc.summary != nil {
+			sc.summary.add(sp, xp)
+		}
+
 		slog.Debug("SpanCore processing",
 			"txid", xp.Txid,
 			"gxid", xp.Gxid,
@@ -128,7 +152,10 @@ func (sc *SpanCore) run() {
 				Time:    xp.EndTime,
 				Txid:    xp.Txid,
 				Gxid:    xp.Gxid,
+				Userid:  xp.Userid,
 				Elapsed: xp.Elapsed,
+				ObjHash: xp.ObjHash,
+				Service: xp.Service,
 				Data:    b,
 			})
 		}
@@ -140,9 +167,10 @@ func (sc *SpanCore) run() {
 			profileData := sc.buildSpanProfile(sp)
 			if len(profileData) > 0 {
 				sc.profileWR.Add(&profile.ProfileEntry{
-					TimeMs: xp.EndTime,
-					Txid:   xp.Txid,
-					Data:   profileData,
+					TimeMs:  xp.EndTime,
+					Txid:    xp.Txid,
+					ObjHash: xp.ObjHash,
+					Data:    profileData,
 				})
 				xp.ProfileCount = 1
 			}
@@ -150,6 +178,42 @@ func (sc *SpanCore) run() {
 	}
 }
 
+// mapObject moves sp to the object of the first zipkin_service_mapping rule
+// matching it, registering that object unless it is alive already.
+func (sc *SpanCore) mapObject(sp *pack.SpanPack) {
+	rules := sc.mapper.current()
+	if len(rules) == 0 {
+		return
+	}
+	service := ""
+	if sp.LocalEndpointServiceName != 0 && sc.textCache != nil {
+		service, _ = sc.textCache.Get("object", sp.LocalEndpointServiceName)
+	}
+	objType, objName, ok := matchSpanRules(rules, service, sp.Tags)
+	if !ok {
+		return
+	}
+	sp.ObjHash = util.HashString(objName)
+	if sc.objectCache == nil {
+		return
+	}
+	if info, ok := sc.objectCache.Get(sp.ObjHash); ok && info.Pack.Alive {
+		return
+	}
+	op := &pack.ObjectPack{
+		ObjType: objType,
+		ObjHash: sp.ObjHash,
+		ObjName: objName,
+		Alive:   true,
+		Tags:    value.NewMapValue(),
+	}
+	if sc.ingest != nil {
+		sc.ingest(op)
+	} else {
+		sc.objectCache.Put(op.ObjHash, op)
+	}
+}
+
 // minReasonableTimeMs is 2000-01-01 00:00:00 UTC in milliseconds.
 // Timestamps before this are considered invalid.
 var minReasonableTimeMs = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// SpanRule groups the spans whose local service name, or tag Tag if set,
+// matches Pattern under one object. ObjName may refer to the groups of
+// Pattern as $1 or ${name}.
+type SpanRule struct {
+	Tag     string
+	Pattern *regexp.Regexp
+	ObjType string
+	ObjName string
+}
+
+// ParseSpanRules parses rules separated by ";", each of the form
+// "[tag~]regex => objType:objName", e.g.
+// "^(order|payment)-.* => mesh:/mesh/$1; k8s.namespace~^batch$ => batch:/batch/jobs".
+// An empty spec has no rules.
+func ParseSpanRules(spec string) ([]SpanRule, error) {
+	var rules []SpanRule
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		match, target, ok := strings.Cut(part, "=>")
+		objType, objName, ok2 := strings.Cut(strings.TrimSpace(target), ":")
+		objType, objName = strings.TrimSpace(objType), strings.TrimSpace(objName)
+		if !ok || !ok2 || objType == "" || !strings.HasPrefix(objName, "/") {
+			return nil, fmt.Errorf("bad span rule %q", part)
+		}
+		r := SpanRule{ObjType: objType, ObjName: objName}
+		expr := strings.TrimSpace(match)
+		if tag, e, ok := strings.Cut(expr, "~"); ok {
+			r.Tag, expr = strings.TrimSpace(tag), strings.TrimSpace(e)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern %q: %w", expr, err)
+		}
+		r.Pattern = re
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// matchSpanRules returns the object of the first rule matching a span of
+// service with tags.
+func matchSpanRules(rules []SpanRule, service string, tags *value.MapValue) (objType, objName string, ok bool) {
+	for _, r := range rules {
+		subject := service
+		if r.Tag != "" {
+			subject = spanTag(tags, r.Tag)
+		}
+		if subject == "" {
+			continue
+		}
+		m := r.Pattern.FindStringSubmatchIndex(subject)
+		if m == nil {
+			continue
+		}
+		name := string(r.Pattern.ExpandString(nil, r.ObjName, subject, m))
+		return r.ObjType, name, true
+	}
+	return "", "", false
+}
+
+func spanTag(tags *value.MapValue, key string) string {
+	if tags == nil {
+		return ""
+	}
+	v, ok := tags.Get(key)
+	if !ok {
+		return ""
+	}
+	if tv, ok := v.(*value.TextValue); ok {
+		return tv.Value
+	}
+	return ""
+}
+
+// spanMapper holds the rules of the zipkin_service_mapping setting.
+type spanMapper struct {
+	mu    sync.Mutex
+	spec  string
+	rules []SpanRule
+}
+
+// current returns the rules, re-parsing them only when the setting changed.
+func (m *spanMapper) current() []SpanRule {
+	spec := ""
+	if cfg := config.Get(); cfg != nil {
+		spec = cfg.ZipkinServiceMapping()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if spec == m.spec {
+		return m.rules
+	}
+	rules, err := ParseSpanRules(spec)
+	if err != nil {
+		slog.Warn("SpanCore: ignoring zipkin_service_mapping", "error", err)
+	}
+	m.spec, m.rules = spec, rules
+	return rules
+}
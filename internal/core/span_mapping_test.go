@@ -0,0 +1,89 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+func TestParseSpanRules(t *testing.T) {
+	rules, err := ParseSpanRules(" ^(order|payment)-.* => mesh:/mesh/$1 ; k8s.namespace~^batch$=>batch:/batch/jobs;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].Tag != "" || rules[1].Tag != "k8s.namespace" || rules[1].ObjType != "batch" {
+		t.Fatalf("rules = %+v", rules)
+	}
+	for _, spec := range []string{"order-.* mesh:/mesh", "order => /mesh", "order => mesh:mesh", "order-( => mesh:/mesh"} {
+		if _, err := ParseSpanRules(spec); err == nil {
+			t.Errorf("ParseSpanRules(%q) accepted", spec)
+		}
+	}
+}
+
+func TestSpanCore_MapObject(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("zipkin_service_mapping=^(order|payment)-.* => mesh:/mesh/$1; k8s.namespace~^batch$ => batch:/batch/jobs\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	textCache := cache.NewTextCache()
+	objectCache := cache.NewObjectCache()
+	var registered []*pack.ObjectPack
+	sc := &SpanCore{textCache: textCache, objectCache: objectCache}
+	sc.SetObjectIngest(func(p pack.Pack) {
+		op := p.(*pack.ObjectPack)
+		registered = append(registered, op)
+		objectCache.Put(op.ObjHash, op)
+	})
+
+	span := func(service string, tags map[string]string) *pack.SpanPack {
+		textCache.Put("object", util.HashString(service), service)
+		sp := &pack.SpanPack{ObjHash: 7, LocalEndpointServiceName: util.HashString(service), Tags: value.NewMapValue()}
+		for k, v := range tags {
+			sp.Tags.Put(k, value.NewTextValue(v))
+		}
+		return sp
+	}
+
+	for _, tc := range []struct {
+		sp   *pack.SpanPack
+		want string
+	}{
+		{span("order-api-7f9c", nil), "/mesh/order"},
+		{span("order-worker", nil), "/mesh/order"},
+		{span("payment-api", nil), "/mesh/payment"},
+		{span("report-cron", map[string]string{"k8s.namespace": "batch"}), "/batch/jobs"},
+		{span("inventory", map[string]string{"k8s.namespace": "shop"}), ""},
+	} {
+		sc.mapObject(tc.sp)
+		want := int32(7)
+		if tc.want != "" {
+			want = util.HashString(tc.want)
+		}
+		if tc.sp.ObjHash != want {
+			t.Errorf("%d: objHash = %d, want %s", tc.sp.LocalEndpointServiceName, tc.sp.ObjHash, tc.want)
+		}
+	}
+
+	// Each mapped object is registered once while alive.
+	if len(registered) != 3 {
+		t.Fatalf("registered %d objects, want 3", len(registered))
+	}
+	if op := registered[0]; op.ObjType != "mesh" || op.ObjName != "/mesh/order" || !op.Alive {
+		t.Errorf("registered %+v", op)
+	}
+	info, _ := objectCache.Get(util.HashString("/mesh/order"))
+	info.Pack.Alive = false
+	sc.mapObject(span("order-api-7f9c", nil))
+	if len(registered) != 4 {
+		t.Errorf("dead object not registered again")
+	}
+}
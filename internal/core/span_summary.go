@@ -0,0 +1,206 @@
+package core
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// spanSummaryInterval is how often span summaries are written, matching the
+// 5-minute summary period of Java agents.
+const spanSummaryInterval = 5 * time.Minute
+
+// Summary types of the synthesized packs (Java SummaryEnum).
+const (
+	summaryTypeApp     byte = 1
+	summaryTypeSQL     byte = 2
+	summaryTypeAPICall byte = 3
+)
+
+// spanSQLTags are the span tags holding a SQL statement (Brave, OpenTelemetry).
+var spanSQLTags = []string{"sql.query", "db.statement"}
+
+type spanSummaryKey struct {
+	objHash int32
+	stype   byte
+}
+
+type spanSummaryStat struct {
+	count   int64
+	errors  int64
+	elapsed int64
+}
+
+// spanSummary aggregates spans into the service, SQL and API call summaries
+// that agents send as SummaryPacks, so summary views also cover services
+// reporting only spans. Server and consumer spans count as services, client
+// and producer spans as SQL when they carry a statement tag and as API calls
+// otherwise.
+type spanSummary struct {
+	mu          sync.Mutex
+	stats       map[spanSummaryKey]map[int32]*spanSummaryStat
+	objectCache *cache.ObjectCache
+	textCache   *cache.TextCache
+	textCore    *TextCore
+	sink        PackHandler
+}
+
+func newSpanSummary(objectCache *cache.ObjectCache, textCache *cache.TextCache, textCore *TextCore, sink PackHandler) *spanSummary {
+	return &spanSummary{
+		stats:       make(map[spanSummaryKey]map[int32]*spanSummaryStat),
+		objectCache: objectCache,
+		textCache:   textCache,
+		textCore:    textCore,
+		sink:        sink,
+	}
+}
+
+// add counts the span sp converted to xp.
+func (s *spanSummary) add(sp *pack.SpanPack, xp *pack.XLogPack) {
+	if cfg := config.Get(); cfg != nil && !cfg.ZipkinSummaryEnabled() {
+		return
+	}
+	stype, id := summaryTypeApp, sp.Name
+	switch sp.SpanType {
+	case 1, 3: // CLIENT, PRODUCER
+		if stmt := spanSQL(sp); stmt != "" {
+			stype, id = summaryTypeSQL, util.HashString(stmt)
+			s.ensureText("sql", id, stmt)
+		} else {
+			stype = summaryTypeAPICall
+			if name, ok := s.text("service", sp.Name); ok {
+				s.ensureText("apicall", id, name)
+			}
+		}
+	}
+	if id == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := spanSummaryKey{objHash: xp.ObjHash, stype: stype}
+	table := s.stats[key]
+	if table == nil {
+		table = make(map[int32]*spanSummaryStat)
+		s.stats[key] = table
+	}
+	st := table[id]
+	if st == nil {
+		st = &spanSummaryStat{}
+		table[id] = st
+	}
+	st.count++
+	st.elapsed += int64(xp.Elapsed)
+	if xp.Error != 0 {
+		st.errors++
+	}
+}
+
+func (s *spanSummary) text(div string, hash int32) (string, bool) {
+	if s.textCache == nil {
+		return "", false
+	}
+	return s.textCache.Get(div, hash)
+}
+
+// ensureText stores text under div so clients can resolve the summary ids.
+func (s *spanSummary) ensureText(div string, hash int32, text string) {
+	if s.textCache == nil {
+		return
+	}
+	if _, ok := s.textCache.Get(div, hash); ok {
+		return
+	}
+	s.textCache.Put(div, hash, text)
+	if s.textCore != nil {
+		s.textCore.AddText(div, hash, text)
+	}
+}
+
+// spanSQL returns the SQL statement tag of sp, or "".
+func spanSQL(sp *pack.SpanPack) string {
+	if sp.Tags == nil {
+		return ""
+	}
+	for _, key := range spanSQLTags {
+		if v, ok := sp.Tags.Get(key); ok {
+			if tv, ok := v.(*value.TextValue); ok && tv.Value != "" {
+				return tv.Value
+			}
+		}
+	}
+	return ""
+}
+
+// flush sends one SummaryPack per object and summary type with the counts
+// since the previous flush, stamped with now.
+func (s *spanSummary) flush(now time.Time) {
+	s.mu.Lock()
+	stats := s.stats
+	s.stats = make(map[spanSummaryKey]map[int32]*spanSummaryStat)
+	s.mu.Unlock()
+
+	for key, table := range stats {
+		ids, counts := value.NewListValue(), value.NewListValue()
+		errs, elapsed := value.NewListValue(), value.NewListValue()
+		for id, st := range table {
+			ids.Value = append(ids.Value, value.NewDecimalValue(int64(id)))
+			counts.Value = append(counts.Value, value.NewDecimalValue(st.count))
+			errs.Value = append(errs.Value, value.NewDecimalValue(st.errors))
+			elapsed.Value = append(elapsed.Value, value.NewDecimalValue(st.elapsed))
+		}
+		t := value.NewMapValue()
+		t.Put("id", ids)
+		t.Put("count", counts)
+		t.Put("error", errs)
+		t.Put("elapsed", elapsed)
+		if key.stype == summaryTypeApp {
+			// Spans carry no resource usage; the client expects the columns.
+			cpu, mem := value.NewListValue(), value.NewListValue()
+			for range table {
+				cpu.Value = append(cpu.Value, value.NewDecimalValue(0))
+				mem.Value = append(mem.Value, value.NewDecimalValue(0))
+			}
+			t.Put("cpu", cpu)
+			t.Put("mem", mem)
+		}
+
+		objType := ""
+		if s.objectCache != nil {
+			if info, ok := s.objectCache.Get(key.objHash); ok {
+				objType = info.Pack.ObjType
+			}
+		}
+		s.sink(&pack.SummaryPack{
+			Time:    now.UnixMilli(),
+			ObjHash: key.objHash,
+			ObjType: objType,
+			SType:   key.stype,
+			Table:   t,
+		}, nil)
+	}
+	if len(stats) > 0 {
+		slog.Debug("Span summaries written", "packs", len(stats))
+	}
+}
+
+// run flushes at each spanSummaryInterval boundary.
+func (s *spanSummary) run() {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	next := time.Now().Truncate(spanSummaryInterval).Add(spanSummaryInterval)
+	for now := range ticker.C {
+		if now.Before(next) {
+			continue
+		}
+		s.flush(now)
+		next = now.Truncate(spanSummaryInterval).Add(spanSummaryInterval)
+	}
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+func TestSpanSummary(t *testing.T) {
+	objectCache := cache.NewObjectCache()
+	objectCache.Put(10, &pack.ObjectPack{ObjHash: 10, ObjName: "/zipkin/orders", ObjType: "zipkin"})
+	textCache := cache.NewTextCache()
+	textCache.Put("service", 100, "GET /orders")
+	textCache.Put("service", 200, "POST /payments")
+
+	var packs []*pack.SummaryPack
+	s := newSpanSummary(objectCache, textCache, nil, func(p pack.Pack, _ *net.UDPAddr) {
+		packs = append(packs, p.(*pack.SummaryPack))
+	})
+
+	add := func(sp *pack.SpanPack) {
+		s.add(sp, spanToXLog(sp))
+	}
+	add(&pack.SpanPack{ObjHash: 10, Name: 100, SpanType: 2, Elapsed: 100})
+	add(&pack.SpanPack{ObjHash: 10, Name: 100, SpanType: 2, Elapsed: 300, Error: 1})
+	add(&pack.SpanPack{ObjHash: 10, Name: 200, SpanType: 1, Elapsed: 50})
+	tags := value.NewMapValue()
+	tags.Put("sql.query", value.NewTextValue("select * from orders"))
+	add(&pack.SpanPack{ObjHash: 10, Name: 300, SpanType: 1, Elapsed: 7, Tags: tags})
+
+	now := time.Now()
+	s.flush(now)
+	if len(packs) != 3 {
+		t.Fatalf("expected 3 summary packs, got %d", len(packs))
+	}
+	byType := make(map[byte]*pack.SummaryPack)
+	for _, sp := range packs {
+		if sp.ObjHash != 10 || sp.ObjType != "zipkin" || sp.Time != now.UnixMilli() {
+			t.Fatalf("unexpected pack header %+v", sp)
+		}
+		byType[sp.SType] = sp
+	}
+
+	column := func(sp *pack.SummaryPack, key string) *value.ListValue {
+		t.Helper()
+		v, ok := sp.Table.Get(key)
+		if !ok {
+			t.Fatalf("stype %d: missing column %q", sp.SType, key)
+		}
+		return v.(*value.ListValue)
+	}
+	app := byType[summaryTypeApp]
+	if app == nil {
+		t.Fatal("no service summary")
+	}
+	if id := column(app, "id").GetLong(0); id != 100 {
+		t.Fatalf("service id = %d", id)
+	}
+	if c, e, el := column(app, "count").GetLong(0), column(app, "error").GetLong(0), column(app, "elapsed").GetLong(0); c != 2 || e != 1 || el != 400 {
+		t.Fatalf("service count/error/elapsed = %d/%d/%d", c, e, el)
+	}
+	column(app, "cpu")
+
+	api := byType[summaryTypeAPICall]
+	if api == nil || column(api, "id").GetLong(0) != 200 {
+		t.Fatal("API call summary missing span 200")
+	}
+	if name, ok := textCache.Get("apicall", 200); !ok || name != "POST /payments" {
+		t.Fatalf("apicall text = %q, %v", name, ok)
+	}
+
+	sql := byType[summaryTypeSQL]
+	sqlHash := util.HashString("select * from orders")
+	if sql == nil || int32(column(sql, "id").GetLong(0)) != sqlHash {
+		t.Fatal("SQL summary missing the statement")
+	}
+	if _, ok := textCache.Get("sql", sqlHash); !ok {
+		t.Fatal("SQL text not stored")
+	}
+
+	packs = nil
+	s.flush(now)
+	if len(packs) != 0 {
+		t.Fatalf("expected no packs after an empty interval, got %d", len(packs))
+	}
+}
@@ -8,12 +8,14 @@ import (
 	"github.com/zbum/scouter-server-go/internal/db/summary"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/sqltop"
 )
 
 // SummaryCore processes incoming SummaryPack data.
 type SummaryCore struct {
 	queue     chan *pack.SummaryPack
 	summaryWR *summary.SummaryWR
+	sqlTop    *sqltop.Core
 }
 
 func NewSummaryCore(summaryWR *summary.SummaryWR) *SummaryCore {
@@ -25,6 +27,11 @@ func NewSummaryCore(summaryWR *summary.SummaryWR) *SummaryCore {
 	return sc
 }
 
+// SetSQLTop feeds the SQL summaries to the hourly slow SQL ranking.
+func (sc *SummaryCore) SetSQLTop(c *sqltop.Core) {
+	sc.sqlTop = c
+}
+
 func (sc *SummaryCore) Handler() PackHandler {
 	return func(p pack.Pack, addr *net.UDPAddr) {
 		sp, ok := p.(*pack.SummaryPack)
@@ -59,5 +66,8 @@ func (sc *SummaryCore) run() {
 				Data:   o.ToByteArray(),
 			})
 		}
+		if sc.sqlTop != nil {
+			sc.sqlTop.ProcessSummary(sp)
+		}
 	}
 }
@@ -15,6 +15,7 @@ import (
 type TextCore struct {
 	textCache *cache.TextCache
 	textWR    *text.TextWR
+	policy    *TextPolicy
 	queue     chan *pack.TextPack
 }
 
@@ -22,6 +23,7 @@ func NewTextCore(textCache *cache.TextCache, textWR *text.TextWR) *TextCore {
 	tc := &TextCore{
 		textCache: textCache,
 		textWR:    textWR,
+		policy:    NewTextPolicy(),
 		queue:     make(chan *pack.TextPack, 2048),
 	}
 	go tc.run()
@@ -34,6 +36,7 @@ func (tc *TextCore) Handler() PackHandler {
 		if !ok {
 			return
 		}
+		tp.Text = tc.policy.Apply(config.Get(), tp.XType, tp.Text)
 		tc.textCache.Put(tp.XType, tp.Hash, tp.Text)
 		select {
 		case tc.queue <- tp:
@@ -46,6 +49,7 @@ func (tc *TextCore) Handler() PackHandler {
 // AddText stores a text entry programmatically (not from a network pack).
 // Used by AgentManager to store object names, etc.
 func (tc *TextCore) AddText(xtype string, hash int32, text string) {
+	text = tc.policy.Apply(config.Get(), xtype, text)
 	tp := &pack.TextPack{XType: xtype, Hash: hash, Text: text}
 	select {
 	case tc.queue <- tp:
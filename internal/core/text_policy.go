@@ -0,0 +1,121 @@
+package core
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/zbum/scouter-server-go/internal/anonymize"
+	"github.com/zbum/scouter-server-go/internal/config"
+)
+
+// textTruncatedSuffix marks a text cut by the length limits.
+const textTruncatedSuffix = "..."
+
+// TextPolicy shortens and normalizes texts before TextCore caches and stores
+// them, so huge SQL statements and URLs carrying per-request query strings do
+// not bloat the text DB. Texts keep the hash the agent assigned, so
+// normalization changes what is shown for a hash, not which hashes exist.
+//
+// Settings are hot-reloadable:
+//   - mgr_text_sql_collapse_literals replaces SQL string and numeric literals
+//     with '?';
+//   - mgr_text_url_strip_query drops the query string and fragment of
+//     service, apicall and referer texts;
+//   - mgr_text_max_length and mgr_text_max_length_by_type ("type:bytes"
+//     pairs separated by commas) cut longer texts, ending them with "...".
+type TextPolicy struct {
+	mu     sync.Mutex
+	spec   string
+	limits map[string]int
+}
+
+// NewTextPolicy creates a TextPolicy.
+func NewTextPolicy() *TextPolicy {
+	return &TextPolicy{}
+}
+
+// Apply returns text as it should be stored for the text type div.
+func (p *TextPolicy) Apply(cfg *config.Config, div, text string) string {
+	if cfg == nil || text == "" {
+		return text
+	}
+	switch div {
+	case "sql":
+		if cfg.MgrTextSqlCollapseLiterals() {
+			text = anonymize.ScrubSQL(text)
+		}
+	case "service", "apicall", "referer":
+		if cfg.MgrTextUrlStripQuery() {
+			text = stripURLQuery(text)
+		}
+	}
+	if limit := p.maxLength(cfg, div); limit > 0 {
+		text = truncateText(text, limit)
+	}
+	return text
+}
+
+// maxLength returns the length limit of div, 0 if unlimited.
+func (p *TextPolicy) maxLength(cfg *config.Config, div string) int {
+	p.mu.Lock()
+	spec := cfg.MgrTextMaxLengthByType()
+	if spec != p.spec || p.limits == nil {
+		p.spec = spec
+		p.limits = parseTextLimits(spec)
+	}
+	limit, ok := p.limits[div]
+	p.mu.Unlock()
+	if ok {
+		return limit
+	}
+	return cfg.MgrTextMaxLength()
+}
+
+// parseTextLimits returns the "type:bytes" entries of spec. A limit of 0
+// exempts the type from mgr_text_max_length.
+func parseTextLimits(spec string) map[string]int {
+	limits := make(map[string]int)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		div, limitStr, ok := strings.Cut(entry, ":")
+		limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+		div = strings.TrimSpace(div)
+		if !ok || err != nil || limit < 0 || div == "" {
+			slog.Warn("Text max length: bad entry ignored", "entry", entry)
+			continue
+		}
+		limits[div] = limit
+	}
+	return limits
+}
+
+// stripURLQuery drops the query string and fragment of a URL or path.
+func stripURLQuery(u string) string {
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		return u[:i]
+	}
+	return u
+}
+
+// truncateText cuts s to at most limit bytes, ending it with
+// textTruncatedSuffix and never splitting a UTF-8 sequence.
+func truncateText(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	suffix := textTruncatedSuffix
+	if limit <= len(suffix) {
+		suffix = ""
+	}
+	cut := limit - len(suffix)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + suffix
+}
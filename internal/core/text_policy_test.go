@@ -0,0 +1,29 @@
+package core
+
+import "testing"
+
+func TestTextPolicy_Apply(t *testing.T) {
+	cfg := mirrorConfig(t, t.TempDir(),
+		"mgr_text_sql_collapse_literals=true\nmgr_text_url_strip_query=true\n"+
+			"mgr_text_max_length=10\nmgr_text_max_length_by_type=sql:60,object:0,bad\n")
+	p := NewTextPolicy()
+
+	cases := []struct{ div, in, want string }{
+		{"sql", "select * from t1 where id = 42 and name = 'it''s'", "select * from t1 where id = ? and name = ?"},
+		{"sql", "select * from t where id in (1, 2, 3)", "select * from t where id IN (?)"},
+		{"service", "/a?x=1#f", "/a"},
+		{"apicall", "http://b/c?d", "http://b/c"},
+		{"error", "0123456789abc", "0123456..."},
+		{"error", "가나다라", "가나..."},
+		{"object", "/host/a/very/long/object/name", "/host/a/very/long/object/name"},
+		{"method", "?keep", "?keep"},
+	}
+	for _, c := range cases {
+		if got := p.Apply(cfg, c.div, c.in); got != c.want {
+			t.Errorf("Apply(%s, %q) = %q, want %q", c.div, c.in, got, c.want)
+		}
+	}
+	if got := p.Apply(nil, "sql", "select 1"); got != "select 1" {
+		t.Errorf("Apply without config = %q", got)
+	}
+}
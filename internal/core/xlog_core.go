@@ -3,15 +3,18 @@ package core
 import (
 	"log/slog"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/heatmap"
 	"github.com/zbum/scouter-server-go/internal/db/profile"
 	"github.com/zbum/scouter-server-go/internal/db/xlog"
 	"github.com/zbum/scouter-server-go/internal/geoip"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/slo"
 	"github.com/zbum/scouter-server-go/internal/tagcnt"
 )
 
@@ -21,12 +24,18 @@ type XLogCore struct {
 	xlogWR        *xlog.XLogWR
 	profileWR     *profile.ProfileWR
 	xlogGroupPerf *XLogGroupPerf
-	queue         chan *pack.XLogPack
+	queue         chan queued[*pack.XLogPack]
 	geoIP         *geoip.GeoIPUtil
 	sqlTables     *SqlTables
 	visitorCore   *VisitorCore
 	tagCountCore  *tagcnt.TagCountCore
 	objectCache   *cache.ObjectCache
+	heatmap       *heatmap.DB
+	slo           *slo.Tracker
+	sampler       *XLogSampler
+	now           func() time.Time
+	noWorkers     bool
+	dropped       atomic.Int64
 }
 
 // XLogCoreOption configures optional XLogCore dependencies.
@@ -57,6 +66,32 @@ func WithObjectCache(oc *cache.ObjectCache) XLogCoreOption {
 	return func(xc *XLogCore) { xc.objectCache = oc }
 }
 
+// WithHeatmap sets the elapsed-time heatmap index.
+func WithHeatmap(db *heatmap.DB) XLogCoreOption {
+	return func(xc *XLogCore) { xc.heatmap = db }
+}
+
+// WithSLO sets the service-level objective tracker.
+func WithSLO(t *slo.Tracker) XLogCoreOption {
+	return func(xc *XLogCore) { xc.slo = t }
+}
+
+// WithSampler sets the sampler deciding which XLogs are stored.
+func WithSampler(s *XLogSampler) XLogCoreOption {
+	return func(xc *XLogCore) { xc.sampler = s }
+}
+
+// WithClock sets the clock stamping XLogs that arrive without an end time.
+func WithClock(now func() time.Time) XLogCoreOption {
+	return func(xc *XLogCore) { xc.now = now }
+}
+
+// WithoutWorkers starts no worker goroutines; queued XLogs are processed
+// only by Drain. Used by deterministic tests.
+func WithoutWorkers() XLogCoreOption {
+	return func(xc *XLogCore) { xc.noWorkers = true }
+}
+
 func NewXLogCore(xlogCache *cache.XLogCache, xlogWR *xlog.XLogWR, profileWR *profile.ProfileWR, xlogGroupPerf *XLogGroupPerf, opts ...XLogCoreOption) *XLogCore {
 	queueSize := 10000
 	if cfg := config.Get(); cfg != nil {
@@ -67,11 +102,15 @@ func NewXLogCore(xlogCache *cache.XLogCache, xlogWR *xlog.XLogWR, profileWR *pro
 		xlogWR:        xlogWR,
 		profileWR:     profileWR,
 		xlogGroupPerf: xlogGroupPerf,
-		queue:         make(chan *pack.XLogPack, queueSize),
+		queue:         make(chan queued[*pack.XLogPack], queueSize),
+		now:           time.Now,
 	}
 	for _, opt := range opts {
 		opt(xc)
 	}
+	if xc.noWorkers {
+		return xc
+	}
 	// Multiple workers to avoid single-goroutine bottleneck.
 	// Go channel supports concurrent receivers safely.
 	numWorkers := 4
@@ -82,88 +121,150 @@ func NewXLogCore(xlogCache *cache.XLogCache, xlogWR *xlog.XLogWR, profileWR *pro
 }
 
 func (xc *XLogCore) Handler() PackHandler {
-	return func(p pack.Pack, addr *net.UDPAddr) {
+	h := xc.StampedHandler()
+	return func(p pack.Pack, addr *net.UDPAddr) { h(p, addr, time.Time{}) }
+}
+
+// StampedHandler is Handler passing the receive time on to XLogWR.
+func (xc *XLogCore) StampedHandler() StampedPackHandler {
+	return func(p pack.Pack, addr *net.UDPAddr, received time.Time) {
 		xp, ok := p.(*pack.XLogPack)
 		if !ok {
 			return
 		}
 		if xp.EndTime == 0 {
-			xp.EndTime = time.Now().UnixMilli()
+			xp.EndTime = xc.now().UnixMilli()
 		}
 		select {
-		case xc.queue <- xp:
+		case xc.queue <- queued[*pack.XLogPack]{xp, received}:
 		default:
+			xc.dropped.Add(1)
 			slog.Warn("XLogCore queue overflow")
 		}
 	}
 }
 
+// Dropped returns the number of XLogs dropped due to queue overflow.
+func (xc *XLogCore) Dropped() int64 {
+	return xc.dropped.Load()
+}
+
+// Pending returns the number of XLogs waiting to be processed.
+func (xc *XLogCore) Pending() int {
+	return len(xc.queue)
+}
+
+// Drain processes every queued XLog on the calling goroutine and returns
+// how many were processed. See WithoutWorkers.
+func (xc *XLogCore) Drain() int {
+	n := 0
+	for {
+		select {
+		case q := <-xc.queue:
+			xc.process(q)
+			n++
+		default:
+			return n
+		}
+	}
+}
+
 func (xc *XLogCore) run() {
-	for xp := range xc.queue {
-		// Only WEB_SERVICE(0) and APP_SERVICE(1) participate in service group
-		// throughput aggregation, matching Scala's XLogCore.calc() filter.
-		isService := xp.XType == pack.XLogTypeWebService || xp.XType == pack.XLogTypeAppService
-
-		// Only WEB_SERVICE and APP_SERVICE go through calc (matching Java's XLogCore)
-		if isService {
-			// Derive group hash from service URL if not already set
-			if xc.xlogGroupPerf != nil {
-				xc.xlogGroupPerf.Process(xp)
+	for q := range xc.queue {
+		xc.process(q)
+	}
+}
+
+func (xc *XLogCore) process(q queued[*pack.XLogPack]) {
+	xp := q.p
+	// Only WEB_SERVICE(0) and APP_SERVICE(1) participate in service group
+	// throughput aggregation, matching Scala's XLogCore.calc() filter.
+	isService := xp.XType == pack.XLogTypeWebService || xp.XType == pack.XLogTypeAppService
+
+	// Only WEB_SERVICE and APP_SERVICE go through calc (matching Java's XLogCore)
+	if isService {
+		// Derive group hash from service URL if not already set
+		if xc.xlogGroupPerf != nil {
+			xc.xlogGroupPerf.Process(xp)
+		}
+		// GeoIP lookup (only for service types, matching Java)
+		if xc.geoIP != nil && len(xp.IPAddr) > 0 {
+			countryCode, _, cityHash := xc.geoIP.Lookup(xp.IPAddr)
+			if countryCode != "" {
+				xp.CountryCode = countryCode
 			}
-			// GeoIP lookup (only for service types, matching Java)
-			if xc.geoIP != nil && len(xp.IPAddr) > 0 {
-				countryCode, _, cityHash := xc.geoIP.Lookup(xp.IPAddr)
-				if countryCode != "" {
-					xp.CountryCode = countryCode
-				}
-				if cityHash != 0 {
-					xp.City = cityHash
-				}
+			if cityHash != 0 {
+				xp.City = cityHash
 			}
 		}
+	}
+
+	// XLogs sampled out are still aggregated below, but neither streamed
+	// nor stored, so the realtime view shows only what can be read back.
+	keep := xc.sampler == nil || xc.sampler.Keep(xp)
 
-		// Serialize and cache for real-time streaming
+	// Serialize and cache for real-time streaming
+	var b []byte
+	if keep {
 		o := protocol.NewDataOutputX()
 		pack.WritePack(o, xp)
-		b := o.ToByteArray()
+		b = o.ToByteArray()
 		xc.xlogCache.Put(xp.ObjHash, xp.Elapsed, xp.Error != 0, b)
+	}
 
-		// Aggregate by service group for real-time throughput display
-		if isService && xc.xlogGroupPerf != nil {
-			xc.xlogGroupPerf.Add(xp)
-		}
+	// Aggregate by service group for real-time throughput display
+	if isService && xc.xlogGroupPerf != nil {
+		xc.xlogGroupPerf.Add(xp)
+	}
 
-		// Visitor counting
-		if xc.visitorCore != nil && xp.Userid != 0 {
-			xc.visitorCore.Add(xp)
-		}
+	// Visitor counting
+	if xc.visitorCore != nil && xp.Userid != 0 {
+		xc.visitorCore.Add(xp)
+	}
 
-		// Tag counting
-		if xc.tagCountCore != nil {
-			if cfg := config.Get(); cfg != nil && cfg.TagcntEnabled() {
-				objType := ""
-				if xc.objectCache != nil {
-					if info, ok := xc.objectCache.Get(xp.ObjHash); ok {
-						objType = info.Pack.ObjType
-					}
-				}
-				xc.tagCountCore.ProcessXLog(objType, xp)
-			}
+	// Tag counting
+	if xc.tagCountCore != nil {
+		if cfg := config.Get(); cfg != nil && cfg.TagcntEnabled() {
+			xc.tagCountCore.ProcessXLog(xc.objType(xp.ObjHash), xp)
 		}
+	}
+
+	// Elapsed-time heatmap
+	if isService && xc.heatmap != nil {
+		xc.heatmap.Add(xc.objType(xp.ObjHash), xp.EndTime, xp.Elapsed, xp.Error != 0)
+	}
+
+	// Service-level objectives
+	if isService && xc.slo != nil {
+		xc.slo.Add(xc.objType(xp.ObjHash), xp)
+	}
+
+	slog.Debug("XLogCore processing",
+		"objHash", xp.ObjHash,
+		"service", xp.Service,
+		"elapsed", xp.Elapsed,
+		"txid", xp.Txid)
+	if keep && xc.xlogWR != nil {
+		xc.xlogWR.Add(&xlog.XLogEntry{
+			Time:     xp.EndTime,
+			Txid:     xp.Txid,
+			Gxid:     xp.Gxid,
+			Userid:   xp.Userid,
+			Elapsed:  xp.Elapsed,
+			ObjHash:  xp.ObjHash,
+			Service:  xp.Service,
+			Data:     b,
+			Received: q.received,
+		})
+	}
+}
 
-		slog.Debug("XLogCore processing",
-			"objHash", xp.ObjHash,
-			"service", xp.Service,
-			"elapsed", xp.Elapsed,
-			"txid", xp.Txid)
-		if xc.xlogWR != nil {
-			xc.xlogWR.Add(&xlog.XLogEntry{
-				Time:    xp.EndTime,
-				Txid:    xp.Txid,
-				Gxid:    xp.Gxid,
-				Elapsed: xp.Elapsed,
-				Data:    b,
-			})
+// objType returns the object type of objHash, or "" if the object is unknown.
+func (xc *XLogCore) objType(objHash int32) string {
+	if xc.objectCache != nil {
+		if info, ok := xc.objectCache.Get(objHash); ok {
+			return info.Pack.ObjType
 		}
 	}
+	return ""
 }
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// XLogSampler decides which XLogs are stored, to bound the disk usage of
+// very high TPS systems. XLogs with an error, with an elapsed time of at
+// least xlog_sampling_elapsed_ms, or of a service matching one of
+// xlog_sampling_exclude_patterns are always kept; of the others only
+// xlog_sampling_rate percent are. Settings are hot-reloadable.
+//
+// The decision is made on the gxid, or the txid for XLogs without one, so
+// the XLogs of one distributed call are kept or discarded together.
+type XLogSampler struct {
+	serviceName func(hash int32) string
+
+	mu       sync.Mutex
+	spec     string
+	patterns []string
+
+	kept      atomic.Int64
+	discarded atomic.Int64
+}
+
+// NewXLogSampler creates an XLogSampler resolving service hashes through
+// serviceName, which returns "" for unknown hashes.
+func NewXLogSampler(serviceName func(hash int32) string) *XLogSampler {
+	return &XLogSampler{serviceName: serviceName}
+}
+
+// Keep reports whether xp is stored.
+func (s *XLogSampler) Keep(xp *pack.XLogPack) bool {
+	cfg := config.Get()
+	if cfg == nil {
+		return true
+	}
+	rate := cfg.XLogSamplingRate()
+	if rate >= 100 {
+		return true
+	}
+	keep := xp.Error != 0 || xp.Elapsed >= int32(cfg.XLogSamplingElapsedMs()) ||
+		s.excluded(cfg, xp.Service) || sampleBucket(xp) < rate
+	if keep {
+		s.kept.Add(1)
+	} else {
+		s.discarded.Add(1)
+	}
+	return keep
+}
+
+// Discarded returns the number of XLogs sampled out since start.
+func (s *XLogSampler) Discarded() int64 {
+	return s.discarded.Load()
+}
+
+// Summary returns a one-line description of the sampling so far.
+func (s *XLogSampler) Summary() string {
+	kept, discarded := s.kept.Load(), s.discarded.Load()
+	if kept+discarded == 0 {
+		return "nothing sampled"
+	}
+	return fmt.Sprintf("kept %d, discarded %d (%.1f%%)", kept, discarded, float64(discarded)*100/float64(kept+discarded))
+}
+
+// excluded reports whether the service matches xlog_sampling_exclude_patterns.
+func (s *XLogSampler) excluded(cfg *config.Config, service int32) bool {
+	s.mu.Lock()
+	if spec := cfg.XLogSamplingExcludePatterns(); spec != s.spec || s.patterns == nil {
+		s.spec = spec
+		s.patterns = parseSamplingPatterns(spec)
+	}
+	patterns := s.patterns
+	s.mu.Unlock()
+	if len(patterns) == 0 || s.serviceName == nil {
+		return false
+	}
+	name := s.serviceName(service)
+	if name == "" {
+		return false
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSamplingPatterns returns the non-empty patterns of spec.
+func parseSamplingPatterns(spec string) []string {
+	patterns := []string{}
+	for _, p := range strings.Split(spec, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// sampleBucket maps the gxid, or the txid, of xp to a uniformly distributed
+// bucket in [0, 100).
+func sampleBucket(xp *pack.XLogPack) int {
+	id := xp.Gxid
+	if id == 0 {
+		id = xp.Txid
+	}
+	// Transaction ids are not uniformly distributed in their low digits;
+	// mix them first (splitmix64 finalizer).
+	x := uint64(id)
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return int(x % 100)
+}
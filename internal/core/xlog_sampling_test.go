@@ -0,0 +1,62 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+func TestXLogSampler(t *testing.T) {
+	dir := t.TempDir()
+	mirrorConfig(t, dir, "xlog_sampling_rate=10\nxlog_sampling_elapsed_ms=500\nxlog_sampling_exclude_patterns=/order/*\n")
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	names := map[int32]string{1: "/order/new", 2: "/home"}
+	s := NewXLogSampler(func(hash int32) string { return names[hash] })
+
+	kept := 0
+	for i := int64(1); i <= 10000; i++ {
+		if s.Keep(&pack.XLogPack{Service: 2, Txid: i, Elapsed: 20}) {
+			kept++
+		}
+	}
+	if kept < 800 || kept > 1200 {
+		t.Errorf("kept %d of 10000 at 10%%", kept)
+	}
+	for i := int64(1); i <= 100; i++ {
+		if !s.Keep(&pack.XLogPack{Service: 2, Txid: i, Elapsed: 20, Error: 7}) ||
+			!s.Keep(&pack.XLogPack{Service: 2, Txid: i, Elapsed: 500}) ||
+			!s.Keep(&pack.XLogPack{Service: 1, Txid: i, Elapsed: 20}) {
+			t.Fatalf("error, slow or excluded XLog %d sampled out", i)
+		}
+	}
+	// The XLogs of one distributed call share the decision.
+	for gxid := int64(1); gxid <= 100; gxid++ {
+		if s.Keep(&pack.XLogPack{Service: 2, Gxid: gxid, Txid: 1}) != s.Keep(&pack.XLogPack{Service: 2, Gxid: gxid, Txid: 2}) {
+			t.Fatalf("gxid %d kept partially", gxid)
+		}
+	}
+	if s.Discarded() == 0 {
+		t.Error("no discards counted")
+	}
+
+	// Sampled-out XLogs are neither cached nor stored.
+	xc := cache.NewXLogCache(20000)
+	core := NewXLogCore(xc, nil, nil, nil, WithSampler(s), WithoutWorkers())
+	h := core.Handler()
+	for i := int64(1); i <= 1000; i++ {
+		h(&pack.XLogPack{Service: 2, Txid: i, Elapsed: 20, EndTime: 1000}, nil)
+	}
+	core.Drain()
+	if n := len(xc.GetRecent(20000)); n < 50 || n > 150 {
+		t.Errorf("cached %d of 1000 at 10%%", n)
+	}
+
+	mirrorConfig(t, dir, "xlog_sampling_rate=100\n")
+	if !s.Keep(&pack.XLogPack{Service: 2, Txid: 3, Elapsed: 20}) {
+		t.Error("rate 100 must keep everything")
+	}
+}
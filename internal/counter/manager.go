@@ -32,6 +32,7 @@ type ObjectTypeManager struct {
 	familyMasters map[string]string          // family name -> master counter name
 	customDirty   bool
 	customXML     []byte
+	onChange      []func()
 }
 
 // NewObjectTypeManager creates a new manager, parsing the embedded counters.xml.
@@ -149,6 +150,9 @@ func (m *ObjectTypeManager) AddObjectTypeIfNotExist(objType string, tags *value.
 		SubObject: refType.SubObject,
 	}
 	m.customDirty = true
+	for _, fn := range m.onChange {
+		fn()
+	}
 
 	slog.Info("Registered new object type",
 		"objType", objType,
@@ -158,6 +162,14 @@ func (m *ObjectTypeManager) AddObjectTypeIfNotExist(objType string, tags *value.
 	return true
 }
 
+// OnChange registers fn to be called when a new object type is registered.
+// fn runs under the manager's lock and must not call back into it.
+func (m *ObjectTypeManager) OnChange(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onChange = append(m.onChange, fn)
+}
+
 // GetCustomXML returns XML bytes containing dynamically registered custom types.
 // Returns nil if there are no custom types.
 func (m *ObjectTypeManager) GetCustomXML() []byte {
@@ -0,0 +1,290 @@
+// Package agentinv keeps an inventory of the version, operating system and
+// capability flags agents report in their ObjectPacks, with the history of
+// every change, so rollouts of new agent versions can be followed and
+// agents left on an old version found.
+//
+// Changes are appended as JSON lines to agentinv/history.jsonl under the
+// data directory; the current inventory is rebuilt from it at startup.
+package agentinv
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Agent is what an agent last reported about itself.
+type Agent struct {
+	ObjHash      int32    `json:"objHash"`
+	ObjName      string   `json:"objName"`
+	ObjType      string   `json:"objType"`
+	Address      string   `json:"address,omitempty"`
+	Version      string   `json:"version"`
+	OS           string   `json:"os,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"` // sorted
+	// Since is when the agent started reporting this version, OS and
+	// capabilities, in ms.
+	Since int64 `json:"since"`
+	// LastSeen is the last heartbeat in ms; after a restart it starts at Since.
+	LastSeen int64 `json:"lastSeen"`
+	// Outdated is set by List when another agent of the same objType
+	// reports a newer version.
+	Outdated bool `json:"outdated,omitempty"`
+}
+
+// Record is one change of an agent, as stored in the history.
+type Record struct {
+	Time         int64    `json:"time"`
+	ObjHash      int32    `json:"objHash"`
+	ObjName      string   `json:"objName"`
+	ObjType      string   `json:"objType"`
+	Address      string   `json:"address,omitempty"`
+	Version      string   `json:"version"`
+	OS           string   `json:"os,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	// PrevVersion is the version reported before, "" for a new agent.
+	PrevVersion string `json:"prevVersion,omitempty"`
+	// Removed marks the removal of the agent from the inventory; it is added
+	// again as a new agent if it reports later.
+	Removed bool `json:"removed,omitempty"`
+}
+
+// VersionGroup is the agents reporting one version.
+type VersionGroup struct {
+	Version string  `json:"version"`
+	Count   int     `json:"count"`
+	Agents  []Agent `json:"agents"`
+}
+
+// Store holds the inventory and appends changes to the history file.
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	agents map[int32]*Agent
+	file   *os.File
+}
+
+// Open loads the inventory kept under baseDir.
+func Open(baseDir string) (*Store, error) {
+	s := &Store{path: filepath.Join(baseDir, "agentinv", "history.jsonl"), agents: make(map[int32]*Agent)}
+	err := s.read(func(r Record) {
+		if r.Removed {
+			delete(s.agents, r.ObjHash)
+			return
+		}
+		s.agents[r.ObjHash] = &Agent{
+			ObjHash: r.ObjHash, ObjName: r.ObjName, ObjType: r.ObjType, Address: r.Address,
+			Version: r.Version, OS: r.OS, Capabilities: r.Capabilities, Since: r.Time, LastSeen: r.Time,
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Update records a heartbeat of a. A changed name, version, OS or
+// capability set is appended to the history; the address and LastSeen only
+// update the inventory. a.Since and a.LastSeen are taken from now (ms).
+func (s *Store) Update(a Agent, now int64) {
+	a.Capabilities = slices.Clone(a.Capabilities)
+	sort.Strings(a.Capabilities)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur := s.agents[a.ObjHash]
+	if cur != nil && cur.ObjName == a.ObjName && cur.ObjType == a.ObjType && cur.Version == a.Version &&
+		cur.OS == a.OS && slices.Equal(cur.Capabilities, a.Capabilities) {
+		cur.Address, cur.LastSeen = a.Address, now
+		return
+	}
+
+	r := Record{
+		Time: now, ObjHash: a.ObjHash, ObjName: a.ObjName, ObjType: a.ObjType, Address: a.Address,
+		Version: a.Version, OS: a.OS, Capabilities: a.Capabilities,
+	}
+	if cur != nil {
+		r.PrevVersion = cur.Version
+	}
+	a.Since, a.LastSeen, a.Outdated = now, now, false
+	s.agents[a.ObjHash] = &a
+	if err := s.appendLocked(r); err != nil {
+		slog.Warn("Agent inventory write failed", "objName", a.ObjName, "error", err)
+	}
+}
+
+func (s *Store) appendLocked(r Record) error {
+	if s.file == nil {
+		if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		s.file = f
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(append(data, '\n'))
+	return err
+}
+
+// Stale returns the agents of objType, or of every type if objType is "",
+// whose LastSeen is before cutoff (ms). LastSeen starts at Since after a
+// restart, so agents that did not report since may be listed.
+func (s *Store) Stale(cutoff int64, objType string) []Agent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []Agent
+	for _, a := range s.agents {
+		if a.LastSeen < cutoff && (objType == "" || a.ObjType == objType) {
+			result = append(result, *a)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ObjName < result[j].ObjName })
+	return result
+}
+
+// Remove drops agents from the inventory, recording the removal in the
+// history so it survives a restart. Unknown objHashes are ignored.
+func (s *Store) Remove(objHashes []int32, now int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, objHash := range objHashes {
+		a := s.agents[objHash]
+		if a == nil {
+			continue
+		}
+		delete(s.agents, objHash)
+		r := Record{
+			Time: now, ObjHash: a.ObjHash, ObjName: a.ObjName, ObjType: a.ObjType,
+			Version: a.Version, Removed: true,
+		}
+		if err := s.appendLocked(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the agents of objType, or of every type if objType is "",
+// newest version first, with Outdated set.
+func (s *Store) List(objType string) []Agent {
+	s.mu.Lock()
+	var result []Agent
+	newest := make(map[string]string)
+	for _, a := range s.agents {
+		if v, ok := newest[a.ObjType]; !ok || CompareVersions(a.Version, v) > 0 {
+			newest[a.ObjType] = a.Version
+		}
+		if objType == "" || a.ObjType == objType {
+			result = append(result, *a)
+		}
+	}
+	s.mu.Unlock()
+
+	for i := range result {
+		result[i].Outdated = CompareVersions(result[i].Version, newest[result[i].ObjType]) < 0
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if c := CompareVersions(result[i].Version, result[j].Version); c != 0 {
+			return c > 0
+		}
+		return result[i].ObjName < result[j].ObjName
+	})
+	return result
+}
+
+// ByVersion returns the agents of List grouped by version, newest first.
+func (s *Store) ByVersion(objType string) []VersionGroup {
+	var groups []VersionGroup
+	for _, a := range s.List(objType) {
+		if n := len(groups); n > 0 && groups[n-1].Version == a.Version {
+			groups[n-1].Agents = append(groups[n-1].Agents, a)
+			groups[n-1].Count++
+			continue
+		}
+		groups = append(groups, VersionGroup{Version: a.Version, Count: 1, Agents: []Agent{a}})
+	}
+	return groups
+}
+
+// History returns the stored changes of objHash, or of every agent if
+// objHash is 0, oldest first.
+func (s *Store) History(objHash int32) ([]Record, error) {
+	var result []Record
+	err := s.read(func(r Record) {
+		if objHash == 0 || r.ObjHash == objHash {
+			result = append(result, r)
+		}
+	})
+	return result, err
+}
+
+// read calls handler for every record in the history file. Lines that
+// cannot be parsed are skipped.
+func (s *Store) read(handler func(Record)) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var r Record
+		if json.Unmarshal(sc.Bytes(), &r) != nil {
+			continue
+		}
+		handler(r)
+	}
+	return sc.Err()
+}
+
+// Close closes the history file.
+func (s *Store) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+}
+
+// CompareVersions compares two version strings such as "2.20.0" and
+// "2.9.1" by their dot, dash or underscore separated parts, numerically
+// where both parts are numbers. It returns -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	split := func(s string) []string {
+		return strings.FieldsFunc(s, func(r rune) bool { return r == '.' || r == '-' || r == '_' })
+	}
+	pa, pb := split(a), split(b)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		var c int
+		if errA == nil && errB == nil {
+			c = cmp.Compare(na, nb)
+		} else {
+			c = strings.Compare(pa[i], pb[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(pa), len(pb))
+}
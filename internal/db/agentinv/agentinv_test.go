@@ -0,0 +1,116 @@
+package agentinv
+
+import (
+	"testing"
+)
+
+func TestStore_UpdateListHistory(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Update(Agent{ObjHash: 1, ObjName: "/a/tomcat", ObjType: "tomcat", Version: "2.9.1", OS: "linux"}, 1000)
+	s.Update(Agent{ObjHash: 2, ObjName: "/b/tomcat", ObjType: "tomcat", Version: "2.20.0", Capabilities: []string{"kube", "async"}}, 1000)
+	s.Update(Agent{ObjHash: 3, ObjName: "/c/batch", ObjType: "batch", Version: "1.0"}, 1000)
+	// Heartbeats with nothing changed only move LastSeen.
+	s.Update(Agent{ObjHash: 1, ObjName: "/a/tomcat", ObjType: "tomcat", Version: "2.9.1", OS: "linux", Address: "10.0.0.1"}, 2000)
+
+	agents := s.List("tomcat")
+	if len(agents) != 2 || agents[0].ObjHash != 2 || agents[1].ObjHash != 1 {
+		t.Fatalf("agents = %+v", agents)
+	}
+	if agents[0].Outdated || !agents[1].Outdated {
+		t.Errorf("outdated = %v, %v; want false, true", agents[0].Outdated, agents[1].Outdated)
+	}
+	if agents[1].Since != 1000 || agents[1].LastSeen != 2000 || agents[1].Address != "10.0.0.1" {
+		t.Errorf("agent 1 = %+v", agents[1])
+	}
+	if caps := agents[0].Capabilities; len(caps) != 2 || caps[0] != "async" {
+		t.Errorf("capabilities = %v, want sorted", caps)
+	}
+
+	// Upgrade, then reopen: the inventory is rebuilt from the history.
+	s.Update(Agent{ObjHash: 1, ObjName: "/a/tomcat", ObjType: "tomcat", Version: "2.20.0", OS: "linux"}, 3000)
+	s.Close()
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	groups := s.ByVersion("")
+	if len(groups) != 2 || groups[0].Version != "2.20.0" || groups[0].Count != 2 || groups[1].Version != "1.0" {
+		t.Fatalf("groups = %+v", groups)
+	}
+	history, err := s.History(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[1].Version != "2.20.0" || history[1].PrevVersion != "2.9.1" || history[1].Time != 3000 {
+		t.Errorf("history = %+v", history)
+	}
+	if all, _ := s.History(0); len(all) != 4 {
+		t.Errorf("all history = %d records, want 4", len(all))
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.20.0", "2.9.1", 1},
+		{"2.9.1", "2.20.0", -1},
+		{"2.20.0", "2.20.0", 0},
+		{"2.20", "2.20.1", -1},
+		{"1.0-beta", "1.0-alpha", 1},
+		{"", "1.0", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestStore_StaleRemove(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Update(Agent{ObjHash: 1, ObjName: "/old/tomcat", ObjType: "tomcat", Version: "2.9.1"}, 1000)
+	s.Update(Agent{ObjHash: 2, ObjName: "/new/tomcat", ObjType: "tomcat", Version: "2.9.1"}, 5000)
+	s.Update(Agent{ObjHash: 3, ObjName: "/old/batch", ObjType: "batch", Version: "1.0"}, 1000)
+
+	stale := s.Stale(2000, "tomcat")
+	if len(stale) != 1 || stale[0].ObjHash != 1 {
+		t.Fatalf("stale = %+v", stale)
+	}
+	if err := s.Remove([]int32{1, 99}, 6000); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// The removal survives a restart and is in the history.
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if agents := s.List(""); len(agents) != 2 {
+		t.Errorf("agents after reopen = %+v", agents)
+	}
+	records, _ := s.History(1)
+	if len(records) != 2 || !records[1].Removed || records[1].Time != 6000 {
+		t.Errorf("history = %+v", records)
+	}
+
+	// An agent reporting again is listed as new.
+	s.Update(Agent{ObjHash: 1, ObjName: "/old/tomcat", ObjType: "tomcat", Version: "2.9.1"}, 7000)
+	records, _ = s.History(1)
+	if len(records) != 3 || records[2].PrevVersion != "" || len(s.List("tomcat")) != 2 {
+		t.Errorf("history after return = %+v", records)
+	}
+}
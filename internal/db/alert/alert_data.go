@@ -2,7 +2,6 @@ package alert
 
 import (
 	"encoding/binary"
-	"os"
 	"path/filepath"
 	"sync"
 
@@ -69,11 +68,9 @@ func (ad *AlertData) ReadRange(stime, etime int64, handler func(timeMs int64, da
 	ad.mu.Lock()
 	defer ad.mu.Unlock()
 
-	dataPath := filepath.Join(ad.dir, "alert.data")
-
 	return ad.index.Read(stime, etime, func(timeMs int64, dataPos []byte) bool {
 		offset := protocol.BigEndian.Int5(dataPos)
-		raw, err := readEntryAt(dataPath, offset)
+		raw, err := readEntryAt(ad.data, offset)
 		if err == nil && raw != nil {
 			handler(timeMs, raw)
 		}
@@ -82,25 +79,15 @@ func (ad *AlertData) ReadRange(stime, etime int64, handler func(timeMs int64, da
 }
 
 // readEntryAt reads a [2-byte length][data] entry from the file at the given offset.
-func readEntryAt(path string, offset int64) ([]byte, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	if _, err := f.Seek(offset, 0); err != nil {
-		return nil, err
-	}
-
+func readEntryAt(f *io.RealDataFile, offset int64) ([]byte, error) {
 	var lenBuf [2]byte
-	if _, err := f.Read(lenBuf[:]); err != nil {
+	if _, err := f.ReadAt(lenBuf[:], offset); err != nil {
 		return nil, err
 	}
 	length := binary.BigEndian.Uint16(lenBuf[:])
 
 	data := make([]byte, length)
-	if _, err := f.Read(data); err != nil {
+	if _, err := f.ReadAt(data, offset+2); err != nil {
 		return nil, err
 	}
 
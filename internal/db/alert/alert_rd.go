@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+
+	"github.com/zbum/scouter-server-go/internal/db/io"
 )
 
 // AlertRD is an alert reader.
@@ -23,6 +25,7 @@ func NewAlertRD(baseDir string) *AlertRD {
 
 // getContainer retrieves or opens a day container for reading.
 func (r *AlertRD) getContainer(date string) (*AlertData, error) {
+	io.RestoreMissingDay(r.baseDir, date)
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -77,6 +80,19 @@ func (r *AlertRD) PurgeOldDays(keepDates map[string]bool) {
 	}
 }
 
+// CloseDay closes the container for a single date, if open.
+func (r *AlertRD) CloseDay(date string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ad, ok := r.days[date]; ok {
+		if ad != nil {
+			ad.Close()
+		}
+		delete(r.days, date)
+	}
+}
+
 // Close closes all open day containers.
 func (r *AlertRD) Close() {
 	r.mu.Lock()
@@ -89,3 +105,21 @@ func (r *AlertRD) Close() {
 	}
 	r.days = make(map[string]*AlertData)
 }
+
+// OpenDays returns the dates with an open container.
+func (r *AlertRD) OpenDays() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	dates := make([]string, 0, len(r.days))
+	for date := range r.days {
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+// OpenDay opens the container of date ahead of a query. A date without alert
+// data is not an error.
+func (r *AlertRD) OpenDay(date string) error {
+	_, err := r.getContainer(date)
+	return err
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/zbum/scouter-server-go/internal/db/io"
 	"github.com/zbum/scouter-server-go/internal/util"
 )
 
@@ -94,7 +95,9 @@ func (w *AlertWR) process(entry *AlertEntry) {
 
 	if err := container.Write(entry.TimeMs, entry.Data); err != nil {
 		slog.Error("AlertWR write error", "error", err)
+		return
 	}
+	io.GetWriteStats().Record("alert", date, 0, len(entry.Data))
 }
 
 // PurgeOldDays closes day containers not in the keepDates set.
@@ -114,6 +117,20 @@ func (w *AlertWR) PurgeOldDays(keepDates map[string]bool) {
 	}
 }
 
+// CloseDay flushes and closes the container for a single date, if open.
+func (w *AlertWR) CloseDay(date string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if ad, ok := w.days[date]; ok {
+		if ad != nil {
+			ad.Flush()
+			ad.Close()
+		}
+		delete(w.days, date)
+	}
+}
+
 // Close closes all open day containers.
 func (w *AlertWR) Close() {
 	w.mu.Lock()
@@ -127,3 +144,14 @@ func (w *AlertWR) Close() {
 	}
 	w.days = make(map[string]*AlertData)
 }
+
+// OpenDays returns the dates with an open container.
+func (w *AlertWR) OpenDays() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	dates := make([]string, 0, len(w.days))
+	for date := range w.days {
+		dates = append(dates, date)
+	}
+	return dates
+}
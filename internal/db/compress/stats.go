@@ -0,0 +1,74 @@
+package compress
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// KindStat sums the records of one kind of data written since start.
+type KindStat struct {
+	Kind       string `json:"kind"`
+	Records    int64  `json:"records"`
+	Compressed int64  `json:"compressed"`  // records stored compressed
+	RawBytes   int64  `json:"rawBytes"`    // before compression
+	Stored     int64  `json:"storedBytes"` // as written
+}
+
+// Ratio returns RawBytes per stored byte, 1 if nothing was written.
+func (s KindStat) Ratio() float64 {
+	if s.Stored == 0 {
+		return 1
+	}
+	return float64(s.RawBytes) / float64(s.Stored)
+}
+
+type kindCounters struct {
+	records, compressed, raw, stored atomic.Int64
+}
+
+// Stats counts the bytes the data files would take uncompressed and take
+// stored, per kind of data such as "xlog" and "profile".
+type Stats struct {
+	kinds sync.Map // kind → *kindCounters
+}
+
+// Add records one record of kind, raw bytes long and stored bytes as written.
+func (s *Stats) Add(kind string, raw, stored int, compressed bool) {
+	v, ok := s.kinds.Load(kind)
+	if !ok {
+		v, _ = s.kinds.LoadOrStore(kind, &kindCounters{})
+	}
+	c := v.(*kindCounters)
+	c.records.Add(1)
+	if compressed {
+		c.compressed.Add(1)
+	}
+	c.raw.Add(int64(raw))
+	c.stored.Add(int64(stored))
+}
+
+// Snapshot returns the counts of every kind, sorted by kind.
+func (s *Stats) Snapshot() []KindStat {
+	var result []KindStat
+	s.kinds.Range(func(k, v any) bool {
+		c := v.(*kindCounters)
+		result = append(result, KindStat{
+			Kind:       k.(string),
+			Records:    c.records.Load(),
+			Compressed: c.compressed.Load(),
+			RawBytes:   c.raw.Load(),
+			Stored:     c.stored.Load(),
+		})
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool { return result[i].Kind < result[j].Kind })
+	return result
+}
+
+var globalStats Stats
+
+// GetStats returns the process-wide compression statistics.
+func GetStats() *Stats {
+	return &globalStats
+}
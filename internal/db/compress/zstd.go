@@ -1,6 +1,7 @@
 package compress
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/klauspost/compress/zstd"
@@ -46,6 +47,27 @@ func (p *Pool) Compress(data []byte) []byte {
 	return out
 }
 
+// Encode returns data as it is stored. With zstd set, data is compressed
+// as [0x00][0x01][zstd payload] unless that is not smaller. Data stored raw
+// is returned as-is, or as [0x00][0x00][data] if it starts with 0x00, so
+// Decode cannot mistake it for a header. The result reports whether data was
+// compressed.
+func (p *Pool) Encode(data []byte, zstd bool) ([]byte, bool) {
+	if zstd && len(data) > 0 {
+		if out := p.Compress(data); len(out) < len(data) {
+			return out, true
+		}
+	}
+	if len(data) > 0 && data[0] == flagNewFormat {
+		out := make([]byte, 2+len(data))
+		out[0] = flagNewFormat
+		out[1] = compTypeRaw
+		copy(out[2:], data)
+		return out, false
+	}
+	return data, false
+}
+
 // Decode detects the format and decompresses if needed.
 //   - body[0] == 0x00 → new format: body[1] selects codec
 //   - body[0] != 0x00 → legacy raw data, returned as-is
@@ -68,7 +90,7 @@ func (p *Pool) Decode(body []byte) ([]byte, error) {
 	case compTypeZstd:
 		return p.decoder.DecodeAll(body[2:], nil)
 	default:
-		return body[2:], nil
+		return nil, fmt.Errorf("compress: unknown codec 0x%02x", body[1])
 	}
 }
 
@@ -119,3 +119,62 @@ func TestConcurrency(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestEncode(t *testing.T) {
+	pool := SharedPool()
+
+	text := bytes.Repeat([]byte("SELECT * FROM orders WHERE id = ?;"), 20)
+	random := make([]byte, 200)
+	rand.Read(random)
+	random[0] = 10
+	zeroLed := append([]byte{0x00}, text...)
+
+	for _, tc := range []struct {
+		name           string
+		data           []byte
+		zstd, wantComp bool
+	}{
+		{"compressible", text, true, true},
+		{"incompressible", random, true, false},
+		{"disabled", text, false, false},
+		{"leading zero raw", zeroLed, false, false},
+		{"leading zero compressed", zeroLed, true, true},
+		{"empty", nil, true, false},
+	} {
+		body, compressed := pool.Encode(tc.data, tc.zstd)
+		if compressed != tc.wantComp {
+			t.Errorf("%s: compressed = %v", tc.name, compressed)
+		}
+		if compressed && len(body) >= len(tc.data) {
+			t.Errorf("%s: %d bytes compressed to %d", tc.name, len(tc.data), len(body))
+		}
+		decoded, err := pool.Decode(body)
+		if err != nil || !bytes.Equal(decoded, tc.data) {
+			t.Errorf("%s: roundtrip = %v, %v", tc.name, decoded, err)
+		}
+	}
+	// Raw data not starting with 0x00 is stored as-is, as before.
+	if body, _ := pool.Encode(random, false); &body[0] != &random[0] {
+		t.Error("raw data copied")
+	}
+	if _, err := pool.Decode([]byte{0x00, 0x07, 1, 2}); err == nil {
+		t.Error("expected an error for an unknown codec")
+	}
+}
+
+func TestStats(t *testing.T) {
+	var s Stats
+	s.Add("xlog", 300, 100, true)
+	s.Add("xlog", 100, 100, false)
+	s.Add("profile", 50, 50, false)
+	got := s.Snapshot()
+	if len(got) != 2 || got[0].Kind != "profile" || got[1].Kind != "xlog" {
+		t.Fatalf("snapshot = %+v", got)
+	}
+	if x := got[1]; x.Records != 2 || x.Compressed != 1 || x.Ratio() != 2 {
+		t.Errorf("xlog = %+v, ratio %v", x, x.Ratio())
+	}
+	if r := (KindStat{}).Ratio(); r != 1 {
+		t.Errorf("empty ratio = %v", r)
+	}
+}
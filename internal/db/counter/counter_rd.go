@@ -1,10 +1,13 @@
 package counter
 
 import (
+	"math"
 	"os"
 	"path/filepath"
 	"sync"
 
+	"github.com/zbum/scouter-server-go/internal/db/format"
+	"github.com/zbum/scouter-server-go/internal/db/io"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
 )
 
@@ -14,6 +17,7 @@ type CounterRD struct {
 	baseDir      string
 	realtimeDays map[string]*RealtimeCounterData
 	dailyDays    map[string]*DailyCounterData
+	sources      func(objHash int32) []int32
 }
 
 func NewCounterRD(baseDir string) *CounterRD {
@@ -24,6 +28,21 @@ func NewCounterRD(baseDir string) *CounterRD {
 	}
 }
 
+// SetSources installs fn returning the objHashes whose history is merged into
+// reads of objHash, e.g. the agents behind an object alias.
+func (r *CounterRD) SetSources(fn func(objHash int32) []int32) {
+	r.sources = fn
+}
+
+// readHashes returns objHash preceded by its sources, which hold the older
+// history.
+func (r *CounterRD) readHashes(objHash int32) []int32 {
+	if r.sources == nil {
+		return []int32{objHash}
+	}
+	return append(r.sources(objHash), objHash)
+}
+
 // ReadRealtime retrieves counter values for an object at a specific second.
 func (r *CounterRD) ReadRealtime(date string, objHash int32, timeSec int32) (map[string]value.Value, error) {
 	data, err := r.getRealtimeData(date)
@@ -33,10 +52,18 @@ func (r *CounterRD) ReadRealtime(date string, objHash int32, timeSec int32) (map
 	if data == nil {
 		return nil, nil
 	}
-	return data.Read(objHash, timeSec)
+	hashes := r.readHashes(objHash)
+	for i := len(hashes) - 1; i >= 0; i-- {
+		counters, err := data.Read(hashes[i], timeSec)
+		if err != nil || counters != nil {
+			return counters, err
+		}
+	}
+	return nil, nil
 }
 
 // ReadRealtimeRange reads all realtime entries for an object in a time range.
+// The entries of its sources are read first.
 func (r *CounterRD) ReadRealtimeRange(date string, objHash int32, startSec, endSec int32,
 	handler func(timeSec int32, counters map[string]value.Value)) error {
 	data, err := r.getRealtimeData(date)
@@ -46,7 +73,24 @@ func (r *CounterRD) ReadRealtimeRange(date string, objHash int32, startSec, endS
 	if data == nil {
 		return nil
 	}
-	return data.ReadRange(objHash, startSec, endSec, handler)
+	for _, h := range r.readHashes(objHash) {
+		if err := data.ReadRange(h, startSec, endSec, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RealtimeKeys lists all realtime entries of a day ordered by time.
+func (r *CounterRD) RealtimeKeys(date string) ([]RealtimeKey, error) {
+	data, err := r.getRealtimeData(date)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, nil
+	}
+	return data.Keys()
 }
 
 // ReadDaily retrieves the value at a specific 5-minute bucket.
@@ -58,7 +102,14 @@ func (r *CounterRD) ReadDaily(date string, objHash int32, counterName string, bu
 	if data == nil {
 		return 0, false, nil
 	}
-	return data.Read(objHash, counterName, bucket)
+	hashes := r.readHashes(objHash)
+	for i := len(hashes) - 1; i >= 0; i-- {
+		v, ok, err := data.Read(hashes[i], counterName, bucket)
+		if err != nil || ok {
+			return v, ok, err
+		}
+	}
+	return 0, false, nil
 }
 
 // ReadDailyAll retrieves all 288 bucket values for a counter key.
@@ -70,10 +121,32 @@ func (r *CounterRD) ReadDailyAll(date string, objHash int32, counterName string)
 	if data == nil {
 		return nil, nil
 	}
-	return data.ReadAll(objHash, counterName)
+	hashes := r.readHashes(objHash)
+	values, err := data.ReadAll(objHash, counterName)
+	if err != nil || len(hashes) == 1 {
+		return values, err
+	}
+	// Fill the buckets objHash has no value for from its sources.
+	for _, h := range hashes[:len(hashes)-1] {
+		src, err := data.ReadAll(h, counterName)
+		if err != nil || src == nil {
+			continue
+		}
+		if values == nil {
+			values = src
+			continue
+		}
+		for i, v := range values {
+			if math.IsNaN(v) && !math.IsNaN(src[i]) {
+				values[i] = src[i]
+			}
+		}
+	}
+	return values, nil
 }
 
 func (r *CounterRD) getRealtimeData(date string) (*RealtimeCounterData, error) {
+	io.RestoreMissingDay(r.baseDir, date)
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -85,6 +158,9 @@ func (r *CounterRD) getRealtimeData(date string) (*RealtimeCounterData, error) {
 	if _, err := os.Stat(filepath.Join(dir, "real.data")); os.IsNotExist(err) {
 		return nil, nil
 	}
+	if err := format.Counter.Check(dir); err != nil {
+		return nil, err
+	}
 
 	d, err := NewRealtimeCounterData(dir)
 	if err != nil {
@@ -95,6 +171,7 @@ func (r *CounterRD) getRealtimeData(date string) (*RealtimeCounterData, error) {
 }
 
 func (r *CounterRD) getDailyData(date string) (*DailyCounterData, error) {
+	io.RestoreMissingDay(r.baseDir, date)
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -106,6 +183,9 @@ func (r *CounterRD) getDailyData(date string) (*DailyCounterData, error) {
 	if _, err := os.Stat(filepath.Join(dir, "5m.data")); os.IsNotExist(err) {
 		return nil, nil
 	}
+	if err := format.Counter.Check(dir); err != nil {
+		return nil, err
+	}
 
 	d, err := NewDailyCounterData(dir)
 	if err != nil {
@@ -136,6 +216,21 @@ func (r *CounterRD) PurgeOldDays(keepDates map[string]bool) {
 	}
 }
 
+// CloseDay closes the realtime and daily containers for a single date.
+func (r *CounterRD) CloseDay(date string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if d, ok := r.realtimeDays[date]; ok {
+		d.Close()
+		delete(r.realtimeDays, date)
+	}
+	if d, ok := r.dailyDays[date]; ok {
+		d.Close()
+		delete(r.dailyDays, date)
+	}
+}
+
 // Close closes all open data files.
 func (r *CounterRD) Close() {
 	r.mu.Lock()
@@ -147,3 +242,31 @@ func (r *CounterRD) Close() {
 		d.Close()
 	}
 }
+
+// OpenDays returns the dates with an open realtime or daily container.
+func (r *CounterRD) OpenDays() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[string]bool, len(r.realtimeDays)+len(r.dailyDays))
+	dates := make([]string, 0, len(seen))
+	for date := range r.realtimeDays {
+		seen[date] = true
+		dates = append(dates, date)
+	}
+	for date := range r.dailyDays {
+		if !seen[date] {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
+
+// OpenDay opens the realtime and daily containers of date ahead of a query.
+// Missing counter files are not an error.
+func (r *CounterRD) OpenDay(date string) error {
+	if _, err := r.getRealtimeData(date); err != nil {
+		return err
+	}
+	_, err := r.getDailyData(date)
+	return err
+}
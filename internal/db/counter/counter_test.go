@@ -105,6 +105,38 @@ func TestRealtimeCounterData_ReadRange(t *testing.T) {
 	}
 }
 
+func TestRealtimeCounterData_Keys(t *testing.T) {
+	dir := t.TempDir()
+
+	data, err := NewRealtimeCounterData(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	counters := map[string]value.Value{"TPS": value.NewDecimalValue(1)}
+	for _, k := range []RealtimeKey{{2, 300}, {1, 200}, {-5, 300}, {1, 100}} {
+		if err := data.Write(k.ObjHash, k.TimeSec, counters); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data.Flush()
+
+	keys, err := data.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RealtimeKey{{1, 100}, {1, 200}, {-5, 300}, {2, 300}}
+	if len(keys) != len(want) {
+		t.Fatalf("expected %d keys, got %v", len(want), keys)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Fatalf("keys[%d] = %v, want %v", i, keys[i], want[i])
+		}
+	}
+}
+
 func TestDailyCounterData_WriteRead(t *testing.T) {
 	dir := t.TempDir()
 
@@ -364,3 +396,111 @@ func init() {
 	// Ensure temp directories are cleaned up
 	os.Setenv("TMPDIR", os.TempDir())
 }
+
+func TestCounterRD_Sources(t *testing.T) {
+	baseDir := t.TempDir()
+
+	wr := NewCounterWR(baseDir)
+	ctx, cancel := context.WithCancel(context.Background())
+	wr.Start(ctx)
+
+	date := "20250101"
+	// objHash 2 is the alias of objHash 1, which holds the older history.
+	wr.AddDaily(&DailyEntry{Date: date, ObjHash: 1, CounterName: "TPS", Bucket: 10, Value: 10})
+	wr.AddDaily(&DailyEntry{Date: date, ObjHash: 1, CounterName: "TPS", Bucket: 20, Value: 99})
+	wr.AddDaily(&DailyEntry{Date: date, ObjHash: 2, CounterName: "TPS", Bucket: 20, Value: 20})
+
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	wr.Close()
+
+	rd := NewCounterRD(baseDir)
+	defer rd.Close()
+	rd.SetSources(func(objHash int32) []int32 {
+		if objHash == 2 {
+			return []int32{1}
+		}
+		return nil
+	})
+
+	values, err := rd.ReadDailyAll(date, 2, "TPS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[10] != 10 || values[20] != 20 {
+		t.Errorf("merged buckets = %v, %v; want 10, 20", values[10], values[20])
+	}
+	if v, ok, _ := rd.ReadDaily(date, 2, "TPS", 10); !ok || v != 10 {
+		t.Errorf("ReadDaily from source = %v (ok=%v)", v, ok)
+	}
+	if v, ok, _ := rd.ReadDaily(date, 1, "TPS", 20); !ok || v != 99 {
+		t.Errorf("source read on its own = %v (ok=%v)", v, ok)
+	}
+}
+
+func TestDownsampleRealtime(t *testing.T) {
+	dir := t.TempDir()
+	data, err := NewRealtimeCounterData(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 600..719: two minutes of object 1; object 2 only in the first minute.
+	for sec := int32(600); sec < 720; sec++ {
+		data.Write(1, sec, map[string]value.Value{
+			"TPS":     value.NewDecimalValue(int64(sec)),
+			"Elapsed": &value.FloatValue{Value: 1.5},
+			"Mode":    value.NewTextValue("m" + string(rune('0'+sec%10))),
+		})
+		if sec < 660 {
+			data.Write(2, sec, map[string]value.Value{"TPS": value.NewDecimalValue(10)})
+		}
+	}
+	data.Flush()
+	data.Close()
+
+	if done, err := DownsampleRealtime(dir); !done || err != nil {
+		t.Fatalf("DownsampleRealtime = %v, %v", done, err)
+	}
+	if !Downsampled(dir) {
+		t.Error("marker not written")
+	}
+	if done, _ := DownsampleRealtime(dir); done {
+		t.Error("downsampled twice")
+	}
+	if entries, _ := os.ReadDir(dir + "/real_1m.tmp"); len(entries) != 0 {
+		t.Error("temporary directory left behind")
+	}
+
+	data, err = NewRealtimeCounterData(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	keys, _ := data.Keys()
+	want := []RealtimeKey{{1, 600}, {2, 600}, {1, 660}}
+	if len(keys) != len(want) {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Fatalf("keys = %v, want %v", keys, want)
+		}
+	}
+
+	c, _ := data.Read(1, 600)
+	if tps := c["TPS"].(*value.DecimalValue).Value; tps != 630 {
+		t.Errorf("TPS avg = %d, want 630", tps)
+	}
+	if e := c["Elapsed"].(*value.FloatValue).Value; e != 1.5 {
+		t.Errorf("Elapsed avg = %v, want 1.5", e)
+	}
+	if m := c["Mode"].(*value.TextValue).Value; m != "m9" {
+		t.Errorf("Mode = %q, want last value m9", m)
+	}
+	if c, _ := data.Read(2, 600); c["TPS"].(*value.DecimalValue).Value != 10 {
+		t.Errorf("object 2 = %v", c)
+	}
+	if c, _ := data.Read(1, 601); c != nil {
+		t.Errorf("per-second entry kept: %v", c)
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/zbum/scouter-server-go/internal/db/format"
+	"github.com/zbum/scouter-server-go/internal/db/io"
+	"github.com/zbum/scouter-server-go/internal/ingest"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
 	"github.com/zbum/scouter-server-go/internal/util"
 )
@@ -17,6 +21,8 @@ type RealtimeEntry struct {
 	TimeMs   int64
 	ObjHash  int32
 	Counters map[string]value.Value
+	// Received is when the pack arrived over UDP, zero if unknown.
+	Received time.Time
 }
 
 // DailyEntry represents a single counter write for daily 5-min storage.
@@ -36,6 +42,7 @@ type CounterWR struct {
 	dailyDays    map[string]*DailyCounterData
 	rtQueue     chan *RealtimeEntry
 	dailyQueue  chan *DailyEntry
+	dropped     atomic.Int64
 }
 
 func NewCounterWR(baseDir string) *CounterWR {
@@ -59,6 +66,7 @@ func (w *CounterWR) AddRealtime(entry *RealtimeEntry) {
 	select {
 	case w.rtQueue <- entry:
 	default:
+		w.dropped.Add(1)
 		slog.Debug("CounterWR: realtime queue full, dropping")
 	}
 }
@@ -68,10 +76,21 @@ func (w *CounterWR) AddDaily(entry *DailyEntry) {
 	select {
 	case w.dailyQueue <- entry:
 	default:
+		w.dropped.Add(1)
 		slog.Debug("CounterWR: daily queue full, dropping")
 	}
 }
 
+// Dropped returns the number of entries dropped due to write queue overflow.
+func (w *CounterWR) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Pending returns the number of entries waiting to be written.
+func (w *CounterWR) Pending() int {
+	return len(w.rtQueue) + len(w.dailyQueue)
+}
+
 // AddRealtimeFromPerfCounter is a convenience that creates a RealtimeEntry from
 // common parameters and queues it.
 func (w *CounterWR) AddRealtimeFromPerfCounter(timeMs int64, objHash int32, counters map[string]value.Value) {
@@ -105,6 +124,29 @@ func (w *CounterWR) processDaily(ctx context.Context) {
 	}
 }
 
+// Drain writes every queued realtime entry, then every queued daily entry,
+// on the calling goroutine and returns how many were written. It replaces
+// Start where writes must happen at a known point, as in deterministic tests.
+func (w *CounterWR) Drain() int {
+	n := 0
+	for {
+		select {
+		case entry := <-w.rtQueue:
+			w.writeRealtime(entry)
+			n++
+			continue
+		default:
+		}
+		select {
+		case entry := <-w.dailyQueue:
+			w.writeDaily(entry)
+			n++
+		default:
+			return n
+		}
+	}
+}
+
 func (w *CounterWR) writeRealtime(entry *RealtimeEntry) {
 	date := util.FormatDate(entry.TimeMs)
 	t := time.UnixMilli(entry.TimeMs)
@@ -118,7 +160,10 @@ func (w *CounterWR) writeRealtime(entry *RealtimeEntry) {
 
 	if err := data.Write(entry.ObjHash, timeSec, entry.Counters); err != nil {
 		slog.Error("CounterWR: write realtime error", "error", err)
+		return
 	}
+	io.GetWriteStats().Record("counter", date, entry.ObjHash, 0)
+	ingest.GetLatency().Observe("counter", entry.Received)
 }
 
 func (w *CounterWR) writeDaily(entry *DailyEntry) {
@@ -145,6 +190,9 @@ func (w *CounterWR) getRealtimeData(date string) (*RealtimeCounterData, error) {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, err
 	}
+	if err := format.Counter.Stamp(dir); err != nil {
+		return nil, err
+	}
 
 	d, err := NewRealtimeCounterData(dir)
 	if err != nil {
@@ -166,6 +214,9 @@ func (w *CounterWR) getDailyData(date string) (*DailyCounterData, error) {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, err
 	}
+	if err := format.Counter.Stamp(dir); err != nil {
+		return nil, err
+	}
 
 	d, err := NewDailyCounterData(dir)
 	if err != nil {
@@ -197,6 +248,22 @@ func (w *CounterWR) PurgeOldDays(keepDates map[string]bool) {
 	}
 }
 
+// CloseDay flushes and closes the realtime and daily containers for a single date.
+func (w *CounterWR) CloseDay(date string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if d, ok := w.realtimeDays[date]; ok {
+		d.Flush()
+		d.Close()
+		delete(w.realtimeDays, date)
+	}
+	if d, ok := w.dailyDays[date]; ok {
+		d.Close()
+		delete(w.dailyDays, date)
+	}
+}
+
 func (w *CounterWR) flushAll() {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -216,3 +283,21 @@ func (w *CounterWR) Close() {
 		d.Close()
 	}
 }
+
+// OpenDays returns the dates with an open realtime or daily container.
+func (w *CounterWR) OpenDays() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	seen := make(map[string]bool, len(w.realtimeDays)+len(w.dailyDays))
+	dates := make([]string, 0, len(seen))
+	for date := range w.realtimeDays {
+		seen[date] = true
+		dates = append(dates, date)
+	}
+	for date := range w.dailyDays {
+		if !seen[date] {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
@@ -0,0 +1,165 @@
+package counter
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// downsampledMarker is created in a counter directory once its realtime data
+// holds one entry per object and minute. Its "real" prefix makes the realtime
+// counter purge remove it along with the data.
+const downsampledMarker = "real.1m"
+
+// Downsampled reports whether the realtime counters in dir have already been
+// reduced to one-minute resolution.
+func Downsampled(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, downsampledMarker))
+	return err == nil
+}
+
+// minuteBucket accumulates the counters of one object over one minute.
+type minuteBucket struct {
+	sums   map[string]float64
+	counts map[string]int
+	last   map[string]value.Value
+}
+
+func newMinuteBucket() *minuteBucket {
+	return &minuteBucket{
+		sums:   make(map[string]float64),
+		counts: make(map[string]int),
+		last:   make(map[string]value.Value),
+	}
+}
+
+func (b *minuteBucket) add(counters map[string]value.Value) {
+	for name, v := range counters {
+		b.last[name] = v
+		switch n := v.(type) {
+		case *value.DecimalValue:
+			b.sums[name] += float64(n.Value)
+		case *value.FloatValue:
+			b.sums[name] += float64(n.Value)
+		case *value.DoubleValue:
+			b.sums[name] += n.Value
+		default:
+			continue
+		}
+		b.counts[name]++
+	}
+}
+
+// counters returns the average of every numeric counter, in its original
+// type, and the last value of the others.
+func (b *minuteBucket) counters() map[string]value.Value {
+	result := make(map[string]value.Value, len(b.last))
+	for name, v := range b.last {
+		n := b.counts[name]
+		if n == 0 {
+			result[name] = v
+			continue
+		}
+		avg := b.sums[name] / float64(n)
+		switch v.(type) {
+		case *value.DecimalValue:
+			result[name] = value.NewDecimalValue(int64(math.Round(avg)))
+		case *value.FloatValue:
+			result[name] = &value.FloatValue{Value: float32(avg)}
+		default:
+			result[name] = &value.DoubleValue{Value: avg}
+		}
+	}
+	return result
+}
+
+// DownsampleRealtime rewrites the per-second realtime counters in dir as one
+// entry per object and minute, stored at the first second of the minute.
+// Numeric counters are averaged over the minute; other values keep the last
+// one. It reports whether there was per-second data to reduce.
+//
+// The day must not be open for writing.
+func DownsampleRealtime(dir string) (bool, error) {
+	if _, err := os.Stat(filepath.Join(dir, "real.data")); err != nil || Downsampled(dir) {
+		return false, nil
+	}
+
+	src, err := NewRealtimeCounterData(dir)
+	if err != nil {
+		return false, err
+	}
+	keys, err := src.Keys()
+	if err != nil {
+		src.Close()
+		return false, err
+	}
+
+	tmp := filepath.Join(dir, "real_1m.tmp")
+	os.RemoveAll(tmp)
+	dst, err := NewRealtimeCounterData(tmp)
+	if err != nil {
+		src.Close()
+		return false, err
+	}
+
+	// Keys are ordered by time, so a minute is complete once a later one
+	// starts.
+	minute := int32(-1)
+	buckets := make(map[int32]*minuteBucket)
+	flush := func() error {
+		for objHash, b := range buckets {
+			if err := dst.Write(objHash, minute, b.counters()); err != nil {
+				return err
+			}
+		}
+		clear(buckets)
+		return nil
+	}
+	for _, k := range keys {
+		if m := k.TimeSec - k.TimeSec%60; m != minute {
+			if err = flush(); err != nil {
+				break
+			}
+			minute = m
+		}
+		counters, rerr := src.Read(k.ObjHash, k.TimeSec)
+		if rerr != nil || counters == nil {
+			continue
+		}
+		b := buckets[k.ObjHash]
+		if b == nil {
+			b = newMinuteBucket()
+			buckets[k.ObjHash] = b
+		}
+		b.add(counters)
+	}
+	if err == nil {
+		err = flush()
+	}
+	if err == nil {
+		err = dst.Flush()
+	}
+	src.Close()
+	dst.Close()
+	if err != nil {
+		os.RemoveAll(tmp)
+		return false, err
+	}
+
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		return false, err
+	}
+	for _, e := range entries {
+		if err := os.Rename(filepath.Join(tmp, e.Name()), filepath.Join(dir, e.Name())); err != nil {
+			return false, err
+		}
+	}
+	os.RemoveAll(tmp)
+	if err := os.WriteFile(filepath.Join(dir, downsampledMarker), nil, 0644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 
 	"github.com/zbum/scouter-server-go/internal/db/io"
@@ -100,26 +101,16 @@ func (r *RealtimeCounterData) Read(objHash int32, timeSec int32) (map[string]val
 }
 
 func (r *RealtimeCounterData) readAtOffset(offset int64) (map[string]value.Value, error) {
-	f, err := os.Open(r.data.Filename())
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	if _, err := f.Seek(offset, 0); err != nil {
-		return nil, err
-	}
-
 	// Read length
 	lenBuf := make([]byte, 4)
-	if _, err := f.Read(lenBuf); err != nil {
+	if _, err := r.data.ReadAt(lenBuf, offset); err != nil {
 		return nil, err
 	}
 	length := int(binary.BigEndian.Uint32(lenBuf))
 
 	// Read data
 	blob := make([]byte, length)
-	if _, err := f.Read(blob); err != nil {
+	if _, err := r.data.ReadAt(blob, offset+4); err != nil {
 		return nil, err
 	}
 
@@ -169,6 +160,39 @@ func (r *RealtimeCounterData) ReadRange(objHash int32, startSec, endSec int32, h
 	return nil
 }
 
+// RealtimeKey identifies a stored realtime counter entry.
+type RealtimeKey struct {
+	ObjHash int32
+	TimeSec int32
+}
+
+// Keys returns the keys of all stored entries ordered by time, then objHash.
+func (r *RealtimeCounterData) Keys() ([]RealtimeKey, error) {
+	r.mu.Lock()
+	var keys []RealtimeKey
+	err := r.index.Read(func(key []byte, _ []byte) {
+		if len(key) != 8 {
+			return
+		}
+		keys = append(keys, RealtimeKey{
+			ObjHash: int32(binary.BigEndian.Uint32(key[0:4])),
+			TimeSec: int32(binary.BigEndian.Uint32(key[4:8])),
+		})
+	})
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].TimeSec != keys[j].TimeSec {
+			return keys[i].TimeSec < keys[j].TimeSec
+		}
+		return keys[i].ObjHash < keys[j].ObjHash
+	})
+	return keys, nil
+}
+
 func (r *RealtimeCounterData) Flush() error {
 	return r.data.Flush()
 }
@@ -2,10 +2,12 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,6 +22,18 @@ import (
 //  2. XLog directory (mgr_purge_xlog_keep_days, default 30)
 //  3. Summary directory (mgr_purge_sum_data_days, default 60)
 //  4. Entire date directory (mgr_purge_counter_keep_days, default 70)
+//
+// With mgr_purge_realtime_counter_downsample_days, realtime counters past
+// mgr_purge_realtime_counter_keep_days are reduced to one-minute resolution
+// and deleted only after the additional days.
+//
+// With mgr_purge_daily_text_days_by_div, the divs of a day's daily text that
+// expire before mgr_purge_daily_text_days are removed by compacting the day.
+//
+// With mgr_purge_profile_error_keep_days or mgr_purge_profile_slow_keep_days
+// above mgr_purge_profile_keep_days, the profiles of a day past
+// mgr_purge_profile_keep_days are compacted to those of errored or slow
+// transactions and deleted once neither keeps them.
 type DataPurgeScheduler struct {
 	baseDir string
 
@@ -28,7 +42,16 @@ type DataPurgeScheduler struct {
 	sumKeepDays             int
 	counterKeepDays         int
 	realtimeCounterKeepDays int
+	realtimeDownsampleDays  int
+	downsample              func(dir string) (bool, error)
 	dailyTextKeepDays       int
+	dailyTextDivKeepDays    map[string]int
+	compactText             func(dir string, drop []string) (reclaimed int64, done bool, err error)
+	closers                 []DayCloser
+	profileErrorKeepDays    int
+	profileSlowKeepDays     int
+	compactProfile          func(date string, errors, slow bool) (reclaimed int64, done bool, err error)
+	profileClosers          []DayCloser
 	diskUsagePct            int
 }
 
@@ -46,6 +69,43 @@ func NewDataPurgeScheduler(baseDir string, profileKeepDays, xlogKeepDays, sumKee
 	}
 }
 
+// SetRealtimeDownsample makes the realtime counters of a day past
+// realtimeCounterKeepDays be reduced by fn, given the day's counter
+// directory, and deleted only days later.
+func (s *DataPurgeScheduler) SetRealtimeDownsample(days int, fn func(dir string) (bool, error)) {
+	s.realtimeDownsampleDays = days
+	s.downsample = fn
+}
+
+// SetDailyTextCompact makes the daily text of a day be compacted by fn,
+// given the day's text directory and the divs to remove, once divs expire
+// per divKeepDays. closers are asked to close the day first.
+func (s *DataPurgeScheduler) SetDailyTextCompact(divKeepDays map[string]int, fn func(dir string, drop []string) (int64, bool, error), closers ...DayCloser) {
+	s.dailyTextDivKeepDays = divKeepDays
+	s.compactText = fn
+	s.closers = closers
+}
+
+// SetProfileOutcomeRetention keeps the profiles of errored transactions for
+// errorDays and of slow ones for slowDays when these exceed profileKeepDays.
+// fn compacts the profiles of a day to the selected outcomes; closers are
+// asked to close the day first.
+func (s *DataPurgeScheduler) SetProfileOutcomeRetention(errorDays, slowDays int, fn func(date string, errors, slow bool) (int64, bool, error), closers ...DayCloser) {
+	s.profileErrorKeepDays = errorDays
+	s.profileSlowKeepDays = slowDays
+	s.compactProfile = fn
+	s.profileClosers = closers
+}
+
+// profileDeleteDays returns the days after which the profiles of a day are
+// deleted whatever their outcome.
+func (s *DataPurgeScheduler) profileDeleteDays() int {
+	if s.compactProfile == nil || s.profileKeepDays <= 0 {
+		return s.profileKeepDays
+	}
+	return max(s.profileKeepDays, s.profileErrorKeepDays, s.profileSlowKeepDays)
+}
+
 // Start begins the periodic purge goroutine (checks every minute, matching Java).
 func (s *DataPurgeScheduler) Start(ctx context.Context) {
 	// Run once immediately
@@ -68,11 +128,18 @@ func (s *DataPurgeScheduler) Start(ctx context.Context) {
 func (s *DataPurgeScheduler) purgeAll() {
 	today := time.Now().Format("20060102")
 
-	s.purgeByType(today, s.profileKeepDays, "profile", s.deleteProfile)
+	s.purgeByType(today, s.profileDeleteDays(), "profile", s.deleteProfile)
+	s.compactProfiles(today)
 	s.purgeByType(today, s.xlogKeepDays, "xlog", s.deleteXLog)
 	s.purgeByType(today, s.sumKeepDays, "summary", s.deleteSummary)
-	s.purgeByType(today, s.realtimeCounterKeepDays, "realtime_counter", s.deleteRealtimeCounter)
+	if s.realtimeCounterKeepDays > 0 && s.realtimeDownsampleDays > 0 && s.downsample != nil {
+		s.purgeByType(today, s.realtimeCounterKeepDays+s.realtimeDownsampleDays, "realtime_counter", s.deleteRealtimeCounter)
+		s.purgeByType(today, s.realtimeCounterKeepDays, "realtime_counter_1m", s.downsampleRealtimeCounter)
+	} else {
+		s.purgeByType(today, s.realtimeCounterKeepDays, "realtime_counter", s.deleteRealtimeCounter)
+	}
 	s.purgeByType(today, s.dailyTextKeepDays, "daily_text", s.deleteDailyText)
+	s.compactDailyText(today)
 	s.purgeByType(today, s.counterKeepDays, "all", s.deleteAll)
 
 	// Disk usage based purge: delete oldest date directories until under threshold
@@ -119,72 +186,153 @@ func (s *DataPurgeScheduler) listDateDirs() []string {
 // deleteProfile removes profile-specific files from {date}/xlog/ directory.
 // Returns true if any files were deleted.
 func (s *DataPurgeScheduler) deleteProfile(date string) bool {
-	xlogDir := filepath.Join(s.baseDir, date, "xlog")
-	if _, err := os.Stat(xlogDir); os.IsNotExist(err) {
-		return false
-	}
-
-	deleted := false
-	profileFiles := []string{
-		"xlog_prof.data",
-		"xlog_prof.hfile",
-		"xlog_prof.kfile",
-	}
-	for _, f := range profileFiles {
-		path := filepath.Join(xlogDir, f)
-		if err := os.Remove(path); err == nil {
-			deleted = true
-		}
-	}
-	return deleted
+	return deleteProfileFiles(s.baseDir, date)
 }
 
 // deleteXLog removes the entire {date}/xlog/ directory.
 func (s *DataPurgeScheduler) deleteXLog(date string) bool {
-	dir := filepath.Join(s.baseDir, date, "xlog")
-	return removeIfExists(dir)
+	return deleteXLogDir(s.baseDir, date)
 }
 
 // deleteSummary removes the entire {date}/summary/ directory.
 func (s *DataPurgeScheduler) deleteSummary(date string) bool {
-	dir := filepath.Join(s.baseDir, date, "summary")
-	return removeIfExists(dir)
+	return deleteSummaryDir(s.baseDir, date)
 }
 
 // deleteAll removes the entire {date}/ directory.
 func (s *DataPurgeScheduler) deleteAll(date string) bool {
-	dir := filepath.Join(s.baseDir, date)
-	return removeIfExists(dir)
+	return deleteDateDir(s.baseDir, date)
 }
 
 // deleteRealtimeCounter removes realtime counter files from {date}/counter/ directory.
 func (s *DataPurgeScheduler) deleteRealtimeCounter(date string) bool {
-	counterDir := filepath.Join(s.baseDir, date, "counter")
-	if _, err := os.Stat(counterDir); os.IsNotExist(err) {
-		return false
-	}
+	return deleteRealtimeCounterFiles(s.baseDir, date)
+}
 
-	entries, err := os.ReadDir(counterDir)
+// downsampleRealtimeCounter reduces the realtime counters of {date}/counter/
+// to one entry per object and minute.
+func (s *DataPurgeScheduler) downsampleRealtimeCounter(date string) bool {
+	done, err := s.downsample(filepath.Join(s.baseDir, date, "counter"))
 	if err != nil {
-		return false
+		slog.Error("DataPurge: downsample error", "date", date, "error", err)
 	}
+	return done
+}
 
-	deleted := false
-	for _, entry := range entries {
-		if strings.HasPrefix(entry.Name(), "real") {
-			path := filepath.Join(counterDir, entry.Name())
-			if err := os.Remove(path); err == nil {
-				deleted = true
+// deleteDailyText removes the {date}/text/ directory.
+func (s *DataPurgeScheduler) deleteDailyText(date string) bool {
+	return deleteDailyTextDir(s.baseDir, date)
+}
+
+// compactDailyText removes the expired divs from the daily text of each day
+// that is not yet deleted as a whole.
+func (s *DataPurgeScheduler) compactDailyText(today string) {
+	if len(s.dailyTextDivKeepDays) == 0 || s.compactText == nil {
+		return
+	}
+	cutoffs := make(map[string]string, len(s.dailyTextDivKeepDays))
+	for div, days := range s.dailyTextDivKeepDays {
+		if days > 0 {
+			cutoffs[div] = time.Now().AddDate(0, 0, -days).Format("20060102")
+		}
+	}
+
+	var reclaimed int64
+	for _, date := range s.listDateDirs() {
+		if date == today {
+			break
+		}
+		var drop []string
+		for div, cutoff := range cutoffs {
+			if date < cutoff {
+				drop = append(drop, div)
 			}
 		}
+		if len(drop) == 0 {
+			break // dates are sorted; remaining are all newer
+		}
+		dir := filepath.Join(s.baseDir, date, "text")
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		sort.Strings(drop)
+		for _, c := range s.closers {
+			c.CloseDay(date)
+		}
+		n, done, err := s.compactText(dir, drop)
+		if err != nil {
+			slog.Error("DataPurge: daily text compaction error", "date", date, "error", err)
+			continue
+		}
+		if done {
+			reclaimed += n
+			slog.Info("DataPurge: compacted daily text", "date", date, "removedDivs", drop, "reclaimedBytes", n)
+		}
+	}
+	if reclaimed > 0 {
+		slog.Info("DataPurge: daily text compaction reclaimed space", "bytes", reclaimed)
 	}
-	return deleted
 }
 
-// deleteDailyText removes the {date}/text/ directory.
-func (s *DataPurgeScheduler) deleteDailyText(date string) bool {
-	dir := filepath.Join(s.baseDir, date, "text")
-	return removeIfExists(dir)
+// compactProfiles reduces the profiles of each day past profileKeepDays to
+// those of the outcomes whose retention has not ended yet.
+func (s *DataPurgeScheduler) compactProfiles(today string) {
+	if s.compactProfile == nil || s.profileKeepDays <= 0 {
+		return
+	}
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, -s.profileKeepDays).Format("20060102")
+	errorCutoff := now.AddDate(0, 0, -s.profileErrorKeepDays).Format("20060102")
+	slowCutoff := now.AddDate(0, 0, -s.profileSlowKeepDays).Format("20060102")
+
+	var reclaimed int64
+	for _, date := range s.listDateDirs() {
+		if date >= cutoff || date == today {
+			break // dates are sorted; remaining are all newer
+		}
+		keepErrors := s.profileErrorKeepDays > s.profileKeepDays && date >= errorCutoff
+		keepSlow := s.profileSlowKeepDays > s.profileKeepDays && date >= slowCutoff
+		if !keepErrors && !keepSlow {
+			continue // deleted by the profile purge
+		}
+		if _, err := os.Stat(filepath.Join(s.baseDir, date, "xlog", "xlog_prof.kfile")); err != nil {
+			continue
+		}
+		for _, c := range s.profileClosers {
+			c.CloseDay(date)
+		}
+		n, done, err := s.compactProfile(date, keepErrors, keepSlow)
+		if err != nil {
+			slog.Error("DataPurge: profile compaction error", "date", date, "error", err)
+			continue
+		}
+		if done {
+			reclaimed += n
+			slog.Info("DataPurge: compacted profiles", "date", date, "keepErrors", keepErrors, "keepSlow", keepSlow, "reclaimedBytes", n)
+		}
+	}
+	if reclaimed > 0 {
+		slog.Info("DataPurge: profile compaction reclaimed space", "bytes", reclaimed)
+	}
+}
+
+// ParseDivKeepDays parses comma-separated div:days pairs, e.g. "ua:7,apicall:30".
+func ParseDivKeepDays(spec string) (map[string]int, error) {
+	result := make(map[string]int)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		div, days, ok := strings.Cut(part, ":")
+		n, err := strconv.Atoi(strings.TrimSpace(days))
+		div = strings.TrimSpace(div)
+		if !ok || err != nil || div == "" || n < 0 {
+			return nil, fmt.Errorf("bad div:days pair %q", part)
+		}
+		result[div] = n
+	}
+	return result, nil
 }
 
 // purgeDiskUsage deletes oldest date directories when disk usage exceeds threshold.
@@ -209,6 +357,74 @@ func (s *DataPurgeScheduler) purgeDiskUsage(today string) {
 	}
 }
 
+// deleteProfileFiles removes profile-specific files from {date}/xlog/ directory.
+// Returns true if any files were deleted.
+func deleteProfileFiles(baseDir, date string) bool {
+	xlogDir := filepath.Join(baseDir, date, "xlog")
+	if _, err := os.Stat(xlogDir); os.IsNotExist(err) {
+		return false
+	}
+
+	deleted := false
+	profileFiles := []string{
+		"xlog_prof.data",
+		"xlog_prof.hfile",
+		"xlog_prof.kfile",
+		"xlog_prof.kept",
+	}
+	for _, f := range profileFiles {
+		path := filepath.Join(xlogDir, f)
+		if err := os.Remove(path); err == nil {
+			deleted = true
+		}
+	}
+	return deleted
+}
+
+// deleteXLogDir removes the entire {date}/xlog/ directory.
+func deleteXLogDir(baseDir, date string) bool {
+	return removeIfExists(filepath.Join(baseDir, date, "xlog"))
+}
+
+// deleteSummaryDir removes the entire {date}/summary/ directory.
+func deleteSummaryDir(baseDir, date string) bool {
+	return removeIfExists(filepath.Join(baseDir, date, "summary"))
+}
+
+// deleteDateDir removes the entire {date}/ directory.
+func deleteDateDir(baseDir, date string) bool {
+	return removeIfExists(filepath.Join(baseDir, date))
+}
+
+// deleteRealtimeCounterFiles removes realtime counter files from {date}/counter/ directory.
+func deleteRealtimeCounterFiles(baseDir, date string) bool {
+	counterDir := filepath.Join(baseDir, date, "counter")
+	if _, err := os.Stat(counterDir); os.IsNotExist(err) {
+		return false
+	}
+
+	entries, err := os.ReadDir(counterDir)
+	if err != nil {
+		return false
+	}
+
+	deleted := false
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "real") {
+			path := filepath.Join(counterDir, entry.Name())
+			if err := os.Remove(path); err == nil {
+				deleted = true
+			}
+		}
+	}
+	return deleted
+}
+
+// deleteDailyTextDir removes the {date}/text/ directory.
+func deleteDailyTextDir(baseDir, date string) bool {
+	return removeIfExists(filepath.Join(baseDir, date, "text"))
+}
+
 // removeIfExists removes a file or directory if it exists.
 func removeIfExists(path string) bool {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
@@ -174,3 +174,131 @@ func TestDataPurgeScheduler_GraduatedPurge(t *testing.T) {
 		t.Error("summary data should remain (20 < 60 days)")
 	}
 }
+
+func TestDataPurgeScheduler_RealtimeDownsample(t *testing.T) {
+	dir := t.TempDir()
+
+	recent := time.Now().AddDate(0, 0, -5).Format("20060102")
+	old := time.Now().AddDate(0, 0, -15).Format("20060102")
+	expired := time.Now().AddDate(0, 0, -25).Format("20060102")
+	for _, date := range []string{recent, old, expired} {
+		counterDir := filepath.Join(dir, date, "counter")
+		os.MkdirAll(counterDir, 0755)
+		os.WriteFile(filepath.Join(counterDir, "real.data"), []byte("rt"), 0644)
+		os.WriteFile(filepath.Join(counterDir, "5m.data"), []byte("5m"), 0644)
+	}
+
+	// Realtime counters at full resolution for 10 days, then 1-minute for 10 more.
+	scheduler := NewDataPurgeScheduler(dir, 0, 0, 0, 0, 10, 0, 0)
+	var downsampled []string
+	scheduler.SetRealtimeDownsample(10, func(counterDir string) (bool, error) {
+		if _, err := os.Stat(filepath.Join(counterDir, "real.data")); err != nil {
+			return false, nil
+		}
+		downsampled = append(downsampled, filepath.Base(filepath.Dir(counterDir)))
+		return true, nil
+	})
+	scheduler.purgeAll()
+
+	if len(downsampled) != 1 || downsampled[0] != old {
+		t.Errorf("downsampled %v, want [%s]", downsampled, old)
+	}
+	if _, err := os.Stat(filepath.Join(dir, old, "counter", "real.data")); err != nil {
+		t.Error("downsampled realtime data should remain")
+	}
+	if _, err := os.Stat(filepath.Join(dir, expired, "counter", "real.data")); !os.IsNotExist(err) {
+		t.Error("realtime data past the downsample period should be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, expired, "counter", "5m.data")); err != nil {
+		t.Error("daily counter data should remain")
+	}
+}
+
+func TestDataPurgeScheduler_DailyTextCompact(t *testing.T) {
+	dir := t.TempDir()
+
+	recent := time.Now().AddDate(0, 0, -3).Format("20060102")
+	old := time.Now().AddDate(0, 0, -10).Format("20060102")
+	older := time.Now().AddDate(0, 0, -40).Format("20060102")
+	for _, date := range []string{recent, old, older} {
+		os.MkdirAll(filepath.Join(dir, date, "text"), 0755)
+	}
+
+	divDays, err := ParseDivKeepDays("ua:7, apicall:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler := NewDataPurgeScheduler(dir, 0, 0, 0, 0, 0, 140, 0)
+	compacted := make(map[string][]string)
+	var closed []string
+	scheduler.SetDailyTextCompact(divDays, func(textDir string, drop []string) (int64, bool, error) {
+		compacted[filepath.Base(filepath.Dir(textDir))] = drop
+		return 10, true, nil
+	}, dayCloserFunc(func(date string) { closed = append(closed, date) }))
+	scheduler.purgeAll()
+
+	if len(compacted) != 2 {
+		t.Fatalf("compacted %v, want %s and %s", compacted, old, older)
+	}
+	if got := compacted[old]; len(got) != 1 || got[0] != "ua" {
+		t.Errorf("%s: dropped %v, want [ua]", old, got)
+	}
+	if got := compacted[older]; len(got) != 2 || got[0] != "apicall" || got[1] != "ua" {
+		t.Errorf("%s: dropped %v, want [apicall ua]", older, got)
+	}
+	if len(closed) != 2 {
+		t.Errorf("closed %v, want the compacted days", closed)
+	}
+
+	for _, spec := range []string{"ua", "ua:x", ":3", "ua:-1"} {
+		if _, err := ParseDivKeepDays(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestDataPurgeScheduler_ProfileOutcomeRetention(t *testing.T) {
+	dir := t.TempDir()
+
+	recent := time.Now().AddDate(0, 0, -3).Format("20060102")
+	middle := time.Now().AddDate(0, 0, -10).Format("20060102")
+	old := time.Now().AddDate(0, 0, -20).Format("20060102")
+	expired := time.Now().AddDate(0, 0, -40).Format("20060102")
+	for _, date := range []string{recent, middle, old, expired} {
+		xlogDir := filepath.Join(dir, date, "xlog")
+		os.MkdirAll(xlogDir, 0755)
+		os.WriteFile(filepath.Join(xlogDir, "xlog_prof.kfile"), []byte("k"), 0644)
+		os.WriteFile(filepath.Join(xlogDir, "xlog_prof.data"), []byte("d"), 0644)
+	}
+
+	// Profiles 5 days, errors 30 days, slow transactions 15 days
+	scheduler := NewDataPurgeScheduler(dir, 5, 0, 0, 0, 0, 140, 0)
+	type keep struct{ errors, slow bool }
+	compacted := make(map[string]keep)
+	var closed []string
+	scheduler.SetProfileOutcomeRetention(30, 15, func(date string, errors, slow bool) (int64, bool, error) {
+		compacted[date] = keep{errors, slow}
+		return 1, true, nil
+	}, dayCloserFunc(func(date string) { closed = append(closed, date) }))
+	scheduler.purgeAll()
+
+	if _, err := os.Stat(filepath.Join(dir, expired, "xlog", "xlog_prof.data")); !os.IsNotExist(err) {
+		t.Errorf("%s: profiles should be deleted", expired)
+	}
+	if _, ok := compacted[recent]; ok {
+		t.Errorf("%s: should not be compacted", recent)
+	}
+	if got := compacted[middle]; got != (keep{true, true}) {
+		t.Errorf("%s: kept %+v, want errors and slow", middle, got)
+	}
+	if got := compacted[old]; got != (keep{true, false}) {
+		t.Errorf("%s: kept %+v, want errors only", old, got)
+	}
+	if len(compacted) != 2 || len(closed) != 2 {
+		t.Errorf("compacted %v closed %v, want %s and %s", compacted, closed, middle, old)
+	}
+}
+
+type dayCloserFunc func(date string)
+
+func (f dayCloserFunc) CloseDay(date string) { f(date) }
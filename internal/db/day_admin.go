@@ -0,0 +1,170 @@
+package db
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/io"
+)
+
+// DayOpener is implemented by components that can open a day's containers
+// ahead of a query.
+type DayOpener interface {
+	OpenDay(date string) error
+}
+
+// DayLister is implemented by components that report the dates they hold open.
+type DayLister interface {
+	OpenDays() []string
+}
+
+// DayContainerInfo describes the open containers of one date.
+type DayContainerInfo struct {
+	Date       string
+	Components []string // components holding the date open
+	Files      int      // index files open for the date
+	MemBytes   int64    // bytes of those files held in memory
+}
+
+type dayComponent struct {
+	name   string
+	closer DayCloser
+}
+
+// DayContainerAdmin closes, opens and lists the day containers of the storage
+// components on request, e.g. when investigating file-handle exhaustion.
+// Containers closed here are reopened by the next read or write of the date.
+type DayContainerAdmin struct {
+	baseDir    string
+	components []dayComponent
+	stats      func() []io.FlushStat
+}
+
+// NewDayContainerAdmin creates an admin for the day directories under baseDir.
+func NewDayContainerAdmin(baseDir string) *DayContainerAdmin {
+	return &DayContainerAdmin{
+		baseDir: baseDir,
+		stats:   io.GetFlushController().Stats,
+	}
+}
+
+// Add registers a component under name. It is also opened and listed when it
+// implements DayOpener and DayLister.
+func (a *DayContainerAdmin) Add(name string, c DayCloser) {
+	a.components = append(a.components, dayComponent{name: name, closer: c})
+}
+
+// Close flushes and closes the containers of date and returns the components
+// that had it open. Today is refused since the writers would reopen it at once.
+func (a *DayContainerAdmin) Close(date string) ([]string, error) {
+	if err := checkDate(date); err != nil {
+		return nil, err
+	}
+	if date == time.Now().Format("20060102") {
+		return nil, fmt.Errorf("today is in use")
+	}
+	var closed []string
+	for _, c := range a.components {
+		if containsDate(c.closer, date) {
+			closed = append(closed, c.name)
+		}
+		c.closer.CloseDay(date)
+	}
+	slog.Info("Day containers closed", "date", date, "components", closed)
+	return closed, nil
+}
+
+// Open opens the containers of date in the components implementing DayOpener
+// and returns the components holding it open afterwards.
+func (a *DayContainerAdmin) Open(date string) ([]string, error) {
+	if err := checkDate(date); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(a.baseDir, date)); err != nil {
+		return nil, fmt.Errorf("no data for %s", date)
+	}
+	var opened []string
+	for _, c := range a.components {
+		o, ok := c.closer.(DayOpener)
+		if !ok {
+			continue
+		}
+		if err := o.OpenDay(date); err != nil {
+			return opened, fmt.Errorf("%s: %w", c.name, err)
+		}
+		if containsDate(c.closer, date) {
+			opened = append(opened, c.name)
+		}
+	}
+	slog.Info("Day containers opened", "date", date, "components", opened)
+	return opened, nil
+}
+
+// List returns the open dates, newest first, with the index files and memory
+// of each. Memory counts the in-memory index blocks, not OS page cache.
+func (a *DayContainerAdmin) List() []DayContainerInfo {
+	byDate := make(map[string]*DayContainerInfo)
+	get := func(date string) *DayContainerInfo {
+		info := byDate[date]
+		if info == nil {
+			info = &DayContainerInfo{Date: date}
+			byDate[date] = info
+		}
+		return info
+	}
+	for _, c := range a.components {
+		l, ok := c.closer.(DayLister)
+		if !ok {
+			continue
+		}
+		for _, date := range l.OpenDays() {
+			info := get(date)
+			info.Components = append(info.Components, c.name)
+		}
+	}
+	for _, st := range a.stats() {
+		rel, err := filepath.Rel(a.baseDir, st.File)
+		if err != nil {
+			continue
+		}
+		date, _, ok := strings.Cut(filepath.ToSlash(rel), "/")
+		if !ok || checkDate(date) != nil {
+			continue // not a day file, e.g. the global text index
+		}
+		info := get(date)
+		info.Files++
+		info.MemBytes += int64(st.MemBytes)
+	}
+
+	result := make([]DayContainerInfo, 0, len(byDate))
+	for _, info := range byDate {
+		result = append(result, *info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date > result[j].Date })
+	return result
+}
+
+func checkDate(date string) error {
+	if _, err := time.Parse("20060102", date); err != nil {
+		return fmt.Errorf("invalid date %q", date)
+	}
+	return nil
+}
+
+func containsDate(c DayCloser, date string) bool {
+	l, ok := c.(DayLister)
+	if !ok {
+		return false
+	}
+	for _, d := range l.OpenDays() {
+		if d == date {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/io"
+)
+
+type fakeDayContainers struct {
+	open map[string]bool
+}
+
+func (f *fakeDayContainers) CloseDay(date string) { delete(f.open, date) }
+
+func (f *fakeDayContainers) OpenDay(date string) error {
+	f.open[date] = true
+	return nil
+}
+
+func (f *fakeDayContainers) OpenDays() []string {
+	var dates []string
+	for d := range f.open {
+		dates = append(dates, d)
+	}
+	return dates
+}
+
+func TestDayContainerAdmin(t *testing.T) {
+	base := t.TempDir()
+	os.MkdirAll(filepath.Join(base, "20260101", "xlog"), 0755)
+
+	rd := &fakeDayContainers{open: map[string]bool{"20260101": true, "20260102": true}}
+	wr := &recordingCloser{}
+	a := NewDayContainerAdmin(base)
+	a.stats = func() []io.FlushStat {
+		return []io.FlushStat{
+			{File: filepath.Join(base, "20260101", "xlog", "xlog_tid.hfile"), MemBytes: 1000},
+			{File: filepath.Join(base, "20260101", "xlog", "xlog_tim.tfile"), MemBytes: 500},
+			{File: filepath.Join(base, "text", "text.hfile"), MemBytes: 9999},
+		}
+	}
+	a.Add("xlog-rd", rd)
+	a.Add("xlog-wr", wr)
+
+	infos := a.List()
+	if len(infos) != 2 || infos[0].Date != "20260102" || infos[1].Date != "20260101" {
+		t.Fatalf("unexpected list %+v", infos)
+	}
+	if infos[1].Files != 2 || infos[1].MemBytes != 1500 || len(infos[1].Components) != 1 || infos[1].Components[0] != "xlog-rd" {
+		t.Errorf("20260101 = %+v", infos[1])
+	}
+
+	closed, err := a.Close("20260101")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(closed) != 1 || closed[0] != "xlog-rd" || rd.open["20260101"] {
+		t.Errorf("close: %v, still open %v", closed, rd.open)
+	}
+	if len(wr.closed) != 1 || wr.closed[0] != "20260101" {
+		t.Errorf("writer not asked to close: %v", wr.closed)
+	}
+	if _, err := a.Close(time.Now().Format("20060102")); err == nil {
+		t.Error("expected today to be refused")
+	}
+	if _, err := a.Close("2026-01-01"); err == nil {
+		t.Error("expected an invalid date to be refused")
+	}
+
+	opened, err := a.Open("20260101")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opened) != 1 || !rd.open["20260101"] {
+		t.Errorf("open: %v, open %v", opened, rd.open)
+	}
+	if _, err := a.Open("20250101"); err == nil {
+		t.Error("expected a date without data to be refused")
+	}
+}
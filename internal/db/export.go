@@ -0,0 +1,278 @@
+package db
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// ExportFormat identifies the archives written by ExportDay.
+	ExportFormat = "scouter-export"
+	// ExportVersion is the version of the archive layout.
+	ExportVersion = 1
+	// ExportManifestName is the first entry of an archive.
+	ExportManifestName = "manifest.json"
+)
+
+// exportTargets maps each export type to a filter choosing, among the files
+// of a day directory (paths relative to it, slash-separated), the ones that
+// hold its data. Profiles share the xlog directory.
+var exportTargets = map[string]func(rel string) bool{
+	PurgeTypeXLog: func(rel string) bool {
+		return strings.HasPrefix(rel, "xlog/") && !isProfileFile(rel)
+	},
+	PurgeTypeProfile: isProfileFile,
+	PurgeTypeCounter: func(rel string) bool { return strings.HasPrefix(rel, "counter/") },
+	PurgeTypeText:    func(rel string) bool { return strings.HasPrefix(rel, "text/") },
+	PurgeTypeAlert:   func(rel string) bool { return strings.HasPrefix(rel, "alert/") },
+	PurgeTypeSummary: func(rel string) bool { return strings.HasPrefix(rel, "summary/") },
+	PurgeTypeVisitor: func(rel string) bool {
+		return strings.HasPrefix(rel, "visit/") || strings.HasPrefix(rel, "visit_hourly/")
+	},
+}
+
+func isProfileFile(rel string) bool {
+	return strings.HasPrefix(rel, "xlog/xlog_prof.")
+}
+
+// DefaultExportTypes are exported when no types are given.
+var DefaultExportTypes = []string{PurgeTypeXLog, PurgeTypeProfile, PurgeTypeCounter, PurgeTypeText, PurgeTypeAlert}
+
+// ExportTypeNames returns the accepted export types, sorted.
+func ExportTypeNames() []string {
+	names := make([]string, 0, len(exportTargets))
+	for name := range exportTargets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseExportTypes parses a comma-separated list of export types, or "all".
+func ParseExportTypes(s string) ([]string, error) {
+	var types []string
+	for _, t := range strings.Split(s, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" {
+			types = append(types, t)
+		}
+	}
+	return exportTypes(types)
+}
+
+// exportTypes validates types, expanding "all" and defaulting to
+// DefaultExportTypes.
+func exportTypes(types []string) ([]string, error) {
+	if len(types) == 0 {
+		return DefaultExportTypes, nil
+	}
+	for _, t := range types {
+		if t == PurgeTypeAll {
+			return ExportTypeNames(), nil
+		}
+		if exportTargets[t] == nil {
+			return nil, fmt.Errorf("unknown export type %q (valid: %s,%s)", t, strings.Join(ExportTypeNames(), ","), PurgeTypeAll)
+		}
+	}
+	return types, nil
+}
+
+// ExportOptions selects what ExportDay writes.
+type ExportOptions struct {
+	Types []string // DefaultExportTypes if empty, every type for "all"
+	// PermText adds the permanent text store (00000000/text) that the
+	// service, SQL and other names of the day's data are resolved from.
+	// It holds every day's texts, not only this one's.
+	PermText      bool
+	ServerID      string
+	ServerVersion string
+}
+
+// ExportFile describes one file of an archive.
+type ExportFile struct {
+	Path   string `json:"path"` // relative to the data directory
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ExportManifest is the first entry of an archive.
+type ExportManifest struct {
+	Format        string       `json:"format"`
+	Version       int          `json:"version"`
+	Date          string       `json:"date"`
+	Types         []string     `json:"types"`
+	PermText      bool         `json:"permText"`
+	ServerID      string       `json:"serverId,omitempty"`
+	ServerVersion string       `json:"serverVersion,omitempty"`
+	Created       int64        `json:"created"` // ms
+	Files         []ExportFile `json:"files"`
+}
+
+// Bytes returns the total size of the files of m.
+func (m *ExportManifest) Bytes() int64 {
+	var n int64
+	for _, f := range m.Files {
+		n += f.Size
+	}
+	return n
+}
+
+// ExportDay writes the data of date under baseDir to w as a zstd-compressed
+// tar archive: manifest.json, listing every file with its size and SHA-256,
+// followed by the files at their paths relative to baseDir, so extracting
+// the archive into another server's data directory restores the day.
+//
+// The day must not be written meanwhile: files are read twice, to hash
+// them for the manifest and to archive them.
+func ExportDay(baseDir, date string, opts ExportOptions, w io.Writer) (*ExportManifest, error) {
+	if _, err := time.Parse("20060102", date); err != nil || len(date) != 8 {
+		return nil, fmt.Errorf("invalid date %q: want YYYYMMDD", date)
+	}
+	types, err := exportTypes(opts.Types)
+	if err != nil {
+		return nil, err
+	}
+	dayDir := filepath.Join(baseDir, date)
+	if info, err := os.Stat(dayDir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("no data for %s in %s", date, baseDir)
+	}
+
+	var paths []string
+	err = filepath.WalkDir(dayDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, _ := filepath.Rel(dayDir, path)
+		rel = filepath.ToSlash(rel)
+		for _, typ := range types {
+			if exportTargets[typ](rel) {
+				paths = append(paths, date+"/"+rel)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.PermText {
+		permDir := filepath.Join(baseDir, "00000000", "text")
+		err := filepath.WalkDir(permDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if d.Type().IsRegular() {
+				rel, _ := filepath.Rel(baseDir, path)
+				paths = append(paths, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(paths)
+
+	m := &ExportManifest{
+		Format:        ExportFormat,
+		Version:       ExportVersion,
+		Date:          date,
+		Types:         types,
+		PermText:      opts.PermText,
+		ServerID:      opts.ServerID,
+		ServerVersion: opts.ServerVersion,
+		Created:       time.Now().UnixMilli(),
+		Files:         make([]ExportFile, 0, len(paths)),
+	}
+	for _, p := range paths {
+		f, err := hashFile(filepath.Join(baseDir, filepath.FromSlash(p)))
+		if err != nil {
+			return nil, err
+		}
+		f.Path = p
+		m.Files = append(m.Files, f)
+	}
+
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	tw := tar.NewWriter(zw)
+	manifest, _ := json.MarshalIndent(m, "", "  ")
+	if err := writeTarEntry(tw, ExportManifestName, int64(len(manifest)), time.UnixMilli(m.Created), strings.NewReader(string(manifest))); err != nil {
+		return nil, err
+	}
+	for _, f := range m.Files {
+		if err := archiveFile(tw, baseDir, f); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func hashFile(path string) (ExportFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ExportFile{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return ExportFile{}, err
+	}
+	return ExportFile{Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// archiveFile adds f, as large as when it was hashed.
+func archiveFile(tw *tar.Writer, baseDir string, f ExportFile) error {
+	file, err := os.Open(filepath.Join(baseDir, filepath.FromSlash(f.Path)))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < f.Size {
+		return fmt.Errorf("%s shrank while exporting", f.Path)
+	}
+	return writeTarEntry(tw, f.Path, f.Size, info.ModTime(), file)
+}
+
+func writeTarEntry(tw *tar.Writer, name string, size int64, modTime time.Time, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: modTime,
+		Format:  tar.FormatPAX,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.CopyN(tw, r, size)
+	return err
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestExportDay(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"20260207/xlog/xlog.data":           "xlog",
+		"20260207/xlog/xlog_prof.data":      "profile",
+		"20260207/counter/counter.data":     "counter",
+		"20260207/counter/real_counter.dat": "realtime",
+		"20260207/text/text.data":           "text",
+		"20260207/alert/alert.data":         "alert",
+		"20260207/summary/summary.data":     "summary",
+		"00000000/text/text.data":           "permanent",
+		"20260208/xlog/xlog.data":           "next day",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	m, err := ExportDay(dir, "20260207", ExportOptions{PermText: true, ServerVersion: "test"}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"00000000/text/text.data",
+		"20260207/alert/alert.data",
+		"20260207/counter/counter.data",
+		"20260207/counter/real_counter.dat",
+		"20260207/text/text.data",
+		"20260207/xlog/xlog.data",
+		"20260207/xlog/xlog_prof.data",
+	}
+	if len(m.Files) != len(want) {
+		t.Fatalf("expected files %v, got %+v", want, m.Files)
+	}
+	for i, f := range m.Files {
+		if f.Path != want[i] {
+			t.Errorf("files[%d] = %s, want %s", i, f.Path, want[i])
+		}
+	}
+
+	zr, err := zstd.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != ExportManifestName {
+		t.Fatalf("expected %s first, got %v, %v", ExportManifestName, hdr, err)
+	}
+	var got ExportManifest
+	if err := json.NewDecoder(tr).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Format != ExportFormat || got.Date != "20260207" || got.ServerVersion != "test" || len(got.Files) != len(want) {
+		t.Errorf("unexpected manifest: %+v", got)
+	}
+	for i := 0; ; i++ {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			if i != len(want) {
+				t.Errorf("archive has %d files, want %d", i, len(want))
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(tr)
+		sum := sha256.Sum256(data)
+		f := got.Files[i]
+		if hdr.Name != f.Path || string(data) != files[f.Path] || hex.EncodeToString(sum[:]) != f.SHA256 {
+			t.Errorf("entry %s (%q) does not match manifest %+v", hdr.Name, data, f)
+		}
+	}
+
+	m, err = ExportDay(dir, "20260207", ExportOptions{Types: []string{"all"}}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Files) != 7 || m.Files[0].Path != "20260207/alert/alert.data" {
+		t.Errorf("expected every day file without permanent text, got %+v", m.Files)
+	}
+
+	if _, err := ExportDay(dir, "20260209", ExportOptions{}, io.Discard); err == nil {
+		t.Error("expected error for a missing day")
+	}
+	if _, err := ParseExportTypes("xlog,bogus"); err == nil {
+		t.Error("expected error for unknown type")
+	}
+}
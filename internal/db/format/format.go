@@ -0,0 +1,131 @@
+// Package format records the on-disk format version of the xlog, profile and
+// counter files of each day directory. Writers stamp new day directories with
+// the current version, readers refuse versions they do not understand, and
+// Upgrade migrates older days in place one version at a time, so format
+// changes such as compression or checksums need no manual migration scripts.
+package format
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Step migrates one day directory of a component from version From to
+// From+1. Apply must leave the directory readable as From if it fails.
+type Step struct {
+	From  int
+	Desc  string
+	Apply func(dir string) error
+}
+
+// Component is a family of files sharing a day subdirectory and a version.
+type Component struct {
+	Name    string   // marker file is {Name}.format
+	Dir     string   // subdirectory of the day directory
+	Data    []string // files whose presence means the component has data
+	Current int      // version written by this server
+	Steps   []Step   // upgrades to Current, by From
+}
+
+var (
+	XLog = &Component{
+		Name:    "xlog",
+		Dir:     "xlog",
+		Data:    []string{"xlog.data"},
+		Current: 1,
+	}
+	Profile = &Component{
+		Name:    "profile",
+		Dir:     "xlog",
+		Data:    []string{"xlog_prof.data"},
+		Current: 1,
+	}
+	Counter = &Component{
+		Name:    "counter",
+		Dir:     "counter",
+		Data:    []string{"real.data", "5m.data"},
+		Current: 1,
+	}
+)
+
+// Components lists every versioned component.
+var Components = []*Component{XLog, Profile, Counter}
+
+// legacyVersion is the version of data written before markers existed.
+const legacyVersion = 1
+
+// ErrUnsupported is returned for files written by a newer server.
+var ErrUnsupported = errors.New("unsupported format version")
+
+func (c *Component) marker(dir string) string {
+	return filepath.Join(dir, c.Name+".format")
+}
+
+// hasData reports whether any of the component's data files exist in dir.
+func (c *Component) hasData(dir string) bool {
+	for _, name := range c.Data {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Version returns the format version of the component in dir and whether a
+// marker records it. Unmarked data predates markers and is reported as
+// version 1; a directory without data reports 0.
+func (c *Component) Version(dir string) (int, bool, error) {
+	raw, err := os.ReadFile(c.marker(dir))
+	if os.IsNotExist(err) {
+		if c.hasData(dir) {
+			return legacyVersion, false, nil
+		}
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	if err != nil || v < 1 {
+		return 0, true, fmt.Errorf("%s: bad format marker %q", c.marker(dir), strings.TrimSpace(string(raw)))
+	}
+	return v, true, nil
+}
+
+// Check returns an error if dir holds files of the component in a version
+// other than Current, which this server cannot read or append to.
+func (c *Component) Check(dir string) error {
+	v, _, err := c.Version(dir)
+	if err != nil {
+		return err
+	}
+	if v == 0 || v == c.Current {
+		return nil
+	}
+	if v > c.Current {
+		return fmt.Errorf("%s: %s version %d, this server supports %d: %w", dir, c.Name, v, c.Current, ErrUnsupported)
+	}
+	return fmt.Errorf("%s: %s version %d needs upgrading to %d, run scouter-server upgrade: %w", dir, c.Name, v, c.Current, ErrUnsupported)
+}
+
+// Stamp prepares dir for writing: a directory without data is marked with
+// Current, existing data is checked. Unmarked legacy data is left for
+// Upgrade to mark.
+func (c *Component) Stamp(dir string) error {
+	v, _, err := c.Version(dir)
+	if err != nil {
+		return err
+	}
+	if v == 0 {
+		return c.write(dir, c.Current)
+	}
+	return c.Check(dir)
+}
+
+func (c *Component) write(dir string, v int) error {
+	return os.WriteFile(c.marker(dir), []byte(strconv.Itoa(v)+"\n"), 0644)
+}
@@ -0,0 +1,110 @@
+package format
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStampAndCheck(t *testing.T) {
+	dir := t.TempDir()
+	if v, marked, err := XLog.Version(dir); v != 0 || marked || err != nil {
+		t.Fatalf("empty dir: v=%d marked=%v err=%v", v, marked, err)
+	}
+	if err := XLog.Stamp(dir); err != nil {
+		t.Fatal(err)
+	}
+	if v, marked, _ := XLog.Version(dir); v != XLog.Current || !marked {
+		t.Fatalf("stamped dir: v=%d marked=%v", v, marked)
+	}
+	if err := XLog.Check(dir); err != nil {
+		t.Fatal(err)
+	}
+	// The profile marker of the shared xlog directory is independent.
+	if v, _, _ := Profile.Version(dir); v != 0 {
+		t.Errorf("profile version %d, want 0", v)
+	}
+
+	os.WriteFile(filepath.Join(dir, "xlog.format"), []byte("99\n"), 0644)
+	if err := XLog.Check(dir); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("newer version: err=%v", err)
+	}
+	if err := XLog.Stamp(dir); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("stamp over newer version: err=%v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "xlog.format"), []byte("x"), 0644)
+	if err := XLog.Check(dir); err == nil {
+		t.Error("bad marker accepted")
+	}
+}
+
+func TestLegacyData(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "5m.data"), []byte{1}, 0644)
+	if v, marked, _ := Counter.Version(dir); v != legacyVersion || marked {
+		t.Fatalf("legacy dir: v=%d marked=%v", v, marked)
+	}
+	if err := Counter.Stamp(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "counter.format")); !os.IsNotExist(err) {
+		t.Error("Stamp marked legacy data")
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	base := t.TempDir()
+	mkday := func(date string) string {
+		dir := filepath.Join(base, date, "counter")
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "real.data"), []byte{1}, 0644)
+		return dir
+	}
+	legacy := mkday("20260101")
+	current := mkday("20260102")
+	Counter.write(current, Counter.Current)
+	skipped := mkday("20260103")
+
+	results, err := Upgrade(base, map[string]bool{"20260103": true}, true)
+	if err != nil || len(results) != 1 || !results[0].Marked || results[0].Date != "20260101" {
+		t.Fatalf("dry run: %+v err=%v", results, err)
+	}
+	if _, err := os.Stat(Counter.marker(legacy)); !os.IsNotExist(err) {
+		t.Fatal("dry run wrote a marker")
+	}
+
+	// A future version 2 with one step.
+	saved := *Counter
+	t.Cleanup(func() { *Counter = saved })
+	var applied []string
+	Counter.Current = 2
+	Counter.Steps = []Step{{From: 1, Desc: "add checksums", Apply: func(dir string) error {
+		applied = append(applied, dir)
+		return nil
+	}}}
+
+	results, err = Upgrade(base, map[string]bool{"20260103": true}, false)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("upgrade: %+v err=%v", results, err)
+	}
+	for _, r := range results {
+		if r.Err != nil || r.From != 1 || r.To != 2 || len(r.Steps) != 1 {
+			t.Errorf("result %+v", r)
+		}
+	}
+	if len(applied) != 2 {
+		t.Errorf("step applied to %v", applied)
+	}
+	for _, dir := range []string{legacy, current} {
+		if v, marked, _ := Counter.Version(dir); v != 2 || !marked {
+			t.Errorf("%s: v=%d marked=%v", dir, v, marked)
+		}
+	}
+	if v, marked, _ := Counter.Version(skipped); v != 1 || marked {
+		t.Errorf("skipped day touched: v=%d marked=%v", v, marked)
+	}
+	if err := Counter.Check(skipped); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("old version readable: err=%v", err)
+	}
+}
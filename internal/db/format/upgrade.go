@@ -0,0 +1,98 @@
+package format
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/zbum/scouter-server-go/internal/db"
+)
+
+// Result describes what Upgrade did, or would do, to one component of a day.
+type Result struct {
+	Date      string
+	Component string
+	From      int
+	To        int
+	Marked    bool     // legacy data without a marker was marked
+	Steps     []string // descriptions of the applied steps
+	Err       error
+}
+
+// Upgrade brings every day directory under baseDir whose date is not in skip
+// to the current format: unmarked legacy data is marked and pending steps are
+// applied in order, the marker advancing after each one. With dryRun nothing
+// is written. Days already current are omitted from the results.
+func Upgrade(baseDir string, skip map[string]bool, dryRun bool) ([]Result, error) {
+	dates, err := db.GetDateDirs(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	for _, date := range dates {
+		if skip[date] {
+			continue
+		}
+		for _, c := range Components {
+			r, ok := c.upgrade(filepath.Join(baseDir, date, c.Dir), dryRun)
+			if ok {
+				r.Date = date
+				results = append(results, r)
+			}
+		}
+	}
+	return results, nil
+}
+
+// upgrade migrates one directory; ok is false if there was nothing to do.
+func (c *Component) upgrade(dir string, dryRun bool) (r Result, ok bool) {
+	r.Component = c.Name
+	v, marked, err := c.Version(dir)
+	r.From, r.To = v, v
+	if err != nil {
+		r.Err = err
+		return r, true
+	}
+	if v == 0 || (marked && v == c.Current) {
+		return r, false
+	}
+	if v > c.Current {
+		r.Err = fmt.Errorf("version %d is newer than %d: %w", v, c.Current, ErrUnsupported)
+		return r, true
+	}
+	for v < c.Current {
+		step := c.step(v)
+		if step == nil {
+			r.Err = fmt.Errorf("no upgrade from version %d", v)
+			return r, true
+		}
+		if !dryRun {
+			if err := step.Apply(dir); err != nil {
+				r.Err = fmt.Errorf("%s: %w", step.Desc, err)
+				return r, true
+			}
+			if err := c.write(dir, v+1); err != nil {
+				r.Err = err
+				return r, true
+			}
+		}
+		r.Steps = append(r.Steps, step.Desc)
+		v++
+		r.To = v
+	}
+	if !marked && len(r.Steps) == 0 {
+		r.Marked = true
+		if !dryRun {
+			r.Err = c.write(dir, v)
+		}
+	}
+	return r, true
+}
+
+func (c *Component) step(from int) *Step {
+	for i := range c.Steps {
+		if c.Steps[i].From == from {
+			return &c.Steps[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+// Package heatmap keeps per-5-minute elapsed-time histograms of service
+// transactions per object type, so response-time heatmaps over days can be
+// drawn without scanning stored xlogs.
+//
+// Each day is stored in {date}/heatmap/heatmap.data:
+//
+//	[4]byte "SCHM", byte version
+//	decimal objType count
+//	per objType: text objType, decimal cell count,
+//	    per non-empty cell: int16 slot, byte bucket, decimal count, decimal errors
+package heatmap
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+)
+
+const (
+	// SlotMs is the width of a time slot.
+	SlotMs = 5 * 60 * 1000
+	// SlotsPerDay is the number of slots in a day.
+	SlotsPerDay = 24 * 60 * 60 * 1000 / SlotMs
+
+	fileVersion = 1
+)
+
+var fileMagic = []byte("SCHM")
+
+// Bounds are the inclusive upper bounds in ms of the elapsed-time buckets.
+// Slower transactions fall into a final open-ended bucket.
+var Bounds = []int32{
+	10, 20, 30, 50, 75, 100, 150, 200, 300, 400, 500, 750,
+	1000, 1500, 2000, 3000, 4000, 5000, 7500, 10000, 15000, 20000, 30000, 60000,
+}
+
+// NumBuckets is the number of elapsed-time buckets.
+var NumBuckets = len(Bounds) + 1
+
+// Bucket returns the bucket index of an elapsed time.
+func Bucket(elapsed int32) int {
+	return sort.Search(len(Bounds), func(i int) bool { return elapsed <= Bounds[i] })
+}
+
+// Cell counts the transactions of one slot and bucket.
+type Cell struct {
+	Count  int64
+	Errors int64
+}
+
+// Grid is the heatmap of one object type for one day.
+type Grid [SlotsPerDay][]Cell
+
+func newGrid() *Grid {
+	return &Grid{}
+}
+
+func (g *Grid) add(slot, bucket int, count, errs int64) {
+	if g[slot] == nil {
+		g[slot] = make([]Cell, NumBuckets)
+	}
+	g[slot][bucket].Count += count
+	g[slot][bucket].Errors += errs
+}
+
+// Merge adds the cells of o to g.
+func (g *Grid) Merge(o *Grid) {
+	for slot, cells := range o {
+		for b, c := range cells {
+			if c.Count != 0 || c.Errors != 0 {
+				g.add(slot, b, c.Count, c.Errors)
+			}
+		}
+	}
+}
+
+type day struct {
+	grids map[string]*Grid // by objType
+	dirty bool
+}
+
+// DB records transactions into per-day heatmaps and persists them.
+type DB struct {
+	mu      sync.Mutex
+	baseDir string
+	days    map[string]*day // today, and other days touched by late data until flushed
+}
+
+// NewDB creates a heatmap DB storing under baseDir.
+func NewDB(baseDir string) *DB {
+	return &DB{baseDir: baseDir, days: make(map[string]*day)}
+}
+
+// Add records one transaction that ended at endTime (epoch ms).
+func (db *DB) Add(objType string, endTime int64, elapsed int32, isError bool) {
+	t := time.UnixMilli(endTime)
+	date := t.Format("20060102")
+	slot := slotOf(t)
+	var errs int64
+	if isError {
+		errs = 1
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	d := db.dayLocked(date)
+	g := d.grids[objType]
+	if g == nil {
+		g = newGrid()
+		d.grids[objType] = g
+	}
+	g.add(slot, Bucket(elapsed), 1, errs)
+	d.dirty = true
+}
+
+// dayLocked returns the in-memory day, loading it from disk on first use so
+// a restart continues the stored counts.
+func (db *DB) dayLocked(date string) *day {
+	d := db.days[date]
+	if d == nil {
+		grids, err := readFile(db.path(date))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("Heatmap: load failed, starting empty", "date", date, "error", err)
+		}
+		if grids == nil {
+			grids = make(map[string]*Grid)
+		}
+		d = &day{grids: grids}
+		db.days[date] = d
+	}
+	return d
+}
+
+// Read returns the heatmaps of date by objType.
+func (db *DB) Read(date string) (map[string]*Grid, error) {
+	db.mu.Lock()
+	if d := db.days[date]; d != nil {
+		result := make(map[string]*Grid, len(d.grids))
+		for t, g := range d.grids {
+			c := newGrid()
+			c.Merge(g)
+			result[t] = c
+		}
+		db.mu.Unlock()
+		return result, nil
+	}
+	db.mu.Unlock()
+
+	grids, err := readFile(db.path(date))
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]*Grid{}, nil
+	}
+	return grids, err
+}
+
+// Flush writes changed days to disk and releases days other than today.
+func (db *DB) Flush() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	today := time.Now().Format("20060102")
+	for date, d := range db.days {
+		if d.dirty {
+			if err := writeFile(db.path(date), d.grids); err != nil {
+				slog.Error("Heatmap: flush failed", "date", date, "error", err)
+				continue
+			}
+			d.dirty = false
+		}
+		if date != today {
+			delete(db.days, date)
+		}
+	}
+}
+
+// StartFlusher flushes every 10 seconds until done is closed.
+func (db *DB) StartFlusher(done <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				db.Flush()
+				return
+			case <-ticker.C:
+				db.Flush()
+			}
+		}
+	}()
+}
+
+func (db *DB) path(date string) string {
+	return filepath.Join(db.baseDir, date, "heatmap", "heatmap.data")
+}
+
+// slotOf returns the slot of t within its local day.
+func slotOf(t time.Time) int {
+	ms := (t.Hour()*3600+t.Minute()*60+t.Second())*1000 + t.Nanosecond()/1e6
+	return ms / SlotMs
+}
+
+func writeFile(path string, grids map[string]*Grid) error {
+	o := protocol.NewDataOutputX()
+	o.Write(fileMagic)
+	o.WriteByte(fileVersion)
+	o.WriteDecimal(int64(len(grids)))
+	for objType, g := range grids {
+		o.WriteText(objType)
+		cells := protocol.NewDataOutputX()
+		n := 0
+		for slot, row := range g {
+			for b, c := range row {
+				if c.Count == 0 && c.Errors == 0 {
+					continue
+				}
+				cells.WriteInt16(int16(slot))
+				cells.WriteByte(byte(b))
+				cells.WriteDecimal(c.Count)
+				cells.WriteDecimal(c.Errors)
+				n++
+			}
+		}
+		o.WriteDecimal(int64(n))
+		o.Write(cells.ToByteArray())
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, o.ToByteArray(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readFile(path string) (map[string]*Grid, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(fileMagic)+1 || string(data[:len(fileMagic)]) != string(fileMagic) {
+		return nil, fmt.Errorf("%s: not a heatmap file", path)
+	}
+	if v := data[len(fileMagic)]; v != fileVersion {
+		return nil, fmt.Errorf("%s: unsupported heatmap version %d", path, v)
+	}
+	in := protocol.NewDataInputX(data[len(fileMagic)+1:])
+	types, err := in.ReadDecimal()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	grids := make(map[string]*Grid, types)
+	for ; types > 0; types-- {
+		objType, err := in.ReadText()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		n, err := in.ReadDecimal()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		g := newGrid()
+		for ; n > 0; n-- {
+			slot, err1 := in.ReadInt16()
+			b, err2 := in.ReadByte()
+			count, err3 := in.ReadDecimal()
+			errs, err4 := in.ReadDecimal()
+			if err := errors.Join(err1, err2, err3, err4); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			if int(slot) < 0 || int(slot) >= SlotsPerDay || int(b) >= NumBuckets {
+				return nil, fmt.Errorf("%s: cell out of range (slot %d, bucket %d)", path, slot, b)
+			}
+			g.add(int(slot), int(b), count, errs)
+		}
+		grids[objType] = g
+	}
+	return grids, nil
+}
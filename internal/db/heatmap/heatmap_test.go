@@ -0,0 +1,63 @@
+package heatmap
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	cases := map[int32]int{0: 0, 10: 0, 11: 1, 100: 5, 101: 6, 60000: len(Bounds) - 1, 60001: len(Bounds)}
+	for elapsed, want := range cases {
+		if got := Bucket(elapsed); got != want {
+			t.Errorf("Bucket(%d) = %d, want %d", elapsed, got, want)
+		}
+	}
+}
+
+func TestDB_AddFlushRead(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDB(dir)
+
+	yesterday := time.Now().AddDate(0, 0, -1)
+	base := time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 10, 3, 0, 0, time.Local)
+	db.Add("tomcat", base.UnixMilli(), 5, false)
+	db.Add("tomcat", base.UnixMilli(), 8, true)
+	db.Add("tomcat", base.Add(5*time.Minute).UnixMilli(), 70000, false)
+	db.Add("nodejs", base.UnixMilli(), 120, false)
+	db.Flush()
+
+	date := base.Format("20060102")
+	if len(db.days) != 0 {
+		t.Fatalf("past day kept in memory after flush")
+	}
+	grids, err := NewDB(dir).Read(date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slot := (10*60 + 3) * 60 * 1000 / SlotMs
+	tomcat := grids["tomcat"]
+	if tomcat == nil || tomcat[slot][0] != (Cell{Count: 2, Errors: 1}) {
+		t.Fatalf("tomcat slot %d = %+v", slot, tomcat[slot])
+	}
+	if tomcat[slot+1][len(Bounds)].Count != 1 {
+		t.Errorf("slow transaction not in open bucket: %+v", tomcat[slot+1])
+	}
+	if grids["nodejs"][slot][Bucket(120)].Count != 1 {
+		t.Errorf("nodejs = %+v", grids["nodejs"][slot])
+	}
+
+	// Late data for a flushed day continues the stored counts.
+	db.Add("tomcat", base.UnixMilli(), 5, false)
+	db.Flush()
+	grids, _ = db.Read(date)
+	if grids["tomcat"][slot][0].Count != 3 {
+		t.Errorf("count after reload = %d, want 3", grids["tomcat"][slot][0].Count)
+	}
+}
+
+func TestDB_ReadMissingDay(t *testing.T) {
+	grids, err := NewDB(t.TempDir()).Read("20200101")
+	if err != nil || len(grids) != 0 {
+		t.Errorf("Read = %v, %v", grids, err)
+	}
+}
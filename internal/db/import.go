@@ -0,0 +1,315 @@
+package db
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/zbum/scouter-server-go/internal/config"
+	dbio "github.com/zbum/scouter-server-go/internal/db/io"
+	"github.com/zbum/scouter-server-go/internal/db/text"
+)
+
+const permTextDir = "00000000/text"
+
+// ImportOptions selects how ImportArchive treats the data directory.
+type ImportOptions struct {
+	// Overwrite replaces the files of the day that exist already; without
+	// it the import fails before anything is written.
+	Overwrite bool
+	// Validate, if set, checks the unpacked day directory before it is
+	// moved into place, e.g. for format versions this server cannot read.
+	Validate func(dayDir string) error
+}
+
+// ImportResult describes what ImportArchive did.
+type ImportResult struct {
+	Manifest *ExportManifest
+	Files    int   // day files written, with rebuilt hash files
+	Bytes    int64 // their size
+	// PermTextCopied is set if the data directory had no permanent texts
+	// and the archive's were copied as they are; otherwise PermTextAdded
+	// counts the texts merged into the existing store.
+	PermTextCopied bool
+	PermTextAdded  int
+	// Rebuilt lists the indexes, relative to the data directory, whose hash
+	// files were rebuilt for this server's hash sizes and time zone.
+	Rebuilt []string
+}
+
+// ImportArchive unpacks an archive written by ExportDay into baseDir. The
+// archive is first unpacked to a staging directory under baseDir, where
+// every file is checked against the manifest's size and SHA-256, the index
+// files against their header, and the hash files of the indexes rebuilt if
+// this server opens them with other hash sizes. Only then are the day's
+// files moved into place, and the permanent texts copied, or merged into
+// the existing ones. The server must not run meanwhile.
+func ImportArchive(baseDir string, r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return nil, err
+	}
+	staging, err := os.MkdirTemp(baseDir, ".import-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	m, err := unpackArchive(r, staging)
+	if err != nil {
+		return nil, err
+	}
+	res := &ImportResult{Manifest: m}
+
+	var dayFiles []ExportFile
+	hasPermText := false
+	for _, f := range m.Files {
+		if strings.HasPrefix(f.Path, permTextDir+"/") {
+			hasPermText = true
+		} else {
+			dayFiles = append(dayFiles, f)
+		}
+	}
+	if err := checkIndexHeaders(staging, m.Files); err != nil {
+		return nil, err
+	}
+	if opts.Validate != nil {
+		if err := opts.Validate(filepath.Join(staging, m.Date)); err != nil {
+			return nil, err
+		}
+	}
+	if !opts.Overwrite {
+		var existing []string
+		for _, f := range dayFiles {
+			if _, err := os.Stat(filepath.Join(baseDir, filepath.FromSlash(f.Path))); err == nil {
+				existing = append(existing, f.Path)
+			}
+		}
+		if len(existing) > 0 {
+			return nil, fmt.Errorf("%d files of %s exist already, e.g. %s", len(existing), m.Date, existing[0])
+		}
+	}
+
+	copyPermText := hasPermText && !hasFiles(filepath.Join(baseDir, filepath.FromSlash(permTextDir)))
+	for _, f := range m.Files {
+		if !strings.HasSuffix(f.Path, ".kfile") || (!copyPermText && strings.HasPrefix(f.Path, permTextDir+"/")) {
+			continue
+		}
+		index := strings.TrimSuffix(f.Path, ".kfile")
+		rebuilt, err := rebuildImportedIndex(filepath.Join(staging, filepath.FromSlash(index)), index)
+		if err != nil {
+			return nil, fmt.Errorf("rebuild %s: %w", index, err)
+		}
+		if rebuilt {
+			res.Rebuilt = append(res.Rebuilt, index)
+		}
+	}
+
+	// Rebuilt hash files missing from the archive are moved too.
+	res.Files, res.Bytes, err = moveStaged(staging, baseDir, m.Date)
+	if err != nil {
+		return res, err
+	}
+	if hasPermText {
+		if copyPermText {
+			if _, _, err := moveStaged(staging, baseDir, permTextDir); err != nil {
+				return res, err
+			}
+			res.PermTextCopied = true
+		} else {
+			res.PermTextAdded, err = text.MergePerm(filepath.Join(staging, filepath.FromSlash(permTextDir)), baseDir)
+			if err != nil {
+				return res, fmt.Errorf("merge permanent texts: %w", err)
+			}
+		}
+	}
+	return res, nil
+}
+
+// unpackArchive extracts r into dir, checking it against its manifest.
+func unpackArchive(r io.Reader, dir string) (*ExportManifest, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("not an export archive: %w", err)
+	}
+	if hdr.Name != ExportManifestName {
+		return nil, fmt.Errorf("not an export archive: %s is not first", ExportManifestName)
+	}
+	var m ExportManifest
+	if err := json.NewDecoder(tr).Decode(&m); err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	if m.Format != ExportFormat || m.Version < 1 || m.Version > ExportVersion {
+		return nil, fmt.Errorf("unsupported archive format %s version %d", m.Format, m.Version)
+	}
+	if _, err := time.Parse("20060102", m.Date); err != nil || len(m.Date) != 8 {
+		return nil, fmt.Errorf("manifest: invalid date %q", m.Date)
+	}
+	pending := make(map[string]ExportFile, len(m.Files))
+	for _, f := range m.Files {
+		// Paths are written to, so only the day and the permanent texts
+		// may be named.
+		if path.Clean(f.Path) != f.Path || !(strings.HasPrefix(f.Path, m.Date+"/") || strings.HasPrefix(f.Path, permTextDir+"/")) {
+			return nil, fmt.Errorf("manifest: unexpected path %q", f.Path)
+		}
+		pending[f.Path] = f
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		f, ok := pending[hdr.Name]
+		if !ok || hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("%s is not in the manifest", hdr.Name)
+		}
+		delete(pending, hdr.Name)
+		if err := unpackFile(tr, filepath.Join(dir, filepath.FromSlash(f.Path)), f); err != nil {
+			return nil, err
+		}
+	}
+	if len(pending) > 0 {
+		missing := make([]string, 0, len(pending))
+		for p := range pending {
+			missing = append(missing, p)
+		}
+		sort.Strings(missing)
+		return nil, fmt.Errorf("%d files of the manifest are missing, e.g. %s", len(missing), missing[0])
+	}
+	return &m, nil
+}
+
+func unpackFile(r io.Reader, dst string, f ExportFile) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(out, h), r)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if n != f.Size || hex.EncodeToString(h.Sum(nil)) != f.SHA256 {
+		return fmt.Errorf("%s does not match its manifest checksum", f.Path)
+	}
+	return nil
+}
+
+// checkIndexHeaders reports index files not starting with the 0xCAFE header
+// every .kfile and .hfile is written with.
+func checkIndexHeaders(dir string, files []ExportFile) error {
+	for _, f := range files {
+		if !strings.HasSuffix(f.Path, ".kfile") && !strings.HasSuffix(f.Path, ".hfile") {
+			continue
+		}
+		if f.Size == 0 {
+			continue
+		}
+		file, err := os.Open(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			return err
+		}
+		var magic [2]byte
+		_, err = io.ReadFull(file, magic[:])
+		file.Close()
+		if err != nil || magic[0] != 0xCA || magic[1] != 0xFE {
+			return fmt.Errorf("%s is not an index file: bad header", f.Path)
+		}
+	}
+	return nil
+}
+
+// rebuildImportedIndex rebuilds the hash file of the index at dst, named
+// index relative to the data directory, for the hash size or time buckets
+// this server opens it with.
+func rebuildImportedIndex(dst, index string) (bool, error) {
+	if strings.HasPrefix(index, permTextDir+"/text_") {
+		hashMB := 1
+		if cfg := config.Get(); cfg != nil {
+			hashMB = cfg.MgrTextDbIndexMB(strings.TrimPrefix(index, permTextDir+"/text_"))
+		}
+		return dbio.RebuildKeyIndex(dst, hashMB)
+	}
+	// Day indexes, by their path below the date.
+	_, rel, _ := strings.Cut(index, "/")
+	switch {
+	case rel == "xlog/xlog_tim", rel == "alert/alert", strings.HasPrefix(rel, "summary/"):
+		return dbio.RebuildTimeIndex(dst)
+	case rel == "text/text":
+		hashMB := 1
+		if cfg := config.Get(); cfg != nil {
+			hashMB = cfg.MgrTextDbDailyIndexMB()
+		}
+		return dbio.RebuildKeyIndex(dst, hashMB)
+	}
+	return dbio.RebuildKeyIndex(dst, 1)
+}
+
+// moveStaged moves the files below dir of staging to the same place in
+// baseDir and returns their number and size.
+func moveStaged(staging, baseDir, dir string) (int, int64, error) {
+	var files int
+	var bytes int64
+	root := filepath.Join(staging, filepath.FromSlash(dir))
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(staging, p)
+		dst := filepath.Join(baseDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(p, dst); err != nil {
+			return err
+		}
+		files++
+		bytes += info.Size()
+		return nil
+	})
+	return files, bytes, err
+}
+
+// hasFiles reports whether dir holds any regular file.
+func hasFiles(dir string) bool {
+	found := false
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			found = true
+			return fs.SkipAll
+		}
+		return nil
+	})
+	return found
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/db/io"
+	"github.com/zbum/scouter-server-go/internal/db/text"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+)
+
+func TestImportArchive(t *testing.T) {
+	src := t.TempDir()
+	xlogDir := filepath.Join(src, "20260207", "xlog")
+	os.MkdirAll(xlogDir, 0755)
+	tid, err := io.NewIndexKeyFile(filepath.Join(xlogDir, "xlog_tid"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tid.Put([]byte("txid-1"), protocol.BigEndian.Bytes5(1))
+	tid.Close()
+	tim, err := io.NewIndexTimeFile(filepath.Join(xlogDir, "xlog_tim"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tim.Put(1770420000000, protocol.BigEndian.Bytes5(1))
+	tim.Close()
+	os.WriteFile(filepath.Join(xlogDir, "xlog.data"), []byte("xlog"), 0644)
+	// A hash file lost in the source is rebuilt on import.
+	os.Remove(filepath.Join(xlogDir, "xlog_tim.hfile"))
+	perm, _ := text.NewTextPermTable(filepath.Join(src, "00000000", "text"))
+	perm.Set("service", 1, "/order")
+	perm.Close()
+
+	var archive bytes.Buffer
+	if _, err := ExportDay(src, "20260207", ExportOptions{PermText: true}, &archive); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := t.TempDir()
+	res, err := ImportArchive(dst, bytes.NewReader(archive.Bytes()), ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Files != 5 || res.Bytes < 1<<20 || !res.PermTextCopied || len(res.Rebuilt) != 1 || res.Rebuilt[0] != "20260207/xlog/xlog_tim" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "20260207", "xlog", "xlog.data")); string(data) != "xlog" {
+		t.Errorf("xlog.data = %q", data)
+	}
+	tid, _ = io.NewIndexKeyFile(filepath.Join(dst, "20260207", "xlog", "xlog_tid"), 1)
+	if v, err := tid.Get([]byte("txid-1")); err != nil || protocol.BigEndian.Int5(v) != 1 {
+		t.Errorf("imported index Get = %v, %v", v, err)
+	}
+	tid.Close()
+	if entries, _ := os.ReadDir(dst); len(entries) != 2 {
+		t.Errorf("expected the day and the permanent texts only, got %d entries", len(entries))
+	}
+
+	if _, err := ImportArchive(dst, bytes.NewReader(archive.Bytes()), ImportOptions{}); err == nil || !strings.Contains(err.Error(), "exist already") {
+		t.Errorf("expected an error for existing files, got %v", err)
+	}
+	res, err = ImportArchive(dst, bytes.NewReader(archive.Bytes()), ImportOptions{Overwrite: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.PermTextCopied || res.PermTextAdded != 0 {
+		t.Errorf("expected the permanent texts merged without additions, got %+v", res)
+	}
+
+	os.WriteFile(filepath.Join(xlogDir, "xlog_tid.kfile"), []byte("garbage"), 0644)
+	archive.Reset()
+	ExportDay(src, "20260207", ExportOptions{}, &archive)
+	if _, err := ImportArchive(t.TempDir(), &archive, ImportOptions{}); err == nil || !strings.Contains(err.Error(), "bad header") {
+		t.Errorf("expected a header error, got %v", err)
+	}
+	if _, err := ImportArchive(t.TempDir(), strings.NewReader("not an archive"), ImportOptions{}); err == nil {
+		t.Error("expected an error for a non-archive")
+	}
+}
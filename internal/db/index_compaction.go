@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	dbio "github.com/zbum/scouter-server-go/internal/db/io"
+)
+
+// IndexCompactor compacts long-lived hash indexes once a day at
+// mgr_compaction_hour, so the deleted and expired records of indexes that
+// are never purged stop taking disk space. Daily indexes are removed with
+// their day and are not registered.
+type IndexCompactor struct {
+	mu      sync.Mutex
+	names   []string
+	targets map[string]func(minDropPct int) (dbio.CompactResult, error)
+	lastDay string // day of the last run, yyyyMMdd
+}
+
+// NewIndexCompactor creates an IndexCompactor without indexes.
+func NewIndexCompactor() *IndexCompactor {
+	return &IndexCompactor{targets: make(map[string]func(int) (dbio.CompactResult, error))}
+}
+
+// Add registers an index, or a set of indexes, under name. compact must
+// serialize itself with the writes to the index.
+func (c *IndexCompactor) Add(name string, compact func(minDropPct int) (dbio.CompactResult, error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.targets[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.targets[name] = compact
+}
+
+// Start checks every minute whether the compaction of the day is due, until
+// ctx is done.
+func (c *IndexCompactor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if cfg := config.Get(); cfg != nil {
+					c.tick(cfg, now)
+				}
+			}
+		}
+	}()
+}
+
+func (c *IndexCompactor) tick(cfg *config.Config, now time.Time) {
+	hour := cfg.MgrCompactionHour()
+	if hour < 0 || now.Hour() != hour {
+		return
+	}
+	day := now.Format("20060102")
+	c.mu.Lock()
+	due := c.lastDay != day
+	c.lastDay = day
+	c.mu.Unlock()
+	if due {
+		c.Run(cfg.MgrCompactionMinDroppedPct())
+	}
+}
+
+// Run compacts every registered index now and returns the results by name.
+// A failed compaction is logged and left out of the results.
+func (c *IndexCompactor) Run(minDropPct int) map[string]dbio.CompactResult {
+	c.mu.Lock()
+	names := append([]string(nil), c.names...)
+	targets := make(map[string]func(int) (dbio.CompactResult, error), len(c.targets))
+	for name, fn := range c.targets {
+		targets[name] = fn
+	}
+	c.mu.Unlock()
+
+	results := make(map[string]dbio.CompactResult, len(names))
+	for _, name := range names {
+		start := time.Now()
+		res, err := targets[name](minDropPct)
+		if err != nil {
+			slog.Error("IndexCompaction: failed", "index", name, "error", err)
+			continue
+		}
+		results[name] = res
+		if res.Compacted {
+			slog.Info("IndexCompaction: compacted", "index", name, "kept", res.Kept, "dropped", res.Dropped,
+				"reclaimed", res.Reclaimed(), "elapsed", time.Since(start).Round(time.Millisecond))
+		} else {
+			slog.Debug("IndexCompaction: skipped", "index", name, "kept", res.Kept, "dropped", res.Dropped)
+		}
+	}
+	return results
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	dbio "github.com/zbum/scouter-server-go/internal/db/io"
+)
+
+func TestIndexCompactor(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("mgr_compaction_hour=3\nmgr_compaction_min_dropped_pct=30\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	c := NewIndexCompactor()
+	var runs []int
+	c.Add("text", func(minDropPct int) (dbio.CompactResult, error) {
+		runs = append(runs, minDropPct)
+		return dbio.CompactResult{Compacted: true, Kept: 7, Dropped: 3, BytesBefore: 100, BytesAfter: 70}, nil
+	})
+	c.Add("broken", func(int) (dbio.CompactResult, error) {
+		return dbio.CompactResult{}, errors.New("disk full")
+	})
+
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	c.tick(config.Get(), day.Add(2*time.Hour))
+	if len(runs) != 0 {
+		t.Fatal("compacted before mgr_compaction_hour")
+	}
+	c.tick(config.Get(), day.Add(3*time.Hour))
+	c.tick(config.Get(), day.Add(3*time.Hour+time.Minute))
+	if len(runs) != 1 || runs[0] != 30 {
+		t.Fatalf("runs = %v, want one with mgr_compaction_min_dropped_pct", runs)
+	}
+	c.tick(config.Get(), day.AddDate(0, 0, 1).Add(3*time.Hour))
+	if len(runs) != 2 {
+		t.Errorf("runs = %v, want one more the next day", runs)
+	}
+
+	results := c.Run(10)
+	if len(results) != 1 || results["text"].Reclaimed() != 30 {
+		t.Errorf("results = %+v, want the failed index left out", results)
+	}
+
+	os.WriteFile(conf, []byte("mgr_compaction_hour=-1\n"), 0644)
+	config.Load(conf)
+	c.tick(config.Get(), day.AddDate(0, 0, 2).Add(4*time.Hour))
+	if len(runs) != 3 {
+		t.Errorf("runs = %v, want none with mgr_compaction_hour=-1", runs)
+	}
+}
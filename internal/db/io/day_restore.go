@@ -0,0 +1,34 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+var dayRestorer atomic.Pointer[func(date string) bool]
+
+// SetDayRestorer sets the function the readers call to bring back a day
+// missing from the data directory, e.g. from a backup, before they report it
+// empty. It reports whether the day was restored. nil removes it.
+func SetDayRestorer(fn func(date string) bool) {
+	if fn == nil {
+		dayRestorer.Store(nil)
+		return
+	}
+	dayRestorer.Store(&fn)
+}
+
+// RestoreMissingDay asks the day restorer, if any, for the day directory of
+// date under baseDir if it does not exist. It returns once the restorer is
+// done, so the caller must not hold locks other reads wait for.
+func RestoreMissingDay(baseDir, date string) {
+	fn := dayRestorer.Load()
+	if fn == nil {
+		return
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, date)); !os.IsNotExist(err) {
+		return
+	}
+	(*fn)(date)
+}
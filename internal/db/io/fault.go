@@ -0,0 +1,184 @@
+package io
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+)
+
+// Storage operations a fault can be injected into.
+const (
+	FaultRead  = "read"  // index record reads, which precede every data read
+	FaultWrite = "write" // index appends and data file writes
+)
+
+// ErrInjected is returned by storage operations failed by an injected fault.
+var ErrInjected = errors.New("injected storage fault")
+
+// Fault delays or fails storage operations, to rehearse how the server and
+// its alerting behave on a slow or failing disk. Faults only take effect while
+// testing_fault_injection_enabled is set.
+type Fault struct {
+	Op       string        // FaultRead or FaultWrite
+	Match    string        // substring of the file path; "" matches every file
+	Latency  time.Duration // added to every matching operation
+	ErrorPct int           // percentage of matching operations failed with ErrInjected
+	Until    time.Time     // zero until cleared
+	Hits     int64         // operations delayed or failed so far
+}
+
+// String describes the fault in the form accepted by ParseFault.
+func (f Fault) String() string {
+	s := f.Op
+	if f.Match != "" {
+		s += " match=" + f.Match
+	}
+	if f.Latency > 0 {
+		s += " latency=" + f.Latency.String()
+	}
+	if f.ErrorPct > 0 {
+		s += fmt.Sprintf(" error=%d", f.ErrorPct)
+	}
+	if !f.Until.IsZero() {
+		s += " until=" + f.Until.Format("15:04:05")
+	}
+	return s
+}
+
+// ParseFault parses "read|write [match=S] [latency=D] [error=PCT] [for=D]".
+func ParseFault(args []string) (Fault, error) {
+	if len(args) == 0 || (args[0] != FaultRead && args[0] != FaultWrite) {
+		return Fault{}, errors.New("operation must be read or write")
+	}
+	f := Fault{Op: args[0]}
+	for _, arg := range args[1:] {
+		key, val, ok := strings.Cut(arg, "=")
+		if !ok {
+			return Fault{}, fmt.Errorf("bad option %q", arg)
+		}
+		var err error
+		switch key {
+		case "match":
+			f.Match = val
+		case "latency":
+			f.Latency, err = time.ParseDuration(val)
+		case "error":
+			_, err = fmt.Sscanf(val, "%d", &f.ErrorPct)
+			if err == nil && (f.ErrorPct < 0 || f.ErrorPct > 100) {
+				err = errors.New("must be between 0 and 100")
+			}
+		case "for":
+			var d time.Duration
+			if d, err = time.ParseDuration(val); err == nil {
+				f.Until = time.Now().Add(d)
+			}
+		default:
+			err = errors.New("unknown option")
+		}
+		if err != nil {
+			return Fault{}, fmt.Errorf("%s: %v", key, err)
+		}
+	}
+	if f.Latency <= 0 && f.ErrorPct == 0 {
+		return Fault{}, errors.New("a fault needs latency or error")
+	}
+	return f, nil
+}
+
+var faultInj = &faultInjector{}
+
+type faultInjector struct {
+	mu     sync.Mutex
+	faults []*Fault
+	active atomic.Bool // any faults installed; keeps the no-fault path to one load
+}
+
+// GetFaultInjector returns the process-wide storage fault injector.
+func GetFaultInjector() *faultInjector {
+	return faultInj
+}
+
+// Add installs f. It fails unless testing_fault_injection_enabled is set.
+func (fi *faultInjector) Add(f Fault) error {
+	if cfg := config.Get(); cfg == nil || !cfg.TestingFaultInjectionEnabled() {
+		return errors.New("testing_fault_injection_enabled is off")
+	}
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.faults = append(fi.faults, &f)
+	fi.active.Store(true)
+	return nil
+}
+
+// Clear removes every fault and returns how many there were.
+func (fi *faultInjector) Clear() int {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	n := len(fi.faults)
+	fi.faults = nil
+	fi.active.Store(false)
+	return n
+}
+
+// List returns the installed faults that have not expired.
+func (fi *faultInjector) List() []Fault {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.expireLocked(time.Now())
+	result := make([]Fault, 0, len(fi.faults))
+	for _, f := range fi.faults {
+		result = append(result, *f)
+	}
+	return result
+}
+
+func (fi *faultInjector) expireLocked(now time.Time) {
+	kept := fi.faults[:0]
+	for _, f := range fi.faults {
+		if f.Until.IsZero() || now.Before(f.Until) {
+			kept = append(kept, f)
+		}
+	}
+	clear(fi.faults[len(kept):])
+	fi.faults = kept
+	fi.active.Store(len(kept) > 0)
+}
+
+// check applies the faults matching op on file: it sleeps for their latency
+// and returns ErrInjected if one of them fails the operation.
+func (fi *faultInjector) check(op, file string) error {
+	if !fi.active.Load() {
+		return nil
+	}
+	if cfg := config.Get(); cfg == nil || !cfg.TestingFaultInjectionEnabled() {
+		return nil
+	}
+	var latency time.Duration
+	fail := false
+	fi.mu.Lock()
+	fi.expireLocked(time.Now())
+	for _, f := range fi.faults {
+		if f.Op != op || !strings.Contains(file, f.Match) {
+			continue
+		}
+		f.Hits++
+		latency += f.Latency
+		if f.ErrorPct > 0 && rand.IntN(100) < f.ErrorPct {
+			fail = true
+		}
+	}
+	fi.mu.Unlock()
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	if fail {
+		return fmt.Errorf("%s %s: %w", op, file, ErrInjected)
+	}
+	return nil
+}
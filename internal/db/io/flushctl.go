@@ -1,8 +1,11 @@
 package io
 
 import (
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
 )
 
 // IFlushable represents an object that can be periodically flushed to disk.
@@ -12,14 +15,50 @@ type IFlushable interface {
 	Interval() time.Duration
 }
 
+// IFlushStats is implemented by an IFlushable that reports its file and the
+// bytes waiting to be flushed, which makes its flush interval adaptive.
+type IFlushStats interface {
+	File() string
+	DirtyBytes() int
+}
+
+// IMemSize is implemented by an IFlushable that holds its file in memory.
+type IMemSize interface {
+	MemBytes() int
+}
+
+// FlushStat describes the flushes of one registered file.
+type FlushStat struct {
+	File         string
+	Flushes      int64
+	Bytes        int64         // dirty bytes flushed in total
+	Dirty        int           // bytes waiting now
+	MemBytes     int           // bytes held in memory, 0 if not memory-backed
+	Interval     time.Duration // interval used for the last flush decision
+	LastFlush    time.Time
+	AvgLatency   time.Duration
+	MaxLatency   time.Duration
+	TotalLatency time.Duration // of all flushes
+}
+
+type flushState struct {
+	registered   time.Time
+	lastFlush    time.Time
+	interval     time.Duration
+	flushes      int64
+	bytes        int64
+	totalLatency time.Duration
+	maxLatency   time.Duration
+}
+
 // FlushController manages periodic flushing of registered IFlushable instances.
 var flushCtl = &flushController{
-	items: make(map[IFlushable]struct{}),
+	items: make(map[IFlushable]*flushState),
 }
 
 type flushController struct {
 	mu      sync.Mutex
-	items   map[IFlushable]struct{}
+	items   map[IFlushable]*flushState
 	started bool
 }
 
@@ -30,7 +69,7 @@ func GetFlushController() *flushController {
 func (fc *flushController) Register(f IFlushable) {
 	fc.mu.Lock()
 	defer fc.mu.Unlock()
-	fc.items[f] = struct{}{}
+	fc.items[f] = &flushState{registered: time.Now()}
 	if !fc.started {
 		fc.started = true
 		go fc.run()
@@ -46,18 +85,125 @@ func (fc *flushController) Unregister(f IFlushable) {
 func (fc *flushController) run() {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
-	for range ticker.C {
+	for now := range ticker.C {
+		fc.flushDue(now)
+	}
+}
+
+// flushDue flushes the dirty items whose interval has passed since their
+// last flush.
+func (fc *flushController) flushDue(now time.Time) {
+	fc.mu.Lock()
+	items := make([]IFlushable, 0, len(fc.items))
+	for f := range fc.items {
+		items = append(items, f)
+	}
+	fc.mu.Unlock()
+
+	for _, f := range items {
+		if !f.IsDirty() {
+			continue
+		}
+		dirty := 0
+		if s, ok := f.(IFlushStats); ok {
+			dirty = s.DirtyBytes()
+		}
+		interval := flushInterval(f, dirty)
+
 		fc.mu.Lock()
-		items := make([]IFlushable, 0, len(fc.items))
-		for f := range fc.items {
-			items = append(items, f)
+		st := fc.items[f]
+		if st == nil {
+			fc.mu.Unlock()
+			continue // unregistered meanwhile
+		}
+		st.interval = interval
+		last := st.lastFlush
+		if last.IsZero() {
+			last = st.registered
 		}
 		fc.mu.Unlock()
+		if now.Sub(last) < interval {
+			continue
+		}
 
-		for _, f := range items {
-			if f.IsDirty() {
-				f.Flush()
+		start := time.Now()
+		f.Flush()
+		latency := time.Since(start)
+
+		fc.mu.Lock()
+		if st := fc.items[f]; st != nil {
+			st.lastFlush = now
+			st.flushes++
+			st.bytes += int64(dirty)
+			st.totalLatency += latency
+			if latency > st.maxLatency {
+				st.maxLatency = latency
 			}
 		}
+		fc.mu.Unlock()
+	}
+}
+
+// flushInterval returns how long f may stay dirty. With flush_adaptive_enabled
+// the interval of a file reporting its dirty bytes shrinks linearly from
+// flush_max_interval_ms to the file's own Interval as the dirty bytes grow,
+// and is zero (next tick) from flush_dirty_bytes_threshold on, so
+// low-traffic files are rewritten less often while busy ones stay fresh.
+func flushInterval(f IFlushable, dirty int) time.Duration {
+	base := f.Interval()
+	cfg := config.Get()
+	if cfg == nil || !cfg.FlushAdaptiveEnabled() {
+		return base
+	}
+	if _, ok := f.(IFlushStats); !ok {
+		return base
+	}
+	threshold := cfg.FlushDirtyBytesThreshold()
+	if threshold <= 0 || dirty >= threshold {
+		return 0
+	}
+	maxInterval := time.Duration(cfg.FlushMaxIntervalMs()) * time.Millisecond
+	if maxInterval <= base {
+		return base
+	}
+	return maxInterval - time.Duration(int64(maxInterval-base)*int64(dirty)/int64(threshold))
+}
+
+// Stats returns the flush statistics of the registered files that report
+// their file name, ordered by file.
+func (fc *flushController) Stats() []FlushStat {
+	fc.mu.Lock()
+	files := make([]IFlushStats, 0, len(fc.items))
+	result := make([]FlushStat, 0, len(fc.items))
+	for f, st := range fc.items {
+		s, ok := f.(IFlushStats)
+		if !ok {
+			continue
+		}
+		fs := FlushStat{
+			Flushes:      st.flushes,
+			Bytes:        st.bytes,
+			Interval:     st.interval,
+			LastFlush:    st.lastFlush,
+			MaxLatency:   st.maxLatency,
+			TotalLatency: st.totalLatency,
+		}
+		if st.flushes > 0 {
+			fs.AvgLatency = st.totalLatency / time.Duration(st.flushes)
+		}
+		files = append(files, s)
+		result = append(result, fs)
+	}
+	fc.mu.Unlock()
+
+	// The files take their own locks; ask them outside ours.
+	for i, s := range files {
+		result[i].File = s.File()
+		result[i].Dirty = s.DirtyBytes()
+		if m, ok := s.(IMemSize); ok {
+			result[i].MemBytes = m.MemBytes()
+		}
 	}
+	sort.Slice(result, func(i, j int) bool { return result[i].File < result[j].File })
+	return result
 }
@@ -0,0 +1,212 @@
+package io
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// compactSuffix names the temporary index a compaction writes next to the
+// one it replaces.
+const compactSuffix = "_compact_tmp"
+
+// CompactResult describes the compaction of one hash index.
+type CompactResult struct {
+	Compacted   bool // false if too few records could be dropped to rewrite
+	Kept        int  // live records
+	Dropped     int  // deleted records, and for IndexKeyFile2 expired ones
+	BytesBefore int64
+	BytesAfter  int64 // of the key file
+}
+
+// Reclaimed returns the bytes freed on disk.
+func (r CompactResult) Reclaimed() int64 {
+	return r.BytesBefore - r.BytesAfter
+}
+
+// worthIt reports whether enough records can be dropped to rewrite the
+// index: at least one, and minDropPct percent of all.
+func (r CompactResult) worthIt(minDropPct int) bool {
+	return r.Dropped > 0 && r.Dropped*100 >= minDropPct*(r.Kept+r.Dropped)
+}
+
+// Compact rewrites the key file without its deleted records and rebuilds the
+// hash file for it, if at least minDropPct percent of the records are
+// deleted. The hash size is kept. Like every write, it must not run
+// concurrently with other calls on the index.
+func (f *IndexKeyFile) Compact(minDropPct int) (CompactResult, error) {
+	res := CompactResult{BytesBefore: f.keyFile.Length()}
+	res.BytesAfter = res.BytesBefore
+	err := f.scan(func(r *KeyRecord) error {
+		if r.Deleted {
+			res.Dropped++
+		} else {
+			res.Kept++
+		}
+		return nil
+	})
+	if err != nil || !res.worthIt(minDropPct) {
+		return res, err
+	}
+
+	tmp := f.path + compactSuffix
+	removeIndexFiles(tmp, ".kfile")
+	hb, err := NewMemHashBlock(tmp, f.hashBlock.bufSize)
+	if err != nil {
+		return res, err
+	}
+	kf, err := NewRealKeyFile(tmp)
+	if err != nil {
+		hb.Close()
+		return res, err
+	}
+	dst := &IndexKeyFile{path: tmp, store: f.store, hashBlock: hb, keyFile: kf}
+	err = f.scan(func(r *KeyRecord) error {
+		if r.Deleted {
+			return nil
+		}
+		return dst.Put(r.TimeKey, r.DataPos)
+	})
+	dst.Close()
+	if err != nil {
+		removeIndexFiles(tmp, ".kfile")
+		return res, fmt.Errorf("compact %s: %w", f.path, err)
+	}
+
+	bufSize := f.hashBlock.bufSize
+	f.hashBlock.Close()
+	f.keyFile.Close()
+	swapErr := swapIndexFiles(tmp, f.path, ".kfile")
+	if f.hashBlock, err = NewMemHashBlock(f.path, bufSize); err != nil {
+		return res, err
+	}
+	if f.keyFile, err = NewRealKeyFile(f.path); err != nil {
+		return res, err
+	}
+	if swapErr != nil {
+		return res, swapErr
+	}
+	res.Compacted = true
+	res.BytesAfter = f.keyFile.Length()
+	return res, nil
+}
+
+// scan calls fn with every record of the key file, deleted ones included.
+func (f *IndexKeyFile) scan(fn func(r *KeyRecord) error) error {
+	pos := f.keyFile.FirstPos()
+	length := f.keyFile.Length()
+	for pos < length && pos > 0 {
+		r, err := f.keyFile.GetRecord(pos)
+		if err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+		pos = r.Offset
+	}
+	return nil
+}
+
+// Compact rewrites the key file without its deleted and expired records,
+// keeping the expiry of the others, and rebuilds the hash file for it, if
+// at least minDropPct percent of the records are dropped. The hash size is
+// kept. Like every write, it must not run concurrently with other calls on
+// the index.
+func (f *IndexKeyFile2) Compact(minDropPct int) (CompactResult, error) {
+	now := time.Now().Unix()
+	res := CompactResult{BytesBefore: f.keyFile.Length()}
+	res.BytesAfter = res.BytesBefore
+	err := f.scan(func(r *KeyRecord2) error {
+		if r.live(now) {
+			res.Kept++
+		} else {
+			res.Dropped++
+		}
+		return nil
+	})
+	if err != nil || !res.worthIt(minDropPct) {
+		return res, err
+	}
+
+	tmp := f.path + compactSuffix
+	removeIndexFiles(tmp, ".k2file")
+	hb, err := NewMemHashBlock(tmp, f.hashBlock.bufSize)
+	if err != nil {
+		return res, err
+	}
+	kf, err := NewRealKeyFile2(tmp)
+	if err != nil {
+		hb.Close()
+		return res, err
+	}
+	dst := &IndexKeyFile2{path: tmp, store: f.store, hashBlock: hb, keyFile: kf}
+	// Records expiring meanwhile are copied; the next compaction drops them.
+	res.Kept, res.Dropped = 0, 0
+	err = f.scan(func(r *KeyRecord2) error {
+		if !r.live(now) {
+			res.Dropped++
+			return nil
+		}
+		res.Kept++
+		return dst.putExpire(r.TimeKey, r.DataPos, r.Expire)
+	})
+	dst.Close()
+	if err != nil {
+		removeIndexFiles(tmp, ".k2file")
+		return res, fmt.Errorf("compact %s: %w", f.path, err)
+	}
+
+	bufSize := f.hashBlock.bufSize
+	f.hashBlock.Close()
+	f.keyFile.Close()
+	swapErr := swapIndexFiles(tmp, f.path, ".k2file")
+	if f.hashBlock, err = NewMemHashBlock(f.path, bufSize); err != nil {
+		return res, err
+	}
+	if f.keyFile, err = NewRealKeyFile2(f.path); err != nil {
+		return res, err
+	}
+	if swapErr != nil {
+		return res, swapErr
+	}
+	res.Compacted = true
+	res.BytesAfter = f.keyFile.Length()
+	return res, nil
+}
+
+// scan calls fn with every record of the key file, deleted and expired
+// ones included.
+func (f *IndexKeyFile2) scan(fn func(r *KeyRecord2) error) error {
+	pos := f.keyFile.FirstPos()
+	length := f.keyFile.Length()
+	for pos < length && pos > 0 {
+		r, err := f.keyFile.GetRecord(pos)
+		if err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+		pos = r.Offset
+	}
+	return nil
+}
+
+// swapIndexFiles moves the key file (ext) and hash file of the index at src
+// over those of dst, the key file first: a crash in between leaves a hash
+// file not matching its key file, which db_index_check_on_open rebuilds for
+// .kfile indexes.
+func swapIndexFiles(src, dst, ext string) error {
+	for _, e := range []string{ext, ".hfile"} {
+		if err := os.Rename(src+e, dst+e); err != nil {
+			return fmt.Errorf("replace %s: %w", dst+e, err)
+		}
+	}
+	return nil
+}
+
+func removeIndexFiles(path, ext string) {
+	os.Remove(path + ext)
+	os.Remove(path + ".hfile")
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"log/slog"
+	"time"
 
 	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/protocol"
@@ -18,6 +19,7 @@ const (
 // IndexKeyFile is a composite hash-based key-value index combining MemHashBlock + RealKeyFile.
 type IndexKeyFile struct {
 	path      string
+	store     string
 	hashBlock *MemHashBlock
 	keyFile   *RealKeyFile
 }
@@ -26,6 +28,7 @@ func NewIndexKeyFile(path string, hashSizeMB int) (*IndexKeyFile, error) {
 	if hashSizeMB <= 0 {
 		hashSizeMB = defaultHashSizeMB
 	}
+	checkKeyFile(path, hashSizeMB)
 	hb, err := NewMemHashBlock(path, hashSizeMB*MB)
 	if err != nil {
 		return nil, err
@@ -37,15 +40,27 @@ func NewIndexKeyFile(path string, hashSizeMB int) (*IndexKeyFile, error) {
 	}
 	return &IndexKeyFile{
 		path:      path,
+		store:     StoreOf(path),
 		hashBlock: hb,
 		keyFile:   kf,
 	}, nil
 }
 
+// File returns the path the index was opened with.
+func (f *IndexKeyFile) File() string {
+	return f.path
+}
+
+// Store returns the storage the index belongs to.
+func (f *IndexKeyFile) Store() string {
+	return f.store
+}
+
 func (f *IndexKeyFile) Put(indexKey []byte, dataOffset []byte) error {
 	if indexKey == nil || dataOffset == nil {
 		return errors.New("invalid key/value")
 	}
+	defer ioSt.observe(f.store, OpWrite, time.Now(), 0, -1)
 	keyHash := util.HashBytes(indexKey)
 	prevKeyPos := f.hashBlock.Get(keyHash)
 	newKeyPos, err := f.keyFile.Append(prevKeyPos, indexKey, dataOffset)
@@ -60,6 +75,7 @@ func (f *IndexKeyFile) Update(key []byte, value []byte) (bool, error) {
 	if key == nil || value == nil {
 		return false, errors.New("invalid key/value")
 	}
+	defer ioSt.observe(f.store, OpWrite, time.Now(), 0, -1)
 	keyHash := util.HashBytes(key)
 	pos := f.hashBlock.Get(keyHash)
 	return f.keyFile.Update(pos, key, value)
@@ -69,20 +85,21 @@ func (f *IndexKeyFile) Get(key []byte) ([]byte, error) {
 	if key == nil {
 		return nil, errors.New("invalid key")
 	}
+	start, looping := time.Now(), 0
+	defer func() { ioSt.observe(f.store, OpRead, start, 0, looping) }()
 	keyHash := util.HashBytes(key)
 	realKeyPos := f.hashBlock.Get(keyHash)
 
-	looping := 0
 	for realKeyPos > 0 {
 		r, err := f.keyFile.GetRecord(realKeyPos)
 		if err != nil {
 			return nil, err
 		}
+		looping++
 		if !r.Deleted && bytes.Equal(r.TimeKey, key) {
 			return r.DataPos, nil
 		}
 		realKeyPos = r.PrevPos
-		looping++
 	}
 	warnCount := 100
 	if cfg := config.Get(); cfg != nil {
@@ -98,6 +115,8 @@ func (f *IndexKeyFile) HasKey(key []byte) (bool, error) {
 	if key == nil {
 		return false, errors.New("invalid key")
 	}
+	start, depth := time.Now(), 0
+	defer func() { ioSt.observe(f.store, OpRead, start, 0, depth) }()
 	keyHash := util.HashBytes(key)
 	pos := f.hashBlock.Get(keyHash)
 	for pos > 0 {
@@ -105,6 +124,7 @@ func (f *IndexKeyFile) HasKey(key []byte) (bool, error) {
 		if err != nil {
 			return false, err
 		}
+		depth++
 		if !r.Deleted && bytes.Equal(r.TimeKey, key) {
 			return true, nil
 		}
@@ -117,6 +137,8 @@ func (f *IndexKeyFile) GetAll(key []byte) ([][]byte, error) {
 	if key == nil {
 		return nil, errors.New("invalid key")
 	}
+	start, depth := time.Now(), 0
+	defer func() { ioSt.observe(f.store, OpRead, start, 0, depth) }()
 	var out [][]byte
 	keyHash := util.HashBytes(key)
 	pos := f.hashBlock.Get(keyHash)
@@ -125,6 +147,7 @@ func (f *IndexKeyFile) GetAll(key []byte) ([][]byte, error) {
 		if err != nil {
 			return nil, err
 		}
+		depth++
 		if !r.Deleted && bytes.Equal(r.TimeKey, key) {
 			out = append(out, r.DataPos)
 		}
@@ -137,6 +160,8 @@ func (f *IndexKeyFile) Delete(key []byte) (int, error) {
 	if key == nil {
 		return 0, errors.New("invalid key")
 	}
+	start, depth := time.Now(), 0
+	defer func() { ioSt.observe(f.store, OpWrite, start, 0, depth) }()
 	keyHash := util.HashBytes(key)
 	pos := f.hashBlock.Get(keyHash)
 	deleted := 0
@@ -145,6 +170,7 @@ func (f *IndexKeyFile) Delete(key []byte) (int, error) {
 		if err != nil {
 			return deleted, err
 		}
+		depth++
 		if !isDel {
 			oKey, err := f.keyFile.GetTimeKey(pos)
 			if err != nil {
@@ -15,6 +15,7 @@ import (
 // combining MemHashBlock + RealKeyFile2.
 type IndexKeyFile2 struct {
 	path      string
+	store     string
 	hashBlock *MemHashBlock
 	keyFile   *RealKeyFile2
 }
@@ -34,11 +35,22 @@ func NewIndexKeyFile2(path string, hashSizeMB int) (*IndexKeyFile2, error) {
 	}
 	return &IndexKeyFile2{
 		path:      path,
+		store:     StoreOf(path),
 		hashBlock: hb,
 		keyFile:   kf,
 	}, nil
 }
 
+// File returns the path the index was opened with.
+func (f *IndexKeyFile2) File() string {
+	return f.path
+}
+
+// Store returns the storage the index belongs to.
+func (f *IndexKeyFile2) Store() string {
+	return f.store
+}
+
 // Put inserts a key-value pair with infinite TTL.
 func (f *IndexKeyFile2) Put(indexKey []byte, dataOffset []byte) error {
 	return f.PutTTL(indexKey, dataOffset, -1)
@@ -46,12 +58,22 @@ func (f *IndexKeyFile2) Put(indexKey []byte, dataOffset []byte) error {
 
 // PutTTL inserts a key-value pair with TTL.
 func (f *IndexKeyFile2) PutTTL(indexKey []byte, dataOffset []byte, ttl int64) error {
+	expire := int64(protocol.LONG5MaxValue)
+	if ttl >= 0 {
+		expire = time.Now().Unix() + ttl
+	}
+	return f.putExpire(indexKey, dataOffset, expire)
+}
+
+// putExpire inserts a key-value pair expiring at the unix time expire.
+func (f *IndexKeyFile2) putExpire(indexKey []byte, dataOffset []byte, expire int64) error {
 	if indexKey == nil || dataOffset == nil {
 		return errors.New("invalid key/value")
 	}
+	defer ioSt.observe(f.store, OpWrite, time.Now(), 0, -1)
 	keyHash := util.HashBytes(indexKey)
 	prevKeyPos := f.hashBlock.Get(keyHash)
-	newKeyPos, err := f.keyFile.AppendTTL(prevKeyPos, ttl, indexKey, dataOffset)
+	newKeyPos, err := f.keyFile.appendExpire(prevKeyPos, expire, indexKey, dataOffset)
 	if err != nil {
 		return err
 	}
@@ -69,15 +91,17 @@ func (f *IndexKeyFile2) UpdateOrPutTTL(key []byte, value []byte, ttl int64) (boo
 	if key == nil || value == nil {
 		return false, errors.New("invalid key/value")
 	}
+	start, looping := time.Now(), 0
+	defer func() { ioSt.observe(f.store, OpWrite, start, 0, looping) }()
 	keyHash := util.HashBytes(key)
 	realKeyPos := f.hashBlock.Get(keyHash)
 
-	looping := 0
 	for realKeyPos > 0 {
 		oKey, err := f.keyFile.GetKey(realKeyPos)
 		if err != nil {
 			return false, err
 		}
+		looping++
 		if bytes.Equal(oKey, key) {
 			ok, err := f.keyFile.Update(realKeyPos, ttl, key, value)
 			if err != nil {
@@ -92,7 +116,6 @@ func (f *IndexKeyFile2) UpdateOrPutTTL(key []byte, value []byte, ttl int64) (boo
 		if err != nil {
 			return false, err
 		}
-		looping++
 	}
 	warnCount := 100
 	if cfg := config.Get(); cfg != nil {
@@ -110,15 +133,17 @@ func (f *IndexKeyFile2) SetTTL(key []byte, ttl int64) (bool, error) {
 	if key == nil {
 		return false, errors.New("invalid key")
 	}
+	start, looping := time.Now(), 0
+	defer func() { ioSt.observe(f.store, OpWrite, start, 0, looping) }()
 	keyHash := util.HashBytes(key)
 	realKeyPos := f.hashBlock.Get(keyHash)
 
-	looping := 0
 	for realKeyPos > 0 {
 		oKey, err := f.keyFile.GetKey(realKeyPos)
 		if err != nil {
 			return false, err
 		}
+		looping++
 		if bytes.Equal(oKey, key) {
 			delOrExp, err := f.keyFile.IsDeletedOrExpired(realKeyPos)
 			if err != nil {
@@ -133,7 +158,6 @@ func (f *IndexKeyFile2) SetTTL(key []byte, ttl int64) (bool, error) {
 		if err != nil {
 			return false, err
 		}
-		looping++
 	}
 	warnCount := 100
 	if cfg := config.Get(); cfg != nil {
@@ -150,15 +174,17 @@ func (f *IndexKeyFile2) Get(key []byte) ([]byte, error) {
 	if key == nil {
 		return nil, errors.New("invalid key")
 	}
+	start, looping := time.Now(), 0
+	defer func() { ioSt.observe(f.store, OpRead, start, 0, looping) }()
 	keyHash := util.HashBytes(key)
 	realKeyPos := f.hashBlock.Get(keyHash)
 
-	looping := 0
 	for realKeyPos > 0 {
 		oKey, err := f.keyFile.GetKey(realKeyPos)
 		if err != nil {
 			return nil, err
 		}
+		looping++
 		if bytes.Equal(oKey, key) {
 			delOrExp, err := f.keyFile.IsDeletedOrExpired(realKeyPos)
 			if err != nil {
@@ -173,7 +199,6 @@ func (f *IndexKeyFile2) Get(key []byte) ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
-		looping++
 	}
 	warnCount := 100
 	if cfg := config.Get(); cfg != nil {
@@ -190,6 +215,8 @@ func (f *IndexKeyFile2) HasKey(key []byte) (bool, error) {
 	if key == nil {
 		return false, errors.New("invalid key")
 	}
+	start, depth := time.Now(), 0
+	defer func() { ioSt.observe(f.store, OpRead, start, 0, depth) }()
 	keyHash := util.HashBytes(key)
 	pos := f.hashBlock.Get(keyHash)
 	for pos > 0 {
@@ -197,6 +224,7 @@ func (f *IndexKeyFile2) HasKey(key []byte) (bool, error) {
 		if err != nil {
 			return false, err
 		}
+		depth++
 		if bytes.Equal(oKey, key) {
 			delOrExp, err := f.keyFile.IsDeletedOrExpired(pos)
 			if err != nil {
@@ -217,6 +245,8 @@ func (f *IndexKeyFile2) GetAll(key []byte) ([][]byte, error) {
 	if key == nil {
 		return nil, errors.New("invalid key")
 	}
+	start, depth := time.Now(), 0
+	defer func() { ioSt.observe(f.store, OpRead, start, 0, depth) }()
 	var out [][]byte
 	keyHash := util.HashBytes(key)
 	pos := f.hashBlock.Get(keyHash)
@@ -225,6 +255,7 @@ func (f *IndexKeyFile2) GetAll(key []byte) ([][]byte, error) {
 		if err != nil {
 			return nil, err
 		}
+		depth++
 		if !isDel {
 			oKey, err := f.keyFile.GetKey(pos)
 			if err != nil {
@@ -251,6 +282,8 @@ func (f *IndexKeyFile2) Delete(key []byte) (int, error) {
 	if key == nil {
 		return 0, errors.New("invalid key")
 	}
+	start, depth := time.Now(), 0
+	defer func() { ioSt.observe(f.store, OpWrite, start, 0, depth) }()
 	keyHash := util.HashBytes(key)
 	pos := f.hashBlock.Get(keyHash)
 	for pos > 0 {
@@ -258,6 +291,7 @@ func (f *IndexKeyFile2) Delete(key []byte) (int, error) {
 		if err != nil {
 			return 0, err
 		}
+		depth++
 		if bytes.Equal(oKey, key) {
 			isDel, err := f.keyFile.IsDeleted(pos)
 			if err != nil {
@@ -302,15 +336,17 @@ func (f *IndexKeyFile2) GetAndRefreshTTL(key []byte, ttl int64) ([]byte, error)
 	if key == nil {
 		return nil, errors.New("invalid key")
 	}
+	start, looping := time.Now(), 0
+	defer func() { ioSt.observe(f.store, OpRead, start, 0, looping) }()
 	keyHash := util.HashBytes(key)
 	realKeyPos := f.hashBlock.Get(keyHash)
 
-	looping := 0
 	for realKeyPos > 0 {
 		oKey, err := f.keyFile.GetKey(realKeyPos)
 		if err != nil {
 			return nil, err
 		}
+		looping++
 		if bytes.Equal(oKey, key) {
 			delOrExp, err := f.keyFile.IsDeletedOrExpired(realKeyPos)
 			if err != nil {
@@ -331,7 +367,6 @@ func (f *IndexKeyFile2) GetAndRefreshTTL(key []byte, ttl int64) ([]byte, error)
 		if err != nil {
 			return nil, err
 		}
-		looping++
 	}
 	warnCount := 100
 	if cfg := config.Get(); cfg != nil {
@@ -372,7 +407,7 @@ func (f *IndexKeyFile2) ReadValidWithDataReader(handler func(key []byte, data []
 		if err != nil {
 			return err
 		}
-		if !r.Deleted && r.Expire > now {
+		if r.live(now) {
 			dataPos := protocol.BigEndian.Int5(r.DataPos)
 			handler(r.TimeKey, reader(dataPos))
 		}
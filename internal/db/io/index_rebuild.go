@@ -0,0 +1,74 @@
+package io
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// RebuildKeyIndex makes the .hfile of the key index at path (as passed to
+// NewIndexKeyFile) a table of hashSizeMB, relinking the chains of the .kfile
+// for it, unless it already is one pointing at the newest record of every
+// bucket. It reports whether the index was rewritten.
+func RebuildKeyIndex(path string, hashSizeMB int) (bool, error) {
+	if hashSizeMB <= 0 {
+		hashSizeMB = defaultHashSizeMB
+	}
+	capacity := hashSizeMB * MB / keyLength
+	return rebuildIndex(path, hashSizeMB*MB, capacity, func(key []byte) int { return bucketOf(key, capacity) })
+}
+
+// RebuildTimeIndex is RebuildKeyIndex for the time index at path (as passed
+// to NewIndexTimeFile). Its buckets depend on the local time zone too, so
+// indexes written by a server in another zone are rebuilt.
+func RebuildTimeIndex(path string) (bool, error) {
+	return rebuildIndex(path, timeBlockBufSize, timeBlockCapacity, func(key []byte) int {
+		return util.GetDateMillis(protocol.BigEndian.Int64(key)) / 500 % timeBlockCapacity
+	})
+}
+
+func rebuildIndex(path string, bufSize, capacity int, bucketOf func(key []byte) int) (bool, error) {
+	kfile, hfile := path+".kfile", path+".hfile"
+	fi, err := os.Stat(kfile)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	size := fi.Size()
+
+	f, err := os.Open(kfile)
+	if err != nil {
+		return false, err
+	}
+	heads := make([]int64, capacity)
+	linked := true
+	validEnd, err := scanKeyRecords(f, size, func(pos, prevPos int64, key []byte, rec []byte) {
+		b := bucketOf(key)
+		if prevPos != heads[b] {
+			linked = false
+		}
+		heads[b] = pos
+	})
+	f.Close()
+	if err != nil {
+		return false, err
+	}
+	if validEnd < size {
+		return false, fmt.Errorf("%s: invalid record at offset %d", kfile, validEnd)
+	}
+
+	hash, _ := readHashFile(hfile)
+	if linked && len(hash) == memHeadReserved+bufSize && hashMatches(hash, heads) {
+		return false, nil
+	}
+	if !linked {
+		if err := relinkKeyFile(kfile, size, capacity, bucketOf); err != nil {
+			return false, err
+		}
+	}
+	return true, writeHashFile(hfile, heads, bufSize)
+}
@@ -0,0 +1,335 @@
+package io
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	stdio "io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// keyFileRepair describes what repairKeyFile changed.
+type keyFileRepair struct {
+	magicFixed     bool
+	truncatedBytes int64 // tail dropped from the .kfile, saved to .kfile.corrupt
+	chainsRelinked bool  // prevPos links of the .kfile rewritten
+	hashRebuilt    bool  // .hfile rebuilt from the .kfile
+	records        int
+}
+
+func (r keyFileRepair) changed() bool {
+	return r.magicFixed || r.truncatedBytes > 0 || r.chainsRelinked || r.hashRebuilt
+}
+
+func (r keyFileRepair) String() string {
+	var parts []string
+	if r.magicFixed {
+		parts = append(parts, "header restored")
+	}
+	if r.truncatedBytes > 0 {
+		parts = append(parts, fmt.Sprintf("%d corrupt tail bytes truncated", r.truncatedBytes))
+	}
+	if r.chainsRelinked {
+		parts = append(parts, "hash chains relinked")
+	}
+	if r.hashRebuilt {
+		parts = append(parts, "hash file rebuilt")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// checkKeyFile repairs the .kfile and .hfile of a hash index before they are
+// opened, if db_index_check_on_open is set. A failed repair is logged and
+// the files are opened as they are.
+func checkKeyFile(path string, hashSizeMB int) {
+	if cfg := config.Get(); cfg != nil && !cfg.DBIndexCheckOnOpen() {
+		return
+	}
+	r, err := repairKeyFile(path, hashSizeMB)
+	if err != nil {
+		slog.Error("Index check failed", "file", path, "error", err)
+		return
+	}
+	if r.changed() {
+		slog.Warn("Index repaired", "file", path, "records", r.records, "repair", r.String())
+	}
+}
+
+// repairKeyFile makes the index at path readable again after a crash or
+// disk fault:
+//   - a .kfile without its 0xCAFE header gets it back;
+//   - a record that cannot be parsed, usually one cut short at the end of
+//     the file, ends the file: it and everything after it are moved to
+//     .kfile.corrupt;
+//   - records whose prevPos does not link to the previous record of their
+//     hash bucket are relinked;
+//   - an .hfile that is missing, damaged or does not point at the newest
+//     record of every bucket (e.g. not flushed before a crash) is rebuilt.
+func repairKeyFile(path string, hashSizeMB int) (keyFileRepair, error) {
+	var r keyFileRepair
+	kfile, hfile := path+".kfile", path+".hfile"
+
+	fi, err := os.Stat(kfile)
+	if err != nil && !os.IsNotExist(err) {
+		return r, err
+	}
+	if err != nil || fi.Size() == 0 {
+		// A hash file without records only points at garbage.
+		if hasNonEmptyHash(hfile) {
+			r.hashRebuilt = true
+			return r, os.Remove(hfile)
+		}
+		return r, nil
+	}
+	size := fi.Size()
+
+	hash, capacity := readHashFile(hfile)
+	bufSize := len(hash) - memHeadReserved
+	if capacity == 0 {
+		if hashSizeMB <= 0 {
+			hashSizeMB = defaultHashSizeMB
+		}
+		bufSize = hashSizeMB * MB
+		capacity = bufSize / keyLength
+	}
+
+	heads := make([]int64, capacity)
+	linked := true
+	validEnd, err := fixKeyFile(kfile, size, &r, func(pos, prevPos int64, key []byte, rec []byte) {
+		// Each record must link to the newest record before it in its bucket.
+		b := bucketOf(key, capacity)
+		if prevPos != heads[b] {
+			linked = false
+		}
+		heads[b] = pos
+		r.records++
+	})
+	if err != nil {
+		return r, err
+	}
+
+	if !linked {
+		if err := relinkKeyFile(kfile, validEnd, capacity, func(key []byte) int { return bucketOf(key, capacity) }); err != nil {
+			return r, err
+		}
+		r.chainsRelinked = true
+	}
+
+	if linked && hash != nil && hashMatches(hash, heads) {
+		return r, nil
+	}
+	r.hashRebuilt = true
+	return r, writeHashFile(hfile, heads, bufSize)
+}
+
+// fixKeyFile restores the header of the .kfile and truncates it after the
+// last valid record, calling fn for each record.
+func fixKeyFile(kfile string, size int64, r *keyFileRepair, fn func(pos, prevPos int64, key []byte, rec []byte)) (int64, error) {
+	f, err := os.OpenFile(kfile, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var header [kfileHeaderSize]byte
+	if _, err := f.ReadAt(header[:], 0); err != nil && err != stdio.EOF {
+		return 0, err
+	}
+	validEnd, err := scanKeyRecords(f, size, fn)
+	if err != nil {
+		return 0, err
+	}
+	if header[0] != 0xCA || header[1] != 0xFE {
+		if _, err := f.WriteAt([]byte{0xCA, 0xFE}, 0); err != nil {
+			return 0, err
+		}
+		r.magicFixed = true
+	}
+	if validEnd < size {
+		if err := saveCorruptTail(f, kfile+".corrupt", validEnd, size); err != nil {
+			return 0, err
+		}
+		if err := f.Truncate(validEnd); err != nil {
+			return 0, err
+		}
+		r.truncatedBytes = size - validEnd
+	}
+	return validEnd, nil
+}
+
+// scanKeyRecords calls fn for each record of the .kfile from the header on
+// and returns the offset where the valid records end.
+func scanKeyRecords(f *os.File, size int64, fn func(pos, prevPos int64, key []byte, rec []byte)) (int64, error) {
+	br := bufio.NewReaderSize(stdio.NewSectionReader(f, kfileHeaderSize, size-kfileHeaderSize), 256*1024)
+	pos := int64(kfileHeaderSize)
+	var rec []byte
+	for pos < size {
+		rec = rec[:0]
+		read := func(n int) ([]byte, bool) {
+			start := len(rec)
+			rec = slices.Grow(rec, n)[:start+n]
+			_, err := stdio.ReadFull(br, rec[start:])
+			return rec[start:], err == nil
+		}
+
+		head, ok := read(1 + 5 + 2)
+		if !ok || head[0] > 1 {
+			return pos, nil
+		}
+		// A bad prevPos does not end the file; relinking repairs it.
+		prevPos := protocol.BigEndian.Int5(head[1:6])
+		keyLen := int(binary.BigEndian.Uint16(head[6:8]))
+		if pos+int64(8+keyLen) > size {
+			return pos, nil
+		}
+		if _, ok := read(keyLen); !ok {
+			return pos, nil
+		}
+
+		prefix, ok := read(1)
+		if !ok {
+			return pos, nil
+		}
+		var blobLen int64
+		switch prefix[0] {
+		case 255:
+			l, ok := read(2)
+			if !ok {
+				return pos, nil
+			}
+			blobLen = int64(binary.BigEndian.Uint16(l))
+		case 254:
+			l, ok := read(4)
+			if !ok {
+				return pos, nil
+			}
+			blobLen = int64(int32(binary.BigEndian.Uint32(l)))
+		default:
+			blobLen = int64(prefix[0])
+		}
+		if blobLen < 0 || pos+int64(len(rec))+blobLen > size {
+			return pos, nil
+		}
+		if _, ok := read(int(blobLen)); !ok {
+			return pos, nil
+		}
+		fn(pos, prevPos, rec[8:8+keyLen], rec)
+		pos += int64(len(rec))
+	}
+	return pos, nil
+}
+
+// relinkKeyFile rewrites the .kfile with every record linked to the
+// previous record of its bucket among capacity.
+func relinkKeyFile(kfile string, size int64, capacity int, bucketOf func(key []byte) int) error {
+	f, err := os.Open(kfile)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.Create(kfile + ".tmp")
+	if err != nil {
+		f.Close()
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriterSize(tmp, 256*1024)
+	w.Write([]byte{0xCA, 0xFE})
+
+	heads := make([]int64, capacity)
+	var werr error
+	if _, err := scanKeyRecords(f, size, func(pos, prevPos int64, key []byte, rec []byte) {
+		b := bucketOf(key)
+		protocol.BigEndian.PutInt5(rec[1:6], heads[b])
+		heads[b] = pos
+		if _, err := w.Write(rec); err != nil && werr == nil {
+			werr = err
+		}
+	}); err != nil {
+		werr = err
+	}
+	f.Close()
+	if werr == nil {
+		werr = w.Flush()
+	}
+	if err := tmp.Close(); werr == nil {
+		werr = err
+	}
+	if werr != nil {
+		return werr
+	}
+	return os.Rename(tmp.Name(), kfile)
+}
+
+// saveCorruptTail copies the bytes of f from start to end to path.
+func saveCorruptTail(f *os.File, path string, start, end int64) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := stdio.Copy(out, stdio.NewSectionReader(f, start, end-start)); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// readHashFile returns the buckets of an .hfile and their number, or nil and
+// 0 if the file is missing or damaged.
+func readHashFile(hfile string) ([]byte, int) {
+	data, err := os.ReadFile(hfile)
+	if err != nil || len(data) < memHeadReserved+keyLength || data[0] != 0xCA || data[1] != 0xFE {
+		return nil, 0
+	}
+	return data, (len(data) - memHeadReserved) / keyLength
+}
+
+func hasNonEmptyHash(hfile string) bool {
+	data, _ := readHashFile(hfile)
+	for _, b := range data[min(len(data), memHeadReserved):] {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func hashMatches(hash []byte, heads []int64) bool {
+	for i, head := range heads {
+		if protocol.BigEndian.Int5(hash[memHeadReserved+i*keyLength:]) != head {
+			return false
+		}
+	}
+	return true
+}
+
+// writeHashFile writes an .hfile of bufSize bucket bytes whose buckets point
+// at heads.
+func writeHashFile(hfile string, heads []int64, bufSize int) error {
+	buf := make([]byte, memHeadReserved+bufSize)
+	buf[0], buf[1] = 0xCA, 0xFE
+	count := 0
+	for i, head := range heads {
+		if head != 0 {
+			protocol.BigEndian.PutInt5(buf[memHeadReserved+i*keyLength:], head)
+			count++
+		}
+	}
+	protocol.BigEndian.PutInt32(buf[4:], int32(count))
+	tmp := hfile + ".tmp"
+	if err := os.WriteFile(tmp, buf, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, hfile)
+}
+
+// bucketOf returns the hash bucket of key, as MemHashBlock.offset does.
+func bucketOf(key []byte, capacity int) int {
+	return int(util.HashBytes(key)&0x7FFFFFFF) % capacity
+}
@@ -3,6 +3,7 @@ package io
 import (
 	"errors"
 	"sort"
+	"time"
 
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/util"
@@ -18,6 +19,7 @@ type TimeToData struct {
 // It provides 500ms-resolution bucketed access plus chain-based collision storage.
 type IndexTimeFile struct {
 	path          string
+	store         string
 	timeBlockHash *MemTimeBlock
 	keyFile       *RealKeyFile
 }
@@ -34,15 +36,27 @@ func NewIndexTimeFile(path string) (*IndexTimeFile, error) {
 	}
 	return &IndexTimeFile{
 		path:          path,
+		store:         StoreOf(path),
 		timeBlockHash: tb,
 		keyFile:       kf,
 	}, nil
 }
 
+// File returns the path the index was opened with.
+func (f *IndexTimeFile) File() string {
+	return f.path
+}
+
+// Store returns the storage the index belongs to.
+func (f *IndexTimeFile) Store() string {
+	return f.store
+}
+
 func (f *IndexTimeFile) Put(timeMs int64, dataPos []byte) (int64, error) {
 	if timeMs <= 0 || dataPos == nil {
 		return 0, errors.New("invalid key/value")
 	}
+	defer ioSt.observe(f.store, OpWrite, time.Now(), 0, -1)
 	prevKeyPos := f.timeBlockHash.Get(timeMs)
 	newKeyPos, err := f.keyFile.Append(prevKeyPos, protocol.BigEndian.Bytes8(timeMs), dataPos)
 	if err != nil {
@@ -57,19 +71,23 @@ func (f *IndexTimeFile) getSecAll(timeMs int64) ([]TimeToData, error) {
 	if timeMs <= 0 {
 		return nil, errors.New("invalid key")
 	}
+	start := time.Now()
 	var items []TimeToData
 	pos := f.timeBlockHash.Get(timeMs)
+	depth := 0
 	for pos > 0 {
 		r, err := f.keyFile.GetRecord(pos)
 		if err != nil {
 			return nil, err
 		}
+		depth++
 		if !r.Deleted {
 			t := protocol.BigEndian.Int64(r.TimeKey)
 			items = append(items, TimeToData{Time: t, DataPos: r.DataPos})
 		}
 		pos = r.PrevPos
 	}
+	ioSt.observe(f.store, OpRead, start, 0, depth)
 	// Sort by time ascending
 	sort.Slice(items, func(i, j int) bool {
 		return items[i].Time < items[j].Time
@@ -78,6 +96,7 @@ func (f *IndexTimeFile) getSecAll(timeMs int64) ([]TimeToData, error) {
 }
 
 func (f *IndexTimeFile) GetDirect(pos int64) (*TimeToData, error) {
+	defer ioSt.observe(f.store, OpRead, time.Now(), 0, -1)
 	r, err := f.keyFile.GetRecord(pos)
 	if err != nil {
 		return nil, err
@@ -95,6 +114,8 @@ func (f *IndexTimeFile) Delete(timeMs int64) (int, error) {
 	if timeMs <= 0 {
 		return 0, errors.New("invalid key")
 	}
+	start, depth := time.Now(), 0
+	defer func() { ioSt.observe(f.store, OpWrite, start, 0, depth) }()
 	pos := f.timeBlockHash.Get(timeMs)
 	deleted := 0
 	for pos > 0 {
@@ -102,6 +123,7 @@ func (f *IndexTimeFile) Delete(timeMs int64) (int, error) {
 		if err != nil {
 			return deleted, err
 		}
+		depth++
 		if !isDel {
 			if err := f.keyFile.SetDelete(pos, true); err != nil {
 				return deleted, err
@@ -0,0 +1,130 @@
+package io
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// File is implemented by every index and data file of the package, so the
+// storages open, name, measure and close them the same way whatever the
+// file layout behind them.
+type File interface {
+	// File returns the path the file was opened with.
+	File() string
+	// Store returns the storage the file belongs to (see StoreOf).
+	Store() string
+	Close()
+}
+
+var (
+	_ File = (*IndexKeyFile)(nil)
+	_ File = (*IndexKeyFile2)(nil)
+	_ File = (*IndexTimeFile)(nil)
+	_ File = (*RealDataFile)(nil)
+)
+
+// Operations measured by the I/O statistics.
+const (
+	OpRead  = "read"
+	OpWrite = "write"
+)
+
+// latencyBounds are the upper bounds of the latency buckets in microseconds.
+var latencyBounds = []int64{
+	10, 20, 50, 100, 200, 500,
+	1000, 2000, 5000, 10000, 20000, 50000,
+	100000, 200000, 500000, 1000000,
+}
+
+// depthBounds are the upper bounds of the chain depth buckets in records.
+var depthBounds = []int64{1, 2, 3, 4, 6, 8, 12, 16, 24, 32, 48, 64, 128, 256}
+
+// StoreOf returns the storage a file path belongs to: the day directory the
+// file sits in ("xlog", "counter", "text", "alert", "summary", ...), with
+// the profiles kept in the xlog directory reported as "profile".
+func StoreOf(path string) string {
+	if strings.HasPrefix(filepath.Base(path), "xlog_prof") {
+		return "profile"
+	}
+	dir := filepath.Base(filepath.Dir(path))
+	if dir == "." || dir == string(filepath.Separator) {
+		return "other"
+	}
+	return dir
+}
+
+// Histogram counts operation latencies in microseconds or queue depths.
+type Histogram = util.Histogram[int64]
+
+// OpStat describes the reads or writes of one storage.
+type OpStat struct {
+	Store   string
+	Op      string
+	Bytes   int64     // data bytes, 0 for index operations
+	Latency Histogram // microseconds, one observation per operation
+	Depth   Histogram // hash chain records walked, key lookups only
+}
+
+type opKey struct {
+	store string
+	op    string
+}
+
+// ioStats counts the lookups, appends and data reads and writes of the
+// files of the package per storage and operation, with their latency and
+// the depth of the hash chains walked, so a slow disk or an overloaded
+// index shows up the same way for every storage.
+var ioSt = &ioStats{ops: make(map[opKey]*OpStat)}
+
+type ioStats struct {
+	mu  sync.Mutex
+	ops map[opKey]*OpStat
+}
+
+// GetIOStats returns the process-wide I/O statistics.
+func GetIOStats() *ioStats {
+	return ioSt
+}
+
+// observe records one operation of store started at start. depth is the
+// number of chain records walked, negative for operations that walk none.
+func (s *ioStats) observe(store, op string, start time.Time, bytes int, depth int) {
+	us := time.Since(start).Microseconds()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := opKey{store, op}
+	st := s.ops[key]
+	if st == nil {
+		st = &OpStat{Store: store, Op: op, Latency: util.NewHistogram(latencyBounds), Depth: util.NewHistogram(depthBounds)}
+		s.ops[key] = st
+	}
+	st.Latency.Observe(us)
+	st.Bytes += int64(bytes)
+	if depth >= 0 {
+		st.Depth.Observe(int64(depth))
+	}
+}
+
+// Drain returns the statistics recorded since the last Drain ordered by
+// storage and operation, and starts new ones.
+func (s *ioStats) Drain() []OpStat {
+	s.mu.Lock()
+	result := make([]OpStat, 0, len(s.ops))
+	for _, st := range s.ops {
+		result = append(result, *st)
+	}
+	clear(s.ops)
+	s.mu.Unlock()
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Store != result[j].Store {
+			return result[i].Store < result[j].Store
+		}
+		return result[i].Op < result[j].Op
+	})
+	return result
+}
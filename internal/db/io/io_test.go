@@ -1,10 +1,14 @@
 package io
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/util"
 )
@@ -1268,3 +1272,430 @@ func TestIndexKeyFile2Delete(t *testing.T) {
 		t.Error("expected nil after delete")
 	}
 }
+
+// fakeFlushable counts flushes and reports a settable dirty size.
+type fakeFlushable struct {
+	dirty   int
+	flushes int
+}
+
+func (f *fakeFlushable) Flush()                  { f.flushes++; f.dirty = 0 }
+func (f *fakeFlushable) IsDirty() bool           { return f.dirty > 0 }
+func (f *fakeFlushable) Interval() time.Duration { return 2 * time.Second }
+func (f *fakeFlushable) File() string            { return "fake.kfile" }
+func (f *fakeFlushable) DirtyBytes() int         { return f.dirty }
+
+func TestFlushControllerAdaptiveInterval(t *testing.T) {
+	dir := tempDir(t)
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("flush_max_interval_ms=10000\nflush_dirty_bytes_threshold=1000\n"), 0644)
+	if _, err := config.Load(conf); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	f := &fakeFlushable{}
+	for _, tc := range []struct {
+		dirty int
+		want  time.Duration
+	}{
+		{1, 10*time.Second - 8*time.Millisecond},
+		{500, 6 * time.Second},
+		{1000, 0},
+	} {
+		if got := flushInterval(f, tc.dirty); got != tc.want {
+			t.Errorf("flushInterval(dirty=%d) = %s, want %s", tc.dirty, got, tc.want)
+		}
+	}
+
+	t0 := time.Now()
+	fc := &flushController{items: map[IFlushable]*flushState{f: {registered: t0}}, started: true}
+	f.dirty = 100 // interval 9.2s
+	fc.flushDue(t0.Add(3 * time.Second))
+	if f.flushes != 0 {
+		t.Fatal("lightly dirty file flushed before its interval")
+	}
+	fc.flushDue(t0.Add(10 * time.Second))
+	if f.flushes != 1 {
+		t.Fatal("lightly dirty file not flushed after its interval")
+	}
+	f.dirty = 2000
+	fc.flushDue(t0.Add(11 * time.Second))
+	if f.flushes != 2 {
+		t.Fatal("file over the dirty threshold not flushed on the next tick")
+	}
+
+	stats := fc.Stats()
+	if len(stats) != 1 || stats[0].File != "fake.kfile" || stats[0].Flushes != 2 || stats[0].Bytes != 2100 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if stats[0].Interval != 0 || !stats[0].LastFlush.Equal(t0.Add(11*time.Second)) {
+		t.Fatalf("unexpected interval/last flush %+v", stats[0])
+	}
+
+	os.WriteFile(conf, []byte("flush_adaptive_enabled=false\n"), 0644)
+	if _, err := config.Load(conf); err != nil {
+		t.Fatal(err)
+	}
+	if got := flushInterval(f, 5000); got != 2*time.Second {
+		t.Fatalf("non-adaptive interval = %s, want the file's own 2s", got)
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	dir := tempDir(t)
+	fi := GetFaultInjector()
+	t.Cleanup(func() { fi.Clear() })
+
+	fault, err := ParseFault([]string{"write", "match=xlog", "error=100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fi.Add(fault); err == nil {
+		t.Fatal("fault added while testing_fault_injection_enabled is off")
+	}
+
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("testing_fault_injection_enabled=true\n"), 0644)
+	if _, err := config.Load(conf); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+	if err := fi.Add(fault); err != nil {
+		t.Fatal(err)
+	}
+
+	xlogData, _ := NewRealDataFile(filepath.Join(dir, "xlog.data"))
+	defer xlogData.Close()
+	if _, err := xlogData.Write([]byte("x")); !errors.Is(err, ErrInjected) {
+		t.Errorf("xlog write err = %v, want ErrInjected", err)
+	}
+	otherData, _ := NewRealDataFile(filepath.Join(dir, "counter.data"))
+	defer otherData.Close()
+	if _, err := otherData.Write([]byte("x")); err != nil {
+		t.Errorf("unmatched write failed: %v", err)
+	}
+
+	slow, _ := ParseFault([]string{"read", "latency=30ms", "for=1h"})
+	fi.Add(slow)
+	idx, _ := NewIndexKeyFile(filepath.Join(dir, "counter_idx"), 1)
+	defer idx.Close()
+	if err := idx.Put([]byte("k"), []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if v, err := idx.Get([]byte("k")); err != nil || len(v) != 1 {
+		t.Fatalf("Get = %v, %v", v, err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("read took %s, want injected latency", elapsed)
+	}
+	if faults := fi.List(); len(faults) != 2 || faults[0].Hits != 1 || faults[1].Hits != 1 {
+		t.Errorf("faults = %+v", faults)
+	}
+
+	// Turning the setting off disables installed faults at once.
+	config.Load(filepath.Join(dir, "missing.conf"))
+	if _, err := xlogData.Write([]byte("x")); err != nil {
+		t.Errorf("write failed with injection disabled: %v", err)
+	}
+	if n := fi.Clear(); n != 2 {
+		t.Errorf("cleared %d faults, want 2", n)
+	}
+
+	for _, args := range [][]string{{}, {"delete"}, {"read"}, {"read", "error=101"}, {"write", "latency"}, {"read", "speed=1"}} {
+		if _, err := ParseFault(args); err == nil {
+			t.Errorf("ParseFault(%q) accepted", args)
+		}
+	}
+}
+
+func TestWriteStats(t *testing.T) {
+	s := GetWriteStats()
+	s.reset()
+	defer s.reset()
+
+	s.Record("profile", "20260101", 1, 100)
+	s.Record("profile", "20260101", 2, 5000)
+	s.Record("profile", "20260101", 2, 5000)
+	s.Record("xlog", "20260101", 1, 10)
+	s.Record("alert", "20260102", 0, 50)
+
+	days := s.Days("")
+	if len(days) != 3 || days[0].Kind != "alert" || days[1].Kind != "profile" || days[2].Kind != "xlog" {
+		t.Fatalf("Days = %+v", days)
+	}
+	if p := days[1]; p.Entries != 3 || p.Bytes != 10100 || p.Objects != 2 || p.LastWrite.IsZero() {
+		t.Errorf("profile = %+v", p)
+	}
+	if days[0].Objects != 0 {
+		t.Errorf("alert objects = %d, want 0 for unknown objHash", days[0].Objects)
+	}
+	objs := s.Objects("20260101", "profile")
+	if len(objs) != 2 || objs[0] != (ObjectWriteStat{ObjHash: 2, Entries: 2, Bytes: 10000}) {
+		t.Errorf("Objects = %+v", objs)
+	}
+	if got := s.Days("20260102"); len(got) != 1 {
+		t.Errorf("Days(20260102) = %+v", got)
+	}
+
+	// Only the latest writeStatsKeepDays dates are kept.
+	for d := 3; d <= 10; d++ {
+		s.Record("xlog", fmt.Sprintf("202601%02d", d), 1, 1)
+	}
+	days = s.Days("")
+	if len(days) != writeStatsKeepDays || days[len(days)-1].Date != "20260104" {
+		t.Errorf("kept %d, oldest %+v", len(days), days[len(days)-1])
+	}
+}
+
+func TestIndexKeyFileRepairOnOpen(t *testing.T) {
+	dir := tempDir(t)
+	path := filepath.Join(dir, "text")
+
+	idx, err := NewIndexKeyFile(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		idx.Put([]byte(fmt.Sprintf("key-%d", i)), []byte{byte(i)})
+	}
+	idx.Close()
+
+	kfile := path + ".kfile"
+	data, _ := os.ReadFile(kfile)
+	validSize := len(data)
+	// A record cut short by a crash, and a hash file lost with it.
+	data = append(data, 0, 0, 0, 0, 0, 2, 0, 50, 'k')
+	data[0], data[1] = 0, 0
+	os.WriteFile(kfile, data, 0644)
+	os.Remove(path + ".hfile")
+
+	r, err := repairKeyFile(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.magicFixed || r.truncatedBytes != 9 || !r.hashRebuilt || r.chainsRelinked || r.records != 100 {
+		t.Fatalf("repair = %+v", r)
+	}
+	if fi, _ := os.Stat(kfile); fi.Size() != int64(validSize) {
+		t.Errorf("kfile size = %d, want %d", fi.Size(), validSize)
+	}
+	if tail, _ := os.ReadFile(kfile + ".corrupt"); len(tail) != 9 {
+		t.Errorf("corrupt tail = %d bytes, want 9", len(tail))
+	}
+	if r, _ := repairKeyFile(path, 1); r.changed() {
+		t.Errorf("second repair = %+v, want no change", r)
+	}
+
+	idx, err = NewIndexKeyFile(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	for i := 0; i < 100; i++ {
+		v, err := idx.Get([]byte(fmt.Sprintf("key-%d", i)))
+		if err != nil || len(v) != 1 || v[0] != byte(i) {
+			t.Fatalf("Get(key-%d) = %v, %v", i, v, err)
+		}
+	}
+	if err := idx.Put([]byte("key-new"), []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIOStats(t *testing.T) {
+	dir := filepath.Join(tempDir(t), "xlog")
+	os.MkdirAll(dir, 0755)
+	stats := GetIOStats()
+	stats.Drain()
+
+	idx, err := NewIndexKeyFile(filepath.Join(dir, "xlog_prof"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	data, err := NewRealDataFile(filepath.Join(dir, "xlog.data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	var files []File = []File{idx, data}
+	if files[0].Store() != "profile" || files[1].Store() != "xlog" {
+		t.Fatalf("stores = %s, %s", files[0].Store(), files[1].Store())
+	}
+
+	idx.Put([]byte("tx"), []byte{1})
+	idx.Put([]byte("tx"), []byte{2})
+	if all, _ := idx.GetAll([]byte("tx")); len(all) != 2 {
+		t.Fatalf("GetAll = %v", all)
+	}
+	pos, _ := data.Write([]byte("hello"))
+	data.Flush()
+	buf := make([]byte, 5)
+	if _, err := data.ReadAt(buf, pos); err != nil || string(buf) != "hello" {
+		t.Fatalf("ReadAt = %q, %v", buf, err)
+	}
+
+	got := make(map[string]OpStat)
+	for _, st := range stats.Drain() {
+		got[st.Store+" "+st.Op] = st
+	}
+	if st := got["profile write"]; st.Latency.Count != 2 || st.Depth.Count != 0 {
+		t.Errorf("profile write = %+v", st)
+	}
+	if st := got["profile read"]; st.Latency.Count != 1 || st.Depth.Max != 2 || st.Depth.Quantile(0.99) != 2 {
+		t.Errorf("profile read = %+v", st)
+	}
+	if st := got["xlog write"]; st.Latency.Count != 1 || st.Bytes != 5 {
+		t.Errorf("xlog write = %+v", st)
+	}
+	if st := got["xlog read"]; st.Latency.Count != 1 || st.Bytes != 5 {
+		t.Errorf("xlog read = %+v", st)
+	}
+	if len(stats.Drain()) != 0 {
+		t.Error("Drain did not start over")
+	}
+
+	data.Close()
+	if _, err := data.ReadAt(buf, pos); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("ReadAt after Close = %v", err)
+	}
+}
+
+func TestRebuildIndex(t *testing.T) {
+	dir := tempDir(t)
+	path := filepath.Join(dir, "text")
+
+	idx, err := NewIndexKeyFile(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		idx.Put([]byte(fmt.Sprintf("key-%d", i)), []byte{byte(i)})
+	}
+	idx.Close()
+
+	if rebuilt, err := RebuildKeyIndex(path, 1); err != nil || rebuilt {
+		t.Fatalf("RebuildKeyIndex at the same size = %v, %v, want no change", rebuilt, err)
+	}
+	if rebuilt, err := RebuildKeyIndex(path, 2); err != nil || !rebuilt {
+		t.Fatalf("RebuildKeyIndex to 2MB = %v, %v", rebuilt, err)
+	}
+	if fi, _ := os.Stat(path + ".hfile"); fi.Size() != memHeadReserved+2*MB {
+		t.Errorf("hfile size = %d, want %d", fi.Size(), memHeadReserved+2*MB)
+	}
+	if rebuilt, _ := RebuildKeyIndex(path, 2); rebuilt {
+		t.Error("second RebuildKeyIndex rewrote the index")
+	}
+	idx, err = NewIndexKeyFile(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		v, err := idx.Get([]byte(fmt.Sprintf("key-%d", i)))
+		if err != nil || len(v) != 1 || v[0] != byte(i) {
+			t.Fatalf("Get(key-%d) = %v, %v", i, v, err)
+		}
+	}
+	idx.Close()
+
+	tpath := filepath.Join(dir, "tidx")
+	tidx, err := NewIndexTimeFile(tpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseTime := int64(1705312245000)
+	for i := 0; i < 10; i++ {
+		tidx.Put(baseTime+int64(i)*300, protocol.BigEndian.Bytes5(int64(i+1)))
+	}
+	tidx.Close()
+	if rebuilt, err := RebuildTimeIndex(tpath); err != nil || rebuilt {
+		t.Fatalf("RebuildTimeIndex = %v, %v, want no change", rebuilt, err)
+	}
+	os.Remove(tpath + ".hfile")
+	if rebuilt, err := RebuildTimeIndex(tpath); err != nil || !rebuilt {
+		t.Fatalf("RebuildTimeIndex without hfile = %v, %v", rebuilt, err)
+	}
+	tidx, err = NewIndexTimeFile(tpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tidx.Close()
+	n := 0
+	tidx.Read(baseTime, baseTime+3000, func(time int64, dataPos []byte) bool {
+		n++
+		return true
+	})
+	if n != 10 {
+		t.Errorf("read %d entries after rebuild, want 10", n)
+	}
+}
+
+func TestCompactIndex(t *testing.T) {
+	dir := tempDir(t)
+	path := filepath.Join(dir, "text")
+
+	idx, err := NewIndexKeyFile(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	for i := 0; i < 100; i++ {
+		idx.Put([]byte(fmt.Sprintf("key-%d", i)), []byte{byte(i)})
+	}
+	for i := 0; i < 10; i++ {
+		idx.Delete([]byte(fmt.Sprintf("key-%d", i)))
+	}
+	if res, err := idx.Compact(20); err != nil || res.Compacted || res.Dropped != 10 {
+		t.Fatalf("Compact(20) with 10%% deleted = %+v, %v, want no rewrite", res, err)
+	}
+	res, err := idx.Compact(10)
+	if err != nil || !res.Compacted || res.Kept != 90 || res.Reclaimed() <= 0 {
+		t.Fatalf("Compact(10) = %+v, %v", res, err)
+	}
+	for i := 0; i < 100; i++ {
+		v, err := idx.Get([]byte(fmt.Sprintf("key-%d", i)))
+		if err != nil || (i < 10) != (v == nil) || (v != nil && v[0] != byte(i)) {
+			t.Fatalf("Get(key-%d) after compaction = %v, %v", i, v, err)
+		}
+	}
+	if _, err := os.Stat(path + compactSuffix + ".kfile"); !os.IsNotExist(err) {
+		t.Error("temporary key file left behind")
+	}
+	idx.Put([]byte("key-new"), []byte{1})
+	if v, _ := idx.Get([]byte("key-new")); len(v) != 1 {
+		t.Error("Put after compaction not readable")
+	}
+
+	path2 := filepath.Join(dir, "idx2")
+	idx2, err := NewIndexKeyFile2(path2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx2.Close()
+	for i := 0; i < 10; i++ {
+		idx2.PutTTL([]byte(fmt.Sprintf("key-%d", i)), protocol.BigEndian.Bytes5(int64(i)), 3600)
+	}
+	idx2.Put([]byte("forever"), protocol.BigEndian.Bytes5(99))
+	for i := 0; i < 5; i++ {
+		pos := idx2.hashBlock.Get(util.HashBytes([]byte(fmt.Sprintf("key-%d", i))))
+		idx2.keyFile.SetExpire(pos, 1)
+	}
+	res, err = idx2.Compact(20)
+	if err != nil || !res.Compacted || res.Kept != 6 || res.Dropped != 5 {
+		t.Fatalf("Compact of IndexKeyFile2 = %+v, %v", res, err)
+	}
+	if has, _ := idx2.HasKey([]byte("key-7")); !has {
+		t.Error("live record dropped")
+	}
+	if has, _ := idx2.HasKey([]byte("forever")); !has {
+		t.Error("record without TTL dropped")
+	}
+	// Copied records keep their expiry.
+	pos := idx2.hashBlock.Get(util.HashBytes([]byte("key-7")))
+	r, err := idx2.keyFile.GetRecord(pos)
+	if err != nil || r.Expire <= time.Now().Unix() || r.Expire > time.Now().Unix()+3600 {
+		t.Fatalf("expiry after compaction = %v, %v", r, err)
+	}
+}
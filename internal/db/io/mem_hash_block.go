@@ -24,6 +24,8 @@ type MemHashBlock struct {
 	count    int
 	capacity int
 	dirty    bool
+	// dirtyBytes counts the bucket bytes changed since the last flush.
+	dirtyBytes int
 }
 
 func NewMemHashBlock(path string, memSize int) (*MemHashBlock, error) {
@@ -94,6 +96,7 @@ func (m *MemHashBlock) Put(keyHash int32, value int64) {
 	}
 	copy(m.buf[pos:], b)
 	m.dirty = true
+	m.dirtyBytes += keyLength
 }
 
 func (m *MemHashBlock) Flush() {
@@ -104,6 +107,7 @@ func (m *MemHashBlock) Flush() {
 	}
 	_ = os.WriteFile(m.file, m.buf, 0644)
 	m.dirty = false
+	m.dirtyBytes = 0
 }
 
 func (m *MemHashBlock) IsDirty() bool {
@@ -116,6 +120,25 @@ func (m *MemHashBlock) Interval() time.Duration {
 	return 4 * time.Second
 }
 
+// File implements IFlushStats.
+func (m *MemHashBlock) File() string {
+	return m.file
+}
+
+// DirtyBytes implements IFlushStats.
+func (m *MemHashBlock) DirtyBytes() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dirtyBytes
+}
+
+// MemBytes implements IMemSize.
+func (m *MemHashBlock) MemBytes() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buf)
+}
+
 func (m *MemHashBlock) Close() {
 	m.Flush()
 	GetFlushController().Unregister(m)
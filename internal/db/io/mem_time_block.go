@@ -23,6 +23,8 @@ type MemTimeBlock struct {
 	bufSize  int
 	count    int
 	dirty    bool
+	// dirtyBytes counts the bucket bytes changed since the last flush.
+	dirtyBytes int
 }
 
 func NewMemTimeBlock(path string) (*MemTimeBlock, error) {
@@ -99,6 +101,7 @@ func (m *MemTimeBlock) Put(timeMs int64, value int64) {
 	}
 	copy(m.buf[pos:], b)
 	m.dirty = true
+	m.dirtyBytes += keyLength
 }
 
 func (m *MemTimeBlock) Flush() {
@@ -109,6 +112,7 @@ func (m *MemTimeBlock) Flush() {
 	}
 	_ = os.WriteFile(m.file, m.buf, 0644)
 	m.dirty = false
+	m.dirtyBytes = 0
 }
 
 func (m *MemTimeBlock) IsDirty() bool {
@@ -121,6 +125,25 @@ func (m *MemTimeBlock) Interval() time.Duration {
 	return 4 * time.Second
 }
 
+// File implements IFlushStats.
+func (m *MemTimeBlock) File() string {
+	return m.file
+}
+
+// DirtyBytes implements IFlushStats.
+func (m *MemTimeBlock) DirtyBytes() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dirtyBytes
+}
+
+// MemBytes implements IMemSize.
+func (m *MemTimeBlock) MemBytes() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buf)
+}
+
 func (m *MemTimeBlock) Close() {
 	m.Flush()
 	GetFlushController().Unregister(m)
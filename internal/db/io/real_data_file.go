@@ -5,15 +5,20 @@ import (
 	"encoding/binary"
 	"os"
 	"sync"
+	"time"
 )
 
-// RealDataFile is an append-only data file with buffered writes.
+// RealDataFile is an append-only data file with buffered writes. Reads go
+// through ReadAt, which does not wait for the writers.
 type RealDataFile struct {
 	mu       sync.Mutex
 	filename string
+	store    string
 	offset   int64
 	file     *os.File
 	writer   *bufio.Writer
+
+	closeMu sync.RWMutex // held for reading by ReadAt, for writing by Close
 }
 
 func NewRealDataFile(filename string) (*RealDataFile, error) {
@@ -30,6 +35,7 @@ func NewRealDataFile(filename string) (*RealDataFile, error) {
 
 	return &RealDataFile{
 		filename: filename,
+		store:    StoreOf(filename),
 		offset:   fi.Size(),
 		file:     f,
 		writer:   bufio.NewWriterSize(f, 8192),
@@ -41,6 +47,16 @@ func (f *RealDataFile) Filename() string {
 	return f.filename
 }
 
+// File returns the file path.
+func (f *RealDataFile) File() string {
+	return f.filename
+}
+
+// Store returns the storage the file belongs to.
+func (f *RealDataFile) Store() string {
+	return f.store
+}
+
 func (f *RealDataFile) Offset() int64 {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -48,6 +64,10 @@ func (f *RealDataFile) Offset() int64 {
 }
 
 func (f *RealDataFile) WriteShort(s int16) (int64, error) {
+	if err := faultInj.check(FaultWrite, f.filename); err != nil {
+		return 0, err
+	}
+	defer ioSt.observe(f.store, OpWrite, time.Now(), 2, -1)
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	idx := f.offset
@@ -61,6 +81,10 @@ func (f *RealDataFile) WriteShort(s int16) (int64, error) {
 }
 
 func (f *RealDataFile) WriteInt(i int32) (int64, error) {
+	if err := faultInj.check(FaultWrite, f.filename); err != nil {
+		return 0, err
+	}
+	defer ioSt.observe(f.store, OpWrite, time.Now(), 4, -1)
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	idx := f.offset
@@ -74,6 +98,10 @@ func (f *RealDataFile) WriteInt(i int32) (int64, error) {
 }
 
 func (f *RealDataFile) Write(data []byte) (int64, error) {
+	if err := faultInj.check(FaultWrite, f.filename); err != nil {
+		return 0, err
+	}
+	defer ioSt.observe(f.store, OpWrite, time.Now(), len(data), -1)
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	idx := f.offset
@@ -84,6 +112,20 @@ func (f *RealDataFile) Write(data []byte) (int64, error) {
 	return idx, nil
 }
 
+// ReadAt reads len(p) bytes at off (pread), concurrently with other reads
+// and with the writers. Only flushed data can be read.
+func (f *RealDataFile) ReadAt(p []byte, off int64) (int, error) {
+	start := time.Now()
+	f.closeMu.RLock()
+	defer f.closeMu.RUnlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	n, err := f.file.ReadAt(p, off)
+	ioSt.observe(f.store, OpRead, start, n, -1)
+	return n, err
+}
+
 func (f *RealDataFile) Flush() error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -91,6 +133,8 @@ func (f *RealDataFile) Flush() error {
 }
 
 func (f *RealDataFile) Close() {
+	f.closeMu.Lock()
+	defer f.closeMu.Unlock()
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	if f.writer != nil {
@@ -112,11 +112,26 @@ func (f *RealKeyFile) Interval() time.Duration {
 	return 2 * time.Second
 }
 
+// File implements IFlushStats.
+func (f *RealKeyFile) File() string {
+	return f.file
+}
+
+// DirtyBytes implements IFlushStats.
+func (f *RealKeyFile) DirtyBytes() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.appendBuf)
+}
+
 // GetRecord reads a complete record at the given position.
 // For on-disk positions (pos < fileEnd), uses ReadAt without flushing
 // or exclusive locking, enabling concurrent reads from multiple goroutines.
 // For buffered positions, flushes first under exclusive lock.
 func (f *RealKeyFile) GetRecord(pos int64) (*KeyRecord, error) {
+	if err := faultInj.check(FaultRead, f.file); err != nil {
+		return nil, err
+	}
 	f.mu.RLock()
 	onDisk := pos < f.fileEnd
 	f.mu.RUnlock()
@@ -442,6 +457,9 @@ func (f *RealKeyFile) Update(pos int64, key []byte, value []byte) (bool, error)
 // The data is buffered in memory and flushed when the buffer exceeds the threshold
 // or before the next read/positional-write operation.
 func (f *RealKeyFile) Append(prevPos int64, indexKey []byte, dataPos []byte) (int64, error) {
+	if err := faultInj.check(FaultWrite, f.file); err != nil {
+		return 0, err
+	}
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
@@ -20,6 +20,11 @@ type KeyRecord2 struct {
 	Offset  int64 // file offset after this record
 }
 
+// live reports whether the record is neither deleted nor expired at now.
+func (r *KeyRecord2) live(now int64) bool {
+	return !r.Deleted && r.Expire > now
+}
+
 // RealKeyFile2 is a hash chain index file (.k2file) with TTL support.
 // Record format: [1B deleted][5B expire][5B prevPos][2B keyLen][keyLen B key][blob dataPos]
 //
@@ -105,8 +110,23 @@ func (f *RealKeyFile2) Interval() time.Duration {
 	return 2 * time.Second
 }
 
+// File implements IFlushStats.
+func (f *RealKeyFile2) File() string {
+	return f.file
+}
+
+// DirtyBytes implements IFlushStats.
+func (f *RealKeyFile2) DirtyBytes() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.appendBuf)
+}
+
 // GetRecord reads a complete record at the given position.
 func (f *RealKeyFile2) GetRecord(pos int64) (*KeyRecord2, error) {
+	if err := faultInj.check(FaultRead, f.file); err != nil {
+		return nil, err
+	}
 	f.mu.RLock()
 	onDisk := pos < f.fileEnd
 	f.mu.RUnlock()
@@ -513,17 +533,24 @@ func (f *RealKeyFile2) Append(prevPos int64, indexKey []byte, dataPos []byte) (i
 
 // AppendTTL writes a new record at the end of the file with TTL.
 func (f *RealKeyFile2) AppendTTL(prevPos int64, ttl int64, indexKey []byte, dataPos []byte) (int64, error) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-
-	pos := f.fileEnd + int64(len(f.appendBuf))
-
 	var expire int64
 	if ttl < 0 {
 		expire = protocol.LONG5MaxValue
 	} else {
 		expire = time.Now().Unix() + ttl
 	}
+	return f.appendExpire(prevPos, expire, indexKey, dataPos)
+}
+
+// appendExpire writes a new record expiring at the unix time expire.
+func (f *RealKeyFile2) appendExpire(prevPos int64, expire int64, indexKey []byte, dataPos []byte) (int64, error) {
+	if err := faultInj.check(FaultWrite, f.file); err != nil {
+		return 0, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pos := f.fileEnd + int64(len(f.appendBuf))
 
 	o := protocol.NewDataOutputX()
 	o.WriteBoolean(false)
@@ -0,0 +1,158 @@
+package io
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// writeStatsKeepDays bounds the dates whose write statistics are kept.
+const writeStatsKeepDays = 7
+
+// WriteStat describes what one storage kind (xlog, profile, ...) wrote into
+// the container of one date since the server started.
+type WriteStat struct {
+	Date      string
+	Kind      string
+	Entries   int64
+	Bytes     int64 // data bytes, 0 for kinds that do not report them
+	Objects   int   // objects that wrote entries
+	LastWrite time.Time
+}
+
+// ObjectWriteStat is the share of one object in a WriteStat.
+type ObjectWriteStat struct {
+	ObjHash int32
+	Entries int64
+	Bytes   int64
+}
+
+type writeKey struct {
+	date string
+	kind string
+}
+
+type dayWrites struct {
+	stat    WriteStat
+	objects map[int32]*ObjectWriteStat
+}
+
+// writeStats counts the entries and bytes the writers store per day
+// container and object, so a single object flooding a day's profiles can
+// be spotted. Counts are kept in memory and start over at restart.
+var writeSt = &writeStats{days: make(map[writeKey]*dayWrites)}
+
+type writeStats struct {
+	mu   sync.Mutex
+	days map[writeKey]*dayWrites
+}
+
+// GetWriteStats returns the process-wide write statistics.
+func GetWriteStats() *writeStats {
+	return writeSt
+}
+
+// Record counts one entry of bytes written by objHash (0 if unknown) into
+// the kind container of date.
+func (s *writeStats) Record(kind, date string, objHash int32, bytes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := writeKey{date, kind}
+	d := s.days[key]
+	if d == nil {
+		d = &dayWrites{stat: WriteStat{Date: date, Kind: kind}, objects: make(map[int32]*ObjectWriteStat)}
+		s.days[key] = d
+		s.pruneLocked()
+	}
+	d.stat.Entries++
+	d.stat.Bytes += int64(bytes)
+	d.stat.LastWrite = time.Now()
+	if objHash != 0 {
+		o := d.objects[objHash]
+		if o == nil {
+			o = &ObjectWriteStat{ObjHash: objHash}
+			d.objects[objHash] = o
+		}
+		o.Entries++
+		o.Bytes += int64(bytes)
+	}
+}
+
+// pruneLocked drops the oldest dates beyond writeStatsKeepDays.
+func (s *writeStats) pruneLocked() {
+	dates := make(map[string]bool)
+	for key := range s.days {
+		dates[key.date] = true
+	}
+	if len(dates) <= writeStatsKeepDays {
+		return
+	}
+	sorted := make([]string, 0, len(dates))
+	for date := range dates {
+		sorted = append(sorted, date)
+	}
+	sort.Strings(sorted)
+	drop := make(map[string]bool)
+	for _, date := range sorted[:len(sorted)-writeStatsKeepDays] {
+		drop[date] = true
+	}
+	for key := range s.days {
+		if drop[key.date] {
+			delete(s.days, key)
+		}
+	}
+}
+
+// Days returns the statistics of date, or of every kept date if date is "",
+// newest date first and by kind within a date.
+func (s *writeStats) Days(date string) []WriteStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []WriteStat
+	for key, d := range s.days {
+		if date != "" && key.date != date {
+			continue
+		}
+		st := d.stat
+		st.Objects = len(d.objects)
+		result = append(result, st)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Date != result[j].Date {
+			return result[i].Date > result[j].Date
+		}
+		return result[i].Kind < result[j].Kind
+	})
+	return result
+}
+
+// Objects returns the objects that wrote into the kind container of date,
+// most bytes (then entries) first.
+func (s *writeStats) Objects(date, kind string) []ObjectWriteStat {
+	s.mu.Lock()
+	d := s.days[writeKey{date, kind}]
+	var result []ObjectWriteStat
+	if d != nil {
+		for _, o := range d.objects {
+			result = append(result, *o)
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Bytes != result[j].Bytes {
+			return result[i].Bytes > result[j].Bytes
+		}
+		if result[i].Entries != result[j].Entries {
+			return result[i].Entries > result[j].Entries
+		}
+		return result[i].ObjHash < result[j].ObjHash
+	})
+	return result
+}
+
+// reset clears the statistics; used in tests.
+func (s *writeStats) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.days = make(map[writeKey]*dayWrites)
+}
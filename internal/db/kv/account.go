@@ -0,0 +1,167 @@
+package kv
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+)
+
+// accountDir holds one store per account under {baseDir}/kv.
+const accountDir = "account"
+
+// AccountUsage is the current usage of one account's store.
+type AccountUsage struct {
+	Account string `json:"account"`
+	Keys    int    `json:"keys"`
+	Bytes   int64  `json:"bytes"`
+}
+
+type accountStore struct {
+	store  *KVStore
+	cancel context.CancelFunc // nil until started
+}
+
+// AccountStores keeps a private KV store per account for client state such as
+// UI layouts and saved filters, so a user finds them on any workstation. Each
+// store is persisted as {baseDir}/kv/account/{hex of account}.json, opened on
+// first use and limited to kv_account_quota_bytes.
+type AccountStores struct {
+	mu      sync.Mutex
+	baseDir string
+	ctx     context.Context // set by Start; stores opened later are started with it
+	stores  map[string]*accountStore
+}
+
+// NewAccountStores creates the account stores under baseDir.
+func NewAccountStores(baseDir string) *AccountStores {
+	return &AccountStores{baseDir: baseDir, stores: make(map[string]*accountStore)}
+}
+
+// Start runs the background cleanup and save of the open stores until ctx is
+// done.
+func (a *AccountStores) Start(ctx context.Context) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ctx = ctx
+	for _, s := range a.stores {
+		a.startLocked(s)
+	}
+}
+
+func (a *AccountStores) startLocked(s *accountStore) {
+	if a.ctx == nil || s.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(a.ctx)
+	s.cancel = cancel
+	s.store.Start(ctx)
+}
+
+// Get returns the store of account, opening it if needed. The quota follows
+// kv_account_quota_bytes.
+func (a *AccountStores) Get(account string) (*KVStore, error) {
+	if account == "" {
+		return nil, errors.New("no account")
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s := a.openLocked(account)
+	quota := int64(0)
+	if cfg := config.Get(); cfg != nil {
+		quota = int64(cfg.KVAccountQuotaBytes())
+	}
+	s.store.SetLimits(quota, 0)
+	return s.store, nil
+}
+
+func (a *AccountStores) openLocked(account string) *accountStore {
+	s, ok := a.stores[account]
+	if !ok {
+		s = &accountStore{store: NewKVStore(a.baseDir, accountFile(account))}
+		a.stores[account] = s
+		a.startLocked(s)
+	}
+	return s
+}
+
+// List returns the usage of every account with stored data, sorted by account.
+func (a *AccountStores) List() []AccountUsage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, account := range a.savedLocked() {
+		a.openLocked(account)
+	}
+	result := make([]AccountUsage, 0, len(a.stores))
+	for account, s := range a.stores {
+		keys, bytes := s.store.Usage()
+		if keys == 0 {
+			continue
+		}
+		result = append(result, AccountUsage{Account: account, Keys: keys, Bytes: bytes})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Account < result[j].Account })
+	return result
+}
+
+// savedLocked returns the accounts that have a file on disk.
+func (a *AccountStores) savedLocked() []string {
+	entries, err := os.ReadDir(filepath.Join(a.baseDir, "kv", accountDir))
+	if err != nil {
+		return nil
+	}
+	var accounts []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		if b, err := hex.DecodeString(name); err == nil && len(b) > 0 {
+			accounts = append(accounts, string(b))
+		}
+	}
+	return accounts
+}
+
+// Drop deletes the data of account and reports whether it had any.
+func (a *AccountStores) Drop(account string) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	found := false
+	if s, ok := a.stores[account]; ok {
+		if s.cancel != nil {
+			s.cancel()
+		}
+		keys, _ := s.store.Usage()
+		found = keys > 0
+		delete(a.stores, account)
+	}
+	path := filepath.Join(a.baseDir, "kv", accountFile(account))
+	if err := os.Remove(path); err == nil {
+		found = true
+	} else if !os.IsNotExist(err) {
+		return found, err
+	}
+	return found, nil
+}
+
+// Close saves the open stores.
+func (a *AccountStores) Close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, s := range a.stores {
+		s.store.Close()
+	}
+}
+
+// accountFile names the store of account; hex keeps any account id a safe
+// file name.
+func accountFile(account string) string {
+	return filepath.Join(accountDir, hex.EncodeToString([]byte(account))+".json")
+}
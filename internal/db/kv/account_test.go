@@ -0,0 +1,65 @@
+package kv
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+)
+
+func TestAccountStores(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("kv_account_quota_bytes=64\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	stores := NewAccountStores(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stores.Start(ctx)
+
+	if _, err := stores.Get(""); err == nil {
+		t.Error("Get without account should fail")
+	}
+	alice, _ := stores.Get("alice")
+	bob, _ := stores.Get("bob/../x")
+	if err := alice.Put("layout", "grid", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := bob.Put("layout", "list", 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := alice.Get("layout"); v != "grid" {
+		t.Errorf("alice layout = %q, accounts are not separated", v)
+	}
+	if err := alice.Put("filter", string(make([]byte, 100)), 0); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected quota error, got %v", err)
+	}
+	stores.Close()
+
+	// Reopened from disk, the accounts are listed before first use.
+	stores = NewAccountStores(dir)
+	list := stores.List()
+	if len(list) != 2 || list[0].Account != "alice" || list[1].Account != "bob/../x" || list[0].Keys != 1 {
+		t.Fatalf("unexpected list %+v", list)
+	}
+
+	found, err := stores.Drop("alice")
+	if err != nil || !found {
+		t.Fatalf("Drop: found=%v err=%v", found, err)
+	}
+	if found, _ := stores.Drop("alice"); found {
+		t.Error("second Drop should find nothing")
+	}
+	alice, _ = stores.Get("alice")
+	if _, ok := alice.Get("layout"); ok {
+		t.Error("dropped data still readable")
+	}
+	if list := stores.List(); len(list) != 1 {
+		t.Errorf("unexpected list after drop %+v", list)
+	}
+}
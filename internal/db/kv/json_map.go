@@ -0,0 +1,131 @@
+package kv
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// errNotChanged makes Update leave the entries as they are.
+var errNotChanged = errors.New("not changed")
+
+// JSONMap keeps named entries as one JSON object under a key of a KVStore.
+// The object is re-parsed only when the stored value changed, so edits made
+// through the KV commands are picked up on the next read.
+type JSONMap[T any] struct {
+	mu      sync.Mutex
+	store   *KVStore
+	key     string
+	check   func(name string, v *T) error
+	raw     string // stored value the entries were parsed from
+	entries map[string]T
+}
+
+// NewJSONMap creates a JSONMap stored under key of store. check, if not nil,
+// runs on every entry read back from the store and may fill in defaults;
+// entries it rejects are left out, as the KV commands can store anything.
+func NewJSONMap[T any](store *KVStore, key string, check func(name string, v *T) error) *JSONMap[T] {
+	return &JSONMap[T]{store: store, key: key, check: check}
+}
+
+// loadLocked returns the current entries. Caller must hold j.mu.
+func (j *JSONMap[T]) loadLocked() map[string]T {
+	raw, _ := j.store.Get(j.key)
+	if j.entries != nil && raw == j.raw {
+		return j.entries
+	}
+	entries := make(map[string]T)
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+			slog.Warn("KV JSON map: bad stored value", "key", j.key, "error", err)
+		}
+	}
+	if j.check != nil {
+		for name, v := range entries {
+			if err := j.check(name, &v); err != nil {
+				slog.Warn("KV JSON map: skipping invalid entry", "key", j.key, "name", name, "error", err)
+				delete(entries, name)
+				continue
+			}
+			entries[name] = v
+		}
+	}
+	j.raw, j.entries = raw, entries
+	return entries
+}
+
+// Snapshot returns the current entries and the stored value they were parsed
+// from, which changes with every update. The map must not be modified.
+func (j *JSONMap[T]) Snapshot() (map[string]T, string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entries := j.loadLocked()
+	return entries, j.raw
+}
+
+// Get returns the entry named name.
+func (j *JSONMap[T]) Get(name string) (T, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	v, ok := j.loadLocked()[name]
+	return v, ok
+}
+
+// Values returns all entries in no particular order.
+func (j *JSONMap[T]) Values() []T {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entries := j.loadLocked()
+	result := make([]T, 0, len(entries))
+	for _, v := range entries {
+		result = append(result, v)
+	}
+	return result
+}
+
+// Update calls fn with a copy of the entries and stores the copy as changed
+// by fn, unless fn returns an error.
+func (j *JSONMap[T]) Update(fn func(entries map[string]T) error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	current := j.loadLocked()
+	entries := make(map[string]T, len(current)+1)
+	for k, v := range current {
+		entries[k] = v
+	}
+	if err := fn(entries); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	j.store.Set(j.key, string(data))
+	j.raw, j.entries = string(data), entries
+	return nil
+}
+
+// Put creates or replaces the entry named name.
+func (j *JSONMap[T]) Put(name string, v T) error {
+	return j.Update(func(entries map[string]T) error {
+		entries[name] = v
+		return nil
+	})
+}
+
+// Delete removes the entry named name and reports whether it existed.
+func (j *JSONMap[T]) Delete(name string) (bool, error) {
+	existed := false
+	err := j.Update(func(entries map[string]T) error {
+		if _, existed = entries[name]; !existed {
+			return errNotChanged
+		}
+		delete(entries, name)
+		return nil
+	})
+	if err == errNotChanged {
+		err = nil
+	}
+	return existed, err
+}
@@ -0,0 +1,64 @@
+package kv
+
+import (
+	"errors"
+	"testing"
+)
+
+type testEntry struct {
+	Name  string `json:"name"`
+	Limit int    `json:"limit"`
+}
+
+func TestJSONMap(t *testing.T) {
+	store := NewKVStore(t.TempDir(), "test.json")
+	m := NewJSONMap[testEntry](store, "entries", func(name string, e *testEntry) error {
+		if e.Limit < 0 {
+			return errors.New("negative limit")
+		}
+		if e.Limit == 0 {
+			e.Limit = 10
+		}
+		return nil
+	})
+
+	if err := m.Put("a", testEntry{Name: "a", Limit: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := m.Get("a"); !ok || e.Limit != 1 {
+		t.Fatalf("Get(a) = %+v, %v", e, ok)
+	}
+	_, raw := m.Snapshot()
+	if stored, _ := store.Get("entries"); stored != raw {
+		t.Errorf("Snapshot raw = %q, stored %q", raw, stored)
+	}
+
+	// Values written through the store directly are re-read and checked.
+	store.Set("entries", `{"b": {"name": "b"}, "bad": {"name": "bad", "limit": -1}}`)
+	entries, raw2 := m.Snapshot()
+	if raw2 == raw || len(entries) != 1 || entries["b"].Limit != 10 {
+		t.Fatalf("entries after a direct write = %v", entries)
+	}
+
+	if existed, err := m.Delete("missing"); existed || err != nil {
+		t.Errorf("Delete(missing) = %v, %v", existed, err)
+	}
+	if existed, err := m.Delete("b"); !existed || err != nil {
+		t.Errorf("Delete(b) = %v, %v", existed, err)
+	}
+	if n := len(m.Values()); n != 0 {
+		t.Errorf("%d entries left", n)
+	}
+
+	// An update failing leaves the entries as they were.
+	m.Put("c", testEntry{Name: "c"})
+	if err := m.Update(func(entries map[string]testEntry) error {
+		delete(entries, "c")
+		return errors.New("abort")
+	}); err == nil {
+		t.Error("Update error lost")
+	}
+	if _, ok := m.Get("c"); !ok {
+		t.Error("failed update was stored")
+	}
+}
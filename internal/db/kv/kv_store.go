@@ -3,20 +3,31 @@ package kv
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+// ErrQuotaExceeded is returned by Put when the write would take the store
+// over its size quota.
+var ErrQuotaExceeded = errors.New("kv quota exceeded")
+
 // KVStore provides in-memory key-value storage with file persistence.
 type KVStore struct {
 	mu       sync.RWMutex
 	data     map[string]kvEntry
 	baseDir  string
 	filename string
-	dirty    bool // tracks if data has changed since last save
+	dirty    bool  // tracks if data has changed since last save
+	size     int64 // bytes of keys and values, expired entries included until cleanup
+
+	quotaBytes   int64 // 0 means unlimited; enforced by Put only
+	defaultTTLMs int64 // TTL applied by Put when none is given
 }
 
 type kvEntry struct {
@@ -63,11 +74,10 @@ func (s *KVStore) Set(key string, value string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.data[key] = kvEntry{
+	s.putLocked(key, kvEntry{
 		Value:     value,
 		ExpiresAt: 0,
-	}
-	s.dirty = true
+	})
 }
 
 // SetTTL stores a key-value pair with a TTL in milliseconds.
@@ -80,13 +90,114 @@ func (s *KVStore) SetTTL(key string, value string, ttlMs int64) {
 		expiresAt = time.Now().UnixMilli() + ttlMs
 	}
 
-	s.data[key] = kvEntry{
+	s.putLocked(key, kvEntry{
 		Value:     value,
 		ExpiresAt: expiresAt,
+	})
+}
+
+// SetLimits sets the size quota in bytes of keys and values (0 for
+// unlimited) and the TTL Put applies when none is given (0 for none).
+func (s *KVStore) SetLimits(quotaBytes, defaultTTLMs int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quotaBytes = quotaBytes
+	s.defaultTTLMs = defaultTTLMs
+}
+
+// Put stores a key-value pair subject to the store's limits. A positive
+// ttlMs sets the TTL, 0 applies the default TTL and a negative value stores
+// the pair without expiry. It returns ErrQuotaExceeded if the pair does not
+// fit in the quota.
+func (s *KVStore) Put(key, value string, ttlMs int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ttlMs == 0 {
+		ttlMs = s.defaultTTLMs
+	}
+	now := time.Now().UnixMilli()
+	expiresAt := int64(0)
+	if ttlMs > 0 {
+		expiresAt = now + ttlMs
+	}
+
+	if s.quotaBytes > 0 {
+		grow := entrySize(key, value)
+		if old, ok := s.data[key]; ok {
+			grow -= entrySize(key, old.Value)
+		}
+		if s.size+grow > s.quotaBytes {
+			// Expired entries still count until cleanup; drop them first.
+			s.removeExpiredLocked(now)
+			if s.size+grow > s.quotaBytes {
+				return ErrQuotaExceeded
+			}
+		}
+	}
+	s.putLocked(key, kvEntry{Value: value, ExpiresAt: expiresAt})
+	return nil
+}
+
+// Delete removes a key and reports whether it was present and not expired.
+func (s *KVStore) Delete(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.data[key]
+	if !ok {
+		return false
+	}
+	s.deleteLocked(key)
+	return entry.ExpiresAt == 0 || time.Now().UnixMilli() <= entry.ExpiresAt
+}
+
+// Keys returns the non-expired keys starting with prefix, sorted.
+func (s *KVStore) Keys(prefix string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now().UnixMilli()
+	var keys []string
+	for key, entry := range s.data {
+		if strings.HasPrefix(key, prefix) && (entry.ExpiresAt == 0 || now <= entry.ExpiresAt) {
+			keys = append(keys, key)
+		}
 	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Usage returns the number of entries and their size in bytes.
+func (s *KVStore) Usage() (keys int, bytes int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.data), s.size
+}
+
+func entrySize(key, value string) int64 {
+	return int64(len(key) + len(value))
+}
+
+// putLocked stores an entry and keeps size up to date. Caller must hold s.mu.
+func (s *KVStore) putLocked(key string, entry kvEntry) {
+	if old, ok := s.data[key]; ok {
+		s.size -= entrySize(key, old.Value)
+	}
+	s.data[key] = entry
+	s.size += entrySize(key, entry.Value)
 	s.dirty = true
 }
 
+// deleteLocked removes an entry and keeps size up to date. Caller must hold s.mu.
+func (s *KVStore) deleteLocked(key string) {
+	if old, ok := s.data[key]; ok {
+		s.size -= entrySize(key, old.Value)
+		delete(s.data, key)
+		s.dirty = true
+	}
+}
+
 // GetBulk retrieves multiple values by their keys.
 // Returns a map containing only the found and non-expired keys.
 func (s *KVStore) GetBulk(keys []string) map[string]string {
@@ -114,12 +225,11 @@ func (s *KVStore) SetBulk(pairs map[string]string) {
 	defer s.mu.Unlock()
 
 	for key, value := range pairs {
-		s.data[key] = kvEntry{
+		s.putLocked(key, kvEntry{
 			Value:     value,
 			ExpiresAt: 0,
-		}
+		})
 	}
-	s.dirty = true
 }
 
 // Start begins background tasks: cleanup of expired entries and periodic save.
@@ -151,20 +261,21 @@ func (s *KVStore) cleanupExpired() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	now := time.Now().UnixMilli()
-	removed := 0
+	if removed := s.removeExpiredLocked(time.Now().UnixMilli()); removed > 0 {
+		slog.Debug("KV store cleanup", "file", s.filename, "removed", removed)
+	}
+}
 
+// removeExpiredLocked deletes the entries expired at now. Caller must hold s.mu.
+func (s *KVStore) removeExpiredLocked(now int64) int {
+	removed := 0
 	for key, entry := range s.data {
 		if entry.ExpiresAt > 0 && now > entry.ExpiresAt {
-			delete(s.data, key)
+			s.deleteLocked(key)
 			removed++
 		}
 	}
-
-	if removed > 0 {
-		s.dirty = true
-		slog.Debug("KV store cleanup", "file", s.filename, "removed", removed)
-	}
+	return removed
 }
 
 // load reads the store from disk.
@@ -190,6 +301,10 @@ func (s *KVStore) load() {
 	if s.data == nil {
 		s.data = make(map[string]kvEntry)
 	}
+	s.size = 0
+	for key, entry := range s.data {
+		s.size += entrySize(key, entry.Value)
+	}
 	s.mu.Unlock()
 
 	slog.Info("KV store loaded", "file", s.filename, "entries", len(s.data))
@@ -243,3 +243,50 @@ func TestKVStore_SetTTLZero(t *testing.T) {
 		t.Errorf("Get after wait failed: got (%v, %v), want (value, true)", val, ok)
 	}
 }
+
+func TestKVStore_Quota(t *testing.T) {
+	store := NewKVStore(t.TempDir(), "test.json")
+	defer store.Close()
+	store.SetLimits(20, 0)
+
+	if err := store.Put("k1", "0123456789", -1); err != nil { // 12 bytes
+		t.Fatal(err)
+	}
+	if err := store.Put("k2", "0123456789", -1); err != ErrQuotaExceeded {
+		t.Fatalf("Put over quota = %v, want ErrQuotaExceeded", err)
+	}
+	// Replacing a value only counts the difference.
+	if err := store.Put("k1", "0123456789abcdef", -1); err != nil {
+		t.Fatal(err)
+	}
+	if keys, bytes := store.Usage(); keys != 1 || bytes != 18 {
+		t.Errorf("Usage = (%d, %d), want (1, 18)", keys, bytes)
+	}
+
+	// Expired entries make room before the quota is enforced.
+	store.Delete("k1")
+	store.Put("old", "0123456789", 10)
+	time.Sleep(30 * time.Millisecond)
+	if err := store.Put("new", "0123456789", -1); err != nil {
+		t.Errorf("Put after expiry = %v", err)
+	}
+}
+
+func TestKVStore_DefaultTTLAndKeys(t *testing.T) {
+	store := NewKVStore(t.TempDir(), "test.json")
+	defer store.Close()
+	store.SetLimits(0, 10)
+
+	store.Put("session:a", "1", 0)  // default TTL
+	store.Put("session:b", "1", -1) // no expiry
+	store.Put("other", "1", -1)
+	time.Sleep(30 * time.Millisecond)
+
+	keys := store.Keys("session:")
+	if len(keys) != 1 || keys[0] != "session:b" {
+		t.Errorf("Keys = %v, want [session:b]", keys)
+	}
+	if !store.Delete("other") || store.Delete("other") {
+		t.Errorf("Delete should report presence once")
+	}
+}
@@ -0,0 +1,231 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// namespacesFile lists the named namespaces and their limits under {baseDir}/kv.
+const namespacesFile = "namespaces.json"
+
+var namespaceName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Namespace describes one KV namespace.
+type Namespace struct {
+	Name string `json:"name"`
+	// QuotaBytes limits the total size of keys and values (0 for unlimited).
+	QuotaBytes int64 `json:"quotaBytes"`
+	// DefaultTTLMs is applied to writes that give no TTL (0 for none).
+	DefaultTTLMs int64 `json:"defaultTtlMs"`
+	// Builtin marks the global and custom stores, which cannot be dropped.
+	Builtin bool `json:"builtin,omitempty"`
+	// Keys and Bytes are the current usage; not persisted.
+	Keys  int   `json:"keys"`
+	Bytes int64 `json:"bytes"`
+}
+
+type namespace struct {
+	Namespace
+	store  *KVStore
+	cancel context.CancelFunc // stops the store's background tasks; nil until started
+}
+
+// Namespaces manages named KV stores created by clients next to the built-in
+// global and custom stores. Each namespace is persisted as
+// {baseDir}/kv/ns-{name}.json.
+type Namespaces struct {
+	mu      sync.Mutex
+	baseDir string
+	ctx     context.Context // set by Start; stores created later are started with it
+	spaces  map[string]*namespace
+}
+
+// NewNamespaces loads the namespaces saved under baseDir. builtin maps names
+// such as "global" to already open stores.
+func NewNamespaces(baseDir string, builtin map[string]*KVStore) *Namespaces {
+	n := &Namespaces{baseDir: baseDir, spaces: make(map[string]*namespace)}
+	for name, store := range builtin {
+		n.spaces[name] = &namespace{Namespace: Namespace{Name: name, Builtin: true}, store: store}
+	}
+
+	data, err := os.ReadFile(n.metaPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("KV namespaces load error", "error", err)
+		}
+		return n
+	}
+	var saved []Namespace
+	if err := json.Unmarshal(data, &saved); err != nil {
+		slog.Warn("KV namespaces unmarshal error", "error", err)
+		return n
+	}
+	for _, ns := range saved {
+		if existing := n.spaces[ns.Name]; existing != nil {
+			existing.QuotaBytes, existing.DefaultTTLMs = ns.QuotaBytes, ns.DefaultTTLMs
+			existing.store.SetLimits(ns.QuotaBytes, ns.DefaultTTLMs)
+			continue
+		}
+		n.spaces[ns.Name] = n.open(ns)
+	}
+	return n
+}
+
+func (n *Namespaces) open(ns Namespace) *namespace {
+	ns.Builtin = false
+	store := NewKVStore(n.baseDir, "ns-"+ns.Name+".json")
+	store.SetLimits(ns.QuotaBytes, ns.DefaultTTLMs)
+	return &namespace{Namespace: ns, store: store}
+}
+
+// Start runs the background cleanup and save of the named namespaces until
+// ctx is done. Built-in stores are started by their owner.
+func (n *Namespaces) Start(ctx context.Context) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.ctx = ctx
+	for _, ns := range n.spaces {
+		n.startLocked(ns)
+	}
+}
+
+// startLocked starts a named namespace if Start was called. Caller must hold n.mu.
+func (n *Namespaces) startLocked(ns *namespace) {
+	if n.ctx == nil || ns.Builtin || ns.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(n.ctx)
+	ns.cancel = cancel
+	ns.store.Start(ctx)
+}
+
+// Get returns the store of a namespace.
+func (n *Namespaces) Get(name string) (*KVStore, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ns, ok := n.spaces[name]
+	if !ok {
+		return nil, false
+	}
+	return ns.store, true
+}
+
+// List returns all namespaces with their current usage, sorted by name.
+func (n *Namespaces) List() []Namespace {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	result := make([]Namespace, 0, len(n.spaces))
+	for _, ns := range n.spaces {
+		info := ns.Namespace
+		info.Keys, info.Bytes = ns.store.Usage()
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Set creates a namespace or changes its limits. maxCount bounds the number
+// of named namespaces (0 for unlimited). Lowering a quota below the current
+// usage keeps the data but rejects further growth.
+func (n *Namespaces) Set(name string, quotaBytes, defaultTTLMs int64, maxCount int) error {
+	if !namespaceName.MatchString(name) {
+		return fmt.Errorf("invalid namespace name %q: use 1-64 letters, digits, '_' or '-'", name)
+	}
+	if quotaBytes < 0 || defaultTTLMs < 0 {
+		return errors.New("quotaBytes and defaultTtlMs must not be negative")
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ns, ok := n.spaces[name]
+	if !ok {
+		if maxCount > 0 && n.namedCountLocked() >= maxCount {
+			return fmt.Errorf("too many namespaces (max %d)", maxCount)
+		}
+		ns = n.open(Namespace{Name: name})
+		n.spaces[name] = ns
+		n.startLocked(ns)
+	}
+	ns.QuotaBytes, ns.DefaultTTLMs = quotaBytes, defaultTTLMs
+	ns.store.SetLimits(quotaBytes, defaultTTLMs)
+	return n.saveLocked()
+}
+
+// Drop deletes a named namespace and its data and reports whether it existed.
+func (n *Namespaces) Drop(name string) (bool, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ns, ok := n.spaces[name]
+	if !ok {
+		return false, nil
+	}
+	if ns.Builtin {
+		return true, fmt.Errorf("namespace %q is built in and cannot be dropped", name)
+	}
+	if ns.cancel != nil {
+		ns.cancel()
+	}
+	delete(n.spaces, name)
+	if err := os.Remove(ns.store.getFilePath()); err != nil && !os.IsNotExist(err) {
+		return true, err
+	}
+	return true, n.saveLocked()
+}
+
+func (n *Namespaces) namedCountLocked() int {
+	count := 0
+	for _, ns := range n.spaces {
+		if !ns.Builtin {
+			count++
+		}
+	}
+	return count
+}
+
+// saveLocked writes the namespace list. Caller must hold n.mu.
+func (n *Namespaces) saveLocked() error {
+	saved := make([]Namespace, 0, len(n.spaces))
+	for _, ns := range n.spaces {
+		if ns.Builtin && ns.QuotaBytes == 0 && ns.DefaultTTLMs == 0 {
+			continue
+		}
+		saved = append(saved, Namespace{Name: ns.Name, QuotaBytes: ns.QuotaBytes, DefaultTTLMs: ns.DefaultTTLMs})
+	}
+	sort.Slice(saved, func(i, j int) bool { return saved[i].Name < saved[j].Name })
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := n.metaPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Close saves the named namespaces. Built-in stores are closed by their owner.
+func (n *Namespaces) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, ns := range n.spaces {
+		if !ns.Builtin {
+			ns.store.Close()
+		}
+	}
+}
+
+func (n *Namespaces) metaPath() string {
+	return filepath.Join(n.baseDir, "kv", namespacesFile)
+}
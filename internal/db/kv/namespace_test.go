@@ -0,0 +1,58 @@
+package kv
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNamespaces_Lifecycle(t *testing.T) {
+	dir := t.TempDir()
+	global := NewKVStore(dir, "global.json")
+	ns := NewNamespaces(dir, map[string]*KVStore{"global": global})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ns.Start(ctx)
+
+	for _, bad := range []string{"", "a/b", "../x"} {
+		if err := ns.Set(bad, 0, 0, 0); err == nil {
+			t.Errorf("Set(%q) should fail", bad)
+		}
+	}
+	if err := ns.Set("plugin-a", 100, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := ns.Set("plugin-b", 0, 0, 1); err == nil {
+		t.Error("Set beyond maxCount should fail")
+	}
+	store, ok := ns.Get("plugin-a")
+	if !ok {
+		t.Fatal("plugin-a not found")
+	}
+	if err := store.Put("k", string(make([]byte, 200)), -1); err != ErrQuotaExceeded {
+		t.Errorf("Put = %v, want ErrQuotaExceeded", err)
+	}
+	store.Put("k", "v", -1)
+	ns.Close()
+
+	// Limits and data survive a restart.
+	ns2 := NewNamespaces(dir, map[string]*KVStore{"global": global})
+	list := ns2.List()
+	if len(list) != 2 || list[0].Name != "global" || !list[0].Builtin || list[1].Name != "plugin-a" || list[1].QuotaBytes != 100 || list[1].Keys != 1 {
+		t.Fatalf("List = %+v", list)
+	}
+
+	if _, err := ns2.Drop("global"); err == nil {
+		t.Error("dropping a built-in namespace should fail")
+	}
+	if found, err := ns2.Drop("plugin-a"); !found || err != nil {
+		t.Fatalf("Drop = (%v, %v)", found, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "kv", "ns-plugin-a.json")); !os.IsNotExist(err) {
+		t.Errorf("namespace file should be removed, stat err = %v", err)
+	}
+	if _, ok := ns2.Get("plugin-a"); ok {
+		t.Error("dropped namespace still present")
+	}
+}
@@ -0,0 +1,312 @@
+package db
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Purge type names accepted by ManualPurger.
+const (
+	PurgeTypeXLog            = "xlog"
+	PurgeTypeProfile         = "profile"
+	PurgeTypeCounter         = "counter"
+	PurgeTypeRealtimeCounter = "realtime_counter"
+	PurgeTypeSummary         = "summary"
+	PurgeTypeText            = "text"
+	PurgeTypeAlert           = "alert"
+	PurgeTypeVisitor         = "visitor"
+	PurgeTypeAll             = "all"
+)
+
+// purgeTargets maps each purge type to the paths, relative to the data
+// directory, holding its files for one date.
+var purgeTargets = map[string]func(baseDir, date string) []string{
+	PurgeTypeXLog: func(_, date string) []string { return []string{filepath.Join(date, "xlog")} },
+	PurgeTypeProfile: func(_, date string) []string {
+		return []string{
+			filepath.Join(date, "xlog", "xlog_prof.data"),
+			filepath.Join(date, "xlog", "xlog_prof.hfile"),
+			filepath.Join(date, "xlog", "xlog_prof.kfile"),
+			filepath.Join(date, "xlog", "xlog_prof.kept"),
+		}
+	},
+	PurgeTypeCounter:         func(_, date string) []string { return []string{filepath.Join(date, "counter")} },
+	PurgeTypeRealtimeCounter: realtimeCounterTargets,
+	PurgeTypeSummary:         func(_, date string) []string { return []string{filepath.Join(date, "summary")} },
+	PurgeTypeText:            func(_, date string) []string { return []string{filepath.Join(date, "text")} },
+	PurgeTypeAlert:           func(_, date string) []string { return []string{filepath.Join(date, "alert")} },
+	PurgeTypeVisitor: func(_, date string) []string {
+		return []string{filepath.Join(date, "visit"), filepath.Join(date, "visit_hourly")}
+	},
+	PurgeTypeAll: func(_, date string) []string { return []string{date} },
+}
+
+// realtimeCounterTargets lists the realtime counter files of {date}/counter.
+func realtimeCounterTargets(baseDir, date string) []string {
+	entries, err := os.ReadDir(filepath.Join(baseDir, date, "counter"))
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "real") {
+			paths = append(paths, filepath.Join(date, "counter", entry.Name()))
+		}
+	}
+	return paths
+}
+
+// DayCloser is implemented by components that can release a single day's
+// open containers, e.g. before the day's files are deleted.
+type DayCloser interface {
+	CloseDay(date string)
+}
+
+// PurgeResult describes the outcome of purging one type for one date.
+type PurgeResult struct {
+	Date    string
+	Type    string
+	Deleted bool   // false if there was nothing to delete
+	Skipped string // non-empty reason if the date was not processed
+	TrashID string // trash entry holding the data until permanent removal
+}
+
+// ManualPurger deletes day data on demand (admin command or CLI), closing any
+// open day containers first so no file handles point at deleted files.
+//
+// When constructed with closers (i.e. inside a running server), today's data is
+// never deleted because the writers would immediately reopen it.
+//
+// With a trash whose undo window is open, the data is moved to the trash
+// instead and can be restored until the window ends.
+type ManualPurger struct {
+	mu      sync.Mutex
+	baseDir string
+	closers []DayCloser
+	trash   *Trash
+}
+
+// NewManualPurger creates a purger for baseDir. closers are asked to close
+// each date before its files are removed.
+func NewManualPurger(baseDir string, closers ...DayCloser) *ManualPurger {
+	return &ManualPurger{
+		baseDir: baseDir,
+		closers: closers,
+	}
+}
+
+// SetTrash moves purged data to t while mgr_purge_trash_hours is positive.
+func (p *ManualPurger) SetTrash(t *Trash) {
+	p.trash = t
+}
+
+// Trash returns the trash set by SetTrash, or nil.
+func (p *ManualPurger) Trash() *Trash {
+	return p.trash
+}
+
+// Restore moves the data of trash entry id back, closing its dates first so
+// readers reopen them.
+func (p *ManualPurger) Restore(id string) (TrashEntry, error) {
+	if p.trash == nil {
+		return TrashEntry{}, fmt.Errorf("trash is not enabled")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, err := p.trash.Restore(id)
+	if err == nil {
+		for _, date := range e.Dates {
+			for _, c := range p.closers {
+				c.CloseDay(date)
+			}
+		}
+	}
+	return e, err
+}
+
+// Purge deletes the given types for each date. Dates without a directory are skipped.
+func (p *ManualPurger) Purge(dates []string, types []string) ([]PurgeResult, error) {
+	if err := validatePurgeTypes(types); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing, err := GetDateDirs(p.baseDir)
+	if err != nil {
+		return nil, err
+	}
+	present := make(map[string]bool, len(existing))
+	for _, d := range existing {
+		present[d] = true
+	}
+
+	useTrash := p.trash != nil && p.trash.Window() > 0
+	today := time.Now().Format("20060102")
+	var results []PurgeResult
+	var moved []string // paths to move to the trash
+	var purgedDates []string
+	var trashed []int // indexes of the results moved to the trash
+	for _, date := range dates {
+		if !present[date] {
+			results = append(results, PurgeResult{Date: date, Skipped: "no data"})
+			continue
+		}
+		if len(p.closers) > 0 && date == today {
+			results = append(results, PurgeResult{Date: date, Skipped: "today is in use"})
+			continue
+		}
+		for _, c := range p.closers {
+			c.CloseDay(date)
+		}
+		purgedDates = append(purgedDates, date)
+		for _, typ := range types {
+			paths := purgeTargets[typ](p.baseDir, date)
+			if useTrash {
+				var found []string
+				for _, rel := range paths {
+					if _, err := os.Stat(filepath.Join(p.baseDir, rel)); err == nil {
+						found = append(found, rel)
+					}
+				}
+				if len(found) > 0 {
+					trashed = append(trashed, len(results))
+					moved = append(moved, found...)
+				}
+				results = append(results, PurgeResult{Date: date, Type: typ, Deleted: len(found) > 0})
+				continue
+			}
+			deleted := false
+			for _, rel := range paths {
+				if removeIfExists(filepath.Join(p.baseDir, rel)) {
+					deleted = true
+				}
+			}
+			if deleted {
+				slog.Info("ManualPurge: purged", "type", typ, "date", date)
+			}
+			results = append(results, PurgeResult{Date: date, Type: typ, Deleted: deleted})
+		}
+	}
+	if len(moved) == 0 {
+		return results, nil
+	}
+
+	e, err := p.trash.Move(moved, purgedDates, types)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range trashed {
+		results[i].TrashID = e.ID
+		slog.Info("ManualPurge: moved to trash", "type", results[i].Type, "date", results[i].Date, "trash", e.ID)
+	}
+	return results, nil
+}
+
+// PurgeSpec parses a date range (see ParseDateRange) and a comma-separated
+// type list (see ParsePurgeTypes) and runs the purge.
+func (p *ManualPurger) PurgeSpec(dateSpec, typeSpec string) ([]PurgeResult, error) {
+	dates, err := ParseDateRange(dateSpec)
+	if err != nil {
+		return nil, err
+	}
+	types, err := ParsePurgeTypes(typeSpec)
+	if err != nil {
+		return nil, err
+	}
+	return p.Purge(dates, types)
+}
+
+// ParsePurgeTypes parses a comma-separated purge type list (e.g. "xlog,profile").
+func ParsePurgeTypes(s string) ([]string, error) {
+	var types []string
+	for _, t := range strings.Split(s, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		return nil, fmt.Errorf("no purge types given")
+	}
+	if err := validatePurgeTypes(types); err != nil {
+		return nil, err
+	}
+	return types, nil
+}
+
+func validatePurgeTypes(types []string) error {
+	for _, t := range types {
+		if _, ok := purgeTargets[t]; !ok {
+			return fmt.Errorf("unknown purge type %q (valid: %s)", t, strings.Join(PurgeTypeNames(), ","))
+		}
+	}
+	return nil
+}
+
+// PurgeTypeNames returns the sorted list of valid purge type names.
+func PurgeTypeNames() []string {
+	names := make([]string, 0, len(purgeTargets))
+	for n := range purgeTargets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MaxPurgeDays bounds the dates one ParseDateRange call expands to, so a
+// typo such as 20000101..29991231 cannot queue a purge of every day since.
+const MaxPurgeDays = 3 * 366
+
+// ParseDateRange expands "YYYYMMDD", "YYYYMMDD..YYYYMMDD" or a comma-separated
+// list of either into an ascending list of at most MaxPurgeDays dates.
+func ParseDateRange(s string) ([]string, error) {
+	seen := make(map[string]bool)
+	var dates []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "..")
+		if !isRange {
+			to = from
+		}
+		start, err := time.Parse("20060102", strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", from)
+		}
+		end, err := time.Parse("20060102", strings.TrimSpace(to))
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", to)
+		}
+		if end.Before(start) {
+			return nil, fmt.Errorf("invalid range %q: end before start", part)
+		}
+		if end.Sub(start) >= MaxPurgeDays*24*time.Hour {
+			return nil, fmt.Errorf("invalid range %q: more than %d days", part, MaxPurgeDays)
+		}
+		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+			ds := d.Format("20060102")
+			if !seen[ds] {
+				seen[ds] = true
+				dates = append(dates, ds)
+			}
+		}
+		if len(dates) > MaxPurgeDays {
+			return nil, fmt.Errorf("more than %d dates given", MaxPurgeDays)
+		}
+	}
+	if len(dates) == 0 {
+		return nil, fmt.Errorf("no dates given")
+	}
+	sort.Strings(dates)
+	return dates, nil
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type recordingCloser struct {
+	closed []string
+}
+
+func (c *recordingCloser) CloseDay(date string) {
+	c.closed = append(c.closed, date)
+}
+
+func TestParseDateRange(t *testing.T) {
+	dates, err := ParseDateRange("20260130..20260202")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"20260130", "20260131", "20260201", "20260202"}
+	if len(dates) != len(want) {
+		t.Fatalf("expected %v, got %v", want, dates)
+	}
+	for i := range want {
+		if dates[i] != want[i] {
+			t.Errorf("dates[%d] = %s, want %s", i, dates[i], want[i])
+		}
+	}
+
+	dates, err = ParseDateRange("20260105,20260101..20260102,20260101")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dates) != 3 || dates[0] != "20260101" || dates[2] != "20260105" {
+		t.Errorf("expected sorted unique dates, got %v", dates)
+	}
+
+	// Ranges are capped, alone and combined.
+	if dates, err := ParseDateRange("20240101..20270102"); err != nil || len(dates) != MaxPurgeDays {
+		t.Errorf("range at the cap: %d dates, %v", len(dates), err)
+	}
+	for _, bad := range []string{"", "2026010", "20260102..20260101", "20261301",
+		"20000101..29991231", "20240101..20270103", "20200101..20221231,20230101..20251231"} {
+		if _, err := ParseDateRange(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestParsePurgeTypes(t *testing.T) {
+	types, err := ParsePurgeTypes(" xlog, Profile ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(types) != 2 || types[0] != "xlog" || types[1] != "profile" {
+		t.Errorf("unexpected types: %v", types)
+	}
+	if _, err := ParsePurgeTypes("xlog,bogus"); err == nil {
+		t.Error("expected error for unknown type")
+	}
+}
+
+func TestManualPurger_PurgeTypes(t *testing.T) {
+	dir := t.TempDir()
+	date := "20260101"
+	xlogDir := filepath.Join(dir, date, "xlog")
+	counterDir := filepath.Join(dir, date, "counter")
+	os.MkdirAll(xlogDir, 0755)
+	os.MkdirAll(counterDir, 0755)
+	os.WriteFile(filepath.Join(xlogDir, "xlog.data"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(xlogDir, "xlog_prof.data"), []byte("p"), 0644)
+	os.WriteFile(filepath.Join(counterDir, "real.data"), []byte("c"), 0644)
+
+	closer := &recordingCloser{}
+	p := NewManualPurger(dir, closer)
+	results, err := p.Purge([]string{date, "20260102"}, []string{PurgeTypeProfile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(closer.closed) != 1 || closer.closed[0] != date {
+		t.Errorf("expected container close for %s, got %v", date, closer.closed)
+	}
+	if _, err := os.Stat(filepath.Join(xlogDir, "xlog_prof.data")); !os.IsNotExist(err) {
+		t.Error("profile data should be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(xlogDir, "xlog.data")); err != nil {
+		t.Error("xlog data should remain after profile purge")
+	}
+	if len(results) != 2 || !results[0].Deleted || results[1].Skipped == "" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestManualPurger_ProtectsTodayWhenOnline(t *testing.T) {
+	dir := t.TempDir()
+	today := time.Now().Format("20060102")
+	os.MkdirAll(filepath.Join(dir, today, "xlog"), 0755)
+
+	p := NewManualPurger(dir, &recordingCloser{})
+	results, err := p.Purge([]string{today}, []string{PurgeTypeAll})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Skipped == "" {
+		t.Errorf("expected today to be skipped, got %+v", results)
+	}
+	if _, err := os.Stat(filepath.Join(dir, today)); err != nil {
+		t.Error("today's directory should remain")
+	}
+
+	// Offline purger (no closers) may delete today.
+	if _, err := NewManualPurger(dir).Purge([]string{today}, []string{PurgeTypeAll}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, today)); !os.IsNotExist(err) {
+		t.Error("offline purge should delete today's directory")
+	}
+}
@@ -0,0 +1,180 @@
+// Package objevent records object lifecycle events (registered, dead,
+// recovered, renamed) so automation can follow topology changes by
+// subscribing instead of polling the object list.
+//
+// Each day is stored as JSON lines in {date}/objevent/events.jsonl. Recent
+// events are also kept in memory with a sequence number for subscribers.
+package objevent
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	Registered = "registered" // first heartbeat since the server started
+	Dead       = "dead"       // no heartbeat within the dead timeout
+	Recovered  = "recovered"  // heartbeat again after being dead
+	Renamed    = "renamed"    // same objHash reported under another name
+)
+
+// recentSize is the number of events kept in memory for subscribers.
+const recentSize = 1024
+
+// Event is one object lifecycle change.
+type Event struct {
+	// Seq increases by one per event. It starts from the server start time
+	// in ms, so it keeps increasing across restarts.
+	Seq     int64  `json:"seq"`
+	Time    int64  `json:"time"`
+	Type    string `json:"type"`
+	ObjHash int32  `json:"objHash"`
+	ObjName string `json:"objName"`
+	ObjType string `json:"objType,omitempty"`
+	Address string `json:"address,omitempty"`
+	// OldName is the previous name of a renamed object.
+	OldName string `json:"oldName,omitempty"`
+}
+
+// Store appends events to the day files and keeps the recent ones.
+type Store struct {
+	mu      sync.Mutex
+	baseDir string
+	seq     int64
+	recent  []Event // ring of the last recentSize events
+	next    int     // write position in recent
+
+	date string
+	file *os.File
+	w    *bufio.Writer
+}
+
+// NewStore creates a Store writing under baseDir.
+func NewStore(baseDir string) *Store {
+	return &Store{baseDir: baseDir, seq: time.Now().UnixMilli(), recent: make([]Event, 0, recentSize)}
+}
+
+// Add assigns e a sequence number, fills in the time if unset and stores it.
+func (s *Store) Add(e Event) Event {
+	if e.Time == 0 {
+		e.Time = time.Now().UnixMilli()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	e.Seq = s.seq
+	if len(s.recent) < recentSize {
+		s.recent = append(s.recent, e)
+	} else {
+		s.recent[s.next] = e
+	}
+	s.next = (s.next + 1) % recentSize
+
+	if err := s.writeLocked(e); err != nil {
+		slog.Warn("Object event write failed", "type", e.Type, "objHash", e.ObjHash, "error", err)
+	}
+	return e
+}
+
+func (s *Store) writeLocked(e Event) error {
+	date := time.UnixMilli(e.Time).Format("20060102")
+	if date != s.date {
+		s.closeLocked()
+		dir := filepath.Join(s.baseDir, date, "objevent")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(filepath.Join(dir, "events.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		s.date, s.file, s.w = date, f, bufio.NewWriter(f)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.w.Write(data)
+	s.w.WriteByte('\n')
+	// Events are rare; flush so readers and crashes see each one.
+	return s.w.Flush()
+}
+
+// Last returns the sequence number of the latest event.
+func (s *Store) Last() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seq
+}
+
+// Since returns the events after seq still held in memory, oldest first.
+// Events older than the in-memory window are skipped; callers can detect
+// the gap when the first Seq is not seq+1 and read the day files instead.
+func (s *Store) Since(seq int64) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []Event
+	n, start := len(s.recent), 0
+	if n == recentSize {
+		start = s.next // oldest entry once the ring is full
+	}
+	for i := 0; i < n; i++ {
+		e := s.recent[(start+i)%n]
+		if e.Seq > seq {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Read calls handler for the events stored for date (YYYYMMDD) with a time
+// in [stime, etime]. Lines that cannot be parsed are skipped.
+func (s *Store) Read(date string, stime, etime int64, handler func(Event)) error {
+	s.mu.Lock()
+	if s.date == date && s.w != nil {
+		s.w.Flush()
+	}
+	s.mu.Unlock()
+
+	f, err := os.Open(filepath.Join(s.baseDir, date, "objevent", "events.jsonl"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var e Event
+		if json.Unmarshal(sc.Bytes(), &e) != nil {
+			continue
+		}
+		if e.Time >= stime && e.Time <= etime {
+			handler(e)
+		}
+	}
+	return sc.Err()
+}
+
+// Close closes the current day file.
+func (s *Store) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+}
+
+func (s *Store) closeLocked() {
+	if s.file != nil {
+		s.w.Flush()
+		s.file.Close()
+		s.file, s.w, s.date = nil, nil, ""
+	}
+}
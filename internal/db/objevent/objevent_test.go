@@ -0,0 +1,62 @@
+package objevent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStore_AddSinceRead(t *testing.T) {
+	s := NewStore(t.TempDir())
+	defer s.Close()
+
+	start := s.Last()
+	now := time.Now().UnixMilli()
+	a := s.Add(Event{Type: Registered, ObjHash: 1, ObjName: "/a", Time: now})
+	b := s.Add(Event{Type: Renamed, ObjHash: 1, ObjName: "/b", OldName: "/a", Time: now + 10})
+	if a.Seq != start+1 || b.Seq != start+2 || s.Last() != b.Seq {
+		t.Fatalf("unexpected seqs: %d %d last=%d start=%d", a.Seq, b.Seq, s.Last(), start)
+	}
+
+	got := s.Since(a.Seq)
+	if len(got) != 1 || got[0].Type != Renamed || got[0].OldName != "/a" {
+		t.Fatalf("Since: unexpected %+v", got)
+	}
+	if got := s.Since(b.Seq); len(got) != 0 {
+		t.Fatalf("Since(last): expected none, got %+v", got)
+	}
+
+	date := time.UnixMilli(now).Format("20060102")
+	var read []Event
+	if err := s.Read(date, now+5, now+100, func(e Event) { read = append(read, e) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 1 || read[0].Seq != b.Seq || read[0].ObjName != "/b" {
+		t.Fatalf("Read: unexpected %+v", read)
+	}
+
+	if err := s.Read("19700101", 0, now, func(Event) { t.Fatal("unexpected event") }); err != nil {
+		t.Fatalf("Read of a missing day: %v", err)
+	}
+}
+
+func TestStore_SinceWrapsRing(t *testing.T) {
+	s := NewStore(t.TempDir())
+	defer s.Close()
+
+	start := s.Last()
+	for i := 0; i < recentSize+10; i++ {
+		s.Add(Event{Type: Dead, ObjHash: int32(i)})
+	}
+	got := s.Since(start)
+	if len(got) != recentSize {
+		t.Fatalf("expected %d events, got %d", recentSize, len(got))
+	}
+	if got[0].Seq != start+11 || got[len(got)-1].Seq != s.Last() {
+		t.Fatalf("unexpected window %d..%d", got[0].Seq, got[len(got)-1].Seq)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Seq != got[i-1].Seq+1 {
+			t.Fatalf("events out of order at %d", i)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/io"
+	"github.com/zbum/scouter-server-go/internal/db/xlog"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// keptMarker holds the label of the profiles a compacted day still keeps,
+// so the day is rewritten again only when that changes.
+const keptMarker = "xlog_prof.kept"
+
+// profileFiles are the profile files of a day's xlog directory.
+var profileFiles = []string{"xlog_prof.data", "xlog_prof.hfile", "xlog_prof.kfile", keptMarker}
+
+// CompactResult describes the compaction of the profiles of one day.
+type CompactResult struct {
+	Compacted   bool // false if the day already keeps only label
+	Kept        int  // profile blocks rewritten
+	Dropped     int  // profile blocks removed
+	BytesBefore int64
+	BytesAfter  int64
+}
+
+// Reclaimed returns the bytes freed on disk.
+func (r CompactResult) Reclaimed() int64 {
+	return r.BytesBefore - r.BytesAfter
+}
+
+// Outcomes selects the transactions whose profiles are kept once ordinary
+// ones expire.
+type Outcomes struct {
+	Errors bool
+	SlowMs int32 // elapsed time from which a transaction is slow, 0 for none
+}
+
+// Label names the selection in the marker of a compacted day.
+func (o Outcomes) Label() string {
+	var parts []string
+	if o.Errors {
+		parts = append(parts, "error")
+	}
+	if o.SlowMs > 0 {
+		parts = append(parts, "slow>="+strconv.Itoa(int(o.SlowMs)))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Match reports whether xp is one of the selected transactions.
+func (o Outcomes) Match(xp *pack.XLogPack) bool {
+	return (o.Errors && xp.Error != 0) || (o.SlowMs > 0 && xp.Elapsed >= o.SlowMs)
+}
+
+// CompactOutcomes keeps only the profiles of the transactions of date
+// selected by o, looked up in the day's XLogs. The day must not be open.
+func CompactOutcomes(baseDir string, xlogRD *xlog.XLogRD, date string, o Outcomes) (CompactResult, error) {
+	return CompactDaily(filepath.Join(baseDir, date, "xlog"), o.Label(), func() (map[int64]bool, error) {
+		start, err := time.ParseInLocation("20060102", date, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		keep := make(map[int64]bool)
+		err = xlogRD.ReadByTime(date, start.UnixMilli(), start.AddDate(0, 0, 1).UnixMilli()-1, func(data []byte) bool {
+			p, err := pack.ReadPack(protocol.NewDataInputX(data))
+			if xp, ok := p.(*pack.XLogPack); err == nil && ok && o.Match(xp) {
+				keep[xp.Txid] = true
+			}
+			return true
+		})
+		return keep, err
+	})
+}
+
+// CompactDaily rewrites the profiles of the xlog directory dir ({date}/xlog)
+// keeping only the blocks of the txids returned by keep, which is called
+// unless the day already keeps the selection named label. The blocks are
+// copied as stored. A day left without blocks loses its profile files.
+//
+// The day must not be open: callers close its profile data first.
+func CompactDaily(dir, label string, keep func() (map[int64]bool, error)) (CompactResult, error) {
+	var result CompactResult
+	oldPath := filepath.Join(dir, "xlog_prof")
+	if _, err := os.Stat(oldPath + ".kfile"); err != nil {
+		return result, nil
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, keptMarker)); err == nil && strings.TrimSpace(string(data)) == label {
+		return result, nil
+	}
+	txids, err := keep()
+	if err != nil {
+		return result, fmt.Errorf("select profiles: %w", err)
+	}
+	result.BytesBefore = filesSize(dir)
+
+	newPath := filepath.Join(dir, "xlog_prof_compact_tmp")
+	removeFiles(newPath)
+	oldIdx, err := io.NewIndexKeyFile(oldPath, 1) // hashSizeMB ignored for existing files
+	if err != nil {
+		return result, fmt.Errorf("open index: %w", err)
+	}
+	oldData, err := os.Open(oldPath + ".data")
+	if err != nil {
+		oldIdx.Close()
+		return result, fmt.Errorf("open data: %w", err)
+	}
+	newIdx, err := io.NewIndexKeyFile(newPath, 1)
+	if err != nil {
+		oldIdx.Close()
+		oldData.Close()
+		return result, fmt.Errorf("create index: %w", err)
+	}
+	newData, err := io.NewRealDataFile(newPath + ".data")
+	if err != nil {
+		oldIdx.Close()
+		oldData.Close()
+		newIdx.Close()
+		return result, fmt.Errorf("create data: %w", err)
+	}
+
+	var copyErr error
+	err = oldIdx.Read(func(key []byte, pos []byte) {
+		if copyErr != nil {
+			return
+		}
+		if len(key) != 8 || !txids[int64(binary.BigEndian.Uint64(key))] {
+			result.Dropped++
+			return
+		}
+		var record []byte
+		if record, copyErr = readRecord(oldData, protocol.BigEndian.Int5(pos)); copyErr != nil {
+			return
+		}
+		var offset int64
+		if offset, copyErr = newData.Write(record); copyErr != nil {
+			return
+		}
+		if copyErr = newIdx.Put(bytes.Clone(key), protocol.BigEndian.Bytes5(offset)); copyErr == nil {
+			result.Kept++
+		}
+	})
+	if err == nil {
+		err = newData.Flush()
+	}
+	oldIdx.Close()
+	oldData.Close()
+	newIdx.Close()
+	newData.Close()
+	if err == nil {
+		err = copyErr
+	}
+	if err != nil {
+		removeFiles(newPath)
+		return result, fmt.Errorf("rewrite profiles: %w", err)
+	}
+
+	result.Compacted = true
+	if result.Kept == 0 {
+		removeFiles(newPath)
+		for _, f := range profileFiles {
+			os.Remove(filepath.Join(dir, f))
+		}
+		result.BytesAfter = filesSize(dir)
+		return result, nil
+	}
+	for _, ext := range []string{".data", ".kfile", ".hfile"} {
+		if err := os.Rename(newPath+ext, oldPath+ext); err != nil {
+			return result, fmt.Errorf("replace %s: %w", ext, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, keptMarker), []byte(label+"\n"), 0644); err != nil {
+		return result, err
+	}
+	result.BytesAfter = filesSize(dir)
+	return result, nil
+}
+
+// readRecord reads the [int32 length][body] record at offset.
+func readRecord(f *os.File, offset int64) ([]byte, error) {
+	head := make([]byte, 4)
+	if _, err := f.ReadAt(head, offset); err != nil {
+		return nil, err
+	}
+	record := make([]byte, 4+int(binary.BigEndian.Uint32(head)))
+	if _, err := f.ReadAt(record, offset); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func removeFiles(path string) {
+	for _, ext := range []string{".data", ".kfile", ".hfile"} {
+		os.Remove(path + ext)
+	}
+}
+
+// filesSize returns the bytes of the profile files of dir.
+func filesSize(dir string) int64 {
+	var total int64
+	for _, f := range profileFiles {
+		if info, err := os.Stat(filepath.Join(dir, f)); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
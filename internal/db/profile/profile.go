@@ -51,10 +51,9 @@ func (p *ProfileData) Write(txid int64, block []byte) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	body := block
-	if cfg := config.Get(); cfg != nil && cfg.CompressProfileEnabled() {
-		body = compress.SharedPool().Compress(block)
-	}
+	cfg := config.Get()
+	body, compressed := compress.SharedPool().Encode(block, cfg != nil && cfg.CompressProfileEnabled())
+	compress.GetStats().Add("profile", len(block), len(body), compressed)
 
 	// Write data: [int32:length][bytes:body]
 	out := protocol.NewDataOutputX()
@@ -66,7 +65,7 @@ func (p *ProfileData) Write(txid int64, block []byte) error {
 		return err
 	}
 
-	// Flush immediately so data is readable by ProfileRD (which opens a separate file handle)
+	// Flush immediately so data is readable by Scan, which reads the file directly
 	if err := p.data.Flush(); err != nil {
 		return err
 	}
@@ -76,57 +75,57 @@ func (p *ProfileData) Write(txid int64, block []byte) error {
 }
 
 // Read retrieves all profile blocks for a txid.
-// Returns blocks in order they were written, up to maxBlocks (-1 for unlimited).
+// Returns blocks in index order (newest first, as in Java), up to maxBlocks
+// (-1 for unlimited).
 func (p *ProfileData) Read(txid int64, maxBlocks int) ([][]byte, error) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	var blocks [][]byte
+	err := p.Scan(txid, func(block []byte) bool {
+		blocks = append(blocks, block)
+		return maxBlocks <= 0 || len(blocks) < maxBlocks
+	})
+	return blocks, err
+}
 
+// Scan calls handler with each profile block of a txid in index order, until
+// handler returns false. Only one block is held at a
+// time, and the lock is released before the blocks are read, so writers are
+// not held up by a slow handler.
+func (p *ProfileData) Scan(txid int64, handler func(block []byte) bool) error {
+	p.mu.Lock()
 	key := protocol.BigEndian.Bytes8(txid)
 	offsets, err := p.index.GetAll(key)
+	p.mu.Unlock()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if len(offsets) == 0 {
-		return nil, nil
+		return nil
 	}
 
-	f, err := os.Open(p.data.Filename())
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	var blocks [][]byte
+	lenBuf := make([]byte, 4)
 	for _, posBytes := range offsets {
-		if maxBlocks > 0 && len(blocks) >= maxBlocks {
-			break
-		}
-
 		offset := protocol.BigEndian.Int5(posBytes)
-		if _, err := f.Seek(offset, 0); err != nil {
-			continue
-		}
 
 		// Read length
-		lenBuf := make([]byte, 4)
-		if _, err := f.Read(lenBuf); err != nil {
+		if _, err := p.data.ReadAt(lenBuf, offset); err != nil {
 			continue
 		}
 		length := int(binary.BigEndian.Uint32(lenBuf))
 
 		// Read body
 		body := make([]byte, length)
-		if _, err := f.Read(body); err != nil {
+		if _, err := p.data.ReadAt(body, offset+4); err != nil {
 			continue
 		}
 		decoded, err := compress.SharedPool().Decode(body)
 		if err != nil {
 			continue
 		}
-		blocks = append(blocks, decoded)
+		if !handler(decoded) {
+			break
+		}
 	}
-
-	return blocks, nil
+	return nil
 }
 
 func (p *ProfileData) Flush() error {
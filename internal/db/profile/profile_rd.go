@@ -4,6 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+
+	"github.com/zbum/scouter-server-go/internal/db/format"
+	"github.com/zbum/scouter-server-go/internal/db/io"
 )
 
 // ProfileRD reads profile data.
@@ -34,6 +37,7 @@ func (r *ProfileRD) GetProfile(date string, txid int64, maxBlocks int) ([][]byte
 }
 
 func (r *ProfileRD) getData(date string) (*ProfileData, error) {
+	io.RestoreMissingDay(r.baseDir, date)
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -45,6 +49,9 @@ func (r *ProfileRD) getData(date string) (*ProfileData, error) {
 	if _, err := os.Stat(filepath.Join(dir, "xlog_prof.data")); os.IsNotExist(err) {
 		return nil, nil
 	}
+	if err := format.Profile.Check(dir); err != nil {
+		return nil, err
+	}
 
 	d, err := NewProfileData(dir)
 	if err != nil {
@@ -68,6 +75,17 @@ func (r *ProfileRD) PurgeOldDays(keepDates map[string]bool) {
 	}
 }
 
+// CloseDay closes the container for a single date, if open.
+func (r *ProfileRD) CloseDay(date string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if d, ok := r.days[date]; ok {
+		d.Close()
+		delete(r.days, date)
+	}
+}
+
 // Close closes all open data files.
 func (r *ProfileRD) Close() {
 	r.mu.Lock()
@@ -76,3 +94,21 @@ func (r *ProfileRD) Close() {
 		d.Close()
 	}
 }
+
+// OpenDays returns the dates with an open container.
+func (r *ProfileRD) OpenDays() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	dates := make([]string, 0, len(r.days))
+	for date := range r.days {
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+// OpenDay opens the container of date ahead of a query. A date without
+// profile data is not an error.
+func (r *ProfileRD) OpenDay(date string) error {
+	_, err := r.getData(date)
+	return err
+}
@@ -2,6 +2,8 @@ package profile
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -158,3 +160,67 @@ func TestProfileRD_NonExistentDate(t *testing.T) {
 		t.Fatal("expected nil for non-existent date")
 	}
 }
+
+func TestCompactDaily(t *testing.T) {
+	dir := t.TempDir()
+
+	data, err := NewProfileData(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Write(100, []byte("tx100-a"))
+	data.Write(200, []byte("tx200"))
+	data.Write(100, []byte("tx100-b"))
+	data.Write(300, []byte("tx300"))
+	data.Close()
+
+	calls := 0
+	keep := func() (map[int64]bool, error) {
+		calls++
+		return map[int64]bool{100: true, 300: true}, nil
+	}
+	result, err := CompactDaily(dir, "error", keep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Compacted || result.Kept != 3 || result.Dropped != 1 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if result.Reclaimed() <= 0 {
+		t.Errorf("expected reclaimed bytes, got %d", result.Reclaimed())
+	}
+
+	data, err = NewProfileData(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b100, _ := data.Read(100, -1)
+	b200, _ := data.Read(200, -1)
+	b300, _ := data.Read(300, -1)
+	data.Close()
+	if len(b100) != 2 || string(b100[0]) != "tx100-b" || string(b100[1]) != "tx100-a" { // newest first, as before
+		t.Errorf("tx100 blocks %q", b100)
+	}
+	if b200 != nil {
+		t.Errorf("tx200 should be dropped, got %q", b200)
+	}
+	if len(b300) != 1 || string(b300[0]) != "tx300" {
+		t.Errorf("tx300 blocks %q", b300)
+	}
+
+	// Same selection again: skipped without reading the XLogs
+	if result, err = CompactDaily(dir, "error", keep); err != nil || result.Compacted || calls != 1 {
+		t.Fatalf("expected skip, got %+v err=%v calls=%d", result, err, calls)
+	}
+
+	// Nothing kept: the profile files go away
+	result, err = CompactDaily(dir, "slow>=8000", func() (map[int64]bool, error) { return nil, nil })
+	if err != nil || !result.Compacted || result.Kept != 0 {
+		t.Fatalf("unexpected result %+v err=%v", result, err)
+	}
+	for _, f := range profileFiles {
+		if _, err := os.Stat(filepath.Join(dir, f)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", f)
+		}
+	}
+}
@@ -6,15 +6,23 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/zbum/scouter-server-go/internal/db/format"
+	"github.com/zbum/scouter-server-go/internal/db/io"
+	"github.com/zbum/scouter-server-go/internal/ingest"
 	"github.com/zbum/scouter-server-go/internal/util"
 )
 
 // ProfileEntry represents a single profile block to be written.
 type ProfileEntry struct {
-	TimeMs int64
-	Txid   int64
-	Data   []byte // pre-serialized step data
+	TimeMs  int64
+	Txid    int64
+	ObjHash int32  // for write statistics, 0 if unknown
+	Data    []byte // pre-serialized step data
+	// Received is when the pack arrived over UDP, zero if unknown.
+	Received time.Time
 }
 
 // ProfileWR manages async writing of profile data.
@@ -23,6 +31,7 @@ type ProfileWR struct {
 	baseDir string
 	days    map[string]*ProfileData
 	queue   chan *ProfileEntry
+	dropped atomic.Int64
 }
 
 func NewProfileWR(baseDir string, queueSize int) *ProfileWR {
@@ -56,10 +65,21 @@ func (w *ProfileWR) Add(entry *ProfileEntry) {
 	select {
 	case w.queue <- entry:
 	default:
+		w.dropped.Add(1)
 		slog.Debug("ProfileWR: queue full, dropping")
 	}
 }
 
+// Dropped returns the number of entries dropped due to write queue overflow.
+func (w *ProfileWR) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Pending returns the number of entries waiting to be written.
+func (w *ProfileWR) Pending() int {
+	return len(w.queue)
+}
+
 func (w *ProfileWR) process(entry *ProfileEntry) {
 	date := util.FormatDate(entry.TimeMs)
 	data, err := w.getData(date)
@@ -70,7 +90,10 @@ func (w *ProfileWR) process(entry *ProfileEntry) {
 
 	if err := data.Write(entry.Txid, entry.Data); err != nil {
 		slog.Error("ProfileWR: write error", "error", err)
+		return
 	}
+	io.GetWriteStats().Record("profile", date, entry.ObjHash, len(entry.Data))
+	ingest.GetLatency().Observe("profile", entry.Received)
 }
 
 func (w *ProfileWR) getData(date string) (*ProfileData, error) {
@@ -85,6 +108,9 @@ func (w *ProfileWR) getData(date string) (*ProfileData, error) {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, err
 	}
+	if err := format.Profile.Stamp(dir); err != nil {
+		return nil, err
+	}
 
 	d, err := NewProfileData(dir)
 	if err != nil {
@@ -109,6 +135,18 @@ func (w *ProfileWR) PurgeOldDays(keepDates map[string]bool) {
 	}
 }
 
+// CloseDay flushes and closes the container for a single date, if open.
+func (w *ProfileWR) CloseDay(date string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if d, ok := w.days[date]; ok {
+		d.Flush()
+		d.Close()
+		delete(w.days, date)
+	}
+}
+
 func (w *ProfileWR) flushAll() {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -127,6 +165,16 @@ func (w *ProfileWR) Read(date string, txid int64, maxBlocks int) ([][]byte, erro
 	return data.Read(txid, maxBlocks)
 }
 
+// Scan calls handler with each profile block of txid through the writer's
+// ProfileData instance, until handler returns false.
+func (w *ProfileWR) Scan(date string, txid int64, handler func(block []byte) bool) error {
+	data, err := w.getData(date)
+	if err != nil {
+		return err
+	}
+	return data.Scan(txid, handler)
+}
+
 // Close closes all open data files.
 func (w *ProfileWR) Close() {
 	w.mu.Lock()
@@ -135,3 +183,14 @@ func (w *ProfileWR) Close() {
 		d.Close()
 	}
 }
+
+// OpenDays returns the dates with an open container.
+func (w *ProfileWR) OpenDays() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	dates := make([]string, 0, len(w.days))
+	for date := range w.days {
+		dates = append(dates, date)
+	}
+	return dates
+}
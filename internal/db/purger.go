@@ -16,6 +16,7 @@ type DayContainerPurger struct {
 	targets   []DayPurgeable
 	keepHours int
 	interval  time.Duration
+	now       func() time.Time
 }
 
 // NewDayContainerPurger creates a purger that keeps containers for the last keepHours.
@@ -27,9 +28,16 @@ func NewDayContainerPurger(keepHours int, targets ...DayPurgeable) *DayContainer
 		targets:   targets,
 		keepHours: keepHours,
 		interval:  1 * time.Hour,
+		now:       time.Now,
 	}
 }
 
+// SetClock makes the purger take the current time from now, so tests can
+// move across days without waiting.
+func (p *DayContainerPurger) SetClock(now func() time.Time) {
+	p.now = now
+}
+
 // Start begins periodic purging in the background.
 func (p *DayContainerPurger) Start(ctx context.Context) {
 	go func() {
@@ -41,13 +49,14 @@ func (p *DayContainerPurger) Start(ctx context.Context) {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				p.purge()
+				p.Purge()
 			}
 		}
 	}()
 }
 
-func (p *DayContainerPurger) purge() {
+// Purge closes the containers of days outside the kept window now.
+func (p *DayContainerPurger) Purge() {
 	keepDates := p.buildKeepDates()
 	purged := 0
 	for _, t := range p.targets {
@@ -58,7 +67,7 @@ func (p *DayContainerPurger) purge() {
 }
 
 func (p *DayContainerPurger) buildKeepDates() map[string]bool {
-	now := time.Now()
+	now := p.now()
 	dates := make(map[string]bool)
 	for h := 0; h < p.keepHours; h += 24 {
 		t := now.Add(-time.Duration(h) * time.Hour)
@@ -55,7 +55,7 @@ func TestDayContainerPurger_Purge(t *testing.T) {
 	m2 := &mockPurgeable{}
 
 	p := NewDayContainerPurger(48, m1, m2)
-	p.purge()
+	p.Purge()
 
 	if m1.callCount != 1 {
 		t.Fatalf("expected m1 called 1 time, got %d", m1.callCount)
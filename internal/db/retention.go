@@ -0,0 +1,279 @@
+package db
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Storage categories that the purge settings remove independently.
+const (
+	CategoryProfile         = "profile"
+	CategoryXLog            = "xlog"
+	CategorySummary         = "summary"
+	CategoryRealtimeCounter = "realtime_counter"
+	CategoryDailyText       = "daily_text"
+	CategoryOther           = "other" // daily counters, alerts, visitors: removed with the date dir
+)
+
+// Categories lists the storage categories in purge order.
+var Categories = []string{
+	CategoryProfile, CategoryXLog, CategorySummary,
+	CategoryRealtimeCounter, CategoryDailyText, CategoryOther,
+}
+
+// DaySize is the on-disk size of one date directory split by category.
+type DaySize struct {
+	Date  string
+	Bytes map[string]int64
+}
+
+// Total returns the size of the whole date directory.
+func (d DaySize) Total() int64 {
+	var n int64
+	for _, b := range d.Bytes {
+		n += b
+	}
+	return n
+}
+
+// MeasureDaySizes returns the size of every date directory under baseDir,
+// oldest first.
+func MeasureDaySizes(baseDir string) []DaySize {
+	s := &DataPurgeScheduler{baseDir: baseDir}
+	var days []DaySize
+	for _, date := range s.listDateDirs() {
+		day := DaySize{Date: date, Bytes: make(map[string]int64)}
+		dateDir := filepath.Join(baseDir, date)
+		filepath.WalkDir(dateDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			rel, _ := filepath.Rel(dateDir, path)
+			day.Bytes[categoryOf(filepath.ToSlash(rel))] += info.Size()
+			return nil
+		})
+		days = append(days, day)
+	}
+	return days
+}
+
+// categoryOf maps a file path relative to its date directory to the category
+// of the purge step that deletes it.
+func categoryOf(rel string) string {
+	dir, name, _ := strings.Cut(rel, "/")
+	switch dir {
+	case "xlog":
+		if strings.HasPrefix(name, "xlog_prof.") {
+			return CategoryProfile
+		}
+		return CategoryXLog
+	case "summary":
+		return CategorySummary
+	case "counter":
+		if strings.HasPrefix(name, "real") {
+			return CategoryRealtimeCounter
+		}
+	case "text":
+		return CategoryDailyText
+	}
+	return CategoryOther
+}
+
+// RetentionPolicy holds the mgr_purge_* settings. A keep value of 0 or less
+// disables purging by age for that category.
+type RetentionPolicy struct {
+	ProfileKeepDays         int
+	XLogKeepDays            int
+	SumKeepDays             int
+	CounterKeepDays         int
+	RealtimeCounterKeepDays int
+	DailyTextKeepDays       int
+	DiskUsagePct            int
+}
+
+// KeepDays returns how many days a category effectively survives, taking
+// into account that removing a directory also removes what it contains.
+// It returns 0 if the category is never purged by age.
+func (p RetentionPolicy) KeepDays(category string) int {
+	switch category {
+	case CategoryProfile:
+		return minKeep(p.ProfileKeepDays, p.XLogKeepDays, p.CounterKeepDays)
+	case CategoryXLog:
+		return minKeep(p.XLogKeepDays, p.CounterKeepDays)
+	case CategorySummary:
+		return minKeep(p.SumKeepDays, p.CounterKeepDays)
+	case CategoryRealtimeCounter:
+		return minKeep(p.RealtimeCounterKeepDays, p.CounterKeepDays)
+	case CategoryDailyText:
+		return minKeep(p.DailyTextKeepDays, p.CounterKeepDays)
+	}
+	return minKeep(p.CounterKeepDays)
+}
+
+func minKeep(days ...int) int {
+	m := 0
+	for _, d := range days {
+		if d > 0 && (m == 0 || d < m) {
+			m = d
+		}
+	}
+	return m
+}
+
+// Disk describes the volume holding the data directory. Other is the space
+// used by files outside the data directory, assumed constant.
+type Disk struct {
+	Total int64
+	Other int64
+}
+
+// RetentionForecast is the outcome of SimulateRetention.
+type RetentionForecast struct {
+	DailyGrowth map[string]int64 // projected bytes per day and category
+	SteadyState map[string]int64 // bytes held per category once retention is reached; -1 if unbounded
+	Peak        int64            // largest data directory size seen in the simulation
+	PeakDate    string
+
+	// ThresholdDate is the first simulated day on which usage exceeds
+	// DiskUsagePct, or empty if it is not reached within the horizon.
+	ThresholdDate string
+	// ThresholdPct is the usage on ThresholdDate before the disk usage purge.
+	ThresholdPct int
+	// EffectiveDays is the fewest days of data left once the disk usage purge
+	// starts deleting whole days, or 0 if it never runs.
+	EffectiveDays int
+}
+
+// SimulateRetention projects disk usage for horizon days after the last
+// measured day. Each future day is assumed to grow like the average of the
+// last window complete days. Purging is simulated the way
+// DataPurgeScheduler does it: by age per category first, then oldest whole
+// days while usage is above the threshold.
+func SimulateRetention(days []DaySize, policy RetentionPolicy, disk Disk, window, horizon int) RetentionForecast {
+	fc := RetentionForecast{
+		DailyGrowth: averageGrowth(days, window),
+		SteadyState: make(map[string]int64),
+	}
+	for _, c := range Categories {
+		if keep := policy.KeepDays(c); keep > 0 {
+			// Purging keeps dates on or after today-keep, i.e. keep+1 days.
+			fc.SteadyState[c] = fc.DailyGrowth[c] * int64(keep+1)
+		} else {
+			fc.SteadyState[c] = -1
+		}
+	}
+	if len(days) == 0 {
+		return fc
+	}
+
+	// Work on a copy so the caller's measurements are untouched.
+	held := make([]DaySize, 0, len(days)+horizon)
+	for _, d := range days {
+		b := make(map[string]int64, len(d.Bytes))
+		for c, n := range d.Bytes {
+			b[c] = n
+		}
+		held = append(held, DaySize{Date: d.Date, Bytes: b})
+	}
+
+	day, err := time.ParseInLocation("20060102", days[len(days)-1].Date, time.Local)
+	if err != nil {
+		return fc
+	}
+	for i := 0; i < horizon; i++ {
+		day = day.AddDate(0, 0, 1)
+		today := day.Format("20060102")
+		b := make(map[string]int64, len(fc.DailyGrowth))
+		for c, n := range fc.DailyGrowth {
+			b[c] = n
+		}
+		held = append(held, DaySize{Date: today, Bytes: b})
+
+		for _, c := range Categories {
+			keep := policy.KeepDays(c)
+			if keep <= 0 {
+				continue
+			}
+			cutoff := day.AddDate(0, 0, -keep).Format("20060102")
+			for _, d := range held {
+				if d.Date >= cutoff {
+					break
+				}
+				delete(d.Bytes, c)
+			}
+		}
+		held = dropEmptyDays(held)
+
+		size := totalSize(held)
+		if size > fc.Peak {
+			fc.Peak, fc.PeakDate = size, today
+		}
+		if policy.DiskUsagePct <= 0 || disk.Total <= 0 {
+			continue
+		}
+		pct := int((disk.Other + size) * 100 / disk.Total)
+		if pct <= policy.DiskUsagePct {
+			continue
+		}
+		if fc.ThresholdDate == "" {
+			fc.ThresholdDate, fc.ThresholdPct = today, pct
+		}
+		for len(held) > 1 && int((disk.Other+size)*100/disk.Total) > policy.DiskUsagePct {
+			size -= held[0].Total()
+			held = held[1:]
+		}
+		if fc.EffectiveDays == 0 || len(held) < fc.EffectiveDays {
+			fc.EffectiveDays = len(held)
+		}
+	}
+	return fc
+}
+
+// averageGrowth averages the sizes of the last window days, excluding the
+// newest day, which is usually still being written.
+func averageGrowth(days []DaySize, window int) map[string]int64 {
+	avg := make(map[string]int64)
+	complete := days
+	if len(complete) > 1 {
+		complete = complete[:len(complete)-1]
+	}
+	if window > 0 && len(complete) > window {
+		complete = complete[len(complete)-window:]
+	}
+	if len(complete) == 0 {
+		return avg
+	}
+	for _, d := range complete {
+		for c, n := range d.Bytes {
+			avg[c] += n
+		}
+	}
+	for c := range avg {
+		avg[c] /= int64(len(complete))
+	}
+	return avg
+}
+
+func dropEmptyDays(days []DaySize) []DaySize {
+	out := days[:0]
+	for _, d := range days {
+		if len(d.Bytes) > 0 {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func totalSize(days []DaySize) int64 {
+	var n int64
+	for _, d := range days {
+		n += d.Total()
+	}
+	return n
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMeasureDaySizes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]int{
+		"20260101/xlog/xlog.data":      100,
+		"20260101/xlog/xlog_prof.data": 50,
+		"20260101/counter/real.data":   30,
+		"20260101/counter/daily.data":  7,
+		"20260101/summary/sum.data":    5,
+		"20260101/text/text.kfile":     3,
+		"20260102/xlog/xlog.data":      10,
+		"00000000/text/text.kfile":     999, // permanent texts are not a day
+	}
+	for name, size := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, make([]byte, size), 0644)
+	}
+
+	days := MeasureDaySizes(dir)
+	if len(days) != 2 || days[0].Date != "20260101" || days[1].Date != "20260102" {
+		t.Fatalf("days = %+v", days)
+	}
+	want := map[string]int64{
+		CategoryXLog:            100,
+		CategoryProfile:         50,
+		CategoryRealtimeCounter: 30,
+		CategoryOther:           7,
+		CategorySummary:         5,
+		CategoryDailyText:       3,
+	}
+	for c, n := range want {
+		if days[0].Bytes[c] != n {
+			t.Errorf("%s = %d, want %d", c, days[0].Bytes[c], n)
+		}
+	}
+	if days[0].Total() != 195 {
+		t.Errorf("total = %d, want 195", days[0].Total())
+	}
+}
+
+func TestRetentionPolicy_KeepDays(t *testing.T) {
+	p := RetentionPolicy{ProfileKeepDays: 40, XLogKeepDays: 30, CounterKeepDays: 70}
+	if got := p.KeepDays(CategoryProfile); got != 30 {
+		t.Errorf("profile keep = %d, want 30 (removed with the xlog dir)", got)
+	}
+	if got := p.KeepDays(CategorySummary); got != 70 {
+		t.Errorf("summary keep = %d, want 70 (removed with the date dir)", got)
+	}
+	if got := (RetentionPolicy{}).KeepDays(CategoryXLog); got != 0 {
+		t.Errorf("unset keep = %d, want 0", got)
+	}
+}
+
+func uniformDays(n int, xlog, counter int64) []DaySize {
+	days := make([]DaySize, n)
+	for i := range days {
+		days[i] = DaySize{
+			Date:  fmt.Sprintf("202601%02d", i+1),
+			Bytes: map[string]int64{CategoryXLog: xlog, CategoryOther: counter},
+		}
+	}
+	return days
+}
+
+func TestSimulateRetention_SteadyState(t *testing.T) {
+	days := uniformDays(5, 100, 10)
+	policy := RetentionPolicy{XLogKeepDays: 3, CounterKeepDays: 10}
+
+	fc := SimulateRetention(days, policy, Disk{}, 7, 30)
+	if fc.DailyGrowth[CategoryXLog] != 100 || fc.DailyGrowth[CategoryOther] != 10 {
+		t.Fatalf("growth = %v", fc.DailyGrowth)
+	}
+	if fc.SteadyState[CategoryXLog] != 400 || fc.SteadyState[CategoryOther] != 110 {
+		t.Errorf("steady state = %v", fc.SteadyState)
+	}
+	// After 30 days only the retained days remain: 4 days of xlog, 11 of counters.
+	if fc.Peak != 400+110 {
+		t.Errorf("peak = %d, want 510", fc.Peak)
+	}
+	if fc.ThresholdDate != "" {
+		t.Errorf("threshold reached without a disk")
+	}
+}
+
+func TestSimulateRetention_DiskThreshold(t *testing.T) {
+	days := uniformDays(5, 100, 0)
+	policy := RetentionPolicy{XLogKeepDays: 30, DiskUsagePct: 80}
+
+	// 1000 bytes of disk with 200 used by other files: 600 bytes of data
+	// fit, so the 7th day (5 measured + 2 simulated) crosses the threshold.
+	fc := SimulateRetention(days, policy, Disk{Total: 1000, Other: 200}, 7, 10)
+	if fc.ThresholdDate != "20260107" || fc.ThresholdPct != 90 {
+		t.Errorf("threshold = %s (%d%%), want 20260107 (90%%)", fc.ThresholdDate, fc.ThresholdPct)
+	}
+	if fc.EffectiveDays != 6 {
+		t.Errorf("effective days = %d, want 6", fc.EffectiveDays)
+	}
+	if fc.SteadyState[CategoryXLog] != 3100 {
+		t.Errorf("steady state = %d", fc.SteadyState[CategoryXLog])
+	}
+}
@@ -2,7 +2,6 @@ package summary
 
 import (
 	"encoding/binary"
-	"os"
 	"path/filepath"
 	"sync"
 
@@ -88,11 +87,9 @@ func (sd *SummaryData) ReadRange(stime, etime int64, handler func(timeMs int64,
 	sd.mu.Lock()
 	defer sd.mu.Unlock()
 
-	dataPath := filepath.Join(sd.dir, formatFileName(sd.stype)+".data")
-
 	return sd.index.Read(stime, etime, func(timeMs int64, dataPos []byte) bool {
 		offset := protocol.BigEndian.Int5(dataPos)
-		raw, err := readEntryAt(dataPath, offset)
+		raw, err := readEntryAt(sd.data, offset)
 		if err == nil && raw != nil {
 			handler(timeMs, raw)
 		}
@@ -101,25 +98,15 @@ func (sd *SummaryData) ReadRange(stime, etime int64, handler func(timeMs int64,
 }
 
 // readEntryAt reads a [2-byte length][data] entry from the file at the given offset.
-func readEntryAt(path string, offset int64) ([]byte, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	if _, err := f.Seek(offset, 0); err != nil {
-		return nil, err
-	}
-
+func readEntryAt(f *io.RealDataFile, offset int64) ([]byte, error) {
 	var lenBuf [2]byte
-	if _, err := f.Read(lenBuf[:]); err != nil {
+	if _, err := f.ReadAt(lenBuf[:], offset); err != nil {
 		return nil, err
 	}
 	length := binary.BigEndian.Uint16(lenBuf[:])
 
 	data := make([]byte, length)
-	if _, err := f.Read(data); err != nil {
+	if _, err := f.ReadAt(data, offset+2); err != nil {
 		return nil, err
 	}
 
@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+
+	"github.com/zbum/scouter-server-go/internal/db/io"
 )
 
 // SummaryRD is a summary reader.
@@ -23,6 +25,7 @@ func NewSummaryRD(baseDir string) *SummaryRD {
 
 // getContainer retrieves or opens a day+type container for reading.
 func (r *SummaryRD) getContainer(date string, stype byte) (*SummaryData, error) {
+	io.RestoreMissingDay(r.baseDir, date)
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -91,6 +94,22 @@ func (r *SummaryRD) PurgeOldDays(keepDates map[string]bool) {
 	}
 }
 
+// CloseDay closes all containers for a single date, if open.
+func (r *SummaryRD) CloseDay(date string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, sd := range r.days {
+		if key.date != date {
+			continue
+		}
+		if sd != nil {
+			sd.Close()
+		}
+		delete(r.days, key)
+	}
+}
+
 // Close closes all open day containers.
 func (r *SummaryRD) Close() {
 	r.mu.Lock()
@@ -103,3 +122,18 @@ func (r *SummaryRD) Close() {
 	}
 	r.days = make(map[dayKey]*SummaryData)
 }
+
+// OpenDays returns the dates with an open container of any summary type.
+func (r *SummaryRD) OpenDays() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[string]bool)
+	var dates []string
+	for key := range r.days {
+		if !seen[key.date] {
+			seen[key.date] = true
+			dates = append(dates, key.date)
+		}
+	}
+	return dates
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/zbum/scouter-server-go/internal/db/io"
 	"github.com/zbum/scouter-server-go/internal/util"
 )
 
@@ -102,7 +103,9 @@ func (w *SummaryWR) process(entry *SummaryEntry) {
 
 	if err := container.Write(entry.TimeMs, entry.Data); err != nil {
 		slog.Error("SummaryWR write error", "error", err)
+		return
 	}
+	io.GetWriteStats().Record("summary", date, 0, len(entry.Data))
 }
 
 // PurgeOldDays closes day containers not in the keepDates set.
@@ -122,6 +125,23 @@ func (w *SummaryWR) PurgeOldDays(keepDates map[string]bool) {
 	}
 }
 
+// CloseDay flushes and closes all containers for a single date, if open.
+func (w *SummaryWR) CloseDay(date string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for key, sd := range w.days {
+		if key.date != date {
+			continue
+		}
+		if sd != nil {
+			sd.Flush()
+			sd.Close()
+		}
+		delete(w.days, key)
+	}
+}
+
 // Close closes all open day containers.
 func (w *SummaryWR) Close() {
 	w.mu.Lock()
@@ -135,3 +155,18 @@ func (w *SummaryWR) Close() {
 	}
 	w.days = make(map[dayKey]*SummaryData)
 }
+
+// OpenDays returns the dates with an open container of any summary type.
+func (w *SummaryWR) OpenDays() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	seen := make(map[string]bool)
+	var dates []string
+	for key := range w.days {
+		if !seen[key.date] {
+			seen[key.date] = true
+			dates = append(dates, key.date)
+		}
+	}
+	return dates
+}
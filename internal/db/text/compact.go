@@ -0,0 +1,153 @@
+package text
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/db/io"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// compactedMarker lists, one per line, the divs already removed from a daily
+// text directory, so a compacted day is not rewritten again.
+const compactedMarker = "compacted"
+
+// CompactResult describes the compaction of one daily text directory.
+type CompactResult struct {
+	Compacted   bool // false if the divs had already been removed
+	Kept        int  // records rewritten
+	Dropped     int  // records of the removed divs
+	Duplicates  int  // records of a key seen earlier
+	BytesBefore int64
+	BytesAfter  int64
+}
+
+// Reclaimed returns the bytes freed on disk.
+func (r CompactResult) Reclaimed() int64 {
+	return r.BytesBefore - r.BytesAfter
+}
+
+// CompactDaily removes the records of drop from the daily text directory dir
+// ({date}/text) and rewrites the remaining ones into fresh index files,
+// leaving out duplicate keys and deleted records. A directory left without
+// records is removed.
+//
+// The day must not be open: callers close its text tables first.
+func CompactDaily(dir string, drop []string) (CompactResult, error) {
+	var result CompactResult
+	oldPath := filepath.Join(dir, "text")
+	if _, err := os.Stat(oldPath + ".kfile"); err != nil {
+		return result, nil
+	}
+	done := compactedDivs(dir)
+	var pending []string
+	for _, div := range drop {
+		if !slices.Contains(done, div) {
+			pending = append(pending, div)
+		}
+	}
+	if len(pending) == 0 {
+		return result, nil
+	}
+	dropHash := make(map[uint32]bool, len(drop))
+	for _, div := range drop {
+		dropHash[uint32(util.HashString(div))] = true
+	}
+
+	result.BytesBefore = dirSize(dir)
+	newPath := filepath.Join(dir, "text_compact_tmp")
+	os.Remove(newPath + ".hfile")
+	os.Remove(newPath + ".kfile")
+
+	oldIdx, err := io.NewIndexKeyFile(oldPath, 1) // hashSizeMB ignored for existing files
+	if err != nil {
+		return result, fmt.Errorf("open index: %w", err)
+	}
+	hashSizeMB := 1
+	if cfg := config.Get(); cfg != nil {
+		hashSizeMB = cfg.MgrTextDbDailyIndexMB()
+	}
+	newIdx, err := io.NewIndexKeyFile(newPath, hashSizeMB)
+	if err != nil {
+		oldIdx.Close()
+		return result, fmt.Errorf("create index: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var putErr error
+	err = oldIdx.Read(func(key []byte, data []byte) {
+		if putErr != nil {
+			return
+		}
+		if len(key) >= 4 && dropHash[binary.BigEndian.Uint32(key[:4])] {
+			result.Dropped++
+			return
+		}
+		if seen[string(key)] {
+			result.Duplicates++
+			return
+		}
+		seen[string(key)] = true
+		if putErr = newIdx.Put(bytes.Clone(key), bytes.Clone(data)); putErr == nil {
+			result.Kept++
+		}
+	})
+	oldIdx.Close()
+	newIdx.Close()
+	if err == nil {
+		err = putErr
+	}
+	if err != nil {
+		os.Remove(newPath + ".hfile")
+		os.Remove(newPath + ".kfile")
+		return result, fmt.Errorf("rewrite records: %w", err)
+	}
+
+	result.Compacted = true
+	if result.Kept == 0 {
+		if err := os.RemoveAll(dir); err != nil {
+			return result, err
+		}
+		return result, nil
+	}
+	for _, ext := range []string{".kfile", ".hfile"} {
+		if err := os.Rename(newPath+ext, oldPath+ext); err != nil {
+			return result, fmt.Errorf("replace %s: %w", ext, err)
+		}
+	}
+	marker := strings.Join(append(done, pending...), "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(dir, compactedMarker), []byte(marker), 0644); err != nil {
+		return result, err
+	}
+	result.BytesAfter = dirSize(dir)
+	return result, nil
+}
+
+// compactedDivs returns the divs listed in the marker of dir.
+func compactedDivs(dir string) []string {
+	data, err := os.ReadFile(filepath.Join(dir, compactedMarker))
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(data))
+}
+
+func dirSize(dir string) int64 {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && !e.IsDir() {
+			total += info.Size()
+		}
+	}
+	return total
+}
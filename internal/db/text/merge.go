@@ -0,0 +1,85 @@
+package text
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zbum/scouter-server-go/internal/db/io"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+)
+
+// MergePerm adds the texts of the permanent text directory srcDir, such as
+// one unpacked from another server's data, that the permanent store of
+// dataDir lacks, and returns how many were added. Texts are keyed by hash,
+// so a text both stores hold is kept once. The store of dataDir must not be
+// open meanwhile.
+func MergePerm(srcDir, dataDir string) (int, error) {
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return 0, err
+	}
+	dst, err := NewTextPermTable(filepath.Join(dataDir, textDirName, "text"))
+	if err != nil {
+		return 0, err
+	}
+	defer dst.Close()
+
+	added := 0
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, "text_") || !strings.HasSuffix(name, ".kfile") {
+			continue
+		}
+		div := strings.TrimSuffix(strings.TrimPrefix(name, "text_"), ".kfile")
+		if div == "" {
+			continue
+		}
+		n, err := mergeDiv(filepath.Join(srcDir, "text_"+div), div, dst)
+		added += n
+		if err != nil {
+			return added, fmt.Errorf("merge %q: %w", div, err)
+		}
+	}
+	return added, nil
+}
+
+func mergeDiv(srcPath, div string, dst *TextPermTable) (int, error) {
+	idx, err := io.NewIndexKeyFile(srcPath, 1) // hashSizeMB ignored for existing files
+	if err != nil {
+		return 0, err
+	}
+	defer idx.Close()
+	data, err := NewTextPermData(srcPath)
+	if err != nil {
+		return 0, err
+	}
+	defer data.Close()
+
+	added := 0
+	var mergeErr error
+	err = idx.Read(func(key []byte, dataPos []byte) {
+		if mergeErr != nil || len(key) != 4 {
+			return
+		}
+		text, err := data.Read(protocol.BigEndian.Int5(dataPos))
+		if err != nil {
+			mergeErr = err
+			return
+		}
+		ok, err := dst.SetIfAbsent(div, int32(binary.BigEndian.Uint32(key)), string(text))
+		if err != nil {
+			mergeErr = err
+			return
+		}
+		if ok {
+			added++
+		}
+	})
+	if err == nil {
+		err = mergeErr
+	}
+	return added, err
+}
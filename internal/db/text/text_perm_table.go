@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/zbum/scouter-server-go/internal/config"
@@ -79,31 +80,40 @@ func (t *TextPermTable) getFiles(div string) (*io.IndexKeyFile, *TextPermData, e
 // Set stores a text string with the given div and hash.
 // Checks HasKey first to avoid duplicate entries (matching Java behavior).
 func (t *TextPermTable) Set(div string, hash int32, text string) error {
+	_, err := t.SetIfAbsent(div, hash, text)
+	return err
+}
+
+// SetIfAbsent is Set, also reporting whether the text was newly written.
+func (t *TextPermTable) SetIfAbsent(div string, hash int32, text string) (bool, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
 	idx, data, err := t.getFiles(div)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	key := makePermHashKey(hash)
 	exists, err := idx.HasKey(key)
 	if err != nil {
-		return err
+		return false, err
 	}
 	if exists {
-		return nil
+		return false, nil
 	}
 
 	// Write text to data file
 	dataPos, err := data.Write([]byte(text))
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Store data position in index
-	return idx.Put(key, protocol.BigEndian.Bytes5(dataPos))
+	if err := idx.Put(key, protocol.BigEndian.Bytes5(dataPos)); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // Get retrieves a text string by div and hash.
@@ -152,6 +162,39 @@ func (t *TextPermTable) HasKey(div string, hash int32) (bool, error) {
 	return idx.HasKey(key)
 }
 
+// Compact compacts the index of every div in the table directory, see
+// io.IndexKeyFile.Compact, and returns the totals.
+func (t *TextPermTable) Compact(minDropPct int) (io.CompactResult, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var total io.CompactResult
+	files, err := filepath.Glob(filepath.Join(t.path, "text_*.kfile"))
+	if err != nil {
+		return total, err
+	}
+	for _, f := range files {
+		div := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(f), "text_"), ".kfile")
+		if strings.HasSuffix(div, "_compact_tmp") {
+			continue // left by an interrupted compaction
+		}
+		idx, _, err := t.getFiles(div)
+		if err != nil {
+			return total, err
+		}
+		r, err := idx.Compact(minDropPct)
+		total.Compacted = total.Compacted || r.Compacted
+		total.Kept += r.Kept
+		total.Dropped += r.Dropped
+		total.BytesBefore += r.BytesBefore
+		total.BytesAfter += r.BytesAfter
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
 // Close closes all underlying files.
 func (t *TextPermTable) Close() {
 	t.mu.Lock()
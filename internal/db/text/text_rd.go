@@ -139,6 +139,17 @@ func (r *TextRD) getDailyTable(date string) (*TextTable, error) {
 	return table, nil
 }
 
+// CloseDay closes the daily text table for a single date, if open.
+func (r *TextRD) CloseDay(date string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t, ok := r.dailyTables[date]; ok {
+		t.Close()
+		delete(r.dailyTables, date)
+	}
+}
+
 // Close closes the text table and clears the cache.
 func (r *TextRD) Close() {
 	r.mu.Lock()
@@ -154,3 +165,14 @@ func (r *TextRD) Close() {
 	r.dailyTables = make(map[string]*TextTable)
 	r.cache = make(map[cacheKey]string)
 }
+
+// OpenDays returns the dates with an open container.
+func (r *TextRD) OpenDays() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	dates := make([]string, 0, len(r.dailyTables))
+	for date := range r.dailyTables {
+		dates = append(dates, date)
+	}
+	return dates
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/zbum/scouter-server-go/internal/util"
 )
@@ -151,6 +154,38 @@ func TestTextPermTable_Dedup(t *testing.T) {
 	}
 }
 
+func TestTextPermTable_Compact(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	table, err := NewTextPermTable(tmpDir)
+	if err != nil {
+		t.Fatalf("NewTextPermTable failed: %v", err)
+	}
+	for _, text := range []string{"/a", "/b", "/c"} {
+		table.Set("service", util.HashString(text), text)
+	}
+	table.Set("sql", util.HashString("select 1"), "select 1")
+	table.Close()
+	os.WriteFile(filepath.Join(tmpDir, "text_sql_compact_tmp.kfile"), nil, 0644)
+
+	// Divs not opened yet are compacted too; nothing is deleted, so nothing is rewritten.
+	table, err = NewTextPermTable(tmpDir)
+	if err != nil {
+		t.Fatalf("NewTextPermTable failed: %v", err)
+	}
+	defer table.Close()
+	res, err := table.Compact(0)
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if res.Compacted || res.Kept != 4 || res.Dropped != 0 {
+		t.Errorf("Compact = %+v, want 4 records kept without rewrite", res)
+	}
+	if text, found, _ := table.Get("service", util.HashString("/b")); !found || text != "/b" {
+		t.Errorf("Get after Compact = %q, %v", text, found)
+	}
+}
+
 func TestTextTable_DailySetGet(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -585,3 +620,143 @@ func TestRehashAll(t *testing.T) {
 		}
 	}
 }
+
+func TestTextWR_Usage(t *testing.T) {
+	tmpDir := t.TempDir()
+	wr := NewTextWR(tmpDir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wr.Start(ctx)
+
+	long := strings.Repeat("가", 150) // 450 bytes, previewed at a rune boundary
+	for _, s := range []string{"SELECT 1", "SELECT * FROM orders WHERE id = ?", long} {
+		wr.Add("sql", util.HashString(s), s)
+	}
+	wr.Add("service", util.HashString("/api/users"), "/api/users")
+	wr.Add("service", util.HashString("/api/users"), "/api/users") // duplicate
+	wr.Flush()
+
+	today := time.Now().Format("20060102")
+	yesterday := time.Now().AddDate(0, 0, -1).Format("20060102")
+	usage, err := wr.Usage(2, []string{yesterday, today})
+	if err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	if len(usage) != 2 || usage[0].Div != "sql" || usage[1].Div != "service" {
+		t.Fatalf("usage = %+v", usage)
+	}
+	sql := usage[0]
+	if sql.Texts != 3 || sql.TextBytes != int64(8+33+len(long)) || sql.DataBytes != sql.TextBytes+3*4 {
+		t.Errorf("sql texts/bytes = %d/%d/%d", sql.Texts, sql.TextBytes, sql.DataBytes)
+	}
+	if sql.IndexBytes == 0 {
+		t.Error("sql index bytes not counted")
+	}
+	if len(sql.Largest) != 2 || sql.Largest[0].Hash != util.HashString(long) || sql.Largest[1].Length != 33 {
+		t.Errorf("largest = %+v", sql.Largest)
+	}
+	if p := sql.Largest[0].Preview; !utf8.ValidString(p) || !strings.HasSuffix(p, "...") || len(p) > largestPreviewLen+3 {
+		t.Errorf("preview = %q", p)
+	}
+	if usage[1].Growth[0].Texts != 0 || usage[1].Growth[1] != (DivGrowth{Texts: 1, Bytes: 10}) {
+		t.Errorf("service growth = %+v", usage[1].Growth)
+	}
+
+	// Growth survives a restart; texts already stored are not counted again.
+	wr.Close()
+	wr = NewTextWR(tmpDir)
+	wr.Start(ctx)
+	wr.Add("service", util.HashString("/api/users"), "/api/users")
+	wr.Flush()
+	usage, _ = wr.Usage(0, []string{today})
+	wr.Close()
+	if usage[1].Growth[0].Texts != 1 || usage[0].Growth[0].Texts != 3 {
+		t.Errorf("growth after restart = %+v / %+v", usage[0].Growth, usage[1].Growth)
+	}
+}
+
+func TestCompactDaily(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "20260301", "text")
+	table, err := NewTextTable(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		ua := strings.Repeat("Mozilla/5.0 ", 10) + string(rune('a'+i%26)) + string(rune('a'+i/26))
+		table.Set("ua", util.HashString(ua), ua)
+	}
+	table.Set("service", util.HashString("/order"), "/order")
+	table.Set("apicall", util.HashString("http://pay/api"), "http://pay/api")
+	table.Close()
+
+	result, err := CompactDaily(dir, []string{"ua"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Compacted || result.Kept != 2 || result.Dropped != 100 {
+		t.Fatalf("result = %+v", result)
+	}
+	if result.Reclaimed() <= 0 {
+		t.Errorf("reclaimed %d bytes", result.Reclaimed())
+	}
+
+	table, _ = NewTextTable(dir)
+	if s, found, _ := table.Get("service", util.HashString("/order")); !found || s != "/order" {
+		t.Errorf("service text = %q, %v", s, found)
+	}
+	if _, found, _ := table.Get("ua", util.HashString(strings.Repeat("Mozilla/5.0 ", 10)+"aa")); found {
+		t.Error("ua text survived compaction")
+	}
+	table.Close()
+
+	if again, _ := CompactDaily(dir, []string{"ua"}); again.Compacted {
+		t.Error("compacted day rewritten again")
+	}
+	if result, _ := CompactDaily(dir, []string{"ua", "service", "apicall"}); !result.Compacted || result.Kept != 0 {
+		t.Fatalf("result = %+v", result)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("empty daily text directory not removed")
+	}
+}
+
+func TestMergePerm(t *testing.T) {
+	srcDir := t.TempDir()
+	dataDir := t.TempDir()
+
+	src, err := NewTextPermTable(srcDir)
+	if err != nil {
+		t.Fatalf("NewTextPermTable failed: %v", err)
+	}
+	for _, s := range []string{"/shared", "/imported"} {
+		src.Set("service", util.HashString(s), s)
+	}
+	src.Set("sql", util.HashString("SELECT 1"), "SELECT 1")
+	src.Close()
+
+	dst, err := NewTextPermTable(filepath.Join(dataDir, textDirName, "text"))
+	if err != nil {
+		t.Fatalf("NewTextPermTable failed: %v", err)
+	}
+	dst.Set("service", util.HashString("/shared"), "/shared")
+	dst.Set("service", util.HashString("/local"), "/local")
+	dst.Close()
+
+	added, err := MergePerm(srcDir, dataDir)
+	if err != nil {
+		t.Fatalf("MergePerm failed: %v", err)
+	}
+	if added != 2 {
+		t.Errorf("Expected 2 texts added, got %d", added)
+	}
+
+	dst, _ = NewTextPermTable(filepath.Join(dataDir, textDirName, "text"))
+	defer dst.Close()
+	for div, texts := range map[string][]string{"service": {"/shared", "/imported", "/local"}, "sql": {"SELECT 1"}} {
+		for _, s := range texts {
+			if got, found, err := dst.Get(div, util.HashString(s)); err != nil || !found || got != s {
+				t.Errorf("Get(%s, %q) = %q, %v, %v", div, s, got, found, err)
+			}
+		}
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"path/filepath"
 	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/io"
 )
 
 const textDirName = "00000000"
@@ -30,6 +33,7 @@ type TextWR struct {
 	table       *TextPermTable
 	dailyTables map[string]*TextTable // date → TextTable for daily text
 	dupCheck    map[dupKey]struct{}   // in-memory dedup cache
+	growth      *growth               // loaded with the permanent table
 	queue       chan *TextData
 	closed      bool
 	wg          sync.WaitGroup
@@ -48,10 +52,18 @@ func NewTextWR(baseDir string) *TextWR {
 // Start begins the background goroutine that processes the write queue.
 func (w *TextWR) Start(ctx context.Context) {
 	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-ctx.Done():
 				return
+			case <-ticker.C:
+				w.mu.Lock()
+				if w.growth != nil {
+					w.growth.save()
+				}
+				w.mu.Unlock()
 			case data, ok := <-w.queue:
 				if !ok {
 					return
@@ -100,9 +112,13 @@ func (w *TextWR) process(data *TextData) {
 	}
 
 	// Write to table
-	if err := table.Set(data.Div, data.Hash, data.Text); err != nil {
+	written, err := table.SetIfAbsent(data.Div, data.Hash, data.Text)
+	if err != nil {
 		return
 	}
+	if written {
+		w.growth.add(time.Now().Format("20060102"), data.Div, len(data.Text))
+	}
 
 	// Mark as written
 	w.dupCheck[key] = struct{}{}
@@ -121,9 +137,25 @@ func (w *TextWR) getTable() (*TextPermTable, error) {
 	}
 
 	w.table = table
+	if w.growth == nil {
+		w.growth = loadGrowth(filepath.Join(dir, growthFile))
+	}
 	return table, nil
 }
 
+// CompactPermanent compacts the indexes of the permanent text table, see
+// TextPermTable.Compact. Readers opened earlier keep reading the replaced
+// files, as they keep a stale index anyway.
+func (w *TextWR) CompactPermanent(minDropPct int) (io.CompactResult, error) {
+	w.mu.Lock()
+	table, err := w.getTable()
+	w.mu.Unlock()
+	if err != nil {
+		return io.CompactResult{}, err
+	}
+	return table.Compact(minDropPct)
+}
+
 // GetString reads a text from the writer's TextPermTable (which has the up-to-date index).
 // This is needed because TextRD has a stale MemHashBlock that can't see data
 // written after it was opened.
@@ -226,6 +258,40 @@ func (w *TextWR) getDailyTable(date string) (*TextTable, error) {
 	return table, nil
 }
 
+// CloseDay closes the daily text table for a single date, if open.
+func (w *TextWR) CloseDay(date string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if t, ok := w.dailyTables[date]; ok {
+		t.Close()
+		delete(w.dailyTables, date)
+	}
+}
+
+// Usage reports the permanent text storage per div with the topN longest
+// texts of each and the texts added on each of the given dates ("20060102").
+func (w *TextWR) Usage(topN int, dates []string) ([]DivUsage, error) {
+	usage, err := ScanUsage(w.baseDir, topN)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.growth == nil && !w.closed {
+		w.getTable()
+	}
+	for i := range usage {
+		if w.growth != nil {
+			usage[i].Growth = w.growth.forDates(usage[i].Div, dates)
+		} else {
+			usage[i].Growth = make([]DivGrowth, len(dates))
+		}
+	}
+	return usage, nil
+}
+
 // Flush waits for all pending writes to complete.
 func (w *TextWR) Flush() {
 	w.wg.Wait()
@@ -242,6 +308,9 @@ func (w *TextWR) Close() {
 	w.closed = true
 
 	close(w.queue)
+	if w.growth != nil {
+		w.growth.save()
+	}
 	if w.table != nil {
 		w.table.Close()
 		w.table = nil
@@ -252,3 +321,14 @@ func (w *TextWR) Close() {
 	w.dailyTables = make(map[string]*TextTable)
 	w.dupCheck = make(map[dupKey]struct{})
 }
+
+// OpenDays returns the dates with an open container.
+func (w *TextWR) OpenDays() []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	dates := make([]string, 0, len(w.dailyTables))
+	for date := range w.dailyTables {
+		dates = append(dates, date)
+	}
+	return dates
+}
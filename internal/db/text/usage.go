@@ -0,0 +1,247 @@
+package text
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// growthFile records the texts newly written per day and div, next to the
+// permanent text files. The text files themselves carry no write time.
+const growthFile = "growth.json"
+
+// MaxGrowthDays is how many days of growth are kept.
+const MaxGrowthDays = 62
+
+// largestPreviewLen bounds the text returned for the largest entries.
+const largestPreviewLen = 200
+
+// DivGrowth is what was added to one div in one day.
+type DivGrowth struct {
+	Texts int   `json:"texts"`
+	Bytes int64 `json:"bytes"`
+}
+
+// LargeText is one of the largest texts of a div.
+type LargeText struct {
+	Hash    int32
+	Length  int
+	Preview string
+}
+
+// DivUsage describes the permanent text storage of one div.
+type DivUsage struct {
+	Div        string
+	Texts      int
+	TextBytes  int64 // sum of the text lengths
+	DataBytes  int64 // size of text_{div}.data
+	IndexBytes int64 // size of text_{div}.hfile and .kfile
+	Largest    []LargeText
+	// Growth is the texts added per requested date; set by TextWR.Usage.
+	Growth []DivGrowth
+}
+
+// ScanUsage reports the permanent text storage under dataDir per div,
+// largest DataBytes first, with the topN longest texts of each div. The data
+// files are append-only, so they are read directly while the writer runs.
+func ScanUsage(dataDir string, topN int) ([]DivUsage, error) {
+	textDir := filepath.Join(dataDir, textDirName, "text")
+	entries, err := os.ReadDir(textDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result []DivUsage
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, "text_") || !strings.HasSuffix(name, ".data") {
+			continue
+		}
+		div := strings.TrimSuffix(strings.TrimPrefix(name, "text_"), ".data")
+		if div == "" {
+			continue
+		}
+		u, err := scanDiv(filepath.Join(textDir, "text_"+div), topN)
+		if err != nil {
+			return nil, fmt.Errorf("scan %q: %w", div, err)
+		}
+		u.Div = div
+		result = append(result, *u)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].DataBytes > result[j].DataBytes })
+	return result, nil
+}
+
+// scanDiv reads the [4B length][text] records of one div's data file.
+func scanDiv(path string, topN int) (*DivUsage, error) {
+	u := &DivUsage{}
+	for _, ext := range []string{".hfile", ".kfile"} {
+		if fi, err := os.Stat(path + ext); err == nil {
+			u.IndexBytes += fi.Size()
+		}
+	}
+	f, err := os.Open(path + ".data")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err == nil {
+		u.DataBytes = fi.Size()
+	}
+
+	r := bufio.NewReaderSize(f, 64*1024)
+	var lenBuf [4]byte
+	var buf []byte
+	for {
+		if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+			if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+				break // a record still being appended is skipped
+			}
+			return nil, err
+		}
+		n := int(binary.BigEndian.Uint32(lenBuf[:]))
+		if cap(buf) < n {
+			buf = make([]byte, n)
+		}
+		buf = buf[:n]
+		if _, err := io.ReadFull(r, buf); err != nil {
+			if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return nil, err
+		}
+		u.Texts++
+		u.TextBytes += int64(n)
+		if topN > 0 && (len(u.Largest) < topN || n > u.Largest[len(u.Largest)-1].Length) {
+			u.Largest = insertLargest(u.Largest, topN, string(buf))
+		}
+	}
+	return u, nil
+}
+
+// insertLargest adds text to largest, kept sorted by length descending and
+// bounded to topN. Texts are hashed the way agents hash them.
+func insertLargest(largest []LargeText, topN int, text string) []LargeText {
+	lt := LargeText{Hash: util.HashString(text), Length: len(text), Preview: preview(text)}
+	i := sort.Search(len(largest), func(i int) bool { return largest[i].Length < lt.Length })
+	largest = append(largest, LargeText{})
+	copy(largest[i+1:], largest[i:])
+	largest[i] = lt
+	if len(largest) > topN {
+		largest = largest[:topN]
+	}
+	return largest
+}
+
+// preview truncates text to largestPreviewLen bytes on a rune boundary.
+func preview(text string) string {
+	if len(text) <= largestPreviewLen {
+		return text
+	}
+	cut := largestPreviewLen
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "..."
+}
+
+// growth counts the texts newly written per day ("20060102") and div.
+type growth struct {
+	path  string
+	days  map[string]map[string]*DivGrowth
+	dirty bool
+}
+
+func loadGrowth(path string) *growth {
+	g := &growth{path: path, days: make(map[string]map[string]*DivGrowth)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("Text growth load error", "error", err)
+		}
+		return g
+	}
+	if err := json.Unmarshal(data, &g.days); err != nil {
+		slog.Warn("Text growth unmarshal error", "error", err)
+		g.days = make(map[string]map[string]*DivGrowth)
+	}
+	return g
+}
+
+func (g *growth) add(date, div string, bytes int) {
+	divs := g.days[date]
+	if divs == nil {
+		divs = make(map[string]*DivGrowth)
+		g.days[date] = divs
+		g.prune()
+	}
+	dg := divs[div]
+	if dg == nil {
+		dg = &DivGrowth{}
+		divs[div] = dg
+	}
+	dg.Texts++
+	dg.Bytes += int64(bytes)
+	g.dirty = true
+}
+
+// prune drops all but the newest MaxGrowthDays days.
+func (g *growth) prune() {
+	if len(g.days) <= MaxGrowthDays {
+		return
+	}
+	dates := make([]string, 0, len(g.days))
+	for d := range g.days {
+		dates = append(dates, d)
+	}
+	sort.Strings(dates)
+	for _, d := range dates[:len(dates)-MaxGrowthDays] {
+		delete(g.days, d)
+	}
+}
+
+func (g *growth) save() {
+	if !g.dirty {
+		return
+	}
+	data, err := json.Marshal(g.days)
+	if err != nil {
+		slog.Warn("Text growth marshal error", "error", err)
+		return
+	}
+	tmp := g.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		slog.Warn("Text growth save error", "error", err)
+		return
+	}
+	if err := os.Rename(tmp, g.path); err != nil {
+		slog.Warn("Text growth save error", "error", err)
+		return
+	}
+	g.dirty = false
+}
+
+// forDates returns the growth of div on each of dates.
+func (g *growth) forDates(div string, dates []string) []DivGrowth {
+	result := make([]DivGrowth, len(dates))
+	for i, d := range dates {
+		if dg := g.days[d][div]; dg != nil {
+			result[i] = *dg
+		}
+	}
+	return result
+}
@@ -0,0 +1,308 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+)
+
+// TrashDirName is the directory under the data directory holding the data
+// removed by manual purges until their undo window ends.
+const TrashDirName = ".trash"
+
+const trashEntryFile = "entry.json"
+
+// ErrNoTrashEntry is returned for an unknown trash entry ID.
+var ErrNoTrashEntry = errors.New("no such trash entry")
+
+// TrashEntry is the data one purge moved to the trash.
+type TrashEntry struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"` // permanent removal
+	Dates   []string  `json:"dates"`
+	Types   []string  `json:"types"`
+	Paths   []string  `json:"paths"` // relative to the data directory
+	Bytes   int64     `json:"bytes"`
+}
+
+// Trash keeps removed day data restorable for mgr_purge_trash_hours. The
+// moved paths keep their layout under {baseDir}/.trash/{id}/data, so a
+// restore is a rename back.
+type Trash struct {
+	mu      sync.Mutex
+	baseDir string
+	now     func() time.Time
+}
+
+// NewTrash creates the trash of baseDir.
+func NewTrash(baseDir string) *Trash {
+	return &Trash{baseDir: baseDir, now: time.Now}
+}
+
+// Window returns mgr_purge_trash_hours as a duration; 0 disables the trash.
+func (t *Trash) Window() time.Duration {
+	if cfg := config.Get(); cfg != nil {
+		return time.Duration(cfg.MgrPurgeTrashHours()) * time.Hour
+	}
+	return 0
+}
+
+func (t *Trash) dir() string {
+	return filepath.Join(t.baseDir, TrashDirName)
+}
+
+// Move moves the given paths, relative to the data directory, to a new
+// entry. Paths that do not exist or lie inside another given path are left
+// out; with none left no entry is created and the returned ID is empty.
+func (t *Trash) Move(paths, dates, types []string) (TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	e := TrashEntry{
+		ID:      strconv.FormatInt(now.UnixMilli(), 10),
+		Created: now,
+		Expires: now.Add(t.Window()),
+		Dates:   dates,
+		Types:   types,
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(t.dir(), e.ID)); os.IsNotExist(err) {
+			break
+		}
+		id, _ := strconv.ParseInt(e.ID, 10, 64)
+		e.ID = strconv.FormatInt(id+1, 10)
+	}
+	entryDir := filepath.Join(t.dir(), e.ID)
+	for _, rel := range paths {
+		if coveredBy(rel, paths) {
+			continue
+		}
+		src := filepath.Join(t.baseDir, rel)
+		info, err := os.Stat(src)
+		if err != nil {
+			continue
+		}
+		dst := filepath.Join(entryDir, "data", rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			slog.Error("Trash: move error", "path", src, "error", err)
+			continue
+		}
+		if err := os.Rename(src, dst); err != nil {
+			slog.Error("Trash: move error", "path", src, "error", err)
+			continue
+		}
+		e.Paths = append(e.Paths, rel)
+		if info.IsDir() {
+			e.Bytes += treeSize(dst)
+		} else {
+			e.Bytes += info.Size()
+		}
+	}
+	if len(e.Paths) == 0 {
+		os.RemoveAll(entryDir)
+		return TrashEntry{}, nil
+	}
+	if err := writeTrashEntry(entryDir, &e); err != nil {
+		return e, err
+	}
+	slog.Info("Trash: moved", "id", e.ID, "dates", e.Dates, "types", e.Types,
+		"bytes", e.Bytes, "expires", e.Expires.Format(time.RFC3339))
+	return e, nil
+}
+
+// List returns the entries, oldest first.
+func (t *Trash) List() ([]TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.list()
+}
+
+func (t *Trash) list() ([]TrashEntry, error) {
+	dirs, err := os.ReadDir(t.dir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []TrashEntry
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		e, err := readTrashEntry(filepath.Join(t.dir(), d.Name()))
+		if err != nil {
+			slog.Warn("Trash: unreadable entry", "id", d.Name(), "error", err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Created.Before(entries[j].Created) })
+	return entries, nil
+}
+
+// Restore moves the data of entry id back. It fails without moving anything
+// if one of the paths exists again.
+func (t *Trash) Restore(id string) (TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entryDir, err := t.entryDir(id)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	e, err := readTrashEntry(entryDir)
+	if err != nil {
+		return e, err
+	}
+	for _, rel := range e.Paths {
+		if _, err := os.Stat(filepath.Join(t.baseDir, rel)); err == nil {
+			return e, fmt.Errorf("%s exists again; remove it first", rel)
+		}
+	}
+	for _, rel := range e.Paths {
+		dst := filepath.Join(t.baseDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return e, err
+		}
+		if err := os.Rename(filepath.Join(entryDir, "data", rel), dst); err != nil {
+			return e, fmt.Errorf("restore %s: %w", rel, err)
+		}
+	}
+	if err := os.RemoveAll(entryDir); err != nil {
+		return e, err
+	}
+	slog.Info("Trash: restored", "id", e.ID, "dates", e.Dates, "types", e.Types)
+	return e, nil
+}
+
+// Remove deletes entry id permanently before its window ends.
+func (t *Trash) Remove(id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entryDir, err := t.entryDir(id)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(entryDir); err != nil {
+		return err
+	}
+	slog.Info("Trash: removed", "id", id)
+	return nil
+}
+
+// Expire deletes the entries whose window ended and returns how many.
+func (t *Trash) Expire() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entries, err := t.list()
+	if err != nil {
+		slog.Warn("Trash: list failed", "error", err)
+		return 0
+	}
+	now := t.now()
+	removed := 0
+	for _, e := range entries {
+		if now.Before(e.Expires) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(t.dir(), e.ID)); err != nil {
+			slog.Error("Trash: remove error", "id", e.ID, "error", err)
+			continue
+		}
+		removed++
+		slog.Info("Trash: expired", "id", e.ID, "dates", e.Dates, "types", e.Types, "bytes", e.Bytes)
+	}
+	return removed
+}
+
+// Start expires entries every minute until ctx is cancelled.
+func (t *Trash) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		t.Expire()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				t.Expire()
+			}
+		}
+	}()
+}
+
+// entryDir returns the directory of entry id, rejecting IDs that are not
+// plain entry names.
+func (t *Trash) entryDir(id string) (string, error) {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return "", fmt.Errorf("%w: %q", ErrNoTrashEntry, id)
+	}
+	dir := filepath.Join(t.dir(), id)
+	if _, err := os.Stat(dir); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrNoTrashEntry, id)
+	}
+	return dir, nil
+}
+
+// coveredBy reports whether rel lies inside one of paths.
+func coveredBy(rel string, paths []string) bool {
+	for _, p := range paths {
+		if strings.HasPrefix(rel, p+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeTrashEntry(dir string, e *TrashEntry) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, trashEntryFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, trashEntryFile))
+}
+
+func readTrashEntry(dir string) (TrashEntry, error) {
+	var e TrashEntry
+	data, err := os.ReadFile(filepath.Join(dir, trashEntryFile))
+	if err != nil {
+		return e, err
+	}
+	err = json.Unmarshal(data, &e)
+	return e, err
+}
+
+// treeSize returns the bytes of the files under dir.
+func treeSize(dir string) int64 {
+	var total int64
+	filepath.WalkDir(dir, func(_ string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
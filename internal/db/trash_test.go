@@ -0,0 +1,129 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+)
+
+func loadTrashConfig(t *testing.T, hours string) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("mgr_purge_trash_hours="+hours+"\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+}
+
+func TestManualPurger_TrashAndRestore(t *testing.T) {
+	loadTrashConfig(t, "2")
+	dir := t.TempDir()
+	xlogDir := filepath.Join(dir, "20260101", "xlog")
+	os.MkdirAll(xlogDir, 0755)
+	os.MkdirAll(filepath.Join(dir, "20260102", "alert"), 0755)
+	os.WriteFile(filepath.Join(xlogDir, "xlog.data"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(xlogDir, "xlog_prof.data"), []byte("pp"), 0644)
+
+	closer := &recordingCloser{}
+	p := NewManualPurger(dir, closer)
+	trash := NewTrash(dir)
+	p.SetTrash(trash)
+	results, err := p.Purge([]string{"20260101", "20260102"}, []string{PurgeTypeProfile, PurgeTypeAll})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 || results[0].TrashID == "" || !results[0].Deleted || !results[3].Deleted {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	for _, date := range []string{"20260101", "20260102"} {
+		if _, err := os.Stat(filepath.Join(dir, date)); !os.IsNotExist(err) {
+			t.Errorf("%s should be gone from the data directory", date)
+		}
+	}
+	// The trash is not a date directory.
+	if dates, _ := GetDateDirs(dir); len(dates) != 0 {
+		t.Errorf("date dirs = %v", dates)
+	}
+
+	entries, err := trash.List()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("entries = %+v, %v", entries, err)
+	}
+	e := entries[0]
+	if e.Bytes != 3 || len(e.Dates) != 2 || e.Expires.Sub(e.Created) != 2*time.Hour {
+		t.Errorf("entry = %+v", e)
+	}
+
+	closer.closed = nil
+	if _, err := p.Restore(e.ID); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(xlogDir, "xlog_prof.data")); err != nil || string(data) != "pp" {
+		t.Errorf("profile not restored: %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(xlogDir, "xlog.data")); err != nil {
+		t.Error("xlog data not restored")
+	}
+	if len(closer.closed) != 2 {
+		t.Errorf("restored dates not closed: %v", closer.closed)
+	}
+	if _, err := p.Restore(e.ID); !errors.Is(err, ErrNoTrashEntry) {
+		t.Errorf("second restore: %v", err)
+	}
+}
+
+func TestTrash_RestoreConflictAndExpire(t *testing.T) {
+	loadTrashConfig(t, "1")
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "20260101", "text"), 0755)
+
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.Local)
+	trash := NewTrash(dir)
+	trash.now = func() time.Time { return now }
+	e, err := trash.Move([]string{filepath.Join("20260101", "text"), filepath.Join("20260101", "alert")}, []string{"20260101"}, []string{PurgeTypeText, PurgeTypeAlert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Paths) != 1 {
+		t.Fatalf("missing paths should be left out: %+v", e)
+	}
+
+	// Data written again in the meantime is not overwritten.
+	os.MkdirAll(filepath.Join(dir, "20260101", "text"), 0755)
+	if _, err := trash.Restore(e.ID); err == nil {
+		t.Error("restore over existing data should fail")
+	}
+
+	if n := trash.Expire(); n != 0 {
+		t.Errorf("expired %d entries inside the window", n)
+	}
+	now = now.Add(time.Hour)
+	if n := trash.Expire(); n != 1 {
+		t.Errorf("expired %d entries, want 1", n)
+	}
+	if entries, _ := trash.List(); len(entries) != 0 {
+		t.Errorf("entries after expiry = %+v", entries)
+	}
+}
+
+func TestManualPurger_NoTrashWindowDeletes(t *testing.T) {
+	loadTrashConfig(t, "0")
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "20260101", "alert"), 0755)
+
+	p := NewManualPurger(dir)
+	p.SetTrash(NewTrash(dir))
+	results, err := p.Purge([]string{"20260101"}, []string{PurgeTypeAlert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !results[0].Deleted || results[0].TrashID != "" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	if _, err := os.Stat(filepath.Join(dir, TrashDirName)); !os.IsNotExist(err) {
+		t.Error("trash should not be used with mgr_purge_trash_hours=0")
+	}
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/io"
+)
+
+// DayWriteStat describes the write path of one kind of day container
+// (xlog, profile, counter, alert, summary) for one date.
+type DayWriteStat struct {
+	io.WriteStat
+	IndexFiles int       // open index files
+	IndexBytes int64     // their size on disk
+	LastFlush  time.Time // of the most recently flushed index file
+}
+
+// DayWriteStats returns the write statistics of the day containers under
+// baseDir for date, or for every date if date is "", newest date first and
+// by kind within a date. Entries and bytes count what was written since the
+// server started; index files are those currently open.
+func DayWriteStats(baseDir, date string) []DayWriteStat {
+	return dayWriteStats(baseDir, date, io.GetWriteStats().Days(date), io.GetFlushController().Stats())
+}
+
+func dayWriteStats(baseDir, date string, writes []io.WriteStat, flushes []io.FlushStat) []DayWriteStat {
+	type key struct{ date, kind string }
+	byKey := make(map[key]*DayWriteStat)
+	get := func(date, kind string) *DayWriteStat {
+		st := byKey[key{date, kind}]
+		if st == nil {
+			st = &DayWriteStat{WriteStat: io.WriteStat{Date: date, Kind: kind}}
+			byKey[key{date, kind}] = st
+		}
+		return st
+	}
+
+	for _, ws := range writes {
+		get(ws.Date, ws.Kind).WriteStat = ws
+	}
+	for _, fs := range flushes {
+		rel, err := filepath.Rel(baseDir, fs.File)
+		if err != nil {
+			continue
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) < 3 || checkDate(parts[0]) != nil || (date != "" && parts[0] != date) {
+			continue // not a day container file, e.g. the global text index
+		}
+		st := get(parts[0], parts[1])
+		st.IndexFiles++
+		if fi, err := os.Stat(fs.File); err == nil {
+			st.IndexBytes += fi.Size()
+		}
+		if fs.LastFlush.After(st.LastFlush) {
+			st.LastFlush = fs.LastFlush
+		}
+	}
+
+	result := make([]DayWriteStat, 0, len(byKey))
+	for _, st := range byKey {
+		result = append(result, *st)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Date != result[j].Date {
+			return result[i].Date > result[j].Date
+		}
+		return result[i].Kind < result[j].Kind
+	})
+	return result
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/io"
+)
+
+func TestDayWriteStats(t *testing.T) {
+	base := t.TempDir()
+	tidFile := filepath.Join(base, "20260101", "xlog", "xlog_tid.hfile")
+	os.MkdirAll(filepath.Dir(tidFile), 0755)
+	os.WriteFile(tidFile, make([]byte, 4096), 0644)
+
+	flushed := time.Date(2026, 1, 1, 10, 0, 0, 0, time.Local)
+	writes := []io.WriteStat{
+		{Date: "20260102", Kind: "profile", Entries: 3, Bytes: 300, Objects: 1},
+		{Date: "20260101", Kind: "xlog", Entries: 10, Bytes: 1000, Objects: 2},
+	}
+	flushes := []io.FlushStat{
+		{File: tidFile, LastFlush: flushed},
+		{File: filepath.Join(base, "20260101", "xlog", "xlog_tim.tfile"), LastFlush: flushed.Add(-time.Minute)},
+		{File: filepath.Join(base, "20260101", "counter", "real.hfile")},
+		{File: filepath.Join(base, "text", "text.hfile")},
+	}
+
+	stats := dayWriteStats(base, "", writes, flushes)
+	if len(stats) != 3 {
+		t.Fatalf("stats = %+v, want 3", stats)
+	}
+	if stats[0].Date != "20260102" || stats[0].Kind != "profile" || stats[0].Entries != 3 {
+		t.Errorf("stats[0] = %+v", stats[0])
+	}
+	counter, xlog := stats[1], stats[2]
+	if counter.Kind != "counter" || counter.IndexFiles != 1 || counter.Entries != 0 {
+		t.Errorf("counter = %+v", counter)
+	}
+	if xlog.Kind != "xlog" || xlog.Entries != 10 || xlog.IndexFiles != 2 || xlog.IndexBytes != 4096 || !xlog.LastFlush.Equal(flushed) {
+		t.Errorf("xlog = %+v", xlog)
+	}
+
+	if got := dayWriteStats(base, "20260102", writes[:1], flushes); len(got) != 1 || got[0].Kind != "profile" {
+		t.Errorf("date filter = %+v", got)
+	}
+}
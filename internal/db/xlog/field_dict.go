@@ -0,0 +1,245 @@
+package xlog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+)
+
+// fieldDictMarker starts a dictionary-encoded record. Stored records
+// otherwise start with the pack type or the 0x00 compression flag.
+const fieldDictMarker byte = 0xFF
+
+// packTypeXLog is pack.PackTypeXLog; other pack types are stored as is.
+const packTypeXLog byte = 21
+
+// maxFieldDictEntries bounds the (objHash, service) pairs of one day; records
+// with new pairs beyond it are stored unencoded.
+const maxFieldDictEntries = 65536
+
+const fieldDictHeaderSize = 8 // int64 base time
+const fieldDictEntrySize = 8  // int32 objHash + int32 service
+
+type fieldPair struct {
+	objHash int32
+	service int32
+}
+
+// fieldDict shrinks the repetitive leading fields of stored XLogPacks. The
+// (objHash, service) pair is replaced by its index in a per-day dictionary
+// kept in xlog.dict, and EndTime by its delta to the base time in the file
+// header, which saves about 15 bytes per record for homogeneous services.
+// The dictionary only grows, so a record stays readable on its own.
+type fieldDict struct {
+	mu      sync.RWMutex
+	path    string
+	loaded  bool
+	hasBase bool
+	base    int64
+	entries []fieldPair
+	index   map[fieldPair]int
+	size    int64    // valid bytes in the file
+	file    *os.File // append handle, opened by the first new entry
+}
+
+func newFieldDict(path string) *fieldDict {
+	return &fieldDict{path: path, index: make(map[fieldPair]int)}
+}
+
+// load reads the dictionary file. Caller holds d.mu for writing.
+func (d *fieldDict) load() error {
+	d.loaded = true
+	buf, err := os.ReadFile(d.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(buf) < fieldDictHeaderSize {
+		return nil
+	}
+	d.hasBase = true
+	d.base = int64(binary.BigEndian.Uint64(buf))
+	d.entries = d.entries[:0]
+	d.index = make(map[fieldPair]int)
+	// A torn trailing entry is ignored and overwritten by the next append.
+	n := (len(buf) - fieldDictHeaderSize) / fieldDictEntrySize
+	for i := 0; i < n; i++ {
+		off := fieldDictHeaderSize + i*fieldDictEntrySize
+		p := fieldPair{
+			objHash: int32(binary.BigEndian.Uint32(buf[off:])),
+			service: int32(binary.BigEndian.Uint32(buf[off+4:])),
+		}
+		d.index[p] = len(d.entries)
+		d.entries = append(d.entries, p)
+	}
+	d.size = int64(fieldDictHeaderSize + n*fieldDictEntrySize)
+	return nil
+}
+
+// add appends p to the file and the dictionary. Caller holds d.mu for
+// writing. The entry is written and synced before any record using it, as
+// the data file is flushed on its own schedule and a record whose entry
+// was lost in a crash could not be decoded. New pairs are rare after the
+// first records of a day, so the sync is cheap.
+func (d *fieldDict) add(p fieldPair, endTime int64) (int, error) {
+	if d.file == nil {
+		f, err := os.OpenFile(d.path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return 0, err
+		}
+		if err := f.Truncate(d.size); err != nil {
+			f.Close()
+			return 0, err
+		}
+		d.file = f
+	}
+	var buf []byte
+	if !d.hasBase {
+		buf = binary.BigEndian.AppendUint64(buf, uint64(endTime))
+	}
+	buf = binary.BigEndian.AppendUint32(buf, uint32(p.objHash))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(p.service))
+	if _, err := d.file.WriteAt(buf, d.size); err != nil {
+		return 0, err
+	}
+	if err := d.file.Sync(); err != nil {
+		return 0, err
+	}
+	d.size += int64(len(buf))
+	if !d.hasBase {
+		d.hasBase, d.base = true, endTime
+	}
+	d.index[p] = len(d.entries)
+	d.entries = append(d.entries, p)
+	return len(d.entries) - 1, nil
+}
+
+// encode returns the dictionary-encoded form of a serialized XLogPack:
+// [marker][pack type][uvarint pair index][varint EndTime delta][rest of the
+// pack body]. It returns false for data it cannot encode.
+func (d *fieldDict) encode(data []byte) ([]byte, bool) {
+	if len(data) == 0 || data[0] != packTypeXLog {
+		return nil, false
+	}
+	din := protocol.NewDataInputX(data[1:])
+	inner, err := din.ReadBlob()
+	if err != nil || din.Available() != 0 {
+		return nil, false
+	}
+	in := protocol.NewDataInputX(inner)
+	endTime, err := in.ReadDecimal()
+	if err != nil {
+		return nil, false
+	}
+	objHash, err := in.ReadDecimal()
+	if err != nil {
+		return nil, false
+	}
+	service, err := in.ReadDecimal()
+	if err != nil {
+		return nil, false
+	}
+	rest := inner[in.Offset():]
+	p := fieldPair{objHash: int32(objHash), service: int32(service)}
+
+	d.mu.Lock()
+	if !d.loaded {
+		if err := d.load(); err != nil {
+			d.mu.Unlock()
+			return nil, false
+		}
+	}
+	idx, ok := d.index[p]
+	if !ok {
+		if len(d.entries) >= maxFieldDictEntries {
+			d.mu.Unlock()
+			return nil, false
+		}
+		if idx, err = d.add(p, endTime); err != nil {
+			d.mu.Unlock()
+			return nil, false
+		}
+	}
+	base := d.base
+	d.mu.Unlock()
+
+	out := make([]byte, 0, 2+2*binary.MaxVarintLen64+len(rest))
+	out = append(out, fieldDictMarker, data[0])
+	out = binary.AppendUvarint(out, uint64(idx))
+	out = binary.AppendVarint(out, endTime-base)
+	return append(out, rest...), true
+}
+
+// decode rebuilds the serialized XLogPack from an encoded record.
+func (d *fieldDict) decode(b []byte) ([]byte, error) {
+	if len(b) < 2 || b[0] != fieldDictMarker {
+		return nil, errors.New("xlog: not a dictionary-encoded record")
+	}
+	packType := b[1]
+	b = b[2:]
+	idx, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, errors.New("xlog: bad dictionary index")
+	}
+	b = b[n:]
+	delta, n := binary.Varint(b)
+	if n <= 0 {
+		return nil, errors.New("xlog: bad time delta")
+	}
+	rest := b[n:]
+
+	p, base, ok := d.lookup(int(idx))
+	if !ok {
+		return nil, fmt.Errorf("xlog: dictionary entry %d not found in %s", idx, d.path)
+	}
+
+	inner := protocol.NewDataOutputX()
+	inner.WriteDecimal(base + delta)
+	inner.WriteDecimal(int64(p.objHash))
+	inner.WriteDecimal(int64(p.service))
+	inner.Write(rest)
+	out := protocol.NewDataOutputX()
+	out.WriteByte(packType)
+	out.WriteBlob(inner.ToByteArray())
+	return out.ToByteArray(), nil
+}
+
+// lookup returns entry idx and the base time, reloading the file once when
+// another instance (the writer) has appended entries since it was read.
+func (d *fieldDict) lookup(idx int) (fieldPair, int64, bool) {
+	d.mu.RLock()
+	if d.loaded && idx < len(d.entries) {
+		p, base := d.entries[idx], d.base
+		d.mu.RUnlock()
+		return p, base, true
+	}
+	d.mu.RUnlock()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.loaded || idx >= len(d.entries) {
+		if err := d.load(); err != nil {
+			return fieldPair{}, 0, false
+		}
+	}
+	if idx >= len(d.entries) {
+		return fieldPair{}, 0, false
+	}
+	return d.entries[idx], d.base, true
+}
+
+// close closes the append handle.
+func (d *fieldDict) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file != nil {
+		d.file.Close()
+		d.file = nil
+	}
+}
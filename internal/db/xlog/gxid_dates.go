@@ -0,0 +1,47 @@
+package xlog
+
+import (
+	"sort"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+)
+
+// maxGxidAdjacentDays bounds xlog_gxid_adjacent_days.
+const maxGxidAdjacentDays = 7
+
+// GxidDates returns dates together with the days adjacent to them within
+// xlog_gxid_adjacent_days, deduplicated and in ascending order, so the legs of
+// a distributed transaction that crossed midnight are found.
+func GxidDates(dates ...string) []string {
+	adjacent := 1
+	if cfg := config.Get(); cfg != nil {
+		adjacent = cfg.XLogGxidAdjacentDays()
+	}
+	adjacent = max(0, min(adjacent, maxGxidAdjacentDays))
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, date := range dates {
+		if date == "" {
+			continue
+		}
+		day, err := time.ParseInLocation("20060102", date, time.Local)
+		if err != nil {
+			if !seen[date] {
+				seen[date] = true
+				result = append(result, date)
+			}
+			continue
+		}
+		for i := -adjacent; i <= adjacent; i++ {
+			d := day.AddDate(0, 0, i).Format("20060102")
+			if !seen[d] {
+				seen[d] = true
+				result = append(result, d)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,194 @@
+package xlog
+
+import (
+	"cmp"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/io"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+)
+
+// Keys of the secondary index: [kind][hash 4B][minute 4B], with the
+// epoch minute of the XLog end time. Every value is [time 8B][offset 5B].
+const (
+	secKindObj     byte = 'o'
+	secKindService byte = 's'
+
+	secBucketMs = 60_000
+
+	// secMaxRangeMs bounds the ranges read through the secondary index,
+	// which probes every minute of them; a day lasts at most 25 hours.
+	secMaxRangeMs = 25 * 60 * 60 * 1000
+)
+
+// secSinceKey holds the end time from which the day's XLogs are indexed.
+var secSinceKey = []byte{0}
+
+// Selection restricts a time range read to the XLogs of some objects or of
+// some services. With both set the objects are used, and callers still filter
+// on the services.
+type Selection struct {
+	ObjHashes []int32
+	Services  []int32
+}
+
+// Empty reports whether s selects every XLog.
+func (s Selection) Empty() bool {
+	return len(s.ObjHashes) == 0 && len(s.Services) == 0
+}
+
+func (s Selection) keys() (byte, []int32) {
+	if len(s.ObjHashes) > 0 {
+		return secKindObj, s.ObjHashes
+	}
+	return secKindService, s.Services
+}
+
+func secKey(kind byte, hash int32, minute int64) []byte {
+	key := make([]byte, 9)
+	key[0] = kind
+	binary.BigEndian.PutUint32(key[1:], uint32(hash))
+	binary.BigEndian.PutUint32(key[5:], uint32(minute))
+	return key
+}
+
+// secondary returns the secondary index, opening it if its files exist or
+// create is set. It returns nil if the day has no secondary index.
+func (x *XLogIndex) secondary(create bool) (*io.IndexKeyFile, error) {
+	x.secMu.Lock()
+	defer x.secMu.Unlock()
+	if x.secIndex != nil {
+		return x.secIndex, nil
+	}
+	path := filepath.Join(x.dir, "xlog_sec")
+	_, statErr := os.Stat(path + ".kfile")
+	if statErr != nil && !create {
+		return nil, nil
+	}
+	idx, err := io.NewIndexKeyFile(path, 2)
+	if err != nil {
+		return nil, err
+	}
+	if statErr != nil {
+		// XLogs written earlier with a later end time are not indexed;
+		// reads from before now use the time index.
+		since := max(x.lastTime, time.Now().UnixMilli())
+		if err := idx.Put(secSinceKey, protocol.BigEndian.Bytes8(since)); err != nil {
+			idx.Close()
+			return nil, err
+		}
+	}
+	x.secIndex = idx
+	return idx, nil
+}
+
+// SetBySecondary stores the objHash and service → data offset mappings of
+// an XLog ending at timeMs. The index is created if create is set; a day
+// that has one keeps it up to date either way.
+func (x *XLogIndex) SetBySecondary(timeMs int64, objHash, service int32, dataPos int64, create bool) error {
+	idx, err := x.secondary(create)
+	if err != nil || idx == nil {
+		x.secMu.Lock()
+		x.lastTime = max(x.lastTime, timeMs)
+		x.secMu.Unlock()
+		return err
+	}
+	value := make([]byte, 13)
+	copy(value, protocol.BigEndian.Bytes8(timeMs))
+	copy(value[8:], protocol.BigEndian.Bytes5(dataPos))
+	minute := timeMs / secBucketMs
+	if err := idx.Put(secKey(secKindObj, objHash, minute), value); err != nil {
+		return err
+	}
+	return idx.Put(secKey(secKindService, service, minute), value)
+}
+
+// secEntry is one value of the secondary index.
+type secEntry struct {
+	time int64
+	pos  int64
+}
+
+// ReadBySecondary calls fn with the data offsets of the XLogs of sel ending
+// within [stime, etime], by end time, latest first if reverse is set. It
+// returns false without calling fn if the day has no secondary index or the
+// index starts after stime.
+func (x *XLogIndex) ReadBySecondary(sel Selection, stime, etime int64, reverse bool, fn func(dataPos int64) bool) (bool, error) {
+	idx, err := x.secondary(false)
+	if err != nil || idx == nil {
+		return false, err
+	}
+	v, err := idx.Get(secSinceKey)
+	if err != nil || len(v) != 8 || protocol.BigEndian.Int64(v) > stime {
+		return false, err
+	}
+
+	kind, hashes := sel.keys()
+	first, last, step := stime/secBucketMs, etime/secBucketMs, int64(1)
+	if reverse {
+		first, last, step = last, first, -1
+	}
+	var entries []secEntry
+	for minute := first; ; minute += step {
+		entries = entries[:0]
+		for _, hash := range hashes {
+			values, err := idx.GetAll(secKey(kind, hash, minute))
+			if err != nil {
+				return true, err
+			}
+			for _, v := range values {
+				if len(v) != 13 {
+					continue
+				}
+				e := secEntry{time: protocol.BigEndian.Int64(v), pos: protocol.BigEndian.Int5(v[8:])}
+				if e.time >= stime && e.time <= etime {
+					entries = append(entries, e)
+				}
+			}
+		}
+		slices.SortFunc(entries, func(a, b secEntry) int {
+			return cmp.Or(cmp.Compare(a.time, b.time), cmp.Compare(a.pos, b.pos))
+		})
+		if reverse {
+			slices.Reverse(entries)
+		}
+		for _, e := range entries {
+			if !fn(e.pos) {
+				return true, nil
+			}
+		}
+		if minute == last {
+			return true, nil
+		}
+	}
+}
+
+// readSelected reads the XLogs of sel ending within [stime, etime] through
+// the secondary index, or all of them through the time index if the day's
+// secondary index does not cover the range, in which case the handler must
+// filter. Handler returns false to stop iteration early.
+func (c *dayContainer) readSelected(sel Selection, stime, etime int64, reverse bool, handler func(data []byte) bool) error {
+	read := func(offset int64) bool {
+		data, err := c.data.Read(offset)
+		if err == nil && data != nil {
+			return handler(data)
+		}
+		return true
+	}
+	if !sel.Empty() && stime <= etime && etime-stime <= secMaxRangeMs {
+		if covered, err := c.index.ReadBySecondary(sel, stime, etime, reverse, read); covered || err != nil {
+			return err
+		}
+	}
+	byTime := func(timeMs int64, dataPos []byte) bool {
+		return read(protocol.BigEndian.Int5(dataPos))
+	}
+	if reverse {
+		return c.index.timeIndex.ReadFromEnd(stime, etime, byTime)
+	}
+	return c.index.timeIndex.Read(stime, etime, byTime)
+}
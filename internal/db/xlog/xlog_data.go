@@ -2,7 +2,6 @@ package xlog
 
 import (
 	"encoding/binary"
-	"os"
 	"path/filepath"
 	"sync"
 
@@ -15,9 +14,7 @@ import (
 type XLogData struct {
 	dataFile *io.RealDataFile
 	path     string
-	initOnce sync.Once // ensures raf is opened exactly once
-	initErr  error     // error from lazy init, if any
-	raf      *os.File  // read-only handle for ReadAt (pread), lazily initialized
+	dict     *fieldDict
 }
 
 // NewXLogData opens the XLog data file.
@@ -31,16 +28,24 @@ func NewXLogData(dir string) (*XLogData, error) {
 	return &XLogData{
 		dataFile: dataFile,
 		path:     path,
+		dict:     newFieldDict(filepath.Join(dir, "xlog.dict")),
 	}, nil
 }
 
 // Write writes an XLog entry as [short:length][bytes:body] and returns the start offset.
-// If compression is enabled, body = [0x00][0x01][zstd payload]; otherwise body = raw data.
+// With xlog_field_dict_enabled the data is dictionary-encoded first (see fieldDict).
+// The body is then encoded by compress.Pool.Encode, zstd-compressed with
+// compress_xlog_enabled where that makes it smaller.
 func (x *XLogData) Write(data []byte) (int64, error) {
-	body := data
-	if cfg := config.Get(); cfg != nil && cfg.CompressXLogEnabled() {
-		body = compress.SharedPool().Compress(data)
+	raw := data
+	cfg := config.Get()
+	if cfg != nil && cfg.XLogFieldDictEnabled() {
+		if enc, ok := x.dict.encode(data); ok {
+			raw = enc
+		}
 	}
+	body, compressed := compress.SharedPool().Encode(raw, cfg != nil && cfg.CompressXLogEnabled())
+	compress.GetStats().Add("xlog", len(data), len(body), compressed)
 	buf := make([]byte, 2+len(body))
 	binary.BigEndian.PutUint16(buf[:2], uint16(len(body)))
 	copy(buf[2:], body)
@@ -61,21 +66,9 @@ var bodyPool = sync.Pool{
 // Uses ReadAt (pread) for lock-free concurrent reads — multiple goroutines
 // can read simultaneously without mutex serialization.
 func (x *XLogData) Read(offset int64) ([]byte, error) {
-	x.initOnce.Do(func() {
-		f, err := os.Open(x.path)
-		if err != nil {
-			x.initErr = err
-			return
-		}
-		x.raf = f
-	})
-	if x.initErr != nil {
-		return nil, x.initErr
-	}
-
 	// Read length header (2 bytes) via pread — no seek, no lock needed
 	var lenBuf [2]byte
-	if _, err := x.raf.ReadAt(lenBuf[:], offset); err != nil {
+	if _, err := x.dataFile.ReadAt(lenBuf[:], offset); err != nil {
 		return nil, err
 	}
 	length := int(binary.BigEndian.Uint16(lenBuf[:]))
@@ -89,20 +82,24 @@ func (x *XLogData) Read(offset int64) ([]byte, error) {
 	}
 
 	// Read body via pread
-	if _, err := x.raf.ReadAt(body, offset+2); err != nil {
+	if _, err := x.dataFile.ReadAt(body, offset+2); err != nil {
 		bodyPool.Put(body[:0])
 		return nil, err
 	}
 
 	decoded, err := compress.SharedPool().Decode(body)
+	if err == nil && len(decoded) > 0 && decoded[0] == fieldDictMarker {
+		decoded, err = x.dict.decode(decoded)
+	}
 	if err != nil {
 		bodyPool.Put(body[:0])
 		return nil, err
 	}
 
-	// Recycle the read buffer only if Decode produced a new buffer (compressed case).
-	// When uncompressed, decoded IS body — must not return it to the pool.
-	if len(decoded) > 0 && len(body) > 0 && &decoded[0] != &body[0] {
+	// Recycle the read buffer only if decoding produced a new buffer.
+	// When stored raw, decoded is body or its tail, ending at the same
+	// element — must not return it to the pool.
+	if len(decoded) > 0 && len(body) > 0 && &decoded[len(decoded)-1] != &body[len(body)-1] {
 		bodyPool.Put(body[:0])
 	}
 
@@ -114,13 +111,9 @@ func (x *XLogData) Flush() error {
 	return x.dataFile.Flush()
 }
 
-// Close closes the data file and the read handle.
+// Close closes the data file and the dictionary.
 func (x *XLogData) Close() {
-	if x.raf != nil {
-		x.raf.Close()
-		x.raf = nil
-	}
-
+	x.dict.close()
 	if x.dataFile != nil {
 		x.dataFile.Close()
 	}
@@ -1,17 +1,30 @@
 package xlog
 
 import (
+	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/zbum/scouter-server-go/internal/db/io"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 )
 
-// XLogIndex manages triple indexing: time, txid, and gxid.
+// XLogIndex manages triple indexing: time, txid, and gxid. The optional
+// userid index is created on the first SetByUserid, so days written without
+// xlog_userid_index_enabled carry no index files for it; likewise for the
+// secondary (objHash, service) index and xlog_secondary_index_enabled.
 type XLogIndex struct {
 	timeIndex *io.IndexTimeFile // time → data offset
 	txidIndex *io.IndexKeyFile  // txid → data offset
 	gxidIndex *io.IndexKeyFile  // gxid → data offsets (multi)
+
+	dir         string
+	uidMu       sync.Mutex
+	useridIndex *io.IndexKeyFile // userid → data offsets (multi); nil until opened
+
+	secMu    sync.Mutex
+	secIndex *io.IndexKeyFile // objHash/service and minute → times and data offsets; nil until opened
+	lastTime int64            // latest end time written before secIndex was opened
 }
 
 // NewXLogIndex opens the triple index files for a given directory.
@@ -38,6 +51,7 @@ func NewXLogIndex(dir string) (*XLogIndex, error) {
 		timeIndex: timeIdx,
 		txidIndex: txidIdx,
 		gxidIndex: gxidIdx,
+		dir:       dir,
 	}, nil
 }
 
@@ -60,6 +74,41 @@ func (x *XLogIndex) SetByGxid(gxid int64, dataPos int64) error {
 	return x.gxidIndex.Put(protocol.BigEndian.Bytes8(gxid), protocol.BigEndian.Bytes5(dataPos))
 }
 
+// SetByUserid stores a userid → data offset mapping, creating the userid
+// index if needed. Skips if userid == 0.
+func (x *XLogIndex) SetByUserid(userid int64, dataPos int64) error {
+	if userid == 0 {
+		return nil
+	}
+	idx, err := x.userid(true)
+	if err != nil {
+		return err
+	}
+	return idx.Put(protocol.BigEndian.Bytes8(userid), protocol.BigEndian.Bytes5(dataPos))
+}
+
+// userid returns the userid index, opening it if its files exist or create
+// is set. It returns nil if the day has no userid index.
+func (x *XLogIndex) userid(create bool) (*io.IndexKeyFile, error) {
+	x.uidMu.Lock()
+	defer x.uidMu.Unlock()
+	if x.useridIndex != nil {
+		return x.useridIndex, nil
+	}
+	path := filepath.Join(x.dir, "xlog_uid")
+	if !create {
+		if _, err := os.Stat(path + ".kfile"); err != nil {
+			return nil, nil
+		}
+	}
+	idx, err := io.NewIndexKeyFile(path, 1)
+	if err != nil {
+		return nil, err
+	}
+	x.useridIndex = idx
+	return idx, nil
+}
+
 // GetByTxid retrieves the data offset for a given txid. Returns -1 if not found.
 func (x *XLogIndex) GetByTxid(txid int64) (int64, error) {
 	value, err := x.txidIndex.Get(protocol.BigEndian.Bytes8(txid))
@@ -86,6 +135,25 @@ func (x *XLogIndex) GetByGxid(gxid int64) ([]int64, error) {
 	return offsets, nil
 }
 
+// GetByUserid retrieves all data offsets for a given userid, latest first.
+// It returns nil if the day has no userid index.
+func (x *XLogIndex) GetByUserid(userid int64) ([]int64, error) {
+	idx, err := x.userid(false)
+	if err != nil || idx == nil {
+		return nil, err
+	}
+	values, err := idx.GetAll(protocol.BigEndian.Bytes8(userid))
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make([]int64, len(values))
+	for i, v := range values {
+		offsets[i] = protocol.BigEndian.Int5(v)
+	}
+	return offsets, nil
+}
+
 // Close closes all index files.
 func (x *XLogIndex) Close() {
 	if x.timeIndex != nil {
@@ -97,4 +165,14 @@ func (x *XLogIndex) Close() {
 	if x.gxidIndex != nil {
 		x.gxidIndex.Close()
 	}
+	x.uidMu.Lock()
+	if x.useridIndex != nil {
+		x.useridIndex.Close()
+	}
+	x.uidMu.Unlock()
+	x.secMu.Lock()
+	if x.secIndex != nil {
+		x.secIndex.Close()
+	}
+	x.secMu.Unlock()
 }
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/zbum/scouter-server-go/internal/db/format"
+	"github.com/zbum/scouter-server-go/internal/db/io"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 )
 
@@ -32,6 +34,7 @@ func (r *XLogRD) getContainer(date string) (*dayContainer, error) {
 	if exists {
 		return container, nil
 	}
+	io.RestoreMissingDay(r.baseDir, date)
 
 	// Slow path: write lock to create container
 	r.mu.Lock()
@@ -48,6 +51,9 @@ func (r *XLogRD) getContainer(date string) (*dayContainer, error) {
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		return nil, nil // No data for this date
 	}
+	if err := format.XLog.Check(dir); err != nil {
+		return nil, err
+	}
 
 	// Open index and data files
 	index, err := NewXLogIndex(dir)
@@ -90,6 +96,21 @@ func (r *XLogRD) ReadByTime(date string, stime, etime int64, handler func(data [
 	})
 }
 
+// ReadSelected reads the XLog entries of sel within a time range, latest
+// first if reverse is set. Days with a secondary index covering the range
+// yield only the selected entries; other days yield every entry, so the
+// handler still filters. Handler returns false to stop iteration early.
+func (r *XLogRD) ReadSelected(date string, stime, etime int64, sel Selection, reverse bool, handler func(data []byte) bool) error {
+	container, err := r.getContainer(date)
+	if err != nil {
+		return err
+	}
+	if container == nil {
+		return nil // No data for this date
+	}
+	return container.readSelected(sel, stime, etime, reverse, handler)
+}
+
 // GetByTxid retrieves a single XLog by transaction ID.
 func (r *XLogRD) GetByTxid(date string, txid int64) ([]byte, error) {
 	container, err := r.getContainer(date)
@@ -136,6 +157,41 @@ func (r *XLogRD) ReadByGxid(date string, gxid int64, handler func(data []byte))
 	return nil
 }
 
+// ReadByGxidDates reads the XLog entries of gxid from each of dates.
+func (r *XLogRD) ReadByGxidDates(dates []string, gxid int64, handler func(data []byte)) error {
+	for _, date := range dates {
+		if err := r.ReadByGxid(date, gxid, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadByUserid reads the XLog entries of userid, latest first. Days written
+// without xlog_userid_index_enabled have no entries.
+// Handler returns false to stop iteration early.
+func (r *XLogRD) ReadByUserid(date string, userid int64, handler func(data []byte) bool) error {
+	container, err := r.getContainer(date)
+	if err != nil {
+		return err
+	}
+	if container == nil {
+		return nil // No data for this date
+	}
+
+	offsets, err := container.index.GetByUserid(userid)
+	if err != nil {
+		return err
+	}
+	for _, offset := range offsets {
+		data, err := container.data.Read(offset)
+		if err == nil && data != nil && !handler(data) {
+			break
+		}
+	}
+	return nil
+}
+
 // ReadFromEndTime reads XLog entries within a time range in reverse order.
 // Handler returns false to stop iteration early.
 func (r *XLogRD) ReadFromEndTime(date string, stime, etime int64, handler func(data []byte) bool) error {
@@ -176,6 +232,24 @@ func (r *XLogRD) PurgeOldDays(keepDates map[string]bool) {
 	}
 }
 
+// CloseDay closes the container for a single date, if open.
+func (r *XLogRD) CloseDay(date string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	container, ok := r.days[date]
+	if !ok {
+		return
+	}
+	if container.data != nil {
+		container.data.Close()
+	}
+	if container.index != nil {
+		container.index.Close()
+	}
+	delete(r.days, date)
+}
+
 // Close closes all open day containers.
 func (r *XLogRD) Close() {
 	r.mu.Lock()
@@ -191,3 +265,21 @@ func (r *XLogRD) Close() {
 	}
 	r.days = make(map[string]*dayContainer)
 }
+
+// OpenDays returns the dates with an open container.
+func (r *XLogRD) OpenDays() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	dates := make([]string, 0, len(r.days))
+	for date := range r.days {
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+// OpenDay opens the container of date ahead of a query. A date without xlog
+// data is not an error.
+func (r *XLogRD) OpenDay(date string) error {
+	_, err := r.getContainer(date)
+	return err
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/ingest"
 	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 )
 
 func setupTestDir(t *testing.T) string {
@@ -155,6 +159,90 @@ func TestXLogDataWriteRead(t *testing.T) {
 	}
 }
 
+// TestXLogDataFieldDict writes XLogPacks dictionary-encoded, with and
+// without compression, and reads them back through the writer's and a
+// freshly opened instance.
+func TestXLogDataFieldDict(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		dir := t.TempDir()
+		conf := filepath.Join(dir, "scouter.conf")
+		body := "xlog_field_dict_enabled=true\n"
+		if compress {
+			body += "compress_xlog_enabled=true\n"
+		}
+		os.WriteFile(conf, []byte(body), 0644)
+		if _, err := config.Load(conf); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+		xdata, err := NewXLogData(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		base := time.Now().UnixMilli()
+		var records [][]byte
+		var offsets []int64
+		for i := 0; i < 20; i++ {
+			o := protocol.NewDataOutputX()
+			pack.WritePack(o, &pack.XLogPack{
+				EndTime: base + int64(i*37) - 100,
+				ObjHash: int32(0x7abc0000 + i%2),
+				Service: -0x5f3e2d1c,
+				Txid:    int64(i),
+				Elapsed: int32(i * 10),
+				IPAddr:  []byte{10, 0, 0, byte(i)},
+			})
+			records = append(records, o.ToByteArray())
+			off, err := xdata.Write(o.ToByteArray())
+			if err != nil {
+				t.Fatal(err)
+			}
+			offsets = append(offsets, off)
+		}
+		// Non-XLog data is stored as is.
+		raw := []byte("not an xlog pack")
+		rawOff, _ := xdata.Write(raw)
+		xdata.Flush()
+
+		rd, err := NewXLogData(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, x := range []*XLogData{xdata, rd} {
+			for i, off := range offsets {
+				got, err := x.Read(off)
+				if err != nil {
+					t.Fatalf("compress=%v record %d: %v", compress, i, err)
+				}
+				if string(got) != string(records[i]) {
+					t.Fatalf("compress=%v record %d: mismatch", compress, i)
+				}
+			}
+			if got, err := x.Read(rawOff); err != nil || string(got) != string(raw) {
+				t.Fatalf("compress=%v raw record: %q, %v", compress, got, err)
+			}
+		}
+		rd.Close()
+		xdata.Close()
+
+		if len(rd.dict.entries) != 2 {
+			t.Fatalf("expected 2 dictionary entries, got %v", rd.dict.entries)
+		}
+		if !compress {
+			rawSize := 0
+			for _, r := range records {
+				rawSize += 2 + len(r)
+			}
+			rawSize += 2 + len(raw)
+			fi, _ := os.Stat(filepath.Join(dir, "xlog.data"))
+			if fi.Size() >= int64(rawSize) {
+				t.Fatalf("encoded size %d not below raw size %d", fi.Size(), rawSize)
+			}
+		}
+	}
+}
+
 // TestXLogWRAsync tests async writer with XLogRD reader.
 func TestXLogWRAsync(t *testing.T) {
 	dir := setupTestDir(t)
@@ -390,6 +478,29 @@ func TestXLogWRBatchProcessing(t *testing.T) {
 	}
 }
 
+// TestXLogWRIngestLatency tests that stamped entries are observed once written.
+func TestXLogWRIngestLatency(t *testing.T) {
+	dir := setupTestDir(t)
+	defer cleanupTestDir(dir)
+
+	writer := NewXLogWR(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	writer.Start(ctx)
+
+	ingest.GetLatency().Drain()
+	now := time.Now()
+	writer.Add(&XLogEntry{Time: now.UnixMilli(), Txid: 1, Data: []byte("a"), Received: now.Add(-50 * time.Millisecond)})
+	writer.Add(&XLogEntry{Time: now.UnixMilli(), Txid: 2, Data: []byte("b")})
+	time.Sleep(200 * time.Millisecond)
+	writer.Close()
+
+	h := ingest.GetLatency().Drain()["xlog"]
+	if h.Count != 1 || h.Max < 50*time.Millisecond {
+		t.Errorf("xlog latency = %+v, want one observation >= 50ms", h)
+	}
+}
+
 // TestXLogWRBatchWithGxid tests batch processing with gxid indexing.
 func TestXLogWRBatchWithGxid(t *testing.T) {
 	dir := setupTestDir(t)
@@ -458,3 +569,99 @@ func TestXLogReaderNonExistentDate(t *testing.T) {
 		t.Error("Expected nil data for non-existent date")
 	}
 }
+
+func TestGxidDates(t *testing.T) {
+	if got := GxidDates("20260301", "20260228"); !slices.Equal(got, []string{"20260227", "20260228", "20260301", "20260302"}) {
+		t.Errorf("default window = %v", got)
+	}
+
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("xlog_gxid_adjacent_days=0\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+	if got := GxidDates("20260301", "", "bad"); !slices.Equal(got, []string{"20260301", "bad"}) {
+		t.Errorf("no window = %v", got)
+	}
+}
+
+// TestXLogSecondaryIndex tests range reads restricted to objects or services.
+func TestXLogSecondaryIndex(t *testing.T) {
+	dir := setupTestDir(t)
+	defer cleanupTestDir(dir)
+	conf := filepath.Join(dir, "scouter.conf")
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	// Tomorrow's XLogs: the index covers end times from its creation on.
+	y, m, d := time.Now().Date()
+	noon := time.Date(y, m, d+1, 12, 0, 0, 0, time.Local)
+	date := noon.Format("20060102")
+	base := noon.UnixMilli()
+	objOf := make(map[int64]int32)
+	add := func(w *XLogWR, timeMs int64, objHash, service int32) {
+		objOf[timeMs] = objHash
+		w.Add(&XLogEntry{Time: timeMs, Txid: timeMs, ObjHash: objHash, Service: service, Data: protocol.BigEndian.Bytes8(timeMs)})
+	}
+
+	writer := NewXLogWR(dir)
+	for i := int64(0); i < 10; i++ {
+		add(writer, base-600_000+i*1000, 1, 100)
+	}
+	writer.Drain()
+	os.WriteFile(conf, []byte("xlog_secondary_index_enabled=true\n"), 0644)
+	config.Load(conf)
+	for i := int64(0); i < 90; i++ {
+		add(writer, base+i*1000, int32(i%3+1), int32(200+i%2))
+	}
+	writer.Drain()
+	// Days that have the index keep it when it is turned off.
+	os.WriteFile(conf, []byte("xlog_secondary_index_enabled=false\n"), 0644)
+	config.Load(conf)
+	add(writer, base+90_000, 1, 200)
+	writer.Drain()
+
+	read := func(sel Selection, stime, etime int64, reverse bool) []int64 {
+		var times []int64
+		found, err := writer.ReadSelected(date, stime, etime, sel, reverse, func(data []byte) bool {
+			times = append(times, protocol.BigEndian.Int64(data))
+			return true
+		})
+		if err != nil || !found {
+			t.Fatalf("ReadSelected = %v, %v", found, err)
+		}
+		return times
+	}
+
+	times := read(Selection{ObjHashes: []int32{1}}, base, base+120_000, false)
+	if len(times) != 31 || !slices.IsSorted(times) {
+		t.Fatalf("objHash 1 read %d XLogs %v, want 31 in time order", len(times), times)
+	}
+	for _, tm := range times {
+		if objOf[tm] != 1 {
+			t.Fatalf("read XLog of objHash %d", objOf[tm])
+		}
+	}
+	rev := read(Selection{ObjHashes: []int32{1, 2}}, base+30_000, base+59_999, true)
+	if len(rev) != 20 || !slices.IsSortedFunc(rev, func(a, b int64) int { return int(b - a) }) {
+		t.Errorf("objHash 1,2 reverse read %d XLogs %v, want 20 latest first", len(rev), rev)
+	}
+	if n := len(read(Selection{Services: []int32{201}}, base, base+120_000, false)); n != 45 {
+		t.Errorf("service 201 read %d XLogs, want 45", n)
+	}
+	// Before the index started every XLog is read.
+	if n := len(read(Selection{ObjHashes: []int32{2}}, base-600_000, base+120_000, false)); n != 101 {
+		t.Errorf("read %d XLogs from before the index, want all 101", n)
+	}
+	writer.Close()
+
+	reader := NewXLogRD(dir)
+	defer reader.Close()
+	var n int
+	reader.ReadSelected(date, base, base+120_000, Selection{ObjHashes: []int32{3}}, false, func(data []byte) bool {
+		n++
+		return n < 10
+	})
+	if n != 10 {
+		t.Errorf("reader stopped after %d XLogs, want 10", n)
+	}
+}
@@ -5,7 +5,13 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/db/format"
+	"github.com/zbum/scouter-server-go/internal/db/io"
+	"github.com/zbum/scouter-server-go/internal/ingest"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/util"
 )
@@ -15,8 +21,14 @@ type XLogEntry struct {
 	Time    int64
 	Txid    int64
 	Gxid    int64
+	Userid  int64
 	Elapsed int32
+	ObjHash int32  // for write statistics, 0 if unknown
+	Service int32  // for the secondary index
 	Data    []byte // pre-serialized XLogPack bytes
+	// Received is when the pack arrived over UDP, zero if unknown; the
+	// delay until it is indexed is recorded as ingest latency.
+	Received time.Time
 }
 
 const batchSize = 512 // max entries per batch drain
@@ -29,6 +41,7 @@ type XLogWR struct {
 	baseDir string
 	days    map[string]*dayContainer
 	queue   chan *XLogEntry
+	dropped atomic.Int64
 }
 
 type dayContainer struct {
@@ -76,27 +89,72 @@ func (w *XLogWR) Start(ctx context.Context) {
 			}
 
 		processBatch:
-			for _, e := range batch {
-				w.process(e)
-			}
-
-			if len(batch) > 0 {
-				w.flushData()
-			}
+			w.writeBatch(batch)
 			batch = batch[:0]
 		}
 	}()
 }
 
+// Drain writes every queued entry on the calling goroutine, in batches as
+// Start does, and returns how many were written. It replaces Start where
+// writes must happen at a known point, as in deterministic tests.
+func (w *XLogWR) Drain() int {
+	n := 0
+	batch := make([]*XLogEntry, 0, batchSize)
+	for {
+	fill:
+		for len(batch) < batchSize {
+			select {
+			case entry := <-w.queue:
+				if entry != nil {
+					batch = append(batch, entry)
+				}
+			default:
+				break fill
+			}
+		}
+		if len(batch) == 0 {
+			return n
+		}
+		w.writeBatch(batch)
+		n += len(batch)
+		batch = batch[:0]
+	}
+}
+
+// writeBatch writes entries and flushes the data files once.
+func (w *XLogWR) writeBatch(batch []*XLogEntry) {
+	for _, e := range batch {
+		w.process(e)
+	}
+	if len(batch) > 0 {
+		w.flushData()
+	}
+	for _, e := range batch {
+		ingest.GetLatency().Observe("xlog", e.Received)
+	}
+}
+
 // Add enqueues an XLog entry for async writing.
 func (w *XLogWR) Add(entry *XLogEntry) {
 	select {
 	case w.queue <- entry:
 	default:
 		// Queue full, drop entry (could log warning here)
+		w.dropped.Add(1)
 	}
 }
 
+// Dropped returns the number of entries dropped due to write queue overflow.
+func (w *XLogWR) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Pending returns the number of entries waiting to be written.
+func (w *XLogWR) Pending() int {
+	return len(w.queue)
+}
+
 // getContainer retrieves or creates a day container.
 func (w *XLogWR) getContainer(date string) (*dayContainer, error) {
 	w.mu.Lock()
@@ -112,6 +170,9 @@ func (w *XLogWR) getContainer(date string) (*dayContainer, error) {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, err
 	}
+	if err := format.XLog.Stamp(dir); err != nil {
+		return nil, err
+	}
 
 	// Open index and data files
 	index, err := NewXLogIndex(dir)
@@ -172,6 +233,16 @@ func (w *XLogWR) process(entry *XLogEntry) {
 	if err := container.index.SetByGxid(entry.Gxid, dataPos); err != nil {
 		return
 	}
+
+	// Index by userid (optional, for its storage cost)
+	cfg := config.Get()
+	if cfg != nil && cfg.XLogUseridIndexEnabled() {
+		container.index.SetByUserid(entry.Userid, dataPos)
+	}
+	// Index by objHash and service (optional; kept for the rest of a day once started)
+	container.index.SetBySecondary(entry.Time, entry.ObjHash, entry.Service, dataPos,
+		cfg != nil && cfg.XLogSecondaryIndexEnabled())
+	io.GetWriteStats().Record("xlog", date, entry.ObjHash, len(entry.Data))
 }
 
 // ReadByTime reads XLog entries from the writer's in-memory containers.
@@ -219,6 +290,19 @@ func (w *XLogWR) ReadFromEndTime(date string, stime, etime int64, handler func(d
 	return true, err
 }
 
+// ReadSelected reads the XLog entries of sel from the writer's containers,
+// as XLogRD.ReadSelected does. Returns false if the writer has no container
+// for the date.
+func (w *XLogWR) ReadSelected(date string, stime, etime int64, sel Selection, reverse bool, handler func(data []byte) bool) (bool, error) {
+	w.mu.RLock()
+	container, exists := w.days[date]
+	w.mu.RUnlock()
+	if !exists {
+		return false, nil
+	}
+	return true, container.readSelected(sel, stime, etime, reverse, handler)
+}
+
 // GetByTxid retrieves a single XLog by transaction ID from the writer's containers.
 // Returns (nil, false, nil) if the writer has no container for the date.
 func (w *XLogWR) GetByTxid(date string, txid int64) ([]byte, bool, error) {
@@ -265,6 +349,46 @@ func (w *XLogWR) ReadByGxid(date string, gxid int64, handler func(data []byte))
 	return true, nil
 }
 
+// ReadByGxidDates reads the XLog entries of gxid from the dates the writer
+// holds and returns the other dates, to be read with XLogRD.ReadByGxidDates.
+func (w *XLogWR) ReadByGxidDates(dates []string, gxid int64, handler func(data []byte)) ([]string, error) {
+	var missing []string
+	for _, date := range dates {
+		found, err := w.ReadByGxid(date, gxid, handler)
+		if err != nil {
+			return missing, err
+		}
+		if !found {
+			missing = append(missing, date)
+		}
+	}
+	return missing, nil
+}
+
+// ReadByUserid reads the XLog entries of userid from the writer's containers,
+// latest first. Returns false if the writer has no container for the date.
+// Handler returns false to stop iteration early.
+func (w *XLogWR) ReadByUserid(date string, userid int64, handler func(data []byte) bool) (bool, error) {
+	w.mu.RLock()
+	container, exists := w.days[date]
+	w.mu.RUnlock()
+	if !exists {
+		return false, nil
+	}
+
+	offsets, err := container.index.GetByUserid(userid)
+	if err != nil {
+		return true, err
+	}
+	for _, offset := range offsets {
+		data, err := container.data.Read(offset)
+		if err == nil && data != nil && !handler(data) {
+			break
+		}
+	}
+	return true, nil
+}
+
 // PurgeOldDays closes day containers not in the keepDates set.
 func (w *XLogWR) PurgeOldDays(keepDates map[string]bool) {
 	w.mu.Lock()
@@ -285,6 +409,25 @@ func (w *XLogWR) PurgeOldDays(keepDates map[string]bool) {
 	}
 }
 
+// CloseDay flushes and closes the container for a single date, if open.
+func (w *XLogWR) CloseDay(date string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	container, ok := w.days[date]
+	if !ok {
+		return
+	}
+	if container.data != nil {
+		container.data.Flush()
+		container.data.Close()
+	}
+	if container.index != nil {
+		container.index.Close()
+	}
+	delete(w.days, date)
+}
+
 // Close closes all open day containers.
 func (w *XLogWR) Close() {
 	w.mu.Lock()
@@ -301,3 +444,14 @@ func (w *XLogWR) Close() {
 	}
 	w.days = make(map[string]*dayContainer)
 }
+
+// OpenDays returns the dates with an open container.
+func (w *XLogWR) OpenDays() []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	dates := make([]string, 0, len(w.days))
+	for date := range w.days {
+		dates = append(dates, date)
+	}
+	return dates
+}
@@ -0,0 +1,222 @@
+// Package deploywin implements deployment windows: periods announced by CI
+// during which alerts of the objects being redeployed are either dropped, for
+// the alert titles the window suppresses, or tagged "deployment", so planned
+// restarts do not page anyone.
+package deploywin
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// TagDeployment is the alert tag holding the ID of the window an alert was
+// raised in.
+const TagDeployment = "deployment"
+
+// SuppressAll in Suppress drops every alert of the window's objects.
+const SuppressAll = "*"
+
+// Window is one deployment window.
+type Window struct {
+	ID int64 `json:"id"`
+	// Objects selects the objects by objName, objHash or objName pattern
+	// (path.Match syntax); empty means every object.
+	Objects []string `json:"objects,omitempty"`
+	// Suppress lists the alert titles dropped during the window; the other
+	// alerts of the objects are tagged.
+	Suppress []string  `json:"suppress,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	By       string    `json:"by,omitempty"`
+	Start    time.Time `json:"start"`
+	Until    time.Time `json:"until"`
+	// Suppressed and Tagged count the alerts the window affected.
+	Suppressed int64 `json:"suppressed"`
+	Tagged     int64 `json:"tagged"`
+}
+
+// covers reports whether the object belongs to the window.
+func (w *Window) covers(objHash int32, objName string) bool {
+	if len(w.Objects) == 0 {
+		return true
+	}
+	for _, o := range w.Objects {
+		if h, err := strconv.ParseInt(o, 10, 32); err == nil {
+			if int32(h) == objHash {
+				return true
+			}
+			continue
+		}
+		if util.HashString(o) == objHash {
+			return true
+		}
+		if objName != "" {
+			if ok, _ := path.Match(o, objName); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (w *Window) suppresses(title string) bool {
+	return slices.Contains(w.Suppress, SuppressAll) || slices.Contains(w.Suppress, title)
+}
+
+// Manager holds the open windows. Windows live in memory only; they are
+// short and CI opens them again on its next deployment.
+type Manager struct {
+	objectCache *cache.ObjectCache
+	now         func() time.Time
+
+	mu      sync.Mutex
+	nextID  int64
+	windows []*Window
+}
+
+// NewManager creates a Manager resolving objNames through objectCache.
+func NewManager(objectCache *cache.ObjectCache) *Manager {
+	return &Manager{objectCache: objectCache, now: time.Now, nextID: 1}
+}
+
+// SetClock replaces the clock, for tests.
+func (m *Manager) SetClock(now func() time.Time) {
+	m.now = now
+}
+
+// Open starts a window of the given duration, bounded by
+// deploy_window_max_minutes, and returns a copy of it.
+func (m *Manager) Open(w Window, d time.Duration) (Window, error) {
+	if d <= 0 {
+		return Window{}, errors.New("duration must be positive")
+	}
+	if cfg := config.Get(); cfg != nil {
+		if limit := time.Duration(cfg.DeployWindowMaxMinutes()) * time.Minute; limit > 0 && d > limit {
+			return Window{}, fmt.Errorf("duration exceeds deploy_window_max_minutes (%s)", limit)
+		}
+	}
+	for _, o := range w.Objects {
+		if _, err := path.Match(o, ""); err != nil {
+			return Window{}, fmt.Errorf("bad pattern %q: %w", o, err)
+		}
+	}
+	w.Objects = trimmed(w.Objects)
+	w.Suppress = trimmed(w.Suppress)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	w.ID = m.nextID
+	m.nextID++
+	w.Start = m.now()
+	w.Until = w.Start.Add(d)
+	w.Suppressed, w.Tagged = 0, 0
+	m.windows = append(m.windows, &w)
+	slog.Info("Deployment window opened", "id", w.ID, "objects", w.Objects,
+		"suppress", w.Suppress, "until", w.Until.Format(time.RFC3339), "by", w.By, "reason", w.Reason)
+	return w, nil
+}
+
+// Close ends a window early and reports whether it was open.
+func (m *Manager) Close(id int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	for i, w := range m.windows {
+		if w.ID == id {
+			m.windows = slices.Delete(m.windows, i, i+1)
+			slog.Info("Deployment window closed", "id", id,
+				"suppressed", w.Suppressed, "tagged", w.Tagged)
+			return true
+		}
+	}
+	return false
+}
+
+// List returns the open windows ordered by ID.
+func (m *Manager) List() []Window {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	result := make([]Window, 0, len(m.windows))
+	for _, w := range m.windows {
+		result = append(result, *w)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// Apply checks an alert against the open windows. It reports false if a
+// window covering the object suppresses the alert's title; otherwise an alert
+// of a covered object gets the TagDeployment tag and true is returned.
+func (m *Manager) Apply(ap *pack.AlertPack) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	if len(m.windows) == 0 {
+		return true
+	}
+	objName := ""
+	if m.objectCache != nil {
+		if info, ok := m.objectCache.Get(ap.ObjHash); ok {
+			objName = info.Pack.ObjName
+		}
+	}
+	var tagged *Window
+	for _, w := range m.windows {
+		if !w.covers(ap.ObjHash, objName) {
+			continue
+		}
+		if w.suppresses(ap.Title) {
+			w.Suppressed++
+			return false
+		}
+		if tagged == nil {
+			tagged = w
+		}
+	}
+	if tagged != nil {
+		tagged.Tagged++
+		if ap.Tags == nil {
+			ap.Tags = value.NewMapValue()
+		}
+		ap.Tags.Put(TagDeployment, value.NewDecimalValue(tagged.ID))
+	}
+	return true
+}
+
+// expire drops the windows that ended. Caller must hold m.mu.
+func (m *Manager) expire() {
+	now := m.now()
+	m.windows = slices.DeleteFunc(m.windows, func(w *Window) bool {
+		if now.Before(w.Until) {
+			return false
+		}
+		slog.Info("Deployment window ended", "id", w.ID,
+			"suppressed", w.Suppressed, "tagged", w.Tagged)
+		return true
+	})
+}
+
+func trimmed(ss []string) []string {
+	var result []string
+	for _, s := range ss {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+package deploywin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+func TestManager_Apply(t *testing.T) {
+	objects := cache.NewObjectCache()
+	for _, name := range []string{"/checkout-1/tomcat", "/cart-1/tomcat"} {
+		h := util.HashString(name)
+		objects.Put(h, &pack.ObjectPack{ObjHash: h, ObjName: name, ObjType: "tomcat"})
+	}
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	m := NewManager(objects)
+	m.SetClock(func() time.Time { return now })
+
+	w, err := m.Open(Window{Objects: []string{"/checkout-*/*"}, Suppress: []string{"INACTIVE_OBJECT"}}, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkout := util.HashString("/checkout-1/tomcat")
+	if m.Apply(&pack.AlertPack{ObjHash: checkout, Title: "INACTIVE_OBJECT"}) {
+		t.Error("suppressed title was kept")
+	}
+	ap := &pack.AlertPack{ObjHash: checkout, Title: "HIGH_ERROR_RATE"}
+	if !m.Apply(ap) {
+		t.Fatal("other title was dropped")
+	}
+	if v, ok := ap.Tags.Get(TagDeployment); !ok || v.(*value.DecimalValue).Value != w.ID {
+		t.Errorf("deployment tag = %v, want %d", v, w.ID)
+	}
+	other := &pack.AlertPack{ObjHash: util.HashString("/cart-1/tomcat"), Title: "INACTIVE_OBJECT"}
+	if !m.Apply(other) || other.Tags != nil {
+		t.Error("alert of an uncovered object was changed")
+	}
+	if got := m.List(); len(got) != 1 || got[0].Suppressed != 1 || got[0].Tagged != 1 {
+		t.Errorf("List = %+v", got)
+	}
+
+	now = now.Add(10 * time.Minute)
+	if !m.Apply(&pack.AlertPack{ObjHash: checkout, Title: "INACTIVE_OBJECT"}) {
+		t.Error("alert dropped after the window ended")
+	}
+	if got := m.List(); len(got) != 0 {
+		t.Errorf("expired window still listed: %+v", got)
+	}
+}
+
+func TestManager_OpenClose(t *testing.T) {
+	m := NewManager(nil)
+	if _, err := m.Open(Window{}, 0); err == nil {
+		t.Error("zero duration accepted")
+	}
+	if _, err := m.Open(Window{Objects: []string{"/a/["}}, time.Minute); err == nil {
+		t.Error("bad pattern accepted")
+	}
+	w, err := m.Open(Window{Suppress: []string{SuppressAll}}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Apply(&pack.AlertPack{ObjHash: 1, Title: "ANY"}) {
+		t.Error("window without objects did not cover every object")
+	}
+	if !m.Close(w.ID) || m.Close(w.ID) {
+		t.Error("Close did not report the open window once")
+	}
+}
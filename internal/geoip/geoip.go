@@ -3,15 +3,29 @@ package geoip
 import (
 	"log/slog"
 	"net"
+	"os"
 	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/util"
 )
 
+// Files lists the GeoIP database files. Empty paths are skipped.
+type Files struct {
+	City       string // GeoIP2/GeoLite2 City or Country database (.mmdb)
+	ASN        string // GeoLite2 ASN database (.mmdb)
+	LegacyCity string // legacy GeoLiteCity.dat, used when City cannot be opened
+}
+
 // GeoIPUtil provides GeoIP lookup with LRU cache.
-// Uses MaxMind MMDB format for IP → city resolution.
+// City and country are resolved from a MaxMind DB (.mmdb) file, falling back
+// to the legacy GeoLiteCity.dat format; ASN data comes from a separate mmdb.
 type GeoIPUtil struct {
 	mu         sync.RWMutex
 	enabled    bool
-	dbPath     string
+	city       *mmdbReader
+	legacy     *legacyReader
+	asn        *mmdbReader
 	cache      map[string]*GeoResult // IP string → result
 	cacheOrder []string              // LRU order tracking
 	maxCache   int
@@ -22,52 +36,124 @@ type GeoResult struct {
 	CountryCode string
 	City        string
 	CityHash    int32
+	ASN         uint32
+	ASOrg       string
 }
 
 // New creates a new GeoIPUtil.
-// If the MMDB file doesn't exist, lookups return empty results.
-func New(dbPath string) *GeoIPUtil {
+// Databases that don't exist or can't be read are logged and skipped;
+// if none is available, lookups return empty results.
+func New(files Files) *GeoIPUtil {
 	g := &GeoIPUtil{
 		enabled:  true,
-		dbPath:   dbPath,
 		cache:    make(map[string]*GeoResult),
 		maxCache: 10000,
 	}
+
+	if files.City != "" {
+		if r, err := openMMDB(files.City); err == nil {
+			g.city = r
+			logDatabase("city", r)
+		} else if !os.IsNotExist(err) {
+			slog.Warn("GeoIP city database unavailable", "path", files.City, "error", err)
+		}
+	}
+	if g.city == nil && files.LegacyCity != "" {
+		if r, err := openLegacy(files.LegacyCity); err == nil {
+			g.legacy = r
+			slog.Info("GeoIP legacy database loaded", "path", r.path)
+		} else if !os.IsNotExist(err) {
+			slog.Warn("GeoIP legacy database unavailable", "path", files.LegacyCity, "error", err)
+		}
+	}
+	if files.ASN != "" {
+		if r, err := openMMDB(files.ASN); err == nil {
+			g.asn = r
+			logDatabase("asn", r)
+		} else if !os.IsNotExist(err) {
+			slog.Warn("GeoIP ASN database unavailable", "path", files.ASN, "error", err)
+		}
+	}
+	if g.city == nil && g.legacy == nil && g.asn == nil {
+		slog.Warn("GeoIP enabled but no database found", "city", files.City, "legacy", files.LegacyCity)
+	}
 	return g
 }
 
+func logDatabase(kind string, r *mmdbReader) {
+	slog.Info("GeoIP database loaded", "kind", kind, "path", r.path,
+		"type", r.meta.DatabaseType, "build", r.BuildTime().UTC().Format("2006-01-02"))
+}
+
 // Lookup resolves IP address bytes to country code and city.
 // Returns empty strings for private IPs or if GeoIP is not available.
 func (g *GeoIPUtil) Lookup(ipAddr []byte) (countryCode string, city string, cityHash int32) {
-	if !g.enabled || len(ipAddr) == 0 {
+	r := g.LookupResult(ipAddr)
+	if r == nil {
 		return "", "", 0
 	}
+	return r.CountryCode, r.City, r.CityHash
+}
+
+// LookupResult resolves IP address bytes to a full GeoResult, including ASN.
+// Returns nil for private IPs or if GeoIP is disabled. The result is shared
+// with the cache and must not be modified.
+func (g *GeoIPUtil) LookupResult(ipAddr []byte) *GeoResult {
+	if len(ipAddr) == 0 {
+		return nil
+	}
 
 	ip := net.IP(ipAddr)
-	if ip == nil {
-		return "", "", 0
+	if ip.To16() == nil {
+		return nil
 	}
 
 	// Skip private/loopback IPs
 	if isPrivateIP(ip) {
-		return "", "", 0
+		return nil
 	}
 
 	ipStr := ip.String()
 
 	// Check cache
 	g.mu.RLock()
+	if !g.enabled {
+		g.mu.RUnlock()
+		return nil
+	}
 	if result, ok := g.cache[ipStr]; ok {
 		g.mu.RUnlock()
-		return result.CountryCode, result.City, result.CityHash
+		return result
 	}
+	city, legacy, asn := g.city, g.legacy, g.asn
 	g.mu.RUnlock()
 
-	// GeoIP MMDB lookup would happen here.
-	// Since we can't add the maxminddb-golang dependency without go mod tidy
-	// being available, this provides the framework for when the MMDB file is present.
-	// The lookup returns empty results until the MMDB reader is initialized.
 	result := &GeoResult{}
+	if city != nil {
+		if rec, err := city.lookup(ip); err != nil {
+			slog.Debug("GeoIP city lookup failed", "ip", ipStr, "error", err)
+		} else if rec != nil {
+			result.CountryCode, _ = path(rec, "country", "iso_code").(string)
+			result.City, _ = path(rec, "city", "names", "en").(string)
+		}
+	} else if legacy != nil {
+		cc, name, err := legacy.lookup(ip)
+		if err != nil {
+			slog.Debug("GeoIP legacy lookup failed", "ip", ipStr, "error", err)
+		}
+		result.CountryCode, result.City = cc, name
+	}
+	if result.City != "" {
+		result.CityHash = util.HashString(result.City)
+	}
+	if asn != nil {
+		if rec, err := asn.lookup(ip); err != nil {
+			slog.Debug("GeoIP ASN lookup failed", "ip", ipStr, "error", err)
+		} else if rec != nil {
+			result.ASN = uint32(toUint64(path(rec, "autonomous_system_number")))
+			result.ASOrg, _ = path(rec, "autonomous_system_organization").(string)
+		}
+	}
 
 	// Cache the result
 	g.mu.Lock()
@@ -80,7 +166,51 @@ func (g *GeoIPUtil) Lookup(ipAddr []byte) (countryCode string, city string, city
 	g.cacheOrder = append(g.cacheOrder, ipStr)
 	g.mu.Unlock()
 
-	return result.CountryCode, result.City, result.CityHash
+	return result
+}
+
+// database returns the loaded mmdb reader of the given kind ("city" or "asn").
+func (g *GeoIPUtil) database(kind string) *mmdbReader {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if kind == "asn" {
+		return g.asn
+	}
+	return g.city
+}
+
+// swap replaces the mmdb reader of the given kind and clears the lookup cache.
+// A new city database takes precedence over the legacy fallback.
+func (g *GeoIPUtil) swap(kind string, r *mmdbReader) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.enabled {
+		return
+	}
+	if kind == "asn" {
+		g.asn = r
+	} else {
+		g.city = r
+		g.legacy = nil
+	}
+	g.cache = make(map[string]*GeoResult)
+	g.cacheOrder = nil
+}
+
+// warnIfStale logs a warning for each loaded mmdb older than staleAfter.
+func (g *GeoIPUtil) warnIfStale() {
+	g.mu.RLock()
+	readers := map[string]*mmdbReader{"city": g.city, "asn": g.asn}
+	g.mu.RUnlock()
+	for kind, r := range readers {
+		if r == nil {
+			continue
+		}
+		if age := time.Since(r.BuildTime()); age > staleAfter {
+			slog.Warn("GeoIP database is stale", "kind", kind, "path", r.path,
+				"build", r.BuildTime().UTC().Format("2006-01-02"), "ageDays", int(age.Hours()/24))
+		}
+	}
 }
 
 // privateCIDRs holds pre-parsed private IP ranges to avoid repeated parsing.
@@ -111,6 +241,7 @@ func (g *GeoIPUtil) Close() {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	g.enabled = false
+	g.city, g.legacy, g.asn = nil, nil, nil
 	g.cache = make(map[string]*GeoResult)
 	slog.Info("GeoIP closed")
 }
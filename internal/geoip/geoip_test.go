@@ -0,0 +1,441 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// ---------------------------------------------------------------------------
+// Test database writers
+// ---------------------------------------------------------------------------
+
+// mmdbEncoder writes MaxMind DB data section values.
+type mmdbEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *mmdbEncoder) ctrl(typ int, size int) {
+	var ext []byte
+	if typ > 7 {
+		ext = []byte{byte(typ - 7)}
+		typ = 0
+	}
+	switch {
+	case size < 29:
+		e.buf.WriteByte(byte(typ<<5 | size))
+		e.buf.Write(ext)
+	case size < 285:
+		e.buf.WriteByte(byte(typ<<5 | 29))
+		e.buf.Write(ext)
+		e.buf.WriteByte(byte(size - 29))
+	default:
+		e.buf.WriteByte(byte(typ<<5 | 30))
+		e.buf.Write(ext)
+		e.buf.Write([]byte{byte((size - 285) >> 8), byte(size - 285)})
+	}
+}
+
+// pointer is an encoded reference to an offset in the data section.
+type pointer uint32
+
+func (e *mmdbEncoder) encode(v any) {
+	switch x := v.(type) {
+	case string:
+		e.ctrl(mmdbString, len(x))
+		e.buf.WriteString(x)
+	case uint32:
+		b := binary.BigEndian.AppendUint32(nil, x)
+		b = bytes.TrimLeft(b, "\x00")
+		e.ctrl(mmdbUint32, len(b))
+		e.buf.Write(b)
+	case uint16:
+		b := bytes.TrimLeft(binary.BigEndian.AppendUint16(nil, x), "\x00")
+		e.ctrl(mmdbUint16, len(b))
+		e.buf.Write(b)
+	case uint64:
+		b := bytes.TrimLeft(binary.BigEndian.AppendUint64(nil, x), "\x00")
+		e.ctrl(mmdbUint64, len(b))
+		e.buf.Write(b)
+	case float64:
+		e.ctrl(mmdbDouble, 8)
+		e.buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(x)))
+	case bool:
+		size := 0
+		if x {
+			size = 1
+		}
+		e.ctrl(mmdbBool, size)
+	case []any:
+		e.ctrl(mmdbArray, len(x))
+		for _, item := range x {
+			e.encode(item)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		e.ctrl(mmdbMap, len(keys))
+		for _, k := range keys {
+			e.encode(k)
+			e.encode(x[k])
+		}
+	case pointer:
+		// Always use the 4-byte pointer form (size bits = 3).
+		e.buf.WriteByte(byte(mmdbPointer<<5 | 3<<3))
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(x)))
+	default:
+		panic("unsupported test value")
+	}
+}
+
+type testNetwork struct {
+	cidr string
+	data any
+}
+
+// buildMMDB writes a MaxMind DB with 24-bit records containing the given
+// networks. preamble values are written at the start of the data section
+// so records can reference them with pointers.
+func buildMMDB(t *testing.T, ipVersion int, dbType string, preamble []any, networks []testNetwork) []byte {
+	t.Helper()
+
+	type record struct {
+		node int // >0: child node index, 0: empty, <0: data index -(i+1)
+	}
+	nodes := [][2]record{{}}
+
+	var data mmdbEncoder
+	for _, v := range preamble {
+		data.encode(v)
+	}
+	dataOffsets := make([]int, len(networks))
+
+	for i, n := range networks {
+		_, ipnet, err := net.ParseCIDR(n.cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ip := ipnet.IP
+		ones, _ := ipnet.Mask.Size()
+		if ipVersion == 6 {
+			if v4 := ip.To4(); v4 != nil {
+				ip = append(make(net.IP, 12), v4...)
+				ones += 96
+			}
+		}
+		dataOffsets[i] = data.buf.Len()
+		data.encode(n.data)
+
+		node := 0
+		for b := 0; b < ones; b++ {
+			bit := int(ip[b/8]>>(7-b%8)) & 1
+			if b == ones-1 {
+				nodes[node][bit] = record{node: -(i + 1)}
+				break
+			}
+			next := nodes[node][bit].node
+			if next <= 0 {
+				nodes = append(nodes, [2]record{})
+				next = len(nodes) - 1
+				nodes[node][bit] = record{node: next}
+			}
+			node = next
+		}
+	}
+
+	nodeCount := len(nodes)
+	var out bytes.Buffer
+	for _, n := range nodes {
+		for _, r := range n {
+			v := nodeCount // empty
+			if r.node > 0 {
+				v = r.node
+			} else if r.node < 0 {
+				v = nodeCount + mmdbDataSeparatorLen + dataOffsets[-r.node-1]
+			}
+			out.Write([]byte{byte(v >> 16), byte(v >> 8), byte(v)})
+		}
+	}
+	out.Write(make([]byte, mmdbDataSeparatorLen))
+	out.Write(data.buf.Bytes())
+
+	out.Write(mmdbMetadataMarker)
+	var meta mmdbEncoder
+	meta.encode(map[string]any{
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+		"ip_version":                  uint16(ipVersion),
+		"database_type":               dbType,
+		"languages":                   []any{"en"},
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1700000000),
+		"description":                 map[string]any{"en": "test"},
+	})
+	out.Write(meta.buf.Bytes())
+	return out.Bytes()
+}
+
+// buildLegacyCity writes a legacy GeoLiteCity.dat (city edition rev1) with
+// one record per /24 network.
+func buildLegacyCity(t *testing.T, networks map[string][3]string) []byte {
+	t.Helper()
+	nodes := [][2]uint32{{}}
+	type leaf struct {
+		node, bit int
+		rec       []byte
+	}
+	var leaves []leaf
+
+	cidrs := make([]string, 0, len(networks))
+	for c := range networks {
+		cidrs = append(cidrs, c)
+	}
+	sort.Strings(cidrs)
+	for _, c := range cidrs {
+		_, ipnet, err := net.ParseCIDR(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ip := ipnet.IP.To4()
+		ones, _ := ipnet.Mask.Size()
+		node := 0
+		for b := 0; b < ones-1; b++ {
+			bit := int(ip[b/8]>>(7-b%8)) & 1
+			next := nodes[node][bit]
+			if next == 0 {
+				nodes = append(nodes, [2]uint32{})
+				next = uint32(len(nodes) - 1)
+				nodes[node][bit] = next
+			}
+			node = int(next)
+		}
+		f := networks[c]
+		var id byte
+		for i, cc := range legacyCountryCodes {
+			if cc == f[0] {
+				id = byte(i)
+				break
+			}
+		}
+		rec := append([]byte{id}, []byte(f[1]+"\x00"+f[2]+"\x00\x00")...)
+		rec = append(rec, make([]byte, 6)...) // latitude, longitude
+		leaves = append(leaves, leaf{node: node, bit: int(ip[(ones-1)/8]>>(7-(ones-1)%8)) & 1, rec: rec})
+	}
+
+	segments := uint32(len(nodes))
+	// Empty records point at segments (not found); data offset 0 is reserved.
+	for i := range nodes {
+		for b := 0; b < 2; b++ {
+			if nodes[i][b] == 0 {
+				nodes[i][b] = segments
+			}
+		}
+	}
+	records := []byte{0}
+	for _, l := range leaves {
+		nodes[l.node][l.bit] = segments + uint32(len(records))
+		records = append(records, l.rec...)
+	}
+
+	var out bytes.Buffer
+	for _, n := range nodes {
+		for _, v := range n {
+			out.Write([]byte{byte(v), byte(v >> 8), byte(v >> 16)})
+		}
+	}
+	out.Write(records)
+	out.Write(make([]byte, legacyFullRecordLen))
+	out.Write([]byte{0xFF, 0xFF, 0xFF, legacyCityEditionRev1, byte(segments), byte(segments >> 8), byte(segments >> 16)})
+	return out.Bytes()
+}
+
+func writeFile(t *testing.T, dir, name string, b []byte) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func cityRecord(countryPtr pointer, city string) map[string]any {
+	return map[string]any{
+		"city":     map[string]any{"geoname_id": uint32(1835848), "names": map[string]any{"en": city, "ko": "서울"}},
+		"country":  countryPtr,
+		"location": map[string]any{"latitude": 37.5, "longitude": 127.0},
+	}
+}
+
+func testCityDB(t *testing.T, ipVersion int) []byte {
+	networks := []testNetwork{
+		{"1.2.3.0/24", cityRecord(0, "Seoul")},
+		{"8.8.8.0/24", map[string]any{"country": map[string]any{"iso_code": "US"}, "registered_country": map[string]any{"iso_code": "US", "is_in_european_union": false}}},
+	}
+	if ipVersion == 6 {
+		networks = append(networks, testNetwork{"2001:db8::/32", cityRecord(0, "Busan")})
+	}
+	// The country map is shared through a pointer, as in real GeoIP2 files.
+	return buildMMDB(t, ipVersion, "GeoLite2-City",
+		[]any{map[string]any{"iso_code": "KR", "names": map[string]any{"en": "South Korea"}}},
+		networks)
+}
+
+// ---------------------------------------------------------------------------
+// Tests
+// ---------------------------------------------------------------------------
+
+func TestMMDBLookup(t *testing.T) {
+	for _, ipVersion := range []int{4, 6} {
+		r, err := newMMDBReader(testCityDB(t, ipVersion))
+		if err != nil {
+			t.Fatalf("ipv%d: %v", ipVersion, err)
+		}
+		if r.meta.DatabaseType != "GeoLite2-City" || r.BuildTime().Unix() != 1700000000 {
+			t.Errorf("ipv%d: metadata = %+v", ipVersion, r.meta)
+		}
+
+		rec, err := r.lookup(net.ParseIP("1.2.3.4"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := path(rec, "country", "iso_code"); got != "KR" {
+			t.Errorf("ipv%d: country = %v, want KR", ipVersion, got)
+		}
+		if got := path(rec, "city", "names", "en"); got != "Seoul" {
+			t.Errorf("ipv%d: city = %v, want Seoul", ipVersion, got)
+		}
+		if got := path(rec, "city", "geoname_id"); got != uint64(1835848) {
+			t.Errorf("ipv%d: geoname_id = %v", ipVersion, got)
+		}
+		if got := path(rec, "location", "latitude"); got != 37.5 {
+			t.Errorf("ipv%d: latitude = %v", ipVersion, got)
+		}
+
+		rec, _ = r.lookup(net.ParseIP("8.8.8.8"))
+		if got := path(rec, "country", "iso_code"); got != "US" {
+			t.Errorf("ipv%d: 8.8.8.8 country = %v", ipVersion, got)
+		}
+		if path(rec, "city") != nil {
+			t.Errorf("ipv%d: 8.8.8.8 should have no city", ipVersion)
+		}
+
+		if rec, _ := r.lookup(net.ParseIP("9.9.9.9")); rec != nil {
+			t.Errorf("ipv%d: 9.9.9.9 = %v, want nil", ipVersion, rec)
+		}
+	}
+
+	r, err := newMMDBReader(testCityDB(t, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, _ := r.lookup(net.ParseIP("2001:db8::1"))
+	if got := path(rec, "city", "names", "en"); got != "Busan" {
+		t.Errorf("ipv6 city = %v, want Busan", got)
+	}
+}
+
+func TestMMDBInvalid(t *testing.T) {
+	if _, err := newMMDBReader([]byte("not a database")); err == nil {
+		t.Error("expected error for missing metadata")
+	}
+	db := testCityDB(t, 4)
+	// Truncate the tree so the data section start is past the metadata.
+	idx := bytes.LastIndex(db, mmdbMetadataMarker)
+	if _, err := newMMDBReader(db[idx:]); err == nil {
+		t.Error("expected error for truncated tree")
+	}
+}
+
+func TestLegacyLookup(t *testing.T) {
+	r, err := newLegacyReader(buildLegacyCity(t, map[string][3]string{
+		"1.2.3.0/24": {"KR", "11", "Seoul"},
+		"5.6.7.0/24": {"DE", "16", "M\xfcnchen"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.dbType != legacyCityEditionRev1 {
+		t.Fatalf("dbType = %d", r.dbType)
+	}
+
+	cc, city, err := r.lookup(net.ParseIP("1.2.3.99"))
+	if err != nil || cc != "KR" || city != "Seoul" {
+		t.Errorf("1.2.3.99 = %q %q %v", cc, city, err)
+	}
+	cc, city, _ = r.lookup(net.ParseIP("5.6.7.8"))
+	if cc != "DE" || city != "München" {
+		t.Errorf("5.6.7.8 = %q %q", cc, city)
+	}
+	cc, city, _ = r.lookup(net.ParseIP("9.9.9.9"))
+	if cc != "" || city != "" {
+		t.Errorf("9.9.9.9 = %q %q, want empty", cc, city)
+	}
+}
+
+func TestGeoIPUtil(t *testing.T) {
+	dir := t.TempDir()
+	cityPath := writeFile(t, dir, "city.mmdb", testCityDB(t, 6))
+	asnPath := writeFile(t, dir, "asn.mmdb", buildMMDB(t, 6, "GeoLite2-ASN", nil, []testNetwork{
+		{"1.2.0.0/16", map[string]any{"autonomous_system_number": uint32(4766), "autonomous_system_organization": "Korea Telecom"}},
+	}))
+	legacyPath := writeFile(t, dir, "GeoLiteCity.dat", buildLegacyCity(t, map[string][3]string{
+		"1.2.3.0/24": {"JP", "40", "Tokyo"},
+	}))
+
+	g := New(Files{City: cityPath, ASN: asnPath, LegacyCity: legacyPath})
+	defer g.Close()
+
+	r := g.LookupResult(net.ParseIP("1.2.3.4").To4())
+	if r == nil {
+		t.Fatal("nil result")
+	}
+	if r.CountryCode != "KR" || r.City != "Seoul" || r.CityHash != util.HashString("Seoul") {
+		t.Errorf("result = %+v", r)
+	}
+	if r.ASN != 4766 || r.ASOrg != "Korea Telecom" {
+		t.Errorf("asn = %d %q", r.ASN, r.ASOrg)
+	}
+	if g.LookupResult(net.ParseIP("1.2.3.4").To4()) != r {
+		t.Error("second lookup should be served from cache")
+	}
+
+	if cc, city, hash := g.Lookup(net.ParseIP("192.168.0.1").To4()); cc != "" || city != "" || hash != 0 {
+		t.Errorf("private ip = %q %q %d", cc, city, hash)
+	}
+}
+
+func TestGeoIPUtilLegacyFallback(t *testing.T) {
+	dir := t.TempDir()
+	legacyPath := writeFile(t, dir, "GeoLiteCity.dat", buildLegacyCity(t, map[string][3]string{
+		"1.2.3.0/24": {"JP", "40", "Tokyo"},
+	}))
+
+	g := New(Files{City: filepath.Join(dir, "missing.mmdb"), LegacyCity: legacyPath})
+	cc, city, hash := g.Lookup(net.ParseIP("1.2.3.4").To4())
+	if cc != "JP" || city != "Tokyo" || hash != util.HashString("Tokyo") {
+		t.Errorf("legacy = %q %q %d", cc, city, hash)
+	}
+
+	g.Close()
+	if cc, _, _ := g.Lookup(net.ParseIP("1.2.3.5").To4()); cc != "" {
+		t.Errorf("lookup after Close = %q", cc)
+	}
+}
+
+func TestGeoIPUtilNoDatabase(t *testing.T) {
+	g := New(Files{City: filepath.Join(t.TempDir(), "missing.mmdb")})
+	if cc, city, hash := g.Lookup(net.ParseIP("1.2.3.4").To4()); cc != "" || city != "" || hash != 0 {
+		t.Errorf("no database = %q %q %d", cc, city, hash)
+	}
+}
@@ -0,0 +1,181 @@
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// Legacy GeoIP (GeoLiteCity.dat / GeoIP.dat) reader, kept as a fallback for
+// installations that still ship the discontinued database. Only the country
+// and city editions are supported.
+
+const (
+	legacyCountryBegin      = 16776960
+	legacyStructureInfoMax  = 20
+	legacySegmentRecordLen  = 3
+	legacyStandardRecordLen = 3
+	legacyFullRecordLen     = 50
+
+	legacyCountryEdition   = 1
+	legacyCityEditionRev1  = 2
+	legacyCityEditionRev0  = 6
+	legacyEditionIDOffset  = 105
+	legacyEditionIDVersion = 106
+)
+
+// legacyCountryCodes is the country table of the legacy format, indexed by the
+// country id stored in the database.
+var legacyCountryCodes = [...]string{
+	"--", "AP", "EU", "AD", "AE", "AF", "AG", "AI", "AL", "AM", "CW", "AO", "AQ", "AR", "AS", "AT",
+	"AU", "AW", "AZ", "BA", "BB", "BD", "BE", "BF", "BG", "BH", "BI", "BJ", "BM", "BN", "BO", "BR",
+	"BS", "BT", "BV", "BW", "BY", "BZ", "CA", "CC", "CD", "CF", "CG", "CH", "CI", "CK", "CL", "CM",
+	"CN", "CO", "CR", "CU", "CV", "CX", "CY", "CZ", "DE", "DJ", "DK", "DM", "DO", "DZ", "EC", "EE",
+	"EG", "EH", "ER", "ES", "ET", "FI", "FJ", "FK", "FM", "FO", "FR", "SX", "GA", "GB", "GD", "GE",
+	"GF", "GH", "GI", "GL", "GM", "GN", "GP", "GQ", "GR", "GS", "GT", "GU", "GW", "GY", "HK", "HM",
+	"HN", "HR", "HT", "HU", "ID", "IE", "IL", "IN", "IO", "IQ", "IR", "IS", "IT", "JM", "JO", "JP",
+	"KE", "KG", "KH", "KI", "KM", "KN", "KP", "KR", "KW", "KY", "KZ", "LA", "LB", "LC", "LI", "LK",
+	"LR", "LS", "LT", "LU", "LV", "LY", "MA", "MC", "MD", "MG", "MH", "MK", "ML", "MM", "MN", "MO",
+	"MP", "MQ", "MR", "MS", "MT", "MU", "MV", "MW", "MX", "MY", "MZ", "NA", "NC", "NE", "NF", "NG",
+	"NI", "NL", "NO", "NP", "NR", "NU", "NZ", "OM", "PA", "PE", "PF", "PG", "PH", "PK", "PL", "PM",
+	"PN", "PR", "PS", "PT", "PW", "PY", "QA", "RE", "RO", "RU", "RW", "SA", "SB", "SC", "SD", "SE",
+	"SG", "SH", "SI", "SJ", "SK", "SL", "SM", "SN", "SO", "SR", "ST", "SV", "SY", "SZ", "TC", "TD",
+	"TF", "TG", "TH", "TJ", "TK", "TM", "TN", "TO", "TL", "TR", "TT", "TV", "TW", "TZ", "UA", "UG",
+	"UM", "US", "UY", "UZ", "VA", "VC", "VE", "VG", "VI", "VN", "VU", "WF", "WS", "YE", "YT", "RS",
+	"ZA", "ZM", "ME", "ZW", "A1", "A2", "O1", "AX", "GG", "IM", "JE", "BL", "MF", "BQ", "SS", "O1",
+}
+
+// legacyReader is an in-memory legacy GeoIP database.
+type legacyReader struct {
+	path     string
+	buf      []byte
+	dbType   int
+	segments uint32
+}
+
+func openLegacy(path string) (*legacyReader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newLegacyReader(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	r.path = path
+	return r, nil
+}
+
+func newLegacyReader(buf []byte) (*legacyReader, error) {
+	r := &legacyReader{buf: buf, dbType: legacyCountryEdition, segments: legacyCountryBegin}
+
+	// The structure info block is found by scanning backwards from the end of
+	// the file for three 0xFF bytes.
+	pos := len(buf) - 3
+	for i := 0; i < legacyStructureInfoMax && pos >= 0; i++ {
+		if buf[pos] == 0xFF && buf[pos+1] == 0xFF && buf[pos+2] == 0xFF {
+			if pos+3 >= len(buf) {
+				return nil, errors.New("geoip: truncated structure info")
+			}
+			r.dbType = int(buf[pos+3])
+			if r.dbType >= legacyEditionIDVersion {
+				r.dbType -= legacyEditionIDOffset
+			}
+			if r.dbType == legacyCityEditionRev0 || r.dbType == legacyCityEditionRev1 {
+				s := pos + 4
+				if s+legacySegmentRecordLen > len(buf) {
+					return nil, errors.New("geoip: truncated structure info")
+				}
+				r.segments = uint32(buf[s]) | uint32(buf[s+1])<<8 | uint32(buf[s+2])<<16
+			}
+			break
+		}
+		pos--
+	}
+
+	switch r.dbType {
+	case legacyCountryEdition, legacyCityEditionRev0, legacyCityEditionRev1:
+	default:
+		return nil, fmt.Errorf("geoip: unsupported legacy database type %d", r.dbType)
+	}
+	return r, nil
+}
+
+// seek walks the 32-level tree and returns the terminal record value.
+func (r *legacyReader) seek(ipnum uint32) (uint32, error) {
+	const nodeLen = 2 * legacyStandardRecordLen
+	offset := uint32(0)
+	for depth := 31; depth >= 0; depth-- {
+		p := int(offset) * nodeLen
+		if p+nodeLen > len(r.buf) {
+			return 0, errors.New("geoip: corrupt legacy database")
+		}
+		b := r.buf[p : p+nodeLen]
+		if ipnum&(1<<uint(depth)) != 0 {
+			b = b[legacyStandardRecordLen:]
+		}
+		x := uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+		if x >= r.segments {
+			return x, nil
+		}
+		offset = x
+	}
+	return 0, errors.New("geoip: corrupt legacy database")
+}
+
+// lookup returns the country code and city for an IPv4 address.
+func (r *legacyReader) lookup(ip net.IP) (countryCode, city string, err error) {
+	v4 := ip.To4()
+	if v4 == nil {
+		return "", "", nil
+	}
+	ipnum := uint32(v4[0])<<24 | uint32(v4[1])<<16 | uint32(v4[2])<<8 | uint32(v4[3])
+	x, err := r.seek(ipnum)
+	if err != nil {
+		return "", "", err
+	}
+
+	if r.dbType == legacyCountryEdition {
+		return legacyCountry(int(x - legacyCountryBegin)), "", nil
+	}
+	if x == r.segments {
+		return "", "", nil
+	}
+
+	p := int(x) + (2*legacyStandardRecordLen-1)*int(r.segments)
+	if p >= len(r.buf) {
+		return "", "", errors.New("geoip: corrupt legacy database")
+	}
+	end := p + legacyFullRecordLen
+	if end > len(r.buf) {
+		end = len(r.buf)
+	}
+	rec := r.buf[p:end]
+
+	countryCode = legacyCountry(int(rec[0]))
+	// Record: country id, region\0, city\0, postal\0, latitude, longitude, ...
+	fields := strings.SplitN(string(rec[1:]), "\x00", 3)
+	if len(fields) >= 2 {
+		city = latin1(fields[1])
+	}
+	return countryCode, city, nil
+}
+
+func legacyCountry(id int) string {
+	if id <= 0 || id >= len(legacyCountryCodes) {
+		return ""
+	}
+	return legacyCountryCodes[id]
+}
+
+// latin1 converts an ISO-8859-1 string, as stored by the legacy format, to UTF-8.
+func latin1(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		b.WriteRune(rune(s[i]))
+	}
+	return b.String()
+}
@@ -0,0 +1,383 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+// MaxMind DB (GeoIP2 / GeoLite2 .mmdb) reader.
+// Format: https://maxmind.github.io/MaxMind-DB/
+//
+// The file is a binary search tree over IP address bits, followed by 16 zero
+// bytes, a data section of typed values, and a metadata map located after the
+// "\xAB\xCD\xEFMaxMind.com" marker at the end of the file.
+
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+const (
+	mmdbMetadataMaxSize  = 128 * 1024
+	mmdbDataSeparatorLen = 16
+)
+
+// MaxMind DB data types.
+const (
+	mmdbExtended = iota
+	mmdbPointer
+	mmdbString
+	mmdbDouble
+	mmdbBytes
+	mmdbUint16
+	mmdbUint32
+	mmdbMap
+	mmdbInt32
+	mmdbUint64
+	mmdbUint128
+	mmdbArray
+	mmdbContainer
+	mmdbEndMarker
+	mmdbBool
+	mmdbFloat
+)
+
+var errMMDBCorrupt = errors.New("mmdb: invalid database")
+
+// mmdbMetadata holds the metadata fields needed for lookups.
+type mmdbMetadata struct {
+	NodeCount    uint
+	RecordSize   uint
+	IPVersion    uint
+	DatabaseType string
+	BuildEpoch   uint64
+}
+
+// mmdbReader is an in-memory MaxMind DB.
+type mmdbReader struct {
+	path      string
+	buf       []byte
+	data      []byte // data section
+	meta      mmdbMetadata
+	nodeBytes uint
+	ipv4Start uint
+}
+
+func openMMDB(path string) (*mmdbReader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newMMDBReader(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	r.path = path
+	return r, nil
+}
+
+func newMMDBReader(buf []byte) (*mmdbReader, error) {
+	searchFrom := len(buf) - mmdbMetadataMaxSize
+	if searchFrom < 0 {
+		searchFrom = 0
+	}
+	idx := bytes.LastIndex(buf[searchFrom:], mmdbMetadataMarker)
+	if idx < 0 {
+		return nil, errors.New("mmdb: metadata marker not found")
+	}
+	metaStart := searchFrom + idx + len(mmdbMetadataMarker)
+
+	metaDec := mmdbDecoder{buf: buf[metaStart:]}
+	v, _, err := metaDec.decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("mmdb: metadata: %w", err)
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("mmdb: metadata is not a map")
+	}
+
+	r := &mmdbReader{buf: buf}
+	r.meta.NodeCount = uint(toUint64(m["node_count"]))
+	r.meta.RecordSize = uint(toUint64(m["record_size"]))
+	r.meta.IPVersion = uint(toUint64(m["ip_version"]))
+	r.meta.BuildEpoch = toUint64(m["build_epoch"])
+	r.meta.DatabaseType, _ = m["database_type"].(string)
+
+	switch r.meta.RecordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("mmdb: unsupported record size %d", r.meta.RecordSize)
+	}
+	if r.meta.IPVersion != 4 && r.meta.IPVersion != 6 {
+		return nil, fmt.Errorf("mmdb: unsupported ip version %d", r.meta.IPVersion)
+	}
+	r.nodeBytes = r.meta.RecordSize / 4
+	treeSize := r.meta.NodeCount * r.nodeBytes
+	dataStart := treeSize + mmdbDataSeparatorLen
+	if dataStart > uint(searchFrom+idx) {
+		return nil, errMMDBCorrupt
+	}
+	r.data = buf[dataStart : searchFrom+idx]
+
+	// IPv4 addresses live under ::/96 in an IPv6 tree.
+	if r.meta.IPVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < r.meta.NodeCount; i++ {
+			node = r.readRecord(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// BuildTime returns the database build time.
+func (r *mmdbReader) BuildTime() time.Time {
+	return time.Unix(int64(r.meta.BuildEpoch), 0)
+}
+
+func (r *mmdbReader) readRecord(node uint, bit uint) uint {
+	b := r.buf[node*r.nodeBytes:]
+	switch r.meta.RecordSize {
+	case 24:
+		off := bit * 3
+		return uint(b[off])<<16 | uint(b[off+1])<<8 | uint(b[off+2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		off := bit * 4
+		return uint(binary.BigEndian.Uint32(b[off:]))
+	}
+}
+
+// lookup returns the decoded record for ip, or nil if the address is not in
+// the database.
+func (r *mmdbReader) lookup(ip net.IP) (any, error) {
+	addr := ip.To4()
+	node := uint(0)
+	if addr != nil {
+		node = r.ipv4Start
+	} else {
+		if r.meta.IPVersion == 4 {
+			return nil, nil
+		}
+		addr = ip.To16()
+		if addr == nil {
+			return nil, nil
+		}
+	}
+
+	nodeCount := r.meta.NodeCount
+	bitCount := uint(len(addr) * 8)
+	for i := uint(0); i < bitCount && node < nodeCount; i++ {
+		bit := uint(addr[i>>3]>>(7-(i&7))) & 1
+		node = r.readRecord(node, bit)
+	}
+	if node <= nodeCount {
+		return nil, nil
+	}
+
+	offset := node - nodeCount - mmdbDataSeparatorLen
+	if offset >= uint(len(r.data)) {
+		return nil, errMMDBCorrupt
+	}
+	dec := mmdbDecoder{buf: r.data}
+	v, _, err := dec.decode(offset, 0)
+	return v, err
+}
+
+// mmdbDecoder decodes values of a data section. Pointers are offsets from the
+// start of buf.
+type mmdbDecoder struct {
+	buf []byte
+}
+
+// maxPointerDepth bounds pointer chains in corrupt files.
+const maxPointerDepth = 32
+
+func (d *mmdbDecoder) decode(offset uint, depth int) (any, uint, error) {
+	if depth > maxPointerDepth {
+		return nil, 0, errMMDBCorrupt
+	}
+	if offset >= uint(len(d.buf)) {
+		return nil, 0, errMMDBCorrupt
+	}
+	ctrl := d.buf[offset]
+	offset++
+	typ := int(ctrl >> 5)
+
+	if typ == mmdbPointer {
+		ptr, next, err := d.decodePointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, _, err := d.decode(ptr, depth+1)
+		return v, next, err
+	}
+
+	if typ == mmdbExtended {
+		if offset >= uint(len(d.buf)) {
+			return nil, 0, errMMDBCorrupt
+		}
+		typ = 7 + int(d.buf[offset])
+		offset++
+	}
+
+	size := uint(ctrl & 0x1F)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d.buf)) {
+			return nil, 0, errMMDBCorrupt
+		}
+		v := uint(0)
+		for _, b := range d.buf[offset : offset+n] {
+			v = v<<8 | uint(b)
+		}
+		offset += n
+		switch size {
+		case 29:
+			size = 29 + v
+		case 30:
+			size = 285 + v
+		default:
+			size = 65821 + v
+		}
+	}
+
+	switch typ {
+	case mmdbMap:
+		m := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			k, next, err := d.decode(offset, depth)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errMMDBCorrupt
+			}
+			v, next, err := d.decode(next, depth)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[key] = v
+			offset = next
+		}
+		return m, offset, nil
+	case mmdbArray:
+		a := make([]any, 0, size)
+		for i := uint(0); i < size; i++ {
+			v, next, err := d.decode(offset, depth)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, v)
+			offset = next
+		}
+		return a, offset, nil
+	case mmdbBool:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, errMMDBCorrupt
+	}
+	b := d.buf[offset : offset+size]
+	next := offset + size
+	switch typ {
+	case mmdbString:
+		return string(b), next, nil
+	case mmdbBytes:
+		return append([]byte(nil), b...), next, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, errMMDBCorrupt
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, errMMDBCorrupt
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(b)), next, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64:
+		if size > 8 {
+			return nil, 0, errMMDBCorrupt
+		}
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, next, nil
+	case mmdbInt32:
+		if size > 4 {
+			return nil, 0, errMMDBCorrupt
+		}
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return int32(v), next, nil
+	case mmdbUint128:
+		if size > 16 {
+			return nil, 0, errMMDBCorrupt
+		}
+		return new(big.Int).SetBytes(b), next, nil
+	default:
+		return nil, 0, fmt.Errorf("mmdb: unexpected data type %d", typ)
+	}
+}
+
+func (d *mmdbDecoder) decodePointer(ctrl byte, offset uint) (uint, uint, error) {
+	n := uint((ctrl>>3)&0x3) + 1
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, errMMDBCorrupt
+	}
+	b := d.buf[offset : offset+n]
+	var ptr uint
+	if n == 4 {
+		ptr = uint(binary.BigEndian.Uint32(b))
+	} else {
+		ptr = uint(ctrl & 0x7)
+		for _, c := range b {
+			ptr = ptr<<8 | uint(c)
+		}
+		switch n {
+		case 2:
+			ptr += 2048
+		case 3:
+			ptr += 526336
+		}
+	}
+	return ptr, offset + n, nil
+}
+
+func toUint64(v any) uint64 {
+	switch n := v.(type) {
+	case uint64:
+		return n
+	case int32:
+		return uint64(n)
+	case *big.Int:
+		return n.Uint64()
+	}
+	return 0
+}
+
+// path walks nested maps, e.g. path(rec, "city", "names", "en").
+func path(v any, keys ...string) any {
+	for _, k := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
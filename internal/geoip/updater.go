@@ -0,0 +1,233 @@
+package geoip
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultUpdateURL is the MaxMind download endpoint. {edition} is replaced by
+// the edition ID; the account ID and license key are sent as basic auth.
+const DefaultUpdateURL = "https://download.maxmind.com/geoip/databases/{edition}/download?suffix=tar.gz"
+
+const (
+	// maxDownloadSize bounds a single database download.
+	maxDownloadSize = 512 << 20
+	// staleAfter is the database age after which a warning is logged.
+	staleAfter = 30 * 24 * time.Hour
+)
+
+// UpdaterConfig configures periodic database downloads.
+type UpdaterConfig struct {
+	URL        string // download URL template, DefaultUpdateURL if empty
+	AccountID  string
+	LicenseKey string
+	Interval   time.Duration
+
+	CityEdition string // e.g. GeoLite2-City; empty skips the city database
+	CityFile    string
+	ASNEdition  string // e.g. GeoLite2-ASN; empty skips the ASN database
+	ASNFile     string
+}
+
+type updateTarget struct {
+	kind    string
+	edition string
+	path    string
+}
+
+// Updater periodically downloads fresh MaxMind databases, writes them next to
+// the configured files and swaps them into a GeoIPUtil without a restart.
+type Updater struct {
+	geo      *GeoIPUtil
+	client   *http.Client
+	cfg      UpdaterConfig
+	interval time.Duration
+	targets  []updateTarget
+}
+
+// NewUpdater creates an Updater for g.
+func NewUpdater(g *GeoIPUtil, cfg UpdaterConfig) *Updater {
+	if cfg.URL == "" {
+		cfg.URL = DefaultUpdateURL
+	}
+	u := &Updater{
+		geo:      g,
+		client:   &http.Client{Timeout: 10 * time.Minute},
+		cfg:      cfg,
+		interval: cfg.Interval,
+	}
+	if u.interval <= 0 {
+		u.interval = 24 * time.Hour
+	}
+	if cfg.CityEdition != "" && cfg.CityFile != "" {
+		u.targets = append(u.targets, updateTarget{"city", cfg.CityEdition, cfg.CityFile})
+	}
+	if cfg.ASNEdition != "" && cfg.ASNFile != "" {
+		u.targets = append(u.targets, updateTarget{"asn", cfg.ASNEdition, cfg.ASNFile})
+	}
+	return u
+}
+
+// Start runs an update immediately and then every interval in the background.
+func (u *Updater) Start(ctx context.Context) {
+	go func() {
+		u.Update(ctx)
+
+		ticker := time.NewTicker(u.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				u.Update(ctx)
+			}
+		}
+	}()
+}
+
+// Update downloads every configured edition once. Failures are logged and
+// the current database is kept.
+func (u *Updater) Update(ctx context.Context) {
+	for _, t := range u.targets {
+		if err := u.update(ctx, t); err != nil {
+			slog.Warn("GeoIP update failed", "edition", t.edition, "error", err)
+		}
+	}
+	u.geo.warnIfStale()
+}
+
+func (u *Updater) update(ctx context.Context, t updateTarget) error {
+	url := strings.NewReplacer("{edition}", t.edition, "{license_key}", u.cfg.LicenseKey).Replace(u.cfg.URL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if u.cfg.AccountID != "" || u.cfg.LicenseKey != "" {
+		req.SetBasicAuth(u.cfg.AccountID, u.cfg.LicenseKey)
+	}
+	if st, err := os.Stat(t.path); err == nil {
+		req.Header.Set("If-Modified-Since", st.ModTime().UTC().Format(http.TimeFormat))
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		slog.Debug("GeoIP database up to date", "edition", t.edition)
+		return nil
+	default:
+		return fmt.Errorf("download: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > maxDownloadSize {
+		return fmt.Errorf("download exceeds %d bytes", maxDownloadSize)
+	}
+	db, err := extractMMDB(body)
+	if err != nil {
+		return err
+	}
+	r, err := newMMDBReader(db)
+	if err != nil {
+		return err
+	}
+	r.path = t.path
+
+	if cur := u.geo.database(t.kind); cur != nil && r.meta.BuildEpoch < cur.meta.BuildEpoch {
+		return fmt.Errorf("downloaded build %s is older than the loaded build %s",
+			r.BuildTime().UTC().Format("2006-01-02"), cur.BuildTime().UTC().Format("2006-01-02"))
+	}
+
+	if err := writeFileAtomic(t.path, db); err != nil {
+		return err
+	}
+	if lm, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		os.Chtimes(t.path, lm, lm)
+	}
+
+	u.geo.swap(t.kind, r)
+	logDatabase(t.kind, r)
+	return nil
+}
+
+// extractMMDB returns the .mmdb file from a download, which is either a
+// tar.gz archive (as served by MaxMind), a gzipped mmdb, or a raw mmdb.
+func extractMMDB(body []byte) ([]byte, error) {
+	if len(body) < 2 || body[0] != 0x1f || body[1] != 0x8b {
+		return body, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(zr, maxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDownloadSize {
+		return nil, fmt.Errorf("database exceeds %d bytes", maxDownloadSize)
+	}
+
+	tr := tar.NewReader(bytes.NewReader(data))
+	hdr, err := tr.Next()
+	if err != nil {
+		// Not a tar archive.
+		return data, nil
+	}
+	for ; err == nil; hdr, err = tr.Next() {
+		if hdr.Typeflag == tar.TypeReg && strings.HasSuffix(hdr.Name, ".mmdb") {
+			return io.ReadAll(tr)
+		}
+	}
+	if !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return nil, errors.New("no .mmdb file in archive")
+}
+
+// writeFileAtomic writes data to a temp file in the target directory and
+// renames it over path.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
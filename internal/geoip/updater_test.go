@@ -0,0 +1,155 @@
+package geoip
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func tarGz(t *testing.T, name string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	tw.WriteHeader(&tar.Header{Name: "GeoLite2-City_20240101/", Typeflag: tar.TypeDir, Mode: 0755})
+	tw.WriteHeader(&tar.Header{Name: "GeoLite2-City_20240101/COPYRIGHT.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 3})
+	tw.Write([]byte("(c)"))
+	tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(data))})
+	tw.Write(data)
+	tw.Close()
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestExtractMMDB(t *testing.T) {
+	db := testCityDB(t, 4)
+
+	got, err := extractMMDB(tarGz(t, "GeoLite2-City_20240101/GeoLite2-City.mmdb", db))
+	if err != nil || !bytes.Equal(got, db) {
+		t.Errorf("tar.gz: err=%v equal=%v", err, bytes.Equal(got, db))
+	}
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(db)
+	zw.Close()
+	if got, err := extractMMDB(gz.Bytes()); err != nil || !bytes.Equal(got, db) {
+		t.Errorf("gzip: err=%v", err)
+	}
+
+	if got, err := extractMMDB(db); err != nil || !bytes.Equal(got, db) {
+		t.Errorf("raw: err=%v", err)
+	}
+
+	if _, err := extractMMDB(tarGz(t, "README.txt", []byte("x"))); err == nil {
+		t.Error("expected error for archive without mmdb")
+	}
+}
+
+func TestUpdaterSwapsDatabase(t *testing.T) {
+	newDB := buildMMDB(t, 6, "GeoLite2-City", nil, []testNetwork{
+		{"1.2.3.0/24", map[string]any{"country": map[string]any{"iso_code": "JP"}, "city": map[string]any{"names": map[string]any{"en": "Osaka"}}}},
+	})
+	lastModified := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if user, pass, ok := r.BasicAuth(); !ok || user != "1234" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/GeoLite2-City" {
+			http.NotFound(w, r)
+			return
+		}
+		if ims, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !lastModified.After(ims) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		w.Write(tarGz(t, "GeoLite2-City_20240102/GeoLite2-City.mmdb", newDB))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cityPath := writeFile(t, dir, "GeoLite2-City.mmdb", testCityDB(t, 6))
+	old := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	os.Chtimes(cityPath, old, old)
+
+	g := New(Files{City: cityPath})
+	defer g.Close()
+	ip := net.ParseIP("1.2.3.4").To4()
+	if _, city, _ := g.Lookup(ip); city != "Seoul" {
+		t.Fatalf("initial city = %q", city)
+	}
+
+	u := NewUpdater(g, UpdaterConfig{
+		URL:         srv.URL + "/{edition}",
+		AccountID:   "1234",
+		LicenseKey:  "secret",
+		CityEdition: "GeoLite2-City",
+		CityFile:    cityPath,
+	})
+	u.Update(context.Background())
+
+	if cc, city, _ := g.Lookup(ip); cc != "JP" || city != "Osaka" {
+		t.Errorf("after update = %q %q, want JP Osaka", cc, city)
+	}
+	onDisk, err := os.ReadFile(cityPath)
+	if err != nil || !bytes.Equal(onDisk, newDB) {
+		t.Errorf("database file not replaced: %v", err)
+	}
+	if st, _ := os.Stat(cityPath); !st.ModTime().Equal(lastModified) {
+		t.Errorf("mtime = %v, want %v", st.ModTime(), lastModified)
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(tmp) != 0 {
+		t.Errorf("temp files left: %v", tmp)
+	}
+
+	// Second run is answered with 304 and keeps the loaded reader.
+	before := g.database("city")
+	u.Update(context.Background())
+	if requests.Load() != 2 {
+		t.Errorf("requests = %d, want 2", requests.Load())
+	}
+	if g.database("city") != before {
+		t.Error("reader swapped on 304")
+	}
+}
+
+func TestUpdaterKeepsDatabaseOnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not a database"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	orig := testCityDB(t, 6)
+	cityPath := writeFile(t, dir, "GeoLite2-City.mmdb", orig)
+	g := New(Files{City: cityPath})
+	defer g.Close()
+
+	NewUpdater(g, UpdaterConfig{
+		URL:         srv.URL + "/{edition}",
+		LicenseKey:  "secret",
+		CityEdition: "GeoLite2-City",
+		CityFile:    cityPath,
+	}).Update(context.Background())
+
+	if _, city, _ := g.Lookup(net.ParseIP("1.2.3.4").To4()); city != "Seoul" {
+		t.Errorf("city = %q, want Seoul", city)
+	}
+	if onDisk, _ := os.ReadFile(cityPath); !bytes.Equal(onDisk, orig) {
+		t.Error("database file overwritten with invalid download")
+	}
+}
@@ -0,0 +1,268 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// metricsInterval is how often the request counters are stored under
+// net_http_api_metrics_obj_name.
+const metricsInterval = 10 * time.Second
+
+// requestInfo carries what inner middleware learns about a request back to
+// the access log.
+type requestInfo struct {
+	account string
+}
+
+type requestInfoKey struct{}
+
+// setAccount records the authenticated account of r for the access log.
+func setAccount(r *http.Request, account string) {
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		info.account = account
+	}
+}
+
+// requestAccount returns the account r was authenticated as, "" if none.
+func requestAccount(r *http.Request) string {
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		return info.account
+	}
+	return ""
+}
+
+// statusRecorder remembers the status and size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack takes over the connection for a WebSocket stream, which is logged
+// with status 101 once it ends.
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// endpointStats accumulates the requests of one endpoint.
+type endpointStats struct {
+	count   int64
+	errors  int64 // responses with status >= 500
+	total   time.Duration
+	maxTime time.Duration
+}
+
+func (e *endpointStats) add(status int, elapsed time.Duration) {
+	e.count++
+	if status >= 500 {
+		e.errors++
+	}
+	e.total += elapsed
+	e.maxTime = max(e.maxTime, elapsed)
+}
+
+// EndpointMetrics is the JSON form of the counters of one endpoint.
+type EndpointMetrics struct {
+	Endpoint  string  `json:"endpoint"`
+	Count     int64   `json:"count"`
+	Errors    int64   `json:"errors"`
+	AvgTimeMs float64 `json:"avgTimeMs"`
+	MaxTimeMs float64 `json:"maxTimeMs"`
+}
+
+// httpMetrics counts requests per endpoint since startup, and in total since
+// the counters were last stored.
+type httpMetrics struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpointStats
+	interval  endpointStats
+}
+
+func newHTTPMetrics() *httpMetrics {
+	return &httpMetrics{endpoints: make(map[string]*endpointStats)}
+}
+
+func (m *httpMetrics) observe(endpoint string, status int, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.endpoints[endpoint]
+	if e == nil {
+		e = &endpointStats{}
+		m.endpoints[endpoint] = e
+	}
+	e.add(status, elapsed)
+	m.interval.add(status, elapsed)
+}
+
+// snapshot returns the counters of every endpoint, sorted by endpoint.
+func (m *httpMetrics) snapshot() []EndpointMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]EndpointMetrics, 0, len(m.endpoints))
+	for name, e := range m.endpoints {
+		result = append(result, EndpointMetrics{
+			Endpoint:  name,
+			Count:     e.count,
+			Errors:    e.errors,
+			AvgTimeMs: durationMs(e.total) / float64(e.count),
+			MaxTimeMs: durationMs(e.maxTime),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Endpoint < result[j].Endpoint })
+	return result
+}
+
+// drain returns the totals since the previous drain and resets them.
+func (m *httpMetrics) drain() endpointStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.interval
+	m.interval = endpointStats{}
+	return e
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// accessMiddleware counts every request under its route pattern and, with
+// log_http_access_enabled, logs it. Requests matching no route are counted
+// as "other" so unknown paths cannot grow the endpoint table. /health is
+// counted but not logged, since load balancers poll it.
+func (s *Server) accessMiddleware(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		info := &requestInfo{}
+		r = r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info))
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		elapsed := time.Since(start)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		endpoint := "other"
+		if _, pattern := mux.Handler(r); pattern != "" {
+			endpoint = r.Method + " " + pattern
+		}
+		s.metrics.observe(endpoint, rec.status, elapsed)
+
+		if r.URL.Path == "/health" {
+			return
+		}
+		if cfg := config.Get(); cfg != nil && !cfg.LogHTTPAccessEnabled() {
+			return
+		}
+		slog.Info("HTTP access",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"elapsedMs", elapsed.Milliseconds(),
+			"bytes", rec.bytes,
+			"account", info.account,
+			"remote", extractIP(r.RemoteAddr))
+	})
+}
+
+// reportMetrics stores the request totals of every interval as the
+// HttpRequests, HttpErrors, HttpElapsed and HttpMaxElapsed counters of the
+// object named by net_http_api_metrics_obj_name, alongside the agents'
+// counters.
+func (s *Server) reportMetrics(ctx context.Context) {
+	ticker := time.NewTicker(metricsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		e := s.metrics.drain()
+		cfg := config.Get()
+		if cfg == nil || cfg.NetHTTPApiMetricsObjName() == "" {
+			continue
+		}
+		for _, p := range metricsPacks(cfg.NetHTTPApiMetricsObjName(), e) {
+			s.ingest(p)
+		}
+	}
+}
+
+// metricsPacks builds the object and counter packs for one interval.
+func metricsPacks(objName string, e endpointStats) []pack.Pack {
+	avg := 0.0
+	if e.count > 0 {
+		avg = durationMs(e.total) / float64(e.count)
+	}
+	tags := value.NewMapValue()
+	tags.Put(pack.TagDeadTime, value.NewDecimalValue(3*metricsInterval.Milliseconds()))
+	data := value.NewMapValue()
+	data.Put("HttpRequests", value.NewDecimalValue(e.count))
+	data.Put("HttpErrors", value.NewDecimalValue(e.errors))
+	data.Put("HttpElapsed", &value.DoubleValue{Value: avg})
+	data.Put("HttpMaxElapsed", &value.DoubleValue{Value: durationMs(e.maxTime)})
+	return []pack.Pack{
+		&pack.ObjectPack{
+			ObjType: "scouter",
+			ObjHash: util.HashString(objName),
+			ObjName: objName,
+			Version: "http",
+			Alive:   true,
+			Tags:    tags,
+		},
+		&pack.PerfCounterPack{
+			Time:     time.Now().UnixMilli(),
+			ObjName:  objName,
+			TimeType: cache.TimeTypeRealtime,
+			Data:     data,
+		},
+	}
+}
+
+// handleHTTPStats returns the request counters of every endpoint since startup.
+func (s *Server) handleHTTPStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"endpoints": s.metrics.snapshot(),
+	})
+}
@@ -0,0 +1,236 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/alertrule"
+	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+const (
+	// alertPreviewMaxDaily bounds the period replayed on 5-minute data.
+	alertPreviewMaxDaily = 31 * 24 * time.Hour
+	// alertPreviewMaxRealtime bounds the period replayed on realtime data.
+	alertPreviewMaxRealtime = 24 * time.Hour
+	// alertPreviewMaxObjects bounds the objects one preview reads.
+	alertPreviewMaxObjects = 200
+	// alertPreviewRealtimeGap is the longest gap between realtime samples
+	// that does not break a condition.
+	alertPreviewRealtimeGap = 30 * time.Second
+)
+
+// alertPreviewRequest is the body of POST /api/v1/alert/preview.
+type alertPreviewRequest struct {
+	alertrule.Rule
+	// ObjHash and ObjType select the objects; objects of ObjType are
+	// looked up among the known objects.
+	ObjHash []int32 `json:"objHash"`
+	ObjType string  `json:"objType"`
+	// From and To bound the period, in epoch milliseconds or RFC3339.
+	From apiTime `json:"from"`
+	To   apiTime `json:"to"`
+	// Source is "daily" (5-minute values, default) or "realtime".
+	Source string `json:"source"`
+}
+
+type alertPreviewObject struct {
+	ObjHash int32                `json:"objHash"`
+	ObjName string               `json:"objName,omitempty"`
+	Samples int                  `json:"samples"`
+	Firings []alertPreviewFiring `json:"firings"`
+}
+
+// alertPreviewFiring is a firing with its times also as RFC3339.
+type alertPreviewFiring struct {
+	alertrule.Firing
+	SinceIso string `json:"sinceIso"`
+	StartIso string `json:"startIso"`
+	EndIso   string `json:"endIso"`
+}
+
+// handleAlertPreview replays a counter alert rule over stored counter data
+// and returns, per object, when it would have fired. The optional tz query
+// parameter selects the zone of the RFC3339 times in the response.
+func (s *Server) handleAlertPreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var req alertPreviewRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxWriteBody)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	if err := req.Rule.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Source == "" {
+		req.Source = "daily"
+	}
+	maxPeriod := alertPreviewMaxDaily
+	switch req.Source {
+	case "daily":
+	case "realtime":
+		maxPeriod = alertPreviewMaxRealtime
+	default:
+		writeError(w, http.StatusBadRequest, "invalid source: use daily or realtime")
+		return
+	}
+	from, to := time.UnixMilli(int64(req.From)), time.UnixMilli(int64(req.To))
+	if req.From <= 0 || !to.After(from) {
+		writeError(w, http.StatusBadRequest, "from and to must be epoch milliseconds or RFC3339 with from before to")
+		return
+	}
+	if to.Sub(from) > maxPeriod {
+		writeError(w, http.StatusBadRequest, "period too long for source "+req.Source+": at most "+maxPeriod.String())
+		return
+	}
+
+	objHashes := s.previewObjects(req.ObjHash, req.ObjType)
+	if len(objHashes) == 0 {
+		writeError(w, http.StatusBadRequest, "no objects selected: give objHash or a known objType")
+		return
+	}
+	if len(objHashes) > alertPreviewMaxObjects {
+		writeError(w, http.StatusBadRequest, "too many objects selected")
+		return
+	}
+
+	objects := make([]alertPreviewObject, 0, len(objHashes))
+	fired := 0
+	for _, objHash := range objHashes {
+		var samples []alertrule.Sample
+		var maxGap int64
+		if req.Source == "realtime" {
+			samples = s.realtimeSamples(objHash, req.Counter, from, to)
+			maxGap = alertPreviewRealtimeGap.Milliseconds()
+		} else {
+			samples = s.dailySamples(objHash, req.Counter, from, to)
+		}
+		firings := make([]alertPreviewFiring, 0)
+		for _, f := range req.Rule.Evaluate(samples, maxGap) {
+			firings = append(firings, alertPreviewFiring{
+				Firing:   f,
+				SinceIso: isoTime(f.SinceMs, loc),
+				StartIso: isoTime(f.StartMs, loc),
+				EndIso:   isoTime(f.EndMs, loc),
+			})
+		}
+		fired += len(firings)
+		obj := alertPreviewObject{ObjHash: objHash, Samples: len(samples), Firings: firings}
+		if s.objectCache != nil {
+			if info, ok := s.objectCache.Get(objHash); ok {
+				obj.ObjName = info.Pack.ObjName
+			}
+		}
+		objects = append(objects, obj)
+	}
+	writeJSON(w, map[string]interface{}{
+		"rule":    req.Rule,
+		"source":  req.Source,
+		"from":    req.From,
+		"fromIso": isoTime(int64(req.From), loc),
+		"to":      req.To,
+		"toIso":   isoTime(int64(req.To), loc),
+		"fired":   fired,
+		"objects": objects,
+	})
+}
+
+// previewObjects returns the listed objects and the known objects of objType.
+func (s *Server) previewObjects(listed []int32, objType string) []int32 {
+	seen := make(map[int32]bool)
+	var out []int32
+	add := func(h int32) {
+		if !seen[h] {
+			seen[h] = true
+			out = append(out, h)
+		}
+	}
+	for _, h := range listed {
+		add(h)
+	}
+	if objType != "" && s.objectCache != nil {
+		for _, info := range s.objectCache.GetAll() {
+			if info.Pack.ObjType == objType {
+				add(info.Pack.ObjHash)
+			}
+		}
+	}
+	return out
+}
+
+// dailySamples returns the 5-minute values of a counter between from and to.
+func (s *Server) dailySamples(objHash int32, counterName string, from, to time.Time) []alertrule.Sample {
+	var samples []alertrule.Sample
+	bucket := time.Duration(24*60/counter.BucketsPerDay) * time.Minute
+	for day := startOfDay(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+		values, err := s.counterRD.ReadDailyAll(day.Format("20060102"), objHash, counterName)
+		if err != nil || values == nil {
+			continue
+		}
+		for i, v := range values {
+			t := day.Add(time.Duration(i) * bucket)
+			if t.Before(from) || !t.Before(to) {
+				continue
+			}
+			samples = append(samples, alertrule.Sample{TimeMs: t.UnixMilli(), Value: v})
+		}
+	}
+	return samples
+}
+
+// realtimeSamples returns the realtime values of a counter between from and to.
+func (s *Server) realtimeSamples(objHash int32, counterName string, from, to time.Time) []alertrule.Sample {
+	var samples []alertrule.Sample
+	for day := startOfDay(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+		startSec, endSec := int32(0), int32(24*60*60-1)
+		if from.After(day) {
+			startSec = int32(from.Sub(day) / time.Second)
+		}
+		if next := day.AddDate(0, 0, 1); to.Before(next) {
+			endSec = int32((to.Sub(day) - time.Millisecond) / time.Second)
+		}
+		s.counterRD.ReadRealtimeRange(day.Format("20060102"), objHash, startSec, endSec,
+			func(timeSec int32, counters map[string]value.Value) {
+				if v, ok := counters[counterName]; ok {
+					samples = append(samples, alertrule.Sample{
+						TimeMs: day.Add(time.Duration(timeSec) * time.Second).UnixMilli(),
+						Value:  numericValue(v),
+					})
+				}
+			})
+	}
+	// The values of an object's sources come first.
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].TimeMs < samples[j].TimeMs })
+	return samples
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// numericValue returns the value of a numeric counter, NaN for other values.
+func numericValue(v value.Value) float64 {
+	switch n := v.(type) {
+	case *value.DecimalValue:
+		return float64(n.Value)
+	case *value.FloatValue:
+		return float64(n.Value)
+	case *value.DoubleValue:
+		return n.Value
+	}
+	return math.NaN()
+}
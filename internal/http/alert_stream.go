@@ -0,0 +1,183 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// alertEventJSON is the data of one alert event of /api/v1/alert/stream.
+type alertEventJSON struct {
+	Time    int64                  `json:"time"`
+	TimeIso string                 `json:"timeIso"`
+	Level   string                 `json:"level"`
+	ObjType string                 `json:"objType"`
+	ObjHash int32                  `json:"objHash"`
+	ObjName string                 `json:"objName,omitempty"`
+	Title   string                 `json:"title"`
+	Message string                 `json:"message"`
+	Tags    map[string]interface{} `json:"tags,omitempty"`
+}
+
+// handleAlertStream streams alerts as Server-Sent Events:
+//
+//	GET /api/v1/alert/stream?objType=tomcat&level=WARN
+//
+// Every alert added to the realtime cache from now on is sent as an "alert"
+// event, objType and level (the lowest, INFO by default) limiting them.
+// Each event's id is the alert's sequence number; a client reconnecting
+// with the Last-Event-ID header, or the lastEventId parameter, first
+// receives the alerts it missed that are still cached. A comment is sent
+// when nothing was for a while, so proxies keep the stream open.
+func (s *Server) handleAlertStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	objType := q.Get("objType")
+	minLevel := byte(0)
+	if v := q.Get("level"); v != "" {
+		level, ok := alertLevels[strings.ToUpper(v)]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "invalid level: use INFO, WARN, ERROR or FATAL")
+			return
+		}
+		minLevel = level
+	}
+	seq := s.alertCache.Seq()
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = q.Get("lastEventId")
+	}
+	if lastID != "" {
+		n, err := strconv.ParseInt(lastID, 10, 64)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid Last-Event-ID: must be a non-negative integer")
+			return
+		}
+		seq = n
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	interval, ok := s.acquireStream(w)
+	if !ok {
+		return
+	}
+	defer s.releaseStream()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	// send writes one chunk of the stream, giving up on a client that does
+	// not read it in time.
+	lastWrite := time.Now()
+	send := func(chunk string) error {
+		rc.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			return err
+		}
+		lastWrite = time.Now()
+		return rc.Flush()
+	}
+	if err := send("retry: 3000\n\n"); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.streamsClosing:
+			return
+		case now := <-ticker.C:
+			alerts, first := s.alertCache.Since(seq)
+			var b strings.Builder
+			for i, data := range alerts {
+				seq = first + int64(i) + 1
+				ap := decodeAlert(data)
+				if ap == nil || ap.Level < minLevel || (objType != "" && ap.ObjType != objType) {
+					continue
+				}
+				event, _ := json.Marshal(s.toAlertEventJSON(ap, loc))
+				fmt.Fprintf(&b, "id: %d\nevent: alert\ndata: %s\n\n", seq, event)
+			}
+			chunk := b.String()
+			if chunk == "" && now.Sub(lastWrite) >= wsPingInterval {
+				chunk = ": ping\n\n"
+			}
+			if chunk != "" {
+				if err := send(chunk); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func decodeAlert(data []byte) *pack.AlertPack {
+	p, err := pack.ReadPack(protocol.NewDataInputX(data))
+	if err != nil {
+		return nil
+	}
+	ap, _ := p.(*pack.AlertPack)
+	return ap
+}
+
+func (s *Server) toAlertEventJSON(ap *pack.AlertPack, loc *time.Location) alertEventJSON {
+	e := alertEventJSON{
+		Time:    ap.Time,
+		TimeIso: isoTime(ap.Time, loc),
+		Level:   strconv.Itoa(int(ap.Level)),
+		ObjType: ap.ObjType,
+		ObjHash: ap.ObjHash,
+		ObjName: s.counterObjName(ap.ObjHash),
+		Title:   ap.Title,
+		Message: ap.Message,
+	}
+	for name, level := range alertLevels {
+		if level == ap.Level {
+			e.Level = name
+		}
+	}
+	if ap.Tags != nil && len(ap.Tags.Entries) > 0 {
+		e.Tags = make(map[string]interface{}, len(ap.Tags.Entries))
+		for _, entry := range ap.Tags.Entries {
+			e.Tags[entry.Key] = tagToJSON(entry.Value)
+		}
+	}
+	return e
+}
+
+// tagToJSON converts an alert tag value. Lists, such as the transaction IDs
+// of an XLog alert, hold 64-bit decimals as strings.
+func tagToJSON(v value.Value) interface{} {
+	list, ok := v.(*value.ListValue)
+	if !ok {
+		return valueToNumber(v)
+	}
+	items := make([]interface{}, 0, len(list.Value))
+	for _, item := range list.Value {
+		if dv, ok := item.(*value.DecimalValue); ok {
+			items = append(items, strconv.FormatInt(dv.Value, 10))
+		} else {
+			items = append(items, valueToNumber(item))
+		}
+	}
+	return items
+}
@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/zbum/scouter-server-go/internal/apitoken"
 	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/login"
 )
@@ -60,14 +61,15 @@ func (s *HTTPSessionStore) create(userID string) string {
 	return id
 }
 
-func (s *HTTPSessionStore) validate(id string) bool {
+// validate returns the user of session id, if it has not expired.
+func (s *HTTPSessionStore) validate(id string) (string, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	sess, ok := s.sessions[id]
-	if !ok {
-		return false
+	if !ok || time.Since(sess.CreatedAt) >= s.timeout {
+		return "", false
 	}
-	return time.Since(sess.CreatedAt) < s.timeout
+	return sess.UserID, true
 }
 
 func (s *HTTPSessionStore) cleanup() {
@@ -82,8 +84,12 @@ func (s *HTTPSessionStore) cleanup() {
 
 // authMiddleware applies HTTP API authentication based on config settings.
 // Checks are applied in order: IP auth, bearer token auth, session auth.
-// /health is always exempt from authentication.
-func authMiddleware(accountManager *login.AccountManager, sessionStore *HTTPSessionStore) func(http.Handler) http.Handler {
+// Bearer tokens are those issued through /api/v1/tokens, or account password
+// hashes; with bearer token auth alone, API routes without a token are
+// refused. Whatever the credential, requests other than GET and HEAD need an
+// account with write permission. /health is always exempt from
+// authentication.
+func authMiddleware(accountManager *login.AccountManager, tokens *apitoken.Store, sessionStore *HTTPSessionStore) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// /health is always exempt
@@ -118,14 +124,28 @@ func authMiddleware(accountManager *login.AccountManager, sessionStore *HTTPSess
 				authHeader := r.Header.Get("Authorization")
 				if strings.HasPrefix(authHeader, "Bearer ") {
 					token := strings.TrimPrefix(authHeader, "Bearer ")
-					// Validate bearer token against account passwords
-					if accountManager != nil && validateBearerToken(accountManager, token) {
-						next.ServeHTTP(w, r)
+					if t, ok := verifyAPIToken(accountManager, tokens, token); ok {
+						if t.ReadOnly && !readOnlyMethod(r.Method) {
+							writeError(w, http.StatusForbidden, "read-only token")
+							return
+						}
+						serveAccount(w, r, next, accountManager, t.Account)
+						return
+					}
+					// Account password hashes are still accepted as tokens
+					if id, ok := validateBearerToken(accountManager, token); ok {
+						serveAccount(w, r, next, accountManager, id)
 						return
 					}
 					writeError(w, http.StatusUnauthorized, "Invalid bearer token")
 					return
 				}
+				// Without session auth to fall back on, the API needs a token
+				if !cfg.NetHTTPApiAuthSessionEnabled() && needsAuth(r.URL.Path) {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="scouter"`)
+					writeError(w, http.StatusUnauthorized, "Bearer token required")
+					return
+				}
 			}
 
 			// Session-based authentication
@@ -138,9 +158,11 @@ func authMiddleware(accountManager *login.AccountManager, sessionStore *HTTPSess
 
 				// Check session cookie
 				cookie, err := r.Cookie("SCOUTER_SESSION")
-				if err == nil && sessionStore.validate(cookie.Value) {
-					next.ServeHTTP(w, r)
-					return
+				if err == nil {
+					if id, ok := sessionStore.validate(cookie.Value); ok {
+						serveAccount(w, r, next, accountManager, id)
+						return
+					}
 				}
 				writeError(w, http.StatusUnauthorized, "Not authenticated")
 				return
@@ -151,6 +173,22 @@ func authMiddleware(accountManager *login.AccountManager, sessionStore *HTTPSess
 	}
 }
 
+// readOnlyMethod reports whether method only reads.
+func readOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// serveAccount passes r, authenticated as account id, to next, unless it
+// would write and the account has no write permission.
+func serveAccount(w http.ResponseWriter, r *http.Request, next http.Handler, am *login.AccountManager, id string) {
+	if am != nil && !readOnlyMethod(r.Method) && am.AccountPermission(id) < login.PermWrite {
+		writeError(w, http.StatusForbidden, "account "+id+" has no write permission")
+		return
+	}
+	setAccount(r, id)
+	next.ServeHTTP(w, r)
+}
+
 // checkIPAuth checks if the request IP is in the allowed list.
 func checkIPAuth(r *http.Request, allowIPs string) bool {
 	remoteIP := extractIP(r.RemoteAddr)
@@ -176,15 +214,37 @@ func extractIP(addr string) string {
 	return host
 }
 
-// validateBearerToken checks if the token matches any account's password hash.
-func validateBearerToken(am *login.AccountManager, token string) bool {
+// needsAuth reports whether path is an API route, as opposed to the static
+// client files.
+func needsAuth(path string) bool {
+	return strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/ws/")
+}
+
+// verifyAPIToken returns the issued token secret is, if it is valid and its
+// account still exists.
+func verifyAPIToken(am *login.AccountManager, tokens *apitoken.Store, secret string) (apitoken.Token, bool) {
+	if tokens == nil {
+		return apitoken.Token{}, false
+	}
+	t, ok := tokens.Verify(secret, time.Now())
+	if !ok || (am != nil && am.GetAccount(t.Account) == nil) {
+		return apitoken.Token{}, false
+	}
+	return t, true
+}
+
+// validateBearerToken returns the account whose password hash matches token.
+func validateBearerToken(am *login.AccountManager, token string) (string, bool) {
+	if am == nil {
+		return "", false
+	}
 	accounts := am.GetAccountList()
 	for _, acct := range accounts {
 		if acct.Password == token {
-			return true
+			return acct.ID, true
 		}
 	}
-	return false
+	return "", false
 }
 
 // handleHTTPLogin handles the /api/v1/login endpoint for session-based auth.
@@ -202,12 +262,15 @@ func handleHTTPLogin(w http.ResponseWriter, r *http.Request, am *login.AccountMa
 		return
 	}
 
+	setAccount(r, id)
 	sessionID := store.create(id)
 	http.SetCookie(w, &http.Cookie{
 		Name:     "SCOUTER_SESSION",
 		Value:    sessionID,
 		Path:     "/",
 		HttpOnly: true,
+		// Cross-site requests, WebSocket handshakes included, go without it.
+		SameSite: http.SameSiteStrictMode,
 	})
 
 	writeJSON(w, map[string]string{"status": "ok", "session": sessionID})
@@ -0,0 +1,192 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+)
+
+// cachedResponse is a stored 200 response of a cached endpoint.
+type cachedResponse struct {
+	header  http.Header
+	body    []byte
+	etag    string
+	expires time.Time
+	ready   chan struct{} // closed once the response is filled in
+}
+
+// responseCache keeps the responses of expensive read endpoints for
+// net_http_api_cache_ttl_sec, keyed by path and query, so dashboards that
+// auto-refresh together hit the storage once. Concurrent misses for the
+// same key wait for the first request instead of reading again. All entries
+// are dropped when the day changes, since "today" parameters then refer to
+// another container.
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedResponse
+	day     string
+	now     func() time.Time
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{entries: make(map[string]*cachedResponse), now: time.Now}
+}
+
+// cacheRecorder captures a handler's response.
+type cacheRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *cacheRecorder) Header() http.Header         { return r.header }
+func (r *cacheRecorder) Write(b []byte) (int, error) { return r.body.Write(b) }
+func (r *cacheRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+// wrap serves GET requests of h from the cache and answers If-None-Match
+// with 304 when the ETag of the response matches. Other methods and non-200
+// responses pass through uncached.
+func (c *responseCache) wrap(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			h(w, r)
+			return
+		}
+		ttl, maxEntries := 30*time.Second, 1000
+		if cfg := config.Get(); cfg != nil {
+			ttl = time.Duration(cfg.NetHTTPApiCacheTTLSec()) * time.Second
+			maxEntries = cfg.NetHTTPApiCacheMaxEntries()
+		}
+		key := r.URL.Path + "?" + r.URL.Query().Encode()
+
+		entry, owner := c.lookup(key, ttl, maxEntries)
+		if entry != nil && !owner {
+			<-entry.ready
+			if entry.body != nil {
+				writeCached(w, r, entry, ttl)
+				return
+			}
+		}
+
+		rec := &cacheRecorder{header: make(http.Header)}
+		h(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if rec.status != http.StatusOK {
+			if owner {
+				c.abandon(key, entry)
+			}
+			copyHeader(w.Header(), rec.header)
+			w.WriteHeader(rec.status)
+			w.Write(rec.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(rec.body.Bytes())
+		resp := &cachedResponse{
+			header: rec.header,
+			body:   rec.body.Bytes(),
+			etag:   `"` + hex.EncodeToString(sum[:12]) + `"`,
+		}
+		if owner {
+			entry.header, entry.body, entry.etag = resp.header, resp.body, resp.etag
+			entry.expires = c.now().Add(ttl)
+			close(entry.ready)
+			resp = entry
+		}
+		writeCached(w, r, resp, ttl)
+	}
+}
+
+// lookup returns the entry for key. owner is true when the caller must fill
+// the returned entry in; entry is nil when caching is off or the cache is full.
+func (c *responseCache) lookup(key string, ttl time.Duration, maxEntries int) (entry *cachedResponse, owner bool) {
+	if ttl <= 0 {
+		return nil, false
+	}
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if day := now.Format("20060102"); day != c.day {
+		c.day = day
+		c.entries = make(map[string]*cachedResponse)
+	}
+	if e := c.entries[key]; e != nil {
+		select {
+		case <-e.ready:
+			if now.Before(e.expires) {
+				return e, false
+			}
+		default:
+			return e, false // being filled in by another request
+		}
+	}
+	if len(c.entries) >= maxEntries {
+		for k, e := range c.entries {
+			select {
+			case <-e.ready:
+				if !now.Before(e.expires) {
+					delete(c.entries, k)
+				}
+			default:
+			}
+		}
+		if len(c.entries) >= maxEntries {
+			return nil, false
+		}
+	}
+	e := &cachedResponse{ready: make(chan struct{})}
+	c.entries[key] = e
+	return e, true
+}
+
+// abandon removes an entry whose response is not cacheable and releases the
+// requests waiting for it; they run the handler themselves.
+func (c *responseCache) abandon(key string, e *cachedResponse) {
+	c.mu.Lock()
+	if c.entries[key] == e {
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+	close(e.ready)
+}
+
+func writeCached(w http.ResponseWriter, r *http.Request, e *cachedResponse, ttl time.Duration) {
+	copyHeader(w.Header(), e.header)
+	w.Header().Set("ETag", e.etag)
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(ttl.Seconds())))
+	if etagMatch(r.Header.Get("If-None-Match"), e.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Write(e.body)
+}
+
+// etagMatch reports whether an If-None-Match header lists etag.
+func etagMatch(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == etag || t == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
@@ -0,0 +1,189 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultCorsMethods = "GET, POST, PUT, DELETE, OPTIONS"
+
+// corsPolicy decides the CORS headers of a request.
+//
+// Origins are net_http_api_cors_allow_origin, a comma-separated list. "*"
+// answers every origin with a literal "*", which browsers refuse for
+// credentialed requests; listed origins are echoed back one at a time
+// instead, so credentials work from several dashboards. An origin may be a
+// pattern such as https://*.example.com for any subdomain.
+type corsPolicy struct {
+	anyOrigin   bool
+	origins     []string
+	credentials string
+	// routes restrict the methods of path prefixes, longest prefix first.
+	routes []corsRoute
+	maxAge string // Access-Control-Max-Age of preflights, "" for none
+}
+
+type corsRoute struct {
+	prefix  string
+	methods []string
+}
+
+// newCorsPolicy parses the origin list, the route method rules and the
+// preflight cache time. Rules are "prefix=METHOD,METHOD" separated by ";",
+// e.g. "/api/v1/admin=GET;/api/v1/kv=GET,PUT"; malformed rules are skipped.
+func newCorsPolicy(origins, credentials, routeMethods string, maxAge time.Duration) *corsPolicy {
+	p := &corsPolicy{credentials: credentials}
+	for _, o := range strings.Split(origins, ",") {
+		o = strings.TrimRight(strings.TrimSpace(o), "/")
+		switch o {
+		case "":
+		case "*":
+			p.anyOrigin = true
+		default:
+			p.origins = append(p.origins, strings.ToLower(o))
+		}
+	}
+	for _, rule := range strings.Split(routeMethods, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		prefix, list, ok := strings.Cut(rule, "=")
+		prefix = strings.TrimRight(strings.TrimSpace(prefix), "/")
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			slog.Warn("Ignoring malformed CORS route rule", "rule", rule)
+			continue
+		}
+		route := corsRoute{prefix: prefix}
+		for _, m := range strings.Split(list, ",") {
+			if m = strings.ToUpper(strings.TrimSpace(m)); m != "" && m != http.MethodOptions {
+				route.methods = append(route.methods, m)
+			}
+		}
+		p.routes = append(p.routes, route)
+	}
+	sort.SliceStable(p.routes, func(i, j int) bool { return len(p.routes[i].prefix) > len(p.routes[j].prefix) })
+	if maxAge > 0 {
+		p.maxAge = strconv.Itoa(int(maxAge / time.Second))
+	}
+	return p
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, ""
+// if it is not allowed.
+func (p *corsPolicy) allowOrigin(origin string) string {
+	if p.anyOrigin {
+		return "*"
+	}
+	if origin == "" {
+		return ""
+	}
+	o := strings.ToLower(origin)
+	for _, allowed := range p.origins {
+		if allowed == o || matchOriginPattern(allowed, o) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// matchOriginPattern matches origin against a pattern with a "*." subdomain
+// wildcard, e.g. https://*.example.com.
+func matchOriginPattern(pattern, origin string) bool {
+	scheme, host, ok := strings.Cut(pattern, "://*.")
+	if !ok {
+		return false
+	}
+	rest, ok := strings.CutPrefix(origin, scheme+"://")
+	if !ok {
+		return false
+	}
+	sub, ok := strings.CutSuffix(rest, "."+host)
+	return ok && sub != "" && !strings.ContainsAny(sub, ":/")
+}
+
+// methods returns the methods allowed on path, nil for any.
+func (p *corsPolicy) methods(path string) []string {
+	for _, r := range p.routes {
+		if path == r.prefix || strings.HasPrefix(path, r.prefix+"/") {
+			return r.methods
+		}
+	}
+	return nil
+}
+
+func methodAllowed(methods []string, method string) bool {
+	if methods == nil {
+		return true
+	}
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// sameOrigin reports whether origin is the server itself, as for the web
+// client served under /client/.
+func sameOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// corsMiddleware adds CORS headers to the responses of allowed origins and
+// answers preflight requests, which browsers may cache for
+// net_http_api_cors_max_age_sec. Cross-origin requests with a method the
+// route does not allow are refused.
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	p := s.cors
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := p.allowOrigin(origin)
+		methods := p.methods(r.URL.Path)
+		if !p.anyOrigin {
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if r.Method == http.MethodOptions {
+			requested := r.Header.Get("Access-Control-Request-Method")
+			if origin != "" && requested != "" && (allowed == "" || !methodAllowed(methods, requested)) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			if allowed != "" {
+				s.setCorsHeaders(w, allowed, methods)
+				if p.maxAge != "" {
+					w.Header().Set("Access-Control-Max-Age", p.maxAge)
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if origin != "" && !sameOrigin(r, origin) && !methodAllowed(methods, r.Method) {
+			writeError(w, http.StatusForbidden, "method not allowed for cross-origin requests")
+			return
+		}
+		if allowed != "" {
+			s.setCorsHeaders(w, allowed, methods)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) setCorsHeaders(w http.ResponseWriter, origin string, methods []string) {
+	allowMethods := defaultCorsMethods
+	if methods != nil {
+		allowMethods = strings.Join(methods, ", ") + ", " + http.MethodOptions
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Set("Access-Control-Allow-Credentials", s.cors.credentials)
+	w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+}
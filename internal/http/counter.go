@@ -0,0 +1,351 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+const (
+	// counterRangeMaxDays bounds the days of one long-range query.
+	counterRangeMaxDays = 366
+	// counterStepDefault is the bucket of realtime aggregates: agents send
+	// their counters at unaligned seconds, so the objects' latest values of
+	// each bucket are combined.
+	counterStepDefault = 10
+)
+
+// counterSeries holds the stored points of one object, or of the aggregate
+// of all the requested objects when ObjHash is zero.
+type counterSeries struct {
+	ObjHash int32     `json:"objHash,omitempty"`
+	ObjName string    `json:"objName,omitempty"`
+	Time    []int64   `json:"time"`
+	Value   []float64 `json:"value"`
+}
+
+// handleCounterPast serves stored counter values:
+//
+//	GET /api/v1/counter/{date}            realtime values of one storage day
+//	GET /api/v1/counter/{sdate}..{edate}  5-minute values of consecutive days,
+//	                                      at most 366
+//
+// Dates are YYYYMMDD. The query takes counter (required), objHash
+// (comma-separated) or objType (the objects of that type known to the
+// server), agg (sum or avg: one series combining the objects), stime and
+// etime (realtime only, epoch ms or RFC3339, default the whole day), step
+// (seconds per realtime aggregate point, default 10) and tz (zone of the Iso
+// fields). Series list only the points that were stored, times in epoch ms.
+// Past req_result_max_rows points the response is cut and "truncated" set.
+func (s *Server) handleCounterPast(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	q := r.URL.Query()
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/counter"), "/")
+	first, last, isRange := strings.Cut(path, "..")
+	if !isRange {
+		last = first
+	}
+	start, err1 := time.ParseInLocation("20060102", first, time.Local)
+	end, err2 := time.ParseInLocation("20060102", last, time.Local)
+	if err1 != nil || err2 != nil || strings.Contains(path, "/") {
+		writeError(w, http.StatusBadRequest, "invalid date: use YYYYMMDD or YYYYMMDD..YYYYMMDD")
+		return
+	}
+	days := 0
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		days++
+	}
+	if days == 0 || days > counterRangeMaxDays {
+		writeError(w, http.StatusBadRequest, "invalid date range: at most "+strconv.Itoa(counterRangeMaxDays)+" days, oldest first")
+		return
+	}
+
+	counterName := q.Get("counter")
+	if counterName == "" {
+		writeError(w, http.StatusBadRequest, "missing required parameter: counter")
+		return
+	}
+	agg := q.Get("agg")
+	if agg != "" && agg != "sum" && agg != "avg" {
+		writeError(w, http.StatusBadRequest, "invalid agg: use sum or avg")
+		return
+	}
+	objHashes, ok := s.counterObjects(w, q.Get("objHash"), q.Get("objType"))
+	if !ok {
+		return
+	}
+	maxRows := 0
+	if cfg := config.Get(); cfg != nil {
+		maxRows = cfg.ReqResultMaxRows()
+	}
+
+	resp := map[string]interface{}{"counter": counterName}
+	var series []counterSeries
+	truncated := false
+	if isRange {
+		series, truncated = s.counterDailySeries(start, days, objHashes, counterName, agg, maxRows)
+		stop := start.AddDate(0, 0, days)
+		resp["startDate"], resp["endDate"] = first, last
+		resp["start"], resp["startIso"] = start.UnixMilli(), isoTime(start.UnixMilli(), loc)
+		resp["end"], resp["endIso"] = stop.UnixMilli(), isoTime(stop.UnixMilli(), loc)
+		resp["bucketMinutes"] = 24 * 60 / counter.BucketsPerDay
+	} else {
+		stime, etime := start.UnixMilli(), start.AddDate(0, 0, 1).UnixMilli()-1
+		for name, dst := range map[string]*int64{"stime": &stime, "etime": &etime} {
+			if v := q.Get(name); v != "" {
+				t, err := parseAPITime(v)
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "invalid "+name+": use epoch milliseconds or RFC3339")
+					return
+				}
+				*dst = min(max(t.UnixMilli(), start.UnixMilli()), start.AddDate(0, 0, 1).UnixMilli()-1)
+			}
+		}
+		step := counterStepDefault
+		if v := q.Get("step"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > 86400 {
+				writeError(w, http.StatusBadRequest, "invalid step: must be between 1 and 86400 seconds")
+				return
+			}
+			step = n
+		}
+		series, truncated = s.counterRealtimeSeries(start, stime, etime, objHashes, counterName, agg, step, maxRows)
+		resp["date"] = first
+		resp["start"], resp["startIso"] = stime, isoTime(stime, loc)
+		resp["end"], resp["endIso"] = etime, isoTime(etime, loc)
+		if agg != "" {
+			resp["step"] = step
+		}
+	}
+	if agg != "" {
+		resp["agg"] = agg
+		resp["objCount"] = len(objHashes)
+	}
+	if objType := q.Get("objType"); objType != "" {
+		resp["objType"] = objType
+	}
+	if series == nil {
+		series = []counterSeries{}
+	}
+	resp["series"] = series
+	resp["truncated"] = truncated
+	writeJSON(w, resp)
+}
+
+// counterObjects returns the objects of a past counter query, answering 400
+// when neither objHash nor objType selects any.
+func (s *Server) counterObjects(w http.ResponseWriter, objHashStr, objType string) ([]int32, bool) {
+	var objHashes []int32
+	switch {
+	case objHashStr != "":
+		for _, f := range strings.Split(objHashStr, ",") {
+			h, err := strconv.ParseInt(strings.TrimSpace(f), 10, 32)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid objHash: must be 32-bit integers")
+				return nil, false
+			}
+			if !slices.Contains(objHashes, int32(h)) {
+				objHashes = append(objHashes, int32(h))
+			}
+		}
+	case objType != "":
+		if s.objectCache != nil {
+			for _, info := range s.objectCache.GetAll() {
+				if info.Pack.ObjType == objType {
+					objHashes = append(objHashes, info.Pack.ObjHash)
+				}
+			}
+		}
+		slices.Sort(objHashes)
+	default:
+		writeError(w, http.StatusBadRequest, "missing required parameter: objHash or objType")
+		return nil, false
+	}
+	return objHashes, true
+}
+
+func (s *Server) counterObjName(objHash int32) string {
+	if s.objectCache != nil {
+		if info, ok := s.objectCache.Get(objHash); ok {
+			return info.Pack.ObjName
+		}
+	}
+	return ""
+}
+
+// counterRealtimeSeries reads the realtime values of day between stime and
+// etime (ms). With agg, the latest value of each object in every step
+// seconds is summed or averaged over the objects that have one.
+func (s *Server) counterRealtimeSeries(day time.Time, stime, etime int64, objHashes []int32, counterName, agg string, step, maxRows int) ([]counterSeries, bool) {
+	date := day.Format("20060102")
+	midnight := day.UnixMilli()
+	startSec, endSec := int32((stime-midnight)/1000), int32((etime-midnight)/1000)
+	rows := 0
+	var series []counterSeries
+	type bucket struct {
+		sum   float64
+		count int
+	}
+	buckets := make(map[int64]*bucket)
+	for _, objHash := range objHashes {
+		if maxRows > 0 && rows > maxRows {
+			return series, true
+		}
+		one := counterSeries{ObjHash: objHash, ObjName: s.counterObjName(objHash)}
+		latest := make(map[int64]float64)
+		s.counterRD.ReadRealtimeRange(date, objHash, startSec, endSec, func(timeSec int32, counters map[string]value.Value) {
+			v, ok := counters[counterName]
+			if !ok {
+				return
+			}
+			t := midnight + int64(timeSec)*1000
+			if agg != "" {
+				latest[t-(t-midnight)%(int64(step)*1000)] = counterFloat(v)
+				return
+			}
+			if maxRows <= 0 || rows < maxRows {
+				one.Time = append(one.Time, t)
+				one.Value = append(one.Value, counterFloat(v))
+			}
+			rows++
+		})
+		if agg != "" {
+			for t, v := range latest {
+				b := buckets[t]
+				if b == nil {
+					b = &bucket{}
+					buckets[t] = b
+				}
+				b.sum += v
+				b.count++
+			}
+		} else if len(one.Time) > 0 {
+			series = append(series, one)
+		}
+	}
+	if maxRows > 0 && rows > maxRows {
+		return series, true
+	}
+	if agg == "" {
+		return series, false
+	}
+	times := make([]int64, 0, len(buckets))
+	for t := range buckets {
+		times = append(times, t)
+	}
+	slices.Sort(times)
+	truncated := maxRows > 0 && len(times) > maxRows
+	if truncated {
+		times = times[:maxRows]
+	}
+	total := counterSeries{Time: times, Value: make([]float64, len(times))}
+	for i, t := range times {
+		total.Value[i] = buckets[t].sum
+		if agg == "avg" {
+			total.Value[i] /= float64(buckets[t].count)
+		}
+	}
+	return []counterSeries{total}, truncated
+}
+
+// counterDailySeries reads the 5-minute values of days consecutive days from
+// start. With agg, each bucket is summed or averaged over the objects that
+// have a value in it.
+func (s *Server) counterDailySeries(start time.Time, days int, objHashes []int32, counterName, agg string, maxRows int) ([]counterSeries, bool) {
+	bucketMs := int64(24*60/counter.BucketsPerDay) * 60 * 1000
+	rows := 0
+	var series []counterSeries
+	var total counterSeries
+	var counts []int
+	for i := 0; i < days; i++ {
+		day := start.AddDate(0, 0, i)
+		date := day.Format("20060102")
+		var sums []float64
+		if agg != "" {
+			sums = make([]float64, counter.BucketsPerDay)
+			counts = make([]int, counter.BucketsPerDay)
+		}
+		for _, objHash := range objHashes {
+			values, err := s.counterRD.ReadDailyAll(date, objHash, counterName)
+			if err != nil || values == nil {
+				continue
+			}
+			if agg != "" {
+				for j, v := range values {
+					if !math.IsNaN(v) {
+						sums[j] += v
+						counts[j]++
+					}
+				}
+				continue
+			}
+			idx := slices.IndexFunc(series, func(c counterSeries) bool { return c.ObjHash == objHash })
+			if idx < 0 {
+				series = append(series, counterSeries{ObjHash: objHash, ObjName: s.counterObjName(objHash)})
+				idx = len(series) - 1
+			}
+			for j, v := range values {
+				if math.IsNaN(v) {
+					continue
+				}
+				if maxRows > 0 && rows >= maxRows {
+					return series, true
+				}
+				series[idx].Time = append(series[idx].Time, day.UnixMilli()+int64(j)*bucketMs)
+				series[idx].Value = append(series[idx].Value, v)
+				rows++
+			}
+		}
+		for j := range sums {
+			if counts[j] == 0 {
+				continue
+			}
+			if maxRows > 0 && rows >= maxRows {
+				return []counterSeries{total}, true
+			}
+			v := sums[j]
+			if agg == "avg" {
+				v /= float64(counts[j])
+			}
+			total.Time = append(total.Time, day.UnixMilli()+int64(j)*bucketMs)
+			total.Value = append(total.Value, v)
+			rows++
+		}
+	}
+	if agg != "" {
+		if total.Time == nil {
+			total.Time, total.Value = []int64{}, []float64{}
+		}
+		return []counterSeries{total}, false
+	}
+	return slices.DeleteFunc(series, func(c counterSeries) bool { return len(c.Time) == 0 }), false
+}
+
+// counterFloat returns the numeric value of a stored counter.
+func counterFloat(v value.Value) float64 {
+	switch tv := v.(type) {
+	case *value.DecimalValue:
+		return float64(tv.Value)
+	case *value.FloatValue:
+		return float64(tv.Value)
+	case *value.DoubleValue:
+		return tv.Value
+	}
+	return 0
+}
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/report"
+)
+
+// dateParam returns the storage day selected by the "date" query parameter
+// as the start of that day, defaulting to today. The parameter is a storage
+// day (YYYYMMDD or YYYY-MM-DD) or an instant (RFC3339 or epoch ms), which
+// selects the storage day containing it.
+func dateParam(r *http.Request) (time.Time, bool) {
+	s := r.URL.Query().Get("date")
+	if s == "" {
+		return startOfDay(time.Now()), true
+	}
+	for _, layout := range []string{"20060102", "2006-01-02"} {
+		if day, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return day, true
+		}
+	}
+	t, err := parseAPITime(s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return startOfDay(t.In(time.Local)), true
+}
+
+// invalidDate is the error message for a bad "date" query parameter.
+const invalidDate = "invalid date: use YYYYMMDD, YYYY-MM-DD, RFC3339 or epoch milliseconds"
+
+// handleCounterDaily returns the 5-minute values of a counter for one day,
+// null where no value was stored.
+// Query params: objHash (required), counter (required), date (see
+// dateParam, default today), tz (zone of startIso).
+func (s *Server) handleCounterDaily(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	objHashStr := r.URL.Query().Get("objHash")
+	counterName := r.URL.Query().Get("counter")
+	if objHashStr == "" {
+		writeError(w, http.StatusBadRequest, "missing required parameter: objHash")
+		return
+	}
+	if counterName == "" {
+		writeError(w, http.StatusBadRequest, "missing required parameter: counter")
+		return
+	}
+	objHash64, err := strconv.ParseInt(objHashStr, 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid objHash: must be a 32-bit integer")
+		return
+	}
+	day, ok := dateParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, invalidDate)
+		return
+	}
+	date := day.Format("20060102")
+
+	values, err := s.counterRD.ReadDailyAll(date, int32(objHash64), counterName)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if values == nil {
+		writeError(w, http.StatusNotFound, "counter not found")
+		return
+	}
+	// Buckets without data are stored as NaN, which JSON cannot carry.
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		if !math.IsNaN(v) {
+			out[i] = v
+		}
+	}
+	writeJSON(w, map[string]interface{}{
+		"objHash":       int32(objHash64),
+		"counter":       counterName,
+		"date":          date,
+		"start":         day.UnixMilli(),
+		"startIso":      isoTime(day.UnixMilli(), loc),
+		"bucketMinutes": 24 * 60 / counter.BucketsPerDay,
+		"values":        out,
+	})
+}
+
+// handleSummaryDaily returns the service totals, object types, slowest
+// services and most frequent alerts of one day, as in the daily report.
+// Query params: date (see dateParam, default today), tz (zone of startIso
+// and endIso).
+func (s *Server) handleSummaryDaily(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	day, ok := dateParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, invalidDate)
+		return
+	}
+	end := day.AddDate(0, 0, 1)
+	rep := s.reports.Build(report.Daily, day, end)
+
+	stat := func(st report.Stat) map[string]interface{} {
+		return map[string]interface{}{
+			"count":      st.Count,
+			"errors":     st.Errors,
+			"errorRate":  st.ErrorRate(),
+			"avgElapsed": st.AvgElapsed(),
+		}
+	}
+	objTypes := make([]map[string]interface{}, 0, len(rep.ObjTypes))
+	for _, t := range rep.ObjTypes {
+		m := stat(t.Stat)
+		m["objType"] = t.ObjType
+		objTypes = append(objTypes, m)
+	}
+	services := make([]map[string]interface{}, 0, len(rep.Services))
+	for _, svc := range rep.Services {
+		m := stat(svc.Stat)
+		m["service"] = svc.Name
+		services = append(services, m)
+	}
+	alerts := make([]map[string]interface{}, 0, len(rep.Alerts))
+	for _, a := range rep.Alerts {
+		alerts = append(alerts, map[string]interface{}{"title": a.Title, "level": a.Level, "count": a.Count})
+	}
+	total := stat(rep.Total)
+	total["tps"] = rep.TPS()
+
+	writeJSON(w, map[string]interface{}{
+		"date":     day.Format("20060102"),
+		"start":    day.UnixMilli(),
+		"startIso": isoTime(day.UnixMilli(), loc),
+		"end":      end.UnixMilli(),
+		"endIso":   isoTime(end.UnixMilli(), loc),
+		"total":    total,
+		"objTypes": objTypes,
+		"services": services,
+		"alerts":   alerts,
+	})
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/deploywin"
+)
+
+// deployWindowRequest is the body of POST /api/v1/deploy-window.
+type deployWindowRequest struct {
+	// Objects selects the objects by objName, objHash or objName pattern;
+	// empty means every object.
+	Objects []string `json:"objects"`
+	// Suppress lists the alert titles to drop; "*" drops every alert.
+	Suppress []string `json:"suppress"`
+	// Minutes is the length of the window; Until, an end time in epoch ms
+	// or RFC3339, may be given instead.
+	Minutes int     `json:"minutes"`
+	Until   apiTime `json:"until"`
+	Reason  string  `json:"reason"`
+	By      string  `json:"by"`
+}
+
+// handleDeployWindow serves the deployment windows:
+//
+//	GET    /api/v1/deploy-window        list open windows
+//	POST   /api/v1/deploy-window        open a window
+//	DELETE /api/v1/deploy-window/{id}   close a window early
+//
+// The optional tz query parameter selects the zone of start and until.
+func (s *Server) handleDeployWindow(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/deploy-window"), "/")
+	if rest != "" {
+		if r.Method != http.MethodDelete {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		id, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid window id")
+			return
+		}
+		if !s.deployWindows.Close(id) {
+			writeError(w, http.StatusNotFound, "no such window")
+			return
+		}
+		writeJSON(w, map[string]interface{}{"id": id, "closed": true})
+		return
+	}
+
+	loc, err := requestLocation(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	inZone := func(win deploywin.Window) deploywin.Window {
+		win.Start, win.Until = win.Start.In(loc), win.Until.In(loc)
+		return win
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		windows := s.deployWindows.List()
+		for i := range windows {
+			windows[i] = inZone(windows[i])
+		}
+		writeJSON(w, map[string]interface{}{"windows": windows})
+	case http.MethodPost:
+		var req deployWindowRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxWriteBody)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+		if req.By == "" {
+			req.By, _, _ = net.SplitHostPort(r.RemoteAddr)
+		}
+		d := time.Duration(req.Minutes) * time.Minute
+		if req.Until != 0 {
+			d = time.Until(time.UnixMilli(int64(req.Until)))
+		}
+		win, err := s.deployWindows.Open(deploywin.Window{
+			Objects:  req.Objects,
+			Suppress: req.Suppress,
+			Reason:   req.Reason,
+			By:       req.By,
+		}, d)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(inZone(win))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,141 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/db/kv"
+)
+
+// kvNamespaceRequest is the body of PUT /api/v1/kv/{ns}.
+type kvNamespaceRequest struct {
+	QuotaBytes   int64 `json:"quotaBytes"`
+	DefaultTTLMs int64 `json:"defaultTtlMs"`
+}
+
+// kvValueRequest is the body of PUT /api/v1/kv/{ns}/{key}.
+type kvValueRequest struct {
+	Value string `json:"value"`
+	// TTLMs is the TTL in ms; 0 applies the namespace default and a negative
+	// value stores the key without expiry.
+	TTLMs int64 `json:"ttlMs"`
+}
+
+// handleKV serves the KV namespaces:
+//
+//	GET    /api/v1/kv                 list namespaces with limits and usage
+//	GET    /api/v1/kv/{ns}?prefix=    list keys
+//	PUT    /api/v1/kv/{ns}            create or change limits (admin group)
+//	DELETE /api/v1/kv/{ns}            drop with all keys (admin group)
+//	GET    /api/v1/kv/{ns}/{key}      read a value
+//	PUT    /api/v1/kv/{ns}/{key}      write a value
+//	DELETE /api/v1/kv/{ns}/{key}      delete a key
+//
+// POST is accepted wherever PUT is.
+func (s *Server) handleKV(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/kv"), "/")
+	if rest == "" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, map[string]interface{}{"namespaces": s.kvNamespaces.List()})
+		return
+	}
+	ns, key, hasKey := strings.Cut(rest, "/")
+	if hasKey {
+		s.handleKVKey(w, r, ns, key)
+		return
+	}
+
+	if r.Method != http.MethodGet && !s.isAdmin(r) {
+		writeError(w, http.StatusForbidden, "namespace management needs an account of the admin group")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		store, ok := s.kvNamespaces.Get(ns)
+		if !ok {
+			writeError(w, http.StatusNotFound, "no such namespace")
+			return
+		}
+		keys := store.Keys(r.URL.Query().Get("prefix"))
+		if keys == nil {
+			keys = []string{}
+		}
+		writeJSON(w, map[string]interface{}{"namespace": ns, "keys": keys})
+	case http.MethodPut, http.MethodPost:
+		var req kvNamespaceRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxWriteBody)).Decode(&req); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+		maxCount := 0
+		if cfg := config.Get(); cfg != nil {
+			maxCount = cfg.KVNamespaceMax()
+		}
+		if err := s.kvNamespaces.Set(ns, req.QuotaBytes, req.DefaultTTLMs, maxCount); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, map[string]string{"result": "ok"})
+	case http.MethodDelete:
+		found, err := s.kvNamespaces.Drop(ns)
+		switch {
+		case !found:
+			writeError(w, http.StatusNotFound, "no such namespace")
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			writeJSON(w, map[string]string{"result": "ok"})
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleKVKey(w http.ResponseWriter, r *http.Request, ns, key string) {
+	store, ok := s.kvNamespaces.Get(ns)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no such namespace")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		val, ok := store.Get(key)
+		if !ok {
+			writeError(w, http.StatusNotFound, "no such key")
+			return
+		}
+		writeJSON(w, map[string]string{"key": key, "value": val})
+	case http.MethodPut, http.MethodPost:
+		var req kvValueRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxWriteBody)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+		if err := store.Put(key, req.Value, req.TTLMs); err != nil {
+			if errors.Is(err, kv.ErrQuotaExceeded) {
+				writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+			} else {
+				writeError(w, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+		writeJSON(w, map[string]string{"result": "ok"})
+	case http.MethodDelete:
+		if !store.Delete(key) {
+			writeError(w, http.StatusNotFound, "no such key")
+			return
+		}
+		writeJSON(w, map[string]string{"result": "ok"})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
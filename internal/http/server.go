@@ -11,50 +11,114 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/zbum/scouter-server-go/internal/alertrule"
+	"github.com/zbum/scouter-server-go/internal/apitoken"
+	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db"
+	"github.com/zbum/scouter-server-go/internal/db/agentinv"
 	"github.com/zbum/scouter-server-go/internal/db/alert"
 	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/db/kv"
+	"github.com/zbum/scouter-server-go/internal/db/text"
 	"github.com/zbum/scouter-server-go/internal/db/xlog"
+	"github.com/zbum/scouter-server-go/internal/deploywin"
 	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/report"
+	"github.com/zbum/scouter-server-go/internal/slo"
+	"github.com/zbum/scouter-server-go/internal/util"
 )
 
 var startTime = time.Now()
 
 // Server is the HTTP REST API server for Scouter monitoring data.
 type Server struct {
-	port                 int
-	corsAllowOrigin      string
-	corsAllowCredentials string
-	gzipEnabled          bool
-	objectCache          *cache.ObjectCache
-	counterCache         *cache.CounterCache
-	xlogCache            *cache.XLogCache
-	textCache            *cache.TextCache
-	xlogRD               *xlog.XLogRD
-	counterRD            *counter.CounterRD
-	alertRD              *alert.AlertRD
-	httpServer           *http.Server
+	port           int
+	cors           *corsPolicy
+	gzipEnabled    bool
+	objectCache    *cache.ObjectCache
+	counterCache   *cache.CounterCache
+	xlogCache      *cache.XLogCache
+	textCache      *cache.TextCache
+	alertCache     *cache.AlertCache
+	xlogRD         *xlog.XLogRD
+	xlogWR         *xlog.XLogWR
+	textRD         *text.TextRD
+	counterRD      *counter.CounterRD
+	alertRD        *alert.AlertRD
+	purger         *db.ManualPurger
+	ingest         func(p pack.Pack)
+	readOnly       func() bool
+	slo            *slo.Tracker
+	alertRules     *alertrule.Engine
+	kvNamespaces   *kv.Namespaces
+	agentInventory *agentinv.Store
+	reports        *report.Builder
+	deployWindows  *deploywin.Manager
+	accountManager *login.AccountManager
+	tokens         *apitoken.Store
+	cache          *responseCache
+	metrics        *httpMetrics
+	httpServer     *http.Server
+	streams        atomic.Int32  // open WebSocket and SSE streams
+	streamsClosing chan struct{} // closed on shutdown, which waits for no stream
 }
 
 // ServerConfig holds all dependencies required to construct a Server.
 type ServerConfig struct {
-	Port                 int
+	Port int
+	// CorsAllowOrigin is a comma-separated list of origins, "*" for any.
 	CorsAllowOrigin      string
 	CorsAllowCredentials string
-	GzipEnabled          bool
-	ClientDir            string
-	AccountManager       *login.AccountManager
-	SessionTimeout       time.Duration
-	ObjectCache          *cache.ObjectCache
-	CounterCache         *cache.CounterCache
-	XLogCache            *cache.XLogCache
-	TextCache            *cache.TextCache
-	XLogRD               *xlog.XLogRD
-	CounterRD            *counter.CounterRD
-	AlertRD              *alert.AlertRD
+	// CorsRouteMethods restricts the methods of path prefixes, e.g.
+	// "/api/v1/admin=GET;/api/v1/kv=GET,PUT".
+	CorsRouteMethods string
+	// CorsMaxAge is how long browsers may cache preflight responses.
+	CorsMaxAge     time.Duration
+	GzipEnabled    bool
+	ClientDir      string
+	AccountManager *login.AccountManager
+	SessionTimeout time.Duration
+	ObjectCache    *cache.ObjectCache
+	CounterCache   *cache.CounterCache
+	XLogCache      *cache.XLogCache
+	TextCache      *cache.TextCache
+	// AlertCache enables /api/v1/alert/stream.
+	AlertCache *cache.AlertCache
+	XLogRD     *xlog.XLogRD
+	// XLogWR serves the days it holds to /api/v1/xlog/{date}, which falls
+	// back to XLogRD for the others.
+	XLogWR *xlog.XLogWR
+	// TextRD resolves the texts of stored XLogs missing from TextCache.
+	TextRD    *text.TextRD
+	CounterRD *counter.CounterRD
+	AlertRD   *alert.AlertRD
+	Purger    *db.ManualPurger
+	// Ingest feeds packs into the collector pipeline as if received from an
+	// agent. The write endpoints are disabled when it is nil.
+	Ingest func(p pack.Pack)
+	// ReadOnly, if set, reports whether ingestion is paused for maintenance;
+	// the write endpoints then answer 503 with a Retry-After hint.
+	ReadOnly func() bool
+	SLO      *slo.Tracker
+	// AlertRules enables /api/v1/alert/rules.
+	AlertRules *alertrule.Engine
+	// KVNamespaces enables the /api/v1/kv endpoints.
+	KVNamespaces *kv.Namespaces
+	// AgentInventory enables the /api/v1/agents endpoints.
+	AgentInventory *agentinv.Store
+	// Reports enables /api/v1/summary/daily.
+	Reports *report.Builder
+	// DeployWindows enables /api/v1/deploy-window.
+	DeployWindows *deploywin.Manager
+	// APITokens enables /api/v1/tokens and the tokens it issues as bearer
+	// tokens.
+	APITokens *apitoken.Store
 }
 
 // NewServer creates and configures a new HTTP API server.
@@ -67,17 +131,33 @@ func NewServer(cfg ServerConfig) *Server {
 	}
 
 	s := &Server{
-		port:                 cfg.Port,
-		corsAllowOrigin:      cfg.CorsAllowOrigin,
-		corsAllowCredentials: cfg.CorsAllowCredentials,
-		gzipEnabled:          cfg.GzipEnabled,
-		objectCache:          cfg.ObjectCache,
-		counterCache:         cfg.CounterCache,
-		xlogCache:            cfg.XLogCache,
-		textCache:            cfg.TextCache,
-		xlogRD:               cfg.XLogRD,
-		counterRD:            cfg.CounterRD,
-		alertRD:              cfg.AlertRD,
+		port:           cfg.Port,
+		cors:           newCorsPolicy(cfg.CorsAllowOrigin, cfg.CorsAllowCredentials, cfg.CorsRouteMethods, cfg.CorsMaxAge),
+		gzipEnabled:    cfg.GzipEnabled,
+		objectCache:    cfg.ObjectCache,
+		counterCache:   cfg.CounterCache,
+		xlogCache:      cfg.XLogCache,
+		textCache:      cfg.TextCache,
+		alertCache:     cfg.AlertCache,
+		xlogRD:         cfg.XLogRD,
+		xlogWR:         cfg.XLogWR,
+		textRD:         cfg.TextRD,
+		counterRD:      cfg.CounterRD,
+		alertRD:        cfg.AlertRD,
+		purger:         cfg.Purger,
+		ingest:         cfg.Ingest,
+		readOnly:       cfg.ReadOnly,
+		slo:            cfg.SLO,
+		alertRules:     cfg.AlertRules,
+		kvNamespaces:   cfg.KVNamespaces,
+		agentInventory: cfg.AgentInventory,
+		reports:        cfg.Reports,
+		deployWindows:  cfg.DeployWindows,
+		accountManager: cfg.AccountManager,
+		tokens:         cfg.APITokens,
+		cache:          newResponseCache(),
+		metrics:        newHTTPMetrics(),
+		streamsClosing: make(chan struct{}),
 	}
 
 	mux := http.NewServeMux()
@@ -85,8 +165,57 @@ func NewServer(cfg ServerConfig) *Server {
 	mux.HandleFunc("/api/v1/counter/realtime", s.handleCounterRealtime)
 	mux.HandleFunc("/api/v1/xlog/realtime", s.handleXLogRealtime)
 	mux.HandleFunc("/api/v1/text", s.handleText)
+	mux.HandleFunc("/ws/v1/xlog", s.handleXLogStream)
+	mux.HandleFunc("/ws/v1/counter", s.handleCounterStream)
+	if s.xlogRD != nil {
+		mux.HandleFunc("/api/v1/xlog/", s.handleXLog)
+	}
+	// Daily data is read from storage; cached against dashboard refresh storms.
+	if s.counterRD != nil {
+		mux.HandleFunc("/api/v1/counter/daily", s.cache.wrap(s.handleCounterDaily))
+		mux.HandleFunc("/api/v1/counter/", s.cache.wrap(s.handleCounterPast))
+		mux.HandleFunc("/api/v1/alert/preview", s.handleAlertPreview)
+	}
+	if s.reports != nil {
+		mux.HandleFunc("/api/v1/summary/daily", s.cache.wrap(s.handleSummaryDaily))
+	}
 	mux.HandleFunc("/health", s.handleHealth)
 	mux.HandleFunc("/api/v1/server/info", s.handleServerInfo)
+	mux.HandleFunc("/api/v1/server/http-stats", s.handleHTTPStats)
+	if s.purger != nil {
+		mux.HandleFunc("/api/v1/admin/purge", s.handlePurge)
+		mux.HandleFunc("/api/v1/admin/trash", s.handleTrash)
+		mux.HandleFunc("/api/v1/admin/trash/", s.handleTrash)
+	}
+	if s.ingest != nil {
+		mux.HandleFunc("/api/v1/counter", s.handleCounterWrite)
+		mux.HandleFunc("/api/v1/alert", s.handleAlertWrite)
+	}
+	if s.slo != nil {
+		mux.HandleFunc("/api/v1/slo", s.handleSLO)
+	}
+	if s.alertCache != nil {
+		mux.HandleFunc("/api/v1/alert/stream", s.handleAlertStream)
+	}
+	if s.alertRules != nil {
+		mux.HandleFunc("/api/v1/alert/rules", s.handleAlertRules)
+	}
+	if s.agentInventory != nil {
+		mux.HandleFunc("/api/v1/agents/versions", s.handleAgentVersions)
+		mux.HandleFunc("/api/v1/agents/history", s.handleAgentHistory)
+	}
+	if s.deployWindows != nil {
+		mux.HandleFunc("/api/v1/deploy-window", s.handleDeployWindow)
+		mux.HandleFunc("/api/v1/deploy-window/", s.handleDeployWindow)
+	}
+	if s.tokens != nil {
+		mux.HandleFunc("/api/v1/tokens", s.handleTokens)
+		mux.HandleFunc("/api/v1/tokens/", s.handleTokens)
+	}
+	if s.kvNamespaces != nil {
+		mux.HandleFunc("/api/v1/kv", s.handleKV)
+		mux.HandleFunc("/api/v1/kv/", s.handleKV)
+	}
 
 	// Serve static client files if client_dir exists
 	if cfg.ClientDir != "" {
@@ -96,7 +225,7 @@ func NewServer(cfg ServerConfig) *Server {
 		}
 	}
 
-	// Build middleware chain: cors → auth → gzip → mux
+	// Build middleware chain: access → cors → auth → gzip → mux
 	var handler http.Handler = mux
 
 	// Gzip middleware
@@ -110,33 +239,22 @@ func NewServer(cfg ServerConfig) *Server {
 		sessionTimeout = 24 * time.Hour
 	}
 	sessionStore := NewHTTPSessionStore(sessionTimeout)
-	handler = authMiddleware(cfg.AccountManager, sessionStore)(handler)
+	handler = authMiddleware(cfg.AccountManager, cfg.APITokens, sessionStore)(handler)
 
-	// CORS middleware (outermost)
+	// CORS middleware
 	handler = s.corsMiddleware(handler)
 
+	// Access log and request metrics (outermost)
+	handler = s.accessMiddleware(mux, handler)
+
 	s.httpServer = &http.Server{
 		Addr:    net.JoinHostPort("", strconv.Itoa(s.port)),
 		Handler: handler,
 	}
+	s.httpServer.RegisterOnShutdown(func() { close(s.streamsClosing) })
 	return s
 }
 
-// corsMiddleware adds CORS headers to every HTTP response.
-func (s *Server) corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", s.corsAllowOrigin)
-		w.Header().Set("Access-Control-Allow-Credentials", s.corsAllowCredentials)
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
-}
-
 // gzipResponseWriter wraps http.ResponseWriter to compress response with gzip.
 type gzipResponseWriter struct {
 	io.Writer
@@ -147,10 +265,23 @@ func (w gzipResponseWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
-// gzipMiddleware applies gzip compression to responses when client supports it.
+// Flush sends what was compressed so far, for streamed responses.
+func (w gzipResponseWriter) Flush() {
+	if gz, ok := w.Writer.(*gzip.Writer); ok {
+		gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// gzipMiddleware applies gzip compression to responses when client supports
+// it. WebSocket upgrades are passed through as they take over the connection.
 func gzipMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || headerHasToken(r.Header, "Upgrade", "websocket") {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -165,6 +296,9 @@ func gzipMiddleware(next http.Handler) http.Handler {
 // Start begins listening for HTTP connections. It blocks until the server
 // is shut down or an error occurs. The provided context controls graceful shutdown.
 func (s *Server) Start(ctx context.Context) error {
+	if s.ingest != nil {
+		go s.reportMetrics(ctx)
+	}
 	go func() {
 		<-ctx.Done()
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -198,9 +332,14 @@ func (s *Server) handleServerInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	uptimeMs := time.Since(startTime).Milliseconds()
+	now := time.Now()
+	zone, _ := now.Zone()
 	writeJSON(w, map[string]interface{}{
 		"version":   "dev",
 		"uptime_ms": uptimeMs,
+		// Storage days ("date" parameters) are days of this zone.
+		"timezone":  zone,
+		"utcOffset": now.Format("-07:00"),
 	})
 }
 
@@ -363,6 +502,393 @@ func (s *Server) handleText(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// purgeResponse is the JSON representation of a single purge result.
+type purgeResponse struct {
+	Date    string `json:"date"`
+	Type    string `json:"type,omitempty"`
+	Deleted bool   `json:"deleted"`
+	Skipped string `json:"skipped,omitempty"`
+	TrashID string `json:"trashId,omitempty"`
+}
+
+// isAdmin reports whether r was authenticated as an account of the admin group.
+func (s *Server) isAdmin(r *http.Request) bool {
+	account := requestAccount(r)
+	if account == "" || s.accountManager == nil {
+		return false
+	}
+	acct := s.accountManager.GetAccount(account)
+	return acct != nil && acct.Group == "admin"
+}
+
+// handlePurge deletes day data for the given dates and types; admin group only.
+// Params: date (required, e.g. 20260101..20260131), types (required, e.g. xlog,profile).
+func (s *Server) handlePurge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.isAdmin(r) {
+		writeError(w, http.StatusForbidden, "purge needs an account of the admin group")
+		return
+	}
+
+	dateSpec := r.FormValue("date")
+	typeSpec := r.FormValue("types")
+	if dateSpec == "" {
+		writeError(w, http.StatusBadRequest, "missing required parameter: date")
+		return
+	}
+	if typeSpec == "" {
+		writeError(w, http.StatusBadRequest, "missing required parameter: types")
+		return
+	}
+
+	results, err := s.purger.PurgeSpec(dateSpec, typeSpec)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	out := make([]purgeResponse, 0, len(results))
+	for _, res := range results {
+		out = append(out, purgeResponse{
+			Date:    res.Date,
+			Type:    res.Type,
+			Deleted: res.Deleted,
+			Skipped: res.Skipped,
+			TrashID: res.TrashID,
+		})
+	}
+	writeJSON(w, map[string]interface{}{
+		"results": out,
+		"total":   len(out),
+	})
+}
+
+// counterWriteRequest is the body of POST /api/v1/counter.
+type counterWriteRequest struct {
+	ObjType  string                 `json:"objType"`
+	ObjName  string                 `json:"objName"`
+	Counters map[string]json.Number `json:"counters"`
+	// Interval is the expected seconds between pushes (default 60). The
+	// object stays alive for twice this long after each push.
+	Interval int     `json:"interval"`
+	Time     apiTime `json:"time"` // epoch ms or RFC3339; default now
+}
+
+// maxWriteBody bounds the request body of the write endpoints.
+const maxWriteBody = 1 << 20
+
+// rejectReadOnly answers 503 with a Retry-After hint of
+// read_only_retry_after_sec if the server is read-only for maintenance.
+func (s *Server) rejectReadOnly(w http.ResponseWriter) bool {
+	if s.readOnly == nil || !s.readOnly() {
+		return false
+	}
+	if cfg := config.Get(); cfg != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(cfg.ReadOnlyRetryAfterSec()))
+	}
+	writeError(w, http.StatusServiceUnavailable, "server is read-only for maintenance")
+	return true
+}
+
+// handleCounterWrite registers an object and records realtime counter values
+// for it, so scripts and batch jobs can chart business KPIs next to APM counters.
+// Body: {"objType": "...", "objName": "/host/name", "counters": {"name": number, ...}}.
+func (s *Server) handleCounterWrite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.rejectReadOnly(w) {
+		return
+	}
+
+	var req counterWriteRequest
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxWriteBody))
+	dec.UseNumber()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	if req.ObjType == "" {
+		writeError(w, http.StatusBadRequest, "missing required field: objType")
+		return
+	}
+	if !strings.HasPrefix(req.ObjName, "/") {
+		writeError(w, http.StatusBadRequest, "objName must be a path such as /host/name")
+		return
+	}
+	if len(req.Counters) == 0 {
+		writeError(w, http.StatusBadRequest, "missing required field: counters")
+		return
+	}
+	if req.Interval < 0 {
+		writeError(w, http.StatusBadRequest, "invalid interval: must not be negative")
+		return
+	}
+	if req.Interval == 0 {
+		req.Interval = 60
+	}
+
+	data := value.NewMapValue()
+	for name, num := range req.Counters {
+		if name == "" {
+			writeError(w, http.StatusBadRequest, "empty counter name")
+			return
+		}
+		if n, err := num.Int64(); err == nil {
+			data.Put(name, value.NewDecimalValue(n))
+		} else if f, err := num.Float64(); err == nil {
+			data.Put(name, &value.DoubleValue{Value: f})
+		} else {
+			writeError(w, http.StatusBadRequest, "counter "+name+" is not a number")
+			return
+		}
+	}
+
+	objHash := util.HashString(req.ObjName)
+	tags := value.NewMapValue()
+	tags.Put(pack.TagDeadTime, value.NewDecimalValue(int64(req.Interval)*2*1000))
+	address := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+	s.ingest(&pack.ObjectPack{
+		ObjType: req.ObjType,
+		ObjHash: objHash,
+		ObjName: req.ObjName,
+		Address: address,
+		Version: "rest",
+		Alive:   true,
+		Tags:    tags,
+	})
+	s.ingest(&pack.PerfCounterPack{
+		Time:     int64(req.Time),
+		ObjName:  req.ObjName,
+		TimeType: cache.TimeTypeRealtime,
+		Data:     data,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"objHash":  objHash,
+		"counters": data.Size(),
+	})
+}
+
+// alertLevels maps the level names accepted by POST /api/v1/alert to
+// AlertPack levels.
+var alertLevels = map[string]byte{"INFO": 0, "WARN": 1, "ERROR": 2, "FATAL": 3}
+
+// alertWriteRequest is the body of POST /api/v1/alert.
+type alertWriteRequest struct {
+	// Source names the external system; the alert is attributed to the
+	// pseudo-object /external/{source} unless ObjName is set.
+	Source  string            `json:"source"`
+	ObjType string            `json:"objType"` // default "external"
+	ObjName string            `json:"objName"`
+	Level   string            `json:"level"` // INFO, WARN, ERROR or FATAL
+	Title   string            `json:"title"`
+	Message string            `json:"message"`
+	Tags    map[string]string `json:"tags"`
+	Time    apiTime           `json:"time"` // epoch ms or RFC3339; default now
+}
+
+// handleAlertWrite records an alert raised by an external system such as a
+// backup job, so it shows up next to the alerts of monitored objects.
+// Body: {"source": "backup", "level": "ERROR", "title": "...", "message": "..."}.
+func (s *Server) handleAlertWrite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.rejectReadOnly(w) {
+		return
+	}
+
+	var req alertWriteRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxWriteBody)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	if req.Title == "" {
+		writeError(w, http.StatusBadRequest, "missing required field: title")
+		return
+	}
+	objName := req.ObjName
+	switch {
+	case objName != "":
+		if !strings.HasPrefix(objName, "/") {
+			writeError(w, http.StatusBadRequest, "objName must be a path such as /host/name")
+			return
+		}
+	case req.Source != "":
+		if strings.Contains(req.Source, "/") {
+			writeError(w, http.StatusBadRequest, "source must not contain '/'")
+			return
+		}
+		objName = "/external/" + req.Source
+	default:
+		writeError(w, http.StatusBadRequest, "missing required field: source or objName")
+		return
+	}
+	level, ok := alertLevels[strings.ToUpper(req.Level)]
+	if req.Level == "" {
+		level, ok = alertLevels["WARN"], true
+	}
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid level: must be INFO, WARN, ERROR or FATAL")
+		return
+	}
+	objType := req.ObjType
+	if objType == "" {
+		objType = "external"
+	}
+
+	var tags *value.MapValue
+	if len(req.Tags) > 0 {
+		tags = value.NewMapValue()
+		for k, v := range req.Tags {
+			tags.Put(k, value.NewTextValue(v))
+		}
+	}
+
+	// Register the object name so clients can resolve the objHash without
+	// the pseudo-object ever showing up (and going inactive) in object lists.
+	objHash := util.HashString(objName)
+	s.ingest(&pack.TextPack{XType: "object", Hash: objHash, Text: objName})
+	s.ingest(&pack.AlertPack{
+		Time:    int64(req.Time),
+		Level:   level,
+		ObjType: objType,
+		ObjHash: objHash,
+		Title:   req.Title,
+		Message: req.Message,
+		Tags:    tags,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"objHash": objHash,
+		"objName": objName,
+	})
+}
+
+// handleSLO returns the current status and error budget of every
+// service-level objective.
+func (s *Server) handleSLO(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"objectives": s.slo.Statuses(time.Now()),
+	})
+}
+
+// handleAlertRules returns the counter alert rules and the conditions
+// currently holding; "start" is set once an alert has been raised.
+func (s *Server) handleAlertRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"rules":  s.alertRules.Store().List(),
+		"active": s.alertRules.Active(),
+	})
+}
+
+// agentResponse is an agent with its times also as RFC3339.
+type agentResponse struct {
+	agentinv.Agent
+	SinceIso    string `json:"sinceIso"`
+	LastSeenIso string `json:"lastSeenIso"`
+}
+
+// versionGroupResponse is agentinv.VersionGroup with agentResponse agents.
+type versionGroupResponse struct {
+	Version string          `json:"version"`
+	Count   int             `json:"count"`
+	Agents  []agentResponse `json:"agents"`
+}
+
+// agentRecordResponse is a history record with its time also as RFC3339.
+type agentRecordResponse struct {
+	agentinv.Record
+	TimeIso string `json:"timeIso"`
+}
+
+// handleAgentVersions lists agents grouped by version, newest first. The
+// optional objType query parameter limits the list to one object type and
+// tz selects the zone of the RFC3339 times.
+func (s *Server) handleAgentVersions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	groups := make([]versionGroupResponse, 0)
+	for _, g := range s.agentInventory.ByVersion(r.URL.Query().Get("objType")) {
+		agents := make([]agentResponse, 0, len(g.Agents))
+		for _, a := range g.Agents {
+			agents = append(agents, agentResponse{
+				Agent:       a,
+				SinceIso:    isoTime(a.Since, loc),
+				LastSeenIso: isoTime(a.LastSeen, loc),
+			})
+		}
+		groups = append(groups, versionGroupResponse{Version: g.Version, Count: g.Count, Agents: agents})
+	}
+	writeJSON(w, map[string]interface{}{
+		"versions": groups,
+	})
+}
+
+// handleAgentHistory returns the recorded version changes of the agent given
+// by the objHash query parameter, or of all agents without it; tz selects
+// the zone of the RFC3339 times.
+func (s *Server) handleAgentHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var objHash int32
+	if v := r.URL.Query().Get("objHash"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid objHash")
+			return
+		}
+		objHash = int32(n)
+	}
+	records, err := s.agentInventory.History(objHash)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	history := make([]agentRecordResponse, 0, len(records))
+	for _, rec := range records {
+		history = append(history, agentRecordResponse{Record: rec, TimeIso: isoTime(rec.Time, loc)})
+	}
+	writeJSON(w, map[string]interface{}{
+		"history": history,
+	})
+}
+
 // writeJSON encodes data as JSON and writes it to the response.
 func writeJSON(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -1,14 +1,45 @@
 package http
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/zbum/scouter-server-go/internal/alertrule"
+	"github.com/zbum/scouter-server-go/internal/apitoken"
+	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db"
+	"github.com/zbum/scouter-server-go/internal/db/agentinv"
+	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/db/kv"
+	"github.com/zbum/scouter-server-go/internal/db/xlog"
+	"github.com/zbum/scouter-server-go/internal/deploywin"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/slo"
 )
 
 // newTestServer creates a Server populated with fresh caches for testing.
@@ -499,3 +530,1517 @@ func TestTextEndpointInvalidHash(t *testing.T) {
 		t.Fatalf("expected 400, got %d", w.Result().StatusCode)
 	}
 }
+
+// testAccounts returns an account manager with the admin account "ops" and
+// the guest account "viewer".
+func testAccounts(t *testing.T) *login.AccountManager {
+	am := login.NewAccountManager(filepath.Join(t.TempDir(), "conf"))
+	am.AddAccount(&login.Account{ID: "ops", Password: "ops-hash", Group: "admin"})
+	am.AddAccount(&login.Account{ID: "viewer", Password: "viewer-hash", Group: "guest"})
+	return am
+}
+
+// asAccount returns r as authenticated for account.
+func asAccount(r *http.Request, account string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, &requestInfo{account: account}))
+}
+
+func TestPurgeEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	xlogDir := filepath.Join(dir, "20260101", "xlog")
+	os.MkdirAll(xlogDir, 0755)
+	os.WriteFile(filepath.Join(xlogDir, "xlog_prof.data"), []byte("p"), 0644)
+
+	s := NewServer(ServerConfig{Purger: db.NewManualPurger(dir), AccountManager: testAccounts(t)})
+
+	target := "/api/v1/admin/purge?date=20260101..20260102&types=profile"
+	for _, account := range []string{"", "viewer"} {
+		w := httptest.NewRecorder()
+		s.handlePurge(w, asAccount(httptest.NewRequest(http.MethodPost, target, nil), account))
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("account %q: expected status 403, got %d", account, w.Code)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(xlogDir, "xlog_prof.data")); err != nil {
+		t.Fatal("profile data deleted without the admin group")
+	}
+
+	req := asAccount(httptest.NewRequest(http.MethodPost, target, nil), "ops")
+	w := httptest.NewRecorder()
+	s.handlePurge(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Results []purgeResponse `json:"results"`
+		Total   int             `json:"total"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body.Total != 2 || !body.Results[0].Deleted || body.Results[1].Skipped == "" {
+		t.Fatalf("unexpected results: %+v", body.Results)
+	}
+	if _, err := os.Stat(filepath.Join(xlogDir, "xlog_prof.data")); !os.IsNotExist(err) {
+		t.Fatal("profile data should be deleted")
+	}
+}
+
+func TestTrashEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("mgr_purge_trash_hours=24\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	dataDir := filepath.Join(dir, "data")
+	os.MkdirAll(filepath.Join(dataDir, "20260101", "alert"), 0755)
+	purger := db.NewManualPurger(dataDir)
+	purger.SetTrash(db.NewTrash(dataDir))
+	s := NewServer(ServerConfig{Purger: purger, AccountManager: testAccounts(t)})
+
+	w := httptest.NewRecorder()
+	s.handlePurge(w, asAccount(httptest.NewRequest(http.MethodPost, "/api/v1/admin/purge?date=20260101&types=all", nil), "ops"))
+	var purged struct {
+		Results []purgeResponse `json:"results"`
+	}
+	json.NewDecoder(w.Body).Decode(&purged)
+	if len(purged.Results) != 1 || purged.Results[0].TrashID == "" {
+		t.Fatalf("purge results = %+v", purged.Results)
+	}
+	id := purged.Results[0].TrashID
+
+	w = httptest.NewRecorder()
+	s.handleTrash(w, asAccount(httptest.NewRequest(http.MethodGet, "/api/v1/admin/trash", nil), "ops"))
+	var list struct {
+		Entries     []db.TrashEntry `json:"entries"`
+		WindowHours int             `json:"windowHours"`
+	}
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list.Entries) != 1 || list.Entries[0].ID != id || list.WindowHours != 24 {
+		t.Fatalf("trash list = %+v", list)
+	}
+
+	restore := httptest.NewRequest(http.MethodPost, "/api/v1/admin/trash/"+id+"/restore", nil)
+	w = httptest.NewRecorder()
+	s.handleTrash(w, asAccount(restore, "viewer"))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("restore without the admin group: expected 403, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.handleTrash(w, asAccount(restore, "ops"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("restore: %d %s", w.Code, w.Body)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "20260101", "alert")); err != nil {
+		t.Error("purged date not restored")
+	}
+
+	w = httptest.NewRecorder()
+	s.handleTrash(w, asAccount(restore, "ops"))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("second restore: expected 404, got %d", w.Code)
+	}
+}
+
+func TestPurgeEndpointBadRequest(t *testing.T) {
+	s := NewServer(ServerConfig{Purger: db.NewManualPurger(t.TempDir()), AccountManager: testAccounts(t)})
+
+	for _, target := range []string{
+		"/api/v1/admin/purge?types=xlog",
+		"/api/v1/admin/purge?date=20260101",
+		"/api/v1/admin/purge?date=20260101&types=bogus",
+	} {
+		req := asAccount(httptest.NewRequest(http.MethodPost, target, nil), "ops")
+		w := httptest.NewRecorder()
+		s.handlePurge(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", target, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/purge?date=20260101&types=xlog", nil)
+	w := httptest.NewRecorder()
+	s.handlePurge(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", w.Code)
+	}
+}
+
+func TestCounterWriteEndpoint(t *testing.T) {
+	var got []pack.Pack
+	s := NewServer(ServerConfig{Ingest: func(p pack.Pack) { got = append(got, p) }})
+
+	body := `{"objType":"batch","objName":"/jobs/orders","counters":{"OrdersPerMin":42,"FillRate":0.75},"interval":300}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/counter", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	s.handleCounterWrite(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 packs, got %d", len(got))
+	}
+	op, ok := got[0].(*pack.ObjectPack)
+	if !ok || op.ObjType != "batch" || op.ObjName != "/jobs/orders" || !op.Alive {
+		t.Fatalf("unexpected object pack: %+v", got[0])
+	}
+	if op.DeadTime() != 600000 {
+		t.Errorf("deadtime = %d, want 600000", op.DeadTime())
+	}
+	cp, ok := got[1].(*pack.PerfCounterPack)
+	if !ok || cp.ObjName != "/jobs/orders" || cp.TimeType != cache.TimeTypeRealtime {
+		t.Fatalf("unexpected counter pack: %+v", got[1])
+	}
+	if v, _ := cp.Data.Get("OrdersPerMin"); v == nil || v.(*value.DecimalValue).Value != 42 {
+		t.Errorf("OrdersPerMin = %v", v)
+	}
+	if v, _ := cp.Data.Get("FillRate"); v == nil || v.(*value.DoubleValue).Value != 0.75 {
+		t.Errorf("FillRate = %v", v)
+	}
+}
+
+func TestCounterWriteEndpointBadRequest(t *testing.T) {
+	s := NewServer(ServerConfig{Ingest: func(p pack.Pack) { t.Errorf("unexpected pack %v", p) }})
+
+	for _, body := range []string{
+		`not json`,
+		`{"objName":"/jobs/orders","counters":{"a":1}}`,
+		`{"objType":"batch","objName":"orders","counters":{"a":1}}`,
+		`{"objType":"batch","objName":"/jobs/orders"}`,
+		`{"objType":"batch","objName":"/jobs/orders","counters":{"a":"x"}}`,
+		`{"objType":"batch","objName":"/jobs/orders","counters":{"a":1},"interval":-1}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/counter", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		s.handleCounterWrite(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/counter", nil)
+	w := httptest.NewRecorder()
+	s.handleCounterWrite(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", w.Code)
+	}
+}
+
+func TestWriteEndpointsReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("read_only_retry_after_sec=120\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	readOnly := true
+	s := NewServer(ServerConfig{
+		Ingest:   func(p pack.Pack) { t.Errorf("unexpected pack %v", p) },
+		ReadOnly: func() bool { return readOnly },
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/counter",
+		strings.NewReader(`{"objType":"batch","objName":"/jobs/orders","counters":{"a":1}}`))
+	w := httptest.NewRecorder()
+	s.handleCounterWrite(w, req)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "120" {
+		t.Errorf("counter: status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/alert", strings.NewReader(`{"source":"backup","title":"X"}`))
+	w = httptest.NewRecorder()
+	s.handleAlertWrite(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("alert: status %d", w.Code)
+	}
+}
+
+func TestAlertWriteEndpoint(t *testing.T) {
+	var got []pack.Pack
+	s := NewServer(ServerConfig{Ingest: func(p pack.Pack) { got = append(got, p) }})
+
+	body := `{"source":"backup","level":"error","title":"BACKUP_FAILED","message":"nightly dump failed","tags":{"host":"db1"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/alert", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	s.handleAlertWrite(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 packs, got %d", len(got))
+	}
+	tp, ok := got[0].(*pack.TextPack)
+	if !ok || tp.XType != "object" || tp.Text != "/external/backup" {
+		t.Fatalf("unexpected text pack: %+v", got[0])
+	}
+	ap, ok := got[1].(*pack.AlertPack)
+	if !ok {
+		t.Fatalf("unexpected pack: %+v", got[1])
+	}
+	if ap.ObjHash != tp.Hash || ap.ObjType != "external" || ap.Level != 2 || ap.Title != "BACKUP_FAILED" {
+		t.Errorf("unexpected alert pack: %+v", ap)
+	}
+	if v, _ := ap.Tags.Get("host"); v == nil || v.(*value.TextValue).Value != "db1" {
+		t.Errorf("host tag = %v", v)
+	}
+}
+
+func TestAlertWriteEndpointBadRequest(t *testing.T) {
+	s := NewServer(ServerConfig{Ingest: func(p pack.Pack) { t.Errorf("unexpected pack %v", p) }})
+
+	for _, body := range []string{
+		`not json`,
+		`{"source":"backup"}`,
+		`{"title":"X"}`,
+		`{"source":"a/b","title":"X"}`,
+		`{"objName":"backup","title":"X"}`,
+		`{"source":"backup","title":"X","level":"CRITICAL"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/alert", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		s.handleAlertWrite(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestAgentVersionsEndpoint(t *testing.T) {
+	inv, err := agentinv.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inv.Close()
+	inv.Update(agentinv.Agent{ObjHash: 1, ObjName: "/a/tomcat", ObjType: "tomcat", Version: "2.9.1"}, 1000)
+	inv.Update(agentinv.Agent{ObjHash: 2, ObjName: "/b/tomcat", ObjType: "tomcat", Version: "2.20.0"}, 1000)
+	inv.Update(agentinv.Agent{ObjHash: 1, ObjName: "/a/tomcat", ObjType: "tomcat", Version: "2.20.0"}, 2000)
+	inv.Update(agentinv.Agent{ObjHash: 3, ObjName: "/c/tomcat", ObjType: "tomcat", Version: "2.17.0"}, 2000)
+	s := NewServer(ServerConfig{AgentInventory: inv})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/agents/versions?objType=tomcat", nil)
+	w := httptest.NewRecorder()
+	s.handleAgentVersions(w, req)
+	var resp struct {
+		Versions []agentinv.VersionGroup `json:"versions"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Versions) != 2 || resp.Versions[0].Count != 2 || !resp.Versions[1].Agents[0].Outdated {
+		t.Errorf("versions = %+v", resp.Versions)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/agents/history?objHash=1", nil)
+	w = httptest.NewRecorder()
+	s.handleAgentHistory(w, req)
+	var hist struct {
+		History []agentinv.Record `json:"history"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&hist); err != nil {
+		t.Fatal(err)
+	}
+	if len(hist.History) != 2 || hist.History[1].PrevVersion != "2.9.1" {
+		t.Errorf("history = %+v", hist.History)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/agents/history?objHash=x", nil)
+	w = httptest.NewRecorder()
+	s.handleAgentHistory(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad objHash: status %d", w.Code)
+	}
+}
+
+func TestSLOEndpoint(t *testing.T) {
+	store := slo.NewStore(kv.NewKVStore(t.TempDir(), "global.json"))
+	store.Put(slo.Objective{Name: "orders", Service: "*", LatencyMs: 500, Target: 99.9})
+	tracker := slo.NewTracker(store, t.TempDir(), func(int32) string { return "/orders" }, nil)
+	tracker.Add("tomcat", &pack.XLogPack{EndTime: time.Now().UnixMilli(), Elapsed: 100})
+	s := NewServer(ServerConfig{SLO: tracker})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/slo", nil)
+	w := httptest.NewRecorder()
+	s.handleSLO(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp struct {
+		Objectives []slo.Status `json:"objectives"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Objectives) != 1 || resp.Objectives[0].Name != "orders" || resp.Objectives[0].Total != 1 {
+		t.Errorf("unexpected objectives: %+v", resp.Objectives)
+	}
+	if resp.Objectives[0].BudgetRemaining != 100 {
+		t.Errorf("budgetRemaining = %v, want 100", resp.Objectives[0].BudgetRemaining)
+	}
+}
+
+func TestAlertRulesEndpoint(t *testing.T) {
+	store := alertrule.NewStore(kv.NewKVStore(t.TempDir(), "global.json"))
+	store.Put(alertrule.Definition{Name: "HIGH_TPS", Rule: alertrule.Rule{Counter: "TPS", Op: ">", Threshold: 100}})
+	engine := alertrule.NewEngine(store, func(int32) (string, string) { return "/host/app1", "tomcat" }, func(*pack.AlertPack) {})
+	engine.Observe(1, time.Now().UnixMilli(), map[string]value.Value{"TPS": value.NewDecimalValue(150)})
+	s := NewServer(ServerConfig{AlertRules: engine})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/alert/rules", nil)
+	w := httptest.NewRecorder()
+	s.handleAlertRules(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp struct {
+		Rules  []alertrule.Definition `json:"rules"`
+		Active []alertrule.Active     `json:"active"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Rules) != 1 || resp.Rules[0].Level != "WARN" {
+		t.Errorf("unexpected rules: %+v", resp.Rules)
+	}
+	if len(resp.Active) != 1 || resp.Active[0].ObjName != "/host/app1" || resp.Active[0].StartMs == 0 {
+		t.Errorf("unexpected active: %+v", resp.Active)
+	}
+}
+
+func TestKVEndpoints(t *testing.T) {
+	dir := t.TempDir()
+	namespaces := kv.NewNamespaces(dir, map[string]*kv.KVStore{"global": kv.NewKVStore(dir, "global.json")})
+	defer namespaces.Close()
+	s := NewServer(ServerConfig{KVNamespaces: namespaces, AccountManager: testAccounts(t)})
+
+	account := "ops"
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := asAccount(httptest.NewRequest(method, path, strings.NewReader(body)), account)
+		w := httptest.NewRecorder()
+		s.handleKV(w, req)
+		return w
+	}
+
+	// Only the admin group creates and drops namespaces.
+	for _, account = range []string{"", "viewer"} {
+		if w := do(http.MethodPut, "/api/v1/kv/plugin", `{"quotaBytes":16}`); w.Code != http.StatusForbidden {
+			t.Fatalf("account %q: create namespace: %d, want 403", account, w.Code)
+		}
+		if w := do(http.MethodDelete, "/api/v1/kv/global", ""); w.Code != http.StatusForbidden {
+			t.Fatalf("account %q: drop namespace: %d, want 403", account, w.Code)
+		}
+	}
+	account = "ops"
+
+	if w := do(http.MethodPut, "/api/v1/kv/plugin", `{"quotaBytes":16}`); w.Code != http.StatusOK {
+		t.Fatalf("create namespace: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPut, "/api/v1/kv/plugin/a/b", `{"value":"v1"}`); w.Code != http.StatusOK {
+		t.Fatalf("put key: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPut, "/api/v1/kv/plugin/big", `{"value":"0123456789abcdef"}`); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("put over quota: %d, want 413", w.Code)
+	}
+	w := do(http.MethodGet, "/api/v1/kv/plugin/a/b", "")
+	var got map[string]string
+	json.NewDecoder(w.Body).Decode(&got)
+	if w.Code != http.StatusOK || got["value"] != "v1" {
+		t.Errorf("get key: %d %v", w.Code, got)
+	}
+
+	w = do(http.MethodGet, "/api/v1/kv", "")
+	var list struct {
+		Namespaces []kv.Namespace `json:"namespaces"`
+	}
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list.Namespaces) != 2 || list.Namespaces[1].Name != "plugin" || list.Namespaces[1].Keys != 1 {
+		t.Errorf("list namespaces: %+v", list.Namespaces)
+	}
+
+	if w := do(http.MethodDelete, "/api/v1/kv/plugin/a/b", ""); w.Code != http.StatusOK {
+		t.Errorf("delete key: %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/v1/kv/global", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("drop builtin: %d, want 400", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/v1/kv/plugin", ""); w.Code != http.StatusOK {
+		t.Errorf("drop namespace: %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/v1/kv/plugin/a/b", ""); w.Code != http.StatusNotFound {
+		t.Errorf("get after drop: %d, want 404", w.Code)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	c := newResponseCache()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	c.now = func() time.Time { return now }
+	calls := 0
+	status := http.StatusOK
+	h := c.wrap(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if status != http.StatusOK {
+			writeError(w, status, "unavailable")
+			return
+		}
+		writeJSON(w, map[string]int{"calls": calls})
+	})
+	get := func(url, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		h(w, req)
+		return w
+	}
+
+	w1 := get("/api/v1/counter/daily?objHash=1&counter=TPS", "")
+	// Same parameters in another order hit the same entry.
+	w2 := get("/api/v1/counter/daily?counter=TPS&objHash=1", "")
+	etag := w1.Header().Get("ETag")
+	if calls != 1 || etag == "" || w2.Header().Get("ETag") != etag || w2.Body.String() != w1.Body.String() {
+		t.Fatalf("calls = %d, etags %q/%q", calls, etag, w2.Header().Get("ETag"))
+	}
+	if w1.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("content type = %q", w1.Header().Get("Content-Type"))
+	}
+	if w := get("/api/v1/counter/daily?objHash=1&counter=TPS", etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("If-None-Match: status %d, body %q", w.Code, w.Body.String())
+	}
+	get("/api/v1/counter/daily?objHash=2&counter=TPS", "")
+	if calls != 2 {
+		t.Errorf("calls = %d after another query, want 2", calls)
+	}
+
+	// Expiry and day rollover read again.
+	now = now.Add(31 * time.Second)
+	if w := get("/api/v1/counter/daily?objHash=1&counter=TPS", etag); w.Code != http.StatusOK || calls != 3 {
+		t.Errorf("after expiry: status %d, calls %d", w.Code, calls)
+	}
+	now = time.Date(2026, 3, 2, 0, 0, 1, 0, time.Local)
+	if get("/api/v1/counter/daily?objHash=1&counter=TPS", ""); calls != 4 {
+		t.Errorf("calls = %d after day rollover, want 4", calls)
+	}
+
+	// Errors are not cached.
+	status = http.StatusServiceUnavailable
+	for i := 0; i < 2; i++ {
+		if w := get("/api/v1/counter/daily?objHash=3&counter=TPS", ""); w.Code != http.StatusServiceUnavailable || w.Header().Get("ETag") != "" {
+			t.Errorf("error response: status %d, etag %q", w.Code, w.Header().Get("ETag"))
+		}
+	}
+	if calls != 6 {
+		t.Errorf("calls = %d, want 6", calls)
+	}
+}
+
+func TestResponseCacheConcurrentMiss(t *testing.T) {
+	c := newResponseCache()
+	var calls atomic.Int32
+	h := c.wrap(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		writeJSON(w, "ok")
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			h(w, httptest.NewRequest(http.MethodGet, "/api/v1/summary/daily", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("status %d", w.Code)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", calls.Load())
+	}
+}
+
+func TestCounterDailyEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	wr := counter.NewCounterWR(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wr.Start(ctx)
+	wr.AddDaily(&counter.DailyEntry{Date: "20260301", ObjHash: 7, CounterName: "TPS", Bucket: 12, Value: 42})
+	for wr.Pending() > 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	wr.Close()
+	s := NewServer(ServerConfig{CounterRD: counter.NewCounterRD(dir)})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/counter/daily?objHash=7&counter=TPS&date=20260301", nil)
+	w := httptest.NewRecorder()
+	s.handleCounterDaily(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		BucketMinutes int        `json:"bucketMinutes"`
+		Values        []*float64 `json:"values"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.BucketMinutes != 5 || len(resp.Values) != counter.BucketsPerDay ||
+		resp.Values[0] != nil || resp.Values[12] == nil || *resp.Values[12] != 42 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	for url, code := range map[string]int{
+		"/api/v1/counter/daily?objHash=8&counter=TPS&date=20260301": http.StatusNotFound,
+		"/api/v1/counter/daily?objHash=7&counter=TPS&date=2026-03":  http.StatusBadRequest,
+		"/api/v1/counter/daily?counter=TPS":                         http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		s.handleCounterDaily(w, httptest.NewRequest(http.MethodGet, url, nil))
+		if w.Code != code {
+			t.Errorf("%s: status %d, want %d", url, w.Code, code)
+		}
+	}
+}
+
+func TestCounterPastEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	wr := counter.NewCounterWR(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wr.Start(ctx)
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	base := day.Add(10 * time.Hour)
+	// objects 7 and 8 report at unaligned seconds of the same 10 seconds
+	wr.AddRealtime(&counter.RealtimeEntry{TimeMs: base.UnixMilli(), ObjHash: 7, Counters: map[string]value.Value{"TPS": value.NewDecimalValue(10)}})
+	wr.AddRealtime(&counter.RealtimeEntry{TimeMs: base.Add(2 * time.Second).UnixMilli(), ObjHash: 7, Counters: map[string]value.Value{"TPS": value.NewDecimalValue(20)}})
+	wr.AddRealtime(&counter.RealtimeEntry{TimeMs: base.Add(3 * time.Second).UnixMilli(), ObjHash: 8, Counters: map[string]value.Value{"TPS": &value.FloatValue{Value: 40}}})
+	wr.AddDaily(&counter.DailyEntry{Date: "20260301", ObjHash: 7, CounterName: "TPS", Bucket: 120, Value: 15})
+	wr.AddDaily(&counter.DailyEntry{Date: "20260301", ObjHash: 8, CounterName: "TPS", Bucket: 120, Value: 45})
+	wr.AddDaily(&counter.DailyEntry{Date: "20260302", ObjHash: 7, CounterName: "TPS", Bucket: 0, Value: 5})
+	for wr.Pending() > 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	wr.Close()
+	objects := cache.NewObjectCache()
+	objects.Put(7, &pack.ObjectPack{ObjHash: 7, ObjName: "/host/app1", ObjType: "tomcat"})
+	objects.Put(8, &pack.ObjectPack{ObjHash: 8, ObjName: "/host/app2", ObjType: "tomcat"})
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("net_http_api_enabled=true\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+	s := NewServer(ServerConfig{CounterRD: counter.NewCounterRD(dir), ObjectCache: objects})
+	handler := s.httpServer.Handler
+
+	type response struct {
+		Series []counterSeries `json:"series"`
+		Agg    string          `json:"agg"`
+	}
+	get := func(url string, code int) response {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		if w.Code != code {
+			t.Fatalf("%s: status %d, want %d: %s", url, w.Code, code, w.Body.String())
+		}
+		var resp response
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp
+	}
+
+	resp := get("/api/v1/counter/20260301?counter=TPS&objHash=7", http.StatusOK)
+	if len(resp.Series) != 1 || resp.Series[0].ObjName != "/host/app1" ||
+		!slices.Equal(resp.Series[0].Value, []float64{10, 20}) || resp.Series[0].Time[0] != base.UnixMilli() {
+		t.Errorf("single object: %+v", resp.Series)
+	}
+	resp = get("/api/v1/counter/20260301?counter=TPS&objType=tomcat&agg=sum", http.StatusOK)
+	if len(resp.Series) != 1 || !slices.Equal(resp.Series[0].Value, []float64{60}) || resp.Series[0].Time[0] != base.UnixMilli() {
+		t.Errorf("sum: %+v", resp.Series)
+	}
+	resp = get("/api/v1/counter/20260301?counter=TPS&objType=tomcat&agg=avg&step=2", http.StatusOK)
+	if len(resp.Series) != 1 || !slices.Equal(resp.Series[0].Value, []float64{10, 30}) {
+		t.Errorf("avg by 2 seconds: %+v", resp.Series)
+	}
+	resp = get("/api/v1/counter/20260301?counter=TPS&objHash=7,8&stime="+strconv.FormatInt(base.Add(time.Second).UnixMilli(), 10), http.StatusOK)
+	if len(resp.Series) != 2 || len(resp.Series[0].Value) != 1 || len(resp.Series[1].Value) != 1 {
+		t.Errorf("stime: %+v", resp.Series)
+	}
+
+	resp = get("/api/v1/counter/20260301..20260302?counter=TPS&objHash=7", http.StatusOK)
+	if len(resp.Series) != 1 || !slices.Equal(resp.Series[0].Value, []float64{15, 5}) ||
+		resp.Series[0].Time[1] != day.AddDate(0, 0, 1).UnixMilli() {
+		t.Errorf("long range: %+v", resp.Series)
+	}
+	resp = get("/api/v1/counter/20260301..20260302?counter=TPS&objType=tomcat&agg=avg", http.StatusOK)
+	if len(resp.Series) != 1 || !slices.Equal(resp.Series[0].Value, []float64{30, 5}) ||
+		resp.Series[0].Time[0] != day.Add(10*time.Hour).UnixMilli() {
+		t.Errorf("long range avg: %+v", resp.Series)
+	}
+
+	get("/api/v1/counter/20260301?objHash=7", http.StatusBadRequest)
+	get("/api/v1/counter/20260301?counter=TPS", http.StatusBadRequest)
+	get("/api/v1/counter/20260302..20260301?counter=TPS&objHash=7", http.StatusBadRequest)
+	get("/api/v1/counter/20250101..20260301?counter=TPS&objHash=7", http.StatusBadRequest)
+	get("/api/v1/counter/20260301?counter=TPS&objHash=7&agg=max", http.StatusBadRequest)
+	// the fixed counter routes still win over the date subtree
+	get("/api/v1/counter/daily?objHash=7&counter=TPS&date=20260301", http.StatusOK)
+}
+
+func TestXLogEndpoints(t *testing.T) {
+	dir := t.TempDir()
+	wr := xlog.NewXLogWR(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	wr.Start(ctx)
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	base := day.Add(10 * time.Hour).UnixMilli()
+	// txid 1..5; 2 and 3 end in the same millisecond
+	for i, end := range []int64{base, base + 1000, base + 1000, base + 2000, base + 3000} {
+		xp := &pack.XLogPack{
+			EndTime: end,
+			ObjHash: int32(10 + i%2),
+			Service: 100,
+			Txid:    int64(i + 1),
+			Gxid:    77,
+			Elapsed: int32(100 * (i + 1)),
+			IPAddr:  []byte{10, 0, 0, 1},
+		}
+		if i == 4 {
+			xp.Error = 200
+		}
+		o := protocol.NewDataOutputX()
+		pack.WritePack(o, xp)
+		wr.Add(&xlog.XLogEntry{Time: xp.EndTime, Txid: xp.Txid, Gxid: xp.Gxid, Elapsed: xp.Elapsed, ObjHash: xp.ObjHash, Data: o.ToByteArray()})
+	}
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	wr.Close()
+
+	texts := cache.NewTextCache()
+	texts.Put("service", 100, "/orders")
+	texts.Put("error", 200, "timeout")
+	rd := xlog.NewXLogRD(dir)
+	defer rd.Close()
+	s := NewServer(ServerConfig{XLogRD: rd, TextCache: texts})
+
+	type page struct {
+		XLogs []xlogJSON `json:"xlogs"`
+		Count int        `json:"count"`
+		Next  string     `json:"next"`
+	}
+	get := func(url string, code int) page {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.handleXLog(w, httptest.NewRequest(http.MethodGet, url, nil))
+		if w.Code != code {
+			t.Fatalf("%s: status %d, want %d: %s", url, w.Code, code, w.Body.String())
+		}
+		var p page
+		json.NewDecoder(w.Body).Decode(&p)
+		return p
+	}
+	// txids lists the txids of p; XLogs of one millisecond come in index order.
+	txids := func(p page) string {
+		var ids []string
+		for _, x := range p.XLogs {
+			ids = append(ids, x.Txid)
+		}
+		slices.Sort(ids)
+		return strings.Join(ids, ",")
+	}
+
+	// Pages of two, the second resuming inside the shared millisecond
+	var all []string
+	pages := 0
+	url := "/api/v1/xlog/20260301?limit=2"
+	for ; pages < 4; pages++ {
+		p := get(url, http.StatusOK)
+		for _, x := range p.XLogs {
+			all = append(all, x.Txid)
+		}
+		if p.Next == "" {
+			break
+		}
+		url = "/api/v1/xlog/20260301?limit=2&cursor=" + p.Next
+	}
+	slices.Sort(all)
+	if got := strings.Join(all, ","); got != "1,2,3,4,5" || pages != 2 {
+		t.Errorf("%d pages of %s, want 3 pages of 1,2,3,4,5", pages+1, got)
+	}
+	if got := txids(get("/api/v1/xlog/20260301?reverse=true&limit=2", http.StatusOK)); got != "4,5" {
+		t.Errorf("reverse: %s", got)
+	}
+	if got := txids(get("/api/v1/xlog/20260301?objHash=11&minElapsed=200", http.StatusOK)); got != "4" {
+		t.Errorf("objHash and minElapsed: %s, want 4", got)
+	}
+	stime := strconv.FormatInt(base+1000, 10)
+	if got := txids(get("/api/v1/xlog/20260301?stime="+stime+"&etime="+stime, http.StatusOK)); got != "2,3" {
+		t.Errorf("time range: %s, want 2,3", got)
+	}
+
+	p := get("/api/v1/xlog/20260301/txid/5", http.StatusOK)
+	if len(p.XLogs) != 1 {
+		t.Fatalf("txid: %+v", p)
+	}
+	x := p.XLogs[0]
+	if x.Service != "/orders" || x.Error != "timeout" || x.IPAddr != "10.0.0.1" || x.Gxid != "77" || x.Elapsed != 500 {
+		t.Errorf("txid 5: %+v", x)
+	}
+	if got := get("/api/v1/xlog/20260301/gxid/77", http.StatusOK); got.Count != 5 {
+		t.Errorf("gxid: %d xlogs, want 5", got.Count)
+	}
+	get("/api/v1/xlog/20260301/txid/9", http.StatusNotFound)
+	get("/api/v1/xlog/2026-03-01", http.StatusBadRequest)
+	get("/api/v1/xlog/20260301?limit=0", http.StatusBadRequest)
+	get("/api/v1/xlog/20260301?cursor=x", http.StatusBadRequest)
+	get("/api/v1/xlog/20260301/spans", http.StatusNotFound)
+}
+
+func TestAlertPreviewEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	wr := counter.NewCounterWR(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wr.Start(ctx)
+	for _, b := range []int{10, 11, 12, 20} {
+		wr.AddDaily(&counter.DailyEntry{Date: "20260301", ObjHash: 7, CounterName: "TPS", Bucket: b, Value: 150})
+	}
+	for wr.Pending() > 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	wr.Close()
+	objects := cache.NewObjectCache()
+	objects.Put(7, &pack.ObjectPack{ObjHash: 7, ObjName: "/host/app", ObjType: "tomcat"})
+	s := NewServer(ServerConfig{CounterRD: counter.NewCounterRD(dir), ObjectCache: objects})
+
+	day, _ := time.ParseInLocation("20060102", "20260301", time.Local)
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.handleAlertPreview(w, httptest.NewRequest(http.MethodPost, "/api/v1/alert/preview", strings.NewReader(body)))
+		return w
+	}
+	w := post(`{"counter":"TPS","op":">","threshold":100,"forSec":600,"objType":"tomcat",` +
+		`"from":` + strconv.FormatInt(day.UnixMilli(), 10) + `,"to":` + strconv.FormatInt(day.AddDate(0, 0, 1).UnixMilli(), 10) + `}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Fired   int `json:"fired"`
+		Objects []struct {
+			ObjName string `json:"objName"`
+			Samples int    `json:"samples"`
+			Firings []struct {
+				Start int64   `json:"start"`
+				End   int64   `json:"end"`
+				Peak  float64 `json:"peak"`
+			} `json:"firings"`
+		} `json:"objects"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	// Buckets 10-12 hold for 10 minutes; bucket 20 alone is too short.
+	bucket := func(b int) int64 { return day.Add(time.Duration(b) * 5 * time.Minute).UnixMilli() }
+	if resp.Fired != 1 || len(resp.Objects) != 1 || resp.Objects[0].ObjName != "/host/app" ||
+		resp.Objects[0].Samples != counter.BucketsPerDay {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if f := resp.Objects[0].Firings[0]; f.Start != bucket(12) || f.End != bucket(13) || f.Peak != 150 {
+		t.Errorf("firing = %+v", f)
+	}
+
+	for _, body := range []string{
+		`{"counter":"TPS","op":"=","threshold":1,"objHash":[7],"from":1,"to":2}`,
+		`{"counter":"TPS","op":">","threshold":1,"objHash":[7],"from":2,"to":1}`,
+		`{"counter":"TPS","op":">","threshold":1,"objType":"unknown","from":1,"to":2}`,
+		`{"counter":"TPS","op":">","threshold":1,"objHash":[7],"from":1,"to":172800000,"source":"realtime"}`,
+	} {
+		if w := post(body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", body, w.Code)
+		}
+	}
+}
+
+func TestAccessLogAndMetrics(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("net_http_api_enabled=true\nnet_http_api_auth_bearer_token_enabled=true\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	var logs bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	am := login.NewAccountManager(filepath.Join(dir, "conf"))
+	am.AddAccount(&login.Account{ID: "ops", Password: "secret-hash", Group: "admin"})
+	s := NewServer(ServerConfig{
+		AccountManager: am,
+		ObjectCache:    cache.NewObjectCache(),
+		CounterCache:   cache.NewCounterCache(),
+		XLogCache:      cache.NewXLogCache(10),
+		TextCache:      cache.NewTextCache(),
+	})
+	handler := s.httpServer.Handler
+
+	for _, path := range []string{"/api/v1/objects", "/api/v1/objects", "/api/v1/text?type=service&hash=1", "/no/such/path"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer secret-hash")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var e map[string]any
+		if json.Unmarshal([]byte(line), &e) == nil && e["msg"] == "HTTP access" {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 access log entries (no /health), got %d: %s", len(entries), logs.String())
+	}
+	if e := entries[0]; e["account"] != "ops" || e["path"] != "/api/v1/objects" || e["status"] != float64(200) || e["method"] != "GET" {
+		t.Errorf("unexpected entry %v", e)
+	}
+	if e := entries[2]; e["status"] != float64(404) {
+		t.Errorf("text lookup status = %v, want 404", e["status"])
+	}
+
+	stats := map[string]EndpointMetrics{}
+	for _, m := range s.metrics.snapshot() {
+		stats[m.Endpoint] = m
+	}
+	if stats["GET /api/v1/objects"].Count != 2 || stats["GET /api/v1/text"].Count != 1 ||
+		stats["other"].Count != 1 || stats["GET /health"].Count != 1 {
+		t.Errorf("unexpected endpoint stats %+v", stats)
+	}
+
+	e := s.metrics.drain()
+	if e.count != 5 {
+		t.Errorf("interval count = %d, want 5", e.count)
+	}
+	if s.metrics.drain().count != 0 {
+		t.Error("drain did not reset the interval")
+	}
+	packs := metricsPacks("/scouter/http", e)
+	cp := packs[1].(*pack.PerfCounterPack)
+	if v, _ := cp.Data.Get("HttpRequests"); v.(*value.DecimalValue).Value != 5 {
+		t.Errorf("HttpRequests = %v", v)
+	}
+	if op := packs[0].(*pack.ObjectPack); op.ObjType != "scouter" || op.ObjName != cp.ObjName {
+		t.Errorf("unexpected object pack %+v", op)
+	}
+}
+
+func TestDeployWindowEndpoint(t *testing.T) {
+	s := NewServer(ServerConfig{ObjectCache: cache.NewObjectCache(), DeployWindows: deploywin.NewManager(nil)})
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.handleDeployWindow(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := do(http.MethodPost, "/api/v1/deploy-window", `{"objects":["/checkout-*/*"],"suppress":["INACTIVE_OBJECT"],"minutes":15,"reason":"release 1.2","by":"ci"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var win deploywin.Window
+	if err := json.NewDecoder(w.Body).Decode(&win); err != nil {
+		t.Fatal(err)
+	}
+	if win.ID == 0 || win.Until.Sub(win.Start) != 15*time.Minute || win.By != "ci" {
+		t.Errorf("window = %+v", win)
+	}
+
+	var list struct {
+		Windows []deploywin.Window `json:"windows"`
+	}
+	w = do(http.MethodGet, "/api/v1/deploy-window", "")
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Windows) != 1 || list.Windows[0].Reason != "release 1.2" {
+		t.Errorf("windows = %+v", list.Windows)
+	}
+
+	if w := do(http.MethodPost, "/api/v1/deploy-window", `{"minutes":0}`); w.Code != http.StatusBadRequest {
+		t.Errorf("zero minutes: status %d, want 400", w.Code)
+	}
+	id := strconv.FormatInt(win.ID, 10)
+	if w := do(http.MethodDelete, "/api/v1/deploy-window/"+id, ""); w.Code != http.StatusOK {
+		t.Errorf("delete: status %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/v1/deploy-window/"+id, ""); w.Code != http.StatusNotFound {
+		t.Errorf("second delete: status %d, want 404", w.Code)
+	}
+}
+
+func TestAPITimeParams(t *testing.T) {
+	var body struct {
+		A apiTime `json:"a"`
+		B apiTime `json:"b"`
+		C apiTime `json:"c"`
+	}
+	if err := json.Unmarshal([]byte(`{"a":1772323200000,"b":"2026-03-01T09:00:00+09:00","c":"1772323200000"}`), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.A != 1772323200000 || body.B != body.A || body.C != body.A {
+		t.Errorf("times = %d, %d, %d; want all 1772323200000", body.A, body.B, body.C)
+	}
+	if err := json.Unmarshal([]byte(`{"a":"2026-03-01 09:00"}`), &body); err == nil {
+		t.Error("expected error for a time without offset")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?tz=%2B09:00", nil)
+	loc, err := requestLocation(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := isoTime(1772323200000, loc); got != "2026-03-01T09:00:00+09:00" {
+		t.Errorf("isoTime = %s", got)
+	}
+	if _, err := requestLocation(httptest.NewRequest(http.MethodGet, "/?tz=Nowhere/City", nil)); err == nil {
+		t.Error("expected error for an unknown zone")
+	}
+
+	// An instant selects the storage day (server local) containing it.
+	instant := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	req = httptest.NewRequest(http.MethodGet, "/?date="+url.QueryEscape(instant.UTC().Format(time.RFC3339)), nil)
+	day, ok := dateParam(req)
+	if !ok || day.Format("20060102") != "20260301" || !day.Equal(startOfDay(instant)) {
+		t.Errorf("dateParam = %v, %v", day, ok)
+	}
+	for _, date := range []string{"20260301", "2026-03-01"} {
+		if day, ok := dateParam(httptest.NewRequest(http.MethodGet, "/?date="+date, nil)); !ok || day.Format("20060102") != "20260301" {
+			t.Errorf("%s: dateParam = %v, %v", date, day, ok)
+		}
+	}
+}
+
+func TestCORSPolicy(t *testing.T) {
+	s := NewServer(ServerConfig{
+		CorsAllowOrigin:  "https://dash.example.com, https://*.ops.example.com",
+		CorsRouteMethods: "/api/v1/admin=GET; /api/v1/admin/trash=GET,POST",
+		CorsMaxAge:       10 * time.Minute,
+		ObjectCache:      cache.NewObjectCache(),
+		CounterCache:     cache.NewCounterCache(),
+		XLogCache:        cache.NewXLogCache(10),
+		TextCache:        cache.NewTextCache(),
+	})
+	handler := s.httpServer.Handler
+	do := func(method, path, origin, preflight string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if preflight != "" {
+			req.Header.Set("Access-Control-Request-Method", preflight)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Listed origins are echoed so credentials work from each of them.
+	for _, origin := range []string{"https://dash.example.com", "https://a.ops.example.com"} {
+		rec := do(http.MethodGet, "/api/v1/objects", origin, "")
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != origin {
+			t.Errorf("%s: Allow-Origin = %q", origin, got)
+		}
+		if rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("%s: credentials not allowed", origin)
+		}
+		if rec.Header().Get("Vary") != "Origin" {
+			t.Errorf("%s: Vary = %q", origin, rec.Header().Get("Vary"))
+		}
+	}
+	for _, origin := range []string{"https://evil.com", "https://ops.example.com", "http://dash.example.com"} {
+		if got := do(http.MethodGet, "/api/v1/objects", origin, "").Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("%s: Allow-Origin = %q, want none", origin, got)
+		}
+	}
+
+	// Preflights are cached and limited to the methods of the route.
+	rec := do(http.MethodOptions, "/api/v1/objects", "https://dash.example.com", "DELETE")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("preflight = %d, Max-Age %q", rec.Code, rec.Header().Get("Access-Control-Max-Age"))
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != defaultCorsMethods {
+		t.Errorf("Allow-Methods = %q", got)
+	}
+	if rec := do(http.MethodOptions, "/api/v1/admin/purge", "https://dash.example.com", "POST"); rec.Code != http.StatusForbidden {
+		t.Errorf("POST preflight on /api/v1/admin = %d, want 403", rec.Code)
+	}
+	rec = do(http.MethodOptions, "/api/v1/admin/trash/1/restore", "https://dash.example.com", "POST")
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("POST preflight on trash = %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST, OPTIONS" {
+		t.Errorf("trash Allow-Methods = %q", got)
+	}
+	if rec := do(http.MethodOptions, "/api/v1/objects", "https://evil.com", "GET"); rec.Code != http.StatusForbidden {
+		t.Errorf("preflight from unlisted origin = %d, want 403", rec.Code)
+	}
+
+	// Cross-origin requests skipping the preflight are refused too, requests
+	// from the server's own pages are not.
+	if rec := do(http.MethodDelete, "/api/v1/admin/trash/1", "https://dash.example.com", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("cross-origin DELETE = %d, want 403", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/v1/admin/trash/1", "http://example.com", ""); strings.Contains(rec.Body.String(), "cross-origin") {
+		t.Error("same-origin DELETE refused")
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/objects", nil)
+	req.Header.Set("Origin", "https://dash.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	newTestServer().httpServer.Handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("preflight = %d, Allow-Origin %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if rec.Header().Get("Access-Control-Max-Age") != "" {
+		t.Errorf("Max-Age without CorsMaxAge = %q", rec.Header().Get("Access-Control-Max-Age"))
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	check := func(s *Server, origin string) bool {
+		req := httptest.NewRequest(http.MethodGet, "http://scouter:6180/ws/v1/xlog", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		return s.wsOriginAllowed(req)
+	}
+
+	// With the default wildcard, only the server's own host may open a
+	// stream from a browser.
+	wildcard := NewServer(ServerConfig{})
+	for origin, want := range map[string]bool{
+		"":                         true,
+		"http://scouter:6180":      true,
+		"https://SCOUTER:6180":     true,
+		"http://scouter:8080":      false,
+		"https://evil.example":     false,
+		"http://scouter:6180.evil": false,
+	} {
+		if got := check(wildcard, origin); got != want {
+			t.Errorf("wildcard: origin %q allowed = %v, want %v", origin, got, want)
+		}
+	}
+
+	listed := NewServer(ServerConfig{CorsAllowOrigin: "https://dash.example.com"})
+	if !check(listed, "https://dash.example.com") || check(listed, "http://scouter:6180") {
+		t.Error("listed origins not applied to WebSockets")
+	}
+}
+
+// wsDial opens a WebSocket to path of the test server at addr.
+func wsDial(t *testing.T, addr, path string) (net.Conn, *bufio.Reader, int) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: %s\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: %s\r\nAccept-Encoding: gzip\r\n\r\n", path, addr, key)
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		sum := sha1.Sum([]byte(key + wsGUID))
+		if got := resp.Header.Get("Sec-WebSocket-Accept"); got != base64.StdEncoding.EncodeToString(sum[:]) {
+			t.Fatalf("Sec-WebSocket-Accept = %q", got)
+		}
+	}
+	return conn, br, resp.StatusCode
+}
+
+// wsReadFrame reads one unmasked frame sent by the server.
+func wsReadFrame(t *testing.T, br *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(br, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	n := int(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(br, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(br, ext[:])
+		n = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatal(err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+// wsWriteFrame sends one masked frame as a client does.
+func wsWriteFrame(conn net.Conn, op byte, payload []byte) {
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | op, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	conn.Write(frame)
+}
+
+func TestStreamEndpoints(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("net_http_api_enabled=true\nnet_http_ws_interval_ms=100\nnet_http_ws_max_conns=2\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	objects := cache.NewObjectCache()
+	objects.Put(1, &pack.ObjectPack{ObjHash: 1, ObjName: "/was1", ObjType: "tomcat", Alive: true})
+	objects.Put(2, &pack.ObjectPack{ObjHash: 2, ObjName: "/db1", ObjType: "mysql", Alive: true})
+	texts := cache.NewTextCache()
+	texts.Put("service", 100, "/orders")
+	s := NewServer(ServerConfig{
+		GzipEnabled:  true,
+		ObjectCache:  objects,
+		CounterCache: cache.NewCounterCache(),
+		XLogCache:    cache.NewXLogCache(100),
+		TextCache:    texts,
+	})
+	ts := httptest.NewServer(s.httpServer.Handler)
+	defer ts.Close()
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	// XLogs put before the stream opened are not sent.
+	putXLog := func(txid int64, objHash, elapsed int32) {
+		o := protocol.NewDataOutputX()
+		pack.WritePack(o, &pack.XLogPack{EndTime: time.Now().UnixMilli(), ObjHash: objHash, Service: 100, Txid: txid, Elapsed: elapsed})
+		s.xlogCache.Put(objHash, elapsed, false, o.ToByteArray())
+	}
+	putXLog(1, 1, 500)
+	conn, br, code := wsDial(t, addr, "/ws/v1/xlog?objHash=1&minElapsed=100")
+	if code != http.StatusSwitchingProtocols {
+		t.Fatalf("xlog stream: status %d", code)
+	}
+	putXLog(2, 1, 50)
+	putXLog(3, 2, 500)
+	putXLog(4, 1, 700)
+	op, payload := wsReadFrame(t, br)
+	var xlogs struct {
+		XLogs []xlogJSON `json:"xlogs"`
+	}
+	if err := json.Unmarshal(payload, &xlogs); op != wsOpText || err != nil {
+		t.Fatalf("op %d: %v: %s", op, err, payload)
+	}
+	if len(xlogs.XLogs) != 1 || xlogs.XLogs[0].Txid != "4" || xlogs.XLogs[0].Service != "/orders" {
+		t.Errorf("unexpected xlogs %+v", xlogs.XLogs)
+	}
+
+	// Pings are answered, and the closing handshake is completed.
+	wsWriteFrame(conn, wsOpPing, []byte("hi"))
+	if op, payload := wsReadFrame(t, br); op != wsOpPong || string(payload) != "hi" {
+		t.Errorf("ping answered with op %d %q", op, payload)
+	}
+	wsWriteFrame(conn, wsOpClose, []byte{0x03, 0xE8})
+	if op, _ := wsReadFrame(t, br); op != wsOpClose {
+		t.Errorf("close answered with op %d", op)
+	}
+
+	// Counters of the objType, realtime and selected names only.
+	_, br, code = wsDial(t, addr, "/ws/v1/counter?objType=tomcat&counter=TPS")
+	if code != http.StatusSwitchingProtocols {
+		t.Fatalf("counter stream: status %d", code)
+	}
+	s.counterCache.Put(cache.CounterKey{ObjHash: 1, Counter: "TPS", TimeType: cache.TimeTypeRealtime}, value.NewDecimalValue(42))
+	s.counterCache.Put(cache.CounterKey{ObjHash: 1, Counter: "Cpu", TimeType: cache.TimeTypeRealtime}, value.NewDecimalValue(5))
+	s.counterCache.Put(cache.CounterKey{ObjHash: 2, Counter: "TPS", TimeType: cache.TimeTypeRealtime}, value.NewDecimalValue(7))
+	_, payload = wsReadFrame(t, br)
+	var counters struct {
+		Counters []counterUpdate `json:"counters"`
+	}
+	json.Unmarshal(payload, &counters)
+	if len(counters.Counters) != 1 || counters.Counters[0].ObjName != "/was1" || counters.Counters[0].Value != float64(42) {
+		t.Errorf("unexpected counters %s", payload)
+	}
+
+	// A second open stream reaches net_http_ws_max_conns; the next is refused.
+	_, br2, code := wsDial(t, addr, "/ws/v1/counter?objHash=2")
+	if code != http.StatusSwitchingProtocols {
+		t.Fatalf("second stream: status %d", code)
+	}
+	if _, _, code := wsDial(t, addr, "/ws/v1/counter?objHash=2"); code != http.StatusServiceUnavailable {
+		t.Errorf("third stream: status %d, want 503", code)
+	}
+	if _, _, code := wsDial(t, addr, "/ws/v1/counter"); code != http.StatusBadRequest {
+		t.Errorf("no objects: status %d, want 400", code)
+	}
+
+	// Shutdown closes the streams as going away.
+	close(s.streamsClosing)
+	for _, r := range []*bufio.Reader{br, br2} {
+		if op, payload := wsReadFrame(t, r); op != wsOpClose || binary.BigEndian.Uint16(payload) != wsCloseGoingAway {
+			t.Errorf("shutdown: op %d %v", op, payload)
+		}
+	}
+}
+
+func TestAlertStreamEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("net_http_api_enabled=true\nnet_http_ws_interval_ms=100\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	alerts := cache.NewAlertCache(100)
+	addAlert := func(level byte, objType, title string) {
+		o := protocol.NewDataOutputX()
+		tags := value.NewMapValue()
+		tags.Put(pack.TagXLogTxid, &value.ListValue{Value: []value.Value{value.NewDecimalValue(9007199254740993)}})
+		pack.WritePack(o, &pack.AlertPack{Time: 1772326800000, Level: level, ObjType: objType, ObjHash: 1, Title: title, Tags: tags})
+		alerts.Add(o.ToByteArray())
+	}
+	addAlert(2, "tomcat", "missed")
+	objects := cache.NewObjectCache()
+	objects.Put(1, &pack.ObjectPack{ObjHash: 1, ObjName: "/was1", ObjType: "tomcat"})
+	s := NewServer(ServerConfig{GzipEnabled: true, ObjectCache: objects, AlertCache: alerts})
+	ts := httptest.NewServer(s.httpServer.Handler)
+	defer ts.Close()
+
+	type event struct {
+		id   string
+		data alertEventJSON
+	}
+	// open reads the first n alert events of a stream.
+	open := func(query, lastID string, n int) []event {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/v1/alert/stream"+query, nil)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || ct != "text/event-stream" {
+			t.Fatalf("status %d, content type %q", resp.StatusCode, ct)
+		}
+		var events []event
+		var cur event
+		sc := bufio.NewScanner(resp.Body)
+		for len(events) < n && sc.Scan() {
+			line := sc.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				cur.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &cur.data)
+			case line == "" && cur.id != "":
+				events = append(events, cur)
+				cur = event{}
+			}
+		}
+		if len(events) < n {
+			t.Fatalf("got %d events, want %d: %v", len(events), n, sc.Err())
+		}
+		return events
+	}
+
+	// New alerts only, filtered by level and objType.
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		addAlert(0, "tomcat", "info")
+		addAlert(2, "mysql", "other type")
+		addAlert(3, "tomcat", "down")
+	}()
+	events := open("?objType=tomcat&level=warn", "", 1)
+	if e := events[0]; e.id != "4" || e.data.Title != "down" || e.data.Level != "FATAL" || e.data.ObjName != "/was1" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if txids, _ := events[0].data.Tags[pack.TagXLogTxid].([]interface{}); len(txids) != 1 || txids[0] != "9007199254740993" {
+		t.Errorf("unexpected tags %v", events[0].data.Tags)
+	}
+
+	// Resuming replays what followed the last event seen.
+	events = open("", "0", 4)
+	var titles []string
+	for _, e := range events {
+		titles = append(titles, e.id+":"+e.data.Title)
+	}
+	if got := strings.Join(titles, ","); got != "1:missed,2:info,3:other type,4:down" {
+		t.Errorf("resumed events %s", got)
+	}
+
+	for _, query := range []string{"?level=LOUD", "?lastEventId=x"} {
+		w := httptest.NewRecorder()
+		s.handleAlertStream(w, httptest.NewRequest(http.MethodGet, "/api/v1/alert/stream"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", query, w.Code)
+		}
+	}
+}
+
+func TestAPITokens(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("net_http_api_enabled=true\nnet_http_api_auth_bearer_token_enabled=true\nnet_http_api_token_max_ttl_days=30\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	am := login.NewAccountManager(filepath.Join(dir, "conf"))
+	am.AddAccount(&login.Account{ID: "ops", Password: "secret-hash", Group: "admin"})
+	am.AddAccount(&login.Account{ID: "viewer", Password: "viewer-hash", Group: "guest"})
+	s := NewServer(ServerConfig{
+		AccountManager: am,
+		ObjectCache:    cache.NewObjectCache(),
+		APITokens:      apitoken.NewStore(kv.NewKVStore(dir, "api_tokens.json")),
+	})
+	do := func(method, path, bearer, body string, code int) tokenJSON {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		w := httptest.NewRecorder()
+		s.httpServer.Handler.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Fatalf("%s %s: status %d, want %d: %s", method, path, w.Code, code, w.Body.String())
+		}
+		var tok tokenJSON
+		json.Unmarshal(w.Body.Bytes(), &tok)
+		return tok
+	}
+
+	// Without session auth, API routes need a token.
+	do(http.MethodGet, "/api/v1/objects", "", "", http.StatusUnauthorized)
+	do(http.MethodGet, "/api/v1/objects", "sct_0123456789", "", http.StatusUnauthorized)
+
+	// An admin, here with a password hash, issues tokens for other accounts.
+	viewer := do(http.MethodPost, "/api/v1/tokens", "secret-hash", `{"name":"grafana","readOnly":true,"account":"viewer"}`, http.StatusCreated)
+	if viewer.Account != "viewer" || !viewer.ReadOnly || !strings.HasPrefix(viewer.Token, apitoken.Prefix) || viewer.Expires == 0 {
+		t.Fatalf("unexpected token %+v", viewer)
+	}
+	do(http.MethodGet, "/api/v1/objects", viewer.Token, "", http.StatusOK)
+	do(http.MethodPost, "/api/v1/tokens", viewer.Token, `{"name":"more"}`, http.StatusForbidden)
+	do(http.MethodPost, "/api/v1/tokens", "secret-hash", `{"name":"long","ttlDays":31}`, http.StatusBadRequest)
+	do(http.MethodPost, "/api/v1/tokens", "secret-hash", `{"name":"x","account":"nobody"}`, http.StatusBadRequest)
+	ops := do(http.MethodPost, "/api/v1/tokens", "secret-hash", `{"name":"ci","ttlDays":1}`, http.StatusCreated)
+	if ops.Account != "ops" || ops.ReadOnly {
+		t.Fatalf("unexpected token %+v", ops)
+	}
+
+	// Accounts see their own tokens, admins every token; secrets never show.
+	list := func(bearer string) string {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/tokens", nil)
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		w := httptest.NewRecorder()
+		s.httpServer.Handler.ServeHTTP(w, req)
+		var body struct {
+			Tokens []tokenJSON `json:"tokens"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		var names []string
+		for _, tok := range body.Tokens {
+			if tok.Token != "" {
+				t.Errorf("secret listed for %s", tok.ID)
+			}
+			names = append(names, tok.Name)
+		}
+		slices.Sort(names)
+		return strings.Join(names, ",")
+	}
+	if got := list(viewer.Token); got != "grafana" {
+		t.Errorf("viewer tokens: %s", got)
+	}
+	if got := list(ops.Token); got != "ci,grafana" {
+		t.Errorf("admin tokens: %s", got)
+	}
+
+	do(http.MethodDelete, "/api/v1/tokens/"+viewer.ID, ops.Token, "", http.StatusOK)
+	do(http.MethodGet, "/api/v1/objects", viewer.Token, "", http.StatusUnauthorized)
+	do(http.MethodDelete, "/api/v1/tokens/"+viewer.ID, ops.Token, "", http.StatusNotFound)
+
+	// Tokens of accounts without write permission are always read-only.
+	gone := do(http.MethodPost, "/api/v1/tokens", ops.Token, `{"name":"temp","account":"viewer"}`, http.StatusCreated)
+	if !gone.ReadOnly {
+		t.Errorf("guest token not forced read-only: %+v", gone)
+	}
+	do(http.MethodGet, "/api/v1/objects", gone.Token, "", http.StatusOK)
+	s.tokens.Revoke(gone.ID)
+	do(http.MethodGet, "/api/v1/objects", gone.Token, "", http.StatusUnauthorized)
+}
+
+func TestAuthMiddleware_WritePermission(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("net_http_api_enabled=true\nnet_http_api_auth_bearer_token_enabled=true\nnet_http_api_auth_session_enabled=true\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	s := NewServer(ServerConfig{
+		AccountManager: testAccounts(t),
+		APITokens:      apitoken.NewStore(kv.NewKVStore(dir, "api_tokens.json")),
+	})
+	do := func(method string, auth func(r *http.Request), code int) {
+		t.Helper()
+		req := httptest.NewRequest(method, "/api/v1/tokens", strings.NewReader(`{"name":"t"}`))
+		auth(req)
+		w := httptest.NewRecorder()
+		s.httpServer.Handler.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Fatalf("%s: status %d, want %d: %s", method, w.Code, code, w.Body.String())
+		}
+	}
+	bearer := func(hash string) func(r *http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+hash) }
+	}
+	cookie := func(id, pass string) func(r *http.Request) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader("id="+id+"&pass="+pass))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		s.httpServer.Handler.ServeHTTP(w, req)
+		cookies := w.Result().Cookies()
+		if w.Code != http.StatusOK || len(cookies) == 0 {
+			t.Fatalf("login %s: status %d", id, w.Code)
+		}
+		if cookies[0].SameSite != http.SameSiteStrictMode {
+			t.Errorf("session cookie SameSite = %v, want Strict", cookies[0].SameSite)
+		}
+		return func(r *http.Request) { r.AddCookie(cookies[0]) }
+	}
+
+	// A guest account reads but does not write, with a password hash bearer...
+	do(http.MethodGet, bearer("viewer-hash"), http.StatusOK)
+	do(http.MethodPost, bearer("viewer-hash"), http.StatusForbidden)
+	// ...or a session cookie.
+	viewer := cookie("viewer", "viewer-hash")
+	do(http.MethodGet, viewer, http.StatusOK)
+	do(http.MethodPost, viewer, http.StatusForbidden)
+	do(http.MethodDelete, viewer, http.StatusForbidden)
+
+	do(http.MethodPost, bearer("ops-hash"), http.StatusCreated)
+	do(http.MethodPost, cookie("ops", "ops-hash"), http.StatusCreated)
+}
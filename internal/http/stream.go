@@ -0,0 +1,210 @@
+package http
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+)
+
+// counterUpdate is one changed counter value pushed by /ws/v1/counter.
+type counterUpdate struct {
+	ObjHash int32       `json:"objHash"`
+	ObjName string      `json:"objName,omitempty"`
+	Counter string      `json:"counter"`
+	Value   interface{} `json:"value"`
+}
+
+// handleXLogStream streams the XLogs arriving from now on over a WebSocket:
+//
+//	GET /ws/v1/xlog?objHash=1,2&minElapsed=1000
+//
+// Every net_http_ws_interval_ms the XLogs added to the realtime cache since
+// the last message are sent as {"xlogs":[...]}, in the form of
+// /api/v1/xlog/{date}, skipping empty intervals. objHash (comma-separated)
+// limits the objects; minElapsed skips faster XLogs except errors. tz sets
+// the zone of the Iso fields.
+func (s *Server) handleXLogStream(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var objHashes map[int32]bool
+	if v := q.Get("objHash"); v != "" {
+		list, ok := s.counterObjects(w, v, "")
+		if !ok {
+			return
+		}
+		objHashes = make(map[int32]bool, len(list))
+		for _, h := range list {
+			objHashes[h] = true
+		}
+	}
+	minElapsed := int32(0)
+	if v := q.Get("minElapsed"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid minElapsed: must be a non-negative integer")
+			return
+		}
+		minElapsed = int32(n)
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	loop, index := s.xlogCache.Position()
+	s.stream(w, r, func(c *wsConn, now time.Time) error {
+		res := s.xlogCache.Get(loop, index, minElapsed, objHashes)
+		loop, index = res.Loop, res.Index
+		if len(res.Data) == 0 {
+			return nil
+		}
+		texts := s.newTextResolver(now.Format("20060102"))
+		xlogs := make([]xlogJSON, 0, len(res.Data))
+		for _, e := range res.Data {
+			if xp := decodeXLog(e.Data); xp != nil {
+				xlogs = append(xlogs, toXLogJSON(xp, texts, loc))
+			}
+		}
+		sort.SliceStable(xlogs, func(i, j int) bool { return xlogs[i].EndTime < xlogs[j].EndTime })
+		return c.WriteJSON(map[string]interface{}{"xlogs": xlogs})
+	})
+}
+
+// handleCounterStream streams the realtime counter values arriving from now
+// on over a WebSocket:
+//
+//	GET /ws/v1/counter?objType=tomcat&counter=TPS,ActiveService
+//
+// objHash (comma-separated) or objType selects the objects, objType
+// including those that start after the stream; counter (comma-separated)
+// limits the counters, all by default. Every net_http_ws_interval_ms the
+// values updated since the last message are sent as
+// {"time":..., "counters":[{"objHash","objName","counter","value"}]},
+// skipping empty intervals.
+func (s *Server) handleCounterStream(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	objType := q.Get("objType")
+	var objHashes map[int32]bool
+	if v := q.Get("objHash"); v != "" || objType == "" {
+		list, ok := s.counterObjects(w, v, "")
+		if !ok {
+			return
+		}
+		objHashes = make(map[int32]bool, len(list))
+		for _, h := range list {
+			objHashes[h] = true
+		}
+	}
+	var counters map[string]bool
+	if v := q.Get("counter"); v != "" {
+		counters = make(map[string]bool)
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				counters[name] = true
+			}
+		}
+	}
+	wanted := func(objHash int32) bool {
+		if objHashes != nil {
+			return objHashes[objHash]
+		}
+		if s.objectCache == nil {
+			return false
+		}
+		info, ok := s.objectCache.Get(objHash)
+		return ok && info.Pack.ObjType == objType
+	}
+
+	seq := s.counterCache.Seq()
+	s.stream(w, r, func(c *wsConn, now time.Time) error {
+		vals, next := s.counterCache.Since(seq)
+		seq = next
+		updates := make([]counterUpdate, 0)
+		for key, v := range vals {
+			if key.TimeType != cache.TimeTypeRealtime || (counters != nil && !counters[key.Counter]) || !wanted(key.ObjHash) {
+				continue
+			}
+			updates = append(updates, counterUpdate{
+				ObjHash: key.ObjHash,
+				ObjName: s.counterObjName(key.ObjHash),
+				Counter: key.Counter,
+				Value:   valueToNumber(v),
+			})
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		sort.Slice(updates, func(i, j int) bool {
+			if updates[i].ObjHash != updates[j].ObjHash {
+				return updates[i].ObjHash < updates[j].ObjHash
+			}
+			return updates[i].Counter < updates[j].Counter
+		})
+		return c.WriteJSON(map[string]interface{}{"time": now.UnixMilli(), "counters": updates})
+	})
+}
+
+// acquireStream counts a new stream, refusing it with 503 when
+// net_http_ws_max_conns are open. It returns the interval between the
+// messages of the stream, and ok once the stream must be released with
+// releaseStream.
+func (s *Server) acquireStream(w http.ResponseWriter) (time.Duration, bool) {
+	interval, maxConns := 500*time.Millisecond, 100
+	if cfg := config.Get(); cfg != nil {
+		interval = time.Duration(cfg.NetHTTPWsIntervalMs()) * time.Millisecond
+		maxConns = cfg.NetHTTPWsMaxConns()
+	}
+	if n := s.streams.Add(1); maxConns > 0 && int(n) > maxConns {
+		s.streams.Add(-1)
+		w.Header().Set("Retry-After", "10")
+		writeError(w, http.StatusServiceUnavailable, "too many streams: at most "+strconv.Itoa(maxConns))
+		return 0, false
+	}
+	return max(interval, 100*time.Millisecond), true
+}
+
+func (s *Server) releaseStream() {
+	s.streams.Add(-1)
+}
+
+// stream upgrades the request and calls push every net_http_ws_interval_ms
+// until the client leaves, a write fails or the server shuts down.
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, push func(c *wsConn, now time.Time) error) {
+	interval, ok := s.acquireStream(w)
+	if !ok {
+		return
+	}
+	defer s.releaseStream()
+
+	c, ok := s.upgradeWebSocket(w, r)
+	if !ok {
+		return
+	}
+	go c.readLoop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-s.streamsClosing:
+			c.Close(wsCloseGoingAway)
+			return
+		case now := <-ticker.C:
+			if err := push(c, now); err != nil {
+				c.conn.Close()
+				return
+			}
+			if err := c.PingIfIdle(now); err != nil {
+				c.conn.Close()
+				return
+			}
+		}
+	}
+}
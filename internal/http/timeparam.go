@@ -0,0 +1,93 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Storage days ("20060102") are days of the server's local zone. The API
+// accepts instants as epoch milliseconds or RFC3339 with an offset and maps
+// them to those days itself, and answers with epoch milliseconds plus an
+// RFC3339 companion (field name + "Iso") in the zone given by the "tz" query
+// parameter, so clients never have to know the server's zone.
+
+// apiTime is a timestamp in a request body: epoch milliseconds as a number,
+// or a string holding epoch milliseconds or RFC3339 with an offset
+// (2026-03-01T09:00:00+09:00). It holds epoch milliseconds; 0 means unset.
+type apiTime int64
+
+func (t *apiTime) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		if s == "" {
+			*t = 0
+			return nil
+		}
+		v, err := parseAPITime(s)
+		if err != nil {
+			return err
+		}
+		*t = apiTime(v.UnixMilli())
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	ms, err := n.Int64()
+	if err != nil {
+		return fmt.Errorf("invalid time %s: use epoch milliseconds or RFC3339", b)
+	}
+	*t = apiTime(ms)
+	return nil
+}
+
+// parseAPITime parses epoch milliseconds or an RFC3339 timestamp.
+func parseAPITime(s string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use epoch milliseconds or RFC3339", s)
+	}
+	return t, nil
+}
+
+// requestLocation returns the zone of the "tz" query parameter: an IANA name
+// such as Asia/Seoul, UTC, or an offset such as +09:00. Without it the
+// server's local zone is used.
+func requestLocation(r *http.Request) (*time.Location, error) {
+	tz := strings.TrimSpace(r.URL.Query().Get("tz"))
+	if tz == "" {
+		return time.Local, nil
+	}
+	if tz[0] == '+' || tz[0] == '-' {
+		if t, err := time.Parse("-07:00", tz); err == nil {
+			_, offset := t.Zone()
+			return time.FixedZone(tz, offset), nil
+		}
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tz %q: use an IANA zone name or an offset such as +09:00", tz)
+	}
+	return loc, nil
+}
+
+// isoTime formats epoch milliseconds as RFC3339 in loc.
+func isoTime(ms int64, loc *time.Location) string {
+	return time.UnixMilli(ms).In(loc).Format(time.RFC3339Nano)
+}
@@ -0,0 +1,152 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/apitoken"
+	"github.com/zbum/scouter-server-go/internal/login"
+)
+
+// tokenRequest is the body of POST /api/v1/tokens.
+type tokenRequest struct {
+	Name string `json:"name"`
+	// TTLDays is the lifetime of the token, net_http_api_token_max_ttl_days
+	// if omitted; 0 never expires and needs that limit to be 0 too.
+	TTLDays  *int `json:"ttlDays"`
+	ReadOnly bool `json:"readOnly"`
+	// Account issues the token for another account; admin group only.
+	Account string `json:"account"`
+}
+
+// tokenJSON describes a token without its secret.
+type tokenJSON struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Account    string `json:"account"`
+	ReadOnly   bool   `json:"readOnly"`
+	Created    int64  `json:"created"`
+	CreatedIso string `json:"createdIso"`
+	Expires    int64  `json:"expires,omitempty"`
+	ExpiresIso string `json:"expiresIso,omitempty"`
+	// Token is the secret, returned once when the token is issued.
+	Token string `json:"token,omitempty"`
+}
+
+func toTokenJSON(t apitoken.Token, loc *time.Location) tokenJSON {
+	j := tokenJSON{
+		ID:         t.ID,
+		Name:       t.Name,
+		Account:    t.Account,
+		ReadOnly:   t.ReadOnly,
+		Created:    t.Created,
+		CreatedIso: isoTime(t.Created, loc),
+		Expires:    t.Expires,
+	}
+	if t.Expires != 0 {
+		j.ExpiresIso = isoTime(t.Expires, loc)
+	}
+	return j
+}
+
+// handleTokens serves the API tokens of the authenticated account, or of
+// every account for the admin group:
+//
+//	GET    /api/v1/tokens        list tokens (admins may filter by account)
+//	POST   /api/v1/tokens        issue a token, its secret in "token"
+//	DELETE /api/v1/tokens/{id}   revoke a token
+//
+// The requests must be authenticated by a session or another token, so an
+// open API cannot hand out tokens.
+func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request) {
+	account := requestAccount(r)
+	if account == "" {
+		writeError(w, http.StatusForbidden, "token management needs an authenticated account")
+		return
+	}
+	admin := false
+	if s.accountManager != nil {
+		if acct := s.accountManager.GetAccount(account); acct != nil {
+			admin = acct.Group == "admin"
+		}
+	}
+
+	if id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/tokens"), "/"); id != "" {
+		if r.Method != http.MethodDelete {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		if t, ok := s.tokens.Get(id); !ok || (!admin && t.Account != account) {
+			writeError(w, http.StatusNotFound, "no such token")
+			return
+		}
+		if _, err := s.tokens.Revoke(id); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, map[string]interface{}{"id": id, "revoked": true})
+		return
+	}
+
+	loc, err := requestLocation(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		filter := account
+		if admin {
+			filter = r.URL.Query().Get("account")
+		}
+		tokens := make([]tokenJSON, 0)
+		for _, t := range s.tokens.List(filter) {
+			tokens = append(tokens, toTokenJSON(t, loc))
+		}
+		writeJSON(w, map[string]interface{}{"tokens": tokens})
+	case http.MethodPost:
+		var req tokenRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxWriteBody)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+		owner := account
+		if req.Account != "" && req.Account != account {
+			if !admin {
+				writeError(w, http.StatusForbidden, "issuing tokens for other accounts needs the admin group")
+				return
+			}
+			if s.accountManager == nil || s.accountManager.GetAccount(req.Account) == nil {
+				writeError(w, http.StatusBadRequest, "no such account: "+req.Account)
+				return
+			}
+			owner = req.Account
+		}
+		ttl, err := apitoken.TTL(req.TTLDays)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		// Accounts that may not write over TCP only get read-only tokens,
+		// which authMiddleware refuses for writes whatever the account.
+		readOnly := req.ReadOnly
+		if s.accountManager != nil && s.accountManager.AccountPermission(owner) < login.PermWrite {
+			readOnly = true
+		}
+		secret, t, err := s.tokens.Issue(req.Name, owner, ttl, readOnly, time.Now())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		resp := toTokenJSON(t, loc)
+		resp.Token = secret
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(resp)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/zbum/scouter-server-go/internal/db"
+)
+
+// handleTrash serves the data removed by manual purges during its undo
+// window (mgr_purge_trash_hours):
+//
+//	GET    /api/v1/admin/trash              list the entries
+//	POST   /api/v1/admin/trash/{id}/restore undo the purge of an entry
+//	DELETE /api/v1/admin/trash/{id}         remove an entry permanently now
+//
+// Like the purge itself, it is limited to the admin group.
+func (s *Server) handleTrash(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdmin(r) {
+		writeError(w, http.StatusForbidden, "trash management needs an account of the admin group")
+		return
+	}
+	trash := s.purger.Trash()
+	if trash == nil {
+		writeError(w, http.StatusNotFound, "trash is not enabled")
+		return
+	}
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/trash"), "/")
+	id, action, _ := strings.Cut(rest, "/")
+
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		entries, err := trash.List()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"entries":     entries,
+			"windowHours": int(trash.Window().Hours()),
+		})
+	case id != "" && action == "restore" && r.Method == http.MethodPost:
+		e, err := s.purger.Restore(id)
+		if errors.Is(err, db.ErrNoTrashEntry) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		writeJSON(w, map[string]interface{}{"restored": e})
+	case id != "" && action == "" && r.Method == http.MethodDelete:
+		if err := trash.Remove(id); err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeJSON(w, map[string]interface{}{"id": id, "removed": true})
+	case id == "" || action == "" || action == "restore":
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
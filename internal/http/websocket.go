@@ -0,0 +1,280 @@
+package http
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket (RFC 6455) support for the streaming endpoints. The server only
+// pushes JSON text messages; what clients send besides pings and the closing
+// handshake is read and discarded.
+
+const (
+	wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+
+	wsCloseNormal    = 1000
+	wsCloseGoingAway = 1001
+	wsCloseProtocol  = 1002
+	wsCloseTooBig    = 1009
+
+	// wsMaxReadPayload bounds the frames accepted from clients.
+	wsMaxReadPayload = 64 * 1024
+	// wsWriteTimeout bounds one write; a client that does not read in time
+	// is disconnected instead of holding back its stream.
+	wsWriteTimeout = 10 * time.Second
+	// wsPingInterval is the idle time after which the server pings, so
+	// proxies do not close quiet streams.
+	wsPingInterval = 30 * time.Second
+)
+
+var errWSClosed = errors.New("websocket closed")
+
+// wsConn is an upgraded connection. Writes may come from several goroutines;
+// readLoop must run in its own.
+type wsConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	mu        sync.Mutex
+	closed    bool
+	lastWrite time.Time
+
+	done chan struct{} // closed when the client is gone
+}
+
+// upgradeWebSocket checks the handshake of r and switches the connection to
+// the WebSocket protocol. On failure it has answered the request itself.
+func (s *Server) upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, bool) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return nil, false
+	}
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		writeError(w, http.StatusBadRequest, "websocket upgrade required")
+		return nil, false
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		writeError(w, http.StatusBadRequest, "unsupported websocket version")
+		return nil, false
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		writeError(w, http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+		return nil, false
+	}
+	if !s.wsOriginAllowed(r) {
+		writeError(w, http.StatusForbidden, "origin not allowed")
+		return nil, false
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "websocket not supported")
+		return nil, false
+	}
+	sum := sha1.Sum([]byte(key + wsGUID))
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+	conn.SetDeadline(time.Time{})
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if _, err := conn.Write([]byte(resp)); err != nil {
+		conn.Close()
+		return nil, false
+	}
+	return &wsConn{conn: conn, br: brw.Reader, lastWrite: time.Now(), done: make(chan struct{})}, true
+}
+
+// wsOriginAllowed checks the Origin of a WebSocket handshake. Browsers do not
+// apply CORS to WebSockets, so the origins allowed for the API are checked
+// here. A wildcard CORS origin only opens the API to cookie-less requests,
+// while a handshake carries the session cookie, so it then has to come from
+// the server's own host. Requests without an Origin are not from browsers.
+func (s *Server) wsOriginAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if s.cors.anyOrigin {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+	return s.cors.allowOrigin(origin) != ""
+}
+
+// headerHasToken reports whether the comma-separated header name lists token.
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteJSON sends v as a text message.
+func (c *wsConn) WriteJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(wsOpText, b)
+}
+
+// PingIfIdle pings the client when nothing was sent for wsPingInterval.
+func (c *wsConn) PingIfIdle(now time.Time) error {
+	c.mu.Lock()
+	idle := now.Sub(c.lastWrite) >= wsPingInterval
+	c.mu.Unlock()
+	if !idle {
+		return nil
+	}
+	return c.writeFrame(wsOpPing, nil)
+}
+
+// Close sends a close frame with code and closes the connection.
+func (c *wsConn) Close(code int) {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	c.writeFrame(wsOpClose, payload)
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.conn.Close()
+}
+
+// writeFrame writes one unmasked, unfragmented frame.
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errWSClosed
+	}
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | op
+	switch n := len(payload); {
+	case n <= 125:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if _, err := (&net.Buffers{header, payload}).WriteTo(c.conn); err != nil {
+		c.closed = true
+		c.conn.Close()
+		return err
+	}
+	c.lastWrite = time.Now()
+	return nil
+}
+
+// readLoop reads the client's frames until it closes the connection or
+// breaks the protocol, answering pings and the closing handshake, then
+// closes done.
+func (c *wsConn) readLoop() {
+	defer close(c.done)
+	for {
+		op, payload, err := c.readFrame()
+		if err != nil {
+			switch {
+			case errors.Is(err, errWSTooBig):
+				c.Close(wsCloseTooBig)
+			case errors.Is(err, errWSProtocol):
+				c.Close(wsCloseProtocol)
+			default:
+				c.conn.Close()
+			}
+			return
+		}
+		switch op {
+		case wsOpPing:
+			c.writeFrame(wsOpPong, payload)
+		case wsOpClose:
+			c.Close(wsCloseNormal)
+			return
+		}
+	}
+}
+
+var (
+	errWSTooBig   = errors.New("websocket frame too big")
+	errWSProtocol = errors.New("websocket protocol error")
+)
+
+// readFrame reads one frame sent by the client, unmasking its payload.
+func (c *wsConn) readFrame() (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return 0, nil, err
+	}
+	op := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	n := uint64(head[1] & 0x7F)
+	if head[0]&0x70 != 0 || !masked {
+		return 0, nil, errWSProtocol
+	}
+	switch op {
+	case wsOpContinuation, wsOpText, wsOpBinary:
+	case wsOpClose, wsOpPing, wsOpPong:
+		if n > 125 || head[0]&0x80 == 0 {
+			return 0, nil, errWSProtocol
+		}
+	default:
+		return 0, nil, errWSProtocol
+	}
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > wsMaxReadPayload {
+		return 0, nil, errWSTooBig
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return op, payload, nil
+}
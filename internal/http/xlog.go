@@ -0,0 +1,383 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/db/xlog"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+const (
+	xlogPageDefault = 100
+	xlogPageMax     = 10000
+)
+
+// xlogJSON is the JSON representation of a stored XLog with its text hashes
+// resolved. 64-bit IDs are decimal strings, as JSON numbers lose their
+// precision in JavaScript.
+type xlogJSON struct {
+	EndTime      int64  `json:"endTime"`
+	EndTimeIso   string `json:"endTimeIso"`
+	ObjHash      int32  `json:"objHash"`
+	ObjName      string `json:"objName,omitempty"`
+	Service      string `json:"service"`
+	ServiceHash  int32  `json:"serviceHash"`
+	Txid         string `json:"txid"`
+	Gxid         string `json:"gxid,omitempty"`
+	Caller       string `json:"caller,omitempty"`
+	Elapsed      int32  `json:"elapsed"`
+	Error        string `json:"error,omitempty"`
+	ErrorHash    int32  `json:"errorHash,omitempty"`
+	Cpu          int32  `json:"cpu"`
+	SqlCount     int32  `json:"sqlCount"`
+	SqlTime      int32  `json:"sqlTime"`
+	ApicallCount int32  `json:"apicallCount"`
+	ApicallTime  int32  `json:"apicallTime"`
+	Kbytes       int32  `json:"kbytes"`
+	Status       int32  `json:"status,omitempty"`
+	IPAddr       string `json:"ipaddr,omitempty"`
+	Userid       string `json:"userid,omitempty"`
+	UserAgent    string `json:"userAgent,omitempty"`
+	Referer      string `json:"referer,omitempty"`
+	Group        string `json:"group,omitempty"`
+	Login        string `json:"login,omitempty"`
+	Desc         string `json:"desc,omitempty"`
+	CountryCode  string `json:"countryCode,omitempty"`
+	City         string `json:"city,omitempty"`
+	XType        byte   `json:"xType"`
+	Text1        string `json:"text1,omitempty"`
+	Text2        string `json:"text2,omitempty"`
+	Text3        string `json:"text3,omitempty"`
+	Text4        string `json:"text4,omitempty"`
+	Text5        string `json:"text5,omitempty"`
+	ProfileCount int32  `json:"profileCount"`
+	ProfileSize  int32  `json:"profileSize,omitempty"`
+}
+
+// handleXLog serves stored XLogs of one storage day (YYYYMMDD):
+//
+//	GET /api/v1/xlog/{date}              XLogs by time, a page at a time
+//	GET /api/v1/xlog/{date}/txid/{txid}  one XLog by transaction ID
+//	GET /api/v1/xlog/{date}/gxid/{gxid}  the XLogs of a global transaction,
+//	                                     also from the adjacent days
+//
+// The time query takes stime and etime (epoch ms or RFC3339, default the
+// whole day), objHash and service (comma-separated hashes), minElapsed (only
+// slower XLogs, at least xlog_pasttime_lower_bound_ms as for
+// TRANX_LOAD_TIME_GROUP), reverse (latest first), limit (page size, default
+// 100, at most 10000) and cursor (the "next" of the previous page). tz sets
+// the zone of the Iso fields.
+func (s *Server) handleXLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/xlog"), "/"), "/")
+	day, err := time.ParseInLocation("20060102", parts[0], time.Local)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid date: use YYYYMMDD")
+		return
+	}
+	date := parts[0]
+	texts := s.newTextResolver(date)
+
+	switch {
+	case len(parts) == 1:
+		s.handleXLogRange(w, r, day, texts, loc)
+	case len(parts) == 3 && (parts[1] == "txid" || parts[1] == "gxid"):
+		id, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid "+parts[1]+": must be a 64-bit integer")
+			return
+		}
+		xlogs := make([]xlogJSON, 0)
+		add := func(data []byte) {
+			if xp := decodeXLog(data); xp != nil {
+				xlogs = append(xlogs, toXLogJSON(xp, texts, loc))
+			}
+		}
+		if parts[1] == "txid" {
+			if data := s.xlogByTxid(date, id); data != nil {
+				add(data)
+			}
+			if len(xlogs) == 0 {
+				writeError(w, http.StatusNotFound, "xlog not found")
+				return
+			}
+		} else {
+			dates := xlog.GxidDates(date)
+			if s.xlogWR != nil {
+				dates, _ = s.xlogWR.ReadByGxidDates(dates, id, add)
+			}
+			s.xlogRD.ReadByGxidDates(dates, id, add)
+		}
+		writeJSON(w, map[string]interface{}{"date": date, "xlogs": xlogs, "count": len(xlogs)})
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleXLogRange serves a page of the XLogs of day by time. A page ends at
+// the XLog named by its "next" cursor ({endTime}:{txid}); the following page
+// resumes after it, so XLogs sharing a millisecond are neither repeated nor
+// skipped.
+func (s *Server) handleXLogRange(w http.ResponseWriter, r *http.Request, day time.Time, texts *textResolver, loc *time.Location) {
+	q := r.URL.Query()
+	date := day.Format("20060102")
+	stime, etime := day.UnixMilli(), day.AddDate(0, 0, 1).UnixMilli()-1
+	for name, dst := range map[string]*int64{"stime": &stime, "etime": &etime} {
+		if v := q.Get(name); v != "" {
+			t, err := parseAPITime(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid "+name+": use epoch milliseconds or RFC3339")
+				return
+			}
+			*dst = t.UnixMilli()
+		}
+	}
+	limit := xlogPageDefault
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > xlogPageMax {
+			writeError(w, http.StatusBadRequest, "invalid limit: must be between 1 and "+strconv.Itoa(xlogPageMax))
+			return
+		}
+		limit = n
+	}
+	var sel xlog.Selection
+	objHashes := make(map[int32]bool)
+	services := make(map[int32]bool)
+	for name, dst := range map[string]map[int32]bool{"objHash": objHashes, "service": services} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		for _, f := range strings.Split(v, ",") {
+			h, err := strconv.ParseInt(strings.TrimSpace(f), 10, 32)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid "+name+": must be 32-bit integers")
+				return
+			}
+			if !dst[int32(h)] {
+				dst[int32(h)] = true
+				if name == "objHash" {
+					sel.ObjHashes = append(sel.ObjHashes, int32(h))
+				} else {
+					sel.Services = append(sel.Services, int32(h))
+				}
+			}
+		}
+	}
+	minElapsed := int32(0)
+	if cfg := config.Get(); cfg != nil {
+		minElapsed = int32(cfg.XLogPasttimeLowerBoundMs())
+	}
+	if v := q.Get("minElapsed"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid minElapsed: must be a non-negative integer")
+			return
+		}
+		minElapsed = max(minElapsed, int32(n))
+	}
+	reverse := q.Get("reverse") == "true"
+
+	// Resume after the cursor XLog: the range restarts at its millisecond
+	// and the XLogs up to it, in read order, are skipped.
+	var cursorTime, cursorTxid int64
+	skipping := false
+	if v := q.Get("cursor"); v != "" {
+		ts, txid, ok := strings.Cut(v, ":")
+		t, err1 := strconv.ParseInt(ts, 10, 64)
+		id, err2 := strconv.ParseInt(txid, 10, 64)
+		if !ok || err1 != nil || err2 != nil {
+			writeError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+		if reverse {
+			etime = t
+		} else {
+			stime = t
+		}
+		cursorTime, cursorTxid, skipping = t, id, true
+	}
+
+	xlogs := make([]xlogJSON, 0)
+	var last *pack.XLogPack
+	more := false
+	handler := func(data []byte) bool {
+		xp := decodeXLog(data)
+		if xp == nil {
+			return true
+		}
+		if skipping {
+			if xp.EndTime == cursorTime {
+				skipping = xp.Txid != cursorTxid
+				return true
+			}
+			skipping = false // the cursor XLog is gone; its millisecond is passed
+		}
+		if len(objHashes) > 0 && !objHashes[xp.ObjHash] {
+			return true
+		}
+		if len(services) > 0 && !services[xp.Service] {
+			return true
+		}
+		if minElapsed > 0 && xp.Elapsed <= minElapsed {
+			return true
+		}
+		if len(xlogs) == limit {
+			more = true
+			return false
+		}
+		xlogs = append(xlogs, toXLogJSON(xp, texts, loc))
+		last = xp
+		return true
+	}
+	if s.xlogWR == nil {
+		s.xlogRD.ReadSelected(date, stime, etime, sel, reverse, handler)
+	} else if found, _ := s.xlogWR.ReadSelected(date, stime, etime, sel, reverse, handler); !found {
+		s.xlogRD.ReadSelected(date, stime, etime, sel, reverse, handler)
+	}
+
+	resp := map[string]interface{}{
+		"date":  date,
+		"xlogs": xlogs,
+		"count": len(xlogs),
+	}
+	if more {
+		resp["next"] = strconv.FormatInt(last.EndTime, 10) + ":" + strconv.FormatInt(last.Txid, 10)
+	}
+	writeJSON(w, resp)
+}
+
+// xlogByTxid returns the stored XLog of txid on date, or nil.
+func (s *Server) xlogByTxid(date string, txid int64) []byte {
+	if s.xlogWR != nil {
+		if data, found, _ := s.xlogWR.GetByTxid(date, txid); found {
+			return data
+		}
+	}
+	data, _ := s.xlogRD.GetByTxid(date, txid)
+	return data
+}
+
+func decodeXLog(data []byte) *pack.XLogPack {
+	p, err := pack.ReadPack(protocol.NewDataInputX(data))
+	if err != nil {
+		return nil
+	}
+	xp, _ := p.(*pack.XLogPack)
+	return xp
+}
+
+func toXLogJSON(xp *pack.XLogPack, texts *textResolver, loc *time.Location) xlogJSON {
+	x := xlogJSON{
+		EndTime:      xp.EndTime,
+		EndTimeIso:   isoTime(xp.EndTime, loc),
+		ObjHash:      xp.ObjHash,
+		ObjName:      texts.objName(xp.ObjHash),
+		Service:      texts.get("service", xp.Service),
+		ServiceHash:  xp.Service,
+		Txid:         strconv.FormatInt(xp.Txid, 10),
+		Elapsed:      xp.Elapsed,
+		Cpu:          xp.Cpu,
+		SqlCount:     xp.SqlCount,
+		SqlTime:      xp.SqlTime,
+		ApicallCount: xp.ApicallCount,
+		ApicallTime:  xp.ApicallTime,
+		Kbytes:       xp.Kbytes,
+		Status:       xp.Status,
+		UserAgent:    texts.get("ua", xp.UserAgent),
+		Referer:      texts.get("referer", xp.Referer),
+		Group:        texts.get("group", xp.Group),
+		Login:        texts.get("login", xp.Login),
+		Desc:         texts.get("desc", xp.Desc),
+		CountryCode:  xp.CountryCode,
+		City:         texts.get("city", xp.City),
+		XType:        xp.XType,
+		Text1:        xp.Text1,
+		Text2:        xp.Text2,
+		Text3:        xp.Text3,
+		Text4:        xp.Text4,
+		Text5:        xp.Text5,
+		ProfileCount: xp.ProfileCount,
+		ProfileSize:  xp.ProfileSize,
+	}
+	if xp.Gxid != 0 {
+		x.Gxid = strconv.FormatInt(xp.Gxid, 10)
+	}
+	if xp.Caller != 0 {
+		x.Caller = strconv.FormatInt(xp.Caller, 10)
+	}
+	if xp.Error != 0 {
+		x.Error = texts.get("error", xp.Error)
+		x.ErrorHash = xp.Error
+	}
+	if len(xp.IPAddr) == net.IPv4len || len(xp.IPAddr) == net.IPv6len {
+		x.IPAddr = net.IP(xp.IPAddr).String()
+	}
+	if xp.Userid != 0 {
+		x.Userid = strconv.FormatInt(xp.Userid, 10)
+	}
+	return x
+}
+
+// textResolver resolves the text hashes of one day's XLogs, from the text
+// cache, then the permanent and daily text stores, remembering each answer
+// for the request.
+type textResolver struct {
+	s     *Server
+	date  string
+	known map[string]map[int32]string
+}
+
+func (s *Server) newTextResolver(date string) *textResolver {
+	return &textResolver{s: s, date: date, known: make(map[string]map[int32]string)}
+}
+
+func (t *textResolver) get(div string, hash int32) string {
+	if hash == 0 {
+		return ""
+	}
+	m := t.known[div]
+	if m == nil {
+		m = make(map[int32]string)
+		t.known[div] = m
+	}
+	if text, ok := m[hash]; ok {
+		return text
+	}
+	text, ok := "", false
+	if t.s.textCache != nil {
+		text, ok = t.s.textCache.Get(div, hash)
+	}
+	if !ok && t.s.textRD != nil {
+		if text, _ = t.s.textRD.GetString(div, hash); text == "" {
+			text, _ = t.s.textRD.GetDailyString(t.date, div, hash)
+		}
+	}
+	m[hash] = text
+	return text
+}
+
+// objName returns the name of a registered object, else its "object" text.
+func (t *textResolver) objName(objHash int32) string {
+	if t.s.objectCache != nil {
+		if info, ok := t.s.objectCache.Get(objHash); ok {
+			return info.Pack.ObjName
+		}
+	}
+	return t.get("object", objHash)
+}
@@ -0,0 +1,72 @@
+// Package ingest measures how long packs take from UDP receive until the
+// writers have indexed them, and sends the texts of packs the server builds
+// itself.
+package ingest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// bounds are the upper bounds of the histogram buckets; a last bucket holds
+// everything slower.
+var bounds = []time.Duration{
+	time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 20 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+}
+
+// Histogram counts latencies in the buckets bounded by Bounds.
+type Histogram = util.Histogram[time.Duration]
+
+// Bounds returns the upper bounds of the histogram buckets.
+func Bounds() []time.Duration {
+	return bounds
+}
+
+// Latency keeps a histogram per pack type.
+type Latency struct {
+	mu    sync.Mutex
+	hists map[string]*Histogram
+}
+
+var latency = &Latency{hists: make(map[string]*Histogram)}
+
+// GetLatency returns the process-wide ingest latency recorder.
+func GetLatency() *Latency {
+	return latency
+}
+
+// Observe records that a pack of kind ("xlog", "profile", "counter")
+// received at received is now indexed. Packs without a receive time, such as
+// those the server ingests itself, are ignored.
+func (l *Latency) Observe(kind string, received time.Time) {
+	if received.IsZero() {
+		return
+	}
+	d := time.Since(received)
+	l.mu.Lock()
+	h := l.hists[kind]
+	if h == nil {
+		hist := util.NewHistogram(bounds)
+		h = &hist
+		l.hists[kind] = h
+	}
+	h.Observe(d)
+	l.mu.Unlock()
+}
+
+// Drain returns the histograms recorded since the last Drain and starts new ones.
+func (l *Latency) Drain() map[string]Histogram {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := make(map[string]Histogram, len(l.hists))
+	for kind, h := range l.hists {
+		result[kind] = *h
+	}
+	clear(l.hists)
+	return result
+}
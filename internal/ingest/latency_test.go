@@ -0,0 +1,24 @@
+package ingest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatency_ObserveDrain(t *testing.T) {
+	l := &Latency{hists: make(map[string]*Histogram)}
+	l.Observe("xlog", time.Now().Add(-20*time.Millisecond))
+	l.Observe("xlog", time.Time{})
+	l.Observe("counter", time.Now())
+
+	hists := l.Drain()
+	if hists["xlog"].Count != 1 || hists["counter"].Count != 1 {
+		t.Fatalf("hists = %+v", hists)
+	}
+	if hists["xlog"].Max < 20*time.Millisecond {
+		t.Errorf("xlog max = %s", hists["xlog"].Max)
+	}
+	if len(l.Drain()) != 0 {
+		t.Error("Drain did not reset")
+	}
+}
@@ -0,0 +1,51 @@
+package ingest
+
+import (
+	"sync"
+
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+type textKey struct {
+	xtype string
+	hash  int32
+}
+
+// Texts sends the texts of packs built from raw strings, such as probe
+// results and OTLP spans, as TextPacks the first time they are seen. The set
+// of texts sent is cleared once it holds max entries.
+type Texts struct {
+	ingest func(p pack.Pack)
+	max    int
+
+	mu   sync.Mutex
+	sent map[textKey]struct{}
+}
+
+// NewTexts creates a Texts handing its TextPacks to ingest.
+func NewTexts(ingest func(p pack.Pack), max int) *Texts {
+	return &Texts{ingest: ingest, max: max, sent: make(map[textKey]struct{})}
+}
+
+// Text returns the hash of s, sending it as a text of xtype the first time.
+func (t *Texts) Text(xtype, s string) int32 {
+	if s == "" {
+		return 0
+	}
+	hash := util.HashString(s)
+	key := textKey{xtype, hash}
+	t.mu.Lock()
+	_, sent := t.sent[key]
+	if !sent {
+		if len(t.sent) >= t.max {
+			t.sent = make(map[textKey]struct{})
+		}
+		t.sent[key] = struct{}{}
+	}
+	t.mu.Unlock()
+	if !sent {
+		t.ingest(&pack.TextPack{XType: xtype, Hash: hash, Text: s})
+	}
+	return hash
+}
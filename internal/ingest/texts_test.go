@@ -0,0 +1,32 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+func TestTexts_SendOnce(t *testing.T) {
+	var sent []*pack.TextPack
+	texts := NewTexts(func(p pack.Pack) { sent = append(sent, p.(*pack.TextPack)) }, 2)
+
+	if h := texts.Text("service", ""); h != 0 || len(sent) != 0 {
+		t.Fatalf("empty text: hash %d, %d sent", h, len(sent))
+	}
+	if h := texts.Text("service", "/orders"); h != util.HashString("/orders") {
+		t.Errorf("hash = %d", h)
+	}
+	texts.Text("service", "/orders")
+	texts.Text("error", "/orders")
+	if len(sent) != 2 || sent[1].XType != "error" {
+		t.Fatalf("sent %d texts, want one per xtype", len(sent))
+	}
+
+	// The full set is cleared, so the text is sent again.
+	texts.Text("service", "/login")
+	texts.Text("service", "/orders")
+	if len(sent) != 4 {
+		t.Errorf("sent %d texts after clearing, want 4", len(sent))
+	}
+}
@@ -0,0 +1,230 @@
+package login
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ImportResult describes the merge of a Java server's accounts and groups
+// into the conf directory of this server.
+type ImportResult struct {
+	AccountsAdded    []string
+	AccountsReplaced []string
+	AccountsSkipped  []string // already present, kept as is
+	GroupsAdded      []string
+	GroupsReplaced   []string
+	GroupsSkipped    []string
+	Warnings         []string
+}
+
+// ImportJavaAccounts merges the account.xml and account_group.xml of a Java
+// server's conf directory srcDir into confDir. Both servers use the same
+// file layout and the same SHA-256 password hashes, so entries are copied
+// as they are. Entries already in confDir are kept unless overwrite is set.
+// A missing account.xml in confDir is created with the imported accounts
+// only, a missing account_group.xml with the default groups as well.
+// Nothing is written when dryRun is set.
+func ImportJavaAccounts(srcDir, confDir string, overwrite, dryRun bool) (*ImportResult, error) {
+	srcAccounts, srcGroups := filepath.Join(srcDir, "account.xml"), filepath.Join(srcDir, "account_group.xml")
+	_, errA := os.Stat(srcAccounts)
+	_, errG := os.Stat(srcGroups)
+	if errA != nil && errG != nil {
+		return nil, fmt.Errorf("no account.xml or account_group.xml in %s", srcDir)
+	}
+
+	result := &ImportResult{}
+	groupNames := make(map[string]bool)
+	var groups *xmlGroups
+	if errG == nil {
+		src, err := readGroupDoc(srcGroups)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", srcGroups, err)
+		}
+		unknown, err := unknownPolicies(srcGroups)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", srcGroups, err)
+		}
+		for _, name := range unknown {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("policy %s is not supported and was dropped", name))
+		}
+		dstGroups := filepath.Join(confDir, "account_group.xml")
+		if groups, err = readGroupDoc(dstGroups); err != nil {
+			return nil, err
+		}
+		// A new group file starts from the default groups, which the Java
+		// server's own groups of the same name replace.
+		var defaults []string
+		if _, err := os.Stat(dstGroups); errors.Is(err, os.ErrNotExist) {
+			if err := xml.Unmarshal(defaultAccountGroupXML, groups); err != nil {
+				return nil, err
+			}
+			for _, g := range groups.Groups {
+				defaults = append(defaults, g.Name)
+			}
+		}
+		for _, g := range src.Groups {
+			g.Policy = normalizePolicy(&g.Policy)
+			i := slices.IndexFunc(groups.Groups, func(x xmlGroup) bool { return x.Name == g.Name })
+			switch {
+			case i < 0:
+				groups.Groups = append(groups.Groups, g)
+				result.GroupsAdded = append(result.GroupsAdded, g.Name)
+			case slices.Contains(defaults, g.Name):
+				groups.Groups[i] = g
+				result.GroupsAdded = append(result.GroupsAdded, g.Name)
+			case overwrite:
+				groups.Groups[i] = g
+				result.GroupsReplaced = append(result.GroupsReplaced, g.Name)
+			default:
+				result.GroupsSkipped = append(result.GroupsSkipped, g.Name)
+			}
+		}
+		for _, g := range groups.Groups {
+			groupNames[g.Name] = true
+		}
+	} else if existing, err := parseGroupFile(filepath.Join(confDir, "account_group.xml")); err == nil {
+		for name := range existing {
+			groupNames[name] = true
+		}
+	} else {
+		// The server creates the default groups on first start.
+		groupNames["admin"], groupNames["guest"] = true, true
+	}
+
+	var accounts *xmlAccounts
+	if errA == nil {
+		src, err := readAccountDoc(srcAccounts)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", srcAccounts, err)
+		}
+		if accounts, err = readAccountDoc(filepath.Join(confDir, "account.xml")); err != nil {
+			return nil, err
+		}
+		for _, a := range src.Accounts {
+			if !isPasswordHash(a.Pass) {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("account %s: password is not a SHA-256 hash, login fails until it is reset", a.ID))
+			}
+			if !groupNames[a.Group] {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("account %s: group %q does not exist", a.ID, a.Group))
+			}
+			i := slices.IndexFunc(accounts.Accounts, func(x xmlAccount) bool { return x.ID == a.ID })
+			switch {
+			case i < 0:
+				accounts.Accounts = append(accounts.Accounts, a)
+				result.AccountsAdded = append(result.AccountsAdded, a.ID)
+			case overwrite:
+				accounts.Accounts[i] = a
+				result.AccountsReplaced = append(result.AccountsReplaced, a.ID)
+			default:
+				result.AccountsSkipped = append(result.AccountsSkipped, a.ID)
+			}
+		}
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if err := os.MkdirAll(confDir, 0755); err != nil {
+		return nil, err
+	}
+	if groups != nil {
+		if err := writeGroupFile(filepath.Join(confDir, "account_group.xml"), groups); err != nil {
+			return nil, err
+		}
+	}
+	if accounts != nil {
+		if err := writeAccountFile(filepath.Join(confDir, "account.xml"), accounts); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// readAccountDoc reads account.xml, returning an empty document if it does
+// not exist.
+func readAccountDoc(path string) (*xmlAccounts, error) {
+	doc := &xmlAccounts{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return doc, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := xml.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// readGroupDoc reads account_group.xml, returning an empty document if it
+// does not exist.
+func readGroupDoc(path string) (*xmlGroups, error) {
+	doc := &xmlGroups{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return doc, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := xml.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// unknownPolicies lists the Policy elements of account_group.xml that are
+// not among policyFieldNames.
+func unknownPolicies(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Groups []struct {
+			Policy struct {
+				Any []struct {
+					XMLName xml.Name
+				} `xml:",any"`
+			} `xml:"Policy"`
+		} `xml:"Group"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, g := range doc.Groups {
+		for _, e := range g.Policy.Any {
+			if name := e.XMLName.Local; !slices.Contains(policyFieldNames, name) && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// normalizePolicy writes every policy as "true" or "false"; a missing one
+// is false, as the Java server reads it.
+func normalizePolicy(p *xmlPolicy) xmlPolicy {
+	pm := policyToMap(p)
+	for _, name := range policyFieldNames {
+		if strings.EqualFold(strings.TrimSpace(pm[name]), "true") {
+			pm[name] = "true"
+		} else {
+			pm[name] = "false"
+		}
+	}
+	return mapToPolicy(pm)
+}
+
+func isPasswordHash(pass string) bool {
+	_, err := hex.DecodeString(pass)
+	return len(pass) == 64 && err == nil
+}
@@ -0,0 +1,95 @@
+package login
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+const javaAccountXML = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<Accounts>
+	<Account id="admin" pass="1111111111111111111111111111111111111111111111111111111111111111" group="admin">
+		<Email>root@example.com</Email>
+	</Account>
+	<Account id="ops" pass="2222222222222222222222222222222222222222222222222222222222222222" group="operator">
+		<Email>ops@example.com</Email>
+	</Account>
+	<Account id="legacy" pass="plaintext" group="nobody">
+		<Email></Email>
+	</Account>
+</Accounts>`
+
+const javaGroupXML = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<Groups>
+	<Group name="operator">
+		<Policy>
+			<AllowThreadDump>TRUE</AllowThreadDump>
+			<AllowConfigure>true</AllowConfigure>
+			<AllowFutureThing>true</AllowFutureThing>
+		</Policy>
+	</Group>
+</Groups>`
+
+func TestImportJavaAccounts(t *testing.T) {
+	src := t.TempDir()
+	os.WriteFile(filepath.Join(src, "account.xml"), []byte(javaAccountXML), 0644)
+	os.WriteFile(filepath.Join(src, "account_group.xml"), []byte(javaGroupXML), 0644)
+
+	// A fresh conf directory receives only the imported entries.
+	conf := filepath.Join(t.TempDir(), "conf")
+	result, err := ImportJavaAccounts(src, conf, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(result.AccountsAdded, []string{"admin", "ops", "legacy"}) || !slices.Equal(result.GroupsAdded, []string{"operator"}) {
+		t.Fatalf("added accounts %v, groups %v", result.AccountsAdded, result.GroupsAdded)
+	}
+	if len(result.Warnings) != 3 {
+		t.Errorf("warnings %v, want unknown policy, plaintext password and missing group", result.Warnings)
+	}
+
+	am := NewAccountManager(conf)
+	if !am.AuthorizeAccount("ops", "2222222222222222222222222222222222222222222222222222222222222222") {
+		t.Error("imported account cannot log in")
+	}
+	if am.GetAccount("guest") != nil {
+		t.Error("default guest account should not be added to an imported account.xml")
+	}
+	policy := am.GetGroupPolicy("operator")
+	if policy == nil {
+		t.Fatal("operator group not imported")
+	}
+	for name, want := range map[string]bool{"AllowThreadDump": true, "AllowConfigure": true, "AllowHeapDump": false} {
+		v, _ := policy.Get(name)
+		if bv, ok := v.(*value.BooleanValue); !ok || bv.Value != want {
+			t.Errorf("%s = %v, want %v", name, v, want)
+		}
+	}
+	if am.GetGroupPolicy("admin") == nil {
+		t.Error("default groups should be created when the Java server has none")
+	}
+
+	// Existing entries are kept unless overwrite is set.
+	am.EditAccount(&Account{ID: "admin", Password: "changed", Group: "admin"})
+	result, err = ImportJavaAccounts(src, conf, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.AccountsAdded) != 0 || len(result.AccountsSkipped) != 3 {
+		t.Errorf("second import added %v, skipped %v", result.AccountsAdded, result.AccountsSkipped)
+	}
+	if _, err := ImportJavaAccounts(src, conf, true, false); err != nil {
+		t.Fatal(err)
+	}
+	accounts, _ := parseAccountFile(filepath.Join(conf, "account.xml"))
+	if accounts["admin"].Password != "1111111111111111111111111111111111111111111111111111111111111111" {
+		t.Error("overwrite should restore the imported password")
+	}
+
+	if _, err := ImportJavaAccounts(t.TempDir(), conf, false, false); err == nil {
+		t.Error("expected an error for a directory without account files")
+	}
+}
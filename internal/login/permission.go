@@ -0,0 +1,70 @@
+package login
+
+import "github.com/zbum/scouter-server-go/internal/protocol/value"
+
+// Permission is the level of access a session has to the TCP service
+// commands. Each level includes the ones below it.
+type Permission int
+
+const (
+	// PermRead allows the commands that only read data.
+	PermRead Permission = iota
+	// PermWrite also allows changing monitoring data and settings, such as
+	// removing objects, saving KV entries or alert rules and acting on
+	// agents.
+	PermWrite
+	// PermAdmin also allows managing accounts and groups, the server
+	// configuration and the stored day data.
+	PermAdmin
+)
+
+func (p Permission) String() string {
+	switch p {
+	case PermRead:
+		return "read"
+	case PermWrite:
+		return "write"
+	case PermAdmin:
+		return "admin"
+	}
+	return "unknown"
+}
+
+// GroupPermission returns the permission of the users of group: admin for
+// the admin group and groups allowed to edit group policies, write for
+// groups allowed to configure, read for the others and unknown groups.
+func (am *AccountManager) GroupPermission(group string) Permission {
+	if group == "admin" {
+		return PermAdmin
+	}
+	policy := am.GetGroupPolicy(group)
+	switch {
+	case policyAllows(policy, "AllowEditGroupPolicy"):
+		return PermAdmin
+	case policyAllows(policy, "AllowConfigure"):
+		return PermWrite
+	}
+	return PermRead
+}
+
+// AccountPermission returns the permission of the account id, read for an
+// unknown account.
+func (am *AccountManager) AccountPermission(id string) Permission {
+	acct := am.GetAccount(id)
+	if acct == nil {
+		return PermRead
+	}
+	return am.GroupPermission(acct.Group)
+}
+
+func policyAllows(policy *value.MapValue, name string) bool {
+	if policy == nil {
+		return false
+	}
+	v, ok := policy.Get(name)
+	if !ok {
+		return false
+	}
+	b, ok := v.(*value.BooleanValue)
+	return ok && b.Value
+}
@@ -0,0 +1,52 @@
+package login
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPermission(t *testing.T) {
+	conf := t.TempDir()
+	os.WriteFile(filepath.Join(conf, "account.xml"), []byte(`<Accounts>
+	<Account id="root" pass="p" group="admin"></Account>
+	<Account id="ops" pass="p" group="operator"></Account>
+	<Account id="view" pass="p" group="guest"></Account>
+</Accounts>`), 0644)
+	os.WriteFile(filepath.Join(conf, "account_group.xml"), []byte(`<Groups>
+	<Group name="admin"><Policy></Policy></Group>
+	<Group name="lead"><Policy><AllowEditGroupPolicy>true</AllowEditGroupPolicy></Policy></Group>
+	<Group name="operator"><Policy><AllowConfigure>true</AllowConfigure></Policy></Group>
+	<Group name="guest"><Policy><AllowThreadDump>true</AllowThreadDump></Policy></Group>
+</Groups>`), 0644)
+	am := NewAccountManager(conf)
+
+	for group, want := range map[string]Permission{
+		"admin":    PermAdmin,
+		"lead":     PermAdmin,
+		"operator": PermWrite,
+		"guest":    PermRead,
+		"missing":  PermRead,
+	} {
+		if got := am.GroupPermission(group); got != want {
+			t.Errorf("GroupPermission(%s) = %s, want %s", group, got, want)
+		}
+	}
+
+	sm := NewSessionManager(am)
+	for id, want := range map[string]Permission{"root": PermAdmin, "ops": PermWrite, "view": PermRead} {
+		session := sm.Login(id, "p", "127.0.0.1")
+		if got, ok := sm.Permission(session); !ok || got != want {
+			t.Errorf("Permission(%s) = %s, %v, want %s", id, got, ok, want)
+		}
+	}
+	if _, ok := sm.Permission(42); ok {
+		t.Error("unknown session should not have a permission")
+	}
+
+	// Without accounts nothing is restricted.
+	open := NewSessionManager(nil)
+	if got, _ := open.Permission(open.Login("anyone", "", "127.0.0.1")); got != PermAdmin {
+		t.Errorf("permission without account manager = %s, want admin", got)
+	}
+}
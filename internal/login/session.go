@@ -16,6 +16,7 @@ type User struct {
 	Version   string
 	Group     string
 	LoginTime time.Time
+	Compress  bool // large responses may be sent zstd-compressed
 }
 
 // SessionManager manages client login sessions.
@@ -61,6 +62,21 @@ func (sm *SessionManager) Login(id, pass, ip string) int64 {
 	return session
 }
 
+// Permission returns the permission of the user of session, resolved from
+// its group on every call so policy edits apply to open sessions. Without an
+// account manager every user is an admin, as nothing restricts the login
+// either. ok is false for an unknown session.
+func (sm *SessionManager) Permission(session int64) (Permission, bool) {
+	user := sm.GetUser(session)
+	if user == nil {
+		return PermRead, false
+	}
+	if sm.accountManager == nil {
+		return PermAdmin, true
+	}
+	return sm.accountManager.GroupPermission(user.Group), true
+}
+
 // OkSession returns true if the session is valid.
 func (sm *SessionManager) OkSession(session int64) bool {
 	sm.mu.RLock()
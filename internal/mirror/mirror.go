@@ -0,0 +1,118 @@
+// Package mirror defines the file format used to capture incoming packs for
+// debugging and to read such captures back for replay.
+//
+// A file starts with a 5-byte header ("SCMR" and a version byte) followed by
+// records of:
+//
+//	int64   receive time (epoch ms)
+//	text    sender address ("" if unknown)
+//	int32   pack length
+//	[]byte  pack (type byte + body, as written by pack.WritePack)
+package mirror
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+const version = 1
+
+var magic = []byte("SCMR")
+
+// Record is one captured pack.
+type Record struct {
+	Time int64
+	Addr string
+	Pack pack.Pack
+}
+
+// Writer appends records to a capture.
+type Writer struct {
+	bw *bufio.Writer
+}
+
+// NewWriter writes the file header to w and returns a Writer. Call Flush to
+// push buffered records to w.
+func NewWriter(w io.Writer) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(magic); err != nil {
+		return nil, err
+	}
+	if err := bw.WriteByte(version); err != nil {
+		return nil, err
+	}
+	return &Writer{bw: bw}, nil
+}
+
+// Write appends one record and returns its encoded size.
+func (w *Writer) Write(timeMs int64, addr string, p pack.Pack) (int, error) {
+	body := protocol.NewDataOutputX()
+	pack.WritePack(body, p)
+	b := body.ToByteArray()
+
+	rec := protocol.NewDataOutputX()
+	rec.WriteInt64(timeMs)
+	rec.WriteText(addr)
+	rec.WriteIntBytes(b)
+	data := rec.ToByteArray()
+	if _, err := w.bw.Write(data); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush writes buffered records to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.bw.Flush()
+}
+
+// Reader reads records from a capture.
+type Reader struct {
+	in *protocol.DataInputX
+}
+
+// NewReader checks the file header of r and returns a Reader.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("read mirror header: %w", err)
+	}
+	if string(header[:len(magic)]) != string(magic) {
+		return nil, errors.New("not a pack mirror file")
+	}
+	if header[len(magic)] != version {
+		return nil, fmt.Errorf("unsupported pack mirror version %d", header[len(magic)])
+	}
+	return &Reader{in: protocol.NewDataInputXStream(br)}, nil
+}
+
+// Next returns the next record, or io.EOF at the end of the capture. A record
+// truncated by a crash or an unreadable pack is reported as an error.
+func (r *Reader) Next() (*Record, error) {
+	t, err := r.in.ReadInt64()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	addr, err := r.in.ReadText()
+	if err != nil {
+		return nil, fmt.Errorf("read record: %w", err)
+	}
+	b, err := r.in.ReadIntBytes()
+	if err != nil {
+		return nil, fmt.Errorf("read record: %w", err)
+	}
+	p, err := pack.ReadPack(protocol.NewDataInputX(b))
+	if err != nil {
+		return nil, fmt.Errorf("decode pack: %w", err)
+	}
+	return &Record{Time: t, Addr: addr, Pack: p}, nil
+}
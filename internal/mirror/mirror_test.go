@@ -0,0 +1,63 @@
+package mirror
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+func TestWriteRead(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(1000, "10.0.0.1:40000", &pack.XLogPack{Txid: 7, ObjHash: 100, Elapsed: 12})
+	w.Write(2000, "", &pack.TextPack{XType: "service", Hash: 5, Text: "/order"})
+	w.Flush()
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xp, ok := rec.Pack.(*pack.XLogPack); !ok || xp.Txid != 7 || rec.Time != 1000 || rec.Addr != "10.0.0.1:40000" {
+		t.Errorf("record 1 = %+v", rec)
+	}
+	rec, err = r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tp, ok := rec.Pack.(*pack.TextPack); !ok || tp.Text != "/order" || rec.Time != 2000 || rec.Addr != "" {
+		t.Errorf("record 2 = %+v", rec)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestReaderRejectsOtherFiles(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader([]byte("hello world"))); err == nil {
+		t.Error("expected error for a file without the mirror header")
+	}
+}
+
+func TestReaderTruncatedRecord(t *testing.T) {
+	var buf bytes.Buffer
+	w, _ := NewWriter(&buf)
+	w.Write(1000, "", &pack.XLogPack{Txid: 7})
+	w.Flush()
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-3]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Next(); err == nil || err == io.EOF {
+		t.Errorf("expected error for a truncated record, got %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// ErrInvalidSession is returned when the server rejects the session.
+var ErrInvalidSession = errors.New("invalid session")
+
+// Client is a minimal Scouter TCP client speaking the TCP_CLIENT protocol.
+// It is used by command-line tools that talk to a running server.
+// A Client is not safe for concurrent use.
+type Client struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	din     *protocol.DataInputX
+	dout    *protocol.DataOutputX
+	timeout time.Duration
+	session int64
+}
+
+// Dial connects to a Scouter server and sends the TCP_CLIENT magic.
+// timeout bounds both the dial and every subsequent call.
+func Dial(addr string, timeout time.Duration) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	c := &Client{
+		conn:    conn,
+		r:       r,
+		din:     protocol.NewDataInputXStream(r),
+		dout:    protocol.NewDataOutputXStream(bufio.NewWriter(conn)),
+		timeout: timeout,
+	}
+	magic := uint32(protocol.TCP_CLIENT)
+	c.dout.WriteInt32(int32(magic))
+	if err := c.dout.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Login authenticates with a plain-text password, which is sent SHA-256 hashed
+// as the server expects.
+func (c *Client) Login(id, pass string) error {
+	sum := sha256.Sum256([]byte(pass))
+
+	param := &pack.MapPack{}
+	param.PutStr("id", id)
+	param.PutStr("pass", hex.EncodeToString(sum[:]))
+	param.PutStr("version", "tool")
+	param.PutStr("compress", "zstd")
+
+	var resp *pack.MapPack
+	if err := c.Call(protocol.LOGIN, param, func(p pack.Pack) {
+		if mp, ok := p.(*pack.MapPack); ok {
+			resp = mp
+		}
+	}); err != nil {
+		return err
+	}
+	if resp == nil {
+		return fmt.Errorf("login: empty response")
+	}
+	c.session = resp.GetLong("session")
+	if c.session == 0 {
+		return fmt.Errorf("login failed for %q", id)
+	}
+	return nil
+}
+
+// Call sends cmd with an optional param pack and invokes handler for every
+// pack returned before the FLAG_NO_NEXT terminator.
+func (c *Client) Call(cmd string, param pack.Pack, handler func(pack.Pack)) error {
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+
+	c.dout.WriteText(cmd)
+	c.dout.WriteInt64(c.session)
+	if param != nil {
+		pack.WritePack(c.dout, param)
+	}
+	if err := c.dout.Flush(); err != nil {
+		return err
+	}
+
+	for {
+		flag, err := c.din.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch flag {
+		case protocol.FLAG_NO_NEXT:
+			return nil
+		case protocol.FLAG_HAS_NEXT:
+			p, err := pack.ReadPack(c.din)
+			if err != nil {
+				return err
+			}
+			if handler != nil {
+				handler(p)
+			}
+		case protocol.FLAG_COMPRESSED:
+			if err := c.readCompressed(cmd, handler); err != nil {
+				return err
+			}
+		case protocol.FLAG_INVALID_SESSION:
+			return ErrInvalidSession
+		default:
+			return fmt.Errorf("%s: unexpected response flag %d", cmd, flag)
+		}
+	}
+}
+
+// readCompressed reads the [flag][pack] records of a FLAG_COMPRESSED body.
+// The uncompressed terminator follows it.
+func (c *Client) readCompressed(cmd string, handler func(pack.Pack)) error {
+	chunks := protocol.NewChunkReader(c.r)
+	dec, err := zstd.NewReader(chunks, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return err
+	}
+	defer dec.Close()
+	din := protocol.NewDataInputXStream(dec)
+	for {
+		flag, err := din.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if flag != protocol.FLAG_HAS_NEXT {
+			return fmt.Errorf("%s: unexpected compressed response flag %d", cmd, flag)
+		}
+		p, err := pack.ReadPack(din)
+		if err != nil {
+			return err
+		}
+		if handler != nil {
+			handler(p)
+		}
+	}
+	// Drain to the terminating chunk so the stream stays in sync.
+	_, err = io.Copy(io.Discard, chunks)
+	return err
+}
+
+// Close tells the server the connection is done and closes it.
+func (c *Client) Close() error {
+	c.conn.SetDeadline(time.Now().Add(time.Second))
+	c.dout.WriteText(protocol.CLOSE)
+	c.dout.Flush()
+	return c.conn.Close()
+}
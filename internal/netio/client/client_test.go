@@ -0,0 +1,115 @@
+package client
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// serveOnce accepts one TCP_CLIENT connection and answers LOGIN and SERVER_TIME.
+func serveOnce(t *testing.T, ln net.Listener, sessions chan<- int64) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	din := protocol.NewDataInputXStream(bufio.NewReader(conn))
+	dout := protocol.NewDataOutputXStream(bufio.NewWriter(conn))
+
+	if magic, err := din.ReadInt32(); err != nil || uint32(magic) != uint32(protocol.TCP_CLIENT) {
+		t.Errorf("unexpected magic %x (%v)", magic, err)
+		return
+	}
+	for {
+		cmd, err := din.ReadText()
+		if err != nil || cmd == protocol.CLOSE {
+			return
+		}
+		session, _ := din.ReadInt64()
+		sessions <- session
+		switch cmd {
+		case protocol.LOGIN:
+			pk, _ := pack.ReadPack(din)
+			m := pk.(*pack.MapPack)
+			if m.GetText("id") == "admin" && len(m.GetText("pass")) == 64 {
+				m.PutLong("session", 42)
+			} else {
+				m.PutLong("session", 0)
+			}
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, m)
+		case protocol.SERVER_TIME:
+			for i := 0; i < 2; i++ {
+				m := &pack.MapPack{}
+				m.PutLong("time", int64(i))
+				dout.WriteByte(protocol.FLAG_HAS_NEXT)
+				pack.WritePack(dout, m)
+			}
+		default:
+			dout.WriteByte(protocol.FLAG_INVALID_SESSION)
+			dout.Flush()
+			continue
+		}
+		dout.WriteByte(protocol.FLAG_NO_NEXT)
+		dout.Flush()
+	}
+}
+
+func TestClient_LoginAndCall(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	sessions := make(chan int64, 10)
+	go serveOnce(t, ln, sessions)
+
+	c, err := Dial(ln.Addr().String(), 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Login("admin", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	<-sessions
+
+	count := 0
+	if err := c.Call(protocol.SERVER_TIME, nil, func(p pack.Pack) { count++ }); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 packs, got %d", count)
+	}
+	if s := <-sessions; s != 42 {
+		t.Errorf("expected session 42 to be sent, got %d", s)
+	}
+
+	if err := c.Call("UNKNOWN", nil, nil); err != ErrInvalidSession {
+		t.Errorf("expected ErrInvalidSession, got %v", err)
+	}
+}
+
+func TestClient_LoginFailure(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go serveOnce(t, ln, make(chan int64, 10))
+
+	c, err := Dial(ln.Addr().String(), 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Login("nobody", "x"); err == nil {
+		t.Error("expected login failure")
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 )
 
@@ -9,14 +10,24 @@ import (
 // login indicates whether the client has been authenticated.
 type HandlerFunc func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool)
 
+// SessionHandlerFunc is a TCP service handler that also receives the
+// session id the client sent with the command.
+type SessionHandlerFunc func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool)
+
 // Registry holds registered service handlers keyed by command name.
 type Registry struct {
-	handlers map[string]HandlerFunc
+	handlers        map[string]HandlerFunc
+	sessionHandlers map[string]SessionHandlerFunc
+	permissions     map[string]login.Permission
+	cache           *responseCache
 }
 
 func NewRegistry() *Registry {
 	return &Registry{
-		handlers: make(map[string]HandlerFunc),
+		handlers:        make(map[string]HandlerFunc),
+		sessionHandlers: make(map[string]SessionHandlerFunc),
+		permissions:     make(map[string]login.Permission),
+		cache:           newResponseCache(),
 	}
 }
 
@@ -25,7 +36,50 @@ func (r *Registry) Register(cmd string, handler HandlerFunc) {
 	r.handlers[cmd] = handler
 }
 
+// RegisterCached is Register for a handler whose response rarely changes:
+// responses are reused for net_tcp_response_cache_ttl_sec unless the
+// command is invalidated first. readsParam tells whether the handler reads a
+// request pack, which then selects the cached response.
+func (r *Registry) RegisterCached(cmd string, readsParam bool, handler HandlerFunc) {
+	r.handlers[cmd] = r.cache.wrap(cmd, readsParam, handler)
+}
+
+// Invalidate drops the cached responses of commands registered with
+// RegisterCached. Handlers changing the data behind them call it.
+func (r *Registry) Invalidate(cmds ...string) {
+	r.cache.invalidate(cmds...)
+}
+
 // Get returns the handler for a command, or nil.
 func (r *Registry) Get(cmd string) HandlerFunc {
 	return r.handlers[cmd]
 }
+
+// RegisterSession associates a session-aware handler with a command name. It
+// takes precedence over a handler registered with Register, which stays
+// available through Get for wrappers.
+func (r *Registry) RegisterSession(cmd string, handler SessionHandlerFunc) {
+	r.sessionHandlers[cmd] = handler
+}
+
+// GetSession returns the session-aware handler for a command, or nil.
+func (r *Registry) GetSession(cmd string) SessionHandlerFunc {
+	return r.sessionHandlers[cmd]
+}
+
+// Require declares the permission a session needs to run cmds. Commands not
+// declared need login.PermWrite, so a command added without a declaration
+// fails closed.
+func (r *Registry) Require(perm login.Permission, cmds ...string) {
+	for _, cmd := range cmds {
+		r.permissions[cmd] = perm
+	}
+}
+
+// Permission returns the permission a session needs to run cmd.
+func (r *Registry) Permission(cmd string) login.Permission {
+	if perm, ok := r.permissions[cmd]; ok {
+		return perm
+	}
+	return login.PermWrite
+}
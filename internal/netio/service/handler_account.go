@@ -9,6 +9,18 @@ import (
 
 // RegisterAccountHandlers registers account management TCP handlers.
 func RegisterAccountHandlers(r *Registry, accountManager *login.AccountManager) {
+	r.Require(login.PermRead,
+		protocol.CHECK_ACCOUNT_ID,
+		protocol.LIST_ACCOUNT,
+		protocol.LIST_ACCOUNT_GROUP,
+		protocol.GET_GROUP_POLICY_ALL,
+	)
+	r.Require(login.PermAdmin,
+		protocol.ADD_ACCOUNT,
+		protocol.EDIT_ACCOUNT,
+		protocol.EDIT_GROUP_POLICY,
+		protocol.ADD_ACCOUNT_GROUP,
+	)
 
 	// ADD_ACCOUNT: create a new account.
 	r.Register(protocol.ADD_ACCOUNT, func(din *protocol.DataInputX, dout *protocol.DataOutputX, loggedIn bool) {
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
@@ -13,6 +14,12 @@ const counterActiveSpeed = "ActiveSpeed"
 
 // RegisterActiveSpeedHandlers registers active speed service handlers.
 func RegisterActiveSpeedHandlers(r *Registry, counterCache *cache.CounterCache, objectCache *cache.ObjectCache, deadTimeout time.Duration) {
+	r.Require(login.PermRead,
+		protocol.ACTIVESPEED_GROUP_REAL_TIME,
+		protocol.ACTIVESPEED_REAL_TIME,
+		protocol.ACTIVESPEED_REAL_TIME_GROUP,
+		protocol.ACTIVESPEED_GROUP_REAL_TIME_GROUP,
+	)
 
 	// ACTIVESPEED_GROUP_REAL_TIME: get active speed for a list of objHash values.
 	r.Register(protocol.ACTIVESPEED_GROUP_REAL_TIME, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"log/slog"
+
+	"github.com/zbum/scouter-server-go/internal/db/agentinv"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// RegisterAgentInventoryHandlers registers the agent version inventory handlers.
+func RegisterAgentInventoryHandlers(r *Registry, inventory *agentinv.Store) {
+	r.Require(login.PermRead,
+		protocol.AGENT_INVENTORY,
+		protocol.AGENT_INVENTORY_HISTORY,
+	)
+
+	// AGENT_INVENTORY: agents by version, newest first.
+	// Param: "objType" (optional).
+	// Response: one MapPack per agent with "objHash", "objName", "objType",
+	// "address", "version", "os", "capabilities" (list), "since", "lastSeen"
+	// and "outdated" (a newer version runs on another agent of the objType).
+	r.Register(protocol.AGENT_INVENTORY, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		for _, a := range inventory.List(param.GetText("objType")) {
+			m := &pack.MapPack{}
+			m.PutLong("objHash", int64(a.ObjHash))
+			m.PutStr("objName", a.ObjName)
+			m.PutStr("objType", a.ObjType)
+			m.PutStr("address", a.Address)
+			m.PutStr("version", a.Version)
+			m.PutStr("os", a.OS)
+			m.Put("capabilities", textList(a.Capabilities))
+			m.PutLong("since", a.Since)
+			m.PutLong("lastSeen", a.LastSeen)
+			m.Put("outdated", &value.BooleanValue{Value: a.Outdated})
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, m)
+		}
+	})
+
+	// AGENT_INVENTORY_HISTORY: recorded version, OS and capability changes.
+	// Param: "objHash" (optional, all agents if 0).
+	// Response: one MapPack per change, oldest first, with "time",
+	// "prevVersion", "removed" (removed by OBJECT_REMOVE_DEAD) and the fields
+	// of AGENT_INVENTORY except "lastSeen" and "outdated".
+	r.Register(protocol.AGENT_INVENTORY_HISTORY, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		records, err := inventory.History(param.GetInt("objHash"))
+		if err != nil {
+			slog.Warn("AGENT_INVENTORY_HISTORY: read failed", "error", err)
+		}
+		for _, rec := range records {
+			m := &pack.MapPack{}
+			m.PutLong("time", rec.Time)
+			m.PutLong("objHash", int64(rec.ObjHash))
+			m.PutStr("objName", rec.ObjName)
+			m.PutStr("objType", rec.ObjType)
+			m.PutStr("address", rec.Address)
+			m.PutStr("version", rec.Version)
+			m.PutStr("prevVersion", rec.PrevVersion)
+			m.PutStr("os", rec.OS)
+			m.Put("capabilities", textList(rec.Capabilities))
+			m.Put("removed", &value.BooleanValue{Value: rec.Removed})
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, m)
+		}
+	})
+}
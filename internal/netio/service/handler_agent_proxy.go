@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
@@ -20,6 +21,75 @@ type AgentCaller interface {
 // RegisterAgentProxyHandlers registers handlers that proxy client commands to agents
 // through the TCP agent connection pool and return the agent responses.
 func RegisterAgentProxyHandlers(r *Registry, caller AgentCaller, objectCache *cache.ObjectCache, deadTimeout time.Duration) {
+	r.Require(login.PermRead,
+		protocol.OBJECT_ACTIVE_SERVICE_LIST,
+		protocol.OBJECT_ACTIVE_SERVICE_LIST_GROUP,
+		protocol.OBJECT_THREAD_LIST,
+		protocol.OBJECT_THREAD_DETAIL,
+		protocol.OBJECT_ENV,
+		protocol.OBJECT_HEAPHISTO,
+		protocol.OBJECT_THREAD_DUMP,
+		protocol.OBJECT_STAT_LIST,
+		protocol.OBJECT_CLASS_LIST,
+		protocol.OBJECT_CLASS_DESC,
+		protocol.OBJECT_LOAD_CLASS_BY_STREAM,
+		protocol.OBJECT_CHECK_RESOURCE_FILE,
+		protocol.OBJECT_DOWNLOAD_JAR,
+		protocol.OBJECT_SOCKET,
+		protocol.OBJECT_DUMP_FILE_LIST,
+		protocol.OBJECT_DUMP_FILE_DETAIL,
+		protocol.OBJECT_LIST_HEAP_DUMP,
+		protocol.OBJECT_CALL_CPU_PROFILE,
+		protocol.OBJECT_CALL_BLOCK_PROFILE,
+		protocol.OBJECT_CALL_MUTEX_PROFILE,
+		protocol.OBJECT_FILE_SOCKET,
+		protocol.OBJECT_BATCH_ACTIVE_LIST,
+		protocol.TRIGGER_ACTIVE_SERVICE_LIST,
+		protocol.TRIGGER_THREAD_DUMP,
+		protocol.TRIGGER_THREAD_LIST,
+		protocol.TRIGGER_HEAPHISTO,
+		protocol.TRIGGER_BLOCK_PROFILE,
+		protocol.TRIGGER_MUTEX_PROFILE,
+		protocol.HOST_TOP,
+		protocol.HOST_PROCESS_DETAIL,
+		protocol.HOST_DISK_USAGE,
+		protocol.HOST_NET_STAT,
+		protocol.HOST_WHO,
+		protocol.HOST_MEMINFO,
+		protocol.GET_CONFIGURE_WAS,
+		protocol.LIST_CONFIGURE_WAS,
+		protocol.ACTIVE_QUERY_LIST,
+		protocol.DB_PROCESS_LIST,
+		protocol.DB_PROCESS_DETAIL,
+		protocol.DB_EXPLAIN_PLAN,
+		protocol.DB_VARIABLES,
+		protocol.LOCK_LIST,
+		protocol.GET_QUERY_INTERVAL,
+		protocol.SCHEMA_SIZE_STATUS,
+		protocol.TABLE_SIZE_STATUS,
+		protocol.INNODB_STATUS,
+		protocol.SLAVE_STATUS,
+		protocol.EXPLAIN_PLAN_FOR_THREAD,
+		protocol.USE_DATABASE,
+		protocol.REDIS_INFO,
+		protocol.DUMP_APACHE_STATUS,
+		protocol.DEBUG_AGENT,
+		protocol.BATCH_ACTIVE_STACK,
+	)
+	r.Require(login.PermWrite,
+		protocol.OBJECT_THREAD_CONTROL,
+		protocol.OBJECT_SYSTEM_GC,
+		protocol.OBJECT_CALL_HEAP_DUMP,
+		protocol.OBJECT_DELETE_HEAP_DUMP,
+		protocol.OBJECT_RESET_CACHE,
+		protocol.SET_CONFIGURE_WAS,
+		protocol.DB_KILL_PROCESS,
+		protocol.SET_QUERY_INTERVAL,
+	)
+	r.Require(login.PermAdmin,
+		protocol.REDEFINE_CLASSES,
+	)
+
 	simpleProxyCmds := []string{
 		// Object commands
 		protocol.OBJECT_THREAD_LIST,
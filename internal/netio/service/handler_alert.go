@@ -3,29 +3,35 @@ package service
 import (
 	"github.com/zbum/scouter-server-go/internal/core/cache"
 	"github.com/zbum/scouter-server-go/internal/db/alert"
+	"github.com/zbum/scouter-server-go/internal/db/xlog"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
 )
 
 // RegisterAlertHandlers registers handlers for loading historical and real-time alerts.
 func RegisterAlertHandlers(r *Registry, alertRD *alert.AlertRD, alertCache *cache.AlertCache) {
+	r.Require(login.PermRead,
+		protocol.ALERT_LOAD_TIME,
+		protocol.ALERT_REAL_TIME,
+	)
 
-	// ALERT_LOAD_TIME: load historical alerts by time range.
+	// ALERT_LOAD_TIME: load historical alerts by time range. Without "date"
+	// the dates are derived from stime/etime.
 	r.Register(protocol.ALERT_LOAD_TIME, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		pk, err := pack.ReadPack(din)
 		if err != nil {
 			return
 		}
 		param := pk.(*pack.MapPack)
-		date := param.GetText("date")
-		stime := param.GetLong("stime")
-		etime := param.GetLong("etime")
-
-		alertRD.ReadRange(date, stime, etime, func(data []byte) {
-			dout.WriteByte(protocol.FLAG_HAS_NEXT)
-			dout.Write(data)
-		})
+		for _, d := range queryDays(param) {
+			alertRD.ReadRange(d.date, d.stime, d.etime, func(data []byte) {
+				dout.WriteByte(protocol.FLAG_HAS_NEXT)
+				dout.Write(data)
+			})
+		}
 	})
 
 	// ALERT_REAL_TIME: return real-time alerts from cache.
@@ -68,3 +74,53 @@ func RegisterAlertHandlers(r *Registry, alertRD *alert.AlertRD, alertCache *cach
 		}
 	})
 }
+
+// RegisterAlertXLogHandlers registers the handler that resolves the
+// transactions an alert refers to.
+func RegisterAlertXLogHandlers(r *Registry, alertRD *alert.AlertRD, xlogRD *xlog.XLogRD, xlogWR *xlog.XLogWR) {
+	r.Require(login.PermRead,
+		protocol.ALERT_XLOGS,
+	)
+
+	// ALERT_XLOGS: return the XLogs referenced by the pack.TagXLogTxid tags of
+	// the alerts raised at "time" (ms) by "objHash", so a client can jump from
+	// an alert to the offending transactions. "date" defaults to the date of
+	// "time"; objHash 0 matches every object.
+	r.Register(protocol.ALERT_XLOGS, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		t := param.GetLong("time")
+		objHash := int32(param.GetLong("objHash"))
+		date := param.GetText("date")
+		if date == "" {
+			date = util.FormatDate(t)
+		}
+
+		var refs []pack.XLogRef
+		alertRD.ReadRange(date, t, t, func(data []byte) {
+			p, err := pack.ReadPack(protocol.NewDataInputX(data))
+			if err != nil {
+				return
+			}
+			ap, ok := p.(*pack.AlertPack)
+			if ok && ap.Time == t && (objHash == 0 || ap.ObjHash == objHash) {
+				refs = append(refs, ap.XLogRefs()...)
+			}
+		})
+
+		for _, ref := range refs {
+			data, found, _ := xlogWR.GetByTxid(ref.Date, ref.Txid)
+			if !found {
+				data, _ = xlogRD.GetByTxid(ref.Date, ref.Txid)
+			}
+			if data == nil {
+				continue
+			}
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			dout.Write(data)
+		}
+	})
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/db/summary"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
@@ -16,22 +17,33 @@ import (
 // RegisterAlertExtHandlers registers extended alert handlers:
 // ALERT_TITLE_COUNT, alert scripting, and alert descriptor commands.
 func RegisterAlertExtHandlers(r *Registry, summaryRD *summary.SummaryRD) {
+	r.Require(login.PermRead,
+		protocol.ALERT_TITLE_COUNT,
+		protocol.GET_ALERT_SCRIPTING_CONTETNS,
+		protocol.GET_ALERT_SCRIPTING_CONFIG_CONTETNS,
+		protocol.GET_ALERT_SCRIPT_LOAD_MESSAGE,
+		protocol.GET_ALERT_REAL_COUNTER_DESC,
+		protocol.GET_PLUGIN_HELPER_DESC,
+	)
+	r.Require(login.PermAdmin,
+		protocol.SAVE_ALERT_SCRIPTING_CONTETNS,
+		protocol.SAVE_ALERT_SCRIPTING_CONFIG_CONTETNS,
+	)
 
 	// ALERT_TITLE_COUNT: aggregate alert summaries by title with hourly breakdowns.
+	// Without "date" the dates are derived from stime/etime; counts of the
+	// same HHMM on different days add up.
 	r.Register(protocol.ALERT_TITLE_COUNT, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		pk, err := pack.ReadPack(din)
 		if err != nil {
 			return
 		}
 		param := pk.(*pack.MapPack)
-		date := param.GetText("date")
-		stime := param.GetLong("stime")
-		etime := param.GetLong("etime")
 
 		// Aggregate: title → MapPack{title, level, count(MapValue of HHMM→count)}
 		valueMap := make(map[string]*pack.MapPack)
 
-		summaryRD.ReadRangeWithTime(date, SummaryTypeAlert, stime, etime, func(timeMs int64, data []byte) {
+		readAlertSummary := func(timeMs int64, data []byte) {
 			d := protocol.NewDataInputX(data)
 			p, err := pack.ReadPack(d)
 			if err != nil {
@@ -79,7 +91,10 @@ func RegisterAlertExtHandlers(r *Registry, summaryRD *summary.SummaryRD) {
 					mv.Put(hhmm, value.NewDecimalValue(int64(count)))
 				}
 			}
-		})
+		}
+		for _, d := range queryDays(param) {
+			summaryRD.ReadRangeWithTime(d.date, SummaryTypeAlert, d.stime, d.etime, readAlertSummary)
+		}
 
 		for _, mp := range valueMap {
 			dout.WriteByte(protocol.FLAG_HAS_NEXT)
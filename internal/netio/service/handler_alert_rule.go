@@ -0,0 +1,107 @@
+package service
+
+import (
+	"github.com/zbum/scouter-server-go/internal/alertrule"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// RegisterAlertRuleHandlers registers the counter alert rule handlers.
+func RegisterAlertRuleHandlers(r *Registry, engine *alertrule.Engine) {
+	r.Require(login.PermRead,
+		protocol.ALERT_RULE_LIST,
+	)
+	r.Require(login.PermWrite,
+		protocol.ALERT_RULE_SET,
+		protocol.ALERT_RULE_DELETE,
+	)
+
+	// ALERT_RULE_LIST: all rules.
+	// Response: one MapPack per rule ("name", "counter", "op", "threshold",
+	// "forSec", "objType", "objHash", "level") with "firing", the number of
+	// objects it currently alerts on.
+	r.Register(protocol.ALERT_RULE_LIST, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pack.ReadPack(din)
+
+		firing := make(map[string]int64)
+		for _, a := range engine.Active() {
+			if a.StartMs != 0 {
+				firing[a.Rule]++
+			}
+		}
+		for _, d := range engine.Store().List() {
+			resp := &pack.MapPack{}
+			resp.PutStr("name", d.Name)
+			resp.PutStr("counter", d.Counter)
+			resp.PutStr("op", d.Op)
+			resp.Put("threshold", &value.DoubleValue{Value: d.Threshold})
+			resp.PutLong("forSec", int64(d.ForSec))
+			resp.PutStr("objType", d.ObjType)
+			resp.PutLong("objHash", int64(d.ObjHash))
+			resp.PutStr("level", d.Level)
+			resp.PutLong("firing", firing[d.Name])
+
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, resp)
+		}
+	})
+
+	// ALERT_RULE_SET: create or replace a rule. Changing a rule restarts its
+	// conditions.
+	// Param: "name", "counter", "op" (>, >=, < or <=), "threshold",
+	// "forSec", "objType", "objHash", "level" (default WARN).
+	// Response: "result" ("ok" or "error: ...").
+	r.Register(protocol.ALERT_RULE_SET, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		d := alertrule.Definition{
+			Name: param.GetText("name"),
+			Rule: alertrule.Rule{
+				Counter:   param.GetText("counter"),
+				Op:        param.GetText("op"),
+				Threshold: floatParam(param, "threshold"),
+				ForSec:    int(param.GetInt("forSec")),
+			},
+			ObjType: param.GetText("objType"),
+			ObjHash: int32(param.GetInt("objHash")),
+			Level:   param.GetText("level"),
+		}
+		resp := &pack.MapPack{}
+		if err := engine.Store().Put(d); err != nil {
+			resp.PutStr("result", "error: "+err.Error())
+		} else {
+			resp.PutStr("result", "ok")
+		}
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+
+	// ALERT_RULE_DELETE: remove a rule.
+	// Param: "name". Response: "result" ("ok" or "error: ...").
+	r.Register(protocol.ALERT_RULE_DELETE, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		resp := &pack.MapPack{}
+		if found, err := engine.Store().Delete(param.GetText("name")); err != nil {
+			resp.PutStr("result", "error: "+err.Error())
+		} else if !found {
+			resp.PutStr("result", "error: no such rule")
+		} else {
+			resp.PutStr("result", "ok")
+		}
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// responseCacheMaxEntries bounds the cached responses; past it expired
+// entries are dropped and, if none are, new responses are not cached.
+const responseCacheMaxEntries = 1000
+
+type cachedResponse struct {
+	body    []byte
+	expires time.Time
+}
+
+// responseCache keeps the responses of rarely-changing handlers, such as the
+// counter model clients load every time a view opens, for
+// net_tcp_response_cache_ttl_sec. Entries are keyed by command and request
+// pack, and the handlers changing the underlying data drop them with
+// Registry.Invalidate.
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]map[string]cachedResponse // cmd -> request -> response
+	now     func() time.Time
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{entries: make(map[string]map[string]cachedResponse), now: time.Now}
+}
+
+// wrap serves h from the cache. With readsParam the request pack is read
+// here and is part of the key; otherwise the command alone is.
+func (c *responseCache) wrap(cmd string, readsParam bool, h HandlerFunc) HandlerFunc {
+	return func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		ttl := 10 * time.Second
+		if cfg := config.Get(); cfg != nil {
+			ttl = time.Duration(cfg.NetTcpResponseCacheTTLSec()) * time.Second
+		}
+		if ttl <= 0 {
+			h(din, dout, login)
+			return
+		}
+		key := ""
+		if readsParam {
+			pk, err := pack.ReadPack(din)
+			if err != nil {
+				return
+			}
+			param := protocol.NewDataOutputX()
+			pack.WritePack(param, pk)
+			key = string(param.ToByteArray())
+			din = protocol.NewDataInputX(param.ToByteArray())
+		}
+		if body, ok := c.get(cmd, key); ok {
+			dout.Write(body)
+			return
+		}
+		out := protocol.NewDataOutputX()
+		h(din, out, login)
+		body := out.ToByteArray()
+		if len(body) > 0 {
+			c.put(cmd, key, body, ttl)
+		}
+		dout.Write(body)
+	}
+}
+
+func (c *responseCache) get(cmd, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[cmd][key]
+	if !ok || !c.now().Before(e.expires) {
+		return nil, false
+	}
+	return e.body, true
+}
+
+func (c *responseCache) put(cmd, key string, body []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if c.size() >= responseCacheMaxEntries {
+		for _, byKey := range c.entries {
+			for k, e := range byKey {
+				if !now.Before(e.expires) {
+					delete(byKey, k)
+				}
+			}
+		}
+		if c.size() >= responseCacheMaxEntries {
+			return
+		}
+	}
+	byKey := c.entries[cmd]
+	if byKey == nil {
+		byKey = make(map[string]cachedResponse)
+		c.entries[cmd] = byKey
+	}
+	byKey[key] = cachedResponse{body: body, expires: now.Add(ttl)}
+}
+
+// size returns the number of entries. Caller must hold c.mu.
+func (c *responseCache) size() int {
+	n := 0
+	for _, byKey := range c.entries {
+		n += len(byKey)
+	}
+	return n
+}
+
+func (c *responseCache) invalidate(cmds ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cmd := range cmds {
+		delete(c.entries, cmd)
+	}
+}
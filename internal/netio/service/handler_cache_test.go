@@ -0,0 +1,88 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/counter"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+func TestRegisterCached(t *testing.T) {
+	registry := NewRegistry()
+	calls := 0
+	registry.RegisterCached("ECHO", true, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, _ := pack.ReadPack(din)
+		calls++
+		resp := &pack.MapPack{}
+		resp.PutStr("name", pk.(*pack.MapPack).GetText("name"))
+		resp.PutLong("call", int64(calls))
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+	call := func(name string) *pack.MapPack {
+		req := &pack.MapPack{}
+		req.PutStr("name", name)
+		out := protocol.NewDataOutputX()
+		registry.Get("ECHO")(buildRequest(req), out, true)
+		r := readMapPacks(t, out)
+		if len(r) != 1 || r[0].GetText("name") != name {
+			t.Fatalf("ECHO %s = %v", name, r)
+		}
+		return r[0]
+	}
+
+	call("a")
+	call("a")
+	call("b")
+	if calls != 2 {
+		t.Errorf("%d calls, want one per distinct request", calls)
+	}
+	registry.Invalidate("ECHO")
+	if r := call("a"); r.GetLong("call") != 3 {
+		t.Errorf("served from the cache after Invalidate: %v", r)
+	}
+
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("net_tcp_response_cache_ttl_sec=0\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+	call("a")
+	call("a")
+	if calls != 5 {
+		t.Errorf("%d calls with caching off, want 5", calls)
+	}
+}
+
+func TestXMLCounterInvalidatedByNewType(t *testing.T) {
+	typeManager := counter.NewObjectTypeManager()
+	registry := NewRegistry()
+	RegisterConfigureHandlers(registry, "test", typeManager, nil)
+	custom := func() []byte {
+		out := protocol.NewDataOutputX()
+		registry.Get(protocol.GET_XML_COUNTER)(protocol.NewDataInputX(nil), out, true)
+		r := readMapPacks(t, out)
+		if len(r) != 1 {
+			t.Fatalf("GET_XML_COUNTER = %v", r)
+		}
+		if b, ok := r[0].Get("custom").(*value.BlobValue); ok {
+			return b.Value
+		}
+		return nil
+	}
+
+	if custom() != nil {
+		t.Fatal("custom types before any were registered")
+	}
+	tags := value.NewMapValue()
+	tags.Put(counter.TagObjDetectedType, value.NewTextValue("tomcat"))
+	typeManager.AddObjectTypeIfNotExist("orders-tomcat", tags)
+	if custom() == nil {
+		t.Error("cached counter model served after a new object type")
+	}
+}
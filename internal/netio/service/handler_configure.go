@@ -1,70 +1,215 @@
 package service
 
 import (
+	"errors"
+	"log/slog"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/counter"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
 )
 
 // RegisterConfigureHandlers registers configuration management handlers.
-func RegisterConfigureHandlers(r *Registry, version string, typeManager *counter.ObjectTypeManager) {
-
-	// GET_CONFIGURE_SERVER: Read the config file and return its contents.
+func RegisterConfigureHandlers(r *Registry, version string, typeManager *counter.ObjectTypeManager, sessions *login.SessionManager) {
+	r.Require(login.PermRead,
+		protocol.GET_CONFIGURE_SERVER,
+		protocol.CONFIGURE_SERVER_HISTORY,
+		protocol.CONFIGURE_VALIDATE,
+		protocol.GET_XML_COUNTER,
+		protocol.LIST_CONFIGURE_SERVER,
+	)
+	r.Require(login.PermAdmin,
+		protocol.SET_CONFIGURE_SERVER,
+	)
+
+	if typeManager != nil {
+		typeManager.OnChange(func() { r.Invalidate(protocol.GET_XML_COUNTER) })
+	}
+
+	// GET_CONFIGURE_SERVER: Read the config file and return its contents as
+	// "serverConfig", read by the client's Server Configure view, and
+	// "configContents", with the known keys as "configKey" for its content
+	// assist.
 	r.Register(protocol.GET_CONFIGURE_SERVER, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		// Read param pack (even though not needed)
 		pack.ReadPack(din)
 
+		contents := ""
+		if cfgPath := config.Get().FilePath(); cfgPath != "" {
+			// A missing or unreadable file is returned empty.
+			if data, err := os.ReadFile(cfgPath); err == nil {
+				contents = string(data)
+			}
+		}
+		keys := value.NewListValue()
+		for _, key := range config.ConfigKeys() {
+			keys.Value = append(keys.Value, value.NewTextValue(key))
+		}
+
 		resp := &pack.MapPack{}
+		resp.PutStr("serverConfig", contents)
+		resp.PutStr("configContents", contents)
+		resp.Put("configKey", keys)
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
 
-		cfgPath := config.Get().FilePath()
-		if cfgPath == "" {
-			resp.PutStr("configContents", "")
+	// SET_CONFIGURE_SERVER: Replace the config file atomically and reload
+	// it, recording the user in the change history. The client's Server
+	// Configure view sends "setConfig" and is answered "result" "true" or
+	// "false"; "configContents" is answered "ok" or "error: ...".
+	r.RegisterSession(protocol.SET_CONFIGURE_SERVER, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		javaClient := param.Get("setConfig") != nil
+		configContents := param.GetText("configContents")
+		if javaClient {
+			configContents = param.GetText("setConfig")
+		}
+
+		source := "unknown"
+		if user := sessions.GetUser(session); user != nil {
+			source = user.ID + "@" + user.IP
+		}
+		if cfg := config.Get(); cfg == nil || cfg.FilePath() == "" {
+			err = errors.New("the server was started without a config file")
 		} else {
-			data, err := os.ReadFile(cfgPath)
+			err = config.Save(cfg.FilePath(), configContents, source)
+		}
+		if err != nil {
+			slog.Warn("SET_CONFIGURE_SERVER failed", "source", source, "error", err)
+		}
+
+		resp := &pack.MapPack{}
+		switch {
+		case javaClient:
+			resp.PutStr("result", strconv.FormatBool(err == nil))
 			if err != nil {
-				// File doesn't exist or can't be read - return empty
-				resp.PutStr("configContents", "")
-			} else {
-				resp.PutStr("configContents", string(data))
+				resp.PutStr("error", err.Error())
 			}
+		case err != nil:
+			resp.PutStr("result", "error: "+err.Error())
+		default:
+			resp.PutStr("result", "ok")
 		}
 
 		dout.WriteByte(protocol.FLAG_HAS_NEXT)
 		pack.WritePack(dout, resp)
 	})
 
-	// SET_CONFIGURE_SERVER: Write new configuration content to the config file.
-	r.Register(protocol.SET_CONFIGURE_SERVER, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+	// CONFIGURE_SERVER_HISTORY: Return the recorded config changes, oldest
+	// first, one pack per change. Param: optional "from" (ms).
+	// Response: "time", "source" and parallel lists "key", "old", "new" and
+	// "op" ("added", "removed" or "changed").
+	r.Register(protocol.CONFIGURE_SERVER_HISTORY, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		pk, err := pack.ReadPack(din)
 		if err != nil {
 			return
 		}
 		param := pk.(*pack.MapPack)
-		configContents := param.GetText("configContents")
 
-		cfgPath := config.Get().FilePath()
-		if cfgPath != "" {
-			err = os.WriteFile(cfgPath, []byte(configContents), 0644)
+		for _, c := range config.History(time.UnixMilli(param.GetLong("from"))) {
+			resp := &pack.MapPack{}
+			resp.PutLong("time", c.Time.UnixMilli())
+			resp.PutStr("source", c.Source)
+			keys, olds, news, ops := value.NewListValue(), value.NewListValue(), value.NewListValue(), value.NewListValue()
+			for _, k := range c.Keys {
+				op := "changed"
+				if k.Added {
+					op = "added"
+				} else if k.Removed {
+					op = "removed"
+				}
+				keys.Value = append(keys.Value, value.NewTextValue(k.Key))
+				olds.Value = append(olds.Value, value.NewTextValue(k.Old))
+				news.Value = append(news.Value, value.NewTextValue(k.New))
+				ops.Value = append(ops.Value, value.NewTextValue(op))
+			}
+			resp.Put("key", keys)
+			resp.Put("old", olds)
+			resp.Put("new", news)
+			resp.Put("op", ops)
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, resp)
 		}
+	})
 
-		resp := &pack.MapPack{}
+	// CONFIGURE_VALIDATE: Check a configuration as `scouter-server config
+	// check` does. Param: optional "configContents" to check before saving
+	// it, the current file otherwise. Response: "valid" (no errors), the
+	// "errors" and "warnings" counts, the issues as "issueKey",
+	// "issueLevel" and "issueMessage" lists and the effective configuration
+	// as "key", "value" and "source" lists, secrets masked.
+	r.Register(protocol.CONFIGURE_VALIDATE, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
 		if err != nil {
-			resp.PutStr("result", "error: "+err.Error())
+			return
+		}
+		param, _ := pk.(*pack.MapPack)
+
+		var cfg *config.Config
+		var issues []config.Issue
+		if param != nil && param.Get("configContents") != nil {
+			cfg, issues = config.Check(param.GetText("configContents"))
+		} else if cur := config.Get(); cur != nil && cur.FilePath() != "" {
+			cfg, issues, err = config.CheckFile(cur.FilePath())
 		} else {
-			resp.PutStr("result", "ok")
+			cfg, issues = config.Check("")
 		}
 
+		resp := &pack.MapPack{}
+		if err != nil {
+			resp.PutStr("result", "error: "+err.Error())
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, resp)
+			return
+		}
+		errors := 0
+		issueKeys, levels, messages := value.NewListValue(), value.NewListValue(), value.NewListValue()
+		for _, issue := range issues {
+			if issue.Level == config.IssueError {
+				errors++
+			}
+			issueKeys.Value = append(issueKeys.Value, value.NewTextValue(issue.Key))
+			levels.Value = append(levels.Value, value.NewTextValue(issue.Level))
+			messages.Value = append(messages.Value, value.NewTextValue(issue.Message))
+		}
+		keys, values, sources := value.NewListValue(), value.NewListValue(), value.NewListValue()
+		for _, s := range cfg.Effective() {
+			v := s.Value
+			if v != "" && config.IsSecretKey(s.Key) {
+				v = "****"
+			}
+			keys.Value = append(keys.Value, value.NewTextValue(s.Key))
+			values.Value = append(values.Value, value.NewTextValue(v))
+			sources.Value = append(sources.Value, value.NewTextValue(s.Source))
+		}
+		resp.PutStr("result", "ok")
+		resp.Put("valid", &value.BooleanValue{Value: errors == 0})
+		resp.PutLong("errors", int64(errors))
+		resp.PutLong("warnings", int64(len(issues)-errors))
+		resp.Put("issueKey", issueKeys)
+		resp.Put("issueLevel", levels)
+		resp.Put("issueMessage", messages)
+		resp.Put("key", keys)
+		resp.Put("value", values)
+		resp.Put("source", sources)
 		dout.WriteByte(protocol.FLAG_HAS_NEXT)
 		pack.WritePack(dout, resp)
 	})
 
 	// GET_XML_COUNTER: Return counter definitions XML for the client's CounterEngine.
-	r.Register(protocol.GET_XML_COUNTER, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+	// Cached until counters.site.xml is saved or an object type is registered.
+	r.RegisterCached(protocol.GET_XML_COUNTER, false, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		resp := &pack.MapPack{}
 		resp.Put("default", &value.BlobValue{Value: counter.DefaultCountersXML})
 
@@ -112,6 +257,17 @@ func RegisterConfigureHandlers(r *Registry, version string, typeManager *counter
 // RegisterConfigureExtHandlers registers configuration handlers that require
 // agent proxy support (hybrid server/agent handlers).
 func RegisterConfigureExtHandlers(r *Registry, caller AgentCaller) {
+	r.Require(login.PermRead,
+		protocol.CONFIGURE_DESC,
+		protocol.CONFIGURE_VALUE_TYPE,
+		protocol.CONFIGURE_VALUE_TYPE_DESC,
+		protocol.GET_CONFIGURE_COUNTERS_SITE,
+		protocol.GET_CONFIGURE_TELEGRAF,
+	)
+	r.Require(login.PermAdmin,
+		protocol.SET_CONFIGURE_COUNTERS_SITE,
+		protocol.SET_CONFIGURE_TELEGRAF,
+	)
 
 	// CONFIGURE_DESC: Return config key descriptions.
 	// objHash==0 → server config, objHash>0 → proxy to agent.
@@ -192,7 +348,7 @@ func RegisterConfigureExtHandlers(r *Registry, caller AgentCaller) {
 	})
 
 	// GET_CONFIGURE_COUNTERS_SITE: Read custom counters.site.xml from conf dir.
-	r.Register(protocol.GET_CONFIGURE_COUNTERS_SITE, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+	r.RegisterCached(protocol.GET_CONFIGURE_COUNTERS_SITE, false, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		resp := &pack.MapPack{}
 		contents := ""
 		if cfg := config.Get(); cfg != nil {
@@ -216,6 +372,7 @@ func RegisterConfigureExtHandlers(r *Registry, caller AgentCaller) {
 		}
 		param := pk.(*pack.MapPack)
 		contents := param.GetText("contents")
+		defer r.Invalidate(protocol.GET_XML_COUNTER, protocol.GET_CONFIGURE_COUNTERS_SITE)
 
 		success := false
 		if cfg := config.Get(); cfg != nil {
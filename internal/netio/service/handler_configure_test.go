@@ -0,0 +1,163 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+func TestConfigureServerHistory(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("mgr_purge_xlog_keep_days=30\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+	from := time.Now().UnixMilli()
+
+	sessions := login.NewSessionManager(nil)
+	session := sessions.Login("admin", "", "10.0.0.1")
+	registry := NewRegistry()
+	RegisterConfigureHandlers(registry, "test", nil, sessions)
+
+	set := &pack.MapPack{}
+	set.PutStr("configContents", "mgr_purge_xlog_keep_days=3\n")
+	out := protocol.NewDataOutputX()
+	registry.GetSession(protocol.SET_CONFIGURE_SERVER)(session, buildRequest(set), out, true)
+	if r := readMapPacks(t, out); len(r) != 1 || r[0].GetText("result") != "ok" {
+		t.Fatalf("SET_CONFIGURE_SERVER = %v", r)
+	}
+	if got := config.Get().MgrPurgeXLogKeepDays(); got != 3 {
+		t.Errorf("keep days after save = %d, want 3", got)
+	}
+
+	req := &pack.MapPack{}
+	req.PutLong("from", from)
+	out = protocol.NewDataOutputX()
+	registry.Get(protocol.CONFIGURE_SERVER_HISTORY)(buildRequest(req), out, true)
+	r := readMapPacks(t, out)
+	if len(r) != 1 || r[0].GetText("source") != "admin@10.0.0.1" {
+		t.Fatalf("CONFIGURE_SERVER_HISTORY = %v", r)
+	}
+	if k, o, n, op := r[0].GetList("key"), r[0].GetList("old"), r[0].GetList("new"), r[0].GetList("op"); k.GetString(0) != "mgr_purge_xlog_keep_days" ||
+		o.GetString(0) != "30" || n.GetString(0) != "3" || op.GetString(0) != "changed" {
+		t.Errorf("change = %v", r[0])
+	}
+}
+
+func TestConfigureServerClientView(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("mgr_purge_xlog_keep_days=30\n"), 0600)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	sessions := login.NewSessionManager(nil)
+	session := sessions.Login("admin", "", "10.0.0.1")
+	registry := NewRegistry()
+	RegisterConfigureHandlers(registry, "test", nil, sessions)
+
+	out := protocol.NewDataOutputX()
+	registry.Get(protocol.GET_CONFIGURE_SERVER)(buildRequest(&pack.MapPack{}), out, true)
+	r := readMapPacks(t, out)
+	if len(r) != 1 || r[0].GetText("serverConfig") != "mgr_purge_xlog_keep_days=30\n" || len(r[0].GetList("configKey").Value) != len(config.ConfigKeys()) {
+		t.Fatalf("GET_CONFIGURE_SERVER = %v", r)
+	}
+
+	set := &pack.MapPack{}
+	set.PutStr("setConfig", "mgr_purge_xlog_keep_days=7\n")
+	out = protocol.NewDataOutputX()
+	registry.GetSession(protocol.SET_CONFIGURE_SERVER)(session, buildRequest(set), out, true)
+	if r := readMapPacks(t, out); len(r) != 1 || r[0].GetText("result") != "true" {
+		t.Fatalf("SET_CONFIGURE_SERVER = %v", r)
+	}
+	if got := config.Get().MgrPurgeXLogKeepDays(); got != 7 {
+		t.Errorf("keep days after save = %d, want 7", got)
+	}
+	if info, err := os.Stat(conf); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("saved file mode = %v, %v; want 0600 kept", info, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files left: %v", entries)
+	}
+
+	// A failed write answers false.
+	blocker := filepath.Join(dir, "not-a-dir")
+	os.WriteFile(blocker, nil, 0644)
+	config.Load(filepath.Join(blocker, "scouter.conf"))
+	set.PutStr("setConfig", "mgr_purge_xlog_keep_days=1\n")
+	out = protocol.NewDataOutputX()
+	registry.GetSession(protocol.SET_CONFIGURE_SERVER)(session, buildRequest(set), out, true)
+	if r := readMapPacks(t, out); len(r) != 1 || r[0].GetText("result") != "false" || r[0].GetText("error") == "" {
+		t.Fatalf("failed SET_CONFIGURE_SERVER = %v", r)
+	}
+}
+
+func TestConfigureValidate(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("debug=maybe\nnotify_smtp_password=hunter2\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	registry := NewRegistry()
+	RegisterConfigureHandlers(registry, "test", nil, login.NewSessionManager(nil))
+	validate := func(param *pack.MapPack) *pack.MapPack {
+		t.Helper()
+		out := protocol.NewDataOutputX()
+		registry.Get(protocol.CONFIGURE_VALIDATE)(buildRequest(param), out, true)
+		r := readMapPacks(t, out)
+		if len(r) != 1 || r[0].GetText("result") != "ok" {
+			t.Fatalf("CONFIGURE_VALIDATE = %v", r)
+		}
+		return r[0]
+	}
+
+	// The current file.
+	r := validate(&pack.MapPack{})
+	if r.GetBoolean("valid") || r.GetLong("errors") != 1 || r.GetList("issueKey").GetString(0) != "debug" {
+		t.Errorf("file result = %v", r)
+	}
+	keys, values, sources := r.GetList("key"), r.GetList("value"), r.GetList("source")
+	found := false
+	for i := range keys.Value {
+		if keys.GetString(i) == "notify_smtp_password" {
+			found = true
+			if values.GetString(i) != "****" || sources.GetString(i) != "file" {
+				t.Errorf("password shown as %q from %q", values.GetString(i), sources.GetString(i))
+			}
+		}
+	}
+	if !found {
+		t.Error("effective configuration misses notify_smtp_password")
+	}
+
+	// Contents about to be saved.
+	param := &pack.MapPack{}
+	param.PutStr("configContents", "db_keep_days=200\nmgr_purge_xlog_keep_days=300\n")
+	r = validate(param)
+	if !r.GetBoolean("valid") || r.GetLong("warnings") != 1 || r.GetList("issueKey").GetString(0) != "mgr_purge_xlog_keep_days" {
+		t.Errorf("contents result = %v", r)
+	}
+}
+
+func readMapPacks(t *testing.T, out *protocol.DataOutputX) []*pack.MapPack {
+	t.Helper()
+	in := protocol.NewDataInputX(out.ToByteArray())
+	var packs []*pack.MapPack
+	for {
+		if flag, err := in.ReadByte(); err != nil || flag != protocol.FLAG_HAS_NEXT {
+			return packs
+		}
+		pk, err := pack.ReadPack(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packs = append(packs, pk.(*pack.MapPack))
+	}
+}
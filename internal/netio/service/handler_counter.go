@@ -5,6 +5,7 @@ import (
 
 	"github.com/zbum/scouter-server-go/internal/core/cache"
 	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
@@ -12,6 +13,11 @@ import (
 
 // RegisterCounterHandlers registers COUNTER_REAL_TIME and COUNTER_REAL_TIME_ALL handlers.
 func RegisterCounterHandlers(r *Registry, counterCache *cache.CounterCache, objectCache *cache.ObjectCache, deadTimeout time.Duration, counterRD *counter.CounterRD) {
+	r.Require(login.PermRead,
+		protocol.COUNTER_REAL_TIME,
+		protocol.COUNTER_REAL_TIME_GROUP,
+		protocol.COUNTER_REAL_TIME_ALL,
+	)
 	// COUNTER_REAL_TIME: get a single counter value for a specific object
 	r.Register(protocol.COUNTER_REAL_TIME, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		pk, err := pack.ReadPack(din)
@@ -6,6 +6,7 @@ import (
 
 	"github.com/zbum/scouter-server-go/internal/core/cache"
 	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
@@ -14,6 +15,18 @@ import (
 
 // RegisterCounterExtHandlers registers extended counter service handlers (P2).
 func RegisterCounterExtHandlers(r *Registry, counterCache *cache.CounterCache, objectCache *cache.ObjectCache, deadTimeout time.Duration, counterRD *counter.CounterRD) {
+	r.Require(login.PermRead,
+		protocol.COUNTER_REAL_TIME_MULTI,
+		protocol.COUNTER_REAL_TIME_ALL_MULTI,
+		protocol.COUNTER_TODAY,
+		protocol.COUNTER_TODAY_ALL,
+		protocol.COUNTER_REAL_TIME_TOT,
+		protocol.COUNTER_TODAY_TOT,
+		protocol.COUNTER_TODAY_GROUP,
+		protocol.COUNTER_REAL_TIME_OBJECT_ALL,
+		protocol.COUNTER_REAL_TIME_OBJECT_TYPE_ALL,
+		protocol.COUNTER_MAP_REAL_TIME,
+	)
 
 	// COUNTER_REAL_TIME_MULTI: get multiple counter values for a single object.
 	r.Register(protocol.COUNTER_REAL_TIME_MULTI, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
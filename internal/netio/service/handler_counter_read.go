@@ -7,6 +7,7 @@ import (
 
 	"github.com/zbum/scouter-server-go/internal/core/cache"
 	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
@@ -15,26 +16,40 @@ import (
 
 // RegisterCounterReadHandlers registers handlers that read counter data from storage.
 func RegisterCounterReadHandlers(r *Registry, counterRD *counter.CounterRD, objectCache *cache.ObjectCache, deadTimeout time.Duration) {
+	r.Require(login.PermRead,
+		protocol.COUNTER_PAST_TIME,
+		protocol.COUNTER_PAST_TIME_ALL,
+		protocol.COUNTER_PAST_DATE,
+		protocol.COUNTER_PAST_DATE_ALL,
+		protocol.COUNTER_PAST_TIME_TOT,
+		protocol.COUNTER_PAST_TIME_GROUP,
+		protocol.COUNTER_PAST_DATE_TOT,
+		protocol.COUNTER_PAST_DATE_GROUP,
+		protocol.COUNTER_PAST_LONGDATE_ALL,
+		protocol.COUNTER_PAST_LONGDATE_TOT,
+		protocol.COUNTER_PAST_LONGDATE_GROUP,
+		protocol.GET_COUNTER_EXIST_DAYS,
+	)
 
 	// COUNTER_PAST_TIME: read realtime counter range for a single object.
+	// See realtimeCounterRange for the stime/etime units.
 	r.Register(protocol.COUNTER_PAST_TIME, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		pk, err := pack.ReadPack(din)
 		if err != nil {
 			return
 		}
 		param := pk.(*pack.MapPack)
-		date := param.GetText("date")
 		objHash := param.GetInt("objHash")
 		counterName := param.GetText("counter")
-		stime := int32(param.GetInt("stime"))
-		etime := int32(param.GetInt("etime"))
+		readRange := realtimeCounterRange(counterRD, param)
 
 		timeList := value.NewListValue()
 		valueList := value.NewListValue()
+		guard := newResultGuard(protocol.COUNTER_PAST_TIME, dout)
 
-		counterRD.ReadRealtimeRange(date, objHash, stime, etime, func(timeSec int32, counters map[string]value.Value) {
-			if v, ok := counters[counterName]; ok {
-				timeList.Value = append(timeList.Value, value.NewDecimalValue(int64(timeSec)))
+		readRange(objHash, func(t int64, counters map[string]value.Value) {
+			if v, ok := counters[counterName]; ok && guard.next() {
+				timeList.Value = append(timeList.Value, value.NewDecimalValue(t))
 				valueList.Value = append(valueList.Value, v)
 			}
 		})
@@ -46,33 +61,37 @@ func RegisterCounterReadHandlers(r *Registry, counterRD *counter.CounterRD, obje
 			dout.WriteByte(protocol.FLAG_HAS_NEXT)
 			pack.WritePack(dout, result)
 		}
+		guard.finish()
 	})
 
 	// COUNTER_PAST_TIME_ALL: read realtime counter range for all live objects of a type.
+	// See realtimeCounterRange for the stime/etime units.
 	r.Register(protocol.COUNTER_PAST_TIME_ALL, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		pk, err := pack.ReadPack(din)
 		if err != nil {
 			return
 		}
 		param := pk.(*pack.MapPack)
-		date := param.GetText("date")
 		counterName := param.GetText("counter")
 		objType := param.GetText("objType")
-		stime := int32(param.GetInt("stime"))
-		etime := int32(param.GetInt("etime"))
+		readRange := realtimeCounterRange(counterRD, param)
 
+		guard := newResultGuard(protocol.COUNTER_PAST_TIME_ALL, dout)
 		live := objectCache.GetLive(deadTimeout)
 		for _, info := range live {
 			if info.Pack.ObjType != objType {
 				continue
 			}
+			if guard.truncated() {
+				break
+			}
 
 			timeList := value.NewListValue()
 			valueList := value.NewListValue()
 
-			counterRD.ReadRealtimeRange(date, info.Pack.ObjHash, stime, etime, func(timeSec int32, counters map[string]value.Value) {
-				if v, ok := counters[counterName]; ok {
-					timeList.Value = append(timeList.Value, value.NewDecimalValue(int64(timeSec)))
+			readRange(info.Pack.ObjHash, func(t int64, counters map[string]value.Value) {
+				if v, ok := counters[counterName]; ok && guard.next() {
+					timeList.Value = append(timeList.Value, value.NewDecimalValue(t))
 					valueList.Value = append(valueList.Value, v)
 				}
 			})
@@ -86,6 +105,7 @@ func RegisterCounterReadHandlers(r *Registry, counterRD *counter.CounterRD, obje
 				pack.WritePack(dout, result)
 			}
 		}
+		guard.finish()
 	})
 
 	// COUNTER_PAST_DATE: read daily (5-min bucket) counter for a single object.
@@ -132,6 +152,7 @@ func RegisterCounterReadHandlers(r *Registry, counterRD *counter.CounterRD, obje
 		counterName := param.GetText("counter")
 		objType := param.GetText("objType")
 
+		guard := newResultGuard(protocol.COUNTER_PAST_DATE_ALL, dout)
 		live := objectCache.GetLive(deadTimeout)
 		for _, info := range live {
 			if info.Pack.ObjType != objType {
@@ -142,6 +163,9 @@ func RegisterCounterReadHandlers(r *Registry, counterRD *counter.CounterRD, obje
 			if err != nil || values == nil {
 				continue
 			}
+			if !guard.take(len(values)) {
+				break
+			}
 
 			floats := make([]float32, len(values))
 			for i, v := range values {
@@ -158,6 +182,7 @@ func RegisterCounterReadHandlers(r *Registry, counterRD *counter.CounterRD, obje
 			dout.WriteByte(protocol.FLAG_HAS_NEXT)
 			pack.WritePack(dout, result)
 		}
+		guard.finish()
 	})
 
 	// COUNTER_PAST_TIME_TOT: total/avg of realtime counter across all objects of a type.
@@ -414,6 +439,8 @@ func RegisterCounterReadHandlers(r *Registry, counterRD *counter.CounterRD, obje
 		stime := util.DateToMillis(sDate)
 		etime := util.DateToMillis(eDate) + int64(util.MillisPerDay)
 
+		guard := newResultGuard(protocol.COUNTER_PAST_LONGDATE_ALL, dout)
+	days:
 		for date := stime; date <= etime-int64(util.MillisPerDay); date += int64(util.MillisPerDay) {
 			d := util.FormatDate(date)
 			for _, objHash := range objHashes {
@@ -421,6 +448,9 @@ func RegisterCounterReadHandlers(r *Registry, counterRD *counter.CounterRD, obje
 				valueList := value.NewListValue()
 
 				v, err := counterRD.ReadDailyAll(d, objHash, counterName)
+				if !guard.take(len(v)) {
+					break days
+				}
 				if err == nil && v != nil {
 					for j, val := range v {
 						t := date + int64(j)*int64(util.MillisPerFiveMinute)
@@ -441,6 +471,7 @@ func RegisterCounterReadHandlers(r *Registry, counterRD *counter.CounterRD, obje
 				pack.WritePack(dout, result)
 			}
 		}
+		guard.finish()
 	})
 
 	// COUNTER_PAST_LONGDATE_TOT: total/avg daily counter across multiple days.
@@ -573,7 +604,7 @@ func RegisterCounterReadHandlers(r *Registry, counterRD *counter.CounterRD, obje
 	})
 
 	// GET_COUNTER_EXIST_DAYS: check which days have counter data.
-	r.Register(protocol.GET_COUNTER_EXIST_DAYS, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+	r.RegisterCached(protocol.GET_COUNTER_EXIST_DAYS, true, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		pk, err := pack.ReadPack(din)
 		if err != nil {
 			return
@@ -626,3 +657,31 @@ func toFloat64(v value.Value) float64 {
 	}
 	return 0
 }
+
+// realtimeCounterRange returns a reader for the realtime counter range of a
+// request. With "date", stime/etime are seconds of that day and the reader
+// passes the second of day as before. Without it they are ms, the range may
+// cross midnight, and the reader passes the time in ms so entries of
+// different days stay ordered.
+func realtimeCounterRange(counterRD *counter.CounterRD, param *pack.MapPack) func(objHash int32, fn func(t int64, counters map[string]value.Value)) {
+	if date := param.GetText("date"); date != "" {
+		stime := int32(param.GetInt("stime"))
+		etime := int32(param.GetInt("etime"))
+		return func(objHash int32, fn func(int64, map[string]value.Value)) {
+			counterRD.ReadRealtimeRange(date, objHash, stime, etime, func(timeSec int32, counters map[string]value.Value) {
+				fn(int64(timeSec), counters)
+			})
+		}
+	}
+	days := queryDays(param)
+	return func(objHash int32, fn func(int64, map[string]value.Value)) {
+		for _, d := range days {
+			midnight := util.DateToMillis(d.date)
+			startSec := int32((d.stime - midnight) / 1000)
+			endSec := int32((d.etime - midnight) / 1000)
+			counterRD.ReadRealtimeRange(d.date, objHash, startSec, endSec, func(timeSec int32, counters map[string]value.Value) {
+				fn(midnight+int64(timeSec)*1000, counters)
+			})
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// maxQueryDays bounds the dates a date-less time-range request may read, so
+// a bogus stime (e.g. 0) cannot make a handler open thousands of day files.
+const maxQueryDays = 31
+
+// dayRange is the part of a queried time range that falls on one date.
+type dayRange struct {
+	date         string
+	stime, etime int64 // ms, both inclusive
+}
+
+// splitDays splits [stime, etime] (ms) at local midnight into per-date
+// ranges, oldest first. Only the last maxQueryDays dates are kept.
+func splitDays(stime, etime int64) []dayRange {
+	if etime < stime {
+		return nil
+	}
+	var days []dayRange
+	for s := stime; s <= etime; {
+		date := util.FormatDate(s)
+		next := time.UnixMilli(util.DateToMillis(date)).AddDate(0, 0, 1).UnixMilli()
+		e := etime
+		if next-1 < e {
+			e = next - 1
+		}
+		days = append(days, dayRange{date: date, stime: s, etime: e})
+		s = next
+	}
+	if len(days) > maxQueryDays {
+		days = days[len(days)-maxQueryDays:]
+	}
+	return days
+}
+
+// queryDays returns the dates a time-range request reads. A request with
+// "date" reads that date with its stime/etime as before; without it the
+// dates are derived from "stime" and "etime" (ms), so a range crossing
+// midnight reads both days.
+func queryDays(param *pack.MapPack) []dayRange {
+	stime, etime := param.GetLong("stime"), param.GetLong("etime")
+	if date := param.GetText("date"); date != "" {
+		return []dayRange{{date: date, stime: stime, etime: etime}}
+	}
+	return splitDays(stime, etime)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+func TestSplitDays(t *testing.T) {
+	midnight := time.Date(2026, 3, 11, 0, 0, 0, 0, time.Local).UnixMilli()
+
+	days := splitDays(midnight-60_000, midnight+60_000)
+	if len(days) != 2 {
+		t.Fatalf("expected 2 days, got %+v", days)
+	}
+	if days[0] != (dayRange{date: "20260310", stime: midnight - 60_000, etime: midnight - 1}) {
+		t.Errorf("day 0 = %+v", days[0])
+	}
+	if days[1] != (dayRange{date: "20260311", stime: midnight, etime: midnight + 60_000}) {
+		t.Errorf("day 1 = %+v", days[1])
+	}
+
+	if days := splitDays(midnight, midnight+1000); len(days) != 1 || days[0].date != "20260311" {
+		t.Errorf("single day = %+v", days)
+	}
+	if days := splitDays(midnight, midnight-1); days != nil {
+		t.Errorf("inverted range = %+v", days)
+	}
+	days = splitDays(midnight-100*24*3600*1000, midnight)
+	if len(days) != maxQueryDays || days[len(days)-1].date != "20260311" {
+		t.Errorf("long range: %d days ending %+v", len(days), days[len(days)-1])
+	}
+
+	param := &pack.MapPack{}
+	param.PutStr("date", "20260310")
+	param.PutLong("stime", midnight-60_000)
+	param.PutLong("etime", midnight+60_000)
+	if days := queryDays(param); len(days) != 1 || days[0].date != "20260310" || days[0].etime != midnight+60_000 {
+		t.Errorf("explicit date = %+v", days)
+	}
+}
+
+// TestCounterPastTimeAcrossMidnight reads a realtime counter range spanning
+// two days without "date".
+func TestCounterPastTimeAcrossMidnight(t *testing.T) {
+	baseDir := t.TempDir()
+	midnight := time.Date(2026, 3, 11, 0, 0, 0, 0, time.Local)
+
+	counterWR := counter.NewCounterWR(baseDir)
+	ctx, cancel := context.WithCancel(context.Background())
+	counterWR.Start(ctx)
+	for i, offset := range []time.Duration{-2 * time.Second, -time.Second, 0, time.Second} {
+		counterWR.AddRealtime(&counter.RealtimeEntry{
+			TimeMs:   midnight.Add(offset).UnixMilli(),
+			ObjHash:  1,
+			Counters: map[string]value.Value{"TPS": value.NewDecimalValue(int64(i))},
+		})
+	}
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+	counterWR.Close()
+
+	counterRD := counter.NewCounterRD(baseDir)
+	defer counterRD.Close()
+	registry := NewRegistry()
+	RegisterCounterReadHandlers(registry, counterRD, cache.NewObjectCache(), 30*time.Second)
+
+	param := &pack.MapPack{}
+	param.PutLong("objHash", 1)
+	param.PutStr("counter", "TPS")
+	param.PutLong("stime", midnight.Add(-time.Second).UnixMilli())
+	param.PutLong("etime", midnight.Add(time.Second).UnixMilli())
+	dout := protocol.NewDataOutputX()
+	registry.Get(protocol.COUNTER_PAST_TIME)(buildRequest(param), dout, true)
+
+	resp := protocol.NewDataInputX(dout.ToByteArray())
+	if flag, err := resp.ReadByte(); err != nil || flag != protocol.FLAG_HAS_NEXT {
+		t.Fatalf("expected FLAG_HAS_NEXT, got 0x%02x, err=%v", flag, err)
+	}
+	pk, err := pack.ReadPack(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp := pk.(*pack.MapPack)
+	times, values := mp.GetList("time"), mp.GetList("value")
+	if times == nil || len(times.Value) != 3 {
+		t.Fatalf("expected 3 entries, got %v", times)
+	}
+	for i := 0; i < 3; i++ {
+		want := midnight.Add(time.Duration(i-1) * time.Second).UnixMilli()
+		if got := times.GetLong(i); got != want {
+			t.Errorf("time[%d] = %d, want %d", i, got, want)
+		}
+		if got := values.GetLong(i); got != int64(i+1) {
+			t.Errorf("value[%d] = %d, want %d", i, got, i+1)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"github.com/zbum/scouter-server-go/internal/core"
 	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
@@ -10,6 +11,9 @@ import (
 
 // RegisterGroupHandlers registers the REALTIME_SERVICE_GROUP handler.
 func RegisterGroupHandlers(r *Registry, xlogGroupPerf *core.XLogGroupPerf, textCache *cache.TextCache) {
+	r.Require(login.PermRead,
+		protocol.REALTIME_SERVICE_GROUP,
+	)
 	r.Register(protocol.REALTIME_SERVICE_GROUP, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		pk, err := pack.ReadPack(din)
 		if err != nil {
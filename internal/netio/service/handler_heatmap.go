@@ -0,0 +1,103 @@
+package service
+
+import (
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/heatmap"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// maxHeatmapDays bounds the range of one XLOG_HEATMAP request.
+const maxHeatmapDays = 31
+
+// RegisterHeatmapHandlers registers the XLOG_HEATMAP handler.
+func RegisterHeatmapHandlers(r *Registry, db *heatmap.DB) {
+	r.Require(login.PermRead,
+		protocol.XLOG_HEATMAP,
+	)
+
+	// XLOG_HEATMAP: elapsed-time histograms per 5-minute slot.
+	// Param: "stime", "etime" (epoch ms), optional "objType" (text or list; all types if empty).
+	// Response: "bounds" (bucket upper bounds in ms; the last bucket is open-ended),
+	// and parallel lists "time" (slot start), "count" and "error" (per-bucket lists)
+	// for the non-empty slots in the range.
+	r.Register(protocol.XLOG_HEATMAP, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		stime := param.GetLong("stime")
+		etime := param.GetLong("etime")
+		if etime <= stime {
+			return
+		}
+		if limit := stime + maxHeatmapDays*24*time.Hour.Milliseconds(); etime > limit {
+			etime = limit
+		}
+
+		var types map[string]bool
+		switch v := param.Get("objType").(type) {
+		case *value.TextValue:
+			if v.Value != "" {
+				types = map[string]bool{v.Value: true}
+			}
+		case *value.ListValue:
+			types = make(map[string]bool)
+			for i := range v.Value {
+				types[v.GetString(i)] = true
+			}
+		}
+
+		boundsLv := value.NewListValue()
+		for _, b := range heatmap.Bounds {
+			boundsLv.Value = append(boundsLv.Value, value.NewDecimalValue(int64(b)))
+		}
+		timeLv := value.NewListValue()
+		countLv := value.NewListValue()
+		errorLv := value.NewListValue()
+
+		start := time.UnixMilli(stime)
+		for d := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location()); d.UnixMilli() <= etime; d = d.AddDate(0, 0, 1) {
+			grids, err := db.Read(d.Format("20060102"))
+			if err != nil {
+				continue
+			}
+			merged := &heatmap.Grid{}
+			for objType, g := range grids {
+				if types == nil || types[objType] {
+					merged.Merge(g)
+				}
+			}
+			for slot, cells := range merged {
+				if cells == nil {
+					continue
+				}
+				t := d.Add(time.Duration(slot) * heatmap.SlotMs * time.Millisecond).UnixMilli()
+				if t+heatmap.SlotMs <= stime || t > etime {
+					continue
+				}
+				counts := value.NewListValue()
+				errs := value.NewListValue()
+				for _, c := range cells {
+					counts.Value = append(counts.Value, value.NewDecimalValue(c.Count))
+					errs.Value = append(errs.Value, value.NewDecimalValue(c.Errors))
+				}
+				timeLv.Value = append(timeLv.Value, value.NewDecimalValue(t))
+				countLv.Value = append(countLv.Value, counts)
+				errorLv.Value = append(errorLv.Value, errs)
+			}
+		}
+
+		resp := &pack.MapPack{}
+		resp.Put("bounds", boundsLv)
+		resp.Put("time", timeLv)
+		resp.Put("count", countLv)
+		resp.Put("error", errorLv)
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+}
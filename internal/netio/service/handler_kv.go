@@ -1,7 +1,11 @@
 package service
 
 import (
+	"errors"
+
+	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/db/kv"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
@@ -9,6 +13,20 @@ import (
 
 // RegisterKVHandlers registers handlers for KV store operations.
 func RegisterKVHandlers(r *Registry, globalKV, customKV *kv.KVStore) {
+	r.Require(login.PermRead,
+		protocol.GET_GLOBAL_KV,
+		protocol.GET_CUSTOM_KV,
+		protocol.GET_GLOBAL_KV_BULK,
+		protocol.GET_CUSTOM_KV_BULK,
+	)
+	r.Require(login.PermWrite,
+		protocol.SET_GLOBAL_KV,
+		protocol.SET_GLOBAL_TTL,
+		protocol.SET_GLOBAL_KV_BULK,
+		protocol.SET_CUSTOM_KV,
+		protocol.SET_CUSTOM_TTL,
+		protocol.SET_CUSTOM_KV_BULK,
+	)
 
 	// GET_GLOBAL_KV: retrieve a value from the global namespace
 	r.Register(protocol.GET_GLOBAL_KV, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
@@ -230,3 +248,374 @@ func RegisterKVHandlers(r *Registry, globalKV, customKV *kv.KVStore) {
 		pack.WritePack(dout, response)
 	})
 }
+
+// RegisterKVNamespaceHandlers registers handlers for named KV namespaces.
+// The built-in "global" and "custom" stores are reachable as namespaces too.
+func RegisterKVNamespaceHandlers(r *Registry, namespaces *kv.Namespaces) {
+	r.Require(login.PermRead,
+		protocol.KV_NAMESPACE_LIST,
+		protocol.GET_NS_KV,
+		protocol.GET_NS_KV_BULK,
+		protocol.GET_NS_KV_KEYS,
+	)
+	r.Require(login.PermWrite,
+		protocol.SET_NS_KV,
+		protocol.DELETE_NS_KV,
+	)
+	r.Require(login.PermAdmin,
+		protocol.KV_NAMESPACE_SET,
+		protocol.KV_NAMESPACE_DROP,
+	)
+
+	// KV_NAMESPACE_LIST: all namespaces with limits and usage.
+	// Response: one MapPack per namespace with "name", "quotaBytes",
+	// "defaultTtl", "builtin", "keys" and "bytes".
+	r.Register(protocol.KV_NAMESPACE_LIST, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pack.ReadPack(din)
+
+		for _, ns := range namespaces.List() {
+			response := &pack.MapPack{}
+			response.PutStr("name", ns.Name)
+			response.PutLong("quotaBytes", ns.QuotaBytes)
+			response.PutLong("defaultTtl", ns.DefaultTTLMs)
+			response.Put("builtin", &value.BooleanValue{Value: ns.Builtin})
+			response.PutLong("keys", int64(ns.Keys))
+			response.PutLong("bytes", ns.Bytes)
+
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, response)
+		}
+	})
+
+	// KV_NAMESPACE_SET: create a namespace or change its limits.
+	// Param: "ns", "quotaBytes" (0 = unlimited), "defaultTtl" (ms, 0 = none).
+	// Response: "result" ("ok" or "error: ...").
+	r.Register(protocol.KV_NAMESPACE_SET, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		maxCount := 0
+		if cfg := config.Get(); cfg != nil {
+			maxCount = cfg.KVNamespaceMax()
+		}
+		err = namespaces.Set(param.GetText("ns"), param.GetLong("quotaBytes"), param.GetLong("defaultTtl"), maxCount)
+		writeKVResult(dout, err)
+	})
+
+	// KV_NAMESPACE_DROP: delete a namespace and all of its keys.
+	// Param: "ns". Response: "result" ("ok" or "error: ...").
+	r.Register(protocol.KV_NAMESPACE_DROP, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		found, err := namespaces.Drop(param.GetText("ns"))
+		if err == nil && !found {
+			err = errNoSuchNamespace
+		}
+		writeKVResult(dout, err)
+	})
+
+	// GET_NS_KV: retrieve a value. Param: "ns", "key".
+	// Response: "value" if the key exists.
+	r.Register(protocol.GET_NS_KV, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		response := &pack.MapPack{}
+		if store, ok := namespaces.Get(param.GetText("ns")); ok {
+			if val, ok := store.Get(param.GetText("key")); ok {
+				response.PutStr("value", val)
+			}
+		}
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, response)
+	})
+
+	// SET_NS_KV: store a value subject to the namespace quota.
+	// Param: "ns", "key", "value", optional "ttl" (ms; 0 = namespace default,
+	// negative = no expiry). Response: "result" ("ok" or "error: ...").
+	r.Register(protocol.SET_NS_KV, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		store, ok := namespaces.Get(param.GetText("ns"))
+		if !ok {
+			writeKVResult(dout, errNoSuchNamespace)
+			return
+		}
+		writeKVResult(dout, store.Put(param.GetText("key"), param.GetText("value"), param.GetLong("ttl")))
+	})
+
+	// DELETE_NS_KV: remove a key. Param: "ns", "key".
+	// Response: "result" ("ok" or "error: ...").
+	r.Register(protocol.DELETE_NS_KV, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		store, ok := namespaces.Get(param.GetText("ns"))
+		if !ok {
+			writeKVResult(dout, errNoSuchNamespace)
+			return
+		}
+		store.Delete(param.GetText("key"))
+		writeKVResult(dout, nil)
+	})
+
+	// GET_NS_KV_BULK: retrieve multiple values. Param: "ns", "keys" (list).
+	// Response: one entry per found key.
+	r.Register(protocol.GET_NS_KV_BULK, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		response := &pack.MapPack{}
+		if store, ok := namespaces.Get(param.GetText("ns")); ok {
+			keys := make([]string, 0)
+			if listVal := param.GetList("keys"); listVal != nil {
+				for i := range listVal.Value {
+					keys = append(keys, listVal.GetString(i))
+				}
+			}
+			for k, v := range store.GetBulk(keys) {
+				response.PutStr(k, v)
+			}
+		}
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, response)
+	})
+
+	// GET_NS_KV_KEYS: list keys. Param: "ns", optional "prefix".
+	// Response: "keys" list, sorted.
+	r.Register(protocol.GET_NS_KV_KEYS, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		store, ok := namespaces.Get(param.GetText("ns"))
+		if !ok {
+			return
+		}
+		keys := value.NewListValue()
+		for _, k := range store.Keys(param.GetText("prefix")) {
+			keys.Value = append(keys.Value, value.NewTextValue(k))
+		}
+		response := &pack.MapPack{}
+		response.Put("keys", keys)
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, response)
+	})
+}
+
+// RegisterAccountKVHandlers registers handlers for the private KV store of
+// the calling session's account, and the admin commands that list and drop
+// the stores of all accounts.
+func RegisterAccountKVHandlers(r *Registry, stores *kv.AccountStores, sessions *login.SessionManager) {
+	// Sessions only reach their own account's store, so read is enough to
+	// change it.
+	r.Require(login.PermRead,
+		protocol.GET_ACCOUNT_KV,
+		protocol.SET_ACCOUNT_KV,
+		protocol.DELETE_ACCOUNT_KV,
+		protocol.GET_ACCOUNT_KV_BULK,
+		protocol.GET_ACCOUNT_KV_KEYS,
+		protocol.KV_ACCOUNT_LIST,
+	)
+	r.Require(login.PermAdmin,
+		protocol.KV_ACCOUNT_DROP,
+	)
+
+	// accountStore returns the store of the account logged in with session.
+	accountStore := func(session int64) (*kv.KVStore, error) {
+		user := sessions.GetUser(session)
+		if user == nil {
+			return nil, errNotLoggedIn
+		}
+		return stores.Get(user.ID)
+	}
+
+	// GET_ACCOUNT_KV: retrieve a value. Param: "key".
+	// Response: "value" if the key exists.
+	r.RegisterSession(protocol.GET_ACCOUNT_KV, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		response := &pack.MapPack{}
+		if store, err := accountStore(session); err == nil {
+			if val, ok := store.Get(param.GetText("key")); ok {
+				response.PutStr("value", val)
+			}
+		}
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, response)
+	})
+
+	// SET_ACCOUNT_KV: store a value subject to kv_account_quota_bytes.
+	// Param: "key", "value", optional "ttl" (ms; 0 or negative = no expiry).
+	// Response: "result" ("ok" or "error: ...").
+	r.RegisterSession(protocol.SET_ACCOUNT_KV, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		store, err := accountStore(session)
+		if err != nil {
+			writeKVResult(dout, err)
+			return
+		}
+		writeKVResult(dout, store.Put(param.GetText("key"), param.GetText("value"), param.GetLong("ttl")))
+	})
+
+	// DELETE_ACCOUNT_KV: remove a key. Param: "key".
+	// Response: "result" ("ok" or "error: ...").
+	r.RegisterSession(protocol.DELETE_ACCOUNT_KV, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		store, err := accountStore(session)
+		if err != nil {
+			writeKVResult(dout, err)
+			return
+		}
+		store.Delete(param.GetText("key"))
+		writeKVResult(dout, nil)
+	})
+
+	// GET_ACCOUNT_KV_BULK: retrieve multiple values. Param: "keys" (list).
+	// Response: one entry per found key.
+	r.RegisterSession(protocol.GET_ACCOUNT_KV_BULK, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		response := &pack.MapPack{}
+		if store, err := accountStore(session); err == nil {
+			for k, v := range store.GetBulk(listTexts(param.GetList("keys"))) {
+				response.PutStr(k, v)
+			}
+		}
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, response)
+	})
+
+	// GET_ACCOUNT_KV_KEYS: list keys. Param: optional "prefix".
+	// Response: "keys" list, sorted.
+	r.RegisterSession(protocol.GET_ACCOUNT_KV_KEYS, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		store, err := accountStore(session)
+		if err != nil {
+			return
+		}
+		keys := value.NewListValue()
+		for _, k := range store.Keys(param.GetText("prefix")) {
+			keys.Value = append(keys.Value, value.NewTextValue(k))
+		}
+		response := &pack.MapPack{}
+		response.Put("keys", keys)
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, response)
+	})
+
+	// KV_ACCOUNT_LIST: usage of every account's store; admin group only.
+	// Response: one MapPack per account with "account", "keys" and "bytes".
+	r.RegisterSession(protocol.KV_ACCOUNT_LIST, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pack.ReadPack(din)
+		if !isAdmin(sessions, session) {
+			return
+		}
+
+		for _, u := range stores.List() {
+			response := &pack.MapPack{}
+			response.PutStr("account", u.Account)
+			response.PutLong("keys", int64(u.Keys))
+			response.PutLong("bytes", u.Bytes)
+
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, response)
+		}
+	})
+
+	// KV_ACCOUNT_DROP: delete the store of an account, e.g. one that was
+	// removed; admin group only. Param: "account".
+	// Response: "result" ("ok" or "error: ...").
+	r.RegisterSession(protocol.KV_ACCOUNT_DROP, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		if !isAdmin(sessions, session) {
+			writeKVResult(dout, errNotAdmin)
+			return
+		}
+		found, err := stores.Drop(param.GetText("account"))
+		if err == nil && !found {
+			err = errNoAccountData
+		}
+		writeKVResult(dout, err)
+	})
+}
+
+// isAdmin reports whether session belongs to a user of the admin group.
+func isAdmin(sessions *login.SessionManager, session int64) bool {
+	user := sessions.GetUser(session)
+	return user != nil && user.Group == "admin"
+}
+
+var (
+	errNoSuchNamespace = errors.New("no such namespace")
+	errNotLoggedIn     = errors.New("not logged in")
+	errNotAdmin        = errors.New("admin group required")
+	errNoAccountData   = errors.New("no data for account")
+)
+
+func writeKVResult(dout *protocol.DataOutputX, err error) {
+	response := &pack.MapPack{}
+	if err != nil {
+		response.PutStr("result", "error: "+err.Error())
+	} else {
+		response.PutStr("result", "ok")
+	}
+	dout.WriteByte(protocol.FLAG_HAS_NEXT)
+	pack.WritePack(dout, response)
+}
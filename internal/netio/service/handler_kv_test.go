@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/zbum/scouter-server-go/internal/db/kv"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
@@ -340,3 +341,136 @@ func TestKVHandlers_TTL(t *testing.T) {
 		}
 	})
 }
+
+func TestKVNamespaceHandlers(t *testing.T) {
+	tmpDir := t.TempDir()
+	globalKV := kv.NewKVStore(tmpDir, "global.json")
+	namespaces := kv.NewNamespaces(tmpDir, map[string]*kv.KVStore{"global": globalKV})
+	defer namespaces.Close()
+
+	registry := NewRegistry()
+	RegisterKVNamespaceHandlers(registry, namespaces)
+
+	call := func(cmd string, req *pack.MapPack) *pack.MapPack {
+		t.Helper()
+		out := protocol.NewDataOutputX()
+		registry.Get(cmd)(buildRequest(req), out, true)
+		in := protocol.NewDataInputX(out.ToByteArray())
+		if flag, _ := in.ReadByte(); flag != protocol.FLAG_HAS_NEXT {
+			return nil
+		}
+		pk, err := pack.ReadPack(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pk.(*pack.MapPack)
+	}
+
+	req := &pack.MapPack{}
+	req.PutStr("ns", "plugin")
+	req.PutLong("quotaBytes", 10)
+	if r := call(protocol.KV_NAMESPACE_SET, req); r.GetText("result") != "ok" {
+		t.Fatalf("KV_NAMESPACE_SET result = %q", r.GetText("result"))
+	}
+
+	req = &pack.MapPack{}
+	req.PutStr("ns", "plugin")
+	req.PutStr("key", "k")
+	req.PutStr("value", "v")
+	if r := call(protocol.SET_NS_KV, req); r.GetText("result") != "ok" {
+		t.Fatalf("SET_NS_KV result = %q", r.GetText("result"))
+	}
+	req.PutStr("key", "big")
+	req.PutStr("value", "0123456789")
+	if r := call(protocol.SET_NS_KV, req); r.GetText("result") != "error: kv quota exceeded" {
+		t.Errorf("SET_NS_KV over quota result = %q", r.GetText("result"))
+	}
+
+	req = &pack.MapPack{}
+	req.PutStr("ns", "plugin")
+	req.PutStr("key", "k")
+	if r := call(protocol.GET_NS_KV, req); r.GetText("value") != "v" {
+		t.Errorf("GET_NS_KV value = %q", r.GetText("value"))
+	}
+	if r := call(protocol.GET_NS_KV_KEYS, req); r.GetList("keys") == nil || len(r.GetList("keys").Value) != 1 {
+		t.Errorf("GET_NS_KV_KEYS = %v", r)
+	}
+
+	req = &pack.MapPack{}
+	req.PutStr("ns", "missing")
+	req.PutStr("key", "k")
+	if r := call(protocol.SET_NS_KV, req); r.GetText("result") != "error: no such namespace" {
+		t.Errorf("SET_NS_KV unknown ns result = %q", r.GetText("result"))
+	}
+}
+
+func TestAccountKVHandlers(t *testing.T) {
+	stores := kv.NewAccountStores(t.TempDir())
+	defer stores.Close()
+
+	sessions := login.NewSessionManager(nil)
+	admin := sessions.Login("admin", "", "127.0.0.1")
+	sessions.GetUser(admin).Group = "admin"
+	guest := sessions.Login("guest", "", "127.0.0.1")
+
+	registry := NewRegistry()
+	RegisterAccountKVHandlers(registry, stores, sessions)
+
+	call := func(cmd string, session int64, req *pack.MapPack) []*pack.MapPack {
+		t.Helper()
+		out := protocol.NewDataOutputX()
+		registry.GetSession(cmd)(session, buildRequest(req), out, true)
+		in := protocol.NewDataInputX(out.ToByteArray())
+		var packs []*pack.MapPack
+		for {
+			if flag, err := in.ReadByte(); err != nil || flag != protocol.FLAG_HAS_NEXT {
+				return packs
+			}
+			pk, err := pack.ReadPack(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			packs = append(packs, pk.(*pack.MapPack))
+		}
+	}
+
+	for session, layout := range map[int64]string{admin: "grid", guest: "list"} {
+		req := &pack.MapPack{}
+		req.PutStr("key", "layout")
+		req.PutStr("value", layout)
+		if r := call(protocol.SET_ACCOUNT_KV, session, req); r[0].GetText("result") != "ok" {
+			t.Fatalf("SET_ACCOUNT_KV result = %q", r[0].GetText("result"))
+		}
+	}
+
+	req := &pack.MapPack{}
+	req.PutStr("key", "layout")
+	if r := call(protocol.GET_ACCOUNT_KV, guest, req); r[0].GetText("value") != "list" {
+		t.Errorf("guest layout = %q", r[0].GetText("value"))
+	}
+	if r := call(protocol.GET_ACCOUNT_KV_KEYS, admin, &pack.MapPack{}); len(r[0].GetList("keys").Value) != 1 {
+		t.Errorf("GET_ACCOUNT_KV_KEYS = %v", r[0])
+	}
+	if r := call(protocol.SET_ACCOUNT_KV, 12345, req); r[0].GetText("result") != "error: not logged in" {
+		t.Errorf("unknown session result = %q", r[0].GetText("result"))
+	}
+
+	if r := call(protocol.KV_ACCOUNT_LIST, guest, &pack.MapPack{}); len(r) != 0 {
+		t.Errorf("KV_ACCOUNT_LIST allowed for guest: %v", r)
+	}
+	if r := call(protocol.KV_ACCOUNT_LIST, admin, &pack.MapPack{}); len(r) != 2 || r[1].GetText("account") != "guest" {
+		t.Errorf("KV_ACCOUNT_LIST = %v", r)
+	}
+
+	drop := &pack.MapPack{}
+	drop.PutStr("account", "guest")
+	if r := call(protocol.KV_ACCOUNT_DROP, guest, drop); r[0].GetText("result") != "error: admin group required" {
+		t.Errorf("KV_ACCOUNT_DROP by guest result = %q", r[0].GetText("result"))
+	}
+	if r := call(protocol.KV_ACCOUNT_DROP, admin, drop); r[0].GetText("result") != "ok" {
+		t.Errorf("KV_ACCOUNT_DROP result = %q", r[0].GetText("result"))
+	}
+	if r := call(protocol.GET_ACCOUNT_KV, guest, req); r[0].GetText("value") != "" {
+		t.Errorf("dropped value still readable: %q", r[0].GetText("value"))
+	}
+}
@@ -13,6 +13,10 @@ import (
 
 // RegisterLoginHandlers registers LOGIN and related handlers.
 func RegisterLoginHandlers(r *Registry, sessions *login.SessionManager, accountManager *login.AccountManager, version string) {
+	r.Require(login.PermRead,
+		protocol.LOGIN,
+		protocol.CHECK_SESSION,
+	)
 	r.Register(protocol.LOGIN, func(din *protocol.DataInputX, dout *protocol.DataOutputX, loggedIn bool) {
 		pk, err := pack.ReadPack(din)
 		if err != nil {
@@ -37,6 +41,18 @@ func RegisterLoginHandlers(r *Registry, sessions *login.SessionManager, accountM
 				user.Hostname = hostname
 				user.Version = clientVer
 			}
+			// Negotiate response compression; the request's "compress" is
+			// echoed back otherwise, so it is always overwritten.
+			compress := ""
+			if m.GetText("compress") == "zstd" {
+				if cfg := config.Get(); cfg == nil || cfg.NetTcpCompressEnabled() {
+					compress = "zstd"
+				}
+			}
+			if user != nil {
+				user.Compress = compress != ""
+			}
+			m.PutStr("compress", compress)
 			m.PutLong("time", time.Now().UnixMilli())
 
 			serverID := getHostname()
@@ -114,6 +130,10 @@ func RegisterLoginHandlers(r *Registry, sessions *login.SessionManager, accountM
 
 // RegisterLoginExtHandlers registers CHECK_LOGIN and GET_LOGIN_LIST handlers.
 func RegisterLoginExtHandlers(r *Registry, sessions *login.SessionManager, accountManager *login.AccountManager) {
+	r.Require(login.PermRead,
+		protocol.CHECK_LOGIN,
+		protocol.GET_LOGIN_LIST,
+	)
 
 	// CHECK_LOGIN: verify user credentials without creating a session.
 	r.Register(protocol.CHECK_LOGIN, func(din *protocol.DataInputX, dout *protocol.DataOutputX, loggedIn bool) {
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/notify"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// RegisterNotifyHandlers registers the notification delivery handler.
+func RegisterNotifyHandlers(r *Registry, deliveries *notify.Deliveries) {
+	r.Require(login.PermRead,
+		protocol.NOTIFY_DELIVERY_LIST,
+	)
+
+	// NOTIFY_DELIVERY_LIST: recent outbound notifications and their outcome.
+	// Param: "status" ("sending", "sent" or "failed"; optional), "channel"
+	// (such as "mail"; optional) and "max" (default all kept).
+	// Response: one MapPack per delivery, newest first, with "id", "time",
+	// "source", "channel", "subject", "status", "attempts", "error" (of the
+	// last failed attempt) and "elapsed" (ms over all attempts).
+	r.Register(protocol.NOTIFY_DELIVERY_LIST, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		for _, d := range deliveries.List(param.GetText("status"), param.GetText("channel"), int(param.GetInt("max"))) {
+			m := &pack.MapPack{}
+			m.PutLong("id", d.ID)
+			m.PutLong("time", d.Time)
+			m.PutStr("source", d.Source)
+			m.PutStr("channel", d.Channel)
+			m.PutStr("subject", d.Subject)
+			m.PutStr("status", d.Status)
+			m.PutLong("attempts", int64(d.Attempts))
+			m.PutStr("error", d.Error)
+			m.PutLong("elapsed", d.Elapsed)
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, m)
+		}
+	})
+}
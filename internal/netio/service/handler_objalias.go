@@ -0,0 +1,70 @@
+package service
+
+import (
+	"github.com/zbum/scouter-server-go/internal/objalias"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// objectAliasAwareCommands are the XLog history commands whose "objHash"
+// filter is widened to the sources of an alias. Counter history is merged by
+// CounterRD itself.
+var objectAliasAwareCommands = []string{
+	protocol.TRANX_LOAD_TIME_GROUP,
+	protocol.TRANX_LOAD_TIME_GROUP_V2,
+}
+
+// RegisterObjectAliasHandlers wraps objectAliasAwareCommands so a filter on an
+// alias objHash also matches the XLogs stored under the objHashes it was fed
+// from. It must be called after the handlers it wraps are registered and
+// before RegisterObjectGroupHandlers, so group variants are widened too.
+func RegisterObjectAliasHandlers(r *Registry, aliases *objalias.Manager) {
+	for _, cmd := range objectAliasAwareCommands {
+		base := r.Get(cmd)
+		if base == nil {
+			continue
+		}
+		r.Register(cmd, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+			pk, err := pack.ReadPack(din)
+			if err != nil {
+				return
+			}
+			param, ok := pk.(*pack.MapPack)
+			if !ok {
+				return
+			}
+			if lv, ok := param.Get("objHash").(*value.ListValue); ok && len(lv.Value) > 0 {
+				param.Put("objHash", withAliasSources(lv, aliases))
+			}
+
+			o := protocol.NewDataOutputX()
+			pack.WritePack(o, param)
+			base(protocol.NewDataInputX(o.ToByteArray()), dout, login)
+		})
+	}
+}
+
+// withAliasSources returns the objHash list extended by the sources of each
+// of its elements.
+func withAliasSources(lv *value.ListValue, aliases *objalias.Manager) *value.ListValue {
+	seen := make(map[int32]bool)
+	var hashes []int32
+	add := func(h int32) {
+		if !seen[h] {
+			seen[h] = true
+			hashes = append(hashes, h)
+		}
+	}
+	for _, v := range lv.Value {
+		dv, ok := v.(*value.DecimalValue)
+		if !ok {
+			continue
+		}
+		add(int32(dv.Value))
+		for _, h := range aliases.Sources(int32(dv.Value)) {
+			add(h)
+		}
+	}
+	return hashList(hashes)
+}
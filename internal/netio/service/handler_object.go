@@ -5,6 +5,7 @@ import (
 
 	"github.com/zbum/scouter-server-go/internal/counter"
 	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
@@ -12,6 +13,9 @@ import (
 
 // RegisterObjectHandlers registers OBJECT_LIST_REAL_TIME and related handlers.
 func RegisterObjectHandlers(r *Registry, objectCache *cache.ObjectCache, deadTimeout time.Duration, counterCache *cache.CounterCache, typeManager *counter.ObjectTypeManager) {
+	r.Require(login.PermRead,
+		protocol.OBJECT_LIST_REAL_TIME,
+	)
 	r.Register(protocol.OBJECT_LIST_REAL_TIME, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		all := objectCache.GetAll()
 		for _, info := range all {
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/agentinv"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// RegisterObjectCleanupHandlers registers the bulk removal of dead objects.
+// inventory may be nil.
+func RegisterObjectCleanupHandlers(r *Registry, objectCache *cache.ObjectCache, inventory *agentinv.Store) {
+	r.Require(login.PermWrite,
+		protocol.OBJECT_REMOVE_DEAD,
+	)
+
+	// OBJECT_REMOVE_DEAD: remove the objects dead for more than "days" from
+	// the object cache and the agent inventory, so the object trees of
+	// long-lived servers are not cluttered by autoscaled hosts long gone.
+	// Live objects are never removed; an agent removed from the inventory
+	// only is listed there again when it reports.
+	// Param: "days" (required, > 0), "objType" (optional), "dryRun" (list
+	// only).
+	// Response: "result" ("ok" or "error: ..."), "dryRun", "count" and
+	// parallel lists "objHash", "objName", "objType", "lastSeen" (ms).
+	r.Register(protocol.OBJECT_REMOVE_DEAD, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		days := param.GetInt("days")
+		objType := param.GetText("objType")
+		dryRun := param.GetBoolean("dryRun")
+
+		resp := &pack.MapPack{}
+		if days <= 0 {
+			resp.PutStr("result", "error: days must be positive")
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, resp)
+			return
+		}
+		now := time.Now()
+		cutoff := now.AddDate(0, 0, -int(days))
+
+		hashes := value.NewListValue()
+		names := value.NewListValue()
+		types := value.NewListValue()
+		lastSeen := value.NewListValue()
+		add := func(objHash int32, objName, objType string, seen int64) {
+			hashes.Value = append(hashes.Value, value.NewDecimalValue(int64(objHash)))
+			names.Value = append(names.Value, value.NewTextValue(objName))
+			types.Value = append(types.Value, value.NewTextValue(objType))
+			lastSeen.Value = append(lastSeen.Value, value.NewDecimalValue(seen))
+		}
+
+		var removed []int32
+		for _, info := range objectCache.RemoveDead(cutoff, objType, dryRun) {
+			removed = append(removed, info.Pack.ObjHash)
+			add(info.Pack.ObjHash, info.Pack.ObjName, info.Pack.ObjType, info.LastSeen.UnixMilli())
+		}
+		result := "ok"
+		if inventory != nil {
+			var stale []int32
+			for _, a := range inventory.Stale(cutoff.UnixMilli(), objType) {
+				if slices.Contains(removed, a.ObjHash) {
+					stale = append(stale, a.ObjHash)
+					continue
+				}
+				if _, ok := objectCache.Get(a.ObjHash); ok {
+					continue // alive, or not dead for long enough
+				}
+				stale = append(stale, a.ObjHash)
+				add(a.ObjHash, a.ObjName, a.ObjType, a.LastSeen)
+			}
+			if !dryRun {
+				if err := inventory.Remove(stale, now.UnixMilli()); err != nil {
+					result = "error: " + err.Error()
+				}
+			}
+		}
+		if !dryRun {
+			slog.Info("OBJECT_REMOVE_DEAD: removed dead objects", "days", days, "objType", objType, "count", len(hashes.Value))
+		}
+
+		resp.PutStr("result", result)
+		resp.Put("dryRun", &value.BooleanValue{Value: dryRun})
+		resp.PutLong("count", int64(len(hashes.Value)))
+		resp.Put("objHash", hashes)
+		resp.Put("objName", names)
+		resp.Put("objType", types)
+		resp.Put("lastSeen", lastSeen)
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/agentinv"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+func TestObjectRemoveDead(t *testing.T) {
+	now := time.Now()
+	objectCache := cache.NewObjectCache()
+	objectCache.PutSeen(1, &pack.ObjectPack{ObjHash: 1, ObjName: "/old/tomcat", ObjType: "tomcat"}, now.AddDate(0, 0, -10))
+	objectCache.PutSeen(2, &pack.ObjectPack{ObjHash: 2, ObjName: "/recent/tomcat", ObjType: "tomcat"}, now.AddDate(0, 0, -1))
+	objectCache.PutSeen(3, &pack.ObjectPack{ObjHash: 3, ObjName: "/live/tomcat", ObjType: "tomcat", Alive: true}, now.AddDate(0, 0, -10))
+	inventory, err := agentinv.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inventory.Close()
+	old := now.AddDate(0, 0, -20).UnixMilli()
+	for i, name := range []string{"/old/tomcat", "/recent/tomcat", "/live/tomcat", "/gone/tomcat"} {
+		inventory.Update(agentinv.Agent{ObjHash: int32(i + 1), ObjName: name, ObjType: "tomcat", Version: "1.0"}, old)
+	}
+
+	registry := NewRegistry()
+	RegisterObjectCleanupHandlers(registry, objectCache, inventory)
+	call := func(days int64, dryRun bool) *pack.MapPack {
+		req := &pack.MapPack{}
+		req.PutLong("days", days)
+		req.Put("dryRun", &value.BooleanValue{Value: dryRun})
+		out := protocol.NewDataOutputX()
+		registry.Get(protocol.OBJECT_REMOVE_DEAD)(buildRequest(req), out, true)
+		r := readMapPacks(t, out)
+		if len(r) != 1 {
+			t.Fatalf("OBJECT_REMOVE_DEAD = %v", r)
+		}
+		return r[0]
+	}
+
+	if r := call(0, false); r.GetText("result") == "ok" {
+		t.Errorf("days 0 accepted: %v", r)
+	}
+	// Dead for 7 days: the old object, and the agent gone before the
+	// server started.
+	r := call(7, true)
+	if r.GetText("result") != "ok" || r.GetLong("count") != 2 {
+		t.Fatalf("dry run = %v", r)
+	}
+	if objectCache.Size() != 3 || len(inventory.List("")) != 4 {
+		t.Fatal("dry run removed objects")
+	}
+	r = call(7, false)
+	if names := r.GetList("objName"); r.GetLong("count") != 2 || names.GetString(0) != "/old/tomcat" || names.GetString(1) != "/gone/tomcat" {
+		t.Errorf("removed = %v", r)
+	}
+	if _, ok := objectCache.Get(1); ok || objectCache.Size() != 2 {
+		t.Error("old object still cached")
+	}
+	if agents := inventory.List(""); len(agents) != 2 {
+		t.Errorf("inventory = %+v", agents)
+	}
+}
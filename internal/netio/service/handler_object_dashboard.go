@@ -0,0 +1,120 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/alert"
+	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// dashboardCounters are the realtime counters OBJECT_DASHBOARD returns when
+// the request names none.
+var dashboardCounters = []string{"TPS", "ElapsedTime", "ErrorRate", "ActiveService", "Cpu", "HeapUsed"}
+
+// dashboardAlerts is the number of recent alerts OBJECT_DASHBOARD returns.
+const dashboardAlerts = 10
+
+// RegisterObjectDashboardHandlers registers the handler that returns what an
+// object view shows when it is opened, in one call.
+func RegisterObjectDashboardHandlers(r *Registry, objectCache *cache.ObjectCache, counterCache *cache.CounterCache,
+	counterRD *counter.CounterRD, alertRD *alert.AlertRD) {
+	r.Require(login.PermRead, protocol.OBJECT_DASHBOARD)
+
+	// OBJECT_DASHBOARD: snapshot of one object. Param: "objHash", optional
+	// "counter" list overriding dashboardCounters.
+	// Response: a MapPack with "objHash", "objName", "objType", "alive",
+	// "counter" (name → latest realtime value), "act1".."act3" (active
+	// service speed), "stime" (today's midnight, ms) and "tps"/"error"
+	// (today's TPS and ErrorRate in 5-minute buckets up to now), followed by
+	// up to 10 of today's AlertPacks of the object, newest first.
+	r.Register(protocol.OBJECT_DASHBOARD, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		objHash := param.GetInt("objHash")
+
+		resp := &pack.MapPack{}
+		resp.PutLong("objHash", int64(objHash))
+		if info, ok := objectCache.Get(objHash); ok {
+			resp.PutStr("objName", info.Pack.ObjName)
+			resp.PutStr("objType", info.Pack.ObjType)
+			resp.Put("alive", &value.BooleanValue{Value: info.Pack.Alive})
+		}
+
+		names := dashboardCounters
+		if lv := param.GetList("counter"); lv != nil && len(lv.Value) > 0 {
+			names = make([]string, len(lv.Value))
+			for i := range lv.Value {
+				names[i] = lv.GetString(i)
+			}
+		}
+		counters := value.NewMapValue()
+		for _, name := range names {
+			if v, ok := counterCache.Get(cache.CounterKey{ObjHash: objHash, Counter: name, TimeType: cache.TimeTypeRealtime}); ok && v != nil {
+				counters.Put(name, v)
+			}
+		}
+		resp.Put("counter", counters)
+
+		var act1, act2, act3 int32
+		if v, ok := counterCache.Get(cache.CounterKey{ObjHash: objHash, Counter: counterActiveSpeed, TimeType: cache.TimeTypeRealtime}); ok {
+			if lv, ok := v.(*value.ListValue); ok && len(lv.Value) >= 3 {
+				act1, act2, act3 = lv.GetInt(0), lv.GetInt(1), lv.GetInt(2)
+			}
+		}
+		resp.PutLong("act1", int64(act1))
+		resp.PutLong("act2", int64(act2))
+		resp.PutLong("act3", int64(act3))
+
+		now := time.Now().UnixMilli()
+		date := util.FormatDate(now)
+		start := util.DateToMillis(date)
+		buckets := util.GetDateMillis(now)/util.MillisPerFiveMinute + 1
+		resp.PutLong("stime", start)
+		resp.Put("tps", todayBuckets(counterRD, date, objHash, "TPS", buckets))
+		resp.Put("error", todayBuckets(counterRD, date, objHash, "ErrorRate", buckets))
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+
+		var alerts [][]byte
+		alertRD.ReadRange(date, start, now, func(data []byte) {
+			p, err := pack.ReadPack(protocol.NewDataInputX(data))
+			if err != nil {
+				return
+			}
+			if ap, ok := p.(*pack.AlertPack); ok && ap.ObjHash == objHash {
+				alerts = append(alerts, data)
+				if len(alerts) > dashboardAlerts {
+					alerts = alerts[1:]
+				}
+			}
+		})
+		for i := len(alerts) - 1; i >= 0; i-- {
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			dout.Write(alerts[i])
+		}
+	})
+}
+
+// todayBuckets returns the first n daily buckets of a counter, with empty
+// buckets as 0 like COUNTER_PAST_DATE.
+func todayBuckets(counterRD *counter.CounterRD, date string, objHash int32, counterName string, n int) *value.FloatArray {
+	values, _ := counterRD.ReadDailyAll(date, objHash, counterName)
+	floats := make([]float32, min(n, len(values)))
+	for i := range floats {
+		if !math.IsNaN(values[i]) {
+			floats[i] = float32(values[i])
+		}
+	}
+	return &value.FloatArray{Value: floats}
+}
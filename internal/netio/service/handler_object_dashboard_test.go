@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/alert"
+	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+func TestObjectDashboard(t *testing.T) {
+	baseDir := t.TempDir()
+	now := time.Now().UnixMilli()
+	date := util.FormatDate(now)
+	bucket := util.GetDateMillis(now) / util.MillisPerFiveMinute
+
+	counterWR := counter.NewCounterWR(baseDir)
+	alertWR := alert.NewAlertWR(baseDir)
+	ctx, cancel := context.WithCancel(context.Background())
+	counterWR.Start(ctx)
+	alertWR.Start(ctx)
+	counterWR.AddDaily(&counter.DailyEntry{Date: date, ObjHash: 11, CounterName: "TPS", Bucket: bucket, Value: 42})
+	counterWR.AddDaily(&counter.DailyEntry{Date: date, ObjHash: 11, CounterName: "ErrorRate", Bucket: bucket, Value: 1.5})
+	start := util.DateToMillis(date)
+	for i := int64(0); i < 12; i++ {
+		for _, objHash := range []int32{11, 22} {
+			out := protocol.NewDataOutputX()
+			pack.WritePack(out, &pack.AlertPack{Time: start + i, ObjHash: objHash, Title: "T"})
+			alertWR.Add(&alert.AlertEntry{TimeMs: start + i, Data: out.ToByteArray()})
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	counterWR.Close()
+	alertWR.Close()
+
+	counterRD := counter.NewCounterRD(baseDir)
+	defer counterRD.Close()
+	alertRD := alert.NewAlertRD(baseDir)
+	defer alertRD.Close()
+
+	objectCache := cache.NewObjectCache()
+	objectCache.Put(11, &pack.ObjectPack{ObjHash: 11, ObjName: "/host/app", ObjType: "java", Alive: true})
+	counterCache := cache.NewCounterCache()
+	counterCache.Put(cache.CounterKey{ObjHash: 11, Counter: "TPS", TimeType: cache.TimeTypeRealtime}, &value.FloatValue{Value: 40})
+	speed := value.NewListValue()
+	speed.Value = append(speed.Value, value.NewDecimalValue(3), value.NewDecimalValue(2), value.NewDecimalValue(1))
+	counterCache.Put(cache.CounterKey{ObjHash: 11, Counter: counterActiveSpeed, TimeType: cache.TimeTypeRealtime}, speed)
+
+	registry := NewRegistry()
+	RegisterObjectDashboardHandlers(registry, objectCache, counterCache, counterRD, alertRD)
+	param := &pack.MapPack{}
+	param.PutLong("objHash", 11)
+	dout := protocol.NewDataOutputX()
+	registry.Get(protocol.OBJECT_DASHBOARD)(buildRequest(param), dout, true)
+
+	din := protocol.NewDataInputX(dout.ToByteArray())
+	var packs []pack.Pack
+	for {
+		if flag, err := din.ReadByte(); err != nil || flag != protocol.FLAG_HAS_NEXT {
+			break
+		}
+		p, err := pack.ReadPack(din)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packs = append(packs, p)
+	}
+	if len(packs) != 1+dashboardAlerts {
+		t.Fatalf("got %d packs, want %d", len(packs), 1+dashboardAlerts)
+	}
+
+	m := packs[0].(*pack.MapPack)
+	if m.GetText("objName") != "/host/app" || !m.GetBoolean("alive") || m.GetLong("act1") != 3 || m.GetLong("act3") != 1 {
+		t.Errorf("summary = %v", m)
+	}
+	if cv, ok := m.Get("counter").(*value.MapValue).Get("TPS"); !ok || cv.(*value.FloatValue).Value != 40 {
+		t.Errorf("counter TPS = %v", cv)
+	}
+	tps := m.Get("tps").(*value.FloatArray).Value
+	if len(tps) != bucket+1 || tps[bucket] != 42 {
+		t.Errorf("tps buckets = %v", tps)
+	}
+	if errs := m.Get("error").(*value.FloatArray).Value; errs[bucket] != 1.5 {
+		t.Errorf("error bucket = %v", errs[bucket])
+	}
+	for i, p := range packs[1:] {
+		ap := p.(*pack.AlertPack)
+		if ap.ObjHash != 11 || ap.Time != start+11-int64(i) {
+			t.Errorf("alert %d = objHash %d time %d", i, ap.ObjHash, ap.Time-start)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
@@ -11,6 +12,16 @@ import (
 
 // RegisterObjectExtHandlers registers extended object service handlers (P2).
 func RegisterObjectExtHandlers(r *Registry, objectCache *cache.ObjectCache, deadTimeout time.Duration) {
+	r.Require(login.PermRead,
+		protocol.OBJECT_TODAY_FULL_LIST,
+		protocol.OBJECT_INFO,
+		protocol.OBJECT_LIST_LOAD_DATE,
+	)
+	r.Require(login.PermWrite,
+		protocol.OBJECT_REMOVE,
+		protocol.OBJECT_REMOVE_INACTIVE,
+		protocol.OBJECT_REMOVE_IN_MEMORY,
+	)
 
 	// OBJECT_TODAY_FULL_LIST: return all objects seen today (including dead ones).
 	r.Register(protocol.OBJECT_TODAY_FULL_LIST, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"log/slog"
+	"math"
+
+	"github.com/zbum/scouter-server-go/internal/db/objevent"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// RegisterObjectEventHandlers registers the object lifecycle event handlers.
+func RegisterObjectEventHandlers(r *Registry, events *objevent.Store) {
+	r.Require(login.PermRead,
+		protocol.OBJECT_EVENT_REAL_TIME,
+		protocol.OBJECT_EVENT_LOAD,
+	)
+
+	// OBJECT_EVENT_REAL_TIME: events after the client's position.
+	// Param: "seq" (last sequence seen; 0 on the first call returns only the
+	// position).
+	// Response: a MapPack with the current "seq", then one MapPack per event
+	// with "seq", "time", "type" (registered, dead, recovered, renamed),
+	// "objHash", "objName", "objType", "address" and "oldName".
+	r.Register(protocol.OBJECT_EVENT_REAL_TIME, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		seq := param.GetLong("seq")
+
+		last := events.Last()
+		resp := &pack.MapPack{}
+		resp.PutLong("seq", last)
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+
+		// A position from before a restart or the first call only syncs.
+		if seq <= 0 || seq > last {
+			return
+		}
+		for _, e := range events.Since(seq) {
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, eventPack(e))
+		}
+	})
+
+	// OBJECT_EVENT_LOAD: stored events of one day.
+	// Param: "date" (YYYYMMDD), "stime", "etime".
+	// Response: one MapPack per event as in OBJECT_EVENT_REAL_TIME.
+	r.Register(protocol.OBJECT_EVENT_LOAD, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		date := param.GetText("date")
+		etime := param.GetLong("etime")
+		if etime == 0 {
+			etime = math.MaxInt64
+		}
+
+		err = events.Read(date, param.GetLong("stime"), etime, func(e objevent.Event) {
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, eventPack(e))
+		})
+		if err != nil {
+			slog.Warn("OBJECT_EVENT_LOAD: read failed", "date", date, "error", err)
+		}
+	})
+}
+
+func eventPack(e objevent.Event) *pack.MapPack {
+	m := &pack.MapPack{}
+	m.PutLong("seq", e.Seq)
+	m.PutLong("time", e.Time)
+	m.PutStr("type", e.Type)
+	m.PutLong("objHash", int64(e.ObjHash))
+	m.PutStr("objName", e.ObjName)
+	m.PutStr("objType", e.ObjType)
+	m.PutStr("address", e.Address)
+	m.PutStr("oldName", e.OldName)
+	return m
+}
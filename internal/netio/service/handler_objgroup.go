@@ -0,0 +1,368 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/objgroup"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// ObjectGroupSuffix is appended to a command that takes an "objHash" list to
+// form its group-aware variant, e.g. COUNTER_REAL_TIME_GROUP_BY_OBJECT_GROUP.
+// The variant takes an "objGroup" name instead of the list.
+const ObjectGroupSuffix = "_BY_OBJECT_GROUP"
+
+// objectGroupAwareCommands are the counter and xlog commands that get a
+// group-aware variant.
+var objectGroupAwareCommands = []string{
+	protocol.COUNTER_REAL_TIME_GROUP,
+	protocol.COUNTER_TODAY_GROUP,
+	protocol.COUNTER_PAST_TIME_GROUP,
+	protocol.COUNTER_PAST_DATE_GROUP,
+	protocol.COUNTER_PAST_LONGDATE_GROUP,
+	protocol.ACTIVESPEED_GROUP_REAL_TIME_GROUP,
+	protocol.TRANX_REAL_TIME_GROUP,
+	protocol.TRANX_REAL_TIME_GROUP_LATEST,
+	protocol.TRANX_LOAD_TIME_GROUP,
+	protocol.TRANX_LOAD_TIME_GROUP_V2,
+	protocol.REALTIME_SERVICE_GROUP,
+}
+
+// RegisterObjectGroupHandlers registers the object group management handlers,
+// the group-aware variants of objectGroupAwareCommands and, when the caches
+// are given, the group aggregate handlers. It must be called after the
+// handlers it wraps are registered.
+func RegisterObjectGroupHandlers(r *Registry, groups *objgroup.Manager, objectCache *cache.ObjectCache, counterCache *cache.CounterCache, xlogCache *cache.XLogCache) {
+	r.Require(login.PermRead,
+		protocol.OBJECT_GROUP_LIST,
+		protocol.OBJECT_GROUP_RESOLVE,
+		protocol.OBJECT_GROUP_COUNTER_REAL_TIME,
+		protocol.OBJECT_GROUP_XLOG_REAL_TIME,
+	)
+	r.Require(login.PermWrite,
+		protocol.OBJECT_GROUP_SET,
+		protocol.OBJECT_GROUP_DELETE,
+	)
+
+	// OBJECT_GROUP_LIST: all groups with their rules and current members,
+	// followed by the groups derived by object_auto_group.
+	// Response: one MapPack per group with "name", "auto", "objType",
+	// "objects", "patterns" and "objHash".
+	r.Register(protocol.OBJECT_GROUP_LIST, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pack.ReadPack(din)
+
+		objects := objectCache.GetAll()
+		for _, g := range groups.List() {
+			resp := &pack.MapPack{}
+			resp.PutStr("name", g.Name)
+			resp.Put("auto", &value.BooleanValue{Value: false})
+			resp.PutStr("objType", g.ObjType)
+			resp.Put("objects", textList(g.Objects))
+			resp.Put("patterns", textList(g.Patterns))
+			hashes, _ := groups.Resolve(g.Name, objects)
+			resp.Put("objHash", hashList(hashes))
+
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, resp)
+		}
+		for _, g := range groups.AutoGroups(objects) {
+			resp := &pack.MapPack{}
+			resp.PutStr("name", g.Name)
+			resp.Put("auto", &value.BooleanValue{Value: true})
+			resp.Put("objHash", hashList(g.ObjHash))
+
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, resp)
+		}
+	})
+
+	// OBJECT_GROUP_SET: create or replace a group.
+	// Param: "name", "objects" (objNames or objHashes), "patterns", "objType".
+	// Response: "result" ("ok" or "error: ...").
+	r.Register(protocol.OBJECT_GROUP_SET, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		g := objgroup.Group{
+			Name:     param.GetText("name"),
+			Objects:  listTexts(param.GetList("objects")),
+			Patterns: listTexts(param.GetList("patterns")),
+			ObjType:  param.GetText("objType"),
+		}
+		resp := &pack.MapPack{}
+		if err := groups.Put(g); err != nil {
+			resp.PutStr("result", "error: "+err.Error())
+		} else {
+			resp.PutStr("result", "ok")
+		}
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+
+	// OBJECT_GROUP_DELETE: remove a group.
+	// Param: "name". Response: "result" ("ok" or "error: ...").
+	r.Register(protocol.OBJECT_GROUP_DELETE, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		resp := &pack.MapPack{}
+		if found, err := groups.Delete(param.GetText("name")); err != nil {
+			resp.PutStr("result", "error: "+err.Error())
+		} else if !found {
+			resp.PutStr("result", "error: no such group")
+		} else {
+			resp.PutStr("result", "ok")
+		}
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+
+	// OBJECT_GROUP_RESOLVE: the objHashes currently in a group.
+	// Param: "objGroup". Response: "objHash" list; nothing if the group is unknown.
+	r.Register(protocol.OBJECT_GROUP_RESOLVE, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		hashes, ok := groups.Resolve(param.GetText("objGroup"), objectCache.GetAll())
+		if !ok {
+			return
+		}
+		resp := &pack.MapPack{}
+		resp.Put("objHash", hashList(hashes))
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+
+	if counterCache != nil {
+		// OBJECT_GROUP_COUNTER_REAL_TIME: a realtime counter aggregated over
+		// the members of a group, or of every group if "objGroup" is empty.
+		// Param: "objGroup", "counter".
+		// Response: one MapPack per group with reporting members with
+		// "objGroup", "counter", "objHash", "value", "sum", "avg", "min", "max".
+		r.Register(protocol.OBJECT_GROUP_COUNTER_REAL_TIME, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+			pk, err := pack.ReadPack(din)
+			if err != nil {
+				return
+			}
+			param := pk.(*pack.MapPack)
+			counter := param.GetText("counter")
+			if counter == "" {
+				return
+			}
+
+			for _, g := range groupMembers(groups, objectCache, param.GetText("objGroup")) {
+				instLv := value.NewListValue()
+				valueLv := value.NewListValue()
+				var sum, lo, hi float64
+				for _, objHash := range g.ObjHash {
+					key := cache.CounterKey{ObjHash: objHash, Counter: counter, TimeType: cache.TimeTypeRealtime}
+					v, ok := counterCache.Get(key)
+					if !ok || v == nil {
+						continue
+					}
+					f := toFloat64(v)
+					if len(valueLv.Value) == 0 || f < lo {
+						lo = f
+					}
+					if len(valueLv.Value) == 0 || f > hi {
+						hi = f
+					}
+					sum += f
+					instLv.Value = append(instLv.Value, value.NewDecimalValue(int64(objHash)))
+					valueLv.Value = append(valueLv.Value, v)
+				}
+				if len(valueLv.Value) == 0 {
+					continue
+				}
+
+				resp := &pack.MapPack{}
+				resp.PutStr("objGroup", g.Name)
+				resp.PutStr("counter", counter)
+				resp.Put("objHash", instLv)
+				resp.Put("value", valueLv)
+				resp.Put("sum", &value.DoubleValue{Value: sum})
+				resp.Put("avg", &value.DoubleValue{Value: sum / float64(len(valueLv.Value))})
+				resp.Put("min", &value.DoubleValue{Value: lo})
+				resp.Put("max", &value.DoubleValue{Value: hi})
+
+				dout.WriteByte(protocol.FLAG_HAS_NEXT)
+				pack.WritePack(dout, resp)
+			}
+		})
+	}
+
+	if xlogCache != nil {
+		// OBJECT_GROUP_XLOG_REAL_TIME: the recent XLogs of a group's members
+		// summarized as one service, or of every group if "objGroup" is empty.
+		// Param: "objGroup", "count" (recent XLogs scanned, default 10000).
+		// Response: one MapPack per group with XLogs with "objGroup", "objHash"
+		// (members seen), "count", "error", "elapsed" (avg ms), "maxElapsed".
+		r.Register(protocol.OBJECT_GROUP_XLOG_REAL_TIME, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+			pk, err := pack.ReadPack(din)
+			if err != nil {
+				return
+			}
+			param := pk.(*pack.MapPack)
+			count := int(param.GetInt("count"))
+			if count <= 0 {
+				count = 10000
+			}
+
+			members := groupMembers(groups, objectCache, param.GetText("objGroup"))
+			if len(members) == 0 {
+				return
+			}
+			type stat struct {
+				seen         map[int32]bool
+				count, error int64
+				elapsed, max int64
+			}
+			stats := make([]stat, len(members))
+			index := make(map[int32][]int)
+			for i, g := range members {
+				stats[i].seen = make(map[int32]bool)
+				for _, h := range g.ObjHash {
+					index[h] = append(index[h], i)
+				}
+			}
+			for _, e := range xlogCache.GetRecent(count) {
+				for _, i := range index[e.ObjHash] {
+					st := &stats[i]
+					st.seen[e.ObjHash] = true
+					st.count++
+					if e.IsError {
+						st.error++
+					}
+					st.elapsed += int64(e.Elapsed)
+					st.max = max(st.max, int64(e.Elapsed))
+				}
+			}
+
+			for i, g := range members {
+				st := stats[i]
+				if st.count == 0 {
+					continue
+				}
+				var seen []int32
+				for _, h := range g.ObjHash {
+					if st.seen[h] {
+						seen = append(seen, h)
+					}
+				}
+
+				resp := &pack.MapPack{}
+				resp.PutStr("objGroup", g.Name)
+				resp.Put("objHash", hashList(seen))
+				resp.PutLong("count", st.count)
+				resp.PutLong("error", st.error)
+				resp.Put("elapsed", &value.FloatValue{Value: float32(st.elapsed) / float32(st.count)})
+				resp.PutLong("maxElapsed", st.max)
+
+				dout.WriteByte(protocol.FLAG_HAS_NEXT)
+				pack.WritePack(dout, resp)
+			}
+		})
+	}
+
+	// Group-aware variants: replace "objGroup" with the group's "objHash"
+	// list and delegate to the original handler.
+	for _, cmd := range objectGroupAwareCommands {
+		base := r.Get(cmd)
+		if base == nil {
+			continue
+		}
+		r.Require(r.Permission(cmd), cmd+ObjectGroupSuffix)
+		r.Register(cmd+ObjectGroupSuffix, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+			pk, err := pack.ReadPack(din)
+			if err != nil {
+				return
+			}
+			param, ok := pk.(*pack.MapPack)
+			if !ok {
+				return
+			}
+			hashes, ok := groups.Resolve(param.GetText("objGroup"), objectCache.GetAll())
+			// An empty list means "all objects" to several handlers; an
+			// unknown or empty group must not widen the query.
+			if !ok || len(hashes) == 0 {
+				return
+			}
+			param.Put("objHash", hashList(hashes))
+
+			o := protocol.NewDataOutputX()
+			pack.WritePack(o, param)
+			base(protocol.NewDataInputX(o.ToByteArray()), dout, login)
+		})
+	}
+}
+
+// groupMembers returns the members of the named group, or of every explicit
+// and derived group if name is empty.
+func groupMembers(groups *objgroup.Manager, objectCache *cache.ObjectCache, name string) []objgroup.AutoGroup {
+	objects := objectCache.GetAll()
+	if name != "" {
+		hashes, ok := groups.Resolve(name, objects)
+		if !ok {
+			return nil
+		}
+		return []objgroup.AutoGroup{{Name: name, ObjHash: hashes}}
+	}
+	var result []objgroup.AutoGroup
+	for _, g := range groups.List() {
+		hashes, _ := groups.Resolve(g.Name, objects)
+		result = append(result, objgroup.AutoGroup{Name: g.Name, ObjHash: hashes})
+	}
+	return append(result, groups.AutoGroups(objects)...)
+}
+
+func textList(ss []string) *value.ListValue {
+	lv := value.NewListValue()
+	for _, s := range ss {
+		lv.Value = append(lv.Value, value.NewTextValue(s))
+	}
+	return lv
+}
+
+func hashList(hashes []int32) *value.ListValue {
+	lv := value.NewListValue()
+	for _, h := range hashes {
+		lv.Value = append(lv.Value, value.NewDecimalValue(int64(h)))
+	}
+	return lv
+}
+
+// listTexts returns the text form of each list element; objHashes sent as
+// numbers become their decimal string.
+func listTexts(lv *value.ListValue) []string {
+	if lv == nil {
+		return nil
+	}
+	var ss []string
+	for _, v := range lv.Value {
+		switch tv := v.(type) {
+		case *value.TextValue:
+			if tv.Value != "" {
+				ss = append(ss, tv.Value)
+			}
+		case *value.DecimalValue:
+			ss = append(ss, strconv.FormatInt(tv.Value, 10))
+		}
+	}
+	return ss
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/kv"
+	"github.com/zbum/scouter-server-go/internal/objgroup"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+func TestObjectGroupHandlers(t *testing.T) {
+	objectCache := cache.NewObjectCache()
+	for _, name := range []string{"/checkout-1/tomcat", "/checkout-2/tomcat", "/cart-1/tomcat"} {
+		h := util.HashString(name)
+		objectCache.Put(h, &pack.ObjectPack{ObjHash: h, ObjName: name, ObjType: "tomcat"})
+	}
+	groups := objgroup.NewManager(kv.NewKVStore(t.TempDir(), "global.json"))
+
+	registry := NewRegistry()
+	var gotParam *pack.MapPack
+	registry.Register(protocol.COUNTER_REAL_TIME_GROUP, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, _ := pack.ReadPack(din)
+		gotParam = pk.(*pack.MapPack)
+	})
+	RegisterObjectGroupHandlers(registry, groups, objectCache, nil, nil)
+
+	call := func(cmd string, param *pack.MapPack) *protocol.DataInputX {
+		t.Helper()
+		handler := registry.Get(cmd)
+		if handler == nil {
+			t.Fatalf("%s not registered", cmd)
+		}
+		in := protocol.NewDataOutputX()
+		pack.WritePack(in, param)
+		out := protocol.NewDataOutputX()
+		handler(protocol.NewDataInputX(in.ToByteArray()), out, true)
+		return protocol.NewDataInputX(out.ToByteArray())
+	}
+
+	set := &pack.MapPack{}
+	set.PutStr("name", "checkout")
+	patterns := value.NewListValue()
+	patterns.Value = append(patterns.Value, value.NewTextValue("/checkout-*/*"))
+	set.Put("patterns", patterns)
+	resp := call(protocol.OBJECT_GROUP_SET, set)
+	resp.ReadByte()
+	pk, _ := pack.ReadPack(resp)
+	if r := pk.(*pack.MapPack).GetText("result"); r != "ok" {
+		t.Fatalf("OBJECT_GROUP_SET result = %q", r)
+	}
+
+	req := &pack.MapPack{}
+	req.PutStr("objGroup", "checkout")
+	req.PutStr("counter", "TPS")
+	call(protocol.COUNTER_REAL_TIME_GROUP+ObjectGroupSuffix, req)
+	if gotParam == nil {
+		t.Fatal("base handler not called")
+	}
+	if gotParam.GetText("counter") != "TPS" {
+		t.Errorf("counter param lost: %q", gotParam.GetText("counter"))
+	}
+	if lv := gotParam.GetList("objHash"); lv == nil || len(lv.Value) != 2 {
+		t.Fatalf("objHash = %v, want 2 members", lv)
+	}
+
+	gotParam = nil
+	req.PutStr("objGroup", "missing")
+	call(protocol.COUNTER_REAL_TIME_GROUP+ObjectGroupSuffix, req)
+	if gotParam != nil {
+		t.Error("base handler called for unknown group")
+	}
+	if registry.Get(protocol.TRANX_REAL_TIME_GROUP+ObjectGroupSuffix) != nil {
+		t.Error("variant registered for a command without a handler")
+	}
+
+	del := &pack.MapPack{}
+	del.PutStr("name", "checkout")
+	resp = call(protocol.OBJECT_GROUP_DELETE, del)
+	resp.ReadByte()
+	pk, _ = pack.ReadPack(resp)
+	if r := pk.(*pack.MapPack).GetText("result"); r != "ok" {
+		t.Fatalf("OBJECT_GROUP_DELETE result = %q", r)
+	}
+}
+
+func TestObjectGroupAggregateHandlers(t *testing.T) {
+	objectCache := cache.NewObjectCache()
+	counterCache := cache.NewCounterCache()
+	xlogCache := cache.NewXLogCache(100)
+	for i, name := range []string{"/checkout-1/tomcat", "/checkout-2/tomcat", "/cart-1/tomcat"} {
+		h := util.HashString(name)
+		objectCache.Put(h, &pack.ObjectPack{ObjHash: h, ObjName: name, ObjType: "tomcat"})
+		counterCache.Put(cache.CounterKey{ObjHash: h, Counter: "TPS", TimeType: cache.TimeTypeRealtime}, value.NewDecimalValue(int64(10*(i+1))))
+		xlogCache.Put(h, int32(100*(i+1)), i == 1, []byte{1})
+	}
+	groups := objgroup.NewManager(kv.NewKVStore(t.TempDir(), "global.json"))
+	if err := groups.Put(objgroup.Group{Name: "checkout", Patterns: []string{"/checkout-*/*"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := NewRegistry()
+	RegisterObjectGroupHandlers(registry, groups, objectCache, counterCache, xlogCache)
+
+	call := func(cmd string, param *pack.MapPack) *pack.MapPack {
+		t.Helper()
+		in := protocol.NewDataOutputX()
+		pack.WritePack(in, param)
+		out := protocol.NewDataOutputX()
+		registry.Get(cmd)(protocol.NewDataInputX(in.ToByteArray()), out, true)
+		resp := protocol.NewDataInputX(out.ToByteArray())
+		if flag, _ := resp.ReadByte(); flag != protocol.FLAG_HAS_NEXT {
+			t.Fatalf("%s: no response", cmd)
+		}
+		pk, _ := pack.ReadPack(resp)
+		return pk.(*pack.MapPack)
+	}
+
+	req := &pack.MapPack{}
+	req.PutStr("objGroup", "checkout")
+	req.PutStr("counter", "TPS")
+	resp := call(protocol.OBJECT_GROUP_COUNTER_REAL_TIME, req)
+	if v := resp.Get("sum").(*value.DoubleValue).Value; v != 30 {
+		t.Errorf("sum = %v, want 30", v)
+	}
+	if v := resp.Get("avg").(*value.DoubleValue).Value; v != 15 {
+		t.Errorf("avg = %v, want 15", v)
+	}
+	if v := resp.Get("max").(*value.DoubleValue).Value; v != 20 {
+		t.Errorf("max = %v, want 20", v)
+	}
+
+	req = &pack.MapPack{}
+	req.PutStr("objGroup", "checkout")
+	resp = call(protocol.OBJECT_GROUP_XLOG_REAL_TIME, req)
+	if resp.GetLong("count") != 2 || resp.GetLong("error") != 1 || resp.GetLong("maxElapsed") != 200 {
+		t.Errorf("count/error/maxElapsed = %d/%d/%d, want 2/1/200",
+			resp.GetLong("count"), resp.GetLong("error"), resp.GetLong("maxElapsed"))
+	}
+	if lv := resp.GetList("objHash"); lv == nil || len(lv.Value) != 2 {
+		t.Errorf("objHash = %v, want 2 members", lv)
+	}
+}
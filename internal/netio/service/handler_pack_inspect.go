@@ -0,0 +1,53 @@
+package service
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/zbum/scouter-server-go/internal/core"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// RegisterPackInspectHandlers registers the admin handler that shows the
+// packs last received from an object.
+func RegisterPackInspectHandlers(r *Registry, inspector *core.PackInspector, sessions *login.SessionManager) {
+	r.Require(login.PermAdmin,
+		protocol.PACK_INSPECT,
+	)
+
+	// PACK_INSPECT: the last packs received from "objHash", newest first;
+	// admin group only. Param: optional "type" (xlog, counter, ... as in
+	// mirror_pack_types) and "max".
+	// Response: one MapPack per pack with "time", "addr", "type", "packType",
+	// "size", "hex" (wire bytes, at most 4096), "truncated" and "fields"
+	// (the decoded pack as JSON).
+	r.RegisterSession(protocol.PACK_INSPECT, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		if !isAdmin(sessions, session) {
+			return
+		}
+
+		for _, ip := range inspector.Recent(param.GetInt("objHash"), param.GetText("type"), int(param.GetLong("max"))) {
+			resp := &pack.MapPack{}
+			resp.PutLong("time", ip.Time)
+			resp.PutStr("addr", ip.Addr)
+			resp.PutStr("type", ip.Type)
+			resp.PutLong("packType", int64(ip.Pack.PackType()))
+			resp.PutLong("size", int64(ip.Size))
+			resp.PutStr("hex", hex.EncodeToString(ip.Raw))
+			resp.Put("truncated", &value.BooleanValue{Value: len(ip.Raw) < ip.Size})
+			if fields, err := json.Marshal(ip.Pack); err == nil {
+				resp.PutStr("fields", string(fields))
+			}
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, resp)
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+func TestPackInspect(t *testing.T) {
+	cfg, _ := config.Load(filepath.Join(t.TempDir(), "missing.conf"))
+	inspector := core.NewPackInspector()
+	xp := &pack.XLogPack{ObjHash: 7, Txid: 42}
+	o := protocol.NewDataOutputX()
+	pack.WritePack(o, xp)
+	inspector.Record(cfg, xp, o.ToByteArray(), nil)
+
+	sessions := login.NewSessionManager(nil)
+	admin := sessions.Login("admin", "", "127.0.0.1")
+	sessions.GetUser(admin).Group = "admin"
+	guest := sessions.Login("guest", "", "127.0.0.1")
+	registry := NewRegistry()
+	RegisterPackInspectHandlers(registry, inspector, sessions)
+
+	call := func(session int64) []*pack.MapPack {
+		req := &pack.MapPack{}
+		req.PutLong("objHash", 7)
+		out := protocol.NewDataOutputX()
+		registry.GetSession(protocol.PACK_INSPECT)(session, buildRequest(req), out, true)
+		return readMapPacks(t, out)
+	}
+	if r := call(guest); len(r) != 0 {
+		t.Errorf("PACK_INSPECT allowed for guest: %v", r)
+	}
+	r := call(admin)
+	if len(r) != 1 || r[0].GetText("type") != "xlog" || r[0].GetLong("size") != int64(len(o.ToByteArray())) {
+		t.Fatalf("PACK_INSPECT = %v", r)
+	}
+	if !strings.Contains(r[0].GetText("fields"), `"Txid":42`) || r[0].GetBoolean("truncated") {
+		t.Errorf("fields = %s", r[0].GetText("fields"))
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/profstat"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// RegisterProfileStatHandlers registers the per-service step time breakdown
+// handlers.
+func RegisterProfileStatHandlers(r *Registry, profStat *profstat.Core) {
+	r.Require(login.PermRead,
+		protocol.PROFILE_STEP_STAT_SERVICE,
+		protocol.PROFILE_STEP_STAT_TOP,
+	)
+
+	// PROFILE_STEP_STAT_SERVICE: where the time of one service went on a
+	// day, in 5-minute buckets.
+	// Param: "date" (default today), "service" (service hash).
+	// Response: one MapPack with "date", "service" and the parallel lists
+	// "time" (bucket start, epoch ms), "profiles", "sql", "apicall",
+	// "method" and "wait" (ms), for the buckets with activity.
+	r.Register(protocol.PROFILE_STEP_STAT_SERVICE, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		date := param.GetText("date")
+		if date == "" {
+			date = time.Now().Format("20060102")
+		}
+		service := int32(param.GetInt("service"))
+		points, err := profStat.Series(date, service)
+		if err != nil {
+			slog.Warn("PROFILE_STEP_STAT_SERVICE: read failed", "date", date, "error", err)
+			return
+		}
+		stats := make([]profstat.Stat, len(points))
+		times := value.NewListValue()
+		for i, p := range points {
+			stats[i] = p.Stat
+			times.Value = append(times.Value, value.NewDecimalValue(p.Time))
+		}
+		m := profStatPack(date, stats)
+		m.PutLong("service", int64(service))
+		m.Put("time", times)
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, m)
+	})
+
+	// PROFILE_STEP_STAT_TOP: the services of a day by total step time, with
+	// their breakdown.
+	// Param: "date" (default today), optional "stime" and "etime" (epoch ms
+	// within the day) and "max" (all services if absent).
+	// Response: one MapPack with "date" and the parallel lists "service",
+	// "profiles", "sql", "apicall", "method" and "wait" (ms).
+	r.Register(protocol.PROFILE_STEP_STAT_TOP, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		date := param.GetText("date")
+		if date == "" {
+			date = time.Now().Format("20060102")
+		}
+		stats, err := profStat.Services(date, param.GetLong("stime"), param.GetLong("etime"), int(param.GetInt("max")))
+		if err != nil {
+			slog.Warn("PROFILE_STEP_STAT_TOP: read failed", "date", date, "error", err)
+			return
+		}
+		services := value.NewListValue()
+		for _, st := range stats {
+			services.Value = append(services.Value, value.NewDecimalValue(int64(st.Service)))
+		}
+		m := profStatPack(date, stats)
+		m.Put("service", services)
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, m)
+	})
+}
+
+func profStatPack(date string, stats []profstat.Stat) *pack.MapPack {
+	profiles, sqls, apicalls := value.NewListValue(), value.NewListValue(), value.NewListValue()
+	methods, waits := value.NewListValue(), value.NewListValue()
+	for _, st := range stats {
+		profiles.Value = append(profiles.Value, value.NewDecimalValue(st.Profiles))
+		sqls.Value = append(sqls.Value, value.NewDecimalValue(st.SQL))
+		apicalls.Value = append(apicalls.Value, value.NewDecimalValue(st.APICall))
+		methods.Value = append(methods.Value, value.NewDecimalValue(st.Method))
+		waits.Value = append(waits.Value, value.NewDecimalValue(st.Wait))
+	}
+	m := &pack.MapPack{}
+	m.PutStr("date", date)
+	m.Put("profiles", profiles)
+	m.Put("sql", sqls)
+	m.Put("apicall", apicalls)
+	m.Put("method", methods)
+	m.Put("wait", waits)
+	return m
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/zbum/scouter-server-go/internal/db"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// RegisterPurgeHandlers registers the manual day-data purge and trash handlers.
+func RegisterPurgeHandlers(r *Registry, purger *db.ManualPurger) {
+	r.Require(login.PermRead,
+		protocol.SERVER_DB_TRASH_LIST,
+	)
+	r.Require(login.PermAdmin,
+		protocol.SERVER_DB_PURGE,
+		protocol.SERVER_DB_TRASH_RESTORE,
+	)
+
+	// SERVER_DB_PURGE: Delete selected data types for a date range.
+	// Param: "date" ("YYYYMMDD", "YYYYMMDD..YYYYMMDD" or comma list), "types" ("xlog,profile", "all", ...).
+	// Response: "result" ("ok" or "error: ..."), and parallel lists "date", "type", "deleted", "skipped",
+	// plus "trashId" if the data was moved to the trash.
+	r.Register(protocol.SERVER_DB_PURGE, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		defer r.Invalidate(protocol.GET_COUNTER_EXIST_DAYS)
+
+		resp := &pack.MapPack{}
+		results, err := purger.PurgeSpec(param.GetText("date"), param.GetText("types"))
+		if err != nil {
+			resp.PutStr("result", "error: "+err.Error())
+		} else {
+			resp.PutStr("result", "ok")
+			dateLv := value.NewListValue()
+			typeLv := value.NewListValue()
+			deletedLv := value.NewListValue()
+			skippedLv := value.NewListValue()
+			for _, res := range results {
+				dateLv.Value = append(dateLv.Value, value.NewTextValue(res.Date))
+				typeLv.Value = append(typeLv.Value, value.NewTextValue(res.Type))
+				deletedLv.Value = append(deletedLv.Value, &value.BooleanValue{Value: res.Deleted})
+				skippedLv.Value = append(skippedLv.Value, value.NewTextValue(res.Skipped))
+			}
+			resp.Put("date", dateLv)
+			resp.Put("type", typeLv)
+			resp.Put("deleted", deletedLv)
+			resp.Put("skipped", skippedLv)
+			for _, res := range results {
+				if res.TrashID != "" {
+					resp.PutStr("trashId", res.TrashID)
+					break
+				}
+			}
+		}
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+
+	// SERVER_DB_TRASH_LIST: List the purges that can still be undone.
+	// Response: parallel lists "id", "created", "expires" (epoch ms), "dates", "types"
+	// (comma-separated) and "bytes".
+	r.Register(protocol.SERVER_DB_TRASH_LIST, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pack.ReadPack(din)
+
+		resp := &pack.MapPack{}
+		var entries []db.TrashEntry
+		if trash := purger.Trash(); trash != nil {
+			var err error
+			if entries, err = trash.List(); err != nil {
+				resp.PutStr("result", "error: "+err.Error())
+			}
+		}
+		idLv, createdLv, expiresLv := value.NewListValue(), value.NewListValue(), value.NewListValue()
+		datesLv, typesLv, bytesLv := value.NewListValue(), value.NewListValue(), value.NewListValue()
+		for _, e := range entries {
+			idLv.Value = append(idLv.Value, value.NewTextValue(e.ID))
+			createdLv.Value = append(createdLv.Value, value.NewDecimalValue(e.Created.UnixMilli()))
+			expiresLv.Value = append(expiresLv.Value, value.NewDecimalValue(e.Expires.UnixMilli()))
+			datesLv.Value = append(datesLv.Value, value.NewTextValue(strings.Join(e.Dates, ",")))
+			typesLv.Value = append(typesLv.Value, value.NewTextValue(strings.Join(e.Types, ",")))
+			bytesLv.Value = append(bytesLv.Value, value.NewDecimalValue(e.Bytes))
+		}
+		resp.Put("id", idLv)
+		resp.Put("created", createdLv)
+		resp.Put("expires", expiresLv)
+		resp.Put("dates", datesLv)
+		resp.Put("types", typesLv)
+		resp.Put("bytes", bytesLv)
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+
+	// SERVER_DB_TRASH_RESTORE: Undo a purge by moving its data back.
+	// Param: "id" (trash entry). Response: "result" ("ok" or "error: ...").
+	r.Register(protocol.SERVER_DB_TRASH_RESTORE, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		defer r.Invalidate(protocol.GET_COUNTER_EXIST_DAYS)
+
+		resp := &pack.MapPack{}
+		if _, err := purger.Restore(param.GetText("id")); err != nil {
+			resp.PutStr("result", "error: "+err.Error())
+		} else {
+			resp.PutStr("result", "ok")
+		}
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"log/slog"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// resultGuard caps the rows and bytes one XLog, counter or summary request
+// streams back (req_result_max_rows, req_result_max_bytes), so a careless
+// multi-day query cannot exhaust the server's memory or the client's link.
+// A cut result ends with a MapPack carrying "truncated" true, the "reason"
+// ("rows" or "bytes") and the "rows" and "bytes" that were sent.
+type resultGuard struct {
+	cmd      string
+	dout     *protocol.DataOutputX
+	start    int
+	maxRows  int
+	maxBytes int
+	rows     int
+	reason   string
+}
+
+func newResultGuard(cmd string, dout *protocol.DataOutputX) *resultGuard {
+	g := &resultGuard{cmd: cmd, dout: dout, start: dout.Size()}
+	if cfg := config.Get(); cfg != nil {
+		g.maxRows = cfg.ReqResultMaxRows()
+		g.maxBytes = cfg.ReqResultMaxBytes()
+	}
+	return g
+}
+
+// next counts one more row and reports whether it may be returned; once a
+// cap is reached it stays false.
+func (g *resultGuard) next() bool {
+	return g.take(1)
+}
+
+// take is next for n rows returned together, such as the points of a
+// counter series.
+func (g *resultGuard) take(n int) bool {
+	if g.reason != "" {
+		return false
+	}
+	if g.maxRows > 0 && g.rows+n > g.maxRows {
+		g.reason = "rows"
+		return false
+	}
+	if g.maxBytes > 0 && g.bytes() >= g.maxBytes {
+		g.reason = "bytes"
+		return false
+	}
+	g.rows += n
+	return true
+}
+
+// truncated reports whether rows were left out.
+func (g *resultGuard) truncated() bool {
+	return g.reason != ""
+}
+
+func (g *resultGuard) bytes() int {
+	return g.dout.Size() - g.start
+}
+
+// finish sends the truncated pack if rows were left out.
+func (g *resultGuard) finish() {
+	if g.reason == "" {
+		return
+	}
+	slog.Warn("Query result truncated", "cmd", g.cmd, "reason", g.reason, "rows", g.rows, "bytes", g.bytes())
+	m := &pack.MapPack{}
+	m.Put("truncated", &value.BooleanValue{Value: true})
+	m.PutStr("reason", g.reason)
+	m.PutLong("rows", int64(g.rows))
+	m.PutLong("bytes", int64(g.bytes()))
+	g.dout.WriteByte(protocol.FLAG_HAS_NEXT)
+	pack.WritePack(g.dout, m)
+	g.dout.Flush()
+}
@@ -3,12 +3,17 @@ package service
 import (
 	"time"
 
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 )
 
 // RegisterServerHandlers registers SERVER_VERSION and SERVER_TIME handlers.
 func RegisterServerHandlers(r *Registry, version string) {
+	r.Require(login.PermRead,
+		protocol.SERVER_VERSION,
+		protocol.SERVER_TIME,
+	)
 	r.Register(protocol.SERVER_VERSION, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		// Read the param pack (client sends it even though it's not needed)
 		pack.ReadPack(din)
@@ -10,13 +10,28 @@ import (
 
 	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/db"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
 )
 
 // RegisterServerMgmtHandlers registers server management and monitoring handlers.
-func RegisterServerMgmtHandlers(r *Registry, version string, dataDir string) {
+// SERVER_DB_DELETE moves the date to trash while its undo window is open.
+func RegisterServerMgmtHandlers(r *Registry, version string, dataDir string, trash *db.Trash) {
+	r.Require(login.PermRead,
+		protocol.SERVER_STATUS,
+		protocol.SERVER_ENV,
+		protocol.SERVER_THREAD_LIST,
+		protocol.SERVER_DB_LIST,
+		protocol.SERVER_LOG_LIST,
+		protocol.SERVER_THREAD_DETAIL,
+		protocol.CHECK_JOB,
+		protocol.SERVER_LOG_DETAIL,
+	)
+	r.Require(login.PermAdmin,
+		protocol.SERVER_DB_DELETE,
+	)
 
 	// SERVER_STATUS: Return current server status info.
 	// The client reads "used" and "total" to display server memory in the Objects Perf column.
@@ -94,6 +109,7 @@ func RegisterServerMgmtHandlers(r *Registry, version string, dataDir string) {
 		}
 		param := pk.(*pack.MapPack)
 		date := param.GetText("date")
+		defer r.Invalidate(protocol.GET_COUNTER_EXIST_DAYS)
 
 		resp := &pack.MapPack{}
 
@@ -114,7 +130,16 @@ func RegisterServerMgmtHandlers(r *Registry, version string, dataDir string) {
 				resp.PutStr("result", "error: invalid date format")
 			} else {
 				dirPath := filepath.Join(dataDir, date)
-				err := os.RemoveAll(dirPath)
+				var err error
+				if trash != nil && trash.Window() > 0 {
+					var e db.TrashEntry
+					e, err = trash.Move([]string{date}, []string{date}, []string{db.PurgeTypeAll})
+					if e.ID != "" {
+						resp.PutStr("trashId", e.ID)
+					}
+				} else {
+					err = os.RemoveAll(dirPath)
+				}
 				if err != nil {
 					resp.PutStr("result", "error: "+err.Error())
 				} else {
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/slo"
+)
+
+// RegisterSLOHandlers registers the service-level objective handlers.
+func RegisterSLOHandlers(r *Registry, tracker *slo.Tracker) {
+	r.Require(login.PermRead,
+		protocol.SLO_LIST,
+	)
+	r.Require(login.PermWrite,
+		protocol.SLO_SET,
+		protocol.SLO_DELETE,
+	)
+
+	// SLO_LIST: all objectives with their current status.
+	// Response: one MapPack per objective with the definition ("name",
+	// "service", "objType", "latencyMs", "target", "windowDays") and the
+	// status ("total", "bad", "compliance", "budgetRemaining", "burnRate1h",
+	// "burnRate6h", "burning").
+	r.Register(protocol.SLO_LIST, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pack.ReadPack(din)
+
+		for _, st := range tracker.Statuses(time.Now()) {
+			resp := &pack.MapPack{}
+			resp.PutStr("name", st.Name)
+			resp.PutStr("service", st.Service)
+			resp.PutStr("objType", st.ObjType)
+			resp.PutLong("latencyMs", int64(st.LatencyMs))
+			resp.Put("target", &value.DoubleValue{Value: st.Target})
+			resp.PutLong("windowDays", int64(st.WindowDays))
+			resp.PutLong("total", st.Total)
+			resp.PutLong("bad", st.Bad)
+			resp.Put("compliance", &value.DoubleValue{Value: st.Compliance})
+			resp.Put("budgetRemaining", &value.DoubleValue{Value: st.BudgetRemaining})
+			resp.Put("burnRate1h", &value.DoubleValue{Value: st.BurnRate1h})
+			resp.Put("burnRate6h", &value.DoubleValue{Value: st.BurnRate6h})
+			resp.PutStr("burning", st.Burning)
+
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, resp)
+		}
+	})
+
+	// SLO_SET: create or replace an objective. Changing the service pattern,
+	// objType or latency threshold restarts its counts.
+	// Param: "name", "service", "objType", "latencyMs", "target" (percent),
+	// "windowDays" (default 30).
+	// Response: "result" ("ok" or "error: ...").
+	r.Register(protocol.SLO_SET, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		o := slo.Objective{
+			Name:       param.GetText("name"),
+			Service:    param.GetText("service"),
+			ObjType:    param.GetText("objType"),
+			LatencyMs:  param.GetInt("latencyMs"),
+			Target:     floatParam(param, "target"),
+			WindowDays: int(param.GetInt("windowDays")),
+		}
+		resp := &pack.MapPack{}
+		if err := tracker.Store().Put(o); err != nil {
+			resp.PutStr("result", "error: "+err.Error())
+		} else {
+			resp.PutStr("result", "ok")
+		}
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+
+	// SLO_DELETE: remove an objective and its counts.
+	// Param: "name". Response: "result" ("ok" or "error: ...").
+	r.Register(protocol.SLO_DELETE, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		resp := &pack.MapPack{}
+		if found, err := tracker.Store().Delete(param.GetText("name")); err != nil {
+			resp.PutStr("result", "error: "+err.Error())
+		} else if !found {
+			resp.PutStr("result", "error: no such objective")
+		} else {
+			resp.PutStr("result", "ok")
+		}
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+}
+
+// floatParam reads a number sent as a double, float, decimal or text value.
+func floatParam(param *pack.MapPack, key string) float64 {
+	switch v := param.Get(key).(type) {
+	case *value.DoubleValue:
+		return v.Value
+	case *value.FloatValue:
+		return float64(v.Value)
+	case *value.DecimalValue:
+		return float64(v.Value)
+	case *value.TextValue:
+		f, _ := strconv.ParseFloat(v.Value, 64)
+		return f
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/sqltop"
+)
+
+// RegisterSQLTopHandlers registers the hourly slow SQL ranking handler.
+func RegisterSQLTopHandlers(r *Registry, sqlTop *sqltop.Core) {
+	r.Require(login.PermRead,
+		protocol.SQL_TOP_HOURLY,
+	)
+
+	// SQL_TOP_HOURLY: the slowest SQL statements of a day, per hour.
+	// Param: "date" (default today), "hour" (0-23, -1 for the whole day;
+	// every hour if absent), "order" ("elapsed" total, "avg", "count" or
+	// "error"; default "elapsed") and "max" (default sql_top_n).
+	// Response: one MapPack per hour with statements, in hour order, with
+	// "date", "hour" and the parallel lists "sql" (text hash, type "sql"),
+	// "count", "error", "elapsed" (total ms) and "avg" (ms).
+	r.Register(protocol.SQL_TOP_HOURLY, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		date := param.GetText("date")
+		if date == "" {
+			date = time.Now().Format("20060102")
+		}
+		order := param.GetText("order")
+		max := int(param.GetInt("max"))
+
+		hours := make([]int, 24)
+		for h := range hours {
+			hours[h] = h
+		}
+		if param.Get("hour") != nil {
+			hours = []int{int(param.GetInt("hour"))}
+		}
+		for _, hour := range hours {
+			stats, err := sqlTop.Top(date, hour, order, max)
+			if err != nil {
+				slog.Warn("SQL_TOP_HOURLY: read failed", "date", date, "error", err)
+				return
+			}
+			if len(stats) == 0 {
+				continue
+			}
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, sqlTopPack(date, hour, stats))
+		}
+	})
+}
+
+func sqlTopPack(date string, hour int, stats []sqltop.Stat) *pack.MapPack {
+	sqls, counts, errs := value.NewListValue(), value.NewListValue(), value.NewListValue()
+	elapsed, avg := value.NewListValue(), value.NewListValue()
+	for _, st := range stats {
+		sqls.Value = append(sqls.Value, value.NewDecimalValue(int64(st.SQL)))
+		counts.Value = append(counts.Value, value.NewDecimalValue(st.Count))
+		errs.Value = append(errs.Value, value.NewDecimalValue(st.Errors))
+		elapsed.Value = append(elapsed.Value, value.NewDecimalValue(st.Elapsed))
+		avg.Value = append(avg.Value, &value.DoubleValue{Value: st.AvgElapsed()})
+	}
+	m := &pack.MapPack{}
+	m.PutStr("date", date)
+	m.PutLong("hour", int64(hour))
+	m.Put("sql", sqls)
+	m.Put("count", counts)
+	m.Put("error", errs)
+	m.Put("elapsed", elapsed)
+	m.Put("avg", avg)
+	return m
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"github.com/zbum/scouter-server-go/internal/db/summary"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 )
@@ -22,71 +23,92 @@ const (
 
 // RegisterSummaryHandlers registers handlers for loading historical summaries.
 func RegisterSummaryHandlers(r *Registry, summaryRD *summary.SummaryRD) {
+	r.Require(login.PermRead,
+		protocol.LOAD_SERVICE_SUMMARY,
+		protocol.LOAD_SQL_SUMMARY,
+		protocol.LOAD_APICALL_SUMMARY,
+		protocol.LOAD_IP_SUMMARY,
+		protocol.LOAD_UA_SUMMARY,
+		protocol.LOAD_SERVICE_ERROR_SUMMARY,
+		protocol.LOAD_ALERT_SUMMARY,
+		protocol.LOAD_ENDUSER_NAV_SUMMARY,
+		protocol.LOAD_ENDUSER_AJAX_SUMMARY,
+		protocol.LOAD_ENDUSER_ERROR_SUMMARY,
+	)
 
 	// LOAD_SERVICE_SUMMARY: load service (app) summary data
 	r.Register(protocol.LOAD_SERVICE_SUMMARY, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
-		loadSummaryByType(din, dout, summaryRD, SummaryTypeApp)
+		loadSummaryByType(din, dout, summaryRD, SummaryTypeApp, protocol.LOAD_SERVICE_SUMMARY)
 	})
 
 	// LOAD_SQL_SUMMARY: load SQL summary data
 	r.Register(protocol.LOAD_SQL_SUMMARY, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
-		loadSummaryByType(din, dout, summaryRD, SummaryTypeSQL)
+		loadSummaryByType(din, dout, summaryRD, SummaryTypeSQL, protocol.LOAD_SQL_SUMMARY)
 	})
 
 	// LOAD_APICALL_SUMMARY: load API call summary data
 	r.Register(protocol.LOAD_APICALL_SUMMARY, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
-		loadSummaryByType(din, dout, summaryRD, SummaryTypeAPICall)
+		loadSummaryByType(din, dout, summaryRD, SummaryTypeAPICall, protocol.LOAD_APICALL_SUMMARY)
 	})
 
 	// LOAD_IP_SUMMARY: load IP summary data
 	r.Register(protocol.LOAD_IP_SUMMARY, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
-		loadSummaryByType(din, dout, summaryRD, SummaryTypeIP)
+		loadSummaryByType(din, dout, summaryRD, SummaryTypeIP, protocol.LOAD_IP_SUMMARY)
 	})
 
 	// LOAD_UA_SUMMARY: load User-Agent summary data
 	r.Register(protocol.LOAD_UA_SUMMARY, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
-		loadSummaryByType(din, dout, summaryRD, SummaryTypeUA)
+		loadSummaryByType(din, dout, summaryRD, SummaryTypeUA, protocol.LOAD_UA_SUMMARY)
 	})
 
 	// LOAD_SERVICE_ERROR_SUMMARY: load service error summary data
 	r.Register(protocol.LOAD_SERVICE_ERROR_SUMMARY, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
-		loadSummaryByType(din, dout, summaryRD, SummaryTypeServiceError)
+		loadSummaryByType(din, dout, summaryRD, SummaryTypeServiceError, protocol.LOAD_SERVICE_ERROR_SUMMARY)
 	})
 
 	// LOAD_ALERT_SUMMARY: load alert summary data
 	r.Register(protocol.LOAD_ALERT_SUMMARY, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
-		loadSummaryByType(din, dout, summaryRD, SummaryTypeAlert)
+		loadSummaryByType(din, dout, summaryRD, SummaryTypeAlert, protocol.LOAD_ALERT_SUMMARY)
 	})
 
 	// LOAD_ENDUSER_NAV_SUMMARY: load end-user navigation timing summary
 	r.Register(protocol.LOAD_ENDUSER_NAV_SUMMARY, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
-		loadSummaryByType(din, dout, summaryRD, SummaryTypeEndUserNav)
+		loadSummaryByType(din, dout, summaryRD, SummaryTypeEndUserNav, protocol.LOAD_ENDUSER_NAV_SUMMARY)
 	})
 
 	// LOAD_ENDUSER_AJAX_SUMMARY: load end-user AJAX timing summary
 	r.Register(protocol.LOAD_ENDUSER_AJAX_SUMMARY, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
-		loadSummaryByType(din, dout, summaryRD, SummaryTypeEndUserAjax)
+		loadSummaryByType(din, dout, summaryRD, SummaryTypeEndUserAjax, protocol.LOAD_ENDUSER_AJAX_SUMMARY)
 	})
 
 	// LOAD_ENDUSER_ERROR_SUMMARY: load end-user script error summary
 	r.Register(protocol.LOAD_ENDUSER_ERROR_SUMMARY, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
-		loadSummaryByType(din, dout, summaryRD, SummaryTypeEndUserError)
+		loadSummaryByType(din, dout, summaryRD, SummaryTypeEndUserError, protocol.LOAD_ENDUSER_ERROR_SUMMARY)
 	})
 }
 
 // loadSummaryByType is a helper function that loads summary data for a specific type.
-func loadSummaryByType(din *protocol.DataInputX, dout *protocol.DataOutputX, summaryRD *summary.SummaryRD, stype byte) {
+// Without "date" the dates are derived from stime/etime. The records of cmd
+// are capped by resultGuard.
+func loadSummaryByType(din *protocol.DataInputX, dout *protocol.DataOutputX, summaryRD *summary.SummaryRD, stype byte, cmd string) {
 	pk, err := pack.ReadPack(din)
 	if err != nil {
 		return
 	}
 	param := pk.(*pack.MapPack)
-	date := param.GetText("date")
-	stime := param.GetLong("stime")
-	etime := param.GetLong("etime")
 
-	summaryRD.ReadRange(date, stype, stime, etime, func(data []byte) {
-		dout.WriteByte(protocol.FLAG_HAS_NEXT)
-		dout.Write(data)
-	})
+	guard := newResultGuard(cmd, dout)
+	for _, d := range queryDays(param) {
+		summaryRD.ReadRange(d.date, stype, d.stime, d.etime, func(data []byte) {
+			if !guard.next() {
+				return
+			}
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			dout.Write(data)
+		})
+		if guard.truncated() {
+			break
+		}
+	}
+	guard.finish()
 }
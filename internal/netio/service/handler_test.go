@@ -0,0 +1,76 @@
+package service
+
+import (
+	"sort"
+	"testing"
+)
+
+// TestRegistry_EveryCommandDeclared fails when a registered command has no
+// Require declaration, which would silently make it need write permission.
+func TestRegistry_EveryCommandDeclared(t *testing.T) {
+	r := NewRegistry()
+	RegisterLoginHandlers(r, nil, nil, "test")
+	RegisterServerHandlers(r, "test")
+	RegisterObjectHandlers(r, nil, 0, nil, nil)
+	RegisterCounterHandlers(r, nil, nil, 0, nil)
+	RegisterXLogHandlers(r, nil, nil)
+	RegisterXLogFilterHandlers(r, nil, nil, nil)
+	RegisterTextHandlers(r, nil, nil, nil)
+	RegisterXLogReadHandlers(r, nil, nil, nil, nil)
+	RegisterXLogSearchHandlers(r, nil, nil, nil, nil, nil)
+	RegisterXLogCallTreeHandlers(r, nil, nil)
+	RegisterCounterReadHandlers(r, nil, nil, 0)
+	RegisterAlertHandlers(r, nil, nil)
+	RegisterAlertXLogHandlers(r, nil, nil, nil)
+	RegisterObjectEventHandlers(r, nil)
+	RegisterAgentInventoryHandlers(r, nil)
+	RegisterNotifyHandlers(r, nil)
+	RegisterSummaryHandlers(r, nil)
+	RegisterSQLTopHandlers(r, nil)
+	RegisterProfileStatHandlers(r, nil)
+	RegisterCounterExtHandlers(r, nil, nil, 0, nil)
+	RegisterObjectExtHandlers(r, nil, 0)
+	RegisterObjectCleanupHandlers(r, nil, nil)
+	RegisterObjectDashboardHandlers(r, nil, nil, nil, nil)
+	RegisterConfigureHandlers(r, "test", nil, nil)
+	RegisterServerMgmtHandlers(r, "test", t.TempDir(), nil)
+	RegisterWriteStatsHandlers(r, t.TempDir())
+	RegisterKVHandlers(r, nil, nil)
+	RegisterKVNamespaceHandlers(r, nil)
+	RegisterAccountKVHandlers(r, nil, nil)
+	RegisterTokenHandlers(r, nil, nil, nil)
+	RegisterPackInspectHandlers(r, nil, nil)
+	RegisterActiveSpeedHandlers(r, nil, nil, 0)
+	RegisterLoginExtHandlers(r, nil, nil)
+	RegisterAccountHandlers(r, nil)
+	RegisterVisitorHandlers(r, nil, nil, nil, 0)
+	RegisterAlertExtHandlers(r, nil)
+	RegisterGroupHandlers(r, nil, nil)
+	RegisterHeatmapHandlers(r, nil)
+	RegisterObjectAliasHandlers(r, nil)
+	RegisterObjectGroupHandlers(r, nil, nil, nil, nil)
+	RegisterSLOHandlers(r, nil)
+	RegisterAlertRuleHandlers(r, nil)
+	RegisterAgentProxyHandlers(r, nil, nil, 0)
+	RegisterConfigureExtHandlers(r, nil)
+	RegisterPurgeHandlers(r, nil)
+
+	var missing []string
+	for cmd := range r.handlers {
+		if _, ok := r.permissions[cmd]; !ok {
+			missing = append(missing, cmd)
+		}
+	}
+	for cmd := range r.sessionHandlers {
+		if _, ok := r.permissions[cmd]; !ok && r.handlers[cmd] == nil {
+			missing = append(missing, cmd)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("commands registered without Require: %v", missing)
+	}
+	if len(r.handlers) < 100 {
+		t.Errorf("only %d commands registered", len(r.handlers))
+	}
+}
@@ -2,9 +2,11 @@ package service
 
 import (
 	"log/slog"
+	"time"
 
 	"github.com/zbum/scouter-server-go/internal/core/cache"
 	"github.com/zbum/scouter-server-go/internal/db/text"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
@@ -39,6 +41,13 @@ func resolveText(textCache *cache.TextCache, textWR *text.TextWR, textRD *text.T
 // textWR is used for reading because it has an up-to-date MemHashBlock index,
 // while textRD is a fallback for data written before the server started.
 func RegisterTextHandlers(r *Registry, textCache *cache.TextCache, textRD *text.TextRD, textWR *text.TextWR) {
+	r.Require(login.PermRead,
+		protocol.GET_TEXT_100,
+		protocol.GET_TEXT_PACK,
+		protocol.GET_TEXT_ANY_TYPE,
+		protocol.GET_TEXT,
+		protocol.TEXT_DIV_USAGE,
+	)
 	// GET_TEXT_100: resolve text hashes to strings in batches of 100
 	r.Register(protocol.GET_TEXT_100, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		pk, err := pack.ReadPack(din)
@@ -208,5 +217,81 @@ func RegisterTextHandlers(r *Registry, textCache *cache.TextCache, textRD *text.
 		}
 	})
 
-	slog.Debug("TextHandlers registered", "commands", "GET_TEXT, GET_TEXT_100, GET_TEXT_PACK, GET_TEXT_ANY_TYPE")
+	// TEXT_DIV_USAGE: permanent text storage per div, largest first, to find
+	// the div responsible for text DB growth.
+	// Param: "top" (largest texts per div, default 10, max 100), "days"
+	// (growth days up to today, default 7).
+	// Response: one MapPack per div with "div", "texts", "textBytes",
+	// "dataBytes", "indexBytes", the growth lists "date", "growthTexts",
+	// "growthBytes" (oldest first) and the largest texts "largestHash",
+	// "largestLength", "largestText" (preview).
+	r.Register(protocol.TEXT_DIV_USAGE, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		if textWR == nil {
+			return
+		}
+
+		top := int(param.GetLong("top"))
+		if top <= 0 {
+			top = 10
+		}
+		top = min(top, 100)
+		days := int(param.GetLong("days"))
+		if days <= 0 {
+			days = 7
+		}
+		days = min(days, text.MaxGrowthDays)
+		dates := make([]string, days)
+		now := time.Now()
+		for i := range dates {
+			dates[i] = now.AddDate(0, 0, i-days+1).Format("20060102")
+		}
+
+		usage, err := textWR.Usage(top, dates)
+		if err != nil {
+			slog.Warn("TEXT_DIV_USAGE: scan failed", "error", err)
+			return
+		}
+		for _, u := range usage {
+			resp := &pack.MapPack{}
+			resp.PutStr("div", u.Div)
+			resp.PutLong("texts", int64(u.Texts))
+			resp.PutLong("textBytes", u.TextBytes)
+			resp.PutLong("dataBytes", u.DataBytes)
+			resp.PutLong("indexBytes", u.IndexBytes)
+
+			dateList := value.NewListValue()
+			growthTexts := value.NewListValue()
+			growthBytes := value.NewListValue()
+			for i, g := range u.Growth {
+				dateList.Value = append(dateList.Value, value.NewTextValue(dates[i]))
+				growthTexts.Value = append(growthTexts.Value, value.NewDecimalValue(int64(g.Texts)))
+				growthBytes.Value = append(growthBytes.Value, value.NewDecimalValue(g.Bytes))
+			}
+			resp.Put("date", dateList)
+			resp.Put("growthTexts", growthTexts)
+			resp.Put("growthBytes", growthBytes)
+
+			hashList := value.NewListValue()
+			lengthList := value.NewListValue()
+			textList := value.NewListValue()
+			for _, lt := range u.Largest {
+				hashList.Value = append(hashList.Value, value.NewDecimalValue(int64(lt.Hash)))
+				lengthList.Value = append(lengthList.Value, value.NewDecimalValue(int64(lt.Length)))
+				textList.Value = append(textList.Value, value.NewTextValue(lt.Preview))
+			}
+			resp.Put("largestHash", hashList)
+			resp.Put("largestLength", lengthList)
+			resp.Put("largestText", textList)
+
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, resp)
+		}
+	})
+
+	slog.Debug("TextHandlers registered", "commands", "GET_TEXT, GET_TEXT_100, GET_TEXT_PACK, GET_TEXT_ANY_TYPE, TEXT_DIV_USAGE")
 }
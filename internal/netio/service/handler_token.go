@@ -0,0 +1,143 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/apitoken"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// RegisterTokenHandlers registers the handlers managing the bearer tokens of
+// the HTTP API. Sessions manage the tokens of their own account; the admin
+// group manages those of every account.
+func RegisterTokenHandlers(r *Registry, tokens *apitoken.Store, sessions *login.SessionManager, accounts *login.AccountManager) {
+	// Tokens never carry more permission than their account; see issueToken.
+	r.Require(login.PermRead,
+		protocol.TOKEN_LIST,
+		protocol.TOKEN_ISSUE,
+		protocol.TOKEN_REVOKE,
+	)
+
+	// TOKEN_LIST: tokens of the session's account, or of every account (or
+	// the one in "account") for admins.
+	// Response: one MapPack per token ("id", "name", "account", "readOnly",
+	// "created", "expires"; ms, 0 for never).
+	r.RegisterSession(protocol.TOKEN_LIST, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		user := sessions.GetUser(session)
+		if user == nil {
+			return
+		}
+
+		account := user.ID
+		if user.Group == "admin" {
+			account = param.GetText("account")
+		}
+		for _, t := range tokens.List(account) {
+			resp := &pack.MapPack{}
+			resp.PutStr("id", t.ID)
+			resp.PutStr("name", t.Name)
+			resp.PutStr("account", t.Account)
+			resp.Put("readOnly", &value.BooleanValue{Value: t.ReadOnly})
+			resp.PutLong("created", t.Created)
+			resp.PutLong("expires", t.Expires)
+
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, resp)
+		}
+	})
+
+	// TOKEN_ISSUE: issue a token. Param: "name", "ttlDays" (default and
+	// limit net_http_api_token_max_ttl_days), "readOnly" (forced for accounts
+	// without write permission), "account" (admins only, default the session's).
+	// Response: "result" ("ok" or "error: ...") and, if ok, "id", "expires"
+	// and "token", the secret, which cannot be read again.
+	r.RegisterSession(protocol.TOKEN_ISSUE, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		resp := &pack.MapPack{}
+		secret, t, err := issueToken(tokens, sessions.GetUser(session), accounts, param)
+		if err != nil {
+			resp.PutStr("result", "error: "+err.Error())
+		} else {
+			resp.PutStr("result", "ok")
+			resp.PutStr("id", t.ID)
+			resp.PutLong("expires", t.Expires)
+			resp.PutStr("token", secret)
+		}
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+
+	// TOKEN_REVOKE: revoke a token of the session's account, or any token
+	// for admins. Param: "id". Response: "result" ("ok" or "error: ...").
+	r.RegisterSession(protocol.TOKEN_REVOKE, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		resp := &pack.MapPack{}
+		user := sessions.GetUser(session)
+		id := param.GetText("id")
+		if t, ok := tokens.Get(id); user == nil {
+			resp.PutStr("result", "error: "+errNotLoggedIn.Error())
+		} else if !ok || (user.Group != "admin" && t.Account != user.ID) {
+			resp.PutStr("result", "error: no such token")
+		} else if _, err := tokens.Revoke(id); err != nil {
+			resp.PutStr("result", "error: "+err.Error())
+		} else {
+			resp.PutStr("result", "ok")
+		}
+
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+}
+
+// issueToken issues the token TOKEN_ISSUE asks for on behalf of user.
+func issueToken(tokens *apitoken.Store, user *login.User, accounts *login.AccountManager, param *pack.MapPack) (string, apitoken.Token, error) {
+	if user == nil {
+		return "", apitoken.Token{}, errNotLoggedIn
+	}
+	account := user.ID
+	if a := param.GetText("account"); a != "" && a != user.ID {
+		if user.Group != "admin" {
+			return "", apitoken.Token{}, errNotAdmin
+		}
+		if accounts == nil || accounts.GetAccount(a) == nil {
+			return "", apitoken.Token{}, fmt.Errorf("no such account: %s", a)
+		}
+		account = a
+	}
+	var days *int
+	if v := param.Get("ttlDays"); v != nil {
+		n := int(param.GetInt("ttlDays"))
+		days = &n
+	}
+	ttl, err := apitoken.TTL(days)
+	if err != nil {
+		return "", apitoken.Token{}, err
+	}
+	// The HTTP write routes check no permissions of their own, so accounts
+	// that may not write over TCP only get read-only tokens.
+	readOnly := param.GetBoolean("readOnly")
+	if accounts != nil && accounts.AccountPermission(account) < login.PermWrite {
+		readOnly = true
+	}
+	return tokens.Issue(param.GetText("name"), account, ttl, readOnly, time.Now())
+}
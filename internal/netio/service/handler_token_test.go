@@ -0,0 +1,42 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/apitoken"
+	"github.com/zbum/scouter-server-go/internal/db/kv"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+func TestIssueToken_ReadOnlyWithoutWritePermission(t *testing.T) {
+	dir := t.TempDir()
+	accounts := login.NewAccountManager(filepath.Join(dir, "conf"))
+	accounts.AddAccount(&login.Account{ID: "viewer", Group: "guest"})
+	tokens := apitoken.NewStore(kv.NewKVStore(dir, "tokens.json"))
+
+	param := &pack.MapPack{}
+	param.PutStr("name", "ci")
+	param.Put("readOnly", &value.BooleanValue{Value: false})
+
+	for _, tc := range []struct {
+		user     *login.User
+		account  string
+		readOnly bool
+	}{
+		{&login.User{ID: "viewer", Group: "guest"}, "", true},
+		{&login.User{ID: "admin", Group: "admin"}, "", false},
+		{&login.User{ID: "admin", Group: "admin"}, "viewer", true},
+	} {
+		param.PutStr("account", tc.account)
+		_, tok, err := issueToken(tokens, tc.user, accounts, param)
+		if err != nil {
+			t.Fatalf("%s for %q: %v", tc.user.ID, tc.account, err)
+		}
+		if tok.ReadOnly != tc.readOnly {
+			t.Errorf("%s for %q: readOnly = %v, want %v", tc.user.ID, tc.account, tok.ReadOnly, tc.readOnly)
+		}
+	}
+}
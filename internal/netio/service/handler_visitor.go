@@ -5,6 +5,7 @@ import (
 
 	"github.com/zbum/scouter-server-go/internal/core/cache"
 	"github.com/zbum/scouter-server-go/internal/db/visitor"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
@@ -12,6 +13,15 @@ import (
 
 // RegisterVisitorHandlers registers visitor-related handlers.
 func RegisterVisitorHandlers(r *Registry, visitorDB *visitor.VisitorDB, hourlyDB *visitor.VisitorHourlyDB, objectCache *cache.ObjectCache, deadTimeout time.Duration) {
+	r.Require(login.PermRead,
+		protocol.VISITOR_REALTIME,
+		protocol.VISITOR_REALTIME_TOTAL,
+		protocol.VISITOR_REALTIME_GROUP,
+		protocol.VISITOR_LOADDATE,
+		protocol.VISITOR_LOADDATE_TOTAL,
+		protocol.VISITOR_LOADDATE_GROUP,
+		protocol.VISITOR_LOADHOUR_GROUP,
+	)
 
 	// VISITOR_REALTIME: real-time visitor count for a single object.
 	r.Register(protocol.VISITOR_REALTIME, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db"
+	"github.com/zbum/scouter-server-go/internal/db/io"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// writeStatsDefaultTop is the number of objects listed per container when
+// DAY_WRITE_STATS is not given "top".
+const writeStatsDefaultTop = 10
+
+// RegisterWriteStatsHandlers registers the day container write statistics handler.
+func RegisterWriteStatsHandlers(r *Registry, dataDir string) {
+	r.Require(login.PermRead,
+		protocol.DAY_WRITE_STATS,
+	)
+
+	// DAY_WRITE_STATS: what the writers stored per day container since the
+	// server started, to spot e.g. one object flooding a day's profiles.
+	// Param: "date" (optional, all kept dates if absent), "top" (objects
+	// listed per container, default 10).
+	// Response: one MapPack per date and kind (xlog, profile, counter, alert,
+	// summary), newest date first, with "date", "kind", "entries", "bytes"
+	// (data bytes; 0 for counters), "objects", "lastWrite", "indexFiles",
+	// "indexBytes", "lastFlush" (ms, 0 if never) and the parallel lists
+	// "topObjHash", "topEntries" and "topBytes" of the objects writing the
+	// most bytes.
+	r.Register(protocol.DAY_WRITE_STATS, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		top := int(param.GetInt("top"))
+		if top <= 0 {
+			top = writeStatsDefaultTop
+		}
+
+		for _, st := range db.DayWriteStats(dataDir, param.GetText("date")) {
+			m := &pack.MapPack{}
+			m.PutStr("date", st.Date)
+			m.PutStr("kind", st.Kind)
+			m.PutLong("entries", st.Entries)
+			m.PutLong("bytes", st.Bytes)
+			m.PutLong("objects", int64(st.Objects))
+			m.PutLong("lastWrite", timeMs(st.LastWrite))
+			m.PutLong("indexFiles", int64(st.IndexFiles))
+			m.PutLong("indexBytes", st.IndexBytes)
+			m.PutLong("lastFlush", timeMs(st.LastFlush))
+
+			hashes, entries, bytes := value.NewListValue(), value.NewListValue(), value.NewListValue()
+			objects := io.GetWriteStats().Objects(st.Date, st.Kind)
+			if len(objects) > top {
+				objects = objects[:top]
+			}
+			for _, o := range objects {
+				hashes.Value = append(hashes.Value, value.NewDecimalValue(int64(o.ObjHash)))
+				entries.Value = append(entries.Value, value.NewDecimalValue(o.Entries))
+				bytes.Value = append(bytes.Value, value.NewDecimalValue(o.Bytes))
+			}
+			m.Put("topObjHash", hashes)
+			m.Put("topEntries", entries)
+			m.Put("topBytes", bytes)
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, m)
+		}
+	})
+}
+
+// timeMs returns t in ms, 0 for the zero time.
+func timeMs(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
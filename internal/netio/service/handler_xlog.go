@@ -4,6 +4,7 @@ import (
 	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/core/cache"
 	"github.com/zbum/scouter-server-go/internal/db/xlog"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
@@ -11,6 +12,10 @@ import (
 
 // RegisterXLogHandlers registers TRANX_REAL_TIME_GROUP and related handlers.
 func RegisterXLogHandlers(r *Registry, xlogCache *cache.XLogCache, xlogRD *xlog.XLogRD) {
+	r.Require(login.PermRead,
+		protocol.TRANX_REAL_TIME_GROUP,
+		protocol.TRANX_REAL_TIME_GROUP_LATEST,
+	)
 	// TRANX_REAL_TIME_GROUP: stream recent XLogs for real-time monitoring.
 	// Uses loop/index pagination matching Java's XLogLoopCache.
 	// Client sends (loop, index) from previous response; server returns only new entries.
@@ -19,45 +24,7 @@ func RegisterXLogHandlers(r *Registry, xlogCache *cache.XLogCache, xlogRD *xlog.
 		if err != nil {
 			return
 		}
-		param := pk.(*pack.MapPack)
-
-		lastIndex := int(param.GetInt("index"))
-		lastLoop := param.GetLong("loop")
-		limit := param.GetInt("limit") // min elapsed ms (not count)
-
-		// Apply server-side lower bound (matching Java's Math.max)
-		if cfg := config.Get(); cfg != nil {
-			if bound := int32(cfg.XLogRealtimeLowerBoundMs()); bound > limit {
-				limit = bound
-			}
-		}
-
-		// Build objHash filter set
-		var objHashSet map[int32]bool
-		objHashVal := param.Get("objHash")
-		if lv, ok := objHashVal.(*value.ListValue); ok && len(lv.Value) > 0 {
-			objHashSet = make(map[int32]bool, len(lv.Value))
-			for _, v := range lv.Value {
-				if dv, ok := v.(*value.DecimalValue); ok {
-					objHashSet[int32(dv.Value)] = true
-				}
-			}
-		}
-
-		d := xlogCache.Get(lastLoop, lastIndex, limit, objHashSet)
-
-		// First packet: metadata (loop/index for pagination)
-		outparam := &pack.MapPack{}
-		outparam.PutLong("loop", d.Loop)
-		outparam.PutLong("index", int64(d.Index))
-		dout.WriteByte(protocol.FLAG_HAS_NEXT)
-		pack.WritePack(dout, outparam)
-
-		// Stream XLog data (pre-serialized bytes)
-		for _, entry := range d.Data {
-			dout.WriteByte(protocol.FLAG_HAS_NEXT)
-			dout.Write(entry.Data)
-		}
+		writeRealTimeXLogs(dout, xlogCache, pk.(*pack.MapPack), nil)
 	})
 
 	// TRANX_REAL_TIME_GROUP_LATEST: same as above but uses count-based retrieval.
@@ -98,3 +65,49 @@ func RegisterXLogHandlers(r *Registry, xlogCache *cache.XLogCache, xlogRD *xlog.
 		}
 	})
 }
+
+// writeRealTimeXLogs answers a TRANX_REAL_TIME_GROUP request. restrict, if not
+// nil, may raise the min elapsed and narrow the objHash set (nil means all
+// objects) before the cache is read.
+func writeRealTimeXLogs(dout *protocol.DataOutputX, xlogCache *cache.XLogCache, param *pack.MapPack, restrict func(limit int32, objHashSet map[int32]bool) (int32, map[int32]bool)) {
+	lastIndex := int(param.GetInt("index"))
+	lastLoop := param.GetLong("loop")
+	limit := param.GetInt("limit") // min elapsed ms (not count)
+
+	// Apply server-side lower bound (matching Java's Math.max)
+	if cfg := config.Get(); cfg != nil {
+		if bound := int32(cfg.XLogRealtimeLowerBoundMs()); bound > limit {
+			limit = bound
+		}
+	}
+
+	// Build objHash filter set
+	var objHashSet map[int32]bool
+	objHashVal := param.Get("objHash")
+	if lv, ok := objHashVal.(*value.ListValue); ok && len(lv.Value) > 0 {
+		objHashSet = make(map[int32]bool, len(lv.Value))
+		for _, v := range lv.Value {
+			if dv, ok := v.(*value.DecimalValue); ok {
+				objHashSet[int32(dv.Value)] = true
+			}
+		}
+	}
+	if restrict != nil {
+		limit, objHashSet = restrict(limit, objHashSet)
+	}
+
+	d := xlogCache.Get(lastLoop, lastIndex, limit, objHashSet)
+
+	// First packet: metadata (loop/index for pagination)
+	outparam := &pack.MapPack{}
+	outparam.PutLong("loop", d.Loop)
+	outparam.PutLong("index", int64(d.Index))
+	dout.WriteByte(protocol.FLAG_HAS_NEXT)
+	pack.WritePack(dout, outparam)
+
+	// Stream XLog data (pre-serialized bytes)
+	for _, entry := range d.Data {
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		dout.Write(entry.Data)
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"math"
+	"sync"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// xlogFilter narrows the real-time XLogs sent to one client session.
+type xlogFilter struct {
+	objTypes   map[string]bool // empty means all types
+	minElapsed int32
+	errorOnly  bool
+}
+
+// restrict applies f to the min elapsed and objHash set of a
+// TRANX_REAL_TIME_GROUP request.
+func (f *xlogFilter) restrict(objectCache *cache.ObjectCache) func(int32, map[int32]bool) (int32, map[int32]bool) {
+	return func(limit int32, objHashSet map[int32]bool) (int32, map[int32]bool) {
+		if f.minElapsed > limit {
+			limit = f.minElapsed
+		}
+		// The cache returns errors regardless of elapsed time.
+		if f.errorOnly {
+			limit = math.MaxInt32
+		}
+		if len(f.objTypes) == 0 {
+			return limit, objHashSet
+		}
+		// A non-nil empty set matches nothing, so the client still gets
+		// its loop/index when no object of the types is known.
+		matched := make(map[int32]bool)
+		for _, info := range objectCache.GetAll() {
+			if !f.objTypes[info.Pack.ObjType] {
+				continue
+			}
+			if objHashSet == nil || objHashSet[info.Pack.ObjHash] {
+				matched[info.Pack.ObjHash] = true
+			}
+		}
+		return limit, matched
+	}
+}
+
+// xlogFilters holds the filters by session id.
+type xlogFilters struct {
+	mu      sync.Mutex
+	filters map[int64]*xlogFilter
+}
+
+func (fs *xlogFilters) get(session int64) *xlogFilter {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.filters[session]
+}
+
+// set stores f for session, or removes the filter when f is nil. Filters of
+// sessions that are no longer valid are dropped.
+func (fs *xlogFilters) set(session int64, f *xlogFilter, sessions *login.SessionManager) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for s := range fs.filters {
+		if !sessions.OkSession(s) {
+			delete(fs.filters, s)
+		}
+	}
+	if f == nil {
+		delete(fs.filters, session)
+	} else {
+		fs.filters[session] = f
+	}
+}
+
+// RegisterXLogFilterHandlers registers the per-session real-time XLog filter
+// commands and makes TRANX_REAL_TIME_GROUP apply the filter of the session
+// it is sent with. The group-aware variant and TRANX_REAL_TIME_GROUP_LATEST
+// are not filtered.
+func RegisterXLogFilterHandlers(r *Registry, xlogCache *cache.XLogCache, objectCache *cache.ObjectCache, sessions *login.SessionManager) {
+	// The filter only applies to the calling session.
+	r.Require(login.PermRead,
+		protocol.XLOG_REALTIME_FILTER_SET,
+		protocol.XLOG_REALTIME_FILTER_GET,
+		protocol.XLOG_REALTIME_FILTER_CLEAR,
+	)
+	filters := &xlogFilters{filters: make(map[int64]*xlogFilter)}
+
+	// XLOG_REALTIME_FILTER_SET: set the filter of the calling session.
+	// Param: "objType" (list of object types, empty for all), "minElapsed"
+	// (ms), "errorOnly" (boolean). A param without criteria clears the filter.
+	// Response: "result" ("ok").
+	r.RegisterSession(protocol.XLOG_REALTIME_FILTER_SET, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+
+		f := &xlogFilter{
+			objTypes:   make(map[string]bool),
+			minElapsed: param.GetInt("minElapsed"),
+			errorOnly:  param.GetBoolean("errorOnly"),
+		}
+		for _, t := range listTexts(param.GetList("objType")) {
+			f.objTypes[t] = true
+		}
+		if len(f.objTypes) == 0 && f.minElapsed <= 0 && !f.errorOnly {
+			f = nil
+		}
+		filters.set(session, f, sessions)
+
+		resp := &pack.MapPack{}
+		resp.PutStr("result", "ok")
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+
+	// XLOG_REALTIME_FILTER_GET: the filter of the calling session.
+	// Response: "objType", "minElapsed" and "errorOnly"; nothing if unset.
+	r.RegisterSession(protocol.XLOG_REALTIME_FILTER_GET, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pack.ReadPack(din)
+
+		f := filters.get(session)
+		if f == nil {
+			return
+		}
+		objTypes := value.NewListValue()
+		for t := range f.objTypes {
+			objTypes.Value = append(objTypes.Value, value.NewTextValue(t))
+		}
+		resp := &pack.MapPack{}
+		resp.Put("objType", objTypes)
+		resp.PutLong("minElapsed", int64(f.minElapsed))
+		resp.Put("errorOnly", &value.BooleanValue{Value: f.errorOnly})
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, resp)
+	})
+
+	// XLOG_REALTIME_FILTER_CLEAR: remove the filter of the calling session.
+	r.RegisterSession(protocol.XLOG_REALTIME_FILTER_CLEAR, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pack.ReadPack(din)
+		filters.set(session, nil, sessions)
+	})
+
+	r.RegisterSession(protocol.TRANX_REAL_TIME_GROUP, func(session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		var restrict func(int32, map[int32]bool) (int32, map[int32]bool)
+		if f := filters.get(session); f != nil {
+			restrict = f.restrict(objectCache)
+		}
+		writeRealTimeXLogs(dout, xlogCache, pk.(*pack.MapPack), restrict)
+	})
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+func TestXLogFilterHandlers(t *testing.T) {
+	objectCache := cache.NewObjectCache()
+	objectCache.Put(1, &pack.ObjectPack{ObjHash: 1, ObjName: "/a/tomcat", ObjType: "tomcat"})
+	objectCache.Put(2, &pack.ObjectPack{ObjHash: 2, ObjName: "/a/node", ObjType: "nodejs"})
+
+	xlogCache := cache.NewXLogCache(100)
+	for _, xp := range []*pack.XLogPack{
+		{ObjHash: 1, Elapsed: 100, Txid: 1},
+		{ObjHash: 1, Elapsed: 5000, Txid: 2},
+		{ObjHash: 1, Elapsed: 10, Error: 7, Txid: 3},
+		{ObjHash: 2, Elapsed: 5000, Txid: 4},
+	} {
+		o := protocol.NewDataOutputX()
+		pack.WritePack(o, xp)
+		xlogCache.Put(xp.ObjHash, xp.Elapsed, xp.Error != 0, o.ToByteArray())
+	}
+
+	sessions := login.NewSessionManager(nil)
+	session := sessions.Login("admin", "admin", "127.0.0.1")
+	other := sessions.Login("guest", "guest", "127.0.0.1")
+
+	registry := NewRegistry()
+	RegisterXLogHandlers(registry, xlogCache, nil)
+	RegisterXLogFilterHandlers(registry, xlogCache, objectCache, sessions)
+
+	call := func(cmd string, session int64, param *pack.MapPack) []pack.Pack {
+		t.Helper()
+		handler := registry.GetSession(cmd)
+		if handler == nil {
+			t.Fatalf("%s not registered", cmd)
+		}
+		in := protocol.NewDataOutputX()
+		pack.WritePack(in, param)
+		out := protocol.NewDataOutputX()
+		handler(session, protocol.NewDataInputX(in.ToByteArray()), out, true)
+		resp := protocol.NewDataInputX(out.ToByteArray())
+		var packs []pack.Pack
+		for {
+			flag, err := resp.ReadByte()
+			if err != nil || flag != protocol.FLAG_HAS_NEXT {
+				return packs
+			}
+			pk, err := pack.ReadPack(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			packs = append(packs, pk)
+		}
+	}
+	txids := func(packs []pack.Pack) map[int64]bool {
+		ids := make(map[int64]bool)
+		for _, pk := range packs[1:] {
+			ids[pk.(*pack.XLogPack).Txid] = true
+		}
+		return ids
+	}
+
+	if got := txids(call(protocol.TRANX_REAL_TIME_GROUP, session, &pack.MapPack{})); len(got) != 4 {
+		t.Fatalf("unfiltered: expected 4 xlogs, got %v", got)
+	}
+
+	set := &pack.MapPack{}
+	objTypes := value.NewListValue()
+	objTypes.Value = append(objTypes.Value, value.NewTextValue("tomcat"))
+	set.Put("objType", objTypes)
+	set.PutLong("minElapsed", 1000)
+	call(protocol.XLOG_REALTIME_FILTER_SET, session, set)
+
+	got := txids(call(protocol.TRANX_REAL_TIME_GROUP, session, &pack.MapPack{}))
+	if len(got) != 2 || !got[2] || !got[3] {
+		t.Fatalf("objType+minElapsed: expected txids 2 and 3, got %v", got)
+	}
+	if got := txids(call(protocol.TRANX_REAL_TIME_GROUP, other, &pack.MapPack{})); len(got) != 4 {
+		t.Fatalf("other session: expected 4 xlogs, got %v", got)
+	}
+
+	set.Put("errorOnly", &value.BooleanValue{Value: true})
+	call(protocol.XLOG_REALTIME_FILTER_SET, session, set)
+	if got := txids(call(protocol.TRANX_REAL_TIME_GROUP, session, &pack.MapPack{})); len(got) != 1 || !got[3] {
+		t.Fatalf("errorOnly: expected txid 3, got %v", got)
+	}
+	resp := call(protocol.XLOG_REALTIME_FILTER_GET, session, &pack.MapPack{})
+	if len(resp) != 1 || !resp[0].(*pack.MapPack).GetBoolean("errorOnly") {
+		t.Fatalf("XLOG_REALTIME_FILTER_GET: unexpected %v", resp)
+	}
+
+	// An objType with no known object matches nothing but keeps paging.
+	objTypes.Value = []value.Value{value.NewTextValue("python")}
+	call(protocol.XLOG_REALTIME_FILTER_SET, session, set)
+	resp = call(protocol.TRANX_REAL_TIME_GROUP, session, &pack.MapPack{})
+	if len(resp) != 1 || resp[0].(*pack.MapPack).GetLong("index") != 4 {
+		t.Fatalf("unknown objType: expected only the position, got %v", resp)
+	}
+
+	call(protocol.XLOG_REALTIME_FILTER_CLEAR, session, &pack.MapPack{})
+	if resp := call(protocol.XLOG_REALTIME_FILTER_GET, session, &pack.MapPack{}); len(resp) != 0 {
+		t.Fatalf("filter not cleared: %v", resp)
+	}
+	if got := txids(call(protocol.TRANX_REAL_TIME_GROUP, session, &pack.MapPack{})); len(got) != 4 {
+		t.Fatalf("cleared: expected 4 xlogs, got %v", got)
+	}
+}
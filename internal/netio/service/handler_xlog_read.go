@@ -1,12 +1,13 @@
 package service
 
 import (
-	"sync"
+	"log/slog"
 	"time"
 
 	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/db/profile"
 	"github.com/zbum/scouter-server-go/internal/db/xlog"
+	"github.com/zbum/scouter-server-go/internal/login"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
@@ -17,6 +18,27 @@ import (
 // xlogWR is used for reading the current day's data (always up-to-date in memory),
 // with fallback to xlogRD for dates not held by the writer.
 func RegisterXLogReadHandlers(r *Registry, xlogRD *xlog.XLogRD, profileRD *profile.ProfileRD, profileWR *profile.ProfileWR, xlogWR *xlog.XLogWR) {
+	r.Require(login.PermRead,
+		protocol.XLOG_READ_BY_TXID,
+		protocol.XLOG_READ_BY_GXID,
+		protocol.TRANX_LOAD_TIME_GROUP,
+		protocol.TRANX_LOAD_TIME_GROUP_V2,
+		protocol.TRANX_PROFILE,
+		protocol.TRANX_PROFILE_STREAM,
+		protocol.TRANX_PROFILE_CHUNKED,
+		protocol.TRANX_PROFILE_FULL,
+		protocol.XLOG_LOAD_BY_TXIDS,
+		protocol.XLOG_LOAD_BY_GXID,
+		protocol.XLOG_LOAD_BY_USERID,
+		protocol.QUICKSEARCH_XLOG_LIST,
+	)
+
+	// readByGxid reads the legs of gxid from dates and their adjacent days
+	// (see xlog.GxidDates), from the writer where it holds the date.
+	readByGxid := func(gxid int64, handler func(data []byte), dates ...string) {
+		missing, _ := xlogWR.ReadByGxidDates(xlog.GxidDates(dates...), gxid, handler)
+		xlogRD.ReadByGxidDates(missing, gxid, handler)
+	}
 
 	// XLOG_READ_BY_TXID: retrieve a single XLog by transaction ID.
 	r.Register(protocol.XLOG_READ_BY_TXID, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
@@ -42,7 +64,8 @@ func RegisterXLogReadHandlers(r *Registry, xlogRD *xlog.XLogRD, profileRD *profi
 		dout.Flush()
 	})
 
-	// XLOG_READ_BY_GXID: retrieve all XLogs related to a global transaction ID.
+	// XLOG_READ_BY_GXID: retrieve all XLogs related to a global transaction ID,
+	// including legs stored on the days adjacent to "date".
 	r.Register(protocol.XLOG_READ_BY_GXID, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		pk, err := pack.ReadPack(din)
 		if err != nil {
@@ -52,17 +75,16 @@ func RegisterXLogReadHandlers(r *Registry, xlogRD *xlog.XLogRD, profileRD *profi
 		date := param.GetText("date")
 		gxid := param.GetLong("gxid")
 
-		gxidHandler := func(data []byte) {
+		readByGxid(gxid, func(data []byte) {
 			dout.WriteByte(protocol.FLAG_HAS_NEXT)
 			dout.Write(data)
 			dout.Flush()
-		}
-		if found, _ := xlogWR.ReadByGxid(date, gxid, gxidHandler); !found {
-			xlogRD.ReadByGxid(date, gxid, gxidHandler)
-		}
+		}, date)
 	})
 
 	// TRANX_LOAD_TIME_GROUP: load XLogs by time range with optional objHash filter.
+	// Without "date" the dates are derived from stime/etime and read in time
+	// order (latest first with "reverse"), with "max" applying to the whole range.
 	// Try xlogWR first (which holds the up-to-date in-memory index for the
 	// current day), then fall back to xlogRD for dates the writer doesn't hold.
 	tranxLoadTimeGroupHandler := func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
@@ -71,9 +93,6 @@ func RegisterXLogReadHandlers(r *Registry, xlogRD *xlog.XLogRD, profileRD *profi
 			return
 		}
 		param := pk.(*pack.MapPack)
-		date := param.GetText("date")
-		stime := param.GetLong("stime")
-		etime := param.GetLong("etime")
 		max := param.GetInt("max")
 		rev := param.GetBoolean("reverse")
 		limitTime := param.GetInt("limit")
@@ -100,6 +119,7 @@ func RegisterXLogReadHandlers(r *Registry, xlogRD *xlog.XLogRD, profileRD *profi
 
 		cnt := 0
 		needFilter := len(objHashFilter) > 0 || limit > 0
+		guard := newResultGuard(protocol.TRANX_LOAD_TIME_GROUP, dout)
 		dataHandler := func(data []byte) bool {
 			if max > 0 && cnt >= int(max) {
 				return false
@@ -117,6 +137,9 @@ func RegisterXLogReadHandlers(r *Registry, xlogRD *xlog.XLogRD, profileRD *profi
 					return true
 				}
 			}
+			if !guard.next() {
+				return false
+			}
 			dout.WriteByte(protocol.FLAG_HAS_NEXT)
 			dout.Write(data)
 			dout.Flush()
@@ -124,24 +147,42 @@ func RegisterXLogReadHandlers(r *Registry, xlogRD *xlog.XLogRD, profileRD *profi
 			return true
 		}
 
+		// Days with a secondary index read only the XLogs of the objects.
+		var sel xlog.Selection
+		for objHash := range objHashFilter {
+			sel.ObjHashes = append(sel.ObjHashes, objHash)
+		}
+
 		// Try xlogWR first (current day has up-to-date in-memory index),
 		// fall back to xlogRD for past dates.
+		days := queryDays(param)
 		if rev {
-			if found, _ := xlogWR.ReadFromEndTime(date, stime, etime, dataHandler); !found {
-				xlogRD.ReadFromEndTime(date, stime, etime, dataHandler)
+			for i := len(days) - 1; i >= 0 && !guard.truncated(); i-- {
+				d := days[i]
+				if found, _ := xlogWR.ReadSelected(d.date, d.stime, d.etime, sel, true, dataHandler); !found {
+					xlogRD.ReadSelected(d.date, d.stime, d.etime, sel, true, dataHandler)
+				}
 			}
 		} else {
-			if found, _ := xlogWR.ReadByTime(date, stime, etime, dataHandler); !found {
-				xlogRD.ReadByTime(date, stime, etime, dataHandler)
+			for _, d := range days {
+				if guard.truncated() {
+					break
+				}
+				if found, _ := xlogWR.ReadSelected(d.date, d.stime, d.etime, sel, false, dataHandler); !found {
+					xlogRD.ReadSelected(d.date, d.stime, d.etime, sel, false, dataHandler)
+				}
 			}
 		}
+		guard.finish()
 	}
 	r.Register(protocol.TRANX_LOAD_TIME_GROUP, tranxLoadTimeGroupHandler)
 	r.Register(protocol.TRANX_LOAD_TIME_GROUP_V2, tranxLoadTimeGroupHandler)
 
 	// TRANX_PROFILE: retrieve profile blocks for a transaction.
 	// Java's processGetProfile concatenates all blocks into one byte array,
-	// wraps it in XLogProfilePack, and sends via writePack.
+	// wraps it in XLogProfilePack, and sends via writePack. When
+	// profile_single_pack_max_bytes cut the profile, a trailer MapPack with
+	// "txid", "bytes" and "truncated" follows.
 	r.Register(protocol.TRANX_PROFILE, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		pk, err := pack.ReadPack(din)
 		if err != nil {
@@ -151,17 +192,20 @@ func RegisterXLogReadHandlers(r *Registry, xlogRD *xlog.XLogRD, profileRD *profi
 		date := param.GetText("date")
 		txid := param.GetLong("txid")
 
-		// Read through ProfileWR which has up-to-date MemHashBlock index.
-		// ProfileRD has a stale index snapshot from when it was opened.
-		blocks, err := profileWR.Read(date, txid, -1)
-		if err != nil || len(blocks) == 0 {
+		// "chunked": answer as TRANX_PROFILE_CHUNKED does.
+		if param.GetBoolean("chunked") {
+			if date == "" {
+				date = time.Now().Format("20060102")
+			}
+			streamProfileChunked(dout, profileWR, date, txid)
 			return
 		}
 
-		// Concatenate all blocks into a single byte array (matching Java's XLogProfileRD.getProfile)
-		var allData []byte
-		for _, block := range blocks {
-			allData = append(allData, block...)
+		// Read through ProfileWR which has up-to-date MemHashBlock index.
+		// ProfileRD has a stale index snapshot from when it was opened.
+		allData, truncated := readProfile(profileWR, date, txid)
+		if allData == nil {
+			return
 		}
 
 		// Wrap in XLogProfilePack (matching Java's processGetProfile)
@@ -170,10 +214,22 @@ func RegisterXLogReadHandlers(r *Registry, xlogRD *xlog.XLogRD, profileRD *profi
 		}
 		dout.WriteByte(protocol.FLAG_HAS_NEXT)
 		pack.WritePack(dout, profilePack)
+		if truncated {
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, profileTrailer(txid, 1, int64(len(allData)), true))
+		}
 	})
 
-	// TRANX_PROFILE_FULL: retrieve full profile including related transactions.
-	r.Register(protocol.TRANX_PROFILE_FULL, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+	// TRANX_PROFILE_STREAM: retrieve profile blocks for a transaction in
+	// chunks of about profile_stream_chunk_bytes, for profiles too large to
+	// send as one XLogProfilePack.
+	// Param: "date", "txid".
+	// Response: one MapPack per chunk with "txid", "seq" (from 0) and
+	// "profile" (blob of whole blocks), then a MapPack with "chunks" and
+	// "bytes" totals so the client can tell the stream is complete, and
+	// "truncated" if profile_stream_max_bytes cut the profile, even when not
+	// a single block fit. Nothing is sent when the transaction has no profile.
+	r.Register(protocol.TRANX_PROFILE_STREAM, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		pk, err := pack.ReadPack(din)
 		if err != nil {
 			return
@@ -184,15 +240,53 @@ func RegisterXLogReadHandlers(r *Registry, xlogRD *xlog.XLogRD, profileRD *profi
 		if date == "" {
 			date = time.Now().Format("20060102")
 		}
+		streamProfile(protocol.TRANX_PROFILE_STREAM, dout, profileWR, date, txid, func(seq int, chunk []byte) pack.Pack {
+			m := &pack.MapPack{}
+			m.PutLong("txid", txid)
+			m.PutLong("seq", int64(seq))
+			m.Put("profile", &value.BlobValue{Value: chunk})
+			return m
+		})
+	})
+
+	// TRANX_PROFILE_CHUNKED: retrieve profile blocks for a transaction as a
+	// series of XLogProfilePacks, each holding whole blocks of about
+	// profile_stream_chunk_bytes, so the server never holds the profile.
+	// Param: "date", "txid".
+	// Response: one XLogProfilePack per chunk with Txid and Profile, then a
+	// MapPack with "txid", "chunks" and "bytes" totals and "truncated" if
+	// profile_stream_max_bytes cut the profile, even when not a single block
+	// fit. Nothing is sent when the transaction has no profile.
+	r.Register(protocol.TRANX_PROFILE_CHUNKED, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		date := param.GetText("date")
+		if date == "" {
+			date = time.Now().Format("20060102")
+		}
+		streamProfileChunked(dout, profileWR, date, param.GetLong("txid"))
+	})
 
-		blocks, err := profileWR.Read(date, txid, -1)
-		if err != nil || len(blocks) == 0 {
+	// TRANX_PROFILE_FULL: retrieve full profile including related transactions.
+	// Like TRANX_PROFILE, a trailer MapPack follows a truncated profile.
+	r.Register(protocol.TRANX_PROFILE_FULL, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
 			return
 		}
+		param := pk.(*pack.MapPack)
+		date := param.GetText("date")
+		txid := param.GetLong("txid")
+		if date == "" {
+			date = time.Now().Format("20060102")
+		}
 
-		var allData []byte
-		for _, block := range blocks {
-			allData = append(allData, block...)
+		allData, truncated := readProfile(profileWR, date, txid)
+		if allData == nil {
+			return
 		}
 
 		profilePack := &pack.XLogProfilePack{
@@ -200,9 +294,18 @@ func RegisterXLogReadHandlers(r *Registry, xlogRD *xlog.XLogRD, profileRD *profi
 		}
 		dout.WriteByte(protocol.FLAG_HAS_NEXT)
 		pack.WritePack(dout, profilePack)
+		if truncated {
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, profileTrailer(txid, 1, int64(len(allData)), true))
+		}
 	})
 
 	// XLOG_LOAD_BY_TXIDS: retrieve XLogs by a list of transaction IDs.
+	// Param: "date", "txid" (list) and "profile": if true, every found
+	// XLogPack is followed by an XLogProfilePack carrying the same txid when
+	// the transaction has a profile, so clients need no TRANX_PROFILE round
+	// trip per transaction. As with TRANX_PROFILE, a trailer MapPack with
+	// "truncated" follows a profile cut by profile_single_pack_max_bytes.
 	r.Register(protocol.XLOG_LOAD_BY_TXIDS, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
 		pk, err := pack.ReadPack(din)
 		if err != nil {
@@ -210,43 +313,48 @@ func RegisterXLogReadHandlers(r *Registry, xlogRD *xlog.XLogRD, profileRD *profi
 		}
 		param := pk.(*pack.MapPack)
 		date := param.GetText("date")
+		withProfile := param.GetBoolean("profile") && profileWR != nil
 		txidLv := param.GetList("txid")
 		if txidLv == nil {
 			return
 		}
 
-		// Parallel lookup: each txid does disk I/O (index + data read via pread),
-		// so concurrent goroutines overlap I/O latency.
-		results := make([][]byte, len(txidLv.Value))
-		var wg sync.WaitGroup
+		// Each txid does disk I/O (index + data read via pread), so up to
+		// txidLoadWorkers lookups run ahead of the one being written to
+		// overlap I/O latency. Results are written in request order.
+		pending := make(chan chan txidLoadResult, txidLoadWorkers)
+		go func() {
+			defer close(pending)
+			for _, hv := range txidLv.Value {
+				dv, ok := hv.(*value.DecimalValue)
+				if !ok {
+					continue
+				}
+				ch := make(chan txidLoadResult, 1)
+				pending <- ch
+				go func(txid int64) {
+					ch <- loadByTxid(xlogRD, xlogWR, profileWR, date, txid, withProfile)
+				}(dv.Value)
+			}
+		}()
 
-		for i, hv := range txidLv.Value {
-			dv, ok := hv.(*value.DecimalValue)
-			if !ok {
+		// dout is not thread-safe, so only this goroutine writes.
+		for ch := range pending {
+			res := <-ch
+			if res.xlog == nil {
 				continue
 			}
-			wg.Add(1)
-			go func(idx int, txid int64) {
-				defer wg.Done()
-				data, found, err := xlogWR.GetByTxid(date, txid)
-				if !found {
-					data, err = xlogRD.GetByTxid(date, txid)
-				}
-				if err == nil && data != nil {
-					results[idx] = data
-				}
-			}(i, dv.Value)
-		}
-
-		wg.Wait()
-
-		// Write results sequentially (dout is not thread-safe)
-		for _, data := range results {
-			if data != nil {
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			dout.Write(res.xlog)
+			if res.profile != nil {
 				dout.WriteByte(protocol.FLAG_HAS_NEXT)
-				dout.Write(data)
-				dout.Flush()
+				pack.WritePack(dout, res.profile)
+				if res.truncated {
+					dout.WriteByte(protocol.FLAG_HAS_NEXT)
+					pack.WritePack(dout, profileTrailer(res.profile.Txid, 1, int64(len(res.profile.Profile)), true))
+				}
 			}
+			dout.Flush()
 		}
 	})
 
@@ -263,21 +371,72 @@ func RegisterXLogReadHandlers(r *Registry, xlogRD *xlog.XLogRD, profileRD *profi
 		date := util.FormatDate(stime)
 		date2 := util.FormatDate(etime)
 
-		gxidHandler := func(data []byte) {
+		readByGxid(gxid, func(data []byte) {
 			dout.WriteByte(protocol.FLAG_HAS_NEXT)
 			dout.Write(data)
 			dout.Flush()
-		}
+		}, date, date2)
+	})
 
-		if found, _ := xlogWR.ReadByGxid(date, gxid, gxidHandler); !found {
-			xlogRD.ReadByGxid(date, gxid, gxidHandler)
+	// XLOG_LOAD_BY_USERID: the XLogs of one user, latest first, from the
+	// per-day userid index written while xlog_userid_index_enabled is on.
+	// Param: "userid", "stime" and "etime" or a whole "date", optional
+	// "objHash" (list) and "max" (default req_search_xlog_max_count).
+	r.Register(protocol.XLOG_LOAD_BY_USERID, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		userid := param.GetLong("userid")
+		if userid == 0 {
+			return
+		}
+		max := int(param.GetInt("max"))
+		if cfg := config.Get(); max <= 0 && cfg != nil {
+			max = cfg.ReqSearchXLogMaxCount()
+		}
+		objHashFilter := make(map[int32]bool)
+		if lv := param.GetList("objHash"); lv != nil {
+			for i := range lv.Value {
+				objHashFilter[int32(lv.GetLong(i))] = true
+			}
 		}
 
-		if date != date2 {
-			if found, _ := xlogWR.ReadByGxid(date2, gxid, gxidHandler); !found {
-				xlogRD.ReadByGxid(date2, gxid, gxidHandler)
+		cnt := 0
+		guard := newResultGuard(protocol.XLOG_LOAD_BY_USERID, dout)
+		days := queryDays(param)
+		for i := len(days) - 1; i >= 0 && !guard.truncated(); i-- {
+			d := days[i]
+			handler := func(data []byte) bool {
+				if max > 0 && cnt >= max {
+					return false
+				}
+				p, err := pack.ReadPack(protocol.NewDataInputX(data))
+				if err != nil {
+					return true
+				}
+				xp, ok := p.(*pack.XLogPack)
+				if !ok || d.etime > 0 && (xp.EndTime < d.stime || xp.EndTime > d.etime) {
+					return true
+				}
+				if len(objHashFilter) > 0 && !objHashFilter[xp.ObjHash] {
+					return true
+				}
+				if !guard.next() {
+					return false
+				}
+				dout.WriteByte(protocol.FLAG_HAS_NEXT)
+				dout.Write(data)
+				dout.Flush()
+				cnt++
+				return true
+			}
+			if found, _ := xlogWR.ReadByUserid(d.date, userid, handler); !found {
+				xlogRD.ReadByUserid(d.date, userid, handler)
 			}
 		}
+		guard.finish()
 	})
 
 	// QUICKSEARCH_XLOG_LIST: search XLogs by txid or gxid.
@@ -303,70 +462,156 @@ func RegisterXLogReadHandlers(r *Registry, xlogRD *xlog.XLogRD, profileRD *profi
 			}
 		}
 		if gxid != 0 {
-			gxidHandler := func(data []byte) {
+			readByGxid(gxid, func(data []byte) {
 				dout.WriteByte(protocol.FLAG_HAS_NEXT)
 				dout.Write(data)
 				dout.Flush()
-			}
-			if found, _ := xlogWR.ReadByGxid(date, gxid, gxidHandler); !found {
-				xlogRD.ReadByGxid(date, gxid, gxidHandler)
-			}
+			}, date)
 		}
 	})
+}
 
-	// SEARCH_XLOG_LIST: search XLogs by time range with optional objHash filter.
-	r.Register(protocol.SEARCH_XLOG_LIST, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
-		pk, err := pack.ReadPack(din)
-		if err != nil {
-			return
-		}
-		param := pk.(*pack.MapPack)
-		stime := param.GetLong("stime")
-		etime := param.GetLong("etime")
-		objHash := param.GetInt("objHash")
+// txidLoadWorkers bounds the lookups XLOG_LOAD_BY_TXIDS runs concurrently.
+const txidLoadWorkers = 8
 
-		date := util.FormatDate(stime)
-		date2 := util.FormatDate(etime)
+// txidLoadResult is the XLog of one txid and, if asked for, its profile.
+type txidLoadResult struct {
+	xlog      []byte
+	profile   *pack.XLogProfilePack
+	truncated bool // profile cut by profile_single_pack_max_bytes
+}
 
-		// req_search_xlog_max_count: limit max results
-		maxCount := 0
-		if cfg := config.Get(); cfg != nil {
-			maxCount = cfg.ReqSearchXLogMaxCount()
-		}
-		cnt := 0
+// loadByTxid reads the XLog of txid, from the writer for days it holds, and
+// with withProfile its concatenated profile.
+func loadByTxid(xlogRD *xlog.XLogRD, xlogWR *xlog.XLogWR, profileWR *profile.ProfileWR, date string, txid int64, withProfile bool) txidLoadResult {
+	data, found, err := xlogWR.GetByTxid(date, txid)
+	if !found {
+		data, err = xlogRD.GetByTxid(date, txid)
+	}
+	if err != nil || data == nil {
+		return txidLoadResult{}
+	}
+	res := txidLoadResult{xlog: data}
+	if !withProfile {
+		return res
+	}
 
-		searchHandler := func(data []byte) bool {
-			if maxCount > 0 && cnt >= maxCount {
-				return false
-			}
-			if objHash != 0 {
-				packObjHash, _, err := pack.ReadXLogFilterFields(data)
-				if err != nil {
-					return true
-				}
-				if packObjHash != objHash {
-					return true
-				}
-			}
-			dout.WriteByte(protocol.FLAG_HAS_NEXT)
-			dout.Write(data)
-			dout.Flush()
-			cnt++
-			return true
+	allData, truncated := readProfile(profileWR, date, txid)
+	if allData == nil {
+		return res
+	}
+	res.profile = &pack.XLogProfilePack{Txid: txid, Profile: allData}
+	res.truncated = truncated
+	if xp, err := pack.ReadPack(protocol.NewDataInputX(data)); err == nil {
+		if x, ok := xp.(*pack.XLogPack); ok {
+			res.profile.Time = x.EndTime
+			res.profile.ObjHash = x.ObjHash
+			res.profile.Service = x.Service
 		}
+	}
+	return res
+}
 
-		readByTime := func(d string, s, e int64) {
-			if found, _ := xlogWR.ReadByTime(d, s, e, searchHandler); !found {
-				xlogRD.ReadByTime(d, s, e, searchHandler)
-			}
+// readProfile concatenates the profile blocks of txid into one byte array
+// (matching Java's XLogProfileRD.getProfile), or returns nil if there are
+// none. Whole blocks beyond profile_single_pack_max_bytes are left out so a
+// huge batch profile cannot spike memory, and truncated is set; the array
+// is then empty rather than nil if not even the first block fits.
+// TRANX_PROFILE_STREAM returns the whole profile.
+func readProfile(profileWR *profile.ProfileWR, date string, txid int64) (data []byte, truncated bool) {
+	maxBytes := 0
+	if cfg := config.Get(); cfg != nil {
+		maxBytes = cfg.ProfileSinglePackMaxBytes()
+	}
+	var allData []byte
+	err := profileWR.Scan(date, txid, func(block []byte) bool {
+		if maxBytes > 0 && len(allData)+len(block) > maxBytes {
+			truncated = true
+			return false
+		}
+		allData = append(allData, block...)
+		return true
+	})
+	if err != nil {
+		return nil, false
+	}
+	if truncated {
+		slog.Warn("Profile truncated to profile_single_pack_max_bytes; use TRANX_PROFILE_CHUNKED for the full profile",
+			"date", date, "txid", txid, "bytes", len(allData))
+		if allData == nil {
+			allData = []byte{}
 		}
+	}
+	return allData, truncated
+}
 
-		if date == date2 {
-			readByTime(date, stime, etime)
-		} else {
-			mtime := util.DateToMillis(date2)
-			readByTime(date, stime, mtime-1)
-			readByTime(date2, mtime, etime)
+// streamProfileChunked answers TRANX_PROFILE_CHUNKED.
+func streamProfileChunked(dout *protocol.DataOutputX, profileWR *profile.ProfileWR, date string, txid int64) {
+	streamProfile(protocol.TRANX_PROFILE_CHUNKED, dout, profileWR, date, txid, func(seq int, chunk []byte) pack.Pack {
+		return &pack.XLogProfilePack{Txid: txid, Profile: chunk}
+	})
+}
+
+// streamProfile sends the profile blocks of txid in chunks of whole blocks
+// of about profile_stream_chunk_bytes, each wrapped by wrap and flushed as
+// soon as it is full, then a trailer MapPack with the totals. It stops
+// before profile_stream_max_bytes are sent and flags the trailer truncated,
+// even if the first block is already over the limit. Nothing is sent when
+// the transaction has no profile.
+func streamProfile(cmd string, dout *protocol.DataOutputX, profileWR *profile.ProfileWR, date string, txid int64, wrap func(seq int, chunk []byte) pack.Pack) {
+	chunkBytes, maxBytes := 256*1024, int64(0)
+	if cfg := config.Get(); cfg != nil {
+		chunkBytes = cfg.ProfileStreamChunkBytes()
+		maxBytes = int64(cfg.ProfileStreamMaxBytes())
+	}
+
+	var chunk []byte
+	seq, total := 0, int64(0)
+	truncated := false
+	flush := func() {
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, wrap(seq, chunk))
+		dout.Flush()
+		seq++
+		total += int64(len(chunk))
+		chunk = chunk[:0]
+	}
+	err := profileWR.Scan(date, txid, func(block []byte) bool {
+		if maxBytes > 0 && total+int64(len(chunk)+len(block)) > maxBytes {
+			truncated = true
+			return false
+		}
+		if len(chunk) > 0 && len(chunk)+len(block) > chunkBytes {
+			flush()
 		}
+		chunk = append(chunk, block...)
+		return true
 	})
+	if err != nil {
+		slog.Warn(cmd+": read failed", "date", date, "txid", txid, "error", err)
+	}
+	if len(chunk) > 0 {
+		flush()
+	}
+	if truncated {
+		slog.Warn(cmd+": profile truncated to profile_stream_max_bytes", "date", date, "txid", txid, "bytes", total)
+	}
+	if seq == 0 && !truncated {
+		return
+	}
+	dout.WriteByte(protocol.FLAG_HAS_NEXT)
+	pack.WritePack(dout, profileTrailer(txid, seq, total, truncated))
+}
+
+// profileTrailer is the MapPack that follows the profile of txid, sent in
+// chunks packs of bytes in total.
+func profileTrailer(txid int64, chunks int, bytes int64, truncated bool) *pack.MapPack {
+	end := &pack.MapPack{}
+	end.PutLong("txid", txid)
+	end.PutLong("chunks", int64(chunks))
+	end.PutLong("bytes", bytes)
+	if truncated {
+		end.Put("truncated", &value.BooleanValue{Value: true})
+	}
+	return end
 }
@@ -2,17 +2,24 @@ package service
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/alert"
 	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/db/heatmap"
 	"github.com/zbum/scouter-server-go/internal/db/profile"
 	"github.com/zbum/scouter-server-go/internal/db/xlog"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
 	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
 )
 
 // buildRequest serializes a MapPack into a DataInputX that handlers can read.
@@ -226,6 +233,211 @@ func TestXLogReadByGxid(t *testing.T) {
 	}
 }
 
+// TestXLogReadByGxidAcrossMidnight reads the legs of a gxid stored on both
+// sides of midnight while asking for one date.
+func TestXLogReadByGxidAcrossMidnight(t *testing.T) {
+	baseDir := t.TempDir()
+
+	writer := xlog.NewXLogWR(baseDir)
+	ctx, cancel := context.WithCancel(context.Background())
+	writer.Start(ctx)
+
+	midnight := time.Date(2026, 2, 8, 0, 0, 0, 0, time.Local)
+	gxid := int64(88003)
+	for i, endTime := range []int64{midnight.UnixMilli() - 500, midnight.UnixMilli() + 500} {
+		xp := &pack.XLogPack{EndTime: endTime, ObjHash: 100, Txid: int64(77200 + i), Gxid: gxid, Elapsed: 1000}
+		xpOut := protocol.NewDataOutputX()
+		pack.WritePack(xpOut, xp)
+		writer.Add(&xlog.XLogEntry{Time: endTime, Txid: xp.Txid, Gxid: gxid, Elapsed: xp.Elapsed, Data: xpOut.ToByteArray()})
+	}
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	writer.Close()
+
+	reader := xlog.NewXLogRD(baseDir)
+	defer reader.Close()
+	registry := NewRegistry()
+	RegisterXLogReadHandlers(registry, reader, profile.NewProfileRD(baseDir), nil, xlog.NewXLogWR(baseDir))
+
+	for _, date := range []string{"20260207", "20260208"} {
+		param := &pack.MapPack{}
+		param.PutStr("date", date)
+		param.PutLong("gxid", gxid)
+		dout := protocol.NewDataOutputX()
+		registry.Get(protocol.XLOG_READ_BY_GXID)(buildRequest(param), dout, true)
+
+		resp := protocol.NewDataInputX(dout.ToByteArray())
+		var txids []int64
+		for resp.Available() > 0 {
+			if flag, _ := resp.ReadByte(); flag != protocol.FLAG_HAS_NEXT {
+				t.Fatalf("expected FLAG_HAS_NEXT, got 0x%02x", flag)
+			}
+			pk, err := pack.ReadPack(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			txids = append(txids, pk.(*pack.XLogPack).Txid)
+		}
+		if len(txids) != 2 || txids[0] != 77200 || txids[1] != 77201 {
+			t.Errorf("date %s: txids = %v, want [77200 77201]", date, txids)
+		}
+	}
+}
+
+func TestXLogLoadByUserid(t *testing.T) {
+	baseDir := t.TempDir()
+	conf := filepath.Join(baseDir, "scouter.conf")
+	os.WriteFile(conf, []byte("xlog_userid_index_enabled=true\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(baseDir, "missing.conf")) })
+
+	writer := xlog.NewXLogWR(baseDir)
+	ctx, cancel := context.WithCancel(context.Background())
+	writer.Start(ctx)
+
+	base := time.Date(2026, 2, 7, 9, 0, 0, 0, time.Local).UnixMilli()
+	for i, xp := range []*pack.XLogPack{
+		{EndTime: base, ObjHash: 100, Userid: 555},
+		{EndTime: base + 3600_000, ObjHash: 100, Userid: 555},
+		{EndTime: base + 7200_000, ObjHash: 200, Userid: 555},
+		{EndTime: base + 7200_000, ObjHash: 100, Userid: 666},
+	} {
+		xp.Txid = int64(77300 + i)
+		xpOut := protocol.NewDataOutputX()
+		pack.WritePack(xpOut, xp)
+		writer.Add(&xlog.XLogEntry{Time: xp.EndTime, Txid: xp.Txid, Userid: xp.Userid, Data: xpOut.ToByteArray()})
+	}
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	writer.Close()
+
+	reader := xlog.NewXLogRD(baseDir)
+	defer reader.Close()
+	registry := NewRegistry()
+	RegisterXLogReadHandlers(registry, reader, profile.NewProfileRD(baseDir), nil, xlog.NewXLogWR(baseDir))
+
+	load := func(userid, stime, etime int64, max int, objHash ...int64) []int64 {
+		t.Helper()
+		param := &pack.MapPack{}
+		param.PutLong("userid", userid)
+		param.PutLong("stime", stime)
+		param.PutLong("etime", etime)
+		param.PutLong("max", int64(max))
+		if len(objHash) > 0 {
+			lv := value.NewListValue()
+			for _, h := range objHash {
+				lv.Value = append(lv.Value, value.NewDecimalValue(h))
+			}
+			param.Put("objHash", lv)
+		}
+		dout := protocol.NewDataOutputX()
+		registry.Get(protocol.XLOG_LOAD_BY_USERID)(buildRequest(param), dout, true)
+
+		resp := protocol.NewDataInputX(dout.ToByteArray())
+		var txids []int64
+		for resp.Available() > 0 {
+			resp.ReadByte()
+			pk, err := pack.ReadPack(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			txids = append(txids, pk.(*pack.XLogPack).Txid)
+		}
+		return txids
+	}
+
+	end := base + 86400_000 - 1
+	if got := load(555, base, end, 0); !slices.Equal(got, []int64{77302, 77301, 77300}) {
+		t.Errorf("all = %v, want latest first [77302 77301 77300]", got)
+	}
+	if got := load(555, base, end, 0, 100); !slices.Equal(got, []int64{77301, 77300}) {
+		t.Errorf("objHash 100 = %v", got)
+	}
+	if got := load(555, base+1, end, 1); !slices.Equal(got, []int64{77302}) {
+		t.Errorf("max 1 = %v", got)
+	}
+	if got := load(555, base+1, base+3600_000, 0); !slices.Equal(got, []int64{77301}) {
+		t.Errorf("time range = %v", got)
+	}
+	if got := load(666, base, end, 0); !slices.Equal(got, []int64{77303}) {
+		t.Errorf("user 666 = %v", got)
+	}
+	if got := load(777, base, end, 0); len(got) != 0 {
+		t.Errorf("unknown user = %v", got)
+	}
+}
+
+func TestAlertXLogs(t *testing.T) {
+	baseDir := t.TempDir()
+	base := time.Date(2026, 3, 4, 10, 0, 0, 0, time.Local).UnixMilli()
+
+	xlogWR := xlog.NewXLogWR(baseDir)
+	alertWR := alert.NewAlertWR(baseDir)
+	ctx, cancel := context.WithCancel(context.Background())
+	xlogWR.Start(ctx)
+	alertWR.Start(ctx)
+	for txid := int64(1); txid <= 3; txid++ {
+		xpOut := protocol.NewDataOutputX()
+		pack.WritePack(xpOut, &pack.XLogPack{EndTime: base - txid*1000, Txid: txid})
+		xlogWR.Add(&xlog.XLogEntry{Time: base - txid*1000, Txid: txid, Data: xpOut.ToByteArray()})
+	}
+	date := util.FormatDate(base)
+	for objHash, txids := range map[int32][]int64{11: {2, 1, 99}, 22: {3}} {
+		ap := &pack.AlertPack{Time: base, ObjHash: objHash, Title: "SLO_FAST_BURN"}
+		var refs []pack.XLogRef
+		for _, txid := range txids {
+			refs = append(refs, pack.XLogRef{Date: date, Txid: txid})
+		}
+		ap.SetXLogRefs(refs)
+		apOut := protocol.NewDataOutputX()
+		pack.WritePack(apOut, ap)
+		alertWR.Add(&alert.AlertEntry{TimeMs: base, Data: apOut.ToByteArray()})
+	}
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	xlogWR.Close()
+	alertWR.Close()
+
+	xlogRD := xlog.NewXLogRD(baseDir)
+	defer xlogRD.Close()
+	alertRD := alert.NewAlertRD(baseDir)
+	defer alertRD.Close()
+	registry := NewRegistry()
+	RegisterAlertXLogHandlers(registry, alertRD, xlogRD, xlog.NewXLogWR(baseDir))
+
+	load := func(objHash int32) []int64 {
+		t.Helper()
+		param := &pack.MapPack{}
+		param.PutLong("time", base)
+		param.PutLong("objHash", int64(objHash))
+		dout := protocol.NewDataOutputX()
+		registry.Get(protocol.ALERT_XLOGS)(buildRequest(param), dout, true)
+
+		resp := protocol.NewDataInputX(dout.ToByteArray())
+		var txids []int64
+		for resp.Available() > 0 {
+			resp.ReadByte()
+			pk, err := pack.ReadPack(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			txids = append(txids, pk.(*pack.XLogPack).Txid)
+		}
+		return txids
+	}
+
+	// Missing transactions (txid 99) are skipped.
+	if got := load(11); !slices.Equal(got, []int64{2, 1}) {
+		t.Errorf("objHash 11 = %v, want [2 1]", got)
+	}
+	if got := load(22); !slices.Equal(got, []int64{3}) {
+		t.Errorf("objHash 22 = %v, want [3]", got)
+	}
+	if got := load(33); len(got) != 0 {
+		t.Errorf("objHash 33 = %v, want none", got)
+	}
+}
+
 // TestTranxProfile writes a profile, reads it back via the TRANX_PROFILE handler.
 func TestTranxProfile(t *testing.T) {
 	baseDir := t.TempDir()
@@ -308,6 +520,157 @@ func TestTranxProfile(t *testing.T) {
 	}
 }
 
+// TestTranxProfileStream reads a profile larger than
+// profile_single_pack_max_bytes: TRANX_PROFILE returns the blocks that fit,
+// TRANX_PROFILE_STREAM returns all of them in chunks.
+func TestTranxProfileStream(t *testing.T) {
+	baseDir := t.TempDir()
+	confPath := filepath.Join(baseDir, "scouter.conf")
+	os.WriteFile(confPath, []byte("profile_single_pack_max_bytes=20\nprofile_stream_chunk_bytes=30\n"), 0644)
+	if _, err := config.Load(confPath); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.Load(filepath.Join(baseDir, "missing.conf")) })
+
+	profileWR := profile.NewProfileWR(baseDir, 1000)
+	ctx, cancel := context.WithCancel(context.Background())
+	profileWR.Start(ctx)
+	defer profileWR.Close()
+	defer cancel()
+
+	now := time.Now()
+	date := now.Format("20060102")
+	txid := int64(77001)
+	blocks := []string{"block-1:aaaaaaa", "block-2:bbbbbbb", "block-3:ccccccc"}
+	for _, b := range blocks {
+		profileWR.Add(&profile.ProfileEntry{TimeMs: now.UnixMilli(), Txid: txid, Data: []byte(b)})
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	xlogRD := xlog.NewXLogRD(baseDir)
+	defer xlogRD.Close()
+	registry := NewRegistry()
+	RegisterXLogReadHandlers(registry, xlogRD, nil, profileWR, xlog.NewXLogWR(baseDir))
+
+	call := func(cmd string, chunked bool) []pack.Pack {
+		t.Helper()
+		param := &pack.MapPack{}
+		param.PutStr("date", date)
+		param.PutLong("txid", txid)
+		if chunked {
+			param.Put("chunked", &value.BooleanValue{Value: true})
+		}
+		dout := protocol.NewDataOutputX()
+		registry.Get(cmd)(buildRequest(param), dout, true)
+		resp := protocol.NewDataInputX(dout.ToByteArray())
+		var packs []pack.Pack
+		for {
+			if flag, err := resp.ReadByte(); err != nil || flag != protocol.FLAG_HAS_NEXT {
+				return packs
+			}
+			pk, err := pack.ReadPack(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			packs = append(packs, pk)
+		}
+	}
+
+	single := call(protocol.TRANX_PROFILE, false)
+	if len(single) != 2 {
+		t.Fatalf("TRANX_PROFILE: expected the profile and a trailer, got %d packs", len(single))
+	}
+	if got := single[0].(*pack.XLogProfilePack).Profile; len(got) != len(blocks[0]) {
+		t.Fatalf("TRANX_PROFILE: expected a single block, got %q", got)
+	}
+	if end := single[1].(*pack.MapPack); !end.GetBoolean("truncated") || end.GetLong("bytes") != int64(len(blocks[0])) {
+		t.Fatalf("TRANX_PROFILE trailer: bytes=%d truncated=%v", end.GetLong("bytes"), end.GetBoolean("truncated"))
+	}
+
+	stream := call(protocol.TRANX_PROFILE_STREAM, false)
+	if len(stream) != 3 {
+		t.Fatalf("TRANX_PROFILE_STREAM: expected 2 chunks and a trailer, got %d packs", len(stream))
+	}
+	var all []byte
+	for i, pk := range stream[:2] {
+		m := pk.(*pack.MapPack)
+		if m.GetLong("seq") != int64(i) || m.GetLong("txid") != txid {
+			t.Fatalf("chunk %d: unexpected seq/txid %d/%d", i, m.GetLong("seq"), m.GetLong("txid"))
+		}
+		bv, ok := m.Get("profile").(*value.BlobValue)
+		if !ok {
+			t.Fatalf("chunk %d: profile is %T", i, m.Get("profile"))
+		}
+		all = append(all, bv.Value...)
+	}
+	for _, b := range blocks {
+		if !strings.Contains(string(all), b) {
+			t.Fatalf("stream content %q is missing %q", all, b)
+		}
+	}
+	end := stream[2].(*pack.MapPack)
+	if end.GetLong("chunks") != 2 || end.GetLong("bytes") != int64(len(all)) {
+		t.Fatalf("trailer: chunks=%d bytes=%d", end.GetLong("chunks"), end.GetLong("bytes"))
+	}
+
+	for _, chunked := range [][]pack.Pack{call(protocol.TRANX_PROFILE_CHUNKED, false), call(protocol.TRANX_PROFILE, true)} {
+		if len(chunked) != 3 {
+			t.Fatalf("TRANX_PROFILE_CHUNKED: expected 2 chunks and a trailer, got %d packs", len(chunked))
+		}
+		var got []byte
+		for _, pk := range chunked[:2] {
+			xp := pk.(*pack.XLogProfilePack)
+			if xp.Txid != txid {
+				t.Fatalf("chunk txid %d, want %d", xp.Txid, txid)
+			}
+			got = append(got, xp.Profile...)
+		}
+		if string(got) != string(all) {
+			t.Fatalf("TRANX_PROFILE_CHUNKED content %q, want %q", got, all)
+		}
+		end := chunked[2].(*pack.MapPack)
+		if end.GetLong("chunks") != 2 || end.GetLong("bytes") != int64(len(all)) || end.GetBoolean("truncated") {
+			t.Fatalf("trailer: chunks=%d bytes=%d truncated=%v", end.GetLong("chunks"), end.GetLong("bytes"), end.GetBoolean("truncated"))
+		}
+	}
+
+	// profile_stream_max_bytes stops the stream at the last whole block under it.
+	os.WriteFile(confPath, []byte("profile_stream_chunk_bytes=30\nprofile_stream_max_bytes=40\n"), 0644)
+	if _, err := config.Load(confPath); err != nil {
+		t.Fatal(err)
+	}
+	cut := call(protocol.TRANX_PROFILE_CHUNKED, false)
+	if len(cut) != 2 {
+		t.Fatalf("truncated TRANX_PROFILE_CHUNKED: expected 1 chunk and a trailer, got %d packs", len(cut))
+	}
+	if got := cut[0].(*pack.XLogProfilePack).Profile; len(got) != 2*len(blocks[0]) || !strings.Contains(string(all), string(got[:len(blocks[0])])) {
+		t.Fatalf("truncated content %q, want two whole blocks", got)
+	}
+	if end := cut[1].(*pack.MapPack); !end.GetBoolean("truncated") || end.GetLong("bytes") != 30 {
+		t.Fatalf("truncated trailer: bytes=%d truncated=%v", end.GetLong("bytes"), end.GetBoolean("truncated"))
+	}
+
+	// A first block over the limits still tells the client the profile was
+	// cut, rather than looking like no profile at all.
+	os.WriteFile(confPath, []byte("profile_single_pack_max_bytes=10\nprofile_stream_max_bytes=10\n"), 0644)
+	if _, err := config.Load(confPath); err != nil {
+		t.Fatal(err)
+	}
+	for _, cmd := range []string{protocol.TRANX_PROFILE_CHUNKED, protocol.TRANX_PROFILE_STREAM} {
+		cut := call(cmd, false)
+		if len(cut) != 1 {
+			t.Fatalf("%s over the limit: expected only a trailer, got %d packs", cmd, len(cut))
+		}
+		if end := cut[0].(*pack.MapPack); !end.GetBoolean("truncated") || end.GetLong("chunks") != 0 || end.GetLong("bytes") != 0 {
+			t.Fatalf("%s trailer: chunks=%d bytes=%d truncated=%v", cmd, end.GetLong("chunks"), end.GetLong("bytes"), end.GetBoolean("truncated"))
+		}
+	}
+	single = call(protocol.TRANX_PROFILE, false)
+	if len(single) != 2 || len(single[0].(*pack.XLogProfilePack).Profile) != 0 || !single[1].(*pack.MapPack).GetBoolean("truncated") {
+		t.Fatalf("TRANX_PROFILE over the limit: %+v", single)
+	}
+}
+
 // TestTranxProfileNotFound tests reading a profile for non-existent txid.
 func TestTranxProfileNotFound(t *testing.T) {
 	baseDir := t.TempDir()
@@ -335,6 +698,117 @@ func TestTranxProfileNotFound(t *testing.T) {
 	}
 }
 
+// TestXLogLoadByTxids reads XLogs in one request, in request order past the
+// concurrent lookups, with the profile of the one that has a profile
+// following it.
+func TestXLogLoadByTxids(t *testing.T) {
+	baseDir := t.TempDir()
+
+	writer := xlog.NewXLogWR(baseDir)
+	profileWR := profile.NewProfileWR(baseDir, 1000)
+	ctx, cancel := context.WithCancel(context.Background())
+	writer.Start(ctx)
+	profileWR.Start(ctx)
+
+	now := time.Date(2026, 2, 7, 14, 0, 0, 0, time.UTC)
+	date := now.Format("20060102")
+
+	var txids []int64
+	for txid := int64(66001); txid <= 66020; txid++ {
+		txids = append(txids, txid)
+	}
+	for _, txid := range txids {
+		xp := &pack.XLogPack{EndTime: now.UnixMilli(), ObjHash: 100, Service: 200, Txid: txid, Elapsed: 10}
+		xpOut := protocol.NewDataOutputX()
+		pack.WritePack(xpOut, xp)
+		writer.Add(&xlog.XLogEntry{Time: xp.EndTime, Txid: txid, Elapsed: xp.Elapsed, Data: xpOut.ToByteArray()})
+	}
+	profileWR.Add(&profile.ProfileEntry{TimeMs: now.UnixMilli(), Txid: 66002, Data: []byte("block1;")})
+	profileWR.Add(&profile.ProfileEntry{TimeMs: now.UnixMilli(), Txid: 66002, Data: []byte("block2;")})
+
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	writer.Close()
+	profileWR.Close()
+
+	xlogRD := xlog.NewXLogRD(baseDir)
+	defer xlogRD.Close()
+	profileWR2 := profile.NewProfileWR(baseDir, 1000)
+	defer profileWR2.Close()
+
+	registry := NewRegistry()
+	RegisterXLogReadHandlers(registry, xlogRD, nil, profileWR2, xlog.NewXLogWR(baseDir))
+	handler := registry.Get(protocol.XLOG_LOAD_BY_TXIDS)
+	if handler == nil {
+		t.Fatal("XLOG_LOAD_BY_TXIDS handler not registered")
+	}
+	// Missing txids are skipped; the others come back in request order.
+	requested := append([]int64{66001, 99999}, txids[1:]...)
+	slices.Reverse(requested[2:])
+
+	request := func(withProfile bool) []pack.Pack {
+		param := &pack.MapPack{}
+		param.PutStr("date", date)
+		param.Put("profile", &value.BooleanValue{Value: withProfile})
+		lv := value.NewListValue()
+		for _, txid := range requested {
+			lv.Value = append(lv.Value, value.NewDecimalValue(txid))
+		}
+		param.Put("txid", lv)
+
+		dout := protocol.NewDataOutputX()
+		handler(buildRequest(param), dout, true)
+
+		var packs []pack.Pack
+		respDin := protocol.NewDataInputX(dout.ToByteArray())
+		for respDin.Available() > 0 {
+			if flag, _ := respDin.ReadByte(); flag != protocol.FLAG_HAS_NEXT {
+				t.Fatalf("expected FLAG_HAS_NEXT, got 0x%02x", flag)
+			}
+			p, err := pack.ReadPack(respDin)
+			if err != nil {
+				t.Fatalf("failed to read pack: %v", err)
+			}
+			packs = append(packs, p)
+		}
+		return packs
+	}
+
+	packs := request(true)
+	if len(packs) != len(txids)+1 {
+		t.Fatalf("expected %d packs (xlogs and one profile), got %d", len(txids)+1, len(packs))
+	}
+	var got []int64
+	var pp *pack.XLogProfilePack
+	for i, p := range packs {
+		switch p := p.(type) {
+		case *pack.XLogPack:
+			got = append(got, p.Txid)
+		case *pack.XLogProfilePack:
+			if xp, ok := packs[i-1].(*pack.XLogPack); !ok || xp.Txid != p.Txid {
+				t.Errorf("profile of %d does not follow its XLog", p.Txid)
+			}
+			pp = p
+		}
+	}
+	if want := slices.DeleteFunc(slices.Clone(requested), func(txid int64) bool { return txid == 99999 }); !slices.Equal(got, want) {
+		t.Errorf("xlog order = %v, want %v", got, want)
+	}
+	if pp == nil {
+		t.Fatal("no XLogProfilePack")
+	}
+	if pp.Txid != 66002 || pp.ObjHash != 100 || pp.Service != 200 {
+		t.Errorf("profile pack = %+v", pp)
+	}
+	if profileStr := string(pp.Profile); !strings.Contains(profileStr, "block1;") || !strings.Contains(profileStr, "block2;") {
+		t.Errorf("profile = %q, want both blocks", profileStr)
+	}
+
+	if packs := request(false); len(packs) != len(txids) {
+		t.Errorf("without profile: expected %d packs, got %d", len(txids), len(packs))
+	}
+}
+
 // TestCounterPastTime writes realtime counter data, reads it back via COUNTER_PAST_TIME handler.
 func TestCounterPastTime(t *testing.T) {
 	baseDir := t.TempDir()
@@ -717,6 +1191,85 @@ func TestTranxLoadTimeGroup(t *testing.T) {
 	}
 }
 
+// TestTranxLoadTimeGroupTruncated checks that a result over the row or byte
+// cap is cut and ends with a truncated pack.
+func TestTranxLoadTimeGroupTruncated(t *testing.T) {
+	baseDir := t.TempDir()
+
+	writer := xlog.NewXLogWR(baseDir)
+	ctx, cancel := context.WithCancel(context.Background())
+	writer.Start(ctx)
+
+	now := time.Date(2026, 2, 7, 14, 0, 0, 0, time.UTC)
+	date := now.Format("20060102")
+	for i := 0; i < 3; i++ {
+		xp := &pack.XLogPack{
+			EndTime: now.UnixMilli() + int64(i*1000),
+			ObjHash: 100,
+			Txid:    int64(67000 + i),
+			Elapsed: 100,
+		}
+		xpOut := protocol.NewDataOutputX()
+		pack.WritePack(xpOut, xp)
+		writer.Add(&xlog.XLogEntry{Time: xp.EndTime, Txid: xp.Txid, Elapsed: xp.Elapsed, Data: xpOut.ToByteArray()})
+	}
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	writer.Close()
+
+	xlogRD := xlog.NewXLogRD(baseDir)
+	defer xlogRD.Close()
+	registry := NewRegistry()
+	RegisterXLogReadHandlers(registry, xlogRD, nil, nil, xlog.NewXLogWR(baseDir))
+	t.Cleanup(func() { config.Load(filepath.Join(baseDir, "missing.conf")) })
+
+	call := func(conf string) (xlogs int, trailer *pack.MapPack) {
+		t.Helper()
+		confPath := filepath.Join(baseDir, "scouter.conf")
+		os.WriteFile(confPath, []byte(conf), 0644)
+		if _, err := config.Load(confPath); err != nil {
+			t.Fatal(err)
+		}
+		param := &pack.MapPack{}
+		param.PutStr("date", date)
+		param.PutLong("stime", now.UnixMilli()-1000)
+		param.PutLong("etime", now.UnixMilli()+5000)
+		dout := protocol.NewDataOutputX()
+		registry.Get(protocol.TRANX_LOAD_TIME_GROUP)(buildRequest(param), dout, true)
+		resp := protocol.NewDataInputX(dout.ToByteArray())
+		for {
+			if flag, err := resp.ReadByte(); err != nil || flag != protocol.FLAG_HAS_NEXT {
+				return
+			}
+			pk, err := pack.ReadPack(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch p := pk.(type) {
+			case *pack.XLogPack:
+				xlogs++
+			case *pack.MapPack:
+				trailer = p
+			}
+		}
+	}
+
+	if xlogs, trailer := call("req_result_max_rows=3\n"); xlogs != 3 || trailer != nil {
+		t.Fatalf("at the cap: %d xlogs, trailer %v", xlogs, trailer)
+	}
+	xlogs, trailer := call("req_result_max_rows=2\n")
+	if xlogs != 2 || trailer == nil {
+		t.Fatalf("row cap: %d xlogs, trailer %v", xlogs, trailer)
+	}
+	if trailer.GetText("reason") != "rows" || trailer.GetLong("rows") != 2 || !trailer.GetBoolean("truncated") {
+		t.Errorf("row cap trailer: %v", trailer)
+	}
+	xlogs, trailer = call("req_result_max_bytes=1\n")
+	if xlogs != 1 || trailer == nil || trailer.GetText("reason") != "bytes" || trailer.GetLong("bytes") <= 0 {
+		t.Fatalf("byte cap: %d xlogs, trailer %v", xlogs, trailer)
+	}
+}
+
 // TestCounterPastTimeAll tests reading realtime counter for all live objects of a type.
 func TestCounterPastTimeAll(t *testing.T) {
 	baseDir := t.TempDir()
@@ -896,3 +1449,57 @@ func TestCounterPastDateAll(t *testing.T) {
 		t.Errorf("expected 2 result packs (one per object), got %d", count)
 	}
 }
+
+func TestXLogHeatmap(t *testing.T) {
+	db := heatmap.NewDB(t.TempDir())
+	base := time.Date(2026, 1, 5, 10, 0, 0, 0, time.Local)
+	db.Add("tomcat", base.UnixMilli(), 5, false)
+	db.Add("tomcat", base.Add(time.Minute).UnixMilli(), 5, true)
+	db.Add("nodejs", base.UnixMilli(), 500, false)
+	db.Add("tomcat", base.AddDate(0, 0, 1).UnixMilli(), 5, false)
+	db.Flush()
+
+	registry := NewRegistry()
+	RegisterHeatmapHandlers(registry, db)
+
+	read := func(objType string) *pack.MapPack {
+		param := &pack.MapPack{}
+		param.PutLong("stime", base.Add(-time.Hour).UnixMilli())
+		param.PutLong("etime", base.Add(time.Hour).UnixMilli())
+		if objType != "" {
+			param.PutStr("objType", objType)
+		}
+		in := protocol.NewDataOutputX()
+		pack.WritePack(in, param)
+		out := protocol.NewDataOutputX()
+		registry.Get(protocol.XLOG_HEATMAP)(protocol.NewDataInputX(in.ToByteArray()), out, true)
+		din := protocol.NewDataInputX(out.ToByteArray())
+		if flag, _ := din.ReadByte(); flag != protocol.FLAG_HAS_NEXT {
+			t.Fatalf("no response")
+		}
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pk.(*pack.MapPack)
+	}
+
+	resp := read("")
+	times := resp.GetList("time")
+	if times == nil || len(times.Value) != 1 || times.GetLong(0) != base.UnixMilli() {
+		t.Fatalf("time = %v", times)
+	}
+	counts := resp.GetList("count").Value[0].(*value.ListValue)
+	if counts.GetLong(heatmap.Bucket(5)) != 2 || counts.GetLong(heatmap.Bucket(500)) != 1 {
+		t.Errorf("counts = %v", counts)
+	}
+	errs := resp.GetList("error").Value[0].(*value.ListValue)
+	if errs.GetLong(heatmap.Bucket(5)) != 1 {
+		t.Errorf("errors = %v", errs)
+	}
+
+	counts = read("nodejs").GetList("count").Value[0].(*value.ListValue)
+	if counts.GetLong(heatmap.Bucket(5)) != 0 || counts.GetLong(heatmap.Bucket(500)) != 1 {
+		t.Errorf("nodejs counts = %v", counts)
+	}
+}
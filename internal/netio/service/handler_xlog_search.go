@@ -0,0 +1,290 @@
+package service
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/text"
+	"github.com/zbum/scouter-server-go/internal/db/xlog"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// strMatch matches a text against a pattern in which '*' stands for any
+// run of characters, like Java's StrMatch. The empty pattern matches all.
+type strMatch []string
+
+func newStrMatch(pattern string) strMatch {
+	if pattern == "" {
+		return nil
+	}
+	return strings.Split(pattern, "*")
+}
+
+func (m strMatch) include(s string) bool {
+	if m == nil {
+		return true
+	}
+	if len(m) == 1 {
+		return s == m[0]
+	}
+	if !strings.HasPrefix(s, m[0]) {
+		return false
+	}
+	s = s[len(m[0]):]
+	last := m[len(m)-1]
+	for _, part := range m[1 : len(m)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, last)
+}
+
+// ipMatch matches an XLog client address against a CIDR prefix
+// (10.0.0.0/8), an inclusive range (10.0.0.1-10.0.0.99) or a strMatch
+// pattern of the dotted form (192.168.*).
+type ipMatch struct {
+	prefix   netip.Prefix
+	from, to netip.Addr
+	pattern  strMatch
+}
+
+func newIPMatch(spec string) ipMatch {
+	spec = strings.TrimSpace(spec)
+	if p, err := netip.ParsePrefix(spec); err == nil {
+		return ipMatch{prefix: p.Masked()}
+	}
+	if a, b, ok := strings.Cut(spec, "-"); ok {
+		from, err1 := netip.ParseAddr(strings.TrimSpace(a))
+		to, err2 := netip.ParseAddr(strings.TrimSpace(b))
+		if err1 == nil && err2 == nil {
+			return ipMatch{from: from.Unmap(), to: to.Unmap()}
+		}
+	}
+	return ipMatch{pattern: newStrMatch(spec)}
+}
+
+func (m ipMatch) include(ip []byte) bool {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+	switch {
+	case m.prefix.IsValid():
+		return m.prefix.Contains(addr)
+	case m.from.IsValid():
+		return addr.BitLen() == m.from.BitLen() && m.from.Compare(addr) <= 0 && addr.Compare(m.to) <= 0
+	}
+	return m.pattern.include(addr.String())
+}
+
+// xlogSearch is the filter of a SEARCH_XLOG_LIST request.
+type xlogSearch struct {
+	objHash   int32
+	services  map[int32]bool // service hashes; empty means all
+	service   strMatch
+	errorOnly bool
+	ip        *ipMatch
+	login     strMatch
+	desc      strMatch
+	userAgent strMatch
+	texts     [5]strMatch // text1 to text5
+}
+
+func newXLogSearch(param *pack.MapPack) *xlogSearch {
+	s := &xlogSearch{
+		objHash:   param.GetInt("objHash"),
+		services:  make(map[int32]bool),
+		service:   newStrMatch(param.GetText("service")),
+		errorOnly: param.GetBoolean("error"),
+		login:     newStrMatch(param.GetText("login")),
+		desc:      newStrMatch(param.GetText("desc")),
+		userAgent: newStrMatch(param.GetText("userAgent")),
+	}
+	switch v := param.Get("serviceHash").(type) {
+	case *value.ListValue:
+		for _, e := range v.Value {
+			if dv, ok := e.(*value.DecimalValue); ok {
+				s.services[int32(dv.Value)] = true
+			}
+		}
+	case *value.DecimalValue:
+		s.services[int32(v.Value)] = true
+	}
+	if ip := param.GetText("ip"); ip != "" {
+		m := newIPMatch(ip)
+		s.ip = &m
+	}
+	for i := range s.texts {
+		s.texts[i] = newStrMatch(param.GetText("text" + strconv.Itoa(i+1)))
+	}
+	return s
+}
+
+// selection returns the objects or services the search is restricted to,
+// for the XLog secondary index.
+func (s *xlogSearch) selection() xlog.Selection {
+	var sel xlog.Selection
+	if s.objHash != 0 {
+		sel.ObjHashes = []int32{s.objHash}
+	}
+	for h := range s.services {
+		sel.Services = append(sel.Services, h)
+	}
+	return sel
+}
+
+// needsPack reports whether matching needs more than the objHash of an XLog.
+func (s *xlogSearch) needsPack() bool {
+	if len(s.services) > 0 || s.service != nil || s.errorOnly || s.ip != nil ||
+		s.login != nil || s.desc != nil || s.userAgent != nil {
+		return true
+	}
+	for _, m := range s.texts {
+		if m != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether xp passes the filter; text resolves text hashes.
+func (s *xlogSearch) match(xp *pack.XLogPack, text func(div string, hash int32) string) bool {
+	if s.objHash != 0 && xp.ObjHash != s.objHash {
+		return false
+	}
+	if len(s.services) > 0 && !s.services[xp.Service] {
+		return false
+	}
+	if s.errorOnly && xp.Error == 0 {
+		return false
+	}
+	if s.ip != nil && !s.ip.include(xp.IPAddr) {
+		return false
+	}
+	if s.service != nil && !s.service.include(text("service", xp.Service)) {
+		return false
+	}
+	if s.login != nil && !s.login.include(text("login", xp.Login)) {
+		return false
+	}
+	if s.desc != nil && !s.desc.include(text("desc", xp.Desc)) {
+		return false
+	}
+	if s.userAgent != nil && !s.userAgent.include(text("ua", xp.UserAgent)) {
+		return false
+	}
+	for i, v := range [5]string{xp.Text1, xp.Text2, xp.Text3, xp.Text4, xp.Text5} {
+		if s.texts[i] != nil && !s.texts[i].include(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// RegisterXLogSearchHandlers registers SEARCH_XLOG_LIST. Text hashes are
+// resolved as GET_TEXT_100 does, then from the daily text of the XLog's day.
+func RegisterXLogSearchHandlers(r *Registry, xlogRD *xlog.XLogRD, xlogWR *xlog.XLogWR, textCache *cache.TextCache, textRD *text.TextRD, textWR *text.TextWR) {
+	r.Require(login.PermRead,
+		protocol.SEARCH_XLOG_LIST,
+	)
+
+	// SEARCH_XLOG_LIST: search XLogs by time range, like Java's XLog search.
+	// Param: "stime", "etime" and optionally "objHash", "serviceHash" (hash
+	// or list of hashes), "service", "login", "desc", "userAgent", "text1"
+	// to "text5" (patterns with '*' wildcards, matched against the texts),
+	// "ip" (CIDR prefix, from-to range or pattern) and "error" (only XLogs
+	// with an error). At most req_search_xlog_max_count XLogs are returned.
+	r.Register(protocol.SEARCH_XLOG_LIST, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		stime := param.GetLong("stime")
+		etime := param.GetLong("etime")
+		search := newXLogSearch(param)
+		needsPack := search.needsPack()
+
+		// req_search_xlog_max_count: limit max results
+		maxCount := 0
+		if cfg := config.Get(); cfg != nil {
+			maxCount = cfg.ReqSearchXLogMaxCount()
+		}
+		cnt := 0
+		guard := newResultGuard(protocol.SEARCH_XLOG_LIST, dout)
+
+		var date string
+		known := make(map[string]map[int32]string)
+		resolve := func(div string, hash int32) string {
+			if hash == 0 {
+				return ""
+			}
+			m := known[div]
+			if m == nil {
+				m = make(map[int32]string)
+				known[div] = m
+			}
+			if txt, ok := m[hash]; ok {
+				return txt
+			}
+			txt, found := resolveText(textCache, textWR, textRD, div, hash)
+			if !found && textRD != nil {
+				txt, _ = textRD.GetDailyString(date, div, hash)
+			}
+			m[hash] = txt
+			return txt
+		}
+
+		searchHandler := func(data []byte) bool {
+			if maxCount > 0 && cnt >= maxCount {
+				return false
+			}
+			if needsPack {
+				p, err := pack.ReadPack(protocol.NewDataInputX(data))
+				if err != nil {
+					return true
+				}
+				xp, ok := p.(*pack.XLogPack)
+				if !ok || !search.match(xp, resolve) {
+					return true
+				}
+			} else if search.objHash != 0 {
+				packObjHash, _, err := pack.ReadXLogFilterFields(data)
+				if err != nil || packObjHash != search.objHash {
+					return true
+				}
+			}
+			if !guard.next() {
+				return false
+			}
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			dout.Write(data)
+			dout.Flush()
+			cnt++
+			return true
+		}
+
+		sel := search.selection()
+		for _, d := range splitDays(stime, etime) {
+			if guard.truncated() {
+				break
+			}
+			date = d.date
+			known = make(map[string]map[int32]string) // daily texts differ by day
+			if found, _ := xlogWR.ReadSelected(d.date, d.stime, d.etime, sel, false, searchHandler); !found {
+				xlogRD.ReadSelected(d.date, d.stime, d.etime, sel, false, searchHandler)
+			}
+		}
+		guard.finish()
+	})
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/xlog"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+func TestStrMatch(t *testing.T) {
+	for _, c := range []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"", "anything", true},
+		{"/order", "/order", true},
+		{"/order", "/order/new", false},
+		{"/order*", "/order/new", true},
+		{"*new", "/order/new", true},
+		{"*der*", "/order/new", true},
+		{"/o*r*w", "/order/new", true},
+		{"ab*ba", "aba", false},
+		{"*", "", true},
+	} {
+		if got := newStrMatch(c.pattern).include(c.s); got != c.want {
+			t.Errorf("%q matching %q = %v", c.pattern, c.s, got)
+		}
+	}
+	for _, c := range []struct {
+		spec string
+		ip   []byte
+		want bool
+	}{
+		{"10.0.0.0/8", []byte{10, 1, 2, 3}, true},
+		{"10.0.0.0/8", []byte{11, 1, 2, 3}, false},
+		{"192.168.0.10-192.168.0.20", []byte{192, 168, 0, 15}, true},
+		{"192.168.0.10-192.168.0.20", []byte{192, 168, 0, 21}, false},
+		{"192.168.*", []byte{192, 168, 7, 7}, true},
+		{"192.168.*", nil, false},
+	} {
+		if got := newIPMatch(c.spec).include(c.ip); got != c.want {
+			t.Errorf("%q matching %v = %v", c.spec, c.ip, got)
+		}
+	}
+}
+
+// TestSearchXLogList writes XLogs with various fields and searches them via
+// the SEARCH_XLOG_LIST handler.
+func TestSearchXLogList(t *testing.T) {
+	baseDir := t.TempDir()
+	writer := xlog.NewXLogWR(baseDir)
+
+	now := time.Date(2026, 2, 7, 14, 0, 0, 0, time.UTC)
+	xlogs := []*pack.XLogPack{
+		{Service: 1, ObjHash: 100, IPAddr: []byte{10, 0, 0, 1}, Login: 11, UserAgent: 21},
+		{Service: 2, ObjHash: 100, IPAddr: []byte{10, 0, 0, 2}, Error: 5, Desc: 31},
+		{Service: 1, ObjHash: 200, IPAddr: []byte{172, 16, 0, 1}, Login: 12, Text1: "tenant-a"},
+		{Service: 3, ObjHash: 200, IPAddr: []byte{10, 0, 1, 1}, Error: 7, Text1: "tenant-b"},
+	}
+	for i, xp := range xlogs {
+		xp.EndTime = now.UnixMilli() + int64(i*1000)
+		xp.Txid = int64(1000 + i)
+		o := protocol.NewDataOutputX()
+		pack.WritePack(o, xp)
+		writer.Add(&xlog.XLogEntry{Time: xp.EndTime, Txid: xp.Txid, ObjHash: xp.ObjHash, Service: xp.Service, Data: o.ToByteArray()})
+	}
+	writer.Drain()
+	defer writer.Close()
+
+	textCache := cache.NewTextCache()
+	for div, texts := range map[string]map[int32]string{
+		"service": {1: "/order/new", 2: "/order/list", 3: "/home"},
+		"login":   {11: "alice", 12: "bob"},
+		"ua":      {21: "Mozilla/5.0 (iPhone)"},
+		"desc":    {31: "batch import"},
+	} {
+		for hash, text := range texts {
+			textCache.Put(div, hash, text)
+		}
+	}
+	xlogRD := xlog.NewXLogRD(baseDir)
+	defer xlogRD.Close()
+	registry := NewRegistry()
+	RegisterXLogSearchHandlers(registry, xlogRD, writer, textCache, nil, nil)
+
+	search := func(set func(p *pack.MapPack)) []int64 {
+		t.Helper()
+		param := &pack.MapPack{}
+		param.PutLong("stime", now.UnixMilli()-1000)
+		param.PutLong("etime", now.UnixMilli()+10000)
+		set(param)
+		dout := protocol.NewDataOutputX()
+		registry.Get(protocol.SEARCH_XLOG_LIST)(buildRequest(param), dout, true)
+		resp := protocol.NewDataInputX(dout.ToByteArray())
+		var txids []int64
+		for {
+			if flag, err := resp.ReadByte(); err != nil || flag != protocol.FLAG_HAS_NEXT {
+				return txids
+			}
+			pk, err := pack.ReadPack(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			txids = append(txids, pk.(*pack.XLogPack).Txid)
+		}
+	}
+	for name, c := range map[string]struct {
+		set  func(p *pack.MapPack)
+		want []int64
+	}{
+		"all":         {func(p *pack.MapPack) {}, []int64{1000, 1001, 1002, 1003}},
+		"objHash":     {func(p *pack.MapPack) { p.PutLong("objHash", 200) }, []int64{1002, 1003}},
+		"service":     {func(p *pack.MapPack) { p.PutStr("service", "/order*") }, []int64{1000, 1001, 1002}},
+		"serviceHash": {func(p *pack.MapPack) { p.Put("serviceHash", hashList([]int32{2, 3})) }, []int64{1001, 1003}},
+		"error":       {func(p *pack.MapPack) { p.Put("error", &value.BooleanValue{Value: true}) }, []int64{1001, 1003}},
+		"ip range":    {func(p *pack.MapPack) { p.PutStr("ip", "10.0.0.0/24") }, []int64{1000, 1001}},
+		"login":       {func(p *pack.MapPack) { p.PutStr("login", "bo*") }, []int64{1002}},
+		"userAgent":   {func(p *pack.MapPack) { p.PutStr("userAgent", "*iPhone*") }, []int64{1000}},
+		"desc":        {func(p *pack.MapPack) { p.PutStr("desc", "batch*") }, []int64{1001}},
+		"text1":       {func(p *pack.MapPack) { p.PutStr("text1", "tenant-b") }, []int64{1003}},
+		"combined": {func(p *pack.MapPack) {
+			p.PutStr("service", "/order*")
+			p.PutStr("ip", "10.0.0.1-10.0.0.9")
+			p.Put("error", &value.BooleanValue{Value: true})
+		}, []int64{1001}},
+	} {
+		got := search(c.set)
+		if len(got) != len(c.want) {
+			t.Errorf("%s: got %v, want %v", name, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: got %v, want %v", name, got, c.want)
+				break
+			}
+		}
+	}
+}
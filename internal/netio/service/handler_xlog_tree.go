@@ -0,0 +1,243 @@
+package service
+
+import (
+	"cmp"
+	"sort"
+
+	"github.com/zbum/scouter-server-go/internal/db/xlog"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+const (
+	// callTreeMaxNodes bounds the transactions returned by XLOG_CALL_TREE.
+	callTreeMaxNodes = 2000
+	// callTreeMaxCallerHops bounds the caller lookups by txid for legs whose
+	// caller was not found through the gxid index.
+	callTreeMaxCallerHops = 32
+)
+
+// callNode is one transaction of a call tree.
+type callNode struct {
+	xp       *pack.XLogPack
+	children []*callNode
+}
+
+// RegisterXLogCallTreeHandlers registers the distributed call tree handler.
+func RegisterXLogCallTreeHandlers(r *Registry, xlogRD *xlog.XLogRD, xlogWR *xlog.XLogWR) {
+	r.Require(login.PermRead,
+		protocol.XLOG_CALL_TREE,
+	)
+
+	// XLOG_CALL_TREE: the distributed call tree a transaction belongs to.
+	// The legs sharing its gxid are read from "date" and the adjacent days
+	// (see xlog.GxidDates), and callers missing from them are looked up by
+	// txid, so trees crossing midnight or legs without a gxid are complete.
+	// Param: "date", "txid".
+	// Response: a MapPack with "gxid" (of the first root), "count" and
+	// "truncated", then one MapPack per transaction in call order (depth
+	// first, callees by start time) with "txid", "caller", "gxid", "objHash",
+	// "service", "endTime", "elapsed", "selfElapsed" (elapsed not spent in
+	// callees), "error", "depth" (0 for roots) and "date". Nothing is
+	// returned if the txid is not found.
+	r.Register(protocol.XLOG_CALL_TREE, func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, err := pack.ReadPack(din)
+		if err != nil {
+			return
+		}
+		param := pk.(*pack.MapPack)
+		date := param.GetText("date")
+		txid := param.GetLong("txid")
+
+		start := findXLog(xlogRD, xlogWR, txid, date)
+		if start == nil {
+			return
+		}
+		xlogs, truncated := collectCallTree(xlogRD, xlogWR, start, date)
+		roots := buildCallTree(xlogs)
+		root := roots[0].xp
+
+		header := &pack.MapPack{}
+		header.PutLong("gxid", cmp.Or(root.Gxid, root.Txid))
+		header.PutLong("count", int64(len(xlogs)))
+		header.Put("truncated", &value.BooleanValue{Value: truncated})
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, header)
+
+		walkCallTree(roots, func(n *callNode, depth int) {
+			xp := n.xp
+			self := int64(xp.Elapsed)
+			for _, c := range n.children {
+				self -= int64(c.xp.Elapsed)
+			}
+			m := &pack.MapPack{}
+			m.PutLong("txid", xp.Txid)
+			m.PutLong("caller", xp.Caller)
+			m.PutLong("gxid", xp.Gxid)
+			m.PutLong("objHash", int64(xp.ObjHash))
+			m.PutLong("service", int64(xp.Service))
+			m.PutLong("endTime", xp.EndTime)
+			m.PutLong("elapsed", int64(xp.Elapsed))
+			// Async callees may outlast their caller.
+			m.PutLong("selfElapsed", max(self, 0))
+			m.PutLong("error", int64(xp.Error))
+			m.PutLong("depth", int64(depth))
+			m.PutStr("date", util.FormatDate(xp.EndTime))
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, m)
+		})
+	})
+}
+
+// collectCallTree gathers the transactions related to start: the legs of its
+// gxid (its own txid for a root without one), then the callers not among them,
+// following their gxids in turn. It reports whether callTreeMaxNodes cut the
+// result short.
+func collectCallTree(xlogRD *xlog.XLogRD, xlogWR *xlog.XLogWR, start *pack.XLogPack, date string) ([]*pack.XLogPack, bool) {
+	byTxid := map[int64]*pack.XLogPack{start.Txid: start}
+	result := []*pack.XLogPack{start}
+	truncated := false
+	add := func(xp *pack.XLogPack) {
+		if _, ok := byTxid[xp.Txid]; ok {
+			return
+		}
+		if len(result) >= callTreeMaxNodes {
+			truncated = true
+			return
+		}
+		byTxid[xp.Txid] = xp
+		result = append(result, xp)
+	}
+
+	readGxids := make(map[int64]bool)
+	readGxid := func(gxid int64, dates ...string) {
+		if gxid == 0 || readGxids[gxid] {
+			return
+		}
+		readGxids[gxid] = true
+		handler := func(data []byte) {
+			if xp := decodeXLog(data); xp != nil {
+				add(xp)
+			}
+		}
+		missing, _ := xlogWR.ReadByGxidDates(xlog.GxidDates(dates...), gxid, handler)
+		xlogRD.ReadByGxidDates(missing, gxid, handler)
+	}
+
+	readGxid(cmp.Or(start.Gxid, start.Txid), date, util.FormatDate(start.EndTime))
+
+	lookedUp := make(map[int64]bool)
+	for hop := 0; hop < callTreeMaxCallerHops && !truncated; hop++ {
+		var callers []*pack.XLogPack
+		for _, xp := range result {
+			if xp.Caller == 0 || lookedUp[xp.Caller] {
+				continue
+			}
+			if _, ok := byTxid[xp.Caller]; ok {
+				continue
+			}
+			lookedUp[xp.Caller] = true
+			if c := findXLog(xlogRD, xlogWR, xp.Caller, util.FormatDate(xp.EndTime)); c != nil {
+				callers = append(callers, c)
+			}
+		}
+		if len(callers) == 0 {
+			break
+		}
+		for _, c := range callers {
+			add(c)
+			readGxid(c.Gxid, util.FormatDate(c.EndTime))
+		}
+	}
+	return result, truncated
+}
+
+// findXLog returns the XLog of txid stored on date or, for transactions
+// ending across midnight, on the adjacent days.
+func findXLog(xlogRD *xlog.XLogRD, xlogWR *xlog.XLogWR, txid int64, date string) *pack.XLogPack {
+	dates := xlog.GxidDates(date)
+	// The given date first; adjacent days only if it is not there.
+	sort.SliceStable(dates, func(i, j int) bool { return dates[i] == date && dates[j] != date })
+	for _, d := range dates {
+		data, found, err := xlogWR.GetByTxid(d, txid)
+		if !found {
+			data, err = xlogRD.GetByTxid(d, txid)
+		}
+		if err == nil && data != nil {
+			return decodeXLog(data)
+		}
+	}
+	return nil
+}
+
+func decodeXLog(data []byte) *pack.XLogPack {
+	p, err := pack.ReadPack(protocol.NewDataInputX(data))
+	if err != nil {
+		return nil
+	}
+	xp, _ := p.(*pack.XLogPack)
+	return xp
+}
+
+// buildCallTree links xlogs by caller txid and returns the roots: the
+// transactions whose caller is not among them. A caller cycle, which only
+// corrupt txids can produce, is broken at the transaction closing it.
+func buildCallTree(xlogs []*pack.XLogPack) []*callNode {
+	nodes := make(map[int64]*callNode, len(xlogs))
+	for _, xp := range xlogs {
+		nodes[xp.Txid] = &callNode{xp: xp}
+	}
+	parentOf := make(map[int64]int64, len(xlogs))
+	reaches := func(from, txid int64) bool {
+		for i := 0; i <= len(parentOf); i++ {
+			if from == txid {
+				return true
+			}
+			next, ok := parentOf[from]
+			if !ok {
+				return false
+			}
+			from = next
+		}
+		return false
+	}
+	var roots []*callNode
+	for _, xp := range xlogs {
+		n := nodes[xp.Txid]
+		if parent, ok := nodes[xp.Caller]; ok && !reaches(xp.Caller, xp.Txid) {
+			parent.children = append(parent.children, n)
+			parentOf[xp.Txid] = xp.Caller
+		} else {
+			roots = append(roots, n)
+		}
+	}
+	sortByStart(roots)
+	for _, n := range nodes {
+		sortByStart(n.children)
+	}
+	return roots
+}
+
+// walkCallTree visits the nodes depth first.
+func walkCallTree(roots []*callNode, visit func(n *callNode, depth int)) {
+	var walk func(n *callNode, depth int)
+	walk = func(n *callNode, depth int) {
+		visit(n, depth)
+		for _, c := range n.children {
+			walk(c, depth+1)
+		}
+	}
+	for _, n := range roots {
+		walk(n, 0)
+	}
+}
+
+func sortByStart(nodes []*callNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := nodes[i].xp, nodes[j].xp
+		return a.EndTime-int64(a.Elapsed) < b.EndTime-int64(b.Elapsed)
+	})
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/xlog"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// TestXLogCallTree resolves a tree whose root ends after midnight and whose
+// leaf has no gxid, starting from the leaf.
+func TestXLogCallTree(t *testing.T) {
+	baseDir := t.TempDir()
+
+	writer := xlog.NewXLogWR(baseDir)
+	ctx, cancel := context.WithCancel(context.Background())
+	writer.Start(ctx)
+
+	midnight := time.Date(2026, 2, 8, 0, 0, 0, 0, time.Local).UnixMilli()
+	for _, xp := range []*pack.XLogPack{
+		{EndTime: midnight + 500, ObjHash: 1, Txid: 10, Gxid: 10, Elapsed: 2000},
+		{EndTime: midnight - 500, ObjHash: 2, Txid: 20, Caller: 10, Gxid: 10, Elapsed: 800},
+		{EndTime: midnight - 700, ObjHash: 3, Txid: 30, Caller: 20, Elapsed: 200},
+		{EndTime: midnight + 300, ObjHash: 2, Txid: 21, Caller: 10, Gxid: 10, Elapsed: 300},
+		{EndTime: midnight - 900, ObjHash: 9, Txid: 99, Gxid: 77, Elapsed: 10},
+	} {
+		xpOut := protocol.NewDataOutputX()
+		pack.WritePack(xpOut, xp)
+		writer.Add(&xlog.XLogEntry{Time: xp.EndTime, Txid: xp.Txid, Gxid: xp.Gxid, Elapsed: xp.Elapsed, Data: xpOut.ToByteArray()})
+	}
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	writer.Close()
+
+	reader := xlog.NewXLogRD(baseDir)
+	defer reader.Close()
+	registry := NewRegistry()
+	RegisterXLogCallTreeHandlers(registry, reader, xlog.NewXLogWR(baseDir))
+
+	param := &pack.MapPack{}
+	param.PutStr("date", "20260207")
+	param.PutLong("txid", 30)
+	dout := protocol.NewDataOutputX()
+	registry.Get(protocol.XLOG_CALL_TREE)(buildRequest(param), dout, true)
+
+	packs := readMapPacks(t, dout)
+	if len(packs) != 5 {
+		t.Fatalf("got %d packs, want header and 4 nodes", len(packs))
+	}
+	if packs[0].GetLong("gxid") != 10 || packs[0].GetLong("count") != 4 || packs[0].GetBoolean("truncated") {
+		t.Errorf("header = %v", packs[0])
+	}
+	want := []struct{ txid, depth, self int64 }{
+		{10, 0, 900}, // 2000 - 800 - 300
+		{20, 1, 600},
+		{30, 2, 200},
+		{21, 1, 300},
+	}
+	for i, w := range want {
+		n := packs[i+1]
+		if n.GetLong("txid") != w.txid || n.GetLong("depth") != w.depth || n.GetLong("selfElapsed") != w.self {
+			t.Errorf("node %d: txid %d depth %d self %d, want %+v",
+				i, n.GetLong("txid"), n.GetLong("depth"), n.GetLong("selfElapsed"), w)
+		}
+	}
+	if packs[1].GetText("date") != "20260208" || packs[3].GetText("date") != "20260207" {
+		t.Errorf("dates = %s, %s", packs[1].GetText("date"), packs[3].GetText("date"))
+	}
+
+	// Unknown txid: no response.
+	param.PutLong("txid", 12345)
+	dout = protocol.NewDataOutputX()
+	registry.Get(protocol.XLOG_CALL_TREE)(buildRequest(param), dout, true)
+	if len(dout.ToByteArray()) != 0 {
+		t.Error("expected an empty response for an unknown txid")
+	}
+}
+
+func TestBuildCallTree_Cycle(t *testing.T) {
+	roots := buildCallTree([]*pack.XLogPack{
+		{Txid: 1, Caller: 2, EndTime: 100},
+		{Txid: 2, Caller: 1, EndTime: 200},
+		{Txid: 3, Caller: 3, EndTime: 300},
+	})
+	var visited []int64
+	walkCallTree(roots, func(n *callNode, depth int) { visited = append(visited, n.xp.Txid) })
+	if len(visited) != 3 {
+		t.Errorf("visited %v, want every node once", visited)
+	}
+}
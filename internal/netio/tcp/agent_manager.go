@@ -5,6 +5,8 @@ import (
 	"log/slog"
 	"sync"
 	"time"
+
+	"github.com/zbum/scouter-server-go/internal/objalias"
 )
 
 const (
@@ -25,6 +27,7 @@ type AgentManager struct {
 	keepaliveInterval time.Duration
 	keepaliveTimeout  time.Duration
 	getConnWait       time.Duration
+	alias             *objalias.Manager
 }
 
 type agentQueue struct {
@@ -174,12 +177,31 @@ func (m *AgentManager) Add(objHash int32, worker *AgentWorker) {
 	q.put(worker, m.maxConnsPerAgent)
 }
 
-// Get retrieves an available agent connection, waiting if necessary.
-func (m *AgentManager) Get(objHash int32) *AgentWorker {
+// SetAlias installs a so an alias objHash reaches the agents behind it.
+func (m *AgentManager) SetAlias(a *objalias.Manager) {
+	m.alias = a
+}
+
+// queue returns the connections of objHash, or of an agent aliased to it.
+func (m *AgentManager) queue(objHash int32) (*agentQueue, bool) {
 	m.mu.Lock()
-	q, ok := m.agents[objHash]
-	m.mu.Unlock()
+	defer m.mu.Unlock()
+	if q, ok := m.agents[objHash]; ok {
+		return q, true
+	}
+	if m.alias != nil {
+		for _, h := range m.alias.Agents(objHash) {
+			if q, ok := m.agents[h]; ok && q.size() > 0 {
+				return q, true
+			}
+		}
+	}
+	return nil, false
+}
 
+// Get retrieves an available agent connection, waiting if necessary.
+func (m *AgentManager) Get(objHash int32) *AgentWorker {
+	q, ok := m.queue(objHash)
 	if !ok {
 		return nil
 	}
@@ -188,9 +210,7 @@ func (m *AgentManager) Get(objHash int32) *AgentWorker {
 
 // HasAgent checks if there's at least one connection for the given objHash.
 func (m *AgentManager) HasAgent(objHash int32) bool {
-	m.mu.Lock()
-	q, ok := m.agents[objHash]
-	m.mu.Unlock()
+	q, ok := m.queue(objHash)
 	if !ok {
 		return false
 	}
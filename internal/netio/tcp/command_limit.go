@@ -0,0 +1,93 @@
+package tcp
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+)
+
+// commandLimiter caps how many requests of a command run at once across all
+// client connections, as set by net_tcp_command_concurrency, so a few
+// clients issuing large range scans cannot tie up every connection slot of
+// the service pool. Requests over the cap wait on their own connection;
+// LOGIN and the other session-free commands are never limited.
+type commandLimiter struct {
+	mu   sync.Mutex
+	spec string
+	// slots holds one semaphore per limited command. A changed setting
+	// replaces them; requests running under the old ones release those.
+	slots map[string]chan struct{}
+}
+
+func newCommandLimiter() *commandLimiter {
+	return &commandLimiter{}
+}
+
+// acquire waits for a slot of cmd and returns the function releasing it. It
+// reports false if ctx ended first.
+func (l *commandLimiter) acquire(ctx context.Context, cfg *config.Config, cmd string) (func(), bool) {
+	if protocol.FreeCmds[cmd] {
+		return func() {}, true
+	}
+	l.mu.Lock()
+	l.updateLocked(cfg)
+	sem := l.slots[cmd]
+	l.mu.Unlock()
+	if sem == nil {
+		return func() {}, true
+	}
+
+	select {
+	case sem <- struct{}{}:
+	default:
+		slog.Debug("TCP command waiting for a slot", "cmd", cmd, "limit", cap(sem))
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+	return func() { <-sem }, true
+}
+
+// updateLocked re-parses net_tcp_command_concurrency when it changes.
+// Caller must hold l.mu.
+func (l *commandLimiter) updateLocked(cfg *config.Config) {
+	spec := ""
+	if cfg != nil {
+		spec = cfg.NetTcpCommandConcurrency()
+	}
+	if spec == l.spec && l.slots != nil {
+		return
+	}
+	l.spec = spec
+	l.slots = make(map[string]chan struct{})
+	for cmd, limit := range parseCommandLimits(spec) {
+		l.slots[cmd] = make(chan struct{}, limit)
+	}
+}
+
+// parseCommandLimits returns the "COMMAND:limit" entries of spec.
+func parseCommandLimits(spec string) map[string]int {
+	limits := make(map[string]int)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		cmd, limitStr, ok := strings.Cut(entry, ":")
+		limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+		cmd = strings.TrimSpace(cmd)
+		if !ok || err != nil || limit <= 0 || cmd == "" {
+			slog.Warn("TCP command concurrency: bad entry ignored", "entry", entry)
+			continue
+		}
+		limits[cmd] = limit
+	}
+	return limits
+}
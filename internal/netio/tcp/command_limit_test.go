@@ -0,0 +1,85 @@
+package tcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+)
+
+func TestCommandLimiter(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("net_tcp_command_concurrency=TRANX_LOAD_TIME_GROUP:1, LOGIN:1, bad, XLOG_READ_BY_TXID:x\n"), 0644)
+	cfg, err := config.Load(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	l := newCommandLimiter()
+	ctx := context.Background()
+	release, ok := l.acquire(ctx, cfg, protocol.TRANX_LOAD_TIME_GROUP)
+	if !ok {
+		t.Fatal("first request refused")
+	}
+
+	// The second waits until the first is done.
+	acquired := make(chan func())
+	go func() {
+		r, _ := l.acquire(ctx, cfg, protocol.TRANX_LOAD_TIME_GROUP)
+		acquired <- r
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second request ran over the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Unlisted and session-free commands are not limited.
+	for _, cmd := range []string{protocol.XLOG_READ_BY_TXID, protocol.LOGIN, protocol.LOGIN} {
+		if _, ok := l.acquire(ctx, cfg, cmd); !ok {
+			t.Errorf("%s refused", cmd)
+		}
+	}
+
+	release()
+	select {
+	case r := <-acquired:
+		r()
+	case <-time.After(time.Second):
+		t.Fatal("second request not started after release")
+	}
+
+	// A waiting request gives up when the connection ends.
+	release, _ = l.acquire(ctx, cfg, protocol.TRANX_LOAD_TIME_GROUP)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, ok := l.acquire(cancelled, cfg, protocol.TRANX_LOAD_TIME_GROUP); ok {
+		t.Error("request over the limit ran on a closed connection")
+	}
+
+	// Raising the limit applies at once; the running request releases its
+	// old slot.
+	os.WriteFile(conf, []byte("net_tcp_command_concurrency=TRANX_LOAD_TIME_GROUP:2\n"), 0644)
+	cfg, _ = config.Load(conf)
+	r1, _ := l.acquire(ctx, cfg, protocol.TRANX_LOAD_TIME_GROUP)
+	r2, ok := l.acquire(cancelled, cfg, protocol.TRANX_LOAD_TIME_GROUP)
+	if !ok {
+		t.Fatal("second request refused after raising the limit")
+	}
+	release()
+	r1()
+	r2()
+}
+
+func TestParseCommandLimits(t *testing.T) {
+	got := parseCommandLimits(" A:2 ,B:0,C,:3,D:-1,E:1")
+	if len(got) != 2 || got["A"] != 2 || got["E"] != 1 {
+		t.Errorf("parseCommandLimits = %v", got)
+	}
+}
@@ -0,0 +1,91 @@
+package tcp
+
+import (
+	"bufio"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+)
+
+var encoderPool = sync.Pool{
+	New: func() any {
+		enc, _ := zstd.NewWriter(nil,
+			zstd.WithEncoderLevel(zstd.SpeedFastest),
+			zstd.WithEncoderConcurrency(1),
+		)
+		return enc
+	},
+}
+
+// compressWriter carries the output of one client command for a session that
+// negotiated compression. Output up to minBytes is passed through unchanged,
+// so small responses cost nothing; once it grows beyond that the response is
+// sent as FLAG_COMPRESSED followed by a chunked zstd stream.
+type compressWriter struct {
+	w        *bufio.Writer
+	minBytes int
+	buf      []byte
+	enc      *zstd.Encoder // non-nil once compressing
+	raw      int64
+	err      error
+}
+
+func newCompressWriter(w *bufio.Writer, minBytes int) *compressWriter {
+	return &compressWriter{w: w, minBytes: minBytes}
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	c.raw += int64(len(p))
+	if c.enc != nil {
+		_, c.err = c.enc.Write(p)
+		return len(p), c.err
+	}
+	c.buf = append(c.buf, p...)
+	if len(c.buf) > c.minBytes {
+		c.w.WriteByte(protocol.FLAG_COMPRESSED)
+		c.enc = encoderPool.Get().(*zstd.Encoder)
+		c.enc.Reset(protocol.NewChunkWriter(c.w))
+		_, c.err = c.enc.Write(c.buf)
+		c.buf = nil
+	}
+	return len(p), c.err
+}
+
+// Flush pushes what the handler has written so far to the client. Output
+// still below minBytes is held back until the handler finishes.
+func (c *compressWriter) Flush() error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.enc == nil {
+		return nil
+	}
+	if c.err = c.enc.Flush(); c.err != nil {
+		return c.err
+	}
+	return c.w.Flush()
+}
+
+// Close ends the response: buffered output is written raw, a compressed
+// stream is finished with its terminating zero-length chunk. It reports
+// whether the response was compressed and the bytes written by the handler.
+func (c *compressWriter) Close() (compressed bool, raw int64, err error) {
+	if c.enc == nil {
+		c.w.Write(c.buf)
+		c.buf = nil
+		return false, c.raw, c.err
+	}
+	if c.err == nil {
+		c.err = c.enc.Close()
+	}
+	c.enc.Reset(nil)
+	encoderPool.Put(c.enc)
+	c.enc = nil
+	var end [4]byte
+	c.w.Write(end[:])
+	return true, c.raw, c.err
+}
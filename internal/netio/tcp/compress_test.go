@@ -0,0 +1,126 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/netio/client"
+	"github.com/zbum/scouter-server-go/internal/netio/service"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// TestTCP_CompressedResponse checks that a session that negotiated compression
+// receives large responses compressed and small ones as is.
+func TestTCP_CompressedResponse(t *testing.T) {
+	sessions := login.NewSessionManager(nil)
+	registry := service.NewRegistry()
+	service.RegisterLoginHandlers(registry, sessions, nil, testVersion)
+	registry.Register("TEST_PACKS", func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pk, _ := pack.ReadPack(din)
+		n := int(pk.(*pack.MapPack).GetLong("count"))
+		for i := 0; i < n; i++ {
+			m := &pack.MapPack{}
+			m.PutLong("index", int64(i))
+			m.PutStr("service", fmt.Sprintf("/api/orders/%d", i%10))
+			dout.WriteByte(protocol.FLAG_HAS_NEXT)
+			pack.WritePack(dout, m)
+			if i == n/2 {
+				dout.Flush()
+			}
+		}
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	server := NewServer(ServerConfig{ListenIP: "127.0.0.1", ListenPort: port, ClientTimeout: 5 * time.Second}, registry, sessions)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	c, err := client.Dial(ln.Addr().String(), 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Login("admin", ""); err != nil {
+		t.Fatal(err)
+	}
+	var user *login.User
+	for _, u := range sessions.GetAllUsers() {
+		user = u
+	}
+	if user == nil || !user.Compress {
+		t.Fatalf("compression not negotiated: %+v", user)
+	}
+
+	// 10 packs stay below the threshold, 5000 exceed it; a second call
+	// checks the stream is still in sync.
+	for _, count := range []int{10, 5000, 3} {
+		param := &pack.MapPack{}
+		param.PutLong("count", int64(count))
+		var got int
+		err := c.Call("TEST_PACKS", param, func(p pack.Pack) {
+			if idx := int(p.(*pack.MapPack).GetLong("index")); idx != got {
+				t.Errorf("count %d: pack %d has index %d", count, got, idx)
+			}
+			got++
+		})
+		if err != nil {
+			t.Fatalf("count %d: %v", count, err)
+		}
+		if got != count {
+			t.Errorf("received %d packs, want %d", got, count)
+		}
+	}
+}
+
+func TestCompressWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	cw := newCompressWriter(w, 100)
+	cw.Write(bytes.Repeat([]byte("x"), 50))
+	if compressed, _, _ := cw.Close(); compressed {
+		t.Error("small response compressed")
+	}
+	w.Flush()
+	if out.Len() != 50 {
+		t.Errorf("raw response is %d bytes, want 50", out.Len())
+	}
+
+	out.Reset()
+	data := bytes.Repeat([]byte("scouter "), 10000)
+	cw = newCompressWriter(w, 100)
+	cw.Write(data)
+	compressed, raw, err := cw.Close()
+	if err != nil || !compressed || raw != int64(len(data)) {
+		t.Fatalf("compressed=%v raw=%d err=%v", compressed, raw, err)
+	}
+	w.Flush()
+	if out.Len() >= len(data)/10 || out.Bytes()[0] != protocol.FLAG_COMPRESSED {
+		t.Fatalf("unexpected body of %d bytes", out.Len())
+	}
+	out.ReadByte()
+	dec, _ := zstd.NewReader(protocol.NewChunkReader(&out))
+	defer dec.Close()
+	got, err := io.ReadAll(dec)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("round trip: %d bytes, err=%v", len(got), err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("%d bytes left after the terminating chunk", out.Len())
+	}
+}
@@ -0,0 +1,48 @@
+package tcp
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+)
+
+// internalAPIAllows reports whether a client without a valid session may run
+// cmd, so exporters and report generators running next to the server can
+// read data without managing an account. It requires
+// net_tcp_internal_api_enabled, a loopback client, cmd listed in
+// net_tcp_internal_api_commands and, if net_tcp_internal_api_token is set,
+// that token sent as the session ID.
+func internalAPIAllows(cfg *config.Config, cmd string, session int64, remoteAddr string) bool {
+	if cfg == nil || !cfg.NetTcpInternalApiEnabled() {
+		return false
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return false
+	}
+	if token := strings.TrimSpace(cfg.NetTcpInternalApiToken()); token != "" {
+		want, err := strconv.ParseInt(token, 10, 64)
+		if err != nil {
+			slog.Warn("TCP internal API: net_tcp_internal_api_token is not a number, denying")
+			return false
+		}
+		got := binary.BigEndian.AppendUint64(nil, uint64(session))
+		if subtle.ConstantTimeCompare(got, binary.BigEndian.AppendUint64(nil, uint64(want))) != 1 {
+			return false
+		}
+	}
+	for _, allowed := range strings.Split(cfg.NetTcpInternalApiCommands(), ",") {
+		if strings.TrimSpace(allowed) == cmd {
+			return true
+		}
+	}
+	return false
+}
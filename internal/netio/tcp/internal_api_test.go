@@ -0,0 +1,71 @@
+package tcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+func TestTCP_InternalAPI(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("net_tcp_internal_api_enabled=true\n"+
+		"net_tcp_internal_api_commands=COUNTER_REAL_TIME, GET_TEXT_100\nnet_tcp_internal_api_token=777\n"), 0644)
+	if _, err := config.Load(conf); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	addr, cancel, _, counterCache, _, _ := startTestServer(t)
+	defer cancel()
+	counterCache.Put(cache.CounterKey{ObjHash: 100, Counter: "TPS", TimeType: cache.TimeTypeRealtime}, value.NewDecimalValue(42))
+
+	din, dout, conn := clientConn(t, addr)
+	defer conn.Close()
+
+	// An allow-listed command runs with the token as session.
+	param := &pack.MapPack{}
+	param.PutLong("objHash", 100)
+	param.PutStr("counter", "TPS")
+	dout.WriteText(protocol.COUNTER_REAL_TIME)
+	dout.WriteInt64(777)
+	pack.WritePack(dout, param)
+	dout.Flush()
+	if flag, _ := din.ReadByte(); flag != protocol.FLAG_HAS_NEXT {
+		t.Fatalf("expected HasNEXT, got %d", flag)
+	}
+	if v, err := value.ReadValue(din); err != nil || v.(*value.DecimalValue).Value != 42 {
+		t.Fatalf("value = %v, %v", v, err)
+	}
+	din.ReadByte() // NoNEXT
+
+	// Others still need a session.
+	dout.WriteText(protocol.OBJECT_LIST_REAL_TIME)
+	dout.WriteInt64(777)
+	dout.Flush()
+	if flag, _ := din.ReadByte(); flag != protocol.FLAG_INVALID_SESSION {
+		t.Fatalf("expected INVALID_SESSION, got %d", flag)
+	}
+
+	cfg := config.Get()
+	for _, c := range []struct {
+		cmd     string
+		session int64
+		addr    string
+		want    bool
+	}{
+		{protocol.GET_TEXT_100, 777, "[::1]:5000", true},
+		{protocol.GET_TEXT_100, 1, "127.0.0.1:5000", false},
+		{protocol.GET_TEXT_100, 777, "10.0.0.1:5000", false},
+	} {
+		if got := internalAPIAllows(cfg, c.cmd, c.session, c.addr); got != c.want {
+			t.Errorf("internalAPIAllows(%s, %d, %s) = %v, want %v", c.cmd, c.session, c.addr, got, c.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package tcp
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"io"
 	"log/slog"
 	"net"
@@ -25,6 +26,12 @@ type ServerConfig struct {
 	AgentSoTimeout  time.Duration
 	ServicePoolSize int
 	AgentConfig     AgentManagerConfig
+
+	// TLSConfig serves the port over TLS only; nil for plain TCP.
+	TLSConfig *tls.Config
+	// AgentCertRequired rejects agents that did not present a client
+	// certificate verified by TLSConfig.ClientCAs.
+	AgentCertRequired bool
 }
 
 func DefaultServerConfig() ServerConfig {
@@ -46,6 +53,7 @@ type Server struct {
 	listener     net.Listener
 	wg           sync.WaitGroup
 	sem          chan struct{} // semaphore for client connection limiting
+	limiter      *commandLimiter
 }
 
 func NewServer(config ServerConfig, registry *service.Registry, sessions *login.SessionManager) *Server {
@@ -61,6 +69,7 @@ func NewServer(config ServerConfig, registry *service.Registry, sessions *login.
 		agentManager: mgr,
 		agentCaller:  NewAgentCall(mgr),
 		sem:          make(chan struct{}, poolSize),
+		limiter:      newCommandLimiter(),
 	}
 }
 
@@ -86,8 +95,11 @@ func (s *Server) Start(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if s.config.TLSConfig != nil {
+		ln = tls.NewListener(ln, s.config.TLSConfig)
+	}
 	s.listener = ln
-	slog.Info("TCP server started", "addr", addr)
+	slog.Info("TCP server started", "addr", addr, "tls", s.config.TLSConfig != nil)
 
 	go func() {
 		<-ctx.Done()
@@ -131,6 +143,16 @@ func (s *Server) Start(ctx context.Context) error {
 
 func (s *Server) handleConnection(ctx context.Context, conn net.Conn) {
 	remoteAddr := conn.RemoteAddr().String()
+	if tc, ok := conn.(*tls.Conn); ok {
+		hctx, cancel := context.WithTimeout(ctx, tlsHandshakeTimeout)
+		err := tc.HandshakeContext(hctx)
+		cancel()
+		if err != nil {
+			slog.Debug("TCP TLS handshake failed", "addr", remoteAddr, "error", err)
+			conn.Close()
+			return
+		}
+	}
 	reader := bufio.NewReaderSize(conn, 8192)
 	writer := bufio.NewWriterSize(conn, 8192)
 
@@ -152,6 +174,11 @@ func (s *Server) handleConnection(ctx context.Context, conn net.Conn) {
 		s.handleClient(ctx, reader, writer, remoteAddr)
 
 	case uint32(protocol.TCP_AGENT), uint32(protocol.TCP_AGENT_V2):
+		if s.config.AgentCertRequired && !verifiedPeer(conn) {
+			slog.Warn("TCP agent rejected without a client certificate", "addr", remoteAddr)
+			conn.Close()
+			return
+		}
 		// Read objHash (4 bytes)
 		objHashInt, err := din.ReadInt32()
 		if err != nil {
@@ -211,10 +238,11 @@ func (s *Server) handleClient(ctx context.Context, reader io.Reader, writer *buf
 			return
 		}
 
-		// Validate session for non-free commands
+		// Validate session for non-free commands. Allow-listed commands of
+		// loopback sidecar tools run without one, each checked on its own.
 		if !sessionOk && !protocol.FreeCmds[cmd] {
 			sessionOk = s.sessions.OkSession(session)
-			if !sessionOk {
+			if !sessionOk && !internalAPIAllows(config.Get(), cmd, session, remoteAddr) {
 				dout.WriteByte(protocol.FLAG_INVALID_SESSION)
 				dout.Flush()
 				slog.Debug("TCP invalid session", "addr", remoteAddr, "cmd", cmd)
@@ -227,17 +255,41 @@ func (s *Server) handleClient(ctx context.Context, reader io.Reader, writer *buf
 			slog.Info("TCP action", "cmd", cmd, "addr", remoteAddr)
 		}
 
-		// Dispatch to handler
-		handler := s.registry.Get(cmd)
-		if handler != nil {
-			handler(din, dout, sessionOk)
-		} else {
-			// Consume the request pack to keep the stream in sync.
-			// All Scouter TCP commands send a request pack after the
-			// command text and session ID. If we don't consume it,
-			// the leftover bytes corrupt the next command read.
-			pack.ReadPack(din)
-			slog.Warn("TCP unknown command", "addr", remoteAddr, "cmd", cmd)
+		// Sessions that negotiated compression at LOGIN get large
+		// responses compressed.
+		out, cw := dout, (*compressWriter)(nil)
+		if user := s.sessions.GetUser(session); user != nil && user.Compress {
+			if cfg := config.Get(); cfg == nil || cfg.NetTcpCompressEnabled() {
+				minBytes := 32768
+				if cfg != nil {
+					minBytes = cfg.NetTcpCompressMinBytes()
+				}
+				cw = newCompressWriter(writer, minBytes)
+				out = protocol.NewDataOutputXStream(cw)
+			}
+		}
+
+		// Per-command concurrency caps (net_tcp_command_concurrency)
+		release, ok := s.limiter.acquire(ctx, config.Get(), cmd)
+		if !ok {
+			return
+		}
+
+		// The slot is released even if the handler panics.
+		func() {
+			defer release()
+			s.dispatch(cmd, session, din, out, sessionOk, remoteAddr)
+		}()
+
+		if cw != nil {
+			compressed, raw, err := cw.Close()
+			if err != nil {
+				slog.Debug("TCP client compress error", "addr", remoteAddr, "cmd", cmd, "error", err)
+				return
+			}
+			if compressed {
+				slog.Debug("TCP response compressed", "cmd", cmd, "bytes", raw)
+			}
 		}
 
 		// Write NoNEXT terminator and flush
@@ -248,3 +300,39 @@ func (s *Server) handleClient(ctx context.Context, reader io.Reader, writer *buf
 		}
 	}
 }
+
+// dispatch runs the handler of cmd.
+func (s *Server) dispatch(cmd string, session int64, din *protocol.DataInputX, dout *protocol.DataOutputX, sessionOk bool, remoteAddr string) {
+	if !s.permitted(cmd, session) {
+		// Consume the request pack, as for unknown commands, and answer
+		// nothing.
+		pack.ReadPack(din)
+		slog.Warn("TCP command denied", "addr", remoteAddr, "cmd", cmd, "required", s.registry.Permission(cmd))
+		return
+	}
+	if handler := s.registry.GetSession(cmd); handler != nil {
+		handler(session, din, dout, sessionOk)
+	} else if handler := s.registry.Get(cmd); handler != nil {
+		handler(din, dout, sessionOk)
+	} else {
+		// Consume the request pack to keep the stream in sync.
+		// All Scouter TCP commands send a request pack after the
+		// command text and session ID. If we don't consume it,
+		// the leftover bytes corrupt the next command read.
+		pack.ReadPack(din)
+		slog.Warn("TCP unknown command", "addr", remoteAddr, "cmd", cmd)
+	}
+}
+
+// permitted reports whether session may run cmd: the permission of its
+// user's group must reach the one the command was registered with. Free and
+// allow-listed internal API commands arrive without a session and may only
+// read.
+func (s *Server) permitted(cmd string, session int64) bool {
+	need := s.registry.Permission(cmd)
+	perm, ok := s.sessions.Permission(session)
+	if !ok {
+		return need == login.PermRead
+	}
+	return perm >= need
+}
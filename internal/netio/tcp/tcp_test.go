@@ -3,13 +3,18 @@ package tcp
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"net"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/zbum/scouter-server-go/internal/counter"
 	"github.com/zbum/scouter-server-go/internal/core/cache"
 	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/netio/client"
 	"github.com/zbum/scouter-server-go/internal/netio/service"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
@@ -495,3 +500,81 @@ func TestTCP_MultipleCommands(t *testing.T) {
 		t.Fatalf("expected 1 object, got %d", count)
 	}
 }
+
+func TestTCP_Permission(t *testing.T) {
+	conf := t.TempDir()
+	os.WriteFile(filepath.Join(conf, "account.xml"), []byte(`<Accounts>
+	<Account id="admin" pass="`+sha256Hex("a")+`" group="admin"></Account>
+	<Account id="guest" pass="`+sha256Hex("g")+`" group="guest"></Account>
+</Accounts>`), 0644)
+	accounts := login.NewAccountManager(conf)
+	sessions := login.NewSessionManager(accounts)
+	registry := service.NewRegistry()
+	service.RegisterLoginHandlers(registry, sessions, accounts, testVersion)
+	echo := func(din *protocol.DataInputX, dout *protocol.DataOutputX, login bool) {
+		pack.ReadPack(din)
+		dout.WriteByte(protocol.FLAG_HAS_NEXT)
+		pack.WritePack(dout, &pack.MapPack{})
+	}
+	registry.Register("TEST_READ", echo)
+	registry.Register("TEST_WRITE", echo)
+	registry.Register("TEST_UNDECLARED", echo)
+	registry.Require(login.PermRead, "TEST_READ")
+	registry.Require(login.PermWrite, "TEST_WRITE")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	server := NewServer(ServerConfig{ListenIP: "127.0.0.1", ListenPort: port, ClientTimeout: 5 * time.Second}, registry, sessions)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	for _, c := range []struct {
+		id, pass string
+		want     int
+	}{
+		{"admin", "a", 1},
+		{"guest", "g", 0},
+	} {
+		cl, err := client.Dial(ln.Addr().String(), 5*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.Login(c.id, c.pass); err != nil {
+			t.Fatal(err)
+		}
+		// A denied command answers nothing and leaves the stream in sync.
+		// Undeclared commands need write permission.
+		for _, cmd := range []string{"TEST_WRITE", "TEST_UNDECLARED", "TEST_WRITE"} {
+			got := 0
+			if err := cl.Call(cmd, &pack.MapPack{}, func(pack.Pack) { got++ }); err != nil {
+				t.Fatalf("%s %s: %v", c.id, cmd, err)
+			}
+			if got != c.want {
+				t.Errorf("%s %s: received %d packs, want %d", c.id, cmd, got, c.want)
+			}
+		}
+		cl.Close()
+	}
+
+	// Commands run without a session, such as those of the internal API,
+	// may only read.
+	if !server.permitted("TEST_READ", 0) {
+		t.Error("read command denied without a session")
+	}
+	for _, cmd := range []string{"TEST_WRITE", "TEST_UNDECLARED"} {
+		if server.permitted(cmd, 0) {
+			t.Errorf("%s permitted without a session", cmd)
+		}
+	}
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,50 @@
+package tcp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// tlsHandshakeTimeout bounds the TLS handshake of an accepted connection,
+// which holds a service pool slot until it completes.
+const tlsHandshakeTimeout = 10 * time.Second
+
+// NewTLSConfig builds the TLS settings of the TCP port from a PEM
+// certificate chain and key. With clientCAFile, clients are asked for a
+// certificate, which is verified against those CAs when presented; whether a
+// connection must present one is decided once it identifies itself as a
+// client or an agent (see ServerConfig.AgentCertRequired).
+func NewTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load certificate: %w", err)
+	}
+	tc := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", clientCAFile)
+		}
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tc, nil
+}
+
+// verifiedPeer reports whether conn is a TLS connection whose peer presented
+// a certificate verified against the client CAs.
+func verifiedPeer(conn net.Conn) bool {
+	tc, ok := conn.(*tls.Conn)
+	return ok && len(tc.ConnectionState().VerifiedChains) > 0
+}
@@ -0,0 +1,169 @@
+package tcp
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/login"
+	"github.com/zbum/scouter-server-go/internal/netio/service"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// testCert issues a certificate for 127.0.0.1, signed by parent (self-signed
+// when nil).
+func testCert(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, isCA bool) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return cert, key,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestTCP_TLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey, caPEM, _ := testCert(t, "test-ca", nil, nil, true)
+	_, _, serverPEM, serverKeyPEM := testCert(t, "server", ca, caKey, false)
+	_, _, agentPEM, agentKeyPEM := testCert(t, "agent", ca, caKey, false)
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, data, 0600)
+		return path
+	}
+	tlsConfig, err := NewTLSConfig(write("server.pem", serverPEM), write("server.key", serverKeyPEM), write("ca.pem", caPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTLSConfig(filepath.Join(dir, "missing.pem"), filepath.Join(dir, "server.key"), ""); err == nil {
+		t.Error("expected an error for a missing certificate")
+	}
+
+	sessions := login.NewSessionManager(nil)
+	registry := service.NewRegistry()
+	service.RegisterServerHandlers(registry, testVersion)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	server := NewServer(ServerConfig{
+		ListenIP:          "127.0.0.1",
+		ListenPort:        port,
+		ClientTimeout:     5 * time.Second,
+		AgentSoTimeout:    5 * time.Second,
+		AgentConfig:       DefaultAgentManagerConfig(),
+		TLSConfig:         tlsConfig,
+		AgentCertRequired: true,
+	}, registry, sessions)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	dial := func(certs ...tls.Certificate) *tls.Conn {
+		t.Helper()
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 2 * time.Second}, "tcp", addr,
+			&tls.Config{RootCAs: roots, Certificates: certs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		return conn
+	}
+
+	// A client needs no certificate.
+	conn := dial()
+	dout := protocol.NewDataOutputXStream(conn)
+	din := protocol.NewDataInputXStream(bufio.NewReader(conn))
+	dout.Write([]byte{0xCA, 0xFE, 0x20, 0x01})
+	dout.WriteText(protocol.SERVER_VERSION)
+	dout.WriteInt64(0)
+	pack.WritePack(dout, &pack.MapPack{})
+	if flag, err := din.ReadByte(); err != nil || flag != protocol.FLAG_HAS_NEXT {
+		t.Fatalf("client over TLS: flag %d, err %v", flag, err)
+	}
+	conn.Close()
+
+	// Plain TCP is refused.
+	plain, err := net.DialTimeout("tcp", addr, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain.SetDeadline(time.Now().Add(5 * time.Second))
+	plain.Write([]byte{0xCA, 0xFE, 0x20, 0x01, 0, 0, 0, 0})
+	if _, err := plain.Read(make([]byte, 1)); err == nil {
+		t.Error("plain TCP connection should be closed")
+	}
+	plain.Close()
+
+	agentHello := func(conn *tls.Conn) {
+		dout := protocol.NewDataOutputXStream(conn)
+		dout.Write([]byte{0xCA, 0xFE, 0x10, 0x01})
+		dout.WriteInt32(1234)
+	}
+
+	// An agent without a certificate is rejected.
+	conn = dial()
+	agentHello(conn)
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("agent without a certificate should be closed")
+	}
+	conn.Close()
+
+	// An agent with a certificate signed by the CA is pooled.
+	agentCert, err := tls.X509KeyPair(agentPEM, agentKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn = dial(agentCert)
+	defer conn.Close()
+	agentHello(conn)
+	deadline := time.Now().Add(2 * time.Second)
+	for !server.AgentMgr().HasAgent(1234) {
+		if time.Now().After(deadline) {
+			t.Fatal("agent with a certificate was not added to the pool")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -1,8 +1,11 @@
 package udp
 
 import (
+	"encoding/binary"
 	"log/slog"
 	"net"
+	"sync/atomic"
+	"time"
 
 	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/core"
@@ -11,16 +14,23 @@ import (
 )
 
 // NetDataProcessor handles incoming UDP data, parses frames, and dispatches packs.
+// Object heartbeats, alerts and performance counters go through a priority
+// lane that workers drain first, so a flood of profile packs cannot delay them
+// long enough for the object to be marked dead.
 type NetDataProcessor struct {
 	multiPacket *MultiPacketProcessor
 	dispatcher  *core.Dispatcher
+	priority    chan netData
 	queue       chan netData
 	workers     int
+	received    atomic.Int64
+	dropped     atomic.Int64
 }
 
 type netData struct {
-	data []byte
-	addr *net.UDPAddr
+	data     []byte
+	addr     *net.UDPAddr
+	received time.Time
 }
 
 func NewNetDataProcessor(dispatcher *core.Dispatcher, workers int) *NetDataProcessor {
@@ -30,6 +40,7 @@ func NewNetDataProcessor(dispatcher *core.Dispatcher, workers int) *NetDataProce
 	p := &NetDataProcessor{
 		multiPacket: NewMultiPacketProcessor(),
 		dispatcher:  dispatcher,
+		priority:    make(chan netData, 1024),
 		queue:       make(chan netData, 2048),
 		workers:     workers,
 	}
@@ -40,16 +51,88 @@ func NewNetDataProcessor(dispatcher *core.Dispatcher, workers int) *NetDataProce
 }
 
 func (p *NetDataProcessor) Add(data []byte, addr *net.UDPAddr) {
+	p.received.Add(1)
+	queue, lane := p.queue, "bulk"
+	if isPriority(data) {
+		queue, lane = p.priority, "priority"
+	}
 	select {
-	case p.queue <- netData{data: data, addr: addr}:
+	case queue <- netData{data: data, addr: addr, received: time.Now()}:
+	default:
+		p.dropped.Add(1)
+		slog.Warn("UDP receive queue overflow, dropping packet", "lane", lane)
+	}
+}
+
+// isPriority reports whether a datagram starts with an ObjectPack, AlertPack
+// or PerfCounterPack. MTU fragments carry large packs such as profiles and
+// always take the bulk lane.
+func isPriority(data []byte) bool {
+	if len(data) < 5 {
+		return false
+	}
+	var packType byte
+	switch int32(binary.BigEndian.Uint32(data)) {
+	case protocol.UDP_CAFE, protocol.UDP_JAVA:
+		packType = data[4]
+	case protocol.UDP_CAFE_N, protocol.UDP_JAVA_N:
+		if len(data) < 7 {
+			return false
+		}
+		packType = data[6] // after the int16 pack count
 	default:
-		slog.Warn("UDP receive queue overflow, dropping packet")
+		return false
 	}
+	switch packType {
+	case pack.PackTypeObject, pack.PackTypeAlert, pack.PackTypePerfCounter:
+		return true
+	}
+	return false
+}
+
+// Received returns the number of datagrams received.
+func (p *NetDataProcessor) Received() int64 {
+	return p.received.Load()
+}
+
+// Dropped returns the number of datagrams dropped due to receive queue overflow.
+func (p *NetDataProcessor) Dropped() int64 {
+	return p.dropped.Load()
+}
+
+// Pending returns the number of datagrams waiting to be processed.
+func (p *NetDataProcessor) Pending() int {
+	return len(p.priority) + len(p.queue)
 }
 
 func (p *NetDataProcessor) workerLoop() {
-	for nd := range p.queue {
-		p.process(nd)
+	priority, queue := p.priority, p.queue
+	for priority != nil || queue != nil {
+		// Take a priority datagram whenever one is waiting.
+		select {
+		case nd, ok := <-priority:
+			if !ok {
+				priority = nil
+				continue
+			}
+			p.process(nd)
+			continue
+		default:
+		}
+		select {
+		case nd, ok := <-priority:
+			if !ok {
+				priority = nil
+				continue
+			}
+			p.process(nd)
+		case nd, ok := <-queue:
+			if !ok {
+				queue = nil
+				continue
+			}
+			p.process(nd)
+		}
 	}
 }
 
@@ -74,42 +157,46 @@ func (p *NetDataProcessor) process(nd netData) {
 
 	switch cafe {
 	case protocol.UDP_CAFE, protocol.UDP_JAVA:
-		p.processCafe(d, nd.addr)
+		p.processCafe(d, nd)
 	case protocol.UDP_CAFE_N, protocol.UDP_JAVA_N:
-		p.processCafeN(d, nd.addr)
+		p.processCafeN(d, nd)
 	case protocol.UDP_CAFE_MTU, protocol.UDP_JAVA_MTU:
-		p.processCafeMTU(d, nd.addr)
+		p.processCafeMTU(d, nd)
 	default:
 		slog.Warn("unknown UDP magic", "magic", cafe, "len", len(nd.data), "addr", nd.addr)
 	}
 }
 
-func (p *NetDataProcessor) processCafe(d *protocol.DataInputX, addr *net.UDPAddr) {
+// processCafe and processCafeN read packs from d, which wraps nd.data, and
+// hand the bytes of each pack along for inspection.
+func (p *NetDataProcessor) processCafe(d *protocol.DataInputX, nd netData) {
+	start := d.Offset()
 	pk, err := pack.ReadPack(d)
 	if err != nil {
 		slog.Warn("failed to read pack", "error", err)
 		return
 	}
-	p.dispatcher.Dispatch(pk, addr)
+	p.dispatcher.DispatchRaw(pk, nd.data[start:d.Offset()], nd.addr, nd.received)
 }
 
-func (p *NetDataProcessor) processCafeN(d *protocol.DataInputX, addr *net.UDPAddr) {
+func (p *NetDataProcessor) processCafeN(d *protocol.DataInputX, nd netData) {
 	n, err := d.ReadInt16()
 	if err != nil {
 		slog.Warn("failed to read pack count", "error", err)
 		return
 	}
 	for i := int16(0); i < n; i++ {
+		start := d.Offset()
 		pk, err := pack.ReadPack(d)
 		if err != nil {
 			slog.Warn("failed to read pack in multi-frame", "index", i, "error", err)
 			return
 		}
-		p.dispatcher.Dispatch(pk, addr)
+		p.dispatcher.DispatchRaw(pk, nd.data[start:d.Offset()], nd.addr, nd.received)
 	}
 }
 
-func (p *NetDataProcessor) processCafeMTU(d *protocol.DataInputX, addr *net.UDPAddr) {
+func (p *NetDataProcessor) processCafeMTU(d *protocol.DataInputX, nd netData) {
 	objHash, err := d.ReadInt32()
 	if err != nil {
 		return
@@ -133,7 +220,7 @@ func (p *NetDataProcessor) processCafeMTU(d *protocol.DataInputX, addr *net.UDPA
 
 	// log_udp_multipacket: log MTU fragment reception
 	if cfg := config.Get(); cfg != nil && cfg.LogUDPMultipacket() {
-		slog.Info("UDP multipacket fragment", "pkid", pkid, "num", num, "total", total, "objHash", objHash, "addr", addr)
+		slog.Info("UDP multipacket fragment", "pkid", pkid, "num", num, "total", total, "objHash", objHash, "addr", nd.addr)
 	}
 
 	done := p.multiPacket.Add(pkid, total, num, data, objHash)
@@ -144,10 +231,11 @@ func (p *NetDataProcessor) processCafeMTU(d *protocol.DataInputX, addr *net.UDPA
 			slog.Warn("failed to read reassembled pack", "error", err)
 			return
 		}
-		p.dispatcher.Dispatch(pk, addr)
+		p.dispatcher.DispatchRaw(pk, done, nd.addr, nd.received)
 	}
 }
 
 func (p *NetDataProcessor) Close() {
+	close(p.priority)
 	close(p.queue)
 }
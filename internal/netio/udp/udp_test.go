@@ -1,12 +1,15 @@
 package udp
 
 import (
+	"bytes"
 	"net"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/zbum/scouter-server-go/internal/config"
 	"github.com/zbum/scouter-server-go/internal/core"
 	"github.com/zbum/scouter-server-go/internal/protocol"
 	"github.com/zbum/scouter-server-go/internal/protocol/pack"
@@ -141,6 +144,35 @@ func TestProcessorCafeN(t *testing.T) {
 	}
 }
 
+func TestProcessorCafeN_Inspect(t *testing.T) {
+	config.Load(filepath.Join(t.TempDir(), "missing.conf"))
+	inspector := core.NewPackInspector()
+	dispatcher := core.NewDispatcher()
+	dispatcher.SetInspector(inspector)
+
+	proc := NewNetDataProcessor(dispatcher, 1)
+	defer proc.Close()
+
+	packs := []pack.Pack{
+		&pack.XLogPack{ObjHash: 7, Txid: 1, Service: 100},
+		&pack.XLogPack{ObjHash: 7, Txid: 2, Service: 200, Error: 5},
+	}
+	proc.Add(buildCafeNPacket(packs), &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1234})
+	time.Sleep(100 * time.Millisecond)
+
+	got := inspector.Recent(7, "xlog", 0)
+	if len(got) != 2 {
+		t.Fatalf("inspected %d packs, want 2", len(got))
+	}
+	for _, ip := range got {
+		o := protocol.NewDataOutputX()
+		pack.WritePack(o, ip.Pack)
+		if !bytes.Equal(ip.Raw, o.ToByteArray()) {
+			t.Errorf("txid %d raw = %x, want %x", ip.Pack.(*pack.XLogPack).Txid, ip.Raw, o.ToByteArray())
+		}
+	}
+}
+
 func TestProcessorCafeMTU(t *testing.T) {
 	dispatcher := core.NewDispatcher()
 
@@ -374,3 +406,62 @@ func TestProcessorObjectPack(t *testing.T) {
 		t.Errorf("expected 1, got %d", received.Load())
 	}
 }
+
+// --- Priority lane ---
+
+func TestProcessorPriorityLane(t *testing.T) {
+	dispatcher := core.NewDispatcher()
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var order []byte
+	record := func(p pack.Pack, addr *net.UDPAddr) {
+		mu.Lock()
+		first := len(order) == 0
+		order = append(order, p.PackType())
+		mu.Unlock()
+		if first {
+			<-release
+		}
+	}
+	dispatcher.Register(pack.PackTypeText, record)
+	dispatcher.Register(pack.PackTypeObject, record)
+
+	proc := NewNetDataProcessor(dispatcher, 1)
+	defer proc.Close()
+
+	addr := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1234}
+	text := buildCafePacket(&pack.TextPack{XType: "service", Hash: 1, Text: "bulk"})
+	proc.Add(text, addr)
+	time.Sleep(50 * time.Millisecond) // the worker is now blocked on the first pack
+	for i := 0; i < 5; i++ {
+		proc.Add(text, addr)
+	}
+	proc.Add(buildCafeNPacket([]pack.Pack{&pack.ObjectPack{ObjType: "java", ObjHash: 1, Tags: value.NewMapValue()}}), addr)
+	close(release)
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != 7 || order[1] != pack.PackTypeObject {
+		t.Errorf("expected the ObjectPack right after the blocked pack, got %v", order)
+	}
+}
+
+func TestIsPriority(t *testing.T) {
+	cases := []struct {
+		data []byte
+		want bool
+	}{
+		{buildCafePacket(&pack.AlertPack{Tags: value.NewMapValue()}), true},
+		{buildCafePacket(&pack.PerfCounterPack{Data: value.NewMapValue()}), true},
+		{buildCafePacket(&pack.XLogProfilePack{}), false},
+		{buildCafeNPacket([]pack.Pack{&pack.ObjectPack{Tags: value.NewMapValue()}}), true},
+		{[]byte{0x43, 0x41}, false},
+	}
+	for i, c := range cases {
+		if got := isPriority(c.data); got != c.want {
+			t.Errorf("case %d: isPriority = %v, want %v", i, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// DefaultAlertTemplate is the webhook payload when alert_webhook_template is
+// empty or does not render valid JSON.
+const DefaultAlertTemplate = `{"level":"${level}","title":"${title}","message":"${message}",` +
+	`"objName":"${objName}","objType":"${objType}","objHash":${objHash},"time":${time},"timeIso":"${timeIso}"}`
+
+// alertWebhookConcurrency bounds the deliveries in flight, so a hanging
+// endpoint cannot pile up goroutines.
+const alertWebhookConcurrency = 16
+
+var alertLevelNames = []string{"INFO", "WARN", "ERROR", "FATAL"}
+
+// DeadLetter is an alert payload that could not be delivered.
+type DeadLetter struct {
+	Time    int64  `json:"time"` // ms
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	Payload string `json:"payload"`
+	Error   string `json:"error"` // of the last attempt
+}
+
+// AlertWebhooks forwards alerts to the HTTP endpoints of alert_webhook_urls
+// as templated JSON payloads, retrying failures with backoff. Payloads still
+// failing after the retries are appended as JSON lines to
+// notify/alert_dead_letter.jsonl under the data directory, so they can be
+// inspected and replayed.
+type AlertWebhooks struct {
+	deliveries *Deliveries
+	objName    func(objHash int32) string
+	deadPath   string
+	queue      chan *pack.AlertPack
+	slots      chan struct{}
+
+	mu       sync.Mutex
+	deadFile *os.File
+}
+
+// NewAlertWebhooks creates an AlertWebhooks recording its sends in
+// deliveries and resolving object names with objName.
+func NewAlertWebhooks(baseDir string, deliveries *Deliveries, objName func(objHash int32) string) *AlertWebhooks {
+	return &AlertWebhooks{
+		deliveries: deliveries,
+		objName:    objName,
+		deadPath:   filepath.Join(baseDir, "notify", "alert_dead_letter.jsonl"),
+		queue:      make(chan *pack.AlertPack, 1024),
+		slots:      make(chan struct{}, alertWebhookConcurrency),
+	}
+}
+
+// Add queues an alert. It never blocks; alerts are dropped while the queue
+// is full or no webhook is configured.
+func (w *AlertWebhooks) Add(ap *pack.AlertPack) {
+	if cfg := config.Get(); cfg == nil || strings.TrimSpace(cfg.AlertWebhookURLs()) == "" {
+		return
+	}
+	select {
+	case w.queue <- ap:
+	default:
+		slog.Warn("Alert webhook queue overflow", "title", ap.Title)
+	}
+}
+
+// Start sends the queued alerts until ctx is cancelled.
+func (w *AlertWebhooks) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ap := <-w.queue:
+				w.dispatch(ctx, ap)
+			}
+		}
+	}()
+}
+
+// dispatch sends ap to every webhook if it passes the level and objType
+// filters.
+func (w *AlertWebhooks) dispatch(ctx context.Context, ap *pack.AlertPack) {
+	cfg := config.Get()
+	if cfg == nil || int(ap.Level) < cfg.AlertWebhookMinLevel() {
+		return
+	}
+	if types := splitList(cfg.AlertWebhookObjTypes()); len(types) > 0 && !slices.Contains(types, ap.ObjType) {
+		return
+	}
+	payload := w.Render(cfg.AlertWebhookTemplate(), ap)
+	msg := Message{Subject: ap.Title, Text: payload}
+	policy := RetryPolicy{
+		Retries: cfg.AlertWebhookRetryCount(),
+		Backoff: time.Duration(cfg.AlertWebhookRetryBackoffMs()) * time.Millisecond,
+	}
+	timeout := time.Duration(cfg.AlertWebhookTimeoutMs()) * time.Millisecond
+	for _, url := range splitList(cfg.AlertWebhookURLs()) {
+		select {
+		case w.slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		go func() {
+			defer func() { <-w.slots }()
+			w.send(ctx, NewWebhook(url, timeout), url, msg, policy)
+		}()
+	}
+}
+
+func (w *AlertWebhooks) send(ctx context.Context, ch *Webhook, url string, msg Message, policy RetryPolicy) {
+	err := w.deliveries.Send(ctx, ch, msg, "alert", policy)
+	if err == nil {
+		return
+	}
+	slog.Error("Alert webhook delivery failed", "url", url, "title", msg.Subject, "error", err)
+	w.deadLetter(DeadLetter{
+		Time:    time.Now().UnixMilli(),
+		URL:     url,
+		Title:   msg.Subject,
+		Payload: msg.Text,
+		Error:   err.Error(),
+	})
+}
+
+func (w *AlertWebhooks) deadLetter(dl DeadLetter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.deadFile == nil {
+		if err := os.MkdirAll(filepath.Dir(w.deadPath), 0755); err != nil {
+			slog.Warn("Alert dead letter write failed", "error", err)
+			return
+		}
+		f, err := os.OpenFile(w.deadPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			slog.Warn("Alert dead letter write failed", "error", err)
+			return
+		}
+		w.deadFile = f
+	}
+	data, _ := json.Marshal(dl)
+	if _, err := w.deadFile.Write(append(data, '\n')); err != nil {
+		slog.Warn("Alert dead letter write failed", "error", err)
+	}
+}
+
+// Close closes the dead letter file.
+func (w *AlertWebhooks) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.deadFile != nil {
+		w.deadFile.Close()
+		w.deadFile = nil
+	}
+}
+
+// Render fills the placeholders of tmpl with the fields of ap: ${level}
+// (name), ${levelNum}, ${title}, ${message}, ${objName}, ${objType},
+// ${objHash}, ${time} (ms) and ${timeIso}. Text is JSON-escaped without
+// quotes, for use inside the template's strings. A template that does not
+// render valid JSON falls back to DefaultAlertTemplate.
+func (w *AlertWebhooks) Render(tmpl string, ap *pack.AlertPack) string {
+	level := levelName(ap.Level)
+	objName := ""
+	if w.objName != nil {
+		objName = w.objName(ap.ObjHash)
+	}
+	r := strings.NewReplacer(
+		"${level}", jsonEscape(level),
+		"${levelNum}", strconv.Itoa(int(ap.Level)),
+		"${title}", jsonEscape(ap.Title),
+		"${message}", jsonEscape(ap.Message),
+		"${objName}", jsonEscape(objName),
+		"${objType}", jsonEscape(ap.ObjType),
+		"${objHash}", strconv.Itoa(int(ap.ObjHash)),
+		"${time}", strconv.FormatInt(ap.Time, 10),
+		"${timeIso}", time.UnixMilli(ap.Time).Format(time.RFC3339),
+	)
+	if strings.TrimSpace(tmpl) != "" {
+		if s := r.Replace(tmpl); json.Valid([]byte(s)) {
+			return s
+		}
+		slog.Warn("alert_webhook_template does not render valid JSON, using the default")
+	}
+	return r.Replace(DefaultAlertTemplate)
+}
+
+// jsonEscape returns s as the inside of a JSON string.
+func jsonEscape(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data[1 : len(data)-1])
+}
+
+// splitList splits a comma-separated setting, dropping empty entries.
+func splitList(s string) []string {
+	var result []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			result = append(result, f)
+		}
+	}
+	return result
+}
@@ -0,0 +1,286 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+const (
+	// alertMailConcurrency bounds the mails in flight.
+	alertMailConcurrency = 4
+	// alertMailDigestLines bounds the alerts listed one by one in a digest.
+	alertMailDigestLines = 200
+)
+
+// ParseMailRecipients parses alert_mail_to: objType=addr,addr entries
+// separated by ';', "*" naming the recipients of the types not listed. An
+// entry without '=' is taken as "*".
+func ParseMailRecipients(s string) (map[string][]string, error) {
+	result := make(map[string][]string)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		objType, addrs := "*", entry
+		if i := strings.Index(entry, "="); i >= 0 {
+			objType, addrs = strings.TrimSpace(entry[:i]), entry[i+1:]
+		}
+		to := splitList(addrs)
+		if objType == "" || len(to) == 0 {
+			return nil, fmt.Errorf("bad recipients entry %q: want objType=addr,addr", entry)
+		}
+		for _, addr := range to {
+			if !strings.Contains(addr, "@") {
+				return nil, fmt.Errorf("bad address %q in %q", addr, entry)
+			}
+		}
+		if _, ok := result[objType]; ok {
+			return nil, fmt.Errorf("objType %q listed twice", objType)
+		}
+		result[objType] = to
+	}
+	return result, nil
+}
+
+// digest holds the alerts waiting for the next digest mail of one list of
+// recipients.
+type digest struct {
+	to     []string
+	alerts []*pack.AlertPack
+}
+
+// AlertMails mails alerts to the recipients alert_mail_to gives their
+// objType, through the SMTP server of the notify_smtp_* settings. Alerts at
+// or above alert_mail_immediate_level are mailed one by one as they come;
+// lower ones, down to alert_mail_min_level, are collected per list of
+// recipients and mailed as one digest every alert_mail_digest_interval_min,
+// so a flapping WARN does not flood the inboxes.
+type AlertMails struct {
+	deliveries *Deliveries
+	objName    func(objHash int32) string
+	channel    func(cfg *config.Config, to []string) Channel
+	queue      chan *pack.AlertPack
+	slots      chan struct{}
+
+	mu         sync.Mutex
+	digests    map[string]*digest // by recipients
+	lastDigest time.Time
+}
+
+// NewAlertMails creates an AlertMails recording its sends in deliveries and
+// resolving object names with objName.
+func NewAlertMails(deliveries *Deliveries, objName func(objHash int32) string) *AlertMails {
+	return &AlertMails{
+		deliveries: deliveries,
+		objName:    objName,
+		channel: func(cfg *config.Config, to []string) Channel {
+			return NewConfiguredMail(cfg, to)
+		},
+		queue:      make(chan *pack.AlertPack, 1024),
+		slots:      make(chan struct{}, alertMailConcurrency),
+		digests:    make(map[string]*digest),
+		lastDigest: time.Now(),
+	}
+}
+
+// Add queues an alert. It never blocks; alerts are dropped while the queue
+// is full or no recipient or SMTP server is configured.
+func (m *AlertMails) Add(ap *pack.AlertPack) {
+	if cfg := config.Get(); cfg == nil || strings.TrimSpace(cfg.AlertMailTo()) == "" || cfg.NotifySMTPAddr() == "" {
+		return
+	}
+	select {
+	case m.queue <- ap:
+	default:
+		slog.Warn("Alert mail queue overflow", "title", ap.Title)
+	}
+}
+
+// Start mails the queued alerts and the digests until ctx is cancelled.
+func (m *AlertMails) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ap := <-m.queue:
+				m.dispatch(ctx, ap)
+			case now := <-ticker.C:
+				m.flushDue(ctx, now)
+			}
+		}
+	}()
+}
+
+// dispatch mails ap at once or keeps it for the digest.
+func (m *AlertMails) dispatch(ctx context.Context, ap *pack.AlertPack) {
+	cfg := config.Get()
+	if cfg == nil || int(ap.Level) < cfg.AlertMailMinLevel() {
+		return
+	}
+	recipients, err := ParseMailRecipients(cfg.AlertMailTo())
+	if err != nil {
+		slog.Warn("Bad alert_mail_to", "error", err)
+		return
+	}
+	to, ok := recipients[ap.ObjType]
+	if !ok {
+		to = recipients["*"]
+	}
+	if len(to) == 0 {
+		return
+	}
+	if cfg.AlertMailDigestIntervalMin() > 0 && int(ap.Level) < cfg.AlertMailImmediateLevel() {
+		key := strings.Join(to, ",")
+		m.mu.Lock()
+		d := m.digests[key]
+		if d == nil {
+			d = &digest{to: to}
+			m.digests[key] = d
+		}
+		d.alerts = append(d.alerts, ap)
+		m.mu.Unlock()
+		return
+	}
+	m.send(ctx, cfg, to, m.alertMessage(ap))
+}
+
+// flushDue mails the digests once alert_mail_digest_interval_min has passed
+// since the last ones.
+func (m *AlertMails) flushDue(ctx context.Context, now time.Time) {
+	cfg := config.Get()
+	if cfg == nil {
+		return
+	}
+	interval := time.Duration(cfg.AlertMailDigestIntervalMin()) * time.Minute
+	m.mu.Lock()
+	if now.Sub(m.lastDigest) < interval {
+		m.mu.Unlock()
+		return
+	}
+	since := m.lastDigest
+	digests := m.digests
+	m.digests = make(map[string]*digest)
+	m.lastDigest = now
+	m.mu.Unlock()
+
+	keys := make([]string, 0, len(digests))
+	for key := range digests {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		d := digests[key]
+		m.send(ctx, cfg, d.to, m.digestMessage(d.alerts, since, now))
+	}
+}
+
+// send mails msg in the background, waiting while alertMailConcurrency
+// mails are in flight.
+func (m *AlertMails) send(ctx context.Context, cfg *config.Config, to []string, msg Message) {
+	ch := m.channel(cfg, to)
+	policy := RetryPolicy{
+		Retries: cfg.NotifyRetryCount(),
+		Backoff: time.Duration(cfg.NotifyRetryBackoffMs()) * time.Millisecond,
+	}
+	select {
+	case m.slots <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	go func() {
+		defer func() { <-m.slots }()
+		if err := m.deliveries.Send(ctx, ch, msg, "alert", policy); err != nil {
+			slog.Error("Alert mail delivery failed", "to", strings.Join(to, ","), "subject", msg.Subject, "error", err)
+		}
+	}()
+}
+
+// alertMessage is the mail of one alert.
+func (m *AlertMails) alertMessage(ap *pack.AlertPack) Message {
+	objName := m.name(ap.ObjHash)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Level:   %s\n", levelName(ap.Level))
+	fmt.Fprintf(&b, "Title:   %s\n", ap.Title)
+	fmt.Fprintf(&b, "Object:  %s (%s)\n", objName, ap.ObjType)
+	fmt.Fprintf(&b, "Time:    %s\n\n", time.UnixMilli(ap.Time).Format(time.RFC3339))
+	b.WriteString(ap.Message)
+	b.WriteString("\n")
+	return Message{
+		Subject: fmt.Sprintf("[scouter] %s %s %s", levelName(ap.Level), ap.Title, objName),
+		Text:    b.String(),
+	}
+}
+
+// digestMessage is the mail summing up alerts collected from since to now:
+// the count per level and title, then the alerts in the order they came.
+func (m *AlertMails) digestMessage(alerts []*pack.AlertPack, since, now time.Time) Message {
+	type titleKey struct {
+		level byte
+		title string
+	}
+	counts := make(map[titleKey]int)
+	for _, ap := range alerts {
+		counts[titleKey{ap.Level, ap.Title}]++
+	}
+	keys := make([]titleKey, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		if keys[i].level != keys[j].level {
+			return keys[i].level > keys[j].level
+		}
+		return keys[i].title < keys[j].title
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d alerts from %s to %s\n\n", len(alerts), since.Format("2006-01-02 15:04"), now.Format("2006-01-02 15:04"))
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%6d  %-5s  %s\n", counts[k], levelName(k.level), k.title)
+	}
+	b.WriteString("\n")
+	for i, ap := range alerts {
+		if i == alertMailDigestLines {
+			fmt.Fprintf(&b, "... and %d more\n", len(alerts)-i)
+			break
+		}
+		fmt.Fprintf(&b, "%s  %-5s  %s  %s  %s\n", time.UnixMilli(ap.Time).Format("01-02 15:04:05"),
+			levelName(ap.Level), m.name(ap.ObjHash), ap.Title, ap.Message)
+	}
+	return Message{
+		Subject: fmt.Sprintf("[scouter] %d alerts (digest)", len(alerts)),
+		Text:    b.String(),
+	}
+}
+
+func (m *AlertMails) name(objHash int32) string {
+	if m.objName != nil {
+		if name := m.objName(objHash); name != "" {
+			return name
+		}
+	}
+	return strconv.Itoa(int(objHash))
+}
+
+func levelName(level byte) string {
+	if int(level) < len(alertLevelNames) {
+		return alertLevelNames[level]
+	}
+	return strconv.Itoa(int(level))
+}
@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+type mailbox struct {
+	mu   sync.Mutex
+	sent map[string][]Message // by recipients
+}
+
+type mailboxChannel struct {
+	box *mailbox
+	to  string
+}
+
+func (c *mailboxChannel) Name() string { return "mail" }
+
+func (c *mailboxChannel) Send(ctx context.Context, msg Message) error {
+	c.box.mu.Lock()
+	defer c.box.mu.Unlock()
+	c.box.sent[c.to] = append(c.box.sent[c.to], msg)
+	return nil
+}
+
+// waitMails waits for the mails send started.
+func waitMails(m *AlertMails) {
+	for i := 0; i < cap(m.slots); i++ {
+		m.slots <- struct{}{}
+	}
+	for i := 0; i < cap(m.slots); i++ {
+		<-m.slots
+	}
+}
+
+func TestParseMailRecipients(t *testing.T) {
+	got, err := ParseMailRecipients(" ops@example.com ; tomcat = was@example.com, dev@example.com ;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got["*"], ",") != "ops@example.com" || strings.Join(got["tomcat"], ",") != "was@example.com,dev@example.com" {
+		t.Errorf("recipients %v", got)
+	}
+	for _, s := range []string{"tomcat=", "=a@b", "tomcat=nobody", "a@b;*=c@d"} {
+		if _, err := ParseMailRecipients(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestAlertMails(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("notify_smtp_addr=smtp.example.com:25\n"+
+		"alert_mail_to=*=ops@example.com;tomcat=was@example.com\nalert_mail_digest_interval_min=30\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	d, _ := openTestDeliveries(t, dir)
+	box := &mailbox{sent: make(map[string][]Message)}
+	m := NewAlertMails(d, func(int32) string { return "/host/app1" })
+	m.channel = func(cfg *config.Config, to []string) Channel {
+		return &mailboxChannel{box: box, to: strings.Join(to, ",")}
+	}
+	ctx := context.Background()
+	start := m.lastDigest
+
+	m.dispatch(ctx, &pack.AlertPack{Level: 3, ObjType: "tomcat", ObjHash: 7, Time: 1000, Title: "OOM", Message: "heap full"})
+	m.dispatch(ctx, &pack.AlertPack{Level: 0, ObjType: "tomcat", Title: "BELOW_MIN"})
+	for range 3 {
+		m.dispatch(ctx, &pack.AlertPack{Level: 1, ObjType: "tomcat", Title: "SLOW"})
+	}
+	m.dispatch(ctx, &pack.AlertPack{Level: 1, ObjType: "mysql", Title: "LOCK_WAIT"})
+	waitMails(m)
+
+	if got := box.sent["was@example.com"]; len(got) != 1 || got[0].Subject != "[scouter] FATAL OOM /host/app1" ||
+		!strings.Contains(got[0].Text, "heap full") {
+		t.Fatalf("immediate mails %+v", got)
+	}
+	if len(box.sent["ops@example.com"]) != 0 {
+		t.Errorf("WARN mailed before the digest: %+v", box.sent["ops@example.com"])
+	}
+
+	m.flushDue(ctx, start.Add(10*time.Minute))
+	waitMails(m)
+	if len(box.sent["was@example.com"]) != 1 {
+		t.Fatal("digest mailed before its interval")
+	}
+	m.flushDue(ctx, start.Add(30*time.Minute))
+	waitMails(m)
+	digests := box.sent["was@example.com"][1:]
+	if len(digests) != 1 || digests[0].Subject != "[scouter] 3 alerts (digest)" || !strings.Contains(digests[0].Text, "     3  WARN   SLOW") {
+		t.Errorf("tomcat digest %+v", digests)
+	}
+	if got := box.sent["ops@example.com"]; len(got) != 1 || !strings.Contains(got[0].Text, "LOCK_WAIT") {
+		t.Errorf("default digest %+v", got)
+	}
+	if sent := d.List(StatusSent, "mail", 0); len(sent) != 3 || sent[0].Source != "alert" {
+		t.Errorf("deliveries %+v", sent)
+	}
+
+	// Nothing collected since: no empty digest.
+	m.flushDue(ctx, start.Add(60*time.Minute))
+	waitMails(m)
+	if len(box.sent["ops@example.com"]) != 1 {
+		t.Error("empty digest mailed")
+	}
+}
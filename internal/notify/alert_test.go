@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// waitSends waits for the deliveries dispatch started.
+func waitSends(w *AlertWebhooks) {
+	for i := 0; i < cap(w.slots); i++ {
+		w.slots <- struct{}{}
+	}
+	for i := 0; i < cap(w.slots); i++ {
+		<-w.slots
+	}
+}
+
+func TestAlertWebhooks(t *testing.T) {
+	var mu sync.Mutex
+	var received []map[string]interface{}
+	failing := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/down" {
+			failing++
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var m map[string]interface{}
+		if err := json.Unmarshal(body, &m); err != nil {
+			t.Errorf("payload %s: %v", body, err)
+		}
+		received = append(received, m)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("alert_webhook_urls="+srv.URL+"/hook,"+srv.URL+"/down\n"+
+		"alert_webhook_obj_types=tomcat\nalert_webhook_retry_count=2\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	d, waits := openTestDeliveries(t, dir)
+	w := NewAlertWebhooks(dir, d, func(int32) string { return "/host/app1" })
+	defer w.Close()
+	ctx := context.Background()
+
+	w.dispatch(ctx, &pack.AlertPack{Level: 2, ObjType: "tomcat", ObjHash: 7, Time: 1000, Title: "HIGH_TPS", Message: `TPS "150"`})
+	w.dispatch(ctx, &pack.AlertPack{Level: 0, ObjType: "tomcat", Title: "LOW_LEVEL"})
+	w.dispatch(ctx, &pack.AlertPack{Level: 3, ObjType: "mysql", Title: "OTHER_TYPE"})
+	waitSends(w)
+
+	if len(received) != 1 {
+		t.Fatalf("received %v", received)
+	}
+	got := received[0]
+	if got["level"] != "ERROR" || got["title"] != "HIGH_TPS" || got["message"] != `TPS "150"` ||
+		got["objName"] != "/host/app1" || got["objHash"] != float64(7) || got["time"] != float64(1000) {
+		t.Errorf("payload %v", got)
+	}
+	if failing != 3 || len(*waits) != 2 {
+		t.Errorf("%d attempts, %d waits on the failing webhook; want 3 and 2", failing, len(*waits))
+	}
+	if failed := d.List(StatusFailed, "webhook", 0); len(failed) != 1 || failed[0].Source != "alert" {
+		t.Errorf("failed deliveries %+v", failed)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "notify", "alert_dead_letter.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dl DeadLetter
+	if err := json.Unmarshal(data, &dl); err != nil || dl.URL != srv.URL+"/down" || dl.Title != "HIGH_TPS" ||
+		!strings.Contains(dl.Error, "503") || !json.Valid([]byte(dl.Payload)) {
+		t.Errorf("dead letter %s: %v", data, err)
+	}
+}
+
+func TestAlertWebhooksRender(t *testing.T) {
+	w := NewAlertWebhooks(t.TempDir(), nil, nil)
+	ap := &pack.AlertPack{Level: 1, Title: "T", Message: "line1\nline2", ObjHash: -3}
+	if got := w.Render(`{"text":"[${level}] ${title}: ${message}","hash":${objHash}}`, ap); got != `{"text":"[WARN] T: line1\nline2","hash":-3}` {
+		t.Errorf("custom template = %s", got)
+	}
+	// Invalid JSON falls back to the default template.
+	if got := w.Render(`{"text":${title}}`, ap); !strings.HasPrefix(got, `{"level":"WARN","title":"T"`) {
+		t.Errorf("fallback = %s", got)
+	}
+}
@@ -0,0 +1,232 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// deliveryKeep is the number of deliveries kept; older ones are dropped from
+// the log when it is opened.
+const deliveryKeep = 1000
+
+// Delivery statuses.
+const (
+	StatusSending = "sending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+// Delivery is the outcome of sending one message over one channel.
+type Delivery struct {
+	ID       int64  `json:"id"`
+	Time     int64  `json:"time"` // first attempt, ms
+	Source   string `json:"source"`
+	Channel  string `json:"channel"`
+	Subject  string `json:"subject"`
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"` // of the last failed attempt
+	// Elapsed is the time from the first attempt until the last ended, in ms.
+	Elapsed int64 `json:"elapsed"`
+}
+
+// RetryPolicy controls how often a failed send is repeated.
+type RetryPolicy struct {
+	Retries int           // attempts after the first
+	Backoff time.Duration // wait before the first retry, doubled for each further one
+}
+
+// Deliveries sends messages with retries and records the outcome of each,
+// so operators can check that a notification actually left the server.
+// Finished deliveries are appended as JSON lines to notify/deliveries.jsonl
+// under the data directory.
+type Deliveries struct {
+	mu     sync.Mutex
+	path   string
+	list   []*Delivery // oldest first
+	nextID int64
+	file   *os.File
+
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// OpenDeliveries loads the deliveries recorded under baseDir, keeping the
+// last deliveryKeep.
+func OpenDeliveries(baseDir string) (*Deliveries, error) {
+	d := &Deliveries{path: filepath.Join(baseDir, "notify", "deliveries.jsonl"), sleep: sleepCtx}
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Send delivers m over ch, retrying failures as policy allows, and records
+// the delivery under source (such as "report"). It returns the error of the
+// last attempt.
+func (d *Deliveries) Send(ctx context.Context, ch Channel, m Message, source string, policy RetryPolicy) error {
+	start := time.Now()
+	d.mu.Lock()
+	d.nextID++
+	dl := &Delivery{ID: d.nextID, Time: start.UnixMilli(), Source: source, Channel: ch.Name(),
+		Subject: m.Subject, Status: StatusSending}
+	d.list = append(d.list, dl)
+	if len(d.list) > deliveryKeep {
+		d.list = d.list[len(d.list)-deliveryKeep:]
+	}
+	d.mu.Unlock()
+
+	backoff := policy.Backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = ch.Send(ctx, m)
+		d.mu.Lock()
+		dl.Attempts = attempt
+		if err != nil {
+			dl.Error = err.Error()
+		}
+		d.mu.Unlock()
+		if err == nil || attempt > policy.Retries {
+			break
+		}
+		slog.Warn("Notification failed, retrying", "channel", ch.Name(), "subject", m.Subject,
+			"attempt", attempt, "error", err)
+		if d.sleep(ctx, backoff) != nil {
+			break
+		}
+		backoff *= 2
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dl.Status = StatusSent
+	if err != nil {
+		dl.Status = StatusFailed
+	}
+	dl.Elapsed = time.Since(start).Milliseconds()
+	if werr := d.appendLocked(dl); werr != nil {
+		slog.Warn("Notification delivery log write failed", "error", werr)
+	}
+	return err
+}
+
+// List returns up to max deliveries (all if max <= 0), newest first,
+// optionally only those with the given status or channel.
+func (d *Deliveries) List(status, channel string, max int) []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var result []Delivery
+	for i := len(d.list) - 1; i >= 0; i-- {
+		dl := d.list[i]
+		if (status != "" && dl.Status != status) || (channel != "" && dl.Channel != channel) {
+			continue
+		}
+		result = append(result, *dl)
+		if max > 0 && len(result) >= max {
+			break
+		}
+	}
+	return result
+}
+
+func (d *Deliveries) appendLocked(dl *Delivery) error {
+	if d.file == nil {
+		if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(d.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		d.file = f
+	}
+	data, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+	_, err = d.file.Write(append(data, '\n'))
+	return err
+}
+
+// load reads the log, rewriting it if it holds more than deliveryKeep
+// deliveries. Lines that cannot be parsed are skipped.
+func (d *Deliveries) load() error {
+	f, err := os.Open(d.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	total := 0
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var dl Delivery
+		if json.Unmarshal(sc.Bytes(), &dl) != nil {
+			continue
+		}
+		total++
+		d.list = append(d.list, &dl)
+		if len(d.list) > deliveryKeep {
+			d.list = d.list[1:]
+		}
+		d.nextID = max(d.nextID, dl.ID)
+	}
+	f.Close()
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if total > deliveryKeep {
+		return d.rewrite()
+	}
+	return nil
+}
+
+// rewrite replaces the log with the kept deliveries.
+func (d *Deliveries) rewrite() error {
+	tmp := d.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, dl := range d.list {
+		data, _ := json.Marshal(dl)
+		w.Write(append(data, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.path)
+}
+
+// Close closes the log file.
+func (d *Deliveries) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file != nil {
+		d.file.Close()
+		d.file = nil
+	}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
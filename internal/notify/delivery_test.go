@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyChannel refuses the first failures messages.
+type flakyChannel struct {
+	failures int
+	calls    int
+}
+
+func (c *flakyChannel) Name() string { return "flaky" }
+
+func (c *flakyChannel) Send(ctx context.Context, m Message) error {
+	c.calls++
+	if c.calls <= c.failures {
+		return fmt.Errorf("attempt %d refused", c.calls)
+	}
+	return nil
+}
+
+func openTestDeliveries(t *testing.T, dir string) (*Deliveries, *[]time.Duration) {
+	d, err := OpenDeliveries(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(d.Close)
+	var waits []time.Duration
+	d.sleep = func(ctx context.Context, w time.Duration) error {
+		waits = append(waits, w)
+		return nil
+	}
+	return d, &waits
+}
+
+func TestDeliveries_Retry(t *testing.T) {
+	dir := t.TempDir()
+	d, waits := openTestDeliveries(t, dir)
+	policy := RetryPolicy{Retries: 2, Backoff: time.Second}
+
+	if err := d.Send(context.Background(), &flakyChannel{failures: 2}, Message{Subject: "ok"}, "report", policy); err != nil {
+		t.Fatalf("Send = %v, want delivered on the third attempt", err)
+	}
+	if len(*waits) != 2 || (*waits)[0] != time.Second || (*waits)[1] != 2*time.Second {
+		t.Errorf("backoff = %v, want [1s 2s]", *waits)
+	}
+	err := d.Send(context.Background(), &flakyChannel{failures: 5}, Message{Subject: "lost"}, "report", policy)
+	if err == nil || err.Error() != "attempt 3 refused" {
+		t.Fatalf("Send = %v, want the last attempt's error", err)
+	}
+
+	list := d.List("", "", 0)
+	if len(list) != 2 {
+		t.Fatalf("List = %+v, want 2 deliveries", list)
+	}
+	lost, ok := list[0], list[1]
+	if lost.Subject != "lost" || lost.Status != StatusFailed || lost.Attempts != 3 || lost.Error != "attempt 3 refused" {
+		t.Errorf("failed delivery = %+v", lost)
+	}
+	if ok.Status != StatusSent || ok.Attempts != 3 || ok.Source != "report" || ok.Channel != "flaky" {
+		t.Errorf("sent delivery = %+v", ok)
+	}
+	if got := d.List(StatusFailed, "", 0); len(got) != 1 || got[0].ID != lost.ID {
+		t.Errorf("List(failed) = %+v", got)
+	}
+	if got := d.List("", "mail", 0); len(got) != 0 {
+		t.Errorf("List(mail) = %+v, want none", got)
+	}
+
+	// The log survives a restart and IDs keep increasing.
+	d.Close()
+	reopened, _ := openTestDeliveries(t, dir)
+	if got := reopened.List("", "", 1); len(got) != 1 || got[0].Subject != "lost" {
+		t.Fatalf("reopened List = %+v", got)
+	}
+	reopened.Send(context.Background(), &flakyChannel{}, Message{Subject: "next"}, "report", RetryPolicy{})
+	if got := reopened.List("", "", 1); got[0].ID != lost.ID+1 {
+		t.Errorf("next ID = %d, want %d", got[0].ID, lost.ID+1)
+	}
+}
+
+func TestDeliveries_CancelStopsRetries(t *testing.T) {
+	d, _ := openTestDeliveries(t, t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	d.sleep = func(ctx context.Context, w time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+	ch := &flakyChannel{failures: 5}
+	if err := d.Send(ctx, ch, Message{}, "report", RetryPolicy{Retries: 3}); err == nil {
+		t.Fatal("Send succeeded")
+	}
+	if ch.calls != 1 {
+		t.Errorf("calls = %d, want 1", ch.calls)
+	}
+}
+
+func TestDeliveries_TrimOnOpen(t *testing.T) {
+	dir := t.TempDir()
+	d, _ := openTestDeliveries(t, dir)
+	for i := 0; i < deliveryKeep+5; i++ {
+		d.Send(context.Background(), &flakyChannel{}, Message{Subject: fmt.Sprint(i)}, "test", RetryPolicy{})
+	}
+	d.Close()
+
+	reopened, _ := openTestDeliveries(t, dir)
+	list := reopened.List("", "", 0)
+	if len(list) != deliveryKeep || list[len(list)-1].Subject != "5" {
+		t.Fatalf("kept %d, oldest %q", len(list), list[len(list)-1].Subject)
+	}
+	data, err := os.ReadFile(reopened.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != deliveryKeep {
+		t.Errorf("log has %d lines, want %d", n, deliveryKeep)
+	}
+}
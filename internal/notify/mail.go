@@ -0,0 +1,225 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+)
+
+// SMTP connection security modes of Mail.TLS.
+const (
+	TLSStartTLS = "starttls" // upgrade with STARTTLS when the server offers it
+	TLSImplicit = "tls"      // TLS from the start (SMTPS, usually port 465)
+	TLSNone     = "none"     // never encrypt
+)
+
+// smtpDialTimeout bounds connecting to the SMTP server.
+const smtpDialTimeout = 30 * time.Second
+
+// Mail sends messages by SMTP.
+type Mail struct {
+	Addr     string // host:port
+	User     string // PLAIN auth user; empty for no auth
+	Password string
+	From     string
+	To       []string
+	TLS      string // TLSStartTLS (default if empty), TLSImplicit or TLSNone
+
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewMail creates a Mail channel. Recipients are trimmed and empty entries
+// dropped, so a comma-separated setting can be split and passed as is.
+func NewMail(addr, user, password, from string, to []string) *Mail {
+	var rcpt []string
+	for _, t := range to {
+		if t = strings.TrimSpace(t); t != "" {
+			rcpt = append(rcpt, t)
+		}
+	}
+	m := &Mail{Addr: addr, User: user, Password: password, From: from, To: rcpt}
+	m.sendMail = m.deliver
+	return m
+}
+
+// NewConfiguredMail creates a Mail channel to the recipients to through the
+// SMTP server of the notify_smtp_* settings.
+func NewConfiguredMail(cfg *config.Config, to []string) *Mail {
+	m := NewMail(cfg.NotifySMTPAddr(), cfg.NotifySMTPUser(), cfg.NotifySMTPPassword(), cfg.NotifyMailFrom(), to)
+	m.TLS = cfg.NotifySMTPTLS()
+	return m
+}
+
+// Name returns "mail".
+func (m *Mail) Name() string {
+	return "mail"
+}
+
+// Send composes m as a MIME mail and hands it to the SMTP server.
+func (m *Mail) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if m.Addr == "" {
+		return errors.New("mail: no SMTP server configured")
+	}
+	if len(m.To) == 0 {
+		return errors.New("mail: no recipients")
+	}
+	data, err := m.compose(msg, time.Now())
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.User != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return fmt.Errorf("mail: bad SMTP address %q: %w", m.Addr, err)
+		}
+		auth = smtp.PlainAuth("", m.User, m.Password, host)
+	}
+	if err := m.sendMail(m.Addr, auth, m.From, m.To, data); err != nil {
+		return fmt.Errorf("mail: %w", err)
+	}
+	return nil
+}
+
+// deliver sends msg over a connection secured as m.TLS asks. PLAIN auth is
+// refused over an unencrypted connection to anything but localhost.
+func (m *Mail) deliver(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+	mode := strings.ToLower(strings.TrimSpace(m.TLS))
+	if mode == "" {
+		mode = TLSStartTLS
+	}
+	if mode != TLSStartTLS && mode != TLSImplicit && mode != TLSNone {
+		return fmt.Errorf("bad TLS mode %q: use %s, %s or %s", m.TLS, TLSStartTLS, TLSImplicit, TLSNone)
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	tlsConfig := &tls.Config{ServerName: host}
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+	var conn net.Conn
+	if mode == TLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if mode == TLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+	if a != nil {
+		if err := c.Auth(a); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// compose builds the RFC 5322 message. A message with attachments is sent
+// as multipart/mixed with the body as its first part.
+func (m *Mail) compose(msg Message, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	bodyType, body := "text/plain; charset=utf-8", msg.Text
+	if msg.HTML != "" {
+		bodyType, body = "text/html; charset=utf-8", msg.HTML
+	}
+
+	if len(msg.Attachments) == 0 {
+		fmt.Fprintf(&buf, "Content-Type: %s\r\n", bodyType)
+		buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		writeBase64(&buf, []byte(body))
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {bodyType},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeBase64(part, []byte(body))
+	for _, a := range msg.Attachments {
+		ct := a.ContentType
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {ct},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		writeBase64(part, a.Data)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 writes data base64-encoded in 76-column lines.
+func writeBase64(w io.Writer, data []byte) {
+	enc := base64.StdEncoding.EncodeToString(data)
+	for len(enc) > 76 {
+		w.Write([]byte(enc[:76] + "\r\n"))
+		enc = enc[76:]
+	}
+	w.Write([]byte(enc + "\r\n"))
+}
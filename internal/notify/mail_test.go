@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/smtp"
+	"strings"
+	"testing"
+)
+
+func TestMail_Send(t *testing.T) {
+	m := NewMail("smtp.example.com:25", "user", "secret", "scouter@example.com", []string{" ops@example.com", "", "dev@example.com "})
+	var gotTo []string
+	var gotMsg string
+	var gotAuth smtp.Auth
+	m.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAuth, gotTo, gotMsg = a, to, string(msg)
+		return nil
+	}
+
+	err := m.Send(context.Background(), Message{
+		Subject:     "Weekly report",
+		HTML:        "<p>hello</p>",
+		Attachments: []Attachment{{Name: "weekly.csv", ContentType: "text/csv", Data: []byte("a,b\n")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gotTo) != 2 || gotTo[0] != "ops@example.com" || gotTo[1] != "dev@example.com" {
+		t.Errorf("to = %q", gotTo)
+	}
+	if gotAuth == nil {
+		t.Error("expected PLAIN auth")
+	}
+	for _, want := range []string{"Subject: Weekly report", "multipart/mixed", "text/html", `filename=weekly.csv`} {
+		if !strings.Contains(gotMsg, want) {
+			t.Errorf("message misses %q:\n%s", want, gotMsg)
+		}
+	}
+}
+
+func TestMail_SendNotConfigured(t *testing.T) {
+	if err := NewMail("", "", "", "a@b", []string{"c@d"}).Send(context.Background(), Message{}); err == nil {
+		t.Error("expected error without SMTP server")
+	}
+	if err := NewMail("h:25", "", "", "a@b", nil).Send(context.Background(), Message{}); err == nil {
+		t.Error("expected error without recipients")
+	}
+}
+
+// serveSMTP answers one SMTP session on ln without STARTTLS or AUTH and
+// returns the commands and data it received.
+func serveSMTP(ln net.Listener) <-chan []string {
+	done := make(chan []string, 1)
+	go func() {
+		var lines []string
+		defer func() { done <- lines }()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 test")
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch {
+			case inData && line == ".":
+				inData = false
+				reply("250 queued")
+			case inData:
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 test")
+			case line == "DATA":
+				inData = true
+				reply("354 go ahead")
+			case line == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return done
+}
+
+func TestMail_DeliverPlain(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	session := serveSMTP(ln)
+
+	m := NewMail(ln.Addr().String(), "", "", "scouter@example.com", []string{"ops@example.com"})
+	m.TLS = TLSNone
+	if err := m.Send(context.Background(), Message{Subject: "ALERT", Text: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(<-session, "\n")
+	for _, want := range []string{"MAIL FROM:<scouter@example.com>", "RCPT TO:<ops@example.com>", "Subject: ALERT", "QUIT"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("session misses %q:\n%s", want, got)
+		}
+	}
+
+	m.TLS = "ssl"
+	if err := m.Send(context.Background(), Message{Subject: "x"}); err == nil || !strings.Contains(err.Error(), "bad TLS mode") {
+		t.Errorf("Send with bad TLS mode = %v", err)
+	}
+}
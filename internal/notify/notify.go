@@ -0,0 +1,26 @@
+// Package notify delivers messages such as reports to operators over
+// external channels.
+package notify
+
+import "context"
+
+// Attachment is a file sent along with a message.
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Message is a channel-independent notification.
+type Message struct {
+	Subject     string
+	Text        string // plain text body
+	HTML        string // optional HTML body; preferred over Text where supported
+	Attachments []Attachment
+}
+
+// Channel delivers messages to one destination.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, m Message) error
+}
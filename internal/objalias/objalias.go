@@ -0,0 +1,257 @@
+// Package objalias gives objects a stable identity across renames. Alias
+// rules map agent objNames, e.g. the pod-named objects of a Kubernetes
+// deployment, to one alias objName; packs are stored under the alias and the
+// history recorded under the original names is merged in at read time.
+package objalias
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/db/kv"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// kvKey is the KV store key holding the recorded source objHashes of every
+// alias as one JSON object.
+const kvKey = "object_aliases"
+
+// maxSources bounds the source objHashes kept per alias; the oldest are
+// forgotten first.
+const maxSources = 100
+
+// Rule maps the objNames matching Pattern (path.Match syntax) to Name.
+type Rule struct {
+	Pattern string
+	Name    string
+}
+
+// ParseRules parses "pattern=name" pairs separated by commas, e.g.
+// "/order-api-*/tomcat=/order-api/tomcat". An empty spec has no rules.
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		pattern, name, ok := strings.Cut(part, "=")
+		pattern, name = strings.TrimSpace(pattern), strings.TrimSpace(name)
+		if !ok || pattern == "" || name == "" {
+			return nil, fmt.Errorf("bad alias rule %q", part)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("bad pattern %q: %w", pattern, err)
+		}
+		rules = append(rules, Rule{Pattern: pattern, Name: name})
+	}
+	return rules, nil
+}
+
+// Manager applies the object_alias rules to incoming packs and records which
+// objHashes each alias was fed from.
+type Manager struct {
+	store *kv.KVStore
+
+	mu      sync.RWMutex
+	spec    string
+	rules   []Rule
+	agents  map[int32]int32   // agent objHash -> alias objHash
+	sources map[int32][]int32 // alias objHash -> agent objHashes, oldest first
+}
+
+// NewManager creates a Manager recording sources in store.
+func NewManager(store *kv.KVStore) *Manager {
+	m := &Manager{
+		store:   store,
+		agents:  make(map[int32]int32),
+		sources: make(map[int32][]int32),
+	}
+	if raw, ok := store.Get(kvKey); ok && raw != "" {
+		var stored map[string][]int32
+		if err := json.Unmarshal([]byte(raw), &stored); err != nil {
+			slog.Warn("Object aliases: bad stored sources", "key", kvKey, "error", err)
+		}
+		for k, hashes := range stored {
+			if h, err := strconv.ParseInt(k, 10, 32); err == nil {
+				m.sources[int32(h)] = hashes
+			}
+		}
+	}
+	return m
+}
+
+// currentRules returns the rules of the object_alias setting, re-parsing
+// them only when it changed. Caller must hold m.mu.
+func (m *Manager) currentRules() []Rule {
+	spec := ""
+	if cfg := config.Get(); cfg != nil {
+		spec = cfg.ObjectAlias()
+	}
+	if spec == m.spec {
+		return m.rules
+	}
+	rules, err := ParseRules(spec)
+	if err != nil {
+		slog.Warn("Object aliases: ignoring object_alias", "error", err)
+	}
+	m.spec, m.rules = spec, rules
+	return rules
+}
+
+// Resolve returns the alias name of objName, if a rule matches it.
+func (m *Manager) Resolve(objName string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return resolve(m.currentRules(), objName)
+}
+
+func resolve(rules []Rule, objName string) (string, bool) {
+	for _, r := range rules {
+		if ok, _ := path.Match(r.Pattern, objName); ok && r.Name != objName {
+			return r.Name, true
+		}
+	}
+	return "", false
+}
+
+// Rewrite moves p to the alias identity of its object. ObjectPacks and
+// counter packs are matched by objName; other packs follow the objHash of an
+// ObjectPack seen earlier, so packs sent before the object's first heartbeat
+// keep the agent objHash and are merged at read time.
+func (m *Manager) Rewrite(p pack.Pack) {
+	switch x := p.(type) {
+	case *pack.ObjectPack:
+		agentHash := x.ObjHash
+		if agentHash == 0 {
+			agentHash = util.HashString(x.ObjName)
+		}
+		alias, ok := m.Resolve(x.ObjName)
+		if !ok {
+			m.forget(agentHash)
+			return
+		}
+		aliasHash := util.HashString(alias)
+		m.record(agentHash, aliasHash)
+		if x.Tags != nil {
+			x.Tags.Put("aliasOf", value.NewTextValue(x.ObjName))
+		}
+		x.ObjName, x.ObjHash = alias, aliasHash
+	case *pack.PerfCounterPack:
+		if alias, ok := m.Resolve(x.ObjName); ok {
+			x.ObjName = alias
+		}
+	case *pack.InteractionPerfCounterPack:
+		if alias, ok := m.Resolve(x.ObjName); ok {
+			x.ObjName = alias
+		}
+	case *pack.XLogPack:
+		x.ObjHash = m.aliasOf(x.ObjHash)
+	case *pack.XLogProfilePack:
+		x.ObjHash = m.aliasOf(x.ObjHash)
+	case *pack.XLogProfilePack2:
+		x.ObjHash = m.aliasOf(x.ObjHash)
+	case *pack.AlertPack:
+		x.ObjHash = m.aliasOf(x.ObjHash)
+	case *pack.SummaryPack:
+		x.ObjHash = m.aliasOf(x.ObjHash)
+	case *pack.BatchPack:
+		x.ObjHash = m.aliasOf(x.ObjHash)
+	case *pack.SpanPack:
+		x.ObjHash = m.aliasOf(x.ObjHash)
+	case *pack.StackPack:
+		x.ObjHash = m.aliasOf(x.ObjHash)
+	case *pack.StatusPack:
+		x.ObjHash = m.aliasOf(x.ObjHash)
+	}
+}
+
+func (m *Manager) aliasOf(objHash int32) int32 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if a, ok := m.agents[objHash]; ok {
+		return a
+	}
+	return objHash
+}
+
+// record maps agentHash to aliasHash and adds it to the alias's sources.
+func (m *Manager) record(agentHash, aliasHash int32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.agents[agentHash] == aliasHash {
+		return
+	}
+	m.agents[agentHash] = aliasHash
+	hashes := m.sources[aliasHash]
+	if slices.Contains(hashes, agentHash) {
+		return
+	}
+	hashes = append(hashes, agentHash)
+	if len(hashes) > maxSources {
+		hashes = hashes[len(hashes)-maxSources:]
+	}
+	m.sources[aliasHash] = hashes
+	m.save()
+}
+
+// forget drops the mapping of an agent whose objName no longer matches a rule.
+func (m *Manager) forget(agentHash int32) {
+	m.mu.Lock()
+	delete(m.agents, agentHash)
+	m.mu.Unlock()
+}
+
+// save stores the sources. Caller must hold m.mu.
+func (m *Manager) save() {
+	stored := make(map[string][]int32, len(m.sources))
+	for h, hashes := range m.sources {
+		stored[strconv.Itoa(int(h))] = hashes
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return
+	}
+	m.store.Set(kvKey, string(data))
+}
+
+// Sources returns the objHashes whose history belongs to objHash: the agents
+// recorded for the alias and the literal objNames of rules naming it. It is
+// empty for objects without an alias.
+func (m *Manager) Sources(objHash int32) []int32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := slices.Clone(m.sources[objHash])
+	for _, r := range m.currentRules() {
+		if strings.ContainsAny(r.Pattern, `*?[\`) || util.HashString(r.Name) != objHash {
+			continue
+		}
+		if h := util.HashString(r.Pattern); !slices.Contains(result, h) {
+			result = append(result, h)
+		}
+	}
+	return result
+}
+
+// Agents returns the agent objHashes currently mapped to objHash, for
+// reaching the agents of an alias over TCP.
+func (m *Manager) Agents(objHash int32) []int32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []int32
+	for agent, alias := range m.agents {
+		if alias == objHash {
+			result = append(result, agent)
+		}
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package objalias
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/db/kv"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+func loadConfig(t *testing.T, content string) {
+	t.Helper()
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	if err := os.WriteFile(conf, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(" /order-api-*/tomcat=/order-api/tomcat , /old/web=/new/web,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0] != (Rule{"/order-api-*/tomcat", "/order-api/tomcat"}) || rules[1].Name != "/new/web" {
+		t.Errorf("rules = %+v", rules)
+	}
+	for _, spec := range []string{"/a", "=/b", "/a-[/x=/b"} {
+		if _, err := ParseRules(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestManager_Rewrite(t *testing.T) {
+	loadConfig(t, "object_alias=/order-api-*/tomcat=/order-api/tomcat,/old/web=/new/web\n")
+	store := kv.NewKVStore(t.TempDir(), "global.json")
+	m := NewManager(store)
+
+	agentHash := util.HashString("/order-api-7f9c/tomcat")
+	aliasHash := util.HashString("/order-api/tomcat")
+
+	// Packs before the first heartbeat keep the agent identity.
+	early := &pack.XLogPack{ObjHash: agentHash}
+	m.Rewrite(early)
+	if early.ObjHash != agentHash {
+		t.Errorf("early xlog moved to %d", early.ObjHash)
+	}
+
+	op := &pack.ObjectPack{ObjName: "/order-api-7f9c/tomcat", ObjHash: agentHash, Tags: value.NewMapValue()}
+	m.Rewrite(op)
+	if op.ObjName != "/order-api/tomcat" || op.ObjHash != aliasHash {
+		t.Errorf("object = %s/%d", op.ObjName, op.ObjHash)
+	}
+	if v, _ := op.Tags.Get("aliasOf"); v == nil || v.(*value.TextValue).Value != "/order-api-7f9c/tomcat" {
+		t.Errorf("aliasOf tag = %v", v)
+	}
+
+	xp := &pack.XLogPack{ObjHash: agentHash}
+	m.Rewrite(xp)
+	cp := &pack.PerfCounterPack{ObjName: "/order-api-7f9c/tomcat"}
+	m.Rewrite(cp)
+	other := &pack.XLogPack{ObjHash: 42}
+	m.Rewrite(other)
+	if xp.ObjHash != aliasHash || cp.ObjName != "/order-api/tomcat" || other.ObjHash != 42 {
+		t.Errorf("xlog %d, counter %s, unrelated %d", xp.ObjHash, cp.ObjName, other.ObjHash)
+	}
+	if agents := m.Agents(aliasHash); len(agents) != 1 || agents[0] != agentHash {
+		t.Errorf("agents = %v", agents)
+	}
+
+	// Sources survive a restart; literal rules name their legacy object.
+	m = NewManager(store)
+	if src := m.Sources(aliasHash); len(src) != 1 || src[0] != agentHash {
+		t.Errorf("sources after reload = %v", src)
+	}
+	if src := m.Sources(util.HashString("/new/web")); !slices.Equal(src, []int32{util.HashString("/old/web")}) {
+		t.Errorf("literal rule sources = %v", src)
+	}
+	if src := m.Sources(42); len(src) != 0 {
+		t.Errorf("unaliased sources = %v", src)
+	}
+
+	// Removing the rule stops the rewrite.
+	loadConfig(t, "")
+	op = &pack.ObjectPack{ObjName: "/order-api-7f9c/tomcat", ObjHash: agentHash}
+	m.Rewrite(op)
+	xp = &pack.XLogPack{ObjHash: agentHash}
+	m.Rewrite(xp)
+	if op.ObjHash != agentHash || xp.ObjHash != agentHash {
+		t.Errorf("rewritten without a rule: %d, %d", op.ObjHash, xp.ObjHash)
+	}
+}
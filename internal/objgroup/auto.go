@@ -0,0 +1,134 @@
+package objgroup
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+)
+
+// AutoRule derives a group name from the objNames Pattern matches. Group may
+// refer to the groups of Pattern as $1 or ${name}.
+type AutoRule struct {
+	Pattern *regexp.Regexp
+	Group   string
+}
+
+// ParseAutoRules parses rules separated by ";", each of the form
+// "regex => group", e.g. "^/[^/]+/(checkout|cart)-\d+$ => $1".
+// An empty spec has no rules.
+func ParseAutoRules(spec string) ([]AutoRule, error) {
+	var rules []AutoRule
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		expr, group, ok := strings.Cut(part, "=>")
+		expr, group = strings.TrimSpace(expr), strings.TrimSpace(group)
+		if !ok || expr == "" || group == "" {
+			return nil, fmt.Errorf("bad auto group rule %q", part)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern %q: %w", expr, err)
+		}
+		rules = append(rules, AutoRule{Pattern: re, Group: group})
+	}
+	return rules, nil
+}
+
+// AutoGroupOf returns the group the first matching rule derives for objName.
+func AutoGroupOf(rules []AutoRule, objName string) (string, bool) {
+	for _, r := range rules {
+		m := r.Pattern.FindStringSubmatchIndex(objName)
+		if m == nil {
+			continue
+		}
+		name := strings.TrimSpace(string(r.Pattern.ExpandString(nil, r.Group, objName, m)))
+		if name != "" {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// AutoGroup is a group derived by the object_auto_group rules.
+type AutoGroup struct {
+	Name    string
+	ObjHash []int32
+}
+
+// autoRules returns the object_auto_group rules, re-parsing them only when
+// the setting changed.
+func (m *Manager) autoRules() []AutoRule {
+	spec := ""
+	if cfg := config.Get(); cfg != nil {
+		spec = cfg.ObjectAutoGroup()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if spec == m.autoSpec {
+		return m.auto
+	}
+	rules, err := ParseAutoRules(spec)
+	if err != nil {
+		slog.Warn("Object groups: ignoring object_auto_group", "error", err)
+	}
+	m.autoSpec, m.auto = spec, rules
+	return rules
+}
+
+// AutoGroups returns the groups the object_auto_group rules derive from
+// objects, sorted by name. A derived group named like an explicit group is
+// left out: the explicit definition takes precedence.
+func (m *Manager) AutoGroups(objects []*cache.ObjectInfo) []AutoGroup {
+	rules := m.autoRules()
+	if len(rules) == 0 {
+		return nil
+	}
+	explicit, _ := m.groups.Snapshot()
+
+	members := make(map[string][]int32)
+	for _, info := range objects {
+		name, ok := AutoGroupOf(rules, info.Pack.ObjName)
+		if !ok {
+			continue
+		}
+		if _, shadowed := explicit[name]; shadowed {
+			continue
+		}
+		members[name] = append(members[name], info.Pack.ObjHash)
+	}
+	result := make([]AutoGroup, 0, len(members))
+	for name, hashes := range members {
+		slices.Sort(hashes)
+		result = append(result, AutoGroup{Name: name, ObjHash: slices.Compact(hashes)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// resolveAuto returns the members of the derived group name.
+func (m *Manager) resolveAuto(name string, objects []*cache.ObjectInfo) ([]int32, bool) {
+	rules := m.autoRules()
+	if len(rules) == 0 {
+		return nil, false
+	}
+	var result []int32
+	for _, info := range objects {
+		if g, ok := AutoGroupOf(rules, info.Pack.ObjName); ok && g == name {
+			result = append(result, info.Pack.ObjHash)
+		}
+	}
+	if len(result) == 0 {
+		return nil, false
+	}
+	slices.Sort(result)
+	return slices.Compact(result), true
+}
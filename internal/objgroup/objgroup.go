@@ -0,0 +1,150 @@
+// Package objgroup manages server-side object groups: named sets of objects
+// given as a static list or as objName patterns, so a cluster such as
+// "checkout-cluster" can be charted without the client listing every objHash.
+// Groups can also be derived from objName conventions by the
+// object_auto_group rules, so scaled-out instances form one service without
+// a definition per service.
+package objgroup
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/kv"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// kvKey is the KV store key holding all group definitions as one JSON object.
+const kvKey = "object_groups"
+
+// Group is one object group definition.
+type Group struct {
+	Name string `json:"name"`
+	// Objects lists members by objName (e.g. /host1/tomcat1) or objHash.
+	Objects []string `json:"objects,omitempty"`
+	// Patterns select members by objName using path.Match syntax,
+	// e.g. /checkout-*/* for every object on the checkout hosts.
+	Patterns []string `json:"patterns,omitempty"`
+	// ObjType, if set, restricts pattern matches to one object type.
+	ObjType string `json:"objType,omitempty"`
+}
+
+// Validate checks that the group has a name, at least one member rule and
+// well-formed patterns.
+func (g *Group) Validate() error {
+	if strings.TrimSpace(g.Name) == "" {
+		return errors.New("group name is empty")
+	}
+	if len(g.Objects) == 0 && len(g.Patterns) == 0 {
+		return errors.New("group has no objects or patterns")
+	}
+	for _, p := range g.Patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("bad pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the object belongs to the group.
+func (g *Group) Matches(objHash int32, objName, objType string) bool {
+	for _, o := range g.Objects {
+		if o == objName {
+			return true
+		}
+		if h, err := strconv.ParseInt(o, 10, 32); err == nil && int32(h) == objHash {
+			return true
+		}
+	}
+	if g.ObjType != "" && g.ObjType != objType {
+		return false
+	}
+	for _, p := range g.Patterns {
+		if ok, _ := path.Match(p, objName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Manager stores group definitions in a KV store.
+type Manager struct {
+	groups *kv.JSONMap[Group]
+
+	mu       sync.Mutex
+	autoSpec string // object_auto_group value auto was parsed from
+	auto     []AutoRule
+}
+
+// NewManager creates a Manager backed by store.
+func NewManager(store *kv.KVStore) *Manager {
+	return &Manager{groups: kv.NewJSONMap[Group](store, kvKey, nil)}
+}
+
+// List returns all groups sorted by name.
+func (m *Manager) List() []Group {
+	result := m.groups.Values()
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Get returns the named group.
+func (m *Manager) Get(name string) (Group, bool) {
+	return m.groups.Get(name)
+}
+
+// Put creates or replaces a group.
+func (m *Manager) Put(g Group) error {
+	g.Name = strings.TrimSpace(g.Name)
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	return m.groups.Put(g.Name, g)
+}
+
+// Delete removes a group and reports whether it existed.
+func (m *Manager) Delete(name string) (bool, error) {
+	return m.groups.Delete(name)
+}
+
+// Resolve returns the objHashes of the known objects in the named group,
+// sorted ascending. Static members given by name are included even if they
+// have not been seen, so past data of removed objects can still be read.
+// A name without an explicit definition is resolved as a group derived by
+// the object_auto_group rules.
+func (m *Manager) Resolve(name string, objects []*cache.ObjectInfo) ([]int32, bool) {
+	g, ok := m.Get(name)
+	if !ok {
+		return m.resolveAuto(name, objects)
+	}
+	seen := make(map[int32]bool)
+	var result []int32
+	add := func(h int32) {
+		if !seen[h] {
+			seen[h] = true
+			result = append(result, h)
+		}
+	}
+	for _, o := range g.Objects {
+		if h, err := strconv.ParseInt(o, 10, 32); err == nil {
+			add(int32(h))
+		} else {
+			add(util.HashString(o))
+		}
+	}
+	for _, info := range objects {
+		p := info.Pack
+		if g.Matches(p.ObjHash, p.ObjName, p.ObjType) {
+			add(p.ObjHash)
+		}
+	}
+	slices.Sort(result)
+	return result, true
+}
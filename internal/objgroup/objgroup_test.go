@@ -0,0 +1,170 @@
+package objgroup
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/kv"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+func objects(ops ...*pack.ObjectPack) []*cache.ObjectInfo {
+	var result []*cache.ObjectInfo
+	for _, op := range ops {
+		op.ObjHash = util.HashString(op.ObjName)
+		result = append(result, &cache.ObjectInfo{Pack: op})
+	}
+	return result
+}
+
+func TestGroup_Validate(t *testing.T) {
+	for _, g := range []Group{
+		{Name: "", Objects: []string{"/a"}},
+		{Name: "empty"},
+		{Name: "bad", Patterns: []string{"/checkout-[/*"}},
+	} {
+		if err := g.Validate(); err == nil {
+			t.Errorf("%+v: expected error", g)
+		}
+	}
+	if err := (&Group{Name: "ok", Patterns: []string{"/checkout-*/*"}}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestManager_Resolve(t *testing.T) {
+	store := kv.NewKVStore(t.TempDir(), "global.json")
+	m := NewManager(store)
+
+	err := m.Put(Group{
+		Name:     "checkout-cluster",
+		Objects:  []string{"/legacy/tomcat", "12345"},
+		Patterns: []string{"/checkout-*/*"},
+		ObjType:  "tomcat",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objs := objects(
+		&pack.ObjectPack{ObjName: "/checkout-1/tomcat1", ObjType: "tomcat"},
+		&pack.ObjectPack{ObjName: "/checkout-2/tomcat1", ObjType: "tomcat"},
+		&pack.ObjectPack{ObjName: "/checkout-1/host", ObjType: "linux"},
+		&pack.ObjectPack{ObjName: "/cart-1/tomcat1", ObjType: "tomcat"},
+	)
+	got, ok := m.Resolve("checkout-cluster", objs)
+	if !ok {
+		t.Fatal("group not found")
+	}
+	want := []int32{
+		util.HashString("/checkout-1/tomcat1"),
+		util.HashString("/checkout-2/tomcat1"),
+		util.HashString("/legacy/tomcat"),
+		12345,
+	}
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("Resolve = %v, want %v", got, want)
+	}
+
+	if _, ok := m.Resolve("missing", objs); ok {
+		t.Error("unknown group resolved")
+	}
+}
+
+func TestManager_PersistAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	store := kv.NewKVStore(dir, "global.json")
+	m := NewManager(store)
+	m.Put(Group{Name: "b", Objects: []string{"/b"}})
+	m.Put(Group{Name: "a", Objects: []string{"/a"}})
+	store.Close()
+
+	m = NewManager(kv.NewKVStore(dir, "global.json"))
+	list := m.List()
+	if len(list) != 2 || list[0].Name != "a" || list[1].Name != "b" {
+		t.Fatalf("List = %+v", list)
+	}
+	if found, err := m.Delete("a"); !found || err != nil {
+		t.Fatalf("Delete = %v, %v", found, err)
+	}
+	if found, _ := m.Delete("a"); found {
+		t.Error("deleted twice")
+	}
+	if _, ok := m.Get("a"); ok {
+		t.Error("group still present")
+	}
+}
+
+func TestParseAutoRules(t *testing.T) {
+	rules, err := ParseAutoRules(" ^/prod/(checkout|cart)-[0-9]+$ => $1 ; ^/batch/ => batch ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for objName, want := range map[string]string{
+		"/prod/checkout-1": "checkout",
+		"/prod/cart-12":    "cart",
+		"/batch/job-7":     "batch",
+		"/prod/search-1":   "",
+	} {
+		got, _ := AutoGroupOf(rules, objName)
+		if got != want {
+			t.Errorf("AutoGroupOf(%q) = %q, want %q", objName, got, want)
+		}
+	}
+	for _, spec := range []string{"^/a/", "=> x", "^/a/ =>", "^/a/[ => x"} {
+		if _, err := ParseAutoRules(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestManager_AutoGroups(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	if err := os.WriteFile(conf, []byte("object_auto_group=^/prod/([a-z]+)-[0-9]+$ => $1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Load(conf); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	m := NewManager(kv.NewKVStore(dir, "global.json"))
+	objs := objects(
+		&pack.ObjectPack{ObjName: "/prod/checkout-1"},
+		&pack.ObjectPack{ObjName: "/prod/checkout-2"},
+		&pack.ObjectPack{ObjName: "/prod/cart-1"},
+		&pack.ObjectPack{ObjName: "/dev/cart"},
+	)
+
+	got, ok := m.Resolve("checkout", objs)
+	if !ok || len(got) != 2 {
+		t.Fatalf("Resolve(checkout) = %v, %v; want 2 members", got, ok)
+	}
+	if _, ok := m.Resolve("search", objs); ok {
+		t.Error("Resolve(search) found a group without members")
+	}
+
+	auto := m.AutoGroups(objs)
+	if len(auto) != 2 || auto[0].Name != "cart" || auto[1].Name != "checkout" {
+		t.Fatalf("AutoGroups = %+v", auto)
+	}
+
+	// An explicit group of the same name takes precedence.
+	if err := m.Put(Group{Name: "cart", Objects: []string{"/dev/cart"}}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = m.Resolve("cart", objs)
+	if !slices.Equal(got, []int32{util.HashString("/dev/cart")}) {
+		t.Errorf("Resolve(cart) = %v, want the explicit member", got)
+	}
+	if auto := m.AutoGroups(objs); len(auto) != 1 || auto[0].Name != "checkout" {
+		t.Errorf("AutoGroups = %+v, want only checkout", auto)
+	}
+}
@@ -0,0 +1,217 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The OTLP/JSON encoding of ExportTraceServiceRequest: lowerCamelCase field
+// names, trace and span IDs in hex, 64-bit integers as strings or numbers
+// and enums as numbers (names are accepted too).
+
+type jsonRequest struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []jsonKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []struct {
+			Spans []jsonSpan `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+type jsonSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId"`
+	Name              string         `json:"name"`
+	Kind              jsonEnum       `json:"kind"`
+	StartTimeUnixNano jsonUint64     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   jsonUint64     `json:"endTimeUnixNano"`
+	Attributes        []jsonKeyValue `json:"attributes"`
+	Events            []struct {
+		TimeUnixNano jsonUint64     `json:"timeUnixNano"`
+		Name         string         `json:"name"`
+		Attributes   []jsonKeyValue `json:"attributes"`
+	} `json:"events"`
+	Status struct {
+		Code    jsonEnum `json:"code"`
+		Message string   `json:"message"`
+	} `json:"status"`
+}
+
+type jsonKeyValue struct {
+	Key   string       `json:"key"`
+	Value jsonAnyValue `json:"value"`
+}
+
+type jsonAnyValue struct {
+	StringValue *string     `json:"stringValue"`
+	BoolValue   *bool       `json:"boolValue"`
+	IntValue    *jsonUint64 `json:"intValue"`
+	DoubleValue *float64    `json:"doubleValue"`
+	BytesValue  *string     `json:"bytesValue"` // base64
+	ArrayValue  *struct {
+		Values []jsonAnyValue `json:"values"`
+	} `json:"arrayValue"`
+	KvlistValue *struct {
+		Values []jsonKeyValue `json:"values"`
+	} `json:"kvlistValue"`
+}
+
+func (v jsonAnyValue) text() string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.IntValue != nil:
+		return strconv.FormatInt(int64(*v.IntValue), 10)
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(*v.DoubleValue, 'g', -1, 64)
+	case v.BytesValue != nil:
+		return *v.BytesValue
+	case v.ArrayValue != nil:
+		items := make([]string, len(v.ArrayValue.Values))
+		for i, item := range v.ArrayValue.Values {
+			items[i] = item.text()
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case v.KvlistValue != nil:
+		items := make([]string, len(v.KvlistValue.Values))
+		for i, kv := range v.KvlistValue.Values {
+			items[i] = kv.Key + "=" + kv.Value.text()
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return ""
+}
+
+// jsonUint64 is a 64-bit integer given as a number or a string.
+type jsonUint64 uint64
+
+func (n *jsonUint64) UnmarshalJSON(b []byte) error {
+	s := string(bytes.Trim(b, `"`))
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+		*n = jsonUint64(v)
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %s", b)
+	}
+	*n = jsonUint64(v)
+	return nil
+}
+
+// jsonEnum is an enum given as a number or by name, e.g. SPAN_KIND_SERVER.
+type jsonEnum int
+
+var enumNames = map[string]int{
+	"SPAN_KIND_UNSPECIFIED": 0, "SPAN_KIND_INTERNAL": 1, "SPAN_KIND_SERVER": 2,
+	"SPAN_KIND_CLIENT": 3, "SPAN_KIND_PRODUCER": 4, "SPAN_KIND_CONSUMER": 5,
+	"STATUS_CODE_UNSET": 0, "STATUS_CODE_OK": 1, "STATUS_CODE_ERROR": 2,
+}
+
+func (e *jsonEnum) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var name string
+		if err := json.Unmarshal(b, &name); err != nil {
+			return err
+		}
+		v, ok := enumNames[name]
+		if !ok {
+			return fmt.Errorf("unknown enum value %q", name)
+		}
+		*e = jsonEnum(v)
+		return nil
+	}
+	var v int
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*e = jsonEnum(v)
+	return nil
+}
+
+// decodeJSON decodes an ExportTraceServiceRequest in the OTLP/JSON encoding.
+func decodeJSON(body []byte) ([]resourceSpans, error) {
+	var req jsonRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	var result []resourceSpans
+	for _, jrs := range req.ResourceSpans {
+		rs := resourceSpans{attrs: jsonAttrs(jrs.Resource.Attributes)}
+		for _, ss := range jrs.ScopeSpans {
+			for _, js := range ss.Spans {
+				s, err := js.span()
+				if err != nil {
+					return nil, err
+				}
+				rs.spans = append(rs.spans, s)
+			}
+		}
+		result = append(result, rs)
+	}
+	return result, nil
+}
+
+func (js *jsonSpan) span() (span, error) {
+	s := span{
+		name:       js.Name,
+		kind:       int(js.Kind),
+		start:      uint64(js.StartTimeUnixNano),
+		end:        uint64(js.EndTimeUnixNano),
+		attrs:      jsonAttrs(js.Attributes),
+		statusCode: int(js.Status.Code),
+		statusMsg:  js.Status.Message,
+	}
+	var err error
+	if s.traceID, err = jsonID(js.TraceID); err != nil {
+		return s, err
+	}
+	if s.spanID, err = jsonID(js.SpanID); err != nil {
+		return s, err
+	}
+	if s.parentID, err = jsonID(js.ParentSpanID); err != nil {
+		return s, err
+	}
+	for _, je := range js.Events {
+		s.events = append(s.events, event{time: uint64(je.TimeUnixNano), name: je.Name, attrs: jsonAttrs(je.Attributes)})
+	}
+	return s, nil
+}
+
+// jsonID decodes a trace or span ID. The spec mandates hex; some exporters
+// send the base64 of the protobuf JSON mapping instead.
+func jsonID(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if b, err := hex.DecodeString(s); err == nil {
+		return b, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trace or span id %q", s)
+	}
+	return b, nil
+}
+
+func jsonAttrs(kvs []jsonKeyValue) []attr {
+	attrs := make([]attr, 0, len(kvs))
+	for _, kv := range kvs {
+		attrs = append(attrs, attr{key: kv.Key, value: kv.Value.text()})
+	}
+	return attrs
+}
@@ -0,0 +1,430 @@
+// Package otlp receives OpenTelemetry traces over OTLP/gRPC and OTLP/HTTP
+// (protobuf or JSON) and feeds them into the span pipeline as SpanPacks, so
+// OTel-instrumented applications show up in the Scouter client like those
+// traced with Zipkin.
+//
+// Each resource becomes an object /{host.name}/{service.name} (/otel/... for
+// resources without host.name) of type otlp_obj_type, kept alive while its
+// spans arrive. Span names become services; the span attributes become
+// tags and the events annotations, which SpanCore stores as the profile.
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/ingest"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+const (
+	// HTTPPath is the OTLP/HTTP trace export path.
+	HTTPPath = "/v1/traces"
+	// GRPCPath is the path of the OTLP/gRPC TraceService Export method.
+	GRPCPath = "/opentelemetry.proto.collector.trace.v1.TraceService/Export"
+
+	maxBodyBytes = 16 << 20
+
+	// objectResend is how often the ObjectPack of a resource sending spans
+	// is sent again, like an agent heartbeat; objectDeadTime keeps it alive
+	// between bursts of spans.
+	objectResend   = 10 * time.Second
+	objectDeadTime = 60 * time.Second
+
+	// maxTexts bounds the set of texts already sent before it is cleared.
+	maxTexts = 100000
+)
+
+// Span kinds of OTLP.
+const (
+	kindServer   = 2
+	kindClient   = 3
+	kindProducer = 4
+	kindConsumer = 5
+)
+
+const statusError = 2
+
+// resourceSpans are the spans of one resource, decoded from either encoding.
+type resourceSpans struct {
+	attrs []attr
+	spans []span
+}
+
+type span struct {
+	traceID    []byte
+	spanID     []byte
+	parentID   []byte
+	name       string
+	kind       int
+	start, end uint64 // unix ns
+	attrs      []attr
+	events     []event
+	statusCode int
+	statusMsg  string
+}
+
+type event struct {
+	time  uint64 // unix ns
+	name  string
+	attrs []attr
+}
+
+type attr struct {
+	key, value string
+}
+
+func attrValue(attrs []attr, keys ...string) string {
+	for _, k := range keys {
+		for _, a := range attrs {
+			if a.key == k && a.value != "" {
+				return a.value
+			}
+		}
+	}
+	return ""
+}
+
+// Receiver converts OTLP trace exports into packs handed to ingest: texts
+// and objects first, then a SpanPack per span.
+type Receiver struct {
+	ingest func(p pack.Pack)
+	texts  *ingest.Texts
+
+	mu      sync.Mutex
+	objects map[int32]time.Time // last ObjectPack sent
+}
+
+// NewReceiver creates a Receiver handing its packs to send.
+func NewReceiver(send func(p pack.Pack)) *Receiver {
+	return &Receiver{
+		ingest:  send,
+		texts:   ingest.NewTexts(send, maxTexts),
+		objects: make(map[int32]time.Time),
+	}
+}
+
+// Start serves OTLP on each of addrs until ctx is cancelled. Every listener
+// accepts both OTLP/HTTP and OTLP/gRPC (HTTP/2 without TLS).
+func (rc *Receiver) Start(ctx context.Context, addrs ...string) error {
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	for _, addr := range addrs {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		srv := &http.Server{
+			Handler:           rc.Handler(),
+			Protocols:         &protocols,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("OTLP receiver error", "addr", addr, "error", err)
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			srv.Shutdown(shutdownCtx)
+		}()
+		slog.Info("OTLP receiver listening", "addr", ln.Addr().String())
+	}
+	return nil
+}
+
+// Handler returns the HTTP handler of the OTLP endpoints.
+func (rc *Receiver) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == GRPCPath && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc"):
+			rc.serveGRPC(w, r)
+		case r.URL.Path == HTTPPath:
+			rc.serveHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// serveHTTP serves OTLP/HTTP: a POST of an ExportTraceServiceRequest in
+// protobuf (application/x-protobuf) or JSON (application/json).
+func (rc *Receiver) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	isJSON := strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+	fail := func(code int, msg string) {
+		if isJSON {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(code)
+			fmt.Fprintf(w, `{"message":%q}`, msg)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(code)
+		w.Write(statusMessage(msg))
+	}
+	if r.Method != http.MethodPost {
+		fail(http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	body, err := readBody(http.MaxBytesReader(w, r.Body, maxBodyBytes), r.Header.Get("Content-Encoding"))
+	if err != nil {
+		fail(http.StatusBadRequest, err.Error())
+		return
+	}
+	var batch []resourceSpans
+	if isJSON {
+		batch, err = decodeJSON(body)
+	} else {
+		batch, err = decodeProto(body)
+	}
+	if err != nil {
+		fail(http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	rc.export(batch)
+	if isJSON {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+		return
+	}
+	// An empty ExportTraceServiceResponse: everything was accepted.
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+// gRPC status codes used in replies.
+const (
+	grpcOK              = 0
+	grpcInvalidArgument = 3
+	grpcUnimplemented   = 12
+)
+
+// serveGRPC serves the unary TraceService/Export call: length-prefixed
+// messages in the body and the status in the trailers.
+func (rc *Receiver) serveGRPC(w http.ResponseWriter, r *http.Request) {
+	fail := func(code int, msg string) {
+		// Trailers-only response.
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", strconv.Itoa(code))
+		w.Header().Set("Grpc-Message", msg)
+		w.WriteHeader(http.StatusOK)
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		fail(grpcInvalidArgument, err.Error())
+		return
+	}
+	for len(body) > 0 {
+		if len(body) < 5 {
+			fail(grpcInvalidArgument, "truncated message")
+			return
+		}
+		compressed, n := body[0], binary.BigEndian.Uint32(body[1:5])
+		if uint64(n) > uint64(len(body)-5) {
+			fail(grpcInvalidArgument, "truncated message")
+			return
+		}
+		msg := body[5 : 5+n]
+		body = body[5+n:]
+		if compressed != 0 {
+			if enc := r.Header.Get("Grpc-Encoding"); enc != "gzip" {
+				fail(grpcUnimplemented, "unsupported grpc-encoding "+enc)
+				return
+			}
+			if msg, err = readBody(bytes.NewReader(msg), "gzip"); err != nil {
+				fail(grpcInvalidArgument, err.Error())
+				return
+			}
+		}
+		batch, err := decodeProto(msg)
+		if err != nil {
+			fail(grpcInvalidArgument, "invalid request: "+err.Error())
+			return
+		}
+		rc.export(batch)
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte{0, 0, 0, 0, 0}) // an empty ExportTraceServiceResponse
+	w.Header().Set("Grpc-Status", strconv.Itoa(grpcOK))
+	w.Header().Set("Grpc-Message", "")
+}
+
+func readBody(r io.Reader, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", "identity":
+		return io.ReadAll(r)
+	case "gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(io.LimitReader(zr, maxBodyBytes))
+	}
+	return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+}
+
+// statusMessage encodes a google.rpc.Status with a message, the error body
+// of OTLP/HTTP in protobuf.
+func statusMessage(msg string) []byte {
+	b := []byte{0x08, grpcInvalidArgument, 0x12} // code, then message
+	b = binary.AppendUvarint(b, uint64(len(msg)))
+	return append(b, msg...)
+}
+
+// export converts the spans of batch into packs and hands them to ingest.
+func (rc *Receiver) export(batch []resourceSpans) {
+	for _, rs := range batch {
+		service := attrValue(rs.attrs, "service.name")
+		if service == "" {
+			service = "unknown_service"
+		}
+		host := attrValue(rs.attrs, "host.name")
+		if host == "" {
+			host = "otel"
+		}
+		objHash := rc.object(rs.attrs, "/"+host+"/"+service)
+		local := rc.texts.Text("object", service)
+		for i := range rs.spans {
+			rc.ingest(rc.spanPack(&rs.spans[i], objHash, local))
+		}
+	}
+}
+
+// object registers the object of a resource unless it was sent recently.
+func (rc *Receiver) object(attrs []attr, objName string) int32 {
+	objHash := util.HashString(objName)
+	now := time.Now()
+	rc.mu.Lock()
+	due := now.Sub(rc.objects[objHash]) >= objectResend
+	if due {
+		rc.objects[objHash] = now
+	}
+	rc.mu.Unlock()
+	if !due {
+		return objHash
+	}
+
+	objType := "otel"
+	if cfg := config.Get(); cfg != nil && cfg.OtlpObjType() != "" {
+		objType = cfg.OtlpObjType()
+	}
+	tags := value.NewMapValue()
+	tags.Put(pack.TagDeadTime, value.NewDecimalValue(objectDeadTime.Milliseconds()))
+	version := strings.TrimSpace(attrValue(attrs, "telemetry.sdk.language") + " " + attrValue(attrs, "telemetry.sdk.version"))
+	rc.ingest(&pack.ObjectPack{
+		ObjType: objType,
+		ObjHash: objHash,
+		ObjName: objName,
+		Version: version,
+		Alive:   true,
+		Tags:    tags,
+	})
+	return objHash
+}
+
+// spanPack converts s of the object objHash into a SpanPack. The 64-bit
+// Scouter IDs are the low 8 bytes of the OTel IDs.
+func (rc *Receiver) spanPack(s *span, objHash, local int32) *pack.SpanPack {
+	sp := &pack.SpanPack{
+		Gxid:                     idInt64(s.traceID),
+		Txid:                     idInt64(s.spanID),
+		Caller:                   idInt64(s.parentID),
+		Timestamp:                int64(s.start / 1e6),
+		SpanType:                 spanType(s.kind),
+		Name:                     rc.texts.Text("service", s.name),
+		ObjHash:                  objHash,
+		LocalEndpointServiceName: local,
+	}
+	if s.end > s.start {
+		sp.Elapsed = int32((s.end - s.start) / 1e6)
+	}
+	if s.statusCode == statusError {
+		msg := s.statusMsg
+		if msg == "" {
+			for _, e := range s.events {
+				if e.name == "exception" {
+					msg = attrValue(e.attrs, "exception.message", "exception.type")
+				}
+			}
+		}
+		if msg == "" {
+			msg = "error"
+		}
+		sp.Error = rc.texts.Text("error", msg)
+	}
+
+	sp.RemoteEndpointServiceName = rc.texts.Text("object", attrValue(s.attrs, "peer.service"))
+	if ip := net.ParseIP(attrValue(s.attrs, "network.peer.address", "net.peer.ip", "server.address")); ip != nil {
+		if v4 := ip.To4(); v4 != nil {
+			ip = v4
+		}
+		sp.RemoteEndpointIp = ip
+	}
+	if port, err := strconv.Atoi(attrValue(s.attrs, "network.peer.port", "net.peer.port", "server.port")); err == nil {
+		sp.RemoteEndpointPort = int16(port)
+	}
+
+	if len(s.attrs) > 0 {
+		sp.Tags = value.NewMapValue()
+		for _, a := range s.attrs {
+			sp.Tags.Put(a.key, value.NewTextValue(a.value))
+		}
+	}
+	if len(s.events) > 0 {
+		sp.AnnotationTimestamps = value.NewListValue()
+		sp.AnnotationValues = value.NewListValue()
+		for _, e := range s.events {
+			text := e.name
+			if msg := attrValue(e.attrs, "exception.message"); msg != "" {
+				text += ": " + msg
+			}
+			sp.AnnotationTimestamps.Value = append(sp.AnnotationTimestamps.Value, value.NewDecimalValue(int64(e.time/1e6)))
+			sp.AnnotationValues.Value = append(sp.AnnotationValues.Value, value.NewTextValue(text))
+		}
+	}
+	return sp
+}
+
+func idInt64(id []byte) int64 {
+	if len(id) < 8 {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(id[len(id)-8:]))
+}
+
+// spanType maps an OTLP span kind to the Zipkin kind of SpanPack.
+func spanType(kind int) byte {
+	switch kind {
+	case kindClient:
+		return 1
+	case kindServer:
+		return 2
+	case kindProducer:
+		return 3
+	case kindConsumer:
+		return 4
+	}
+	return 0
+}
@@ -0,0 +1,306 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// pb builds protobuf messages for the tests.
+type pb []byte
+
+func (m pb) bytes(field int, b []byte) pb {
+	m = binary.AppendUvarint(m, uint64(field<<3|wireBytes))
+	m = binary.AppendUvarint(m, uint64(len(b)))
+	return append(m, b...)
+}
+
+func (m pb) str(field int, s string) pb { return m.bytes(field, []byte(s)) }
+
+func (m pb) varint(field int, v uint64) pb {
+	m = binary.AppendUvarint(m, uint64(field<<3|wireVarint))
+	return binary.AppendUvarint(m, v)
+}
+
+func (m pb) fixed64(field int, v uint64) pb {
+	m = binary.AppendUvarint(m, uint64(field<<3|wireFixed64))
+	return binary.LittleEndian.AppendUint64(m, v)
+}
+
+func kv(key string, anyValue pb) pb {
+	return pb{}.str(1, key).bytes(2, anyValue)
+}
+
+const t0 = uint64(1767225600000000000) // 2026-01-01T00:00:00Z in ns
+
+// exportRequest is an ExportTraceServiceRequest with a failed server span
+// and a client span under it.
+func exportRequest() []byte {
+	resource := pb{}.
+		bytes(1, kv("service.name", pb{}.str(1, "checkout"))).
+		bytes(1, kv("host.name", pb{}.str(1, "web1"))).
+		bytes(1, kv("telemetry.sdk.language", pb{}.str(1, "java")))
+	traceID := bytes.Repeat([]byte{0}, 8)
+	traceID = append(traceID, 0, 0, 0, 0, 0, 0, 0x12, 0x34)
+	exception := pb{}.fixed64(1, t0+5e6).str(2, "exception").
+		bytes(3, kv("exception.message", pb{}.str(1, "out of stock")))
+	server := pb{}.
+		bytes(1, traceID).
+		bytes(2, []byte{0, 0, 0, 0, 0, 0, 0, 1}).
+		str(5, "POST /orders").
+		varint(6, kindServer).
+		fixed64(7, t0).
+		fixed64(8, t0+120e6).
+		bytes(9, kv("http.status_code", pb{}.varint(3, 500))).
+		bytes(9, kv("ratio", pb{}.fixed64(4, math.Float64bits(0.5)))).
+		bytes(9, kv("retry", pb{}.varint(2, 1))).
+		bytes(11, exception).
+		bytes(15, pb{}.varint(3, statusError))
+	client := pb{}.
+		bytes(1, traceID).
+		bytes(2, []byte{0, 0, 0, 0, 0, 0, 0, 2}).
+		bytes(4, []byte{0, 0, 0, 0, 0, 0, 0, 1}).
+		str(5, "SELECT orders").
+		varint(6, kindClient).
+		fixed64(7, t0+10e6).
+		fixed64(8, t0+40e6).
+		bytes(9, kv("db.statement", pb{}.str(1, "select * from orders"))).
+		bytes(9, kv("peer.service", pb{}.str(1, "orders-db"))).
+		bytes(9, kv("network.peer.address", pb{}.str(1, "10.0.0.5"))).
+		bytes(9, kv("network.peer.port", pb{}.varint(3, 5432)))
+	scope := pb{}.bytes(1, pb{}.str(1, "io.opentelemetry.tomcat")).bytes(2, server).bytes(2, client)
+	rs := pb{}.bytes(1, resource).bytes(2, scope)
+	return pb{}.bytes(1, rs)
+}
+
+const exportJSON = `{"resourceSpans":[{
+	"resource":{"attributes":[
+		{"key":"service.name","value":{"stringValue":"checkout"}},
+		{"key":"host.name","value":{"stringValue":"web1"}}]},
+	"scopeSpans":[{"scope":{"name":"x"},"spans":[
+		{"traceId":"00000000000000000000000000001234","spanId":"0000000000000001",
+		 "name":"POST /orders","kind":2,
+		 "startTimeUnixNano":"1767225600000000000","endTimeUnixNano":"1767225600120000000",
+		 "attributes":[{"key":"http.status_code","value":{"intValue":"500"}},
+			{"key":"tags","value":{"arrayValue":{"values":[{"stringValue":"a"},{"boolValue":true}]}}}],
+		 "events":[{"timeUnixNano":"1767225600005000000","name":"exception",
+			"attributes":[{"key":"exception.message","value":{"stringValue":"out of stock"}}]}],
+		 "status":{"code":"STATUS_CODE_ERROR"}}]}]}]}`
+
+type collector struct {
+	mu    sync.Mutex
+	packs []pack.Pack
+}
+
+func (c *collector) add(p pack.Pack) {
+	c.mu.Lock()
+	c.packs = append(c.packs, p)
+	c.mu.Unlock()
+}
+
+func (c *collector) spans() []*pack.SpanPack {
+	var result []*pack.SpanPack
+	for _, p := range c.packs {
+		if sp, ok := p.(*pack.SpanPack); ok {
+			result = append(result, sp)
+		}
+	}
+	return result
+}
+
+func (c *collector) texts() map[string]string {
+	result := make(map[string]string)
+	for _, p := range c.packs {
+		if tp, ok := p.(*pack.TextPack); ok {
+			result[tp.XType+":"+tp.Text] = tp.Text
+		}
+	}
+	return result
+}
+
+func tag(sp *pack.SpanPack, key string) string {
+	if sp.Tags == nil {
+		return ""
+	}
+	if v, ok := sp.Tags.Get(key); ok {
+		if tv, ok := v.(*value.TextValue); ok {
+			return tv.Value
+		}
+	}
+	return ""
+}
+
+// checkServerSpan checks the packs of the failed server span.
+func checkServerSpan(t *testing.T, c *collector) {
+	t.Helper()
+	op, ok := c.packs[0].(*pack.ObjectPack)
+	if !ok || op.ObjName != "/web1/checkout" || op.ObjType != "otel" || !op.Alive {
+		t.Fatalf("first pack = %#v, want the object", c.packs[0])
+	}
+	if op.DeadTime() != objectDeadTime.Milliseconds() {
+		t.Errorf("deadtime = %d", op.DeadTime())
+	}
+	texts := c.texts()
+	for _, want := range []string{"object:checkout", "service:POST /orders", "error:out of stock"} {
+		if _, ok := texts[want]; !ok {
+			t.Errorf("text %s not sent: %v", want, texts)
+		}
+	}
+
+	sp := c.spans()[0]
+	if sp.ObjHash != op.ObjHash || sp.Gxid != 0x1234 || sp.Txid != 1 || sp.Caller != 0 {
+		t.Errorf("ids = obj %d gxid %x txid %d caller %d", sp.ObjHash, sp.Gxid, sp.Txid, sp.Caller)
+	}
+	if sp.Timestamp != int64(t0/1e6) || sp.Elapsed != 120 || sp.SpanType != 2 {
+		t.Errorf("timestamp %d elapsed %d type %d", sp.Timestamp, sp.Elapsed, sp.SpanType)
+	}
+	if sp.Name != util.HashString("POST /orders") || sp.Error != util.HashString("out of stock") {
+		t.Errorf("name %d error %d", sp.Name, sp.Error)
+	}
+	if sp.LocalEndpointServiceName != util.HashString("checkout") {
+		t.Errorf("local service = %d", sp.LocalEndpointServiceName)
+	}
+	if tag(sp, "http.status_code") != "500" {
+		t.Errorf("http.status_code tag = %q", tag(sp, "http.status_code"))
+	}
+	if sp.AnnotationValues == nil || len(sp.AnnotationValues.Value) != 1 {
+		t.Fatalf("annotations = %v", sp.AnnotationValues)
+	}
+	if v := sp.AnnotationValues.Value[0].(*value.TextValue).Value; v != "exception: out of stock" {
+		t.Errorf("annotation = %q", v)
+	}
+	if ts := sp.AnnotationTimestamps.Value[0].(*value.DecimalValue).Value; ts != int64(t0/1e6)+5 {
+		t.Errorf("annotation time = %d", ts)
+	}
+}
+
+func TestHTTPProtobuf(t *testing.T) {
+	var c collector
+	rc := NewReceiver(c.add)
+	req := httptest.NewRequest(http.MethodPost, HTTPPath, bytes.NewReader(exportRequest()))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	rec := httptest.NewRecorder()
+	rc.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Fatalf("status %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	checkServerSpan(t, &c)
+	spans := c.spans()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans, want 2", len(spans))
+	}
+	sp := spans[1]
+	if sp.Caller != 1 || sp.SpanType != 1 || sp.Elapsed != 30 || sp.Error != 0 {
+		t.Errorf("client span: caller %d type %d elapsed %d error %d", sp.Caller, sp.SpanType, sp.Elapsed, sp.Error)
+	}
+	if sp.RemoteEndpointServiceName != util.HashString("orders-db") || sp.RemoteEndpointPort != 5432 ||
+		!bytes.Equal(sp.RemoteEndpointIp, []byte{10, 0, 0, 5}) {
+		t.Errorf("remote = %d %v:%d", sp.RemoteEndpointServiceName, sp.RemoteEndpointIp, sp.RemoteEndpointPort)
+	}
+	if tag(sp, "db.statement") != "select * from orders" {
+		t.Errorf("db.statement tag = %q", tag(sp, "db.statement"))
+	}
+	if got := tag(spans[0], "ratio") + " " + tag(spans[0], "retry"); got != "0.5 true" {
+		t.Errorf("double and bool tags = %q", got)
+	}
+
+	// The object and texts are sent once.
+	before := len(c.packs)
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, HTTPPath, bytes.NewReader(exportRequest()))
+	rc.Handler().ServeHTTP(rec, req)
+	if added := len(c.packs) - before; added != 2 {
+		t.Errorf("second export added %d packs, want the 2 spans", added)
+	}
+}
+
+func TestHTTPJSON(t *testing.T) {
+	var c collector
+	rc := NewReceiver(c.add)
+	req := httptest.NewRequest(http.MethodPost, HTTPPath, strings.NewReader(exportJSON))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	rc.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "{}" {
+		t.Fatalf("status %d, body %q", rec.Code, rec.Body.String())
+	}
+	checkServerSpan(t, &c)
+	if got := tag(c.spans()[0], "tags"); got != "[a, true]" {
+		t.Errorf("array tag = %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, HTTPPath, strings.NewReader(`{"resourceSpans":[{"scopeSpans":[{"spans":[{"traceId":"zz"}]}]}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	rc.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid") {
+		t.Errorf("bad request: status %d, body %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHTTPBadProtobuf(t *testing.T) {
+	rc := NewReceiver(func(pack.Pack) {})
+	req := httptest.NewRequest(http.MethodPost, HTTPPath, bytes.NewReader([]byte{0x0a, 0x10, 0x01}))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	rec := httptest.NewRecorder()
+	rc.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status %d, want 400", rec.Code)
+	}
+}
+
+func TestGRPC(t *testing.T) {
+	var c collector
+	rc := NewReceiver(c.add)
+	srv := httptest.NewUnstartedServer(rc.Handler())
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	defer srv.Close()
+
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+
+	call := func(frame []byte) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+GRPCPath, bytes.NewReader(frame))
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("Te", "trailers")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	msg := exportRequest()
+	frame := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(msg)))
+	resp := call(append(frame, msg...))
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 || !bytes.Equal(body, []byte{0, 0, 0, 0, 0}) {
+		t.Fatalf("proto %d, body %v", resp.ProtoMajor, body)
+	}
+	if got := resp.Trailer.Get("Grpc-Status"); got != "0" {
+		t.Fatalf("grpc-status = %q", got)
+	}
+	checkServerSpan(t, &c)
+
+	resp = call([]byte{0, 0, 0, 1})
+	resp.Body.Close()
+	if got := resp.Header.Get("Grpc-Status"); got != "3" {
+		t.Errorf("truncated frame: grpc-status = %q, want 3", got)
+	}
+}
@@ -0,0 +1,352 @@
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// pbReader walks the fields of one protobuf message. Only the wire format is
+// needed to read the OTLP messages, so no generated code is involved.
+type pbReader struct {
+	b []byte
+}
+
+// next returns the number and wire type of the next field; ok is false at
+// the end of the message.
+func (r *pbReader) next() (field int, wire int, ok bool, err error) {
+	if len(r.b) == 0 {
+		return 0, 0, false, nil
+	}
+	key, err := r.varint()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return int(key >> 3), int(key & 7), true, nil
+}
+
+func (r *pbReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	r.b = r.b[n:]
+	return v, nil
+}
+
+func (r *pbReader) fixed64() (uint64, error) {
+	if len(r.b) < 8 {
+		return 0, errTruncated
+	}
+	v := binary.LittleEndian.Uint64(r.b)
+	r.b = r.b[8:]
+	return v, nil
+}
+
+func (r *pbReader) bytes() ([]byte, error) {
+	n, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(r.b)) {
+		return nil, errTruncated
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v, nil
+}
+
+func (r *pbReader) skip(wire int) error {
+	switch wire {
+	case wireVarint:
+		_, err := r.varint()
+		return err
+	case wireFixed64:
+		_, err := r.fixed64()
+		return err
+	case wireBytes:
+		_, err := r.bytes()
+		return err
+	case wireFixed32:
+		if len(r.b) < 4 {
+			return errTruncated
+		}
+		r.b = r.b[4:]
+		return nil
+	}
+	return errors.New("unsupported protobuf wire type " + strconv.Itoa(wire))
+}
+
+// fields calls fn for every field of msg; fn reads the value of the fields
+// it wants and returns handled=false to have the others skipped.
+func fields(msg []byte, fn func(r *pbReader, field, wire int) (handled bool, err error)) error {
+	r := &pbReader{b: msg}
+	for {
+		field, wire, ok, err := r.next()
+		if err != nil || !ok {
+			return err
+		}
+		handled, err := fn(r, field, wire)
+		if err != nil {
+			return err
+		}
+		if !handled {
+			if err := r.skip(wire); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// decodeProto decodes an ExportTraceServiceRequest.
+func decodeProto(msg []byte) ([]resourceSpans, error) {
+	var result []resourceSpans
+	err := fields(msg, func(r *pbReader, field, wire int) (bool, error) {
+		if field != 1 || wire != wireBytes {
+			return false, nil
+		}
+		b, err := r.bytes()
+		if err != nil {
+			return true, err
+		}
+		rs, err := decodeResourceSpans(b)
+		result = append(result, rs)
+		return true, err
+	})
+	return result, err
+}
+
+func decodeResourceSpans(msg []byte) (resourceSpans, error) {
+	var rs resourceSpans
+	err := fields(msg, func(r *pbReader, field, wire int) (bool, error) {
+		if wire != wireBytes {
+			return false, nil
+		}
+		switch field {
+		case 1: // resource
+			b, err := r.bytes()
+			if err != nil {
+				return true, err
+			}
+			return true, fields(b, func(r *pbReader, field, wire int) (bool, error) {
+				if field != 1 || wire != wireBytes {
+					return false, nil
+				}
+				a, err := readKeyValue(r)
+				rs.attrs = append(rs.attrs, a)
+				return true, err
+			})
+		case 2: // scope_spans
+			b, err := r.bytes()
+			if err != nil {
+				return true, err
+			}
+			return true, fields(b, func(r *pbReader, field, wire int) (bool, error) {
+				if field != 2 || wire != wireBytes {
+					return false, nil
+				}
+				sb, err := r.bytes()
+				if err != nil {
+					return true, err
+				}
+				s, err := decodeSpan(sb)
+				rs.spans = append(rs.spans, s)
+				return true, err
+			})
+		}
+		return false, nil
+	})
+	return rs, err
+}
+
+func decodeSpan(msg []byte) (span, error) {
+	var s span
+	err := fields(msg, func(r *pbReader, field, wire int) (bool, error) {
+		var err error
+		switch {
+		case field == 1 && wire == wireBytes:
+			s.traceID, err = r.bytes()
+		case field == 2 && wire == wireBytes:
+			s.spanID, err = r.bytes()
+		case field == 4 && wire == wireBytes:
+			s.parentID, err = r.bytes()
+		case field == 5 && wire == wireBytes:
+			var b []byte
+			b, err = r.bytes()
+			s.name = string(b)
+		case field == 6 && wire == wireVarint:
+			var v uint64
+			v, err = r.varint()
+			s.kind = int(v)
+		case field == 7 && wire == wireFixed64:
+			s.start, err = r.fixed64()
+		case field == 8 && wire == wireFixed64:
+			s.end, err = r.fixed64()
+		case field == 9 && wire == wireBytes:
+			var a attr
+			a, err = readKeyValue(r)
+			s.attrs = append(s.attrs, a)
+		case field == 11 && wire == wireBytes:
+			var e event
+			e, err = readEvent(r)
+			s.events = append(s.events, e)
+		case field == 15 && wire == wireBytes:
+			var b []byte
+			if b, err = r.bytes(); err == nil {
+				err = fields(b, func(r *pbReader, field, wire int) (bool, error) {
+					var err error
+					switch {
+					case field == 2 && wire == wireBytes:
+						var m []byte
+						m, err = r.bytes()
+						s.statusMsg = string(m)
+					case field == 3 && wire == wireVarint:
+						var v uint64
+						v, err = r.varint()
+						s.statusCode = int(v)
+					default:
+						return false, nil
+					}
+					return true, err
+				})
+			}
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	return s, err
+}
+
+func readEvent(r *pbReader) (event, error) {
+	var e event
+	b, err := r.bytes()
+	if err != nil {
+		return e, err
+	}
+	err = fields(b, func(r *pbReader, field, wire int) (bool, error) {
+		var err error
+		switch {
+		case field == 1 && wire == wireFixed64:
+			e.time, err = r.fixed64()
+		case field == 2 && wire == wireBytes:
+			var n []byte
+			n, err = r.bytes()
+			e.name = string(n)
+		case field == 3 && wire == wireBytes:
+			var a attr
+			a, err = readKeyValue(r)
+			e.attrs = append(e.attrs, a)
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	return e, err
+}
+
+// readKeyValue reads a KeyValue, rendering its AnyValue as text.
+func readKeyValue(r *pbReader) (attr, error) {
+	var a attr
+	b, err := r.bytes()
+	if err != nil {
+		return a, err
+	}
+	err = fields(b, func(r *pbReader, field, wire int) (bool, error) {
+		if wire != wireBytes || (field != 1 && field != 2) {
+			return false, nil
+		}
+		v, err := r.bytes()
+		if err != nil {
+			return true, err
+		}
+		if field == 1 {
+			a.key = string(v)
+		} else {
+			a.value, err = anyValueText(v)
+		}
+		return true, err
+	})
+	return a, err
+}
+
+// anyValueText renders an AnyValue as text: arrays as [a, b] and key-value
+// lists as {k=v, ...}, bytes in base64.
+func anyValueText(msg []byte) (string, error) {
+	var text string
+	err := fields(msg, func(r *pbReader, field, wire int) (bool, error) {
+		switch {
+		case field == 1 && wire == wireBytes:
+			b, err := r.bytes()
+			text = string(b)
+			return true, err
+		case field == 2 && wire == wireVarint:
+			v, err := r.varint()
+			text = strconv.FormatBool(v != 0)
+			return true, err
+		case field == 3 && wire == wireVarint:
+			v, err := r.varint()
+			text = strconv.FormatInt(int64(v), 10)
+			return true, err
+		case field == 4 && wire == wireFixed64:
+			v, err := r.fixed64()
+			text = strconv.FormatFloat(math.Float64frombits(v), 'g', -1, 64)
+			return true, err
+		case field == 5 && wire == wireBytes:
+			b, err := r.bytes()
+			if err != nil {
+				return true, err
+			}
+			var items []string
+			err = fields(b, func(r *pbReader, field, wire int) (bool, error) {
+				if field != 1 || wire != wireBytes {
+					return false, nil
+				}
+				v, err := r.bytes()
+				if err != nil {
+					return true, err
+				}
+				s, err := anyValueText(v)
+				items = append(items, s)
+				return true, err
+			})
+			text = "[" + strings.Join(items, ", ") + "]"
+			return true, err
+		case field == 6 && wire == wireBytes:
+			b, err := r.bytes()
+			if err != nil {
+				return true, err
+			}
+			var items []string
+			err = fields(b, func(r *pbReader, field, wire int) (bool, error) {
+				if field != 1 || wire != wireBytes {
+					return false, nil
+				}
+				a, err := readKeyValue(r)
+				items = append(items, a.key+"="+a.value)
+				return true, err
+			})
+			text = "{" + strings.Join(items, ", ") + "}"
+			return true, err
+		case field == 7 && wire == wireBytes:
+			b, err := r.bytes()
+			text = base64.StdEncoding.EncodeToString(b)
+			return true, err
+		}
+		return false, nil
+	})
+	return text, err
+}
@@ -0,0 +1,231 @@
+// Package probe runs synthetic HTTP health checks against the endpoints of
+// probe_targets and records every check like a monitored transaction, so
+// uptime checks and APM data share the same timeline.
+//
+// Each target becomes an object /synthetic/{name} of type probe_obj_type.
+// A check is stored as an XLog of that object, with the URL as its service,
+// the HTTP status, the elapsed time and, when it fails, an error text; and
+// as the realtime counters ProbeUp (1 or 0), ProbeElapsed (ms) and
+// ProbeStatus (HTTP status, 0 without a response).
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/ingest"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+const (
+	// maxTexts bounds the set of texts already sent before it is cleared.
+	maxTexts = 10000
+	// maxBodyBytes is how much of a response body is read, so the
+	// connection can be reused without downloading large pages.
+	maxBodyBytes = 64 << 10
+)
+
+// Target is an endpoint checked by the prober.
+type Target struct {
+	Name string
+	URL  string
+}
+
+// ObjName returns the name of the object the checks of t are stored under.
+func (t Target) ObjName() string {
+	return "/synthetic/" + t.Name
+}
+
+// ParseTargets parses probe_targets: name=url entries separated by ';',
+// e.g. "orders=https://orders.example.com/health;login=http://10.0.0.5/ping".
+func ParseTargets(s string) ([]Target, error) {
+	var targets []Target
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, url, ok := strings.Cut(entry, "=")
+		name, url = strings.TrimSpace(name), strings.TrimSpace(url)
+		if !ok || name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("invalid probe target %q: use name=url", entry)
+		}
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return nil, fmt.Errorf("invalid probe target %q: url must be http or https", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate probe target %q", name)
+		}
+		seen[name] = true
+		targets = append(targets, Target{Name: name, URL: url})
+	}
+	return targets, nil
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Target  Target
+	Time    time.Time // when the check ended
+	Elapsed time.Duration
+	Status  int    // HTTP status, 0 without a response
+	Err     string // empty for a successful check
+}
+
+// Up reports whether the check succeeded.
+func (r Result) Up() bool {
+	return r.Err == ""
+}
+
+// Prober checks the targets of probe_targets every probe_interval_sec and
+// hands the resulting packs to ingest.
+type Prober struct {
+	ingest func(p pack.Pack)
+	client *http.Client
+	texts  *ingest.Texts
+}
+
+// NewProber creates a Prober handing its packs to send.
+func NewProber(send func(p pack.Pack)) *Prober {
+	return &Prober{
+		ingest: send,
+		client: &http.Client{},
+		texts:  ingest.NewTexts(send, maxTexts),
+	}
+}
+
+// Start checks the targets until ctx is cancelled. The targets, interval,
+// timeout and objType are read from the config before every round.
+func (p *Prober) Start(ctx context.Context) {
+	go func() {
+		for {
+			interval := 30 * time.Second
+			if cfg := config.Get(); cfg != nil {
+				interval = time.Duration(max(cfg.ProbeIntervalSec(), 1)) * time.Second
+			}
+			p.RunOnce(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// RunOnce checks every target concurrently and records the results.
+func (p *Prober) RunOnce(ctx context.Context) []Result {
+	cfg := config.Get()
+	if cfg == nil {
+		return nil
+	}
+	targets, err := ParseTargets(cfg.ProbeTargets())
+	if err != nil {
+		slog.Warn("Probe targets ignored", "error", err)
+		return nil
+	}
+	timeout := time.Duration(cfg.ProbeTimeoutMs()) * time.Millisecond
+	deadTime := 3 * time.Duration(max(cfg.ProbeIntervalSec(), 1)) * time.Second
+	objType := cfg.ProbeObjType()
+
+	results := make([]Result, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = p.Check(ctx, t, timeout)
+		}()
+	}
+	wg.Wait()
+	for _, r := range results {
+		p.record(r, objType, deadTime)
+	}
+	return results
+}
+
+// Check sends a GET to t. It fails on a transport error or timeout, or on
+// a status of 400 or above.
+func (p *Prober) Check(ctx context.Context, t Target, timeout time.Duration) Result {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	r := Result{Target: t}
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL, nil)
+	if err == nil {
+		req.Header.Set("User-Agent", "scouter-probe")
+		var resp *http.Response
+		if resp, err = p.client.Do(req); err == nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyBytes))
+			resp.Body.Close()
+			r.Status = resp.StatusCode
+			if resp.StatusCode >= 400 {
+				r.Err = "HTTP " + resp.Status
+			}
+		}
+	}
+	if err != nil {
+		r.Err = err.Error()
+	}
+	r.Time = time.Now()
+	r.Elapsed = r.Time.Sub(start)
+	return r
+}
+
+// record sends the object, XLog and counters of r.
+func (p *Prober) record(r Result, objType string, deadTime time.Duration) {
+	objName := r.Target.ObjName()
+	objHash := util.HashString(objName)
+	tags := value.NewMapValue()
+	tags.Put(pack.TagDeadTime, value.NewDecimalValue(deadTime.Milliseconds()))
+	p.ingest(&pack.ObjectPack{
+		ObjType: objType,
+		ObjHash: objHash,
+		ObjName: objName,
+		Version: "probe",
+		Alive:   true,
+		Tags:    tags,
+	})
+
+	elapsed := int32(r.Elapsed.Milliseconds())
+	xp := &pack.XLogPack{
+		EndTime: r.Time.UnixMilli(),
+		ObjHash: objHash,
+		Service: p.texts.Text("service", r.Target.URL),
+		Txid:    rand.Int64(),
+		Elapsed: elapsed,
+		Error:   p.texts.Text("error", r.Err),
+		Status:  int32(r.Status),
+		XType:   pack.XLogTypeWebService,
+	}
+	p.ingest(xp)
+
+	up := int64(0)
+	if r.Up() {
+		up = 1
+	}
+	data := value.NewMapValue()
+	data.Put("ProbeUp", value.NewDecimalValue(up))
+	data.Put("ProbeElapsed", value.NewDecimalValue(int64(elapsed)))
+	data.Put("ProbeStatus", value.NewDecimalValue(int64(r.Status)))
+	p.ingest(&pack.PerfCounterPack{
+		Time:     r.Time.UnixMilli(),
+		ObjName:  objName,
+		TimeType: cache.TimeTypeRealtime,
+		Data:     data,
+	})
+}
@@ -0,0 +1,111 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+func TestParseTargets(t *testing.T) {
+	targets, err := ParseTargets(" orders = https://orders.example.com/health?deep=1 ;; login=http://10.0.0.5/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 || targets[0] != (Target{"orders", "https://orders.example.com/health?deep=1"}) ||
+		targets[1].ObjName() != "/synthetic/login" {
+		t.Errorf("targets %+v", targets)
+	}
+	for _, s := range []string{"orders", "=http://x", "a/b=http://x", "ftp=ftp://x", "a=http://x;a=http://y"} {
+		if _, err := ParseTargets(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestProberRunOnce(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/slow":
+			time.Sleep(500 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("probe_targets=up="+srv.URL+"/ok;down="+srv.URL+"/down;slow="+srv.URL+"/slow\nprobe_timeout_ms=100\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	var packs []pack.Pack
+	p := NewProber(func(pk pack.Pack) { packs = append(packs, pk) })
+	results := p.RunOnce(context.Background())
+	if len(results) != 3 {
+		t.Fatalf("%d results", len(results))
+	}
+	if !results[0].Up() || results[0].Status != 200 {
+		t.Errorf("up: %+v", results[0])
+	}
+	if results[1].Up() || results[1].Status != 503 || !strings.Contains(results[1].Err, "503") {
+		t.Errorf("down: %+v", results[1])
+	}
+	if results[2].Up() || results[2].Status != 0 {
+		t.Errorf("timed out: %+v", results[2])
+	}
+
+	xlogs := make(map[int32]*pack.XLogPack)
+	counters := make(map[string]*pack.PerfCounterPack)
+	texts := 0
+	for _, pk := range packs {
+		switch v := pk.(type) {
+		case *pack.ObjectPack:
+			if v.ObjType != "synthetic" || !v.Alive {
+				t.Errorf("object %+v", v)
+			}
+		case *pack.XLogPack:
+			xlogs[v.ObjHash] = v
+		case *pack.PerfCounterPack:
+			counters[v.ObjName] = v
+		case *pack.TextPack:
+			texts++
+		}
+	}
+	down := xlogs[util.HashString("/synthetic/down")]
+	if down == nil || down.Status != 503 || down.Error == 0 || down.Service != util.HashString(srv.URL+"/down") {
+		t.Errorf("down xlog %+v", down)
+	}
+	if up := xlogs[util.HashString("/synthetic/up")]; up == nil || up.Error != 0 || up.Txid == 0 {
+		t.Errorf("up xlog %+v", up)
+	}
+	upValue := func(objName string) int64 {
+		v, _ := counters[objName].Data.Get("ProbeUp")
+		return v.(*value.DecimalValue).Value
+	}
+	if upValue("/synthetic/up") != 1 || upValue("/synthetic/down") != 0 || upValue("/synthetic/slow") != 0 {
+		t.Error("unexpected ProbeUp counters")
+	}
+
+	// Texts are sent once.
+	packs = nil
+	p.RunOnce(context.Background())
+	for _, pk := range packs {
+		if tp, ok := pk.(*pack.TextPack); ok && tp.XType == "service" {
+			t.Errorf("service text %q sent again", tp.Text)
+		}
+	}
+	if texts < 4 {
+		t.Errorf("%d texts sent, want the 3 services and the errors", texts)
+	}
+}
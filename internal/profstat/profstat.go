@@ -0,0 +1,378 @@
+// Package profstat breaks the time of every service down by step type (SQL,
+// API calls, method self time and waits) from the profiles agents send, in
+// 5-minute buckets, so "where does the time go" charts need no raw profiles.
+//
+// The current day is aggregated in memory. Every minute it is written to
+// {date}/profstat/profstat.json; earlier days are read from there.
+package profstat
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/step"
+)
+
+// BucketsPerDay is the number of 5-minute buckets of a day.
+const BucketsPerDay = 24 * 12
+
+// Stat is the time, in ms, one service spent in each kind of step during a
+// bucket.
+type Stat struct {
+	Service  int32 `json:"service"`  // hash of the service, text type "service"
+	Profiles int64 `json:"profiles"` // profile blocks aggregated
+	SQL      int64 `json:"sql"`
+	APICall  int64 `json:"apicall"` // API calls, dispatches and span calls
+	Method   int64 `json:"method"`  // method self time, without timed children
+	Wait     int64 `json:"wait"`    // sockets and thread calls
+}
+
+// Total returns the time of all step types.
+func (s Stat) Total() int64 {
+	return s.SQL + s.APICall + s.Method + s.Wait
+}
+
+func (s *Stat) add(o Stat) {
+	s.Profiles += o.Profiles
+	s.SQL += o.SQL
+	s.APICall += o.APICall
+	s.Method += o.Method
+	s.Wait += o.Wait
+}
+
+// Point is a Stat of the bucket starting at Time (epoch ms).
+type Point struct {
+	Time int64 `json:"time"`
+	Stat
+}
+
+// Breakdown sums the elapsed time of the steps of one profile block. The
+// self time of a method is its elapsed time less that of the timed steps
+// directly under it in the same block.
+func Breakdown(profile []byte) (Stat, error) {
+	var st Stat
+	in := protocol.NewDataInputX(profile)
+	methods := make(map[int32]int64)  // elapsed by step index
+	children := make(map[int32]int64) // elapsed of timed children by parent index
+	child := func(parent int32, elapsed int32) {
+		children[parent] += int64(elapsed)
+	}
+	for in.Available() > 0 {
+		s, err := step.ReadStep(in)
+		if err != nil {
+			return st, err
+		}
+		switch x := s.(type) {
+		case *step.MethodStep:
+			methods[x.Index] = int64(x.Elapsed)
+			child(x.Parent, x.Elapsed)
+		case *step.MethodStep2:
+			methods[x.Index] = int64(x.Elapsed)
+			child(x.Parent, x.Elapsed)
+		case *step.MethodSum:
+			st.Method += int64(x.Elapsed)
+		case *step.SqlStep:
+			st.SQL += int64(x.Elapsed)
+			child(x.Parent, x.Elapsed)
+		case *step.SqlStep2:
+			st.SQL += int64(x.Elapsed)
+			child(x.Parent, x.Elapsed)
+		case *step.SqlStep3:
+			st.SQL += int64(x.Elapsed)
+			child(x.Parent, x.Elapsed)
+		case *step.SqlSum:
+			st.SQL += int64(x.Elapsed)
+		case *step.ApiCallStep:
+			st.APICall += int64(x.Elapsed)
+			child(x.Parent, x.Elapsed)
+		case *step.ApiCallStep2:
+			st.APICall += int64(x.Elapsed)
+			child(x.Parent, x.Elapsed)
+		case *step.DispatchStep:
+			st.APICall += int64(x.Elapsed)
+			child(x.Parent, x.Elapsed)
+		case *step.SpanCallStep:
+			st.APICall += int64(x.Elapsed)
+			child(x.Parent, x.Elapsed)
+		case *step.ApiCallSum:
+			st.APICall += int64(x.Elapsed)
+		case *step.SocketStep:
+			st.Wait += int64(x.Elapsed)
+			child(x.Parent, x.Elapsed)
+		case *step.ThreadSubmitStep:
+			st.Wait += int64(x.Elapsed)
+			child(x.Parent, x.Elapsed)
+		case *step.ThreadCallPossibleStep:
+			st.Wait += int64(x.Elapsed)
+			child(x.Parent, x.Elapsed)
+		case *step.SocketSum:
+			st.Wait += int64(x.Elapsed)
+		}
+	}
+	for index, elapsed := range methods {
+		if self := elapsed - children[index]; self > 0 {
+			st.Method += self
+		}
+	}
+	st.Profiles = 1
+	return st, nil
+}
+
+// dayFile is the on-disk format of one day.
+type dayFile struct {
+	Buckets [BucketsPerDay][]Stat `json:"buckets"`
+}
+
+// Core aggregates profiles into per-service step time buckets.
+type Core struct {
+	baseDir string
+	queue   chan *pack.XLogProfilePack
+
+	mu      sync.Mutex
+	date    string
+	buckets [BucketsPerDay]map[int32]*Stat
+	dirty   bool
+}
+
+// NewCore creates a Core storing under baseDir and resumes today's buckets.
+func NewCore(baseDir string) *Core {
+	c := &Core{baseDir: baseDir, queue: make(chan *pack.XLogProfilePack, 4096)}
+	c.reset(time.Now().Format("20060102"))
+	go c.run()
+	go c.flusher()
+	return c
+}
+
+// ProcessProfile queues pp for aggregation.
+func (c *Core) ProcessProfile(pp *pack.XLogProfilePack) {
+	if pp.Service == 0 || len(pp.Profile) == 0 {
+		return
+	}
+	select {
+	case c.queue <- pp:
+	default:
+		slog.Debug("Profile stat queue overflow")
+	}
+}
+
+func (c *Core) run() {
+	for pp := range c.queue {
+		c.add(pp)
+	}
+}
+
+// add merges the breakdown of pp into the bucket of its time.
+func (c *Core) add(pp *pack.XLogProfilePack) {
+	st, err := Breakdown(pp.Profile)
+	if err != nil {
+		slog.Debug("Profile stat: undecodable profile", "txid", pp.Txid, "error", err)
+	}
+	if st.Total() == 0 {
+		return
+	}
+	st.Service = pp.Service
+
+	t := time.UnixMilli(pp.Time)
+	date := t.Format("20060102")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case date > c.date:
+		c.flushLocked()
+		c.reset(date)
+	case date < c.date:
+		return // late profiles of a stored day are not merged
+	}
+	bucket := c.buckets[bucketOf(t)]
+	s := bucket[pp.Service]
+	if s == nil {
+		s = &Stat{Service: pp.Service}
+		bucket[pp.Service] = s
+	}
+	s.add(st)
+	c.dirty = true
+}
+
+// reset starts aggregating date from its stored buckets, if any.
+func (c *Core) reset(date string) {
+	c.date, c.dirty = date, false
+	stored, _ := c.load(date)
+	for b := range c.buckets {
+		c.buckets[b] = make(map[int32]*Stat)
+		if stored != nil {
+			for _, st := range stored.Buckets[b] {
+				c.buckets[b][st.Service] = &st
+			}
+		}
+	}
+}
+
+func (c *Core) flusher() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		c.Flush()
+	}
+}
+
+// Flush writes the buckets of the current day if they changed.
+func (c *Core) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushLocked()
+}
+
+func (c *Core) flushLocked() {
+	if !c.dirty {
+		return
+	}
+	var df dayFile
+	for b, stats := range c.buckets {
+		for _, st := range stats {
+			df.Buckets[b] = append(df.Buckets[b], *st)
+		}
+		sort.Slice(df.Buckets[b], func(i, j int) bool {
+			return df.Buckets[b][i].Service < df.Buckets[b][j].Service
+		})
+	}
+	if err := c.save(c.date, &df); err != nil {
+		slog.Warn("Profile stat: save failed", "date", c.date, "error", err)
+		return
+	}
+	c.dirty = false
+}
+
+// day returns the buckets of date, from memory for the current day.
+func (c *Core) day(date string) (*dayFile, error) {
+	c.mu.Lock()
+	if date == c.date {
+		var df dayFile
+		for b, stats := range c.buckets {
+			for _, st := range stats {
+				df.Buckets[b] = append(df.Buckets[b], *st)
+			}
+		}
+		c.mu.Unlock()
+		return &df, nil
+	}
+	c.mu.Unlock()
+	return c.load(date)
+}
+
+// Series returns the non-empty buckets of service on date in time order.
+func (c *Core) Series(date string, service int32) ([]Point, error) {
+	df, err := c.day(date)
+	if err != nil || df == nil {
+		return nil, err
+	}
+	start, err := time.ParseInLocation("20060102", date, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	var result []Point
+	for b, stats := range df.Buckets {
+		for _, st := range stats {
+			if st.Service == service {
+				result = append(result, Point{Time: bucketTime(start, b).UnixMilli(), Stat: st})
+			}
+		}
+	}
+	return result, nil
+}
+
+// Services returns the services of date over the buckets from stime to
+// etime (epoch ms, 0 for the start or end of the day), by total time, at
+// most max of them (all if max <= 0).
+func (c *Core) Services(date string, stime, etime int64, max int) ([]Stat, error) {
+	df, err := c.day(date)
+	if err != nil || df == nil {
+		return nil, err
+	}
+	from, to := 0, BucketsPerDay-1
+	if stime > 0 {
+		from = bucketOf(time.UnixMilli(stime))
+	}
+	if etime > 0 {
+		to = bucketOf(time.UnixMilli(etime))
+	}
+	merged := make(map[int32]*Stat)
+	for b := from; b <= to; b++ {
+		for _, st := range df.Buckets[b] {
+			m := merged[st.Service]
+			if m == nil {
+				m = &Stat{Service: st.Service}
+				merged[st.Service] = m
+			}
+			m.add(st)
+		}
+	}
+	result := make([]Stat, 0, len(merged))
+	for _, st := range merged {
+		result = append(result, *st)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if ti, tj := result[i].Total(), result[j].Total(); ti != tj {
+			return ti > tj
+		}
+		return result[i].Service < result[j].Service
+	})
+	if max > 0 && len(result) > max {
+		result = result[:max]
+	}
+	return result, nil
+}
+
+func bucketOf(t time.Time) int {
+	return t.Hour()*12 + t.Minute()/5
+}
+
+// bucketTime returns the start of bucket b of the day starting at day. It
+// goes through the wall clock so days with a DST change stay aligned with
+// bucketOf.
+func bucketTime(day time.Time, b int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), b/12, b%12*5, 0, 0, day.Location())
+}
+
+func (c *Core) path(date string) string {
+	return filepath.Join(c.baseDir, date, "profstat", "profstat.json")
+}
+
+func (c *Core) save(date string, df *dayFile) error {
+	path := c.path(date)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(df)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// load reads the stored buckets of date, nil if there are none.
+func (c *Core) load(date string) (*dayFile, error) {
+	data, err := os.ReadFile(c.path(date))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var df dayFile
+	if err := json.Unmarshal(data, &df); err != nil {
+		return nil, err
+	}
+	return &df, nil
+}
@@ -0,0 +1,124 @@
+package profstat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/step"
+)
+
+func encode(steps ...step.Step) []byte {
+	out := protocol.NewDataOutputX()
+	for _, s := range steps {
+		step.WriteStep(out, s)
+	}
+	return out.ToByteArray()
+}
+
+// sampleProfile is a method of 100ms calling a 30ms SQL and a 20ms API call,
+// then a 5ms socket connect outside the method.
+func sampleProfile() []byte {
+	m := &step.MethodStep{Elapsed: 100}
+	m.Parent, m.Index = -1, 0
+	sql := &step.SqlStep{Elapsed: 30}
+	sql.Parent, sql.Index = 0, 1
+	api := &step.ApiCallStep{Elapsed: 20}
+	api.Parent, api.Index = 0, 2
+	sock := &step.SocketStep{Elapsed: 5}
+	sock.Parent, sock.Index = -1, 3
+	return encode(m, sql, api, sock, &step.MessageStep{Message: "done"})
+}
+
+func newTestCore(t *testing.T, date string) *Core {
+	c := &Core{baseDir: t.TempDir()}
+	c.reset(date)
+	return c
+}
+
+func TestBreakdown(t *testing.T) {
+	st, err := Breakdown(sampleProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Stat{Profiles: 1, SQL: 30, APICall: 20, Method: 50, Wait: 5}
+	if st != want {
+		t.Errorf("breakdown = %+v, want %+v", st, want)
+	}
+
+	// Summary steps add up as they are.
+	st, _ = Breakdown(encode(&step.SqlSum{Count: 3, Elapsed: 12}, &step.MethodSum{Count: 2, Elapsed: 7}))
+	if st.SQL != 12 || st.Method != 7 {
+		t.Errorf("summary breakdown = %+v", st)
+	}
+
+	if _, err := Breakdown([]byte{0xff}); err == nil {
+		t.Error("undecodable profile accepted")
+	}
+}
+
+func TestCoreSeriesAndServices(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	c := newTestCore(t, "20260310")
+	at := func(service int32, d time.Duration) *pack.XLogProfilePack {
+		return &pack.XLogProfilePack{Time: day.Add(d).UnixMilli(), Service: service, Profile: sampleProfile()}
+	}
+	c.add(at(1, 9*time.Hour+1*time.Minute))
+	c.add(at(1, 9*time.Hour+4*time.Minute))
+	c.add(at(1, 9*time.Hour+6*time.Minute))
+	c.add(at(2, 10*time.Hour))
+
+	points, err := c.Series("20260310", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 2 {
+		t.Fatalf("series = %+v, want 2 buckets", points)
+	}
+	if points[0].Time != day.Add(9*time.Hour).UnixMilli() || points[0].Profiles != 2 || points[0].SQL != 60 {
+		t.Errorf("09:00 bucket = %+v", points[0])
+	}
+	if points[1].Time != day.Add(9*time.Hour+5*time.Minute).UnixMilli() || points[1].Profiles != 1 {
+		t.Errorf("09:05 bucket = %+v", points[1])
+	}
+
+	stats, _ := c.Services("20260310", 0, 0, 0)
+	if len(stats) != 2 || stats[0].Service != 1 || stats[0].Method != 150 {
+		t.Errorf("services = %+v", stats)
+	}
+	stats, _ = c.Services("20260310", day.Add(10*time.Hour).UnixMilli(), 0, 0)
+	if len(stats) != 1 || stats[0].Service != 2 {
+		t.Errorf("services from 10:00 = %+v", stats)
+	}
+	if stats, _ := c.Services("20260310", 0, 0, 1); len(stats) != 1 {
+		t.Errorf("services max 1 = %+v", stats)
+	}
+}
+
+func TestCoreFlushAndReload(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	c := newTestCore(t, "20260310")
+	c.add(&pack.XLogProfilePack{Time: day.Add(9 * time.Hour).UnixMilli(), Service: 1, Profile: sampleProfile()})
+
+	// The next day flushes the previous one.
+	c.add(&pack.XLogProfilePack{Time: day.Add(33 * time.Hour).UnixMilli(), Service: 2, Profile: sampleProfile()})
+	if c.date != "20260311" {
+		t.Fatalf("date = %s, want 20260311", c.date)
+	}
+	points, err := c.Series("20260310", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 1 || points[0].Wait != 5 {
+		t.Fatalf("stored series = %+v", points)
+	}
+
+	// A restart resumes the current day.
+	c.Flush()
+	restarted := &Core{baseDir: c.baseDir}
+	restarted.reset("20260311")
+	if points, _ := restarted.Series("20260311", 2); len(points) != 1 {
+		t.Errorf("resumed series = %+v", points)
+	}
+}
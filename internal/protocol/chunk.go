@@ -0,0 +1,64 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// ChunkWriter frames each write as [int32 len][bytes], the body format of a
+// FLAG_COMPRESSED response. The terminating zero-length chunk is written by
+// the caller.
+type ChunkWriter struct {
+	w io.Writer
+}
+
+func NewChunkWriter(w io.Writer) *ChunkWriter {
+	return &ChunkWriter{w: w}
+}
+
+func (c *ChunkWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	var hdr [4]byte
+	binary.BigEndian.PutUint32(hdr[:], uint32(len(p)))
+	if _, err := c.w.Write(hdr[:]); err != nil {
+		return 0, err
+	}
+	return c.w.Write(p)
+}
+
+// ChunkReader reads the chunks written by ChunkWriter and returns io.EOF at
+// the terminating zero-length chunk, leaving r positioned after it.
+type ChunkReader struct {
+	r    io.Reader
+	left int
+	done bool
+}
+
+func NewChunkReader(r io.Reader) *ChunkReader {
+	return &ChunkReader{r: r}
+}
+
+func (c *ChunkReader) Read(p []byte) (int, error) {
+	if c.done {
+		return 0, io.EOF
+	}
+	if c.left == 0 {
+		var hdr [4]byte
+		if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+			return 0, err
+		}
+		c.left = int(binary.BigEndian.Uint32(hdr[:]))
+		if c.left == 0 {
+			c.done = true
+			return 0, io.EOF
+		}
+	}
+	if len(p) > c.left {
+		p = p[:c.left]
+	}
+	n, err := c.r.Read(p)
+	c.left -= n
+	return n, err
+}
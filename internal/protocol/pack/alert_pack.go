@@ -16,6 +16,59 @@ type AlertPack struct {
 	Tags    *value.MapValue
 }
 
+// Tags keys with which an alert raised from XLog conditions refers to the
+// offending transactions. Each holds a ListValue; the i-th entries of the
+// three lists describe one transaction.
+const (
+	TagXLogTxid = "xlog_txid"
+	TagXLogGxid = "xlog_gxid"
+	TagXLogDate = "xlog_date" // yyyyMMdd the XLog is stored under
+)
+
+// XLogRef identifies a transaction an alert refers to.
+type XLogRef struct {
+	Date string
+	Txid int64
+	Gxid int64
+}
+
+// SetXLogRefs stores refs in the TagXLogTxid, TagXLogGxid and TagXLogDate tags.
+func (p *AlertPack) SetXLogRefs(refs []XLogRef) {
+	if p.Tags == nil {
+		p.Tags = value.NewMapValue()
+	}
+	txids, gxids, dates := value.NewListValue(), value.NewListValue(), value.NewListValue()
+	for _, r := range refs {
+		txids.Value = append(txids.Value, value.NewDecimalValue(r.Txid))
+		gxids.Value = append(gxids.Value, value.NewDecimalValue(r.Gxid))
+		dates.Value = append(dates.Value, value.NewTextValue(r.Date))
+	}
+	p.Tags.Put(TagXLogTxid, txids)
+	p.Tags.Put(TagXLogGxid, gxids)
+	p.Tags.Put(TagXLogDate, dates)
+}
+
+// XLogRefs returns the transactions the alert refers to, if any.
+func (p *AlertPack) XLogRefs() []XLogRef {
+	if p.Tags == nil {
+		return nil
+	}
+	list := func(key string) *value.ListValue {
+		v, _ := p.Tags.Get(key)
+		lv, _ := v.(*value.ListValue)
+		if lv == nil {
+			return value.NewListValue()
+		}
+		return lv
+	}
+	txids, gxids, dates := list(TagXLogTxid), list(TagXLogGxid), list(TagXLogDate)
+	refs := make([]XLogRef, 0, len(txids.Value))
+	for i := range txids.Value {
+		refs = append(refs, XLogRef{Date: dates.GetString(i), Txid: txids.GetLong(i), Gxid: gxids.GetLong(i)})
+	}
+	return refs
+}
+
 // PackType returns the pack type code.
 func (p *AlertPack) PackType() byte {
 	return PackTypeAlert
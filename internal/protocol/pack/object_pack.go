@@ -17,11 +17,34 @@ type ObjectPack struct {
 	Tags    *value.MapValue
 }
 
+// TagDeadTime is the Tags key with which an object overrides the server's
+// dead timeout, in milliseconds. Producers that report less often than
+// agents (batch jobs, REST pushes) use it to stay alive between reports.
+const TagDeadTime = "deadtime"
+
+// TagOS is the Tags key with which an agent reports its operating system.
+const TagOS = "os"
+
 // PackType returns the pack type code.
 func (p *ObjectPack) PackType() byte {
 	return PackTypeObject
 }
 
+// DeadTime returns the TagDeadTime tag in milliseconds, or 0 if unset.
+func (p *ObjectPack) DeadTime() int64 {
+	if p.Tags == nil {
+		return 0
+	}
+	v, ok := p.Tags.Get(TagDeadTime)
+	if !ok {
+		return 0
+	}
+	if d, ok := v.(*value.DecimalValue); ok {
+		return d.Value
+	}
+	return 0
+}
+
 // Write serializes the ObjectPack to the output stream.
 func (p *ObjectPack) Write(o *protocol.DataOutputX) {
 	o.WriteText(p.ObjType)
@@ -29,6 +29,8 @@ const (
 	OBJECT_REMOVE                     = "OBJECT_REMOVE"
 	OBJECT_HEAPHISTO                  = "OBJECT_HEAPHISTO"
 	OBJECT_THREAD_DUMP                = "OBJECT_THREAD_DUMP"
+	OBJECT_DASHBOARD                  = "OBJECT_DASHBOARD"
+	PACK_INSPECT                      = "PACK_INSPECT"
 
 	// Trigger commands
 	TRIGGER_ACTIVE_SERVICE_LIST            = "TRIGGER_ACTIVE_SERVICE_LIST"
@@ -58,6 +60,7 @@ const (
 	OBJECT_LIST_LOAD_DATE    = "OBJECT_LIST_LOAD_DATE"
 	OBJECT_REMOVE_INACTIVE   = "OBJECT_REMOVE_INACTIVE"
 	OBJECT_REMOVE_IN_MEMORY  = "OBJECT_REMOVE_IN_MEMORY"
+	OBJECT_REMOVE_DEAD       = "OBJECT_REMOVE_DEAD"
 	OBJECT_FILE_SOCKET       = "OBJECT_FILE_SOCKET"
 	OBJECT_SOCKET            = "SOCKET"
 
@@ -86,6 +89,7 @@ const (
 	SERVER_TIME           = "SERVER_TIME"
 	SERVER_DB_LIST        = "SERVER_DB_LIST"
 	SERVER_DB_DELETE      = "SERVER_DB_DELETE"
+	SERVER_DB_PURGE       = "SERVER_DB_PURGE"
 	REMOTE_CONTROL        = "REMOTE_CONTROL"
 	REMOTE_CONTROL_ALL    = "REMOTE_CONTROL_ALL"
 	CHECK_JOB             = "CHECK_JOB"
@@ -96,9 +100,14 @@ const (
 	XLOG_READ_BY_TXID              = "XLOG_READ_BY_TXID"
 	XLOG_READ_BY_GXID              = "XLOG_READ_BY_GXID"
 	XLOG_LOAD_BY_TXIDS             = "XLOG_LOAD_BY_TXIDS"
+	XLOG_HEATMAP                   = "XLOG_HEATMAP"
 	XLOG_LOAD_BY_GXID              = "XLOG_LOAD_BY_GXID"
+	XLOG_CALL_TREE                 = "XLOG_CALL_TREE"
+	XLOG_LOAD_BY_USERID            = "XLOG_LOAD_BY_USERID"
 	TRANX_PROFILE                  = "TRANX_PROFILE"
 	TRANX_PROFILE_FULL             = "TRANX_PROFILE_FULL"
+	TRANX_PROFILE_STREAM           = "TRANX_PROFILE_STREAM"
+	TRANX_PROFILE_CHUNKED          = "TRANX_PROFILE_CHUNKED"
 	TRANX_REAL_TIME_GROUP          = "TRANX_REAL_TIME_GROUP"
 	TRANX_REAL_TIME_GROUP_LATEST   = "TRANX_REAL_TIME_GROUP_LATEST"
 	TRANX_LOAD_TIME_GROUP          = "TRANX_LOAD_TIME_GROUP"
@@ -106,6 +115,11 @@ const (
 	QUICKSEARCH_XLOG_LIST          = "QUICKSEARCH_XLOG_LIST"
 	SEARCH_XLOG_LIST               = "SEARCH_XLOG_LIST"
 
+	// Per-session real-time XLog filter commands
+	XLOG_REALTIME_FILTER_SET   = "XLOG_REALTIME_FILTER_SET"
+	XLOG_REALTIME_FILTER_GET   = "XLOG_REALTIME_FILTER_GET"
+	XLOG_REALTIME_FILTER_CLEAR = "XLOG_REALTIME_FILTER_CLEAR"
+
 	// Counter past time commands
 	COUNTER_PAST_TIME           = "COUNTER_PAST_TIME"
 	COUNTER_PAST_TIME_ALL       = "COUNTER_PAST_TIME_ALL"
@@ -151,6 +165,7 @@ const (
 	ALERT_LOAD_TIME         = "ALERT_LOAD_TIME"
 	ALERT_DAILY_COUNT       = "ALERT_DAILY_COUNT"
 	ALERT_TITLE_COUNT       = "ALERT_TITLE_COUNT"
+	ALERT_XLOGS             = "ALERT_XLOGS"
 	GET_COUNTER_EXIST_DAYS  = "GET_COUNTER_EXIST_DAYS"
 
 	// Text commands
@@ -158,6 +173,7 @@ const (
 	GET_TEXT_100      = "GET_TEXT_100"
 	GET_TEXT_PACK     = "GET_TEXT_PACK"
 	GET_TEXT_ANY_TYPE = "GET_TEXT_ANY_TYPE"
+	TEXT_DIV_USAGE    = "TEXT_DIV_USAGE"
 
 	// Key-Value store commands
 	GET_GLOBAL_KV      = "GET_GLOBAL_KV"
@@ -171,10 +187,31 @@ const (
 	GET_CUSTOM_KV_BULK = "GET_CUSTOM_KV_BULK"
 	SET_CUSTOM_KV_BULK = "SET_CUSTOM_KV_BULK"
 
+	// KV namespace commands
+	KV_NAMESPACE_LIST = "KV_NAMESPACE_LIST"
+	KV_NAMESPACE_SET  = "KV_NAMESPACE_SET"
+	KV_NAMESPACE_DROP = "KV_NAMESPACE_DROP"
+	GET_NS_KV         = "GET_NS_KV"
+	SET_NS_KV         = "SET_NS_KV"
+	DELETE_NS_KV      = "DELETE_NS_KV"
+	GET_NS_KV_BULK    = "GET_NS_KV_BULK"
+	GET_NS_KV_KEYS    = "GET_NS_KV_KEYS"
+
+	// Account-scoped KV commands
+	GET_ACCOUNT_KV      = "GET_ACCOUNT_KV"
+	SET_ACCOUNT_KV      = "SET_ACCOUNT_KV"
+	DELETE_ACCOUNT_KV   = "DELETE_ACCOUNT_KV"
+	GET_ACCOUNT_KV_BULK = "GET_ACCOUNT_KV_BULK"
+	GET_ACCOUNT_KV_KEYS = "GET_ACCOUNT_KV_KEYS"
+	KV_ACCOUNT_LIST     = "KV_ACCOUNT_LIST"
+	KV_ACCOUNT_DROP     = "KV_ACCOUNT_DROP"
+
 	// Configuration commands
 	GET_CONFIGURE_SERVER          = "GET_CONFIGURE_SERVER"
 	SET_CONFIGURE_SERVER          = "SET_CONFIGURE_SERVER"
 	LIST_CONFIGURE_SERVER         = "LIST_CONFIGURE_SERVER"
+	CONFIGURE_SERVER_HISTORY      = "CONFIGURE_SERVER_HISTORY"
+	CONFIGURE_VALIDATE            = "CONFIGURE_VALIDATE"
 	GET_CONFIGURE_WAS             = "GET_CONFIGURE_WAS"
 	SET_CONFIGURE_WAS             = "SET_CONFIGURE_WAS"
 	LIST_CONFIGURE_WAS            = "LIST_CONFIGURE_WAS"
@@ -263,6 +300,55 @@ const (
 	EDIT_GROUP_POLICY      = "EDIT_GROUP_POLICY"
 	ADD_ACCOUNT_GROUP      = "ADD_ACCOUNT_GROUP"
 
+	// Object group commands
+	OBJECT_GROUP_LIST    = "OBJECT_GROUP_LIST"
+	OBJECT_GROUP_SET     = "OBJECT_GROUP_SET"
+	OBJECT_GROUP_DELETE  = "OBJECT_GROUP_DELETE"
+	OBJECT_GROUP_RESOLVE = "OBJECT_GROUP_RESOLVE"
+
+	OBJECT_GROUP_COUNTER_REAL_TIME = "OBJECT_GROUP_COUNTER_REAL_TIME"
+	OBJECT_GROUP_XLOG_REAL_TIME    = "OBJECT_GROUP_XLOG_REAL_TIME"
+
+	// Object lifecycle event commands
+	OBJECT_EVENT_REAL_TIME = "OBJECT_EVENT_REAL_TIME"
+	OBJECT_EVENT_LOAD      = "OBJECT_EVENT_LOAD"
+
+	// Agent inventory commands
+	AGENT_INVENTORY         = "AGENT_INVENTORY"
+	AGENT_INVENTORY_HISTORY = "AGENT_INVENTORY_HISTORY"
+
+	// Slow SQL ranking commands
+	SQL_TOP_HOURLY = "SQL_TOP_HOURLY"
+
+	// Purge trash commands
+	SERVER_DB_TRASH_LIST    = "SERVER_DB_TRASH_LIST"
+	SERVER_DB_TRASH_RESTORE = "SERVER_DB_TRASH_RESTORE"
+
+	// Profile step time breakdown commands
+	PROFILE_STEP_STAT_SERVICE = "PROFILE_STEP_STAT_SERVICE"
+	PROFILE_STEP_STAT_TOP     = "PROFILE_STEP_STAT_TOP"
+
+	// Notification delivery commands
+	NOTIFY_DELIVERY_LIST = "NOTIFY_DELIVERY_LIST"
+
+	// Storage write path commands
+	DAY_WRITE_STATS = "DAY_WRITE_STATS"
+
+	// SLO commands
+	SLO_LIST   = "SLO_LIST"
+	SLO_SET    = "SLO_SET"
+	SLO_DELETE = "SLO_DELETE"
+
+	// Counter alert rule commands
+	ALERT_RULE_LIST   = "ALERT_RULE_LIST"
+	ALERT_RULE_SET    = "ALERT_RULE_SET"
+	ALERT_RULE_DELETE = "ALERT_RULE_DELETE"
+
+	// HTTP API token commands
+	TOKEN_LIST   = "TOKEN_LIST"
+	TOKEN_ISSUE  = "TOKEN_ISSUE"
+	TOKEN_REVOKE = "TOKEN_REVOKE"
+
 	// Object type commands
 	DEFINE_OBJECT_TYPE = "DEFINE_OBJECT_TYPE"
 	EDIT_OBJECT_TYPE   = "EDIT_OBJECT_TYPE"
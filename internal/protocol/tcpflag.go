@@ -7,4 +7,10 @@ const (
 	FLAG_NO_NEXT         byte = 0x04
 	FLAG_FAIL            byte = 0x05
 	FLAG_INVALID_SESSION byte = 0x44
+
+	// FLAG_COMPRESSED precedes a zstd stream of the remaining [flag][pack]
+	// records of a response, sent as [int32 len][bytes] chunks ending with a
+	// zero length. The FLAG_NO_NEXT terminator follows uncompressed. Only sent
+	// to sessions that asked for "compress"="zstd" at LOGIN.
+	FLAG_COMPRESSED byte = 0x06
 )
@@ -0,0 +1,103 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/mirror"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+)
+
+// RunCapture re-sends the packs of a pack mirror capture (see
+// mirror_pack_enabled) in their original order and spacing. Only
+// Options.Speed, Options.Rebase and Options.Progress apply; the capture
+// already contains the texts and objects the agents sent.
+func RunCapture(ctx context.Context, path string, sink Sink, opts Options, progress func(Stats)) (Stats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer f.Close()
+	mr, err := mirror.NewReader(f)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	r := &Replayer{sink: sink, opts: opts, wallStart: time.Now(), origin: -1}
+	lastProgress := r.wallStart
+	for {
+		rec, err := mr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return r.finish(), fmt.Errorf("%s: %w", path, err)
+		}
+		if err := r.pace(ctx, rec.Time); err != nil {
+			break
+		}
+		r.rebasePack(rec.Pack, rec.Time)
+		if r.send(rec.Pack) {
+			r.countPack(rec.Pack)
+		}
+		r.stats.Position = rec.Time
+
+		if progress != nil && opts.Progress > 0 && time.Since(lastProgress) >= opts.Progress {
+			lastProgress = time.Now()
+			progress(r.finish())
+		}
+	}
+	return r.finish(), nil
+}
+
+// rebasePack shifts the time fields of a captured pack by the same amount
+// its receive time is shifted.
+func (r *Replayer) rebasePack(p pack.Pack, received int64) {
+	if !r.opts.Rebase {
+		return
+	}
+	delta := r.timestamp(received) - received
+	switch x := p.(type) {
+	case *pack.XLogPack:
+		if x.EndTime != 0 {
+			x.EndTime += delta
+		}
+	case *pack.XLogProfilePack:
+		if x.Time != 0 {
+			x.Time += delta
+		}
+	case *pack.XLogProfilePack2:
+		if x.Time != 0 {
+			x.Time += delta
+		}
+	case *pack.PerfCounterPack:
+		if x.Time != 0 {
+			x.Time += delta
+		}
+	case *pack.AlertPack:
+		if x.Time != 0 {
+			x.Time += delta
+		}
+	}
+}
+
+func (r *Replayer) countPack(p pack.Pack) {
+	switch p.(type) {
+	case *pack.XLogPack:
+		r.stats.XLogs++
+	case *pack.XLogProfilePack, *pack.XLogProfilePack2:
+		r.stats.Profiles++
+	case *pack.PerfCounterPack:
+		r.stats.Counters++
+	case *pack.TextPack:
+		r.stats.Texts++
+	case *pack.ObjectPack:
+		r.stats.Objects++
+	default:
+		r.stats.Other++
+	}
+}
@@ -0,0 +1,406 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/db/profile"
+	"github.com/zbum/scouter-server-go/internal/db/text"
+	"github.com/zbum/scouter-server-go/internal/db/xlog"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/step"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+const (
+	// window is the slice of the day loaded and merged at a time.
+	window = time.Minute
+	// objectResendInterval keeps replayed agents alive on the target,
+	// which marks objects dead when no heartbeat arrives.
+	objectResendInterval = 3 * time.Second
+)
+
+// Sink receives replayed packs. It is implemented by the bench sinks.
+type Sink interface {
+	Send(p pack.Pack) error
+}
+
+// Options controls a replay run.
+type Options struct {
+	Date     string  // YYYYMMDD
+	Speed    float64 // playback speed multiplier; 0 sends as fast as possible
+	XLog     bool
+	Profile  bool
+	Counter  bool
+	Rebase   bool   // rewrite timestamps to the replay wall clock
+	ObjType  string // object type announced for replayed agents
+	Progress time.Duration
+}
+
+// Stats counts packs sent by a replay.
+type Stats struct {
+	XLogs      int64
+	Profiles   int64
+	Counters   int64
+	Texts      int64
+	Objects    int64
+	Other      int64 // other pack types, sent only when replaying a capture
+	SendErrors int64
+	Elapsed    time.Duration
+	// Position is the original timestamp of the last replayed record.
+	Position int64
+}
+
+// Replayer reads stored data for one day and re-sends it in time order.
+type Replayer struct {
+	sink Sink
+	opts Options
+
+	xlogRD    *xlog.XLogRD
+	profileRD *profile.ProfileRD
+	counterRD *counter.CounterRD
+	textRD    *text.TextRD
+
+	sentTexts map[string]map[int32]bool
+	objects   map[int32]*pack.ObjectPack
+	objSentAt map[int32]time.Time
+
+	dayStart  int64
+	origin    int64 // original time of the first record
+	wallStart time.Time
+	stats     Stats
+}
+
+// New creates a Replayer reading from dataDir.
+func New(dataDir string, sink Sink, opts Options) *Replayer {
+	if opts.ObjType == "" {
+		opts.ObjType = "java"
+	}
+	return &Replayer{
+		sink:      sink,
+		opts:      opts,
+		xlogRD:    xlog.NewXLogRD(dataDir),
+		profileRD: profile.NewProfileRD(dataDir),
+		counterRD: counter.NewCounterRD(dataDir),
+		textRD:    text.NewTextRD(dataDir),
+		sentTexts: make(map[string]map[int32]bool),
+		objects:   make(map[int32]*pack.ObjectPack),
+		objSentAt: make(map[int32]time.Time),
+	}
+}
+
+// Close releases the underlying readers.
+func (r *Replayer) Close() {
+	r.xlogRD.Close()
+	r.profileRD.Close()
+	r.counterRD.Close()
+	r.textRD.Close()
+}
+
+// event is one record of the merged xlog/counter stream.
+type event struct {
+	time    int64
+	xlog    *pack.XLogPack
+	counter *counter.RealtimeKey
+}
+
+// Run replays the day until all records are sent or ctx is cancelled.
+// progress, if non-nil, is called every Options.Progress.
+func (r *Replayer) Run(ctx context.Context, progress func(Stats)) (Stats, error) {
+	r.dayStart = util.DateToMillis(r.opts.Date)
+	if r.dayStart == 0 {
+		return r.stats, fmt.Errorf("invalid date %q", r.opts.Date)
+	}
+
+	var keys []counter.RealtimeKey
+	if r.opts.Counter {
+		var err error
+		keys, err = r.counterRD.RealtimeKeys(r.opts.Date)
+		if err != nil {
+			return r.stats, fmt.Errorf("read counter index: %w", err)
+		}
+	}
+
+	r.wallStart = time.Now()
+	r.origin = -1
+	lastProgress := r.wallStart
+
+	dayEnd := r.dayStart + 24*time.Hour.Milliseconds()
+	for ws := r.dayStart; ws < dayEnd; ws += window.Milliseconds() {
+		we := ws + window.Milliseconds()
+
+		var events []event
+		if r.opts.XLog || r.opts.Profile {
+			err := r.xlogRD.ReadByTime(r.opts.Date, ws, we-1, func(data []byte) bool {
+				p, err := pack.ReadPack(protocol.NewDataInputX(data))
+				if xp, ok := p.(*pack.XLogPack); err == nil && ok {
+					events = append(events, event{time: xp.EndTime, xlog: xp})
+				}
+				return true
+			})
+			if err != nil {
+				return r.stats, fmt.Errorf("read xlog: %w", err)
+			}
+		}
+		for len(keys) > 0 && r.dayStart+int64(keys[0].TimeSec)*1000 < we {
+			k := keys[0]
+			events = append(events, event{time: r.dayStart + int64(k.TimeSec)*1000, counter: &k})
+			keys = keys[1:]
+		}
+		sort.SliceStable(events, func(i, j int) bool { return events[i].time < events[j].time })
+
+		for _, ev := range events {
+			if err := r.pace(ctx, ev.time); err != nil {
+				return r.finish(), nil
+			}
+			if ev.xlog != nil {
+				r.sendXLog(ev.xlog)
+			} else {
+				r.sendCounter(*ev.counter)
+			}
+			r.stats.Position = ev.time
+
+			if progress != nil && r.opts.Progress > 0 && time.Since(lastProgress) >= r.opts.Progress {
+				lastProgress = time.Now()
+				progress(r.finish())
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return r.finish(), nil
+}
+
+func (r *Replayer) finish() Stats {
+	r.stats.Elapsed = time.Since(r.wallStart)
+	return r.stats
+}
+
+// pace waits until the original time t is due at the configured speed.
+func (r *Replayer) pace(ctx context.Context, t int64) error {
+	if r.origin < 0 {
+		r.origin = t
+	}
+	if r.opts.Speed <= 0 {
+		return ctx.Err()
+	}
+	due := r.wallStart.Add(time.Duration(float64(t-r.origin) / r.opts.Speed * float64(time.Millisecond)))
+	if d := time.Until(due); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return ctx.Err()
+}
+
+// timestamp maps an original time to the time sent to the target.
+func (r *Replayer) timestamp(t int64) int64 {
+	if !r.opts.Rebase {
+		return t
+	}
+	if r.opts.Speed <= 0 {
+		return time.Now().UnixMilli()
+	}
+	return r.wallStart.UnixMilli() + int64(float64(t-r.origin)/r.opts.Speed)
+}
+
+func (r *Replayer) send(p pack.Pack) bool {
+	if err := r.sink.Send(p); err != nil {
+		r.stats.SendErrors++
+		return false
+	}
+	return true
+}
+
+// lookupText resolves a hash from the permanent text table, falling back to
+// the replayed day's daily table.
+func (r *Replayer) lookupText(div string, hash int32) string {
+	if s, err := r.textRD.GetString(div, hash); err == nil && s != "" {
+		return s
+	}
+	if s, err := r.textRD.GetDailyString(r.opts.Date, div, hash); err == nil {
+		return s
+	}
+	return ""
+}
+
+// sendText sends the text for hash once so the target can resolve it.
+func (r *Replayer) sendText(div string, hash int32) {
+	if hash == 0 {
+		return
+	}
+	sent := r.sentTexts[div]
+	if sent == nil {
+		sent = make(map[int32]bool)
+		r.sentTexts[div] = sent
+	}
+	if sent[hash] {
+		return
+	}
+	sent[hash] = true
+	if s := r.lookupText(div, hash); s != "" {
+		if r.send(&pack.TextPack{XType: div, Hash: hash, Text: s}) {
+			r.stats.Texts++
+		}
+	}
+}
+
+// object returns the replayed agent for objHash, named after the stored
+// "object" text when available.
+func (r *Replayer) object(objHash int32) *pack.ObjectPack {
+	op := r.objects[objHash]
+	if op == nil {
+		name := r.lookupText("object", objHash)
+		if name == "" {
+			name = "/replay/" + util.Hexa32ToString32(objHash)
+		}
+		op = &pack.ObjectPack{
+			ObjType: r.opts.ObjType,
+			ObjHash: objHash,
+			ObjName: name,
+			Address: "127.0.0.1",
+			Version: "replay",
+			Alive:   true,
+			Tags:    value.NewMapValue(),
+		}
+		r.objects[objHash] = op
+		r.stats.Objects++
+	}
+	if time.Since(r.objSentAt[objHash]) >= objectResendInterval {
+		r.objSentAt[objHash] = time.Now()
+		op.Wakeup = time.Now().UnixMilli()
+		r.send(op)
+	}
+	return op
+}
+
+func (r *Replayer) sendXLog(xp *pack.XLogPack) {
+	r.object(xp.ObjHash)
+
+	orig := xp.EndTime
+	xp.EndTime = r.timestamp(orig)
+	if r.opts.XLog {
+		r.sendText("service", xp.Service)
+		r.sendText("error", xp.Error)
+		r.sendText("group", xp.Group)
+		r.sendText("ua", xp.UserAgent)
+		r.sendText("referer", xp.Referer)
+		r.sendText("login", xp.Login)
+		r.sendText("desc", xp.Desc)
+		if r.send(xp) {
+			r.stats.XLogs++
+		}
+	}
+
+	if !r.opts.Profile {
+		return
+	}
+	blocks, err := r.profileRD.GetProfile(r.opts.Date, xp.Txid, -1)
+	if err != nil {
+		return
+	}
+	for _, b := range blocks {
+		r.sendStepTexts(b)
+		pp := &pack.XLogProfilePack{
+			Time:    xp.EndTime,
+			ObjHash: xp.ObjHash,
+			Service: xp.Service,
+			Txid:    xp.Txid,
+			Profile: b,
+		}
+		if r.send(pp) {
+			r.stats.Profiles++
+		}
+	}
+}
+
+func (r *Replayer) sendCounter(k counter.RealtimeKey) {
+	counters, err := r.counterRD.ReadRealtime(r.opts.Date, k.ObjHash, k.TimeSec)
+	if err != nil || len(counters) == 0 {
+		return
+	}
+	op := r.object(k.ObjHash)
+
+	data := value.NewMapValue()
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data.Put(name, counters[name])
+	}
+	cp := &pack.PerfCounterPack{
+		Time:     r.timestamp(r.dayStart + int64(k.TimeSec)*1000),
+		ObjName:  op.ObjName,
+		TimeType: cache.TimeTypeRealtime,
+		Data:     data,
+	}
+	if r.send(cp) {
+		r.stats.Counters++
+	}
+}
+
+// sendStepTexts sends the texts referenced by the steps of a profile block.
+func (r *Replayer) sendStepTexts(block []byte) {
+	in := protocol.NewDataInputX(block)
+	for in.Available() > 0 {
+		s, err := step.ReadStep(in)
+		if err != nil {
+			return
+		}
+		for _, ref := range StepTexts(s) {
+			r.sendText(ref.Div, ref.Hash)
+		}
+	}
+}
+
+// TextRef is a text hash referenced by a step.
+type TextRef struct {
+	Div  string
+	Hash int32
+}
+
+// StepTexts returns the text hashes a profile step refers to.
+func StepTexts(s step.Step) []TextRef {
+	switch x := s.(type) {
+	case *step.MethodStep:
+		return []TextRef{{"method", x.Hash}}
+	case *step.MethodStep2:
+		return []TextRef{{"method", x.Hash}, {"error", x.Error}}
+	case *step.SqlStep:
+		return []TextRef{{"sql", x.Hash}, {"error", x.Error}}
+	case *step.SqlStep2:
+		return []TextRef{{"sql", x.Hash}, {"error", x.Error}}
+	case *step.SqlStep3:
+		return []TextRef{{"sql", x.Hash}, {"error", x.Error}}
+	case *step.ApiCallStep:
+		return []TextRef{{"apicall", x.Hash}, {"error", x.Error}}
+	case *step.ApiCallStep2:
+		return []TextRef{{"apicall", x.Hash}, {"error", x.Error}}
+	case *step.DispatchStep:
+		return []TextRef{{"apicall", x.Hash}, {"error", x.Error}}
+	case *step.ThreadSubmitStep:
+		return []TextRef{{"apicall", x.Hash}, {"error", x.Error}}
+	case *step.ThreadCallPossibleStep:
+		return []TextRef{{"apicall", x.Hash}}
+	case *step.HashedMessageStep:
+		return []TextRef{{"hmsg", x.Hash}}
+	case *step.ParameterizedMessageStep:
+		return []TextRef{{"hmsg", x.Hash}}
+	case *step.SocketStep:
+		return []TextRef{{"error", x.Error}}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package replay
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/db/profile"
+	"github.com/zbum/scouter-server-go/internal/db/text"
+	"github.com/zbum/scouter-server-go/internal/db/xlog"
+	"github.com/zbum/scouter-server-go/internal/mirror"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+const testDate = "20260207"
+
+type collectSink struct {
+	packs []pack.Pack
+	sent  []time.Time
+}
+
+func (s *collectSink) Send(p pack.Pack) error {
+	s.packs = append(s.packs, p)
+	s.sent = append(s.sent, time.Now())
+	return nil
+}
+
+func writeTestDay(t *testing.T, dir string) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	xlogWR := xlog.NewXLogWR(dir)
+	xlogWR.Start(ctx)
+	profileWR := profile.NewProfileWR(dir, 100)
+	profileWR.Start(ctx)
+	counterWR := counter.NewCounterWR(dir)
+	counterWR.Start(ctx)
+	textWR := text.NewTextWR(dir)
+	textWR.Start(ctx)
+
+	day := util.DateToMillis(testDate)
+	objHash := util.HashString("/host/tomcat1")
+	textWR.Add("object", objHash, "/host/tomcat1")
+	textWR.Add("service", 100, "/order")
+
+	for i, sec := range []int64{3600, 3601, 3605} {
+		xp := &pack.XLogPack{
+			EndTime: day + sec*1000,
+			ObjHash: objHash,
+			Service: 100,
+			Txid:    int64(1000 + i),
+			Elapsed: int32(10 * (i + 1)),
+		}
+		o := protocol.NewDataOutputX()
+		pack.WritePack(o, xp)
+		xlogWR.Add(&xlog.XLogEntry{Time: xp.EndTime, Txid: xp.Txid, Elapsed: xp.Elapsed, Data: o.ToByteArray()})
+	}
+	profileWR.Add(&profile.ProfileEntry{TimeMs: day + 3601*1000, Txid: 1001, Data: []byte{1, 2, 3}})
+	counterWR.AddRealtimeFromPerfCounter(day+3602*1000, objHash, map[string]value.Value{
+		"TPS": value.NewDecimalValue(7),
+	})
+
+	time.Sleep(200 * time.Millisecond)
+	xlogWR.Close()
+	profileWR.Close()
+	counterWR.Close()
+	textWR.Close()
+}
+
+func TestReplayOrderAndContent(t *testing.T) {
+	dir := t.TempDir()
+	writeTestDay(t, dir)
+
+	sink := &collectSink{}
+	r := New(dir, sink, Options{Date: testDate, XLog: true, Profile: true, Counter: true})
+	defer r.Close()
+
+	st, err := r.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.XLogs != 3 || st.Profiles != 1 || st.Counters != 1 || st.Objects != 1 {
+		t.Fatalf("stats = %+v", st)
+	}
+
+	var kinds []string
+	for _, p := range sink.packs {
+		switch x := p.(type) {
+		case *pack.ObjectPack:
+			if x.ObjName != "/host/tomcat1" {
+				t.Errorf("object name = %q", x.ObjName)
+			}
+			kinds = append(kinds, "object")
+		case *pack.TextPack:
+			if x.XType != "service" || x.Text != "/order" {
+				t.Errorf("text = %+v", x)
+			}
+			kinds = append(kinds, "text")
+		case *pack.XLogPack:
+			kinds = append(kinds, "xlog")
+		case *pack.XLogProfilePack:
+			if x.Txid != 1001 || len(x.Profile) != 3 {
+				t.Errorf("profile = %+v", x)
+			}
+			kinds = append(kinds, "profile")
+		case *pack.PerfCounterPack:
+			if x.ObjName != "/host/tomcat1" {
+				t.Errorf("counter objName = %q", x.ObjName)
+			}
+			if v, ok := x.Data.Get("TPS"); !ok || v.(*value.DecimalValue).Value != 7 {
+				t.Errorf("counter TPS = %v", v)
+			}
+			kinds = append(kinds, "counter")
+		}
+	}
+	want := []string{"object", "text", "xlog", "xlog", "profile", "counter", "xlog"}
+	if len(kinds) != len(want) {
+		t.Fatalf("sent %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("sent %v, want %v", kinds, want)
+		}
+	}
+
+	// Without rebase the original timestamps are kept.
+	if xp := sink.packs[2].(*pack.XLogPack); util.FormatDate(xp.EndTime) != testDate {
+		t.Errorf("EndTime = %d, want original day", xp.EndTime)
+	}
+}
+
+func TestReplaySpeedAndRebase(t *testing.T) {
+	dir := t.TempDir()
+	writeTestDay(t, dir)
+
+	sink := &collectSink{}
+	r := New(dir, sink, Options{Date: testDate, XLog: true, Speed: 20, Rebase: true})
+	defer r.Close()
+
+	start := time.Now()
+	st, err := r.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.XLogs != 3 {
+		t.Fatalf("xlogs = %d", st.XLogs)
+	}
+	// The records span 5 seconds; at 20x that is 250ms.
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("elapsed = %s, want about 250ms", elapsed)
+	}
+
+	var xlogs []*pack.XLogPack
+	for _, p := range sink.packs {
+		if xp, ok := p.(*pack.XLogPack); ok {
+			xlogs = append(xlogs, xp)
+		}
+	}
+	if d := time.Since(time.UnixMilli(xlogs[0].EndTime)); d < 0 || d > 5*time.Second {
+		t.Errorf("rebased EndTime is %s from now", d)
+	}
+	if gap := xlogs[2].EndTime - xlogs[0].EndTime; gap != 250 {
+		t.Errorf("rebased gap = %dms, want 250", gap)
+	}
+}
+
+func TestReplayCancel(t *testing.T) {
+	dir := t.TempDir()
+	writeTestDay(t, dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sink := &collectSink{}
+	r := New(dir, sink, Options{Date: testDate, XLog: true, Speed: 1})
+	defer r.Close()
+
+	time.AfterFunc(100*time.Millisecond, cancel)
+	st, err := r.Run(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.XLogs != 1 {
+		t.Errorf("xlogs = %d, want 1 before cancel", st.XLogs)
+	}
+}
+
+func TestRunCapture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.scm")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, _ := mirror.NewWriter(f)
+	w.Write(1000, "", &pack.TextPack{XType: "service", Hash: 1, Text: "/order"})
+	w.Write(1100, "", &pack.XLogPack{EndTime: 1090, Txid: 1})
+	w.Write(1200, "", &pack.AlertPack{Time: 1200, Title: "x"})
+	w.Flush()
+	f.Close()
+
+	sink := &collectSink{}
+	start := time.Now()
+	st, err := RunCapture(context.Background(), path, sink, Options{Speed: 1, Rebase: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Texts != 1 || st.XLogs != 1 || st.Other != 1 || len(sink.packs) != 3 {
+		t.Fatalf("stats = %+v, sent %d", st, len(sink.packs))
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("elapsed = %s, want original 200ms spacing", elapsed)
+	}
+	// The xlog keeps its 10ms offset from its receive time.
+	xp := sink.packs[1].(*pack.XLogPack)
+	if d := sink.sent[1].UnixMilli() - xp.EndTime; d < 0 || d > 50 {
+		t.Errorf("rebased EndTime is %dms before send", d)
+	}
+}
@@ -0,0 +1,100 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+)
+
+var levelNames = []string{"INFO", "WARN", "ERROR", "FATAL"}
+
+func levelName(level byte) string {
+	if int(level) < len(levelNames) {
+		return levelNames[level]
+	}
+	return strconv.Itoa(int(level))
+}
+
+// Title returns a one-line title such as "Scouter weekly report 2026-10-05 ~ 2026-10-11".
+func (r *Report) Title() string {
+	last := r.End.AddDate(0, 0, -1)
+	if r.Kind == Daily {
+		return fmt.Sprintf("Scouter daily report %s", r.Start.Format("2006-01-02"))
+	}
+	return fmt.Sprintf("Scouter %s report %s ~ %s", r.Kind, r.Start.Format("2006-01-02"), last.Format("2006-01-02"))
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"level": levelName,
+	"f1":    func(f float64) string { return strconv.FormatFloat(f, 'f', 1, 64) },
+	"f2":    func(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; color: #222; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; }
+th { background: #f0f0f0; text-align: left; }
+td.num { text-align: right; }
+</style>
+</head>
+<body>
+<h2>{{.Title}}</h2>
+<table>
+<tr><th>Transactions</th><td class="num">{{.Total.Count}}</td></tr>
+<tr><th>Average TPS</th><td class="num">{{f2 .TPS}}</td></tr>
+<tr><th>Errors</th><td class="num">{{.Total.Errors}} ({{f2 .Total.ErrorRate}}%)</td></tr>
+<tr><th>Average elapsed (ms)</th><td class="num">{{f1 .Total.AvgElapsed}}</td></tr>
+</table>
+{{if .ObjTypes}}<h3>By object type</h3>
+<table>
+<tr><th>objType</th><th>Transactions</th><th>Errors</th><th>Error rate (%)</th><th>Average elapsed (ms)</th></tr>
+{{range .ObjTypes}}<tr><td>{{.ObjType}}</td><td class="num">{{.Count}}</td><td class="num">{{.Errors}}</td><td class="num">{{f2 .ErrorRate}}</td><td class="num">{{f1 .AvgElapsed}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Services}}<h3>Slowest services</h3>
+<table>
+<tr><th>Service</th><th>Transactions</th><th>Errors</th><th>Error rate (%)</th><th>Average elapsed (ms)</th></tr>
+{{range .Services}}<tr><td>{{.Name}}</td><td class="num">{{.Count}}</td><td class="num">{{.Errors}}</td><td class="num">{{f2 .ErrorRate}}</td><td class="num">{{f1 .AvgElapsed}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Alerts}}<h3>Top alerts</h3>
+<table>
+<tr><th>Title</th><th>Level</th><th>Count</th></tr>
+{{range .Alerts}}<tr><td>{{.Title}}</td><td>{{level .Level}}</td><td class="num">{{.Count}}</td></tr>
+{{end}}</table>
+{{end}}<p style="color:#888">Generated {{.Generated.Format "2006-01-02 15:04:05"}}</p>
+</body>
+</html>
+`))
+
+// WriteHTML renders the report as a standalone HTML page.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, r)
+}
+
+// WriteCSV renders the report as CSV with one row per total, object type,
+// service and alert, distinguished by the first column.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"section", "name", "level", "count", "errors", "error_rate_pct", "avg_elapsed_ms", "tps"})
+	stat := func(section, name string, s Stat, tps string) {
+		cw.Write([]string{section, name, "", strconv.FormatInt(s.Count, 10), strconv.FormatInt(s.Errors, 10),
+			strconv.FormatFloat(s.ErrorRate(), 'f', 2, 64), strconv.FormatFloat(s.AvgElapsed(), 'f', 1, 64), tps})
+	}
+	stat("total", r.Name(), r.Total, strconv.FormatFloat(r.TPS(), 'f', 2, 64))
+	for _, t := range r.ObjTypes {
+		stat("objtype", t.ObjType, t.Stat, "")
+	}
+	for _, s := range r.Services {
+		stat("service", s.Name, s.Stat, "")
+	}
+	for _, a := range r.Alerts {
+		cw.Write([]string{"alert", a.Title, levelName(a.Level), strconv.FormatInt(a.Count, 10), "", "", "", ""})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,267 @@
+// Package report builds daily and weekly operational summaries (throughput,
+// error rate, slowest services and top alerts) from stored summary and alert
+// data, renders them as HTML and CSV, and delivers them on a schedule.
+package report
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// Report kinds.
+const (
+	Daily  = "daily"
+	Weekly = "weekly"
+)
+
+// summaryTypeApp is the service summary type written by agents
+// (see service.SummaryTypeApp).
+const summaryTypeApp byte = 1
+
+// minServiceCount keeps rarely called services out of the slowest list,
+// where a single slow call would otherwise dominate.
+const minServiceCount = 10
+
+// Stat aggregates service calls.
+type Stat struct {
+	Count      int64
+	Errors     int64
+	ElapsedSum int64 // ms
+}
+
+// ErrorRate returns the percentage of calls that failed.
+func (s Stat) ErrorRate() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Errors) * 100 / float64(s.Count)
+}
+
+// AvgElapsed returns the mean elapsed time in ms.
+func (s Stat) AvgElapsed() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.ElapsedSum) / float64(s.Count)
+}
+
+func (s *Stat) add(count, errors, elapsed int64) {
+	s.Count += count
+	s.Errors += errors
+	s.ElapsedSum += elapsed
+}
+
+// TypeStat is the service totals of one object type.
+type TypeStat struct {
+	ObjType string
+	Stat
+}
+
+// ServiceStat is the totals of one service.
+type ServiceStat struct {
+	Name string
+	Stat
+}
+
+// AlertStat counts alerts with the same title and level.
+type AlertStat struct {
+	Title string
+	Level byte
+	Count int64
+}
+
+// Report is a summary of one period.
+type Report struct {
+	Kind      string
+	Start     time.Time // inclusive
+	End       time.Time // exclusive
+	Generated time.Time
+
+	Total    Stat
+	ObjTypes []TypeStat    // by call count, descending
+	Services []ServiceStat // slowest first
+	Alerts   []AlertStat   // most frequent first
+}
+
+// TPS returns the average transactions per second over the period.
+func (r *Report) TPS() float64 {
+	secs := r.End.Sub(r.Start).Seconds()
+	if secs <= 0 {
+		return 0
+	}
+	return float64(r.Total.Count) / secs
+}
+
+// Name returns the base file name of the report, e.g. daily-20261015.
+func (r *Report) Name() string {
+	return r.Kind + "-" + r.Start.Format("20060102")
+}
+
+// Period returns the period of the given kind that most recently ended
+// before now: yesterday for daily reports and last Monday to Sunday for
+// weekly ones.
+func Period(kind string, now time.Time) (start, end time.Time) {
+	end = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if kind == Weekly {
+		// Back to this week's Monday.
+		end = end.AddDate(0, 0, -((int(end.Weekday()) + 6) % 7))
+		return end.AddDate(0, 0, -7), end
+	}
+	return end.AddDate(0, 0, -1), end
+}
+
+// SummaryReader reads stored summaries; implemented by summary.SummaryRD.
+type SummaryReader interface {
+	ReadRangeWithTime(date string, stype byte, stime, etime int64, handler func(timeMs int64, data []byte)) error
+}
+
+// AlertReader reads stored alerts; implemented by alert.AlertRD.
+type AlertReader interface {
+	ReadRange(date string, stime, etime int64, handler func(data []byte)) error
+}
+
+// Builder builds reports from stored data.
+type Builder struct {
+	summaries SummaryReader
+	alerts    AlertReader
+	// text resolves a service hash for the given date; it may return "".
+	text func(date, div string, hash int32) string
+	topN int
+}
+
+// NewBuilder creates a Builder. topN limits the service and alert lists.
+func NewBuilder(summaries SummaryReader, alerts AlertReader, text func(date, div string, hash int32) string, topN int) *Builder {
+	if topN <= 0 {
+		topN = 10
+	}
+	return &Builder{summaries: summaries, alerts: alerts, text: text, topN: topN}
+}
+
+// Build aggregates the data of [start, end).
+func (b *Builder) Build(kind string, start, end time.Time) *Report {
+	r := &Report{Kind: kind, Start: start, End: end, Generated: time.Now()}
+	types := make(map[string]*Stat)
+	services := make(map[int32]*Stat)
+	serviceDate := make(map[int32]string)
+	alerts := make(map[AlertStat]int64)
+
+	stime, etime := start.UnixMilli(), end.UnixMilli()-1
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format("20060102")
+		err := b.summaries.ReadRangeWithTime(date, summaryTypeApp, stime, etime, func(_ int64, data []byte) {
+			p, err := pack.ReadPack(protocol.NewDataInputX(data))
+			if err != nil {
+				return
+			}
+			sp, ok := p.(*pack.SummaryPack)
+			if !ok || sp.Table == nil {
+				return
+			}
+			ts := types[sp.ObjType]
+			if ts == nil {
+				ts = &Stat{}
+				types[sp.ObjType] = ts
+			}
+			ids := listOf(sp.Table, "id")
+			counts := listOf(sp.Table, "count")
+			errs := listOf(sp.Table, "error")
+			elapsed := listOf(sp.Table, "elapsed")
+			if ids == nil || counts == nil {
+				return
+			}
+			for i := range ids.Value {
+				count := longAt(counts, i)
+				nerr := longAt(errs, i)
+				el := longAt(elapsed, i)
+				hash := int32(longAt(ids, i))
+				r.Total.add(count, nerr, el)
+				ts.add(count, nerr, el)
+				s := services[hash]
+				if s == nil {
+					s = &Stat{}
+					services[hash] = s
+					serviceDate[hash] = date
+				}
+				s.add(count, nerr, el)
+			}
+		})
+		if err != nil {
+			slog.Warn("Report: read summaries failed", "date", date, "error", err)
+		}
+
+		err = b.alerts.ReadRange(date, stime, etime, func(data []byte) {
+			p, err := pack.ReadPack(protocol.NewDataInputX(data))
+			if err != nil {
+				return
+			}
+			if ap, ok := p.(*pack.AlertPack); ok {
+				alerts[AlertStat{Title: ap.Title, Level: ap.Level}]++
+			}
+		})
+		if err != nil {
+			slog.Warn("Report: read alerts failed", "date", date, "error", err)
+		}
+	}
+
+	for t, s := range types {
+		r.ObjTypes = append(r.ObjTypes, TypeStat{ObjType: t, Stat: *s})
+	}
+	sort.Slice(r.ObjTypes, func(i, j int) bool { return r.ObjTypes[i].Count > r.ObjTypes[j].Count })
+
+	for hash, s := range services {
+		if s.Count < minServiceCount {
+			continue
+		}
+		r.Services = append(r.Services, ServiceStat{Stat: *s, Name: b.serviceName(serviceDate[hash], hash)})
+	}
+	sort.Slice(r.Services, func(i, j int) bool { return r.Services[i].AvgElapsed() > r.Services[j].AvgElapsed() })
+	if len(r.Services) > b.topN {
+		r.Services = r.Services[:b.topN]
+	}
+
+	for a, n := range alerts {
+		a.Count = n
+		r.Alerts = append(r.Alerts, a)
+	}
+	sort.Slice(r.Alerts, func(i, j int) bool {
+		if r.Alerts[i].Count != r.Alerts[j].Count {
+			return r.Alerts[i].Count > r.Alerts[j].Count
+		}
+		return r.Alerts[i].Title < r.Alerts[j].Title
+	})
+	if len(r.Alerts) > b.topN {
+		r.Alerts = r.Alerts[:b.topN]
+	}
+	return r
+}
+
+func (b *Builder) serviceName(date string, hash int32) string {
+	if b.text != nil {
+		if s := b.text(date, "service", hash); s != "" {
+			return s
+		}
+	}
+	return util.Hexa32ToString32(hash)
+}
+
+func listOf(mv *value.MapValue, key string) *value.ListValue {
+	v, ok := mv.Get(key)
+	if !ok {
+		return nil
+	}
+	lv, _ := v.(*value.ListValue)
+	return lv
+}
+
+func longAt(lv *value.ListValue, i int) int64 {
+	if lv == nil || i >= len(lv.Value) {
+		return 0
+	}
+	return lv.GetLong(i)
+}
@@ -0,0 +1,187 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/notify"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+type record struct {
+	time int64
+	data []byte
+}
+
+// fakeStore serves summaries and alerts keyed by date.
+type fakeStore struct {
+	summaries map[string][]record
+	alerts    map[string][]record
+}
+
+func (f *fakeStore) ReadRangeWithTime(date string, stype byte, stime, etime int64, handler func(int64, []byte)) error {
+	for _, r := range f.summaries[date] {
+		if stype == summaryTypeApp && r.time >= stime && r.time <= etime {
+			handler(r.time, r.data)
+		}
+	}
+	return nil
+}
+
+type fakeAlerts fakeStore
+
+func (f *fakeAlerts) ReadRange(date string, stime, etime int64, handler func([]byte)) error {
+	for _, r := range f.alerts[date] {
+		if r.time >= stime && r.time <= etime {
+			handler(r.data)
+		}
+	}
+	return nil
+}
+
+func encode(p pack.Pack) []byte {
+	o := protocol.NewDataOutputX()
+	pack.WritePack(o, p)
+	return o.ToByteArray()
+}
+
+func longs(vs ...int64) *value.ListValue {
+	lv := value.NewListValue()
+	for _, v := range vs {
+		lv.Value = append(lv.Value, value.NewDecimalValue(v))
+	}
+	return lv
+}
+
+func (f *fakeStore) addSummary(t time.Time, objType string, ids, counts, errors, elapsed []int64) {
+	table := value.NewMapValue()
+	table.Put("id", longs(ids...))
+	table.Put("count", longs(counts...))
+	table.Put("error", longs(errors...))
+	table.Put("elapsed", longs(elapsed...))
+	date := t.Format("20060102")
+	f.summaries[date] = append(f.summaries[date], record{t.UnixMilli(),
+		encode(&pack.SummaryPack{Time: t.UnixMilli(), ObjType: objType, SType: summaryTypeApp, Table: table})})
+}
+
+func (f *fakeStore) addAlert(t time.Time, title string, level byte) {
+	date := t.Format("20060102")
+	f.alerts[date] = append(f.alerts[date], record{t.UnixMilli(),
+		encode(&pack.AlertPack{Time: t.UnixMilli(), Title: title, Level: level})})
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{summaries: map[string][]record{}, alerts: map[string][]record{}}
+}
+
+func texts(date, div string, hash int32) string {
+	return map[int32]string{1: "/fast", 2: "/slow", 3: "/rare"}[hash]
+}
+
+func TestPeriod(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 30, 0, 0, time.Local) // Wednesday
+	start, end := Period(Daily, now)
+	if start.Format("20060102") != "20261013" || end.Format("20060102") != "20261014" {
+		t.Errorf("daily = %v..%v", start, end)
+	}
+	start, end = Period(Weekly, now)
+	if start.Format("20060102") != "20261005" || end.Format("20060102") != "20261012" {
+		t.Errorf("weekly = %v..%v", start, end)
+	}
+	// On Monday the week that just ended is reported.
+	start, _ = Period(Weekly, time.Date(2026, 10, 12, 9, 0, 0, 0, time.Local))
+	if start.Format("20060102") != "20261005" {
+		t.Errorf("weekly on monday starts %v", start)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	f := newFakeStore()
+	day := time.Date(2026, 10, 13, 0, 0, 0, 0, time.Local)
+	f.addSummary(day.Add(time.Hour), "tomcat", []int64{1, 2, 3}, []int64{100, 20, 1}, []int64{1, 2, 0}, []int64{1000, 4000, 9000})
+	f.addSummary(day.Add(2*time.Hour), "nodejs", []int64{1}, []int64{100}, []int64{1}, []int64{1000})
+	f.addSummary(day.Add(25*time.Hour), "tomcat", []int64{1}, []int64{1000}, []int64{0}, []int64{1000}) // next day
+	f.addAlert(day.Add(time.Hour), "GC_TIME", 1)
+	f.addAlert(day.Add(2*time.Hour), "GC_TIME", 1)
+	f.addAlert(day.Add(3*time.Hour), "INACTIVE_OBJECT", 2)
+
+	r := NewBuilder(f, (*fakeAlerts)(f), texts, 10).Build(Daily, day, day.AddDate(0, 0, 1))
+	if r.Total.Count != 221 || r.Total.Errors != 4 || r.Total.ElapsedSum != 15000 {
+		t.Fatalf("total = %+v", r.Total)
+	}
+	if len(r.ObjTypes) != 2 || r.ObjTypes[0].ObjType != "tomcat" || r.ObjTypes[0].Count != 121 {
+		t.Errorf("objTypes = %+v", r.ObjTypes)
+	}
+	// /rare is below minServiceCount; /slow averages 200ms, /fast 10ms.
+	if len(r.Services) != 2 || r.Services[0].Name != "/slow" || r.Services[1].Name != "/fast" || r.Services[1].Count != 200 {
+		t.Errorf("services = %+v", r.Services)
+	}
+	if len(r.Alerts) != 2 || r.Alerts[0].Title != "GC_TIME" || r.Alerts[0].Count != 2 {
+		t.Errorf("alerts = %+v", r.Alerts)
+	}
+
+	var html, csv bytes.Buffer
+	if err := r.WriteHTML(&html); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html.String(), "/slow") || !strings.Contains(html.String(), "INACTIVE_OBJECT") {
+		t.Errorf("html misses rows:\n%s", html.String())
+	}
+	if err := r.WriteCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(csv.String(), "alert,GC_TIME,WARN,2") {
+		t.Errorf("csv misses alert row:\n%s", csv.String())
+	}
+}
+
+type fakeChannel struct{ sent []notify.Message }
+
+func (c *fakeChannel) Name() string { return "fake" }
+
+func (c *fakeChannel) Send(ctx context.Context, m notify.Message) error {
+	c.sent = append(c.sent, m)
+	return nil
+}
+
+func TestScheduler_GeneratesOnce(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("report_enabled=true\nreport_schedule=daily,weekly\nreport_hour=8\nreport_dir="+dir+"\n"), 0644)
+	cfg, err := config.Load(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFakeStore()
+	ch := &fakeChannel{}
+	s := NewScheduler(f, (*fakeAlerts)(f), texts)
+	s.channels = func(*config.Config) []notify.Channel { return []notify.Channel{ch} }
+
+	s.tick(context.Background(), cfg, time.Date(2026, 10, 14, 7, 0, 0, 0, time.Local))
+	if len(ch.sent) != 0 {
+		t.Fatal("report generated before report_hour")
+	}
+	now := time.Date(2026, 10, 14, 8, 0, 0, 0, time.Local)
+	s.tick(context.Background(), cfg, now)
+	s.tick(context.Background(), cfg, now.Add(time.Minute))
+	if len(ch.sent) != 2 {
+		t.Fatalf("sent %d reports, want 2", len(ch.sent))
+	}
+	for _, name := range []string{"daily-20261013.html", "daily-20261013.csv", "weekly-20261005.html"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if len(ch.sent[0].Attachments) != 1 || ch.sent[0].Attachments[0].Name != "daily-20261013.csv" {
+		t.Errorf("attachments = %+v", ch.sent[0].Attachments)
+	}
+}
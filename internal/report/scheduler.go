@@ -0,0 +1,142 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/notify"
+)
+
+// Scheduler generates the reports selected by report_schedule once their
+// period has ended and report_hour has passed, writes them to report_dir and
+// mails them to report_mail_to. A report whose HTML file already exists is
+// not generated again, so restarts neither skip nor repeat reports.
+type Scheduler struct {
+	summaries SummaryReader
+	alerts    AlertReader
+	text      func(date, div string, hash int32) string
+
+	// channels returns the delivery channels for the current settings;
+	// replaced in tests.
+	channels func(cfg *config.Config) []notify.Channel
+	// deliveries records deliveries and retries failed ones; nil to send once
+	// without recording.
+	deliveries *notify.Deliveries
+}
+
+// NewScheduler creates a Scheduler reading from the given stores.
+func NewScheduler(summaries SummaryReader, alerts AlertReader, text func(date, div string, hash int32) string) *Scheduler {
+	return &Scheduler{summaries: summaries, alerts: alerts, text: text, channels: mailChannels}
+}
+
+// SetDeliveries makes report mails go through d.
+func (s *Scheduler) SetDeliveries(d *notify.Deliveries) {
+	s.deliveries = d
+}
+
+// Start checks for due reports every minute until ctx is done.
+func (s *Scheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if cfg := config.Get(); cfg != nil {
+					s.tick(ctx, cfg, now)
+				}
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) tick(ctx context.Context, cfg *config.Config, now time.Time) {
+	if !cfg.ReportEnabled() || now.Hour() < cfg.ReportHour() {
+		return
+	}
+	for _, kind := range strings.Split(cfg.ReportSchedule(), ",") {
+		kind = strings.TrimSpace(kind)
+		if kind != Daily && kind != Weekly {
+			continue
+		}
+		start, end := Period(kind, now)
+		name := kind + "-" + start.Format("20060102")
+		if _, err := os.Stat(filepath.Join(cfg.ReportDir(), name+".html")); err == nil {
+			continue
+		}
+		if err := s.Generate(ctx, cfg, kind, start, end); err != nil {
+			slog.Error("Report: generation failed", "report", name, "error", err)
+		}
+	}
+}
+
+// Generate builds one report, writes its HTML and CSV files to report_dir and
+// delivers it. Delivery failures are logged; the files are kept either way.
+func (s *Scheduler) Generate(ctx context.Context, cfg *config.Config, kind string, start, end time.Time) error {
+	r := NewBuilder(s.summaries, s.alerts, s.text, cfg.ReportTopN()).Build(kind, start, end)
+
+	var html, csv bytes.Buffer
+	if err := r.WriteHTML(&html); err != nil {
+		return err
+	}
+	if err := r.WriteCSV(&csv); err != nil {
+		return err
+	}
+	dir := cfg.ReportDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// CSV first: the HTML file marks the report as done.
+	if err := os.WriteFile(filepath.Join(dir, r.Name()+".csv"), csv.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, r.Name()+".html"), html.Bytes(), 0644); err != nil {
+		return err
+	}
+	slog.Info("Report: generated", "report", r.Name(), "dir", dir,
+		"transactions", r.Total.Count, "services", len(r.Services), "alerts", len(r.Alerts))
+
+	msg := notify.Message{
+		Subject: r.Title(),
+		HTML:    html.String(),
+		Attachments: []notify.Attachment{
+			{Name: r.Name() + ".csv", ContentType: "text/csv; charset=utf-8", Data: csv.Bytes()},
+		},
+	}
+	policy := notify.RetryPolicy{
+		Retries: cfg.NotifyRetryCount(),
+		Backoff: time.Duration(cfg.NotifyRetryBackoffMs()) * time.Millisecond,
+	}
+	for _, ch := range s.channels(cfg) {
+		var err error
+		if s.deliveries != nil {
+			err = s.deliveries.Send(ctx, ch, msg, "report", policy)
+		} else {
+			err = ch.Send(ctx, msg)
+		}
+		if err != nil {
+			slog.Error("Report: delivery failed", "report", r.Name(), "channel", ch.Name(), "error", err)
+		} else {
+			slog.Info("Report: delivered", "report", r.Name(), "channel", ch.Name())
+		}
+	}
+	return nil
+}
+
+// mailChannels returns a mail channel when report_mail_to and an SMTP server
+// are configured.
+func mailChannels(cfg *config.Config) []notify.Channel {
+	to := cfg.ReportMailTo()
+	if strings.TrimSpace(to) == "" || cfg.NotifySMTPAddr() == "" {
+		return nil
+	}
+	return []notify.Channel{notify.NewConfiguredMail(cfg, strings.Split(to, ","))}
+}
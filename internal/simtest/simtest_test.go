@@ -0,0 +1,93 @@
+package simtest
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/counter"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+var start = time.Date(2026, 3, 1, 23, 59, 50, 0, time.Local)
+
+func TestPipelineDayRollover(t *testing.T) {
+	p := New(t, 1, start, 48)
+	for i := 0; i < 20; i++ {
+		p.SendXLog(1, 100, int32(i))
+		p.SendCounters("/host/app", map[string]float64{"TPS": float64(i)})
+		p.Advance(time.Second)
+	}
+
+	before, after := p.XLogs("20260301"), p.XLogs("20260302")
+	if len(before) != 10 || len(after) != 10 {
+		t.Fatalf("xlogs before/after midnight = %d/%d, want 10/10", len(before), len(after))
+	}
+	for i, xp := range after {
+		if xp.Elapsed != int32(10+i) {
+			t.Fatalf("after[%d].Elapsed = %d", i, xp.Elapsed)
+		}
+	}
+
+	rd := counter.NewCounterRD(p.Dir)
+	defer rd.Close()
+	p.CounterWR.Close()
+	v, err := rd.ReadRealtime("20260302", util.HashString("/host/app"), 5)
+	if err != nil || v["TPS"].(*value.DoubleValue).Value != 15 {
+		t.Errorf("TPS at 00:00:05 = %v, %v", v, err)
+	}
+}
+
+func TestPipelineBatchesAndSeeds(t *testing.T) {
+	a, b := New(t, 7, start, 48), New(t, 7, start, 48)
+	for i := 0; i < 1300; i++ {
+		a.SendXLog(1, 100, 5)
+		b.SendXLog(1, 100, 5)
+	}
+	// Several XLogWR batches are written in one step.
+	if n := a.Step(); n != 1300 {
+		t.Fatalf("Step wrote %d entries, want 1300", n)
+	}
+	b.Step()
+	xa, xb := a.XLogs("20260301"), b.XLogs("20260301")
+	if len(xa) != 1300 || len(xb) != 1300 {
+		t.Fatalf("stored %d/%d xlogs", len(xa), len(xb))
+	}
+	for i := range xa {
+		if xa[i].Txid != xb[i].Txid {
+			t.Fatalf("txid %d differs with the same seed", i)
+		}
+	}
+	if n := a.Step(); n != 0 {
+		t.Errorf("second Step wrote %d entries", n)
+	}
+}
+
+func TestPipelinePurge(t *testing.T) {
+	p := New(t, 1, start, 24)
+	p.SendXLog(1, 100, 5)
+	p.SendCounters("/host/app", map[string]float64{"TPS": 1})
+	p.Step()
+
+	// Yesterday is kept.
+	p.Advance(24 * time.Hour)
+	p.Purge()
+	if days := p.XLogWR.OpenDays(); !slices.Equal(days, []string{"20260301"}) {
+		t.Fatalf("open days after 1 day = %v", days)
+	}
+
+	p.Advance(24 * time.Hour)
+	p.SendXLog(1, 100, 5)
+	p.Step()
+	p.Purge()
+	if days := p.XLogWR.OpenDays(); !slices.Equal(days, []string{"20260303"}) {
+		t.Errorf("open xlog days = %v", days)
+	}
+	if days := p.CounterWR.OpenDays(); len(days) != 0 {
+		t.Errorf("open counter days = %v", days)
+	}
+	if got := p.XLogs("20260301"); got != nil {
+		t.Errorf("purged day still readable: %d xlogs", len(got))
+	}
+}
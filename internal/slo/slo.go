@@ -0,0 +1,120 @@
+// Package slo tracks service-level objectives: the share of service
+// transactions that finish within a latency threshold without error, over a
+// rolling window. Objectives are evaluated continuously from the XLog stream,
+// raise burn-rate alerts when the error budget is being used up too fast, and
+// keep their counts across restarts.
+package slo
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/zbum/scouter-server-go/internal/db/kv"
+)
+
+// kvKey is the KV store key holding all objective definitions as one JSON object.
+const kvKey = "slo_objectives"
+
+// MaxWindowDays bounds the rolling window of an objective.
+const MaxWindowDays = 31
+
+// Objective is one SLO definition.
+type Objective struct {
+	Name string `json:"name"`
+	// Service selects services by name using path.Match syntax, e.g.
+	// /api/orders/*; a lone * selects every service.
+	Service string `json:"service"`
+	// ObjType, if set, restricts the objective to one object type.
+	ObjType string `json:"objType,omitempty"`
+	// LatencyMs is the threshold a good transaction must finish within.
+	LatencyMs int32 `json:"latencyMs"`
+	// Target is the percentage of good transactions, e.g. 99.9.
+	Target float64 `json:"target"`
+	// WindowDays is the length of the rolling window (default 30).
+	WindowDays int `json:"windowDays"`
+}
+
+// Validate checks the definition and fills in the default window.
+func (o *Objective) Validate() error {
+	if strings.TrimSpace(o.Name) == "" {
+		return errors.New("objective name is empty")
+	}
+	if o.Service == "" {
+		return errors.New("service pattern is empty")
+	}
+	if _, err := path.Match(o.Service, ""); err != nil {
+		return fmt.Errorf("bad service pattern %q: %w", o.Service, err)
+	}
+	if o.LatencyMs <= 0 {
+		return errors.New("latencyMs must be positive")
+	}
+	if o.Target <= 0 || o.Target >= 100 {
+		return errors.New("target must be between 0 and 100 exclusive")
+	}
+	if o.WindowDays == 0 {
+		o.WindowDays = 30
+	}
+	if o.WindowDays < 0 || o.WindowDays > MaxWindowDays {
+		return fmt.Errorf("windowDays must be between 1 and %d", MaxWindowDays)
+	}
+	return nil
+}
+
+// Matches reports whether a transaction of the service belongs to the objective.
+func (o *Objective) Matches(service, objType string) bool {
+	if o.ObjType != "" && o.ObjType != objType {
+		return false
+	}
+	if o.Service == "*" {
+		return true
+	}
+	ok, _ := path.Match(o.Service, service)
+	return ok
+}
+
+// sameEvents reports whether o and p classify transactions identically, so
+// counts collected under one are valid under the other.
+func (o *Objective) sameEvents(p *Objective) bool {
+	return o.Service == p.Service && o.ObjType == p.ObjType && o.LatencyMs == p.LatencyMs
+}
+
+// Store keeps objective definitions in a KV store.
+type Store struct {
+	objs *kv.JSONMap[Objective]
+}
+
+// NewStore creates a Store backed by store. The key is writable through the
+// generic KV commands, so every stored definition is checked again on load;
+// an invalid window would break the tracker.
+func NewStore(store *kv.KVStore) *Store {
+	return &Store{objs: kv.NewJSONMap[Objective](store, kvKey, func(name string, o *Objective) error {
+		if o.Name != name {
+			return fmt.Errorf("objective %q stored under %q", o.Name, name)
+		}
+		return o.Validate()
+	})}
+}
+
+// List returns all objectives sorted by name.
+func (s *Store) List() []Objective {
+	result := s.objs.Values()
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Put creates or replaces an objective.
+func (s *Store) Put(o Objective) error {
+	o.Name = strings.TrimSpace(o.Name)
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	return s.objs.Put(o.Name, o)
+}
+
+// Delete removes an objective and reports whether it existed.
+func (s *Store) Delete(name string) (bool, error) {
+	return s.objs.Delete(name)
+}
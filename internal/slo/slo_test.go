@@ -0,0 +1,175 @@
+package slo
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/db/kv"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+var services = map[int32]string{
+	util.HashString("/api/orders/list"): "/api/orders/list",
+	util.HashString("/api/users"):       "/api/users",
+}
+
+func serviceName(hash int32) string { return services[hash] }
+
+func xlog(service string, end time.Time, elapsed int32, failed bool) *pack.XLogPack {
+	xp := &pack.XLogPack{Service: util.HashString(service), EndTime: end.UnixMilli(), Elapsed: elapsed}
+	if failed {
+		xp.Error = 1
+	}
+	return xp
+}
+
+func TestObjective_Validate(t *testing.T) {
+	for _, o := range []Objective{
+		{Name: "", Service: "*", LatencyMs: 100, Target: 99},
+		{Name: "a", Service: "", LatencyMs: 100, Target: 99},
+		{Name: "a", Service: "/api/[", LatencyMs: 100, Target: 99},
+		{Name: "a", Service: "*", LatencyMs: 0, Target: 99},
+		{Name: "a", Service: "*", LatencyMs: 100, Target: 100},
+		{Name: "a", Service: "*", LatencyMs: 100, Target: 99, WindowDays: MaxWindowDays + 1},
+	} {
+		if err := o.Validate(); err == nil {
+			t.Errorf("%+v: expected error", o)
+		}
+	}
+	o := Objective{Name: "a", Service: "*", LatencyMs: 100, Target: 99.9}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if o.WindowDays != 30 {
+		t.Errorf("WindowDays = %d, want default 30", o.WindowDays)
+	}
+}
+
+func TestStore_SkipsInvalidStoredObjectives(t *testing.T) {
+	global := kv.NewKVStore(t.TempDir(), "global.json")
+	global.Set(kvKey, `{
+		"zero": {"name": "zero", "service": "*", "latencyMs": 100, "target": 99, "windowDays": 0},
+		"neg": {"name": "neg", "service": "*", "latencyMs": 100, "target": 99, "windowDays": -1},
+		"renamed": {"name": "other", "service": "*", "latencyMs": 100, "target": 99}
+	}`)
+	store := NewStore(global)
+	objs := store.List()
+	if len(objs) != 1 || objs[0].Name != "zero" || objs[0].WindowDays != 30 {
+		t.Fatalf("List() = %+v, want only zero with the default window", objs)
+	}
+
+	tr := NewTracker(store, t.TempDir(), serviceName, nil)
+	tr.Add("tomcat", xlog("/api/users", time.Now(), 100, false))
+	if st := tr.Statuses(time.Now()); len(st) != 1 || st[0].Total != 1 {
+		t.Errorf("Statuses() = %+v, want one transaction on zero", st)
+	}
+}
+
+func TestTracker_Status(t *testing.T) {
+	store := NewStore(kv.NewKVStore(t.TempDir(), "global.json"))
+	if err := store.Put(Objective{Name: "orders", Service: "/api/orders/*", ObjType: "tomcat", LatencyMs: 500, Target: 99}); err != nil {
+		t.Fatal(err)
+	}
+	tr := NewTracker(store, t.TempDir(), serviceName, nil)
+
+	now := time.Now()
+	for i := 0; i < 196; i++ {
+		tr.Add("tomcat", xlog("/api/orders/list", now, 100, false))
+	}
+	tr.Add("tomcat", xlog("/api/orders/list", now, 900, false)) // slow
+	tr.Add("tomcat", xlog("/api/orders/list", now, 100, true))  // failed
+	tr.Add("tomcat", xlog("/api/users", now, 900, true))        // other service
+	tr.Add("nodejs", xlog("/api/orders/list", now, 900, true))  // other objType
+	tr.Add("tomcat", xlog("/api/orders/list", now.AddDate(0, 0, -31), 900, true))
+
+	st := tr.Statuses(now)
+	if len(st) != 1 {
+		t.Fatalf("statuses = %+v", st)
+	}
+	s := st[0]
+	if s.Total != 198 || s.Bad != 2 {
+		t.Errorf("total/bad = %d/%d, want 198/2", s.Total, s.Bad)
+	}
+	if math.Abs(s.Compliance-98.99) > 0.01 {
+		t.Errorf("compliance = %.2f", s.Compliance)
+	}
+	// 2 bad of 1.98 allowed: the budget is just exhausted.
+	if math.Abs(s.BudgetRemaining-(100-2*100/1.98)) > 0.01 {
+		t.Errorf("budgetRemaining = %.2f", s.BudgetRemaining)
+	}
+}
+
+func TestTracker_BurnAlerts(t *testing.T) {
+	store := NewStore(kv.NewKVStore(t.TempDir(), "global.json"))
+	store.Put(Objective{Name: "orders", Service: "*", LatencyMs: 500, Target: 99})
+	var alerts []*pack.AlertPack
+	tr := NewTracker(store, t.TempDir(), serviceName, func(p pack.Pack) {
+		if ap, ok := p.(*pack.AlertPack); ok {
+			alerts = append(alerts, ap)
+		}
+	})
+
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		xp := xlog("/api/users", now, 100, i < 20) // 20% bad: 20x burn
+		xp.Txid, xp.Gxid = int64(1000+i), int64(2000+i)
+		tr.Add("tomcat", xp)
+	}
+	tr.Evaluate(now)
+	tr.Evaluate(now)
+	if len(alerts) != 1 || alerts[0].Title != "SLO_FAST_BURN" || alerts[0].Level != 2 {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+	if alerts[0].ObjHash != util.HashString("/slo/orders") {
+		t.Errorf("objHash = %d", alerts[0].ObjHash)
+	}
+	// The alert links the latest bad transactions, newest first.
+	refs := alerts[0].XLogRefs()
+	if len(refs) != alertXLogs || refs[0].Txid != 1019 || refs[0].Gxid != 2019 || refs[alertXLogs-1].Txid != 1010 {
+		t.Errorf("refs = %+v", refs)
+	}
+	if refs[0].Date != util.FormatDate(now.UnixMilli()) {
+		t.Errorf("ref date = %q", refs[0].Date)
+	}
+	if st := tr.Statuses(now); st[0].Burning != "fast" {
+		t.Errorf("burning = %q", st[0].Burning)
+	}
+
+	// The short window recovers ten minutes later.
+	later := now.Add(10 * time.Minute)
+	for i := 0; i < 1000; i++ {
+		tr.Add("tomcat", xlog("/api/users", later, 100, false))
+	}
+	tr.Evaluate(later)
+	if len(alerts) != 2 || alerts[1].Title != "SLO_BURN_RESOLVED" {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+	if refs := alerts[1].XLogRefs(); len(refs) != 0 {
+		t.Errorf("resolved alert refs = %+v", refs)
+	}
+}
+
+func TestTracker_PersistsCounts(t *testing.T) {
+	store := NewStore(kv.NewKVStore(t.TempDir(), "global.json"))
+	store.Put(Objective{Name: "orders", Service: "/api/orders/*", LatencyMs: 500, Target: 99})
+	store.Put(Objective{Name: "users", Service: "/api/users", LatencyMs: 500, Target: 99})
+	dir := t.TempDir()
+	now := time.Now()
+
+	tr := NewTracker(store, dir, serviceName, nil)
+	tr.Add("tomcat", xlog("/api/orders/list", now.Add(-48*time.Hour), 900, false))
+	tr.Add("tomcat", xlog("/api/users", now, 100, false))
+	tr.save()
+
+	// A changed latency threshold invalidates the stored counts.
+	store.Put(Objective{Name: "users", Service: "/api/users", LatencyMs: 50, Target: 99})
+	st := NewTracker(store, dir, serviceName, nil).Statuses(now)
+	if st[0].Total != 1 || st[0].Bad != 1 {
+		t.Errorf("orders total/bad = %d/%d, want 1/1", st[0].Total, st[0].Bad)
+	}
+	if st[1].Total != 0 {
+		t.Errorf("users total = %d, want 0", st[1].Total)
+	}
+}
@@ -0,0 +1,430 @@
+package slo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// Burn-rate alerting follows the multiwindow scheme of the Google SRE
+// workbook: an alert fires only when both the long and the short window burn
+// faster than the threshold, so it starts quickly and stops soon after the
+// problem does.
+const (
+	fastBurnRate   = 14.4 // 2% of a 30-day budget in one hour
+	fastLongMin    = 60
+	fastShortMin   = 5
+	slowBurnRate   = 6.0 // 5% of a 30-day budget in six hours
+	slowLongMin    = 360
+	slowShortMin   = 30
+	minutesPerDay  = 24 * 60
+	burningFast    = "fast"
+	burningSlow    = "slow"
+	stateFileName  = "state.json"
+	alertObjType   = "slo"
+	alertObjPrefix = "/slo/"
+	alertXLogs     = 10 // latest bad transactions referenced by a burn alert
+)
+
+// Status is the current state of one objective.
+type Status struct {
+	Objective
+	Total int64 `json:"total"` // transactions in the window
+	Bad   int64 `json:"bad"`   // slow or failed transactions in the window
+	// Compliance is the percentage of good transactions (100 with no traffic).
+	Compliance float64 `json:"compliance"`
+	// BudgetRemaining is the percentage of the error budget left; it goes
+	// negative once the objective is breached.
+	BudgetRemaining float64 `json:"budgetRemaining"`
+	BurnRate1h      float64 `json:"burnRate1h"`
+	BurnRate6h      float64 `json:"burnRate6h"`
+	// Burning is "fast" or "slow" while a burn-rate alert is active.
+	Burning string `json:"burning,omitempty"`
+}
+
+// bucket counts the transactions ending in one minute.
+type bucket struct {
+	minute int64 // epoch minutes
+	total  int64
+	bad    int64
+}
+
+type matchKey struct {
+	service int32
+	objType string
+}
+
+type series struct {
+	obj     Objective
+	ring    []bucket // indexed by minute modulo its length
+	matched map[matchKey]bool
+	burning string
+	recent  []badXLog // latest bad transactions, oldest first
+}
+
+// badXLog is a slow or failed transaction an alert can point to.
+type badXLog struct {
+	endTime int64
+	txid    int64
+	gxid    int64
+}
+
+func newSeries(o Objective) *series {
+	return &series{obj: o, ring: make([]bucket, o.WindowDays*minutesPerDay), matched: make(map[matchKey]bool)}
+}
+
+func (s *series) add(minute, total, bad int64) {
+	b := &s.ring[minute%int64(len(s.ring))]
+	if b.minute != minute {
+		if minute < b.minute {
+			return // older than the window
+		}
+		*b = bucket{minute: minute}
+	}
+	b.total += total
+	b.bad += bad
+}
+
+// addBad remembers a bad transaction, keeping the latest alertXLogs.
+func (s *series) addBad(xp *pack.XLogPack) {
+	if len(s.recent) == alertXLogs {
+		copy(s.recent, s.recent[1:])
+		s.recent = s.recent[:alertXLogs-1]
+	}
+	s.recent = append(s.recent, badXLog{endTime: xp.EndTime, txid: xp.Txid, gxid: xp.Gxid})
+}
+
+// xlogRefs returns the remembered bad transactions of the n minutes ending
+// with now, newest first.
+func (s *series) xlogRefs(now int64, n int) []pack.XLogRef {
+	since := (now - int64(n) + 1) * 60000
+	var refs []pack.XLogRef
+	for i := len(s.recent) - 1; i >= 0; i-- {
+		if x := s.recent[i]; x.endTime >= since {
+			refs = append(refs, pack.XLogRef{Date: util.FormatDate(x.endTime), Txid: x.txid, Gxid: x.gxid})
+		}
+	}
+	return refs
+}
+
+// sum returns the counts of the n minutes ending with minute now.
+func (s *series) sum(now int64, n int) (total, bad int64) {
+	n = min(n, len(s.ring))
+	for m := now - int64(n) + 1; m <= now; m++ {
+		if b := s.ring[m%int64(len(s.ring))]; b.minute == m {
+			total += b.total
+			bad += b.bad
+		}
+	}
+	return total, bad
+}
+
+// burnRate returns how many times faster than sustainable the error budget
+// was spent over the n minutes ending with now.
+func (s *series) burnRate(now int64, n int) float64 {
+	total, bad := s.sum(now, n)
+	if total == 0 {
+		return 0
+	}
+	return float64(bad) / float64(total) / s.budget()
+}
+
+// budget returns the allowed fraction of bad transactions.
+func (s *series) budget() float64 {
+	return 1 - s.obj.Target/100
+}
+
+// Tracker evaluates the stored objectives against the XLog stream.
+type Tracker struct {
+	mu          sync.Mutex
+	store       *Store
+	serviceName func(hash int32) string
+	ingest      func(p pack.Pack)
+	dir         string
+	raw         string // store value the series were built for
+	series      map[string]*series
+	saved       map[string]savedSeries // loaded state not yet claimed by a series
+}
+
+// NewTracker creates a Tracker for the objectives in store. serviceName
+// resolves a service hash ("" if unknown), ingest receives the alert packs,
+// and the counts are persisted under {dataDir}/slo.
+func NewTracker(store *Store, dataDir string, serviceName func(hash int32) string, ingest func(p pack.Pack)) *Tracker {
+	t := &Tracker{
+		store:       store,
+		serviceName: serviceName,
+		ingest:      ingest,
+		dir:         filepath.Join(dataDir, "slo"),
+		series:      make(map[string]*series),
+	}
+	if err := t.load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("SLO: load state failed, starting empty", "error", err)
+	}
+	return t
+}
+
+// Store returns the definition store.
+func (t *Tracker) Store() *Store {
+	return t.store
+}
+
+// syncLocked rebuilds the series when the definitions changed, keeping the
+// counts of objectives whose matching rules are unchanged. Caller must hold t.mu.
+func (t *Tracker) syncLocked() {
+	objs, raw := t.store.objs.Snapshot()
+	if raw == t.raw && t.saved == nil {
+		return
+	}
+	t.raw = raw
+
+	next := make(map[string]*series, len(objs))
+	for name, o := range objs {
+		old := t.series[name]
+		switch {
+		case old != nil && old.obj.sameEvents(&o):
+			s := newSeries(o)
+			s.burning = old.burning
+			s.recent = old.recent
+			for _, b := range old.ring {
+				if b.minute != 0 {
+					s.add(b.minute, b.total, b.bad)
+				}
+			}
+			next[name] = s
+		case old == nil && t.saved != nil:
+			s := newSeries(o)
+			if sv, ok := t.saved[name]; ok && sv.Objective.sameEvents(&o) {
+				s.burning = sv.Burning
+				for _, b := range sv.Buckets {
+					s.add(b[0], b[1], b[2])
+				}
+			}
+			next[name] = s
+		default:
+			next[name] = newSeries(o)
+		}
+	}
+	t.series = next
+	t.saved = nil
+}
+
+// Add records one service transaction.
+func (t *Tracker) Add(objType string, xp *pack.XLogPack) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.syncLocked()
+	if len(t.series) == 0 {
+		return
+	}
+
+	key := matchKey{service: xp.Service, objType: objType}
+	minute := xp.EndTime / 60000
+	name := ""
+	for _, s := range t.series {
+		m, ok := s.matched[key]
+		if !ok {
+			if name == "" {
+				if name = t.serviceName(xp.Service); name == "" {
+					return // not resolvable yet; try again on a later transaction
+				}
+			}
+			m = s.obj.Matches(name, objType)
+			s.matched[key] = m
+		}
+		if !m {
+			continue
+		}
+		var bad int64
+		if xp.Error != 0 || xp.Elapsed > s.obj.LatencyMs {
+			bad = 1
+			s.addBad(xp)
+		}
+		s.add(minute, 1, bad)
+	}
+}
+
+// Statuses returns the status of every objective at now, sorted by name.
+func (t *Tracker) Statuses(now time.Time) []Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.syncLocked()
+
+	minute := now.UnixMilli() / 60000
+	result := make([]Status, 0, len(t.series))
+	for _, s := range t.series {
+		st := Status{
+			Objective:       s.obj,
+			Compliance:      100,
+			BudgetRemaining: 100,
+			BurnRate1h:      s.burnRate(minute, 60),
+			BurnRate6h:      s.burnRate(minute, 360),
+			Burning:         s.burning,
+		}
+		st.Total, st.Bad = s.sum(minute, len(s.ring))
+		if st.Total > 0 {
+			st.Compliance = float64(st.Total-st.Bad) * 100 / float64(st.Total)
+			st.BudgetRemaining = 100 - float64(st.Bad)*100/(float64(st.Total)*s.budget())
+		}
+		result = append(result, st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Evaluate checks the burn rates at now and raises an alert when an
+// objective starts burning, escalates from slow to fast, or recovers.
+func (t *Tracker) Evaluate(now time.Time) {
+	t.mu.Lock()
+	t.syncLocked()
+	minute := now.UnixMilli() / 60000
+	var alerts []*alert
+	for _, s := range t.series {
+		fast := s.burnRate(minute, fastLongMin)
+		slow := s.burnRate(minute, slowLongMin)
+		state := ""
+		switch {
+		case fast >= fastBurnRate && s.burnRate(minute, fastShortMin) >= fastBurnRate:
+			state = burningFast
+		case slow >= slowBurnRate && s.burnRate(minute, slowShortMin) >= slowBurnRate:
+			state = burningSlow
+		}
+		prev := s.burning
+		s.burning = state
+		switch {
+		case state == prev, state == burningSlow && prev == burningFast:
+			continue
+		case state == burningFast:
+			a := newAlert(s.obj, now, 2, "SLO_FAST_BURN",
+				fmt.Sprintf("%s is burning its error budget %.1fx too fast over the last hour.", s.obj.Name, fast))
+			a.pack.SetXLogRefs(s.xlogRefs(minute, fastLongMin))
+			alerts = append(alerts, a)
+		case state == burningSlow:
+			a := newAlert(s.obj, now, 1, "SLO_SLOW_BURN",
+				fmt.Sprintf("%s is burning its error budget %.1fx too fast over the last 6 hours.", s.obj.Name, slow))
+			a.pack.SetXLogRefs(s.xlogRefs(minute, slowLongMin))
+			alerts = append(alerts, a)
+		default:
+			alerts = append(alerts, newAlert(s.obj, now, 0, "SLO_BURN_RESOLVED",
+				fmt.Sprintf("%s is no longer burning its error budget too fast.", s.obj.Name)))
+		}
+	}
+	t.mu.Unlock()
+
+	if t.ingest == nil {
+		return
+	}
+	for _, a := range alerts {
+		// Register the pseudo-object name so clients can resolve the objHash
+		// to the objective.
+		t.ingest(&pack.TextPack{XType: "object", Hash: a.pack.ObjHash, Text: a.objName})
+		t.ingest(a.pack)
+	}
+}
+
+// alert is a burn-rate alert raised for the pseudo-object /slo/{name}.
+type alert struct {
+	objName string
+	pack    *pack.AlertPack
+}
+
+func newAlert(o Objective, now time.Time, level byte, title, message string) *alert {
+	objName := alertObjPrefix + o.Name
+	tags := value.NewMapValue()
+	tags.Put("slo", value.NewTextValue(o.Name))
+	return &alert{objName: objName, pack: &pack.AlertPack{
+		Time:    now.UnixMilli(),
+		Level:   level,
+		ObjType: alertObjType,
+		ObjHash: util.HashString(objName),
+		Title:   title,
+		Message: message,
+		Tags:    tags,
+	}}
+}
+
+// Start evaluates burn rates and saves the counts every minute until ctx is
+// done, then saves once more.
+func (t *Tracker) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				t.save()
+				return
+			case now := <-ticker.C:
+				t.Evaluate(now)
+				t.save()
+			}
+		}
+	}()
+}
+
+// savedSeries is the persisted form of a series. Buckets holds
+// [minute, total, bad] for the non-empty minutes.
+type savedSeries struct {
+	Objective Objective  `json:"objective"`
+	Burning   string     `json:"burning,omitempty"`
+	Buckets   [][3]int64 `json:"buckets"`
+}
+
+func (t *Tracker) save() {
+	t.mu.Lock()
+	state := make(map[string]savedSeries, len(t.series))
+	for name, s := range t.series {
+		sv := savedSeries{Objective: s.obj, Burning: s.burning}
+		for _, b := range s.ring {
+			if b.minute != 0 {
+				sv.Buckets = append(sv.Buckets, [3]int64{b.minute, b.total, b.bad})
+			}
+		}
+		state[name] = sv
+	}
+	t.mu.Unlock()
+
+	if err := writeState(filepath.Join(t.dir, stateFileName), state); err != nil {
+		slog.Error("SLO: save state failed", "error", err)
+	}
+}
+
+func writeState(path string, state map[string]savedSeries) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// load reads the saved counts; they are attached to the objectives on the
+// first sync.
+func (t *Tracker) load() error {
+	data, err := os.ReadFile(filepath.Join(t.dir, stateFileName))
+	if err != nil {
+		return err
+	}
+	state := make(map[string]savedSeries)
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	t.saved = state
+	return nil
+}
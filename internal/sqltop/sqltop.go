@@ -0,0 +1,343 @@
+// Package sqltop ranks the slowest SQL statements of every hour from the SQL
+// summary packs agents send every few minutes, so slow queries can be found
+// without scanning profiles.
+//
+// The current day is aggregated in memory. Every minute the top entries of
+// each hour are written to {date}/sqltop/sqltop.json: the sql_top_n
+// statements with the highest total elapsed time, plus those among the top
+// by average elapsed time, execution count or errors, so every ordering
+// offered by Top is exact for the stored day.
+package sqltop
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// summaryTypeSQL is the SummaryPack type of SQL statistics.
+const summaryTypeSQL byte = 2
+
+// Orders accepted by Top.
+const (
+	OrderElapsed = "elapsed" // total elapsed time
+	OrderAvg     = "avg"     // average elapsed time
+	OrderCount   = "count"
+	OrderErrors  = "error"
+)
+
+// Stat is the activity of one SQL statement in one hour.
+type Stat struct {
+	SQL     int32 `json:"sql"` // hash of the statement, text type "sql"
+	Count   int64 `json:"count"`
+	Errors  int64 `json:"error"`
+	Elapsed int64 `json:"elapsed"` // total ms
+}
+
+// AvgElapsed returns the average elapsed time in ms.
+func (s Stat) AvgElapsed() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Elapsed) / float64(s.Count)
+}
+
+// dayFile is the on-disk format of one day.
+type dayFile struct {
+	Hours [24][]Stat `json:"hours"`
+}
+
+// Core aggregates SQL summaries into hourly rankings.
+type Core struct {
+	baseDir string
+	queue   chan *pack.SummaryPack
+
+	mu    sync.Mutex
+	date  string
+	hours [24]map[int32]*Stat
+	dirty bool
+}
+
+// NewCore creates a Core storing under baseDir and resumes today's rankings.
+func NewCore(baseDir string) *Core {
+	c := &Core{baseDir: baseDir, queue: make(chan *pack.SummaryPack, 1024)}
+	c.reset(time.Now().Format("20060102"))
+	go c.run()
+	go c.flusher()
+	return c
+}
+
+// ProcessSummary queues sp if it is a SQL summary.
+func (c *Core) ProcessSummary(sp *pack.SummaryPack) {
+	if sp.SType != summaryTypeSQL || sp.Table == nil {
+		return
+	}
+	select {
+	case c.queue <- sp:
+	default:
+		slog.Debug("SQL top queue overflow")
+	}
+}
+
+func (c *Core) run() {
+	for sp := range c.queue {
+		c.add(sp)
+	}
+}
+
+// add merges sp into the hour its interval ends in.
+func (c *Core) add(sp *pack.SummaryPack) {
+	ids := listOf(sp.Table, "id")
+	if ids == nil {
+		return
+	}
+	counts := listOf(sp.Table, "count")
+	errs := listOf(sp.Table, "error")
+	elapsed := listOf(sp.Table, "elapsed")
+
+	// A summary stamped 10:00:00 covers the interval before it.
+	t := time.UnixMilli(sp.Time - 1)
+	date := t.Format("20060102")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case date > c.date:
+		c.flushLocked()
+		c.reset(date)
+	case date < c.date:
+		return // late summaries of a stored day are not merged
+	}
+	hour := c.hours[t.Hour()]
+	for i := range ids.Value {
+		sql := int32(longAt(ids, i))
+		st := hour[sql]
+		if st == nil {
+			st = &Stat{SQL: sql}
+			hour[sql] = st
+		}
+		st.Count += longAt(counts, i)
+		st.Errors += longAt(errs, i)
+		st.Elapsed += longAt(elapsed, i)
+	}
+	c.dirty = true
+}
+
+// reset starts aggregating date from its stored rankings, if any.
+func (c *Core) reset(date string) {
+	c.date, c.dirty = date, false
+	stored, _ := c.load(date)
+	for h := range c.hours {
+		c.hours[h] = make(map[int32]*Stat)
+		if stored != nil {
+			for _, st := range stored.Hours[h] {
+				c.hours[h][st.SQL] = &st
+			}
+		}
+	}
+}
+
+func (c *Core) flusher() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		c.Flush()
+	}
+}
+
+// Flush writes the rankings of the current day if they changed.
+func (c *Core) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushLocked()
+}
+
+func (c *Core) flushLocked() {
+	if !c.dirty {
+		return
+	}
+	var df dayFile
+	n := topN()
+	for h, stats := range c.hours {
+		df.Hours[h] = keep(stats, n)
+	}
+	if err := c.save(c.date, &df); err != nil {
+		slog.Warn("SQL top: save failed", "date", c.date, "error", err)
+		return
+	}
+	c.dirty = false
+}
+
+// Top returns the top max statements of date by order, for one hour (0-23)
+// or, with hour -1, for the whole day. max <= 0 means sql_top_n.
+func (c *Core) Top(date string, hour int, order string, max int) ([]Stat, error) {
+	if max <= 0 {
+		max = topN()
+	}
+	merged := make(map[int32]*Stat)
+	addAll := func(stats []Stat) {
+		for _, st := range stats {
+			m := merged[st.SQL]
+			if m == nil {
+				m = &Stat{SQL: st.SQL}
+				merged[st.SQL] = m
+			}
+			m.Count += st.Count
+			m.Errors += st.Errors
+			m.Elapsed += st.Elapsed
+		}
+	}
+
+	c.mu.Lock()
+	if date == c.date {
+		for h, stats := range c.hours {
+			if hour < 0 || h == hour {
+				for _, st := range stats {
+					addAll([]Stat{*st})
+				}
+			}
+		}
+		c.mu.Unlock()
+	} else {
+		c.mu.Unlock()
+		df, err := c.load(date)
+		if err != nil {
+			return nil, err
+		}
+		if df != nil {
+			for h, stats := range df.Hours {
+				if hour < 0 || h == hour {
+					addAll(stats)
+				}
+			}
+		}
+	}
+
+	result := make([]Stat, 0, len(merged))
+	for _, st := range merged {
+		result = append(result, *st)
+	}
+	sortStats(result, order)
+	if len(result) > max {
+		result = result[:max]
+	}
+	return result, nil
+}
+
+// keep returns the statements among the top n by any order, by total
+// elapsed time.
+func keep(stats map[int32]*Stat, n int) []Stat {
+	all := make([]Stat, 0, len(stats))
+	for _, st := range stats {
+		all = append(all, *st)
+	}
+	if len(all) <= n {
+		sortStats(all, OrderElapsed)
+		return all
+	}
+	kept := make(map[int32]bool)
+	for _, order := range []string{OrderAvg, OrderCount, OrderErrors, OrderElapsed} {
+		sortStats(all, order)
+		for _, st := range all[:n] {
+			if order != OrderErrors || st.Errors > 0 {
+				kept[st.SQL] = true
+			}
+		}
+	}
+	result := all[:0]
+	for _, st := range all {
+		if kept[st.SQL] {
+			result = append(result, st)
+		}
+	}
+	return result
+}
+
+// sortStats sorts stats by order, highest first; unknown orders sort by
+// total elapsed time.
+func sortStats(stats []Stat, order string) {
+	key := func(s Stat) float64 {
+		switch order {
+		case OrderAvg:
+			return s.AvgElapsed()
+		case OrderCount:
+			return float64(s.Count)
+		case OrderErrors:
+			return float64(s.Errors)
+		}
+		return float64(s.Elapsed)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if ki, kj := key(stats[i]), key(stats[j]); ki != kj {
+			return ki > kj
+		}
+		return stats[i].SQL < stats[j].SQL
+	})
+}
+
+func (c *Core) path(date string) string {
+	return filepath.Join(c.baseDir, date, "sqltop", "sqltop.json")
+}
+
+func (c *Core) save(date string, df *dayFile) error {
+	path := c.path(date)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(df)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// load reads the stored rankings of date, nil if there are none.
+func (c *Core) load(date string) (*dayFile, error) {
+	data, err := os.ReadFile(c.path(date))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var df dayFile
+	if err := json.Unmarshal(data, &df); err != nil {
+		return nil, err
+	}
+	return &df, nil
+}
+
+func topN() int {
+	if cfg := config.Get(); cfg != nil && cfg.SQLTopN() > 0 {
+		return cfg.SQLTopN()
+	}
+	return 50
+}
+
+func listOf(mv *value.MapValue, key string) *value.ListValue {
+	v, ok := mv.Get(key)
+	if !ok {
+		return nil
+	}
+	lv, _ := v.(*value.ListValue)
+	return lv
+}
+
+func longAt(lv *value.ListValue, i int) int64 {
+	if lv == nil || i >= len(lv.Value) {
+		return 0
+	}
+	return lv.GetLong(i)
+}
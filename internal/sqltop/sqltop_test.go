@@ -0,0 +1,117 @@
+package sqltop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+// sqlSummary builds a SQL summary of rows {sql, count, error, elapsed}.
+func sqlSummary(t time.Time, rows ...[4]int64) *pack.SummaryPack {
+	cols := []string{"id", "count", "error", "elapsed"}
+	table := value.NewMapValue()
+	lists := make([]*value.ListValue, len(cols))
+	for i, name := range cols {
+		lists[i] = value.NewListValue()
+		table.Put(name, lists[i])
+	}
+	for _, row := range rows {
+		for i, v := range row {
+			lists[i].Value = append(lists[i].Value, value.NewDecimalValue(v))
+		}
+	}
+	return &pack.SummaryPack{Time: t.UnixMilli(), SType: summaryTypeSQL, Table: table}
+}
+
+func newTestCore(t *testing.T, date string) *Core {
+	c := &Core{baseDir: t.TempDir()}
+	c.reset(date)
+	return c
+}
+
+func TestCoreHourlyTop(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	c := newTestCore(t, "20260310")
+
+	c.add(sqlSummary(day.Add(9*time.Hour+5*time.Minute), [4]int64{1, 10, 0, 1000}, [4]int64{2, 1, 1, 900}))
+	c.add(sqlSummary(day.Add(9*time.Hour+10*time.Minute), [4]int64{1, 10, 0, 1000}))
+	// Stamped 11:00:00, it covers the end of hour 10.
+	c.add(sqlSummary(day.Add(11*time.Hour), [4]int64{3, 5, 0, 5000}))
+
+	stats, err := c.Top("20260310", 9, OrderElapsed, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 || stats[0] != (Stat{SQL: 1, Count: 20, Elapsed: 2000}) {
+		t.Fatalf("hour 9 by elapsed = %+v", stats)
+	}
+	if stats, _ := c.Top("20260310", 9, OrderAvg, 0); stats[0].SQL != 2 {
+		t.Errorf("hour 9 by avg = %+v, want sql 2 first", stats)
+	}
+	if stats, _ := c.Top("20260310", 10, OrderElapsed, 0); len(stats) != 1 || stats[0].SQL != 3 {
+		t.Errorf("hour 10 = %+v, want sql 3", stats)
+	}
+	if stats, _ := c.Top("20260310", -1, OrderElapsed, 1); len(stats) != 1 || stats[0].SQL != 3 {
+		t.Errorf("day top 1 = %+v, want sql 3", stats)
+	}
+
+	// Non-SQL summaries are ignored.
+	sp := sqlSummary(day.Add(9*time.Hour), [4]int64{9, 1, 0, 1})
+	sp.SType = 1
+	c.ProcessSummary(sp)
+	if len(c.queue) != 0 {
+		t.Error("non-SQL summary queued")
+	}
+}
+
+func TestCoreFlushAndReload(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	c := newTestCore(t, "20260310")
+	c.add(sqlSummary(day.Add(9*time.Hour+5*time.Minute), [4]int64{1, 2, 1, 300}))
+
+	// The next day flushes the previous one.
+	c.add(sqlSummary(day.Add(33*time.Hour), [4]int64{2, 1, 0, 10}))
+	if c.date != "20260311" {
+		t.Fatalf("date = %s, want 20260311", c.date)
+	}
+	stats, err := c.Top("20260310", 9, OrderElapsed, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0] != (Stat{SQL: 1, Count: 2, Errors: 1, Elapsed: 300}) {
+		t.Fatalf("stored hour 9 = %+v", stats)
+	}
+
+	// Late summaries of a stored day are dropped.
+	c.add(sqlSummary(day.Add(9*time.Hour+10*time.Minute), [4]int64{1, 2, 1, 300}))
+	if stats, _ := c.Top("20260310", 9, OrderElapsed, 0); stats[0].Count != 2 {
+		t.Errorf("late summary merged: %+v", stats)
+	}
+
+	// A restart resumes the current day.
+	c.Flush()
+	restarted := &Core{baseDir: c.baseDir}
+	restarted.reset("20260311")
+	if stats, _ := restarted.Top("20260311", 8, OrderElapsed, 0); len(stats) != 1 || stats[0].SQL != 2 {
+		t.Errorf("resumed hour 8 = %+v", stats)
+	}
+}
+
+func TestKeep(t *testing.T) {
+	stats := map[int32]*Stat{
+		1: {SQL: 1, Count: 100, Elapsed: 10000}, // top by elapsed and count
+		2: {SQL: 2, Count: 1, Elapsed: 5000},    // top by avg
+		3: {SQL: 3, Count: 10, Errors: 5, Elapsed: 100},
+		4: {SQL: 4, Count: 10, Elapsed: 200},
+	}
+	kept := keep(stats, 1)
+	got := make(map[int32]bool)
+	for _, st := range kept {
+		got[st.SQL] = true
+	}
+	if len(kept) != 3 || !got[1] || !got[2] || !got[3] {
+		t.Errorf("keep = %+v, want sql 1, 2 and 3", kept)
+	}
+}
@@ -0,0 +1,407 @@
+// Package standby replicates the realtime caches (objects, counters and the
+// XLog ring) of a primary server to a warm standby, so that after a failover
+// the standby's realtime views are populated at once instead of blank until
+// agents send again.
+//
+// The standby dials the primary and sends:
+//
+//	int32   magic ("SCSB")
+//	text    standby_repl_token
+//	int64   epoch of the primary it last synced from (0 if none)
+//	int64   XLog ring loop and
+//	int32   index it last received
+//
+// The primary answers with its own epoch and then, every
+// standby_repl_interval_ms, a batch (int32 length + bytes) of:
+//
+//	int64   primary time (epoch ms)
+//	int64   XLog ring loop and
+//	int32   index after the batch
+//	records until the end of the batch, each a type byte and:
+//	  object:  int64 ms since last seen, ObjectPack
+//	  counter: int32 objHash, text name, byte time type, value
+//	  xlog:    int32 objHash, int32 elapsed, boolean error, blob XLogPack
+//
+// Every batch carries all objects and counters; XLogs are sent once, from
+// where the standby left off if the primary has not restarted since.
+package standby
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+const magic = int32(0x53435342) // "SCSB"
+
+// Record types of a batch.
+const (
+	recObject  byte = 1
+	recCounter byte = 2
+	recXLog    byte = 3
+)
+
+// ioTimeout bounds a blocked read or write of the stream.
+const ioTimeout = 30 * time.Second
+
+// Caches are the realtime caches replicated.
+type Caches struct {
+	Objects  *cache.ObjectCache
+	Counters *cache.CounterCache
+	XLogs    *cache.XLogCache
+}
+
+func interval() time.Duration {
+	if cfg := config.Get(); cfg != nil && cfg.StandbyReplIntervalMs() > 0 {
+		return time.Duration(cfg.StandbyReplIntervalMs()) * time.Millisecond
+	}
+	return 2 * time.Second
+}
+
+// Publisher streams the caches of a primary to the standbys that connect.
+type Publisher struct {
+	caches Caches
+	token  string
+	epoch  int64 // identifies this process's XLog ring
+
+	clients atomic.Int32
+	batches atomic.Int64
+	bytes   atomic.Int64
+}
+
+// NewPublisher creates a Publisher accepting standbys that present token.
+func NewPublisher(caches Caches, token string) *Publisher {
+	return &Publisher{caches: caches, token: token, epoch: time.Now().UnixMilli()}
+}
+
+// Start listens on addr and serves standbys until ctx is cancelled. It
+// refuses to start without a token, which would let any client read the
+// caches.
+func (p *Publisher) Start(ctx context.Context, addr string) error {
+	if p.token == "" {
+		return errors.New("standby_repl_token is empty")
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				slog.Warn("Standby publisher: accept error", "error", err)
+				time.Sleep(time.Second)
+				continue
+			}
+			go p.serve(ctx, conn)
+		}
+	}()
+	return nil
+}
+
+func (p *Publisher) serve(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	addr := conn.RemoteAddr().String()
+
+	conn.SetReadDeadline(time.Now().Add(ioTimeout))
+	in := protocol.NewDataInputXStream(bufio.NewReader(conn))
+	m, err := in.ReadInt32()
+	if err != nil || m != magic {
+		slog.Warn("Standby publisher: not a standby", "addr", addr)
+		return
+	}
+	token, err := in.ReadText()
+	if err != nil {
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(p.token)) != 1 {
+		slog.Warn("Standby publisher: token mismatch", "addr", addr)
+		return
+	}
+	epoch, err := in.ReadInt64()
+	if err != nil {
+		return
+	}
+	loop, err := in.ReadInt64()
+	if err != nil {
+		return
+	}
+	index, err := in.ReadInt32()
+	if err != nil {
+		return
+	}
+	if epoch != p.epoch {
+		loop, index = -1, 0 // another ring: send all of it
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	p.clients.Add(1)
+	defer p.clients.Add(-1)
+	slog.Info("Standby connected", "addr", addr, "resume", epoch == p.epoch)
+
+	hello := protocol.NewDataOutputX()
+	hello.WriteInt64(p.epoch)
+	if err := p.write(conn, hello.ToByteArray()); err != nil {
+		return
+	}
+	for {
+		var batch []byte
+		batch, loop, index = p.Batch(loop, index)
+		out := protocol.NewDataOutputX()
+		out.WriteIntBytes(batch)
+		if err := p.write(conn, out.ToByteArray()); err != nil {
+			slog.Info("Standby disconnected", "addr", addr, "error", err)
+			return
+		}
+		p.batches.Add(1)
+		p.bytes.Add(int64(len(batch)))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval()):
+		}
+	}
+}
+
+func (p *Publisher) write(conn net.Conn, b []byte) error {
+	conn.SetWriteDeadline(time.Now().Add(ioTimeout))
+	_, err := conn.Write(b)
+	return err
+}
+
+// Batch encodes the caches, with the XLogs added after (loop, index), and
+// returns the batch and the ring position after it.
+func (p *Publisher) Batch(loop int64, index int32) ([]byte, int64, int32) {
+	now := time.Now()
+	xlogs := p.caches.XLogs.Get(loop, int(index), 0, nil)
+
+	out := protocol.NewDataOutputX()
+	out.WriteInt64(now.UnixMilli())
+	out.WriteInt64(xlogs.Loop)
+	out.WriteInt32(int32(xlogs.Index))
+	for _, info := range p.caches.Objects.GetAll() {
+		out.WriteByte(recObject)
+		out.WriteInt64(now.Sub(info.LastSeen).Milliseconds())
+		pack.WritePack(out, info.Pack)
+	}
+	for key, v := range p.caches.Counters.Snapshot() {
+		out.WriteByte(recCounter)
+		out.WriteInt32(key.ObjHash)
+		out.WriteText(key.Counter)
+		out.WriteByte(key.TimeType)
+		value.WriteValue(out, v)
+	}
+	for _, e := range xlogs.Data {
+		out.WriteByte(recXLog)
+		out.WriteInt32(e.ObjHash)
+		out.WriteInt32(e.Elapsed)
+		out.WriteBoolean(e.IsError)
+		out.WriteBlob(e.Data)
+	}
+	return out.ToByteArray(), xlogs.Loop, int32(xlogs.Index)
+}
+
+// Summary describes the connected standbys for admin status.
+func (p *Publisher) Summary() string {
+	return fmt.Sprintf("%d standby(s) connected, %d batches, %d bytes sent",
+		p.clients.Load(), p.batches.Load(), p.bytes.Load())
+}
+
+// Replica keeps the caches of a standby in sync with its primary.
+type Replica struct {
+	caches  Caches
+	primary string
+	token   string
+
+	// Position of the last batch, to resume without resending XLogs.
+	epoch int64
+	loop  int64
+	index int32
+
+	connected atomic.Bool
+	lastSync  atomic.Int64 // epoch ms of the last applied batch
+	batches   atomic.Int64
+}
+
+// NewReplica creates a Replica of the primary at addr (host:port).
+func NewReplica(caches Caches, primary, token string) *Replica {
+	return &Replica{caches: caches, primary: primary, token: token, loop: -1}
+}
+
+// Start syncs from the primary until ctx is cancelled, reconnecting after
+// failures.
+func (r *Replica) Start(ctx context.Context) {
+	go func() {
+		for ctx.Err() == nil {
+			err := r.session(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Warn("Standby replica: connection to primary lost", "primary", r.primary, "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}()
+}
+
+func (r *Replica) session(ctx context.Context) error {
+	d := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := d.DialContext(ctx, "tcp", r.primary)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	hello := protocol.NewDataOutputX()
+	hello.WriteInt32(magic)
+	hello.WriteText(r.token)
+	hello.WriteInt64(r.epoch)
+	hello.WriteInt64(r.loop)
+	hello.WriteInt32(r.index)
+	conn.SetWriteDeadline(time.Now().Add(ioTimeout))
+	if _, err := conn.Write(hello.ToByteArray()); err != nil {
+		return err
+	}
+
+	in := protocol.NewDataInputXStream(bufio.NewReader(conn))
+	conn.SetReadDeadline(time.Now().Add(ioTimeout))
+	epoch, err := in.ReadInt64()
+	if err != nil {
+		return err
+	}
+	if epoch != r.epoch {
+		r.epoch, r.loop, r.index = epoch, -1, 0
+	}
+	r.connected.Store(true)
+	defer r.connected.Store(false)
+	slog.Info("Standby replica: syncing from primary", "primary", r.primary)
+
+	for {
+		// The primary sends every interval; allow for a slow one.
+		conn.SetReadDeadline(time.Now().Add(ioTimeout + 2*interval()))
+		batch, err := in.ReadIntBytes()
+		if err != nil {
+			return err
+		}
+		if err := r.Apply(batch); err != nil {
+			return err
+		}
+	}
+}
+
+// Apply merges one batch into the caches.
+func (r *Replica) Apply(batch []byte) error {
+	in := protocol.NewDataInputX(batch)
+	if _, err := in.ReadInt64(); err != nil {
+		return err
+	}
+	loop, err := in.ReadInt64()
+	if err != nil {
+		return err
+	}
+	index, err := in.ReadInt32()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for in.Available() > 0 {
+		typ, err := in.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case recObject:
+			age, err := in.ReadInt64()
+			if err != nil {
+				return err
+			}
+			p, err := pack.ReadPack(in)
+			if err != nil {
+				return err
+			}
+			op, ok := p.(*pack.ObjectPack)
+			if !ok {
+				return fmt.Errorf("object record holds pack type %d", p.PackType())
+			}
+			r.caches.Objects.PutSeen(op.ObjHash, op, now.Add(-time.Duration(age)*time.Millisecond))
+		case recCounter:
+			var key cache.CounterKey
+			if key.ObjHash, err = in.ReadInt32(); err != nil {
+				return err
+			}
+			if key.Counter, err = in.ReadText(); err != nil {
+				return err
+			}
+			if key.TimeType, err = in.ReadByte(); err != nil {
+				return err
+			}
+			v, err := value.ReadValue(in)
+			if err != nil {
+				return err
+			}
+			r.caches.Counters.Put(key, v)
+		case recXLog:
+			objHash, err := in.ReadInt32()
+			if err != nil {
+				return err
+			}
+			elapsed, err := in.ReadInt32()
+			if err != nil {
+				return err
+			}
+			isError, err := in.ReadBoolean()
+			if err != nil {
+				return err
+			}
+			data, err := in.ReadBlob()
+			if err != nil {
+				return err
+			}
+			r.caches.XLogs.Put(objHash, elapsed, isError, data)
+		default:
+			return errors.New("unknown record type")
+		}
+	}
+	r.loop, r.index = loop, index
+	r.lastSync.Store(now.UnixMilli())
+	r.batches.Add(1)
+	return nil
+}
+
+// Summary describes the sync state for admin status.
+func (r *Replica) Summary() string {
+	state := "disconnected"
+	if r.connected.Load() {
+		state = "connected"
+	}
+	last := "never"
+	if ms := r.lastSync.Load(); ms > 0 {
+		last = time.Since(time.UnixMilli(ms)).Round(time.Second).String() + " ago"
+	}
+	return fmt.Sprintf("%s to %s, last sync %s, %d batches", state, r.primary, last, r.batches.Load())
+}
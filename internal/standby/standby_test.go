@@ -0,0 +1,131 @@
+package standby
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/config"
+	"github.com/zbum/scouter-server-go/internal/core/cache"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+)
+
+func newCaches() Caches {
+	return Caches{
+		Objects:  cache.NewObjectCache(),
+		Counters: cache.NewCounterCache(),
+		XLogs:    cache.NewXLogCache(16),
+	}
+}
+
+func TestBatchApply(t *testing.T) {
+	primary := newCaches()
+	primary.Objects.PutSeen(7, &pack.ObjectPack{ObjType: "java", ObjHash: 7, ObjName: "/host/app", Alive: true, Tags: value.NewMapValue()}, time.Now().Add(-time.Minute))
+	key := cache.CounterKey{ObjHash: 7, Counter: "TPS", TimeType: cache.TimeTypeRealtime}
+	primary.Counters.Put(key, &value.FloatValue{Value: 12.5})
+	primary.XLogs.Put(7, 100, false, []byte{1})
+	primary.XLogs.Put(7, 200, true, []byte{2})
+
+	p := NewPublisher(primary, "")
+	batch, loop, index := p.Batch(-1, 0)
+
+	standby := newCaches()
+	r := NewReplica(standby, "", "")
+	if err := r.Apply(batch); err != nil {
+		t.Fatal(err)
+	}
+	info, ok := standby.Objects.Get(7)
+	if !ok || info.Pack.ObjName != "/host/app" {
+		t.Fatalf("object not replicated: %+v", info)
+	}
+	if age := time.Since(info.LastSeen); age < 59*time.Second || age > 61*time.Second {
+		t.Errorf("last seen %s ago, want about 1m", age)
+	}
+	if v, ok := standby.Counters.Get(key); !ok || v.(*value.FloatValue).Value != 12.5 {
+		t.Errorf("counter = %v", v)
+	}
+	if recent := standby.XLogs.GetRecent(10); len(recent) != 2 || !recent[1].IsError {
+		t.Errorf("xlogs = %+v", recent)
+	}
+
+	// The next batch carries only the XLogs added since.
+	primary.XLogs.Put(7, 300, false, []byte{3})
+	batch, _, _ = p.Batch(loop, index)
+	if err := r.Apply(batch); err != nil {
+		t.Fatal(err)
+	}
+	if n := standby.XLogs.Count(); n != 3 {
+		t.Errorf("standby has %d xlogs, want 3", n)
+	}
+}
+
+func TestReplicaSyncsAndResumes(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "scouter.conf")
+	os.WriteFile(conf, []byte("standby_repl_interval_ms=20\n"), 0644)
+	config.Load(conf)
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.conf")) })
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	primary := newCaches()
+	primary.Objects.Put(1, &pack.ObjectPack{ObjHash: 1, ObjName: "/a", Alive: true, Tags: value.NewMapValue()})
+	primary.XLogs.Put(1, 10, false, []byte{1})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := NewPublisher(primary, "").Start(ctx, addr); err == nil {
+		t.Fatal("publisher started without a token")
+	}
+	if err := NewPublisher(primary, "secret").Start(ctx, addr); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// A wrong token gets nothing.
+	wrong := newCaches()
+	wrongCtx, stopWrong := context.WithCancel(ctx)
+	NewReplica(wrong, addr, "guess").Start(wrongCtx)
+
+	standby := newCaches()
+	r := NewReplica(standby, addr, "secret")
+	rctx, stop := context.WithCancel(ctx)
+	r.Start(rctx)
+	waitFor("initial sync", func() bool { return standby.Objects.Size() == 1 && standby.XLogs.Count() == 1 })
+
+	primary.XLogs.Put(1, 20, false, []byte{2})
+	waitFor("new xlog", func() bool { return standby.XLogs.Count() == 2 })
+	stopWrong()
+	if wrong.Objects.Size() != 0 {
+		t.Error("replica with a wrong token was served")
+	}
+
+	// After a reconnect the XLogs already received are not sent again.
+	stop()
+	waitFor("disconnect", func() bool { return !r.connected.Load() })
+	primary.XLogs.Put(1, 30, false, []byte{3})
+	r.Start(ctx)
+	waitFor("resumed sync", func() bool { return standby.XLogs.Count() >= 3 })
+	time.Sleep(100 * time.Millisecond)
+	if n := standby.XLogs.Count(); n != 3 {
+		t.Errorf("standby has %d xlogs after resume, want 3", n)
+	}
+}
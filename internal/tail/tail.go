@@ -0,0 +1,233 @@
+package tail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// objectRefreshInterval controls how often the object list is re-read so that
+// newly started agents of the selected type are picked up.
+const objectRefreshInterval = 10 * time.Second
+
+// Caller issues a TCP request and streams back the response packs.
+// It is implemented by *client.Client.
+type Caller interface {
+	Call(cmd string, param pack.Pack, handler func(pack.Pack)) error
+}
+
+// Options selects which XLogs are printed.
+type Options struct {
+	ObjType    string        // only objects of this type (empty = all)
+	ErrorOnly  bool          // only XLogs with an error
+	MinElapsed int32         // only XLogs at least this slow (ms)
+	Interval   time.Duration // polling interval (default 1s)
+}
+
+// Tailer polls the realtime XLog stream and prints one line per transaction.
+type Tailer struct {
+	caller Caller
+	opts   Options
+
+	objects       map[int32]*pack.ObjectPack
+	objectsLoaded time.Time
+	texts         map[string]map[int32]string
+
+	loop  int64
+	index int64
+}
+
+// New creates a Tailer using the given connection.
+func New(caller Caller, opts Options) *Tailer {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	return &Tailer{
+		caller:  caller,
+		opts:    opts,
+		objects: make(map[int32]*pack.ObjectPack),
+		texts:   make(map[string]map[int32]string),
+	}
+}
+
+// Run prints matching XLogs to w until ctx is cancelled or a request fails.
+// XLogs already buffered on the server when Run starts are skipped.
+func (t *Tailer) Run(ctx context.Context, w io.Writer) error {
+	if _, err := t.Poll(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(t.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		xlogs, err := t.Poll()
+		if err != nil {
+			return err
+		}
+		for _, line := range t.Format(xlogs) {
+			fmt.Fprintln(w, line)
+		}
+	}
+}
+
+// Poll fetches XLogs that arrived since the previous poll and applies the filters.
+func (t *Tailer) Poll() ([]*pack.XLogPack, error) {
+	if time.Since(t.objectsLoaded) >= objectRefreshInterval {
+		if err := t.loadObjects(); err != nil {
+			return nil, err
+		}
+	}
+
+	param := &pack.MapPack{}
+	param.PutLong("loop", t.loop)
+	param.PutLong("index", t.index)
+	param.PutLong("limit", int64(t.opts.MinElapsed))
+	if t.opts.ObjType != "" {
+		objHashes := value.NewListValue()
+		for hash, obj := range t.objects {
+			if obj.ObjType == t.opts.ObjType {
+				objHashes.Value = append(objHashes.Value, value.NewDecimalValue(int64(hash)))
+			}
+		}
+		if len(objHashes.Value) == 0 {
+			// No matching agents yet; an empty filter would match every object.
+			return nil, nil
+		}
+		param.Put("objHash", objHashes)
+	}
+
+	var xlogs []*pack.XLogPack
+	first := true
+	err := t.caller.Call(protocol.TRANX_REAL_TIME_GROUP, param, func(p pack.Pack) {
+		if first {
+			first = false
+			if mp, ok := p.(*pack.MapPack); ok {
+				t.loop = mp.GetLong("loop")
+				t.index = mp.GetLong("index")
+			}
+			return
+		}
+		xp, ok := p.(*pack.XLogPack)
+		if !ok || (t.opts.ErrorOnly && xp.Error == 0) {
+			return
+		}
+		xlogs = append(xlogs, xp)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return xlogs, nil
+}
+
+// Format renders XLogs as terminal lines, resolving object, service and error names.
+func (t *Tailer) Format(xlogs []*pack.XLogPack) []string {
+	if len(xlogs) == 0 {
+		return nil
+	}
+	services := make([]int32, 0, len(xlogs))
+	errHashes := make([]int32, 0)
+	for _, xp := range xlogs {
+		services = append(services, xp.Service)
+		if xp.Error != 0 {
+			errHashes = append(errHashes, xp.Error)
+		}
+	}
+	t.resolve("service", services)
+	t.resolve("error", errHashes)
+
+	lines := make([]string, 0, len(xlogs))
+	for _, xp := range xlogs {
+		objName := util.Hexa32ToString32(xp.ObjHash)
+		if obj := t.objects[xp.ObjHash]; obj != nil {
+			objName = obj.ObjName
+		}
+		line := fmt.Sprintf("%s %-30s %6dms  %s",
+			time.UnixMilli(xp.EndTime).Format("15:04:05.000"),
+			objName, xp.Elapsed, t.text("service", xp.Service))
+		if xp.SqlCount > 0 {
+			line += fmt.Sprintf("  sql=%d/%dms", xp.SqlCount, xp.SqlTime)
+		}
+		line += "  txid=" + util.Hexa32ToString64(xp.Txid)
+		if xp.Error != 0 {
+			line += "  ERROR " + t.text("error", xp.Error)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (t *Tailer) loadObjects() error {
+	objects := make(map[int32]*pack.ObjectPack)
+	err := t.caller.Call(protocol.OBJECT_LIST_REAL_TIME, nil, func(p pack.Pack) {
+		if op, ok := p.(*pack.ObjectPack); ok {
+			objects[op.ObjHash] = op
+		}
+	})
+	if err != nil {
+		return err
+	}
+	t.objects = objects
+	t.objectsLoaded = time.Now()
+	return nil
+}
+
+// resolve fetches unknown hashes of the given text type with GET_TEXT_100.
+func (t *Tailer) resolve(textType string, hashes []int32) {
+	known := t.texts[textType]
+	if known == nil {
+		known = make(map[int32]string)
+		t.texts[textType] = known
+	}
+
+	missing := value.NewListValue()
+	seen := make(map[int32]bool)
+	for _, h := range hashes {
+		if _, ok := known[h]; !ok && !seen[h] {
+			seen[h] = true
+			missing.Value = append(missing.Value, value.NewDecimalValue(int64(h)))
+		}
+	}
+	if len(missing.Value) == 0 {
+		return
+	}
+
+	param := &pack.MapPack{}
+	param.PutStr("type", textType)
+	param.Put("hash", missing)
+	t.caller.Call(protocol.GET_TEXT_100, param, func(p pack.Pack) {
+		mp, ok := p.(*pack.MapPack)
+		if !ok {
+			return
+		}
+		for h := range seen {
+			if s := mp.GetText(util.Hexa32ToString32(h)); s != "" {
+				known[h] = s
+			}
+		}
+	})
+	// Remember misses too so unresolvable hashes are not re-requested every poll.
+	for h := range seen {
+		if _, ok := known[h]; !ok {
+			known[h] = ""
+		}
+	}
+}
+
+func (t *Tailer) text(textType string, hash int32) string {
+	if s := t.texts[textType][hash]; s != "" {
+		return s
+	}
+	return "{" + util.Hexa32ToString32(hash) + "}"
+}
@@ -0,0 +1,109 @@
+package tail
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zbum/scouter-server-go/internal/protocol"
+	"github.com/zbum/scouter-server-go/internal/protocol/pack"
+	"github.com/zbum/scouter-server-go/internal/protocol/value"
+	"github.com/zbum/scouter-server-go/internal/util"
+)
+
+// fakeCaller answers the realtime commands from in-memory data.
+type fakeCaller struct {
+	objects  []*pack.ObjectPack
+	xlogs    []*pack.XLogPack
+	services map[int32]string
+	lastReq  *pack.MapPack
+	textReqs int
+}
+
+func (f *fakeCaller) Call(cmd string, param pack.Pack, handler func(pack.Pack)) error {
+	switch cmd {
+	case protocol.OBJECT_LIST_REAL_TIME:
+		for _, o := range f.objects {
+			handler(o)
+		}
+	case protocol.TRANX_REAL_TIME_GROUP:
+		f.lastReq = param.(*pack.MapPack)
+		meta := &pack.MapPack{}
+		meta.PutLong("loop", 0)
+		meta.PutLong("index", f.lastReq.GetLong("index")+int64(len(f.xlogs)))
+		handler(meta)
+		for _, xp := range f.xlogs {
+			handler(xp)
+		}
+	case protocol.GET_TEXT_100:
+		f.textReqs++
+		req := param.(*pack.MapPack)
+		resp := &pack.MapPack{}
+		for _, v := range req.Get("hash").(*value.ListValue).Value {
+			h := int32(v.(*value.DecimalValue).Value)
+			if s, ok := f.services[h]; ok && req.GetText("type") == "service" {
+				resp.PutStr(util.Hexa32ToString32(h), s)
+			}
+		}
+		handler(resp)
+	}
+	return nil
+}
+
+func TestTailer_FiltersAndFormats(t *testing.T) {
+	f := &fakeCaller{
+		objects: []*pack.ObjectPack{
+			{ObjHash: 1, ObjName: "/host1/tomcat1", ObjType: "java"},
+			{ObjHash: 2, ObjName: "/host2/node", ObjType: "nodejs"},
+		},
+		xlogs: []*pack.XLogPack{
+			{ObjHash: 1, Service: 10, Txid: 100, Elapsed: 120},
+			{ObjHash: 1, Service: 11, Txid: 101, Elapsed: 3400, Error: 99},
+		},
+		services: map[int32]string{10: "/ok", 11: "/fail"},
+	}
+
+	tl := New(f, Options{ObjType: "java", ErrorOnly: true})
+	xlogs, err := tl.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objHashes := f.lastReq.Get("objHash").(*value.ListValue)
+	if len(objHashes.Value) != 1 || objHashes.Value[0].(*value.DecimalValue).Value != 1 {
+		t.Errorf("expected objHash filter [1], got %v", objHashes.Value)
+	}
+	if len(xlogs) != 1 || xlogs[0].Txid != 101 {
+		t.Fatalf("expected only the error xlog, got %+v", xlogs)
+	}
+
+	lines := tl.Format(xlogs)
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, got %d", len(lines))
+	}
+	for _, want := range []string{"/host1/tomcat1", "3400ms", "/fail", "ERROR {x33}"} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("line %q does not contain %q", lines[0], want)
+		}
+	}
+
+	// Resolved (and unresolvable) hashes are cached between polls.
+	before := f.textReqs
+	tl.Format(xlogs)
+	if f.textReqs != before {
+		t.Errorf("expected cached text lookups, got %d new requests", f.textReqs-before)
+	}
+}
+
+func TestTailer_AdvancesIndex(t *testing.T) {
+	f := &fakeCaller{xlogs: []*pack.XLogPack{{ObjHash: 1}, {ObjHash: 1}}}
+	tl := New(f, Options{})
+
+	tl.Poll()
+	tl.Poll()
+	if got := f.lastReq.GetLong("index"); got != 2 {
+		t.Errorf("expected second poll to resume from index 2, got %d", got)
+	}
+	if f.lastReq.Get("objHash") != nil {
+		t.Error("expected no objHash filter without --objtype")
+	}
+}
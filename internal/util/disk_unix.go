@@ -7,15 +7,21 @@ import "syscall"
 // DiskUsagePct returns the disk usage percentage for the filesystem containing the given path.
 // Returns 0 on error.
 func DiskUsagePct(path string) int {
+	total, used, err := DiskSpace(path)
+	if err != nil || total == 0 {
+		return 0
+	}
+	return int(used * 100 / total)
+}
+
+// DiskSpace returns the total and used bytes of the filesystem containing the
+// given path. Space reserved for the superuser is counted as used.
+func DiskSpace(path string) (total, used uint64, err error) {
 	var stat syscall.Statfs_t
 	if err := syscall.Statfs(path, &stat); err != nil {
-		return 0
+		return 0, 0, err
 	}
-	total := stat.Blocks * uint64(stat.Bsize)
+	total = stat.Blocks * uint64(stat.Bsize)
 	free := stat.Bavail * uint64(stat.Bsize)
-	if total == 0 {
-		return 0
-	}
-	used := total - free
-	return int(used * 100 / total)
+	return total, total - free, nil
 }
@@ -10,27 +10,33 @@ import (
 // DiskUsagePct returns the disk usage percentage for the filesystem containing the given path.
 // Returns 0 on error.
 func DiskUsagePct(path string) int {
+	total, used, err := DiskSpace(path)
+	if err != nil || total == 0 {
+		return 0
+	}
+	return int(used * 100 / total)
+}
+
+// DiskSpace returns the total and used bytes of the volume containing the
+// given path.
+func DiskSpace(path string) (total, used uint64, err error) {
 	kernel32 := syscall.NewLazyDLL("kernel32.dll")
 	getDiskFreeSpaceEx := kernel32.NewProc("GetDiskFreeSpaceExW")
 
 	var freeBytesAvailable, totalBytes, totalFreeBytes uint64
 	pathPtr, err := syscall.UTF16PtrFromString(path)
 	if err != nil {
-		return 0
+		return 0, 0, err
 	}
 
-	ret, _, _ := getDiskFreeSpaceEx.Call(
+	ret, _, callErr := getDiskFreeSpaceEx.Call(
 		uintptr(unsafe.Pointer(pathPtr)),
 		uintptr(unsafe.Pointer(&freeBytesAvailable)),
 		uintptr(unsafe.Pointer(&totalBytes)),
 		uintptr(unsafe.Pointer(&totalFreeBytes)),
 	)
 	if ret == 0 {
-		return 0
-	}
-	if totalBytes == 0 {
-		return 0
+		return 0, 0, callErr
 	}
-	used := totalBytes - totalFreeBytes
-	return int(used * 100 / totalBytes)
+	return totalBytes, totalBytes - totalFreeBytes, nil
 }
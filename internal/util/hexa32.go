@@ -17,3 +17,14 @@ func Hexa32ToString32(h int32) string {
 	// negative
 	return "z" + strconv.FormatInt(-int64(h), 32)
 }
+
+// Hexa32ToString64 is the int64 variant of Hexa32ToString32, used for txids.
+func Hexa32ToString64(v int64) string {
+	if v >= 0 && v < 10 {
+		return strconv.FormatInt(v, 10)
+	}
+	if v >= 0 {
+		return "x" + strconv.FormatInt(v, 32)
+	}
+	return "z" + strconv.FormatUint(uint64(-v), 32)
+}
@@ -23,3 +23,21 @@ func TestHexa32ToString32(t *testing.T) {
 		}
 	}
 }
+
+func TestHexa32ToString64(t *testing.T) {
+	tests := []struct {
+		input    int64
+		expected string
+	}{
+		{7, "7"},
+		{100, "x34"},
+		{-100, "z34"},
+		{1 << 40, "x100000000"},
+	}
+	for _, tc := range tests {
+		got := Hexa32ToString64(tc.input)
+		if got != tc.expected {
+			t.Errorf("Hexa32ToString64(%d) = %q, want %q", tc.input, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package util
+
+import "sort"
+
+// Histogram counts observations in the buckets bounded by Bounds; a last
+// bucket holds everything larger.
+type Histogram[V ~int64] struct {
+	Bounds  []V
+	Buckets []int64 // len(Bounds)+1
+	Count   int64
+	Max     V
+}
+
+// NewHistogram creates a histogram with the given ascending bucket bounds.
+func NewHistogram[V ~int64](bounds []V) Histogram[V] {
+	return Histogram[V]{Bounds: bounds, Buckets: make([]int64, len(bounds)+1)}
+}
+
+// Observe records one observation.
+func (h *Histogram[V]) Observe(v V) {
+	h.Buckets[sort.Search(len(h.Bounds), func(i int) bool { return v <= h.Bounds[i] })]++
+	h.Count++
+	h.Max = max(h.Max, v)
+}
+
+// Quantile returns the upper bound of the bucket holding quantile q, or Max
+// if that is the last bucket or smaller.
+func (h Histogram[V]) Quantile(q float64) V {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := int64(q*float64(h.Count) + 0.5)
+	rank = min(max(rank, 1), h.Count)
+	var seen int64
+	for i, n := range h.Buckets {
+		seen += n
+		if seen >= rank {
+			if i < len(h.Bounds) && h.Bounds[i] < h.Max {
+				return h.Bounds[i]
+			}
+			return h.Max
+		}
+	}
+	return h.Max
+}
@@ -0,0 +1,37 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogram_Quantile(t *testing.T) {
+	h := NewHistogram([]time.Duration{time.Millisecond, 5 * time.Millisecond, 500 * time.Millisecond, time.Second})
+	if h.Quantile(0.99) != 0 {
+		t.Error("empty histogram quantile not 0")
+	}
+	for range 98 {
+		h.Observe(3 * time.Millisecond)
+	}
+	h.Observe(700 * time.Millisecond)
+	h.Observe(45 * time.Second)
+
+	if got := h.Quantile(0.5); got != 5*time.Millisecond {
+		t.Errorf("p50 = %s, want 5ms", got)
+	}
+	if got := h.Quantile(0.99); got != time.Second {
+		t.Errorf("p99 = %s, want 1s", got)
+	}
+	if got := h.Quantile(1); got != 45*time.Second {
+		t.Errorf("p100 = %s, want max 45s", got)
+	}
+	if h.Count != 100 || h.Max != 45*time.Second {
+		t.Errorf("count = %d, max = %s", h.Count, h.Max)
+	}
+
+	small := NewHistogram([]int64{10, 20})
+	small.Observe(5)
+	if got := small.Quantile(0.99); got != 5 {
+		t.Errorf("quantile below the bucket bound = %d, want max 5", got)
+	}
+}
@@ -1,6 +1,10 @@
 @echo off
 setlocal
 
+set SCRIPT_DIR=%~dp0
+set SCOUTER_CONF=%SCRIPT_DIR%conf\scouter.conf
+
 echo Stopping Scouter Server...
-taskkill /im scouter-server.exe /f
+"%SCRIPT_DIR%scouter-server.exe" admin shutdown --wait 30s
+if errorlevel 1 taskkill /im scouter-server.exe /f
 echo Scouter Server stopped.
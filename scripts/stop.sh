@@ -2,34 +2,37 @@
 set -e
 
 SCRIPT_DIR="$(cd "$(dirname "$0")" && pwd)"
+BINARY="$SCRIPT_DIR/scouter-server"
+PID_FILE="$SCRIPT_DIR/scouter-server.pid"
 
-# Find *.scouter PID files (the server creates {PID}.scouter)
-PID_FILES=("$SCRIPT_DIR"/*.scouter)
+export SCOUTER_CONF="$SCRIPT_DIR/conf/scouter.conf"
 
-if [ ! -e "${PID_FILES[0]}" ]; then
-    echo "No .scouter PID file found. Scouter Server may not be running."
-    exit 1
+# Graceful shutdown through the admin socket; waits until the server exits.
+if "$BINARY" admin shutdown --wait 30s; then
+    rm -f "$PID_FILE"
+    echo "Scouter Server stopped."
+    exit 0
 fi
 
-for PID_FILE in "${PID_FILES[@]}"; do
-    PID=$(basename "$PID_FILE" .scouter)
-    echo "Stopping Scouter Server (PID: $PID) by removing $PID_FILE ..."
-    rm -f "$PID_FILE"
-done
+# Fall back to signals if the admin socket is unavailable.
+if [ ! -f "$PID_FILE" ]; then
+    echo "Scouter Server does not appear to be running."
+    exit 1
+fi
 
-# Wait for the process to exit gracefully (up to 30 seconds)
-for PID_FILE in "${PID_FILES[@]}"; do
-    PID=$(basename "$PID_FILE" .scouter)
-    for i in $(seq 1 30); do
-        if ! kill -0 "$PID" 2>/dev/null; then
-            break
-        fi
-        sleep 1
-    done
-    if kill -0 "$PID" 2>/dev/null; then
-        echo "Force killing Scouter Server (PID: $PID)..."
-        kill -9 "$PID"
+PID=$(cat "$PID_FILE")
+echo "Stopping Scouter Server (PID: $PID) with SIGTERM ..."
+kill "$PID" 2>/dev/null || true
+for i in $(seq 1 30); do
+    if ! kill -0 "$PID" 2>/dev/null; then
+        break
     fi
+    sleep 1
 done
+if kill -0 "$PID" 2>/dev/null; then
+    echo "Force killing Scouter Server (PID: $PID)..."
+    kill -9 "$PID"
+fi
+rm -f "$PID_FILE"
 
 echo "Scouter Server stopped."